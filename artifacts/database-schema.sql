@@ -4,13 +4,30 @@
 -- Enable UUID extension
 CREATE EXTENSION IF NOT EXISTS "pgcrypto";
 
--- Users table
+-- Organizations (tenants); data without an explicit tenant belongs to 'default'
+CREATE TABLE organizations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    slug VARCHAR(63) UNIQUE NOT NULL CHECK (slug ~ '^[a-z0-9]([a-z0-9-]*[a-z0-9])?$'),
+    name VARCHAR(100) NOT NULL CHECK (length(name) >= 1),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+INSERT INTO organizations (id, slug, name)
+VALUES ('00000000-0000-0000-0000-000000000001', 'default', 'Default');
+
+-- Users table (usernames and emails are unique per organization)
 CREATE TABLE users (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    username VARCHAR(50) UNIQUE NOT NULL CHECK (length(username) >= 3),
-    email VARCHAR(255) UNIQUE NOT NULL CHECK (email ~* '^[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}$'),
+    org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE,
+    username VARCHAR(50) NOT NULL CHECK (length(username) >= 3),
+    email VARCHAR(255) NOT NULL CHECK (email ~* '^[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}$'),
     password_hash VARCHAR(255) NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+    role VARCHAR(20) NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin')),
+    locale VARCHAR(10) NOT NULL DEFAULT '',
+    deactivated_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT users_org_username_key UNIQUE (org_id, username),
+    CONSTRAINT users_org_email_key UNIQUE (org_id, email)
 );
 
 -- Indexes for users
@@ -20,6 +37,7 @@ CREATE INDEX idx_users_email ON users(email);
 -- Chatrooms table
 CREATE TABLE chatrooms (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE,
     name VARCHAR(100) NOT NULL CHECK (length(name) >= 1),
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
     created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
@@ -28,10 +46,12 @@ CREATE TABLE chatrooms (
 -- Indexes for chatrooms
 CREATE INDEX idx_chatrooms_name ON chatrooms(name);
 CREATE INDEX idx_chatrooms_created_by ON chatrooms(created_by);
+CREATE INDEX idx_chatrooms_org ON chatrooms(org_id, created_at DESC);
 
 -- Messages table
 CREATE TABLE messages (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE,
     chatroom_id UUID NOT NULL REFERENCES chatrooms(id) ON DELETE CASCADE,
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     content TEXT NOT NULL CHECK (length(content) > 0 AND length(content) <= 1000),
@@ -59,9 +79,14 @@ CREATE INDEX idx_members_user ON chatroom_members(user_id);
 -- Sessions table (for session-based authentication)
 CREATE TABLE sessions (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE,
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     token VARCHAR(255) UNIQUE NOT NULL,
+    -- LEAST(last_activity_at + idle timeout, absolute_expires_at), renewed on activity
     expires_at TIMESTAMP NOT NULL,
+    absolute_expires_at TIMESTAMP NOT NULL,
+    idle_timeout_seconds INTEGER NOT NULL DEFAULT 86400 CHECK (idle_timeout_seconds > 0),
+    last_activity_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
 );
 
@@ -70,6 +95,52 @@ CREATE INDEX idx_sessions_token ON sessions(token);
 CREATE INDEX idx_sessions_user ON sessions(user_id);
 CREATE INDEX idx_sessions_expires ON sessions(expires_at);
 
+-- Single-use WebSocket connection tickets (see POST /api/v1/ws-ticket)
+CREATE TABLE ws_tickets (
+    ticket VARCHAR(64) PRIMARY KEY,
+    session_token VARCHAR(255) NOT NULL REFERENCES sessions(token) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_ws_tickets_expires ON ws_tickets(expires_at);
+
+-- Accounts at external OAuth/OIDC providers linked to local users
+CREATE TABLE user_identities (
+    org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (org_id, provider, subject)
+);
+
+CREATE INDEX idx_user_identities_user ON user_identities(user_id);
+
+-- Per-organization quota overrides (NULL inherits the global limit, 0 is unlimited)
+CREATE TABLE organization_quotas (
+    org_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    max_rooms_per_user INTEGER CHECK (max_rooms_per_user >= 0),
+    max_messages_per_room_per_day INTEGER CHECK (max_messages_per_room_per_day >= 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+-- Bot command usage analytics, one row per answered command
+CREATE TABLE bot_command_usage (
+    id BIGSERIAL PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    chatroom_id UUID NOT NULL,
+    command_type VARCHAR(20) NOT NULL,
+    symbol TEXT NOT NULL DEFAULT '',
+    requested_by VARCHAR(50) NOT NULL DEFAULT '',
+    latency_ms INTEGER NOT NULL,
+    success BOOLEAN NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_bot_command_usage_org_created ON bot_command_usage(org_id, created_at);
+
 -- Function to clean up expired sessions
 CREATE OR REPLACE FUNCTION cleanup_expired_sessions()
 RETURNS void AS $$
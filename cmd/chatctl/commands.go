@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"jobsity-chat/internal/directory"
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/messaging"
+	"jobsity-chat/internal/service"
+)
+
+func runCreateUser(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("create-user")
+	username := fs.String("username", "", "username (required)")
+	email := fs.String("email", "", "email address (required)")
+	password := fs.String("password", "", "password; prefer -password-stdin or CHATCTL_PASSWORD")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from the first line of stdin")
+	role := fs.String("role", domain.RoleUser, "role: user, moderator or admin")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *passwordStdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read password from stdin: %w", err)
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+	if *password == "" {
+		*password = os.Getenv("CHATCTL_PASSWORD")
+	}
+
+	if *username == "" || *email == "" || *password == "" {
+		fmt.Fprintln(fs.Output(), "username, email and password are required")
+		fs.Usage()
+		return errUsage
+	}
+	if !domain.IsValidRole(*role) {
+		return fmt.Errorf("unknown role %q", *role)
+	}
+
+	authService, err := a.authService()
+	if err != nil {
+		return err
+	}
+
+	user, err := authService.Register(ctx, *username, *email, *password)
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	if *role != domain.RoleUser {
+		userRepo, err := a.userRepository()
+		if err != nil {
+			return err
+		}
+		if err := userRepo.UpdateRole(ctx, user.ID, *role); err != nil {
+			return fmt.Errorf("user %s created but role not set: %w", user.ID, err)
+		}
+		user.Role = *role
+	}
+
+	fmt.Fprintf(a.out, "created user %s (id=%s, role=%s)\n", user.Username, user.ID, user.Role)
+	return nil
+}
+
+func runPromoteAdmin(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("promote-admin")
+	username := fs.String("username", "", "username (required)")
+	role := fs.String("role", domain.RoleAdmin, "role to grant: user, moderator or admin")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *username == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	userRepo, err := a.userRepository()
+	if err != nil {
+		return err
+	}
+
+	user, err := userRepo.GetByUsername(ctx, *username)
+	if err != nil {
+		return fmt.Errorf("failed to find user %q: %w", *username, err)
+	}
+
+	if err := userRepo.UpdateRole(ctx, user.ID, *role); err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+
+	fmt.Fprintf(a.out, "user %s role changed from %s to %s\n", user.Username, user.Role, *role)
+	return nil
+}
+
+func runListRooms(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("list-rooms")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	chatroomRepo, err := a.chatroomRepository()
+	if err != nil {
+		return err
+	}
+
+	rooms, err := chatroomRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tCREATED BY\tCREATED AT")
+	for _, room := range rooms {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", room.ID, room.Name, room.CreatedBy, room.CreatedAt.Format(time.RFC3339))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "\n%d room(s)\n", len(rooms))
+	return nil
+}
+
+func runPurgeSessions(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("purge-sessions")
+	username := fs.String("user", "", "delete every session of this username instead of expired sessions")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sessionRepo, err := a.sessionRepository()
+	if err != nil {
+		return err
+	}
+
+	if *username == "" {
+		count, err := sessionRepo.DeleteExpired(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.out, "deleted %d expired session(s)\n", count)
+		return nil
+	}
+
+	userRepo, err := a.userRepository()
+	if err != nil {
+		return err
+	}
+	user, err := userRepo.GetByUsername(ctx, *username)
+	if err != nil {
+		return fmt.Errorf("failed to find user %q: %w", *username, err)
+	}
+
+	count, err := sessionRepo.DeleteByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "deleted %d session(s) of %s\n", count, user.Username)
+	return nil
+}
+
+func runPurgeDeleted(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("purge-deleted")
+	retention := fs.Duration("retention", a.cfg.DeletedRetention, "purge what was deleted longer ago than this")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *retention <= 0 {
+		return fmt.Errorf("-retention must be positive")
+	}
+
+	chatroomRepo, err := a.chatroomRepository()
+	if err != nil {
+		return err
+	}
+	messageRepo, err := a.messageRepository()
+	if err != nil {
+		return err
+	}
+
+	rooms, messages, err := service.NewDeletedPurger(chatroomRepo, messageRepo, *retention).Purge(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "purged %d chatroom(s) and %d message(s)\n", rooms, messages)
+	return nil
+}
+
+func runSendAnnouncement(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("send-announcement")
+	roomID := fs.String("room", "", "chatroom ID")
+	allRooms := fs.Bool("all", false, "send to every chatroom")
+	message := fs.String("message", "", "announcement text (required)")
+	sender := fs.String("sender", "Announcement", "display name shown with the message")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *message == "" || (*roomID == "") == !*allRooms {
+		fmt.Fprintln(fs.Output(), "message and exactly one of -room or -all are required")
+		fs.Usage()
+		return errUsage
+	}
+
+	chatroomRepo, err := a.chatroomRepository()
+	if err != nil {
+		return err
+	}
+
+	var roomIDs []string
+	if *allRooms {
+		rooms, err := chatroomRepo.List(ctx)
+		if err != nil {
+			return err
+		}
+		for _, room := range rooms {
+			roomIDs = append(roomIDs, room.ID)
+		}
+	} else {
+		if _, err := chatroomRepo.GetByID(ctx, *roomID); err != nil {
+			return fmt.Errorf("failed to find chatroom %q: %w", *roomID, err)
+		}
+		roomIDs = []string{*roomID}
+	}
+
+	rmq, err := a.rabbitMQ(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Announcements ride the bot response pipeline so every chat server
+	// instance broadcasts them to its connected clients.
+	for _, id := range roomIDs {
+		err := rmq.PublishStockResponse(ctx, &messaging.StockResponse{
+			ChatroomID:       id,
+			Symbol:           "announcement",
+			FormattedMessage: *message,
+			Sender:           *sender,
+			Timestamp:        time.Now().Unix(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to announce in %s: %w", id, err)
+		}
+	}
+
+	fmt.Fprintf(a.out, "announcement sent to %d room(s)\n", len(roomIDs))
+	return nil
+}
+
+func runReplayDLQ(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("replay-dlq")
+	queue := fs.String("queue", messaging.TopologyFromConfig(a.cfg).DeadLetterQueueName(), "dead-letter queue to drain")
+	limit := fs.Int("limit", 100, "maximum number of messages to replay (0 = all)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	rmq, err := a.rabbitMQ(ctx)
+	if err != nil {
+		return err
+	}
+
+	replayed, err := rmq.ReplayQueue(ctx, *queue, *limit)
+	fmt.Fprintf(a.out, "replayed %d message(s) from %s\n", replayed, *queue)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+func runMigrateCommandsQueue(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("migrate-commands-queue")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	// Not a.rabbitMQ: its Setup fails on the queue this command fixes
+	topology := messaging.TopologyFromConfig(a.cfg)
+	moved, err := messaging.MigrateCommandsQueue(ctx, a.cfg.RabbitMQURL, topology)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "recreated %s, moved %d message(s)\n", topology.CommandsQueue, moved)
+	return nil
+}
+
+func runSyncDirectory(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("sync-directory")
+	dryRun := fs.Bool("dry-run", a.cfg.DirectorySyncDryRun, "report the changes without applying them")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	source, err := directory.FromConfig(a.cfg)
+	if err != nil {
+		return err
+	}
+	if source == nil {
+		return errors.New("DIRECTORY_SYNC_SOURCE is not set")
+	}
+
+	userRepo, err := a.userRepository()
+	if err != nil {
+		return err
+	}
+	identityRepo, err := a.identityRepository()
+	if err != nil {
+		return err
+	}
+	chatroomRepo, err := a.chatroomRepository()
+	if err != nil {
+		return err
+	}
+	sessionRepo, err := a.sessionRepository()
+	if err != nil {
+		return err
+	}
+	txManager, err := a.txManager()
+	if err != nil {
+		return err
+	}
+
+	syncService := service.NewDirectorySyncService(source, userRepo, identityRepo, chatroomRepo, sessionRepo,
+		service.DirectorySyncOptions{
+			GroupRooms: directory.ParseGroupRooms(a.cfg.DirectoryGroupRooms),
+			DryRun:     *dryRun,
+		})
+	syncService.SetTxManager(txManager)
+	report, err := syncService.Sync(ctx)
+	if err != nil {
+		return err
+	}
+
+	verb := "applied"
+	if report.DryRun {
+		verb = "planned (dry run)"
+	}
+	fmt.Fprintf(a.out, "%s sync %s: %d created, %d conflict(s), %d deactivated, %d reactivated, %d membership(s) added, %d skipped\n",
+		source.Name(), verb, report.Created, report.Conflicts, report.Deactivated, report.Reactivated,
+		report.MembershipsAdded, report.Skipped)
+	return nil
+}
+
+func runCreateOrg(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("create-org")
+	slug := fs.String("slug", "", "subdomain / X-Organization value, e.g. acme (required)")
+	name := fs.String("name", "", "display name (defaults to the slug)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *slug == "" {
+		fs.Usage()
+		return errUsage
+	}
+	if *name == "" {
+		*name = *slug
+	}
+
+	orgRepo, err := a.organizationRepository()
+	if err != nil {
+		return err
+	}
+
+	org := &domain.Organization{Slug: strings.ToLower(*slug), Name: *name}
+	if err := orgRepo.Create(ctx, org); err != nil {
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	fmt.Fprintf(a.out, "created organization %s (id=%s)\n", org.Slug, org.ID)
+	return nil
+}
+
+func runSetQuota(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("set-quota")
+	// Every call replaces all overrides; 0 is unlimited, -1 inherits the global limit
+	rooms := fs.Int("rooms-per-user", -1, "chatrooms a user may create (0 unlimited, -1 global limit)")
+	messages := fs.Int("messages-per-room-per-day", -1, "user messages per chatroom per UTC day (0 unlimited, -1 global limit)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	overrides := &domain.QuotaOverrides{}
+	if *rooms >= 0 {
+		overrides.MaxRoomsPerUser = rooms
+	}
+	if *messages >= 0 {
+		overrides.MaxMessagesPerRoomPerDay = messages
+	}
+
+	quotaRepo, err := a.quotaRepository()
+	if err != nil {
+		return err
+	}
+
+	orgID := domain.OrgIDFromContext(ctx)
+	if err := quotaRepo.SetOverrides(ctx, orgID, overrides); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "quotas of organization %s: rooms-per-user=%s messages-per-room-per-day=%s\n",
+		orgID, quotaValue(*rooms), quotaValue(*messages))
+	return nil
+}
+
+func quotaValue(v int) string {
+	switch {
+	case v < 0:
+		return "inherited"
+	case v == 0:
+		return "unlimited"
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,264 @@
+// Command chatctl is an operator CLI for routine administration tasks that
+// would otherwise require psql or the RabbitMQ management UI.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"jobsity-chat/internal/config"
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/messaging"
+	"jobsity-chat/internal/observability"
+	"jobsity-chat/internal/repository/postgres"
+	"jobsity-chat/internal/service"
+)
+
+// command is a chatctl subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, a *app, args []string) error
+}
+
+var commands = []command{
+	{"create-user", "Create a user account", runCreateUser},
+	{"promote-admin", "Change the role of a user (admin by default)", runPromoteAdmin},
+	{"list-rooms", "List chatrooms", runListRooms},
+	{"purge-sessions", "Delete expired sessions, or all sessions of a user", runPurgeSessions},
+	{"purge-deleted", "Permanently delete chatrooms and messages deleted longer ago than the retention window", runPurgeDeleted},
+	{"send-announcement", "Broadcast an announcement to one or all chatrooms", runSendAnnouncement},
+	{"replay-dlq", "Move dead-lettered bot commands back to the commands queue", runReplayDLQ},
+	{"migrate-commands-queue", "Recreate the commands queue with the arguments of this version, keeping its messages", runMigrateCommandsQueue},
+	{"sync-directory", "Provision and deactivate users from the LDAP/SCIM directory once", runSyncDirectory},
+	{"seed", "Create demo users, rooms and message history (idempotent)", runSeed},
+	{"create-org", "Create an organization (tenant)", runCreateOrg},
+	{"set-quota", "Override the usage quotas of the organization", runSetQuota},
+}
+
+// errUsage signals invalid arguments; the usage has already been printed
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help" {
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+
+	cmd, ok := findCommand(os.Args[1])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "chatctl: unknown command %q\n\n", os.Args[1])
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+
+	// Keep operator output readable: only surface warnings from libraries
+	logLevel := os.Getenv("LOG_LEVEL")
+	if logLevel == "" {
+		logLevel = "warn"
+	}
+	observability.InitLogger(logLevel, "text")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	a := &app{cfg: config.Load(), out: os.Stdout}
+	defer a.close()
+
+	ctx, err := a.scopeToOrganization(ctx, os.Getenv("CHATCTL_ORG"))
+	if err == nil {
+		err = cmd.run(ctx, a, os.Args[2:])
+	}
+	if err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "chatctl %s: %v\n", cmd.name, err)
+		}
+		a.close()
+		os.Exit(1)
+	}
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: chatctl <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-22s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'chatctl <command> -h' for command flags.")
+	fmt.Fprintln(w, "Connection settings are read from the same environment as the server.")
+	fmt.Fprintln(w, "Commands act on the default organization unless CHATCTL_ORG names another by slug.")
+}
+
+// newFlagSet returns a flag set whose parse errors are reported as errUsage
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("chatctl "+name, flag.ContinueOnError)
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	return nil
+}
+
+// app lazily opens the connections a command needs
+type app struct {
+	cfg *config.Config
+	out io.Writer
+
+	db  *sql.DB
+	rmq *messaging.RabbitMQ
+}
+
+func (a *app) database() (*sql.DB, error) {
+	if a.db != nil {
+		return a.db, nil
+	}
+	db, err := config.NewPostgresConnection(a.cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	a.db = db
+	return db, nil
+}
+
+func (a *app) rabbitMQ(ctx context.Context) (*messaging.RabbitMQ, error) {
+	if a.rmq != nil {
+		return a.rmq, nil
+	}
+	connCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rmq, err := messaging.NewRabbitMQWithRetry(connCtx, a.cfg.RabbitMQURL, messaging.TopologyFromConfig(a.cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to rabbitmq: %w", err)
+	}
+	a.rmq = rmq
+	return rmq, nil
+}
+
+func (a *app) userRepository() (*postgres.UserRepository, error) {
+	db, err := a.database()
+	if err != nil {
+		return nil, err
+	}
+	return postgres.NewUserRepository(db)
+}
+
+func (a *app) sessionRepository() (*postgres.SessionRepository, error) {
+	db, err := a.database()
+	if err != nil {
+		return nil, err
+	}
+	return postgres.NewSessionRepository(db)
+}
+
+func (a *app) txManager() (*postgres.TxManager, error) {
+	db, err := a.database()
+	if err != nil {
+		return nil, err
+	}
+	return postgres.NewTxManager(db), nil
+}
+
+func (a *app) chatroomRepository() (*postgres.ChatroomRepository, error) {
+	db, err := a.database()
+	if err != nil {
+		return nil, err
+	}
+	return postgres.NewChatroomRepository(db)
+}
+
+func (a *app) messageRepository() (*postgres.MessageRepository, error) {
+	db, err := a.database()
+	if err != nil {
+		return nil, err
+	}
+	return postgres.NewMessageRepository(db)
+}
+
+func (a *app) identityRepository() (*postgres.IdentityRepository, error) {
+	db, err := a.database()
+	if err != nil {
+		return nil, err
+	}
+	return postgres.NewIdentityRepository(db)
+}
+
+func (a *app) organizationRepository() (*postgres.OrganizationRepository, error) {
+	db, err := a.database()
+	if err != nil {
+		return nil, err
+	}
+	return postgres.NewOrganizationRepository(db)
+}
+
+func (a *app) quotaRepository() (*postgres.QuotaRepository, error) {
+	db, err := a.database()
+	if err != nil {
+		return nil, err
+	}
+	return postgres.NewQuotaRepository(db)
+}
+
+// scopeToOrganization returns ctx scoped to the organization with the given
+// slug, or ctx unchanged (the default organization) when slug is empty
+func (a *app) scopeToOrganization(ctx context.Context, slug string) (context.Context, error) {
+	if slug == "" {
+		return ctx, nil
+	}
+	orgRepo, err := a.organizationRepository()
+	if err != nil {
+		return nil, err
+	}
+	org, err := orgRepo.GetBySlug(ctx, slug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find organization %q: %w", slug, err)
+	}
+	return domain.WithOrgID(ctx, org.ID), nil
+}
+
+func (a *app) authService() (*service.AuthService, error) {
+	userRepo, err := a.userRepository()
+	if err != nil {
+		return nil, err
+	}
+	sessionRepo, err := a.sessionRepository()
+	if err != nil {
+		return nil, err
+	}
+	return service.NewAuthService(userRepo, sessionRepo), nil
+}
+
+func (a *app) close() {
+	if a.rmq != nil {
+		if err := a.rmq.Close(); err != nil {
+			slog.Warn("failed to close rabbitmq", slog.String("error", err.Error()))
+		}
+		a.rmq = nil
+	}
+	if a.db != nil {
+		a.db.Close()
+		a.db = nil
+	}
+}
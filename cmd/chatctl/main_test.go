@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode"
+
+	"jobsity-chat/internal/config"
+)
+
+// newTestApp returns an app whose connections cannot be opened, so commands
+// under test must fail before connecting
+func newTestApp() (*app, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return &app{
+		cfg: &config.Config{DatabaseURL: "postgres://invalid host/db", RabbitMQURL: "amqp://invalid host/"},
+		out: out,
+	}, out
+}
+
+func TestFindCommand(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, cmd := range commands {
+		if seen[cmd.name] {
+			t.Errorf("command %q is registered twice", cmd.name)
+		}
+		seen[cmd.name] = true
+
+		found, ok := findCommand(cmd.name)
+		if !ok || found.name != cmd.name {
+			t.Errorf("findCommand(%q) = %q, %v", cmd.name, found.name, ok)
+		}
+	}
+
+	if _, ok := findCommand("drop-database"); ok {
+		t.Error("expected an unknown command not to be found")
+	}
+}
+
+func TestPrintUsage(t *testing.T) {
+	var buf bytes.Buffer
+	printUsage(&buf)
+	for _, cmd := range commands {
+		if !strings.Contains(buf.String(), cmd.name) {
+			t.Errorf("usage does not list %q", cmd.name)
+		}
+	}
+}
+
+func TestCommands_RejectUnknownFlags(t *testing.T) {
+	for _, cmd := range commands {
+		t.Run(cmd.name, func(t *testing.T) {
+			a, _ := newTestApp()
+			defer a.close()
+
+			err := cmd.run(context.Background(), a, []string{"-no-such-flag"})
+			if !errors.Is(err, errUsage) {
+				t.Errorf("expected errUsage, got %v", err)
+			}
+			if a.db != nil || a.rmq != nil {
+				t.Error("expected no connection to be opened")
+			}
+		})
+	}
+}
+
+func TestCommands_RequireFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"create-user", []string{"-username", "alice"}},
+		{"promote-admin", nil},
+		{"send-announcement", []string{"-all"}},
+		{"create-org", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CHATCTL_PASSWORD", "")
+			cmd, ok := findCommand(tt.name)
+			if !ok {
+				t.Fatalf("command %q not found", tt.name)
+			}
+			a, _ := newTestApp()
+			defer a.close()
+
+			if err := cmd.run(context.Background(), a, tt.args); !errors.Is(err, errUsage) {
+				t.Errorf("expected errUsage, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateUser_UnknownRole(t *testing.T) {
+	a, _ := newTestApp()
+	defer a.close()
+
+	err := runCreateUser(context.Background(), a, []string{"-username", "alice", "-email", "alice@example.com", "-password", "s3cret-Passw0rd", "-role", "root"})
+	if err == nil || !strings.Contains(err.Error(), `unknown role "root"`) {
+		t.Errorf("expected an unknown role error, got %v", err)
+	}
+}
+
+func TestSeedPassword(t *testing.T) {
+	first, second := seedPassword(), seedPassword()
+	if first == second {
+		t.Error("expected a random password")
+	}
+	for _, class := range []func(rune) bool{unicode.IsLower, unicode.IsUpper, unicode.IsDigit, unicode.IsPunct} {
+		if !strings.ContainsFunc(first, class) {
+			t.Errorf("expected %q to mix every character class", first)
+		}
+	}
+	if len(first) > 72 {
+		t.Errorf("expected at most 72 bytes, got %d", len(first))
+	}
+}
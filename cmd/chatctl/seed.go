@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"jobsity-chat/internal/domain"
+)
+
+// seedUser is a demo account created by `chatctl seed`
+type seedUser struct {
+	username string
+	email    string
+	role     string
+}
+
+// seedRoom is a demo chatroom with its members and scripted history.
+// The first member is the room creator.
+type seedRoom struct {
+	name     string
+	members  []string
+	messages []seedMessage
+}
+
+type seedMessage struct {
+	username string
+	content  string
+}
+
+var seedUsers = []seedUser{
+	{"alice", "alice@example.com", domain.RoleAdmin},
+	{"bob", "bob@example.com", domain.RoleModerator},
+	{"carol", "carol@example.com", domain.RoleUser},
+	{"dave", "dave@example.com", domain.RoleUser},
+}
+
+var seedRooms = []seedRoom{
+	{
+		name:    "General",
+		members: []string{"alice", "bob", "carol", "dave"},
+		messages: []seedMessage{
+			{"alice", "Welcome to the demo! Say hi 👋"},
+			{"bob", "Hi everyone"},
+			{"carol", "Hello! Try /stock=AAPL.US to get a quote"},
+			{"dave", "And /hello for some zen"},
+		},
+	},
+	{
+		name:    "Markets",
+		members: []string{"bob", "carol"},
+		messages: []seedMessage{
+			{"bob", "Anyone watching tech stocks today?"},
+			{"carol", "Checking /stock=MSFT.US now"},
+		},
+	},
+	{
+		name:    "Random",
+		members: []string{"carol", "dave", "alice"},
+		messages: []seedMessage{
+			{"dave", "Coffee or tea?"},
+			{"alice", "Tea, always"},
+		},
+	},
+}
+
+// runSeed creates demo users, rooms, memberships and message history.
+// Every step checks for existing data first, so running it again only fills
+// in what is missing.
+func runSeed(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("seed")
+	password := fs.String("password", "", "password for the demo users created (default: a random one, printed)")
+	force := fs.Bool("force", false, "allow seeding when ENVIRONMENT is production")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if a.cfg.IsProduction() && !*force {
+		return errors.New("refusing to seed a production environment (use -force to override)")
+	}
+	if *password == "" {
+		*password = seedPassword()
+	}
+
+	authService, err := a.authService()
+	if err != nil {
+		return err
+	}
+	userRepo, err := a.userRepository()
+	if err != nil {
+		return err
+	}
+	chatroomRepo, err := a.chatroomRepository()
+	if err != nil {
+		return err
+	}
+	messageRepo, err := a.messageRepository()
+	if err != nil {
+		return err
+	}
+
+	users := make(map[string]*domain.User, len(seedUsers))
+	created := 0
+	for _, su := range seedUsers {
+		user, err := userRepo.GetByUsername(ctx, su.username)
+		switch {
+		case err == nil:
+			fmt.Fprintf(a.out, "user %s exists\n", su.username)
+		case errors.Is(err, domain.ErrUserNotFound):
+			user, err = authService.Register(ctx, su.username, su.email, *password)
+			if err != nil {
+				return fmt.Errorf("failed to create user %s: %w", su.username, err)
+			}
+			fmt.Fprintf(a.out, "user %s created\n", su.username)
+			created++
+		default:
+			return fmt.Errorf("failed to look up user %s: %w", su.username, err)
+		}
+
+		if user.Role != su.role {
+			if err := userRepo.UpdateRole(ctx, user.ID, su.role); err != nil {
+				return fmt.Errorf("failed to set role of %s: %w", su.username, err)
+			}
+			user.Role = su.role
+		}
+		users[su.username] = user
+	}
+
+	existing, err := chatroomRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+	roomsByName := make(map[string]*domain.Chatroom, len(existing))
+	for _, room := range existing {
+		roomsByName[room.Name] = room
+	}
+
+	for _, sr := range seedRooms {
+		room, ok := roomsByName[sr.name]
+		if ok {
+			fmt.Fprintf(a.out, "room %s exists\n", sr.name)
+		} else {
+			room = &domain.Chatroom{Name: sr.name, CreatedBy: users[sr.members[0]].ID}
+			if err := chatroomRepo.CreateWithMember(ctx, room, room.CreatedBy); err != nil {
+				return fmt.Errorf("failed to create room %s: %w", sr.name, err)
+			}
+			fmt.Fprintf(a.out, "room %s created\n", sr.name)
+		}
+
+		// AddMember ignores existing memberships
+		for _, username := range sr.members {
+			if err := chatroomRepo.AddMember(ctx, room.ID, users[username].ID); err != nil {
+				return fmt.Errorf("failed to add %s to %s: %w", username, sr.name, err)
+			}
+		}
+
+		// Only seed history into empty rooms so reruns don't duplicate it
+		history, err := messageRepo.GetByChatroom(ctx, room.ID, 1)
+		if err != nil {
+			return err
+		}
+		if len(history) > 0 {
+			continue
+		}
+		for _, sm := range sr.messages {
+			msg := &domain.Message{
+				ChatroomID: room.ID,
+				UserID:     users[sm.username].ID,
+				Content:    sm.content,
+			}
+			if err := messageRepo.Create(ctx, msg); err != nil {
+				return fmt.Errorf("failed to seed message in %s: %w", sr.name, err)
+			}
+		}
+		fmt.Fprintf(a.out, "room %s: %d message(s) seeded\n", sr.name, len(sr.messages))
+	}
+
+	fmt.Fprintf(a.out, "seed complete: %d users, %d rooms\n", len(seedUsers), len(seedRooms))
+	if created > 0 {
+		fmt.Fprintf(a.out, "password of the %d user(s) created: %s\n", created, *password)
+	}
+	return nil
+}
+
+// seedPassword returns a random demo password. The fixed parts mix every
+// character class, so it satisfies any password policy.
+func seedPassword() string {
+	return "Demo-" + rand.Text() + "-1"
+}
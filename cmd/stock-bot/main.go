@@ -3,17 +3,29 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"jobsity-chat/internal/app"
 	"jobsity-chat/internal/config"
+	"jobsity-chat/internal/gif"
+	"jobsity-chat/internal/handler"
+	"jobsity-chat/internal/i18n"
 	"jobsity-chat/internal/messaging"
 	"jobsity-chat/internal/observability"
 	"jobsity-chat/internal/stock"
+	"jobsity-chat/internal/zen"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 func main() {
@@ -27,170 +39,312 @@ func main() {
 	if logFormat == "" {
 		logFormat = "json"
 	}
-	observability.InitLogger(logLevel, logFormat)
+	observability.InitLoggerWithOptions(logLevel, logFormat, observability.LoggerOptions{Redact: cfg.LogRedaction})
 
 	slog.Info("starting stock bot")
 
 	rmqCtx, rmqCancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer rmqCancel()
 
-	rmq, err := messaging.NewRabbitMQWithRetry(rmqCtx, cfg.RabbitMQURL)
+	rmq, err := messaging.NewRabbitMQWithRetry(rmqCtx, cfg.RabbitMQURL, messaging.TopologyFromConfig(cfg))
 	if err != nil {
 		slog.Error("failed to connect to rabbitmq", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 	defer rmq.Close()
 
-	stooqClient := stock.NewStooqClient(cfg.StooqAPIURL)
+	stooqClient := stock.StooqClientFromConfig(cfg)
+
+	zenQuotes, err := app.NewZenProvider(cfg)
+	if err != nil {
+		slog.Error("invalid zen provider configuration", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	gifs, err := gif.FromConfig(cfg)
+	if err != nil {
+		slog.Error("invalid gif provider configuration", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	quoteFormatter, err := stock.FormatterFromConfig(cfg)
+	if err != nil {
+		slog.Error("invalid quote template configuration", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
-	msgs, err := rmq.ConsumeStockCommands()
+	concurrency := max(cfg.StockBotConcurrency, 1)
+	prefetch := cfg.StockBotPrefetch
+	if prefetch <= 0 {
+		prefetch = concurrency * 2
+	}
+
+	msgs, err := rmq.ConsumeStockCommands(prefetch)
 	if err != nil {
 		slog.Error("failed to start consuming", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 
-	slog.Info("stock bot is ready to process commands")
+	healthServer := newHealthServer(cfg, rmq, stooqClient)
+	go func() {
+		slog.Info("stock bot health server listening", slog.String("port", cfg.StockBotHealthPort))
+		if err := healthServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("health server error", slog.String("error", err.Error()))
+		}
+	}()
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	slog.Info("stock bot is ready to process commands",
+		slog.Int("concurrency", concurrency),
+		slog.Int("prefetch", prefetch))
+
+	// workCtx is only cancelled when the drain deadline expires, so a
+	// shutdown signal never interrupts a command that can still finish.
+	workCtx, abortWork := context.WithCancel(context.Background())
+	defer abortWork()
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				slog.Info("stopping message consumer")
-				return
-			case msg, ok := <-msgs:
-				if !ok {
-					slog.Info("message channel closed")
-					return
-				}
-				msgCtx, msgCancel := context.WithTimeout(ctx, 30*time.Second)
-				if err := processCommand(msgCtx, msg.Body, stooqClient, rmq); err != nil {
-					slog.Error("error processing command", slog.String("error", err.Error()))
-				}
-				msgCancel()
-				msg.Ack(false)
+	// Workers exit once the delivery channel closes, so waiting on them
+	// covers every in-flight command.
+	var workers sync.WaitGroup
+	for range concurrency {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for msg := range msgs {
+				handleDelivery(workCtx, msg, cfg.StockBotCommandTimeout, stooqClient, quoteFormatter, zenQuotes, gifs, rmq, cfg.StockBotName)
 			}
-		}
-	}()
+		}()
+	}
 
 	<-sigChan
-	slog.Info("shutting down stock bot")
-	cancel()
-	time.Sleep(1 * time.Second)
+	slog.Info("shutting down stock bot, draining in-flight commands",
+		slog.Duration("timeout", cfg.StockBotDrainTimeout))
+
+	if err := rmq.StopConsumingStockCommands(); err != nil {
+		slog.Error("failed to stop consuming", slog.String("error", err.Error()))
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		slog.Info("in-flight commands drained")
+	case <-time.After(cfg.StockBotDrainTimeout):
+		slog.Warn("drain timeout exceeded, requeueing in-flight commands")
+		abortWork()
+		select {
+		case <-drained:
+		case <-time.After(5 * time.Second):
+			slog.Error("in-flight commands did not stop after abort")
+		}
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	if err := healthServer.Shutdown(shutdownCtx); err != nil {
+		slog.Error("health server shutdown error", slog.String("error", err.Error()))
+	}
+
 	slog.Info("stock bot stopped")
 }
 
-var zenPhrases = []string{
-	"The obstacle is the path.",
-	"Let go or be dragged.",
-	"The quieter you become, the more you can hear.",
-	"Nature does not hurry, yet everything is accomplished.",
-	"When you realize nothing is lacking, the whole world belongs to you.",
-	"The journey of a thousand miles begins with a single step.",
-	"Be like water, flowing around obstacles.",
-	"In the midst of chaos, there is also opportunity.",
-	"The wise adapt themselves to circumstances, as water molds itself to the pitcher.",
-	"Tension is who you think you should be. Relaxation is who you are.",
-	"Empty your mind, be formless, shapeless — like water.",
-	"The seed of suffering in you may be strong, but don't wait until you have no more suffering before allowing yourself to be happy.",
-	"Walk as if you are kissing the Earth with your feet.",
-	"Breathing in, I calm body and mind. Breathing out, I smile.",
-	"The present moment is filled with joy and happiness. If you are attentive, you will see it.",
-	"Wherever you are, be there totally.",
-	"Realize deeply that the present moment is all you have.",
-	"Accept — then act. Whatever the present moment contains, accept it as if you had chosen it.",
-	"The primary cause of unhappiness is never the situation but your thoughts about it.",
-	"Life is a balance of holding on and letting go.",
-	"Sometimes you need to step outside, get some air, and remind yourself of who you are and where you want to be.",
-	"The only Zen you find on tops of mountains is the Zen you bring there.",
-	"Before enlightenment: chop wood, carry water. After enlightenment: chop wood, carry water.",
-	"Let things flow naturally forward in whatever way they like.",
-	"Do not seek the truth, only cease to cherish your opinions.",
-	"When the student is ready, the teacher appears.",
-	"The cave you fear to enter holds the treasure you seek.",
-	"Silence is the language of the wise.",
-	"The mind is everything. What you think you become.",
-	"Peace comes from within. Do not seek it without.",
-	"No snowflake ever falls in the wrong place.",
-	"Knowledge is learning something new every day. Wisdom is letting go of something every day.",
-	"In the beginner's mind there are many possibilities, but in the expert's there are few.",
-	"If you understand, things are just as they are. If you do not understand, things are just as they are.",
-	"The moon does not fight. It attacks no one. It does not worry. It does not try to crush others.",
-	"Sitting quietly, doing nothing, spring comes, and the grass grows by itself.",
-	"The snow falls, each flake in its appropriate place.",
-	"To a mind that is still, the whole universe surrenders.",
-	"Muddy water is best cleared by leaving it alone.",
-	"The best time to plant a tree was 20 years ago. The second best time is now.",
-	"A single arrow is easily broken, but not ten in a bundle.",
-	"The bamboo that bends is stronger than the oak that resists.",
-	"Where there is no desire, there is stillness.",
-	"The flame that burns twice as bright burns half as long.",
-	"Be master of mind rather than mastered by mind.",
-	"Flow with whatever may happen and let your mind be free.",
-	"The wise man knows he doesn't know. The fool thinks he knows all.",
-	"Inner peace begins the moment you choose not to allow another person or event to control your emotions.",
-	"Patience is not about waiting, but the ability to keep a good attitude while working hard.",
-	"The root of suffering is attachment.",
+// handleDelivery processes one command and settles it with the broker.
+// Commands interrupted by shutdown are requeued so another instance can
+// pick them up; anything else is acked to avoid poison-message loops.
+func handleDelivery(ctx context.Context, msg amqp.Delivery, timeout time.Duration, stooqClient *stock.StooqClient, quoteFormatter *stock.Formatter, zenQuotes zen.QuoteProvider, gifs gif.Provider, rmq *messaging.RabbitMQ, botName string) {
+	observability.StockBotCommandsInFlight.Inc()
+	defer observability.StockBotCommandsInFlight.Dec()
+
+	msgCtx, msgCancel := context.WithTimeout(ctx, timeout)
+	defer msgCancel()
+
+	err := processCommand(msgCtx, msg.Body, stooqClient, quoteFormatter, zenQuotes, gifs, rmq, botName)
+	if err != nil && ctx.Err() != nil {
+		slog.Warn("requeueing command interrupted by shutdown", slog.String("error", err.Error()))
+		if nackErr := msg.Nack(false, true); nackErr != nil {
+			slog.Error("failed to nack command", slog.String("error", nackErr.Error()))
+		}
+		return
+	}
+	if err != nil {
+		slog.Error("error processing command", slog.String("error", err.Error()))
+	}
+	if ackErr := msg.Ack(false); ackErr != nil {
+		slog.Error("failed to ack command", slog.String("error", ackErr.Error()))
+	}
 }
 
-func processCommand(ctx context.Context, body []byte, stooqClient *stock.StooqClient, rmq *messaging.RabbitMQ) error {
+// newHealthServer exposes /health (RabbitMQ and Stooq reachability) and
+// /metrics for orchestration and scraping.
+func newHealthServer(cfg *config.Config, rmq *messaging.RabbitMQ, stooqClient *stock.StooqClient) *http.Server {
+	readinessCfg := handler.DefaultReadinessConfig()
+	readinessCfg.Timeout = cfg.ReadinessCheckTimeout
+	// Cache longer than the chat server so probes don't hammer Stooq
+	readinessCfg.CacheTTL = max(cfg.ReadinessCacheTTL, 15*time.Second)
+	readinessCfg.DegradedLatency = 0
+
+	checker := handler.NewReadinessChecker(readinessCfg,
+		handler.RabbitMQCheck(rmq),
+		handler.StooqCheck(stooqClient),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /health", checker.Handler())
+	mux.Handle("GET /metrics", promhttp.Handler())
+
+	return &http.Server{
+		Addr:              ":" + cfg.StockBotHealthPort,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
+
+func processCommand(ctx context.Context, body []byte, stooqClient *stock.StooqClient, quoteFormatter *stock.Formatter, zenQuotes zen.QuoteProvider, gifs gif.Provider, rmq *messaging.RabbitMQ, botName string) error {
+	received := time.Now()
+
 	var cmd messaging.BotCommand
 	if err := json.Unmarshal(body, &cmd); err != nil {
+		observability.StockBotErrorsTotal.WithLabelValues("decode").Inc()
+		observability.StockBotCommandsTotal.WithLabelValues("invalid", "error").Inc()
 		return fmt.Errorf("failed to unmarshal command: %w", err)
 	}
 
-	slog.Info("processing bot command",
+	if cmd.CorrelationID != "" {
+		ctx = observability.WithCorrelationID(ctx, cmd.CorrelationID)
+	}
+	logger := observability.FromContext(ctx)
+
+	logger.Info("processing bot command",
 		slog.String("type", cmd.Type),
 		slog.String("chatroom_id", cmd.ChatroomID),
 		slog.String("requested_by", cmd.RequestedBy))
 
 	response := &messaging.StockResponse{
-		ChatroomID: cmd.ChatroomID,
-		Timestamp:  time.Now().Unix(),
+		ChatroomID:      cmd.ChatroomID,
+		CorrelationID:   cmd.CorrelationID,
+		RequestedBy:     cmd.RequestedBy,
+		RequestedByID:   cmd.RequestedByID,
+		Bot:             botName,
+		ParentMessageID: cmd.MessageID,
+		Timestamp:       time.Now().Unix(),
 	}
 
 	switch cmd.Type {
 	case "stock":
+		// Failed lookups are reported under the requested code
+		response.Symbol = strings.ToUpper(cmd.StockCode)
+
+		start := time.Now()
 		quote, err := stooqClient.GetQuote(ctx, cmd.StockCode)
+		observability.StooqRequestDuration.WithLabelValues(stooqOutcome(err)).Observe(time.Since(start).Seconds())
+
+		// Aborted by shutdown (not a lookup timeout): don't answer, the
+		// command will be requeued
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return fmt.Errorf("quote lookup aborted: %w", ctx.Err())
+		}
 
 		if err != nil {
-			slog.Error("error fetching quote",
+			observability.StockBotErrorsTotal.WithLabelValues("fetch").Inc()
+			logger.Error("error fetching quote",
 				slog.String("stock_code", cmd.StockCode),
 				slog.String("error", err.Error()))
-			response.Error = fmt.Sprintf("Failed to fetch quote for %s", cmd.StockCode)
+			response.Error = i18n.T(cmd.Locale, i18n.BotQuoteFailed, cmd.StockCode)
 
-			if err == stock.ErrStockNotFound {
-				response.Error = fmt.Sprintf("Stock %s not found", cmd.StockCode)
+			if errors.Is(err, stock.ErrStockNotFound) {
+				response.Error = i18n.T(cmd.Locale, i18n.BotStockNotFound, cmd.StockCode)
+				if suggestions := stock.Suggest(cmd.StockCode); len(suggestions) > 0 {
+					response.Suggestions = suggestions
+					response.Error = i18n.T(cmd.Locale, i18n.BotStockSuggest, cmd.StockCode, strings.Join(suggestions, ", "))
+				}
 			}
 		} else {
 			response.Symbol = quote.Symbol
 			response.Price = quote.Price
-			response.FormattedMessage = fmt.Sprintf("%s quote is $%.2f per share", quote.Symbol, quote.Price)
-			slog.Info("successfully fetched quote",
+			response.Open, response.High, response.Low = quote.Open, quote.High, quote.Low
+			response.Volume = quote.Volume
+			if percent, ok := quote.ChangePercent(); ok {
+				response.ChangePercent = &percent
+			}
+			response.FormattedMessage = quoteFormatter.Format(cmd.Locale, quote)
+			logger.Info("successfully fetched quote",
 				slog.String("symbol", quote.Symbol),
 				slog.Float64("price", quote.Price))
 		}
 
 	case "hello":
-		phrase := zenPhrases[time.Now().UnixNano()%int64(len(zenPhrases))]
+		phrase, err := zenQuotes.Quote(ctx, cmd.Locale)
+		if err != nil {
+			observability.StockBotErrorsTotal.WithLabelValues("zen").Inc()
+			logger.Error("error fetching zen phrase", slog.String("error", err.Error()))
+			response.Error = i18n.T(cmd.Locale, i18n.BotZenFailed)
+			break
+		}
 		response.FormattedMessage = phrase
 		response.Symbol = "zen"
-		slog.Info("sending zen phrase",
+		logger.Info("sending zen phrase",
 			slog.String("phrase", phrase))
 
+	case "giphy":
+		response.Symbol = "gif"
+		attachment, err := gifs.Search(ctx, cmd.Query)
+		switch {
+		case errors.Is(err, gif.ErrDisabled):
+			response.Error = i18n.T(cmd.Locale, i18n.BotGIFDisabled)
+		case errors.Is(err, gif.ErrNotFound):
+			response.Error = i18n.T(cmd.Locale, i18n.BotGIFNotFound, cmd.Query)
+		case err != nil:
+			observability.StockBotErrorsTotal.WithLabelValues("gif").Inc()
+			logger.Error("error searching gif",
+				slog.String("query", cmd.Query),
+				slog.String("error", err.Error()))
+			response.Error = i18n.T(cmd.Locale, i18n.BotGIFFailed, cmd.Query)
+		default:
+			response.FormattedMessage = i18n.T(cmd.Locale, i18n.BotGIF, cmd.Query)
+			response.Attachment = attachment
+			logger.Info("sending gif",
+				slog.String("query", cmd.Query),
+				slog.String("url", attachment.URL))
+		}
+
 	default:
-		response.Error = fmt.Sprintf("Unknown command type: %s", cmd.Type)
-		slog.Warn("unknown command type", slog.String("type", cmd.Type))
+		response.Error = i18n.T(cmd.Locale, i18n.BotUnknownCommand, cmd.Type)
+		logger.Warn("unknown command type", slog.String("type", cmd.Type))
+		cmd.Type = "unknown" // bound label cardinality
 	}
 
+	response.CommandType = cmd.Type
+	response.DurationMS = time.Since(received).Milliseconds()
+
 	if err := rmq.PublishStockResponse(ctx, response); err != nil {
+		observability.StockBotErrorsTotal.WithLabelValues("publish").Inc()
+		observability.StockBotCommandsTotal.WithLabelValues(cmd.Type, "error").Inc()
 		return fmt.Errorf("failed to publish response: %w", err)
 	}
 
+	result := "success"
+	if response.Error != "" {
+		result = "error"
+	}
+	observability.StockBotCommandsTotal.WithLabelValues(cmd.Type, result).Inc()
+	observability.StockBotCommandDuration.WithLabelValues(cmd.Type, result).Observe(time.Since(received).Seconds())
+
 	return nil
 }
+
+func stooqOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, stock.ErrStockNotFound):
+		return "not_found"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,496 @@
+// Package app wires the chat server together from its configuration, so it
+// can be started by cmd/chat-server or embedded in tests and other
+// entrypoints.
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"jobsity-chat/internal/config"
+	"jobsity-chat/internal/directory"
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/events"
+	"jobsity-chat/internal/handler"
+	"jobsity-chat/internal/jobs"
+	"jobsity-chat/internal/mail"
+	"jobsity-chat/internal/messaging"
+	"jobsity-chat/internal/middleware"
+	"jobsity-chat/internal/oauth"
+	"jobsity-chat/internal/push"
+	"jobsity-chat/internal/pwned"
+	"jobsity-chat/internal/repository/postgres"
+	"jobsity-chat/internal/service"
+	"jobsity-chat/internal/unfurl"
+	"jobsity-chat/internal/websocket"
+)
+
+// Server is a wired chat server. New connects it to its dependencies, Run
+// starts its background work and serves HTTP, and Shutdown stops both.
+type Server struct {
+	cfg *config.Config
+	db  *sql.DB
+	rmq *messaging.RabbitMQ
+	hub *websocket.Hub
+
+	authService     *service.AuthService
+	chatService     *service.ChatService
+	profileService  *service.ProfileService
+	exportService   *service.ExportService
+	chatroomStats   *service.ChatroomStatsService
+	sessionActivity *service.SessionActivityTracker
+	botStats        domain.BotStatsRepository
+	bots            *messaging.BotRegistry
+	linkPreviews    *unfurl.Worker
+	pushNotifier    *push.Notifier
+	leader          *postgres.LeaderElector
+	jobRunner       *jobs.Runner
+	// loginThrottle is nil when failed logins are not throttled
+	loginThrottle *service.LoginThrottle
+	// instanceID tells this instance's presence samples from the others'
+	instanceID string
+
+	listen         listenSpec
+	trustedProxies []*net.IPNet
+	// csrfKey is set when cookie-authenticated requests need CSRF tokens
+	csrfKey    []byte
+	handler    http.Handler
+	httpServer *http.Server
+
+	// Each group is stopped in its own step of the lifecycle; ctx outlives
+	// them all and only ends once the connections are closed
+	hubGroup    *group
+	consumers   *group
+	jobGroup    *group
+	leaderGroup *group
+	lifecycle   lifecycle
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+// New validates cfg, connects to Postgres and RabbitMQ and wires the
+// repositories, services, handlers and routes. Nothing runs until Run.
+func New(cfg *config.Config) (*Server, error) {
+	listen, err := parseListenSpec(cfg.ListenAddress, cfg.Port, cfg.ListenSocketMode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen address: %w", err)
+	}
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	cookieMode, err := handler.ParseCookieMode(cfg.CookieMode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cookie configuration: %w", err)
+	}
+	duplicatePolicy, err := websocket.ParseDuplicatePolicy(cfg.WSDuplicateConnectionPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid websocket configuration: %w", err)
+	}
+	jobSchedules, err := jobs.ParseSchedules(cfg.JobSchedules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid job schedules: %w", err)
+	}
+	directorySource, err := directory.FromConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid directory sync configuration: %w", err)
+	}
+	pushProviders, vapidPublicKey, err := push.ProvidersFromConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid push notification configuration: %w", err)
+	}
+	mailSender, err := mail.FromConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mail configuration: %w", err)
+	}
+	if cfg.GuestAccessEnabled && cfg.GuestSessionTTL <= 0 {
+		return nil, fmt.Errorf("invalid guest access configuration: GUEST_SESSION_TTL must be positive")
+	}
+	bots, err := messaging.BotsFromConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bots configuration: %w", err)
+	}
+
+	s := &Server{
+		cfg:            cfg,
+		listen:         listen,
+		trustedProxies: trustedProxies,
+		bots:           bots,
+		hubGroup:       newGroup(),
+		consumers:      newGroup(),
+		jobGroup:       newGroup(),
+		leaderGroup:    newGroup(),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.addLifecycle()
+	if err := s.connect(); err != nil {
+		s.close()
+		return nil, err
+	}
+
+	repos, err := newRepositories(s.db)
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+
+	// Subsystems react to what services do through the event bus
+	eventBus := events.NewBus()
+	eventBus.SubscribeAll(events.Audit)
+
+	txManager := postgres.NewTxManager(s.db)
+
+	s.authService = service.NewAuthServiceWithPolicy(repos.users, repos.sessions, service.SessionPolicy{
+		IdleTimeout:             cfg.SessionIdleTimeout,
+		AbsoluteTimeout:         cfg.SessionAbsoluteTimeout,
+		RememberIdleTimeout:     cfg.SessionRememberIdleTimeout,
+		RememberAbsoluteTimeout: cfg.SessionRememberAbsoluteTimeout,
+	})
+	quotaService := service.NewQuotaService(repos.quotas, domain.QuotaLimits{
+		MaxRoomsPerUser:          cfg.QuotaMaxRoomsPerUser,
+		MaxMessagesPerRoomPerDay: cfg.QuotaMaxMessagesPerRoomPerDay,
+	})
+	s.authService.SetEventPublisher(eventBus)
+	// Every password hash of the server shares one pool
+	passwordHasher := service.NewHashingPool(service.HashingPoolConfig{
+		Workers:   cfg.PasswordHashWorkers,
+		QueueSize: cfg.PasswordHashQueueSize,
+		MaxWait:   cfg.PasswordHashMaxWait,
+	})
+	s.authService.SetPasswordHasher(passwordHasher)
+	s.authService.SetPasswordPolicy(service.PasswordPolicy{
+		MinLength:      cfg.PasswordMinLength,
+		MinCharClasses: cfg.PasswordMinCharClasses,
+		Banned:         middleware.ParseCORSList(cfg.PasswordBanned),
+	})
+	if cfg.PasswordBreachCheck {
+		breachURL := cfg.PasswordBreachAPIURL
+		if breachURL == "" {
+			breachURL = pwned.DefaultURL
+		}
+		s.authService.SetBreachChecker(pwned.NewClient(breachURL))
+	}
+	s.authService.SetUsernameHistory(repos.usernames, cfg.UsernameHoldPeriod)
+	reservedUsernames := reservedNames(cfg.ReservedUsernames, service.DefaultReservedUsernames, bots.Names()...)
+	s.authService.SetReservedNames(reservedUsernames)
+	if cfg.GuestAccessEnabled {
+		s.authService.SetGuestSessionTTL(cfg.GuestSessionTTL)
+	}
+	s.chatService = service.NewChatServiceWithQuotas(repos.messages, repos.chatrooms, quotaService)
+	s.chatService.SetUserRepository(repos.users)
+	s.chatService.SetEventPublisher(eventBus)
+	s.chatService.SetRSVPRepository(repos.rsvps)
+	s.chatService.SetReservedRoomNames(reservedNames(cfg.ReservedRoomNames, service.DefaultReservedRoomNames))
+	s.chatService.SetTxManager(txManager)
+	ticketService := service.NewWSTicketService(repos.tickets, repos.sessions)
+	moderationService := service.NewModerationService(repos.moderation, repos.messages, repos.chatrooms)
+	moderationService.SetHideThreshold(cfg.MessageFlagHideThreshold)
+	oauthService := service.NewOAuthService(repos.users, repos.identities, s.authService)
+	oauthService.SetTxManager(txManager)
+	oauthService.SetReservedNames(reservedUsernames)
+	oauthService.SetPasswordHasher(passwordHasher)
+	oauthProviders := oauth.RegistryFromConfig(cfg)
+	slog.Info("oauth providers configured", slog.Any("providers", oauthProviders.Names()))
+
+	s.hub = websocket.NewHub()
+	s.hub.SetDuplicatePolicy(duplicatePolicy)
+	s.hub.SetAwayAfter(cfg.AwayAfter)
+	s.hub.SetMalformedFrameBudget(cfg.WSMalformedFrameBudget)
+	s.hub.SetSlowClientThreshold(cfg.WSSlowClientThreshold)
+	s.chatService.SetPresence(s.hub)
+	moderationService.SetShadowBanFilter(s.hub)
+	s.botStats = repos.botStats
+
+	// Cleanups, purges and directory syncs run on one instance only
+	s.leader = postgres.NewLeaderElector(s.db, postgres.LeaderLockKey)
+	s.jobRunner = jobs.NewRunner(s.leader)
+	s.jobRunner.SetSchedules(jobSchedules)
+	s.exportService = service.NewExportService(repos.exports, repos.users, cfg.DataExportTTL)
+	s.chatroomStats = service.NewChatroomStatsService(repos.stats, repos.chatrooms)
+	s.instanceID = rand.Text()
+
+	var directorySync *service.DirectorySyncService
+	if directorySource != nil {
+		directorySync = service.NewDirectorySyncService(directorySource, repos.users, repos.identities, repos.chatrooms, repos.sessions,
+			service.DirectorySyncOptions{
+				GroupRooms: directory.ParseGroupRooms(cfg.DirectoryGroupRooms),
+				DryRun:     cfg.DirectorySyncDryRun,
+			})
+		directorySync.SetTxManager(txManager)
+		directorySync.SetPasswordHasher(passwordHasher)
+		directorySync.SetDeactivationNotifier(s.hub)
+		slog.Info("directory sync scheduled",
+			slog.String("source", directorySource.Name()),
+			slog.Bool("dry_run", cfg.DirectorySyncDryRun))
+	}
+	s.pushNotifier = push.NewNotifier(repos.pushDevices, s.hub, pushProviders)
+	if len(pushProviders) > 0 {
+		events.On(eventBus, func(ctx context.Context, e domain.MessageSent) {
+			s.pushNotifier.Enqueue(ctx, e.Message)
+		})
+	}
+
+	if err := s.addJobs(repos, directorySync); err != nil {
+		s.close()
+		return nil, err
+	}
+
+	if allow := unfurl.ParseAllowlist(cfg.LinkPreviewAllowedDomains); !allow.Empty() {
+		s.linkPreviews = unfurl.NewWorker(unfurl.NewFetcher(allow), repos.linkPreview, s.hub)
+		events.On(eventBus, func(ctx context.Context, e domain.MessageSent) {
+			s.linkPreviews.Enqueue(ctx, e.Message)
+		})
+	}
+
+	mailer := mail.NewMailer(mailSender, cfg.PublicBaseURL)
+	inviteService := service.NewInviteService(repos.invites, repos.chatrooms, repos.users, mailer, cfg.InviteTTL)
+	inviteService.SetEventPublisher(eventBus)
+
+	s.sessionActivity = service.NewSessionActivityTracker(repos.sessions, cfg.SessionActivityFlushInterval)
+
+	profileService := service.NewProfileService(repos.users, s.hub)
+	s.profileService = profileService
+	profileService.SetStatusPublisher(s.hub)
+	profileService.SetUsernameHistory(repos.usernames, service.UsernamePolicy{
+		Cooldown:   cfg.UsernameChangeCooldown,
+		HoldPeriod: cfg.UsernameHoldPeriod,
+	})
+	profileService.SetRenameNotifier(s.hub)
+	profileService.SetReservedNames(reservedUsernames)
+
+	h := &handlers{
+		auth:       handler.NewAuthHandler(s.authService),
+		oauth:      handler.NewOAuthHandler(oauthService, oauthProviders),
+		chatroom:   handler.NewChatroomHandler(s.chatService, s.hub),
+		ws:         handler.NewWebSocketHandler(s.hub, s.chatService, s.authService, s.rmq, repos.sessions, cfg.AllowedOrigins),
+		wsTicket:   handler.NewWSTicketHandler(ticketService),
+		botStats:   handler.NewBotStatsHandler(repos.botStats),
+		hubStats:   handler.NewHubStatsHandler(s.hub),
+		connection: handler.NewConnectionHandler(s.hub),
+		user:       handler.NewUserHandler(profileService),
+		moderation: handler.NewModerationHandler(moderationService),
+		push: handler.NewPushHandler(s.pushNotifier, handler.PushConfig{
+			Platforms:      s.pushNotifier.Platforms(),
+			VAPIDPublicKey: vapidPublicKey,
+		}),
+		invite:   handler.NewInviteHandler(inviteService),
+		export:   handler.NewExportHandler(s.exportService),
+		search:   handler.NewSearchHandler(service.NewSearchService(repos.search)),
+		stats:    handler.NewChatroomStatsHandler(s.chatroomStats),
+		presence: handler.NewPresenceHistoryHandler(repos.stats),
+	}
+	h.auth.SetInviteService(inviteService)
+	if cfg.LoginThrottleBaseDelay > 0 {
+		s.loginThrottle = service.NewLoginThrottle(repos.logins, service.LoginThrottleConfig{
+			BaseDelay: cfg.LoginThrottleBaseDelay,
+			MaxDelay:  cfg.LoginThrottleMaxDelay,
+			Window:    cfg.LoginThrottleWindow,
+		})
+		h.auth.SetLoginThrottle(s.loginThrottle)
+	}
+	h.auth.SetCookieMode(cookieMode)
+	h.oauth.SetCookieMode(cookieMode)
+	h.oauth.SetSessionLookup(repos.sessions)
+	if cookieMode == handler.CookieModeEmbedded {
+		// Embedded cookies are sent on cross-site requests
+		s.csrfKey = []byte(cfg.SessionSecret)
+		h.auth.SetCSRFKey(s.csrfKey)
+		slog.Info("embedded cookie mode enabled, CSRF tokens required")
+	}
+	h.ws.SetSessionToucher(s.sessionActivity)
+	h.ws.SetTicketService(ticketService)
+	h.ws.SetShadowBanSource(moderationService)
+	h.ws.SetStatusSource(profileService)
+
+	s.handler = s.routes(repos, h)
+	return s, nil
+}
+
+// addLifecycle registers the shutdown steps in dependency order: no new
+// requests, then no WebSocket clients, then no consumers or jobs using the
+// connections, and only then closing them
+func (s *Server) addLifecycle() {
+	s.lifecycle.add("http", httpShutdownTimeout, func(ctx context.Context) error {
+		if s.httpServer == nil {
+			return nil
+		}
+		return s.httpServer.Shutdown(ctx)
+	})
+	// Stopping the hub flushes its pending broadcasts and closes the clients
+	s.lifecycle.add("hub", hubShutdownTimeout, s.hubGroup.stop)
+	// Consumers finish the message in progress; the session activity tracker
+	// persists pending renewals
+	s.lifecycle.add("consumers", consumersShutdownTimeout, s.consumers.stop)
+	s.lifecycle.add("jobs", jobsShutdownTimeout, s.jobGroup.stop)
+	// Leadership is only handed over once this instance's jobs have stopped
+	s.lifecycle.add("leader", leaderShutdownTimeout, s.leaderGroup.stop)
+	s.lifecycle.add("rabbitmq", closeTimeout, func(ctx context.Context) error {
+		if s.rmq == nil {
+			return nil
+		}
+		return s.rmq.Close()
+	})
+	s.lifecycle.add("database", closeTimeout, func(ctx context.Context) error {
+		if s.db == nil {
+			return nil
+		}
+		return s.db.Close()
+	})
+}
+
+func (s *Server) connect() error {
+	connCtx, connCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer connCancel()
+
+	db, err := config.NewInstrumentedPostgresConnection(s.cfg.DatabaseURL, s.cfg.DBSlowQueryThreshold)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	s.db = db
+	if err := db.PingContext(connCtx); err != nil {
+		return fmt.Errorf("database ping failed: %w", err)
+	}
+	slog.Info("connected to postgresql")
+
+	rmqCtx, rmqCancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer rmqCancel()
+
+	rmq, err := messaging.NewRabbitMQWithRetry(rmqCtx, s.cfg.RabbitMQURL, messaging.TopologyFromConfig(s.cfg))
+	if err != nil {
+		return fmt.Errorf("failed to connect to rabbitmq: %w", err)
+	}
+	rmq.SetBots(s.bots)
+	s.rmq = rmq
+	return nil
+}
+
+// Handler returns the server's routes, e.g. to serve them with httptest
+// after Start
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Start starts the WebSocket hub, the bot response consumer, leader
+// election, background jobs and workers, without serving HTTP
+func (s *Server) Start() error {
+	s.hubGroup.Go(func(ctx context.Context) {
+		if err := s.hub.Run(ctx); err != nil && err != context.Canceled {
+			slog.Error("hub error", slog.String("error", err.Error()))
+		}
+	})
+	slog.Info("websocket hub started")
+
+	if err := provisionBots(s.authService, s.profileService, s.bots); err != nil {
+		return err
+	}
+
+	responseConsumer := messaging.NewResponseConsumer(s.rmq, s.hub, s.chatService, s.bots)
+	responseConsumer.SetBotStats(s.botStats)
+	if err := responseConsumer.Start(s.consumers.ctx); err != nil {
+		return fmt.Errorf("failed to start response consumer: %w", err)
+	}
+	s.consumers.Go(func(context.Context) { <-responseConsumer.Done() })
+	slog.Info("response consumer started")
+
+	s.leader.Check(s.leaderGroup.ctx)
+	s.leaderGroup.Go(func(ctx context.Context) {
+		s.leader.Run(ctx, s.cfg.LeaderElectionInterval)
+	})
+	slog.Info("leader election started", slog.Bool("leader", s.leader.IsLeader()))
+
+	s.jobGroup.Go(s.jobRunner.Run)
+
+	s.consumers.Go(func(ctx context.Context) { s.exportService.Run(ctx, 1) })
+	slog.Info("data export worker started")
+
+	if s.linkPreviews != nil {
+		s.consumers.Go(func(ctx context.Context) { s.linkPreviews.Run(ctx, s.cfg.LinkPreviewWorkers) })
+		slog.Info("link previews enabled", slog.String("allowed_domains", s.cfg.LinkPreviewAllowedDomains))
+	}
+	if len(s.pushNotifier.Platforms()) > 0 {
+		s.consumers.Go(func(ctx context.Context) { s.pushNotifier.Run(ctx, s.cfg.PushWorkers) })
+		slog.Info("push notifications enabled", slog.Any("platforms", s.pushNotifier.Platforms()))
+	}
+
+	s.consumers.Go(s.sessionActivity.Run)
+	return nil
+}
+
+// Run starts the server and serves HTTP on the configured port, Unix socket
+// or systemd socket until ctx is cancelled or the listener fails. Call
+// Shutdown afterwards either way.
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := s.listen.listen()
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	if err := s.Start(); err != nil {
+		ln.Close()
+		return err
+	}
+
+	s.httpServer = &http.Server{
+		Handler:      s.handler,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("chat server listening",
+			slog.String("network", ln.Addr().Network()),
+			slog.String("address", ln.Addr().String()))
+		serveErr <- s.httpServer.Serve(ln)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("server error: %w", err)
+	}
+}
+
+// Shutdown stops the server one component at a time: HTTP, the hub, the
+// consumers and workers, background jobs and leader election, and finally
+// the RabbitMQ and database connections. Each step has its own deadline
+// within ctx; the connections are closed even if earlier steps fail.
+func (s *Server) Shutdown(ctx context.Context) error {
+	defer s.cancel()
+	return s.lifecycle.stop(ctx)
+}
+
+// close releases what New set up when wiring fails, before anything started
+func (s *Server) close() {
+	for _, g := range []*group{s.hubGroup, s.consumers, s.jobGroup, s.leaderGroup} {
+		g.cancel()
+	}
+	s.cancel()
+	if s.rmq != nil {
+		s.rmq.Close()
+	}
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+// reservedNames returns the comma-separated names of list, or defaults when
+// it is empty, along with extra
+func reservedNames(list string, defaults []string, extra ...string) *service.ReservedNames {
+	names := middleware.ParseCORSList(list)
+	if len(names) == 0 {
+		names = defaults
+	}
+	return service.NewReservedNames(append(slices.Clone(names), extra...))
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/config"
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestNew_RejectsInvalidConfigBeforeConnecting(t *testing.T) {
+	tests := map[string]func(cfg *config.Config){
+		"listen address":       func(cfg *config.Config) { cfg.ListenAddress = "localhost" },
+		"cookie configuration": func(cfg *config.Config) { cfg.CookieMode = "strict" },
+		"websocket":            func(cfg *config.Config) { cfg.WSDuplicateConnectionPolicy = "newest" },
+		"job schedule":         func(cfg *config.Config) { cfg.JobSchedules = "session_cleanup=@sometimes" },
+		"guest access":         func(cfg *config.Config) { cfg.GuestAccessEnabled = true },
+		"bots":                 func(cfg *config.Config) { cfg.Bots = "name=GifBot commands=weather" },
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Connecting would fail on this URL, so the error must come first
+			cfg := &config.Config{DatabaseURL: "postgres://invalid host/db"}
+			mutate(cfg)
+
+			_, err := New(cfg)
+			testutil.AssertError(t, err)
+			testutil.AssertContains(t, err.Error(), "invalid "+name)
+		})
+	}
+}
+
+func TestCORSConfig_AdminOverride(t *testing.T) {
+	cfg := &config.Config{
+		AllowedOrigins:          "http://localhost:3000",
+		CORSAllowedMethods:      "GET,POST,PUT,DELETE,OPTIONS",
+		CORSAllowedHeaders:      "Content-Type",
+		CORSAdminAllowedOrigins: "https://admin.example.com",
+	}
+
+	cors := corsConfig(cfg)
+	testutil.AssertEqual(t, strings.Join(cors.AllowedOrigins, ","), "http://localhost:3000")
+	testutil.AssertLen(t, cors.Overrides, 2)
+	for _, o := range cors.Overrides {
+		testutil.AssertTrue(t, strings.HasSuffix(o.PathPrefix, "/admin/"), "override should cover admin routes")
+		testutil.AssertEqual(t, strings.Join(o.Policy.AllowedOrigins, ","), "https://admin.example.com")
+		testutil.AssertEqual(t, strings.Join(o.Policy.AllowedMethods, ","), "GET,POST,OPTIONS")
+	}
+}
+
+func TestCleanupSessions(t *testing.T) {
+	sessions := testutil.NewMockSessionRepository()
+	tickets := testutil.NewMockWSTicketRepository()
+	testutil.AssertNoError(t, cleanupSessions(context.Background(), sessions, tickets))
+
+	// A failed session cleanup still cleans up tickets
+	sessions.DeleteExpiredFunc = func(ctx context.Context) (int64, error) {
+		return 0, errors.New("database error")
+	}
+	ticketsCleaned := false
+	tickets.DeleteExpiredFunc = func(ctx context.Context) (int64, error) {
+		ticketsCleaned = true
+		return 0, nil
+	}
+	err := cleanupSessions(context.Background(), sessions, tickets)
+	testutil.AssertError(t, err)
+	testutil.AssertTrue(t, ticketsCleaned, "tickets should be cleaned up after a failed session cleanup")
+}
+
+func TestCleanupGuests(t *testing.T) {
+	users := testutil.NewMockUserRepository()
+	users.Users["old"] = &domain.User{ID: "old", Role: domain.RoleGuest, CreatedAt: time.Now().Add(-25 * time.Hour)}
+	users.Users["new"] = &domain.User{ID: "new", Role: domain.RoleGuest, CreatedAt: time.Now()}
+	users.Users["user"] = &domain.User{ID: "user", Role: domain.RoleUser, CreatedAt: time.Now().Add(-48 * time.Hour)}
+
+	testutil.AssertNoError(t, cleanupGuests(context.Background(), users, 24*time.Hour))
+
+	_, oldKept := users.Users["old"]
+	_, newKept := users.Users["new"]
+	_, userKept := users.Users["user"]
+	testutil.AssertFalse(t, oldKept, "expired guest should be deleted")
+	testutil.AssertTrue(t, newKept, "recent guest should be kept")
+	testutil.AssertTrue(t, userKept, "regular users are never deleted")
+}
+
+func TestCleanupBotStats(t *testing.T) {
+	botStats := testutil.NewMockBotStatsRepository()
+	var cutoff time.Time
+	botStats.DeleteBeforeFunc = func(ctx context.Context, before time.Time) (int64, error) {
+		cutoff = before
+		return 3, nil
+	}
+
+	testutil.AssertNoError(t, cleanupBotStats(context.Background(), botStats, 24*time.Hour))
+	testutil.AssertTrue(t, time.Since(cutoff) >= 24*time.Hour && time.Since(cutoff) < 25*time.Hour,
+		"usage older than the retention should be deleted")
+
+	botStats.DeleteBeforeFunc = func(ctx context.Context, before time.Time) (int64, error) {
+		return 0, errors.New("database error")
+	}
+	testutil.AssertError(t, cleanupBotStats(context.Background(), botStats, 24*time.Hour))
+}
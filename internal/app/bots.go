@@ -0,0 +1,51 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"jobsity-chat/internal/messaging"
+	"jobsity-chat/internal/service"
+)
+
+// stockBotEmail is the email of StockBot's system account, which predates
+// the other bots'
+const stockBotEmail = "bot@jobsity.com"
+
+// provisionBots makes sure every bot has a system account with its avatar
+// and records the account IDs in bots. It runs before the response consumer
+// starts, so answers are never attributed to an account someone can log in
+// to.
+func provisionBots(authService *service.AuthService, profileService *service.ProfileService, bots *messaging.BotRegistry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, bot := range bots.Bots() {
+		user, err := authService.EnsureSystemUser(ctx, bot.Name, botEmail(bot.Name))
+		if err != nil {
+			return fmt.Errorf("failed to provision bot user %s: %w", bot.Name, err)
+		}
+		if bot.AvatarURL != "" {
+			if _, err := profileService.UpdateSettings(ctx, user.ID, service.ProfileSettingsUpdate{AvatarURL: &bot.AvatarURL}); err != nil {
+				return fmt.Errorf("failed to set avatar of bot %s: %w", bot.Name, err)
+			}
+		}
+		bot.UserID = user.ID
+		slog.Info("bot user ready",
+			slog.String("username", user.Username),
+			slog.String("id", user.ID),
+			slog.String("routing_key", bot.RoutingKey))
+	}
+	return nil
+}
+
+// botEmail returns the email of a bot's system account
+func botEmail(name string) string {
+	if strings.EqualFold(name, messaging.DefaultBotName) {
+		return stockBotEmail
+	}
+	return "bot+" + strings.ToLower(name) + "@jobsity.com"
+}
@@ -0,0 +1,169 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/jobs"
+	"jobsity-chat/internal/service"
+)
+
+// cleanupRetry retries failed cleanups a couple of times before waiting for
+// their next scheduled run
+var cleanupRetry = jobs.RetryPolicy{MaxAttempts: 3, Backoff: 30 * time.Second, MaxBackoff: 5 * time.Minute}
+
+// addJobs registers the server's background jobs. Cleanups, purges,
+// statistics refreshes, deferred push notifications and directory syncs run
+// on the leader only.
+func (s *Server) addJobs(repos *repositories, directorySync *service.DirectorySyncService) error {
+	all := []jobs.Job{
+		{
+			Name:           "session_cleanup",
+			Schedule:       jobs.Every(time.Hour),
+			Timeout:        30 * time.Second,
+			Retry:          cleanupRetry,
+			SingleInstance: true,
+			Run: func(ctx context.Context) error {
+				return cleanupSessions(ctx, repos.sessions, repos.tickets)
+			},
+		},
+		{
+			Name:           "deleted_purge",
+			Schedule:       jobs.Every(s.cfg.DeletedPurgeInterval),
+			Timeout:        5 * time.Minute,
+			Retry:          cleanupRetry,
+			SingleInstance: true,
+			Run:            service.NewDeletedPurger(repos.chatrooms, repos.messages, s.cfg.DeletedRetention).Run,
+		},
+		{
+			Name:           "export_cleanup",
+			Schedule:       jobs.Every(time.Hour),
+			Timeout:        5 * time.Minute,
+			Retry:          cleanupRetry,
+			SingleInstance: true,
+			Run:            s.exportService.DeleteExpired,
+		},
+		{
+			// Every instance samples its own hub's connections
+			Name:     "presence_sample",
+			Schedule: jobs.Every(time.Minute),
+			Timeout:  10 * time.Second,
+			Run: func(ctx context.Context) error {
+				return s.chatroomStats.SamplePresence(ctx, s.instanceID, s.hub.GetAllConnectedCounts())
+			},
+		},
+		{
+			Name:           "chatroom_stats_refresh",
+			Schedule:       jobs.Every(15 * time.Minute),
+			RunAtStart:     true,
+			Timeout:        5 * time.Minute,
+			Retry:          cleanupRetry,
+			SingleInstance: true,
+			Run:            s.chatroomStats.Refresh,
+		},
+	}
+	if s.cfg.GuestAccessEnabled {
+		all = append(all, jobs.Job{
+			Name:           "guest_cleanup",
+			Schedule:       jobs.Every(time.Hour),
+			Timeout:        5 * time.Minute,
+			Retry:          cleanupRetry,
+			SingleInstance: true,
+			Run: func(ctx context.Context) error {
+				return cleanupGuests(ctx, repos.users, s.cfg.GuestSessionTTL)
+			},
+		})
+	}
+	if s.loginThrottle != nil {
+		all = append(all, jobs.Job{
+			Name:           "login_failure_cleanup",
+			Schedule:       jobs.Every(time.Hour),
+			Timeout:        time.Minute,
+			Retry:          cleanupRetry,
+			SingleInstance: true,
+			Run:            s.loginThrottle.DeleteExpired,
+		})
+	}
+	if s.cfg.BotStatsRetention > 0 {
+		all = append(all, jobs.Job{
+			Name:           "bot_stats_cleanup",
+			Schedule:       jobs.Every(time.Hour),
+			Timeout:        5 * time.Minute,
+			Retry:          cleanupRetry,
+			SingleInstance: true,
+			Run: func(ctx context.Context) error {
+				return cleanupBotStats(ctx, s.botStats, s.cfg.BotStatsRetention)
+			},
+		})
+	}
+	if len(s.pushNotifier.Platforms()) > 0 {
+		all = append(all, jobs.Job{
+			Name:           "push_deferred",
+			Schedule:       jobs.Every(time.Minute),
+			Timeout:        2 * time.Minute,
+			SingleInstance: true,
+			Run:            s.pushNotifier.DeliverDeferred,
+		})
+	}
+	if directorySync != nil {
+		all = append(all, jobs.Job{
+			Name:           "directory_sync",
+			Schedule:       jobs.Every(s.cfg.DirectorySyncInterval),
+			RunAtStart:     true,
+			Timeout:        10 * time.Minute,
+			SingleInstance: true,
+			Run:            directorySync.Run,
+		})
+	}
+
+	for _, job := range all {
+		if err := s.jobRunner.Add(job); err != nil {
+			return fmt.Errorf("failed to schedule background job: %w", err)
+		}
+	}
+	return nil
+}
+
+// cleanupSessions deletes expired sessions and WebSocket tickets, as the
+// session_cleanup job
+func cleanupSessions(ctx context.Context, repo domain.SessionRepository, ticketRepo domain.WSTicketRepository) error {
+	var errs []error
+	if count, err := repo.DeleteExpired(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("session cleanup failed: %w", err))
+	} else {
+		slog.Info("session cleanup completed", slog.Int64("sessions_deleted", count))
+	}
+
+	if tickets, err := ticketRepo.DeleteExpired(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("ws ticket cleanup failed: %w", err))
+	} else {
+		slog.Info("ws ticket cleanup completed", slog.Int64("tickets_deleted", tickets))
+	}
+	return errors.Join(errs...)
+}
+
+// cleanupGuests deletes the guests whose session TTL has run out, as the
+// guest_cleanup job. Their messages are deleted with them.
+func cleanupGuests(ctx context.Context, repo domain.UserRepository, ttl time.Duration) error {
+	count, err := repo.DeleteGuestsCreatedBefore(ctx, time.Now().Add(-ttl))
+	if err != nil {
+		return fmt.Errorf("guest cleanup failed: %w", err)
+	}
+	slog.Info("guest cleanup completed", slog.Int64("guests_deleted", count))
+	return nil
+}
+
+// cleanupBotStats deletes the bot command usage older than retention, as the
+// bot_stats_cleanup job
+func cleanupBotStats(ctx context.Context, repo domain.BotStatsRepository, retention time.Duration) error {
+	count, err := repo.DeleteBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return fmt.Errorf("bot stats cleanup failed: %w", err)
+	}
+	slog.Info("bot stats cleanup completed", slog.Int64("usage_deleted", count))
+	return nil
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Deadlines for each step of the shutdown. The hub gets enough time to wait
+// for its pending broadcasts, and jobs enough for the runner's grace period.
+const (
+	httpShutdownTimeout      = 10 * time.Second
+	hubShutdownTimeout       = 7 * time.Second
+	consumersShutdownTimeout = 10 * time.Second
+	jobsShutdownTimeout      = 15 * time.Second
+	leaderShutdownTimeout    = 5 * time.Second
+	closeTimeout             = 5 * time.Second
+)
+
+// component is a part of the server the lifecycle stops
+type component struct {
+	name    string
+	timeout time.Duration
+	stop    func(ctx context.Context) error
+}
+
+// lifecycle stops the server's components in the order they were added,
+// which is the reverse of the order they depend on each other, each within
+// its own deadline
+type lifecycle struct {
+	components []component
+}
+
+func (l *lifecycle) add(name string, timeout time.Duration, stop func(ctx context.Context) error) {
+	l.components = append(l.components, component{name: name, timeout: timeout, stop: stop})
+}
+
+// stop stops every component, even after one fails or overruns its
+// deadline, so the connections are always closed. Components stopped once
+// ctx is done get no time at all.
+func (l *lifecycle) stop(ctx context.Context) error {
+	start := time.Now()
+	var errs []error
+	for _, c := range l.components {
+		stopCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		began := time.Now()
+		err := c.stop(stopCtx)
+		cancel()
+
+		if err != nil {
+			slog.Error("failed to stop component",
+				slog.String("component", c.name),
+				slog.Duration("duration", time.Since(began)),
+				slog.String("error", err.Error()))
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+			continue
+		}
+		slog.Info("component stopped",
+			slog.String("component", c.name),
+			slog.Duration("duration", time.Since(began)))
+	}
+	slog.Info("shutdown completed", slog.Duration("duration", time.Since(start)))
+	return errors.Join(errs...)
+}
+
+// group is a set of goroutines started with a shared context and stopped
+// together
+type group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newGroup() *group {
+	g := &group{}
+	g.ctx, g.cancel = context.WithCancel(context.Background())
+	return g
+}
+
+// Go runs fn in a goroutine stop waits for
+func (g *group) Go(fn func(ctx context.Context)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn(g.ctx)
+	}()
+}
+
+// stop cancels the group's context and waits for its goroutines to return
+// until ctx is done
+func (g *group) stop(ctx context.Context) error {
+	g.cancel()
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	return waitDone(ctx, done)
+}
+
+// waitDone waits for done to be closed until ctx is done
+func waitDone(ctx context.Context, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/testutil"
+)
+
+func TestLifecycle_StopsInOrder(t *testing.T) {
+	var l lifecycle
+	var stopped []string
+	for _, name := range []string{"http", "hub", "consumers", "database"} {
+		l.add(name, time.Second, func(ctx context.Context) error {
+			stopped = append(stopped, name)
+			return nil
+		})
+	}
+
+	testutil.AssertNoError(t, l.stop(context.Background()))
+	testutil.AssertEqual(t, len(stopped), 4)
+	for i, name := range []string{"http", "hub", "consumers", "database"} {
+		testutil.AssertEqual(t, stopped[i], name)
+	}
+}
+
+func TestLifecycle_ContinuesAfterFailedStep(t *testing.T) {
+	var l lifecycle
+	slow := newGroup()
+	slow.Go(func(ctx context.Context) {
+		// Ignores cancellation, overrunning its deadline
+		time.Sleep(200 * time.Millisecond)
+	})
+	l.add("slow", 10*time.Millisecond, slow.stop)
+	l.add("broken", time.Second, func(ctx context.Context) error {
+		return errors.New("close failed")
+	})
+	closed := false
+	l.add("database", time.Second, func(ctx context.Context) error {
+		closed = true
+		return nil
+	})
+
+	err := l.stop(context.Background())
+	testutil.AssertError(t, err)
+	testutil.AssertErrorIs(t, err, context.DeadlineExceeded)
+	testutil.AssertContains(t, err.Error(), "broken: close failed")
+	testutil.AssertTrue(t, closed, "later steps should run after a failed one")
+}
+
+func TestGroup_StopWaitsForGoroutines(t *testing.T) {
+	g := newGroup()
+	finished := false
+	g.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		finished = true
+	})
+
+	testutil.AssertNoError(t, g.stop(context.Background()))
+	testutil.AssertTrue(t, finished, "stop should wait for the group's goroutines")
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// systemdFirstFD is the first file descriptor systemd passes listeners on
+const systemdFirstFD = 3
+
+// listenSpec is where the HTTP server listens, parsed from the configuration
+// before anything connects
+type listenSpec struct {
+	// kind is "tcp", "unix" or "systemd"
+	kind string
+	// address is a host:port, a socket path or a systemd socket name
+	address string
+	mode    os.FileMode
+}
+
+// parseListenSpec reads LISTEN_ADDRESS, falling back to PORT on all
+// interfaces when it is empty
+func parseListenSpec(address, port, socketMode string) (listenSpec, error) {
+	switch {
+	case address == "":
+		return listenSpec{kind: "tcp", address: ":" + port}, nil
+
+	case strings.HasPrefix(address, "unix:"):
+		path := strings.TrimPrefix(address, "unix:")
+		if path == "" {
+			return listenSpec{}, errors.New("unix listen address needs a socket path")
+		}
+		mode, err := strconv.ParseUint(socketMode, 8, 32)
+		if err != nil || mode > 0o777 {
+			return listenSpec{}, fmt.Errorf("invalid socket mode %q", socketMode)
+		}
+		return listenSpec{kind: "unix", address: path, mode: os.FileMode(mode)}, nil
+
+	case address == "systemd" || strings.HasPrefix(address, "systemd:"):
+		return listenSpec{kind: "systemd", address: strings.TrimPrefix(strings.TrimPrefix(address, "systemd"), ":")}, nil
+
+	default:
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return listenSpec{}, fmt.Errorf("invalid listen address %q: %w", address, err)
+		}
+		return listenSpec{kind: "tcp", address: address}, nil
+	}
+}
+
+func (l listenSpec) listen() (net.Listener, error) {
+	switch l.kind {
+	case "unix":
+		return listenUnix(l.address, l.mode)
+	case "systemd":
+		return listenSystemd(l.address)
+	default:
+		return net.Listen("tcp", l.address)
+	}
+}
+
+// listenUnix listens on a Unix socket at path, replacing a socket left
+// behind by a previous run. The socket is removed when the listener closes.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// The reverse proxy usually runs as another user sharing a group
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to set socket mode: %w", err)
+	}
+	return ln, nil
+}
+
+// listenSystemd returns a listener passed by systemd socket activation: the
+// one named name with FileDescriptorName=, or the only one when name is
+// empty. The activation variables are cleared so they aren't inherited.
+func listenSystemd(name string) (net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, errors.New("no sockets passed by systemd")
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil, errors.New("no sockets passed by systemd")
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	index := -1
+	switch {
+	case name != "":
+		for i := 0; i < count && i < len(names); i++ {
+			if names[i] == name {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("no socket named %q passed by systemd", name)
+		}
+	case count == 1:
+		index = 0
+	default:
+		return nil, fmt.Errorf("systemd passed %d sockets; name one with systemd:<name>", count)
+	}
+
+	// FileListener duplicates the descriptor, so the original is closed
+	f := os.NewFile(uintptr(systemdFirstFD+index), "systemd:"+name)
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("systemd socket is not a listener: %w", err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"jobsity-chat/internal/testutil"
+)
+
+func TestParseListenSpec(t *testing.T) {
+	tests := []struct {
+		address string
+		want    listenSpec
+		wantErr bool
+	}{
+		{address: "", want: listenSpec{kind: "tcp", address: ":8080"}},
+		{address: "127.0.0.1:9000", want: listenSpec{kind: "tcp", address: "127.0.0.1:9000"}},
+		{address: "unix:/run/chat/chat.sock", want: listenSpec{kind: "unix", address: "/run/chat/chat.sock", mode: 0o660}},
+		{address: "systemd", want: listenSpec{kind: "systemd"}},
+		{address: "systemd:http", want: listenSpec{kind: "systemd", address: "http"}},
+		{address: "unix:", wantErr: true},
+		{address: "localhost", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			got, err := parseListenSpec(tt.address, "8080", "0660")
+			if tt.wantErr {
+				testutil.AssertError(t, err)
+				return
+			}
+			testutil.AssertNoError(t, err)
+			testutil.AssertEqual(t, got, tt.want)
+		})
+	}
+
+	_, err := parseListenSpec("unix:/tmp/chat.sock", "8080", "0999")
+	testutil.AssertError(t, err)
+}
+
+func TestListenUnix_ReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chat.sock")
+
+	// A socket left behind by a previous run that didn't remove it
+	stale, err := net.Listen("unix", path)
+	testutil.AssertNoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := listenUnix(path, 0o600)
+	testutil.AssertNoError(t, err)
+	defer ln.Close()
+
+	info, err := os.Stat(path)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, info.Mode().Perm(), os.FileMode(0o600))
+
+	conn, err := net.Dial("unix", path)
+	testutil.AssertNoError(t, err)
+	conn.Close()
+}
+
+func TestListenUnix_RefusesToReplaceFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chat.sock")
+	testutil.AssertNoError(t, os.WriteFile(path, []byte("data"), 0o600))
+
+	_, err := listenUnix(path, 0o660)
+	testutil.AssertError(t, err)
+	testutil.AssertContains(t, err.Error(), "not a socket")
+}
+
+func TestListenSystemd_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		pid     string
+		fds     string
+		fdNames string
+		want    string
+	}{
+		{name: "", pid: "1", fds: "1", want: "no sockets passed by systemd"},
+		{name: "", pid: strconv.Itoa(os.Getpid()), fds: "0", want: "no sockets passed by systemd"},
+		{name: "", pid: strconv.Itoa(os.Getpid()), fds: "2", fdNames: "http:admin", want: "name one with systemd:<name>"},
+		{name: "metrics", pid: strconv.Itoa(os.Getpid()), fds: "2", fdNames: "http:admin", want: `no socket named "metrics"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			t.Setenv("LISTEN_PID", tt.pid)
+			t.Setenv("LISTEN_FDS", tt.fds)
+			t.Setenv("LISTEN_FDNAMES", tt.fdNames)
+
+			_, err := listenSystemd(tt.name)
+			testutil.AssertError(t, err)
+			testutil.AssertContains(t, err.Error(), tt.want)
+			// The activation variables aren't passed on to children
+			testutil.AssertEqual(t, os.Getenv("LISTEN_FDS"), "")
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"database/sql"
+	"fmt"
+
+	"jobsity-chat/internal/repository/postgres"
+)
+
+// repositories holds the Postgres repositories the server is wired with
+type repositories struct {
+	users       *postgres.UserRepository
+	sessions    *postgres.SessionRepository
+	messages    *postgres.MessageRepository
+	chatrooms   *postgres.ChatroomRepository
+	tickets     *postgres.WSTicketRepository
+	identities  *postgres.IdentityRepository
+	orgs        *postgres.OrganizationRepository
+	quotas      *postgres.QuotaRepository
+	botStats    *postgres.BotStatsRepository
+	moderation  *postgres.ModerationRepository
+	linkPreview *postgres.LinkPreviewRepository
+	pushDevices *postgres.PushDeviceRepository
+	invites     *postgres.RoomInviteRepository
+	exports     *postgres.DataExportRepository
+	rsvps       *postgres.RSVPRepository
+	search      *postgres.SearchRepository
+	stats       *postgres.ChatroomStatsRepository
+	logins      *postgres.LoginFailureRepository
+	usernames   *postgres.UsernameHistoryRepository
+}
+
+func newRepositories(db *sql.DB) (*repositories, error) {
+	var (
+		r   repositories
+		err error
+	)
+	// Each repository prepares its statements; the first failure stops
+	create := func(name string, fn func() error) {
+		if err == nil {
+			if ferr := fn(); ferr != nil {
+				err = fmt.Errorf("failed to create %s repository: %w", name, ferr)
+			}
+		}
+	}
+
+	create("user", func() (err error) { r.users, err = postgres.NewUserRepository(db); return })
+	create("session", func() (err error) { r.sessions, err = postgres.NewSessionRepository(db); return })
+	create("message", func() (err error) { r.messages, err = postgres.NewMessageRepository(db); return })
+	create("chatroom", func() (err error) { r.chatrooms, err = postgres.NewChatroomRepository(db); return })
+	create("ws ticket", func() (err error) { r.tickets, err = postgres.NewWSTicketRepository(db); return })
+	create("identity", func() (err error) { r.identities, err = postgres.NewIdentityRepository(db); return })
+	create("organization", func() (err error) { r.orgs, err = postgres.NewOrganizationRepository(db); return })
+	create("quota", func() (err error) { r.quotas, err = postgres.NewQuotaRepository(db); return })
+	create("bot stats", func() (err error) { r.botStats, err = postgres.NewBotStatsRepository(db); return })
+	create("moderation", func() (err error) { r.moderation, err = postgres.NewModerationRepository(db); return })
+	create("link preview", func() (err error) { r.linkPreview, err = postgres.NewLinkPreviewRepository(db); return })
+	create("push device", func() (err error) { r.pushDevices, err = postgres.NewPushDeviceRepository(db); return })
+	create("room invite", func() (err error) { r.invites, err = postgres.NewRoomInviteRepository(db); return })
+	create("data export", func() (err error) { r.exports, err = postgres.NewDataExportRepository(db); return })
+	create("rsvp", func() (err error) { r.rsvps, err = postgres.NewRSVPRepository(db); return })
+	create("search", func() (err error) { r.search, err = postgres.NewSearchRepository(db); return })
+	create("chatroom stats", func() (err error) { r.stats, err = postgres.NewChatroomStatsRepository(db); return })
+	create("login failure", func() (err error) { r.logins, err = postgres.NewLoginFailureRepository(db); return })
+	create("username history", func() (err error) { r.usernames, err = postgres.NewUsernameHistoryRepository(db); return })
+
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
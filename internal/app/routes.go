@@ -0,0 +1,252 @@
+package app
+
+import (
+	"log/slog"
+	"net/http"
+
+	"jobsity-chat/internal/config"
+	"jobsity-chat/internal/handler"
+	"jobsity-chat/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// handlers are the HTTP handlers the routes dispatch to
+type handlers struct {
+	auth       *handler.AuthHandler
+	oauth      *handler.OAuthHandler
+	chatroom   *handler.ChatroomHandler
+	ws         *handler.WebSocketHandler
+	wsTicket   *handler.WSTicketHandler
+	botStats   *handler.BotStatsHandler
+	hubStats   *handler.HubStatsHandler
+	connection *handler.ConnectionHandler
+	user       *handler.UserHandler
+	moderation *handler.ModerationHandler
+	push       *handler.PushHandler
+	invite     *handler.InviteHandler
+	export     *handler.ExportHandler
+	search     *handler.SearchHandler
+	stats      *handler.ChatroomStatsHandler
+	presence   *handler.PresenceHistoryHandler
+}
+
+// corsConfig applies the configured CORS policy, with admin routes only
+// accepting their own origins and the methods they use. Browsers only keep
+// admin preflights briefly, so revoking an origin takes effect quickly.
+func corsConfig(cfg *config.Config) middleware.CORSConfig {
+	policy := middleware.CORSPolicy{
+		AllowedOrigins:   middleware.ParseOrigins(cfg.AllowedOrigins),
+		AllowedMethods:   middleware.ParseCORSList(cfg.CORSAllowedMethods),
+		AllowedHeaders:   middleware.ParseCORSList(cfg.CORSAllowedHeaders),
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
+	}
+	admin := policy
+	admin.AllowedOrigins = middleware.ParseCORSList(cfg.CORSAdminAllowedOrigins)
+	admin.AllowedMethods = []string{"GET", "POST", "OPTIONS"}
+	admin.MaxAge = 0
+	return middleware.CORSConfig{
+		CORSPolicy: policy,
+		Overrides: []middleware.CORSOverride{
+			{PathPrefix: "/api/v1/admin/", Policy: admin},
+			{PathPrefix: "/api/v2/admin/", Policy: admin},
+		},
+	}
+}
+
+func (s *Server) routes(repos *repositories, h *handlers) http.Handler {
+	cfg := s.cfg
+	r := chi.NewRouter()
+
+	r.Use(chimiddleware.RequestID)
+	r.Use(middleware.Proxy(s.trustedProxies))
+	r.Use(middleware.Correlation())
+	r.Use(middleware.AccessLog(middleware.AccessLogConfig{
+		SampleRates:   middleware.ParseSampleRates(cfg.AccessLogSampling),
+		SlowThreshold: cfg.AccessLogSlowThreshold,
+	}))
+	r.Use(chimiddleware.Recoverer)
+	r.Use(middleware.CORSWithConfig(corsConfig(cfg)))
+	r.Use(middleware.MetricsWithConfig(middleware.MetricsConfig{
+		PathLabels: middleware.ParseMetricsPathLabels(cfg.MetricsPathLabels),
+	}))
+	r.Use(middleware.Compress(middleware.DefaultCompressConfig()))
+	// r.Use(middleware.OpenAPIValidator(middleware.DefaultOpenAPIValidatorConfig()))
+
+	readinessChecks := []handler.DependencyCheck{
+		handler.DatabaseCheck(s.db),
+		handler.RabbitMQCheck(s.rmq),
+	}
+	if cfg.RedisURL != "" {
+		readinessChecks = append(readinessChecks, handler.RedisCheck(cfg.RedisURL))
+	}
+	readinessCfg := handler.DefaultReadinessConfig()
+	readinessCfg.CacheTTL = cfg.ReadinessCacheTTL
+	readinessCfg.Timeout = cfg.ReadinessCheckTimeout
+	readiness := handler.NewReadinessChecker(readinessCfg, readinessChecks...)
+
+	// A hub that misses three heartbeats is considered dead
+	r.Get("/health", handler.Liveness(s.hub, 3*s.hub.HeartbeatInterval()))
+	r.Get("/health/ready", readiness.Handler())
+	r.Handle("/metrics", promhttp.Handler())
+
+	// Scopes API, WebSocket and debug requests to the organization they name
+	tenant := middleware.Tenant(repos.orgs, cfg.TenantBaseDomain)
+
+	if cfg.DebugEndpointsEnabled {
+		debugHandler := handler.NewDebugHandler(s.db, s.hub)
+		r.With(tenant, middleware.DebugAccess(cfg.DebugToken, repos.sessions, repos.users)).
+			Mount("/debug", debugHandler.Routes())
+		slog.Info("debug endpoints enabled", slog.Bool("token_access", cfg.DebugToken != ""))
+	}
+
+	r.Get("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./static/login.html")
+	})
+	r.Get("/register", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./static/register.html")
+	})
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./static/index.html")
+	})
+
+	r.Get("/login.html", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login", http.StatusMovedPermanently)
+	})
+	r.Get("/register.html", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/register", http.StatusMovedPermanently)
+	})
+	r.Get("/index.html", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/", http.StatusMovedPermanently)
+	})
+
+	// Block all other routes to prevent access to files we're not explicitly serving
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Not Found", http.StatusNotFound)
+	})
+
+	// Both API versions share the handlers and rate limits. /api/v2 wraps
+	// errors in a structured envelope and serves the listings with sequence
+	// numbers and pagination metadata.
+	authLimiter := middleware.NewRateLimiter(s.ctx, 5, 10)
+	apiLimiter := middleware.NewRateLimiter(s.ctx, 20, 50)
+	// Profile lookups get a tighter limit to slow down user enumeration
+	profileLimiter := middleware.NewRateLimiter(s.ctx, 2, 10)
+	// Every invite emails an address the owner chose: 20 an hour per user
+	inviteLimiter := middleware.NewRateLimiter(s.ctx, 20.0/3600, 20)
+
+	apiRoutes := func(version int) func(chi.Router) {
+		listChatrooms, getMessages := h.chatroom.List, h.chatroom.GetMessages
+		if version == middleware.APIv2 {
+			listChatrooms, getMessages = h.chatroom.ListV2, h.chatroom.GetMessagesV2
+		}
+
+		return func(r chi.Router) {
+			r.Use(middleware.APIVersion(version))
+			if version == middleware.APIv2 {
+				r.Use(middleware.ErrorEnvelopes())
+			}
+			r.Use(tenant)
+
+			r.Get("/ws/protocol", h.ws.Protocol)
+
+			r.Group(func(r chi.Router) {
+				r.Use(authLimiter.Middleware())
+				r.Post("/auth/register", h.auth.Register)
+				r.Post("/auth/login", h.auth.Login)
+				r.Post("/auth/guest", h.auth.Guest)
+				r.Get("/auth/oauth", h.oauth.Providers)
+				r.Get("/auth/oauth/{provider}", h.oauth.Start)
+				r.Get("/auth/oauth/{provider}/callback", h.oauth.Callback)
+				r.Post("/invites/lookup", h.invite.Lookup)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.Auth(repos.sessions))
+				if s.csrfKey != nil {
+					r.Use(middleware.CSRF(s.csrfKey))
+				}
+				r.Use(middleware.SlidingSession(s.sessionActivity))
+				r.Use(apiLimiter.Middleware())
+
+				r.Get("/auth/me", h.auth.Me)
+				r.Post("/auth/logout", h.auth.Logout)
+				r.Post("/ws-ticket", h.wsTicket.Issue)
+				r.Post("/chatrooms/{id}/join", h.chatroom.Join)
+				r.Get("/chatrooms/{id}/settings", h.chatroom.GetSettings)
+				r.Get("/chatrooms/{id}/messages", getMessages)
+
+				// Guests may only use the routes above, and only for public
+				// chatrooms
+				r.Group(func(r chi.Router) {
+					r.Use(middleware.DenyGuests())
+
+					r.Put("/auth/me/locale", h.auth.SetLocale)
+					r.Put("/auth/me/profile", h.user.UpdateProfileSettings)
+					r.Put("/auth/me/status", h.user.SetStatus)
+					r.Put("/auth/me/username", h.user.ChangeUsername)
+					r.Get("/me/username-history", h.user.UsernameHistory)
+					r.Get("/me/activity", h.chatroom.Activity)
+					r.Get("/me/export", h.export.Export)
+					r.Get("/me/export/{id}/archive", h.export.Download)
+					r.Get("/me/push-devices", h.push.List)
+					r.Post("/me/push-devices", h.push.Register)
+					r.Delete("/me/push-devices/{id}", h.push.Delete)
+					r.Get("/me/dnd-schedule", h.push.DNDSchedule)
+					r.Put("/me/dnd-schedule", h.push.SetDNDSchedule)
+					r.Get("/push/config", h.push.Config)
+					r.Get("/chatrooms", listChatrooms)
+					r.Post("/chatrooms", h.chatroom.Create)
+					r.Delete("/chatrooms/{id}", h.chatroom.Delete)
+					r.Get("/chatrooms/{id}/members", h.chatroom.Members)
+					r.Get("/chatrooms/{id}/stats", h.stats.Stats)
+					r.Post("/chatrooms/{id}/members", h.chatroom.AddMembers)
+					r.Get("/chatrooms/{id}/invites", h.invite.List)
+					r.With(inviteLimiter.UserMiddleware()).Post("/chatrooms/{id}/invites", h.invite.Create)
+					r.Delete("/chatrooms/{id}/invites/{invite_id}", h.invite.Revoke)
+					r.Post("/invites/accept", h.invite.Accept)
+					r.Put("/chatrooms/{id}/settings", h.chatroom.UpdateSettings)
+					r.Put("/chatrooms/{id}/tags", h.chatroom.SetTags)
+					r.Delete("/chatrooms/{id}/tags/{tag}", h.chatroom.RemoveTag)
+					r.Get("/tags", h.chatroom.Tags)
+					r.Get("/search", h.search.Search)
+					r.Put("/chatrooms/{id}/star", h.chatroom.Star)
+					r.Delete("/chatrooms/{id}/star", h.chatroom.Unstar)
+					r.Get("/chatrooms/{id}/keys", h.chatroom.GetKeys)
+					r.Put("/chatrooms/{id}/keys", h.chatroom.SetKey)
+					r.Post("/chatrooms/{id}/read", h.chatroom.MarkRead)
+					r.Post("/messages/{id}/flag", h.moderation.Flag)
+					r.Post("/messages/{id}/forward", h.chatroom.Forward)
+					r.Post("/chatrooms/{id}/events", h.chatroom.CreateEvent)
+					r.Put("/messages/{id}/rsvp", h.chatroom.RSVP)
+					r.Get("/messages/{id}/rsvps", h.chatroom.ListRSVPs)
+					r.Get("/messages/{id}/event.ics", h.chatroom.ExportEvent)
+					r.With(middleware.RequireModerator(repos.users)).Get("/chatrooms/{id}/shadow-bans", h.moderation.ListShadowBans)
+					r.With(middleware.RequireModerator(repos.users)).Put("/chatrooms/{id}/shadow-bans/{user_id}", h.moderation.ShadowBan)
+					r.With(middleware.RequireModerator(repos.users)).Delete("/chatrooms/{id}/shadow-bans/{user_id}", h.moderation.LiftShadowBan)
+					r.With(profileLimiter.Middleware()).Get("/users/{id}", h.user.GetProfile)
+					r.With(profileLimiter.Middleware()).Get("/users/by-username/{username}", h.user.GetProfileByUsername)
+
+					r.With(middleware.RequireAdmin(repos.users)).Get("/admin/bot-stats", h.botStats.Stats)
+					r.With(middleware.RequireAdmin(repos.users)).Get("/admin/hub/stats", h.hubStats.Stats)
+					r.With(middleware.RequireAdmin(repos.users)).Get("/admin/hub/history", h.presence.History)
+					r.With(middleware.RequireAdmin(repos.users)).Post("/admin/users/{id}/disconnect", h.connection.DisconnectUser)
+					r.With(middleware.RequireAdmin(repos.users)).Get("/admin/flags", h.moderation.Queue)
+					r.With(middleware.RequireAdmin(repos.users)).Post("/admin/flags/{id}/resolve", h.moderation.Resolve)
+					r.With(middleware.RequireAdmin(repos.users)).Post("/admin/chatrooms/{id}/restore", h.chatroom.Restore)
+					r.With(middleware.RequireAdmin(repos.users)).Post("/admin/messages/{id}/restore", h.moderation.RestoreMessage)
+				})
+			})
+		}
+	}
+	r.Route("/api/v1", apiRoutes(middleware.APIv1))
+	r.Route("/api/v2", apiRoutes(middleware.APIv2))
+
+	// Auth handled internally to support query param tokens
+	r.With(tenant).Get("/ws/chat/{chatroom_id}", h.ws.HandleConnection)
+
+	return r
+}
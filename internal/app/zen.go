@@ -0,0 +1,30 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"jobsity-chat/internal/config"
+	"jobsity-chat/internal/zen"
+)
+
+// NewZenProvider returns the zen phrase provider configured by
+// STOCK_BOT_ZEN_PROVIDER, defaulting to the embedded phrases
+func NewZenProvider(cfg *config.Config) (zen.QuoteProvider, error) {
+	switch strings.ToLower(cfg.StockBotZenProvider) {
+	case "", zen.ProviderEmbedded:
+		return zen.NewEmbeddedProvider(), nil
+	case zen.ProviderFile:
+		if cfg.StockBotZenFile == "" {
+			return nil, fmt.Errorf("STOCK_BOT_ZEN_FILE is required for the file zen provider")
+		}
+		return zen.NewFileProvider(cfg.StockBotZenFile)
+	case zen.ProviderAPI:
+		if cfg.StockBotZenAPIURL == "" {
+			return nil, fmt.Errorf("STOCK_BOT_ZEN_API_URL is required for the api zen provider")
+		}
+		return zen.NewAPIProvider(cfg.StockBotZenAPIURL, zen.NewEmbeddedProvider()), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", zen.ErrUnknownProvider, cfg.StockBotZenProvider)
+	}
+}
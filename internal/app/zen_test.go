@@ -0,0 +1,35 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"jobsity-chat/internal/config"
+	"jobsity-chat/internal/zen"
+)
+
+func TestNewZenProvider(t *testing.T) {
+	provider, err := NewZenProvider(&config.Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := provider.(*zen.EmbeddedProvider); !ok {
+		t.Errorf("expected the embedded provider by default, got %T", provider)
+	}
+
+	provider, err = NewZenProvider(&config.Config{StockBotZenProvider: "API", StockBotZenAPIURL: "https://zenquotes.io/api/random"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := provider.(*zen.APIProvider); !ok {
+		t.Errorf("expected the api provider, got %T", provider)
+	}
+
+	if _, err := NewZenProvider(&config.Config{StockBotZenProvider: "file"}); err == nil {
+		t.Error("expected an error without STOCK_BOT_ZEN_FILE")
+	}
+
+	if _, err := NewZenProvider(&config.Config{StockBotZenProvider: "fortune"}); !errors.Is(err, zen.ErrUnknownProvider) {
+		t.Errorf("expected ErrUnknownProvider, got %v", err)
+	}
+}
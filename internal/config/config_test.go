@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestConfig_IsProduction(t *testing.T) {
@@ -229,3 +230,108 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+func TestGetEnvDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected time.Duration
+	}{
+		{"valid_duration", "250ms", 250 * time.Millisecond},
+		{"invalid_uses_default", "not-a-duration", time.Second},
+		{"unset_uses_default", "", time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.envValue != "" {
+				t.Setenv("TEST_DURATION_KEY", tt.envValue)
+			}
+
+			got := getEnvDuration("TEST_DURATION_KEY", time.Second)
+			if got != tt.expected {
+				t.Errorf("getEnvDuration() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetEnvInt(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected int
+	}{
+		{"valid_int", "8", 8},
+		{"invalid_uses_default", "eight", 4},
+		{"unset_uses_default", "", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.envValue != "" {
+				t.Setenv("TEST_INT_KEY", tt.envValue)
+			}
+
+			got := getEnvInt("TEST_INT_KEY", 4)
+			if got != tt.expected {
+				t.Errorf("getEnvInt() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetEnvRenamed(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		oldValue string
+		expected string
+	}{
+		{"new_name", "new", "", "new"},
+		{"old_name_fallback", "", "old", "old"},
+		{"new_name_wins", "new", "old", "new"},
+		{"unset_uses_default", "", "", "default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.value != "" {
+				t.Setenv("TEST_RENAMED_KEY", tt.value)
+			}
+			if tt.oldValue != "" {
+				t.Setenv("TEST_OLD_KEY", tt.oldValue)
+			}
+
+			if got := getEnvRenamed("TEST_RENAMED_KEY", "TEST_OLD_KEY", "default"); got != tt.expected {
+				t.Errorf("getEnvRenamed() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetEnvBool(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected bool
+	}{
+		{"true", "true", true},
+		{"one", "1", true},
+		{"false", "false", false},
+		{"invalid_uses_default", "maybe", true},
+		{"unset_uses_default", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.envValue != "" {
+				t.Setenv("TEST_BOOL_KEY", tt.envValue)
+			}
+
+			if got := getEnvBool("TEST_BOOL_KEY", true); got != tt.expected {
+				t.Errorf("getEnvBool() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"time"
 
-	_ "github.com/lib/pq"
+	"jobsity-chat/internal/observability"
+
+	"github.com/lib/pq"
 )
 
 // NewPostgresConnection creates a new PostgreSQL database connection
@@ -13,7 +15,21 @@ func NewPostgresConnection(dbURL string) (*sql.DB, error) {
 	if err != nil {
 		return nil, err
 	}
+	return configurePool(db)
+}
+
+// NewInstrumentedPostgresConnection creates a PostgreSQL database connection
+// whose queries are timed, traced and logged when slower than
+// slowQueryThreshold (see observability.InstrumentConnector)
+func NewInstrumentedPostgresConnection(dbURL string, slowQueryThreshold time.Duration) (*sql.DB, error) {
+	connector, err := pq.NewConnector(dbURL)
+	if err != nil {
+		return nil, err
+	}
+	return configurePool(sql.OpenDB(observability.InstrumentConnector(connector, slowQueryThreshold)))
+}
 
+func configurePool(db *sql.DB) (*sql.DB, error) {
 	// Configure connection pool
 	db.SetMaxOpenConns(25)
 	db.SetMaxIdleConns(5)
@@ -21,6 +37,7 @@ func NewPostgresConnection(dbURL string) (*sql.DB, error) {
 
 	// Verify connection
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, err
 	}
 
@@ -0,0 +1,66 @@
+// Package directory lists users from enterprise directories (LDAP and SCIM
+// service providers) as domain.DirectoryUser for service.DirectorySyncService.
+package directory
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"jobsity-chat/internal/config"
+	"jobsity-chat/internal/domain"
+)
+
+const (
+	SourceLDAP = "ldap"
+	SourceSCIM = "scim"
+)
+
+var ErrUnknownSource = errors.New("unknown directory source")
+
+// FromConfig returns the directory configured by DIRECTORY_SYNC_SOURCE, or
+// nil when directory sync is disabled
+func FromConfig(cfg *config.Config) (domain.DirectorySource, error) {
+	switch strings.ToLower(cfg.DirectorySyncSource) {
+	case "":
+		return nil, nil
+	case SourceLDAP:
+		if cfg.LDAPURL == "" || cfg.LDAPBaseDN == "" {
+			return nil, fmt.Errorf("LDAP_URL and LDAP_BASE_DN are required for ldap directory sync")
+		}
+		return NewLDAP(LDAPConfig{
+			URL:               cfg.LDAPURL,
+			BindDN:            cfg.LDAPBindDN,
+			BindPassword:      cfg.LDAPBindPassword,
+			BaseDN:            cfg.LDAPBaseDN,
+			UserFilter:        cfg.LDAPUserFilter,
+			IDAttribute:       cfg.LDAPIDAttribute,
+			UsernameAttribute: cfg.LDAPUsernameAttribute,
+			EmailAttribute:    cfg.LDAPEmailAttribute,
+			GroupAttribute:    cfg.LDAPGroupAttribute,
+		}), nil
+	case SourceSCIM:
+		if cfg.SCIMBaseURL == "" {
+			return nil, fmt.Errorf("SCIM_BASE_URL is required for scim directory sync")
+		}
+		return NewSCIM(cfg.SCIMBaseURL, cfg.SCIMToken), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSource, cfg.DirectorySyncSource)
+	}
+}
+
+// ParseGroupRooms parses "group=roomID,group=roomID" into a map from group
+// name to chatroom IDs. A group may be listed several times to join several
+// rooms. Malformed pairs are ignored.
+func ParseGroupRooms(spec string) map[string][]string {
+	groupRooms := make(map[string][]string)
+	for _, pair := range strings.Split(spec, ",") {
+		group, roomID, ok := strings.Cut(pair, "=")
+		group, roomID = strings.TrimSpace(group), strings.TrimSpace(roomID)
+		if !ok || group == "" || roomID == "" {
+			continue
+		}
+		groupRooms[group] = append(groupRooms[group], roomID)
+	}
+	return groupRooms
+}
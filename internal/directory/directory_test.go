@@ -0,0 +1,134 @@
+package directory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"jobsity-chat/internal/config"
+	"jobsity-chat/internal/testutil"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+func TestParseGroupRooms(t *testing.T) {
+	got := ParseGroupRooms(" engineering=room-1, engineering=room-2,sales=room-3,broken,=room-4,empty=")
+
+	want := map[string][]string{
+		"engineering": {"room-1", "room-2"},
+		"sales":       {"room-3"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseGroupRooms() = %v, want %v", got, want)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	source, err := FromConfig(&config.Config{})
+	testutil.AssertNoError(t, err)
+	testutil.AssertNil(t, source)
+
+	source, err = FromConfig(&config.Config{DirectorySyncSource: "SCIM", SCIMBaseURL: "https://idp.example.com/scim/v2"})
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, source.Name(), SourceSCIM)
+
+	_, err = FromConfig(&config.Config{DirectorySyncSource: "ldap"})
+	testutil.AssertErrorContains(t, err, "LDAP_URL")
+
+	_, err = FromConfig(&config.Config{DirectorySyncSource: "okta"})
+	testutil.AssertTrue(t, errors.Is(err, ErrUnknownSource), "expected ErrUnknownSource")
+}
+
+func TestSCIMSource_ListUsers(t *testing.T) {
+	users := []string{
+		`{"id":"u1","userName":"alice@example.com","groups":[{"value":"g1","display":"Engineering"}]}`,
+		`{"id":"u2","userName":"bob","active":false,"emails":[{"value":"bob@home.example"},{"value":"bob@example.com","primary":true}]}`,
+		`{"id":"u3","userName":"carol","emails":[{"value":"carol@example.com"}],"groups":[{"value":"g2"}]}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/scim/v2/Users" || r.Header.Get("Authorization") != "Bearer scim-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		start, _ := strconv.Atoi(r.URL.Query().Get("startIndex"))
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+		page := users[start-1 : min(start-1+count, len(users))]
+
+		w.Header().Set("Content-Type", "application/scim+json")
+		fmt.Fprintf(w, `{"totalResults":%d,"startIndex":%d,"Resources":[`, len(users), start)
+		for i, u := range page {
+			if i > 0 {
+				w.Write([]byte(","))
+			}
+			w.Write([]byte(u))
+		}
+		w.Write([]byte("]}"))
+	}))
+	defer server.Close()
+
+	source := NewSCIM(server.URL+"/scim/v2/", "scim-token")
+	source.pageSize = 2
+
+	got, err := source.ListUsers(context.Background())
+
+	testutil.AssertNoError(t, err)
+	testutil.AssertLen(t, got, 3)
+
+	testutil.AssertEqual(t, got[0].ExternalID, "u1")
+	testutil.AssertEqual(t, got[0].Username, "alice")
+	testutil.AssertEqual(t, got[0].Email, "alice@example.com")
+	testutil.AssertTrue(t, got[0].Active, "active should default to true")
+	testutil.AssertEqual(t, got[0].Groups[0], "Engineering")
+
+	testutil.AssertEqual(t, got[1].Email, "bob@example.com")
+	testutil.AssertFalse(t, got[1].Active, "bob should be inactive")
+
+	testutil.AssertEqual(t, got[2].Groups[0], "g2")
+}
+
+func TestSCIMSource_ListUsers_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, err := NewSCIM(server.URL, "").ListUsers(context.Background())
+
+	testutil.AssertErrorContains(t, err, "403")
+}
+
+func TestLDAPSource_UserFromEntry(t *testing.T) {
+	source := NewLDAP(LDAPConfig{
+		IDAttribute:       "objectGUID",
+		UsernameAttribute: "sAMAccountName",
+		EmailAttribute:    "mail",
+		GroupAttribute:    "memberOf",
+	})
+
+	user := source.userFromEntry(ldap.NewEntry("cn=Alice,ou=people,dc=example,dc=com", map[string][]string{
+		"objectGUID":         {string([]byte{0xde, 0xad, 0xbe, 0xef})},
+		"sAMAccountName":     {"alice"},
+		"mail":               {"alice@example.com"},
+		"memberOf":           {"CN=Engineering,OU=Groups,DC=example,DC=com", "cn=everyone,ou=groups,dc=example,dc=com"},
+		"userAccountControl": {"514"},
+	}))
+
+	testutil.AssertEqual(t, user.ExternalID, "deadbeef")
+	testutil.AssertEqual(t, user.Username, "alice")
+	testutil.AssertEqual(t, user.Email, "alice@example.com")
+	testutil.AssertFalse(t, user.Active, "ACCOUNTDISABLE should deactivate")
+	if !reflect.DeepEqual(user.Groups, []string{"Engineering", "everyone"}) {
+		t.Errorf("Groups = %v", user.Groups)
+	}
+
+	// Without an ID attribute the DN identifies the user
+	user = source.userFromEntry(ldap.NewEntry("uid=bob,dc=example,dc=com", map[string][]string{"mail": {"bob@example.com"}}))
+	testutil.AssertEqual(t, user.ExternalID, "uid=bob,dc=example,dc=com")
+	testutil.AssertTrue(t, user.Active, "entries without userAccountControl are active")
+}
@@ -0,0 +1,129 @@
+package directory
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	ldapPageSize = 500
+	ldapTimeout  = 30 * time.Second
+
+	// adAccountDisabled is the ACCOUNTDISABLE flag of Active Directory's
+	// userAccountControl attribute
+	adAccountDisabled = 0x2
+)
+
+// LDAPConfig describes how to find users in an LDAP directory
+type LDAPConfig struct {
+	// URL is an ldap:// or ldaps:// URL
+	URL          string
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	UserFilter   string
+	// IDAttribute holds a stable identifier such as entryUUID or objectGUID.
+	// The entry's DN is used when it is missing.
+	IDAttribute       string
+	UsernameAttribute string
+	EmailAttribute    string
+	// GroupAttribute lists the DNs of the user's groups (e.g. memberOf); the
+	// value of each group's first RDN is used as the group name
+	GroupAttribute string
+}
+
+// LDAPSource lists users with a paged subtree search
+type LDAPSource struct {
+	cfg LDAPConfig
+}
+
+func NewLDAP(cfg LDAPConfig) *LDAPSource {
+	return &LDAPSource{cfg: cfg}
+}
+
+func (s *LDAPSource) Name() string {
+	return SourceLDAP
+}
+
+func (s *LDAPSource) ListUsers(ctx context.Context) ([]domain.DirectoryUser, error) {
+	conn, err := ldap.DialURL(s.cfg.URL, ldap.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ldap: %w", err)
+	}
+	defer conn.Close()
+	conn.SetTimeout(ldapTimeout)
+
+	if s.cfg.BindDN != "" {
+		if err := conn.Bind(s.cfg.BindDN, s.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("failed to bind to ldap: %w", err)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	request := ldap.NewSearchRequest(
+		s.cfg.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(ldapTimeout.Seconds()), false,
+		s.cfg.UserFilter,
+		[]string{s.cfg.IDAttribute, s.cfg.UsernameAttribute, s.cfg.EmailAttribute, s.cfg.GroupAttribute, "userAccountControl"},
+		nil,
+	)
+	result, err := conn.SearchWithPaging(request, ldapPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search ldap: %w", err)
+	}
+
+	users := make([]domain.DirectoryUser, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		users = append(users, s.userFromEntry(entry))
+	}
+	return users, nil
+}
+
+func (s *LDAPSource) userFromEntry(entry *ldap.Entry) domain.DirectoryUser {
+	user := domain.DirectoryUser{
+		ExternalID: entry.DN,
+		Username:   entry.GetAttributeValue(s.cfg.UsernameAttribute),
+		Email:      entry.GetAttributeValue(s.cfg.EmailAttribute),
+		Active:     true,
+	}
+
+	// Binary identifiers such as objectGUID are hex encoded
+	if raw := entry.GetRawAttributeValue(s.cfg.IDAttribute); len(raw) > 0 {
+		if utf8.Valid(raw) {
+			user.ExternalID = string(raw)
+		} else {
+			user.ExternalID = hex.EncodeToString(raw)
+		}
+	}
+
+	if flags, err := strconv.Atoi(entry.GetAttributeValue("userAccountControl")); err == nil {
+		user.Active = flags&adAccountDisabled == 0
+	}
+
+	for _, groupDN := range entry.GetAttributeValues(s.cfg.GroupAttribute) {
+		user.Groups = append(user.Groups, groupName(groupDN))
+	}
+	return user
+}
+
+// groupName returns the value of the first RDN of dn, e.g. "engineering" for
+// "cn=engineering,ou=groups,dc=example,dc=com"
+func groupName(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return dn
+	}
+	return parsed.RDNs[0].Attributes[0].Value
+}
@@ -0,0 +1,139 @@
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"jobsity-chat/internal/domain"
+)
+
+const scimPageSize = 100
+
+// SCIMSource lists users from a SCIM 2.0 service provider's /Users endpoint
+// (e.g. an identity provider's SCIM API)
+type SCIMSource struct {
+	baseURL  string
+	token    string
+	client   *http.Client
+	pageSize int
+}
+
+func NewSCIM(baseURL, token string) *SCIMSource {
+	return &SCIMSource{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		token:    token,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		pageSize: scimPageSize,
+	}
+}
+
+func (s *SCIMSource) Name() string {
+	return SourceSCIM
+}
+
+type scimListResponse struct {
+	TotalResults int        `json:"totalResults"`
+	Resources    []scimUser `json:"Resources"`
+}
+
+type scimUser struct {
+	ID       string `json:"id"`
+	UserName string `json:"userName"`
+	// Active is optional; users without it are treated as active
+	Active *bool `json:"active"`
+	Emails []struct {
+		Value   string `json:"value"`
+		Primary bool   `json:"primary"`
+	} `json:"emails"`
+	Groups []struct {
+		Value   string `json:"value"`
+		Display string `json:"display"`
+	} `json:"groups"`
+}
+
+func (s *SCIMSource) ListUsers(ctx context.Context) ([]domain.DirectoryUser, error) {
+	var users []domain.DirectoryUser
+	for startIndex := 1; ; {
+		page, err := s.fetchPage(ctx, startIndex)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range page.Resources {
+			users = append(users, u.toDirectoryUser())
+		}
+
+		startIndex += len(page.Resources)
+		if len(page.Resources) == 0 || startIndex > page.TotalResults {
+			return users, nil
+		}
+	}
+}
+
+func (s *SCIMSource) fetchPage(ctx context.Context, startIndex int) (*scimListResponse, error) {
+	query := url.Values{}
+	query.Set("startIndex", strconv.Itoa(startIndex))
+	query.Set("count", strconv.Itoa(s.pageSize))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/Users?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/scim+json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scim users: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("scim /Users returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var page scimListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode scim users: %w", err)
+	}
+	return &page, nil
+}
+
+func (u scimUser) toDirectoryUser() domain.DirectoryUser {
+	user := domain.DirectoryUser{
+		ExternalID: u.ID,
+		Username:   u.UserName,
+		Active:     u.Active == nil || *u.Active,
+	}
+
+	for _, email := range u.Emails {
+		if user.Email == "" || email.Primary {
+			user.Email = email.Value
+		}
+	}
+	// userName is commonly the email address itself
+	if user.Email == "" && strings.Contains(u.UserName, "@") {
+		user.Email = u.UserName
+	}
+	if local, _, ok := strings.Cut(user.Username, "@"); ok {
+		user.Username = local
+	}
+
+	for _, group := range u.Groups {
+		name := group.Display
+		if name == "" {
+			name = group.Value
+		}
+		user.Groups = append(user.Groups, name)
+	}
+	return user
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrBotUnavailable is returned for a command no bot answers in the chatroom
+var ErrBotUnavailable = errors.New("no bot answers this command in this chatroom")
+
+// BotCommandUsage records one bot command answered by the stock bot
+type BotCommandUsage struct {
+	CommandType string
+	Symbol      string
+	ChatroomID  string
+	RequestedBy string
+	Latency     time.Duration
+	Success     bool
+}
+
+// BotCommandStats aggregates the usage of one command type and symbol
+type BotCommandStats struct {
+	CommandType  string  `json:"command_type"`
+	Symbol       string  `json:"symbol,omitempty"`
+	Count        int64   `json:"count"`
+	Errors       int64   `json:"errors"`
+	AvgLatencyMS float64 `json:"avg_latency_ms"`
+	P95LatencyMS float64 `json:"p95_latency_ms"`
+}
+
+// BotStatsRepository stores bot command usage. Usage is recorded in the
+// organization owning the chatroom; Stats reads the organization ctx is
+// scoped to.
+type BotStatsRepository interface {
+	Record(ctx context.Context, usage *BotCommandUsage) error
+	// Stats returns usage since the given time, busiest commands first
+	Stats(ctx context.Context, since time.Time) ([]BotCommandStats, error)
+	// DeleteBefore deletes the usage of every organization recorded before
+	// the given time and returns how much was deleted
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrNotAnEvent is returned for RSVPs to, or calendar exports of, messages
+// that don't announce an event
+var ErrNotAnEvent = errors.New("message is not an event")
+
+const (
+	// MaxEventTitleLength caps the title of an event, in characters
+	MaxEventTitleLength = 200
+	// MaxEventLocationLength caps the location of an event, in characters
+	MaxEventLocationLength = 200
+)
+
+// RSVP responses
+const (
+	RSVPGoing    = "going"
+	RSVPMaybe    = "maybe"
+	RSVPDeclined = "declined"
+)
+
+// RSVPResponses are the responses an RSVP accepts
+var RSVPResponses = []string{RSVPGoing, RSVPMaybe, RSVPDeclined}
+
+// CalendarEvent is the event an event message announces. Its title is also
+// the message's content, so clients that don't know events still show it.
+type CalendarEvent struct {
+	Title    string    `json:"title"`
+	StartsAt time.Time `json:"starts_at"`
+	// EndsAt is nil for events without a set end
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+	Location string     `json:"location,omitempty"`
+}
+
+// Validate trims the title and location and returns ErrInvalidInput
+// describing the first invalid field
+func (e *CalendarEvent) Validate() error {
+	e.Title = strings.TrimSpace(e.Title)
+	e.Location = strings.TrimSpace(e.Location)
+	if e.Title == "" || utf8.RuneCountInString(e.Title) > MaxEventTitleLength {
+		return fmt.Errorf("%w: title must be 1 to %d characters", ErrInvalidInput, MaxEventTitleLength)
+	}
+	if utf8.RuneCountInString(e.Location) > MaxEventLocationLength {
+		return fmt.Errorf("%w: location must be at most %d characters", ErrInvalidInput, MaxEventLocationLength)
+	}
+	if e.StartsAt.IsZero() {
+		return fmt.Errorf("%w: starts_at is required", ErrInvalidInput)
+	}
+	if e.EndsAt != nil && !e.EndsAt.After(e.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidInput)
+	}
+	return nil
+}
+
+// RSVP is a member's answer to an event message
+type RSVP struct {
+	MessageID  string    `json:"message_id"`
+	ChatroomID string    `json:"chatroom_id"`
+	UserID     string    `json:"user_id"`
+	Username   string    `json:"username"`
+	Response   string    `json:"response"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ValidRSVPResponse reports whether response is one of RSVPResponses
+func ValidRSVPResponse(response string) bool {
+	return slices.Contains(RSVPResponses, response)
+}
+
+// RSVPRepository stores the RSVPs to event messages
+type RSVPRepository interface {
+	// Set stores rsvp, replacing the user's earlier answer, and sets its
+	// UpdatedAt. It returns ErrMessageNotFound if the message is not in
+	// ctx's organization.
+	Set(ctx context.Context, rsvp *RSVP) error
+	// List returns the RSVPs to a message, oldest answer first
+	List(ctx context.Context, messageID string) ([]*RSVP, error)
+}
@@ -3,20 +3,178 @@ package domain
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 var (
 	ErrChatroomNotFound = errors.New("chatroom not found")
 	ErrNotMember        = errors.New("user is not a member of this chatroom")
+	ErrNotOwner         = errors.New("only the chatroom owner can do this")
+	ErrNotEncrypted     = errors.New("chatroom is not end-to-end encrypted")
+	ErrGuestNotAllowed  = errors.New("chatroom is not open to guests")
+	ErrPublicEncrypted  = errors.New("encrypted chatrooms cannot be public")
+	ErrReadOnly         = errors.New("only the owner and moderators can post in this chatroom")
 )
 
+// Outcomes of adding one user in a bulk membership add. Deactivated users
+// and system accounts such as bots' are never added.
+const (
+	MemberAdded           = "added"
+	MemberAlreadyMember   = "already_member"
+	MemberUserNotFound    = "user_not_found"
+	MemberUserDeactivated = "user_deactivated"
+	MemberSystemUser      = "system_user"
+)
+
+// MemberAddResult reports what happened to one identifier (user ID or
+// username) of a bulk membership add
+type MemberAddResult struct {
+	Identifier string `json:"identifier"`
+	UserID     string `json:"user_id,omitempty"`
+	Status     string `json:"status"`
+}
+
 // Chatroom represents a chat room
 type Chatroom struct {
 	ID        string    `json:"id"`
 	Name      string    `json:"name"`
 	CreatedAt time.Time `json:"created_at"`
 	CreatedBy string    `json:"created_by"`
+	OrgID     string    `json:"org_id"`
+	// Encrypted rooms are end-to-end encrypted: the server relays and stores
+	// their messages as ciphertext it cannot read. It is set at creation
+	// and never changes.
+	Encrypted bool `json:"encrypted"`
+
+	// LastMessageAt, MemberCount, Tags and the caller's star are only set by
+	// ListPaginated
+	LastMessageAt *time.Time `json:"last_message_at,omitempty"`
+	MemberCount   int        `json:"member_count,omitempty"`
+	Tags          []string   `json:"tags,omitempty"`
+	Starred       bool       `json:"starred,omitempty"`
+	// StarPosition orders the caller's starred rooms, lowest first; nil
+	// unless Starred
+	StarPosition *int `json:"star_position,omitempty"`
+}
+
+const (
+	// MaxChatroomTags caps how many tags a chatroom, or a directory filter,
+	// may have
+	MaxChatroomTags = 10
+	// MaxTagLength caps a tag, in characters
+	MaxTagLength = 32
+)
+
+// NormalizeTag returns the canonical, lowercase form of a chatroom tag, or
+// false if it is empty, too long, or has characters other than letters,
+// digits and hyphens
+func NormalizeTag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || utf8.RuneCountInString(tag) > MaxTagLength {
+		return "", false
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' {
+			return "", false
+		}
+	}
+	return tag, true
+}
+
+// TagCount is a tag of the chatroom directory with how many chatrooms carry it
+type TagCount struct {
+	Tag       string `json:"tag"`
+	Chatrooms int    `json:"chatrooms"`
+}
+
+// Sort orders of the chatroom directory
+const (
+	// ChatroomSortNewest lists the most recently created rooms first
+	ChatroomSortNewest = "newest"
+	// ChatroomSortActive lists rooms by their latest message, falling back
+	// to their creation time
+	ChatroomSortActive = "active"
+	// ChatroomSortMembers lists the rooms with the most members first
+	ChatroomSortMembers = "members"
+)
+
+// ChatroomListOptions selects a page of the chatroom directory. Cursor is the
+// ID of the last room of the previous page.
+type ChatroomListOptions struct {
+	Limit  int
+	Cursor string
+	// Sort is one of the ChatroomSort constants, newest when empty
+	Sort string
+	// Name keeps the rooms whose name contains it, case-insensitively
+	Name string
+	// Tags keeps the rooms carrying all of them
+	Tags []string
+	// UserID is the caller, whose stars are reported; empty reports none
+	UserID string
+	// Starred keeps the rooms UserID starred
+	Starred bool
+}
+
+// ChatroomSettings are the owner-configurable settings of a chatroom
+type ChatroomSettings struct {
+	// WelcomeMessage is sent privately to each member the first time they
+	// connect to the chatroom; empty disables it
+	WelcomeMessage string `json:"welcome_message"`
+	// Public opens the chatroom to guests, who can join and read it
+	Public bool `json:"public"`
+	// GuestsCanPost lets guests post in a public chatroom too
+	GuestsCanPost bool `json:"guests_can_post"`
+	// ReadOnly makes the chatroom an announcement channel where only its
+	// owner and moderators post
+	ReadOnly bool `json:"read_only"`
+	// Markdown renders bold, italics, code and links in the chatroom's
+	// messages, which then carry safe HTML next to their raw content. It has
+	// no effect in encrypted chatrooms.
+	Markdown bool `json:"markdown"`
+	// Version is bumped by every update; settings never changed are at
+	// version 1. An update naming a version (non-zero) fails with
+	// ErrVersionConflict unless it is the current one.
+	Version int `json:"version"`
+}
+
+// MemberKey is the key exchange metadata a member published for an
+// encrypted chatroom. KeyData is opaque to the server.
+type MemberKey struct {
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	KeyData   string    `json:"key_data"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Member is a user in a chatroom's member list, with the status they chose.
+// Their presence is not stored and is filled in from live connections.
+type Member struct {
+	UserID           string
+	Username         string
+	Status           UserStatus
+	ShowOnlineStatus bool
+}
+
+// RoomActivity summarizes what a member has not read in one chatroom
+type RoomActivity struct {
+	ChatroomID   string `json:"chatroom_id"`
+	ChatroomName string `json:"chatroom_name"`
+	UnreadCount  int    `json:"unread_count"`
+	// MentionCount is how many unread messages mention the member by
+	// @username
+	MentionCount int             `json:"mention_count"`
+	LastMessage  *MessagePreview `json:"last_message"`
+}
+
+// MessagePreview is the start of a chatroom's latest message
+type MessagePreview struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // ChatroomRepository defines the interface for chatroom data access
@@ -25,7 +183,63 @@ type ChatroomRepository interface {
 	CreateWithMember(ctx context.Context, chatroom *Chatroom, userID string) error
 	GetByID(ctx context.Context, id string) (*Chatroom, error)
 	List(ctx context.Context) ([]*Chatroom, error)
-	ListPaginated(ctx context.Context, limit int, cursor string) ([]*Chatroom, string, error)
+	ListPaginated(ctx context.Context, opts ChatroomListOptions) ([]*Chatroom, string, error)
 	AddMember(ctx context.Context, chatroomID, userID string) error
+	// AddMembers resolves each identifier as a user ID or username of the
+	// chatroom's organization and adds the users in one transaction. Unknown
+	// users are reported rather than failing the batch.
+	AddMembers(ctx context.Context, chatroomID string, identifiers []string) ([]MemberAddResult, error)
 	IsMember(ctx context.Context, chatroomID, userID string) (bool, error)
+	// GetSettings returns the chatroom's settings, with defaults for
+	// settings never changed
+	GetSettings(ctx context.Context, chatroomID string) (*ChatroomSettings, error)
+	// UpdateSettings replaces the chatroom's settings and sets
+	// settings.Version to the new version
+	UpdateSettings(ctx context.Context, chatroomID string, settings *ChatroomSettings) error
+	// ClaimWelcome returns the chatroom's welcome message if the member has
+	// not been sent it yet, and records that they have. It returns "" when
+	// there is no welcome message or it was already sent.
+	ClaimWelcome(ctx context.Context, chatroomID, userID string) (string, error)
+	// Activity returns the unread activity of every chatroom userID is a
+	// member of, most recently active first
+	Activity(ctx context.Context, userID string) ([]*RoomActivity, error)
+	// MarkRead marks the chatroom read by the member up to messageID, or up
+	// to its latest message when messageID is empty. Read markers never move
+	// back. Returns ErrNotMember if userID is not a member.
+	MarkRead(ctx context.Context, chatroomID, userID, messageID string) error
+	// Delete soft-deletes a chatroom: it is left out of every query until it
+	// is restored or purged
+	Delete(ctx context.Context, id string) error
+	// Restore undoes Delete. Returns ErrChatroomNotFound if there is no
+	// deleted chatroom with that ID.
+	Restore(ctx context.Context, id string) error
+	// PurgeDeleted permanently deletes the chatrooms of every organization
+	// that were soft-deleted before cutoff, with their messages, and returns
+	// how many were purged
+	PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error)
+	// SetMemberKey replaces the key exchange metadata userID published for
+	// an encrypted chatroom. Returns ErrNotMember if userID is not a member
+	// and ErrNotEncrypted if the chatroom is not encrypted.
+	SetMemberKey(ctx context.Context, chatroomID, userID, keyData string) error
+	// MemberKeys returns the key exchange metadata of the chatroom's
+	// current members, in the order they published it
+	MemberKeys(ctx context.Context, chatroomID string) ([]*MemberKey, error)
+	// ListMembers returns the active members of the chatroom by username
+	ListMembers(ctx context.Context, chatroomID string) ([]*Member, error)
+	// SetTags replaces the chatroom's tags, which must be normalized and
+	// unique
+	SetTags(ctx context.Context, chatroomID string, tags []string) error
+	// RemoveTag removes one tag of the chatroom; removing a tag it doesn't
+	// carry is a no-op
+	RemoveTag(ctx context.Context, chatroomID, tag string) error
+	// ListTags returns the tags of the organization's chatrooms, the most
+	// used first
+	ListTags(ctx context.Context) ([]*TagCount, error)
+	// Star stars the chatroom for userID at position, or moves an existing
+	// star there. A nil position keeps an existing star's position and puts a
+	// new one after the user's other stars. It returns the star's position,
+	// or ErrChatroomNotFound.
+	Star(ctx context.Context, chatroomID, userID string, position *int) (int, error)
+	// Unstar removes userID's star; unstarring a room not starred is a no-op
+	Unstar(ctx context.Context, chatroomID, userID string) error
 }
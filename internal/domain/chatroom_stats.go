@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// MaxChatroomStatsDays is how many days of activity chatroom statistics
+	// cover at most
+	MaxChatroomStatsDays = 90
+	// TopPostersLimit is how many top posters chatroom statistics list
+	TopPostersLimit = 10
+)
+
+// DailyActivity is a chatroom's activity on one UTC day
+type DailyActivity struct {
+	// Date is the day as YYYY-MM-DD
+	Date     string `json:"date"`
+	Messages int64  `json:"messages"`
+	// PeakUsers is the most users connected at once that day
+	PeakUsers int `json:"peak_users"`
+}
+
+// TopPoster is a member ranked by the messages they posted
+type TopPoster struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	Messages int64  `json:"messages"`
+}
+
+// ChatroomStats summarizes a chatroom's activity over its last days. It is
+// computed from materialized views, so it lags behind by up to the refresh
+// interval of the chatroom_stats_refresh job.
+type ChatroomStats struct {
+	ChatroomID string `json:"chatroom_id"`
+	Days       int    `json:"days"`
+	Messages   int64  `json:"messages"`
+	PeakUsers  int    `json:"peak_users"`
+	// Daily holds every day of the period, oldest first, including those
+	// without activity
+	Daily      []DailyActivity `json:"daily"`
+	TopPosters []TopPoster     `json:"top_posters"`
+}
+
+// PresencePoint is the most users connected at once to a chatroom during
+// the period starting at At
+type PresencePoint struct {
+	At    time.Time `json:"at"`
+	Users int       `json:"users"`
+}
+
+// RoomPresenceHistory is a chatroom's concurrency over time. Periods without
+// samples, when nobody was connected, have no point.
+type RoomPresenceHistory struct {
+	ChatroomID   string          `json:"chatroom_id"`
+	ChatroomName string          `json:"chatroom_name"`
+	PeakUsers    int             `json:"peak_users"`
+	Points       []PresencePoint `json:"points"`
+}
+
+// ChatroomStatsRepository stores presence samples of the WebSocket hubs and
+// computes chatroom statistics from them and from the messages
+type ChatroomStatsRepository interface {
+	// RecordPresence stores the connected users per chatroom seen by one
+	// server instance. Samples are bucketed by minute; within a bucket, an
+	// instance's highest count is kept. Chatrooms that no longer exist are
+	// dropped.
+	RecordPresence(ctx context.Context, instanceID string, sampledAt time.Time, counts map[string]int) error
+	// PresenceHistory returns the concurrency of the chatrooms of the
+	// organization ctx is scoped to since the given time, in periods of
+	// bucket, oldest first; an empty chatroomID returns every chatroom with
+	// samples, by name
+	PresenceHistory(ctx context.Context, chatroomID string, since time.Time, bucket time.Duration) ([]*RoomPresenceHistory, error)
+	// DeletePresenceBefore deletes the samples taken before the given time
+	DeletePresenceBefore(ctx context.Context, before time.Time) (int64, error)
+	// Refresh recomputes the materialized views statistics are read from
+	Refresh(ctx context.Context) error
+	// Stats returns the statistics of a chatroom of the organization ctx is
+	// scoped to, from the UTC day of since to today
+	Stats(ctx context.Context, chatroomID string, since time.Time) (*ChatroomStats, error)
+}
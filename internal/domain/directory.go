@@ -0,0 +1,23 @@
+package domain
+
+import "context"
+
+// DirectoryUser is an account listed by an enterprise directory such as
+// LDAP or a SCIM service provider
+type DirectoryUser struct {
+	// ExternalID is the directory's stable identifier for the account
+	ExternalID string
+	Username   string
+	Email      string
+	Active     bool
+	// Groups holds the names of the groups the account belongs to
+	Groups []string
+}
+
+// DirectorySource lists the accounts of an enterprise directory
+type DirectorySource interface {
+	// Name identifies the directory; it is stored as the identity provider
+	// of synced users
+	Name() string
+	ListUsers(ctx context.Context) ([]DirectoryUser, error)
+}
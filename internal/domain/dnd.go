@@ -0,0 +1,132 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxDNDWindows bounds the windows of a do-not-disturb schedule
+const MaxDNDWindows = 14
+
+// dndDays are the day names a DNDWindow accepts, by time.Weekday
+var dndDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// DNDSchedule is when a user does not want to be notified of messages, in
+// their time zone. Notifications due in a window are held back and sent
+// together when it ends; realtime delivery is not affected.
+type DNDSchedule struct {
+	// TimeZone is an IANA name such as "America/Bogota"; empty is UTC
+	TimeZone string      `json:"time_zone"`
+	Windows  []DNDWindow `json:"windows"`
+}
+
+// DNDWindow is a daily period from Start to End, "HH:MM" in 24-hour time.
+// An End before Start ends the next day. Days limits the window to the days
+// it starts on ("mon" to "sun"); empty is every day.
+type DNDWindow struct {
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+	Days  []string `json:"days,omitempty"`
+}
+
+// Validate returns ErrInvalidInput describing the first invalid field
+func (s *DNDSchedule) Validate() error {
+	if _, err := time.LoadLocation(s.TimeZone); err != nil || s.TimeZone == "Local" {
+		return fmt.Errorf("%w: unknown time zone %q", ErrInvalidInput, s.TimeZone)
+	}
+	if len(s.Windows) > MaxDNDWindows {
+		return fmt.Errorf("%w: at most %d windows", ErrInvalidInput, MaxDNDWindows)
+	}
+	for _, w := range s.Windows {
+		start, okStart := parseClock(w.Start)
+		end, okEnd := parseClock(w.End)
+		if !okStart || !okEnd {
+			return fmt.Errorf("%w: window times must be HH:MM", ErrInvalidInput)
+		}
+		if start == end {
+			return fmt.Errorf("%w: window must not start and end at the same time", ErrInvalidInput)
+		}
+		for _, day := range w.Days {
+			if !slices.Contains(dndDays, day) {
+				return fmt.Errorf("%w: unknown day %q", ErrInvalidInput, day)
+			}
+		}
+	}
+	return nil
+}
+
+// Until reports whether t falls in one of the schedule's windows and, if so,
+// when the do-not-disturb period ends, following windows that overlap or
+// adjoin it
+func (s *DNDSchedule) Until(t time.Time) (time.Time, bool) {
+	if s == nil || len(s.Windows) == 0 {
+		return time.Time{}, false
+	}
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	until, active := s.windowEnd(t.In(loc))
+	if !active {
+		return time.Time{}, false
+	}
+	// A week of chained windows at most
+	for range 7 * len(s.Windows) {
+		next, ok := s.windowEnd(until)
+		if !ok || !next.After(until) {
+			break
+		}
+		until = next
+	}
+	return until, true
+}
+
+// windowEnd returns the latest end of the windows t falls in
+func (s *DNDSchedule) windowEnd(t time.Time) (time.Time, bool) {
+	var until time.Time
+	active := false
+	for _, w := range s.Windows {
+		start, okStart := parseClock(w.Start)
+		end, okEnd := parseClock(w.End)
+		if !okStart || !okEnd {
+			continue
+		}
+		// Windows starting yesterday may run past midnight
+		for _, offset := range []int{0, -1} {
+			day := t.AddDate(0, 0, offset)
+			if len(w.Days) > 0 && !slices.Contains(w.Days, dndDays[day.Weekday()]) {
+				continue
+			}
+			startAt := time.Date(day.Year(), day.Month(), day.Day(), start/60, start%60, 0, 0, t.Location())
+			endAt := time.Date(day.Year(), day.Month(), day.Day(), end/60, end%60, 0, 0, t.Location())
+			if end < start {
+				endAt = endAt.AddDate(0, 0, 1)
+			}
+			if !t.Before(startAt) && t.Before(endAt) && endAt.After(until) {
+				until, active = endAt, true
+			}
+		}
+	}
+	return until, active
+}
+
+// parseClock returns the minutes since midnight of an "HH:MM" time
+func parseClock(s string) (int, bool) {
+	hours, minutes, ok := strings.Cut(s, ":")
+	if !ok || len(hours) != 2 || len(minutes) != 2 {
+		return 0, false
+	}
+	h, err := strconv.Atoi(hours)
+	if err != nil || h < 0 || h > 23 {
+		return 0, false
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 || m > 59 {
+		return 0, false
+	}
+	return h*60 + m, true
+}
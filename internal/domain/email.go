@@ -0,0 +1,17 @@
+package domain
+
+import "context"
+
+// Email is a rendered email. HTML is optional; Text is always sent as the
+// plain-text alternative.
+type Email struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// EmailSender delivers emails
+type EmailSender interface {
+	Send(ctx context.Context, email *Email) error
+}
@@ -0,0 +1,44 @@
+package domain
+
+// Names of the domain events services publish
+const (
+	EventMessageSent    = "message_sent"
+	EventUserJoinedRoom = "user_joined_room"
+	EventUserRegistered = "user_registered"
+	EventRoomCreated    = "room_created"
+)
+
+// Event is something that happened in the domain, published after it is
+// stored so subsystems can react without the service knowing about them
+type Event interface {
+	EventName() string
+}
+
+// MessageSent is published when a user or bot message is stored
+type MessageSent struct {
+	Message *Message
+}
+
+func (MessageSent) EventName() string { return EventMessageSent }
+
+// UserJoinedRoom is published when a user becomes a member of a chatroom
+type UserJoinedRoom struct {
+	ChatroomID string
+	UserID     string
+}
+
+func (UserJoinedRoom) EventName() string { return EventUserJoinedRoom }
+
+// UserRegistered is published when a user signs up
+type UserRegistered struct {
+	User *User
+}
+
+func (UserRegistered) EventName() string { return EventUserRegistered }
+
+// RoomCreated is published when a chatroom is created
+type RoomCreated struct {
+	Chatroom *Chatroom
+}
+
+func (RoomCreated) EventName() string { return EventRoomCreated }
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrExportNotFound = errors.New("data export not found or expired")
+
+// Data export statuses
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+)
+
+// DataExport is a user's request for a copy of their data. The archive is
+// assembled in the background and can be downloaded until ExpiresAt.
+type DataExport struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"-"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// ExportMembership is a chatroom the user belongs to, as written to their
+// data export
+type ExportMembership struct {
+	ChatroomID   string    `json:"chatroom_id"`
+	ChatroomName string    `json:"chatroom_name"`
+	Owner        bool      `json:"owner"`
+	JoinedAt     time.Time `json:"joined_at"`
+}
+
+// ExportMessage is a message the user wrote, as written to their data
+// export. Deleted messages are included until they are purged.
+type ExportMessage struct {
+	ID           string     `json:"id"`
+	ChatroomID   string     `json:"chatroom_id"`
+	ChatroomName string     `json:"chatroom_name"`
+	Content      string     `json:"content"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+}
+
+// DataExportRepository tracks data export jobs and reads the data that goes
+// into them
+type DataExportRepository interface {
+	// Create stores a pending export for a user of the context's
+	// organization. Returns ErrUserNotFound if there is no such user.
+	Create(ctx context.Context, export *DataExport) error
+	// Latest returns the user's most recent export, or ErrExportNotFound
+	Latest(ctx context.Context, userID string) (*DataExport, error)
+	// Complete stores the archive of a pending export and marks it ready
+	// until expiresAt
+	Complete(ctx context.Context, id string, archive []byte, expiresAt time.Time) error
+	// Fail marks a pending export failed with reason
+	Fail(ctx context.Context, id, reason string) error
+	// Archive returns the archive of one of userID's ready, unexpired
+	// exports, or ErrExportNotFound
+	Archive(ctx context.Context, userID, id string) ([]byte, error)
+	// DeleteExpired removes the exports of every organization that expired,
+	// archives included
+	DeleteExpired(ctx context.Context) (int64, error)
+
+	// Memberships returns the chatrooms userID belongs to, oldest first
+	Memberships(ctx context.Context, userID string) ([]*ExportMembership, error)
+	// Messages returns every message userID wrote, oldest first
+	Messages(ctx context.Context, userID string) ([]*ExportMessage, error)
+}
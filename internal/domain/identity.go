@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrIdentityNotFound = errors.New("identity not found")
+	// ErrIdentityLinked is returned when linking a provider account that is
+	// already linked to another user
+	ErrIdentityLinked = errors.New("identity is linked to another user")
+	// ErrEmailNotVerified is returned when the provider does not vouch for
+	// the email of the account signing in
+	ErrEmailNotVerified = errors.New("email not verified by the identity provider")
+)
+
+// UserIdentity links a user to an account at an external identity provider
+type UserIdentity struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExternalProfile is the account information returned by an identity
+// provider after a successful login
+type ExternalProfile struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	// Username is a suggested username, e.g. the GitHub login
+	Username string
+}
+
+// IdentityRepository defines the interface for external identity data access
+type IdentityRepository interface {
+	Create(ctx context.Context, identity *UserIdentity) error
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*UserIdentity, error)
+	ListByProvider(ctx context.Context, provider string) ([]*UserIdentity, error)
+}
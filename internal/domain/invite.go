@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrInviteNotFound = errors.New("invite not found or expired")
+
+// RoomInvite invites an email address to a chatroom. Whoever registers or
+// signs in with the emailed link joins the chatroom; an invite can be
+// accepted once, until it expires or the owner revokes it.
+type RoomInvite struct {
+	ID         string `json:"id"`
+	ChatroomID string `json:"chatroom_id"`
+	Email      string `json:"email"`
+	InvitedBy  string `json:"invited_by"`
+	// Token is only set on the invite returned by creation, and is never
+	// stored: the repository keeps TokenHash
+	Token     string    `json:"-"`
+	TokenHash string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// ChatroomName and InviterName are only set by GetPending
+	ChatroomName string `json:"chatroom_name,omitempty"`
+	InviterName  string `json:"inviter_name,omitempty"`
+}
+
+// RoomInviteRepository defines the interface for chatroom invite data access
+type RoomInviteRepository interface {
+	// Create stores an invite to a chatroom of the context's organization.
+	// Returns ErrChatroomNotFound if there is no such chatroom.
+	Create(ctx context.Context, invite *RoomInvite) error
+	// GetPending returns the unexpired, unaccepted and unrevoked invite with
+	// the token hash, or ErrInviteNotFound
+	GetPending(ctx context.Context, tokenHash string) (*RoomInvite, error)
+	// ListPending returns a chatroom's pending invites, newest first
+	ListPending(ctx context.Context, chatroomID string) ([]*RoomInvite, error)
+	// Revoke cancels a pending invite. Returns ErrInviteNotFound if the
+	// chatroom has no such pending invite.
+	Revoke(ctx context.Context, chatroomID, inviteID string) error
+	// Accept marks the pending invite with the token hash accepted by userID
+	// and adds them to its chatroom in one transaction. Returns
+	// ErrInviteNotFound if the invite is no longer pending.
+	Accept(ctx context.Context, tokenHash, userID string) (*RoomInvite, error)
+}
@@ -0,0 +1,7 @@
+package domain
+
+// Leader reports whether this instance currently runs the background jobs
+// that must run on one instance only, such as cleanups and purges
+type Leader interface {
+	IsLeader() bool
+}
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// LinkPreview is the OpenGraph metadata of a URL posted in a message
+type LinkPreview struct {
+	MessageID   string    `json:"message_id"`
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	SiteName    string    `json:"site_name,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// LinkPreviewRepository stores link previews
+type LinkPreviewRepository interface {
+	// Save stores a preview, replacing an earlier one for the same message
+	// and URL. It returns ErrMessageNotFound if the message is not in ctx's
+	// organization.
+	Save(ctx context.Context, preview *LinkPreview) error
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrLoginThrottled = errors.New("too many failed logins")
+
+// LoginThrottledError reports how long until the next login attempt of a
+// username from an IP is accepted. It matches ErrLoginThrottled with
+// errors.Is.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("too many failed logins, retry in %s", e.RetryAfter)
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return ErrLoginThrottled
+}
+
+// LoginFailures counts the consecutive failed logins of a username from
+// one IP
+type LoginFailures struct {
+	Count        int
+	LastFailedAt time.Time
+}
+
+// LoginFailureRepository stores failed logins, shared by every server
+// instance, within the organization ctx is scoped to. Usernames are
+// compared case-insensitively.
+type LoginFailureRepository interface {
+	// Get returns the failures last recorded at or after since; none is a
+	// zero Count, not an error
+	Get(ctx context.Context, username, ip string, since time.Time) (*LoginFailures, error)
+	// Reserve counts an attempt at the given time as a failure, restarting
+	// from one when the previous failure is older than since, unless the
+	// previous failure is too recent: after n failures the next attempt is
+	// accepted baseDelay·2ⁿ⁻¹ later, at most maxDelay. The check and the
+	// count are atomic, so concurrent attempts cannot both be accepted. It
+	// returns the failures and whether the attempt was accepted.
+	Reserve(ctx context.Context, username, ip string, at, since time.Time, baseDelay, maxDelay time.Duration) (*LoginFailures, bool, error)
+	// Release uncounts a reserved attempt that did not fail on its
+	// credentials; the time of the last failure is kept
+	Release(ctx context.Context, username, ip string) error
+	// Reset forgets the failures of a username from an IP
+	Reset(ctx context.Context, username, ip string) error
+	// DeleteBefore deletes the failures last recorded before the given time,
+	// in every organization
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
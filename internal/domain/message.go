@@ -2,9 +2,18 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrInvalidQuote is returned for replies quoting a message that is not in
+// their chatroom
+var ErrInvalidQuote = errors.New("quoted message is not in this chatroom")
+
+// ErrDuplicateMessage is returned when the sender already stored a message
+// with the same ClientMsgID; the message is then set to the stored one's ID
+var ErrDuplicateMessage = errors.New("message already stored")
+
 // Message represents a chat message
 type Message struct {
 	ID         string    `json:"id"`
@@ -14,11 +23,83 @@ type Message struct {
 	Content    string    `json:"content"`
 	IsBot      bool      `json:"is_bot"`
 	CreatedAt  time.Time `json:"created_at"`
+	// Encrypted is set on messages of end-to-end encrypted chatrooms, whose
+	// Content is ciphertext. It must match the chatroom's flag: messages
+	// are only stored in chatrooms of the same kind.
+	Encrypted bool `json:"-"`
+	// Seq increases with every stored message. It is only part of the
+	// /api/v2 payloads, so it is left out of the v1 JSON.
+	Seq int64 `json:"-"`
+	// ForwardedFrom is set on messages forwarded from another chatroom
+	ForwardedFrom *ForwardedFrom `json:"forwarded_from,omitempty"`
+	// QuotedMessageID is the message a reply quotes, as sent by the client;
+	// sending the reply snapshots it into Quote
+	QuotedMessageID string `json:"-"`
+	// Quote is set on replies quoting another message of the chatroom
+	Quote *Quote `json:"quote,omitempty"`
+	// ParentMessageID is the message a threaded reply answers, such as the
+	// command a bot response answers
+	ParentMessageID string `json:"parent_message_id,omitempty"`
+	// Event is set on messages announcing an event members can RSVP to
+	Event *CalendarEvent `json:"event,omitempty"`
+	// ClientMsgID is the ID the sender's client gave the message, unique per
+	// user, so retries of it are only stored once
+	ClientMsgID string `json:"-"`
+	// HTML is Content rendered from Markdown, set in chatrooms that enable
+	// it. It is rendered on the way out rather than stored, so it follows
+	// the chatroom's current setting.
+	HTML string `json:"html,omitempty"`
+}
+
+// Quote is a snapshot of the message a reply quotes, taken when the reply was
+// sent, so later edits or deletion of the original don't change the reply
+type Quote struct {
+	MessageID string    `json:"message_id"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MaxForwardTargets caps the chatrooms a message is forwarded to at once
+const MaxForwardTargets = 10
+
+// ForwardedFrom attributes a forwarded message to its original author and
+// links back to the original. MessageID and ChatroomID are empty once the
+// original has been purged or its chatroom deleted.
+type ForwardedFrom struct {
+	MessageID  string `json:"message_id,omitempty"`
+	ChatroomID string `json:"chatroom_id,omitempty"`
+	UserID     string `json:"user_id,omitempty"`
+	Username   string `json:"username"`
+}
+
+// AttachmentImage is the type of an image attachment
+const AttachmentImage = "image"
+
+// Attachment is media shown with a message, such as the GIF answering a
+// /giphy command
+type Attachment struct {
+	Type   string `json:"type"`
+	URL    string `json:"url"`
+	Title  string `json:"title,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
 }
 
 // MessageRepository defines the interface for message data access
 type MessageRepository interface {
 	Create(ctx context.Context, message *Message) error
+	GetByID(ctx context.Context, id string) (*Message, error)
 	GetByChatroom(ctx context.Context, chatroomID string, limit int) ([]*Message, error)
 	GetByChatroomBefore(ctx context.Context, chatroomID string, before string, limit int) ([]*Message, error)
+	// GetByChatroomSince returns up to limit messages posted after the
+	// message with ID sinceID, oldest first
+	GetByChatroomSince(ctx context.Context, chatroomID string, sinceID string, limit int) ([]*Message, error)
+	// Restore undoes the soft delete of a message. Returns
+	// ErrMessageNotFound if there is no deleted message with that ID.
+	Restore(ctx context.Context, id string) error
+	// PurgeDeleted permanently deletes the messages of every organization
+	// that were soft-deleted before cutoff, and returns how many were purged
+	PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error)
 }
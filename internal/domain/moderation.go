@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrMessageNotFound = errors.New("message not found")
+	ErrAlreadyFlagged  = errors.New("message already flagged by this user")
+	ErrNoOpenFlags     = errors.New("message has no open flags")
+)
+
+// Moderator decisions on a flagged message
+const (
+	// FlagActionKeep dismisses the flags and shows the message again
+	FlagActionKeep = "keep"
+	// FlagActionDelete soft-deletes the message; an administrator can
+	// restore it until it is purged
+	FlagActionDelete = "delete"
+)
+
+// MessageFlag is one user's report of a message
+type MessageFlag struct {
+	MessageID  string
+	ReporterID string
+	Reason     string
+}
+
+// FlaggedMessage is a message awaiting review in the moderation queue
+type FlaggedMessage struct {
+	Message        *Message  `json:"message"`
+	FlagCount      int       `json:"flag_count"`
+	Reasons        []string  `json:"reasons"`
+	Hidden         bool      `json:"hidden"`
+	FirstFlaggedAt time.Time `json:"first_flagged_at"`
+}
+
+// ModerationRepository stores message flags and moderation decisions,
+// scoped to the organization in ctx
+type ModerationRepository interface {
+	// Flag records a flag and returns the number of open flags on the message
+	Flag(ctx context.Context, flag *MessageFlag) (int, error)
+	// Hide removes a message from chatroom history until it is kept
+	Hide(ctx context.Context, messageID string) error
+	// Queue returns messages with open flags, most flagged first
+	Queue(ctx context.Context, limit int) ([]*FlaggedMessage, error)
+	// Resolve closes the open flags on a message by keeping (and unhiding)
+	// or soft-deleting it
+	Resolve(ctx context.Context, messageID, moderatorID, action string) error
+	// SetShadowBan shadow-bans or lifts the shadow-ban of a user in a chatroom
+	SetShadowBan(ctx context.Context, chatroomID, userID, moderatorID string, banned bool) error
+	// ShadowBannedUsers returns the IDs of the users shadow-banned in a chatroom
+	ShadowBannedUsers(ctx context.Context, chatroomID string) ([]string, error)
+}
+
+type viewerIDKey struct{}
+
+// WithViewerID returns a context reading messages on behalf of userID.
+// Messages of users shadow-banned in a chatroom, and the bot answers to
+// their commands, are only returned to those users themselves.
+func WithViewerID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, viewerIDKey{}, userID)
+}
+
+// ViewerIDFromContext returns the user ctx reads messages for, or "" when
+// it reads for nobody in particular and shadow-banned users' messages are
+// left out
+func ViewerIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(viewerIDKey{}).(string)
+	return userID
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultOrganizationID is the tenant of requests that do not name one and
+// of all data created before multi-tenancy was introduced
+const DefaultOrganizationID = "00000000-0000-0000-0000-000000000001"
+
+var (
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrOrganizationExists   = errors.New("organization already exists")
+)
+
+// Organization is a tenant. Users, chatrooms and messages belong to exactly
+// one organization and are invisible to the others.
+type Organization struct {
+	ID        string    `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OrganizationRepository defines the interface for organization data access
+type OrganizationRepository interface {
+	Create(ctx context.Context, org *Organization) error
+	GetByID(ctx context.Context, id string) (*Organization, error)
+	GetBySlug(ctx context.Context, slug string) (*Organization, error)
+}
+
+type orgIDKey struct{}
+
+// WithOrgID returns a context scoped to the organization. Repositories read
+// and write only that organization's data.
+func WithOrgID(ctx context.Context, orgID string) context.Context {
+	return context.WithValue(ctx, orgIDKey{}, orgID)
+}
+
+// OrgIDFromContext returns the organization ctx is scoped to, or
+// DefaultOrganizationID when it is not scoped
+func OrgIDFromContext(ctx context.Context) string {
+	if orgID, ok := ctx.Value(orgIDKey{}).(string); ok && orgID != "" {
+		return orgID
+	}
+	return DefaultOrganizationID
+}
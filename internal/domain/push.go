@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrPushDeviceNotFound = errors.New("push device not found")
+
+// Push platforms a device can register for
+const (
+	// PushPlatformWebPush devices carry a browser PushSubscription as JSON
+	PushPlatformWebPush = "webpush"
+	// PushPlatformFCM devices carry a Firebase Cloud Messaging registration
+	// token
+	PushPlatformFCM = "fcm"
+)
+
+// PushDevice is a device that receives push notifications for a user
+type PushDevice struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"-"`
+	Platform  string    `json:"platform"`
+	Token     string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PushNotification is what a device shows for a message. ChatroomID and
+// MessageID let the client open the conversation when it is tapped.
+type PushNotification struct {
+	Title      string `json:"title"`
+	Body       string `json:"body"`
+	ChatroomID string `json:"chatroom_id"`
+	MessageID  string `json:"message_id"`
+}
+
+// DeferredPush is a notification held back during a user's do-not-disturb
+// window
+type DeferredPush struct {
+	OrgID        string
+	UserID       string
+	Notification PushNotification
+	CreatedAt    time.Time
+}
+
+// PushDeviceRepository stores push devices and picks who is notified
+type PushDeviceRepository interface {
+	// Register stores a device, moving the token to device.UserID if
+	// another user had registered it
+	Register(ctx context.Context, device *PushDevice) error
+	ListByUser(ctx context.Context, userID string) ([]*PushDevice, error)
+	// Delete removes one of userID's devices. Returns ErrPushDeviceNotFound
+	// if there is no such device.
+	Delete(ctx context.Context, userID, deviceID string) error
+	// DeleteToken removes a token the push service reported as expired
+	DeleteToken(ctx context.Context, platform, token string) error
+	// Recipients returns the members of the message's chatroom, other than
+	// its sender, to notify of it: those mentioned by one of the usernames,
+	// current or former, or the other member of a two-member chatroom
+	Recipients(ctx context.Context, msg *Message, mentions []string) ([]string, error)
+	// DNDSchedule returns the user's do-not-disturb schedule, empty if they
+	// have none
+	DNDSchedule(ctx context.Context, userID string) (*DNDSchedule, error)
+	// SetDNDSchedule replaces the user's do-not-disturb schedule. Returns
+	// ErrUserNotFound if there is no such user.
+	SetDNDSchedule(ctx context.Context, userID string, schedule *DNDSchedule) error
+	// Defer holds n back for userID until deliverAt
+	Defer(ctx context.Context, userID string, n *PushNotification, deliverAt time.Time) error
+	// ClaimDeferred removes and returns up to limit notifications of every
+	// organization that were due by now, oldest first
+	ClaimDeferred(ctx context.Context, now time.Time, limit int) ([]*DeferredPush, error)
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Quota names, used in QuotaExceededError and metric labels. There is no
+// attachment size quota until messages can carry uploads.
+const (
+	QuotaRoomsPerUser          = "rooms_per_user"
+	QuotaMessagesPerRoomPerDay = "messages_per_room_per_day"
+)
+
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaExceededError reports which quota was hit. It matches ErrQuotaExceeded
+// with errors.Is.
+type QuotaExceededError struct {
+	Quota string
+	Limit int64
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s quota of %d exceeded", e.Quota, e.Limit)
+}
+
+func (e *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// QuotaLimits are the effective usage limits of an organization. Zero means
+// unlimited.
+type QuotaLimits struct {
+	MaxRoomsPerUser          int `json:"max_rooms_per_user"`
+	MaxMessagesPerRoomPerDay int `json:"max_messages_per_room_per_day"`
+}
+
+// QuotaOverrides replace the global limits for one organization. Nil fields
+// inherit the global limit.
+type QuotaOverrides struct {
+	MaxRoomsPerUser          *int
+	MaxMessagesPerRoomPerDay *int
+}
+
+// Apply returns l with the overrides set in o
+func (l QuotaLimits) Apply(o *QuotaOverrides) QuotaLimits {
+	if o == nil {
+		return l
+	}
+	if o.MaxRoomsPerUser != nil {
+		l.MaxRoomsPerUser = *o.MaxRoomsPerUser
+	}
+	if o.MaxMessagesPerRoomPerDay != nil {
+		l.MaxMessagesPerRoomPerDay = *o.MaxMessagesPerRoomPerDay
+	}
+	return l
+}
+
+// QuotaRepository stores per-organization overrides and counts usage within
+// the organization ctx is scoped to
+type QuotaRepository interface {
+	// GetOverrides returns empty overrides for organizations without any
+	GetOverrides(ctx context.Context, orgID string) (*QuotaOverrides, error)
+	SetOverrides(ctx context.Context, orgID string, overrides *QuotaOverrides) error
+	CountRoomsCreatedBy(ctx context.Context, userID string) (int, error)
+	CountMessagesSince(ctx context.Context, chatroomID string, since time.Time) (int, error)
+	// LockChatroomMessages makes the message quota checks of a chatroom
+	// wait for each other until the transaction ctx carries ends, so a
+	// count and the insert it allows cannot interleave with another send's
+	LockChatroomMessages(ctx context.Context, chatroomID string) error
+}
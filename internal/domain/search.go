@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Kinds of search results
+const (
+	SearchMessages = "messages"
+	SearchRooms    = "rooms"
+	SearchUsers    = "users"
+)
+
+// SearchTypes are the kinds of results a search returns, in response order
+var SearchTypes = []string{SearchMessages, SearchRooms, SearchUsers}
+
+// SearchOptions selects a page of one kind of search result
+type SearchOptions struct {
+	Query string
+	// UserID is the caller: only messages and rooms of chatrooms they belong
+	// to, and users who share one with them, are searched
+	UserID string
+	Limit  int
+	Offset int
+}
+
+// MessageHit is a message matching a search
+type MessageHit struct {
+	ID           string    `json:"id"`
+	ChatroomID   string    `json:"chatroom_id"`
+	ChatroomName string    `json:"chatroom_name"`
+	UserID       string    `json:"user_id"`
+	Username     string    `json:"username"`
+	Content      string    `json:"content"`
+	CreatedAt    time.Time `json:"created_at"`
+	Rank         float64   `json:"rank"`
+}
+
+// RoomHit is a chatroom whose name matches a search
+type RoomHit struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	Encrypted bool    `json:"encrypted"`
+	Rank      float64 `json:"rank"`
+}
+
+// UserHit is a user whose username matches a search
+type UserHit struct {
+	ID       string  `json:"id"`
+	Username string  `json:"username"`
+	Rank     float64 `json:"rank"`
+}
+
+// SearchRepository searches the caller's organization, best match first
+type SearchRepository interface {
+	// SearchMessages matches the words of the query against the messages of
+	// the caller's chatrooms. Encrypted chatrooms are skipped, as their
+	// messages are ciphertext.
+	SearchMessages(ctx context.Context, opts SearchOptions) ([]*MessageHit, error)
+	// SearchRooms matches the query against the names of the caller's
+	// chatrooms
+	SearchRooms(ctx context.Context, opts SearchOptions) ([]*RoomHit, error)
+	// SearchUsers matches the query against the usernames of the active
+	// users sharing a chatroom with the caller
+	SearchUsers(ctx context.Context, opts SearchOptions) ([]*UserHit, error)
+}
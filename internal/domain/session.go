@@ -3,6 +3,7 @@ package domain
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 )
 
@@ -11,13 +12,43 @@ var (
 	ErrSessionExpired  = errors.New("session expired")
 )
 
-// Session represents a user session
+// Session represents a user session.
+// ExpiresAt slides forward on activity by IdleTimeout but never past
+// AbsoluteExpiresAt.
 type Session struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                string        `json:"id"`
+	UserID            string        `json:"user_id"`
+	OrgID             string        `json:"org_id"`
+	Token             string        `json:"token"`
+	ExpiresAt         time.Time     `json:"expires_at"`
+	AbsoluteExpiresAt time.Time     `json:"absolute_expires_at"`
+	IdleTimeout       time.Duration `json:"-"`
+	LastActivityAt    time.Time     `json:"last_activity_at"`
+	CreatedAt         time.Time     `json:"created_at"`
+}
+
+// GuestTokenPrefix starts the token of every guest session, so requests from
+// guests are told apart without loading the user
+const GuestTokenPrefix = "guest_"
+
+// IsGuest reports whether the session belongs to a guest
+func (s *Session) IsGuest() bool {
+	return strings.HasPrefix(s.Token, GuestTokenPrefix)
+}
+
+// RenewedExpiry returns the expiry the session would get for activity at now
+func (s *Session) RenewedExpiry(now time.Time) time.Time {
+	expiry := now.Add(s.IdleTimeout)
+	if !s.AbsoluteExpiresAt.IsZero() && expiry.After(s.AbsoluteExpiresAt) {
+		return s.AbsoluteExpiresAt
+	}
+	return expiry
+}
+
+// SessionActivity records that the session identified by Token was used at At
+type SessionActivity struct {
+	Token string
+	At    time.Time
 }
 
 // SessionRepository defines the interface for session data access
@@ -26,4 +57,8 @@ type SessionRepository interface {
 	GetByToken(ctx context.Context, token string) (*Session, error)
 	Delete(ctx context.Context, token string) error
 	DeleteExpired(ctx context.Context) (int64, error)
+	DeleteByUserID(ctx context.Context, userID string) (int64, error)
+	// Touch records activity for many sessions at once, sliding their
+	// expiry. It returns the number of sessions renewed.
+	Touch(ctx context.Context, activity []SessionActivity) (int64, error)
 }
@@ -0,0 +1,10 @@
+package domain
+
+import "context"
+
+// TxManager runs a unit of work spanning several repositories in one
+// transaction. Repository calls made with the ctx passed to fn join the
+// transaction; it commits when fn returns nil and rolls back otherwise.
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
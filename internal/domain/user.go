@@ -7,20 +7,148 @@ import (
 )
 
 var (
-	ErrUserNotFound      = errors.New("user not found")
-	ErrUsernameExists    = errors.New("username already exists")
-	ErrEmailExists       = errors.New("email already exists")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUsernameExists     = errors.New("username already exists")
+	ErrEmailExists        = errors.New("email already exists")
 	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrInvalidInput      = errors.New("invalid input")
+	ErrInvalidInput       = errors.New("invalid input")
+	ErrUserDeactivated    = errors.New("user is deactivated")
+	ErrGuestsDisabled     = errors.New("guest access is disabled")
+	// ErrNameReserved is returned for a name, such as a chatroom's, that
+	// spells one reserved to the server
+	ErrNameReserved = errors.New("name is reserved")
+	// ErrHashingBusy is returned when too many passwords are being hashed
+	// to take another one; the request may be retried shortly
+	ErrHashingBusy = errors.New("password hashing is busy")
+)
+
+// User roles
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+	// RoleGuest is an ephemeral user without credentials, limited to public
+	// chatrooms. It is never assigned with UpdateRole.
+	RoleGuest = "guest"
 )
 
 // User represents a user in the system
 type User struct {
-	ID           string    `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID            string     `json:"id"`
+	Username      string     `json:"username"`
+	Email         string     `json:"email"`
+	PasswordHash  string     `json:"-"`
+	Role          string     `json:"role"`
+	OrgID         string     `json:"org_id"`
+	Locale        string     `json:"locale,omitempty"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+	// IsSystem marks an account the server itself posts as, such as a
+	// bot's. It has no password and nobody can log in to it.
+	IsSystem  bool      `json:"is_system,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Profile visibility settings
+const (
+	// ProfilePublic shows the full profile to everyone in the organization
+	ProfilePublic = "public"
+	// ProfilePrivate shows only the username to other users
+	ProfilePrivate = "private"
+)
+
+// Profile is the part of a user that other users may see, along with the
+// privacy settings that control how much of it they see
+type Profile struct {
+	ID               string
+	Username         string
+	AvatarURL        string
+	Visibility       string
+	ShowOnlineStatus bool
+	// Version is bumped by every profile settings update
+	Version   int
+	CreatedAt time.Time
+}
+
+// ProfileSettings are the user-editable profile fields. In an update, a
+// non-zero Version must be the current one or the update fails with
+// ErrVersionConflict.
+type ProfileSettings struct {
+	AvatarURL        string
+	Visibility       string
+	ShowOnlineStatus bool
+	Version          int
+}
+
+// User statuses. StatusAway is also shown for connected users who have been
+// inactive for a while, and StatusOffline for users without a connection;
+// neither offline nor idleness is stored.
+const (
+	StatusActive  = "active"
+	StatusAway    = "away"
+	StatusDND     = "dnd"
+	StatusOffline = "offline"
+)
+
+// MaxStatusTextLength bounds the custom text of a status, in characters
+const MaxStatusTextLength = 100
+
+// UserStatus is the status a user chose, with optional custom text such as
+// "In a meeting"
+type UserStatus struct {
+	Status string `json:"status"`
+	Text   string `json:"text"`
+}
+
+// IsValidStatus reports whether status is one a user may choose
+func IsValidStatus(status string) bool {
+	return status == StatusActive || status == StatusAway || status == StatusDND
+}
+
+// EffectiveStatus is the status others see for a user who chose status:
+// offline without a connection, and away when idle unless they chose not to
+// be disturbed
+func EffectiveStatus(status string, online, idle bool) string {
+	switch {
+	case !online:
+		return StatusOffline
+	case status == StatusDND || status == StatusAway:
+		return status
+	case idle:
+		return StatusAway
+	default:
+		return StatusActive
+	}
+}
+
+// IsValidProfileVisibility reports whether visibility is a known setting
+func IsValidProfileVisibility(visibility string) bool {
+	return visibility == ProfilePublic || visibility == ProfilePrivate
+}
+
+// IsValidRole reports whether role is one of the known user roles
+func IsValidRole(role string) bool {
+	return role == RoleUser || role == RoleModerator || role == RoleAdmin
+}
+
+// IsAdmin reports whether the user has the administrator role
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// IsActive reports whether the user is allowed to sign in
+func (u *User) IsActive() bool {
+	return u.DeactivatedAt == nil
+}
+
+// IsGuest reports whether the user is an ephemeral guest
+func (u *User) IsGuest() bool {
+	return u.Role == RoleGuest
+}
+
+// IsModerator reports whether the user can moderate chatrooms.
+// Administrators are implicitly moderators.
+func (u *User) IsModerator() bool {
+	return u.Role == RoleModerator || u.Role == RoleAdmin
 }
 
 // UserRepository defines the interface for user data access
@@ -29,4 +157,32 @@ type UserRepository interface {
 	GetByID(ctx context.Context, id string) (*User, error)
 	GetByUsername(ctx context.Context, username string) (*User, error)
 	GetByEmail(ctx context.Context, email string) (*User, error)
+	// HasConfusableUsername reports whether a user other than exceptUserID
+	// has a username that can be mistaken for username: the same in another
+	// case, with other separators or with lookalike digits, like St0ck_Bot
+	// for StockBot
+	HasConfusableUsername(ctx context.Context, username, exceptUserID string) (bool, error)
+	UpdateRole(ctx context.Context, userID, role string) error
+	// SetDeactivated disables or re-enables a user's account
+	SetDeactivated(ctx context.Context, userID string, deactivated bool) error
+	// SetLocale sets the user's preferred locale; empty clears it
+	SetLocale(ctx context.Context, userID, locale string) error
+	// GetProfile returns an active user's public profile
+	GetProfile(ctx context.Context, id string) (*Profile, error)
+	// UpdateProfileSettings replaces the user's avatar and privacy settings
+	// and sets settings.Version to the new version
+	UpdateProfileSettings(ctx context.Context, userID string, settings *ProfileSettings) error
+	// GetStatus returns the status an active user chose
+	GetStatus(ctx context.Context, userID string) (*UserStatus, error)
+	// SetStatus replaces the status the user chose
+	SetStatus(ctx context.Context, userID string, status *UserStatus) error
+	// CreateGuest creates user with the guest role
+	CreateGuest(ctx context.Context, user *User) error
+	// ConvertGuest turns a guest into a regular user with user's username,
+	// email and password, keeping its ID. Returns ErrUserNotFound unless
+	// user.ID is a guest.
+	ConvertGuest(ctx context.Context, user *User) error
+	// DeleteGuestsCreatedBefore deletes the guests of every organization
+	// created before cutoff and returns how many were deleted
+	DeleteGuestsCreatedBefore(ctx context.Context, cutoff time.Time) (int64, error)
 }
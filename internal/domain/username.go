@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrUsernameChangeTooSoon = errors.New("username changed too recently")
+
+// UsernameCooldownError reports when a user may change their username
+// again. It matches ErrUsernameChangeTooSoon with errors.Is.
+type UsernameCooldownError struct {
+	Until time.Time
+}
+
+func (e *UsernameCooldownError) Error() string {
+	return fmt.Sprintf("username can be changed again at %s", e.Until.Format(time.RFC3339))
+}
+
+func (e *UsernameCooldownError) Unwrap() error {
+	return ErrUsernameChangeTooSoon
+}
+
+// UsernameChange is one change of a user's username
+type UsernameChange struct {
+	OldUsername string    `json:"old_username"`
+	NewUsername string    `json:"new_username"`
+	ChangedAt   time.Time `json:"changed_at"`
+}
+
+// UsernameHistoryRepository changes usernames and keeps the former ones,
+// within the organization ctx is scoped to. Former usernames are compared
+// case-insensitively.
+type UsernameHistoryRepository interface {
+	// Rename changes the username of a registered user and records the
+	// change. Returns ErrUsernameExists when another user has the username
+	// or gave it up at or after heldSince, and ErrUserNotFound when there
+	// is no such user.
+	Rename(ctx context.Context, userID, username string, heldSince time.Time) (*UsernameChange, error)
+	// History returns the user's username changes, newest first
+	History(ctx context.Context, userID string) ([]*UsernameChange, error)
+	// IsHeld reports whether a user gave up username at or after since
+	IsHeld(ctx context.Context, username string, since time.Time) (bool, error)
+	// ResolveFormer returns the ID of the user who last gave up username,
+	// unless a user has it now. Returns ErrUserNotFound when there is none.
+	ResolveFormer(ctx context.Context, username string) (string, error)
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError codes
+const (
+	FieldInvalid           = "invalid"
+	FieldTooShort          = "too_short"
+	FieldTooLong           = "too_long"
+	FieldTooFewCharClasses = "too_few_char_classes"
+	FieldBanned            = "banned"
+	FieldBreached          = "breached"
+	FieldReserved          = "reserved"
+	FieldConfusable        = "confusable"
+)
+
+// FieldError is why one field of a request is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists the invalid fields of a request. It matches
+// ErrInvalidInput with errors.Is.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = fmt.Sprintf("%s: %s", f.Field, f.Message)
+	}
+	return fmt.Sprintf("%v: %s", ErrInvalidInput, strings.Join(msgs, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidInput
+}
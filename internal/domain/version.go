@@ -0,0 +1,7 @@
+package domain
+
+import "errors"
+
+// ErrVersionConflict is returned when an update names a version of a resource
+// that someone else has changed since
+var ErrVersionConflict = errors.New("resource was changed by someone else")
@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrTicketNotFound = errors.New("ticket not found or expired")
+
+// WSTicket is a short-lived, single-use credential for opening a WebSocket
+// connection on behalf of a session.
+type WSTicket struct {
+	Ticket       string    `json:"ticket"`
+	SessionToken string    `json:"-"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"-"`
+}
+
+// WSTicketRepository defines the interface for WebSocket ticket data access
+type WSTicketRepository interface {
+	Create(ctx context.Context, ticket *WSTicket) error
+	// Consume deletes and returns an unexpired ticket, so each ticket can be
+	// redeemed at most once.
+	Consume(ctx context.Context, ticket string) (*WSTicket, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
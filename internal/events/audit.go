@@ -0,0 +1,28 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/observability"
+)
+
+// Audit writes an audit record for sign-ups, new chatrooms and joins.
+// Messages are not audited.
+func Audit(ctx context.Context, event domain.Event) {
+	switch e := event.(type) {
+	case domain.UserRegistered:
+		observability.Audit(ctx, event.EventName(),
+			slog.String("new_user_id", e.User.ID),
+			slog.String("username", e.User.Username))
+	case domain.RoomCreated:
+		observability.Audit(ctx, event.EventName(),
+			slog.String("chatroom_id", e.Chatroom.ID),
+			slog.String("created_by", e.Chatroom.CreatedBy))
+	case domain.UserJoinedRoom:
+		observability.Audit(ctx, event.EventName(),
+			slog.String("chatroom_id", e.ChatroomID),
+			slog.String("member_id", e.UserID))
+	}
+}
@@ -0,0 +1,80 @@
+// Package events delivers domain events from the services that publish them
+// to the subsystems that react to them, such as push notifications, link
+// previews, audit logging and metrics.
+package events
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/observability"
+)
+
+// Handler reacts to a published event
+type Handler func(ctx context.Context, event domain.Event)
+
+// Bus is an in-process event bus. Handlers run synchronously in the
+// publisher's goroutine, in subscription order, so they must not block: slow
+// work belongs on a queue the handler feeds. A panicking handler is logged
+// and does not stop the others.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	all      []Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe registers handler for the events named name
+func (b *Bus) Subscribe(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[name] = append(b.handlers[name], handler)
+}
+
+// SubscribeAll registers handler for every event
+func (b *Bus) SubscribeAll(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.all = append(b.all, handler)
+}
+
+// Publish delivers event to its subscribers
+func (b *Bus) Publish(ctx context.Context, event domain.Event) {
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.all)+len(b.handlers[event.EventName()]))
+	handlers = append(handlers, b.all...)
+	handlers = append(handlers, b.handlers[event.EventName()]...)
+	b.mu.RUnlock()
+
+	observability.DomainEventsTotal.WithLabelValues(event.EventName()).Inc()
+	for _, handler := range handlers {
+		b.dispatch(ctx, handler, event)
+	}
+}
+
+func (b *Bus) dispatch(ctx context.Context, handler Handler, event domain.Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.ErrorContext(ctx, "event handler panicked",
+				slog.String("event", event.EventName()),
+				slog.String("panic", fmt.Sprint(r)))
+		}
+	}()
+	handler(ctx, event)
+}
+
+// On subscribes a handler typed to one event, E
+func On[E domain.Event](b *Bus, handler func(ctx context.Context, event E)) {
+	var zero E
+	b.Subscribe(zero.EventName(), func(ctx context.Context, event domain.Event) {
+		if e, ok := event.(E); ok {
+			handler(ctx, e)
+		}
+	})
+}
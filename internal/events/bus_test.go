@@ -0,0 +1,52 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestBus_DeliversToSubscribers(t *testing.T) {
+	bus := NewBus()
+	var all []string
+	var joined []domain.UserJoinedRoom
+	var sent int
+
+	bus.SubscribeAll(func(ctx context.Context, event domain.Event) {
+		all = append(all, event.EventName())
+	})
+	On(bus, func(ctx context.Context, e domain.UserJoinedRoom) {
+		joined = append(joined, e)
+	})
+	bus.Subscribe(domain.EventMessageSent, func(ctx context.Context, event domain.Event) {
+		sent++
+	})
+
+	ctx := context.Background()
+	bus.Publish(ctx, domain.UserJoinedRoom{ChatroomID: "room-1", UserID: "user-1"})
+	bus.Publish(ctx, domain.MessageSent{Message: &domain.Message{ID: "msg-1"}})
+	bus.Publish(ctx, domain.RoomCreated{Chatroom: &domain.Chatroom{ID: "room-2"}})
+
+	testutil.AssertLen(t, all, 3)
+	testutil.AssertEqual(t, all[2], domain.EventRoomCreated)
+	testutil.AssertLen(t, joined, 1)
+	testutil.AssertEqual(t, joined[0].UserID, "user-1")
+	testutil.AssertEqual(t, sent, 1)
+}
+
+func TestBus_RecoversFromPanickingHandlers(t *testing.T) {
+	bus := NewBus()
+	delivered := false
+	bus.Subscribe(domain.EventRoomCreated, func(ctx context.Context, event domain.Event) {
+		panic("boom")
+	})
+	bus.Subscribe(domain.EventRoomCreated, func(ctx context.Context, event domain.Event) {
+		delivered = true
+	})
+
+	bus.Publish(context.Background(), domain.RoomCreated{Chatroom: &domain.Chatroom{ID: "room-1"}})
+
+	testutil.AssertTrue(t, delivered, "handlers after a panicking one still run")
+}
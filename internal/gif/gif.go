@@ -0,0 +1,260 @@
+// Package gif provides the GIF search the stock bot answers /giphy with. The
+// provider is selected with STOCK_BOT_GIF_PROVIDER: Giphy, Tenor, or none to
+// disable the command.
+package gif
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"jobsity-chat/internal/config"
+	"jobsity-chat/internal/domain"
+)
+
+const (
+	ProviderGiphy = "giphy"
+	ProviderTenor = "tenor"
+	ProviderNone  = "none"
+
+	DefaultGiphyURL = "https://api.giphy.com/v1/gifs/search"
+	DefaultTenorURL = "https://tenor.googleapis.com/v2/search"
+
+	// searchLimit is how many results a random GIF is picked from
+	searchLimit = 10
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown gif provider")
+	ErrDisabled        = errors.New("gif search disabled")
+	ErrNotFound        = errors.New("no gif found")
+)
+
+// Provider searches for a GIF matching query and returns it as an image
+// attachment
+type Provider interface {
+	Search(ctx context.Context, query string) (*domain.Attachment, error)
+}
+
+// FromConfig returns the provider configured by STOCK_BOT_GIF_PROVIDER.
+// Without one, the returned provider answers every search with ErrDisabled.
+func FromConfig(cfg *config.Config) (Provider, error) {
+	switch strings.ToLower(cfg.StockBotGIFProvider) {
+	case "", ProviderNone:
+		return disabledProvider{}, nil
+	case ProviderGiphy:
+		if cfg.StockBotGIFAPIKey == "" {
+			return nil, fmt.Errorf("STOCK_BOT_GIF_API_KEY is required for the giphy provider")
+		}
+		return NewGiphyProvider(firstNonEmpty(cfg.StockBotGIFAPIURL, DefaultGiphyURL), cfg.StockBotGIFAPIKey, cfg.StockBotGIFRating), nil
+	case ProviderTenor:
+		if cfg.StockBotGIFAPIKey == "" {
+			return nil, fmt.Errorf("STOCK_BOT_GIF_API_KEY is required for the tenor provider")
+		}
+		return NewTenorProvider(firstNonEmpty(cfg.StockBotGIFAPIURL, DefaultTenorURL), cfg.StockBotGIFAPIKey, cfg.StockBotGIFRating), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, cfg.StockBotGIFProvider)
+	}
+}
+
+type disabledProvider struct{}
+
+func (disabledProvider) Search(ctx context.Context, query string) (*domain.Attachment, error) {
+	return nil, ErrDisabled
+}
+
+// GiphyProvider searches the Giphy API
+type GiphyProvider struct {
+	url        string
+	apiKey     string
+	rating     string
+	httpClient *http.Client
+}
+
+func NewGiphyProvider(apiURL, apiKey, rating string) *GiphyProvider {
+	return &GiphyProvider{
+		url:        apiURL,
+		apiKey:     apiKey,
+		rating:     strings.ToLower(rating),
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+type giphyResponse struct {
+	Data []struct {
+		Title  string `json:"title"`
+		Images struct {
+			FixedHeight struct {
+				URL    string `json:"url"`
+				Width  string `json:"width"`
+				Height string `json:"height"`
+			} `json:"fixed_height"`
+		} `json:"images"`
+	} `json:"data"`
+}
+
+func (p *GiphyProvider) Search(ctx context.Context, query string) (*domain.Attachment, error) {
+	params := url.Values{
+		"api_key": {p.apiKey},
+		"q":       {query},
+		"limit":   {strconv.Itoa(searchLimit)},
+	}
+	if p.rating != "" {
+		params.Set("rating", p.rating)
+	}
+
+	var resp giphyResponse
+	if err := getJSON(ctx, p.httpClient, p.url, params, &resp); err != nil {
+		return nil, err
+	}
+
+	var results []*domain.Attachment
+	for _, gif := range resp.Data {
+		image := gif.Images.FixedHeight
+		if !isHTTPS(image.URL) {
+			continue
+		}
+		width, _ := strconv.Atoi(image.Width)
+		height, _ := strconv.Atoi(image.Height)
+		results = append(results, &domain.Attachment{
+			Type:   domain.AttachmentImage,
+			URL:    image.URL,
+			Title:  gif.Title,
+			Width:  width,
+			Height: height,
+		})
+	}
+	return pick(results)
+}
+
+// TenorProvider searches the Tenor v2 API
+type TenorProvider struct {
+	url           string
+	apiKey        string
+	contentFilter string
+	httpClient    *http.Client
+}
+
+// NewTenorProvider maps rating (g, pg, pg-13, r) onto Tenor's content filter
+func NewTenorProvider(apiURL, apiKey, rating string) *TenorProvider {
+	return &TenorProvider{
+		url:           apiURL,
+		apiKey:        apiKey,
+		contentFilter: tenorContentFilter(rating),
+		httpClient:    &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func tenorContentFilter(rating string) string {
+	switch strings.ToLower(rating) {
+	case "pg":
+		return "medium"
+	case "pg-13":
+		return "low"
+	case "r":
+		return "off"
+	default:
+		return "high"
+	}
+}
+
+type tenorResponse struct {
+	Results []struct {
+		ContentDescription string `json:"content_description"`
+		MediaFormats       map[string]struct {
+			URL  string `json:"url"`
+			Dims []int  `json:"dims"`
+		} `json:"media_formats"`
+	} `json:"results"`
+}
+
+func (p *TenorProvider) Search(ctx context.Context, query string) (*domain.Attachment, error) {
+	params := url.Values{
+		"key":           {p.apiKey},
+		"q":             {query},
+		"limit":         {strconv.Itoa(searchLimit)},
+		"media_filter":  {"tinygif"},
+		"contentfilter": {p.contentFilter},
+	}
+
+	var resp tenorResponse
+	if err := getJSON(ctx, p.httpClient, p.url, params, &resp); err != nil {
+		return nil, err
+	}
+
+	var results []*domain.Attachment
+	for _, gif := range resp.Results {
+		media, ok := gif.MediaFormats["tinygif"]
+		if !ok || !isHTTPS(media.URL) {
+			continue
+		}
+		attachment := &domain.Attachment{
+			Type:  domain.AttachmentImage,
+			URL:   media.URL,
+			Title: gif.ContentDescription,
+		}
+		if len(media.Dims) == 2 {
+			attachment.Width, attachment.Height = media.Dims[0], media.Dims[1]
+		}
+		results = append(results, attachment)
+	}
+	return pick(results)
+}
+
+func getJSON(ctx context.Context, client *http.Client, apiURL string, params url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// The request URL carries the API key
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to search gifs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("gif API returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode gif search: %w", err)
+	}
+	return nil
+}
+
+// pick returns a random result so repeated searches vary
+func pick(results []*domain.Attachment) (*domain.Attachment, error) {
+	if len(results) == 0 {
+		return nil, ErrNotFound
+	}
+	return results[rand.IntN(len(results))], nil
+}
+
+// isHTTPS keeps only https media, which browsers load on secure pages
+func isHTTPS(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package gif
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"jobsity-chat/internal/config"
+)
+
+func TestFromConfig(t *testing.T) {
+	provider, err := FromConfig(&config.Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := provider.Search(context.Background(), "cats"); !errors.Is(err, ErrDisabled) {
+		t.Errorf("expected ErrDisabled by default, got %v", err)
+	}
+
+	provider, err = FromConfig(&config.Config{StockBotGIFProvider: "Giphy", StockBotGIFAPIKey: "key"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p, ok := provider.(*GiphyProvider); !ok || p.url != DefaultGiphyURL {
+		t.Errorf("expected the giphy provider with the default URL, got %T", provider)
+	}
+
+	provider, err = FromConfig(&config.Config{StockBotGIFProvider: "tenor", StockBotGIFAPIKey: "key", StockBotGIFAPIURL: "https://gifs.internal/search"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p, ok := provider.(*TenorProvider); !ok || p.url != "https://gifs.internal/search" {
+		t.Errorf("expected the tenor provider with the configured URL, got %T", provider)
+	}
+
+	if _, err := FromConfig(&config.Config{StockBotGIFProvider: "giphy"}); err == nil {
+		t.Error("expected an error without STOCK_BOT_GIF_API_KEY")
+	}
+
+	if _, err := FromConfig(&config.Config{StockBotGIFProvider: "imgur"}); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("expected ErrUnknownProvider, got %v", err)
+	}
+}
+
+func TestGiphyProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("api_key") != "secret" || q.Get("rating") != "pg" {
+			t.Errorf("unexpected query %q", r.URL.RawQuery)
+		}
+		if q.Get("q") == "nothing" {
+			w.Write([]byte(`{"data":[]}`))
+			return
+		}
+		w.Write([]byte(`{"data":[
+			{"title":"insecure","images":{"fixed_height":{"url":"http://media.giphy.com/a.gif"}}},
+			{"title":"Happy Cat","images":{"fixed_height":{"url":"https://media.giphy.com/cat.gif","width":"356","height":"200"}}}
+		]}`))
+	}))
+	defer server.Close()
+
+	p := NewGiphyProvider(server.URL, "secret", "PG")
+
+	gif, err := p.Search(context.Background(), "cats")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gif.Type != "image" || gif.URL != "https://media.giphy.com/cat.gif" || gif.Title != "Happy Cat" {
+		t.Errorf("unexpected attachment %+v", gif)
+	}
+	if gif.Width != 356 || gif.Height != 200 {
+		t.Errorf("expected 356x200, got %dx%d", gif.Width, gif.Height)
+	}
+
+	if _, err := p.Search(context.Background(), "nothing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestTenorProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if filter := r.URL.Query().Get("contentfilter"); filter != "high" {
+			t.Errorf("expected contentfilter high, got %q", filter)
+		}
+		w.Write([]byte(`{"results":[{"content_description":"Dancing dog","media_formats":{"tinygif":{"url":"https://media.tenor.com/dog.gif","dims":[220,160]}}}]}`))
+	}))
+	defer server.Close()
+
+	gif, err := NewTenorProvider(server.URL, "secret", "g").Search(context.Background(), "dog")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gif.URL != "https://media.tenor.com/dog.gif" || gif.Width != 220 || gif.Height != 160 {
+		t.Errorf("unexpected attachment %+v", gif)
+	}
+}
+
+func TestSearch_ErrorStatusHidesKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	_, err := NewGiphyProvider(server.URL, "secret", "g").Search(context.Background(), "cats")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	server.Close()
+	_, err = NewGiphyProvider(server.URL, "secret", "g").Search(context.Background(), "cats")
+	if err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("expected an error without the API key, got %v", err)
+	}
+}
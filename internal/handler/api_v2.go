@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"jobsity-chat/internal/domain"
+)
+
+// The /api/v2 routes share every handler with /api/v1. Errors are rewritten
+// into the structured envelope by middleware.ErrorEnvelopes; the handlers
+// below adapt the listings whose payloads changed, adding message sequence
+// numbers and pagination metadata.
+
+// Pagination describes where a v2 listing page sits in the full result
+type Pagination struct {
+	Limit   int  `json:"limit"`
+	HasMore bool `json:"has_more"`
+	// NextCursor fetches the next page: the cursor query parameter of a
+	// chatroom listing, or the before parameter of a message history
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// MessageV2 is a message as served by /api/v2
+type MessageV2 struct {
+	*domain.Message
+	Seq int64 `json:"seq"`
+}
+
+type MessagesResponseV2 struct {
+	Messages   []MessageV2 `json:"messages"`
+	Pagination Pagination  `json:"pagination"`
+}
+
+type ChatroomsResponseV2 struct {
+	Chatrooms  []ChatroomResponse `json:"chatrooms"`
+	Pagination Pagination         `json:"pagination"`
+}
+
+// ListV2 serves GET /api/v2/chatrooms
+func (h *ChatroomHandler) ListV2(w http.ResponseWriter, r *http.Request) {
+	opts := chatroomsQuery(r)
+	chatrooms, nextCursor, err := h.chatService.ListChatroomsPaginated(r.Context(), opts)
+	if err != nil {
+		listChatroomsError(w, err)
+		return
+	}
+
+	writeJSONV2(w, ChatroomsResponseV2{
+		Chatrooms: h.chatroomResponses(chatrooms),
+		Pagination: Pagination{
+			Limit:      opts.Limit,
+			HasMore:    nextCursor != "",
+			NextCursor: nextCursor,
+		},
+	})
+}
+
+// GetMessagesV2 serves GET /api/v2/chatrooms/{id}/messages. Pages go back in
+// time: NextCursor is the oldest message of the page, passed as before.
+func (h *ChatroomHandler) GetMessagesV2(w http.ResponseWriter, r *http.Request) {
+	chatroomID, limit, ok := h.messagesQuery(w, r)
+	if !ok {
+		return
+	}
+
+	before := r.URL.Query().Get("before")
+	messages, hasMore, err := h.chatService.GetMessagesPage(r.Context(), chatroomID, before, limit)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to retrieve messages"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if notModified(w, r, messagesETag(chatroomID, before, limit, messages)) {
+		return
+	}
+
+	response := MessagesResponseV2{
+		Messages:   make([]MessageV2, len(messages)),
+		Pagination: Pagination{Limit: limit, HasMore: hasMore},
+	}
+	for i, msg := range messages {
+		response.Messages[i] = MessageV2{Message: msg, Seq: msg.Seq}
+	}
+	if hasMore && len(messages) > 0 {
+		response.Pagination.NextCursor = messages[0].ID
+	}
+	writeJSONV2(w, response)
+}
+
+func writeJSONV2(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("failed to encode v2 response", slog.String("error", err.Error()))
+	}
+}
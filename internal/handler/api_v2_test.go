@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestChatroomHandler_ListV2(t *testing.T) {
+	handler := NewChatroomHandler(&mockChatService{
+		listChatroomsFunc: func(ctx context.Context) ([]*domain.Chatroom, error) {
+			return []*domain.Chatroom{{ID: "room-1", Name: "General"}}, nil
+		},
+	}, &mockHub{connectedCounts: map[string]int{"room-1": 4}})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/chatrooms?limit=10", nil)
+	w := httptest.NewRecorder()
+	handler.ListV2(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var response ChatroomsResponseV2
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Chatrooms) != 1 || response.Chatrooms[0].UserCount != 4 {
+		t.Errorf("unexpected chatrooms %+v", response.Chatrooms)
+	}
+	if response.Pagination != (Pagination{Limit: 10}) {
+		t.Errorf("unexpected pagination %+v", response.Pagination)
+	}
+}
+
+func TestChatroomHandler_GetMessagesV2(t *testing.T) {
+	var gotBefore string
+	var gotLimit int
+	handler := NewChatroomHandler(&mockChatService{
+		isMemberFunc: func(ctx context.Context, chatroomID, userID string) (bool, error) {
+			return userID == "user-123", nil
+		},
+		getMessagesPageFunc: func(ctx context.Context, chatroomID, before string, limit int) ([]*domain.Message, bool, error) {
+			gotBefore, gotLimit = before, limit
+			return []*domain.Message{
+				{ID: "msg-7", ChatroomID: chatroomID, Content: "older", Seq: 7},
+				{ID: "msg-9", ChatroomID: chatroomID, Content: "newer", Seq: 9},
+			}, true, nil
+		},
+	}, &mockHub{connectedCounts: make(map[string]int)})
+
+	get := func(userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v2/chatrooms/room-1/messages?limit=2&before=msg-10", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "room-1")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		req = req.WithContext(middleware.WithUserID(req.Context(), userID))
+
+		w := httptest.NewRecorder()
+		handler.GetMessagesV2(w, req)
+		return w
+	}
+
+	w := get("user-123")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w.Header().Get("ETag") == "" {
+		t.Error("expected an ETag")
+	}
+	if gotBefore != "msg-10" || gotLimit != 2 {
+		t.Errorf("expected before=msg-10 limit=2, got %q %d", gotBefore, gotLimit)
+	}
+
+	var response struct {
+		Messages []struct {
+			ID  string `json:"id"`
+			Seq int64  `json:"seq"`
+		} `json:"messages"`
+		Pagination Pagination `json:"pagination"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Messages) != 2 || response.Messages[0].Seq != 7 || response.Messages[1].Seq != 9 {
+		t.Errorf("expected messages with sequence numbers, got %+v", response.Messages)
+	}
+	if response.Pagination != (Pagination{Limit: 2, HasMore: true, NextCursor: "msg-7"}) {
+		t.Errorf("unexpected pagination %+v", response.Pagination)
+	}
+
+	if w := get("outsider"); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a non-member, got %d", w.Code)
+	}
+}
+
+func TestChatroomHandler_GetMessages_V1OmitsSeq(t *testing.T) {
+	handler := NewChatroomHandler(&mockChatService{
+		isMemberFunc: func(ctx context.Context, chatroomID, userID string) (bool, error) {
+			return true, nil
+		},
+		getMessagesFunc: func(ctx context.Context, chatroomID string, limit int) ([]*domain.Message, error) {
+			return []*domain.Message{{ID: "msg-1", Seq: 1}}, nil
+		},
+	}, &mockHub{connectedCounts: make(map[string]int)})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/chatrooms/room-1/messages", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "room-1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	req = req.WithContext(middleware.WithUserID(req.Context(), "user-123"))
+	w := httptest.NewRecorder()
+	handler.GetMessages(w, req)
+
+	var response struct {
+		Messages   []map[string]any `json:"messages"`
+		Pagination any              `json:"pagination"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if _, ok := response.Messages[0]["seq"]; ok || response.Pagination != nil {
+		t.Errorf("expected the v1 payload to be unchanged, got %+v", response)
+	}
+}
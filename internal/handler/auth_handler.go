@@ -1,53 +1,138 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log/slog"
+	"math"
 	"net/http"
-	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"jobsity-chat/internal/domain"
 	"jobsity-chat/internal/middleware"
 	"jobsity-chat/internal/service"
 )
 
-type AuthHandler struct {
-	authService  *service.AuthService
-	isProduction bool
+// AuthServiceInterface registers users and manages their sessions
+type AuthServiceInterface interface {
+	Register(ctx context.Context, username, email, password string) (*domain.User, error)
+	LoginWithOptions(ctx context.Context, username, password string, opts service.LoginOptions) (*domain.Session, *domain.User, error)
+	Logout(ctx context.Context, token string) error
+	ValidateSession(ctx context.Context, token string) (*domain.Session, error)
+	CreateGuest(ctx context.Context) (*domain.Session, *domain.User, error)
+	ConvertGuest(ctx context.Context, guestID, username, email, password string) (*domain.User, error)
+	GetUserByID(ctx context.Context, userID string) (*domain.User, error)
+	SetLocale(ctx context.Context, userID, locale string) error
+}
+
+// LoginThrottler delays repeated failed logins of a username from an IP
+type LoginThrottler interface {
+	// Reserve counts an attempt as failed before the password is checked,
+	// or returns a domain.LoginThrottledError while attempts are delayed
+	Reserve(ctx context.Context, username, ip string) error
+	// Release gives back an attempt that did not fail on its credentials
+	Release(ctx context.Context, username, ip string) error
+	Succeeded(ctx context.Context, username, ip string) error
 }
 
-func NewAuthHandler(authService *service.AuthService) *AuthHandler {
-	env := os.Getenv("ENVIRONMENT")
-	isProduction := env == "production" || env == "prod"
+// hashingRetryAfter is the Retry-After sent when the password hashing pool
+// is saturated, in seconds
+const hashingRetryAfter = 2
 
+type AuthHandler struct {
+	authService AuthServiceInterface
+	cookies     cookiePolicy
+	// invites is nil when chatroom invites are disabled
+	invites InviteServiceInterface
+	// csrfKey is nil unless clients need CSRF tokens
+	csrfKey []byte
+	// throttle is nil when failed logins are not throttled
+	throttle LoginThrottler
+}
+
+func NewAuthHandler(authService AuthServiceInterface) *AuthHandler {
 	return &AuthHandler{
-		authService:  authService,
-		isProduction: isProduction,
+		authService: authService,
+		cookies:     newCookiePolicy(),
+	}
+}
+
+// SetCookieMode sets how browsers may send the session cookie
+func (h *AuthHandler) SetCookieMode(mode CookieMode) {
+	h.cookies.mode = mode
+}
+
+// SetCSRFKey returns the session's CSRF token, derived with key, in login
+// and current user responses, for clients to send to middleware.CSRF
+func (h *AuthHandler) SetCSRFKey(key []byte) {
+	h.csrfKey = key
+}
+
+// csrfToken returns the CSRF token of the session, or "" when CSRF tokens
+// are not used
+func (h *AuthHandler) csrfToken(sessionToken string) string {
+	if h.csrfKey == nil {
+		return ""
 	}
+	return middleware.CSRFToken(h.csrfKey, sessionToken)
+}
+
+// SetLoginThrottle delays logins after failed attempts of the same
+// username from the same IP
+func (h *AuthHandler) SetLoginThrottle(throttle LoginThrottler) {
+	h.throttle = throttle
+}
+
+// SetInviteService lets registrations carry an invite token, joining the new
+// user to the invite's chatroom
+func (h *AuthHandler) SetInviteService(invites InviteServiceInterface) {
+	h.invites = invites
 }
 
 type RegisterRequest struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// InviteToken is the token of an emailed chatroom invite, if the user
+	// registers from one
+	InviteToken string `json:"invite_token,omitempty"`
 }
 
 type RegisterResponse struct {
 	ID       string `json:"id"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
+	Locale   string `json:"locale,omitempty"`
+	// CSRFToken is only returned for the current user, when the server
+	// requires CSRF tokens
+	CSRFToken string `json:"csrf_token,omitempty"`
+}
+
+// ValidationErrorResponse lists why each invalid field of a request was
+// rejected
+type ValidationErrorResponse struct {
+	Error  string              `json:"error"`
+	Fields []domain.FieldError `json:"fields"`
 }
 
 type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	RememberMe bool   `json:"remember_me"`
+}
+
+type LocaleRequest struct {
+	Locale string `json:"locale"`
 }
 
 type LoginResponse struct {
 	Success      bool             `json:"success"`
 	User         RegisterResponse `json:"user"`
 	SessionToken string           `json:"session_token"` // Token for WebSocket authentication
+	CSRFToken    string           `json:"csrf_token,omitempty"`
 }
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -57,7 +142,31 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.authService.Register(r.Context(), req.Username, req.Email, req.Password)
+	// Reject a stale invite before creating an account the user did not
+	// mean to create without it
+	if req.InviteToken != "" && h.invites != nil {
+		if _, err := h.invites.LookupInvite(r.Context(), req.InviteToken); err != nil {
+			writeInviteTokenError(w, err)
+			return
+		}
+	}
+
+	// A guest registering keeps their identity, memberships and messages
+	guest := h.guestSession(r)
+	var user *domain.User
+	var err error
+	if guest != nil {
+		user, err = h.authService.ConvertGuest(r.Context(), guest.UserID, req.Username, req.Email, req.Password)
+	} else {
+		user, err = h.authService.Register(r.Context(), req.Username, req.Email, req.Password)
+	}
+	var invalid *domain.ValidationError
+	if errors.As(err, &invalid) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ValidationErrorResponse{Error: "Invalid input", Fields: invalid.Fields})
+		return
+	}
 	if err != nil {
 		var status int
 		var message string
@@ -66,6 +175,10 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		case errors.Is(err, domain.ErrInvalidInput):
 			status = http.StatusBadRequest
 			message = "Invalid input"
+		case errors.Is(err, domain.ErrHashingBusy):
+			status = http.StatusServiceUnavailable
+			message = "Server busy, try again shortly"
+			w.Header().Set("Retry-After", strconv.Itoa(hashingRetryAfter))
 		case errors.Is(err, domain.ErrUsernameExists), errors.Is(err, domain.ErrEmailExists):
 			status = http.StatusConflict
 			message = "User already exists"
@@ -82,6 +195,22 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.InviteToken != "" && h.invites != nil {
+		// The invite may have been accepted or revoked since the lookup; the
+		// account is still created
+		if _, err := h.invites.AcceptInvite(r.Context(), req.InviteToken, user.ID); err != nil {
+			slog.Warn("failed to accept invite on registration",
+				slog.String("user_id", user.ID),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	if guest != nil {
+		// Converting ended the guest session; the user logs in with their
+		// new credentials
+		h.clearSessionCookie(w, r)
+	}
+
 	resp := RegisterResponse{
 		ID:       user.ID,
 		Username: user.Username,
@@ -97,6 +226,51 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// guestSession returns the live guest session the request's cookie belongs
+// to, if any
+func (h *AuthHandler) guestSession(r *http.Request) *domain.Session {
+	cookie, err := r.Cookie("session_id")
+	if err != nil || !strings.HasPrefix(cookie.Value, domain.GuestTokenPrefix) {
+		return nil
+	}
+	session, err := h.authService.ValidateSession(r.Context(), cookie.Value)
+	if err != nil {
+		return nil
+	}
+	return session
+}
+
+// Guest signs the caller in as a new guest, who can read and, where the
+// owner allows it, post in public chatrooms until the guest session expires
+func (h *AuthHandler) Guest(w http.ResponseWriter, r *http.Request) {
+	session, user, err := h.authService.CreateGuest(r.Context())
+	if errors.Is(err, domain.ErrGuestsDisabled) {
+		http.Error(w, `{"error":"Guest access is disabled"}`, http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to create guest", slog.String("error", err.Error()))
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, h.cookies.apply(r, newSessionCookie(session, false)))
+
+	resp := LoginResponse{
+		Success: true,
+		User: RegisterResponse{
+			ID:       user.ID,
+			Username: user.Username,
+		},
+		SessionToken: session.Token,
+		CSRFToken:    h.csrfToken(session.Token),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -104,34 +278,67 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, user, err := h.authService.Login(r.Context(), req.Username, req.Password)
+	// Throttle by the username login looks up, so spelling variants of one
+	// account share its failures
+	ip, username := middleware.ClientIP(r), service.NormalizeUsername(req.Username)
+	reserved := false
+	if h.throttle != nil {
+		var throttled *domain.LoginThrottledError
+		if err := h.throttle.Reserve(r.Context(), username, ip); errors.As(err, &throttled) {
+			retryAfter := int(math.Ceil(throttled.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"Too many failed logins, try again later"}`, http.StatusTooManyRequests)
+			return
+		} else if err != nil {
+			// Logins go on without throttling rather than fail with the store
+			slog.Error("login throttle check failed", slog.String("error", err.Error()))
+		} else {
+			reserved = true
+		}
+	}
+
+	session, user, err := h.authService.LoginWithOptions(r.Context(), req.Username, req.Password,
+		service.LoginOptions{RememberMe: req.RememberMe})
 	if err != nil {
 		var status int
 		var message string
 
-		if errors.Is(err, domain.ErrInvalidCredentials) {
+		switch {
+		case errors.Is(err, domain.ErrInvalidCredentials):
 			status = http.StatusUnauthorized
 			message = "Invalid credentials"
-		} else {
+			// The failure was counted when the attempt was reserved
+			reserved = false
+		case errors.Is(err, domain.ErrUserDeactivated):
+			status = http.StatusForbidden
+			message = "Account is deactivated"
+		case errors.Is(err, domain.ErrHashingBusy):
+			status = http.StatusServiceUnavailable
+			message = "Server busy, try again shortly"
+			w.Header().Set("Retry-After", strconv.Itoa(hashingRetryAfter))
+		default:
 			status = http.StatusInternalServerError
 			message = "Internal server error"
 			slog.Error("login error", slog.String("error", err.Error()))
 		}
+		if reserved {
+			if err := h.throttle.Release(r.Context(), username, ip); err != nil {
+				slog.Error("failed to release login attempt", slog.String("error", err.Error()))
+			}
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		http.Error(w, `{"error":"`+message+`"}`, status)
 		return
 	}
+	if h.throttle != nil {
+		if err := h.throttle.Succeeded(r.Context(), username, ip); err != nil {
+			slog.Error("failed to reset login failures", slog.String("error", err.Error()))
+		}
+	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_id",
-		Value:    session.Token,
-		Path:     "/",
-		MaxAge:   86400,
-		HttpOnly: true,
-		Secure:   h.isProduction,
-		SameSite: http.SameSiteLaxMode,
-	})
+	http.SetCookie(w, h.cookies.apply(r, newSessionCookie(session, req.RememberMe)))
 
 	resp := LoginResponse{
 		Success: true,
@@ -141,6 +348,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 			Email:    user.Email,
 		},
 		SessionToken: session.Token,
+		CSRFToken:    h.csrfToken(session.Token),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -164,12 +372,52 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 		ID:       user.ID,
 		Username: user.Username,
 		Email:    user.Email,
+		Locale:   user.Locale,
+	}
+	if session, ok := middleware.GetSession(r.Context()); ok {
+		resp.CSRFToken = h.csrfToken(session.Token)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
+// SetLocale updates the current user's preferred locale for bot and system
+// messages. It applies to WebSocket connections opened afterwards.
+func (h *AuthHandler) SetLocale(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req LocaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	err := h.authService.SetLocale(r.Context(), userID, req.Locale)
+	if errors.Is(err, domain.ErrInvalidInput) {
+		http.Error(w, `{"error":"Unsupported locale"}`, http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, domain.ErrUserNotFound) {
+		http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to set locale",
+			slog.String("error", err.Error()),
+			slog.String("user_id", userID))
+		http.Error(w, `{"error":"Failed to set locale"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LocaleRequest{Locale: req.Locale})
+}
+
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	session, ok := middleware.GetSession(r.Context())
 	if !ok {
@@ -182,15 +430,7 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_id",
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-		Secure:   h.isProduction,
-		SameSite: http.SameSiteLaxMode,
-	})
+	h.clearSessionCookie(w, r)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(map[string]bool{"success": true}); err != nil {
@@ -199,3 +439,21 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func (h *AuthHandler) clearSessionCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, h.cookies.apply(r, &http.Cookie{
+		Name:   "session_id",
+		Value:  "",
+		Path:   "/",
+		MaxAge: -1,
+	}))
+}
+
+// sessionMaxAge keeps the cookie until the session's absolute expiry; the
+// server enforces the shorter idle timeout.
+func sessionMaxAge(session *domain.Session) int {
+	if session.AbsoluteExpiresAt.IsZero() {
+		return int(time.Until(session.ExpiresAt).Seconds())
+	}
+	return int(time.Until(session.AbsoluteExpiresAt).Seconds())
+}
@@ -8,12 +8,14 @@ import (
 	"net/http/httptest"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"jobsity-chat/internal/domain"
 	"jobsity-chat/internal/middleware"
 	"jobsity-chat/internal/service"
+	"jobsity-chat/internal/testutil"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -54,6 +56,50 @@ func (m *mockUserRepository) GetByEmail(ctx context.Context, email string) (*dom
 	return nil, errors.New("not implemented")
 }
 
+func (m *mockUserRepository) HasConfusableUsername(ctx context.Context, username, exceptUserID string) (bool, error) {
+	return false, nil
+}
+
+func (m *mockUserRepository) UpdateRole(ctx context.Context, userID, role string) error {
+	return errors.New("not implemented")
+}
+
+func (m *mockUserRepository) SetDeactivated(ctx context.Context, userID string, deactivated bool) error {
+	return errors.New("not implemented")
+}
+
+func (m *mockUserRepository) SetLocale(ctx context.Context, userID, locale string) error {
+	return errors.New("not implemented")
+}
+
+func (m *mockUserRepository) GetProfile(ctx context.Context, id string) (*domain.Profile, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockUserRepository) UpdateProfileSettings(ctx context.Context, userID string, settings *domain.ProfileSettings) error {
+	return errors.New("not implemented")
+}
+
+func (m *mockUserRepository) GetStatus(ctx context.Context, userID string) (*domain.UserStatus, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockUserRepository) SetStatus(ctx context.Context, userID string, status *domain.UserStatus) error {
+	return errors.New("not implemented")
+}
+
+func (m *mockUserRepository) CreateGuest(ctx context.Context, user *domain.User) error {
+	return errors.New("not implemented")
+}
+
+func (m *mockUserRepository) ConvertGuest(ctx context.Context, user *domain.User) error {
+	return errors.New("not implemented")
+}
+
+func (m *mockUserRepository) DeleteGuestsCreatedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return 0, errors.New("not implemented")
+}
+
 // mockSessionRepository implements domain.SessionRepository for testing
 type mockSessionRepository struct {
 	createFunc        func(ctx context.Context, session *domain.Session) error
@@ -90,6 +136,14 @@ func (m *mockSessionRepository) DeleteExpired(ctx context.Context) (int64, error
 	return 0, nil
 }
 
+func (m *mockSessionRepository) DeleteByUserID(ctx context.Context, userID string) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockSessionRepository) Touch(ctx context.Context, activity []domain.SessionActivity) (int64, error) {
+	return 0, nil
+}
+
 func TestAuthHandler_Register_Success(t *testing.T) {
 	userRepo := &mockUserRepository{
 		createFunc: func(ctx context.Context, user *domain.User) error {
@@ -130,6 +184,26 @@ func TestAuthHandler_Register_Success(t *testing.T) {
 	}
 }
 
+func TestAuthHandler_Register_FieldErrors(t *testing.T) {
+	authService := service.NewAuthService(&mockUserRepository{}, &mockSessionRepository{})
+	authService.SetPasswordPolicy(service.PasswordPolicy{MinLength: 12})
+	handler := NewAuthHandler(authService)
+
+	reqBody := `{"username":"testuser","email":"bad","password":"password123"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", strings.NewReader(reqBody))
+	w := httptest.NewRecorder()
+
+	handler.Register(w, req)
+
+	testutil.AssertStatusCode(t, w, http.StatusBadRequest)
+	resp := testutil.DecodeJSON[ValidationErrorResponse](t, w)
+	testutil.AssertEqual(t, resp.Error, "Invalid input")
+	testutil.AssertLen(t, resp.Fields, 2)
+	testutil.AssertEqual(t, resp.Fields[0].Field, "email")
+	testutil.AssertEqual(t, resp.Fields[1].Field, "password")
+	testutil.AssertEqual(t, resp.Fields[1].Code, domain.FieldTooShort)
+}
+
 func TestAuthHandler_Register_InvalidJSON(t *testing.T) {
 	authService := service.NewAuthService(&mockUserRepository{}, &mockSessionRepository{})
 	handler := NewAuthHandler(authService)
@@ -260,6 +334,7 @@ func TestAuthHandler_Login_Success(t *testing.T) {
 	tests := []struct {
 		name             string
 		environment      string
+		forwardedProto   string
 		expectedSecure   bool
 		expectedSameSite http.SameSite
 	}{
@@ -275,6 +350,13 @@ func TestAuthHandler_Login_Success(t *testing.T) {
 			expectedSecure:   false,
 			expectedSameSite: http.SameSiteLaxMode,
 		},
+		{
+			name:             "development behind a TLS-terminating proxy",
+			environment:      "development",
+			forwardedProto:   "https",
+			expectedSecure:   true,
+			expectedSameSite: http.SameSiteLaxMode,
+		},
 	}
 
 	for _, tt := range tests {
@@ -305,9 +387,14 @@ func TestAuthHandler_Login_Success(t *testing.T) {
 			reqBody := `{"username":"testuser","password":"password123"}`
 			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(reqBody))
 			req.Header.Set("Content-Type", "application/json")
+			if tt.forwardedProto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.forwardedProto)
+			}
 			w := httptest.NewRecorder()
 
-			handler.Login(w, req)
+			// httptest requests come from 192.0.2.1
+			trusted, _ := middleware.ParseTrustedProxies("192.0.2.0/24")
+			middleware.Proxy(trusted)(http.HandlerFunc(handler.Login)).ServeHTTP(w, req)
 
 			if w.Code != http.StatusOK {
 				t.Errorf("expected status %d, got %d, body: %s", http.StatusOK, w.Code, w.Body.String())
@@ -355,6 +442,68 @@ func TestAuthHandler_Login_Success(t *testing.T) {
 	}
 }
 
+func TestAuthHandler_Login_RememberMeCookie(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantMaxAge time.Duration
+	}{
+		{
+			name:       "short session uses a browser-session cookie",
+			body:       `{"username":"testuser","password":"password123"}`,
+			wantMaxAge: 0,
+		},
+		{
+			name:       "remember me persists until the absolute expiry",
+			body:       `{"username":"testuser","password":"password123","remember_me":true}`,
+			wantMaxAge: service.DefaultSessionPolicy().RememberAbsoluteTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := &mockUserRepository{
+				getUsernameFunc: func(ctx context.Context, username string) (*domain.User, error) {
+					return &domain.User{
+						ID:           "user-123",
+						Username:     "testuser",
+						PasswordHash: string(hashedPassword),
+					}, nil
+				},
+			}
+			sessionRepo := &mockSessionRepository{
+				createFunc: func(ctx context.Context, session *domain.Session) error {
+					return nil
+				},
+			}
+
+			handler := NewAuthHandler(service.NewAuthService(userRepo, sessionRepo))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.Login(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d, body: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+
+			cookies := w.Result().Cookies()
+			if len(cookies) != 1 {
+				t.Fatalf("expected 1 cookie, got %d", len(cookies))
+			}
+
+			gotMaxAge := time.Duration(cookies[0].MaxAge) * time.Second
+			if (gotMaxAge - tt.wantMaxAge).Abs() > time.Minute {
+				t.Errorf("expected MaxAge ~%v, got %v", tt.wantMaxAge, gotMaxAge)
+			}
+		})
+	}
+}
+
 func TestAuthHandler_Login_InvalidJSON(t *testing.T) {
 	authService := service.NewAuthService(&mockUserRepository{}, &mockSessionRepository{})
 	handler := NewAuthHandler(authService)
@@ -397,6 +546,39 @@ func TestAuthHandler_Login_InvalidCredentials(t *testing.T) {
 	}
 }
 
+func TestAuthHandler_Login_Deactivated(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	deactivatedAt := time.Now()
+
+	userRepo := &mockUserRepository{
+		getUsernameFunc: func(ctx context.Context, username string) (*domain.User, error) {
+			return &domain.User{
+				ID:            "user-123",
+				Username:      "testuser",
+				PasswordHash:  string(hashedPassword),
+				DeactivatedAt: &deactivatedAt,
+			}, nil
+		},
+	}
+
+	authService := service.NewAuthService(userRepo, &mockSessionRepository{})
+	handler := NewAuthHandler(authService)
+
+	reqBody := `{"username":"testuser","password":"password123"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.Login(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("expected no session cookie for a deactivated account")
+	}
+}
+
 func TestAuthHandler_Login_InternalError(t *testing.T) {
 	// Hash a password for testing
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
@@ -478,6 +660,64 @@ func TestAuthHandler_Me_Success(t *testing.T) {
 	}
 }
 
+func TestAuthHandler_Me_CSRFToken(t *testing.T) {
+	userRepo := &mockUserRepository{
+		getByIDFunc: func(ctx context.Context, userID string) (*domain.User, error) {
+			return &domain.User{ID: userID, Username: "testuser"}, nil
+		},
+	}
+	handler := NewAuthHandler(service.NewAuthService(userRepo, &mockSessionRepository{}))
+	key := []byte("csrf-test-key")
+	handler.SetCSRFKey(key)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+	ctx := middleware.WithUserID(req.Context(), "user-123")
+	ctx = middleware.WithSession(ctx, &domain.Session{Token: "session-token", UserID: "user-123"})
+	w := httptest.NewRecorder()
+
+	handler.Me(w, req.WithContext(ctx))
+
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	var resp RegisterResponse
+	testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	testutil.AssertEqual(t, resp.CSRFToken, middleware.CSRFToken(key, "session-token"))
+}
+
+func TestAuthHandler_SetLocale(t *testing.T) {
+	userRepo := testutil.NewMockUserRepository()
+	userRepo.Users["user-123"] = &domain.User{ID: "user-123", Username: "testuser"}
+	handler := NewAuthHandler(service.NewAuthService(userRepo, &mockSessionRepository{}))
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantLocale string
+	}{
+		{"supported_locale", `{"locale":"pt"}`, http.StatusOK, "pt"},
+		{"unsupported_locale", `{"locale":"fr"}`, http.StatusBadRequest, "pt"},
+		{"invalid_json", `{`, http.StatusBadRequest, "pt"},
+		{"clear_preference", `{"locale":""}`, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/auth/me/locale", strings.NewReader(tt.body))
+			req = req.WithContext(middleware.WithUserID(req.Context(), "user-123"))
+			w := httptest.NewRecorder()
+
+			handler.SetLocale(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if got := userRepo.Users["user-123"].Locale; got != tt.wantLocale {
+				t.Errorf("expected stored locale %q, got %q", tt.wantLocale, got)
+			}
+		})
+	}
+}
+
 func TestAuthHandler_Me_NoUserIDInContext(t *testing.T) {
 	authService := service.NewAuthService(&mockUserRepository{}, &mockSessionRepository{})
 	handler := NewAuthHandler(authService)
@@ -624,3 +864,230 @@ func TestAuthHandler_Logout_ServiceError(t *testing.T) {
 		t.Errorf("expected error message about logout failure, got: %s", w.Body.String())
 	}
 }
+
+func TestAuthHandler_Register_WithInvite(t *testing.T) {
+	userRepo := &mockUserRepository{
+		createFunc: func(ctx context.Context, user *domain.User) error {
+			user.ID = "user-123"
+			return nil
+		},
+	}
+	invites := &mockInvites{accepted: make(map[string]string)}
+	handler := NewAuthHandler(service.NewAuthService(userRepo, &mockSessionRepository{}))
+	handler.SetInviteService(invites)
+
+	body := `{"username":"newuser","email":"new@example.com","password":"password123","invite_token":"expired"}`
+	w := httptest.NewRecorder()
+	handler.Register(w, httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", strings.NewReader(body)))
+	testutil.AssertEqual(t, w.Code, http.StatusNotFound)
+
+	body = `{"username":"newuser","email":"new@example.com","password":"password123","invite_token":"valid"}`
+	w = httptest.NewRecorder()
+	handler.Register(w, httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", strings.NewReader(body)))
+	testutil.AssertEqual(t, w.Code, http.StatusCreated)
+	testutil.AssertEqual(t, invites.accepted["valid"], "user-123")
+}
+
+func TestAuthHandler_Guest(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		handler := NewAuthHandler(service.NewAuthService(testutil.NewMockUserRepository(), testutil.NewMockSessionRepository()))
+
+		w := httptest.NewRecorder()
+		handler.Guest(w, httptest.NewRequest(http.MethodPost, "/api/v1/auth/guest", nil))
+
+		testutil.AssertStatusCode(t, w, http.StatusForbidden)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		userRepo := testutil.NewMockUserRepository()
+		sessionRepo := testutil.NewMockSessionRepository()
+		authService := service.NewAuthService(userRepo, sessionRepo)
+		authService.SetGuestSessionTTL(time.Hour)
+		handler := NewAuthHandler(authService)
+
+		w := httptest.NewRecorder()
+		handler.Guest(w, httptest.NewRequest(http.MethodPost, "/api/v1/auth/guest", nil))
+
+		testutil.AssertStatusCode(t, w, http.StatusCreated)
+		var resp LoginResponse
+		testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		testutil.AssertTrue(t, strings.HasPrefix(resp.SessionToken, domain.GuestTokenPrefix), "guest session token should be prefixed")
+		testutil.AssertTrue(t, userRepo.Users[resp.User.ID].IsGuest(), "user should be a guest")
+
+		cookies := w.Result().Cookies()
+		testutil.AssertLen(t, cookies, 1)
+		testutil.AssertEqual(t, cookies[0].Value, resp.SessionToken)
+	})
+}
+
+func TestAuthHandler_Register_ConvertsGuest(t *testing.T) {
+	userRepo := testutil.NewMockUserRepository()
+	sessionRepo := testutil.NewMockSessionRepository()
+	authService := service.NewAuthService(userRepo, sessionRepo)
+	authService.SetGuestSessionTTL(time.Hour)
+	handler := NewAuthHandler(authService)
+
+	session, guest, err := authService.CreateGuest(context.Background())
+	testutil.AssertNoError(t, err)
+
+	body := `{"username":"newuser","email":"new@example.com","password":"password123"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", strings.NewReader(body))
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: session.Token})
+	w := httptest.NewRecorder()
+
+	handler.Register(w, req)
+
+	testutil.AssertStatusCode(t, w, http.StatusCreated)
+	var resp RegisterResponse
+	testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	testutil.AssertEqual(t, resp.ID, guest.ID)
+	testutil.AssertEqual(t, userRepo.Users[guest.ID].Role, domain.RoleUser)
+	testutil.AssertEqual(t, userRepo.Users[guest.ID].Username, "newuser")
+
+	_, err = sessionRepo.GetByToken(context.Background(), session.Token)
+	testutil.AssertError(t, err)
+}
+
+// busyHasher is a saturated password hashing pool
+type busyHasher struct{}
+
+func (busyHasher) Hash(ctx context.Context, password string) (string, error) {
+	return "", domain.ErrHashingBusy
+}
+
+func (busyHasher) Compare(ctx context.Context, hash, password string) error {
+	return domain.ErrHashingBusy
+}
+
+func TestAuthHandler_HashingBusy(t *testing.T) {
+	userRepo := &mockUserRepository{
+		getUsernameFunc: func(ctx context.Context, username string) (*domain.User, error) {
+			if username == "testuser" {
+				return &domain.User{ID: "user-123", Username: username, PasswordHash: "$2a$04$hash"}, nil
+			}
+			return nil, domain.ErrUserNotFound
+		},
+	}
+	authService := service.NewAuthService(userRepo, &mockSessionRepository{})
+	authService.SetPasswordHasher(busyHasher{})
+	handler := NewAuthHandler(authService)
+
+	for _, tt := range []struct {
+		name   string
+		target string
+		body   string
+		serve  http.HandlerFunc
+	}{
+		{"register", "/api/v1/auth/register", `{"username":"newuser","email":"new@example.com","password":"password123"}`, handler.Register},
+		{"login", "/api/v1/auth/login", `{"username":"testuser","password":"password123"}`, handler.Login},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			tt.serve(w, req)
+
+			testutil.AssertStatusCode(t, w, http.StatusServiceUnavailable)
+			testutil.AssertEqual(t, w.Header().Get("Retry-After"), "2")
+		})
+	}
+}
+
+func TestAuthHandler_LoginThrottle(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	userRepo := &mockUserRepository{
+		getUsernameFunc: func(ctx context.Context, username string) (*domain.User, error) {
+			return &domain.User{ID: "user-123", Username: "testuser", PasswordHash: string(hashedPassword)}, nil
+		},
+	}
+	failures := testutil.NewMockLoginFailureRepository()
+	sessionRepo := &mockSessionRepository{
+		createFunc: func(ctx context.Context, session *domain.Session) error { return nil },
+	}
+	handler := NewAuthHandler(service.NewAuthService(userRepo, sessionRepo))
+	handler.SetLoginThrottle(service.NewLoginThrottle(failures, service.LoginThrottleConfig{
+		BaseDelay: time.Minute,
+		MaxDelay:  time.Hour,
+	}))
+
+	login := func(password, remoteAddr string) *httptest.ResponseRecorder {
+		body := `{"username":"testuser","password":"` + password + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.Login(w, req)
+		return w
+	}
+
+	testutil.AssertStatusCode(t, login("wrong", "203.0.113.7:5000"), http.StatusUnauthorized)
+	testutil.AssertEqual(t, failures.Failures[testutil.LoginFailureKey("testuser", "203.0.113.7")].Count, 1)
+
+	// Even the right password waits out the delay, from any port
+	w := login("password123", "203.0.113.7:5001")
+	testutil.AssertStatusCode(t, w, http.StatusTooManyRequests)
+	testutil.AssertEqual(t, w.Header().Get("Retry-After"), "60")
+
+	// Other IPs are not delayed, and a success forgets their past failures
+	failures.Failures[testutil.LoginFailureKey("testuser", "198.51.100.1")] = domain.LoginFailures{Count: 1, LastFailedAt: time.Now().Add(-2 * time.Minute)}
+	testutil.AssertStatusCode(t, login("password123", "198.51.100.1:5000"), http.StatusOK)
+	_, ok := failures.Failures[testutil.LoginFailureKey("testuser", "198.51.100.1")]
+	testutil.AssertFalse(t, ok, "a successful login should reset the failures")
+
+	// Spellings login normalizes to the same username share its failures
+	for _, username := range []string{" testuser", "testuser ", "ｔｅｓｔｕｓｅｒ"} {
+		body := `{"username":"` + username + `","password":"wrong"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(body))
+		req.RemoteAddr = "192.0.2.9:5000"
+		w := httptest.NewRecorder()
+		handler.Login(w, req)
+		if username == " testuser" {
+			testutil.AssertStatusCode(t, w, http.StatusUnauthorized)
+		} else {
+			testutil.AssertStatusCode(t, w, http.StatusTooManyRequests)
+		}
+	}
+	testutil.AssertEqual(t, failures.Failures[testutil.LoginFailureKey("testuser", "192.0.2.9")].Count, 1)
+}
+
+func TestAuthHandler_LoginThrottleConcurrent(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	userRepo := &mockUserRepository{
+		getUsernameFunc: func(ctx context.Context, username string) (*domain.User, error) {
+			return &domain.User{ID: "user-123", Username: "testuser", PasswordHash: string(hashedPassword)}, nil
+		},
+	}
+	failures := testutil.NewMockLoginFailureRepository()
+	handler := NewAuthHandler(service.NewAuthService(userRepo, &mockSessionRepository{}))
+	handler.SetLoginThrottle(service.NewLoginThrottle(failures, service.LoginThrottleConfig{
+		BaseDelay: time.Minute,
+		MaxDelay:  time.Hour,
+	}))
+
+	// Parallel guesses are throttled before any of them failed
+	const attempts = 10
+	statuses := make([]int, attempts)
+	var wg sync.WaitGroup
+	for i := range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := `{"username":"testuser","password":"wrong"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(body))
+			req.RemoteAddr = "203.0.113.7:5000"
+			w := httptest.NewRecorder()
+			handler.Login(w, req)
+			statuses[i] = w.Code
+		}()
+	}
+	wg.Wait()
+
+	unauthorized := 0
+	for _, status := range statuses {
+		if status == http.StatusUnauthorized {
+			unauthorized++
+		} else {
+			testutil.AssertEqual(t, status, http.StatusTooManyRequests)
+		}
+	}
+	testutil.AssertEqual(t, unauthorized, 1)
+	testutil.AssertEqual(t, failures.Failures[testutil.LoginFailureKey("testuser", "203.0.113.7")].Count, 1)
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"jobsity-chat/internal/domain"
+)
+
+const (
+	defaultBotStatsWindow = 24 * time.Hour
+	maxBotStatsWindow     = 90 * 24 * time.Hour
+)
+
+// BotStatsHandler reports bot command usage to administrators.
+// It must be guarded by middleware.RequireAdmin.
+type BotStatsHandler struct {
+	botStats domain.BotStatsRepository
+}
+
+func NewBotStatsHandler(botStats domain.BotStatsRepository) *BotStatsHandler {
+	return &BotStatsHandler{botStats: botStats}
+}
+
+// BotStatsResponse is the payload returned by GET /api/v1/admin/bot-stats
+type BotStatsResponse struct {
+	Since    time.Time                `json:"since"`
+	Commands []domain.BotCommandStats `json:"commands"`
+}
+
+// Stats returns usage per command type and symbol over the window given by
+// the "window" query parameter (a duration such as 1h or 168h, default 24h).
+func (h *BotStatsHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	window := defaultBotStatsWindow
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > maxBotStatsWindow {
+			http.Error(w, `{"error":"Invalid window"}`, http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+
+	since := time.Now().Add(-window).UTC()
+	stats, err := h.botStats.Stats(r.Context(), since)
+	if err != nil {
+		slog.Error("failed to get bot stats", slog.String("error", err.Error()))
+		http.Error(w, `{"error":"Failed to get bot stats"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BotStatsResponse{Since: since, Commands: stats})
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestBotStatsHandler_Stats(t *testing.T) {
+	repo := testutil.NewMockBotStatsRepository()
+	repo.Usage = []domain.BotCommandUsage{
+		{CommandType: "stock", Symbol: "AAPL.US", Success: true},
+		{CommandType: "stock", Symbol: "AAPL.US", Success: false},
+		{CommandType: "hello", Symbol: "zen", Success: true},
+	}
+	handler := NewBotStatsHandler(repo)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/bot-stats", nil)
+	w := httptest.NewRecorder()
+
+	handler.Stats(w, req)
+
+	testutil.AssertEqual(t, w.Code, http.StatusOK)
+
+	var resp BotStatsResponse
+	testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	testutil.AssertLen(t, resp.Commands, 2)
+	testutil.AssertEqual(t, resp.Commands[0], domain.BotCommandStats{CommandType: "stock", Symbol: "AAPL.US", Count: 2, Errors: 1})
+	testutil.AssertTrue(t, time.Since(resp.Since) > 23*time.Hour, "expected a 24h default window")
+}
+
+func TestBotStatsHandler_Window(t *testing.T) {
+	var gotSince time.Time
+	repo := testutil.NewMockBotStatsRepository()
+	repo.StatsFunc = func(ctx context.Context, since time.Time) ([]domain.BotCommandStats, error) {
+		gotSince = since
+		return nil, nil
+	}
+	handler := NewBotStatsHandler(repo)
+
+	w := httptest.NewRecorder()
+	handler.Stats(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/bot-stats?window=1h", nil))
+	testutil.AssertEqual(t, w.Code, http.StatusOK)
+	testutil.AssertTrue(t, time.Since(gotSince) < 2*time.Hour, "expected a 1h window")
+
+	for _, window := range []string{"soon", "-1h", "10000h"} {
+		w := httptest.NewRecorder()
+		handler.Stats(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/bot-stats?window="+window, nil))
+		testutil.AssertEqual(t, w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestBotStatsHandler_RepositoryError(t *testing.T) {
+	repo := testutil.NewMockBotStatsRepository()
+	repo.StatsFunc = func(ctx context.Context, since time.Time) ([]domain.BotCommandStats, error) {
+		return nil, errors.New("database error")
+	}
+
+	w := httptest.NewRecorder()
+	NewBotStatsHandler(repo).Stats(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/bot-stats", nil))
+
+	testutil.AssertEqual(t, w.Code, http.StatusInternalServerError)
+}
@@ -2,13 +2,20 @@ package handler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"jobsity-chat/internal/domain"
 	"jobsity-chat/internal/middleware"
+	"jobsity-chat/internal/service"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -16,17 +23,42 @@ import (
 type HubInterface interface {
 	GetConnectedUserCount(chatroomID string) int
 	GetAllConnectedCounts() map[string]int
+	PublishMessage(msg *domain.Message) error
+	PublishRSVP(rsvp *domain.RSVP) error
 }
 
 type ChatServiceInterface interface {
 	CreateChatroom(ctx context.Context, name, createdBy string) (*domain.Chatroom, error)
+	CreateEncryptedChatroom(ctx context.Context, name, createdBy string) (*domain.Chatroom, error)
 	ListChatrooms(ctx context.Context) ([]*domain.Chatroom, error)
-	ListChatroomsPaginated(ctx context.Context, limit int, cursor string) ([]*domain.Chatroom, string, error)
+	ListChatroomsPaginated(ctx context.Context, opts domain.ChatroomListOptions) ([]*domain.Chatroom, string, error)
 	JoinChatroom(ctx context.Context, chatroomID, userID string) error
+	GuestAccess(ctx context.Context, chatroomID string) (bool, error)
 	IsMember(ctx context.Context, chatroomID, userID string) (bool, error)
 	GetMessages(ctx context.Context, chatroomID string, limit int) ([]*domain.Message, error)
 	GetMessagesBefore(ctx context.Context, chatroomID, before string, limit int) ([]*domain.Message, error)
+	GetMessagesPage(ctx context.Context, chatroomID, before string, limit int) ([]*domain.Message, bool, error)
 	SendMessage(ctx context.Context, message *domain.Message) error
+	AddMembers(ctx context.Context, chatroomID, requesterID string, identifiers []string) ([]domain.MemberAddResult, error)
+	GetChatroomSettings(ctx context.Context, chatroomID, userID string) (*domain.ChatroomSettings, error)
+	UpdateChatroomSettings(ctx context.Context, chatroomID, requesterID string, settings *domain.ChatroomSettings) error
+	GetActivity(ctx context.Context, userID string) ([]*domain.RoomActivity, error)
+	MarkRead(ctx context.Context, chatroomID, userID, messageID string) error
+	DeleteChatroom(ctx context.Context, chatroomID, requesterID string) error
+	RestoreChatroom(ctx context.Context, chatroomID, adminID string) error
+	SetMemberKey(ctx context.Context, chatroomID, userID, keyData string) error
+	MemberKeys(ctx context.Context, chatroomID, userID string) ([]*domain.MemberKey, error)
+	ListMembers(ctx context.Context, chatroomID, userID string) ([]*service.MemberView, error)
+	ForwardMessage(ctx context.Context, messageID, userID string, chatroomIDs []string) ([]*domain.Message, error)
+	CreateEvent(ctx context.Context, chatroomID, userID string, event *domain.CalendarEvent) (*domain.Message, error)
+	RSVP(ctx context.Context, messageID, userID, response string) (*domain.RSVP, error)
+	ListRSVPs(ctx context.Context, messageID, userID string) ([]*domain.RSVP, error)
+	GetEvent(ctx context.Context, messageID, userID string) (*domain.Message, error)
+	SetChatroomTags(ctx context.Context, chatroomID, requesterID string, tags []string) ([]string, error)
+	RemoveChatroomTag(ctx context.Context, chatroomID, requesterID, tag string) error
+	ListTags(ctx context.Context) ([]*domain.TagCount, error)
+	StarChatroom(ctx context.Context, chatroomID, userID string, position *int) (int, error)
+	UnstarChatroom(ctx context.Context, chatroomID, userID string) error
 }
 
 type ChatroomHandler struct {
@@ -41,8 +73,41 @@ func NewChatroomHandler(chatService ChatServiceInterface, hub HubInterface) *Cha
 	}
 }
 
+// CreateChatroomRequest creates an end-to-end encrypted chatroom when
+// Encrypted is set. It cannot be changed afterwards.
 type CreateChatroomRequest struct {
-	Name string `json:"name"`
+	Name      string `json:"name"`
+	Encrypted bool   `json:"encrypted"`
+}
+
+// SetMemberKeyRequest carries the caller's key exchange metadata for an
+// encrypted chatroom, opaque to the server
+type SetMemberKeyRequest struct {
+	KeyData string `json:"key_data"`
+}
+
+// MemberKeysResponse lists the key exchange metadata of an encrypted
+// chatroom's members
+type MemberKeysResponse struct {
+	Keys []*domain.MemberKey `json:"keys"`
+}
+
+// MembersResponse lists a chatroom's members with their statuses
+type MembersResponse struct {
+	Members []*service.MemberView `json:"members"`
+}
+
+// AddMembersRequest names users by ID or username
+type AddMembersRequest struct {
+	Users []string `json:"users"`
+}
+
+type AddMembersResponse struct {
+	Added         int                      `json:"added"`
+	AlreadyMember int                      `json:"already_member"`
+	NotFound      int                      `json:"not_found"`
+	Rejected      int                      `json:"rejected"` // deactivated and system users
+	Results       []domain.MemberAddResult `json:"results"`
 }
 
 type ChatroomResponse struct {
@@ -50,40 +115,32 @@ type ChatroomResponse struct {
 	Name      string `json:"name"`
 	CreatedAt string `json:"created_at"`
 	CreatedBy string `json:"created_by"`
+	Encrypted bool   `json:"encrypted"`
 	UserCount int    `json:"user_count"`
+	// LastMessageAt is null for rooms without messages
+	LastMessageAt *string  `json:"last_message_at"`
+	MemberCount   int      `json:"member_count"`
+	Tags          []string `json:"tags"`
+	Starred       bool     `json:"starred"`
+	// StarPosition orders the caller's starred rooms, lowest first; null
+	// for rooms not starred
+	StarPosition *int `json:"star_position"`
 }
 
+// List serves the chatroom directory, optionally filtered by a name
+// substring, tags or starred=true and sorted by sort=newest|active|members.
+// Rooms report whether the caller starred them.
 func (h *ChatroomHandler) List(w http.ResponseWriter, r *http.Request) {
-	cursor := r.URL.Query().Get("cursor")
-	limit := 50
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
-	}
-
-	chatrooms, nextCursor, err := h.chatService.ListChatroomsPaginated(r.Context(), limit, cursor)
+	opts := chatroomsQuery(r)
+	chatrooms, nextCursor, err := h.chatService.ListChatroomsPaginated(r.Context(), opts)
 	if err != nil {
-		http.Error(w, `{"error":"Failed to retrieve chatrooms"}`, http.StatusInternalServerError)
+		listChatroomsError(w, err)
 		return
 	}
 
-	connectedCounts := h.hub.GetAllConnectedCounts()
-
-	response := make([]ChatroomResponse, len(chatrooms))
-	for i, room := range chatrooms {
-		response[i] = ChatroomResponse{
-			ID:        room.ID,
-			Name:      room.Name,
-			CreatedAt: room.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-			CreatedBy: room.CreatedBy,
-			UserCount: connectedCounts[room.ID],
-		}
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	responseData := map[string]any{
-		"chatrooms": response,
+		"chatrooms": h.chatroomResponses(chatrooms),
 	}
 	if nextCursor != "" {
 		responseData["next_cursor"] = nextCursor
@@ -95,6 +152,71 @@ func (h *ChatroomHandler) List(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// chatroomsQuery parses the paging, sort, name, tag and starred filters of a
+// chatroom listing. Tags are given as repeated or comma-separated tag
+// parameters.
+func chatroomsQuery(r *http.Request) domain.ChatroomListOptions {
+	query := r.URL.Query()
+	userID, _ := middleware.GetUserID(r.Context())
+	opts := domain.ChatroomListOptions{
+		Limit:   50,
+		Cursor:  query.Get("cursor"),
+		Sort:    query.Get("sort"),
+		Name:    query.Get("name"),
+		UserID:  userID,
+		Starred: query.Get("starred") == "true",
+	}
+	for _, param := range query["tag"] {
+		for _, tag := range strings.Split(param, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				opts.Tags = append(opts.Tags, tag)
+			}
+		}
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			opts.Limit = l
+		}
+	}
+	return opts
+}
+
+func listChatroomsError(w http.ResponseWriter, err error) {
+	if errors.Is(err, domain.ErrInvalidInput) {
+		http.Error(w, `{"error":"Invalid sort, name or tag filter"}`, http.StatusBadRequest)
+		return
+	}
+	http.Error(w, `{"error":"Failed to retrieve chatrooms"}`, http.StatusInternalServerError)
+}
+
+func (h *ChatroomHandler) chatroomResponses(chatrooms []*domain.Chatroom) []ChatroomResponse {
+	connectedCounts := h.hub.GetAllConnectedCounts()
+
+	response := make([]ChatroomResponse, len(chatrooms))
+	for i, room := range chatrooms {
+		response[i] = ChatroomResponse{
+			ID:           room.ID,
+			Name:         room.Name,
+			CreatedAt:    room.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+			CreatedBy:    room.CreatedBy,
+			Encrypted:    room.Encrypted,
+			UserCount:    connectedCounts[room.ID],
+			MemberCount:  room.MemberCount,
+			Tags:         room.Tags,
+			Starred:      room.Starred,
+			StarPosition: room.StarPosition,
+		}
+		if response[i].Tags == nil {
+			response[i].Tags = []string{}
+		}
+		if room.LastMessageAt != nil {
+			lastMessageAt := room.LastMessageAt.Format("2006-01-02T15:04:05Z07:00")
+			response[i].LastMessageAt = &lastMessageAt
+		}
+	}
+	return response
+}
+
 func (h *ChatroomHandler) Create(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
@@ -108,9 +230,17 @@ func (h *ChatroomHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	chatroom, err := h.chatService.CreateChatroom(r.Context(), req.Name, userID)
+	create := h.chatService.CreateChatroom
+	if req.Encrypted {
+		create = h.chatService.CreateEncryptedChatroom
+	}
+	chatroom, err := create(r.Context(), req.Name, userID)
 	if err != nil {
-		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		status := http.StatusBadRequest
+		if errors.Is(err, domain.ErrQuotaExceeded) {
+			status = quotaStatus(err)
+		}
+		http.Error(w, `{"error":"`+err.Error()+`"}`, status)
 		return
 	}
 
@@ -124,22 +254,62 @@ func (h *ChatroomHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ChatroomHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
+	chatroomID, limit, ok := h.messagesQuery(w, r)
+	if !ok {
+		return
+	}
+
+	var messages []*domain.Message
+	var err error
+	before := r.URL.Query().Get("before")
+	if before != "" {
+		messages, err = h.chatService.GetMessagesBefore(r.Context(), chatroomID, before, limit)
+	} else {
+		messages, err = h.chatService.GetMessages(r.Context(), chatroomID, limit)
+	}
+
+	if err != nil {
+		http.Error(w, `{"error":"Failed to retrieve messages"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if notModified(w, r, messagesETag(chatroomID, before, limit, messages)) {
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"messages": messages,
+	}); err != nil {
+		slog.Error("failed to encode get messages response", slog.String("error", err.Error()))
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// messagesQuery checks that the user may read the chatroom's history and
+// parses the page size. It writes the error response and returns false
+// otherwise.
+func (h *ChatroomHandler) messagesQuery(w http.ResponseWriter, r *http.Request) (string, int, bool) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
 		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
-		return
+		return "", 0, false
 	}
 
 	chatroomID := chi.URLParam(r, "id")
 	if chatroomID == "" {
 		http.Error(w, `{"error":"Chatroom ID required"}`, http.StatusBadRequest)
-		return
+		return "", 0, false
+	}
+
+	if !h.allowGuest(w, r, chatroomID) {
+		return "", 0, false
 	}
 
 	isMember, err := h.chatService.IsMember(r.Context(), chatroomID, userID)
 	if err != nil || !isMember {
 		http.Error(w, `{"error":"Not a member of this chatroom"}`, http.StatusForbidden)
-		return
+		return "", 0, false
 	}
 
 	const (
@@ -160,27 +330,45 @@ func (h *ChatroomHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+	return chatroomID, limit, true
+}
 
-	var messages []*domain.Message
-	before := r.URL.Query().Get("before")
-	if before != "" {
-		messages, err = h.chatService.GetMessagesBefore(r.Context(), chatroomID, before, limit)
-	} else {
-		messages, err = h.chatService.GetMessages(r.Context(), chatroomID, limit)
+// allowGuest checks that the chatroom is public when the caller is a guest.
+// It writes the error response and returns false otherwise.
+func (h *ChatroomHandler) allowGuest(w http.ResponseWriter, r *http.Request, chatroomID string) bool {
+	session, ok := middleware.GetSession(r.Context())
+	if !ok || !session.IsGuest() {
+		return true
 	}
 
-	if err != nil {
-		http.Error(w, `{"error":"Failed to retrieve messages"}`, http.StatusInternalServerError)
-		return
+	_, err := h.chatService.GuestAccess(r.Context(), chatroomID)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, domain.ErrGuestNotAllowed):
+		http.Error(w, `{"error":"Chatroom is not open to guests"}`, http.StatusForbidden)
+	case errors.Is(err, domain.ErrChatroomNotFound):
+		http.Error(w, `{"error":"Chatroom not found"}`, http.StatusNotFound)
+	default:
+		slog.Error("failed to check guest access",
+			slog.String("error", err.Error()),
+			slog.String("chatroom_id", chatroomID))
+		http.Error(w, `{"error":"Failed to check guest access"}`, http.StatusInternalServerError)
 	}
+	return false
+}
 
-	if err := json.NewEncoder(w).Encode(map[string]any{
-		"messages": messages,
-	}); err != nil {
-		slog.Error("failed to encode get messages response", slog.String("error", err.Error()))
-		http.Error(w, "failed to encode response", http.StatusInternalServerError)
-		return
+// notModified sets the ETag of a page of history and answers 304 when the
+// client already has it. Polling clients revalidate with If-None-Match and
+// get a 304 until a new message arrives.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
 	}
+	return false
 }
 
 func (h *ChatroomHandler) Join(w http.ResponseWriter, r *http.Request) {
@@ -196,6 +384,10 @@ func (h *ChatroomHandler) Join(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.allowGuest(w, r, chatroomID) {
+		return
+	}
+
 	if err := h.chatService.JoinChatroom(r.Context(), chatroomID, userID); err != nil {
 		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
 		return
@@ -207,3 +399,472 @@ func (h *ChatroomHandler) Join(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// Members lists the members of a chatroom with the status each one shows
+func (h *ChatroomHandler) Members(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	members, err := h.chatService.ListMembers(r.Context(), chatroomID, userID)
+	if errors.Is(err, domain.ErrNotMember) {
+		http.Error(w, `{"error":"Not a member of this chatroom"}`, http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to list members",
+			slog.String("error", err.Error()),
+			slog.String("chatroom_id", chatroomID))
+		http.Error(w, `{"error":"Failed to list members"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MembersResponse{Members: members})
+}
+
+// ForwardMessageRequest lists the chatrooms to forward a message to
+type ForwardMessageRequest struct {
+	ChatroomIDs []string `json:"chatroom_ids"`
+}
+
+// ForwardMessageResponse carries the message posted in each chatroom
+type ForwardMessageResponse struct {
+	Messages []*domain.Message `json:"messages"`
+}
+
+// Forward posts a copy of a message in other chatrooms the caller is a
+// member of, attributed to its author and linking back to it
+func (h *ChatroomHandler) Forward(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req ForwardMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	messageID := chi.URLParam(r, "id")
+	messages, err := h.chatService.ForwardMessage(r.Context(), messageID, userID, req.ChatroomIDs)
+	// Messages posted before a failure are stored, so they are delivered
+	for _, msg := range messages {
+		if err := h.hub.PublishMessage(msg); err != nil {
+			slog.Warn("failed to broadcast forwarded message",
+				slog.String("error", err.Error()),
+				slog.String("message_id", msg.ID),
+				slog.String("chatroom_id", msg.ChatroomID))
+		}
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidInput):
+			http.Error(w, fmt.Sprintf(`{"error":"Provide between 1 and %d other chatrooms; encrypted messages cannot be forwarded"}`, domain.MaxForwardTargets), http.StatusBadRequest)
+		case errors.Is(err, domain.ErrMessageNotFound):
+			http.Error(w, `{"error":"Message not found"}`, http.StatusNotFound)
+		case errors.Is(err, domain.ErrChatroomNotFound):
+			http.Error(w, `{"error":"Chatroom not found"}`, http.StatusNotFound)
+		case errors.Is(err, domain.ErrNotMember):
+			http.Error(w, `{"error":"Not a member of every target chatroom"}`, http.StatusForbidden)
+		case errors.Is(err, domain.ErrReadOnly):
+			http.Error(w, `{"error":"A target chatroom is read-only"}`, http.StatusForbidden)
+		case errors.Is(err, domain.ErrQuotaExceeded):
+			http.Error(w, `{"error":"`+err.Error()+`"}`, quotaStatus(err))
+		default:
+			slog.Error("failed to forward message",
+				slog.String("error", err.Error()),
+				slog.String("message_id", messageID))
+			http.Error(w, `{"error":"Failed to forward message"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ForwardMessageResponse{Messages: messages})
+}
+
+// AddMembers adds users to a chatroom in bulk, e.g. to migrate a team into
+// it. Owner only. Users that do not exist are reported per identifier
+// instead of failing the request.
+func (h *ChatroomHandler) AddMembers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	if chatroomID == "" {
+		http.Error(w, `{"error":"Chatroom ID required"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req AddMembersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.chatService.AddMembers(r.Context(), chatroomID, userID, req.Users)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrChatroomNotFound):
+			http.Error(w, `{"error":"Chatroom not found"}`, http.StatusNotFound)
+		case errors.Is(err, domain.ErrNotOwner):
+			http.Error(w, `{"error":"Only the chatroom owner can add members"}`, http.StatusForbidden)
+		case errors.Is(err, domain.ErrInvalidInput):
+			http.Error(w, fmt.Sprintf(`{"error":"Provide between 1 and %d users"}`, service.MaxBulkMembers), http.StatusBadRequest)
+		default:
+			slog.Error("failed to add chatroom members",
+				slog.String("error", err.Error()),
+				slog.String("chatroom_id", chatroomID))
+			http.Error(w, `{"error":"Failed to add members"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	resp := AddMembersResponse{Results: results}
+	for _, result := range results {
+		switch result.Status {
+		case domain.MemberAdded:
+			resp.Added++
+		case domain.MemberAlreadyMember:
+			resp.AlreadyMember++
+		case domain.MemberUserNotFound:
+			resp.NotFound++
+		case domain.MemberUserDeactivated, domain.MemberSystemUser:
+			resp.Rejected++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("failed to encode add members response", slog.String("error", err.Error()))
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetSettings returns a chatroom's settings to its members
+func (h *ChatroomHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	if !h.allowGuest(w, r, chatroomID) {
+		return
+	}
+	settings, err := h.chatService.GetChatroomSettings(r.Context(), chatroomID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotMember):
+			http.Error(w, `{"error":"Not a member of this chatroom"}`, http.StatusForbidden)
+		case errors.Is(err, domain.ErrChatroomNotFound):
+			http.Error(w, `{"error":"Chatroom not found"}`, http.StatusNotFound)
+		default:
+			slog.Error("failed to get chatroom settings",
+				slog.String("error", err.Error()),
+				slog.String("chatroom_id", chatroomID))
+			http.Error(w, `{"error":"Failed to get chatroom settings"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(settings.Version))
+	json.NewEncoder(w).Encode(settings)
+}
+
+// UpdateSettings replaces a chatroom's settings (owner only). The version
+// read from GetSettings, sent as If-Match or in the body, makes the update
+// fail with 409 if someone else changed the settings in between.
+func (h *ChatroomHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var settings domain.ChatroomSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	version, ok := expectedVersion(r, settings.Version)
+	if !ok {
+		http.Error(w, `{"error":"Invalid settings version"}`, http.StatusBadRequest)
+		return
+	}
+	settings.Version = version
+
+	chatroomID := chi.URLParam(r, "id")
+	if err := h.chatService.UpdateChatroomSettings(r.Context(), chatroomID, userID, &settings); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrChatroomNotFound):
+			http.Error(w, `{"error":"Chatroom not found"}`, http.StatusNotFound)
+		case errors.Is(err, domain.ErrNotOwner):
+			http.Error(w, `{"error":"Only the chatroom owner can change its settings"}`, http.StatusForbidden)
+		case errors.Is(err, domain.ErrInvalidInput):
+			http.Error(w, fmt.Sprintf(`{"error":"Welcome message must be at most %d characters"}`, service.MaxWelcomeMessageLength), http.StatusBadRequest)
+		case errors.Is(err, domain.ErrPublicEncrypted):
+			http.Error(w, `{"error":"Encrypted chatrooms cannot be opened to guests"}`, http.StatusBadRequest)
+		case errors.Is(err, domain.ErrVersionConflict):
+			http.Error(w, `{"error":"Chatroom settings were changed by someone else; reload them and try again"}`, http.StatusConflict)
+		default:
+			slog.Error("failed to update chatroom settings",
+				slog.String("error", err.Error()),
+				slog.String("chatroom_id", chatroomID))
+			http.Error(w, `{"error":"Failed to update chatroom settings"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(settings.Version))
+	json.NewEncoder(w).Encode(settings)
+}
+
+// ActivityResponse lists the unread activity of the caller's chatrooms
+type ActivityResponse struct {
+	Rooms []*domain.RoomActivity `json:"rooms"`
+}
+
+// MarkReadRequest optionally names the last message read
+type MarkReadRequest struct {
+	MessageID string `json:"message_id"`
+}
+
+// Activity returns unread counts, mention counts and the latest message of
+// every chatroom the caller belongs to, for the room list sidebar
+func (h *ChatroomHandler) Activity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	rooms, err := h.chatService.GetActivity(r.Context(), userID)
+	if err != nil {
+		slog.Error("failed to get activity",
+			slog.String("error", err.Error()),
+			slog.String("user_id", userID))
+		http.Error(w, `{"error":"Failed to get activity"}`, http.StatusInternalServerError)
+		return
+	}
+	if rooms == nil {
+		rooms = []*domain.RoomActivity{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	json.NewEncoder(w).Encode(ActivityResponse{Rooms: rooms})
+}
+
+// MarkRead marks a chatroom read up to the given message, or up to its latest
+// message when the body is empty
+func (h *ChatroomHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req MarkReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	if err := h.chatService.MarkRead(r.Context(), chatroomID, userID, req.MessageID); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotMember):
+			http.Error(w, `{"error":"Not a member of this chatroom"}`, http.StatusForbidden)
+		case errors.Is(err, domain.ErrInvalidInput):
+			http.Error(w, `{"error":"Invalid message ID"}`, http.StatusBadRequest)
+		default:
+			slog.Error("failed to mark chatroom read",
+				slog.String("error", err.Error()),
+				slog.String("chatroom_id", chatroomID))
+			http.Error(w, `{"error":"Failed to mark chatroom read"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Delete deletes a chatroom (owner only). Administrators can restore it until
+// it is purged.
+func (h *ChatroomHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	if err := h.chatService.DeleteChatroom(r.Context(), chatroomID, userID); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrChatroomNotFound):
+			http.Error(w, `{"error":"Chatroom not found"}`, http.StatusNotFound)
+		case errors.Is(err, domain.ErrNotOwner):
+			http.Error(w, `{"error":"Only the chatroom owner can delete it"}`, http.StatusForbidden)
+		default:
+			slog.Error("failed to delete chatroom",
+				slog.String("error", err.Error()),
+				slog.String("chatroom_id", chatroomID))
+			http.Error(w, `{"error":"Failed to delete chatroom"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Restore undeletes a chatroom that has not been purged yet. The route must
+// be guarded by middleware.RequireAdmin.
+func (h *ChatroomHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	if err := h.chatService.RestoreChatroom(r.Context(), chatroomID, userID); err != nil {
+		if errors.Is(err, domain.ErrChatroomNotFound) {
+			http.Error(w, `{"error":"No deleted chatroom with this ID"}`, http.StatusNotFound)
+			return
+		}
+		slog.Error("failed to restore chatroom",
+			slog.String("error", err.Error()),
+			slog.String("chatroom_id", chatroomID))
+		http.Error(w, `{"error":"Failed to restore chatroom"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// GetKeys lists the key exchange metadata published by the members of an
+// encrypted chatroom, so clients can encrypt the room key for each of them
+func (h *ChatroomHandler) GetKeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	keys, err := h.chatService.MemberKeys(r.Context(), chatroomID, userID)
+	if err != nil {
+		memberKeysError(w, err, chatroomID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(MemberKeysResponse{Keys: keys})
+}
+
+// SetKey publishes the caller's key exchange metadata for an encrypted
+// chatroom, replacing what they published before
+func (h *ChatroomHandler) SetKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req SetMemberKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	if err := h.chatService.SetMemberKey(r.Context(), chatroomID, userID, req.KeyData); err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			http.Error(w, fmt.Sprintf(`{"error":"Key data must be 1 to %d bytes"}`, service.MaxMemberKeyLength), http.StatusBadRequest)
+			return
+		}
+		memberKeysError(w, err, chatroomID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func memberKeysError(w http.ResponseWriter, err error, chatroomID string) {
+	switch {
+	case errors.Is(err, domain.ErrChatroomNotFound):
+		http.Error(w, `{"error":"Chatroom not found"}`, http.StatusNotFound)
+	case errors.Is(err, domain.ErrNotMember):
+		http.Error(w, `{"error":"Not a member of this chatroom"}`, http.StatusForbidden)
+	case errors.Is(err, domain.ErrNotEncrypted):
+		http.Error(w, `{"error":"Chatroom is not end-to-end encrypted"}`, http.StatusBadRequest)
+	default:
+		slog.Error("failed to access chatroom member keys",
+			slog.String("error", err.Error()),
+			slog.String("chatroom_id", chatroomID))
+		http.Error(w, `{"error":"Failed to access member keys"}`, http.StatusInternalServerError)
+	}
+}
+
+// quotaStatus maps a quota error to 429 for rate-like quotas that reset over
+// time and 403 for the others
+func quotaStatus(err error) int {
+	var quotaErr *domain.QuotaExceededError
+	if errors.As(err, &quotaErr) && quotaErr.Quota == domain.QuotaMessagesPerRoomPerDay {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusForbidden
+}
+
+// messagesETag identifies a page of message history by its query and the
+// newest message in it. Messages are immutable, so a page only changes when a
+// message is added or the chatroom starts or stops rendering Markdown.
+func messagesETag(chatroomID, before string, limit int, messages []*domain.Message) string {
+	latest, rendered := "", false
+	if len(messages) > 0 {
+		latest = messages[len(messages)-1].ID
+		rendered = messages[len(messages)-1].HTML != ""
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		chatroomID, before, strconv.Itoa(limit), strconv.Itoa(len(messages)), latest, strconv.FormatBool(rendered),
+	}, "\x00")))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using the
+// weak comparison RFC 9110 prescribes for If-None-Match (compressed responses
+// carry weak ETags).
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for candidate := range strings.SplitSeq(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -6,24 +6,51 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"jobsity-chat/internal/domain"
 	"jobsity-chat/internal/middleware"
+	"jobsity-chat/internal/service"
+	"jobsity-chat/internal/testutil"
 
 	"github.com/go-chi/chi/v5"
 )
 
 // mockChatService implements service.ChatService interface for testing
 type mockChatService struct {
-	createChatroomFunc    func(ctx context.Context, name, createdBy string) (*domain.Chatroom, error)
-	listChatroomsFunc     func(ctx context.Context) ([]*domain.Chatroom, error)
-	joinChatroomFunc      func(ctx context.Context, chatroomID, userID string) error
-	isMemberFunc          func(ctx context.Context, chatroomID, userID string) (bool, error)
-	getMessagesFunc       func(ctx context.Context, chatroomID string, limit int) ([]*domain.Message, error)
-	getMessagesBeforeFunc func(ctx context.Context, chatroomID, before string, limit int) ([]*domain.Message, error)
+	createChatroomFunc         func(ctx context.Context, name, createdBy string) (*domain.Chatroom, error)
+	createEncryptedFunc        func(ctx context.Context, name, createdBy string) (*domain.Chatroom, error)
+	listChatroomsFunc          func(ctx context.Context) ([]*domain.Chatroom, error)
+	listChatroomsPaginatedFunc func(ctx context.Context, opts domain.ChatroomListOptions) ([]*domain.Chatroom, string, error)
+	joinChatroomFunc           func(ctx context.Context, chatroomID, userID string) error
+	guestAccessFunc            func(ctx context.Context, chatroomID string) (bool, error)
+	isMemberFunc               func(ctx context.Context, chatroomID, userID string) (bool, error)
+	getMessagesFunc            func(ctx context.Context, chatroomID string, limit int) ([]*domain.Message, error)
+	getMessagesBeforeFunc      func(ctx context.Context, chatroomID, before string, limit int) ([]*domain.Message, error)
+	getMessagesPageFunc        func(ctx context.Context, chatroomID, before string, limit int) ([]*domain.Message, bool, error)
+	addMembersFunc             func(ctx context.Context, chatroomID, requesterID string, identifiers []string) ([]domain.MemberAddResult, error)
+	getSettingsFunc            func(ctx context.Context, chatroomID, userID string) (*domain.ChatroomSettings, error)
+	updateSettingsFunc         func(ctx context.Context, chatroomID, requesterID string, settings *domain.ChatroomSettings) error
+	getActivityFunc            func(ctx context.Context, userID string) ([]*domain.RoomActivity, error)
+	markReadFunc               func(ctx context.Context, chatroomID, userID, messageID string) error
+	deleteChatroomFunc         func(ctx context.Context, chatroomID, requesterID string) error
+	restoreChatroomFunc        func(ctx context.Context, chatroomID, adminID string) error
+	setMemberKeyFunc           func(ctx context.Context, chatroomID, userID, keyData string) error
+	memberKeysFunc             func(ctx context.Context, chatroomID, userID string) ([]*domain.MemberKey, error)
+	listMembersFunc            func(ctx context.Context, chatroomID, userID string) ([]*service.MemberView, error)
+	forwardMessageFunc         func(ctx context.Context, messageID, userID string, chatroomIDs []string) ([]*domain.Message, error)
+	createEventFunc            func(ctx context.Context, chatroomID, userID string, event *domain.CalendarEvent) (*domain.Message, error)
+	rsvpFunc                   func(ctx context.Context, messageID, userID, response string) (*domain.RSVP, error)
+	listRSVPsFunc              func(ctx context.Context, messageID, userID string) ([]*domain.RSVP, error)
+	getEventFunc               func(ctx context.Context, messageID, userID string) (*domain.Message, error)
+	setChatroomTagsFunc        func(ctx context.Context, chatroomID, requesterID string, tags []string) ([]string, error)
+	removeChatroomTagFunc      func(ctx context.Context, chatroomID, requesterID, tag string) error
+	listTagsFunc               func(ctx context.Context) ([]*domain.TagCount, error)
+	starChatroomFunc           func(ctx context.Context, chatroomID, userID string, position *int) (int, error)
+	unstarChatroomFunc         func(ctx context.Context, chatroomID, userID string) error
 }
 
 func (m *mockChatService) CreateChatroom(ctx context.Context, name, createdBy string) (*domain.Chatroom, error) {
@@ -33,6 +60,13 @@ func (m *mockChatService) CreateChatroom(ctx context.Context, name, createdBy st
 	return nil, errors.New("not implemented")
 }
 
+func (m *mockChatService) CreateEncryptedChatroom(ctx context.Context, name, createdBy string) (*domain.Chatroom, error) {
+	if m.createEncryptedFunc != nil {
+		return m.createEncryptedFunc(ctx, name, createdBy)
+	}
+	return nil, errors.New("not implemented")
+}
+
 func (m *mockChatService) ListChatrooms(ctx context.Context) ([]*domain.Chatroom, error) {
 	if m.listChatroomsFunc != nil {
 		return m.listChatroomsFunc(ctx)
@@ -40,7 +74,10 @@ func (m *mockChatService) ListChatrooms(ctx context.Context) ([]*domain.Chatroom
 	return nil, errors.New("not implemented")
 }
 
-func (m *mockChatService) ListChatroomsPaginated(ctx context.Context, limit int, cursor string) ([]*domain.Chatroom, string, error) {
+func (m *mockChatService) ListChatroomsPaginated(ctx context.Context, opts domain.ChatroomListOptions) ([]*domain.Chatroom, string, error) {
+	if m.listChatroomsPaginatedFunc != nil {
+		return m.listChatroomsPaginatedFunc(ctx, opts)
+	}
 	if m.listChatroomsFunc != nil {
 		chatrooms, err := m.listChatroomsFunc(ctx)
 		return chatrooms, "", err
@@ -55,6 +92,13 @@ func (m *mockChatService) JoinChatroom(ctx context.Context, chatroomID, userID s
 	return errors.New("not implemented")
 }
 
+func (m *mockChatService) GuestAccess(ctx context.Context, chatroomID string) (bool, error) {
+	if m.guestAccessFunc != nil {
+		return m.guestAccessFunc(ctx, chatroomID)
+	}
+	return false, errors.New("not implemented")
+}
+
 func (m *mockChatService) IsMember(ctx context.Context, chatroomID, userID string) (bool, error) {
 	if m.isMemberFunc != nil {
 		return m.isMemberFunc(ctx, chatroomID, userID)
@@ -76,6 +120,153 @@ func (m *mockChatService) GetMessagesBefore(ctx context.Context, chatroomID, bef
 	return nil, errors.New("not implemented")
 }
 
+func (m *mockChatService) GetMessagesPage(ctx context.Context, chatroomID, before string, limit int) ([]*domain.Message, bool, error) {
+	if m.getMessagesPageFunc != nil {
+		return m.getMessagesPageFunc(ctx, chatroomID, before, limit)
+	}
+	return nil, false, errors.New("not implemented")
+}
+
+func (m *mockChatService) AddMembers(ctx context.Context, chatroomID, requesterID string, identifiers []string) ([]domain.MemberAddResult, error) {
+	if m.addMembersFunc != nil {
+		return m.addMembersFunc(ctx, chatroomID, requesterID, identifiers)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockChatService) GetChatroomSettings(ctx context.Context, chatroomID, userID string) (*domain.ChatroomSettings, error) {
+	if m.getSettingsFunc != nil {
+		return m.getSettingsFunc(ctx, chatroomID, userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockChatService) UpdateChatroomSettings(ctx context.Context, chatroomID, requesterID string, settings *domain.ChatroomSettings) error {
+	if m.updateSettingsFunc != nil {
+		return m.updateSettingsFunc(ctx, chatroomID, requesterID, settings)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *mockChatService) GetActivity(ctx context.Context, userID string) ([]*domain.RoomActivity, error) {
+	if m.getActivityFunc != nil {
+		return m.getActivityFunc(ctx, userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockChatService) MarkRead(ctx context.Context, chatroomID, userID, messageID string) error {
+	if m.markReadFunc != nil {
+		return m.markReadFunc(ctx, chatroomID, userID, messageID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *mockChatService) DeleteChatroom(ctx context.Context, chatroomID, requesterID string) error {
+	if m.deleteChatroomFunc != nil {
+		return m.deleteChatroomFunc(ctx, chatroomID, requesterID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *mockChatService) RestoreChatroom(ctx context.Context, chatroomID, adminID string) error {
+	if m.restoreChatroomFunc != nil {
+		return m.restoreChatroomFunc(ctx, chatroomID, adminID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *mockChatService) SetMemberKey(ctx context.Context, chatroomID, userID, keyData string) error {
+	if m.setMemberKeyFunc != nil {
+		return m.setMemberKeyFunc(ctx, chatroomID, userID, keyData)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *mockChatService) MemberKeys(ctx context.Context, chatroomID, userID string) ([]*domain.MemberKey, error) {
+	if m.memberKeysFunc != nil {
+		return m.memberKeysFunc(ctx, chatroomID, userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockChatService) ListMembers(ctx context.Context, chatroomID, userID string) ([]*service.MemberView, error) {
+	if m.listMembersFunc != nil {
+		return m.listMembersFunc(ctx, chatroomID, userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockChatService) ForwardMessage(ctx context.Context, messageID, userID string, chatroomIDs []string) ([]*domain.Message, error) {
+	if m.forwardMessageFunc != nil {
+		return m.forwardMessageFunc(ctx, messageID, userID, chatroomIDs)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockChatService) CreateEvent(ctx context.Context, chatroomID, userID string, event *domain.CalendarEvent) (*domain.Message, error) {
+	if m.createEventFunc != nil {
+		return m.createEventFunc(ctx, chatroomID, userID, event)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockChatService) RSVP(ctx context.Context, messageID, userID, response string) (*domain.RSVP, error) {
+	if m.rsvpFunc != nil {
+		return m.rsvpFunc(ctx, messageID, userID, response)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockChatService) ListRSVPs(ctx context.Context, messageID, userID string) ([]*domain.RSVP, error) {
+	if m.listRSVPsFunc != nil {
+		return m.listRSVPsFunc(ctx, messageID, userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockChatService) GetEvent(ctx context.Context, messageID, userID string) (*domain.Message, error) {
+	if m.getEventFunc != nil {
+		return m.getEventFunc(ctx, messageID, userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockChatService) SetChatroomTags(ctx context.Context, chatroomID, requesterID string, tags []string) ([]string, error) {
+	if m.setChatroomTagsFunc != nil {
+		return m.setChatroomTagsFunc(ctx, chatroomID, requesterID, tags)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockChatService) RemoveChatroomTag(ctx context.Context, chatroomID, requesterID, tag string) error {
+	if m.removeChatroomTagFunc != nil {
+		return m.removeChatroomTagFunc(ctx, chatroomID, requesterID, tag)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *mockChatService) ListTags(ctx context.Context) ([]*domain.TagCount, error) {
+	if m.listTagsFunc != nil {
+		return m.listTagsFunc(ctx)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockChatService) StarChatroom(ctx context.Context, chatroomID, userID string, position *int) (int, error) {
+	if m.starChatroomFunc != nil {
+		return m.starChatroomFunc(ctx, chatroomID, userID, position)
+	}
+	return 0, errors.New("not implemented")
+}
+
+func (m *mockChatService) UnstarChatroom(ctx context.Context, chatroomID, userID string) error {
+	if m.unstarChatroomFunc != nil {
+		return m.unstarChatroomFunc(ctx, chatroomID, userID)
+	}
+	return errors.New("not implemented")
+}
+
 func (m *mockChatService) SendMessage(ctx context.Context, message *domain.Message) error {
 	return errors.New("not implemented")
 }
@@ -83,6 +274,8 @@ func (m *mockChatService) SendMessage(ctx context.Context, message *domain.Messa
 // mockHub implements HubInterface for testing
 type mockHub struct {
 	connectedCounts map[string]int
+	published       []*domain.Message
+	rsvps           []*domain.RSVP
 }
 
 func (m *mockHub) GetConnectedUserCount(chatroomID string) int {
@@ -93,6 +286,16 @@ func (m *mockHub) GetAllConnectedCounts() map[string]int {
 	return m.connectedCounts
 }
 
+func (m *mockHub) PublishMessage(msg *domain.Message) error {
+	m.published = append(m.published, msg)
+	return nil
+}
+
+func (m *mockHub) PublishRSVP(rsvp *domain.RSVP) error {
+	m.rsvps = append(m.rsvps, rsvp)
+	return nil
+}
+
 func TestChatroomHandler_List_Success(t *testing.T) {
 	now := time.Now()
 
@@ -172,6 +375,71 @@ func TestChatroomHandler_List_ServiceError(t *testing.T) {
 	}
 }
 
+func TestChatroomHandler_List_SortAndFilter(t *testing.T) {
+	lastMessageAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	starPosition := 3
+	var got domain.ChatroomListOptions
+	chatService := &mockChatService{
+		listChatroomsPaginatedFunc: func(ctx context.Context, opts domain.ChatroomListOptions) ([]*domain.Chatroom, string, error) {
+			got = opts
+			if opts.Sort == "popular" {
+				return nil, "", domain.ErrInvalidInput
+			}
+			return []*domain.Chatroom{
+				{ID: "room-1", Name: "Stocks", LastMessageAt: &lastMessageAt, MemberCount: 12, Tags: []string{"finance"}, Starred: true, StarPosition: &starPosition},
+				{ID: "room-2", Name: "Stock tips"},
+			}, "room-2", nil
+		},
+	}
+	handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/chatrooms?sort=active&name=stock&limit=2&cursor=room-0&tag=finance,news&tag=Daily&starred=true", nil)
+	req = req.WithContext(middleware.WithUserID(req.Context(), "user-1"))
+	w := httptest.NewRecorder()
+	handler.List(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	want := domain.ChatroomListOptions{Limit: 2, Cursor: "room-0", Sort: "active", Name: "stock", Tags: []string{"finance", "news", "Daily"}, UserID: "user-1", Starred: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected options %+v, got %+v", want, got)
+	}
+
+	var resp struct {
+		Chatrooms  []ChatroomResponse `json:"chatrooms"`
+		NextCursor string             `json:"next_cursor"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.NextCursor != "room-2" || len(resp.Chatrooms) != 2 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if resp.Chatrooms[0].LastMessageAt == nil || *resp.Chatrooms[0].LastMessageAt != "2025-03-01T12:00:00Z" || resp.Chatrooms[0].MemberCount != 12 {
+		t.Errorf("unexpected room %+v", resp.Chatrooms[0])
+	}
+	if resp.Chatrooms[1].LastMessageAt != nil {
+		t.Errorf("expected a null last_message_at for a room without messages")
+	}
+	if !reflect.DeepEqual(resp.Chatrooms[0].Tags, []string{"finance"}) || resp.Chatrooms[1].Tags == nil {
+		t.Errorf("expected tags, and an empty list for an untagged room, got %v and %v", resp.Chatrooms[0].Tags, resp.Chatrooms[1].Tags)
+	}
+	if !resp.Chatrooms[0].Starred || resp.Chatrooms[0].StarPosition == nil || *resp.Chatrooms[0].StarPosition != 3 {
+		t.Errorf("expected room-1 starred at position 3, got %+v", resp.Chatrooms[0])
+	}
+	if resp.Chatrooms[1].Starred || resp.Chatrooms[1].StarPosition != nil {
+		t.Errorf("expected room-2 not starred, got %+v", resp.Chatrooms[1])
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/chatrooms?sort=popular", nil)
+	w = httptest.NewRecorder()
+	handler.List(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an unknown sort, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
 func TestChatroomHandler_Create_Success(t *testing.T) {
 	now := time.Now()
 
@@ -281,6 +549,39 @@ func TestChatroomHandler_Create_EmptyName(t *testing.T) {
 	}
 }
 
+func TestChatroomHandler_Create_QuotaExceeded(t *testing.T) {
+	chatService := &mockChatService{
+		createChatroomFunc: func(ctx context.Context, name, createdBy string) (*domain.Chatroom, error) {
+			return nil, &domain.QuotaExceededError{Quota: domain.QuotaRoomsPerUser, Limit: 3}
+		},
+	}
+
+	hub := &mockHub{connectedCounts: make(map[string]int)}
+	handler := NewChatroomHandler(chatService, hub)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chatrooms", strings.NewReader(`{"name":"General"}`))
+	req = req.WithContext(middleware.WithUserID(req.Context(), "user-123"))
+	w := httptest.NewRecorder()
+
+	handler.Create(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "rooms_per_user quota of 3 exceeded") {
+		t.Errorf("unexpected body: %s", w.Body.String())
+	}
+}
+
+func TestQuotaStatus(t *testing.T) {
+	if got := quotaStatus(&domain.QuotaExceededError{Quota: domain.QuotaMessagesPerRoomPerDay}); got != http.StatusTooManyRequests {
+		t.Errorf("messages quota: expected %d, got %d", http.StatusTooManyRequests, got)
+	}
+	if got := quotaStatus(&domain.QuotaExceededError{Quota: domain.QuotaRoomsPerUser}); got != http.StatusForbidden {
+		t.Errorf("rooms quota: expected %d, got %d", http.StatusForbidden, got)
+	}
+}
+
 func TestChatroomHandler_GetMessages_Success(t *testing.T) {
 	now := time.Now()
 
@@ -343,6 +644,58 @@ func TestChatroomHandler_GetMessages_Success(t *testing.T) {
 	}
 }
 
+func TestChatroomHandler_GetMessages_ETag(t *testing.T) {
+	messages := []*domain.Message{
+		{ID: "msg-1", ChatroomID: "room-1", Username: "alice", Content: "Hello"},
+	}
+
+	chatService := &mockChatService{
+		isMemberFunc: func(ctx context.Context, chatroomID, userID string) (bool, error) {
+			return true, nil
+		},
+		getMessagesFunc: func(ctx context.Context, chatroomID string, limit int) ([]*domain.Message, error) {
+			return messages, nil
+		},
+	}
+	handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/chatrooms/room-1/messages", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "room-1")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		req = req.WithContext(middleware.WithUserID(req.Context(), "user-123"))
+
+		w := httptest.NewRecorder()
+		handler.GetMessages(w, req)
+		return w
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected 200 with an ETag, got %d %q", first.Code, etag)
+	}
+
+	for _, ifNoneMatch := range []string{etag, "W/" + etag, `"other", ` + etag} {
+		if w := get(ifNoneMatch); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: expected an empty 304, got %d", ifNoneMatch, w.Code)
+		}
+	}
+
+	messages = append(messages, &domain.Message{ID: "msg-2", ChatroomID: "room-1", Username: "bob", Content: "Hi"})
+	w := get(etag)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 after a new message, got %d", w.Code)
+	}
+	if w.Header().Get("ETag") == etag {
+		t.Error("expected the ETag to change with a new message")
+	}
+}
+
 func TestChatroomHandler_GetMessages_WithBefore(t *testing.T) {
 	now := time.Now()
 
@@ -586,3 +939,550 @@ func TestChatroomHandler_Join_ChatroomNotFound(t *testing.T) {
 		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
 	}
 }
+
+func TestChatroomHandler_Join_Guest(t *testing.T) {
+	tests := []struct {
+		name       string
+		accessErr  error
+		wantStatus int
+		wantJoined bool
+	}{
+		{name: "public chatroom", wantStatus: http.StatusOK, wantJoined: true},
+		{name: "private chatroom", accessErr: domain.ErrGuestNotAllowed, wantStatus: http.StatusForbidden},
+		{name: "missing chatroom", accessErr: domain.ErrChatroomNotFound, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			joined := false
+			chatService := &mockChatService{
+				guestAccessFunc: func(ctx context.Context, chatroomID string) (bool, error) {
+					return false, tt.accessErr
+				},
+				joinChatroomFunc: func(ctx context.Context, chatroomID, userID string) error {
+					joined = true
+					return nil
+				},
+			}
+			handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/chatrooms/room-1/join", nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "room-1")
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			ctx = middleware.WithUserID(ctx, "guest-1")
+			ctx = middleware.WithSession(ctx, &domain.Session{UserID: "guest-1", Token: domain.GuestTokenPrefix + "token"})
+			w := httptest.NewRecorder()
+
+			handler.Join(w, req.WithContext(ctx))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d, body: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if joined != tt.wantJoined {
+				t.Errorf("expected joined %v, got %v", tt.wantJoined, joined)
+			}
+		})
+	}
+}
+
+func TestChatroomHandler_AddMembers(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		serviceErr     error
+		expectedStatus int
+	}{
+		{"success", `{"users":["alice","user-2","ghost"]}`, nil, http.StatusOK},
+		{"invalid_body", `{"users":`, nil, http.StatusBadRequest},
+		{"invalid_input", `{"users":[]}`, domain.ErrInvalidInput, http.StatusBadRequest},
+		{"not_owner", `{"users":["alice"]}`, domain.ErrNotOwner, http.StatusForbidden},
+		{"chatroom_not_found", `{"users":["alice"]}`, domain.ErrChatroomNotFound, http.StatusNotFound},
+		{"service_error", `{"users":["alice"]}`, errors.New("database error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatService := &mockChatService{
+				addMembersFunc: func(ctx context.Context, chatroomID, requesterID string, identifiers []string) ([]domain.MemberAddResult, error) {
+					if tt.serviceErr != nil {
+						return nil, tt.serviceErr
+					}
+					return []domain.MemberAddResult{
+						{Identifier: "alice", UserID: "user-1", Status: domain.MemberAdded},
+						{Identifier: "user-2", UserID: "user-2", Status: domain.MemberAlreadyMember},
+						{Identifier: "ghost", Status: domain.MemberUserNotFound},
+						{Identifier: "StockBot", UserID: "bot-1", Status: domain.MemberSystemUser},
+					}, nil
+				},
+			}
+			handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/chatrooms/room-1/members", strings.NewReader(tt.body))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "room-1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req = req.WithContext(middleware.WithUserID(req.Context(), "owner-1"))
+			w := httptest.NewRecorder()
+
+			handler.AddMembers(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d, body: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp AddMembersResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Added != 1 || resp.AlreadyMember != 1 || resp.NotFound != 1 || resp.Rejected != 1 {
+				t.Errorf("unexpected counts: %+v", resp)
+			}
+			if len(resp.Results) != 4 {
+				t.Errorf("expected 4 results, got %d", len(resp.Results))
+			}
+		})
+	}
+}
+
+func TestChatroomHandler_Settings(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		serviceErr     error
+		expectedStatus int
+	}{
+		{"get", http.MethodGet, "", nil, http.StatusOK},
+		{"get_not_member", http.MethodGet, "", domain.ErrNotMember, http.StatusForbidden},
+		{"update", http.MethodPut, `{"welcome_message":"Hi!"}`, nil, http.StatusOK},
+		{"update_invalid_body", http.MethodPut, `{`, nil, http.StatusBadRequest},
+		{"update_not_owner", http.MethodPut, `{"welcome_message":"Hi!"}`, domain.ErrNotOwner, http.StatusForbidden},
+		{"update_too_long", http.MethodPut, `{"welcome_message":"Hi!"}`, domain.ErrInvalidInput, http.StatusBadRequest},
+		{"update_not_found", http.MethodPut, `{"welcome_message":"Hi!"}`, domain.ErrChatroomNotFound, http.StatusNotFound},
+		{"update_stale_version", http.MethodPut, `{"welcome_message":"Hi!","version":1}`, domain.ErrVersionConflict, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatService := &mockChatService{
+				getSettingsFunc: func(ctx context.Context, chatroomID, userID string) (*domain.ChatroomSettings, error) {
+					if tt.serviceErr != nil {
+						return nil, tt.serviceErr
+					}
+					return &domain.ChatroomSettings{WelcomeMessage: "Hi!"}, nil
+				},
+				updateSettingsFunc: func(ctx context.Context, chatroomID, requesterID string, settings *domain.ChatroomSettings) error {
+					return tt.serviceErr
+				},
+			}
+			handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+			req := httptest.NewRequest(tt.method, "/api/v1/chatrooms/room-1/settings", strings.NewReader(tt.body))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "room-1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req = req.WithContext(middleware.WithUserID(req.Context(), "owner-1"))
+			w := httptest.NewRecorder()
+
+			if tt.method == http.MethodGet {
+				handler.GetSettings(w, req)
+			} else {
+				handler.UpdateSettings(w, req)
+			}
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d, body: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var settings domain.ChatroomSettings
+			if err := json.NewDecoder(w.Body).Decode(&settings); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if settings.WelcomeMessage != "Hi!" {
+				t.Errorf("expected welcome message %q, got %q", "Hi!", settings.WelcomeMessage)
+			}
+		})
+	}
+}
+
+func TestChatroomHandler_DeleteAndRestore(t *testing.T) {
+	tests := []struct {
+		name           string
+		restore        bool
+		serviceErr     error
+		expectedStatus int
+	}{
+		{"delete", false, nil, http.StatusNoContent},
+		{"delete_not_owner", false, domain.ErrNotOwner, http.StatusForbidden},
+		{"delete_not_found", false, domain.ErrChatroomNotFound, http.StatusNotFound},
+		{"delete_error", false, errors.New("database error"), http.StatusInternalServerError},
+		{"restore", true, nil, http.StatusOK},
+		{"restore_not_deleted", true, domain.ErrChatroomNotFound, http.StatusNotFound},
+		{"restore_error", true, errors.New("database error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatService := &mockChatService{
+				deleteChatroomFunc: func(ctx context.Context, chatroomID, requesterID string) error {
+					return tt.serviceErr
+				},
+				restoreChatroomFunc: func(ctx context.Context, chatroomID, adminID string) error {
+					return tt.serviceErr
+				},
+			}
+			handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/chatrooms/room-1", nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "room-1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req = req.WithContext(middleware.WithUserID(req.Context(), "owner-1"))
+			w := httptest.NewRecorder()
+
+			if tt.restore {
+				handler.Restore(w, req)
+			} else {
+				handler.Delete(w, req)
+			}
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d, body: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestChatroomHandler_CreateEncrypted(t *testing.T) {
+	chatService := &mockChatService{
+		createEncryptedFunc: func(ctx context.Context, name, createdBy string) (*domain.Chatroom, error) {
+			return &domain.Chatroom{ID: "room-1", Name: name, CreatedBy: createdBy, Encrypted: true}, nil
+		},
+	}
+	handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chatrooms", strings.NewReader(`{"name":"Secret","encrypted":true}`))
+	req = req.WithContext(middleware.WithUserID(req.Context(), "owner-1"))
+	w := httptest.NewRecorder()
+	handler.Create(w, req)
+
+	testutil.AssertStatusCode(t, w, http.StatusCreated)
+	var chatroom domain.Chatroom
+	testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&chatroom))
+	testutil.AssertTrue(t, chatroom.Encrypted, "chatroom should be encrypted")
+}
+
+func TestChatroomHandler_MemberKeys(t *testing.T) {
+	tests := []struct {
+		name           string
+		set            bool
+		body           string
+		serviceErr     error
+		expectedStatus int
+	}{
+		{"get", false, "", nil, http.StatusOK},
+		{"get_not_member", false, "", domain.ErrNotMember, http.StatusForbidden},
+		{"get_not_encrypted", false, "", domain.ErrNotEncrypted, http.StatusBadRequest},
+		{"get_not_found", false, "", domain.ErrChatroomNotFound, http.StatusNotFound},
+		{"get_error", false, "", errors.New("database error"), http.StatusInternalServerError},
+		{"set", true, `{"key_data":"pk"}`, nil, http.StatusNoContent},
+		{"set_invalid_body", true, `{`, nil, http.StatusBadRequest},
+		{"set_invalid_key", true, `{"key_data":""}`, domain.ErrInvalidInput, http.StatusBadRequest},
+		{"set_not_member", true, `{"key_data":"pk"}`, domain.ErrNotMember, http.StatusForbidden},
+		{"set_not_encrypted", true, `{"key_data":"pk"}`, domain.ErrNotEncrypted, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatService := &mockChatService{
+				memberKeysFunc: func(ctx context.Context, chatroomID, userID string) ([]*domain.MemberKey, error) {
+					if tt.serviceErr != nil {
+						return nil, tt.serviceErr
+					}
+					return []*domain.MemberKey{{UserID: userID, Username: "alice", KeyData: "pk"}}, nil
+				},
+				setMemberKeyFunc: func(ctx context.Context, chatroomID, userID, keyData string) error {
+					return tt.serviceErr
+				},
+			}
+			handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/chatrooms/room-1/keys", strings.NewReader(tt.body))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "room-1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req = req.WithContext(middleware.WithUserID(req.Context(), "user-1"))
+			w := httptest.NewRecorder()
+
+			if tt.set {
+				handler.SetKey(w, req)
+			} else {
+				handler.GetKeys(w, req)
+			}
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d, body: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus == http.StatusOK {
+				var resp MemberKeysResponse
+				testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				testutil.AssertLen(t, resp.Keys, 1)
+				testutil.AssertEqual(t, resp.Keys[0].KeyData, "pk")
+			}
+		})
+	}
+}
+
+func TestChatroomHandler_Members(t *testing.T) {
+	tests := []struct {
+		name           string
+		serviceErr     error
+		expectedStatus int
+	}{
+		{"success", nil, http.StatusOK},
+		{"not_member", domain.ErrNotMember, http.StatusForbidden},
+		{"error", errors.New("database error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatService := &mockChatService{
+				listMembersFunc: func(ctx context.Context, chatroomID, userID string) ([]*service.MemberView, error) {
+					if tt.serviceErr != nil {
+						return nil, tt.serviceErr
+					}
+					return []*service.MemberView{{ID: userID, Username: "alice", Status: domain.StatusDND, StatusText: "Focusing"}}, nil
+				},
+			}
+			handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/chatrooms/room-1/members", nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "room-1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req = req.WithContext(middleware.WithUserID(req.Context(), "user-1"))
+			w := httptest.NewRecorder()
+
+			handler.Members(w, req)
+
+			testutil.AssertStatusCode(t, w, tt.expectedStatus)
+			if tt.expectedStatus == http.StatusOK {
+				var resp MembersResponse
+				testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				testutil.AssertLen(t, resp.Members, 1)
+				testutil.AssertEqual(t, resp.Members[0].Status, domain.StatusDND)
+				testutil.AssertEqual(t, resp.Members[0].StatusText, "Focusing")
+			}
+		})
+	}
+}
+
+func TestChatroomHandler_UpdateSettings_IfMatch(t *testing.T) {
+	tests := []struct {
+		name            string
+		ifMatch         string
+		expectedStatus  int
+		expectedVersion int
+	}{
+		{"overrides_body_version", `"4"`, http.StatusOK, 4},
+		{"any_version", "*", http.StatusOK, 0},
+		{"no_header_uses_body", "", http.StatusOK, 2},
+		{"weak_etag", `W/"4"`, http.StatusBadRequest, 0},
+		{"not_a_version", `"abc"`, http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotVersion int
+			chatService := &mockChatService{
+				updateSettingsFunc: func(ctx context.Context, chatroomID, requesterID string, settings *domain.ChatroomSettings) error {
+					gotVersion = settings.Version
+					settings.Version = 5
+					return nil
+				},
+			}
+			handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/chatrooms/room-1/settings", strings.NewReader(`{"welcome_message":"Hi!","version":2}`))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "room-1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req = req.WithContext(middleware.WithUserID(req.Context(), "owner-1"))
+			w := httptest.NewRecorder()
+
+			handler.UpdateSettings(w, req)
+
+			testutil.AssertEqual(t, w.Code, tt.expectedStatus)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			testutil.AssertEqual(t, gotVersion, tt.expectedVersion)
+			testutil.AssertEqual(t, w.Header().Get("ETag"), `"5"`)
+		})
+	}
+}
+
+func TestChatroomHandler_Activity(t *testing.T) {
+	chatService := &mockChatService{
+		getActivityFunc: func(ctx context.Context, userID string) ([]*domain.RoomActivity, error) {
+			if userID != "user-1" {
+				return nil, nil
+			}
+			return []*domain.RoomActivity{{
+				ChatroomID:   "room-1",
+				ChatroomName: "General",
+				UnreadCount:  3,
+				MentionCount: 1,
+				LastMessage:  &domain.MessagePreview{ID: "msg-1", Username: "bob", Content: "hey @alice"},
+			}}, nil
+		},
+	}
+	handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+	t.Run("rooms", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/me/activity", nil)
+		req = req.WithContext(middleware.WithUserID(req.Context(), "user-1"))
+		w := httptest.NewRecorder()
+
+		handler.Activity(w, req)
+
+		testutil.AssertEqual(t, w.Code, http.StatusOK)
+		var resp ActivityResponse
+		testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		testutil.AssertLen(t, resp.Rooms, 1)
+		testutil.AssertEqual(t, resp.Rooms[0].UnreadCount, 3)
+		testutil.AssertEqual(t, resp.Rooms[0].MentionCount, 1)
+		testutil.AssertEqual(t, resp.Rooms[0].LastMessage.Username, "bob")
+	})
+
+	t.Run("no_rooms", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/me/activity", nil)
+		req = req.WithContext(middleware.WithUserID(req.Context(), "user-2"))
+		w := httptest.NewRecorder()
+
+		handler.Activity(w, req)
+
+		testutil.AssertEqual(t, w.Code, http.StatusOK)
+		testutil.AssertEqual(t, strings.TrimSpace(w.Body.String()), `{"rooms":[]}`)
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.Activity(w, httptest.NewRequest(http.MethodGet, "/api/v1/me/activity", nil))
+		testutil.AssertEqual(t, w.Code, http.StatusUnauthorized)
+	})
+}
+
+func TestChatroomHandler_MarkRead(t *testing.T) {
+	tests := []struct {
+		name              string
+		body              string
+		serviceErr        error
+		expectedStatus    int
+		expectedMessageID string
+	}{
+		{"latest", "", nil, http.StatusNoContent, ""},
+		{"up_to_message", `{"message_id":"msg-1"}`, nil, http.StatusNoContent, "msg-1"},
+		{"invalid_body", `{`, nil, http.StatusBadRequest, ""},
+		{"invalid_message_id", `{"message_id":"x"}`, domain.ErrInvalidInput, http.StatusBadRequest, "x"},
+		{"not_member", "", domain.ErrNotMember, http.StatusForbidden, ""},
+		{"error", "", errors.New("database error"), http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotMessageID string
+			chatService := &mockChatService{
+				markReadFunc: func(ctx context.Context, chatroomID, userID, messageID string) error {
+					gotMessageID = messageID
+					return tt.serviceErr
+				},
+			}
+			handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/chatrooms/room-1/read", strings.NewReader(tt.body))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "room-1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req = req.WithContext(middleware.WithUserID(req.Context(), "user-1"))
+			w := httptest.NewRecorder()
+
+			handler.MarkRead(w, req)
+
+			testutil.AssertEqual(t, w.Code, tt.expectedStatus)
+			testutil.AssertEqual(t, gotMessageID, tt.expectedMessageID)
+		})
+	}
+}
+
+func TestChatroomHandler_Forward(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		serviceErr     error
+		expectedStatus int
+		published      int
+	}{
+		{"success", `{"chatroom_ids":["room-2","room-3"]}`, nil, http.StatusCreated, 2},
+		{"invalid_body", `{`, nil, http.StatusBadRequest, 0},
+		{"invalid_targets", `{"chatroom_ids":[]}`, domain.ErrInvalidInput, http.StatusBadRequest, 0},
+		{"message_not_found", `{"chatroom_ids":["room-2"]}`, domain.ErrMessageNotFound, http.StatusNotFound, 0},
+		{"not_member", `{"chatroom_ids":["room-2"]}`, domain.ErrNotMember, http.StatusForbidden, 0},
+		{"read_only", `{"chatroom_ids":["room-2"]}`, domain.ErrReadOnly, http.StatusForbidden, 0},
+		{"error", `{"chatroom_ids":["room-2"]}`, errors.New("database error"), http.StatusInternalServerError, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatService := &mockChatService{
+				forwardMessageFunc: func(ctx context.Context, messageID, userID string, chatroomIDs []string) ([]*domain.Message, error) {
+					if tt.serviceErr != nil {
+						return nil, tt.serviceErr
+					}
+					var messages []*domain.Message
+					for _, id := range chatroomIDs {
+						messages = append(messages, &domain.Message{
+							ID:            "fwd-" + id,
+							ChatroomID:    id,
+							UserID:        userID,
+							Content:       "hello",
+							ForwardedFrom: &domain.ForwardedFrom{MessageID: messageID, ChatroomID: "room-1", UserID: "user-2", Username: "bob"},
+						})
+					}
+					return messages, nil
+				},
+			}
+			hub := &mockHub{connectedCounts: make(map[string]int)}
+			handler := NewChatroomHandler(chatService, hub)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/messages/msg-1/forward", strings.NewReader(tt.body))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "msg-1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req = req.WithContext(middleware.WithUserID(req.Context(), "user-1"))
+			w := httptest.NewRecorder()
+
+			handler.Forward(w, req)
+
+			testutil.AssertStatusCode(t, w, tt.expectedStatus)
+			testutil.AssertLen(t, hub.published, tt.published)
+			if tt.expectedStatus == http.StatusCreated {
+				var resp ForwardMessageResponse
+				testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				testutil.AssertLen(t, resp.Messages, 2)
+				testutil.AssertEqual(t, resp.Messages[0].ForwardedFrom.Username, "bob")
+				testutil.AssertEqual(t, resp.Messages[0].ForwardedFrom.MessageID, "msg-1")
+			}
+		})
+	}
+}
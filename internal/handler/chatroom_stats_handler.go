@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ChatroomStatsService reports a chatroom's activity to its members
+type ChatroomStatsService interface {
+	Stats(ctx context.Context, chatroomID, userID string, days int) (*domain.ChatroomStats, error)
+}
+
+type ChatroomStatsHandler struct {
+	stats ChatroomStatsService
+}
+
+func NewChatroomStatsHandler(stats ChatroomStatsService) *ChatroomStatsHandler {
+	return &ChatroomStatsHandler{stats: stats}
+}
+
+// Stats serves GET /chatrooms/{id}/stats: messages and peak concurrent users
+// per day, and the top posters, over the last days (30 by default)
+func (h *ChatroomStatsHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var days int
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		var err error
+		if days, err = strconv.Atoi(daysStr); err != nil || days < 1 {
+			http.Error(w, `{"error":"Invalid days"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	stats, err := h.stats.Stats(r.Context(), chatroomID, userID, days)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidInput):
+			http.Error(w, fmt.Sprintf(`{"error":"Days must be between 1 and %d"}`, domain.MaxChatroomStatsDays), http.StatusBadRequest)
+		case errors.Is(err, domain.ErrChatroomNotFound):
+			http.Error(w, `{"error":"Chatroom not found"}`, http.StatusNotFound)
+		case errors.Is(err, domain.ErrNotMember):
+			http.Error(w, `{"error":"Not a member of this chatroom"}`, http.StatusForbidden)
+		default:
+			slog.Error("failed to get chatroom stats",
+				slog.String("error", err.Error()),
+				slog.String("chatroom_id", chatroomID))
+			http.Error(w, `{"error":"Failed to get chatroom stats"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+	"jobsity-chat/internal/service"
+	"jobsity-chat/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestChatroomStatsHandler_Stats(t *testing.T) {
+	const roomID = "6f1c1a52-4e0b-4c8e-9d3a-0b6f5a1f2c01"
+	statsRepo := testutil.NewMockChatroomStatsRepository()
+	statsRepo.StatsResult = &domain.ChatroomStats{
+		Days:       2,
+		Messages:   5,
+		PeakUsers:  3,
+		Daily:      []domain.DailyActivity{{Date: "2026-03-01", Messages: 5, PeakUsers: 3}, {Date: "2026-03-02"}},
+		TopPosters: []domain.TopPoster{{UserID: "user-1", Username: "alice", Messages: 5}},
+	}
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms[roomID] = &domain.Chatroom{ID: roomID, Name: "General"}
+	chatroomRepo.AddMember(context.Background(), roomID, "user-1")
+	handler := NewChatroomStatsHandler(service.NewChatroomStatsService(statsRepo, chatroomRepo))
+
+	stats := func(id, userID, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/chatrooms/"+id+"/stats"+query, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		req = req.WithContext(middleware.WithUserID(req.Context(), userID))
+		w := httptest.NewRecorder()
+		handler.Stats(w, req)
+		return w
+	}
+
+	w := stats(roomID, "user-1", "?days=2")
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	response := testutil.DecodeJSON[domain.ChatroomStats](t, w)
+	testutil.AssertEqual(t, response.ChatroomID, roomID)
+	testutil.AssertEqual(t, response.PeakUsers, 3)
+	testutil.AssertLen(t, response.Daily, 2)
+	testutil.AssertEqual(t, response.TopPosters[0].Username, "alice")
+
+	testutil.AssertStatusCode(t, stats(roomID, "user-1", "?days=abc"), http.StatusBadRequest)
+	testutil.AssertStatusCode(t, stats(roomID, "user-1", "?days=365"), http.StatusBadRequest)
+	testutil.AssertStatusCode(t, stats("room-1", "user-1", ""), http.StatusNotFound)
+	testutil.AssertStatusCode(t, stats(roomID, "user-2", ""), http.StatusForbidden)
+
+	statsRepo.StatsFunc = func(ctx context.Context, chatroomID string, since time.Time) (*domain.ChatroomStats, error) {
+		return nil, errors.New("database error")
+	}
+	testutil.AssertStatusCode(t, stats(roomID, "user-1", ""), http.StatusInternalServerError)
+}
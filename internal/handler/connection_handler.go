@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+	"jobsity-chat/internal/observability"
+	ws "jobsity-chat/internal/websocket"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxCloseReasonBytes is the longest reason a WebSocket close frame can carry
+const maxCloseReasonBytes = 123
+
+// ConnectionManager closes WebSocket connections on request
+type ConnectionManager interface {
+	DisconnectUser(orgID, userID, chatroomID string, code int, reason string) (int, error)
+}
+
+// ConnectionHandler lets administrators close users' WebSocket connections,
+// e.g. after a ban or revoking their sessions. It must be guarded by
+// middleware.RequireAdmin.
+type ConnectionHandler struct {
+	hub ConnectionManager
+}
+
+func NewConnectionHandler(hub ConnectionManager) *ConnectionHandler {
+	return &ConnectionHandler{hub: hub}
+}
+
+// DisconnectUserRequest selects the connections to close and the close frame
+// sent to them. All fields are optional.
+type DisconnectUserRequest struct {
+	// ChatroomID limits the disconnect to one chatroom
+	ChatroomID string `json:"chatroom_id"`
+	// Code defaults to websocket.CloseDisconnected
+	Code   int    `json:"code"`
+	Reason string `json:"reason"`
+}
+
+type DisconnectUserResponse struct {
+	Disconnected int `json:"disconnected"`
+}
+
+// validCloseCode reports whether code may be sent by an application: normal
+// closure, going away, policy violation or the private 4000-4999 range
+func validCloseCode(code int) bool {
+	switch code {
+	case 1000, 1001, 1008:
+		return true
+	}
+	return code >= 4000 && code <= 4999
+}
+
+// DisconnectUser closes the user's connections to this server in the
+// caller's organization. Other servers keep theirs.
+func (h *ConnectionHandler) DisconnectUser(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req DisconnectUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Code == 0 {
+		req.Code = ws.CloseDisconnected
+	}
+	if !validCloseCode(req.Code) {
+		http.Error(w, `{"error":"Close code must be 1000, 1001, 1008 or 4000-4999"}`, http.StatusBadRequest)
+		return
+	}
+	if len(req.Reason) > maxCloseReasonBytes {
+		http.Error(w, `{"error":"Reason must be at most 123 bytes"}`, http.StatusBadRequest)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+	disconnected, err := h.hub.DisconnectUser(domain.OrgIDFromContext(r.Context()), userID, req.ChatroomID, req.Code, req.Reason)
+	if err != nil {
+		slog.Error("failed to disconnect user",
+			slog.String("error", err.Error()),
+			slog.String("user_id", userID))
+		http.Error(w, `{"error":"Failed to disconnect user"}`, http.StatusServiceUnavailable)
+		return
+	}
+
+	observability.Audit(r.Context(), "user_disconnected",
+		slog.String("admin_id", adminID),
+		slog.String("user_id", userID),
+		slog.String("chatroom_id", req.ChatroomID),
+		slog.Int("close_code", req.Code),
+		slog.Int("connections", disconnected))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DisconnectUserResponse{Disconnected: disconnected})
+}
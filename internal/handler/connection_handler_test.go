@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"jobsity-chat/internal/middleware"
+	"jobsity-chat/internal/testutil"
+	ws "jobsity-chat/internal/websocket"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type disconnectCall struct {
+	userID, chatroomID string
+	code               int
+	reason             string
+}
+
+type stubConnectionManager struct {
+	calls []disconnectCall
+}
+
+func (s *stubConnectionManager) DisconnectUser(orgID, userID, chatroomID string, code int, reason string) (int, error) {
+	s.calls = append(s.calls, disconnectCall{userID, chatroomID, code, reason})
+	return 2, nil
+}
+
+func disconnectRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/user-2/disconnect", strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "user-2")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	return req.WithContext(middleware.WithUserID(req.Context(), "admin-1"))
+}
+
+func TestConnectionHandler_DisconnectUser(t *testing.T) {
+	hub := &stubConnectionManager{}
+	handler := NewConnectionHandler(hub)
+
+	w := httptest.NewRecorder()
+	handler.DisconnectUser(w, disconnectRequest(""))
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	var resp DisconnectUserResponse
+	testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	testutil.AssertEqual(t, resp.Disconnected, 2)
+	testutil.AssertEqual(t, hub.calls[0], disconnectCall{userID: "user-2", code: ws.CloseDisconnected})
+
+	w = httptest.NewRecorder()
+	handler.DisconnectUser(w, disconnectRequest(`{"chatroom_id":"room-1","code":1008,"reason":"banned"}`))
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	testutil.AssertEqual(t, hub.calls[1], disconnectCall{userID: "user-2", chatroomID: "room-1", code: 1008, reason: "banned"})
+}
+
+func TestConnectionHandler_DisconnectUserInvalid(t *testing.T) {
+	tests := map[string]string{
+		"reserved code": `{"code":1006}`,
+		"long reason":   `{"reason":"` + strings.Repeat("x", 124) + `"}`,
+		"invalid body":  `{`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			hub := &stubConnectionManager{}
+			w := httptest.NewRecorder()
+			NewConnectionHandler(hub).DisconnectUser(w, disconnectRequest(body))
+			testutil.AssertStatusCode(t, w, http.StatusBadRequest)
+			testutil.AssertLen(t, hub.calls, 0)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+)
+
+// CookieMode is how browsers may send the server's cookies
+type CookieMode string
+
+const (
+	// CookieModeLax only sends cookies from the app's own pages and on
+	// top-level navigation to it
+	CookieModeLax CookieMode = "lax"
+	// CookieModeEmbedded also sends cookies when the app is framed by
+	// another site, e.g. as a chat widget: they are SameSite=None, Secure
+	// and partitioned by the embedding site (CHIPS). Cookie-authenticated
+	// requests that change state then need a CSRF token.
+	CookieModeEmbedded CookieMode = "embedded"
+)
+
+// ParseCookieMode parses a COOKIE_MODE value
+func ParseCookieMode(s string) (CookieMode, error) {
+	switch mode := CookieMode(s); mode {
+	case CookieModeLax, CookieModeEmbedded:
+		return mode, nil
+	case "":
+		return CookieModeLax, nil
+	default:
+		return "", fmt.Errorf("unknown cookie mode %q", s)
+	}
+}
+
+// cookiePolicy sets the security attributes of the cookies a handler sets
+type cookiePolicy struct {
+	isProduction bool
+	mode         CookieMode
+}
+
+func newCookiePolicy() cookiePolicy {
+	env := os.Getenv("ENVIRONMENT")
+	return cookiePolicy{
+		isProduction: env == "production" || env == "prod",
+		mode:         CookieModeLax,
+	}
+}
+
+// apply makes cookie Secure in production and whenever the client connected
+// over HTTPS, directly or through a trusted TLS-terminating proxy. Embedded
+// cookies are always Secure, as browsers require for SameSite=None.
+func (p cookiePolicy) apply(r *http.Request, cookie *http.Cookie) *http.Cookie {
+	cookie.HttpOnly = true
+	if p.mode == CookieModeEmbedded {
+		cookie.Secure = true
+		cookie.SameSite = http.SameSiteNoneMode
+		cookie.Partitioned = true
+		return cookie
+	}
+	cookie.Secure = p.isProduction || middleware.IsSecure(r)
+	cookie.SameSite = http.SameSiteLaxMode
+	return cookie
+}
+
+// newSessionCookie returns the session_id cookie for session. Short sessions
+// use a browser-session cookie; remember-me sessions persist until their
+// absolute expiry.
+func newSessionCookie(session *domain.Session, persistent bool) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:  "session_id",
+		Value: session.Token,
+		Path:  "/",
+	}
+	if persistent {
+		cookie.MaxAge = sessionMaxAge(session)
+	}
+	return cookie
+}
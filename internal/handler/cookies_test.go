@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"jobsity-chat/internal/testutil"
+)
+
+func TestParseCookieMode(t *testing.T) {
+	mode, err := ParseCookieMode("")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, mode, CookieModeLax)
+
+	mode, err = ParseCookieMode("embedded")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, mode, CookieModeEmbedded)
+
+	_, err = ParseCookieMode("strict")
+	testutil.AssertError(t, err)
+}
+
+func TestCookiePolicy_Apply(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+
+	lax := cookiePolicy{mode: CookieModeLax}.apply(req, &http.Cookie{Name: "session_id"})
+	testutil.AssertTrue(t, lax.HttpOnly, "cookie should be HttpOnly")
+	testutil.AssertFalse(t, lax.Secure, "lax cookie over http should not be Secure")
+	testutil.AssertEqual(t, lax.SameSite, http.SameSiteLaxMode)
+	testutil.AssertFalse(t, lax.Partitioned, "lax cookie should not be partitioned")
+
+	embedded := cookiePolicy{mode: CookieModeEmbedded}.apply(req, &http.Cookie{Name: "session_id"})
+	testutil.AssertTrue(t, embedded.HttpOnly, "cookie should be HttpOnly")
+	testutil.AssertTrue(t, embedded.Secure, "SameSite=None cookies must be Secure")
+	testutil.AssertEqual(t, embedded.SameSite, http.SameSiteNoneMode)
+	testutil.AssertTrue(t, embedded.Partitioned, "embedded cookie should be partitioned")
+	testutil.AssertContains(t, embedded.String(), "Partitioned")
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// DebugHandler exposes pprof profiles and runtime statistics.
+// It must be mounted at /debug and guarded by middleware.DebugAccess.
+type DebugHandler struct {
+	db        *sql.DB
+	hub       HubInterface
+	startedAt time.Time
+}
+
+func NewDebugHandler(db *sql.DB, hub HubInterface) *DebugHandler {
+	return &DebugHandler{
+		db:        db,
+		hub:       hub,
+		startedAt: time.Now(),
+	}
+}
+
+// RuntimeStats is the payload returned by GET /debug/vars
+type RuntimeStats struct {
+	Timestamp     string         `json:"timestamp"`
+	UptimeSeconds int64          `json:"uptime_seconds"`
+	GoVersion     string         `json:"go_version"`
+	NumCPU        int            `json:"num_cpu"`
+	Goroutines    int            `json:"goroutines"`
+	Memory        MemoryStats    `json:"memory"`
+	Hub           HubStats       `json:"hub"`
+	Database      *DatabaseStats `json:"database,omitempty"`
+}
+
+type MemoryStats struct {
+	AllocBytes      uint64 `json:"alloc_bytes"`
+	TotalAllocBytes uint64 `json:"total_alloc_bytes"`
+	SysBytes        uint64 `json:"sys_bytes"`
+	HeapObjects     uint64 `json:"heap_objects"`
+	NumGC           uint32 `json:"num_gc"`
+	PauseTotalNs    uint64 `json:"pause_total_ns"`
+}
+
+type HubStats struct {
+	Rooms       int `json:"rooms"`
+	Connections int `json:"connections"`
+}
+
+type DatabaseStats struct {
+	OpenConnections int   `json:"open_connections"`
+	InUse           int   `json:"in_use"`
+	Idle            int   `json:"idle"`
+	WaitCount       int64 `json:"wait_count"`
+	WaitDurationMs  int64 `json:"wait_duration_ms"`
+}
+
+// Routes returns a router serving /pprof/* and /vars
+func (h *DebugHandler) Routes() http.Handler {
+	r := chi.NewRouter()
+
+	// pprof.Index resolves named profiles from the /debug/pprof/ prefix
+	r.Get("/pprof/", pprof.Index)
+	r.Get("/pprof/cmdline", pprof.Cmdline)
+	r.Get("/pprof/profile", pprof.Profile)
+	r.Post("/pprof/symbol", pprof.Symbol)
+	r.Get("/pprof/symbol", pprof.Symbol)
+	r.Get("/pprof/trace", pprof.Trace)
+	r.Get("/pprof/{profile}", pprof.Index)
+
+	r.Get("/vars", h.Vars)
+
+	return r
+}
+
+func (h *DebugHandler) Vars(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := RuntimeStats{
+		Timestamp:     time.Now().Format(time.RFC3339),
+		UptimeSeconds: int64(time.Since(h.startedAt).Seconds()),
+		GoVersion:     runtime.Version(),
+		NumCPU:        runtime.NumCPU(),
+		Goroutines:    runtime.NumGoroutine(),
+		Memory: MemoryStats{
+			AllocBytes:      mem.Alloc,
+			TotalAllocBytes: mem.TotalAlloc,
+			SysBytes:        mem.Sys,
+			HeapObjects:     mem.HeapObjects,
+			NumGC:           mem.NumGC,
+			PauseTotalNs:    mem.PauseTotalNs,
+		},
+	}
+
+	if h.hub != nil {
+		counts := h.hub.GetAllConnectedCounts()
+		stats.Hub.Rooms = len(counts)
+		for _, c := range counts {
+			stats.Hub.Connections += c
+		}
+	}
+
+	if h.db != nil {
+		dbStats := h.db.Stats()
+		stats.Database = &DatabaseStats{
+			OpenConnections: dbStats.OpenConnections,
+			InUse:           dbStats.InUse,
+			Idle:            dbStats.Idle,
+			WaitCount:       dbStats.WaitCount,
+			WaitDurationMs:  dbStats.WaitDuration.Milliseconds(),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		slog.Error("failed to encode debug vars response", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"jobsity-chat/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func newDebugRouter(h *DebugHandler) http.Handler {
+	r := chi.NewRouter()
+	r.Mount("/debug", h.Routes())
+	return r
+}
+
+func TestDebugHandler_Vars(t *testing.T) {
+	h := NewDebugHandler(nil, &mockHub{connectedCounts: map[string]int{"room-1": 2, "room-2": 3}})
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+	w := httptest.NewRecorder()
+	newDebugRouter(h).ServeHTTP(w, req)
+
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	testutil.AssertHeader(t, w, "Content-Type", "application/json")
+
+	var stats RuntimeStats
+	testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&stats))
+	testutil.AssertEqual(t, stats.Hub.Rooms, 2)
+	testutil.AssertEqual(t, stats.Hub.Connections, 5)
+	testutil.AssertTrue(t, stats.Goroutines > 0, "expected goroutine count")
+	testutil.AssertNil(t, stats.Database)
+}
+
+func TestDebugHandler_PprofIndex(t *testing.T) {
+	h := NewDebugHandler(nil, &mockHub{})
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"index", "/debug/pprof/"},
+		{"named profile", "/debug/pprof/goroutine?debug=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			newDebugRouter(h).ServeHTTP(w, req)
+
+			testutil.AssertStatusCode(t, w, http.StatusOK)
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateEventRequest describes the event an event message announces
+type CreateEventRequest struct {
+	Title    string     `json:"title"`
+	StartsAt time.Time  `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+	Location string     `json:"location,omitempty"`
+}
+
+// RSVPRequest carries a member's answer to an event
+type RSVPRequest struct {
+	Response string `json:"response"`
+}
+
+// RSVPsResponse lists the answers to an event, oldest first, and counts
+// them by response
+type RSVPsResponse struct {
+	RSVPs  []*domain.RSVP `json:"rsvps"`
+	Counts map[string]int `json:"counts"`
+}
+
+// CreateEvent posts a message announcing an event members can RSVP to
+func (h *ChatroomHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	msg, err := h.chatService.CreateEvent(r.Context(), chatroomID, userID, &domain.CalendarEvent{
+		Title:    req.Title,
+		StartsAt: req.StartsAt,
+		EndsAt:   req.EndsAt,
+		Location: req.Location,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidInput):
+			http.Error(w, fmt.Sprintf(`{"error":"Invalid event: give a title of up to %d characters, a start time, an end after it if any and a location of up to %d characters; encrypted chatrooms cannot have events"}`,
+				domain.MaxEventTitleLength, domain.MaxEventLocationLength), http.StatusBadRequest)
+		case errors.Is(err, domain.ErrChatroomNotFound):
+			http.Error(w, `{"error":"Chatroom not found"}`, http.StatusNotFound)
+		case errors.Is(err, domain.ErrNotMember):
+			http.Error(w, `{"error":"Not a member of this chatroom"}`, http.StatusForbidden)
+		case errors.Is(err, domain.ErrReadOnly):
+			http.Error(w, `{"error":"This chatroom is read-only"}`, http.StatusForbidden)
+		case errors.Is(err, domain.ErrQuotaExceeded):
+			http.Error(w, `{"error":"`+err.Error()+`"}`, quotaStatus(err))
+		default:
+			slog.Error("failed to create event",
+				slog.String("error", err.Error()),
+				slog.String("chatroom_id", chatroomID))
+			http.Error(w, `{"error":"Failed to create event"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := h.hub.PublishMessage(msg); err != nil {
+		slog.Warn("failed to broadcast event message",
+			slog.String("error", err.Error()),
+			slog.String("message_id", msg.ID),
+			slog.String("chatroom_id", msg.ChatroomID))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(msg)
+}
+
+// RSVP records the caller's answer to an event and broadcasts it to the
+// chatroom as an event_rsvp frame
+func (h *ChatroomHandler) RSVP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req RSVPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	messageID := chi.URLParam(r, "id")
+	rsvp, err := h.chatService.RSVP(r.Context(), messageID, userID, req.Response)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidInput):
+			http.Error(w, fmt.Sprintf(`{"error":"Response must be one of %s"}`, strings.Join(domain.RSVPResponses, ", ")), http.StatusBadRequest)
+		default:
+			writeEventError(w, err, messageID, "failed to save rsvp", "Failed to save RSVP")
+		}
+		return
+	}
+
+	if err := h.hub.PublishRSVP(rsvp); err != nil {
+		slog.Warn("failed to broadcast rsvp",
+			slog.String("error", err.Error()),
+			slog.String("message_id", rsvp.MessageID),
+			slog.String("chatroom_id", rsvp.ChatroomID))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rsvp)
+}
+
+// ListRSVPs returns the answers to an event
+func (h *ChatroomHandler) ListRSVPs(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	messageID := chi.URLParam(r, "id")
+	rsvps, err := h.chatService.ListRSVPs(r.Context(), messageID, userID)
+	if err != nil {
+		writeEventError(w, err, messageID, "failed to list rsvps", "Failed to list RSVPs")
+		return
+	}
+
+	response := RSVPsResponse{RSVPs: rsvps, Counts: make(map[string]int, len(domain.RSVPResponses))}
+	for _, answer := range domain.RSVPResponses {
+		response.Counts[answer] = 0
+	}
+	for _, rsvp := range rsvps {
+		response.Counts[rsvp.Response]++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ExportEvent serves an event as an iCalendar file, to add it to a calendar
+func (h *ChatroomHandler) ExportEvent(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	messageID := chi.URLParam(r, "id")
+	msg, err := h.chatService.GetEvent(r.Context(), messageID, userID)
+	if err != nil {
+		writeEventError(w, err, messageID, "failed to get event", "Failed to get event")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="event.ics"`)
+	w.Write([]byte(iCalendar(msg)))
+}
+
+// writeEventError writes the response for the errors shared by the event
+// endpoints, logging unexpected ones as logMsg
+func writeEventError(w http.ResponseWriter, err error, messageID, logMsg, publicMsg string) {
+	switch {
+	case errors.Is(err, domain.ErrMessageNotFound):
+		http.Error(w, `{"error":"Message not found"}`, http.StatusNotFound)
+	case errors.Is(err, domain.ErrNotAnEvent):
+		http.Error(w, `{"error":"Message is not an event"}`, http.StatusNotFound)
+	default:
+		slog.Error(logMsg,
+			slog.String("error", err.Error()),
+			slog.String("message_id", messageID))
+		http.Error(w, `{"error":"`+publicMsg+`"}`, http.StatusInternalServerError)
+	}
+}
+
+// icsTimeFormat is the UTC DATE-TIME form of RFC 5545
+const icsTimeFormat = "20060102T150405Z"
+
+// iCalendar returns the RFC 5545 calendar of an event message
+func iCalendar(msg *domain.Message) string {
+	event := msg.Event
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Chattorumu//Events//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"BEGIN:VEVENT",
+		"UID:" + msg.ID + "@chattorumu",
+		"DTSTAMP:" + msg.CreatedAt.UTC().Format(icsTimeFormat),
+		"DTSTART:" + event.StartsAt.UTC().Format(icsTimeFormat),
+	}
+	if event.EndsAt != nil {
+		lines = append(lines, "DTEND:"+event.EndsAt.UTC().Format(icsTimeFormat))
+	}
+	lines = append(lines, "SUMMARY:"+icsText(event.Title))
+	if event.Location != "" {
+		lines = append(lines, "LOCATION:"+icsText(event.Location))
+	}
+	if msg.Username != "" {
+		lines = append(lines, "DESCRIPTION:"+icsText("Posted by "+msg.Username))
+	}
+	lines = append(lines, "END:VEVENT", "END:VCALENDAR")
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(foldICSLine(line))
+		b.WriteString("\r\n")
+	}
+	return b.String()
+}
+
+// icsText escapes a TEXT value
+func icsText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", "",
+	).Replace(s)
+}
+
+// foldICSLine splits a content line into lines of at most 75 octets, without
+// breaking UTF-8 sequences; continuation lines start with a space
+func foldICSLine(line string) string {
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	b.WriteString(line)
+	return b.String()
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+	"jobsity-chat/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func newEventRequest(method, target, id, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	return req.WithContext(middleware.WithUserID(req.Context(), "user-1"))
+}
+
+func TestChatroomHandler_CreateEvent(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		serviceErr     error
+		expectedStatus int
+		published      int
+	}{
+		{"success", `{"title":"Team lunch","starts_at":"2026-02-03T12:00:00Z","location":"Cafeteria"}`, nil, http.StatusCreated, 1},
+		{"invalid_body", `{`, nil, http.StatusBadRequest, 0},
+		{"invalid_event", `{"title":""}`, domain.ErrInvalidInput, http.StatusBadRequest, 0},
+		{"not_member", `{"title":"Lunch","starts_at":"2026-02-03T12:00:00Z"}`, domain.ErrNotMember, http.StatusForbidden, 0},
+		{"read_only", `{"title":"Lunch","starts_at":"2026-02-03T12:00:00Z"}`, domain.ErrReadOnly, http.StatusForbidden, 0},
+		{"error", `{"title":"Lunch","starts_at":"2026-02-03T12:00:00Z"}`, errors.New("database error"), http.StatusInternalServerError, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatService := &mockChatService{
+				createEventFunc: func(ctx context.Context, chatroomID, userID string, event *domain.CalendarEvent) (*domain.Message, error) {
+					if tt.serviceErr != nil {
+						return nil, tt.serviceErr
+					}
+					return &domain.Message{ID: "msg-1", ChatroomID: chatroomID, UserID: userID, Content: event.Title, Event: event}, nil
+				},
+			}
+			hub := &mockHub{connectedCounts: make(map[string]int)}
+			handler := NewChatroomHandler(chatService, hub)
+
+			w := httptest.NewRecorder()
+			handler.CreateEvent(w, newEventRequest(http.MethodPost, "/api/v1/chatrooms/room-1/events", "room-1", tt.body))
+
+			testutil.AssertStatusCode(t, w, tt.expectedStatus)
+			testutil.AssertLen(t, hub.published, tt.published)
+			if tt.expectedStatus == http.StatusCreated {
+				var msg domain.Message
+				testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&msg))
+				testutil.AssertNotNil(t, msg.Event)
+				testutil.AssertEqual(t, msg.Event.Location, "Cafeteria")
+				testutil.AssertEqual(t, msg.ChatroomID, "room-1")
+			}
+		})
+	}
+}
+
+func TestChatroomHandler_RSVP(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		serviceErr     error
+		expectedStatus int
+		published      int
+	}{
+		{"success", `{"response":"going"}`, nil, http.StatusOK, 1},
+		{"invalid_body", `{`, nil, http.StatusBadRequest, 0},
+		{"invalid_response", `{"response":"sure"}`, domain.ErrInvalidInput, http.StatusBadRequest, 0},
+		{"message_not_found", `{"response":"maybe"}`, domain.ErrMessageNotFound, http.StatusNotFound, 0},
+		{"not_an_event", `{"response":"maybe"}`, domain.ErrNotAnEvent, http.StatusNotFound, 0},
+		{"error", `{"response":"maybe"}`, errors.New("database error"), http.StatusInternalServerError, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatService := &mockChatService{
+				rsvpFunc: func(ctx context.Context, messageID, userID, response string) (*domain.RSVP, error) {
+					if tt.serviceErr != nil {
+						return nil, tt.serviceErr
+					}
+					return &domain.RSVP{MessageID: messageID, ChatroomID: "room-1", UserID: userID, Response: response}, nil
+				},
+			}
+			hub := &mockHub{connectedCounts: make(map[string]int)}
+			handler := NewChatroomHandler(chatService, hub)
+
+			w := httptest.NewRecorder()
+			handler.RSVP(w, newEventRequest(http.MethodPut, "/api/v1/messages/msg-1/rsvp", "msg-1", tt.body))
+
+			testutil.AssertStatusCode(t, w, tt.expectedStatus)
+			testutil.AssertLen(t, hub.rsvps, tt.published)
+		})
+	}
+}
+
+func TestChatroomHandler_ListRSVPs(t *testing.T) {
+	chatService := &mockChatService{
+		listRSVPsFunc: func(ctx context.Context, messageID, userID string) ([]*domain.RSVP, error) {
+			return []*domain.RSVP{
+				{MessageID: messageID, UserID: "user-1", Response: domain.RSVPGoing},
+				{MessageID: messageID, UserID: "user-2", Response: domain.RSVPGoing},
+				{MessageID: messageID, UserID: "user-3", Response: domain.RSVPDeclined},
+			}, nil
+		},
+	}
+	handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+	w := httptest.NewRecorder()
+	handler.ListRSVPs(w, newEventRequest(http.MethodGet, "/api/v1/messages/msg-1/rsvps", "msg-1", ""))
+
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	var response RSVPsResponse
+	testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&response))
+	testutil.AssertLen(t, response.RSVPs, 3)
+	testutil.AssertEqual(t, response.Counts[domain.RSVPGoing], 2)
+	testutil.AssertEqual(t, response.Counts[domain.RSVPMaybe], 0)
+	testutil.AssertEqual(t, response.Counts[domain.RSVPDeclined], 1)
+}
+
+func TestChatroomHandler_ExportEvent(t *testing.T) {
+	endsAt := time.Date(2026, 2, 3, 18, 30, 0, 0, time.UTC)
+	chatService := &mockChatService{
+		getEventFunc: func(ctx context.Context, messageID, userID string) (*domain.Message, error) {
+			if messageID != "msg-1" {
+				return nil, domain.ErrNotAnEvent
+			}
+			return &domain.Message{
+				ID:        "msg-1",
+				Username:  "alice",
+				CreatedAt: time.Date(2026, 1, 28, 10, 30, 0, 0, time.UTC),
+				Event: &domain.CalendarEvent{
+					Title:    "Lunch; pizza, maybe " + strings.Repeat("very ", 15) + "long",
+					StartsAt: time.Date(2026, 2, 3, 12, 0, 0, 0, time.FixedZone("UTC-5", -5*3600)),
+					EndsAt:   &endsAt,
+					Location: "Cafeteria",
+				},
+			}, nil
+		},
+	}
+	handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+	w := httptest.NewRecorder()
+	handler.ExportEvent(w, newEventRequest(http.MethodGet, "/api/v1/messages/msg-1/event.ics", "msg-1", ""))
+
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	testutil.AssertEqual(t, w.Header().Get("Content-Type"), "text/calendar; charset=utf-8")
+	body := w.Body.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:msg-1@chattorumu\r\n",
+		"DTSTAMP:20260128T103000Z\r\n",
+		"DTSTART:20260203T170000Z\r\n",
+		"DTEND:20260203T183000Z\r\n",
+		`SUMMARY:Lunch\; pizza\, maybe very`,
+		"LOCATION:Cafeteria\r\n",
+		"DESCRIPTION:Posted by alice\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		testutil.AssertTrue(t, strings.Contains(body, want), "expected "+want)
+	}
+	for _, line := range strings.Split(body, "\r\n") {
+		testutil.AssertTrue(t, len(line) <= 75, "line longer than 75 octets: "+line)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ExportEvent(w, newEventRequest(http.MethodGet, "/api/v1/messages/msg-2/event.ics", "msg-2", ""))
+	testutil.AssertStatusCode(t, w, http.StatusNotFound)
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// DataExportService assembles downloadable copies of a user's data
+type DataExportService interface {
+	Request(ctx context.Context, userID string) (*domain.DataExport, error)
+	Archive(ctx context.Context, userID, exportID string) ([]byte, error)
+}
+
+// exportPollInterval is the Retry-After sent while an export is pending, in
+// seconds
+const exportPollInterval = 5
+
+type ExportHandler struct {
+	exports DataExportService
+}
+
+func NewExportHandler(exports DataExportService) *ExportHandler {
+	return &ExportHandler{exports: exports}
+}
+
+// DataExportResponse is a data export with the URL of its archive, set once
+// it is ready
+type DataExportResponse struct {
+	*domain.DataExport
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// Export returns the caller's data export, starting one when they have none
+// in progress or ready. It answers 202 Accepted while the archive is being
+// assembled; clients poll it until the status is ready and then fetch
+// download_url.
+func (h *ExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	export, err := h.exports.Request(r.Context(), userID)
+	if err != nil {
+		slog.Error("failed to request data export",
+			slog.String("error", err.Error()),
+			slog.String("user_id", userID))
+		http.Error(w, `{"error":"Failed to export data"}`, http.StatusInternalServerError)
+		return
+	}
+
+	resp := DataExportResponse{DataExport: export}
+	status := http.StatusOK
+	switch export.Status {
+	case domain.ExportReady:
+		resp.DownloadURL = strings.TrimSuffix(r.URL.Path, "/") + "/" + export.ID + "/archive"
+	case domain.ExportPending:
+		status = http.StatusAccepted
+		w.Header().Set("Retry-After", strconv.Itoa(exportPollInterval))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Download serves the zip archive of one of the caller's ready exports
+func (h *ExportHandler) Download(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	exportID := chi.URLParam(r, "id")
+	archive, err := h.exports.Archive(r.Context(), userID, exportID)
+	if err != nil {
+		if errors.Is(err, domain.ErrExportNotFound) {
+			http.Error(w, `{"error":"Export not found or expired"}`, http.StatusNotFound)
+			return
+		}
+		slog.Error("failed to get data export archive",
+			slog.String("error", err.Error()),
+			slog.String("export_id", exportID))
+		http.Error(w, `{"error":"Failed to download export"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="chat-export.zip"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+	w.Write(archive)
+}
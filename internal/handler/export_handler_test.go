@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+	"jobsity-chat/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type mockDataExports struct {
+	export *domain.DataExport
+	err    error
+}
+
+func (m *mockDataExports) Request(ctx context.Context, userID string) (*domain.DataExport, error) {
+	return m.export, m.err
+}
+
+func (m *mockDataExports) Archive(ctx context.Context, userID, exportID string) ([]byte, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.export == nil || m.export.ID != exportID || m.export.Status != domain.ExportReady {
+		return nil, domain.ErrExportNotFound
+	}
+	return []byte("PK\x05\x06"), nil
+}
+
+func TestExportHandler_Export(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	tests := []struct {
+		name           string
+		exports        *mockDataExports
+		expectedStatus int
+		downloadURL    string
+	}{
+		{"pending", &mockDataExports{export: &domain.DataExport{ID: "export-1", Status: domain.ExportPending}}, http.StatusAccepted, ""},
+		{"ready", &mockDataExports{export: &domain.DataExport{ID: "export-1", Status: domain.ExportReady, ExpiresAt: &expiresAt}}, http.StatusOK, "/api/v1/me/export/export-1/archive"},
+		{"failed", &mockDataExports{export: &domain.DataExport{ID: "export-1", Status: domain.ExportFailed, Error: "boom"}}, http.StatusOK, ""},
+		{"error", &mockDataExports{err: errors.New("database error")}, http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewExportHandler(tt.exports)
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/me/export", nil)
+			req = req.WithContext(middleware.WithUserID(req.Context(), "user-1"))
+			w := httptest.NewRecorder()
+
+			handler.Export(w, req)
+
+			testutil.AssertStatusCode(t, w, tt.expectedStatus)
+			if tt.expectedStatus == http.StatusInternalServerError {
+				return
+			}
+			var resp DataExportResponse
+			testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			testutil.AssertEqual(t, resp.Status, tt.exports.export.Status)
+			testutil.AssertEqual(t, resp.DownloadURL, tt.downloadURL)
+		})
+	}
+
+	t.Run("no_user", func(t *testing.T) {
+		handler := NewExportHandler(&mockDataExports{})
+		w := httptest.NewRecorder()
+		handler.Export(w, httptest.NewRequest(http.MethodGet, "/api/v1/me/export", nil))
+		testutil.AssertStatusCode(t, w, http.StatusUnauthorized)
+	})
+}
+
+func TestExportHandler_Download(t *testing.T) {
+	handler := NewExportHandler(&mockDataExports{export: &domain.DataExport{ID: "export-1", Status: domain.ExportReady}})
+
+	download := func(exportID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/me/export/"+exportID+"/archive", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", exportID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		req = req.WithContext(middleware.WithUserID(req.Context(), "user-1"))
+		w := httptest.NewRecorder()
+		handler.Download(w, req)
+		return w
+	}
+
+	w := download("export-1")
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	testutil.AssertHeader(t, w, "Content-Type", "application/zip")
+	testutil.AssertHeaderContains(t, w, "Content-Disposition", "attachment")
+	testutil.AssertEqual(t, w.Body.String(), "PK\x05\x06")
+
+	w = download("export-2")
+	testutil.AssertStatusCode(t, w, http.StatusNotFound)
+}
@@ -1,16 +1,36 @@
 package handler
 
 import (
+	"bufio"
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"jobsity-chat/internal/messaging"
 )
 
+// Dependency check statuses
+const (
+	CheckStatusUp       = "up"
+	CheckStatusDegraded = "degraded"
+	CheckStatusDown     = "down"
+)
+
+// Overall readiness statuses
+const (
+	ReadyStatusReady    = "ready"
+	ReadyStatusDegraded = "degraded"
+	ReadyStatusNotReady = "not_ready"
+)
+
 func Health(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -22,57 +42,289 @@ func Health(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// HeartbeatSource reports the last time a background loop proved it was alive
+type HeartbeatSource interface {
+	LastHeartbeat() time.Time
+}
+
+// LivenessResponse is the JSON body returned by the liveness endpoint
+type LivenessResponse struct {
+	Status string           `json:"status"`
+	Hub    *HeartbeatStatus `json:"hub,omitempty"`
+}
+
+type HeartbeatStatus struct {
+	Status        string `json:"status"`
+	LastHeartbeat string `json:"last_heartbeat,omitempty"`
+	AgeMs         int64  `json:"age_ms"`
+}
+
+// Liveness returns a liveness handler that fails with 503 when the hub's
+// run loop has not produced a heartbeat within maxAge, so a deadlocked or
+// exited hub gets the process restarted instead of silently dropping messages.
+func Liveness(hub HeartbeatSource, maxAge time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := LivenessResponse{Status: "ok"}
+		status := http.StatusOK
+
+		last := hub.LastHeartbeat()
+		hb := &HeartbeatStatus{Status: CheckStatusUp}
+		if last.IsZero() {
+			hb.Status = CheckStatusDown
+		} else {
+			hb.LastHeartbeat = last.Format(time.RFC3339Nano)
+			hb.AgeMs = time.Since(last).Milliseconds()
+			if time.Since(last) > maxAge {
+				hb.Status = CheckStatusDown
+			}
+		}
+		resp.Hub = hb
+
+		if hb.Status != CheckStatusUp {
+			resp.Status = "unhealthy"
+			status = http.StatusServiceUnavailable
+			slog.Error("liveness check failed: hub heartbeat stale",
+				slog.Int64("age_ms", hb.AgeMs),
+				slog.Duration("max_age", maxAge))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			slog.Error("failed to encode liveness response", slog.String("error", err.Error()))
+		}
+	}
+}
+
 type HealthCheckResult struct {
 	Status    string         `json:"status"`
 	LatencyMs int64          `json:"latency_ms,omitempty"`
+	Critical  bool           `json:"critical,omitempty"`
 	Metadata  map[string]any `json:"metadata,omitempty"`
 	Error     string         `json:"error,omitempty"`
 }
 
+// DependencyCheck describes one dependency probed by the readiness endpoint.
+// A failing Critical dependency makes the service not ready (503); a failing
+// non-critical dependency only degrades it (200).
+type DependencyCheck struct {
+	Name     string
+	Critical bool
+	Check    func(ctx context.Context) HealthCheckResult
+}
+
+// ReadinessConfig tunes the readiness checker
+type ReadinessConfig struct {
+	// Timeout bounds each individual dependency check.
+	Timeout time.Duration
+	// CacheTTL is how long a computed report is reused, protecting
+	// dependencies from aggressive probing.
+	CacheTTL time.Duration
+	// DegradedLatency marks a healthy dependency as degraded when its check
+	// takes longer than this value. Zero disables latency-based degradation.
+	DegradedLatency time.Duration
+}
+
+// DefaultReadinessConfig returns sensible defaults for orchestration probes
+func DefaultReadinessConfig() ReadinessConfig {
+	return ReadinessConfig{
+		Timeout:         2 * time.Second,
+		CacheTTL:        3 * time.Second,
+		DegradedLatency: 500 * time.Millisecond,
+	}
+}
+
+// ReadinessReport is the JSON body returned by the readiness endpoint
+type ReadinessReport struct {
+	Status    string                       `json:"status"`
+	Timestamp string                       `json:"timestamp"`
+	Cached    bool                         `json:"cached"`
+	Checks    map[string]HealthCheckResult `json:"checks"`
+}
+
+// ReadinessChecker runs dependency checks concurrently and caches the result
+type ReadinessChecker struct {
+	cfg    ReadinessConfig
+	checks []DependencyCheck
+
+	mu       sync.Mutex
+	cached   *ReadinessReport
+	cachedAt time.Time
+}
+
+func NewReadinessChecker(cfg ReadinessConfig, checks ...DependencyCheck) *ReadinessChecker {
+	return &ReadinessChecker{
+		cfg:    cfg,
+		checks: checks,
+	}
+}
+
+// Ready returns a readiness handler checking the database and RabbitMQ
+// with the default configuration.
 func Ready(db *sql.DB, rmq *messaging.RabbitMQ) http.HandlerFunc {
+	return NewReadinessChecker(DefaultReadinessConfig(),
+		DatabaseCheck(db),
+		RabbitMQCheck(rmq),
+	).Handler()
+}
+
+// Handler serves the readiness report: 200 when ready or degraded,
+// 503 when a critical dependency is down.
+func (c *ReadinessChecker) Handler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-		defer cancel()
+		report := c.Report(r.Context())
 
-		dbResult := make(chan HealthCheckResult, 1)
-		rmqResult := make(chan HealthCheckResult, 1)
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status == ReadyStatusNotReady {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
 
-		go func() {
-			dbResult <- checkDatabase(ctx, db)
-		}()
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			slog.Error("failed to encode ready response", slog.String("error", err.Error()))
+			return
+		}
+	}
+}
 
+// Report returns the cached report if still fresh, otherwise runs all checks.
+// Concurrent callers during a refresh wait for the same result. The checks
+// are bounded by the configured timeout rather than by ctx, so a probe that
+// gives up early does not cache failures for the others.
+func (c *ReadinessChecker) Report(ctx context.Context) ReadinessReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cached != nil && time.Since(c.cachedAt) < c.cfg.CacheTTL {
+		report := *c.cached
+		report.Cached = true
+		return report
+	}
+
+	report := c.run(context.WithoutCancel(ctx))
+	c.cached = &report
+	c.cachedAt = time.Now()
+	return report
+}
+
+func (c *ReadinessChecker) run(ctx context.Context) ReadinessReport {
+	results := make([]HealthCheckResult, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
 		go func() {
-			rmqResult <- checkRabbitMQ(ctx, rmq)
+			defer wg.Done()
+			results[i] = c.runCheck(ctx, check)
 		}()
+	}
+	wg.Wait()
+
+	report := ReadinessReport{
+		Status:    ReadyStatusReady,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Checks:    make(map[string]HealthCheckResult, len(c.checks)),
+	}
 
-		dbCheck := <-dbResult
-		rmqCheck := <-rmqResult
+	for i, check := range c.checks {
+		result := results[i]
+		report.Checks[check.Name] = result
 
-		response := map[string]any{
-			"timestamp": time.Now().Format(time.RFC3339),
-			"checks": map[string]HealthCheckResult{
-				"database": dbCheck,
-				"rabbitmq": rmqCheck,
-			},
+		switch {
+		case result.Status == CheckStatusDown && check.Critical:
+			report.Status = ReadyStatusNotReady
+		case result.Status != CheckStatusUp && report.Status == ReadyStatusReady:
+			report.Status = ReadyStatusDegraded
 		}
+	}
 
-		allHealthy := dbCheck.Status == "up" && rmqCheck.Status == "up"
+	return report
+}
 
-		if allHealthy {
-			response["status"] = "ready"
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-		} else {
-			response["status"] = "not_ready"
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusServiceUnavailable)
-		}
+// runCheck executes a single check bounded by the configured timeout.
+// A check that does not return in time is reported as down.
+func (c *ReadinessChecker) runCheck(ctx context.Context, check DependencyCheck) HealthCheckResult {
+	timeout := c.cfg.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			slog.Error("failed to encode ready response", slog.String("error", err.Error()))
-			return
+	resultCh := make(chan HealthCheckResult, 1)
+	go func() {
+		resultCh <- check.Check(checkCtx)
+	}()
+
+	var result HealthCheckResult
+	select {
+	case result = <-resultCh:
+	case <-checkCtx.Done():
+		result = HealthCheckResult{
+			Status:    CheckStatusDown,
+			LatencyMs: timeout.Milliseconds(),
+			Error:     "check timed out",
 		}
 	}
+
+	result.Critical = check.Critical
+	if result.Status == CheckStatusUp && c.cfg.DegradedLatency > 0 &&
+		time.Duration(result.LatencyMs)*time.Millisecond > c.cfg.DegradedLatency {
+		result.Status = CheckStatusDegraded
+	}
+	return result
+}
+
+// DatabaseCheck probes PostgreSQL with a ping (critical)
+func DatabaseCheck(db *sql.DB) DependencyCheck {
+	return DependencyCheck{
+		Name:     "database",
+		Critical: true,
+		Check: func(ctx context.Context) HealthCheckResult {
+			return checkDatabase(ctx, db)
+		},
+	}
+}
+
+// RabbitMQCheck reports the RabbitMQ connection state (critical)
+func RabbitMQCheck(rmq *messaging.RabbitMQ) DependencyCheck {
+	return DependencyCheck{
+		Name:     "rabbitmq",
+		Critical: true,
+		Check: func(ctx context.Context) HealthCheckResult {
+			return checkRabbitMQ(ctx, rmq)
+		},
+	}
+}
+
+// RedisCheck probes a Redis server with a RESP PING (non-critical).
+// redisURL has the form redis://[:password@]host:port[/db].
+func RedisCheck(redisURL string) DependencyCheck {
+	return DependencyCheck{
+		Name:     "redis",
+		Critical: false,
+		Check: func(ctx context.Context) HealthCheckResult {
+			return checkRedis(ctx, redisURL)
+		},
+	}
+}
+
+// Pinger is implemented by dependencies that can verify their own reachability
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// StooqCheck verifies the Stooq API is reachable (non-critical: the bot
+// still answers commands with an error message while Stooq is down).
+func StooqCheck(stooq Pinger) DependencyCheck {
+	return DependencyCheck{
+		Name:     "stooq",
+		Critical: false,
+		Check: func(ctx context.Context) HealthCheckResult {
+			return checkPinger(ctx, stooq)
+		},
+	}
 }
 
 func checkDatabase(ctx context.Context, db *sql.DB) HealthCheckResult {
@@ -84,14 +336,14 @@ func checkDatabase(ctx context.Context, db *sql.DB) HealthCheckResult {
 
 	if err != nil {
 		return HealthCheckResult{
-			Status:    "down",
+			Status:    CheckStatusDown,
 			LatencyMs: latency.Milliseconds(),
 			Error:     err.Error(),
 		}
 	}
 
 	return HealthCheckResult{
-		Status:    "up",
+		Status:    CheckStatusUp,
 		LatencyMs: latency.Milliseconds(),
 		Metadata: map[string]any{
 			"connections_open":   stats.OpenConnections,
@@ -107,7 +359,7 @@ func checkRabbitMQ(_ context.Context, rmq *messaging.RabbitMQ) HealthCheckResult
 
 	if rmq.IsClosed() {
 		return HealthCheckResult{
-			Status: "down",
+			Status: CheckStatusDown,
 			Error:  "connection closed",
 		}
 	}
@@ -115,7 +367,98 @@ func checkRabbitMQ(_ context.Context, rmq *messaging.RabbitMQ) HealthCheckResult
 	latency := time.Since(start)
 
 	return HealthCheckResult{
-		Status:    "up",
+		Status:    CheckStatusUp,
+		LatencyMs: latency.Milliseconds(),
+	}
+}
+
+func checkRedis(ctx context.Context, redisURL string) HealthCheckResult {
+	start := time.Now()
+	err := pingRedis(ctx, redisURL)
+	latency := time.Since(start)
+
+	if err != nil {
+		return HealthCheckResult{
+			Status:    CheckStatusDown,
+			LatencyMs: latency.Milliseconds(),
+			Error:     err.Error(),
+		}
+	}
+
+	return HealthCheckResult{
+		Status:    CheckStatusUp,
+		LatencyMs: latency.Milliseconds(),
+	}
+}
+
+func checkPinger(ctx context.Context, p Pinger) HealthCheckResult {
+	start := time.Now()
+	err := p.Ping(ctx)
+	latency := time.Since(start)
+
+	if err != nil {
+		return HealthCheckResult{
+			Status:    CheckStatusDown,
+			LatencyMs: latency.Milliseconds(),
+			Error:     err.Error(),
+		}
+	}
+
+	return HealthCheckResult{
+		Status:    CheckStatusUp,
 		LatencyMs: latency.Milliseconds(),
 	}
 }
+
+// pingRedis speaks just enough RESP to authenticate and PING
+func pingRedis(ctx context.Context, redisURL string) error {
+	u, err := url.Parse(redisURL)
+	if err != nil {
+		return fmt.Errorf("invalid redis url: %w", err)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	reader := bufio.NewReader(conn)
+
+	if password, ok := u.User.Password(); ok && password != "" {
+		if err := redisCommand(conn, reader, "+OK", "AUTH", password); err != nil {
+			return fmt.Errorf("auth failed: %w", err)
+		}
+	}
+
+	return redisCommand(conn, reader, "+PONG", "PING")
+}
+
+func redisCommand(conn net.Conn, reader *bufio.Reader, want string, args ...string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := conn.Write([]byte(b.String())); err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("read failed: %w", err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line != want {
+		return fmt.Errorf("unexpected reply %q", line)
+	}
+	return nil
+}
@@ -1,10 +1,18 @@
 package handler
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"jobsity-chat/internal/testutil"
 )
@@ -254,6 +262,217 @@ func TestHealth_ValidJSON(t *testing.T) {
 	testutil.AssertNoError(t, err)
 }
 
+func staticCheck(name string, critical bool, status string, calls *atomic.Int32) DependencyCheck {
+	return DependencyCheck{
+		Name:     name,
+		Critical: critical,
+		Check: func(ctx context.Context) HealthCheckResult {
+			if calls != nil {
+				calls.Add(1)
+			}
+			return HealthCheckResult{Status: status}
+		},
+	}
+}
+
+func TestReadinessChecker_Statuses(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []DependencyCheck
+		wantCode   int
+		wantStatus string
+	}{
+		{
+			name: "all up is ready",
+			checks: []DependencyCheck{
+				staticCheck("database", true, CheckStatusUp, nil),
+				staticCheck("redis", false, CheckStatusUp, nil),
+			},
+			wantCode:   http.StatusOK,
+			wantStatus: ReadyStatusReady,
+		},
+		{
+			name: "non-critical down is degraded",
+			checks: []DependencyCheck{
+				staticCheck("database", true, CheckStatusUp, nil),
+				staticCheck("redis", false, CheckStatusDown, nil),
+			},
+			wantCode:   http.StatusOK,
+			wantStatus: ReadyStatusDegraded,
+		},
+		{
+			name: "critical down is not ready",
+			checks: []DependencyCheck{
+				staticCheck("database", true, CheckStatusDown, nil),
+				staticCheck("redis", false, CheckStatusDown, nil),
+			},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: ReadyStatusNotReady,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewReadinessChecker(DefaultReadinessConfig(), tt.checks...)
+
+			req := httptest.NewRequest(http.MethodGet, "/health/ready", nil)
+			w := httptest.NewRecorder()
+			checker.Handler()(w, req)
+
+			testutil.AssertStatusCode(t, w, tt.wantCode)
+
+			var report ReadinessReport
+			testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&report))
+			testutil.AssertEqual(t, report.Status, tt.wantStatus)
+			testutil.AssertEqual(t, len(report.Checks), len(tt.checks))
+		})
+	}
+}
+
+func TestReadinessChecker_CachesResults(t *testing.T) {
+	var calls atomic.Int32
+	cfg := DefaultReadinessConfig()
+	cfg.CacheTTL = time.Minute
+	checker := NewReadinessChecker(cfg, staticCheck("database", true, CheckStatusUp, &calls))
+
+	first := checker.Report(context.Background())
+	second := checker.Report(context.Background())
+
+	testutil.AssertEqual(t, calls.Load(), int32(1))
+	testutil.AssertFalse(t, first.Cached, "first report should be fresh")
+	testutil.AssertTrue(t, second.Cached, "second report should be cached")
+}
+
+func TestReadinessChecker_CanceledProbeIsNotCached(t *testing.T) {
+	cfg := DefaultReadinessConfig()
+	cfg.CacheTTL = time.Minute
+	check := DependencyCheck{
+		Name:     "database",
+		Critical: true,
+		Check: func(ctx context.Context) HealthCheckResult {
+			if err := ctx.Err(); err != nil {
+				return HealthCheckResult{Status: CheckStatusDown, Error: err.Error()}
+			}
+			return HealthCheckResult{Status: CheckStatusUp}
+		},
+	}
+	checker := NewReadinessChecker(cfg, check)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	testutil.AssertEqual(t, checker.Report(ctx).Status, ReadyStatusReady)
+
+	report := checker.Report(context.Background())
+	testutil.AssertTrue(t, report.Cached, "second report should be cached")
+	testutil.AssertEqual(t, report.Status, ReadyStatusReady)
+}
+
+func TestReadinessChecker_TimeoutMarksDown(t *testing.T) {
+	cfg := DefaultReadinessConfig()
+	cfg.Timeout = 20 * time.Millisecond
+	slow := DependencyCheck{
+		Name:     "database",
+		Critical: true,
+		Check: func(ctx context.Context) HealthCheckResult {
+			time.Sleep(200 * time.Millisecond)
+			return HealthCheckResult{Status: CheckStatusUp}
+		},
+	}
+
+	report := NewReadinessChecker(cfg, slow).Report(context.Background())
+
+	testutil.AssertEqual(t, report.Status, ReadyStatusNotReady)
+	testutil.AssertEqual(t, report.Checks["database"].Error, "check timed out")
+}
+
+func TestReadinessChecker_SlowCheckIsDegraded(t *testing.T) {
+	cfg := DefaultReadinessConfig()
+	cfg.DegradedLatency = 10 * time.Millisecond
+	slow := DependencyCheck{
+		Name:     "database",
+		Critical: true,
+		Check: func(ctx context.Context) HealthCheckResult {
+			return HealthCheckResult{Status: CheckStatusUp, LatencyMs: 50}
+		},
+	}
+
+	report := NewReadinessChecker(cfg, slow).Report(context.Background())
+
+	testutil.AssertEqual(t, report.Status, ReadyStatusDegraded)
+	testutil.AssertEqual(t, report.Checks["database"].Status, CheckStatusDegraded)
+}
+
+// startFakeRedis serves a minimal RESP server answering PING (and AUTH when password is set)
+func startFakeRedis(t *testing.T, password string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	testutil.AssertNoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				r := bufio.NewReader(c)
+				for {
+					header, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					var args []string
+					var n int
+					if _, err := fmt.Sscanf(header, "*%d", &n); err != nil {
+						return
+					}
+					for range n {
+						r.ReadString('\n')
+						arg, _ := r.ReadString('\n')
+						args = append(args, strings.TrimRight(arg, "\r\n"))
+					}
+					switch {
+					case args[0] == "AUTH" && len(args) == 2 && args[1] == password:
+						c.Write([]byte("+OK\r\n"))
+					case args[0] == "AUTH":
+						c.Write([]byte("-ERR invalid password\r\n"))
+					case args[0] == "PING":
+						c.Write([]byte("+PONG\r\n"))
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestRedisCheck(t *testing.T) {
+	addr := startFakeRedis(t, "pw")
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus string
+	}{
+		{"ping with auth", "redis://:pw@" + addr, CheckStatusUp},
+		{"wrong password", "redis://:bad@" + addr, CheckStatusDown},
+		{"unreachable", "redis://127.0.0.1:1", CheckStatusDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			result := RedisCheck(tt.url).Check(ctx)
+			testutil.AssertEqual(t, result.Status, tt.wantStatus)
+		})
+	}
+}
+
 // Benchmark health endpoint
 func BenchmarkHealth(b *testing.B) {
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
@@ -264,3 +483,55 @@ func BenchmarkHealth(b *testing.B) {
 		Health(w, req)
 	}
 }
+
+type fakeHeartbeat struct {
+	last time.Time
+}
+
+func (f fakeHeartbeat) LastHeartbeat() time.Time { return f.last }
+
+func TestLiveness(t *testing.T) {
+	tests := []struct {
+		name       string
+		last       time.Time
+		wantCode   int
+		wantStatus string
+	}{
+		{"fresh heartbeat", time.Now(), http.StatusOK, "ok"},
+		{"stale heartbeat", time.Now().Add(-time.Minute), http.StatusServiceUnavailable, "unhealthy"},
+		{"hub not running", time.Time{}, http.StatusServiceUnavailable, "unhealthy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/health", nil)
+			w := httptest.NewRecorder()
+
+			Liveness(fakeHeartbeat{last: tt.last}, 15*time.Second)(w, req)
+
+			testutil.AssertStatusCode(t, w, tt.wantCode)
+
+			var resp LivenessResponse
+			testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			testutil.AssertEqual(t, resp.Status, tt.wantStatus)
+			testutil.AssertNotNil(t, resp.Hub)
+		})
+	}
+}
+
+type fakePinger struct {
+	err error
+}
+
+func (f fakePinger) Ping(ctx context.Context) error { return f.err }
+
+func TestStooqCheck(t *testing.T) {
+	check := StooqCheck(fakePinger{})
+	testutil.AssertFalse(t, check.Critical, "stooq check should not be critical")
+	testutil.AssertEqual(t, check.Check(context.Background()).Status, CheckStatusUp)
+
+	down := StooqCheck(fakePinger{err: errors.New("dial tcp: connection refused")})
+	result := down.Check(context.Background())
+	testutil.AssertEqual(t, result.Status, CheckStatusDown)
+	testutil.AssertContains(t, result.Error, "connection refused")
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	ws "jobsity-chat/internal/websocket"
+)
+
+// HubStatsSource reports the state of the WebSocket hub
+type HubStatsSource interface {
+	Stats() ws.HubStats
+}
+
+// HubStatsHandler reports the WebSocket hub's connections and queues to
+// administrators. It must be guarded by middleware.RequireAdmin.
+type HubStatsHandler struct {
+	hub HubStatsSource
+}
+
+func NewHubStatsHandler(hub HubStatsSource) *HubStatsHandler {
+	return &HubStatsHandler{hub: hub}
+}
+
+// Stats returns a snapshot of this server's hub. The numbers cover every
+// organization connected to the server.
+func (h *HubStatsHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(h.hub.Stats())
+}
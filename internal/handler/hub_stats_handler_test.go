@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"jobsity-chat/internal/testutil"
+	ws "jobsity-chat/internal/websocket"
+)
+
+type stubHubStats ws.HubStats
+
+func (s stubHubStats) Stats() ws.HubStats { return ws.HubStats(s) }
+
+func TestHubStatsHandler_Stats(t *testing.T) {
+	handler := NewHubStatsHandler(stubHubStats{Rooms: 2, Connections: 5, UniqueUsers: 4, DroppedMessages: 3})
+
+	w := httptest.NewRecorder()
+	handler.Stats(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/hub/stats", nil))
+
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	var resp ws.HubStats
+	testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	testutil.AssertEqual(t, resp.Rooms, 2)
+	testutil.AssertEqual(t, resp.Connections, 5)
+	testutil.AssertEqual(t, resp.UniqueUsers, 4)
+	testutil.AssertEqual(t, resp.DroppedMessages, uint64(3))
+}
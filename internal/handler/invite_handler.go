@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// InviteServiceInterface manages emailed chatroom invites
+type InviteServiceInterface interface {
+	Invite(ctx context.Context, chatroomID, requesterID, email string) (*domain.RoomInvite, error)
+	PendingInvites(ctx context.Context, chatroomID, requesterID string) ([]*domain.RoomInvite, error)
+	RevokeInvite(ctx context.Context, chatroomID, requesterID, inviteID string) error
+	LookupInvite(ctx context.Context, token string) (*domain.RoomInvite, error)
+	AcceptInvite(ctx context.Context, token, userID string) (*domain.RoomInvite, error)
+}
+
+type InviteHandler struct {
+	invites InviteServiceInterface
+}
+
+func NewInviteHandler(invites InviteServiceInterface) *InviteHandler {
+	return &InviteHandler{invites: invites}
+}
+
+type CreateInviteRequest struct {
+	Email string `json:"email"`
+}
+
+type InvitesResponse struct {
+	Invites []*domain.RoomInvite `json:"invites"`
+}
+
+// InviteTokenRequest carries the token of an invite link. Tokens are sent in
+// the body rather than the path so they stay out of access logs.
+type InviteTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// InvitePreview is what an invite link shows before it is accepted
+type InvitePreview struct {
+	Email        string    `json:"email"`
+	ChatroomID   string    `json:"chatroom_id"`
+	ChatroomName string    `json:"chatroom_name"`
+	InviterName  string    `json:"inviter_name"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type AcceptInviteResponse struct {
+	ChatroomID string `json:"chatroom_id"`
+}
+
+// Create emails an invite to the chatroom (owner only)
+func (h *InviteHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	var req CreateInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	invite, err := h.invites.Invite(r.Context(), chatroomID, userID, req.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidInput):
+			http.Error(w, `{"error":"Invalid email address"}`, http.StatusBadRequest)
+		default:
+			writeInviteOwnerError(w, err, chatroomID, "Failed to send invite")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(invite); err != nil {
+		slog.Error("failed to encode invite response", slog.String("error", err.Error()))
+	}
+}
+
+// List returns the chatroom's pending invites (owner only)
+func (h *InviteHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	invites, err := h.invites.PendingInvites(r.Context(), chatroomID, userID)
+	if err != nil {
+		writeInviteOwnerError(w, err, chatroomID, "Failed to list invites")
+		return
+	}
+	if invites == nil {
+		invites = []*domain.RoomInvite{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(InvitesResponse{Invites: invites}); err != nil {
+		slog.Error("failed to encode invites response", slog.String("error", err.Error()))
+	}
+}
+
+// Revoke cancels a pending invite (owner only)
+func (h *InviteHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	err := h.invites.RevokeInvite(r.Context(), chatroomID, userID, chi.URLParam(r, "invite_id"))
+	if err != nil {
+		if errors.Is(err, domain.ErrInviteNotFound) {
+			http.Error(w, `{"error":"Invite not found"}`, http.StatusNotFound)
+			return
+		}
+		writeInviteOwnerError(w, err, chatroomID, "Failed to revoke invite")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Lookup describes the invite behind a link, for the registration form
+func (h *InviteHandler) Lookup(w http.ResponseWriter, r *http.Request) {
+	var req InviteTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	invite, err := h.invites.LookupInvite(r.Context(), req.Token)
+	if err != nil {
+		writeInviteTokenError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(InvitePreview{
+		Email:        invite.Email,
+		ChatroomID:   invite.ChatroomID,
+		ChatroomName: invite.ChatroomName,
+		InviterName:  invite.InviterName,
+		ExpiresAt:    invite.ExpiresAt,
+	}); err != nil {
+		slog.Error("failed to encode invite preview", slog.String("error", err.Error()))
+	}
+}
+
+// Accept joins the signed-in user to the invite's chatroom
+func (h *InviteHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req InviteTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	invite, err := h.invites.AcceptInvite(r.Context(), req.Token, userID)
+	if err != nil {
+		writeInviteTokenError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(AcceptInviteResponse{ChatroomID: invite.ChatroomID}); err != nil {
+		slog.Error("failed to encode accept invite response", slog.String("error", err.Error()))
+	}
+}
+
+func writeInviteOwnerError(w http.ResponseWriter, err error, chatroomID, message string) {
+	switch {
+	case errors.Is(err, domain.ErrChatroomNotFound):
+		http.Error(w, `{"error":"Chatroom not found"}`, http.StatusNotFound)
+	case errors.Is(err, domain.ErrNotOwner):
+		http.Error(w, `{"error":"Only the chatroom owner can manage invites"}`, http.StatusForbidden)
+	default:
+		slog.Error("chatroom invite request failed",
+			slog.String("error", err.Error()),
+			slog.String("chatroom_id", chatroomID))
+		http.Error(w, `{"error":"`+message+`"}`, http.StatusInternalServerError)
+	}
+}
+
+func writeInviteTokenError(w http.ResponseWriter, err error) {
+	if errors.Is(err, domain.ErrInviteNotFound) {
+		http.Error(w, `{"error":"Invite not found or expired"}`, http.StatusNotFound)
+		return
+	}
+	slog.Error("invite token request failed", slog.String("error", err.Error()))
+	http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+	"jobsity-chat/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// mockInvites serves one pending invite to room-1, owned by owner-1, whose
+// token is "valid"
+type mockInvites struct {
+	accepted map[string]string // token -> user ID
+	revoked  bool
+}
+
+func (m *mockInvites) owner(chatroomID, requesterID string) error {
+	if chatroomID != "room-1" {
+		return domain.ErrChatroomNotFound
+	}
+	if requesterID != "owner-1" {
+		return domain.ErrNotOwner
+	}
+	return nil
+}
+
+func (m *mockInvites) Invite(ctx context.Context, chatroomID, requesterID, email string) (*domain.RoomInvite, error) {
+	if err := m.owner(chatroomID, requesterID); err != nil {
+		return nil, err
+	}
+	switch email {
+	case "":
+		return nil, domain.ErrInvalidInput
+	}
+	return &domain.RoomInvite{ID: "invite-1", ChatroomID: chatroomID, Email: email, Token: "secret", TokenHash: "hash"}, nil
+}
+
+func (m *mockInvites) PendingInvites(ctx context.Context, chatroomID, requesterID string) ([]*domain.RoomInvite, error) {
+	if err := m.owner(chatroomID, requesterID); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (m *mockInvites) RevokeInvite(ctx context.Context, chatroomID, requesterID, inviteID string) error {
+	if err := m.owner(chatroomID, requesterID); err != nil {
+		return err
+	}
+	if m.revoked || inviteID != "invite-1" {
+		return domain.ErrInviteNotFound
+	}
+	m.revoked = true
+	return nil
+}
+
+func (m *mockInvites) LookupInvite(ctx context.Context, token string) (*domain.RoomInvite, error) {
+	if token != "valid" || m.accepted[token] != "" {
+		return nil, domain.ErrInviteNotFound
+	}
+	return &domain.RoomInvite{
+		ID:           "invite-1",
+		ChatroomID:   "room-1",
+		Email:        "new@example.com",
+		InvitedBy:    "owner-1",
+		ExpiresAt:    time.Now().Add(time.Hour),
+		ChatroomName: "general",
+		InviterName:  "alice",
+	}, nil
+}
+
+func (m *mockInvites) AcceptInvite(ctx context.Context, token, userID string) (*domain.RoomInvite, error) {
+	invite, err := m.LookupInvite(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	m.accepted[token] = userID
+	return invite, nil
+}
+
+func inviteRequest(method, target, body, userID string, params map[string]string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	for key, value := range params {
+		rctx.URLParams.Add(key, value)
+	}
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	if userID != "" {
+		ctx = middleware.WithUserID(ctx, userID)
+	}
+	return req.WithContext(ctx)
+}
+
+func TestInviteHandler_Create(t *testing.T) {
+	tests := []struct {
+		name           string
+		chatroomID     string
+		userID         string
+		body           string
+		expectedStatus int
+	}{
+		{"created", "room-1", "owner-1", `{"email":"new@example.com"}`, http.StatusCreated},
+		{"invalid_email", "room-1", "owner-1", `{"email":""}`, http.StatusBadRequest},
+		{"invalid_body", "room-1", "owner-1", `{`, http.StatusBadRequest},
+		{"not_owner", "room-1", "user-2", `{"email":"new@example.com"}`, http.StatusForbidden},
+		{"unknown_chatroom", "missing", "owner-1", `{"email":"new@example.com"}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewInviteHandler(&mockInvites{accepted: make(map[string]string)})
+			req := inviteRequest(http.MethodPost, "/api/v1/chatrooms/"+tt.chatroomID+"/invites", tt.body, tt.userID,
+				map[string]string{"id": tt.chatroomID})
+			w := httptest.NewRecorder()
+
+			handler.Create(w, req)
+
+			testutil.AssertEqual(t, w.Code, tt.expectedStatus)
+			if tt.expectedStatus == http.StatusCreated {
+				testutil.AssertContains(t, w.Body.String(), `"email":"new@example.com"`)
+				testutil.AssertNotContains(t, w.Body.String(), "secret")
+				testutil.AssertNotContains(t, w.Body.String(), "hash")
+			}
+		})
+	}
+}
+
+func TestInviteHandler_ListAndRevoke(t *testing.T) {
+	handler := NewInviteHandler(&mockInvites{accepted: make(map[string]string)})
+
+	w := httptest.NewRecorder()
+	handler.List(w, inviteRequest(http.MethodGet, "/api/v1/chatrooms/room-1/invites", "", "owner-1", map[string]string{"id": "room-1"}))
+	testutil.AssertEqual(t, w.Code, http.StatusOK)
+	testutil.AssertEqual(t, strings.TrimSpace(w.Body.String()), `{"invites":[]}`)
+
+	w = httptest.NewRecorder()
+	handler.List(w, inviteRequest(http.MethodGet, "/api/v1/chatrooms/room-1/invites", "", "user-2", map[string]string{"id": "room-1"}))
+	testutil.AssertEqual(t, w.Code, http.StatusForbidden)
+
+	for _, expected := range []int{http.StatusNoContent, http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		handler.Revoke(w, inviteRequest(http.MethodDelete, "/api/v1/chatrooms/room-1/invites/invite-1", "", "owner-1",
+			map[string]string{"id": "room-1", "invite_id": "invite-1"}))
+		testutil.AssertEqual(t, w.Code, expected)
+	}
+}
+
+func TestInviteHandler_LookupAndAccept(t *testing.T) {
+	invites := &mockInvites{accepted: make(map[string]string)}
+	handler := NewInviteHandler(invites)
+
+	w := httptest.NewRecorder()
+	handler.Lookup(w, inviteRequest(http.MethodPost, "/api/v1/invites/lookup", `{"token":"valid"}`, "", nil))
+	testutil.AssertEqual(t, w.Code, http.StatusOK)
+	testutil.AssertHeader(t, w, "Cache-Control", "no-store")
+	var preview InvitePreview
+	testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&preview))
+	testutil.AssertEqual(t, preview.Email, "new@example.com")
+	testutil.AssertEqual(t, preview.ChatroomName, "general")
+	testutil.AssertEqual(t, preview.InviterName, "alice")
+
+	w = httptest.NewRecorder()
+	handler.Lookup(w, inviteRequest(http.MethodPost, "/api/v1/invites/lookup", `{"token":""}`, "", nil))
+	testutil.AssertEqual(t, w.Code, http.StatusBadRequest)
+
+	w = httptest.NewRecorder()
+	handler.Accept(w, inviteRequest(http.MethodPost, "/api/v1/invites/accept", `{"token":"valid"}`, "user-2", nil))
+	testutil.AssertEqual(t, w.Code, http.StatusOK)
+	testutil.AssertEqual(t, strings.TrimSpace(w.Body.String()), `{"chatroom_id":"room-1"}`)
+	testutil.AssertEqual(t, invites.accepted["valid"], "user-2")
+
+	w = httptest.NewRecorder()
+	handler.Accept(w, inviteRequest(http.MethodPost, "/api/v1/invites/accept", `{"token":"valid"}`, "user-3", nil))
+	testutil.AssertEqual(t, w.Code, http.StatusNotFound)
+}
@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ModerationHandler serves message flagging, the moderation queue and
+// shadow-bans. The queue, resolve and restore routes must be guarded by
+// middleware.RequireAdmin, the shadow-ban routes by middleware.RequireModerator.
+type ModerationHandler struct {
+	moderation ModerationService
+}
+
+// ModerationService flags, reviews and restores messages and shadow-bans
+// users
+type ModerationService interface {
+	FlagMessage(ctx context.Context, messageID, reporterID, reason string) (bool, error)
+	Queue(ctx context.Context, limit int) ([]*domain.FlaggedMessage, error)
+	Resolve(ctx context.Context, messageID, moderatorID, action string) error
+	RestoreMessage(ctx context.Context, messageID, adminID string) error
+	SetShadowBan(ctx context.Context, chatroomID, userID, moderatorID string, banned bool) error
+	ShadowBannedUsers(ctx context.Context, chatroomID string) ([]string, error)
+}
+
+func NewModerationHandler(moderation ModerationService) *ModerationHandler {
+	return &ModerationHandler{moderation: moderation}
+}
+
+type FlagMessageRequest struct {
+	Reason string `json:"reason"`
+}
+
+type FlagMessageResponse struct {
+	Success bool `json:"success"`
+	// Hidden reports whether the message reached the flag threshold and is
+	// hidden until reviewed
+	Hidden bool `json:"hidden"`
+}
+
+type ModerationQueueResponse struct {
+	Flags []*domain.FlaggedMessage `json:"flags"`
+}
+
+type ResolveFlagsRequest struct {
+	Action string `json:"action"`
+}
+
+type ShadowBansResponse struct {
+	UserIDs []string `json:"user_ids"`
+}
+
+// Flag reports a message to moderators. The reason is optional.
+func (h *ModerationHandler) Flag(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req FlagMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	messageID := chi.URLParam(r, "id")
+	hidden, err := h.moderation.FlagMessage(r.Context(), messageID, userID, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrMessageNotFound):
+			http.Error(w, `{"error":"Message not found"}`, http.StatusNotFound)
+		case errors.Is(err, domain.ErrNotMember):
+			http.Error(w, `{"error":"Not a member of this chatroom"}`, http.StatusForbidden)
+		case errors.Is(err, domain.ErrAlreadyFlagged):
+			http.Error(w, `{"error":"Message already flagged"}`, http.StatusConflict)
+		case errors.Is(err, domain.ErrInvalidInput):
+			http.Error(w, `{"error":"Cannot flag this message"}`, http.StatusBadRequest)
+		default:
+			slog.Error("failed to flag message",
+				slog.String("error", err.Error()),
+				slog.String("message_id", messageID))
+			http.Error(w, `{"error":"Failed to flag message"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(FlagMessageResponse{Success: true, Hidden: hidden})
+}
+
+// Queue lists messages with open flags, most flagged first, up to the
+// "limit" query parameter (default 50, max 200)
+func (h *ModerationHandler) Queue(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, `{"error":"Invalid limit"}`, http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	flags, err := h.moderation.Queue(r.Context(), limit)
+	if err != nil {
+		slog.Error("failed to get moderation queue", slog.String("error", err.Error()))
+		http.Error(w, `{"error":"Failed to get moderation queue"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ModerationQueueResponse{Flags: flags})
+}
+
+// Resolve closes the flags on a message with the "keep" or "delete" action
+func (h *ModerationHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req ResolveFlagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	messageID := chi.URLParam(r, "id")
+	err := h.moderation.Resolve(r.Context(), messageID, userID, req.Action)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidInput):
+			http.Error(w, `{"error":"Action must be keep or delete"}`, http.StatusBadRequest)
+		case errors.Is(err, domain.ErrNoOpenFlags):
+			http.Error(w, `{"error":"Message has no open flags"}`, http.StatusNotFound)
+		default:
+			slog.Error("failed to resolve flags",
+				slog.String("error", err.Error()),
+				slog.String("message_id", messageID))
+			http.Error(w, `{"error":"Failed to resolve flags"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// RestoreMessage shows a deleted message again, if it has not been purged yet
+func (h *ModerationHandler) RestoreMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	messageID := chi.URLParam(r, "id")
+	if err := h.moderation.RestoreMessage(r.Context(), messageID, userID); err != nil {
+		if errors.Is(err, domain.ErrMessageNotFound) {
+			http.Error(w, `{"error":"No deleted message with this ID"}`, http.StatusNotFound)
+			return
+		}
+		slog.Error("failed to restore message",
+			slog.String("error", err.Error()),
+			slog.String("message_id", messageID))
+		http.Error(w, `{"error":"Failed to restore message"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// ShadowBan shadow-bans a member of a chatroom
+func (h *ModerationHandler) ShadowBan(w http.ResponseWriter, r *http.Request) {
+	h.setShadowBan(w, r, true)
+}
+
+// LiftShadowBan lifts a shadow-ban
+func (h *ModerationHandler) LiftShadowBan(w http.ResponseWriter, r *http.Request) {
+	h.setShadowBan(w, r, false)
+}
+
+func (h *ModerationHandler) setShadowBan(w http.ResponseWriter, r *http.Request, banned bool) {
+	moderatorID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	userID := chi.URLParam(r, "user_id")
+	err := h.moderation.SetShadowBan(r.Context(), chatroomID, userID, moderatorID, banned)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrChatroomNotFound):
+			http.Error(w, `{"error":"Chatroom not found"}`, http.StatusNotFound)
+		case errors.Is(err, domain.ErrNotMember):
+			http.Error(w, `{"error":"User is not a member of this chatroom"}`, http.StatusNotFound)
+		case errors.Is(err, domain.ErrInvalidInput):
+			http.Error(w, `{"error":"Cannot shadow-ban yourself"}`, http.StatusBadRequest)
+		default:
+			slog.Error("failed to update shadow-ban",
+				slog.String("error", err.Error()),
+				slog.String("chatroom_id", chatroomID),
+				slog.String("user_id", userID))
+			http.Error(w, `{"error":"Failed to update shadow-ban"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// ListShadowBans lists the users shadow-banned in a chatroom
+func (h *ModerationHandler) ListShadowBans(w http.ResponseWriter, r *http.Request) {
+	chatroomID := chi.URLParam(r, "id")
+	userIDs, err := h.moderation.ShadowBannedUsers(r.Context(), chatroomID)
+	if err != nil {
+		slog.Error("failed to list shadow-bans",
+			slog.String("error", err.Error()),
+			slog.String("chatroom_id", chatroomID))
+		http.Error(w, `{"error":"Failed to list shadow-bans"}`, http.StatusInternalServerError)
+		return
+	}
+	if userIDs == nil {
+		userIDs = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ShadowBansResponse{UserIDs: userIDs})
+}
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+	"jobsity-chat/internal/service"
+	"jobsity-chat/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const flaggedMessageID = "33333333-3333-3333-3333-333333333333"
+
+func newModerationTestHandler() (*ModerationHandler, *testutil.MockModerationRepository) {
+	moderationRepo := testutil.NewMockModerationRepository()
+	messageRepo := testutil.NewMockMessageRepository()
+	messageRepo.Messages = append(messageRepo.Messages, testutil.NewTestMessage(
+		testutil.WithMessageID(flaggedMessageID),
+		testutil.WithMessageChatroomID("room-1"),
+		testutil.WithMessageUserID("author"),
+	))
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["room-1"] = testutil.NewTestChatroom(testutil.WithChatroomID("room-1"))
+	chatroomRepo.Members["room-1"] = map[string]bool{"author": true, "user-1": true}
+
+	moderation := service.NewModerationService(moderationRepo, messageRepo, chatroomRepo)
+	return NewModerationHandler(moderation), moderationRepo
+}
+
+func moderationRequest(method, target, messageID, userID, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", messageID)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	return req.WithContext(middleware.WithUserID(req.Context(), userID))
+}
+
+func TestModerationHandler_Flag(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         string
+		body           string
+		expectedStatus int
+	}{
+		{"success", "user-1", `{"reason":"spam"}`, http.StatusCreated},
+		{"empty_body", "user-1", ``, http.StatusCreated},
+		{"invalid_body", "user-1", `{`, http.StatusBadRequest},
+		{"own_message", "author", `{}`, http.StatusBadRequest},
+		{"not_member", "outsider", `{}`, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _ := newModerationTestHandler()
+			w := httptest.NewRecorder()
+
+			handler.Flag(w, moderationRequest(http.MethodPost, "/api/v1/messages/"+flaggedMessageID+"/flag", flaggedMessageID, tt.userID, tt.body))
+
+			testutil.AssertStatusCode(t, w, tt.expectedStatus)
+		})
+	}
+
+	t.Run("flagged_twice", func(t *testing.T) {
+		handler, _ := newModerationTestHandler()
+
+		w := httptest.NewRecorder()
+		handler.Flag(w, moderationRequest(http.MethodPost, "/", flaggedMessageID, "user-1", `{}`))
+		testutil.AssertStatusCode(t, w, http.StatusCreated)
+
+		w = httptest.NewRecorder()
+		handler.Flag(w, moderationRequest(http.MethodPost, "/", flaggedMessageID, "user-1", `{}`))
+		testutil.AssertStatusCode(t, w, http.StatusConflict)
+	})
+
+	t.Run("message_not_found", func(t *testing.T) {
+		handler, _ := newModerationTestHandler()
+		w := httptest.NewRecorder()
+
+		handler.Flag(w, moderationRequest(http.MethodPost, "/", "not-a-message", "user-1", `{}`))
+
+		testutil.AssertStatusCode(t, w, http.StatusNotFound)
+	})
+}
+
+func TestModerationHandler_QueueAndResolve(t *testing.T) {
+	handler, moderationRepo := newModerationTestHandler()
+
+	w := httptest.NewRecorder()
+	handler.Flag(w, moderationRequest(http.MethodPost, "/", flaggedMessageID, "user-1", `{"reason":"spam"}`))
+	testutil.AssertStatusCode(t, w, http.StatusCreated)
+
+	w = httptest.NewRecorder()
+	handler.Queue(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/flags?limit=10", nil))
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	var queue ModerationQueueResponse
+	testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&queue))
+	testutil.AssertLen(t, queue.Flags, 1)
+	testutil.AssertEqual(t, queue.Flags[0].Message.ID, flaggedMessageID)
+
+	w = httptest.NewRecorder()
+	handler.Queue(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/flags?limit=abc", nil))
+	testutil.AssertStatusCode(t, w, http.StatusBadRequest)
+
+	w = httptest.NewRecorder()
+	handler.Resolve(w, moderationRequest(http.MethodPost, "/", flaggedMessageID, "mod-1", `{"action":"archive"}`))
+	testutil.AssertStatusCode(t, w, http.StatusBadRequest)
+
+	w = httptest.NewRecorder()
+	handler.Resolve(w, moderationRequest(http.MethodPost, "/", flaggedMessageID, "mod-1", `{"action":"keep"}`))
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	testutil.AssertEqual(t, moderationRepo.Resolutions[flaggedMessageID], domain.FlagActionKeep)
+
+	w = httptest.NewRecorder()
+	handler.Resolve(w, moderationRequest(http.MethodPost, "/", flaggedMessageID, "mod-1", `{"action":"delete"}`))
+	testutil.AssertStatusCode(t, w, http.StatusNotFound)
+}
+
+func TestModerationHandler_RestoreMessage(t *testing.T) {
+	messageRepo := testutil.NewMockMessageRepository()
+	messageRepo.DeletedAt[flaggedMessageID] = time.Now()
+	moderation := service.NewModerationService(testutil.NewMockModerationRepository(), messageRepo, testutil.NewMockChatroomRepository())
+	handler := NewModerationHandler(moderation)
+
+	w := httptest.NewRecorder()
+	handler.RestoreMessage(w, moderationRequest(http.MethodPost, "/", flaggedMessageID, "admin-1", ""))
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+
+	w = httptest.NewRecorder()
+	handler.RestoreMessage(w, moderationRequest(http.MethodPost, "/", flaggedMessageID, "admin-1", ""))
+	testutil.AssertStatusCode(t, w, http.StatusNotFound)
+}
+
+func shadowBanRequest(method, chatroomID, userID, moderatorID string) *http.Request {
+	req := httptest.NewRequest(method, "/api/v1/chatrooms/"+chatroomID+"/shadow-bans/"+userID, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", chatroomID)
+	rctx.URLParams.Add("user_id", userID)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	return req.WithContext(middleware.WithUserID(req.Context(), moderatorID))
+}
+
+func TestModerationHandler_ShadowBan(t *testing.T) {
+	tests := []struct {
+		name           string
+		chatroomID     string
+		userID         string
+		expectedStatus int
+	}{
+		{"success", "room-1", "user-1", http.StatusOK},
+		{"self", "room-1", "mod-1", http.StatusBadRequest},
+		{"not_member", "room-1", "outsider", http.StatusNotFound},
+		{"chatroom_not_found", "room-2", "user-1", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _ := newModerationTestHandler()
+			w := httptest.NewRecorder()
+
+			handler.ShadowBan(w, shadowBanRequest(http.MethodPut, tt.chatroomID, tt.userID, "mod-1"))
+
+			testutil.AssertStatusCode(t, w, tt.expectedStatus)
+		})
+	}
+
+	t.Run("list_and_lift", func(t *testing.T) {
+		handler, moderationRepo := newModerationTestHandler()
+
+		w := httptest.NewRecorder()
+		handler.ShadowBan(w, shadowBanRequest(http.MethodPut, "room-1", "user-1", "mod-1"))
+		testutil.AssertStatusCode(t, w, http.StatusOK)
+
+		w = httptest.NewRecorder()
+		handler.ListShadowBans(w, shadowBanRequest(http.MethodGet, "room-1", "", "mod-1"))
+		testutil.AssertStatusCode(t, w, http.StatusOK)
+		var resp ShadowBansResponse
+		testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		testutil.AssertLen(t, resp.UserIDs, 1)
+		testutil.AssertEqual(t, resp.UserIDs[0], "user-1")
+
+		w = httptest.NewRecorder()
+		handler.LiftShadowBan(w, shadowBanRequest(http.MethodDelete, "room-1", "user-1", "mod-1"))
+		testutil.AssertStatusCode(t, w, http.StatusOK)
+		testutil.AssertFalse(t, moderationRepo.ShadowBans["room-1"]["user-1"], "ban should be lifted")
+	})
+}
@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/oauth"
+	"jobsity-chat/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oauthStateCookie = "oauth_state"
+	oauthCookiePath  = "/api/v1/auth/oauth"
+	// oauthStateMaxAge bounds how long the user may take at the provider
+	oauthStateMaxAge = 600
+	// oauthLinkMode replaces the remember-me flag of the state cookie when
+	// a signed-in user links a provider account instead of signing in
+	oauthLinkMode = "link"
+)
+
+// OAuthLoginService signs in the user of an external identity, or links it
+// to a signed-in user
+type OAuthLoginService interface {
+	Login(ctx context.Context, profile *domain.ExternalProfile, opts service.LoginOptions) (*domain.Session, *domain.User, error)
+	Link(ctx context.Context, userID string, profile *domain.ExternalProfile) error
+}
+
+// OAuthSessionLookup resolves the session of a user linking a provider
+type OAuthSessionLookup interface {
+	GetByToken(ctx context.Context, token string) (*domain.Session, error)
+}
+
+type OAuthHandler struct {
+	oauthService OAuthLoginService
+	providers    *oauth.Registry
+	cookies      cookiePolicy
+	// sessions is nil until SetSessionLookup is called, and provider
+	// accounts cannot be linked
+	sessions OAuthSessionLookup
+}
+
+func NewOAuthHandler(oauthService OAuthLoginService, providers *oauth.Registry) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: oauthService,
+		providers:    providers,
+		cookies:      newCookiePolicy(),
+	}
+}
+
+// SetCookieMode sets how browsers may send the state and session cookies
+func (h *OAuthHandler) SetCookieMode(mode CookieMode) {
+	h.cookies.mode = mode
+}
+
+// SetSessionLookup lets signed-in users link provider accounts to their own
+func (h *OAuthHandler) SetSessionLookup(sessions OAuthSessionLookup) {
+	h.sessions = sessions
+}
+
+type OAuthProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+// Providers lists the enabled identity providers
+func (h *OAuthHandler) Providers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(OAuthProvidersResponse{Providers: h.providers.Names()}); err != nil {
+		slog.Error("failed to encode oauth providers response", slog.String("error", err.Error()))
+	}
+}
+
+// Start redirects to the provider's consent page. The state and PKCE
+// verifier are kept in a short-lived cookie scoped to the callback. With
+// link=true, the callback links the provider account to the signed-in user
+// instead of signing in.
+func (h *OAuthHandler) Start(w http.ResponseWriter, r *http.Request) {
+	provider, err := h.providers.Get(chi.URLParam(r, "provider"))
+	if err != nil {
+		http.Error(w, `{"error":"Unknown provider"}`, http.StatusNotFound)
+		return
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		slog.Error("failed to generate oauth state", slog.String("error", err.Error()))
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	state := hex.EncodeToString(buf)
+	verifier := oauth2.GenerateVerifier()
+
+	remember := "0"
+	if r.URL.Query().Get("remember_me") == "true" {
+		remember = "1"
+	}
+	if r.URL.Query().Get("link") == "true" {
+		if h.sessions == nil {
+			http.Error(w, `{"error":"Linking accounts is not enabled"}`, http.StatusNotFound)
+			return
+		}
+		remember = oauthLinkMode
+	}
+
+	// At least Lax, so the cookie is sent on the provider's top-level
+	// redirect back
+	http.SetCookie(w, h.cookies.apply(r, &http.Cookie{
+		Name:   oauthStateCookie,
+		Value:  strings.Join([]string{state, verifier, remember}, "."),
+		Path:   oauthCookiePath,
+		MaxAge: oauthStateMaxAge,
+	}))
+
+	http.Redirect(w, r, provider.AuthCodeURL(state, verifier), http.StatusFound)
+}
+
+// Callback completes the login, sets the session cookie and redirects to the
+// app. Failures redirect to the login page with an error code.
+func (h *OAuthHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	providerName := chi.URLParam(r, "provider")
+	provider, err := h.providers.Get(providerName)
+	if err != nil {
+		http.Error(w, `{"error":"Unknown provider"}`, http.StatusNotFound)
+		return
+	}
+
+	// The state cookie is single-use
+	http.SetCookie(w, h.cookies.apply(r, &http.Cookie{
+		Name:   oauthStateCookie,
+		Value:  "",
+		Path:   oauthCookiePath,
+		MaxAge: -1,
+	}))
+
+	query := r.URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		slog.Info("oauth login declined",
+			slog.String("provider", providerName),
+			slog.String("error", errCode))
+		h.redirectToLogin(w, r, "oauth_declined")
+		return
+	}
+
+	cookie, err := r.Cookie(oauthStateCookie)
+	if err != nil {
+		h.redirectToLogin(w, r, "oauth_state")
+		return
+	}
+	state, verifier, mode, ok := parseOAuthState(cookie.Value)
+	if !ok || subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		slog.Warn("oauth state mismatch",
+			slog.String("provider", providerName),
+			slog.String("remote_addr", r.RemoteAddr))
+		h.redirectToLogin(w, r, "oauth_state")
+		return
+	}
+
+	profile, err := provider.Exchange(r.Context(), query.Get("code"), verifier)
+	if err != nil {
+		slog.Error("oauth exchange failed",
+			slog.String("provider", providerName),
+			slog.String("error", err.Error()))
+		h.redirectToLogin(w, r, "oauth_failed")
+		return
+	}
+
+	if mode == oauthLinkMode {
+		h.link(w, r, providerName, profile)
+		return
+	}
+
+	remember := mode == "1"
+	session, user, err := h.oauthService.Login(r.Context(), profile, service.LoginOptions{RememberMe: remember})
+	if err != nil {
+		code := "oauth_failed"
+		switch {
+		case errors.Is(err, domain.ErrEmailExists):
+			code = "oauth_email_exists"
+		case errors.Is(err, domain.ErrEmailNotVerified):
+			code = "oauth_email_unverified"
+		case errors.Is(err, domain.ErrUserDeactivated):
+			code = "oauth_deactivated"
+		case errors.Is(err, domain.ErrHashingBusy):
+			code = "oauth_busy"
+		default:
+			slog.Error("oauth login failed",
+				slog.String("provider", providerName),
+				slog.String("error", err.Error()))
+		}
+		h.redirectToLogin(w, r, code)
+		return
+	}
+
+	slog.Info("oauth login successful",
+		slog.String("provider", providerName),
+		slog.String("user_id", user.ID))
+
+	http.SetCookie(w, h.cookies.apply(r, newSessionCookie(session, remember)))
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// link links the provider account of profile to the user signed in with
+// the session cookie, then redirects to the app
+func (h *OAuthHandler) link(w http.ResponseWriter, r *http.Request, providerName string, profile *domain.ExternalProfile) {
+	cookie, err := r.Cookie("session_id")
+	if err != nil || h.sessions == nil {
+		h.redirectToLogin(w, r, "oauth_link_session")
+		return
+	}
+	session, err := h.sessions.GetByToken(r.Context(), cookie.Value)
+	if err != nil || session.IsGuest() {
+		h.redirectToLogin(w, r, "oauth_link_session")
+		return
+	}
+
+	if err := h.oauthService.Link(r.Context(), session.UserID, profile); err != nil {
+		code := "oauth_failed"
+		if errors.Is(err, domain.ErrIdentityLinked) {
+			code = "oauth_identity_linked"
+		} else {
+			slog.Error("oauth link failed",
+				slog.String("provider", providerName),
+				slog.String("error", err.Error()))
+		}
+		h.redirectToLogin(w, r, code)
+		return
+	}
+
+	http.Redirect(w, r, "/?linked="+url.QueryEscape(providerName), http.StatusFound)
+}
+
+func (h *OAuthHandler) redirectToLogin(w http.ResponseWriter, r *http.Request, code string) {
+	http.Redirect(w, r, "/login.html?error="+url.QueryEscape(code), http.StatusFound)
+}
+
+// parseOAuthState splits the state cookie into its state, PKCE verifier and
+// mode: the remember-me flag ("1" or "0") or oauthLinkMode
+func parseOAuthState(value string) (state, verifier, mode string, ok bool) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
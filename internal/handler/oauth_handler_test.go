@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/oauth"
+	"jobsity-chat/internal/service"
+	"jobsity-chat/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/oauth2"
+)
+
+// setupOAuthHandler wires a fake provider whose token endpoint accepts the
+// code "auth-code" and whose profile is returned as-is
+func setupOAuthHandler(t *testing.T, profile *domain.ExternalProfile) (http.Handler, *testutil.MockUserRepository, *testutil.MockSessionRepository) {
+	t.Helper()
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("code") != "auth-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"access-token","token_type":"bearer"}`))
+	}))
+	t.Cleanup(tokenServer.Close)
+
+	provider := oauth.NewProvider("fake", &oauth2.Config{
+		ClientID: "client",
+		Endpoint: oauth2.Endpoint{AuthURL: "https://provider.example.com/auth", TokenURL: tokenServer.URL},
+	}, func(ctx context.Context, client *http.Client) (*domain.ExternalProfile, error) {
+		p := *profile
+		return &p, nil
+	})
+
+	userRepo := testutil.NewMockUserRepository()
+	sessionRepo := testutil.NewMockSessionRepository()
+	authService := service.NewAuthService(userRepo, sessionRepo)
+	oauthService := service.NewOAuthService(userRepo, testutil.NewMockIdentityRepository(), authService)
+	h := NewOAuthHandler(oauthService, oauth.NewRegistry(provider))
+	h.SetSessionLookup(sessionRepo)
+
+	r := chi.NewRouter()
+	r.Get("/api/v1/auth/oauth", h.Providers)
+	r.Get("/api/v1/auth/oauth/{provider}", h.Start)
+	r.Get("/api/v1/auth/oauth/{provider}/callback", h.Callback)
+	return r, userRepo, sessionRepo
+}
+
+func findCookie(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// startOAuth runs the start step and returns the state cookie and state
+func startOAuth(t *testing.T, router http.Handler, query string) (*http.Cookie, string) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oauth/fake"+query, nil))
+	testutil.AssertStatusCode(t, w, http.StatusFound)
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, location.Host, "provider.example.com")
+
+	cookie := findCookie(w, oauthStateCookie)
+	testutil.AssertNotNil(t, cookie)
+	return cookie, location.Query().Get("state")
+}
+
+func TestOAuthHandler_Providers(t *testing.T) {
+	router, _, _ := setupOAuthHandler(t, &domain.ExternalProfile{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oauth", nil))
+
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	testutil.AssertContains(t, w.Body.String(), `"providers":["fake"]`)
+}
+
+func TestOAuthHandler_StartUnknownProvider(t *testing.T) {
+	router, _, _ := setupOAuthHandler(t, &domain.ExternalProfile{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oauth/unknown", nil))
+
+	testutil.AssertStatusCode(t, w, http.StatusNotFound)
+}
+
+func TestOAuthHandler_CallbackSuccess(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		wantPersistent bool
+	}{
+		{"session cookie", "", false},
+		{"remember me", "?remember_me=true", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, userRepo, _ := setupOAuthHandler(t, &domain.ExternalProfile{
+				Subject:       "42",
+				Email:         "octo@example.com",
+				EmailVerified: true,
+				Username:      "octocat",
+			})
+			stateCookie, state := startOAuth(t, router, tt.query)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oauth/fake/callback?code=auth-code&state="+state, nil)
+			req.AddCookie(stateCookie)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			testutil.AssertStatusCode(t, w, http.StatusFound)
+			testutil.AssertHeader(t, w, "Location", "/")
+
+			session := findCookie(w, "session_id")
+			testutil.AssertNotNil(t, session)
+			testutil.AssertEqual(t, session.MaxAge > 0, tt.wantPersistent)
+			testutil.AssertEqual(t, findCookie(w, oauthStateCookie).MaxAge, -1)
+			testutil.AssertEqual(t, len(userRepo.Users), 1)
+		})
+	}
+}
+
+func TestOAuthHandler_CallbackFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     func(state string) string
+		addCookie bool
+		wantError string
+	}{
+		{
+			name:      "state mismatch",
+			query:     func(state string) string { return "?code=auth-code&state=forged" },
+			addCookie: true,
+			wantError: "oauth_state",
+		},
+		{
+			name:      "missing state cookie",
+			query:     func(state string) string { return "?code=auth-code&state=" + state },
+			addCookie: false,
+			wantError: "oauth_state",
+		},
+		{
+			name:      "user declined consent",
+			query:     func(state string) string { return "?error=access_denied&state=" + state },
+			addCookie: true,
+			wantError: "oauth_declined",
+		},
+		{
+			name:      "invalid code",
+			query:     func(state string) string { return "?code=bad-code&state=" + state },
+			addCookie: true,
+			wantError: "oauth_failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, _, _ := setupOAuthHandler(t, &domain.ExternalProfile{Subject: "42", Email: "octo@example.com"})
+			stateCookie, state := startOAuth(t, router, "")
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oauth/fake/callback"+tt.query(state), nil)
+			if tt.addCookie {
+				req.AddCookie(stateCookie)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			testutil.AssertStatusCode(t, w, http.StatusFound)
+			testutil.AssertTrue(t, strings.HasSuffix(w.Header().Get("Location"), "error="+tt.wantError),
+				"unexpected redirect: "+w.Header().Get("Location"))
+			testutil.AssertNil(t, findCookie(w, "session_id"))
+		})
+	}
+}
+
+func TestOAuthHandler_Link(t *testing.T) {
+	profile := &domain.ExternalProfile{Subject: "42", Email: "alice@work.example.com", Username: "alice"}
+
+	t.Run("signed in", func(t *testing.T) {
+		router, userRepo, sessionRepo := setupOAuthHandler(t, profile)
+		alice := testutil.NewTestUser(testutil.WithUserID("user-1"), testutil.WithEmail("alice@example.com"))
+		userRepo.Users[alice.ID] = alice
+		sessionRepo.Sessions["session-token"] = &domain.Session{UserID: "user-1", Token: "session-token", ExpiresAt: time.Now().Add(time.Hour)}
+
+		stateCookie, state := startOAuth(t, router, "?link=true")
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oauth/fake/callback?code=auth-code&state="+state, nil)
+		req.AddCookie(stateCookie)
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: "session-token"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		testutil.AssertStatusCode(t, w, http.StatusFound)
+		testutil.AssertHeader(t, w, "Location", "/?linked=fake")
+		testutil.AssertEqual(t, len(userRepo.Users), 1)
+
+		// The provider now signs alice in
+		stateCookie, state = startOAuth(t, router, "")
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/auth/oauth/fake/callback?code=auth-code&state="+state, nil)
+		req.AddCookie(stateCookie)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		testutil.AssertHeader(t, w, "Location", "/")
+		testutil.AssertNotNil(t, findCookie(w, "session_id"))
+	})
+
+	t.Run("signed out", func(t *testing.T) {
+		router, userRepo, _ := setupOAuthHandler(t, profile)
+
+		stateCookie, state := startOAuth(t, router, "?link=true")
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oauth/fake/callback?code=auth-code&state="+state, nil)
+		req.AddCookie(stateCookie)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		testutil.AssertHeader(t, w, "Location", "/login.html?error=oauth_link_session")
+		testutil.AssertEqual(t, len(userRepo.Users), 0)
+	})
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPresenceWindow = 24 * time.Hour
+	maxPresenceWindow     = domain.MaxChatroomStatsDays * 24 * time.Hour
+	defaultPresenceBucket = 5 * time.Minute
+	// maxPresencePoints bounds the points per chatroom, so long windows need
+	// coarser buckets
+	maxPresencePoints = 2016
+)
+
+// PresenceHistoryHandler reports the recorded concurrency of chatrooms to
+// administrators. It must be guarded by middleware.RequireAdmin.
+type PresenceHistoryHandler struct {
+	presence domain.ChatroomStatsRepository
+}
+
+func NewPresenceHistoryHandler(presence domain.ChatroomStatsRepository) *PresenceHistoryHandler {
+	return &PresenceHistoryHandler{presence: presence}
+}
+
+// PresenceHistoryResponse is the payload returned by GET
+// /api/v1/admin/hub/history
+type PresenceHistoryResponse struct {
+	Since         time.Time                     `json:"since"`
+	BucketSeconds int64                         `json:"bucket_seconds"`
+	Rooms         []*domain.RoomPresenceHistory `json:"rooms"`
+}
+
+// History returns the most users connected at once to each chatroom, across
+// every server, per period of "bucket" (whole minutes, default 5m) over the
+// "window" query parameter (default 24h, at most 90 days). "chatroom_id"
+// narrows it to one chatroom.
+func (h *PresenceHistoryHandler) History(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	window := defaultPresenceWindow
+	if v := query.Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > maxPresenceWindow {
+			http.Error(w, `{"error":"Invalid window"}`, http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+	bucket := defaultPresenceBucket
+	if v := query.Get("bucket"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Minute || d%time.Minute != 0 {
+			http.Error(w, `{"error":"Invalid bucket: give whole minutes"}`, http.StatusBadRequest)
+			return
+		}
+		bucket = d
+	}
+	if window/bucket > maxPresencePoints {
+		http.Error(w, fmt.Sprintf(`{"error":"Window spans more than %d buckets; use a larger bucket"}`, maxPresencePoints), http.StatusBadRequest)
+		return
+	}
+	chatroomID := query.Get("chatroom_id")
+	if chatroomID != "" {
+		if _, err := uuid.Parse(chatroomID); err != nil {
+			http.Error(w, `{"error":"Invalid chatroom_id"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	since := time.Now().Add(-window).UTC()
+	rooms, err := h.presence.PresenceHistory(r.Context(), chatroomID, since, bucket)
+	if err != nil {
+		slog.Error("failed to get presence history", slog.String("error", err.Error()))
+		http.Error(w, `{"error":"Failed to get presence history"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PresenceHistoryResponse{
+		Since:         since,
+		BucketSeconds: int64(bucket / time.Second),
+		Rooms:         rooms,
+	})
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestPresenceHistoryHandler_History(t *testing.T) {
+	var (
+		gotRoom   string
+		gotSince  time.Time
+		gotBucket time.Duration
+	)
+	repo := testutil.NewMockChatroomStatsRepository()
+	repo.PresenceHistoryFunc = func(ctx context.Context, chatroomID string, since time.Time, bucket time.Duration) ([]*domain.RoomPresenceHistory, error) {
+		gotRoom, gotSince, gotBucket = chatroomID, since, bucket
+		return []*domain.RoomPresenceHistory{{
+			ChatroomID:   "room-1",
+			ChatroomName: "General",
+			PeakUsers:    7,
+			Points:       []domain.PresencePoint{{At: since, Users: 7}},
+		}}, nil
+	}
+	handler := NewPresenceHistoryHandler(repo)
+
+	w := httptest.NewRecorder()
+	handler.History(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/hub/history", nil))
+	testutil.AssertEqual(t, w.Code, http.StatusOK)
+
+	var resp PresenceHistoryResponse
+	testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	testutil.AssertEqual(t, resp.BucketSeconds, int64(300))
+	testutil.AssertLen(t, resp.Rooms, 1)
+	testutil.AssertEqual(t, resp.Rooms[0].PeakUsers, 7)
+	testutil.AssertEqual(t, gotRoom, "")
+	testutil.AssertEqual(t, gotBucket, 5*time.Minute)
+	testutil.AssertTrue(t, time.Since(gotSince) > 23*time.Hour, "expected a 24h default window")
+
+	const roomID = "6f1c1a52-4e0b-4c8e-9d3a-0b6f5a1f2c01"
+	w = httptest.NewRecorder()
+	handler.History(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/hub/history?window=168h&bucket=1h&chatroom_id="+roomID, nil))
+	testutil.AssertEqual(t, w.Code, http.StatusOK)
+	testutil.AssertEqual(t, gotRoom, roomID)
+	testutil.AssertEqual(t, gotBucket, time.Hour)
+	testutil.AssertTrue(t, time.Since(gotSince) > 167*time.Hour, "expected a 168h window")
+
+	for _, query := range []string{
+		"window=soon",
+		"window=10000h",
+		"bucket=30s",
+		"bucket=90s",
+		"window=2160h&bucket=1m",
+		"chatroom_id=room-1",
+	} {
+		w := httptest.NewRecorder()
+		handler.History(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/hub/history?"+query, nil))
+		testutil.AssertEqual(t, w.Code, http.StatusBadRequest)
+	}
+
+	repo.PresenceHistoryFunc = func(ctx context.Context, chatroomID string, since time.Time, bucket time.Duration) ([]*domain.RoomPresenceHistory, error) {
+		return nil, errors.New("database error")
+	}
+	w = httptest.NewRecorder()
+	handler.History(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/hub/history", nil))
+	testutil.AssertEqual(t, w.Code, http.StatusInternalServerError)
+}
@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// PushDeviceService registers the devices that receive push notifications
+type PushDeviceService interface {
+	RegisterDevice(ctx context.Context, userID, platform, token string) (*domain.PushDevice, error)
+	Devices(ctx context.Context, userID string) ([]*domain.PushDevice, error)
+	RemoveDevice(ctx context.Context, userID, deviceID string) error
+	DNDSchedule(ctx context.Context, userID string) (*domain.DNDSchedule, error)
+	SetDNDSchedule(ctx context.Context, userID string, schedule *domain.DNDSchedule) error
+}
+
+// PushConfig tells clients how to subscribe
+type PushConfig struct {
+	// Platforms lists the enabled push platforms; empty when push
+	// notifications are disabled
+	Platforms []string `json:"platforms"`
+	// VAPIDPublicKey is the applicationServerKey for
+	// PushManager.subscribe(); empty when Web Push is disabled
+	VAPIDPublicKey string `json:"vapid_public_key,omitempty"`
+}
+
+type PushHandler struct {
+	devices PushDeviceService
+	config  PushConfig
+}
+
+func NewPushHandler(devices PushDeviceService, config PushConfig) *PushHandler {
+	if config.Platforms == nil {
+		config.Platforms = []string{}
+	}
+	return &PushHandler{devices: devices, config: config}
+}
+
+// RegisterPushDeviceRequest carries an FCM registration token, or for Web
+// Push the JSON-encoded PushSubscription
+type RegisterPushDeviceRequest struct {
+	Platform string `json:"platform"`
+	Token    string `json:"token"`
+}
+
+type PushDevicesResponse struct {
+	Devices []*domain.PushDevice `json:"devices"`
+}
+
+// Config returns the enabled platforms and the Web Push public key
+func (h *PushHandler) Config(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.config)
+}
+
+// Register adds a device that receives the caller's notifications.
+// Registering a token again moves it to the caller.
+func (h *PushHandler) Register(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req RegisterPushDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	device, err := h.devices.RegisterDevice(r.Context(), userID, req.Platform, req.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidInput):
+			http.Error(w, `{"error":"Unsupported platform or invalid token"}`, http.StatusBadRequest)
+		default:
+			slog.Error("failed to register push device",
+				slog.String("error", err.Error()),
+				slog.String("user_id", userID))
+			http.Error(w, `{"error":"Failed to register device"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(device)
+}
+
+// List returns the caller's registered devices
+func (h *PushHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	devices, err := h.devices.Devices(r.Context(), userID)
+	if err != nil {
+		slog.Error("failed to list push devices",
+			slog.String("error", err.Error()),
+			slog.String("user_id", userID))
+		http.Error(w, `{"error":"Failed to list devices"}`, http.StatusInternalServerError)
+		return
+	}
+	if devices == nil {
+		devices = []*domain.PushDevice{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PushDevicesResponse{Devices: devices})
+}
+
+// Delete unregisters one of the caller's devices, e.g. on sign-out
+func (h *PushHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.devices.RemoveDevice(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrPushDeviceNotFound):
+			http.Error(w, `{"error":"Device not found"}`, http.StatusNotFound)
+		default:
+			slog.Error("failed to delete push device",
+				slog.String("error", err.Error()),
+				slog.String("user_id", userID))
+			http.Error(w, `{"error":"Failed to delete device"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DNDSchedule returns the caller's do-not-disturb schedule
+func (h *PushHandler) DNDSchedule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	schedule, err := h.devices.DNDSchedule(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrUserNotFound):
+			http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+		default:
+			slog.Error("failed to get do-not-disturb schedule",
+				slog.String("error", err.Error()),
+				slog.String("user_id", userID))
+			http.Error(w, `{"error":"Failed to get do-not-disturb schedule"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// SetDNDSchedule replaces the caller's do-not-disturb schedule; no windows
+// turns do-not-disturb off
+func (h *PushHandler) SetDNDSchedule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var schedule domain.DNDSchedule
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.devices.SetDNDSchedule(r.Context(), userID, &schedule); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidInput):
+			http.Error(w, fmt.Sprintf(`{"error":"Invalid schedule: use an IANA time zone and at most %d windows of HH:MM times"}`, domain.MaxDNDWindows), http.StatusBadRequest)
+		case errors.Is(err, domain.ErrUserNotFound):
+			http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+		default:
+			slog.Error("failed to set do-not-disturb schedule",
+				slog.String("error", err.Error()),
+				slog.String("user_id", userID))
+			http.Error(w, `{"error":"Failed to set do-not-disturb schedule"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+	"jobsity-chat/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type mockPushDevices struct {
+	devices  map[string][]*domain.PushDevice
+	schedule *domain.DNDSchedule
+}
+
+func (m *mockPushDevices) RegisterDevice(ctx context.Context, userID, platform, token string) (*domain.PushDevice, error) {
+	switch {
+	case platform != domain.PushPlatformFCM || token == "":
+		return nil, domain.ErrInvalidInput
+	case token == "fail":
+		return nil, errors.New("database error")
+	}
+	device := &domain.PushDevice{ID: "device-1", UserID: userID, Platform: platform, Token: token}
+	m.devices[userID] = append(m.devices[userID], device)
+	return device, nil
+}
+
+func (m *mockPushDevices) Devices(ctx context.Context, userID string) ([]*domain.PushDevice, error) {
+	return m.devices[userID], nil
+}
+
+func (m *mockPushDevices) RemoveDevice(ctx context.Context, userID, deviceID string) error {
+	for i, device := range m.devices[userID] {
+		if device.ID == deviceID {
+			m.devices[userID] = append(m.devices[userID][:i], m.devices[userID][i+1:]...)
+			return nil
+		}
+	}
+	return domain.ErrPushDeviceNotFound
+}
+
+func (m *mockPushDevices) DNDSchedule(ctx context.Context, userID string) (*domain.DNDSchedule, error) {
+	if m.schedule == nil {
+		return &domain.DNDSchedule{Windows: []domain.DNDWindow{}}, nil
+	}
+	return m.schedule, nil
+}
+
+func (m *mockPushDevices) SetDNDSchedule(ctx context.Context, userID string, schedule *domain.DNDSchedule) error {
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+	m.schedule = schedule
+	return nil
+}
+
+func TestPushHandler_Config(t *testing.T) {
+	handler := NewPushHandler(&mockPushDevices{}, PushConfig{})
+	w := httptest.NewRecorder()
+	handler.Config(w, httptest.NewRequest(http.MethodGet, "/api/v1/push/config", nil))
+
+	testutil.AssertEqual(t, w.Code, http.StatusOK)
+	testutil.AssertEqual(t, strings.TrimSpace(w.Body.String()), `{"platforms":[]}`)
+}
+
+func TestPushHandler_Register(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"fcm", `{"platform":"fcm","token":"abc"}`, http.StatusCreated},
+		{"disabled_platform", `{"platform":"webpush","token":"{}"}`, http.StatusBadRequest},
+		{"invalid_body", `{`, http.StatusBadRequest},
+		{"error", `{"platform":"fcm","token":"fail"}`, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewPushHandler(&mockPushDevices{devices: make(map[string][]*domain.PushDevice)}, PushConfig{Platforms: []string{domain.PushPlatformFCM}})
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/me/push-devices", strings.NewReader(tt.body))
+			req = req.WithContext(middleware.WithUserID(req.Context(), "user-1"))
+			w := httptest.NewRecorder()
+
+			handler.Register(w, req)
+
+			testutil.AssertEqual(t, w.Code, tt.expectedStatus)
+			if tt.expectedStatus == http.StatusCreated {
+				testutil.AssertContains(t, w.Body.String(), `"id":"device-1"`)
+				testutil.AssertNotContains(t, w.Body.String(), `"token"`)
+			}
+		})
+	}
+}
+
+func TestPushHandler_ListAndDelete(t *testing.T) {
+	devices := &mockPushDevices{devices: map[string][]*domain.PushDevice{
+		"user-1": {{ID: "device-1", Platform: domain.PushPlatformFCM, Token: "secret"}},
+	}}
+	handler := NewPushHandler(devices, PushConfig{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/push-devices", nil)
+	req = req.WithContext(middleware.WithUserID(req.Context(), "user-1"))
+	w := httptest.NewRecorder()
+	handler.List(w, req)
+
+	testutil.AssertEqual(t, w.Code, http.StatusOK)
+	var resp PushDevicesResponse
+	testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	testutil.AssertLen(t, resp.Devices, 1)
+	testutil.AssertEqual(t, resp.Devices[0].Token, "")
+
+	for _, expected := range []int{http.StatusNoContent, http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/me/push-devices/device-1", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "device-1")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		req = req.WithContext(middleware.WithUserID(req.Context(), "user-1"))
+		w := httptest.NewRecorder()
+
+		handler.Delete(w, req)
+		testutil.AssertEqual(t, w.Code, expected)
+	}
+}
+
+func TestPushHandler_DNDSchedule(t *testing.T) {
+	devices := &mockPushDevices{}
+	handler := NewPushHandler(devices, PushConfig{})
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"valid", `{"time_zone":"Europe/Paris","windows":[{"start":"22:00","end":"07:00","days":["mon","tue"]}]}`, http.StatusOK},
+		{"unknown_time_zone", `{"time_zone":"Nowhere/City","windows":[]}`, http.StatusBadRequest},
+		{"bad_time", `{"windows":[{"start":"25:00","end":"07:00"}]}`, http.StatusBadRequest},
+		{"invalid_body", `{`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/me/dnd-schedule", strings.NewReader(tt.body))
+			req = req.WithContext(middleware.WithUserID(req.Context(), "user-1"))
+			w := httptest.NewRecorder()
+
+			handler.SetDNDSchedule(w, req)
+			testutil.AssertStatusCode(t, w, tt.expectedStatus)
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/dnd-schedule", nil)
+	req = req.WithContext(middleware.WithUserID(req.Context(), "user-1"))
+	w := httptest.NewRecorder()
+	handler.DNDSchedule(w, req)
+
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	var schedule domain.DNDSchedule
+	testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&schedule))
+	testutil.AssertEqual(t, schedule.TimeZone, "Europe/Paris")
+	testutil.AssertLen(t, schedule.Windows, 1)
+	testutil.AssertEqual(t, strings.Join(schedule.Windows[0].Days, ","), "mon,tue")
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+	"jobsity-chat/internal/service"
+)
+
+// SearchService searches the messages, chatrooms and users a user can see
+type SearchService interface {
+	Search(ctx context.Context, userID string, req service.SearchRequest) (*service.SearchResults, error)
+}
+
+type SearchHandler struct {
+	search SearchService
+}
+
+func NewSearchHandler(search SearchService) *SearchHandler {
+	return &SearchHandler{search: search}
+}
+
+// Search serves GET /search?q=: ranked messages, chatrooms and users across
+// the caller's chatrooms. type=messages,rooms,users narrows the kinds of
+// results, limit applies to each kind and <type>_cursor pages through one.
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	req := service.SearchRequest{
+		Query:   query.Get("q"),
+		Cursors: make(map[string]string, len(domain.SearchTypes)),
+	}
+	for _, param := range query["type"] {
+		for _, t := range strings.Split(param, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				req.Types = append(req.Types, t)
+			}
+		}
+	}
+	for _, t := range domain.SearchTypes {
+		if cursor := query.Get(t + "_cursor"); cursor != "" {
+			req.Cursors[t] = cursor
+		}
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			http.Error(w, `{"error":"Invalid limit"}`, http.StatusBadRequest)
+			return
+		}
+		req.Limit = limit
+	}
+
+	results, err := h.search.Search(r.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			http.Error(w, fmt.Sprintf(`{"error":"Give a query of %d to %d characters, types among %s, a limit of at most 50 and cursors from a previous page"}`,
+				service.MinSearchQueryLength, service.MaxSearchQueryLength, strings.Join(domain.SearchTypes, ", ")), http.StatusBadRequest)
+			return
+		}
+		slog.Error("failed to search",
+			slog.String("error", err.Error()),
+			slog.String("user_id", userID))
+		http.Error(w, `{"error":"Failed to search"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
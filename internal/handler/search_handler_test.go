@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+	"jobsity-chat/internal/service"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestSearchHandler_Search(t *testing.T) {
+	repo := testutil.NewMockSearchRepository()
+	repo.Messages = []*domain.MessageHit{
+		{ID: "msg-1", ChatroomID: "room-1", Content: "release notes"},
+		{ID: "msg-2", ChatroomID: "room-1", Content: "release tomorrow"},
+	}
+	repo.Rooms = []*domain.RoomHit{{ID: "room-2", Name: "Releases"}}
+	handler := NewSearchHandler(service.NewSearchService(repo))
+
+	search := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req = req.WithContext(middleware.WithUserID(req.Context(), "user-1"))
+		w := httptest.NewRecorder()
+		handler.Search(w, req)
+		return w
+	}
+
+	w := search("/api/v1/search?q=release&limit=1")
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	results := testutil.DecodeJSON[service.SearchResults](t, w)
+	testutil.AssertNotNil(t, results.Messages)
+	testutil.AssertLen(t, results.Messages.Items, 1)
+	testutil.AssertEqual(t, results.Messages.NextCursor, "1")
+	testutil.AssertLen(t, results.Rooms.Items, 1)
+	testutil.AssertLen(t, results.Users.Items, 0)
+
+	w = search("/api/v1/search?q=release&type=messages&limit=1&messages_cursor=1")
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	results = testutil.DecodeJSON[service.SearchResults](t, w)
+	testutil.AssertEqual(t, results.Messages.Items[0].ID, "msg-2")
+	testutil.AssertEqual(t, results.Messages.NextCursor, "")
+	testutil.AssertNil(t, results.Rooms)
+
+	for _, target := range []string{
+		"/api/v1/search",
+		"/api/v1/search?q=r",
+		"/api/v1/search?q=release&type=files",
+		"/api/v1/search?q=release&limit=abc",
+		"/api/v1/search?q=release&rooms_cursor=x",
+	} {
+		testutil.AssertStatusCode(t, search(target), http.StatusBadRequest)
+	}
+
+	repo.SearchRoomsFunc = func(ctx context.Context, opts domain.SearchOptions) ([]*domain.RoomHit, error) {
+		return nil, errors.New("database error")
+	}
+	testutil.AssertStatusCode(t, search("/api/v1/search?q=release"), http.StatusInternalServerError)
+}
+
+// countingSearch decorates a SearchService the way a tracing or caching
+// layer would
+type countingSearch struct {
+	SearchService
+	calls int
+}
+
+func (s *countingSearch) Search(ctx context.Context, userID string, req service.SearchRequest) (*service.SearchResults, error) {
+	s.calls++
+	return s.SearchService.Search(ctx, userID, req)
+}
+
+func TestSearchHandler_DecoratedService(t *testing.T) {
+	repo := testutil.NewMockSearchRepository()
+	repo.Rooms = []*domain.RoomHit{{ID: "room-1", Name: "Releases"}}
+	decorated := &countingSearch{SearchService: service.NewSearchService(repo)}
+	handler := NewSearchHandler(decorated)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/search?q=release", nil)
+	req = req.WithContext(middleware.WithUserID(req.Context(), "user-1"))
+	w := httptest.NewRecorder()
+	handler.Search(w, req)
+
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	testutil.AssertEqual(t, decorated.calls, 1)
+	results := testutil.DecodeJSON[service.SearchResults](t, w)
+	testutil.AssertLen(t, results.Rooms.Items, 1)
+}
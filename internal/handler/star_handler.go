@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// StarRequest optionally places a starred chatroom among the caller's others
+type StarRequest struct {
+	Position *int `json:"position,omitempty"`
+}
+
+// StarResponse is the caller's star on a chatroom
+type StarResponse struct {
+	Starred  bool `json:"starred"`
+	Position int  `json:"position"`
+}
+
+// Star stars a chatroom for the caller, or moves their star to position.
+// Without a body, a new star goes after the caller's other starred rooms.
+func (h *ChatroomHandler) Star(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req StarRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	position, err := h.chatService.StarChatroom(r.Context(), chatroomID, userID, req.Position)
+	if err != nil {
+		writeStarError(w, err, chatroomID, "failed to star chatroom", "Failed to star chatroom")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StarResponse{Starred: true, Position: position})
+}
+
+// Unstar removes the caller's star from a chatroom
+func (h *ChatroomHandler) Unstar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	if err := h.chatService.UnstarChatroom(r.Context(), chatroomID, userID); err != nil {
+		writeStarError(w, err, chatroomID, "failed to unstar chatroom", "Failed to unstar chatroom")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeStarError(w http.ResponseWriter, err error, chatroomID, logMsg, publicMsg string) {
+	switch {
+	case errors.Is(err, domain.ErrChatroomNotFound):
+		http.Error(w, `{"error":"Chatroom not found"}`, http.StatusNotFound)
+	case errors.Is(err, domain.ErrInvalidInput):
+		http.Error(w, `{"error":"Position must be a non-negative integer"}`, http.StatusBadRequest)
+	default:
+		slog.Error(logMsg,
+			slog.String("error", err.Error()),
+			slog.String("chatroom_id", chatroomID))
+		http.Error(w, `{"error":"`+publicMsg+`"}`, http.StatusInternalServerError)
+	}
+}
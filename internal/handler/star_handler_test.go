@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestChatroomHandler_Star(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		serviceErr       error
+		expectedStatus   int
+		expectedPosition int
+	}{
+		{"no_body", "", nil, http.StatusOK, 5},
+		{"position", `{"position":2}`, nil, http.StatusOK, 2},
+		{"invalid_body", `{`, nil, http.StatusBadRequest, 0},
+		{"invalid_position", `{"position":-1}`, domain.ErrInvalidInput, http.StatusBadRequest, 0},
+		{"not_found", "", domain.ErrChatroomNotFound, http.StatusNotFound, 0},
+		{"error", "", errors.New("database error"), http.StatusInternalServerError, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPosition *int
+			chatService := &mockChatService{
+				starChatroomFunc: func(ctx context.Context, chatroomID, userID string, position *int) (int, error) {
+					gotPosition = position
+					if tt.serviceErr != nil {
+						return 0, tt.serviceErr
+					}
+					if position != nil {
+						return *position, nil
+					}
+					return 5, nil
+				},
+			}
+			handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+			w := httptest.NewRecorder()
+			handler.Star(w, newTagRequest(http.MethodPut, "/api/v1/chatrooms/room-1/star", tt.body, map[string]string{"id": "room-1"}))
+
+			testutil.AssertStatusCode(t, w, tt.expectedStatus)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var response StarResponse
+			testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&response))
+			testutil.AssertTrue(t, response.Starred, "expected starred")
+			testutil.AssertEqual(t, response.Position, tt.expectedPosition)
+			if tt.body == "" {
+				testutil.AssertNil(t, gotPosition)
+			}
+		})
+	}
+}
+
+func TestChatroomHandler_Unstar(t *testing.T) {
+	chatService := &mockChatService{
+		unstarChatroomFunc: func(ctx context.Context, chatroomID, userID string) error {
+			if chatroomID != "room-1" {
+				return domain.ErrChatroomNotFound
+			}
+			return nil
+		},
+	}
+	handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+	w := httptest.NewRecorder()
+	handler.Unstar(w, newTagRequest(http.MethodDelete, "/api/v1/chatrooms/room-1/star", "", map[string]string{"id": "room-1"}))
+	testutil.AssertStatusCode(t, w, http.StatusNoContent)
+
+	w = httptest.NewRecorder()
+	handler.Unstar(w, newTagRequest(http.MethodDelete, "/api/v1/chatrooms/room-2/star", "", map[string]string{"id": "room-2"}))
+	testutil.AssertStatusCode(t, w, http.StatusNotFound)
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TagsRequest replaces a chatroom's tags
+type TagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// TagsResponse holds a chatroom's tags, normalized
+type TagsResponse struct {
+	Tags []string `json:"tags"`
+}
+
+// TagCountsResponse lists the tags of the chatroom directory
+type TagCountsResponse struct {
+	Tags []*domain.TagCount `json:"tags"`
+}
+
+// SetTags replaces the tags of a chatroom; owner only
+func (h *ChatroomHandler) SetTags(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req TagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	tags, err := h.chatService.SetChatroomTags(r.Context(), chatroomID, userID, req.Tags)
+	if err != nil {
+		writeTagError(w, err, chatroomID, "failed to set chatroom tags", "Failed to set chatroom tags")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TagsResponse{Tags: tags})
+}
+
+// RemoveTag removes one tag of a chatroom; owner only
+func (h *ChatroomHandler) RemoveTag(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"User not authenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	chatroomID := chi.URLParam(r, "id")
+	if err := h.chatService.RemoveChatroomTag(r.Context(), chatroomID, userID, chi.URLParam(r, "tag")); err != nil {
+		writeTagError(w, err, chatroomID, "failed to remove chatroom tag", "Failed to remove chatroom tag")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Tags lists the tags of the organization's chatrooms with how many carry
+// each, to browse the directory by category
+func (h *ChatroomHandler) Tags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.chatService.ListTags(r.Context())
+	if err != nil {
+		slog.Error("failed to list chatroom tags", slog.String("error", err.Error()))
+		http.Error(w, `{"error":"Failed to list tags"}`, http.StatusInternalServerError)
+		return
+	}
+	if tags == nil {
+		tags = []*domain.TagCount{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TagCountsResponse{Tags: tags})
+}
+
+// writeTagError writes the response for the errors shared by the tag
+// management endpoints, logging unexpected ones as logMsg
+func writeTagError(w http.ResponseWriter, err error, chatroomID, logMsg, publicMsg string) {
+	switch {
+	case errors.Is(err, domain.ErrChatroomNotFound):
+		http.Error(w, `{"error":"Chatroom not found"}`, http.StatusNotFound)
+	case errors.Is(err, domain.ErrNotOwner):
+		http.Error(w, `{"error":"Only the chatroom owner can change its tags"}`, http.StatusForbidden)
+	case errors.Is(err, domain.ErrInvalidInput):
+		http.Error(w, fmt.Sprintf(`{"error":"Give at most %d tags of up to %d letters, digits or hyphens"}`,
+			domain.MaxChatroomTags, domain.MaxTagLength), http.StatusBadRequest)
+	default:
+		slog.Error(logMsg,
+			slog.String("error", err.Error()),
+			slog.String("chatroom_id", chatroomID))
+		http.Error(w, `{"error":"`+publicMsg+`"}`, http.StatusInternalServerError)
+	}
+}
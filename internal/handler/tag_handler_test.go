@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+	"jobsity-chat/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func newTagRequest(method, target, body string, params map[string]string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	for key, value := range params {
+		rctx.URLParams.Add(key, value)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	return req.WithContext(middleware.WithUserID(req.Context(), "user-1"))
+}
+
+func TestChatroomHandler_SetTags(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		serviceErr     error
+		expectedStatus int
+	}{
+		{"success", `{"tags":["Go","backend"]}`, nil, http.StatusOK},
+		{"invalid_body", `{`, nil, http.StatusBadRequest},
+		{"invalid_tag", `{"tags":["no spaces"]}`, domain.ErrInvalidInput, http.StatusBadRequest},
+		{"not_owner", `{"tags":["go"]}`, domain.ErrNotOwner, http.StatusForbidden},
+		{"not_found", `{"tags":["go"]}`, domain.ErrChatroomNotFound, http.StatusNotFound},
+		{"error", `{"tags":["go"]}`, errors.New("database error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatService := &mockChatService{
+				setChatroomTagsFunc: func(ctx context.Context, chatroomID, requesterID string, tags []string) ([]string, error) {
+					if tt.serviceErr != nil {
+						return nil, tt.serviceErr
+					}
+					return []string{"backend", "go"}, nil
+				},
+			}
+			handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+			w := httptest.NewRecorder()
+			handler.SetTags(w, newTagRequest(http.MethodPut, "/api/v1/chatrooms/room-1/tags", tt.body, map[string]string{"id": "room-1"}))
+
+			testutil.AssertStatusCode(t, w, tt.expectedStatus)
+			if tt.expectedStatus == http.StatusOK {
+				var response TagsResponse
+				testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&response))
+				testutil.AssertLen(t, response.Tags, 2)
+				testutil.AssertEqual(t, response.Tags[0], "backend")
+			}
+		})
+	}
+}
+
+func TestChatroomHandler_RemoveTag(t *testing.T) {
+	var removed string
+	chatService := &mockChatService{
+		removeChatroomTagFunc: func(ctx context.Context, chatroomID, requesterID, tag string) error {
+			if requesterID != "user-1" {
+				return domain.ErrNotOwner
+			}
+			removed = tag
+			return nil
+		},
+	}
+	handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+	w := httptest.NewRecorder()
+	handler.RemoveTag(w, newTagRequest(http.MethodDelete, "/api/v1/chatrooms/room-1/tags/go", "", map[string]string{"id": "room-1", "tag": "go"}))
+
+	testutil.AssertStatusCode(t, w, http.StatusNoContent)
+	testutil.AssertEqual(t, removed, "go")
+}
+
+func TestChatroomHandler_Tags(t *testing.T) {
+	chatService := &mockChatService{
+		listTagsFunc: func(ctx context.Context) ([]*domain.TagCount, error) {
+			return []*domain.TagCount{{Tag: "go", Chatrooms: 3}, {Tag: "news", Chatrooms: 1}}, nil
+		},
+	}
+	handler := NewChatroomHandler(chatService, &mockHub{connectedCounts: make(map[string]int)})
+
+	w := httptest.NewRecorder()
+	handler.Tags(w, newTagRequest(http.MethodGet, "/api/v1/tags", "", nil))
+
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	var response TagCountsResponse
+	testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&response))
+	testutil.AssertLen(t, response.Tags, 2)
+	testutil.AssertEqual(t, response.Tags[0].Chatrooms, 3)
+}
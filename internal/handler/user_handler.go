@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+	"jobsity-chat/internal/service"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ProfileService shows and updates user profiles and statuses
+type ProfileService interface {
+	GetProfile(ctx context.Context, viewerID, userID string) (*service.ProfileView, error)
+	UpdateSettings(ctx context.Context, userID string, update service.ProfileSettingsUpdate) (*domain.ProfileSettings, error)
+	SetStatus(ctx context.Context, userID string, status domain.UserStatus) (*domain.UserStatus, error)
+	ChangeUsername(ctx context.Context, userID, username string) (*domain.UsernameChange, error)
+	UsernameHistory(ctx context.Context, userID string) ([]*domain.UsernameChange, error)
+	ResolveUsername(ctx context.Context, username string) (string, error)
+}
+
+type UserHandler struct {
+	profiles ProfileService
+}
+
+func NewUserHandler(profiles ProfileService) *UserHandler {
+	return &UserHandler{profiles: profiles}
+}
+
+type ProfileSettingsResponse struct {
+	AvatarURL        string `json:"avatar_url"`
+	Visibility       string `json:"profile_visibility"`
+	ShowOnlineStatus bool   `json:"show_online_status"`
+	Version          int    `json:"version"`
+}
+
+// GetProfile returns a user's public profile, limited by their privacy
+// settings
+func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	viewerID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+	profile, err := h.profiles.GetProfile(r.Context(), viewerID, userID)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to get user profile",
+			slog.String("error", err.Error()),
+			slog.String("user_id", userID))
+		http.Error(w, `{"error":"Failed to get profile"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if profile.Version != 0 {
+		w.Header().Set("ETag", versionETag(profile.Version))
+	}
+	json.NewEncoder(w).Encode(profile)
+}
+
+// UpdateProfileSettings changes the current user's avatar and privacy
+// settings. Fields left out of the request are unchanged. A version, sent as
+// If-Match or in the body, makes the update fail with 409 if the settings
+// changed since it was read.
+func (h *UserHandler) UpdateProfileSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req service.ProfileSettingsUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	version, ok := expectedVersion(r, req.Version)
+	if !ok {
+		http.Error(w, `{"error":"Invalid profile version"}`, http.StatusBadRequest)
+		return
+	}
+	req.Version = version
+
+	settings, err := h.profiles.UpdateSettings(r.Context(), userID, req)
+	if errors.Is(err, domain.ErrInvalidInput) {
+		http.Error(w, `{"error":"Invalid profile settings"}`, http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, domain.ErrUserNotFound) {
+		http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, domain.ErrVersionConflict) {
+		http.Error(w, `{"error":"Profile settings were changed elsewhere; reload them and try again"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to update profile settings",
+			slog.String("error", err.Error()),
+			slog.String("user_id", userID))
+		http.Error(w, `{"error":"Failed to update profile settings"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(settings.Version))
+	json.NewEncoder(w).Encode(ProfileSettingsResponse{
+		AvatarURL:        settings.AvatarURL,
+		Visibility:       settings.Visibility,
+		ShowOnlineStatus: settings.ShowOnlineStatus,
+		Version:          settings.Version,
+	})
+}
+
+// SetStatus sets the current user's status, which members of their
+// chatrooms see, and announces it where they are connected
+func (h *UserHandler) SetStatus(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req domain.UserStatus
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	status, err := h.profiles.SetStatus(r.Context(), userID, req)
+	if errors.Is(err, domain.ErrInvalidInput) {
+		http.Error(w, fmt.Sprintf(`{"error":"Status must be active, away or dnd, with at most %d characters of text"}`, domain.MaxStatusTextLength), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, domain.ErrUserNotFound) {
+		http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to set status",
+			slog.String("error", err.Error()),
+			slog.String("user_id", userID))
+		http.Error(w, `{"error":"Failed to set status"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+type ChangeUsernameRequest struct {
+	Username string `json:"username"`
+}
+
+type UsernameHistoryResponse struct {
+	Changes []*domain.UsernameChange `json:"changes"`
+}
+
+// ChangeUsername renames the current user. Their former username stays
+// theirs for the hold period, so mentions of it keep reaching them and
+// nobody else can pose as them under it.
+func (h *UserHandler) ChangeUsername(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req ChangeUsernameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	change, err := h.profiles.ChangeUsername(r.Context(), userID, req.Username)
+	var invalid *domain.ValidationError
+	if errors.As(err, &invalid) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ValidationErrorResponse{Error: "Invalid input", Fields: invalid.Fields})
+		return
+	}
+	var cooldown *domain.UsernameCooldownError
+	if errors.As(err, &cooldown) {
+		retryAfter := int(math.Ceil(time.Until(cooldown.Until).Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		http.Error(w, `{"error":"Username was changed recently, try again later"}`, http.StatusTooManyRequests)
+		return
+	}
+	if errors.Is(err, domain.ErrUsernameExists) {
+		http.Error(w, `{"error":"Username is not available"}`, http.StatusConflict)
+		return
+	}
+	if errors.Is(err, domain.ErrUserNotFound) {
+		http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to change username",
+			slog.String("error", err.Error()),
+			slog.String("user_id", userID))
+		http.Error(w, `{"error":"Failed to change username"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(change)
+}
+
+// UsernameHistory lists the current user's username changes, newest first
+func (h *UserHandler) UsernameHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	changes, err := h.profiles.UsernameHistory(r.Context(), userID)
+	if err != nil {
+		slog.Error("failed to get username history",
+			slog.String("error", err.Error()),
+			slog.String("user_id", userID))
+		http.Error(w, `{"error":"Failed to get username history"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UsernameHistoryResponse{Changes: changes})
+}
+
+// GetProfileByUsername redirects to the profile of the user with a username,
+// current or former, so links and mentions made before a rename still work
+func (h *UserHandler) GetProfileByUsername(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+	userID, err := h.profiles.ResolveUsername(r.Context(), username)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to resolve username",
+			slog.String("error", err.Error()),
+			slog.String("username", username))
+		http.Error(w, `{"error":"Failed to get profile"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Relative to /users/by-username/{username}, keeping the API version
+	http.Redirect(w, r, "../"+url.PathEscape(userID), http.StatusFound)
+}
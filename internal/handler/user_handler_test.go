@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+	"jobsity-chat/internal/service"
+	"jobsity-chat/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const profileUserID = "11111111-1111-1111-1111-111111111111"
+
+type stubPresence map[string]bool
+
+func (p stubPresence) IsUserOnline(userID string) bool {
+	return p[userID]
+}
+
+func newUserTestHandler() (*UserHandler, *testutil.MockUserRepository) {
+	userRepo := testutil.NewMockUserRepository()
+	userRepo.Users[profileUserID] = testutil.NewTestUser(testutil.WithUserID(profileUserID), testutil.WithUsername("alice"))
+	profiles := service.NewProfileService(userRepo, stubPresence{profileUserID: true})
+	return NewUserHandler(profiles), userRepo
+}
+
+func profileRequest(id, viewerID string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/"+id, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	if viewerID != "" {
+		req = req.WithContext(middleware.WithUserID(req.Context(), viewerID))
+	}
+	return req
+}
+
+func TestUserHandler_GetProfile(t *testing.T) {
+	handler, _ := newUserTestHandler()
+
+	w := httptest.NewRecorder()
+	handler.GetProfile(w, profileRequest(profileUserID, "viewer-1"))
+
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	var resp map[string]any
+	testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	testutil.AssertEqual(t, resp["username"], any("alice"))
+	testutil.AssertEqual(t, resp["online"], any(true))
+	_, hasEmail := resp["email"]
+	testutil.AssertFalse(t, hasEmail, "profile must not expose the email address")
+}
+
+func TestUserHandler_GetProfile_Private(t *testing.T) {
+	handler, userRepo := newUserTestHandler()
+	userRepo.ProfileSettings[profileUserID] = domain.ProfileSettings{Visibility: domain.ProfilePrivate, ShowOnlineStatus: true}
+
+	w := httptest.NewRecorder()
+	handler.GetProfile(w, profileRequest(profileUserID, "viewer-1"))
+
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	var resp map[string]any
+	testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	testutil.AssertEqual(t, len(resp), 2)
+	testutil.AssertEqual(t, resp["username"], any("alice"))
+}
+
+func TestUserHandler_GetProfile_Errors(t *testing.T) {
+	handler, _ := newUserTestHandler()
+
+	w := httptest.NewRecorder()
+	handler.GetProfile(w, profileRequest(profileUserID, ""))
+	testutil.AssertStatusCode(t, w, http.StatusUnauthorized)
+
+	w = httptest.NewRecorder()
+	handler.GetProfile(w, profileRequest("22222222-2222-2222-2222-222222222222", "viewer-1"))
+	testutil.AssertStatusCode(t, w, http.StatusNotFound)
+}
+
+func TestUserHandler_UpdateProfileSettings(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"success", `{"profile_visibility":"private","show_online_status":false}`, http.StatusOK},
+		{"invalid_body", `{`, http.StatusBadRequest},
+		{"invalid_visibility", `{"profile_visibility":"friends"}`, http.StatusBadRequest},
+		{"invalid_avatar", `{"avatar_url":"ftp://example.com/a.png"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, userRepo := newUserTestHandler()
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/auth/me/profile", strings.NewReader(tt.body))
+			req = req.WithContext(middleware.WithUserID(req.Context(), profileUserID))
+			w := httptest.NewRecorder()
+
+			handler.UpdateProfileSettings(w, req)
+
+			testutil.AssertStatusCode(t, w, tt.expectedStatus)
+			if tt.expectedStatus == http.StatusOK {
+				testutil.AssertEqual(t, userRepo.ProfileSettings[profileUserID], domain.ProfileSettings{Visibility: domain.ProfilePrivate, Version: 2})
+				testutil.AssertEqual(t, w.Header().Get("ETag"), `"2"`)
+			}
+		})
+	}
+}
+
+func TestUserHandler_UpdateProfileSettings_StaleVersion(t *testing.T) {
+	handler, userRepo := newUserTestHandler()
+	userRepo.ProfileSettings[profileUserID] = domain.ProfileSettings{Visibility: domain.ProfilePublic, Version: 3}
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/auth/me/profile", strings.NewReader(`{"profile_visibility":"private"}`))
+	req.Header.Set("If-Match", `"2"`)
+	req = req.WithContext(middleware.WithUserID(req.Context(), profileUserID))
+	w := httptest.NewRecorder()
+
+	handler.UpdateProfileSettings(w, req)
+
+	testutil.AssertStatusCode(t, w, http.StatusConflict)
+	testutil.AssertEqual(t, userRepo.ProfileSettings[profileUserID].Visibility, domain.ProfilePublic)
+}
+
+func TestUserHandler_SetStatus(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"success", `{"status":"dnd","text":"In a meeting"}`, http.StatusOK},
+		{"invalid_body", `{`, http.StatusBadRequest},
+		{"invalid_status", `{"status":"offline"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, userRepo := newUserTestHandler()
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/auth/me/status", strings.NewReader(tt.body))
+			req = req.WithContext(middleware.WithUserID(req.Context(), profileUserID))
+			w := httptest.NewRecorder()
+
+			handler.SetStatus(w, req)
+
+			testutil.AssertStatusCode(t, w, tt.expectedStatus)
+			if tt.expectedStatus == http.StatusOK {
+				want := domain.UserStatus{Status: domain.StatusDND, Text: "In a meeting"}
+				testutil.AssertEqual(t, userRepo.Statuses[profileUserID], want)
+				var resp domain.UserStatus
+				testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				testutil.AssertEqual(t, resp, want)
+			}
+		})
+	}
+}
+
+func changeUsernameRequest(userID, username string) *http.Request {
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/auth/me/username", strings.NewReader(`{"username":"`+username+`"}`))
+	return req.WithContext(middleware.WithUserID(req.Context(), userID))
+}
+
+func TestUserHandler_ChangeUsername(t *testing.T) {
+	userRepo := testutil.NewMockUserRepository()
+	userRepo.Users[profileUserID] = testutil.NewTestUser(testutil.WithUserID(profileUserID), testutil.WithUsername("alice"))
+	userRepo.Users["bob-id"] = testutil.NewTestUser(testutil.WithUserID("bob-id"), testutil.WithUsername("bob"))
+	profiles := service.NewProfileService(userRepo, stubPresence{})
+	profiles.SetUsernameHistory(testutil.NewMockUsernameHistoryRepository(userRepo), service.UsernamePolicy{Cooldown: time.Hour})
+	handler := NewUserHandler(profiles)
+
+	w := httptest.NewRecorder()
+	handler.ChangeUsername(w, changeUsernameRequest(profileUserID, "a!"))
+	testutil.AssertStatusCode(t, w, http.StatusBadRequest)
+	invalid := testutil.DecodeJSON[ValidationErrorResponse](t, w)
+	testutil.AssertLen(t, invalid.Fields, 1)
+	testutil.AssertEqual(t, invalid.Fields[0].Field, "username")
+
+	w = httptest.NewRecorder()
+	handler.ChangeUsername(w, changeUsernameRequest(profileUserID, "bob"))
+	testutil.AssertStatusCode(t, w, http.StatusConflict)
+
+	w = httptest.NewRecorder()
+	handler.ChangeUsername(w, changeUsernameRequest(profileUserID, "alicia"))
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	change := testutil.DecodeJSON[domain.UsernameChange](t, w)
+	testutil.AssertEqual(t, change.OldUsername, "alice")
+	testutil.AssertEqual(t, change.NewUsername, "alicia")
+
+	w = httptest.NewRecorder()
+	handler.ChangeUsername(w, changeUsernameRequest(profileUserID, "alice_b"))
+	testutil.AssertStatusCode(t, w, http.StatusTooManyRequests)
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	testutil.AssertNoError(t, err)
+	testutil.AssertTrue(t, retryAfter > 3500 && retryAfter <= 3600, "Retry-After should be the rest of the cooldown")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/username-history", nil)
+	w = httptest.NewRecorder()
+	handler.UsernameHistory(w, req.WithContext(middleware.WithUserID(req.Context(), profileUserID)))
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	history := testutil.DecodeJSON[UsernameHistoryResponse](t, w)
+	testutil.AssertLen(t, history.Changes, 1)
+}
+
+func TestUserHandler_GetProfileByUsername(t *testing.T) {
+	userRepo := testutil.NewMockUserRepository()
+	userRepo.Users[profileUserID] = testutil.NewTestUser(testutil.WithUserID(profileUserID), testutil.WithUsername("alice"))
+	history := testutil.NewMockUsernameHistoryRepository(userRepo)
+	profiles := service.NewProfileService(userRepo, stubPresence{})
+	profiles.SetUsernameHistory(history, service.UsernamePolicy{})
+	handler := NewUserHandler(profiles)
+	_, err := history.Rename(context.Background(), profileUserID, "alicia", time.Now())
+	testutil.AssertNoError(t, err)
+
+	byUsername := func(username string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/by-username/"+username, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("username", username)
+		w := httptest.NewRecorder()
+		handler.GetProfileByUsername(w, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+		return w
+	}
+
+	for _, username := range []string{"alicia", "alice"} {
+		w := byUsername(username)
+		testutil.AssertStatusCode(t, w, http.StatusFound)
+		testutil.AssertEqual(t, w.Header().Get("Location"), "/api/v1/users/"+profileUserID)
+	}
+	testutil.AssertStatusCode(t, byUsername("carol"), http.StatusNotFound)
+}
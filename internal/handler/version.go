@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// versionETag is the ETag of a versioned resource, e.g. "3"
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// expectedVersion returns the version an update is conditional on: the one in
+// its If-Match header if present, otherwise bodyVersion. "*" and a zero
+// version make the update unconditional. ok is false when If-Match is not a
+// single version ETag; weak ETags never match under If-Match.
+func expectedVersion(r *http.Request, bodyVersion int) (version int, ok bool) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	switch ifMatch {
+	case "":
+		return bodyVersion, bodyVersion >= 0
+	case "*":
+		return 0, true
+	}
+
+	unquoted, found := strings.CutPrefix(ifMatch, `"`)
+	if !found {
+		return 0, false
+	}
+	unquoted, found = strings.CutSuffix(unquoted, `"`)
+	if !found {
+		return 0, false
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
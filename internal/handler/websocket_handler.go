@@ -2,12 +2,16 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"jobsity-chat/internal/domain"
-	"jobsity-chat/internal/service"
+	"jobsity-chat/internal/i18n"
+	"jobsity-chat/internal/middleware"
 	ws "jobsity-chat/internal/websocket"
 
 	"github.com/go-chi/chi/v5"
@@ -22,15 +26,77 @@ func truncateToken(token string) string {
 	return token[:8] + "..."
 }
 
+// ChatSubprotocol is the WebSocket subprotocol spoken by the chat endpoint.
+// Clients authenticate without exposing the session token in the URL by
+// offering it as a second subprotocol, e.g.
+//
+//	Sec-WebSocket-Protocol: chat, token.<session token>
+//
+// The server always selects ChatSubprotocol, never the token entry.
+const ChatSubprotocol = "chat"
+
+// tokenSubprotocolPrefix marks the subprotocol entry carrying the token
+const tokenSubprotocolPrefix = "token."
+
+// ticketSubprotocolPrefix marks the subprotocol entry carrying a ticket
+const ticketSubprotocolPrefix = "ticket."
+
+// ProtocolVersionHeader is the upgrade response header carrying the
+// protocol version the connection speaks
+const ProtocolVersionHeader = "X-Chat-Protocol-Version"
+
+// ticketFromRequest returns a one-time ticket from the subprotocol header or
+// the ticket query parameter. Tickets are single-use and expire within
+// seconds, so unlike session tokens they are safe to put in URLs.
+func ticketFromRequest(r *http.Request) string {
+	for _, protocol := range websocket.Subprotocols(r) {
+		if ticket, ok := strings.CutPrefix(protocol, ticketSubprotocolPrefix); ok && ticket != "" {
+			return ticket
+		}
+	}
+	return r.URL.Query().Get("ticket")
+}
+
+// sessionTokenFromRequest returns the session token and where it was found.
+// The subprotocol header is preferred because, unlike the query string, it
+// does not end up in proxy and access logs.
+func sessionTokenFromRequest(r *http.Request) (token, source string) {
+	for _, protocol := range websocket.Subprotocols(r) {
+		if token, ok := strings.CutPrefix(protocol, tokenSubprotocolPrefix); ok && token != "" {
+			return token, "subprotocol"
+		}
+	}
+
+	if cookie, err := r.Cookie("session_id"); err == nil && cookie.Value != "" {
+		return cookie.Value, "cookie"
+	}
+
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && token != "" {
+		return token, "header"
+	}
+
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token, "query"
+	}
+
+	return "", ""
+}
+
 func createUpgrader(allowedOrigins []string) websocket.Upgrader {
 	return websocket.Upgrader{
 		ReadBufferSize:  1024,
 		WriteBufferSize: 1024,
+		Subprotocols:    []string{ChatSubprotocol},
 		CheckOrigin: func(r *http.Request) bool {
 			origin := r.Header.Get("Origin")
 			if origin == "" {
 				return true
 			}
+			// Pages served by this server may always connect; behind a
+			// trusted proxy its public scheme and host are compared
+			if origin == middleware.RequestScheme(r)+"://"+r.Host {
+				return true
+			}
 
 			for _, allowed := range allowedOrigins {
 				if origin == allowed || allowed == "*" {
@@ -46,16 +112,52 @@ func createUpgrader(allowedOrigins []string) websocket.Upgrader {
 	}
 }
 
+// ShadowBanSource lists the users shadow-banned in a chatroom
+type ShadowBanSource interface {
+	ShadowBannedUsers(ctx context.Context, chatroomID string) ([]string, error)
+}
+
+// StatusSource returns the status a user chose
+type StatusSource interface {
+	GetStatus(ctx context.Context, userID string) (*domain.UserStatus, error)
+}
+
+// WebSocketChatService admits users to chatrooms and serves the messages
+// of their connections
+type WebSocketChatService interface {
+	ws.ChatService
+	IsMember(ctx context.Context, chatroomID, userID string) (bool, error)
+	GetChatroom(ctx context.Context, chatroomID string) (*domain.Chatroom, error)
+	GuestAccess(ctx context.Context, chatroomID string) (bool, error)
+	ClaimWelcomeMessage(ctx context.Context, chatroomID, userID string) (string, error)
+}
+
+// UserSource looks users up by ID
+type UserSource interface {
+	GetUserByID(ctx context.Context, userID string) (*domain.User, error)
+}
+
+// TicketRedeemer exchanges a one-time ticket for the session it was issued
+// to
+type TicketRedeemer interface {
+	Redeem(ctx context.Context, ticket string) (*domain.Session, error)
+}
+
 type WebSocketHandler struct {
 	hub         *ws.Hub
-	chatService *service.ChatService
-	authService *service.AuthService
+	chatService WebSocketChatService
+	authService UserSource
 	publisher   ws.MessagePublisher
 	upgrader    websocket.Upgrader
 	sessionRepo domain.SessionRepository
+
+	sessionToucher middleware.SessionToucher
+	tickets        TicketRedeemer
+	shadowBans     ShadowBanSource
+	statuses       StatusSource
 }
 
-func NewWebSocketHandler(hub *ws.Hub, chatService *service.ChatService, authService *service.AuthService, publisher ws.MessagePublisher, sessionRepo domain.SessionRepository, allowedOrigins string) *WebSocketHandler {
+func NewWebSocketHandler(hub *ws.Hub, chatService WebSocketChatService, authService UserSource, publisher ws.MessagePublisher, sessionRepo domain.SessionRepository, allowedOrigins string) *WebSocketHandler {
 	origins := strings.Split(allowedOrigins, ",")
 	for i := range origins {
 		origins[i] = strings.TrimSpace(origins[i])
@@ -71,43 +173,37 @@ func NewWebSocketHandler(hub *ws.Hub, chatService *service.ChatService, authServ
 	}
 }
 
-func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Request) {
-	var sessionToken string
+// SetTicketService enables authentication with one-time tickets
+func (h *WebSocketHandler) SetTicketService(tickets TicketRedeemer) {
+	h.tickets = tickets
+}
 
-	if cookie, err := r.Cookie("session_id"); err == nil {
-		sessionToken = cookie.Value
-	}
+// SetSessionToucher renews the connection's session on every client message
+func (h *WebSocketHandler) SetSessionToucher(toucher middleware.SessionToucher) {
+	h.sessionToucher = toucher
+}
 
-	if sessionToken == "" {
-		sessionToken = r.URL.Query().Get("token")
-	}
+// SetShadowBanSource loads a chatroom's shadow-bans into the hub as clients
+// connect, so bans survive restarts and apply across instances
+func (h *WebSocketHandler) SetShadowBanSource(source ShadowBanSource) {
+	h.shadowBans = source
+}
 
-	if sessionToken == "" {
-		auth := r.Header.Get("Authorization")
-		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
-			sessionToken = token
-		}
-	}
+// SetStatusSource loads the status users chose as they connect, for the
+// presence frames sent when they go away and come back
+func (h *WebSocketHandler) SetStatusSource(source StatusSource) {
+	h.statuses = source
+}
 
-	if sessionToken == "" {
-		slog.Warn("websocket auth failed: no token",
-			slog.String("remote_addr", r.RemoteAddr),
-			slog.String("chatroom_id", chi.URLParam(r, "chatroom_id")))
-		http.Error(w, `{"error":"No session token provided"}`, http.StatusUnauthorized)
+func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Request) {
+	version, err := ws.ParseProtocolVersion(r.URL.Query().Get("protocol_version"))
+	if err != nil {
+		h.rejectProtocolVersion(w, r, err)
 		return
 	}
 
-	slog.Debug("websocket auth attempt",
-		slog.String("token", truncateToken(sessionToken)),
-		slog.String("chatroom_id", chi.URLParam(r, "chatroom_id")))
-
-	session, err := h.sessionRepo.GetByToken(r.Context(), sessionToken)
-	if err != nil {
-		slog.Warn("websocket auth failed: invalid session",
-			slog.String("error", err.Error()),
-			slog.String("token_prefix", truncateToken(sessionToken)),
-			slog.String("remote_addr", r.RemoteAddr))
-		http.Error(w, `{"error":"Invalid or expired session"}`, http.StatusUnauthorized)
+	session, ok := h.authenticate(w, r)
+	if !ok {
 		return
 	}
 
@@ -135,7 +231,24 @@ func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	conn, err := h.upgrader.Upgrade(w, r, nil)
+	chatroom, err := h.chatService.GetChatroom(r.Context(), chatroomID)
+	if err != nil {
+		http.Error(w, `{"error":"Chatroom not found"}`, http.StatusNotFound)
+		return
+	}
+
+	// Guests stay limited to public chatrooms, even ones they joined before
+	// the owner closed them
+	guestCanPost := false
+	if user.IsGuest() {
+		guestCanPost, err = h.chatService.GuestAccess(r.Context(), chatroomID)
+		if err != nil {
+			http.Error(w, `{"error":"Chatroom is not open to guests"}`, http.StatusForbidden)
+			return
+		}
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, http.Header{ProtocolVersionHeader: {strconv.Itoa(version)}})
 	if err != nil {
 		slog.Error("websocket upgrade error",
 			slog.String("error", err.Error()),
@@ -144,12 +257,149 @@ func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Use background context for the client since the HTTP request context
-	// will be cancelled after the upgrade completes
-	client := ws.NewClient(context.Background(), h.hub, conn, userID, user.Username, chatroomID, h.chatService, h.publisher)
+	// Detach from the request's cancellation since the HTTP request context
+	// will be cancelled after the upgrade completes, but keep its values
+	// (request and correlation IDs) for logging.
+	clientCtx := context.WithoutCancel(r.Context())
+	// Bot responses and system messages use the user's preferred locale,
+	// falling back to the browser's
+	clientCtx = i18n.WithLocale(clientCtx, i18n.Resolve(user.Locale, r.Header.Get("Accept-Language")))
+	client := ws.NewClient(clientCtx, h.hub, conn, userID, user.Username, chatroomID, h.chatService, h.publisher)
+	client.SetProtocolVersion(version)
+	client.SetEncrypted(chatroom.Encrypted)
+	client.SetReadOnly(user.IsGuest() && !guestCanPost)
 
-	h.hub.Register(client)
+	if h.sessionToucher != nil {
+		client.SetActivityHook(func() { h.sessionToucher.Touch(session) })
+	}
+
+	if h.statuses != nil {
+		status, err := h.statuses.GetStatus(clientCtx, userID)
+		if err != nil {
+			slog.Error("failed to load status",
+				slog.String("error", err.Error()),
+				slog.String("user_id", userID))
+		} else {
+			client.SetStatus(*status)
+		}
+	}
+
+	if h.shadowBans != nil {
+		banned, err := h.shadowBans.ShadowBannedUsers(clientCtx, chatroomID)
+		if err != nil {
+			slog.Error("failed to load shadow-bans",
+				slog.String("error", err.Error()),
+				slog.String("chatroom_id", chatroomID))
+		} else {
+			h.hub.SetShadowBans(chatroomID, banned)
+		}
+	}
+
+	if err := h.hub.Register(client); err != nil {
+		client.Reject(ws.CloseDuplicateConnection, "already connected to this chatroom")
+		return
+	}
+
+	welcome, err := h.chatService.ClaimWelcomeMessage(clientCtx, chatroomID, userID)
+	if err != nil {
+		slog.Error("failed to claim welcome message",
+			slog.String("error", err.Error()),
+			slog.String("user_id", userID),
+			slog.String("chatroom_id", chatroomID))
+	} else if welcome != "" {
+		client.SendWelcome(welcome)
+	}
 
 	go client.WritePump()
 	go client.ReadPump()
 }
+
+// rejectProtocolVersion refuses a connection asking for a protocol version
+// the server does not speak. Browsers cannot read the response to a failed
+// handshake, so WebSocket requests are upgraded and closed with
+// ws.CloseUnsupportedProtocolVersion and the supported versions instead.
+func (h *WebSocketHandler) rejectProtocolVersion(w http.ResponseWriter, r *http.Request, err error) {
+	slog.Warn("websocket protocol version rejected",
+		slog.String("error", err.Error()),
+		slog.String("remote_addr", r.RemoteAddr))
+
+	if !websocket.IsWebSocketUpgrade(r) {
+		http.Error(w, `{"error":"Unsupported protocol version"}`, http.StatusBadRequest)
+		return
+	}
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reason := "supported protocol versions: " + ws.SupportedVersionList()
+	_ = conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(ws.CloseUnsupportedProtocolVersion, reason),
+		time.Now().Add(time.Second))
+}
+
+// Protocol describes the frames of a WebSocket protocol version, the
+// current one unless ?version= asks for another supported one
+func (h *WebSocketHandler) Protocol(w http.ResponseWriter, r *http.Request) {
+	version := ws.ProtocolVersion
+	if v := r.URL.Query().Get("version"); v != "" {
+		var err error
+		if version, err = ws.ParseProtocolVersion(v); err != nil {
+			http.Error(w, `{"error":"Unsupported protocol version"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ws.Schema(version))
+}
+
+// authenticate resolves the connection's session from a one-time ticket or,
+// failing that, a session token. It writes the error response itself.
+func (h *WebSocketHandler) authenticate(w http.ResponseWriter, r *http.Request) (*domain.Session, bool) {
+	if ticket := ticketFromRequest(r); ticket != "" && h.tickets != nil {
+		session, err := h.tickets.Redeem(r.Context(), ticket)
+		if err != nil {
+			slog.Warn("websocket auth failed: invalid ticket",
+				slog.String("error", err.Error()),
+				slog.String("ticket_prefix", truncateToken(ticket)),
+				slog.String("remote_addr", r.RemoteAddr))
+			http.Error(w, `{"error":"Invalid or expired ticket"}`, http.StatusUnauthorized)
+			return nil, false
+		}
+		return session, true
+	}
+
+	sessionToken, tokenSource := sessionTokenFromRequest(r)
+
+	if sessionToken == "" {
+		slog.Warn("websocket auth failed: no token",
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.String("chatroom_id", chi.URLParam(r, "chatroom_id")))
+		http.Error(w, `{"error":"No session token provided"}`, http.StatusUnauthorized)
+		return nil, false
+	}
+
+	slog.Debug("websocket auth attempt",
+		slog.String("token", truncateToken(sessionToken)),
+		slog.String("token_source", tokenSource),
+		slog.String("chatroom_id", chi.URLParam(r, "chatroom_id")))
+
+	if tokenSource == "query" {
+		slog.Warn("websocket token passed in query string; use a ticket or the Sec-WebSocket-Protocol header instead",
+			slog.String("remote_addr", r.RemoteAddr))
+	}
+
+	session, err := h.sessionRepo.GetByToken(r.Context(), sessionToken)
+	if err != nil {
+		slog.Warn("websocket auth failed: invalid session",
+			slog.String("error", err.Error()),
+			slog.String("token_prefix", truncateToken(sessionToken)),
+			slog.String("remote_addr", r.RemoteAddr))
+		http.Error(w, `{"error":"Invalid or expired session"}`, http.StatusUnauthorized)
+		return nil, false
+	}
+
+	return session, true
+}
@@ -2,16 +2,21 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
 	"jobsity-chat/internal/service"
 	"jobsity-chat/internal/testutil"
 	ws "jobsity-chat/internal/websocket"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
 )
 
 // setupWebSocketHandler creates a WebSocketHandler with mock dependencies for testing
@@ -76,6 +81,7 @@ func TestWebSocketHandler_TokenFromCookie(t *testing.T) {
 
 	// Setup chatroom membership
 	chatroomRepo.Members["room-1"] = map[string]bool{"user-123": true}
+	chatroomRepo.Chatrooms["room-1"] = testutil.NewTestChatroom(testutil.WithChatroomID("room-1"))
 
 	handler := setupWebSocketHandler(sessionRepo, userRepo, chatroomRepo, "*")
 
@@ -113,6 +119,7 @@ func TestWebSocketHandler_TokenFromQuery(t *testing.T) {
 
 	// Setup chatroom membership
 	chatroomRepo.Members["room-1"] = map[string]bool{"user-123": true}
+	chatroomRepo.Chatrooms["room-1"] = testutil.NewTestChatroom(testutil.WithChatroomID("room-1"))
 
 	handler := setupWebSocketHandler(sessionRepo, userRepo, chatroomRepo, "*")
 
@@ -147,6 +154,7 @@ func TestWebSocketHandler_TokenFromHeader(t *testing.T) {
 
 	// Setup chatroom membership
 	chatroomRepo.Members["room-1"] = map[string]bool{"user-123": true}
+	chatroomRepo.Chatrooms["room-1"] = testutil.NewTestChatroom(testutil.WithChatroomID("room-1"))
 
 	handler := setupWebSocketHandler(sessionRepo, userRepo, chatroomRepo, "*")
 
@@ -161,6 +169,191 @@ func TestWebSocketHandler_TokenFromHeader(t *testing.T) {
 	testutil.AssertTrue(t, w.Code != http.StatusForbidden, "should not return 403")
 }
 
+func TestWebSocketHandler_TokenFromSubprotocol(t *testing.T) {
+	sessionRepo := testutil.NewMockSessionRepository()
+	userRepo := testutil.NewMockUserRepository()
+	chatroomRepo := testutil.NewMockChatroomRepository()
+
+	session := testutil.NewTestSession(
+		testutil.WithToken("valid-protocol-token"),
+		testutil.WithSessionUserID("user-123"),
+	)
+	sessionRepo.Sessions[session.Token] = session
+
+	user := testutil.NewTestUser(
+		testutil.WithUserID("user-123"),
+		testutil.WithUsername("testuser"),
+	)
+	userRepo.Users[user.ID] = user
+
+	chatroomRepo.Members["room-1"] = map[string]bool{"user-123": true}
+	chatroomRepo.Chatrooms["room-1"] = testutil.NewTestChatroom(testutil.WithChatroomID("room-1"))
+
+	handler := setupWebSocketHandler(sessionRepo, userRepo, chatroomRepo, "*")
+
+	r := chi.NewRouter()
+	r.Get("/ws/chat/{chatroom_id}", handler.HandleConnection)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/chat/room-1"
+	dialer := websocket.Dialer{Subprotocols: []string{ChatSubprotocol, "token.valid-protocol-token"}}
+	conn, resp, err := dialer.Dial(wsURL, nil)
+	testutil.AssertNoError(t, err)
+	defer conn.Close()
+
+	testutil.AssertEqual(t, resp.StatusCode, http.StatusSwitchingProtocols)
+	testutil.AssertEqual(t, conn.Subprotocol(), ChatSubprotocol)
+}
+
+func TestWebSocketHandler_ProtocolVersion(t *testing.T) {
+	sessionRepo := testutil.NewMockSessionRepository()
+	userRepo := testutil.NewMockUserRepository()
+	chatroomRepo := testutil.NewMockChatroomRepository()
+
+	session := testutil.NewTestSession(
+		testutil.WithToken("valid-protocol-token"),
+		testutil.WithSessionUserID("user-123"),
+	)
+	sessionRepo.Sessions[session.Token] = session
+	userRepo.Users["user-123"] = testutil.NewTestUser(
+		testutil.WithUserID("user-123"),
+		testutil.WithUsername("testuser"),
+	)
+	chatroomRepo.Members["room-1"] = map[string]bool{"user-123": true}
+	chatroomRepo.Chatrooms["room-1"] = testutil.NewTestChatroom(testutil.WithChatroomID("room-1"))
+
+	handler := setupWebSocketHandler(sessionRepo, userRepo, chatroomRepo, "*")
+
+	r := chi.NewRouter()
+	r.Get("/ws/chat/{chatroom_id}", handler.HandleConnection)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/chat/room-1"
+	dialer := websocket.Dialer{Subprotocols: []string{ChatSubprotocol, "token.valid-protocol-token"}}
+
+	t.Run("supported version is confirmed", func(t *testing.T) {
+		conn, resp, err := dialer.Dial(wsURL+"?protocol_version=1", nil)
+		testutil.AssertNoError(t, err)
+		defer conn.Close()
+
+		testutil.AssertEqual(t, resp.Header.Get(ProtocolVersionHeader), "1")
+	})
+
+	t.Run("unknown version is closed with the supported ones", func(t *testing.T) {
+		conn, _, err := dialer.Dial(wsURL+"?protocol_version=99", nil)
+		testutil.AssertNoError(t, err)
+		defer conn.Close()
+
+		_, _, err = conn.ReadMessage()
+		var closeErr *websocket.CloseError
+		testutil.AssertTrue(t, errors.As(err, &closeErr), "expected a close frame")
+		testutil.AssertEqual(t, closeErr.Code, ws.CloseUnsupportedProtocolVersion)
+		testutil.AssertEqual(t, closeErr.Text, "supported protocol versions: 1")
+	})
+
+	t.Run("plain request gets a 400", func(t *testing.T) {
+		req := createRequestWithChiContext(http.MethodGet, "/ws/chat/room-1?protocol_version=abc", "room-1")
+		w := httptest.NewRecorder()
+
+		handler.HandleConnection(w, req)
+
+		testutil.AssertEqual(t, w.Code, http.StatusBadRequest)
+	})
+}
+
+func TestWebSocketHandler_Protocol(t *testing.T) {
+	handler := setupWebSocketHandler(testutil.NewMockSessionRepository(), testutil.NewMockUserRepository(),
+		testutil.NewMockChatroomRepository(), "*")
+
+	w := httptest.NewRecorder()
+	handler.Protocol(w, httptest.NewRequest(http.MethodGet, "/api/v1/ws/protocol", nil))
+
+	testutil.AssertEqual(t, w.Code, http.StatusOK)
+	var schema ws.ProtocolSchema
+	testutil.AssertNoError(t, json.Unmarshal(w.Body.Bytes(), &schema))
+	testutil.AssertEqual(t, schema.Version, ws.ProtocolVersion)
+	testutil.AssertLen(t, schema.ClientFrames, len(ws.ClientFrames))
+	testutil.AssertLen(t, schema.ServerFrames, len(ws.ServerFrames))
+
+	w = httptest.NewRecorder()
+	handler.Protocol(w, httptest.NewRequest(http.MethodGet, "/api/v1/ws/protocol?version=2", nil))
+	testutil.AssertEqual(t, w.Code, http.StatusBadRequest)
+}
+
+func TestSessionTokenFromRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(r *http.Request)
+		wantToken  string
+		wantSource string
+	}{
+		{
+			name:       "no token",
+			setup:      func(r *http.Request) {},
+			wantToken:  "",
+			wantSource: "",
+		},
+		{
+			name: "subprotocol preferred over cookie and query",
+			setup: func(r *http.Request) {
+				r.Header.Set("Sec-WebSocket-Protocol", "chat, token.protocol-token")
+				r.AddCookie(&http.Cookie{Name: "session_id", Value: "cookie-token"})
+				r.URL.RawQuery = "token=query-token"
+			},
+			wantToken:  "protocol-token",
+			wantSource: "subprotocol",
+		},
+		{
+			name: "cookie preferred over query",
+			setup: func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: "session_id", Value: "cookie-token"})
+				r.URL.RawQuery = "token=query-token"
+			},
+			wantToken:  "cookie-token",
+			wantSource: "cookie",
+		},
+		{
+			name: "bearer header preferred over query",
+			setup: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer header-token")
+				r.URL.RawQuery = "token=query-token"
+			},
+			wantToken:  "header-token",
+			wantSource: "header",
+		},
+		{
+			name: "query as last resort",
+			setup: func(r *http.Request) {
+				r.URL.RawQuery = "token=query-token"
+			},
+			wantToken:  "query-token",
+			wantSource: "query",
+		},
+		{
+			name: "empty token subprotocol is ignored",
+			setup: func(r *http.Request) {
+				r.Header.Set("Sec-WebSocket-Protocol", "chat, token.")
+			},
+			wantToken:  "",
+			wantSource: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ws/chat/room-1", nil)
+			tt.setup(req)
+
+			token, source := sessionTokenFromRequest(req)
+
+			testutil.AssertEqual(t, token, tt.wantToken)
+			testutil.AssertEqual(t, source, tt.wantSource)
+		})
+	}
+}
+
 func TestWebSocketHandler_InvalidSession(t *testing.T) {
 	sessionRepo := testutil.NewMockSessionRepository()
 	userRepo := testutil.NewMockUserRepository()
@@ -272,6 +465,7 @@ func TestWebSocketHandler_UserNotFound(t *testing.T) {
 
 	// Setup chatroom membership but NO user in repo
 	chatroomRepo.Members["room-1"] = map[string]bool{"user-123": true}
+	chatroomRepo.Chatrooms["room-1"] = testutil.NewTestChatroom(testutil.WithChatroomID("room-1"))
 
 	handler := setupWebSocketHandler(sessionRepo, userRepo, chatroomRepo, "*")
 
@@ -341,6 +535,28 @@ func TestCreateUpgrader_AllowedOrigin(t *testing.T) {
 	}
 }
 
+func TestCreateUpgrader_SameOriginBehindProxy(t *testing.T) {
+	upgrader := createUpgrader([]string{"http://localhost:3000"})
+	trusted, _ := middleware.ParseTrustedProxies("192.0.2.0/24")
+
+	var got bool
+	check := middleware.Proxy(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = upgrader.CheckOrigin(r)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.Header.Set("Origin", "https://chat.example.com")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "chat.example.com")
+	check.ServeHTTP(httptest.NewRecorder(), req)
+	testutil.AssertTrue(t, got, "the server's public origin should be allowed")
+
+	// Without the proxy's scheme the page's origin doesn't match
+	req.Header.Del("X-Forwarded-Proto")
+	check.ServeHTTP(httptest.NewRecorder(), req)
+	testutil.AssertFalse(t, got, "an http request should not match an https origin")
+}
+
 func TestCreateUpgrader_WildcardOrigin(t *testing.T) {
 	upgrader := createUpgrader([]string{"*"})
 
@@ -415,6 +631,7 @@ func TestWebSocketHandler_TokenPriority(t *testing.T) {
 
 	// Setup membership for cookie user
 	chatroomRepo.Members["room-1"] = map[string]bool{"user-cookie": true}
+	chatroomRepo.Chatrooms["room-1"] = testutil.NewTestChatroom(testutil.WithChatroomID("room-1"))
 
 	handler := setupWebSocketHandler(sessionRepo, userRepo, chatroomRepo, "*")
 
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+)
+
+// WSTicketIssuer exchanges sessions for single-use WebSocket tickets
+type WSTicketIssuer interface {
+	Issue(ctx context.Context, session *domain.Session) (*domain.WSTicket, error)
+}
+
+type WSTicketHandler struct {
+	tickets WSTicketIssuer
+}
+
+func NewWSTicketHandler(tickets WSTicketIssuer) *WSTicketHandler {
+	return &WSTicketHandler{tickets: tickets}
+}
+
+type WSTicketResponse struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Issue exchanges the caller's session for a single-use WebSocket ticket
+func (h *WSTicketHandler) Issue(w http.ResponseWriter, r *http.Request) {
+	session, ok := middleware.GetSession(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"Session not found"}`, http.StatusUnauthorized)
+		return
+	}
+
+	ticket, err := h.tickets.Issue(r.Context(), session)
+	if err != nil {
+		slog.Error("failed to issue ws ticket",
+			slog.String("error", err.Error()),
+			slog.String("user_id", session.UserID))
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(WSTicketResponse{
+		Ticket:    ticket.Ticket,
+		ExpiresAt: ticket.ExpiresAt,
+	}); err != nil {
+		slog.Error("failed to encode ws ticket response", slog.String("error", err.Error()))
+	}
+}
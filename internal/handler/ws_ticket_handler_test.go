@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/middleware"
+	"jobsity-chat/internal/service"
+	"jobsity-chat/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+)
+
+func TestWSTicketHandler_Issue(t *testing.T) {
+	sessionRepo := testutil.NewMockSessionRepository()
+	ticketRepo := testutil.NewMockWSTicketRepository()
+	handler := NewWSTicketHandler(service.NewWSTicketService(ticketRepo, sessionRepo))
+
+	session := testutil.NewTestSession(testutil.WithToken("session-token"))
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ws-ticket", nil)
+	req = req.WithContext(middleware.WithSession(req.Context(), session))
+	w := httptest.NewRecorder()
+
+	handler.Issue(w, req)
+
+	testutil.AssertStatusCode(t, w, http.StatusCreated)
+	testutil.AssertHeader(t, w, "Cache-Control", "no-store")
+
+	var resp WSTicketResponse
+	testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	testutil.AssertNotEqual(t, resp.Ticket, "")
+	testutil.AssertEqual(t, ticketRepo.Tickets[resp.Ticket].SessionToken, "session-token")
+}
+
+func TestWSTicketHandler_Issue_NoSession(t *testing.T) {
+	handler := NewWSTicketHandler(service.NewWSTicketService(testutil.NewMockWSTicketRepository(), testutil.NewMockSessionRepository()))
+
+	w := httptest.NewRecorder()
+	handler.Issue(w, httptest.NewRequest(http.MethodPost, "/api/v1/ws-ticket", nil))
+
+	testutil.AssertStatusCode(t, w, http.StatusUnauthorized)
+}
+
+func TestWSTicketHandler_Issue_RepositoryError(t *testing.T) {
+	ticketRepo := testutil.NewMockWSTicketRepository()
+	ticketRepo.CreateFunc = func(ctx context.Context, ticket *domain.WSTicket) error {
+		return errors.New("connection lost")
+	}
+	handler := NewWSTicketHandler(service.NewWSTicketService(ticketRepo, testutil.NewMockSessionRepository()))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ws-ticket", nil)
+	req = req.WithContext(middleware.WithSession(req.Context(), testutil.NewTestSession()))
+	w := httptest.NewRecorder()
+
+	handler.Issue(w, req)
+
+	testutil.AssertStatusCode(t, w, http.StatusInternalServerError)
+}
+
+func TestWebSocketHandler_Ticket(t *testing.T) {
+	sessionRepo := testutil.NewMockSessionRepository()
+	userRepo := testutil.NewMockUserRepository()
+	chatroomRepo := testutil.NewMockChatroomRepository()
+
+	session := testutil.NewTestSession(
+		testutil.WithToken("session-token"),
+		testutil.WithSessionUserID("user-123"),
+	)
+	sessionRepo.Sessions[session.Token] = session
+	user := testutil.NewTestUser(
+		testutil.WithUserID("user-123"),
+		testutil.WithUsername("testuser"),
+	)
+	userRepo.Users[user.ID] = user
+	chatroomRepo.Members["room-1"] = map[string]bool{"user-123": true}
+	chatroomRepo.Chatrooms["room-1"] = testutil.NewTestChatroom(testutil.WithChatroomID("room-1"))
+
+	tickets := service.NewWSTicketService(testutil.NewMockWSTicketRepository(), sessionRepo)
+	handler := setupWebSocketHandler(sessionRepo, userRepo, chatroomRepo, "*")
+	handler.SetTicketService(tickets)
+
+	r := chi.NewRouter()
+	r.Get("/ws/chat/{chatroom_id}", handler.HandleConnection)
+	server := httptest.NewServer(r)
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/chat/room-1"
+
+	ticket, err := tickets.Issue(context.Background(), session)
+	testutil.AssertNoError(t, err)
+
+	t.Run("ticket in subprotocol connects", func(t *testing.T) {
+		dialer := websocket.Dialer{Subprotocols: []string{ChatSubprotocol, "ticket." + ticket.Ticket}}
+		conn, _, err := dialer.Dial(wsURL, nil)
+		testutil.AssertNoError(t, err)
+		conn.Close()
+	})
+
+	t.Run("ticket cannot be reused", func(t *testing.T) {
+		_, resp, err := websocket.DefaultDialer.Dial(wsURL+"?ticket="+ticket.Ticket, nil)
+		testutil.AssertError(t, err)
+		testutil.AssertEqual(t, resp.StatusCode, http.StatusUnauthorized)
+	})
+}
@@ -0,0 +1,83 @@
+package i18n
+
+var en = catalog{
+	messages: map[string]string{
+		BotQuote:          "%s quote is $%.2f per share",
+		BotQuoteChange:    "%s quote is $%.2f per share (%+.2f%% today)",
+		BotQuoteFailed:    "Failed to fetch quote for %s",
+		BotStockNotFound:  "Stock %s not found",
+		BotStockSuggest:   "Stock %s not found. Did you mean %s?",
+		BotUnknownCommand: "Unknown command type: %s",
+		BotZenFailed:      "Failed to fetch a zen phrase",
+		BotGIF:            "GIF for \"%s\"",
+		BotGIFFailed:      "Failed to fetch a GIF for \"%s\"",
+		BotGIFNotFound:    "No GIF found for \"%s\"",
+		BotGIFDisabled:    "GIF search is not enabled",
+
+		ErrorBotUnavailable: "No bot answers this command in this chatroom",
+		ErrorCommandFailed:  "Failed to process command",
+		ErrorFetchFailed:    "Failed to load missed messages",
+		ErrorInvalidQuote:   "The quoted message is not in this chatroom",
+		ErrorMalformedFrame: "Your client sent a message the server could not read",
+		ErrorMessageFailed:  "Your message could not be sent",
+		ErrorMessageQuota:   "This chatroom has reached its daily message limit",
+		ErrorReadOnly:       "Guests can only read this chatroom; register to post",
+		ErrorRoomReadOnly:   "Only the owner and moderators can post in this chatroom",
+		ErrorUnknownFrame:   "This kind of frame is not supported by your client's protocol version",
+
+		SystemUserJoined: "%s joined the chatroom",
+		SystemUserLeft:   "%s left the chatroom",
+	},
+	zen: []string{
+		"The obstacle is the path.",
+		"Let go or be dragged.",
+		"The quieter you become, the more you can hear.",
+		"Nature does not hurry, yet everything is accomplished.",
+		"When you realize nothing is lacking, the whole world belongs to you.",
+		"The journey of a thousand miles begins with a single step.",
+		"Be like water, flowing around obstacles.",
+		"In the midst of chaos, there is also opportunity.",
+		"The wise adapt themselves to circumstances, as water molds itself to the pitcher.",
+		"Tension is who you think you should be. Relaxation is who you are.",
+		"Empty your mind, be formless, shapeless — like water.",
+		"The seed of suffering in you may be strong, but don't wait until you have no more suffering before allowing yourself to be happy.",
+		"Walk as if you are kissing the Earth with your feet.",
+		"Breathing in, I calm body and mind. Breathing out, I smile.",
+		"The present moment is filled with joy and happiness. If you are attentive, you will see it.",
+		"Wherever you are, be there totally.",
+		"Realize deeply that the present moment is all you have.",
+		"Accept — then act. Whatever the present moment contains, accept it as if you had chosen it.",
+		"The primary cause of unhappiness is never the situation but your thoughts about it.",
+		"Life is a balance of holding on and letting go.",
+		"Sometimes you need to step outside, get some air, and remind yourself of who you are and where you want to be.",
+		"The only Zen you find on tops of mountains is the Zen you bring there.",
+		"Before enlightenment: chop wood, carry water. After enlightenment: chop wood, carry water.",
+		"Let things flow naturally forward in whatever way they like.",
+		"Do not seek the truth, only cease to cherish your opinions.",
+		"When the student is ready, the teacher appears.",
+		"The cave you fear to enter holds the treasure you seek.",
+		"Silence is the language of the wise.",
+		"The mind is everything. What you think you become.",
+		"Peace comes from within. Do not seek it without.",
+		"No snowflake ever falls in the wrong place.",
+		"Knowledge is learning something new every day. Wisdom is letting go of something every day.",
+		"In the beginner's mind there are many possibilities, but in the expert's there are few.",
+		"If you understand, things are just as they are. If you do not understand, things are just as they are.",
+		"The moon does not fight. It attacks no one. It does not worry. It does not try to crush others.",
+		"Sitting quietly, doing nothing, spring comes, and the grass grows by itself.",
+		"The snow falls, each flake in its appropriate place.",
+		"To a mind that is still, the whole universe surrenders.",
+		"Muddy water is best cleared by leaving it alone.",
+		"The best time to plant a tree was 20 years ago. The second best time is now.",
+		"A single arrow is easily broken, but not ten in a bundle.",
+		"The bamboo that bends is stronger than the oak that resists.",
+		"Where there is no desire, there is stillness.",
+		"The flame that burns twice as bright burns half as long.",
+		"Be master of mind rather than mastered by mind.",
+		"Flow with whatever may happen and let your mind be free.",
+		"The wise man knows he doesn't know. The fool thinks he knows all.",
+		"Inner peace begins the moment you choose not to allow another person or event to control your emotions.",
+		"Patience is not about waiting, but the ability to keep a good attitude while working hard.",
+		"The root of suffering is attachment.",
+	},
+}
@@ -0,0 +1,83 @@
+package i18n
+
+var es = catalog{
+	messages: map[string]string{
+		BotQuote:          "La cotización de %s es $%.2f por acción",
+		BotQuoteChange:    "La cotización de %s es $%.2f por acción (%+.2f%% hoy)",
+		BotQuoteFailed:    "No se pudo obtener la cotización de %s",
+		BotStockNotFound:  "No se encontró la acción %s",
+		BotStockSuggest:   "No se encontró la acción %s. ¿Quisiste decir %s?",
+		BotUnknownCommand: "Tipo de comando desconocido: %s",
+		BotZenFailed:      "No se pudo obtener una frase zen",
+		BotGIF:            "GIF de \"%s\"",
+		BotGIFFailed:      "No se pudo obtener un GIF de \"%s\"",
+		BotGIFNotFound:    "No se encontró ningún GIF de \"%s\"",
+		BotGIFDisabled:    "La búsqueda de GIF no está habilitada",
+
+		ErrorBotUnavailable: "Ningún bot responde a este comando en esta sala",
+		ErrorCommandFailed:  "No se pudo procesar el comando",
+		ErrorFetchFailed:    "No se pudieron cargar los mensajes perdidos",
+		ErrorInvalidQuote:   "El mensaje citado no está en esta sala",
+		ErrorMalformedFrame: "Tu cliente envió un mensaje que el servidor no pudo leer",
+		ErrorMessageFailed:  "No se pudo enviar tu mensaje",
+		ErrorMessageQuota:   "Esta sala alcanzó su límite diario de mensajes",
+		ErrorReadOnly:       "Los invitados solo pueden leer esta sala; regístrate para escribir",
+		ErrorRoomReadOnly:   "Solo el propietario y los moderadores pueden escribir en esta sala",
+		ErrorUnknownFrame:   "La versión del protocolo de tu cliente no admite este tipo de mensaje",
+
+		SystemUserJoined: "%s se unió a la sala",
+		SystemUserLeft:   "%s salió de la sala",
+	},
+	zen: []string{
+		"El obstáculo es el camino.",
+		"Suelta o serás arrastrado.",
+		"Cuanto más callado te vuelves, más puedes oír.",
+		"La naturaleza no se apresura, y sin embargo todo se cumple.",
+		"Cuando te das cuenta de que nada falta, el mundo entero te pertenece.",
+		"Un viaje de mil millas comienza con un solo paso.",
+		"Sé como el agua, que fluye alrededor de los obstáculos.",
+		"En medio del caos también hay oportunidad.",
+		"El sabio se adapta a las circunstancias, como el agua toma la forma del cántaro.",
+		"La tensión es quien crees que deberías ser. La relajación es quien eres.",
+		"Vacía tu mente, sé amorfo, sin forma, como el agua.",
+		"La semilla del sufrimiento en ti puede ser fuerte, pero no esperes a no sufrir para permitirte ser feliz.",
+		"Camina como si besaras la Tierra con tus pies.",
+		"Al inspirar, calmo cuerpo y mente. Al espirar, sonrío.",
+		"El momento presente está lleno de alegría y felicidad. Si estás atento, lo verás.",
+		"Dondequiera que estés, está allí por completo.",
+		"Comprende profundamente que el momento presente es todo lo que tienes.",
+		"Acepta y luego actúa. Lo que sea que contenga el momento presente, acéptalo como si lo hubieras elegido.",
+		"La causa principal de la infelicidad nunca es la situación, sino lo que piensas de ella.",
+		"La vida es un equilibrio entre aferrarse y soltar.",
+		"A veces necesitas salir, tomar aire y recordar quién eres y dónde quieres estar.",
+		"El único Zen que encuentras en la cima de las montañas es el Zen que llevas contigo.",
+		"Antes de la iluminación: cortar leña, acarrear agua. Después de la iluminación: cortar leña, acarrear agua.",
+		"Deja que las cosas fluyan con naturalidad, del modo que quieran.",
+		"No busques la verdad, solo deja de aferrarte a tus opiniones.",
+		"Cuando el alumno está listo, aparece el maestro.",
+		"La cueva en la que temes entrar guarda el tesoro que buscas.",
+		"El silencio es el lenguaje de los sabios.",
+		"La mente lo es todo. En lo que piensas te conviertes.",
+		"La paz viene de dentro. No la busques fuera.",
+		"Ningún copo de nieve cae en el lugar equivocado.",
+		"El conocimiento es aprender algo nuevo cada día. La sabiduría es soltar algo cada día.",
+		"En la mente del principiante hay muchas posibilidades; en la del experto, pocas.",
+		"Si comprendes, las cosas son como son. Si no comprendes, las cosas son como son.",
+		"La luna no pelea. No ataca a nadie. No se preocupa. No intenta aplastar a otros.",
+		"Sentado en silencio, sin hacer nada, llega la primavera y la hierba crece sola.",
+		"Cae la nieve, cada copo en su lugar apropiado.",
+		"Ante una mente en calma, el universo entero se rinde.",
+		"El agua turbia se aclara mejor si se la deja en paz.",
+		"El mejor momento para plantar un árbol fue hace 20 años. El segundo mejor momento es ahora.",
+		"Una sola flecha se rompe con facilidad, pero no diez en un haz.",
+		"El bambú que se dobla es más fuerte que el roble que resiste.",
+		"Donde no hay deseo, hay quietud.",
+		"La llama que arde con el doble de brillo dura la mitad.",
+		"Sé dueño de tu mente en lugar de que tu mente sea tu dueña.",
+		"Fluye con lo que suceda y deja tu mente libre.",
+		"El sabio sabe que no sabe. El necio cree saberlo todo.",
+		"La paz interior comienza en el momento en que decides no permitir que otra persona o suceso controle tus emociones.",
+		"La paciencia no consiste en esperar, sino en mantener una buena actitud mientras trabajas duro.",
+		"La raíz del sufrimiento es el apego.",
+	},
+}
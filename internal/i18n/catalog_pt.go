@@ -0,0 +1,83 @@
+package i18n
+
+var pt = catalog{
+	messages: map[string]string{
+		BotQuote:          "A cotação de %s é $%.2f por ação",
+		BotQuoteChange:    "A cotação de %s é $%.2f por ação (%+.2f%% hoje)",
+		BotQuoteFailed:    "Não foi possível obter a cotação de %s",
+		BotStockNotFound:  "Ação %s não encontrada",
+		BotStockSuggest:   "Ação %s não encontrada. Você quis dizer %s?",
+		BotUnknownCommand: "Tipo de comando desconhecido: %s",
+		BotZenFailed:      "Não foi possível obter uma frase zen",
+		BotGIF:            "GIF de \"%s\"",
+		BotGIFFailed:      "Não foi possível obter um GIF de \"%s\"",
+		BotGIFNotFound:    "Nenhum GIF encontrado para \"%s\"",
+		BotGIFDisabled:    "A busca de GIF não está habilitada",
+
+		ErrorBotUnavailable: "Nenhum bot responde a este comando nesta sala",
+		ErrorCommandFailed:  "Não foi possível processar o comando",
+		ErrorFetchFailed:    "Não foi possível carregar as mensagens perdidas",
+		ErrorInvalidQuote:   "A mensagem citada não está nesta sala",
+		ErrorMalformedFrame: "Seu cliente enviou uma mensagem que o servidor não conseguiu ler",
+		ErrorMessageFailed:  "Não foi possível enviar sua mensagem",
+		ErrorMessageQuota:   "Esta sala atingiu o limite diário de mensagens",
+		ErrorReadOnly:       "Convidados só podem ler esta sala; cadastre-se para escrever",
+		ErrorRoomReadOnly:   "Somente o dono e os moderadores podem escrever nesta sala",
+		ErrorUnknownFrame:   "A versão do protocolo do seu cliente não suporta este tipo de mensagem",
+
+		SystemUserJoined: "%s entrou na sala",
+		SystemUserLeft:   "%s saiu da sala",
+	},
+	zen: []string{
+		"O obstáculo é o caminho.",
+		"Solte ou seja arrastado.",
+		"Quanto mais silencioso você se torna, mais consegue ouvir.",
+		"A natureza não se apressa, e ainda assim tudo se realiza.",
+		"Quando você percebe que nada falta, o mundo inteiro lhe pertence.",
+		"A jornada de mil milhas começa com um único passo.",
+		"Seja como a água, que flui ao redor dos obstáculos.",
+		"No meio do caos também há oportunidade.",
+		"O sábio se adapta às circunstâncias, como a água toma a forma do jarro.",
+		"A tensão é quem você acha que deveria ser. O relaxamento é quem você é.",
+		"Esvazie sua mente, seja amorfo, sem forma, como a água.",
+		"A semente do sofrimento em você pode ser forte, mas não espere deixar de sofrer para se permitir ser feliz.",
+		"Caminhe como se estivesse beijando a Terra com os pés.",
+		"Inspirando, acalmo corpo e mente. Expirando, sorrio.",
+		"O momento presente está repleto de alegria e felicidade. Se você estiver atento, verá.",
+		"Onde quer que você esteja, esteja lá por inteiro.",
+		"Perceba profundamente que o momento presente é tudo o que você tem.",
+		"Aceite e depois aja. Seja o que for que o momento presente contenha, aceite como se o tivesse escolhido.",
+		"A principal causa da infelicidade nunca é a situação, mas o que você pensa sobre ela.",
+		"A vida é um equilíbrio entre segurar e soltar.",
+		"Às vezes você precisa sair, tomar um ar e se lembrar de quem você é e de onde quer estar.",
+		"O único Zen que você encontra no topo das montanhas é o Zen que leva até lá.",
+		"Antes da iluminação: cortar lenha, carregar água. Depois da iluminação: cortar lenha, carregar água.",
+		"Deixe as coisas seguirem naturalmente, do jeito que quiserem.",
+		"Não busque a verdade, apenas deixe de se apegar às suas opiniões.",
+		"Quando o aluno está pronto, o mestre aparece.",
+		"A caverna em que você teme entrar guarda o tesouro que procura.",
+		"O silêncio é a linguagem dos sábios.",
+		"A mente é tudo. Você se torna aquilo que pensa.",
+		"A paz vem de dentro. Não a procure fora.",
+		"Nenhum floco de neve cai no lugar errado.",
+		"Conhecimento é aprender algo novo todos os dias. Sabedoria é soltar algo todos os dias.",
+		"Na mente do principiante há muitas possibilidades; na do especialista, poucas.",
+		"Se você entende, as coisas são como são. Se você não entende, as coisas são como são.",
+		"A lua não luta. Não ataca ninguém. Não se preocupa. Não tenta esmagar os outros.",
+		"Sentado em silêncio, sem fazer nada, a primavera chega e a grama cresce sozinha.",
+		"A neve cai, cada floco em seu devido lugar.",
+		"Diante de uma mente serena, o universo inteiro se rende.",
+		"A água turva se clareia melhor quando deixada em paz.",
+		"A melhor época para plantar uma árvore foi há 20 anos. A segunda melhor é agora.",
+		"Uma única flecha se quebra facilmente, mas não dez em um feixe.",
+		"O bambu que se curva é mais forte que o carvalho que resiste.",
+		"Onde não há desejo, há quietude.",
+		"A chama que brilha com o dobro da intensidade dura a metade do tempo.",
+		"Seja mestre da mente em vez de ser dominado por ela.",
+		"Flua com o que acontecer e deixe sua mente livre.",
+		"O sábio sabe que não sabe. O tolo acha que sabe tudo.",
+		"A paz interior começa no momento em que você escolhe não permitir que outra pessoa ou acontecimento controle suas emoções.",
+		"Paciência não é saber esperar, mas manter uma boa atitude enquanto se trabalha duro.",
+		"A raiz do sofrimento é o apego.",
+	},
+}
@@ -0,0 +1,141 @@
+// Package i18n holds the translated bot responses, error strings and system
+// messages. Messages are fmt templates looked up by key in a per-locale
+// catalog; keys missing from a catalog fall back to English.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Supported locales
+const (
+	English    = "en"
+	Spanish    = "es"
+	Portuguese = "pt"
+
+	DefaultLocale = English
+)
+
+// Message keys
+const (
+	BotQuote          = "bot.quote"
+	BotQuoteChange    = "bot.quote_change"
+	BotQuoteFailed    = "bot.quote_failed"
+	BotStockNotFound  = "bot.stock_not_found"
+	BotStockSuggest   = "bot.stock_suggest"
+	BotUnknownCommand = "bot.unknown_command"
+	BotZenFailed      = "bot.zen_failed"
+	BotGIF            = "bot.gif"
+	BotGIFFailed      = "bot.gif_failed"
+	BotGIFNotFound    = "bot.gif_not_found"
+	BotGIFDisabled    = "bot.gif_disabled"
+
+	ErrorBotUnavailable = "error.bot_unavailable"
+	ErrorCommandFailed  = "error.command_failed"
+	ErrorFetchFailed    = "error.fetch_failed"
+	ErrorInvalidQuote   = "error.invalid_quote"
+	ErrorMalformedFrame = "error.malformed_frame"
+	ErrorMessageFailed  = "error.message_failed"
+	ErrorMessageQuota   = "error.message_quota"
+	ErrorReadOnly       = "error.read_only"
+	ErrorRoomReadOnly   = "error.room_read_only"
+	ErrorUnknownFrame   = "error.unknown_frame"
+
+	SystemUserJoined = "system.user_joined"
+	SystemUserLeft   = "system.user_left"
+)
+
+type catalog struct {
+	messages map[string]string
+	zen      []string
+}
+
+var catalogs = map[string]*catalog{
+	English:    &en,
+	Spanish:    &es,
+	Portuguese: &pt,
+}
+
+// Locales returns the supported locales
+func Locales() []string {
+	return []string{English, Spanish, Portuguese}
+}
+
+// IsSupported reports whether locale has a catalog
+func IsSupported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// T formats the message for key in locale. Unknown locales and keys missing
+// from the locale's catalog use English; unknown keys are returned as is.
+func T(locale, key string, args ...any) string {
+	format, ok := lookup(locale).messages[key]
+	if !ok {
+		format, ok = en.messages[key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// ZenPhrase returns the n-th zen phrase of locale, wrapping around
+func ZenPhrase(locale string, n int64) string {
+	phrases := lookup(locale).zen
+	if n < 0 {
+		n = -n
+	}
+	return phrases[n%int64(len(phrases))]
+}
+
+func lookup(locale string) *catalog {
+	if c, ok := catalogs[locale]; ok {
+		return c
+	}
+	return &en
+}
+
+// Match returns the first supported locale in an Accept-Language header,
+// ignoring quality values and region subtags ("pt-BR" matches "pt"), or
+// DefaultLocale if there is none.
+func Match(acceptLanguage string) string {
+	for part := range strings.SplitSeq(acceptLanguage, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		tag, _, _ = strings.Cut(strings.TrimSpace(tag), "-")
+		tag = strings.ToLower(tag)
+		if IsSupported(tag) {
+			return tag
+		}
+	}
+	return DefaultLocale
+}
+
+// Resolve returns the user's preferred locale if it is supported, otherwise
+// the best match for the Accept-Language header
+func Resolve(preferred, acceptLanguage string) string {
+	if IsSupported(preferred) {
+		return preferred
+	}
+	return Match(acceptLanguage)
+}
+
+type contextKey struct{}
+
+// WithLocale returns a context carrying locale
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, contextKey{}, locale)
+}
+
+// LocaleFromContext returns the locale stored in ctx, or DefaultLocale
+func LocaleFromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(contextKey{}).(string); ok && locale != "" {
+		return locale
+	}
+	return DefaultLocale
+}
@@ -0,0 +1,116 @@
+package i18n
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCatalogsCoverEnglishKeys(t *testing.T) {
+	for _, locale := range Locales() {
+		c := catalogs[locale]
+		for key, format := range en.messages {
+			translated, ok := c.messages[key]
+			if !ok {
+				t.Errorf("%s: missing key %q", locale, key)
+				continue
+			}
+			if verbs(translated) != verbs(format) {
+				t.Errorf("%s: %q has verbs %q, want %q", locale, key, verbs(translated), verbs(format))
+			}
+		}
+		if len(c.zen) == 0 {
+			t.Errorf("%s: no zen phrases", locale)
+		}
+	}
+}
+
+// verbs returns the formatting verbs of format in order
+func verbs(format string) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		j := i + 1
+		for j < len(format) && strings.IndexByte("+-#0123456789.", format[j]) >= 0 {
+			j++
+		}
+		if j < len(format) {
+			b.WriteString(format[i : j+1])
+		}
+		i = j
+	}
+	return b.String()
+}
+
+func TestT(t *testing.T) {
+	tests := []struct {
+		locale string
+		key    string
+		args   []any
+		want   string
+	}{
+		{English, BotQuote, []any{"AAPL.US", 93.42}, "AAPL.US quote is $93.42 per share"},
+		{Spanish, BotQuote, []any{"AAPL.US", 93.42}, "La cotización de AAPL.US es $93.42 por acción"},
+		{Portuguese, SystemUserJoined, []any{"ana"}, "ana entrou na sala"},
+		{"fr", ErrorCommandFailed, nil, "Failed to process command"},
+		{English, "no.such.key", nil, "no.such.key"},
+	}
+
+	for _, tt := range tests {
+		if got := T(tt.locale, tt.key, tt.args...); got != tt.want {
+			t.Errorf("T(%q, %q) = %q, want %q", tt.locale, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestZenPhrase(t *testing.T) {
+	if got := ZenPhrase(Spanish, 0); got != es.zen[0] {
+		t.Errorf("ZenPhrase(es, 0) = %q, want %q", got, es.zen[0])
+	}
+	if got := ZenPhrase(English, int64(len(en.zen))+1); got != en.zen[1] {
+		t.Errorf("ZenPhrase should wrap around, got %q", got)
+	}
+	if got := ZenPhrase("xx", -1); got != en.zen[1] {
+		t.Errorf("ZenPhrase(xx, -1) = %q, want %q", got, en.zen[1])
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", English},
+		{"es", Spanish},
+		{"pt-BR,pt;q=0.9,en;q=0.8", Portuguese},
+		{"fr-FR, ES;q=0.5", Spanish},
+		{"de, fr", English},
+	}
+
+	for _, tt := range tests {
+		if got := Match(tt.header); got != tt.want {
+			t.Errorf("Match(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	if got := Resolve(Portuguese, "es"); got != Portuguese {
+		t.Errorf("preference should win over Accept-Language, got %q", got)
+	}
+	if got := Resolve("", "es"); got != Spanish {
+		t.Errorf("expected Accept-Language fallback, got %q", got)
+	}
+}
+
+func TestLocaleContext(t *testing.T) {
+	if got := LocaleFromContext(context.Background()); got != DefaultLocale {
+		t.Errorf("LocaleFromContext() = %q, want default", got)
+	}
+	ctx := WithLocale(context.Background(), Spanish)
+	if got := LocaleFromContext(ctx); got != Spanish {
+		t.Errorf("LocaleFromContext() = %q, want %q", got, Spanish)
+	}
+}
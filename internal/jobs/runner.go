@@ -0,0 +1,235 @@
+// Package jobs runs the server's periodic background jobs, such as
+// cleanups and purges, on a schedule with timeouts and retries.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/observability"
+)
+
+// DefaultShutdownGrace is how long runs in progress may continue after the
+// runner is stopped before they are cancelled
+const DefaultShutdownGrace = 10 * time.Second
+
+// Job is a task run on a schedule. Runs of a job never overlap: a run that
+// lasts past the next scheduled time delays it.
+type Job struct {
+	// Name identifies the job in logs, metrics and schedule overrides
+	Name     string
+	Schedule Schedule
+	// RunAtStart runs the job once as soon as the runner starts
+	RunAtStart bool
+	// Timeout bounds each attempt; zero leaves attempts unbounded
+	Timeout time.Duration
+	Retry   RetryPolicy
+	// SingleInstance runs the job only on the instance the runner's leader
+	// reports as the leader, for work that must not be duplicated across
+	// replicas
+	SingleInstance bool
+	Run            func(ctx context.Context) error
+}
+
+// RetryPolicy retries failed runs after an exponential backoff, starting at
+// Backoff and capped at MaxBackoff. A job without one is attempted once per
+// scheduled run.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff << (attempt - 1)
+	if d <= 0 || (p.MaxBackoff > 0 && d > p.MaxBackoff) {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// Runner runs jobs on their schedules until it is stopped
+type Runner struct {
+	leader    domain.Leader
+	schedules map[string]Schedule
+	jobs      []Job
+	grace     time.Duration
+}
+
+// NewRunner returns a Runner running SingleInstance jobs only while leader
+// reports this instance as the leader, or always when leader is nil
+func NewRunner(leader domain.Leader) *Runner {
+	return &Runner{leader: leader, grace: DefaultShutdownGrace}
+}
+
+// SetSchedules replaces the schedules of the jobs named in schedules, e.g.
+// from ParseSchedules
+func (r *Runner) SetSchedules(schedules map[string]Schedule) {
+	r.schedules = schedules
+}
+
+// SetShutdownGrace sets how long runs in progress may continue once the
+// runner is stopped
+func (r *Runner) SetShutdownGrace(grace time.Duration) {
+	r.grace = grace
+}
+
+// Add registers a job. It must be called before Run.
+func (r *Runner) Add(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return errors.New("job needs a name and a run function")
+	}
+	if schedule, ok := r.schedules[job.Name]; ok {
+		job.Schedule = schedule
+	}
+	if job.Schedule == nil {
+		return fmt.Errorf("job %s has no schedule", job.Name)
+	}
+	for _, j := range r.jobs {
+		if j.Name == job.Name {
+			return fmt.Errorf("job %s is already registered", job.Name)
+		}
+	}
+	r.jobs = append(r.jobs, job)
+	return nil
+}
+
+// Run runs the jobs until ctx is cancelled. It then starts no more runs or
+// retries, lets those in progress finish for the shutdown grace period,
+// cancels them after it and returns once they have all returned.
+func (r *Runner) Run(ctx context.Context) {
+	for name := range r.schedules {
+		if !r.registered(name) {
+			slog.Warn("schedule set for unknown job", slog.String("job", name))
+		}
+	}
+
+	// Runs outlive ctx by the grace period
+	runCtx, cancelRuns := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelRuns()
+
+	var wg sync.WaitGroup
+	for _, job := range r.jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.loop(ctx, runCtx, job)
+		}()
+	}
+	slog.Info("background jobs started", slog.Int("jobs", len(r.jobs)))
+
+	<-ctx.Done()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(r.grace):
+		slog.Warn("cancelling background jobs still running at shutdown")
+		cancelRuns()
+		<-done
+	}
+	slog.Info("background jobs stopped")
+}
+
+func (r *Runner) registered(name string) bool {
+	for _, j := range r.jobs {
+		if j.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// loop runs job on its schedule until ctx is cancelled. Attempts use runCtx,
+// which is only cancelled once the shutdown grace period is over.
+func (r *Runner) loop(ctx, runCtx context.Context, job Job) {
+	if job.RunAtStart {
+		r.run(ctx, runCtx, job)
+	}
+	for {
+		next := job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			slog.Error("background job schedule never fires", slog.String("job", job.Name))
+			return
+		}
+		if !wait(ctx, time.Until(next)) {
+			return
+		}
+		r.run(ctx, runCtx, job)
+	}
+}
+
+// run attempts job until it succeeds, its retries are exhausted or ctx is
+// cancelled
+func (r *Runner) run(ctx, runCtx context.Context, job Job) {
+	if job.SingleInstance && r.leader != nil && !r.leader.IsLeader() {
+		observability.BackgroundJobRunsTotal.WithLabelValues(job.Name, "skipped").Inc()
+		return
+	}
+
+	start := time.Now()
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = attemptJob(runCtx, job)
+		if err == nil || attempt >= job.Retry.MaxAttempts || ctx.Err() != nil {
+			break
+		}
+		delay := job.Retry.delay(attempt)
+		slog.Warn("background job failed, retrying",
+			slog.String("job", job.Name),
+			slog.Int("attempt", attempt),
+			slog.Duration("retry_in", delay),
+			slog.String("error", err.Error()))
+		if !wait(ctx, delay) {
+			break
+		}
+	}
+	observability.BackgroundJobDuration.WithLabelValues(job.Name).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		observability.BackgroundJobRunsTotal.WithLabelValues(job.Name, "error").Inc()
+		slog.Error("background job failed",
+			slog.String("job", job.Name),
+			slog.String("error", err.Error()))
+		return
+	}
+	observability.BackgroundJobRunsTotal.WithLabelValues(job.Name, "ok").Inc()
+	observability.BackgroundJobLastSuccess.WithLabelValues(job.Name).SetToCurrentTime()
+}
+
+// attemptJob runs job once within its timeout, turning a panic into an
+// error
+func attemptJob(ctx context.Context, job Job) (err error) {
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+// wait sleeps for d and reports whether it did so without ctx being
+// cancelled
+func wait(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,176 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/testutil"
+)
+
+type fixedLeader bool
+
+func (l fixedLeader) IsLeader() bool { return bool(l) }
+
+// runFor runs r for d and waits for it to stop
+func runFor(r *Runner, d time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	r.Run(ctx)
+}
+
+func TestRunner_RunsOnSchedule(t *testing.T) {
+	var runs atomic.Int32
+	r := NewRunner(nil)
+	testutil.AssertNoError(t, r.Add(Job{
+		Name:       "tick",
+		Schedule:   Every(20 * time.Millisecond),
+		RunAtStart: true,
+		Run: func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		},
+	}))
+
+	runFor(r, 70*time.Millisecond)
+	if n := runs.Load(); n < 3 {
+		t.Errorf("expected at least 3 runs, got %d", n)
+	}
+}
+
+func TestRunner_RetriesFailedRuns(t *testing.T) {
+	var attempts atomic.Int32
+	r := NewRunner(nil)
+	testutil.AssertNoError(t, r.Add(Job{
+		Name:       "flaky",
+		Schedule:   Every(time.Hour),
+		RunAtStart: true,
+		Retry:      RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+		Run: func(ctx context.Context) error {
+			if attempts.Add(1) < 3 {
+				return errors.New("temporary failure")
+			}
+			return nil
+		},
+	}))
+
+	runFor(r, 50*time.Millisecond)
+	testutil.AssertEqual(t, attempts.Load(), int32(3))
+}
+
+func TestRunner_TimeoutAndPanic(t *testing.T) {
+	var timedOut, panicked atomic.Bool
+	r := NewRunner(nil)
+	testutil.AssertNoError(t, r.Add(Job{
+		Name:       "slow",
+		Schedule:   Every(time.Hour),
+		RunAtStart: true,
+		Timeout:    10 * time.Millisecond,
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			timedOut.Store(errors.Is(ctx.Err(), context.DeadlineExceeded))
+			return ctx.Err()
+		},
+	}))
+	testutil.AssertNoError(t, r.Add(Job{
+		Name:       "broken",
+		Schedule:   Every(time.Hour),
+		RunAtStart: true,
+		Run: func(ctx context.Context) error {
+			panicked.Store(true)
+			panic("boom")
+		},
+	}))
+
+	runFor(r, 50*time.Millisecond)
+	testutil.AssertTrue(t, timedOut.Load(), "attempt should time out")
+	testutil.AssertTrue(t, panicked.Load(), "panicking job should not stop the runner")
+}
+
+func TestRunner_SingleInstanceOnlyOnLeader(t *testing.T) {
+	for _, leader := range []bool{false, true} {
+		var ran atomic.Bool
+		r := NewRunner(fixedLeader(leader))
+		testutil.AssertNoError(t, r.Add(Job{
+			Name:           "cleanup",
+			Schedule:       Every(time.Hour),
+			RunAtStart:     true,
+			SingleInstance: true,
+			Run: func(ctx context.Context) error {
+				ran.Store(true)
+				return nil
+			},
+		}))
+
+		runFor(r, 20*time.Millisecond)
+		testutil.AssertEqual(t, ran.Load(), leader)
+	}
+}
+
+func TestRunner_GracefulShutdown(t *testing.T) {
+	started := make(chan struct{})
+	var finished, cancelled atomic.Bool
+	r := NewRunner(nil)
+	testutil.AssertNoError(t, r.Add(Job{
+		Name:       "finishes",
+		Schedule:   Every(time.Hour),
+		RunAtStart: true,
+		Run: func(ctx context.Context) error {
+			close(started)
+			select {
+			case <-time.After(30 * time.Millisecond):
+				finished.Store(true)
+			case <-ctx.Done():
+			}
+			return nil
+		},
+	}))
+	testutil.AssertNoError(t, r.Add(Job{
+		Name:       "outlives_grace",
+		Schedule:   Every(time.Hour),
+		RunAtStart: true,
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			cancelled.Store(true)
+			return ctx.Err()
+		},
+	}))
+	r.SetShutdownGrace(100 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+	<-started
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("runner did not stop")
+	}
+	testutil.AssertTrue(t, finished.Load(), "run in progress should finish within the grace period")
+	testutil.AssertTrue(t, cancelled.Load(), "run outliving the grace period should be cancelled")
+}
+
+func TestRunner_Add(t *testing.T) {
+	r := NewRunner(nil)
+	r.SetSchedules(map[string]Schedule{"overridden": Every(time.Minute)})
+	noop := func(ctx context.Context) error { return nil }
+
+	testutil.AssertError(t, r.Add(Job{Name: "unscheduled", Run: noop}))
+	testutil.AssertError(t, r.Add(Job{Schedule: Every(time.Minute), Run: noop}))
+	testutil.AssertNoError(t, r.Add(Job{Name: "overridden", Run: noop}))
+	testutil.AssertError(t, r.Add(Job{Name: "overridden", Schedule: Every(time.Hour), Run: noop}))
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	testutil.AssertEqual(t, p.delay(1), time.Second)
+	testutil.AssertEqual(t, p.delay(2), 2*time.Second)
+	testutil.AssertEqual(t, p.delay(4), 5*time.Second)
+}
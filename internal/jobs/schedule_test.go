@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/testutil"
+)
+
+func TestParseSchedule_Next(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2024, time.May, 15, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.May, 15, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.May, 15, 10, 30, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2024, time.May, 15, 11, 0, 0, 0, time.UTC)},
+		{"30 3 * * *", time.Date(2024, time.May, 16, 3, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2024, time.May, 15, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * 1,5", time.Date(2024, time.May, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.May, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// Day of month or day of week when both are restricted
+		{"0 0 20 * 6", time.Date(2024, time.May, 18, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, time.May, 16, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, time.May, 19, 0, 0, 0, 0, time.UTC)},
+		{"@every 90m", from.Add(90 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			testutil.AssertNoError(t, err)
+			testutil.AssertEqual(t, schedule.Next(from), tt.want)
+		})
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@every",
+		"@every -1h",
+		"@fortnightly",
+	} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) should fail", spec)
+		}
+	}
+}
+
+func TestParseSchedule_NeverMatches(t *testing.T) {
+	schedule, err := ParseSchedule("0 0 30 2 *")
+	testutil.AssertNoError(t, err)
+	testutil.AssertTrue(t, schedule.Next(time.Now()).IsZero(), "February 30th should never match")
+}
+
+func TestParseSchedules(t *testing.T) {
+	schedules, err := ParseSchedules(" session_cleanup=@every 30m; deleted_purge=0,30 4 * * * ;")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, len(schedules), 2)
+	from := time.Date(2024, time.May, 15, 4, 10, 0, 0, time.UTC)
+	testutil.AssertEqual(t, schedules["deleted_purge"].Next(from), time.Date(2024, time.May, 15, 4, 30, 0, 0, time.UTC))
+
+	_, err = ParseSchedules("session_cleanup")
+	testutil.AssertError(t, err)
+	_, err = ParseSchedules("session_cleanup=@sometimes")
+	testutil.AssertError(t, err)
+}
@@ -0,0 +1,212 @@
+// Package mail renders and delivers the emails the chat server sends:
+// address verification, password resets, chatroom invitations and unread
+// digests. MAIL_SENDER selects the delivery: "log" (development, nothing is
+// sent), "smtp" or "ses" (Amazon SES API).
+package mail
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log/slog"
+	"net/url"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"jobsity-chat/internal/config"
+	"jobsity-chat/internal/domain"
+)
+
+const (
+	SenderLog  = "log"
+	SenderSMTP = "smtp"
+	SenderSES  = "ses"
+)
+
+var ErrUnknownSender = errors.New("unknown mail sender")
+
+// Template names
+const (
+	TemplateVerification  = "verification"
+	TemplatePasswordReset = "password_reset"
+	TemplateInvite        = "invite"
+	TemplateDigest        = "digest"
+)
+
+// VerificationData fills the verification template
+type VerificationData struct {
+	Username  string
+	URL       string
+	ExpiresIn time.Duration
+}
+
+// PasswordResetData fills the password_reset template
+type PasswordResetData struct {
+	Username  string
+	URL       string
+	ExpiresIn time.Duration
+}
+
+// InviteData fills the invite template
+type InviteData struct {
+	InviterName  string
+	ChatroomName string
+	URL          string
+	ExpiresIn    time.Duration
+}
+
+// DigestData fills the digest template
+type DigestData struct {
+	Username    string
+	Rooms       []DigestRoom
+	TotalUnread int
+	URL         string
+}
+
+// DigestRoom is one chatroom of a digest
+type DigestRoom struct {
+	Name         string
+	UnreadCount  int
+	MentionCount int
+	LastMessage  string
+}
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var (
+	funcs = map[string]any{"duration": formatDuration}
+
+	textTemplates = map[string]*texttemplate.Template{}
+	htmlTemplates = map[string]*htmltemplate.Template{}
+)
+
+func init() {
+	for _, name := range []string{TemplateVerification, TemplatePasswordReset, TemplateInvite, TemplateDigest} {
+		textTemplates[name] = texttemplate.Must(texttemplate.New(name).Funcs(funcs).
+			ParseFS(templateFS, "templates/"+name+".txt.tmpl"))
+		htmlTemplates[name] = htmltemplate.Must(htmltemplate.New(name).Funcs(funcs).
+			ParseFS(templateFS, "templates/layout.html.tmpl", "templates/"+name+".html.tmpl"))
+	}
+}
+
+// Render builds the email to send to the given address from a template and
+// its data (VerificationData, PasswordResetData, InviteData or DigestData)
+func Render(to, name string, data any) (*domain.Email, error) {
+	text, ok := textTemplates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, body, html strings.Builder
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s subject: %w", name, err)
+	}
+	if err := text.ExecuteTemplate(&body, "text", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s text: %w", name, err)
+	}
+	if err := htmlTemplates[name].ExecuteTemplate(&html, "layout", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s html: %w", name, err)
+	}
+
+	return &domain.Email{
+		To:      to,
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(body.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// formatDuration spells out a link lifetime, e.g. "24 hours" or "30 minutes"
+func formatDuration(d time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	switch {
+	case d >= 48*time.Hour && d%(24*time.Hour) == 0:
+		return plural(int(d/(24*time.Hour)), "day")
+	case d >= time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	default:
+		return plural(max(int(d/time.Minute), 1), "minute")
+	}
+}
+
+// FromConfig returns the sender selected by MAIL_SENDER
+func FromConfig(cfg *config.Config) (domain.EmailSender, error) {
+	switch strings.ToLower(cfg.MailSender) {
+	case "", SenderLog:
+		return LogSender{}, nil
+	case SenderSMTP:
+		if cfg.MailSMTPHost == "" || cfg.MailFrom == "" {
+			return nil, fmt.Errorf("MAIL_SMTP_HOST and MAIL_FROM are required for the smtp mail sender")
+		}
+		return NewSMTPSender(SMTPConfig{
+			Host:     cfg.MailSMTPHost,
+			Port:     cfg.MailSMTPPort,
+			Username: cfg.MailSMTPUsername,
+			Password: cfg.MailSMTPPassword,
+			From:     cfg.MailFrom,
+		})
+	case SenderSES:
+		if cfg.MailSESRegion == "" || cfg.MailSESAccessKeyID == "" || cfg.MailSESSecretAccessKey == "" || cfg.MailFrom == "" {
+			return nil, fmt.Errorf("MAIL_SES_REGION, MAIL_SES_ACCESS_KEY_ID, MAIL_SES_SECRET_ACCESS_KEY and MAIL_FROM are required for the ses mail sender")
+		}
+		return NewSESSender(SESConfig{
+			Region:          cfg.MailSESRegion,
+			AccessKeyID:     cfg.MailSESAccessKeyID,
+			SecretAccessKey: cfg.MailSESSecretAccessKey,
+			SessionToken:    cfg.MailSESSessionToken,
+			From:            cfg.MailFrom,
+		})
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSender, cfg.MailSender)
+	}
+}
+
+// Mailer renders emails and sends them, building their links from the
+// server's public base URL
+type Mailer struct {
+	sender  domain.EmailSender
+	baseURL string
+}
+
+func NewMailer(sender domain.EmailSender, baseURL string) *Mailer {
+	return &Mailer{sender: sender, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// SendInvite emails a chatroom invite linking to the registration page,
+// which pre-fills the form from the invite token
+func (m *Mailer) SendInvite(ctx context.Context, to, inviterName, chatroomName, token string, expiresIn time.Duration) error {
+	return m.send(ctx, to, TemplateInvite, InviteData{
+		InviterName:  inviterName,
+		ChatroomName: chatroomName,
+		URL:          m.baseURL + "/register?invite=" + url.QueryEscape(token),
+		ExpiresIn:    expiresIn,
+	})
+}
+
+func (m *Mailer) send(ctx context.Context, to, name string, data any) error {
+	email, err := Render(to, name, data)
+	if err != nil {
+		return err
+	}
+	return m.sender.Send(ctx, email)
+}
+
+// LogSender logs emails instead of sending them, for development
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, email *domain.Email) error {
+	slog.InfoContext(ctx, "email not sent (log-only mail sender)",
+		slog.String("to", email.To),
+		slog.String("subject", email.Subject),
+		slog.String("text", email.Text))
+	return nil
+}
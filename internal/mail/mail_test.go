@@ -0,0 +1,325 @@
+package mail
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/config"
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     any
+		subject  string
+		contains []string
+	}{
+		{
+			name:     TemplateVerification,
+			data:     VerificationData{Username: "alice", URL: "https://chat.example.com/verify?token=abc", ExpiresIn: 24 * time.Hour},
+			subject:  "Confirm your email address",
+			contains: []string{"alice", "https://chat.example.com/verify?token=abc", "24 hours"},
+		},
+		{
+			name:     TemplatePasswordReset,
+			data:     PasswordResetData{Username: "alice", URL: "https://chat.example.com/reset?token=abc", ExpiresIn: 30 * time.Minute},
+			subject:  "Reset your password",
+			contains: []string{"https://chat.example.com/reset?token=abc", "30 minutes"},
+		},
+		{
+			name:     TemplateInvite,
+			data:     InviteData{InviterName: "bob", ChatroomName: "general", URL: "https://chat.example.com/invite/xyz", ExpiresIn: 7 * 24 * time.Hour},
+			subject:  "bob invited you to general",
+			contains: []string{"general", "https://chat.example.com/invite/xyz", "7 days"},
+		},
+		{
+			name: TemplateDigest,
+			data: DigestData{
+				Username:    "alice",
+				Rooms:       []DigestRoom{{Name: "general", UnreadCount: 3, MentionCount: 1, LastMessage: "see you"}},
+				TotalUnread: 3,
+				URL:         "https://chat.example.com",
+			},
+			subject:  "You have 3 unread messages",
+			contains: []string{"general", "see you"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, err := Render("alice@example.com", tt.name, tt.data)
+			testutil.AssertNoError(t, err)
+			testutil.AssertEqual(t, email.To, "alice@example.com")
+			testutil.AssertEqual(t, email.Subject, tt.subject)
+			for _, s := range tt.contains {
+				testutil.AssertContains(t, email.Text, s)
+				testutil.AssertContains(t, email.HTML, s)
+			}
+			testutil.AssertContains(t, email.HTML, "<html")
+		})
+	}
+}
+
+func TestRender_EscapesHTML(t *testing.T) {
+	email, err := Render("alice@example.com", TemplateInvite, InviteData{
+		InviterName:  "<script>alert(1)</script>",
+		ChatroomName: "general",
+		URL:          "javascript:alert(1)",
+		ExpiresIn:    time.Hour,
+	})
+	testutil.AssertNoError(t, err)
+	testutil.AssertNotContains(t, email.HTML, "<script>")
+	testutil.AssertNotContains(t, email.HTML, `href="javascript:`)
+}
+
+func TestRender_UnknownTemplate(t *testing.T) {
+	_, err := Render("alice@example.com", "welcome", nil)
+	testutil.AssertError(t, err)
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		expected string
+	}{
+		{10 * time.Second, "1 minute"},
+		{45 * time.Minute, "45 minutes"},
+		{time.Hour, "1 hour"},
+		{24 * time.Hour, "24 hours"},
+		{72 * time.Hour, "3 days"},
+		{50 * time.Hour, "50 hours"},
+	}
+
+	for _, tt := range tests {
+		testutil.AssertEqual(t, formatDuration(tt.d), tt.expected)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	sender, err := FromConfig(&config.Config{MailSender: "log"})
+	testutil.AssertNoError(t, err)
+	_, ok := sender.(LogSender)
+	testutil.AssertTrue(t, ok, "expected a LogSender")
+
+	_, err = FromConfig(&config.Config{MailSender: "smtp"})
+	testutil.AssertError(t, err)
+	sender, err = FromConfig(&config.Config{MailSender: "smtp", MailSMTPHost: "smtp.example.com", MailFrom: "Chattorumu <noreply@example.com>"})
+	testutil.AssertNoError(t, err)
+	_, ok = sender.(*SMTPSender)
+	testutil.AssertTrue(t, ok, "expected an SMTPSender")
+
+	_, err = FromConfig(&config.Config{MailSender: "ses", MailSESRegion: "us-east-1"})
+	testutil.AssertError(t, err)
+	sender, err = FromConfig(&config.Config{
+		MailSender:             "SES",
+		MailFrom:               "noreply@example.com",
+		MailSESRegion:          "us-east-1",
+		MailSESAccessKeyID:     "AKID",
+		MailSESSecretAccessKey: "secret",
+	})
+	testutil.AssertNoError(t, err)
+	_, ok = sender.(*SESSender)
+	testutil.AssertTrue(t, ok, "expected an SESSender")
+
+	_, err = FromConfig(&config.Config{MailSender: "sendmail"})
+	testutil.AssertErrorIs(t, err, ErrUnknownSender)
+}
+
+// fakeSMTPServer accepts a single session and sends the commands and
+// message data it received on the returned channel
+func fakeSMTPServer(t *testing.T) (int, <-chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	testutil.AssertNoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		var lines []string
+		defer func() { received <- lines }()
+
+		tp.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			lines = append(lines, line)
+			switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+			case "EHLO":
+				tp.PrintfLine("250-localhost")
+				tp.PrintfLine("250 AUTH PLAIN")
+			case "AUTH":
+				tp.PrintfLine("235 Authenticated")
+			case "DATA":
+				tp.PrintfLine("354 Go ahead")
+				data, _ := io.ReadAll(tp.DotReader())
+				lines = append(lines, string(data))
+				tp.PrintfLine("250 Queued")
+			case "QUIT":
+				tp.PrintfLine("221 Bye")
+				return
+			default:
+				tp.PrintfLine("250 OK")
+			}
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, received
+}
+
+func TestSMTPSender_Send(t *testing.T) {
+	port, received := fakeSMTPServer(t)
+	sender, err := NewSMTPSender(SMTPConfig{
+		Host:     "localhost",
+		Port:     port,
+		Username: "user",
+		Password: "pass",
+		From:     "Chattorumu <noreply@example.com>",
+	})
+	testutil.AssertNoError(t, err)
+
+	err = sender.Send(context.Background(), &domain.Email{
+		To:      "alice@example.com",
+		Subject: "Héllo",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	})
+	testutil.AssertNoError(t, err)
+
+	lines := <-received
+	session := strings.Join(lines, "\n")
+	testutil.AssertContains(t, session, "AUTH PLAIN")
+	testutil.AssertContains(t, session, "MAIL FROM:<noreply@example.com>")
+	testutil.AssertContains(t, session, "RCPT TO:<alice@example.com>")
+	testutil.AssertContains(t, session, "Subject: =?utf-8?q?H=C3=A9llo?=")
+	testutil.AssertContains(t, session, "multipart/alternative")
+	testutil.AssertContains(t, session, "plain body")
+	testutil.AssertContains(t, session, "<p>html body</p>")
+}
+
+func TestSMTPSender_InvalidAddresses(t *testing.T) {
+	_, err := NewSMTPSender(SMTPConfig{Host: "localhost", From: "not an address"})
+	testutil.AssertError(t, err)
+
+	sender, err := NewSMTPSender(SMTPConfig{Host: "localhost", From: "noreply@example.com"})
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, sender.cfg.Port, 587)
+	err = sender.Send(context.Background(), &domain.Email{To: "alice", Subject: "hi", Text: "hi"})
+	testutil.AssertError(t, err)
+}
+
+func TestSigningKey(t *testing.T) {
+	// Example from the AWS Signature Version 4 documentation
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	testutil.AssertEqual(t, hex.EncodeToString(key), "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d")
+}
+
+func TestSESSender_Send(t *testing.T) {
+	var (
+		gotAuth, gotDate, gotPath string
+		gotBody                   sesRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotDate = r.Header.Get("X-Amz-Date")
+		gotPath = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Write([]byte(`{"MessageId":"m-1"}`))
+	}))
+	defer server.Close()
+
+	sender, err := NewSESSender(SESConfig{Region: "eu-west-1", AccessKeyID: "AKID", SecretAccessKey: "secret", From: "noreply@example.com"})
+	testutil.AssertNoError(t, err)
+	sender.endpoint = server.URL
+	sender.now = func() time.Time { return time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC) }
+
+	err = sender.Send(context.Background(), &domain.Email{To: "alice@example.com", Subject: "Hi", Text: "plain", HTML: "<p>html</p>"})
+	testutil.AssertNoError(t, err)
+
+	testutil.AssertEqual(t, gotPath, sesPath)
+	testutil.AssertEqual(t, gotDate, "20260301T120000Z")
+	testutil.AssertTrue(t, strings.HasPrefix(gotAuth,
+		"AWS4-HMAC-SHA256 Credential=AKID/20260301/eu-west-1/ses/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature="), "unexpected Authorization header: "+gotAuth)
+	testutil.AssertEqual(t, gotBody.FromEmailAddress, "noreply@example.com")
+	testutil.AssertEqual(t, strings.Join(gotBody.Destination.ToAddresses, ","), "<alice@example.com>")
+	testutil.AssertEqual(t, gotBody.Content.Simple.Subject.Data, "Hi")
+	testutil.AssertEqual(t, gotBody.Content.Simple.Body.HTML.Data, "<p>html</p>")
+}
+
+func TestSESSender_SignsSessionToken(t *testing.T) {
+	var gotAuth, gotToken string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotToken = r.Header.Get("X-Amz-Security-Token")
+		w.Write([]byte(`{"MessageId":"m-1"}`))
+	}))
+	defer server.Close()
+
+	sender, err := NewSESSender(SESConfig{
+		Region: "eu-west-1", AccessKeyID: "ASIA", SecretAccessKey: "secret", SessionToken: "tok3n", From: "noreply@example.com",
+	})
+	testutil.AssertNoError(t, err)
+	sender.endpoint = server.URL
+	sender.now = func() time.Time { return time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC) }
+
+	err = sender.Send(context.Background(), &domain.Email{To: "alice@example.com", Subject: "Hi", Text: "plain"})
+	testutil.AssertNoError(t, err)
+
+	testutil.AssertEqual(t, gotToken, "tok3n")
+	testutil.AssertContains(t, gotAuth, "SignedHeaders=content-type;host;x-amz-date;x-amz-security-token, ")
+}
+
+func TestSESSender_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message":"Email address is not verified."}`))
+	}))
+	defer server.Close()
+
+	sender, err := NewSESSender(SESConfig{Region: "us-east-1", From: "noreply@example.com"})
+	testutil.AssertNoError(t, err)
+	sender.endpoint = server.URL
+
+	err = sender.Send(context.Background(), &domain.Email{To: "alice@example.com", Subject: "Hi", Text: "plain"})
+	testutil.AssertError(t, err)
+	testutil.AssertContains(t, err.Error(), "not verified")
+}
+
+type recordingSender struct {
+	sent []*domain.Email
+}
+
+func (s *recordingSender) Send(ctx context.Context, email *domain.Email) error {
+	s.sent = append(s.sent, email)
+	return nil
+}
+
+func TestMailer_SendInvite(t *testing.T) {
+	sender := &recordingSender{}
+	mailer := NewMailer(sender, "https://chat.example.com/")
+
+	err := mailer.SendInvite(context.Background(), "new@example.com", "alice", "general", "tok3n", 48*time.Hour)
+	testutil.AssertNoError(t, err)
+	testutil.AssertLen(t, sender.sent, 1)
+	testutil.AssertEqual(t, sender.sent[0].To, "new@example.com")
+	testutil.AssertEqual(t, sender.sent[0].Subject, "alice invited you to general")
+	testutil.AssertContains(t, sender.sent[0].Text, "https://chat.example.com/register?invite=tok3n")
+	testutil.AssertContains(t, sender.sent[0].Text, "2 days")
+}
@@ -0,0 +1,210 @@
+// Package markdown renders the Markdown subset chat messages may use to HTML
+// that is safe to insert into a page: bold, italics, inline code and links.
+// Everything else, HTML in the source included, is escaped, and links only
+// keep http, https and mailto URLs.
+package markdown
+
+import (
+	"net/url"
+	"strings"
+)
+
+// linkRel is set on every link, as messages come from other users
+const linkRel = "nofollow noopener noreferrer"
+
+// Render returns the HTML of the message s. Line breaks become <br>.
+func Render(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	var b strings.Builder
+	b.Grow(len(s) + len(s)/4)
+	render(&b, s, true)
+	return b.String()
+}
+
+// render writes the HTML of s to b; links is false inside link text, as links
+// don't nest
+func render(b *strings.Builder, s string, links bool) {
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) && isPunct(s[i+1]) {
+				writeEscaped(b, s[i+1:i+2])
+				i += 2
+				continue
+			}
+		case '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end > 0 {
+				b.WriteString("<code>")
+				writeEscaped(b, s[i+1:i+1+end])
+				b.WriteString("</code>")
+				i += end + 2
+				continue
+			}
+		case '*', '_':
+			if next, ok := emphasis(b, s, i, links); ok {
+				i = next
+				continue
+			}
+		case '[':
+			if links {
+				if next, ok := link(b, s, i); ok {
+					i = next
+					continue
+				}
+			}
+		case '\n':
+			b.WriteString("<br>")
+			i++
+			continue
+		}
+		writeEscaped(b, s[i:i+1])
+		i++
+	}
+}
+
+// emphasis writes the bold (doubled delimiter) or italic span opening at
+// s[i] and returns where it ends, or false if the delimiter is unmatched
+func emphasis(b *strings.Builder, s string, i int, links bool) (int, bool) {
+	c := s[i]
+	delim := s[i : i+1]
+	tag := "em"
+	if strings.HasPrefix(s[i:], strings.Repeat(delim, 2)) {
+		delim, tag = s[i:i+2], "strong"
+	}
+	// Underscores inside words, as in snake_case, are literal
+	if c == '_' && i > 0 && isWordByte(s[i-1]) {
+		return 0, false
+	}
+
+	start := i + len(delim)
+	if start >= len(s) || isSpace(s[start]) {
+		return 0, false
+	}
+	end := closing(s, start, delim)
+	if end < 0 {
+		return 0, false
+	}
+	after := end + len(delim)
+	if c == '_' && after < len(s) && isWordByte(s[after]) {
+		return 0, false
+	}
+
+	b.WriteString("<" + tag + ">")
+	render(b, s[start:end], links)
+	b.WriteString("</" + tag + ">")
+	return after, true
+}
+
+// closing returns the index of the delimiter closing a span whose content
+// starts at s[start], skipping code spans and escaped characters, or -1.
+// A single delimiter doesn't close on part of a doubled one.
+func closing(s string, start int, delim string) int {
+	for j := start + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+			continue
+		case '`':
+			if end := strings.IndexByte(s[j+1:], '`'); end > 0 {
+				j += end + 1
+				continue
+			}
+		}
+		if !strings.HasPrefix(s[j:], delim) || isSpace(s[j-1]) {
+			continue
+		}
+		if len(delim) == 1 {
+			if s[j-1] == delim[0] || (j+1 < len(s) && s[j+1] == delim[0]) {
+				continue
+			}
+		}
+		return j
+	}
+	return -1
+}
+
+// link writes the [text](url) link opening at s[i] and returns where it
+// ends, or false if it isn't one or its URL is not allowed
+func link(b *strings.Builder, s string, i int) (int, bool) {
+	textEnd := strings.IndexByte(s[i+1:], ']')
+	if textEnd <= 0 || strings.IndexByte(s[i+1:i+1+textEnd], '[') >= 0 {
+		return 0, false
+	}
+	textEnd += i + 1
+	if textEnd+1 >= len(s) || s[textEnd+1] != '(' {
+		return 0, false
+	}
+	urlEnd := strings.IndexByte(s[textEnd+2:], ')')
+	if urlEnd <= 0 {
+		return 0, false
+	}
+	urlEnd += textEnd + 2
+
+	href, ok := safeURL(s[textEnd+2 : urlEnd])
+	if !ok {
+		return 0, false
+	}
+	b.WriteString(`<a href="`)
+	writeEscaped(b, href)
+	b.WriteString(`" rel="` + linkRel + `">`)
+	render(b, s[i+1:textEnd], false)
+	b.WriteString("</a>")
+	return urlEnd + 1, true
+}
+
+// safeURL returns the normalized form of raw if it is an absolute http,
+// https or mailto URL
+func safeURL(raw string) (string, bool) {
+	if raw == "" || strings.ContainsAny(raw, " \t\n\"'<>") {
+		return "", false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return "", false
+		}
+	case "mailto":
+		if u.Opaque == "" {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	return u.String(), true
+}
+
+func writeEscaped(b *strings.Builder, s string) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '&':
+			b.WriteString("&amp;")
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		case '"':
+			b.WriteString("&#34;")
+		case '\'':
+			b.WriteString("&#39;")
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("\\`*_[]()#+-.!", c) >= 0
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
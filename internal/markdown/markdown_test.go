@@ -0,0 +1,45 @@
+package markdown
+
+import (
+	"testing"
+
+	"jobsity-chat/internal/testutil"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"plain", "hello world", "hello world"},
+		{"bold", "**hi** there", "<strong>hi</strong> there"},
+		{"bold underscores", "__hi__", "<strong>hi</strong>"},
+		{"italics", "an *important* _point_", "an <em>important</em> <em>point</em>"},
+		{"nested", "*a **b** c*", "<em>a <strong>b</strong> c</em>"},
+		{"code", "run `rm -rf <dir>`", "run <code>rm -rf &lt;dir&gt;</code>"},
+		{"no markup in code", "`**x**`", "<code>**x**</code>"},
+		{"delimiter in code", "*a `*` b*", "<em>a <code>*</code> b</em>"},
+		{"snake_case", "use snake_case_names", "use snake_case_names"},
+		{"unmatched", "2 * 3 = 6 and **open", "2 * 3 = 6 and **open"},
+		{"escaped", `\*not italic\*`, "*not italic*"},
+		{"html escaped", `<script>alert("x")</script>`, "&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;"},
+		{"line breaks", "one\r\ntwo\nthree", "one<br>two<br>three"},
+		{
+			"link",
+			"see [the **docs**](https://example.com/a?b=1&c=2)",
+			`see <a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener noreferrer">the <strong>docs</strong></a>`,
+		},
+		{"mailto", "[mail](mailto:a@example.com)", `<a href="mailto:a@example.com" rel="nofollow noopener noreferrer">mail</a>`},
+		{"javascript link", "[x](javascript:alert(1))", "[x](javascript:alert(1))"},
+		{"relative link", "[x](/admin)", "[x](/admin)"},
+		{"quote in link", `[x](https://e.com/"onclick=")`, "[x](https://e.com/&#34;onclick=&#34;)"},
+		{"no nested links", "[[a](https://a.io)](https://b.io)", `[<a href="https://a.io" rel="nofollow noopener noreferrer">a</a>](https://b.io)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.AssertEqual(t, Render(tt.input), tt.expected)
+		})
+	}
+}
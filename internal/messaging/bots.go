@@ -0,0 +1,210 @@
+package messaging
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"jobsity-chat/internal/config"
+)
+
+// CommandTypes are the chat commands bots can answer
+var CommandTypes = []string{"stock", "hello", "giphy"}
+
+// DefaultBotName is the bot answering the commands no other bot answers,
+// and the one responses without a bot name are attributed to
+const DefaultBotName = "StockBot"
+
+// Bot is a worker service answering chat commands. Its commands are
+// published to the commands exchange with its routing key, and the
+// responses naming it are posted as its system account.
+type Bot struct {
+	// Name is the username of the bot's system account
+	Name string
+	// AvatarURL is the avatar of its system account; empty keeps the
+	// current one
+	AvatarURL  string
+	RoutingKey string
+	// Commands are the command types the bot answers
+	Commands []string
+	// Rooms are the chatroom IDs the bot answers in; empty for all of them
+	Rooms []string
+	// UserID is the bot's system account, set once it is provisioned
+	UserID string
+}
+
+// EnabledIn reports whether the bot answers commands in chatroomID
+func (b *Bot) EnabledIn(chatroomID string) bool {
+	return len(b.Rooms) == 0 || slices.Contains(b.Rooms, chatroomID)
+}
+
+// BotRegistry holds the bots of the server, each answering its own command
+// types. It is read-only once built, apart from the bots' UserID which is
+// set before the registry is shared.
+type BotRegistry struct {
+	bots      []*Bot
+	byName    map[string]*Bot
+	byCommand map[string]*Bot
+}
+
+// NewBotRegistry returns a registry of fallback and bots. fallback answers
+// every command no other bot answers; a bot with its name overrides the
+// fields it sets instead of being added.
+func NewBotRegistry(fallback *Bot, bots ...*Bot) (*BotRegistry, error) {
+	def := *fallback
+	var others []*Bot
+	for _, bot := range bots {
+		if !strings.EqualFold(bot.Name, def.Name) {
+			others = append(others, bot)
+			continue
+		}
+		if bot.RoutingKey != "" {
+			def.RoutingKey = bot.RoutingKey
+		}
+		if len(bot.Commands) > 0 {
+			def.Commands = bot.Commands
+		}
+		if bot.AvatarURL != "" {
+			def.AvatarURL = bot.AvatarURL
+		}
+		def.Rooms = bot.Rooms
+	}
+
+	claimed := make(map[string]bool)
+	for _, bot := range others {
+		for _, command := range bot.Commands {
+			claimed[command] = true
+		}
+	}
+	def.Commands = slices.DeleteFunc(slices.Clone(def.Commands), func(command string) bool {
+		return claimed[command]
+	})
+
+	r := &BotRegistry{
+		byName:    make(map[string]*Bot),
+		byCommand: make(map[string]*Bot),
+	}
+	for _, bot := range append([]*Bot{&def}, others...) {
+		if bot.Name == "" {
+			return nil, fmt.Errorf("bot without a name")
+		}
+		if bot.RoutingKey == "" {
+			return nil, fmt.Errorf("bot %s has no routing key", bot.Name)
+		}
+		key := strings.ToLower(bot.Name)
+		if r.byName[key] != nil {
+			return nil, fmt.Errorf("bot %s is defined twice", bot.Name)
+		}
+		for _, command := range bot.Commands {
+			if !slices.Contains(CommandTypes, command) {
+				return nil, fmt.Errorf("bot %s: unknown command %q (want one of %s)",
+					bot.Name, command, strings.Join(CommandTypes, ", "))
+			}
+			if other := r.byCommand[command]; other != nil {
+				return nil, fmt.Errorf("command %q is answered by both %s and %s", command, other.Name, bot.Name)
+			}
+			r.byCommand[command] = bot
+		}
+		r.byName[key] = bot
+		r.bots = append(r.bots, bot)
+	}
+	return r, nil
+}
+
+// DefaultBot returns StockBot answering every command on routingKey
+func DefaultBot(routingKey string) *Bot {
+	return &Bot{
+		Name:       DefaultBotName,
+		RoutingKey: routingKey,
+		Commands:   slices.Clone(CommandTypes),
+	}
+}
+
+// BotsFromConfig builds the registry of StockBot, answering on the
+// configured command routing key, and the bots of cfg.Bots
+func BotsFromConfig(cfg *config.Config) (*BotRegistry, error) {
+	bots, err := ParseBots(cfg.Bots)
+	if err != nil {
+		return nil, err
+	}
+	return NewBotRegistry(DefaultBot(cfg.RabbitMQCommandRoutingKey), bots...)
+}
+
+// ParseBots parses bots separated by semicolons, each a list of key=value
+// fields separated by spaces: name, routing_key, commands and rooms (both
+// comma-separated) and avatar, e.g.
+// "name=GifBot routing_key=gif.request commands=giphy rooms=room-1,room-2".
+func ParseBots(spec string) ([]*Bot, error) {
+	var bots []*Bot
+	for entry := range strings.SplitSeq(spec, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		bot := &Bot{}
+		for _, field := range strings.Fields(entry) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid bot field %q: expected key=value", field)
+			}
+			switch key {
+			case "name":
+				bot.Name = value
+			case "routing_key":
+				bot.RoutingKey = value
+			case "commands":
+				bot.Commands = splitList(value)
+			case "rooms":
+				bot.Rooms = splitList(value)
+			case "avatar":
+				bot.AvatarURL = value
+			default:
+				return nil, fmt.Errorf("unknown bot field %q", key)
+			}
+		}
+		if bot.Name == "" {
+			return nil, fmt.Errorf("invalid bot %q: missing name", strings.TrimSpace(entry))
+		}
+		bots = append(bots, bot)
+	}
+	return bots, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Bots returns the bots, StockBot first
+func (r *BotRegistry) Bots() []*Bot {
+	return r.bots
+}
+
+// Names returns the names of the bots, StockBot first
+func (r *BotRegistry) Names() []string {
+	names := make([]string, len(r.bots))
+	for i, bot := range r.bots {
+		names[i] = bot.Name
+	}
+	return names
+}
+
+// Lookup returns the bot named name, in any case. The empty name is
+// StockBot's, as worker services predating the registry send no name.
+func (r *BotRegistry) Lookup(name string) (*Bot, bool) {
+	if name == "" {
+		return r.bots[0], true
+	}
+	bot, ok := r.byName[strings.ToLower(name)]
+	return bot, ok
+}
+
+// ForCommand returns the bot answering commandType
+func (r *BotRegistry) ForCommand(commandType string) (*Bot, bool) {
+	bot, ok := r.byCommand[commandType]
+	return bot, ok
+}
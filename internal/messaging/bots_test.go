@@ -0,0 +1,79 @@
+package messaging
+
+import (
+	"testing"
+
+	"jobsity-chat/internal/testutil"
+)
+
+func TestParseBots(t *testing.T) {
+	bots, err := ParseBots(" name=GifBot routing_key=gif.request commands=giphy rooms=room-1,room-2 avatar=https://example.com/gif.png ;; name=StockBot rooms=room-3")
+	testutil.AssertNoError(t, err)
+	testutil.AssertLen(t, bots, 2)
+	testutil.AssertEqual(t, bots[0].Name, "GifBot")
+	testutil.AssertEqual(t, bots[0].RoutingKey, "gif.request")
+	testutil.AssertEqual(t, bots[0].AvatarURL, "https://example.com/gif.png")
+	testutil.AssertLen(t, bots[0].Rooms, 2)
+	testutil.AssertLen(t, bots[1].Rooms, 1)
+
+	for _, spec := range []string{"routing_key=gif.request", "name=GifBot commands", "name=GifBot color=red"} {
+		if _, err := ParseBots(spec); err == nil {
+			t.Errorf("ParseBots(%q) expected error", spec)
+		}
+	}
+}
+
+func TestNewBotRegistry(t *testing.T) {
+	bots, err := NewBotRegistry(DefaultBot("stock.request"),
+		&Bot{Name: "GifBot", RoutingKey: "gif.request", Commands: []string{"giphy"}, Rooms: []string{"room-1"}},
+		&Bot{Name: "stockbot", Rooms: []string{"room-1", "room-2"}},
+	)
+	testutil.AssertNoError(t, err)
+	testutil.AssertLen(t, bots.Bots(), 2)
+
+	// giphy moved to GifBot; StockBot kept the rest and took the rooms of
+	// its entry
+	stock, ok := bots.ForCommand("stock")
+	testutil.AssertTrue(t, ok, "expected a bot for stock")
+	testutil.AssertEqual(t, stock.Name, "StockBot")
+	testutil.AssertEqual(t, stock.RoutingKey, "stock.request")
+	testutil.AssertFalse(t, stock.EnabledIn("room-3"), "StockBot should be limited to its rooms")
+	gif, _ := bots.ForCommand("giphy")
+	testutil.AssertEqual(t, gif.Name, "GifBot")
+
+	def, ok := bots.Lookup("")
+	testutil.AssertTrue(t, ok, "the empty name should be StockBot")
+	testutil.AssertEqual(t, def, stock)
+	if found, ok := bots.Lookup("GIFBOT"); !ok || found != gif {
+		t.Error("Lookup() should ignore case")
+	}
+	if _, ok := bots.Lookup("EvilBot"); ok {
+		t.Error("Lookup() of an unknown bot should fail")
+	}
+}
+
+func TestNewBotRegistry_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		bots []*Bot
+	}{
+		{"no routing key", []*Bot{{Name: "GifBot", Commands: []string{"giphy"}}}},
+		{"unknown command", []*Bot{{Name: "GifBot", RoutingKey: "gif.request", Commands: []string{"weather"}}}},
+		{"command answered twice", []*Bot{
+			{Name: "GifBot", RoutingKey: "gif.request", Commands: []string{"giphy"}},
+			{Name: "OtherGifBot", RoutingKey: "gif2.request", Commands: []string{"giphy"}},
+		}},
+		{"duplicate name", []*Bot{
+			{Name: "GifBot", RoutingKey: "gif.request"},
+			{Name: "gifbot", RoutingKey: "gif2.request"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewBotRegistry(DefaultBot("stock.request"), tt.bots...); err == nil {
+				t.Error("NewBotRegistry() expected error")
+			}
+		})
+	}
+}
@@ -3,9 +3,12 @@ package messaging
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"time"
 
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/observability"
 	"jobsity-chat/internal/service"
 	"jobsity-chat/internal/websocket"
 )
@@ -14,18 +17,28 @@ type ResponseConsumer struct {
 	rmq         *RabbitMQ
 	hub         *websocket.Hub
 	chatService *service.ChatService
-	botUserID   string
+	bots        *BotRegistry
+	botStats    domain.BotStatsRepository
+	done        chan struct{}
 }
 
-func NewResponseConsumer(rmq *RabbitMQ, hub *websocket.Hub, chatService *service.ChatService, botUserID string) *ResponseConsumer {
+// NewResponseConsumer returns a consumer posting each response as the bot of
+// bots it names. The bots' system accounts must be provisioned already.
+func NewResponseConsumer(rmq *RabbitMQ, hub *websocket.Hub, chatService *service.ChatService, bots *BotRegistry) *ResponseConsumer {
 	return &ResponseConsumer{
 		rmq:         rmq,
 		hub:         hub,
 		chatService: chatService,
-		botUserID:   botUserID,
+		bots:        bots,
+		done:        make(chan struct{}),
 	}
 }
 
+// SetBotStats enables recording the usage of answered bot commands
+func (c *ResponseConsumer) SetBotStats(botStats domain.BotStatsRepository) {
+	c.botStats = botStats
+}
+
 func (c *ResponseConsumer) Start(ctx context.Context) error {
 	queue, err := c.rmq.channel.QueueDeclare(
 		"",    // auto-generated name
@@ -40,9 +53,9 @@ func (c *ResponseConsumer) Start(ctx context.Context) error {
 	}
 
 	if err := c.rmq.channel.QueueBind(
-		queue.Name,       // queue name
-		"",               // routing key
-		"chat.responses", // exchange
+		queue.Name,                       // queue name
+		"",                               // routing key
+		c.rmq.topology.ResponsesExchange, // exchange
 		false,
 		nil,
 	); err != nil {
@@ -64,9 +77,10 @@ func (c *ResponseConsumer) Start(ctx context.Context) error {
 
 	slog.Info("started consuming stock responses",
 		slog.String("queue", queue.Name),
-		slog.String("exchange", "chat.responses"))
+		slog.String("exchange", c.rmq.topology.ResponsesExchange))
 
 	go func() {
+		defer close(c.done)
 		for {
 			select {
 			case <-ctx.Done():
@@ -89,11 +103,16 @@ func (c *ResponseConsumer) Start(ctx context.Context) error {
 					continue
 				}
 
-				slog.Info("processing stock response",
+				respCtx := ctx
+				if response.CorrelationID != "" {
+					respCtx = observability.WithCorrelationID(ctx, response.CorrelationID)
+				}
+
+				observability.FromContext(respCtx).Info("processing stock response",
 					slog.String("chatroom_id", response.ChatroomID),
 					slog.String("symbol", response.Symbol))
 
-				c.processResponse(ctx, &response)
+				c.processResponse(respCtx, &response)
 			}
 		}
 	}()
@@ -101,42 +120,149 @@ func (c *ResponseConsumer) Start(ctx context.Context) error {
 	return nil
 }
 
-func (c *ResponseConsumer) processResponse(_ context.Context, response *StockResponse) {
+// Done is closed once a started consumer has stopped, after its context is
+// cancelled or its channel closes, and has finished the response in progress
+func (c *ResponseConsumer) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *ResponseConsumer) processResponse(ctx context.Context, response *StockResponse) {
+	logger := observability.FromContext(ctx)
+
+	bot, ok := c.bots.Lookup(response.Bot)
+	if !ok {
+		logger.Warn("dropping response of unknown bot",
+			slog.String("bot", response.Bot),
+			slog.String("chatroom_id", response.ChatroomID))
+		return
+	}
+	// Announcements go to any chatroom; answers only where the bot is enabled
+	if response.CommandType != "" && !bot.EnabledIn(response.ChatroomID) {
+		logger.Warn("dropping response of bot not enabled in chatroom",
+			slog.String("bot", bot.Name),
+			slog.String("chatroom_id", response.ChatroomID))
+		return
+	}
+
+	c.recordUsage(ctx, response)
+
 	content := response.FormattedMessage
 	if response.Error != "" {
 		content = response.Error
 	}
 
-	// Bot messages are NOT saved to database - only broadcast via WebSocket
-	slog.Info("processing bot message (not saving to database)",
-		slog.String("chatroom_id", response.ChatroomID),
-		slog.String("symbol", response.Symbol))
+	username := bot.Name
+	if response.Sender != "" {
+		username = response.Sender
+	}
 
 	now := time.Now()
 	serverMsg := websocket.ServerMessage{
-		Type:      "chat_message",
-		ID:        "bot-" + response.ChatroomID + "-" + response.Symbol,
-		UserID:    c.botUserID,
-		Username:  "StockBot",
-		Content:   content,
-		IsBot:     true,
-		IsError:   response.Error != "",
-		CreatedAt: &now,
+		Type:       "chat_message",
+		ID:         "bot-" + response.ChatroomID + "-" + response.Symbol,
+		UserID:     bot.UserID,
+		Username:   username,
+		Content:    content,
+		IsBot:      true,
+		IsError:    response.Error != "",
+		CreatedAt:  &now,
+		Attachment: response.Attachment,
+
+		ParentMessageID: response.ParentMessageID,
+	}
+
+	// Answers are stored whether or not anyone is connected, so members who
+	// were offline see them in the history. Errors are only shown live.
+	if response.Error == "" {
+		if msg := c.persist(ctx, bot, response, content); msg != nil {
+			serverMsg.ID = msg.ID
+			serverMsg.CreatedAt = &msg.CreatedAt
+			serverMsg.HTML = msg.HTML
+		}
+	}
+
+	if c.hub.GetConnectedUserCount(response.ChatroomID) == 0 {
+		logger.Debug("no clients connected, skipping bot message broadcast",
+			slog.String("chatroom_id", response.ChatroomID),
+			slog.String("symbol", response.Symbol))
+		return
 	}
 
 	if data, err := json.Marshal(serverMsg); err == nil {
-		if err := c.hub.Broadcast(response.ChatroomID, data); err != nil {
-			slog.Warn("failed to broadcast bot message",
+		// Errors only concern whoever issued the command, and answers to a
+		// shadow-banned user's command only reach them, like the command
+		if response.Error != "" && response.RequestedByID != "" {
+			err = c.hub.SendToUser(response.ChatroomID, response.RequestedByID, data)
+		} else {
+			err = c.hub.BroadcastFrom(response.ChatroomID, response.RequestedByID, data)
+		}
+		switch {
+		case errors.Is(err, websocket.ErrUserNotConnected):
+			logger.Debug("requester disconnected, dropping bot error",
+				slog.String("chatroom_id", response.ChatroomID),
+				slog.String("symbol", response.Symbol))
+		case err != nil:
+			logger.Warn("failed to broadcast bot message",
 				slog.String("error", err.Error()),
 				slog.String("chatroom_id", response.ChatroomID),
 				slog.String("symbol", response.Symbol))
-		} else {
-			slog.Info("broadcast bot message to websocket",
+		default:
+			logger.Info("broadcast bot message to websocket",
 				slog.String("chatroom_id", response.ChatroomID),
 				slog.String("content", content))
 		}
 	} else {
-		slog.Error("error marshaling server message",
+		logger.Error("error marshaling server message",
 			slog.String("error", err.Error()))
 	}
 }
+
+// persist stores the message of bot answering response, as a threaded
+// reply to the command when it has one, returning nil when it could not be
+// stored. Failures are only logged since the response must still reach the
+// chatroom.
+func (c *ResponseConsumer) persist(ctx context.Context, bot *Bot, response *StockResponse, content string) *domain.Message {
+	msg := &domain.Message{
+		ChatroomID:      response.ChatroomID,
+		UserID:          bot.UserID,
+		Content:         content,
+		IsBot:           true,
+		ParentMessageID: response.ParentMessageID,
+	}
+
+	persistCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if err := c.chatService.SendMessage(persistCtx, msg); err != nil {
+		observability.FromContext(ctx).Warn("failed to save bot message",
+			slog.String("error", err.Error()),
+			slog.String("chatroom_id", response.ChatroomID))
+		return nil
+	}
+	return msg
+}
+
+// recordUsage stores the answered command for analytics. Failures are only
+// logged since the response must still reach the chatroom.
+func (c *ResponseConsumer) recordUsage(ctx context.Context, response *StockResponse) {
+	if c.botStats == nil || response.CommandType == "" {
+		return
+	}
+
+	recordCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	err := c.botStats.Record(recordCtx, &domain.BotCommandUsage{
+		CommandType: response.CommandType,
+		Symbol:      response.Symbol,
+		ChatroomID:  response.ChatroomID,
+		RequestedBy: response.RequestedBy,
+		Latency:     time.Duration(response.DurationMS) * time.Millisecond,
+		Success:     response.Error == "",
+	})
+	if err != nil {
+		observability.FromContext(ctx).Warn("failed to record bot command usage",
+			slog.String("error", err.Error()),
+			slog.String("type", response.CommandType))
+	}
+}
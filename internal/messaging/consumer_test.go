@@ -0,0 +1,161 @@
+package messaging
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/service"
+	"jobsity-chat/internal/testutil"
+	"jobsity-chat/internal/websocket"
+)
+
+func newTestChatService() (*service.ChatService, *testutil.MockMessageRepository) {
+	messageRepo := testutil.NewMockMessageRepository()
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["room-1"] = &domain.Chatroom{ID: "room-1", Name: "General"}
+	return service.NewChatService(messageRepo, chatroomRepo), messageRepo
+}
+
+// newTestBots returns StockBot, account bot-id, and GifBot, account gif-id,
+// answering giphy commands in room-1 only
+func newTestBots(t *testing.T) *BotRegistry {
+	t.Helper()
+	stockBot := DefaultBot("stock.request")
+	stockBot.UserID = "bot-id"
+	bots, err := NewBotRegistry(stockBot, &Bot{
+		Name:       "GifBot",
+		RoutingKey: "gif.request",
+		Commands:   []string{"giphy"},
+		Rooms:      []string{"room-1"},
+		UserID:     "gif-id",
+	})
+	testutil.AssertNoError(t, err)
+	return bots
+}
+
+func TestResponseConsumer_RecordsBotUsage(t *testing.T) {
+	botStats := testutil.NewMockBotStatsRepository()
+	chatService, _ := newTestChatService()
+	consumer := NewResponseConsumer(nil, websocket.NewHub(), chatService, newTestBots(t))
+	consumer.SetBotStats(botStats)
+
+	consumer.processResponse(context.Background(), &StockResponse{
+		ChatroomID:  "room-1",
+		Symbol:      "AAPL.US",
+		Error:       "Stock AAPL.US not found",
+		CommandType: "stock",
+		RequestedBy: "alice",
+		DurationMS:  42,
+	})
+	// Announcements are not bot commands
+	consumer.processResponse(context.Background(), &StockResponse{
+		ChatroomID:       "room-1",
+		FormattedMessage: "Maintenance at noon",
+		Sender:           "Operator",
+	})
+
+	testutil.AssertLen(t, botStats.Usage, 1)
+	usage := botStats.Usage[0]
+	testutil.AssertEqual(t, usage.CommandType, "stock")
+	testutil.AssertEqual(t, usage.Symbol, "AAPL.US")
+	testutil.AssertEqual(t, usage.RequestedBy, "alice")
+	testutil.AssertEqual(t, usage.Latency, 42*time.Millisecond)
+	testutil.AssertFalse(t, usage.Success, "expected a failed command")
+}
+
+func TestResponseConsumer_PersistsAnswersForOfflineRoom(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hub := websocket.NewHub()
+	go hub.Run(ctx)
+
+	chatService, messageRepo := newTestChatService()
+	consumer := NewResponseConsumer(nil, hub, chatService, newTestBots(t))
+
+	// Nobody is connected to room-1
+	testutil.AssertEqual(t, hub.GetConnectedUserCount("room-1"), 0)
+
+	consumer.processResponse(ctx, &StockResponse{
+		ChatroomID:       "room-1",
+		Symbol:           "AAPL.US",
+		FormattedMessage: "AAPL.US quote is $93.42 per share",
+		CommandType:      "stock",
+	})
+	// Errors are only shown to whoever is connected
+	consumer.processResponse(ctx, &StockResponse{
+		ChatroomID:  "room-1",
+		Symbol:      "XYZ.US",
+		Error:       "Stock XYZ.US not found",
+		CommandType: "stock",
+	})
+
+	messages, err := chatService.GetMessages(ctx, "room-1", 10)
+	testutil.AssertNoError(t, err)
+	testutil.AssertLen(t, messages, 1)
+	testutil.AssertEqual(t, messages[0].Content, "AAPL.US quote is $93.42 per share")
+	testutil.AssertEqual(t, messages[0].UserID, "bot-id")
+	testutil.AssertTrue(t, messages[0].IsBot, "expected a bot message")
+	testutil.AssertLen(t, messageRepo.Messages, 1)
+}
+
+func TestResponseConsumer_PersistFailureStillHandled(t *testing.T) {
+	chatService, messageRepo := newTestChatService()
+	consumer := NewResponseConsumer(nil, websocket.NewHub(), chatService, newTestBots(t))
+
+	// Too long to store: logged, not fatal
+	consumer.processResponse(context.Background(), &StockResponse{
+		ChatroomID:       "room-1",
+		FormattedMessage: strings.Repeat("x", 1001),
+	})
+
+	testutil.AssertLen(t, messageRepo.Messages, 0)
+}
+
+func TestResponseConsumer_AttributesResponsesToTheirBot(t *testing.T) {
+	ctx := context.Background()
+	chatService, messageRepo := newTestChatService()
+	consumer := NewResponseConsumer(nil, websocket.NewHub(), chatService, newTestBots(t))
+
+	consumer.processResponse(ctx, &StockResponse{
+		ChatroomID:       "room-1",
+		FormattedMessage: "a gif",
+		CommandType:      "giphy",
+		Bot:              "gifbot",
+	})
+	// Responses of unknown bots, or of bots outside their chatrooms, are
+	// dropped
+	consumer.processResponse(ctx, &StockResponse{
+		ChatroomID:       "room-1",
+		FormattedMessage: "spoofed",
+		CommandType:      "stock",
+		Bot:              "EvilBot",
+	})
+	consumer.processResponse(ctx, &StockResponse{
+		ChatroomID:       "room-2",
+		FormattedMessage: "elsewhere",
+		CommandType:      "giphy",
+		Bot:              "GifBot",
+	})
+
+	testutil.AssertLen(t, messageRepo.Messages, 1)
+	testutil.AssertEqual(t, messageRepo.Messages[0].UserID, "gif-id")
+	testutil.AssertEqual(t, messageRepo.Messages[0].Content, "a gif")
+}
+
+func TestResponseConsumer_ThreadsAnswersUnderTheCommand(t *testing.T) {
+	chatService, messageRepo := newTestChatService()
+	consumer := NewResponseConsumer(nil, websocket.NewHub(), chatService, newTestBots(t))
+
+	consumer.processResponse(context.Background(), &StockResponse{
+		ChatroomID:       "room-1",
+		FormattedMessage: "AAPL.US quote is $93.42 per share",
+		CommandType:      "stock",
+		ParentMessageID:  "msg-1",
+	})
+
+	testutil.AssertLen(t, messageRepo.Messages, 1)
+	testutil.AssertEqual(t, messageRepo.Messages[0].ParentMessageID, "msg-1")
+}
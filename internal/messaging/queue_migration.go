@@ -0,0 +1,172 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrQueueArgumentsChanged is returned by Setup when the commands queue
+// exists with other arguments than the topology declares, e.g. without the
+// x-max-priority of classic queues. RabbitMQ cannot change the arguments of
+// a queue; MigrateCommandsQueue recreates it.
+var ErrQueueArgumentsChanged = errors.New("commands queue was declared with other arguments; run 'chatctl migrate-commands-queue'")
+
+// isPreconditionFailed reports whether err is the broker refusing to
+// redeclare a queue with other arguments
+func isPreconditionFailed(err error) bool {
+	var amqpErr *amqp.Error
+	return errors.As(err, &amqpErr) && amqpErr.Code == amqp.PreconditionFailed
+}
+
+// migrationQueueName is the queue holding the commands while the commands
+// queue is recreated
+func (t Topology) migrationQueueName() string {
+	return t.CommandsQueue + ".migration"
+}
+
+// MigrateCommandsQueue recreates the commands queue with the arguments of
+// topology, keeping its messages. Commands published meanwhile wait in a
+// temporary queue bound in its place, so nothing is lost, but the bots must
+// be stopped: the queue is only deleted once nothing consumes it. A
+// migration that was interrupted is finished by running it again. It
+// returns how many messages were moved into the new queue.
+func MigrateCommandsQueue(ctx context.Context, url string, topology Topology) (int, error) {
+	if err := topology.Validate(); err != nil {
+		return 0, fmt.Errorf("invalid rabbitmq topology: %w", err)
+	}
+
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+	defer conn.Close()
+
+	// A passive declare of a missing queue closes its channel
+	existing, err := inspectQueue(conn, topology.CommandsQueue)
+	if err != nil {
+		return 0, err
+	}
+	if existing != nil && existing.Consumers > 0 {
+		return 0, fmt.Errorf("%d consumer(s) still read %s; stop the bots first", existing.Consumers, existing.Name)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer ch.Close()
+	if err := ch.Confirm(false); err != nil {
+		return 0, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
+	t := topology
+	temp := t.migrationQueueName()
+	if _, err := ch.QueueDeclare(temp, true, false, false, false, nil); err != nil {
+		return 0, fmt.Errorf("failed to declare %s: %w", temp, err)
+	}
+
+	if existing != nil {
+		if err := ch.QueueBind(temp, t.CommandRoutingKey, t.CommandsExchange, false, nil); err != nil {
+			return 0, fmt.Errorf("failed to bind %s: %w", temp, err)
+		}
+		if err := ch.QueueUnbind(t.CommandsQueue, t.CommandRoutingKey, t.CommandsExchange, nil); err != nil {
+			return 0, fmt.Errorf("failed to unbind %s: %w", t.CommandsQueue, err)
+		}
+		if _, err := moveMessages(ctx, ch, t.CommandsQueue, temp); err != nil {
+			return 0, err
+		}
+		if _, err := ch.QueueDelete(t.CommandsQueue, true, true, false); err != nil {
+			return 0, fmt.Errorf("failed to delete %s: %w", t.CommandsQueue, err)
+		}
+	}
+
+	if _, err := ch.QueueDeclare(t.CommandsQueue, t.Durable, false, false, false, t.commandsQueueArgs()); err != nil {
+		return 0, fmt.Errorf("failed to declare %s: %w", t.CommandsQueue, err)
+	}
+	if err := ch.QueueBind(t.CommandsQueue, t.CommandRoutingKey, t.CommandsExchange, false, nil); err != nil {
+		return 0, fmt.Errorf("failed to bind %s: %w", t.CommandsQueue, err)
+	}
+	if err := ch.QueueUnbind(temp, t.CommandRoutingKey, t.CommandsExchange, nil); err != nil {
+		return 0, fmt.Errorf("failed to unbind %s: %w", temp, err)
+	}
+
+	moved, err := moveMessages(ctx, ch, temp, t.CommandsQueue)
+	if err != nil {
+		return moved, err
+	}
+	if _, err := ch.QueueDelete(temp, false, true, false); err != nil {
+		return moved, fmt.Errorf("failed to delete %s: %w", temp, err)
+	}
+
+	slog.Info("commands queue recreated",
+		slog.String("queue", t.CommandsQueue),
+		slog.String("queue_type", t.QueueType),
+		slog.Int("messages", moved))
+	return moved, nil
+}
+
+// inspectQueue returns the state of queue, or nil if it does not exist
+func inspectQueue(conn *amqp.Connection, queue string) (*amqp.Queue, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer ch.Close()
+
+	q, err := ch.QueueDeclarePassive(queue, false, false, false, false, nil)
+	if err != nil {
+		var amqpErr *amqp.Error
+		if errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to inspect %s: %w", queue, err)
+	}
+	return &q, nil
+}
+
+// moveMessages moves every message of from to the queue to, through the
+// default exchange. A message is only removed from from once the broker
+// confirmed its copy. ch must be in confirm mode.
+func moveMessages(ctx context.Context, ch *amqp.Channel, from, to string) (int, error) {
+	moved := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return moved, err
+		}
+
+		msg, ok, err := ch.Get(from, false)
+		if err != nil {
+			return moved, fmt.Errorf("failed to get message from %s: %w", from, err)
+		}
+		if !ok {
+			return moved, nil
+		}
+
+		confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, "", to, true, false, amqp.Publishing{
+			ContentType:   msg.ContentType,
+			Headers:       msg.Headers,
+			Body:          msg.Body,
+			DeliveryMode:  amqp.Persistent,
+			Priority:      msg.Priority,
+			CorrelationId: msg.CorrelationId,
+		})
+		if err == nil {
+			var acked bool
+			if acked, err = confirm.WaitContext(ctx); err == nil && !acked {
+				err = ErrPublishNacked
+			}
+		}
+		if err != nil {
+			_ = msg.Nack(false, true)
+			return moved, fmt.Errorf("failed to move message to %s: %w", to, err)
+		}
+		if err := msg.Ack(false); err != nil {
+			return moved, fmt.Errorf("failed to ack moved message: %w", err)
+		}
+		moved++
+	}
+}
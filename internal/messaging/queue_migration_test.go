@@ -0,0 +1,34 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestIsPreconditionFailed(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"inequivalent args", &amqp.Error{Code: amqp.PreconditionFailed, Reason: "PRECONDITION_FAILED - inequivalent arg 'x-max-priority'"}, true},
+		{"wrapped", fmt.Errorf("declare: %w", &amqp.Error{Code: amqp.PreconditionFailed}), true},
+		{"not found", &amqp.Error{Code: amqp.NotFound}, false},
+		{"other error", errors.New("connection refused"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isPreconditionFailed(tt.err); got != tt.want {
+			t.Errorf("%s: isPreconditionFailed() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTopology_MigrationQueueName(t *testing.T) {
+	if got := DefaultTopology().migrationQueueName(); got != "stock.commands.migration" {
+		t.Errorf("migrationQueueName() = %q, want %q", got, "stock.commands.migration")
+	}
+}
@@ -3,14 +3,37 @@ package messaging
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
 	"time"
 
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/i18n"
+	"jobsity-chat/internal/observability"
+
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// ErrPublishNacked is returned when the broker refuses a message after retries
+var ErrPublishNacked = errors.New("message nacked by broker")
+
+// ErrUnroutable is returned when a mandatory message matched no queue
+var ErrUnroutable = errors.New("message unroutable")
+
+const (
+	publishMaxAttempts = 3
+	publishRetryDelay  = 100 * time.Millisecond
+)
+
+// publishChannel is a channel in confirm mode with its own listener for
+// mandatory messages the broker could not route.
+type publishChannel struct {
+	ch      *amqp.Channel
+	returns chan amqp.Return
+}
+
 type channelPool struct {
 	conn *amqp.Connection
 	pool *sync.Pool
@@ -23,42 +46,58 @@ func newChannelPool(conn *amqp.Connection) *channelPool {
 	}
 	cp.pool = &sync.Pool{
 		New: func() any {
-			ch, err := cp.newChannel()
+			pc, err := cp.newChannel()
 			if err != nil {
 				slog.Error("failed to create channel in pool", slog.String("error", err.Error()))
 				return nil
 			}
-			return ch
+			return pc
 		},
 	}
 	return cp
 }
 
-func (cp *channelPool) newChannel() (*amqp.Channel, error) {
+func (cp *channelPool) newChannel() (*publishChannel, error) {
 	ch, err := cp.conn.Channel()
 	if err != nil {
 		return nil, fmt.Errorf("failed to open channel: %w", err)
 	}
-	return ch, nil
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to enable confirm mode: %w", err)
+	}
+
+	return &publishChannel{
+		ch:      ch,
+		returns: ch.NotifyReturn(make(chan amqp.Return, 16)),
+	}, nil
 }
 
-func (cp *channelPool) getChannel() (*amqp.Channel, error) {
+func (cp *channelPool) getChannel() (*publishChannel, error) {
 	obj := cp.pool.Get()
 	if obj == nil {
 		return cp.newChannel()
 	}
 
-	ch := obj.(*amqp.Channel)
-	if ch.IsClosed() {
+	pc := obj.(*publishChannel)
+	if pc.ch.IsClosed() {
 		return cp.newChannel()
 	}
 
-	return ch, nil
+	return pc, nil
+}
+
+func (cp *channelPool) putChannel(pc *publishChannel) {
+	if pc != nil && !pc.ch.IsClosed() {
+		cp.pool.Put(pc)
+	}
 }
 
-func (cp *channelPool) putChannel(ch *amqp.Channel) {
-	if ch != nil && !ch.IsClosed() {
-		cp.pool.Put(ch)
+// discardChannel closes a channel whose confirm state is unknown, so a late
+// return for an abandoned message cannot be attributed to the next publish.
+func (cp *channelPool) discardChannel(pc *publishChannel) {
+	if pc != nil {
+		pc.ch.Close()
 	}
 }
 
@@ -66,15 +105,53 @@ type RabbitMQ struct {
 	conn        *amqp.Connection
 	channel     *amqp.Channel
 	publishPool *channelPool
+	topology    Topology
 	mu          sync.Mutex
+	// bots is nil until SetBots is called, and every command goes to the
+	// topology's routing key
+	bots *BotRegistry
+}
+
+// Priority orders bot commands on the commands queue. Higher values
+// are delivered first.
+type Priority uint8
+
+const (
+	// PriorityScheduled is for background work such as alerts and scheduled posts
+	PriorityScheduled Priority = 1
+	// PriorityDefault is used when no priority is given
+	PriorityDefault Priority = 5
+	// PriorityInteractive is for commands typed by a live user
+	PriorityInteractive Priority = 9
+
+	// maxPriority is the x-max-priority of a classic commands queue
+	maxPriority = 10
+)
+
+// PublishOptions controls how a bot command is delivered
+type PublishOptions struct {
+	Priority Priority
+	// Delay postpones delivery to the bot. Zero delivers immediately.
+	Delay time.Duration
 }
 
 type BotCommand struct {
-	Type        string `json:"type"`         // "stock" or "hello"
-	ChatroomID  string `json:"chatroom_id"`
-	StockCode   string `json:"stock_code,omitempty"`
+	Type       string `json:"type"` // "stock", "hello" or "giphy"
+	ChatroomID string `json:"chatroom_id"`
+	StockCode  string `json:"stock_code,omitempty"`
+	// Query is the search text of a giphy command
+	Query       string `json:"query,omitempty"`
 	RequestedBy string `json:"requested_by"`
-	Timestamp   int64  `json:"timestamp"`
+	// RequestedByID is the requester's user ID, used to send them the
+	// bot's errors privately
+	RequestedByID string `json:"requested_by_id,omitempty"`
+	// MessageID is the chat message the command was sent in, which the
+	// response replies to in a thread
+	MessageID     string `json:"message_id,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Locale is the requester's locale, used to translate the response
+	Locale    string `json:"locale,omitempty"`
+	Timestamp int64  `json:"timestamp"`
 }
 
 type StockCommand struct {
@@ -85,15 +162,43 @@ type StockCommand struct {
 }
 
 type StockResponse struct {
-	ChatroomID       string  `json:"chatroom_id"`
-	Symbol           string  `json:"symbol"`
-	Price            float64 `json:"price"`
-	FormattedMessage string  `json:"formatted_message"`
-	Error            string  `json:"error,omitempty"`
-	Timestamp        int64   `json:"timestamp"`
+	ChatroomID string  `json:"chatroom_id"`
+	Symbol     string  `json:"symbol"`
+	Price      float64 `json:"price"`
+	// Open, High, Low, Volume and ChangePercent describe the trading day of a
+	// stock quote; they are omitted when Stooq does not report them
+	Open          float64  `json:"open,omitempty"`
+	High          float64  `json:"high,omitempty"`
+	Low           float64  `json:"low,omitempty"`
+	Volume        int64    `json:"volume,omitempty"`
+	ChangePercent *float64 `json:"change_percent,omitempty"`
+	// Suggestions are close matches offered for a stock symbol that was not
+	// found
+	Suggestions      []string `json:"suggestions,omitempty"`
+	FormattedMessage string   `json:"formatted_message"`
+	Error            string   `json:"error,omitempty"`
+	CorrelationID    string   `json:"correlation_id,omitempty"`
+	// Sender overrides the display name of the bot message (e.g. operator announcements)
+	Sender string `json:"sender,omitempty"`
+	// CommandType, RequestedBy and DurationMS describe the command answered,
+	// for usage analytics. They are empty for announcements.
+	CommandType string `json:"command_type,omitempty"`
+	RequestedBy string `json:"requested_by,omitempty"`
+	DurationMS  int64  `json:"duration_ms,omitempty"`
+	// RequestedByID is the user errors are sent to instead of the whole
+	// chatroom, when known
+	RequestedByID string `json:"requested_by_id,omitempty"`
+	// Attachment is the image answering a giphy command
+	Attachment *domain.Attachment `json:"attachment,omitempty"`
+	// Bot names the bot answering, empty for StockBot
+	Bot string `json:"bot,omitempty"`
+	// ParentMessageID is the MessageID of the command answered; the
+	// response is posted as a threaded reply to it
+	ParentMessageID string `json:"parent_message_id,omitempty"`
+	Timestamp       int64  `json:"timestamp"`
 }
 
-func NewRabbitMQWithRetry(ctx context.Context, url string) (*RabbitMQ, error) {
+func NewRabbitMQWithRetry(ctx context.Context, url string, topology Topology) (*RabbitMQ, error) {
 	maxRetries := 5
 	baseDelay := time.Second
 
@@ -105,7 +210,7 @@ func NewRabbitMQWithRetry(ctx context.Context, url string) (*RabbitMQ, error) {
 		default:
 		}
 
-		rmq, err := NewRabbitMQ(url)
+		rmq, err := NewRabbitMQ(url, topology)
 		if err == nil {
 			slog.Info("connected to rabbitmq",
 				slog.Int("attempt", attempt+1))
@@ -113,6 +218,10 @@ func NewRabbitMQWithRetry(ctx context.Context, url string) (*RabbitMQ, error) {
 		}
 
 		lastErr = err
+		// Retrying cannot fix a queue that needs migrating
+		if errors.Is(err, ErrQueueArgumentsChanged) {
+			break
+		}
 		if attempt < maxRetries-1 {
 			delay := baseDelay * time.Duration(1<<uint(attempt))
 			slog.Warn("rabbitmq connection failed, retrying",
@@ -132,7 +241,11 @@ func NewRabbitMQWithRetry(ctx context.Context, url string) (*RabbitMQ, error) {
 	return nil, fmt.Errorf("failed to connect after %d attempts: %w", maxRetries, lastErr)
 }
 
-func NewRabbitMQ(url string) (*RabbitMQ, error) {
+func NewRabbitMQ(url string, topology Topology) (*RabbitMQ, error) {
+	if err := topology.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rabbitmq topology: %w", err)
+	}
+
 	conn, err := amqp.Dial(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
@@ -148,6 +261,7 @@ func NewRabbitMQ(url string) (*RabbitMQ, error) {
 		conn:        conn,
 		channel:     ch,
 		publishPool: newChannelPool(conn),
+		topology:    topology,
 	}
 
 	if err := rmq.Setup(); err != nil {
@@ -159,109 +273,216 @@ func NewRabbitMQ(url string) (*RabbitMQ, error) {
 }
 
 func (r *RabbitMQ) Setup() error {
+	t := r.topology
+
 	if err := r.channel.ExchangeDeclare(
-		"chat.commands", // name
-		"topic",         // type
-		true,            // durable
-		false,           // auto-deleted
-		false,           // internal
-		false,           // no-wait
-		nil,             // arguments
+		t.CommandsExchange, // name
+		"topic",            // type
+		t.Durable,          // durable
+		false,              // auto-deleted
+		false,              // internal
+		false,              // no-wait
+		nil,                // arguments
 	); err != nil {
 		return fmt.Errorf("failed to declare commands exchange: %w", err)
 	}
 
 	if err := r.channel.ExchangeDeclare(
-		"chat.responses", // name
-		"fanout",         // type
-		true,             // durable
-		false,            // auto-deleted
-		false,            // internal
-		false,            // no-wait
-		nil,              // arguments
+		t.ResponsesExchange, // name
+		"fanout",            // type
+		t.Durable,           // durable
+		false,               // auto-deleted
+		false,               // internal
+		false,               // no-wait
+		nil,                 // arguments
 	); err != nil {
 		return fmt.Errorf("failed to declare responses exchange: %w", err)
 	}
 
 	if _, err := r.channel.QueueDeclare(
-		"stock.commands", // name
-		true,             // durable
-		false,            // delete when unused
-		false,            // exclusive
-		false,            // no-wait
-		nil,              // arguments
+		t.CommandsQueue, // name
+		t.Durable,       // durable
+		false,           // delete when unused
+		false,           // exclusive
+		false,           // no-wait
+		t.commandsQueueArgs(),
 	); err != nil {
-		return fmt.Errorf("failed to declare stock.commands queue: %w", err)
+		if isPreconditionFailed(err) {
+			return fmt.Errorf("failed to declare %s queue: %w", t.CommandsQueue, ErrQueueArgumentsChanged)
+		}
+		return fmt.Errorf("failed to declare %s queue: %w", t.CommandsQueue, err)
 	}
 
 	if err := r.channel.QueueBind(
-		"stock.commands", // queue name
-		"stock.request",  // routing key
-		"chat.commands",  // exchange
+		t.CommandsQueue,     // queue name
+		t.CommandRoutingKey, // routing key
+		t.CommandsExchange,  // exchange
 		false,
 		nil,
 	); err != nil {
-		return fmt.Errorf("failed to bind stock.commands queue: %w", err)
+		return fmt.Errorf("failed to bind %s queue: %w", t.CommandsQueue, err)
 	}
 
-	slog.Info("rabbitmq setup completed successfully")
+	dlq := t.DeadLetterQueueName()
+	if _, err := r.channel.QueueDeclare(
+		dlq,       // name
+		t.Durable, // durable
+		false,     // delete when unused
+		false,     // exclusive
+		false,     // no-wait
+		t.deadLetterQueueArgs(),
+	); err != nil {
+		return fmt.Errorf("failed to declare %s queue: %w", dlq, err)
+	}
+
+	slog.Info("rabbitmq setup completed successfully",
+		slog.String("queue_type", t.QueueType),
+		slog.Bool("durable", t.Durable))
 	return nil
 }
 
+// SetBots routes each command to the routing key of the bot answering it.
+// Commands no bot answers in their chatroom fail with
+// domain.ErrBotUnavailable.
+func (r *RabbitMQ) SetBots(bots *BotRegistry) {
+	r.bots = bots
+}
+
 func (r *RabbitMQ) PublishCommand(ctx context.Context, cmd *BotCommand) error {
+	return r.PublishCommandWithOptions(ctx, cmd, PublishOptions{Priority: PriorityDefault})
+}
+
+// PublishCommandWithOptions publishes a bot command with a priority and an
+// optional delivery delay. Delayed commands wait in a per-delay TTL queue
+// that dead-letters them into the commands exchange once expired.
+func (r *RabbitMQ) PublishCommandWithOptions(ctx context.Context, cmd *BotCommand, opts PublishOptions) error {
+	routingKey := r.topology.CommandRoutingKey
+	if r.bots != nil {
+		bot, ok := r.bots.ForCommand(cmd.Type)
+		if !ok || !bot.EnabledIn(cmd.ChatroomID) {
+			return domain.ErrBotUnavailable
+		}
+		routingKey = bot.RoutingKey
+	}
+
 	body, err := json.Marshal(cmd)
 	if err != nil {
 		return fmt.Errorf("failed to marshal command: %w", err)
 	}
 
-	ch, err := r.publishPool.getChannel()
-	if err != nil {
-		return fmt.Errorf("failed to get channel from pool: %w", err)
+	priority := opts.Priority
+	if priority == 0 {
+		priority = PriorityDefault
+	}
+	msg := amqp.Publishing{
+		ContentType:   "application/json",
+		Body:          body,
+		DeliveryMode:  amqp.Persistent,
+		Priority:      uint8(min(priority, maxPriority)),
+		CorrelationId: cmd.CorrelationID,
 	}
-	defer r.publishPool.putChannel(ch)
 
-	err = ch.PublishWithContext(
-		ctx,
-		"chat.commands",
-		"stock.request",
-		false,
-		false,
-		amqp.Publishing{
-			ContentType:  "application/json",
-			Body:         body,
-			DeliveryMode: amqp.Persistent,
-		},
-	)
+	exchange, key := r.topology.CommandsExchange, routingKey
+	if opts.Delay > 0 {
+		queue, err := r.ensureDelayQueue(routingKey, max(opts.Delay, time.Millisecond))
+		if err != nil {
+			return fmt.Errorf("failed to prepare delayed delivery: %w", err)
+		}
+		// Publish straight to the delay queue via the default exchange
+		exchange, key = "", queue
+	}
 
-	if err != nil {
+	if err := r.publish(ctx, exchange, key, msg); err != nil {
 		return fmt.Errorf("failed to publish command: %w", err)
 	}
 
 	slog.Info("published bot command",
 		slog.String("type", cmd.Type),
-		slog.String("chatroom_id", cmd.ChatroomID))
+		slog.String("routing_key", routingKey),
+		slog.String("chatroom_id", cmd.ChatroomID),
+		slog.Int("priority", int(msg.Priority)),
+		slog.Duration("delay", opts.Delay),
+		slog.String("correlation_id", cmd.CorrelationID))
 	return nil
 }
 
-func (r *RabbitMQ) PublishStockCommand(ctx context.Context, chatroomID, stockCode, requestedBy string) error {
+// ensureDelayQueue declares the delay queue for routingKey and delay. It is
+// declared again before every delayed publish rather than remembered: the
+// queue expires once unused, and a redeclaration both recreates an expired
+// queue and restarts its expiry, so it outlives the messages published to it.
+func (r *RabbitMQ) ensureDelayQueue(routingKey string, delay time.Duration) (string, error) {
+	name := r.topology.delayQueueName(routingKey, delay)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.channel.QueueDeclare(
+		name,
+		r.topology.Durable,
+		false, // delete when unused
+		false, // exclusive
+		false, // no-wait
+		r.topology.delayQueueArgs(routingKey, delay),
+	); err != nil {
+		return "", fmt.Errorf("failed to declare delay queue %s: %w", name, err)
+	}
+	return name, nil
+}
+
+// PublishStockCommand asks the bot for the quote of stockCode, requested in
+// message messageID. The other Publish*Command methods are alike.
+func (r *RabbitMQ) PublishStockCommand(ctx context.Context, chatroomID, messageID, stockCode, requestedBy string) error {
+	cmd := &BotCommand{
+		Type:          "stock",
+		ChatroomID:    chatroomID,
+		MessageID:     messageID,
+		StockCode:     stockCode,
+		RequestedBy:   requestedBy,
+		RequestedByID: observability.UserID(ctx),
+		CorrelationID: commandCorrelationID(ctx),
+		Locale:        i18n.LocaleFromContext(ctx),
+		Timestamp:     time.Now().Unix(),
+	}
+	return r.PublishCommandWithOptions(ctx, cmd, PublishOptions{Priority: PriorityInteractive})
+}
+
+func (r *RabbitMQ) PublishHelloCommand(ctx context.Context, chatroomID, messageID, requestedBy string) error {
 	cmd := &BotCommand{
-		Type:        "stock",
-		ChatroomID:  chatroomID,
-		StockCode:   stockCode,
-		RequestedBy: requestedBy,
-		Timestamp:   time.Now().Unix(),
+		Type:          "hello",
+		ChatroomID:    chatroomID,
+		MessageID:     messageID,
+		RequestedBy:   requestedBy,
+		RequestedByID: observability.UserID(ctx),
+		CorrelationID: commandCorrelationID(ctx),
+		Locale:        i18n.LocaleFromContext(ctx),
+		Timestamp:     time.Now().Unix(),
 	}
-	return r.PublishCommand(ctx, cmd)
+	return r.PublishCommandWithOptions(ctx, cmd, PublishOptions{Priority: PriorityInteractive})
 }
 
-func (r *RabbitMQ) PublishHelloCommand(ctx context.Context, chatroomID, requestedBy string) error {
+// PublishGiphyCommand asks the bot for a GIF matching query
+func (r *RabbitMQ) PublishGiphyCommand(ctx context.Context, chatroomID, messageID, query, requestedBy string) error {
 	cmd := &BotCommand{
-		Type:        "hello",
-		ChatroomID:  chatroomID,
-		RequestedBy: requestedBy,
-		Timestamp:   time.Now().Unix(),
+		Type:          "giphy",
+		ChatroomID:    chatroomID,
+		MessageID:     messageID,
+		Query:         query,
+		RequestedBy:   requestedBy,
+		RequestedByID: observability.UserID(ctx),
+		CorrelationID: commandCorrelationID(ctx),
+		Locale:        i18n.LocaleFromContext(ctx),
+		Timestamp:     time.Now().Unix(),
 	}
-	return r.PublishCommand(ctx, cmd)
+	return r.PublishCommandWithOptions(ctx, cmd, PublishOptions{Priority: PriorityInteractive})
+}
+
+// commandCorrelationID returns the correlation ID carried by ctx, generating
+// a new one when the caller did not provide any.
+func commandCorrelationID(ctx context.Context) string {
+	if corrID := observability.CorrelationID(ctx); corrID != "" {
+		return corrID
+	}
+	return observability.NewCorrelationID()
 }
 
 func (r *RabbitMQ) PublishStockResponse(ctx context.Context, response *StockResponse) error {
@@ -270,39 +491,100 @@ func (r *RabbitMQ) PublishStockResponse(ctx context.Context, response *StockResp
 		return fmt.Errorf("failed to marshal response: %w", err)
 	}
 
-	ch, err := r.publishPool.getChannel()
+	err = r.publish(ctx, r.topology.ResponsesExchange, "", amqp.Publishing{
+		ContentType:   "application/json",
+		Body:          body,
+		DeliveryMode:  amqp.Persistent,
+		CorrelationId: response.CorrelationID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish response: %w", err)
+	}
+
+	slog.Info("published stock response",
+		slog.String("symbol", response.Symbol),
+		slog.Float64("price", response.Price),
+		slog.String("correlation_id", response.CorrelationID))
+	return nil
+}
+
+// publish sends a mandatory message and waits for the broker's confirm.
+// Nacked messages are retried with a short backoff; unroutable messages and
+// channel errors are returned immediately.
+func (r *RabbitMQ) publish(ctx context.Context, exchange, key string, msg amqp.Publishing) error {
+	for attempt := 1; ; attempt++ {
+		err := r.publishOnce(ctx, exchange, key, msg)
+		if err == nil || !errors.Is(err, ErrPublishNacked) || attempt == publishMaxAttempts {
+			return err
+		}
+
+		slog.Warn("message nacked by broker, retrying",
+			slog.String("exchange", exchange),
+			slog.Int("attempt", attempt),
+			slog.Int("max_attempts", publishMaxAttempts))
+
+		select {
+		case <-time.After(time.Duration(attempt) * publishRetryDelay):
+		case <-ctx.Done():
+			return fmt.Errorf("publish retry cancelled: %w", ctx.Err())
+		}
+	}
+}
+
+func (r *RabbitMQ) publishOnce(ctx context.Context, exchange, key string, msg amqp.Publishing) error {
+	pc, err := r.publishPool.getChannel()
 	if err != nil {
 		return fmt.Errorf("failed to get channel from pool: %w", err)
 	}
-	defer r.publishPool.putChannel(ch)
 
-	err = ch.PublishWithContext(
-		ctx,
-		"chat.responses",
-		"",
-		false,
-		false,
-		amqp.Publishing{
-			ContentType:  "application/json",
-			Body:         body,
-			DeliveryMode: amqp.Persistent,
-		},
-	)
+	// Drop returns left over from earlier publishes on this channel
+	for len(pc.returns) > 0 {
+		<-pc.returns
+	}
 
+	confirm, err := pc.ch.PublishWithDeferredConfirmWithContext(ctx, exchange, key, true, false, msg)
 	if err != nil {
-		return fmt.Errorf("failed to publish response: %w", err)
+		r.publishPool.discardChannel(pc)
+		return err
 	}
 
-	slog.Info("published stock response",
-		slog.String("symbol", response.Symbol),
-		slog.Float64("price", response.Price))
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		r.publishPool.discardChannel(pc)
+		return fmt.Errorf("failed waiting for publisher confirm: %w", err)
+	}
+	defer r.publishPool.putChannel(pc)
+
+	// The broker sends basic.return before the ack of an unroutable message,
+	// and both are dispatched in order, so the return is already buffered.
+	select {
+	case ret := <-pc.returns:
+		return fmt.Errorf("%w: exchange %q key %q: %d %s",
+			ErrUnroutable, ret.Exchange, ret.RoutingKey, ret.ReplyCode, ret.ReplyText)
+	default:
+	}
+
+	if !acked {
+		return ErrPublishNacked
+	}
 	return nil
 }
 
-func (r *RabbitMQ) ConsumeStockCommands() (<-chan amqp.Delivery, error) {
+// stockCommandsConsumerTag identifies the stock command consumer so it can
+// be cancelled during shutdown
+const stockCommandsConsumerTag = "stock-bot"
+
+// ConsumeStockCommands starts consuming stock commands. prefetch caps the
+// number of unacknowledged deliveries the broker pushes to this consumer
+// (zero means unlimited).
+func (r *RabbitMQ) ConsumeStockCommands(prefetch int) (<-chan amqp.Delivery, error) {
+	if err := r.channel.Qos(prefetch, 0, false); err != nil {
+		return nil, fmt.Errorf("failed to set qos: %w", err)
+	}
+
 	msgs, err := r.channel.Consume(
-		"stock.commands",
-		"",
+		r.topology.CommandsQueue,
+		stockCommandsConsumerTag,
 		false,
 		false,
 		false,
@@ -314,10 +596,85 @@ func (r *RabbitMQ) ConsumeStockCommands() (<-chan amqp.Delivery, error) {
 	}
 
 	slog.Info("started consuming stock commands",
-		slog.String("queue", "stock.commands"))
+		slog.String("queue", r.topology.CommandsQueue),
+		slog.Int("prefetch", prefetch))
 	return msgs, nil
 }
 
+// StopConsumingStockCommands asks the broker to stop delivering stock
+// commands and closes the delivery channel. Unacknowledged deliveries are
+// requeued by the broker when the channel closes.
+func (r *RabbitMQ) StopConsumingStockCommands() error {
+	if err := r.channel.Cancel(stockCommandsConsumerTag, false); err != nil {
+		return fmt.Errorf("failed to cancel consumer: %w", err)
+	}
+	return nil
+}
+
+// ReplayQueue moves up to limit messages (all when limit <= 0) from queue,
+// typically a dead-letter queue, back to the commands exchange with the
+// routing key they were dead-lettered with, so each command reaches its own
+// bot again. A message is only removed from queue once its republish has
+// been confirmed.
+func (r *RabbitMQ) ReplayQueue(ctx context.Context, queue string, limit int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	replayed := 0
+	for limit <= 0 || replayed < limit {
+		if err := ctx.Err(); err != nil {
+			return replayed, err
+		}
+
+		msg, ok, err := r.channel.Get(queue, false)
+		if err != nil {
+			return replayed, fmt.Errorf("failed to get message from %s: %w", queue, err)
+		}
+		if !ok {
+			break
+		}
+
+		err = r.publish(ctx, r.topology.CommandsExchange, deadLetteredRoutingKey(msg, r.topology.CommandRoutingKey), amqp.Publishing{
+			ContentType:   msg.ContentType,
+			Headers:       msg.Headers,
+			Body:          msg.Body,
+			DeliveryMode:  amqp.Persistent,
+			Priority:      msg.Priority,
+			CorrelationId: msg.CorrelationId,
+		})
+		if err != nil {
+			_ = msg.Nack(false, true)
+			return replayed, fmt.Errorf("failed to republish message: %w", err)
+		}
+		if err := msg.Ack(false); err != nil {
+			return replayed, fmt.Errorf("failed to ack replayed message: %w", err)
+		}
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+// deadLetteredRoutingKey returns the routing key msg had when it was last
+// dead-lettered, read from its x-death header, or the key it was delivered
+// with. fallback is used when neither is known.
+func deadLetteredRoutingKey(msg amqp.Delivery, fallback string) string {
+	// The latest death comes first
+	if deaths, ok := msg.Headers["x-death"].([]any); ok && len(deaths) > 0 {
+		if death, ok := deaths[0].(amqp.Table); ok {
+			if keys, ok := death["routing-keys"].([]any); ok && len(keys) > 0 {
+				if key, ok := keys[0].(string); ok && key != "" {
+					return key
+				}
+			}
+		}
+	}
+	if msg.RoutingKey != "" {
+		return msg.RoutingKey
+	}
+	return fallback
+}
+
 func (r *RabbitMQ) IsClosed() bool {
 	return r.conn == nil || r.conn.IsClosed()
 }
@@ -0,0 +1,87 @@
+package messaging
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestDelayQueueName(t *testing.T) {
+	tests := []struct {
+		delay time.Duration
+		want  string
+	}{
+		{time.Second, "stock.commands.delay.1000"},
+		{5 * time.Minute, "stock.commands.delay.300000"},
+		{1500 * time.Microsecond, "stock.commands.delay.1"},
+	}
+
+	for _, tt := range tests {
+		if got := DefaultTopology().delayQueueName("stock.request", tt.delay); got != tt.want {
+			t.Errorf("delayQueueName(%v) = %q, want %q", tt.delay, got, tt.want)
+		}
+	}
+
+	// Other bots' commands wait in their own queues
+	if got := DefaultTopology().delayQueueName("gif.request", time.Second); got != "gif.request.delay.1000" {
+		t.Errorf("delayQueueName(gif.request) = %q, want gif.request.delay.1000", got)
+	}
+}
+
+func TestDelayQueueArgs(t *testing.T) {
+	args := DefaultTopology().delayQueueArgs("stock.request", 30*time.Second)
+
+	if args["x-message-ttl"] != int64(30000) {
+		t.Errorf("x-message-ttl = %v, want 30000", args["x-message-ttl"])
+	}
+	if args["x-dead-letter-exchange"] != "chat.commands" {
+		t.Errorf("x-dead-letter-exchange = %v, want chat.commands", args["x-dead-letter-exchange"])
+	}
+	if args["x-dead-letter-routing-key"] != "stock.request" {
+		t.Errorf("x-dead-letter-routing-key = %v, want stock.request", args["x-dead-letter-routing-key"])
+	}
+	if args["x-expires"] != int64(90000) {
+		t.Errorf("x-expires = %v, want 90000", args["x-expires"])
+	}
+
+	if key := DefaultTopology().delayQueueArgs("gif.request", time.Second)["x-dead-letter-routing-key"]; key != "gif.request" {
+		t.Errorf("x-dead-letter-routing-key = %v, want gif.request", key)
+	}
+}
+
+func TestDeadLetteredRoutingKey(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  amqp.Delivery
+		want string
+	}{
+		{
+			name: "latest x-death entry",
+			msg: amqp.Delivery{
+				RoutingKey: "stock.commands.dlq",
+				Headers: amqp.Table{"x-death": []any{
+					amqp.Table{"queue": "gif.commands", "routing-keys": []any{"gif.request"}},
+					amqp.Table{"queue": "gif.request.delay.1000", "routing-keys": []any{"gif.request.delay.1000"}},
+				}},
+			},
+			want: "gif.request",
+		},
+		{
+			name: "delivery routing key",
+			msg:  amqp.Delivery{RoutingKey: "gif.request"},
+			want: "gif.request",
+		},
+		{
+			name: "fallback",
+			msg:  amqp.Delivery{Headers: amqp.Table{"x-death": []any{}}},
+			want: "stock.request",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := deadLetteredRoutingKey(tt.msg, "stock.request"); got != tt.want {
+			t.Errorf("%s: deadLetteredRoutingKey() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package messaging
+
+import (
+	"fmt"
+	"time"
+
+	"jobsity-chat/internal/config"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Queue types supported by the topology
+const (
+	QueueTypeClassic = "classic"
+	QueueTypeQuorum  = "quorum"
+)
+
+// Topology names the exchanges and queues used between the chat server and
+// the stock bot, and how the shared queues are declared.
+type Topology struct {
+	CommandsExchange  string
+	ResponsesExchange string
+	CommandsQueue     string
+	CommandRoutingKey string
+
+	// Durable controls durability of exchanges and the commands queue.
+	Durable bool
+	// QueueType is "classic" or "quorum" and applies to the commands queue
+	// and its delay queues. Per-instance response queues are always
+	// classic because quorum queues cannot be transient.
+	QueueType string
+}
+
+// DefaultTopology returns the names used before the topology was configurable
+func DefaultTopology() Topology {
+	return Topology{
+		CommandsExchange:  "chat.commands",
+		ResponsesExchange: "chat.responses",
+		CommandsQueue:     "stock.commands",
+		CommandRoutingKey: "stock.request",
+		Durable:           true,
+		QueueType:         QueueTypeClassic,
+	}
+}
+
+// TopologyFromConfig builds the topology from application configuration
+func TopologyFromConfig(cfg *config.Config) Topology {
+	return Topology{
+		CommandsExchange:  cfg.RabbitMQCommandsExchange,
+		ResponsesExchange: cfg.RabbitMQResponsesExchange,
+		CommandsQueue:     cfg.RabbitMQCommandsQueue,
+		CommandRoutingKey: cfg.RabbitMQCommandRoutingKey,
+		Durable:           cfg.RabbitMQDurable,
+		QueueType:         cfg.RabbitMQQueueType,
+	}
+}
+
+// Validate checks that the topology can be declared
+func (t Topology) Validate() error {
+	if t.CommandsExchange == "" || t.ResponsesExchange == "" || t.CommandsQueue == "" || t.CommandRoutingKey == "" {
+		return fmt.Errorf("exchange, queue and routing key names must not be empty")
+	}
+
+	switch t.QueueType {
+	case QueueTypeClassic:
+	case QueueTypeQuorum:
+		if !t.Durable {
+			return fmt.Errorf("quorum queues must be durable")
+		}
+	default:
+		return fmt.Errorf("unsupported queue type %q (want %q or %q)", t.QueueType, QueueTypeClassic, QueueTypeQuorum)
+	}
+
+	return nil
+}
+
+// commandsQueueArgs returns the arguments of the commands queue. Classic
+// queues need x-max-priority for priorities; quorum queues prioritize
+// natively and reject that argument.
+func (t Topology) commandsQueueArgs() amqp.Table {
+	if t.QueueType == QueueTypeQuorum {
+		return amqp.Table{"x-queue-type": QueueTypeQuorum}
+	}
+	return amqp.Table{"x-max-priority": maxPriority}
+}
+
+// DeadLetterQueueName returns the queue collecting dead-lettered bot
+// commands, which 'chatctl replay-dlq' drains. Setup declares it; commands
+// reach it through a RabbitMQ dead-letter policy on the commands queue.
+func (t Topology) DeadLetterQueueName() string {
+	return t.CommandsQueue + ".dlq"
+}
+
+// deadLetterQueueArgs returns the arguments of the dead-letter queue, which
+// has the commands queue's type but no priorities: it is only drained.
+func (t Topology) deadLetterQueueArgs() amqp.Table {
+	if t.QueueType == QueueTypeQuorum {
+		return amqp.Table{"x-queue-type": QueueTypeQuorum}
+	}
+	return nil
+}
+
+// delayQueueName returns the TTL queue used for a given routing key and
+// delay. One queue per delay keeps expiry FIFO: RabbitMQ only expires
+// messages at the queue head, so mixing delays in one queue would hold short
+// delays behind long ones. Commands of other bots than the one on the
+// topology's routing key wait in queues named after their own key.
+func (t Topology) delayQueueName(routingKey string, delay time.Duration) string {
+	prefix := t.CommandsQueue
+	if routingKey != t.CommandRoutingKey {
+		prefix = routingKey
+	}
+	return fmt.Sprintf("%s.delay.%d", prefix, delay.Milliseconds())
+}
+
+// delayQueueArgs dead-letters expired messages into the command exchange
+// with routingKey and removes the queue once it has been unused for a while.
+// Publishing does not count as use, so publishers must redeclare the queue
+// before each message; see RabbitMQ.ensureDelayQueue.
+func (t Topology) delayQueueArgs(routingKey string, delay time.Duration) amqp.Table {
+	ttl := delay.Milliseconds()
+	args := amqp.Table{
+		"x-message-ttl":             ttl,
+		"x-dead-letter-exchange":    t.CommandsExchange,
+		"x-dead-letter-routing-key": routingKey,
+		"x-expires":                 ttl + time.Minute.Milliseconds(),
+	}
+	if t.QueueType == QueueTypeQuorum {
+		args["x-queue-type"] = QueueTypeQuorum
+	}
+	return args
+}
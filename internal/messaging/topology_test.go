@@ -0,0 +1,68 @@
+package messaging
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTopology_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Topology)
+		wantErr bool
+	}{
+		{"default", func(*Topology) {}, false},
+		{"durable quorum", func(t *Topology) { t.QueueType = QueueTypeQuorum }, false},
+		{"transient quorum", func(t *Topology) { t.QueueType = QueueTypeQuorum; t.Durable = false }, true},
+		{"unknown queue type", func(t *Topology) { t.QueueType = "stream" }, true},
+		{"empty queue name", func(t *Topology) { t.CommandsQueue = "" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topology := DefaultTopology()
+			tt.modify(&topology)
+
+			err := topology.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTopology_QueueArgs(t *testing.T) {
+	classic := DefaultTopology()
+	if classic.commandsQueueArgs()["x-max-priority"] != maxPriority {
+		t.Error("Expected classic commands queue to declare x-max-priority")
+	}
+
+	quorum := DefaultTopology()
+	quorum.QueueType = QueueTypeQuorum
+	quorum.CommandsQueue = "bots.stock"
+
+	args := quorum.commandsQueueArgs()
+	if args["x-queue-type"] != QueueTypeQuorum {
+		t.Errorf("x-queue-type = %v, want quorum", args["x-queue-type"])
+	}
+	if _, ok := args["x-max-priority"]; ok {
+		t.Error("Quorum queues must not declare x-max-priority")
+	}
+
+	if got := quorum.delayQueueName(quorum.CommandRoutingKey, time.Second); got != "bots.stock.delay.1000" {
+		t.Errorf("delayQueueName() = %q, want bots.stock.delay.1000", got)
+	}
+	if quorum.delayQueueArgs(quorum.CommandRoutingKey, time.Second)["x-queue-type"] != QueueTypeQuorum {
+		t.Error("Expected delay queues to follow the configured queue type")
+	}
+
+	if got := quorum.DeadLetterQueueName(); got != "bots.stock.dlq" {
+		t.Errorf("DeadLetterQueueName() = %q, want bots.stock.dlq", got)
+	}
+	if quorum.deadLetterQueueArgs()["x-queue-type"] != QueueTypeQuorum {
+		t.Error("Expected the dead-letter queue to follow the configured queue type")
+	}
+	if classic.deadLetterQueueArgs() != nil {
+		t.Error("Expected a classic dead-letter queue without arguments")
+	}
+}
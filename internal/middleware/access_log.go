@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"jobsity-chat/internal/observability"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AccessLogConfig controls which requests the access logger emits.
+type AccessLogConfig struct {
+	// SampleRates maps exact request paths to the fraction of successful
+	// requests that are logged (0 disables, 1 logs everything).
+	// Paths not listed are always logged.
+	SampleRates map[string]float64
+
+	// SlowThreshold forces logging of requests slower than this value,
+	// regardless of sampling. Zero disables the override.
+	SlowThreshold time.Duration
+}
+
+type accessLogKey struct{}
+
+// accessLogState is shared through the request context so that inner
+// middleware (e.g. Auth) can enrich the access log entry written by the
+// outer AccessLog middleware.
+type accessLogState struct {
+	userID string
+}
+
+// AccessLog returns a middleware emitting one structured slog record per
+// request with latency, status, response size, request ID and, when the
+// request was authenticated, the user ID.
+// Errors (status >= 400) and slow requests are never sampled out.
+// Must be registered after chimiddleware.RequestID and Correlation.
+func AccessLog(cfg AccessLogConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			state := &accessLogState{}
+			ctx := context.WithValue(r.Context(), accessLogKey{}, state)
+
+			ww := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			duration := time.Since(start)
+			if !shouldLogRequest(cfg, r.URL.Path, ww.statusCode, duration) {
+				return
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", ww.statusCode),
+				slog.Int("bytes", ww.bytesWritten),
+				slog.Float64("latency_ms", float64(duration.Microseconds())/1000),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.String("user_agent", r.UserAgent()),
+			}
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				if pattern := rctx.RoutePattern(); pattern != "" {
+					attrs = append(attrs, slog.String("route", pattern))
+				}
+			}
+			if state.userID != "" {
+				attrs = append(attrs, slog.String("user_id", state.userID))
+			}
+
+			level := slog.LevelInfo
+			switch {
+			case ww.statusCode >= 500:
+				level = slog.LevelError
+			case ww.statusCode >= 400:
+				level = slog.LevelWarn
+			}
+
+			observability.FromContext(ctx).LogAttrs(ctx, level, "http request", attrs...)
+		})
+	}
+}
+
+// setAccessLogUserID records the authenticated user on the in-flight access
+// log entry, if any.
+func setAccessLogUserID(ctx context.Context, userID string) {
+	if state, ok := ctx.Value(accessLogKey{}).(*accessLogState); ok {
+		state.userID = userID
+	}
+}
+
+func shouldLogRequest(cfg AccessLogConfig, path string, status int, duration time.Duration) bool {
+	if status >= 400 {
+		return true
+	}
+	if cfg.SlowThreshold > 0 && duration >= cfg.SlowThreshold {
+		return true
+	}
+
+	rate, ok := cfg.SampleRates[path]
+	if !ok || rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	return rand.Float64() < rate
+}
+
+// ParseSampleRates parses a comma-separated list of path=rate pairs,
+// e.g. "/health=0,/metrics=0.01". Malformed entries are ignored.
+func ParseSampleRates(spec string) map[string]float64 {
+	rates := make(map[string]float64)
+	for entry := range strings.SplitSeq(spec, ",") {
+		path, rateStr, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || path == "" {
+			continue
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rateStr), 64)
+		if err != nil || rate < 0 || rate > 1 {
+			continue
+		}
+		rates[strings.TrimSpace(path)] = rate
+	}
+	return rates
+}
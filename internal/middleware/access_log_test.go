@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// captureLogs redirects the default slog logger to a buffer for the test duration
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var entries []map[string]any
+	for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestAccessLog_LogsRequestFields(t *testing.T) {
+	buf := captureLogs(t)
+
+	handler := chimiddleware.RequestID(AccessLog(AccessLogConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chatrooms", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	entries := decodeLogLines(t, buf)
+	testutil.AssertLen(t, entries, 1)
+
+	entry := entries[0]
+	testutil.AssertEqual(t, entry["msg"], any("http request"))
+	testutil.AssertEqual(t, entry["method"], any("POST"))
+	testutil.AssertEqual(t, entry["path"], any("/api/v1/chatrooms"))
+	testutil.AssertEqual(t, entry["status"], any(float64(http.StatusCreated)))
+	testutil.AssertEqual(t, entry["bytes"], any(float64(5)))
+	if _, ok := entry["latency_ms"]; !ok {
+		t.Error("expected latency_ms field")
+	}
+}
+
+func TestAccessLog_IncludesAuthenticatedUserID(t *testing.T) {
+	buf := captureLogs(t)
+
+	sessionRepo := testutil.NewMockSessionRepository()
+	sessionRepo.Sessions["tok"] = &domain.Session{
+		UserID:    "user-42",
+		Token:     "tok",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	inner := Auth(sessionRepo)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	handler := AccessLog(AccessLogConfig{})(inner)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "tok"})
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := decodeLogLines(t, buf)
+	testutil.AssertLen(t, entries, 1)
+	testutil.AssertEqual(t, entries[0]["user_id"], any("user-42"))
+}
+
+func TestAccessLog_Sampling(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		delay   time.Duration
+		wantLog bool
+	}{
+		{"success sampled out", http.StatusOK, 0, false},
+		{"errors always logged", http.StatusInternalServerError, 0, true},
+		{"slow requests always logged", http.StatusOK, 20 * time.Millisecond, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureLogs(t)
+
+			cfg := AccessLogConfig{
+				SampleRates:   map[string]float64{"/health": 0},
+				SlowThreshold: 10 * time.Millisecond,
+			}
+			handler := AccessLog(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(tt.delay)
+				w.WriteHeader(tt.status)
+			}))
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			got := len(decodeLogLines(t, buf)) > 0
+			if got != tt.wantLog {
+				t.Errorf("logged = %v, want %v", got, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestParseSampleRates(t *testing.T) {
+	rates := ParseSampleRates(" /health=0, /metrics=0.25,bad,/x=2,/y=abc,=1")
+
+	testutil.AssertEqual(t, len(rates), 2)
+	testutil.AssertEqual(t, rates["/health"], 0.0)
+	testutil.AssertEqual(t, rates["/metrics"], 0.25)
+}
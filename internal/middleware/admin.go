@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+
+	"jobsity-chat/internal/domain"
+)
+
+// DebugTokenHeader carries the shared debug token for DebugAccess
+const DebugTokenHeader = "X-Debug-Token"
+
+// RequireAdmin rejects requests from users without the admin role.
+// Must be registered after Auth.
+func RequireAdmin(userRepo domain.UserRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := GetUserID(r.Context())
+			if !ok {
+				http.Error(w, `{"error":"Not authenticated"}`, http.StatusUnauthorized)
+				return
+			}
+
+			user, err := userRepo.GetByID(r.Context(), userID)
+			if err != nil || !user.IsAdmin() {
+				slog.Warn("admin access denied",
+					slog.String("user_id", userID),
+					slog.String("path", r.URL.Path))
+				http.Error(w, `{"error":"Forbidden"}`, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireModerator rejects requests from users who are neither moderators nor
+// administrators. Must be registered after Auth.
+func RequireModerator(userRepo domain.UserRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := GetUserID(r.Context())
+			if !ok {
+				http.Error(w, `{"error":"Not authenticated"}`, http.StatusUnauthorized)
+				return
+			}
+
+			user, err := userRepo.GetByID(r.Context(), userID)
+			if err != nil || !user.IsModerator() {
+				slog.Warn("moderator access denied",
+					slog.String("user_id", userID),
+					slog.String("path", r.URL.Path))
+				http.Error(w, `{"error":"Forbidden"}`, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DebugAccess guards debug endpoints. Requests presenting the configured
+// debug token in the X-Debug-Token header are allowed; all others must be
+// authenticated as an administrator. An empty debugToken disables token access.
+func DebugAccess(debugToken string, sessionRepo domain.SessionRepository, userRepo domain.UserRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		adminOnly := Auth(sessionRepo)(RequireAdmin(userRepo)(next))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if debugToken != "" {
+				provided := r.Header.Get(DebugTokenHeader)
+				if provided != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(debugToken)) == 1 {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			adminOnly.ServeHTTP(w, r)
+		})
+	}
+}
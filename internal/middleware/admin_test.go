@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func newAdminTestRepos() (*testutil.MockSessionRepository, *testutil.MockUserRepository) {
+	sessionRepo := testutil.NewMockSessionRepository()
+	userRepo := testutil.NewMockUserRepository()
+
+	userRepo.Users["admin-1"] = &domain.User{ID: "admin-1", Username: "root", Role: domain.RoleAdmin}
+	userRepo.Users["user-1"] = &domain.User{ID: "user-1", Username: "alice", Role: domain.RoleUser}
+	userRepo.Users["mod-1"] = &domain.User{ID: "mod-1", Username: "bob", Role: domain.RoleModerator}
+
+	expires := time.Now().Add(time.Hour)
+	sessionRepo.Sessions["admin-token"] = &domain.Session{UserID: "admin-1", Token: "admin-token", ExpiresAt: expires}
+	sessionRepo.Sessions["user-token"] = &domain.Session{UserID: "user-1", Token: "user-token", ExpiresAt: expires}
+	sessionRepo.Sessions["mod-token"] = &domain.Session{UserID: "mod-1", Token: "mod-token", ExpiresAt: expires}
+
+	return sessionRepo, userRepo
+}
+
+func TestRequireAdmin(t *testing.T) {
+	sessionRepo, userRepo := newAdminTestRepos()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := Auth(sessionRepo)(RequireAdmin(userRepo)(ok))
+
+	tests := []struct {
+		name       string
+		cookie     string
+		wantStatus int
+	}{
+		{"admin allowed", "admin-token", http.StatusOK},
+		{"regular user forbidden", "user-token", http.StatusForbidden},
+		{"anonymous unauthorized", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "session_id", Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			testutil.AssertStatusCode(t, w, tt.wantStatus)
+		})
+	}
+}
+
+func TestRequireModerator(t *testing.T) {
+	sessionRepo, userRepo := newAdminTestRepos()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := Auth(sessionRepo)(RequireModerator(userRepo)(ok))
+
+	tests := []struct {
+		name       string
+		cookie     string
+		wantStatus int
+	}{
+		{"moderator allowed", "mod-token", http.StatusOK},
+		{"admin allowed", "admin-token", http.StatusOK},
+		{"regular user forbidden", "user-token", http.StatusForbidden},
+		{"anonymous unauthorized", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/chatrooms/room-1/shadow-bans/user-1", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "session_id", Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			testutil.AssertStatusCode(t, w, tt.wantStatus)
+		})
+	}
+}
+
+func TestDebugAccess(t *testing.T) {
+	sessionRepo, userRepo := newAdminTestRepos()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		debugToken string
+		header     string
+		cookie     string
+		wantStatus int
+	}{
+		{"valid token", "s3cret", "s3cret", "", http.StatusOK},
+		{"wrong token falls back to session", "s3cret", "nope", "", http.StatusUnauthorized},
+		{"token disabled ignores header", "", "", "", http.StatusUnauthorized},
+		{"admin session without token", "s3cret", "", "admin-token", http.StatusOK},
+		{"non-admin session", "s3cret", "", "user-token", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := DebugAccess(tt.debugToken, sessionRepo, userRepo)(ok)
+
+			req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+			if tt.header != "" {
+				req.Header.Set(DebugTokenHeader, tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "session_id", Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			testutil.AssertStatusCode(t, w, tt.wantStatus)
+		})
+	}
+}
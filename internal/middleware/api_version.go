@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"jobsity-chat/internal/observability"
+)
+
+// APIVersionHeader reports the API version that served a response
+const APIVersionHeader = "API-Version"
+
+const (
+	APIv1 = 1
+	APIv2 = 2
+)
+
+// APIVersionKey stores the API version of the request in the context
+const APIVersionKey contextKey = "api_version"
+
+// maxErrorBody caps how much of an error response is buffered for rewriting
+const maxErrorBody = 64 << 10
+
+// APIVersion records the API version a route group serves, so shared
+// handlers can pick the payload shape of their version with GetAPIVersion
+func APIVersion(version int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(APIVersionHeader, strconv.Itoa(version))
+			ctx := context.WithValue(r.Context(), APIVersionKey, version)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetAPIVersion returns the API version of the request, APIv1 when unset
+func GetAPIVersion(ctx context.Context) int {
+	if version, ok := ctx.Value(APIVersionKey).(int); ok {
+		return version
+	}
+	return APIv1
+}
+
+// ErrorDetail is the body of the v2 error envelope
+type ErrorDetail struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Status    int    `json:"status"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// ErrorEnvelope is the error response of /api/v2
+type ErrorEnvelope struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorEnvelopes adapts the v1 error responses of the handlers and
+// middleware below it, {"error":"message"} or plain text, into the v2
+// ErrorEnvelope. Successful responses pass through untouched.
+func ErrorEnvelopes() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ew := &envelopeWriter{ResponseWriter: w}
+			next.ServeHTTP(ew, r)
+			if ew.status >= http.StatusBadRequest {
+				ew.writeEnvelope(observability.RequestID(r.Context()))
+			}
+		})
+	}
+}
+
+type envelopeWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (ew *envelopeWriter) WriteHeader(statusCode int) {
+	if ew.status != 0 {
+		return
+	}
+	ew.status = statusCode
+	if statusCode < http.StatusBadRequest {
+		ew.ResponseWriter.WriteHeader(statusCode)
+	}
+}
+
+func (ew *envelopeWriter) Write(b []byte) (int, error) {
+	if ew.status == 0 {
+		ew.WriteHeader(http.StatusOK)
+	}
+	if ew.status < http.StatusBadRequest {
+		return ew.ResponseWriter.Write(b)
+	}
+	if room := maxErrorBody - ew.body.Len(); room > 0 {
+		ew.body.Write(b[:min(len(b), room)])
+	}
+	return len(b), nil
+}
+
+// Flush passes through for successful streaming responses
+func (ew *envelopeWriter) Flush() {
+	if ew.status < http.StatusBadRequest {
+		if f, ok := ew.ResponseWriter.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+}
+
+func (ew *envelopeWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
+
+func (ew *envelopeWriter) writeEnvelope(requestID string) {
+	envelope := ErrorEnvelope{Error: ErrorDetail{
+		Code:      errorCode(ew.status),
+		Message:   errorMessage(ew.body.Bytes(), ew.status),
+		Status:    ew.status,
+		RequestID: requestID,
+	}}
+
+	header := ew.ResponseWriter.Header()
+	header.Del("Content-Length")
+	header.Set("Content-Type", "application/json")
+	header.Set("X-Content-Type-Options", "nosniff")
+	ew.ResponseWriter.WriteHeader(ew.status)
+	json.NewEncoder(ew.ResponseWriter).Encode(envelope)
+}
+
+// errorMessage extracts the message of a v1 error body
+func errorMessage(body []byte, status int) string {
+	var v1 struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &v1); err == nil && v1.Error != "" {
+		return v1.Error
+	}
+	if text := strings.TrimSpace(string(body)); text != "" && !strings.HasPrefix(text, "{") {
+		return text
+	}
+	return http.StatusText(status)
+}
+
+// errorCode maps a status to the machine-readable code of the envelope
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return "INVALID_REQUEST"
+	case http.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		return "FORBIDDEN"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusMethodNotAllowed:
+		return "METHOD_NOT_ALLOWED"
+	case http.StatusConflict:
+		return "CONFLICT"
+	case http.StatusPreconditionFailed:
+		return "PRECONDITION_FAILED"
+	case http.StatusRequestEntityTooLarge:
+		return "PAYLOAD_TOO_LARGE"
+	case http.StatusTooManyRequests:
+		return "RATE_LIMITED"
+	case http.StatusServiceUnavailable:
+		return "UNAVAILABLE"
+	}
+	if status >= http.StatusInternalServerError {
+		return "INTERNAL"
+	}
+	return "REQUEST_FAILED"
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"jobsity-chat/internal/observability"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestAPIVersion(t *testing.T) {
+	var seen int
+	handler := APIVersion(APIv2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = GetAPIVersion(r.Context())
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/chatrooms", nil))
+
+	testutil.AssertEqual(t, seen, APIv2)
+	testutil.AssertEqual(t, w.Header().Get(APIVersionHeader), "2")
+	testutil.AssertEqual(t, GetAPIVersion(httptest.NewRequest(http.MethodGet, "/", nil).Context()), APIv1)
+}
+
+func TestErrorEnvelopes(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+		code    string
+		message string
+	}{
+		{
+			name: "json_error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, `{"error":"Not a member of this chatroom"}`, http.StatusForbidden)
+			},
+			status:  http.StatusForbidden,
+			code:    "FORBIDDEN",
+			message: "Not a member of this chatroom",
+		},
+		{
+			name: "plain_text",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			},
+			status:  http.StatusTooManyRequests,
+			code:    "RATE_LIMITED",
+			message: "Too many requests",
+		},
+		{
+			name: "empty_body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			status:  http.StatusInternalServerError,
+			code:    "INTERNAL",
+			message: "Internal Server Error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v2/chatrooms", nil)
+			req = req.WithContext(observability.WithRequestID(req.Context(), "req-1"))
+			w := httptest.NewRecorder()
+
+			ErrorEnvelopes()(tt.handler).ServeHTTP(w, req)
+
+			testutil.AssertEqual(t, w.Code, tt.status)
+			testutil.AssertEqual(t, w.Header().Get("Content-Type"), "application/json")
+
+			var envelope ErrorEnvelope
+			testutil.AssertNoError(t, json.NewDecoder(w.Body).Decode(&envelope))
+			testutil.AssertEqual(t, envelope.Error, ErrorDetail{
+				Code:      tt.code,
+				Message:   tt.message,
+				Status:    tt.status,
+				RequestID: "req-1",
+			})
+		})
+	}
+}
+
+func TestErrorEnvelopes_PassesSuccess(t *testing.T) {
+	handler := ErrorEnvelopes()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"id":"room-1"}`)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v2/chatrooms", nil))
+
+	testutil.AssertEqual(t, w.Code, http.StatusCreated)
+	testutil.AssertEqual(t, w.Body.String(), `{"id":"room-1"}`)
+}
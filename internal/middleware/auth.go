@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/observability"
 )
 
 type contextKey string
@@ -31,12 +32,29 @@ func Auth(sessionRepo domain.SessionRepository) func(http.Handler) http.Handler
 
 			ctx := context.WithValue(r.Context(), UserIDKey, session.UserID)
 			ctx = context.WithValue(ctx, SessionKey, session)
+			ctx = observability.WithUserID(ctx, session.UserID)
+			ctx = domain.WithViewerID(ctx, session.UserID)
+			setAccessLogUserID(ctx, session.UserID)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// DenyGuests rejects requests from guest sessions, limiting guests to the
+// routes registered without it. Must be registered after Auth.
+func DenyGuests() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if session, ok := GetSession(r.Context()); ok && session.IsGuest() {
+				http.Error(w, `{"error":"Guests cannot do this; register to continue"}`, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func GetUserID(ctx context.Context) (string, bool) {
 	userID, ok := ctx.Value(UserIDKey).(string)
 	return userID, ok
@@ -322,3 +322,29 @@ func TestAuth_MultipleMiddleware(t *testing.T) {
 	testutil.AssertEqual(t, callOrder[1], "handler")
 	testutil.AssertEqual(t, callOrder[2], "logging-after")
 }
+
+func TestDenyGuests(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{name: "user", token: "user-token", wantStatus: http.StatusOK},
+		{name: "guest", token: domain.GuestTokenPrefix + "token", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := DenyGuests()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/chatrooms", nil)
+			req = req.WithContext(WithSession(req.Context(), &domain.Session{UserID: "user-1", Token: tt.token}))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			testutil.AssertStatusCode(t, rec, tt.wantStatus)
+		})
+	}
+}
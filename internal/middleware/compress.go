@@ -0,0 +1,305 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// CompressConfig controls which responses Compress encodes.
+type CompressConfig struct {
+	// MinSize is the smallest body, in bytes, worth compressing.
+	MinSize int
+
+	// ContentTypes lists the media types that are compressed; other
+	// responses (images, already compressed files) are passed through.
+	ContentTypes []string
+
+	// GzipLevel and BrotliLevel set the compression levels. Dynamic
+	// responses favour speed over ratio.
+	GzipLevel   int
+	BrotliLevel int
+}
+
+// DefaultCompressConfig compresses JSON, HTML, CSS, JavaScript, YAML and plain
+// text bodies of 1KB or more.
+func DefaultCompressConfig() CompressConfig {
+	return CompressConfig{
+		MinSize: 1024,
+		ContentTypes: []string{
+			"application/json",
+			"application/problem+json",
+			"application/javascript",
+			"application/yaml",
+			"text/css",
+			"text/html",
+			"text/javascript",
+			"text/plain",
+		},
+		GzipLevel:   gzip.DefaultCompression,
+		BrotliLevel: 4,
+	}
+}
+
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// Compress returns a middleware that encodes responses with Brotli or gzip,
+// whichever the client prefers in Accept-Encoding (Brotli on a tie). Bodies
+// smaller than MinSize and content types not listed are sent as is.
+// WebSocket upgrades and server-sent event streams are never compressed, so
+// the connection can be hijacked and events are not held back in a buffer.
+func Compress(cfg CompressConfig) func(http.Handler) http.Handler {
+	contentTypes := make(map[string]bool, len(cfg.ContentTypes))
+	for _, ct := range cfg.ContentTypes {
+		contentTypes[ct] = true
+	}
+
+	gzipPool := sync.Pool{New: func() any {
+		gz, err := gzip.NewWriterLevel(io.Discard, cfg.GzipLevel)
+		if err != nil {
+			gz = gzip.NewWriter(io.Discard)
+		}
+		return gz
+	}}
+	brotliPool := sync.Pool{New: func() any {
+		return brotli.NewWriterLevel(io.Discard, cfg.BrotliLevel)
+	}}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead || isStreamingRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" {
+				w.Header().Add("Vary", "Accept-Encoding")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				cfg:            cfg,
+				contentTypes:   contentTypes,
+				encoding:       encoding,
+				gzipPool:       &gzipPool,
+				brotliPool:     &brotliPool,
+				statusCode:     http.StatusOK,
+			}
+			defer cw.Close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+func isStreamingRequest(r *http.Request) bool {
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// negotiateEncoding picks br or gzip from an Accept-Encoding header, honouring
+// q-values; returns "" if the client accepts neither.
+func negotiateEncoding(acceptEncoding string) string {
+	var best string
+	bestQ := 0.0
+	for part := range strings.SplitSeq(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != encodingBrotli && name != encodingGzip && name != "*" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if name == "*" {
+			name = encodingBrotli
+		}
+		if q > bestQ || (q == bestQ && name == encodingBrotli) {
+			best, bestQ = name, q
+		}
+	}
+	if bestQ <= 0 {
+		return ""
+	}
+	return best
+}
+
+// compressWriter buffers the first MinSize bytes of a response to decide
+// whether it is worth compressing, then streams the rest through the encoder.
+type compressWriter struct {
+	http.ResponseWriter
+	cfg          CompressConfig
+	contentTypes map[string]bool
+	encoding     string
+	gzipPool     *sync.Pool
+	brotliPool   *sync.Pool
+
+	statusCode  int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	encoder     io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(statusCode int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.statusCode = statusCode
+
+	// Bodiless and informational responses are never compressed
+	if statusCode < http.StatusOK || statusCode == http.StatusNoContent || statusCode == http.StatusNotModified {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.encoder != nil {
+			return cw.encoder.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.cfg.MinSize {
+		if err := cw.flushBuffer(cw.compressible()); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// compressible reports whether the response's headers allow compressing it
+func (cw *compressWriter) compressible() bool {
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(cw.buf)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if cw.contentTypes[mediaType] {
+		header.Add("Vary", "Accept-Encoding")
+		return true
+	}
+	return false
+}
+
+// decide commits to compressing or not and writes the status line
+func (cw *compressWriter) decide(compress bool) {
+	if cw.decided {
+		return
+	}
+	cw.decided = true
+
+	if compress {
+		header := cw.Header()
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		// Strong validators describe the identity encoding
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+
+		switch cw.encoding {
+		case encodingBrotli:
+			bw := cw.brotliPool.Get().(*brotli.Writer)
+			bw.Reset(cw.ResponseWriter)
+			cw.encoder = bw
+		default:
+			gz := cw.gzipPool.Get().(*gzip.Writer)
+			gz.Reset(cw.ResponseWriter)
+			cw.encoder = gz
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+}
+
+func (cw *compressWriter) flushBuffer(compress bool) error {
+	cw.decide(compress)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.encoder != nil {
+		_, err := cw.encoder.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+// Flush sends what has been written so far, compressed if the buffered part
+// already qualified.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			cw.WriteHeader(http.StatusOK)
+		}
+		_ = cw.flushBuffer(len(cw.buf) >= cw.cfg.MinSize && cw.compressible())
+	}
+	if f, ok := cw.encoder.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close writes a response that never reached MinSize and finishes the
+// encoder, returning it to its pool.
+func (cw *compressWriter) Close() {
+	if !cw.decided {
+		if !cw.wroteHeader && len(cw.buf) == 0 {
+			// The handler wrote nothing; leave the default response to net/http
+			return
+		}
+		_ = cw.flushBuffer(false)
+	}
+
+	switch encoder := cw.encoder.(type) {
+	case *brotli.Writer:
+		_ = encoder.Close()
+		encoder.Reset(io.Discard)
+		cw.brotliPool.Put(encoder)
+	case *gzip.Writer:
+		_ = encoder.Close()
+		encoder.Reset(io.Discard)
+		cw.gzipPool.Put(encoder)
+	}
+	cw.encoder = nil
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"jobsity-chat/internal/testutil"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br;q=0, gzip;q=0", ""},
+		{"*", "br"},
+		{"GZIP;q=0.8", "gzip"},
+		{"gzip;q=abc", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			testutil.AssertEqual(t, negotiateEncoding(tt.acceptEncoding), tt.expected)
+		})
+	}
+}
+
+func compressHandler(contentType string, status int, body string) http.Handler {
+	return Compress(DefaultCompressConfig())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+}
+
+func TestCompress_EncodesLargeJSON(t *testing.T) {
+	body := `{"messages":[` + strings.Repeat(`{"content":"hello"},`, 100) + `{}]}`
+
+	tests := []struct {
+		encoding string
+		decode   func(io.Reader) (io.Reader, error)
+	}{
+		{"gzip", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"br", func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoding, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/chatrooms", nil)
+			req.Header.Set("Accept-Encoding", tt.encoding)
+			w := httptest.NewRecorder()
+
+			compressHandler("application/json", http.StatusOK, body).ServeHTTP(w, req)
+
+			testutil.AssertEqual(t, w.Code, http.StatusOK)
+			testutil.AssertEqual(t, w.Header().Get("Content-Encoding"), tt.encoding)
+			testutil.AssertEqual(t, w.Header().Get("Vary"), "Accept-Encoding")
+			testutil.AssertEqual(t, w.Header().Get("ETag"), `W/"v1"`)
+			if w.Body.Len() >= len(body) {
+				t.Errorf("expected a compressed body, got %d bytes for %d", w.Body.Len(), len(body))
+			}
+
+			reader, err := tt.decode(w.Body)
+			testutil.AssertNoError(t, err)
+			decoded, err := io.ReadAll(reader)
+			testutil.AssertNoError(t, err)
+			testutil.AssertEqual(t, string(decoded), body)
+		})
+	}
+}
+
+func TestCompress_PassesThrough(t *testing.T) {
+	large := strings.Repeat("x", 2048)
+
+	tests := []struct {
+		name        string
+		contentType string
+		status      int
+		body        string
+		header      map[string]string
+	}{
+		{"small_body", "application/json", http.StatusOK, `{"ok":true}`, nil},
+		{"binary_type", "image/png", http.StatusOK, large, nil},
+		{"not_modified", "application/json", http.StatusNotModified, "", nil},
+		{"no_accept_encoding", "application/json", http.StatusOK, large, map[string]string{"Accept-Encoding": ""}},
+		{"websocket_upgrade", "application/json", http.StatusOK, large, map[string]string{"Upgrade": "websocket"}},
+		{"event_stream", "text/event-stream", http.StatusOK, large, map[string]string{"Accept": "text/event-stream"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/chatrooms", nil)
+			req.Header.Set("Accept-Encoding", "gzip, br")
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+
+			compressHandler(tt.contentType, tt.status, tt.body).ServeHTTP(w, req)
+
+			testutil.AssertEqual(t, w.Code, tt.status)
+			testutil.AssertEqual(t, w.Header().Get("Content-Encoding"), "")
+			testutil.AssertEqual(t, w.Header().Get("ETag"), `"v1"`)
+			testutil.AssertEqual(t, w.Body.String(), tt.body)
+		})
+	}
+}
+
+func TestCompress_StreamsAfterThreshold(t *testing.T) {
+	handler := Compress(DefaultCompressConfig())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for range 10 {
+			io.WriteString(w, strings.Repeat("chunk ", 50))
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	testutil.AssertEqual(t, w.Header().Get("Content-Encoding"), "gzip")
+	reader, err := gzip.NewReader(w.Body)
+	testutil.AssertNoError(t, err)
+	decoded, err := io.ReadAll(reader)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, string(decoded), strings.Repeat("chunk ", 500))
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+
+	"jobsity-chat/internal/observability"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// CorrelationIDHeader is the header used to accept and echo correlation IDs
+const CorrelationIDHeader = "X-Correlation-ID"
+
+var correlationIDRegex = regexp.MustCompile(`^[a-zA-Z0-9._\-]{1,64}$`)
+
+// Correlation stores the chi request ID and a correlation ID in the request
+// context so that observability.FromContext loggers and downstream publishers
+// can attach them. A well-formed X-Correlation-ID header from the caller is
+// reused; otherwise the chi request ID (or a fresh ID) is used.
+// Must be registered after chimiddleware.RequestID.
+func Correlation() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			reqID := chimiddleware.GetReqID(ctx)
+			if reqID != "" {
+				ctx = observability.WithRequestID(ctx, reqID)
+			}
+
+			corrID := r.Header.Get(CorrelationIDHeader)
+			if !correlationIDRegex.MatchString(corrID) {
+				corrID = reqID
+			}
+			if corrID == "" {
+				corrID = observability.NewCorrelationID()
+			}
+			ctx = observability.WithCorrelationID(ctx, corrID)
+
+			w.Header().Set(CorrelationIDHeader, corrID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"jobsity-chat/internal/observability"
+	"jobsity-chat/internal/testutil"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+func TestCorrelation(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		wantHeader string
+		useReqID   bool
+	}{
+		{
+			name:       "reuses valid incoming header",
+			header:     "client-trace-42",
+			wantHeader: "client-trace-42",
+		},
+		{
+			name:     "falls back to request id when header missing",
+			useReqID: true,
+		},
+		{
+			name:     "ignores malformed header",
+			header:   "bad header\nvalue",
+			useReqID: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotCorrID, gotReqID string
+			handler := chimiddleware.RequestID(Correlation()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotCorrID = observability.CorrelationID(r.Context())
+				gotReqID = observability.RequestID(r.Context())
+			})))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(CorrelationIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if gotReqID == "" {
+				t.Fatal("expected request id in context")
+			}
+			want := tt.wantHeader
+			if tt.useReqID {
+				want = gotReqID
+			}
+			testutil.AssertEqual(t, gotCorrID, want)
+			testutil.AssertHeader(t, w, CorrelationIDHeader, want)
+		})
+	}
+}
+
+func TestCorrelation_GeneratesIDWithoutRequestID(t *testing.T) {
+	var gotCorrID string
+	handler := Correlation()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotCorrID = observability.CorrelationID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if gotCorrID == "" {
+		t.Error("expected generated correlation id")
+	}
+}
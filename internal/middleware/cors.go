@@ -2,27 +2,97 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// CORSPolicy is the cross-origin policy applied to a set of routes.
+type CORSPolicy struct {
+	// AllowedOrigins lists the origins allowed to call the routes; "*"
+	// allows any. An empty list refuses every cross-origin request.
+	AllowedOrigins []string
+
+	// AllowedMethods and AllowedHeaders are sent in
+	// Access-Control-Allow-Methods and Access-Control-Allow-Headers.
+	AllowedMethods []string
+	AllowedHeaders []string
+
+	// AllowCredentials lets browsers send cookies with cross-origin
+	// requests.
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache a preflight response. Zero
+	// leaves Access-Control-Max-Age unset, so browsers use their default.
+	MaxAge time.Duration
+}
+
+// CORSOverride applies a different policy to the paths under PathPrefix.
+type CORSOverride struct {
+	PathPrefix string
+	Policy     CORSPolicy
+}
+
+// CORSConfig is the default policy plus overrides for some routes. The
+// override with the longest matching prefix wins.
+type CORSConfig struct {
+	CORSPolicy
+	Overrides []CORSOverride
+}
+
+// DefaultCORSMaxAge caches preflight responses for 10 minutes, the longest
+// Chromium honours.
+const DefaultCORSMaxAge = 10 * time.Minute
+
+// DefaultCORSConfig allows the given origins to send credentials with the
+// usual methods and the headers the API reads.
+func DefaultCORSConfig(allowedOrigins []string) CORSConfig {
+	return CORSConfig{CORSPolicy: CORSPolicy{
+		AllowedOrigins:   allowedOrigins,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Organization"},
+		AllowCredentials: true,
+		MaxAge:           DefaultCORSMaxAge,
+	}}
+}
+
 func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
+	return CORSWithConfig(DefaultCORSConfig(allowedOrigins))
+}
+
+// CORSWithConfig returns a middleware that sets the CORS headers of the
+// policy matching the request path for allowed origins, and answers
+// preflight requests without calling the next handler.
+func CORSWithConfig(cfg CORSConfig) func(http.Handler) http.Handler {
+	defaultHeaders := newCORSHeaders(cfg.CORSPolicy)
+	overrides := make([]corsRoute, len(cfg.Overrides))
+	for i, o := range cfg.Overrides {
+		overrides[i] = corsRoute{prefix: o.PathPrefix, headers: newCORSHeaders(o.Policy)}
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			origin := r.Header.Get("Origin")
-
-			allowed := false
-			for _, o := range allowedOrigins {
-				if o == origin || o == "*" {
-					allowed = true
-					break
+			headers := defaultHeaders
+			longest := -1
+			for _, o := range overrides {
+				if len(o.prefix) > longest && strings.HasPrefix(r.URL.Path, o.prefix) {
+					headers, longest = o.headers, len(o.prefix)
 				}
 			}
 
-			if allowed {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Access-Control-Allow-Credentials", "true")
-				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			origin := r.Header.Get("Origin")
+			if headers.allows(origin) {
+				h := w.Header()
+				h.Set("Access-Control-Allow-Origin", origin)
+				h.Add("Vary", "Origin")
+				if headers.policy.AllowCredentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+				h.Set("Access-Control-Allow-Methods", headers.methods)
+				h.Set("Access-Control-Allow-Headers", headers.headers)
+				if r.Method == "OPTIONS" && headers.maxAge != "" {
+					h.Set("Access-Control-Max-Age", headers.maxAge)
+				}
 			}
 
 			if r.Method == "OPTIONS" {
@@ -35,6 +105,43 @@ func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
 	}
 }
 
+type corsRoute struct {
+	prefix  string
+	headers *corsHeaders
+}
+
+// corsHeaders holds a policy's header values, joined once
+type corsHeaders struct {
+	policy  CORSPolicy
+	methods string
+	headers string
+	maxAge  string
+}
+
+func newCORSHeaders(p CORSPolicy) *corsHeaders {
+	h := &corsHeaders{
+		policy:  p,
+		methods: strings.Join(p.AllowedMethods, ", "),
+		headers: strings.Join(p.AllowedHeaders, ", "),
+	}
+	if p.MaxAge > 0 {
+		h.maxAge = strconv.Itoa(int(p.MaxAge.Seconds()))
+	}
+	return h
+}
+
+func (h *corsHeaders) allows(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, o := range h.policy.AllowedOrigins {
+		if o == origin || o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
 func ParseOrigins(originsStr string) []string {
 	origins := strings.Split(originsStr, ",")
 	for i, origin := range origins {
@@ -42,3 +149,15 @@ func ParseOrigins(originsStr string) []string {
 	}
 	return origins
 }
+
+// ParseCORSList splits a comma-separated list of origins, methods or
+// headers. Unlike ParseOrigins, an empty string is an empty list.
+func ParseCORSList(spec string) []string {
+	var items []string
+	for item := range strings.SplitSeq(spec, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"jobsity-chat/internal/testutil"
@@ -351,3 +352,63 @@ func BenchmarkParseOrigins(b *testing.B) {
 		ParseOrigins(originsStr)
 	}
 }
+
+func TestCORSWithConfig_MaxAgeOnPreflightOnly(t *testing.T) {
+	handler := CORS([]string{"http://localhost:3000"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for method, want := range map[string]string{http.MethodOptions: "600", http.MethodGet: ""} {
+		req := httptest.NewRequest(method, "/api/test", nil)
+		req.Header.Set("Origin", "http://localhost:3000")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		testutil.AssertEqual(t, w.Header().Get("Access-Control-Max-Age"), want)
+		testutil.AssertEqual(t, w.Header().Get("Vary"), "Origin")
+	}
+}
+
+func TestCORSWithConfig_Overrides(t *testing.T) {
+	cfg := DefaultCORSConfig([]string{"http://localhost:3000", "http://admin.local"})
+	cfg.AllowCredentials = false
+	cfg.AllowedHeaders = []string{"Content-Type"}
+	cfg.Overrides = []CORSOverride{
+		{PathPrefix: "/api/v1/", Policy: cfg.CORSPolicy},
+		{PathPrefix: "/api/v1/admin/", Policy: CORSPolicy{
+			AllowedOrigins:   []string{"http://admin.local"},
+			AllowedMethods:   []string{"GET", "POST"},
+			AllowCredentials: true,
+		}},
+	}
+	handler := CORSWithConfig(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		path, origin    string
+		wantOrigin      string
+		wantMethods     string
+		wantHeaders     string
+		wantMaxAge      string
+		wantCredentials string
+	}{
+		{"/api/v1/chatrooms", "http://localhost:3000", "http://localhost:3000", "GET, POST, PUT, DELETE, OPTIONS", "Content-Type", "600", ""},
+		{"/api/v1/admin/flags", "http://localhost:3000", "", "", "", "", ""},
+		{"/api/v1/admin/flags", "http://admin.local", "http://admin.local", "GET, POST", "", "", "true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.origin, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			testutil.AssertEqual(t, w.Header().Get("Access-Control-Allow-Origin"), tt.wantOrigin)
+			testutil.AssertEqual(t, w.Header().Get("Access-Control-Allow-Methods"), tt.wantMethods)
+			testutil.AssertEqual(t, w.Header().Get("Access-Control-Allow-Headers"), tt.wantHeaders)
+			testutil.AssertEqual(t, w.Header().Get("Access-Control-Max-Age"), tt.wantMaxAge)
+			testutil.AssertEqual(t, w.Header().Get("Access-Control-Allow-Credentials"), tt.wantCredentials)
+		})
+	}
+}
+
+func TestParseCORSList(t *testing.T) {
+	testutil.AssertLen(t, ParseCORSList(""), 0)
+	testutil.AssertEqual(t, strings.Join(ParseCORSList(" GET, ,POST "), "|"), "GET|POST")
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestCSRF(t *testing.T) {
+	key := []byte("csrf-test-key")
+	session := &domain.Session{Token: "session-token", UserID: "user-123"}
+	valid := CSRFToken(key, session.Token)
+
+	tests := []struct {
+		name       string
+		method     string
+		token      string
+		noSession  bool
+		wantStatus int
+	}{
+		{name: "safe method without token", method: http.MethodGet, wantStatus: http.StatusOK},
+		{name: "post with token", method: http.MethodPost, token: valid, wantStatus: http.StatusOK},
+		{name: "delete with token", method: http.MethodDelete, token: valid, wantStatus: http.StatusOK},
+		{name: "post without token", method: http.MethodPost, wantStatus: http.StatusForbidden},
+		{name: "put with another session's token", method: http.MethodPut, token: CSRFToken(key, "other"), wantStatus: http.StatusForbidden},
+		{name: "post without session", method: http.MethodPost, token: valid, noSession: true, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CSRF(key)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(tt.method, "/api/v1/chatrooms", nil)
+			if !tt.noSession {
+				req = req.WithContext(WithSession(req.Context(), session))
+			}
+			if tt.token != "" {
+				req.Header.Set(CSRFHeader, tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			testutil.AssertStatusCode(t, w, tt.wantStatus)
+		})
+	}
+}
+
+func TestCSRFToken_DependsOnKey(t *testing.T) {
+	testutil.AssertEqual(t, CSRFToken([]byte("a"), "session"), CSRFToken([]byte("a"), "session"))
+	testutil.AssertTrue(t, CSRFToken([]byte("a"), "session") != CSRFToken([]byte("b"), "session"),
+		"tokens should differ between keys")
+}
@@ -6,12 +6,40 @@ import (
 	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"jobsity-chat/internal/observability"
+
+	"github.com/go-chi/chi/v5"
 )
 
+// unmatchedPathLabel labels requests no route matched, so scanners probing
+// random paths add a single series
+const unmatchedPathLabel = "unmatched"
+
+// MetricsConfig controls the path label of the HTTP metrics. Requests are
+// labeled by their chi route pattern (e.g. /api/v1/chatrooms/{id}/messages)
+// rather than their raw path, so IDs in URLs do not create a series each.
+type MetricsConfig struct {
+	// PathLabels lists raw request paths that get their own path label
+	// instead of their route pattern, e.g. the profiles under the
+	// /debug/pprof/* wildcard
+	PathLabels []string
+}
+
+// Metrics records HTTP request counts and latencies labeled by route pattern
 func Metrics() func(http.Handler) http.Handler {
+	return MetricsWithConfig(MetricsConfig{})
+}
+
+// MetricsWithConfig is Metrics with additional raw path labels
+func MetricsWithConfig(cfg MetricsConfig) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(cfg.PathLabels))
+	for _, path := range cfg.PathLabels {
+		allowed[path] = true
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -22,25 +50,53 @@ func Metrics() func(http.Handler) http.Handler {
 
 			duration := time.Since(start).Seconds()
 			status := strconv.Itoa(ww.statusCode)
+			path := metricsPathLabel(r, allowed)
 
 			observability.HTTPRequestDuration.WithLabelValues(
 				r.Method,
-				r.URL.Path,
+				path,
 				status,
 			).Observe(duration)
 
 			observability.HTTPRequestsTotal.WithLabelValues(
 				r.Method,
-				r.URL.Path,
+				path,
 				status,
 			).Inc()
 		})
 	}
 }
 
+// metricsPathLabel returns the request's path label. It must run after the
+// router has served the request, when the route pattern is complete.
+func metricsPathLabel(r *http.Request, allowed map[string]bool) string {
+	if allowed[r.URL.Path] {
+		return r.URL.Path
+	}
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return unmatchedPathLabel
+}
+
+// ParseMetricsPathLabels parses a comma-separated list of raw paths, e.g.
+// "/debug/pprof/heap,/debug/pprof/goroutine". Blank entries are ignored.
+func ParseMetricsPathLabels(spec string) []string {
+	var paths []string
+	for path := range strings.SplitSeq(spec, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
 type responseWriter struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode   int
+	bytesWritten int
 }
 
 func (rw *responseWriter) WriteHeader(statusCode int) {
@@ -48,6 +104,12 @@ func (rw *responseWriter) WriteHeader(statusCode int) {
 	rw.ResponseWriter.WriteHeader(statusCode)
 }
 
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytesWritten += n
+	return n, err
+}
+
 func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
 	if !ok {
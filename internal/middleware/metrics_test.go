@@ -9,7 +9,11 @@ import (
 	"testing"
 	"time"
 
+	"jobsity-chat/internal/observability"
+
+	"github.com/go-chi/chi/v5"
 	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -528,3 +532,45 @@ func TestMetrics_StatusCodeVariations(t *testing.T) {
 		})
 	}
 }
+
+func TestMetrics_LabelsByRoutePattern(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(MetricsWithConfig(MetricsConfig{PathLabels: []string{"/debug/pprof/heap"}}))
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Get("/chatrooms/{id}/messages", func(w http.ResponseWriter, r *http.Request) {})
+	})
+	r.Get("/debug/pprof/*", func(w http.ResponseWriter, r *http.Request) {})
+
+	count := func(path string) float64 {
+		return promtestutil.ToFloat64(observability.HTTPRequestsTotal.WithLabelValues(http.MethodGet, path, "200"))
+	}
+	countNotFound := func() float64 {
+		return promtestutil.ToFloat64(observability.HTTPRequestsTotal.WithLabelValues(http.MethodGet, unmatchedPathLabel, "404"))
+	}
+	before := map[string]float64{
+		"/api/v1/chatrooms/{id}/messages": count("/api/v1/chatrooms/{id}/messages"),
+		"/debug/pprof/*":                  count("/debug/pprof/*"),
+		"/debug/pprof/heap":               count("/debug/pprof/heap"),
+	}
+	notFoundBefore := countNotFound()
+
+	for _, path := range []string{
+		"/api/v1/chatrooms/6f1c1e9a-0000-4000-8000-000000000001/messages",
+		"/api/v1/chatrooms/6f1c1e9a-0000-4000-8000-000000000002/messages",
+		"/debug/pprof/goroutine",
+		"/debug/pprof/heap",
+		"/wp-login.php",
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, 2.0, count("/api/v1/chatrooms/{id}/messages")-before["/api/v1/chatrooms/{id}/messages"])
+	assert.Equal(t, 1.0, count("/debug/pprof/*")-before["/debug/pprof/*"])
+	assert.Equal(t, 1.0, count("/debug/pprof/heap")-before["/debug/pprof/heap"])
+	assert.Equal(t, 1.0, countNotFound()-notFoundBefore)
+}
+
+func TestParseMetricsPathLabels(t *testing.T) {
+	assert.Nil(t, ParseMetricsPathLabels(""))
+	assert.Equal(t, []string{"/debug/pprof/heap", "/debug/vars"}, ParseMetricsPathLabels(" /debug/pprof/heap, ,/debug/vars "))
+}
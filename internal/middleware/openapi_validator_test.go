@@ -87,11 +87,62 @@ func TestOpenAPIPathsMatchImplementation(t *testing.T) {
 	expectedPaths := []string{
 		"/auth/register",
 		"/auth/login",
+		"/auth/guest",
 		"/auth/me",
+		"/auth/me/locale",
+		"/auth/me/profile",
+		"/auth/me/status",
+		"/auth/me/username",
 		"/auth/logout",
+		"/auth/oauth",
+		"/auth/oauth/{provider}",
+		"/auth/oauth/{provider}/callback",
+		"/ws-ticket",
+		"/ws/protocol",
 		"/chatrooms",
+		"/chatrooms/{id}",
 		"/chatrooms/{id}/join",
+		"/chatrooms/{id}/members",
+		"/chatrooms/{id}/stats",
+		"/chatrooms/{id}/invites",
+		"/chatrooms/{id}/invites/{invite_id}",
+		"/invites/lookup",
+		"/invites/accept",
+		"/chatrooms/{id}/settings",
+		"/chatrooms/{id}/keys",
+		"/chatrooms/{id}/read",
 		"/chatrooms/{id}/messages",
+		"/chatrooms/{id}/shadow-bans",
+		"/chatrooms/{id}/shadow-bans/{user_id}",
+		"/me/activity",
+		"/me/username-history",
+		"/me/export",
+		"/me/export/{id}/archive",
+		"/me/push-devices",
+		"/me/push-devices/{id}",
+		"/me/dnd-schedule",
+		"/push/config",
+		"/messages/{id}/flag",
+		"/messages/{id}/forward",
+		"/chatrooms/{id}/events",
+		"/messages/{id}/rsvp",
+		"/messages/{id}/rsvps",
+		"/messages/{id}/event.ics",
+		"/chatrooms/{id}/tags",
+		"/chatrooms/{id}/tags/{tag}",
+		"/tags",
+		"/chatrooms/{id}/star",
+		"/search",
+		"/users/{id}",
+		"/users/by-username/{username}",
+		"/admin/bot-stats",
+		"/admin/hub/stats",
+		"/admin/hub/history",
+		"/admin/users/{id}/disconnect",
+		"/admin/flags",
+		"/admin/flags/{id}/resolve",
+		"/admin/chatrooms/{id}/restore",
+		"/admin/messages/{id}/restore",
 		"/ws/chat/{chatroom_id}",
 		"/health",
 		"/health/ready",
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type schemeKey struct{}
+
+// ParseTrustedProxies parses a comma-separated list of IP addresses and
+// CIDR ranges, e.g. "127.0.0.1,10.0.0.0/8". Empty trusts no proxy.
+func ParseTrustedProxies(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, entry := range ParseCORSList(s) {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+			}
+			bits := 8 * len(ip.To16())
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// Proxy applies the X-Forwarded-For, X-Real-IP, X-Forwarded-Proto and
+// X-Forwarded-Host headers of requests from trusted proxies: the client
+// address replaces RemoteAddr, the host replaces Host and the scheme is
+// reported by RequestScheme. Connections over a Unix socket come from a
+// local proxy and are always trusted. Anyone else's forwarding headers are
+// ignored, so clients cannot spoof their address or scheme.
+func Proxy(trusted []*net.IPNet) func(http.Handler) http.Handler {
+	isTrusted := func(addr string) bool {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			// Unix socket peers have no IP address
+			return true
+		}
+		for _, n := range trusted {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isTrusted(r.RemoteAddr) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if ip := forwardedClientIP(r, isTrusted); ip != "" {
+				r.RemoteAddr = ip
+			}
+			if host := firstValue(r.Header.Get("X-Forwarded-Host")); host != "" {
+				r.Host = host
+			}
+			if proto := strings.ToLower(firstValue(r.Header.Get("X-Forwarded-Proto"))); proto == "https" || proto == "http" {
+				r = r.WithContext(context.WithValue(r.Context(), schemeKey{}, proto))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClientIP returns the nearest address in X-Forwarded-For that
+// isn't a trusted proxy, as earlier ones could have been set by the client,
+// or else X-Real-IP
+func forwardedClientIP(r *http.Request, isTrusted func(string) bool) string {
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			if !isTrusted(hop) || i == 0 {
+				return hop
+			}
+		}
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return ""
+}
+
+func firstValue(header string) string {
+	first, _, _ := strings.Cut(header, ",")
+	return strings.TrimSpace(first)
+}
+
+// RequestScheme returns "https" for requests received over TLS, directly or
+// through a trusted proxy, and "http" otherwise
+func RequestScheme(r *http.Request) string {
+	if scheme, ok := r.Context().Value(schemeKey{}).(string); ok {
+		return scheme
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// IsSecure reports whether the client reached the server over HTTPS
+func IsSecure(r *http.Request) bool {
+	return RequestScheme(r) == "https"
+}
+
+// ClientIP returns the address of the client that sent the request, set by
+// Proxy for requests from trusted proxies, without its port
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"jobsity-chat/internal/testutil"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	nets, err := ParseTrustedProxies("127.0.0.0/8, ::1, 10.1.2.3")
+	testutil.AssertNoError(t, err)
+	testutil.AssertLen(t, nets, 3)
+	testutil.AssertEqual(t, nets[1].String(), "::1/128")
+	testutil.AssertEqual(t, nets[2].String(), "10.1.2.3/32")
+
+	nets, err = ParseTrustedProxies("")
+	testutil.AssertNoError(t, err)
+	testutil.AssertLen(t, nets, 0)
+
+	_, err = ParseTrustedProxies("10.0.0.0/33")
+	testutil.AssertError(t, err)
+	_, err = ParseTrustedProxies("proxy.internal")
+	testutil.AssertError(t, err)
+}
+
+func TestProxy(t *testing.T) {
+	trusted, _ := ParseTrustedProxies("10.0.0.0/8")
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		wantAddr   string
+		wantHost   string
+		wantScheme string
+	}{
+		{
+			name:       "direct request",
+			remoteAddr: "203.0.113.7:4321",
+			wantAddr:   "203.0.113.7:4321",
+			wantHost:   "example.com",
+			wantScheme: "http",
+		},
+		{
+			name:       "untrusted client spoofing headers",
+			remoteAddr: "203.0.113.7:4321",
+			headers: map[string]string{
+				"X-Forwarded-For":   "198.51.100.1",
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "chat.example.com",
+			},
+			wantAddr:   "203.0.113.7:4321",
+			wantHost:   "example.com",
+			wantScheme: "http",
+		},
+		{
+			name:       "trusted TLS-terminating proxy",
+			remoteAddr: "10.0.0.2:5555",
+			headers: map[string]string{
+				"X-Forwarded-For":   "198.51.100.1",
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "chat.example.com",
+			},
+			wantAddr:   "198.51.100.1",
+			wantHost:   "chat.example.com",
+			wantScheme: "https",
+		},
+		{
+			name:       "client-supplied hops before the proxy are skipped",
+			remoteAddr: "10.0.0.2:5555",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.1, 10.0.0.3"},
+			wantAddr:   "198.51.100.1",
+			wantHost:   "example.com",
+			wantScheme: "http",
+		},
+		{
+			name:       "real ip header",
+			remoteAddr: "10.0.0.2:5555",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.9"},
+			wantAddr:   "198.51.100.9",
+			wantHost:   "example.com",
+			wantScheme: "http",
+		},
+		{
+			name:       "unix socket peer",
+			remoteAddr: "@",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "https"},
+			wantAddr:   "198.51.100.1",
+			wantHost:   "example.com",
+			wantScheme: "https",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var addr, host, scheme string
+			handler := Proxy(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				addr, host, scheme = r.RemoteAddr, r.Host, RequestScheme(r)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			testutil.AssertEqual(t, addr, tt.wantAddr)
+			testutil.AssertEqual(t, host, tt.wantHost)
+			testutil.AssertEqual(t, scheme, tt.wantScheme)
+		})
+	}
+}
+
+func TestRequestScheme_TLS(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	testutil.AssertTrue(t, IsSecure(req), "requests over TLS should be secure")
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for addr, want := range map[string]string{
+		"203.0.113.7:5123": "203.0.113.7",
+		"[2001:db8::1]:80": "2001:db8::1",
+		"198.51.100.1":     "198.51.100.1",
+	} {
+		req.RemoteAddr = addr
+		testutil.AssertEqual(t, ClientIP(req), want)
+	}
+}
@@ -131,10 +131,27 @@ func (rl *RateLimiter) getLimiter(key string) *rate.Limiter {
 }
 
 func (rl *RateLimiter) Middleware() func(http.Handler) http.Handler {
+	return rl.middleware(func(r *http.Request) string {
+		return r.RemoteAddr
+	})
+}
+
+// UserMiddleware limits each signed-in user instead of each client address,
+// so switching networks does not reset the limit. It must run after Auth;
+// requests without a user are limited by address.
+func (rl *RateLimiter) UserMiddleware() func(http.Handler) http.Handler {
+	return rl.middleware(func(r *http.Request) string {
+		if userID, ok := GetUserID(r.Context()); ok {
+			return "user:" + userID
+		}
+		return r.RemoteAddr
+	})
+}
+
+func (rl *RateLimiter) middleware(key func(r *http.Request) string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			key := r.RemoteAddr
-			limiter := rl.getLimiter(key)
+			limiter := rl.getLimiter(key(r))
 
 			if !limiter.Allow() {
 				http.Error(w, `{"error":"Rate limit exceeded"}`, http.StatusTooManyRequests)
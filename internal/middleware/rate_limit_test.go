@@ -277,3 +277,37 @@ func TestRateLimiter_ContextCancellation(t *testing.T) {
 	// Stop should not hang
 	rl.Stop()
 }
+
+func TestRateLimiter_UserMiddleware(t *testing.T) {
+	rl := NewRateLimiter(context.Background(), 1, 2)
+	defer rl.Stop()
+
+	handler := rl.UserMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	send := func(userID, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/chatrooms/room-1/invites", nil)
+		req.RemoteAddr = remoteAddr
+		req = req.WithContext(WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Switching addresses does not reset a user's limit
+	if code := send("owner-1", "192.168.1.1:1234"); code != http.StatusCreated {
+		t.Errorf("first request: expected 201, got %d", code)
+	}
+	if code := send("owner-1", "10.0.0.1:1234"); code != http.StatusCreated {
+		t.Errorf("second request: expected 201, got %d", code)
+	}
+	if code := send("owner-1", "172.16.0.1:1234"); code != http.StatusTooManyRequests {
+		t.Errorf("third request: expected 429, got %d", code)
+	}
+
+	// Other users sharing the address are not limited
+	if code := send("owner-2", "192.168.1.1:1234"); code != http.StatusCreated {
+		t.Errorf("other user: expected 201, got %d", code)
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"jobsity-chat/internal/domain"
+)
+
+// SessionToucher records session activity for sliding expiration
+type SessionToucher interface {
+	Touch(session *domain.Session)
+}
+
+// SlidingSession renews the authenticated session on every request.
+// Must be registered after Auth.
+func SlidingSession(toucher SessionToucher) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if session, ok := GetSession(r.Context()); ok {
+				toucher.Touch(session)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
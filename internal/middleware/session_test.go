@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+type recordingToucher struct {
+	touched []*domain.Session
+}
+
+func (t *recordingToucher) Touch(session *domain.Session) {
+	t.touched = append(t.touched, session)
+}
+
+func TestSlidingSession_TouchesAuthenticatedSession(t *testing.T) {
+	session := testutil.NewTestSession(testutil.WithToken("valid-token"))
+	toucher := &recordingToucher{}
+
+	handler := SlidingSession(toucher)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req = req.WithContext(WithSession(req.Context(), session))
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	testutil.AssertStatusCode(t, w, http.StatusOK)
+	testutil.AssertEqual(t, len(toucher.touched), 1)
+	testutil.AssertEqual(t, toucher.touched[0].Token, "valid-token")
+}
+
+func TestSlidingSession_NoSession(t *testing.T) {
+	toucher := &recordingToucher{}
+
+	nextHandlerCalled := false
+	handler := SlidingSession(toucher)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nextHandlerCalled = true
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/public", nil))
+
+	testutil.AssertTrue(t, nextHandlerCalled, "next handler should be called")
+	testutil.AssertEqual(t, len(toucher.touched), 0)
+}
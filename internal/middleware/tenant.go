@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+
+	"jobsity-chat/internal/domain"
+)
+
+// OrganizationHeader names the organization (by slug) a request is for. It
+// takes precedence over the Host subdomain.
+const OrganizationHeader = "X-Organization"
+
+// OrganizationQueryParam carries the organization slug for clients that
+// cannot set headers, such as browser WebSocket connections
+const OrganizationQueryParam = "org"
+
+// Tenant scopes the request context to an organization with domain.WithOrgID.
+// The organization slug is taken from the X-Organization header, the org
+// query parameter or, when baseDomain is set, from the subdomain of the Host (acme.chat.example.com
+// for a baseDomain of chat.example.com). Requests naming neither are served
+// by the default organization; requests naming an unknown one get a 404.
+func Tenant(orgRepo domain.OrganizationRepository, baseDomain string) func(http.Handler) http.Handler {
+	baseDomain = strings.ToLower(strings.Trim(baseDomain, "."))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			slug := r.Header.Get(OrganizationHeader)
+			if slug == "" {
+				slug = r.URL.Query().Get(OrganizationQueryParam)
+			}
+			slug = strings.ToLower(strings.TrimSpace(slug))
+			if slug == "" && baseDomain != "" {
+				slug = subdomain(r.Host, baseDomain)
+			}
+			if slug == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			org, err := orgRepo.GetBySlug(r.Context(), slug)
+			if errors.Is(err, domain.ErrOrganizationNotFound) {
+				http.Error(w, `{"error":"Organization not found"}`, http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				slog.Error("failed to resolve organization",
+					slog.String("slug", slug),
+					slog.String("error", err.Error()))
+				http.Error(w, `{"error":"Failed to resolve organization"}`, http.StatusInternalServerError)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(domain.WithOrgID(r.Context(), org.ID)))
+		})
+	}
+}
+
+// subdomain returns the single label in front of baseDomain in host, or ""
+// when host is baseDomain itself, a www alias, or another domain
+func subdomain(host, baseDomain string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	label, ok := strings.CutSuffix(host, "."+baseDomain)
+	if !ok || label == "www" || strings.Contains(label, ".") {
+		return ""
+	}
+	return label
+}
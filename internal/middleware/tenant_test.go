@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestTenant(t *testing.T) {
+	orgRepo := testutil.NewMockOrganizationRepository()
+	orgRepo.Organizations["org-acme"] = &domain.Organization{ID: "org-acme", Slug: "acme", Name: "Acme"}
+
+	var gotOrgID string
+	handler := Tenant(orgRepo, "chat.example.com")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotOrgID = domain.OrgIDFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		host       string
+		header     string
+		wantStatus int
+		wantOrgID  string
+	}{
+		{"no tenant uses default", "chat.example.com", "", http.StatusOK, domain.DefaultOrganizationID},
+		{"subdomain", "acme.chat.example.com:8080", "", http.StatusOK, "org-acme"},
+		{"header", "chat.example.com", "Acme", http.StatusOK, "org-acme"},
+		{"header wins over subdomain", "other.chat.example.com", "acme", http.StatusOK, "org-acme"},
+		{"query parameter", "chat.example.com/?org=acme", "", http.StatusOK, "org-acme"},
+		{"www is not a tenant", "www.chat.example.com", "", http.StatusOK, domain.DefaultOrganizationID},
+		{"foreign domain ignored", "acme.evil.com", "", http.StatusOK, domain.DefaultOrganizationID},
+		{"unknown subdomain", "missing.chat.example.com", "", http.StatusNotFound, ""},
+		{"unknown header", "chat.example.com", "missing", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOrgID = ""
+			host, query, _ := strings.Cut(tt.host, "/")
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/chatrooms"+query, nil)
+			req.Host = host
+			if tt.header != "" {
+				req.Header.Set(OrganizationHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			testutil.AssertStatusCode(t, w, tt.wantStatus)
+			testutil.AssertEqual(t, gotOrgID, tt.wantOrgID)
+		})
+	}
+}
+
+func TestTenant_WithoutBaseDomain(t *testing.T) {
+	orgRepo := testutil.NewMockOrganizationRepository()
+	orgRepo.GetBySlugFunc = func(ctx context.Context, slug string) (*domain.Organization, error) {
+		t.Fatalf("unexpected lookup of %q", slug)
+		return nil, nil
+	}
+
+	handler := Tenant(orgRepo, "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testutil.AssertEqual(t, domain.OrgIDFromContext(r.Context()), domain.DefaultOrganizationID)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "acme.chat.example.com"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestTenant_LookupError(t *testing.T) {
+	orgRepo := testutil.NewMockOrganizationRepository()
+	orgRepo.GetBySlugFunc = func(ctx context.Context, slug string) (*domain.Organization, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	handler := Tenant(orgRepo, "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler should not be called")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(OrganizationHeader, "acme")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	testutil.AssertStatusCode(t, w, http.StatusInternalServerError)
+}
@@ -0,0 +1,68 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"jobsity-chat/internal/domain"
+
+	"golang.org/x/oauth2"
+)
+
+var githubEndpoint = oauth2.Endpoint{
+	AuthURL:  "https://github.com/login/oauth/authorize",
+	TokenURL: "https://github.com/login/oauth/access_token",
+}
+
+const githubAPIURL = "https://api.github.com"
+
+// NewGitHub returns a GitHub OAuth provider
+func NewGitHub(clientID, clientSecret, redirectURL string) *Provider {
+	return newGitHub(clientID, clientSecret, redirectURL, githubEndpoint, githubAPIURL)
+}
+
+func newGitHub(clientID, clientSecret, redirectURL string, endpoint oauth2.Endpoint, apiURL string) *Provider {
+	return NewProvider(ProviderGitHub,
+		&oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Endpoint:     endpoint,
+			Scopes:       []string{"read:user", "user:email"},
+		},
+		func(ctx context.Context, client *http.Client) (*domain.ExternalProfile, error) {
+			var user struct {
+				ID    int64  `json:"id"`
+				Login string `json:"login"`
+			}
+			if err := getJSON(ctx, client, apiURL+"/user", &user); err != nil {
+				return nil, err
+			}
+
+			// The public profile email may be unset; use the primary address
+			// and its verification status instead
+			var emails []struct {
+				Email    string `json:"email"`
+				Primary  bool   `json:"primary"`
+				Verified bool   `json:"verified"`
+			}
+			if err := getJSON(ctx, client, apiURL+"/user/emails", &emails); err != nil {
+				return nil, err
+			}
+
+			profile := &domain.ExternalProfile{
+				Subject:  strconv.FormatInt(user.ID, 10),
+				Username: user.Login,
+			}
+			for _, e := range emails {
+				if e.Primary {
+					profile.Email = e.Email
+					profile.EmailVerified = e.Verified
+					break
+				}
+			}
+			return profile, nil
+		},
+	)
+}
@@ -0,0 +1,58 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"jobsity-chat/internal/domain"
+
+	"golang.org/x/oauth2"
+)
+
+var googleEndpoint = oauth2.Endpoint{
+	AuthURL:  "https://accounts.google.com/o/oauth2/v2/auth",
+	TokenURL: "https://oauth2.googleapis.com/token",
+}
+
+const googleUserInfoURL = "https://openidconnect.googleapis.com/v1/userinfo"
+
+// NewGoogle returns a Google OpenID Connect provider
+func NewGoogle(clientID, clientSecret, redirectURL string) *Provider {
+	return newGoogle(clientID, clientSecret, redirectURL, googleEndpoint, googleUserInfoURL)
+}
+
+func newGoogle(clientID, clientSecret, redirectURL string, endpoint oauth2.Endpoint, userInfoURL string) *Provider {
+	return NewProvider(ProviderGoogle,
+		&oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Endpoint:     endpoint,
+			Scopes:       []string{"openid", "email", "profile"},
+		},
+		func(ctx context.Context, client *http.Client) (*domain.ExternalProfile, error) {
+			var info struct {
+				Sub           string `json:"sub"`
+				Email         string `json:"email"`
+				EmailVerified bool   `json:"email_verified"`
+				GivenName     string `json:"given_name"`
+			}
+			if err := getJSON(ctx, client, userInfoURL, &info); err != nil {
+				return nil, err
+			}
+
+			username := info.GivenName
+			if local, _, ok := strings.Cut(info.Email, "@"); ok {
+				username = local
+			}
+
+			return &domain.ExternalProfile{
+				Subject:       info.Sub,
+				Email:         info.Email,
+				EmailVerified: info.EmailVerified,
+				Username:      username,
+			}, nil
+		},
+	)
+}
@@ -0,0 +1,142 @@
+// Package oauth implements the authorization code flow against external
+// identity providers (Google, GitHub) and maps their user info to
+// domain.ExternalProfile.
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"jobsity-chat/internal/config"
+	"jobsity-chat/internal/domain"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	ProviderGoogle = "google"
+	ProviderGitHub = "github"
+)
+
+var ErrUnknownProvider = errors.New("unknown oauth provider")
+
+// ProfileFetcher loads the user's profile with an authorized client
+type ProfileFetcher func(ctx context.Context, client *http.Client) (*domain.ExternalProfile, error)
+
+// Provider is a configured identity provider
+type Provider struct {
+	Name string
+
+	config       *oauth2.Config
+	fetchProfile ProfileFetcher
+}
+
+// NewProvider returns a provider for any OAuth2 server; NewGoogle and
+// NewGitHub cover the built-in ones
+func NewProvider(name string, config *oauth2.Config, fetchProfile ProfileFetcher) *Provider {
+	return &Provider{
+		Name:         name,
+		config:       config,
+		fetchProfile: fetchProfile,
+	}
+}
+
+// AuthCodeURL returns the provider's consent page URL. The PKCE verifier
+// must be presented again to Exchange.
+func (p *Provider) AuthCodeURL(state, verifier string) string {
+	return p.config.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange trades an authorization code for the user's profile
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (*domain.ExternalProfile, error) {
+	token, err := p.config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange %s authorization code: %w", p.Name, err)
+	}
+
+	profile, err := p.fetchProfile(ctx, p.config.Client(ctx, token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s profile: %w", p.Name, err)
+	}
+	if profile.Subject == "" {
+		return nil, fmt.Errorf("%s profile has no subject", p.Name)
+	}
+	profile.Provider = p.Name
+	return profile, nil
+}
+
+// Registry holds the enabled providers by name
+type Registry struct {
+	providers map[string]*Provider
+}
+
+func NewRegistry(providers ...*Provider) *Registry {
+	r := &Registry{providers: make(map[string]*Provider, len(providers))}
+	for _, p := range providers {
+		r.providers[p.Name] = p
+	}
+	return r
+}
+
+// RegistryFromConfig enables every provider with a client ID configured
+func RegistryFromConfig(cfg *config.Config) *Registry {
+	base := strings.TrimRight(cfg.OAuthRedirectBaseURL, "/")
+	callback := func(name string) string {
+		return base + "/api/v1/auth/oauth/" + name + "/callback"
+	}
+
+	var providers []*Provider
+	if cfg.OAuthGoogleClientID != "" {
+		providers = append(providers, NewGoogle(cfg.OAuthGoogleClientID, cfg.OAuthGoogleClientSecret, callback(ProviderGoogle)))
+	}
+	if cfg.OAuthGitHubClientID != "" {
+		providers = append(providers, NewGitHub(cfg.OAuthGitHubClientID, cfg.OAuthGitHubClientSecret, callback(ProviderGitHub)))
+	}
+	return NewRegistry(providers...)
+}
+
+// Get returns the named provider
+func (r *Registry) Get(name string) (*Provider, error) {
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// Names returns the enabled provider names in sorted order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getJSON decodes a successful JSON response from url into v
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GET %s: status %d: %s", url, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,118 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"jobsity-chat/internal/config"
+	"jobsity-chat/internal/testutil"
+
+	"golang.org/x/oauth2"
+)
+
+// newFakeProviderServer serves a token endpoint plus the given JSON routes,
+// all of which require the issued access token
+func newFakeProviderServer(t *testing.T, routes map[string]string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("code") != "auth-code" || r.Form.Get("code_verifier") == "" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"access-token","token_type":"bearer"}`))
+	})
+	for path, body := range routes {
+		mux.HandleFunc("GET "+path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer access-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(body))
+		})
+	}
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGoogle_Exchange(t *testing.T) {
+	server := newFakeProviderServer(t, map[string]string{
+		"/userinfo": `{"sub":"g-123","email":"alice@example.com","email_verified":true,"given_name":"Alice"}`,
+	})
+	provider := newGoogle("client", "secret", "http://localhost/callback",
+		oauth2.Endpoint{AuthURL: server.URL + "/auth", TokenURL: server.URL + "/token"}, server.URL+"/userinfo")
+
+	profile, err := provider.Exchange(context.Background(), "auth-code", oauth2.GenerateVerifier())
+
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, profile.Provider, ProviderGoogle)
+	testutil.AssertEqual(t, profile.Subject, "g-123")
+	testutil.AssertEqual(t, profile.Email, "alice@example.com")
+	testutil.AssertTrue(t, profile.EmailVerified, "email should be verified")
+	testutil.AssertEqual(t, profile.Username, "alice")
+}
+
+func TestGitHub_Exchange(t *testing.T) {
+	server := newFakeProviderServer(t, map[string]string{
+		"/user":        `{"id":42,"login":"octocat"}`,
+		"/user/emails": `[{"email":"old@example.com","primary":false,"verified":true},{"email":"octo@example.com","primary":true,"verified":false}]`,
+	})
+	provider := newGitHub("client", "secret", "http://localhost/callback",
+		oauth2.Endpoint{AuthURL: server.URL + "/auth", TokenURL: server.URL + "/token"}, server.URL)
+
+	profile, err := provider.Exchange(context.Background(), "auth-code", oauth2.GenerateVerifier())
+
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, profile.Provider, ProviderGitHub)
+	testutil.AssertEqual(t, profile.Subject, "42")
+	testutil.AssertEqual(t, profile.Username, "octocat")
+	testutil.AssertEqual(t, profile.Email, "octo@example.com")
+	testutil.AssertFalse(t, profile.EmailVerified, "primary email is unverified")
+}
+
+func TestProvider_ExchangeInvalidCode(t *testing.T) {
+	server := newFakeProviderServer(t, nil)
+	provider := newGitHub("client", "secret", "http://localhost/callback",
+		oauth2.Endpoint{AuthURL: server.URL + "/auth", TokenURL: server.URL + "/token"}, server.URL)
+
+	_, err := provider.Exchange(context.Background(), "bad-code", oauth2.GenerateVerifier())
+
+	testutil.AssertErrorContains(t, err, "failed to exchange github authorization code")
+}
+
+func TestProvider_AuthCodeURL(t *testing.T) {
+	provider := NewGitHub("client-id", "secret", "http://localhost:8080/api/v1/auth/oauth/github/callback")
+
+	u, err := url.Parse(provider.AuthCodeURL("state-123", oauth2.GenerateVerifier()))
+	testutil.AssertNoError(t, err)
+
+	q := u.Query()
+	testutil.AssertEqual(t, q.Get("client_id"), "client-id")
+	testutil.AssertEqual(t, q.Get("state"), "state-123")
+	testutil.AssertEqual(t, q.Get("code_challenge_method"), "S256")
+	testutil.AssertNotEqual(t, q.Get("code_challenge"), "")
+}
+
+func TestRegistryFromConfig(t *testing.T) {
+	registry := RegistryFromConfig(&config.Config{
+		OAuthRedirectBaseURL: "https://chat.example.com/",
+		OAuthGitHubClientID:  "github-client",
+	})
+
+	testutil.AssertEqual(t, len(registry.Names()), 1)
+
+	github, err := registry.Get(ProviderGitHub)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, github.config.RedirectURL, "https://chat.example.com/api/v1/auth/oauth/github/callback")
+
+	_, err = registry.Get(ProviderGoogle)
+	testutil.AssertErrorIs(t, err, ErrUnknownProvider)
+}
@@ -4,19 +4,34 @@ import (
 	"context"
 	"log/slog"
 	"os"
+
+	"github.com/google/uuid"
 )
 
 type contextKey string
 
 const (
-	requestIDKey contextKey = "request_id"
-	userIDKey    contextKey = "user_id"
+	requestIDKey     contextKey = "request_id"
+	userIDKey        contextKey = "user_id"
+	correlationIDKey contextKey = "correlation_id"
 )
 
 var logger *slog.Logger
 
+// LoggerOptions configures the global logger beyond its level and format
+type LoggerOptions struct {
+	// Redact masks emails, tokens and passwords in log attributes, see
+	// NewRedactHandler
+	Redact bool
+}
+
 // InitLogger initializes the global structured logger
 func InitLogger(level, format string) {
+	InitLoggerWithOptions(level, format, LoggerOptions{})
+}
+
+// InitLoggerWithOptions initializes the global structured logger with opts
+func InitLoggerWithOptions(level, format string, loggerOpts LoggerOptions) {
 	var handler slog.Handler
 
 	opts := &slog.HandlerOptions{
@@ -29,6 +44,9 @@ func InitLogger(level, format string) {
 	} else {
 		handler = slog.NewTextHandler(os.Stdout, opts)
 	}
+	if loggerOpts.Redact {
+		handler = NewRedactHandler(handler)
+	}
 
 	logger = slog.New(handler)
 	slog.SetDefault(logger)
@@ -50,6 +68,9 @@ func FromContext(ctx context.Context) *slog.Logger {
 	if userID, ok := ctx.Value(userIDKey).(string); ok && userID != "" {
 		attrs = append(attrs, slog.String("user_id", userID))
 	}
+	if corrID := CorrelationID(ctx); corrID != "" {
+		attrs = append(attrs, slog.String("correlation_id", corrID))
+	}
 
 	if len(attrs) > 0 {
 		return logger.With(attrs...)
@@ -67,6 +88,36 @@ func WithUserID(ctx context.Context, userID string) context.Context {
 	return context.WithValue(ctx, userIDKey, userID)
 }
 
+// UserID returns the user ID stored in context, or "" if none
+func UserID(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey).(string)
+	return userID
+}
+
+// WithCorrelationID adds a correlation ID to context. The correlation ID
+// follows a single user action across process boundaries (HTTP/WebSocket ->
+// RabbitMQ -> stock bot -> RabbitMQ -> chat server).
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey, correlationID)
+}
+
+// CorrelationID returns the correlation ID stored in context, or "" if none
+func CorrelationID(ctx context.Context) string {
+	corrID, _ := ctx.Value(correlationIDKey).(string)
+	return corrID
+}
+
+// RequestID returns the request ID stored in context, or "" if none
+func RequestID(ctx context.Context) string {
+	reqID, _ := ctx.Value(requestIDKey).(string)
+	return reqID
+}
+
+// NewCorrelationID generates a new random correlation ID
+func NewCorrelationID() string {
+	return uuid.New().String()
+}
+
 // parseLevel converts string level to slog.Level
 func parseLevel(level string) slog.Level {
 	switch level {
@@ -81,6 +132,16 @@ func parseLevel(level string) slog.Level {
 	}
 }
 
+// Audit logs a security-relevant action, such as a moderation decision.
+// Audit records carry log_type=audit so log pipelines can route them to
+// long-term storage, along with the request and user IDs in ctx.
+func Audit(ctx context.Context, action string, args ...any) {
+	FromContext(ctx).With(
+		slog.String("log_type", "audit"),
+		slog.String("action", action),
+	).Info("audit event", args...)
+}
+
 // Info logs at info level
 func Info(msg string, args ...any) {
 	if logger != nil {
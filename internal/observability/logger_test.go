@@ -1,12 +1,15 @@
 package observability
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"log/slog"
 	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInitLogger_JSONFormat(t *testing.T) {
@@ -455,3 +458,41 @@ func TestInitLogger_AddSourceOption(t *testing.T) {
 		assert.NotNil(t, logger)
 	})
 }
+
+func TestCorrelationID(t *testing.T) {
+	t.Run("returns_empty_when_not_set", func(t *testing.T) {
+		assert.Equal(t, "", CorrelationID(context.Background()))
+	})
+
+	t.Run("returns_stored_value", func(t *testing.T) {
+		ctx := WithCorrelationID(context.Background(), "corr-123")
+		assert.Equal(t, "corr-123", CorrelationID(ctx))
+	})
+
+	t.Run("new_correlation_ids_are_unique", func(t *testing.T) {
+		assert.NotEqual(t, NewCorrelationID(), NewCorrelationID())
+	})
+
+	t.Run("request_id_accessor", func(t *testing.T) {
+		ctx := WithRequestID(context.Background(), "req-1")
+		assert.Equal(t, "req-1", RequestID(ctx))
+	})
+}
+
+func TestAudit(t *testing.T) {
+	savedLogger := logger
+	defer func() { logger = savedLogger }()
+
+	var buf bytes.Buffer
+	logger = slog.New(slog.NewJSONHandler(&buf, nil))
+
+	ctx := WithUserID(context.Background(), "mod-1")
+	Audit(ctx, "message_deleted", slog.String("message_id", "msg-1"))
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "audit", entry["log_type"])
+	assert.Equal(t, "message_deleted", entry["action"])
+	assert.Equal(t, "mod-1", entry["user_id"])
+	assert.Equal(t, "msg-1", entry["message_id"])
+}
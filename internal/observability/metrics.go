@@ -41,6 +41,36 @@ var (
 		[]string{"chatroom_id", "type"},
 	)
 
+	WebSocketDuplicateConnectionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "websocket_duplicate_connections_total",
+			Help: "Connections opened by a user already connected to the chatroom, by the policy applied",
+		},
+		[]string{"policy"},
+	)
+
+	WebSocketMalformedFramesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "websocket_malformed_frames_total",
+			Help: "Frames clients sent that were not valid JSON or of an unknown type, by error code",
+		},
+		[]string{"code"},
+	)
+
+	WebSocketConnectionsDegradedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "websocket_connections_degraded_total",
+			Help: "Connections warned that their send buffer filled past the slow client threshold",
+		},
+	)
+
+	WebSocketMalformedFrameClosesTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "websocket_malformed_frame_closes_total",
+			Help: "Connections closed for sending more malformed frames than their budget",
+		},
+	)
+
 	// Database metrics
 	DBQueryDuration = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -71,4 +101,125 @@ var (
 			Help: "Number of idle database connections",
 		},
 	)
+
+	// Quota metrics
+	QuotaRejectionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "quota_rejections_total",
+			Help: "Total number of actions rejected because a usage quota was reached",
+		},
+		[]string{"quota"},
+	)
+
+	// Service metrics
+	ServiceCallsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "service_calls_total",
+			Help: "Total number of service method calls by result (ok, rejected or error)",
+		},
+		[]string{"service", "method", "result"},
+	)
+
+	ServiceCallDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "service_call_duration_seconds",
+			Help:    "Service method latency in seconds",
+			Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		},
+		[]string{"service", "method"},
+	)
+
+	// Background job metrics
+	BackgroundJobRunsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "background_job_runs_total",
+			Help: "Total number of scheduled background job runs by result (ok, error or skipped on non-leader instances)",
+		},
+		[]string{"job", "result"},
+	)
+
+	BackgroundJobDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "background_job_duration_seconds",
+			Help:    "Background job run duration in seconds, including retries",
+			Buckets: []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300},
+		},
+		[]string{"job"},
+	)
+
+	BackgroundJobLastSuccess = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "background_job_last_success_timestamp_seconds",
+			Help: "Unix time of the last successful run of each background job",
+		},
+		[]string{"job"},
+	)
+
+	// Domain event metrics
+	DomainEventsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "domain_events_total",
+			Help: "Total number of domain events published, by event",
+		},
+		[]string{"event"},
+	)
+)
+
+var (
+	// Stock bot metrics
+	StockBotCommandsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "stock_bot_commands_total",
+			Help: "Total number of bot commands processed",
+		},
+		[]string{"type", "result"},
+	)
+
+	StockBotErrorsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "stock_bot_errors_total",
+			Help: "Total number of stock bot errors by processing stage",
+		},
+		[]string{"stage"},
+	)
+
+	StockBotCommandDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "stock_bot_command_duration_seconds",
+			Help:    "Time from receiving a bot command to publishing its response",
+			Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"type", "result"},
+	)
+
+	StockBotCommandsInFlight = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "stock_bot_commands_in_flight",
+			Help: "Number of bot commands currently being processed",
+		},
+	)
+
+	StooqRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "stooq_request_duration_seconds",
+			Help:    "Stooq quote lookup latency in seconds, including retries",
+			Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"outcome"},
+	)
+
+	PasswordHashesInFlight = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "password_hashes_in_flight",
+			Help: "Number of password hashes running on the hashing pool",
+		},
+	)
+
+	PasswordHashRejectedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "password_hash_rejected_total",
+			Help: "Password hashes rejected because the hashing pool was saturated",
+		},
+		[]string{"op", "reason"},
+	)
 )
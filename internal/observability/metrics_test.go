@@ -437,3 +437,18 @@ func TestPrometheusMetricTypes(t *testing.T) {
 		assert.NotNil(t, gauge)
 	})
 }
+
+func TestStockBotMetrics(t *testing.T) {
+	t.Run("metrics_are_registered", func(t *testing.T) {
+		assert.NotNil(t, StockBotCommandsTotal)
+		assert.NotNil(t, StockBotErrorsTotal)
+		assert.NotNil(t, StockBotCommandsInFlight)
+		assert.NotNil(t, StooqRequestDuration)
+	})
+
+	t.Run("metrics_accept_labels", func(t *testing.T) {
+		StockBotCommandsTotal.WithLabelValues("stock", "success").Inc()
+		StockBotErrorsTotal.WithLabelValues("fetch").Inc()
+		StooqRequestDuration.WithLabelValues("success").Observe(0.2)
+	})
+}
@@ -0,0 +1,254 @@
+package observability
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxLoggedQueryLength caps the statement text in slow-query logs and spans
+const maxLoggedQueryLength = 1000
+
+var (
+	queryTracer = otel.Tracer("jobsity-chat/database")
+
+	queryTableRegex = regexp.MustCompile(`(?i)\b(?:from|into|update)\s+"?([a-z_][a-z0-9_.]*)`)
+)
+
+// InstrumentConnector wraps a database connector so every query run through
+// it is timed in db_query_duration_seconds and traced as an OpenTelemetry
+// span. Queries slower than slowQueryThreshold are logged with their
+// statement and argument count; argument values are never logged. A zero
+// threshold disables slow-query logging.
+func InstrumentConnector(connector driver.Connector, slowQueryThreshold time.Duration) driver.Connector {
+	return &queryConnector{Connector: connector, slowThreshold: slowQueryThreshold}
+}
+
+type queryConnector struct {
+	driver.Connector
+	slowThreshold time.Duration
+}
+
+func (c *queryConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &queryConn{conn: conn, slowThreshold: c.slowThreshold}, nil
+}
+
+// queryConn forwards to the driver's connection, instrumenting direct
+// queries and the statements it prepares
+type queryConn struct {
+	conn          driver.Conn
+	slowThreshold time.Duration
+}
+
+func (c *queryConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *queryConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &queryStmt{stmt: stmt, query: query, slowThreshold: c.slowThreshold}, nil
+}
+
+func (c *queryConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *queryConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *queryConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != driver.IsolationLevel(0) || opts.ReadOnly {
+		return nil, errors.New("database driver does not support transaction options")
+	}
+	return c.conn.Begin()
+}
+
+func (c *queryConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	done := startQuery(ctx, query, len(args), c.slowThreshold)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	done(err)
+	return rows, err
+}
+
+func (c *queryConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	done := startQuery(ctx, query, len(args), c.slowThreshold)
+	result, err := execer.ExecContext(ctx, query, args)
+	done(err)
+	return result, err
+}
+
+func (c *queryConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *queryConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *queryConn) IsValid() bool {
+	if validator, ok := c.conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// queryStmt instruments each execution of a prepared statement
+type queryStmt struct {
+	stmt          driver.Stmt
+	query         string
+	slowThreshold time.Duration
+}
+
+func (s *queryStmt) Close() error {
+	return s.stmt.Close()
+}
+
+func (s *queryStmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+func (s *queryStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.stmt.Exec(args)
+}
+
+func (s *queryStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.stmt.Query(args)
+}
+
+func (s *queryStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	done := startQuery(ctx, s.query, len(args), s.slowThreshold)
+	var result driver.Result
+	var err error
+	if execer, ok := s.stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		result, err = s.stmt.Exec(namedValues(args))
+	}
+	done(err)
+	return result, err
+}
+
+func (s *queryStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	done := startQuery(ctx, s.query, len(args), s.slowThreshold)
+	var rows driver.Rows
+	var err error
+	if queryer, ok := s.stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		rows, err = s.stmt.Query(namedValues(args))
+	}
+	done(err)
+	return rows, err
+}
+
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+// startQuery opens a span for a query and returns the func that ends it,
+// records its duration and logs it when slow. Row scanning happens after
+// the func is called, so it is not included in the duration.
+func startQuery(ctx context.Context, query string, argCount int, slowThreshold time.Duration) func(error) {
+	operation, table := queryLabels(query)
+	statement := compactQuery(query)
+	_, span := queryTracer.Start(ctx, "db."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", operation),
+			attribute.String("db.sql.table", table),
+			attribute.String("db.statement", statement),
+		))
+	start := time.Now()
+
+	return func(err error) {
+		elapsed := time.Since(start)
+		defer span.End()
+		if errors.Is(err, driver.ErrSkip) {
+			return
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		DBQueryDuration.WithLabelValues(operation, table).Observe(elapsed.Seconds())
+		if slowThreshold > 0 && elapsed >= slowThreshold {
+			slog.WarnContext(ctx, "slow database query",
+				slog.String("operation", operation),
+				slog.String("table", table),
+				slog.Int64("duration_ms", elapsed.Milliseconds()),
+				slog.String("query", statement),
+				slog.Int("args", argCount))
+		}
+	}
+}
+
+// queryLabels returns the statement's leading keyword and first table, used
+// as metric labels. Statements without a table get "none".
+func queryLabels(query string) (operation, table string) {
+	fields := strings.Fields(query)
+	operation = "unknown"
+	if len(fields) > 0 {
+		operation = strings.ToLower(fields[0])
+	}
+	table = "none"
+	if match := queryTableRegex.FindStringSubmatch(query); match != nil {
+		table = strings.ToLower(match[1])
+	}
+	return operation, table
+}
+
+// compactQuery collapses a statement's whitespace for logging. Repositories
+// pass values as $n placeholders, so the text carries no parameters.
+func compactQuery(query string) string {
+	compact := strings.Join(strings.Fields(query), " ")
+	if len(compact) > maxLoggedQueryLength {
+		compact = compact[:maxLoggedQueryLength] + "..."
+	}
+	return compact
+}
@@ -0,0 +1,114 @@
+package observability
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dsnConnector adapts a registered driver to driver.Connector
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func newInstrumentedMock(t *testing.T, slowThreshold time.Duration) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	dsn := "instrumented_" + t.Name()
+	mockDB, mock, err := sqlmock.NewWithDSN(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	db := sql.OpenDB(InstrumentConnector(dsnConnector{dsn: dsn, driver: mockDB.Driver()}, slowThreshold))
+	t.Cleanup(func() { db.Close() })
+	return db, mock
+}
+
+func TestQueryLabels(t *testing.T) {
+	tests := []struct {
+		query     string
+		operation string
+		table     string
+	}{
+		{"SELECT id FROM users WHERE id = $1", "select", "users"},
+		{"\n\t\tINSERT INTO messages (content) VALUES ($1)", "insert", "messages"},
+		{"UPDATE chatroom_members SET last_read_at = NOW()", "update", "chatroom_members"},
+		{`DELETE FROM "sessions" WHERE expires_at <= $1`, "delete", "sessions"},
+		{"SELECT 1", "select", "none"},
+		{"", "unknown", "none"},
+	}
+
+	for _, tt := range tests {
+		operation, table := queryLabels(tt.query)
+		assert.Equal(t, tt.operation, operation, tt.query)
+		assert.Equal(t, tt.table, table, tt.query)
+	}
+}
+
+func TestInstrumentConnector_LogsSlowQueriesWithoutParameters(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	db, mock := newInstrumentedMock(t, time.Nanosecond)
+	mock.ExpectQuery("SELECT id FROM users").
+		WithArgs("alice@example.com").
+		WillDelayFor(time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("user-1"))
+
+	var id string
+	err := db.QueryRowContext(context.Background(), "SELECT id FROM users WHERE email = $1", "alice@example.com").Scan(&id)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", id)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	logged := buf.String()
+	assert.Contains(t, logged, "slow database query")
+	assert.Contains(t, logged, `"query":"SELECT id FROM users WHERE email = $1"`)
+	assert.Contains(t, logged, `"table":"users"`)
+	assert.Contains(t, logged, `"args":1`)
+	assert.NotContains(t, logged, "alice@example.com")
+}
+
+func TestInstrumentConnector_PreparedStatements(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	db, mock := newInstrumentedMock(t, time.Hour)
+	mock.ExpectPrepare("DELETE FROM sessions").
+		ExpectExec().
+		WithArgs("token").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	stmt, err := db.Prepare("DELETE FROM sessions WHERE token = $1")
+	require.NoError(t, err)
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(context.Background(), "token")
+	require.NoError(t, err)
+	affected, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Empty(t, buf.String(), "fast queries are not logged")
+}
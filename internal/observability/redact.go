@@ -0,0 +1,138 @@
+package observability
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces sensitive values in redacted logs
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values are always redacted. Keys
+// ending in _password, _secret, _token or _email are redacted too;
+// token_prefix and similar keys are kept since they only carry a
+// deliberately truncated value.
+var sensitiveKeys = map[string]bool{
+	"password":      true,
+	"passwd":        true,
+	"secret":        true,
+	"token":         true,
+	"ticket":        true,
+	"api_key":       true,
+	"apikey":        true,
+	"authorization": true,
+	"cookie":        true,
+	"set-cookie":    true,
+	"email":         true,
+	"private_key":   true,
+}
+
+var sensitiveSuffixes = []string{"_password", "_secret", "_token", "_email"}
+
+// redactPatterns rewrite secrets and emails embedded in string values, such
+// as a connection URL in an error. Credentials in URLs go first so their
+// user:password@host is not taken for an email address.
+var redactPatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+@`), "${1}" + Redacted + "@"},
+	{regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9\-._~+/]+=*`), "$1 " + Redacted},
+	{regexp.MustCompile(`(?i)\b((?:password|passwd|secret|token|access_token|ticket|api_key|apikey|code)=)[^&\s"']+`), "${1}" + Redacted},
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`), Redacted},
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), Redacted},
+}
+
+// redactHandler redacts emails, tokens and passwords from the attributes of
+// the records it passes on
+type redactHandler struct {
+	next slog.Handler
+}
+
+// NewRedactHandler wraps next so that attributes with sensitive keys are
+// replaced with Redacted, and emails, URL credentials, bearer tokens, JWTs
+// and secret query parameters are masked inside other string values. Log
+// messages are passed on as is: they must not carry data.
+func NewRedactHandler(next slog.Handler) slog.Handler {
+	return &redactHandler{next: next}
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redactAttr(a)
+	}
+	return &redactHandler{next: h.next.WithAttrs(redacted)}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name)}
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		group := a.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			redacted[i] = redactAttr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+	case slog.KindString:
+		if sensitiveKey(a.Key) {
+			return slog.String(a.Key, Redacted)
+		}
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	case slog.KindAny:
+		if sensitiveKey(a.Key) {
+			return slog.String(a.Key, Redacted)
+		}
+		if err, ok := a.Value.Any().(error); ok {
+			s := err.Error()
+			if redacted := RedactString(s); redacted != s {
+				return slog.String(a.Key, redacted)
+			}
+		}
+	}
+	// Numbers, booleans, times and durations cannot carry a secret
+	return a
+}
+
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	if sensitiveKeys[key] {
+		return true
+	}
+	for _, suffix := range sensitiveSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactString masks emails, URL credentials, bearer tokens, JWTs and secret
+// query parameters in s
+func RedactString(s string) string {
+	for _, p := range redactPatterns {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+	return s
+}
@@ -0,0 +1,93 @@
+package observability
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRedactedLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(NewRedactHandler(slog.NewJSONHandler(buf, nil)))
+}
+
+func decodeLogLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	return entry
+}
+
+func TestRedactHandler_SensitiveKeys(t *testing.T) {
+	var buf bytes.Buffer
+	newRedactedLogger(&buf).Info("user registered",
+		slog.String("email", "alice@example.com"),
+		slog.String("password", "hunter22"),
+		slog.String("session_token", "abc123"),
+		slog.Any("Authorization", []string{"Bearer abc"}),
+		slog.String("token_prefix", "abc1"),
+		slog.Bool("token_access", true),
+		slog.String("username", "alice"),
+	)
+
+	entry := decodeLogLine(t, &buf)
+	assert.Equal(t, Redacted, entry["email"])
+	assert.Equal(t, Redacted, entry["password"])
+	assert.Equal(t, Redacted, entry["session_token"])
+	assert.Equal(t, Redacted, entry["Authorization"])
+	assert.Equal(t, "abc1", entry["token_prefix"])
+	assert.Equal(t, true, entry["token_access"])
+	assert.Equal(t, "alice", entry["username"])
+	assert.Equal(t, "user registered", entry["msg"])
+}
+
+func TestRedactHandler_Patterns(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"email", "sent invite to bob@example.com", "sent invite to [REDACTED]"},
+		{"url_credentials", "dial postgres://app:s3cret@db:5432/chat failed", "dial postgres://app:[REDACTED]@db:5432/chat failed"},
+		{"bearer", "header Bearer eyJhbGciOi.payload.sig", "header Bearer [REDACTED]"},
+		{"jwt", "token eyJhbGciOi.eyJzdWIi.c2lnbmF0dXJl", "token [REDACTED]"},
+		{"query", "/ws/chat/room-1?ticket=abc&lang=en", "/ws/chat/room-1?ticket=[REDACTED]&lang=en"},
+		{"oauth_code", "/callback?code=4/0Ab&state=xyz", "/callback?code=[REDACTED]&state=xyz"},
+		{"stock_code", "stock_code=AAPL.US", "stock_code=AAPL.US"},
+		{"clean", "chatroom not found", "chatroom not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, RedactString(tt.value))
+		})
+	}
+}
+
+func TestRedactHandler_ErrorsGroupsAndWith(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newRedactedLogger(&buf).With(slog.String("email", "alice@example.com")).WithGroup("req")
+	logger.Info("request failed",
+		slog.Any("error", errors.New("user carol@example.com not found")),
+		slog.Group("auth", slog.String("token", "abc"), slog.Int("attempt", 2)),
+	)
+
+	entry := decodeLogLine(t, &buf)
+	assert.Equal(t, Redacted, entry["email"])
+	req := entry["req"].(map[string]any)
+	assert.Equal(t, "user [REDACTED] not found", req["error"])
+	auth := req["auth"].(map[string]any)
+	assert.Equal(t, Redacted, auth["token"])
+	assert.Equal(t, float64(2), auth["attempt"])
+}
+
+func TestRedactHandler_Enabled(t *testing.T) {
+	handler := NewRedactHandler(slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	assert.False(t, handler.Enabled(context.Background(), slog.LevelInfo))
+	assert.True(t, handler.Enabled(context.Background(), slog.LevelError))
+}
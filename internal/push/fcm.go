@@ -0,0 +1,230 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"jobsity-chat/internal/domain"
+)
+
+const (
+	fcmScope        = "https://www.googleapis.com/auth/firebase.messaging"
+	fcmSendURL      = "https://fcm.googleapis.com/v1/projects/%s/messages:send"
+	fcmTokenRefresh = time.Minute
+)
+
+// ServiceAccount is the part of a Google service account key file FCM needs
+type ServiceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// FCMProvider sends notifications through the Firebase Cloud Messaging HTTP
+// v1 API, authenticating as a service account
+type FCMProvider struct {
+	account ServiceAccount
+	key     *rsa.PrivateKey
+	sendURL string
+	client  *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCMProviderFromFile reads a service account key file downloaded from the
+// Firebase console
+func NewFCMProviderFromFile(path string) (*FCMProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FCM credentials: %w", err)
+	}
+	var account ServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("invalid FCM credentials: %w", err)
+	}
+	return NewFCMProvider(account)
+}
+
+func NewFCMProvider(account ServiceAccount) (*FCMProvider, error) {
+	if account.ProjectID == "" || account.ClientEmail == "" || account.TokenURI == "" {
+		return nil, errors.New("FCM credentials need project_id, client_email and token_uri")
+	}
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, errors.New("FCM credentials have no PEM private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FCM private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("FCM private key is not an RSA key")
+	}
+
+	return &FCMProvider{
+		account: account,
+		key:     key,
+		sendURL: fmt.Sprintf(fcmSendURL, url.PathEscape(account.ProjectID)),
+		client:  &http.Client{Timeout: providerTimeout},
+	}, nil
+}
+
+func (p *FCMProvider) ValidateToken(token string) error {
+	if strings.ContainsAny(token, " \t\r\n") {
+		return errors.New("FCM registration token must not contain whitespace")
+	}
+	return nil
+}
+
+type fcmMessage struct {
+	Message struct {
+		Token        string            `json:"token"`
+		Notification map[string]string `json:"notification"`
+		Data         map[string]string `json:"data"`
+		Android      struct {
+			Priority string `json:"priority"`
+			TTL      string `json:"ttl"`
+		} `json:"android"`
+	} `json:"message"`
+}
+
+func (p *FCMProvider) Send(ctx context.Context, token string, n *domain.PushNotification) error {
+	accessToken, err := p.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	var msg fcmMessage
+	msg.Message.Token = token
+	msg.Message.Notification = map[string]string{"title": n.Title, "body": n.Body}
+	msg.Message.Data = map[string]string{"chatroom_id": n.ChatroomID, "message_id": n.MessageID}
+	msg.Message.Android.Priority = "high"
+	msg.Message.Android.TTL = strconv.Itoa(int(notificationTTL.Seconds())) + "s"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode FCM message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.sendURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", defaultUserAgent)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send FCM message: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	switch {
+	// FCM answers 404 UNREGISTERED for tokens of uninstalled apps
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrDeviceGone
+	case resp.StatusCode == http.StatusUnauthorized:
+		p.mu.Lock()
+		p.accessToken = ""
+		p.mu.Unlock()
+		return errors.New("FCM rejected the access token")
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("FCM returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// token returns a cached OAuth access token, exchanging a signed JWT for a
+// new one when it is about to expire
+func (p *FCMProvider) token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.accessToken != "" && time.Now().Add(fcmTokenRefresh).Before(p.expiresAt) {
+		return p.accessToken, nil
+	}
+
+	assertion, err := p.assertion(time.Now())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get FCM access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("FCM token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode FCM access token: %w", err)
+	}
+	if result.AccessToken == "" {
+		return "", errors.New("FCM token endpoint returned no access token")
+	}
+
+	p.accessToken = result.AccessToken
+	p.expiresAt = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	return p.accessToken, nil
+}
+
+// assertion signs the RS256 JWT exchanged for an access token
+func (p *FCMProvider) assertion(now time.Time) (string, error) {
+	claims, err := json.Marshal(map[string]any{
+		"iss":   p.account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   p.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token claims: %w", err)
+	}
+
+	enc := base64.RawURLEncoding
+	signingInput := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token assertion: %w", err)
+	}
+	return signingInput + "." + enc.EncodeToString(signature), nil
+}
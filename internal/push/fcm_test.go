@@ -0,0 +1,82 @@
+package push
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func testServiceAccount(t *testing.T, tokenURI string) ServiceAccount {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	testutil.AssertNoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	testutil.AssertNoError(t, err)
+
+	return ServiceAccount{
+		ProjectID:   "chat-test",
+		ClientEmail: "push@chat-test.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    tokenURI,
+	}
+}
+
+func TestNewFCMProvider(t *testing.T) {
+	_, err := NewFCMProvider(ServiceAccount{ProjectID: "p", ClientEmail: "e", TokenURI: "https://t", PrivateKey: "nope"})
+	testutil.AssertError(t, err)
+	_, err = NewFCMProvider(ServiceAccount{})
+	testutil.AssertError(t, err)
+
+	provider, err := NewFCMProvider(testServiceAccount(t, "https://oauth2.googleapis.com/token"))
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, provider.sendURL, "https://fcm.googleapis.com/v1/projects/chat-test/messages:send")
+	testutil.AssertNoError(t, provider.ValidateToken("abc:APA91b"))
+	testutil.AssertError(t, provider.ValidateToken("abc def"))
+}
+
+func TestFCMProvider_Send(t *testing.T) {
+	var (
+		tokenRequests int
+		sent          fcmMessage
+		sendStatus    = http.StatusOK
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		testutil.AssertEqual(t, r.FormValue("grant_type"), "urn:ietf:params:oauth:grant-type:jwt-bearer")
+		testutil.AssertNotEqual(t, r.FormValue("assertion"), "")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"access-1","expires_in":3600}`))
+	})
+	mux.HandleFunc("/send", func(w http.ResponseWriter, r *http.Request) {
+		testutil.AssertEqual(t, r.Header.Get("Authorization"), "Bearer access-1")
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.WriteHeader(sendStatus)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	provider, err := NewFCMProvider(testServiceAccount(t, server.URL+"/token"))
+	testutil.AssertNoError(t, err)
+	provider.sendURL = server.URL + "/send"
+	ctx := context.Background()
+	notification := &domain.PushNotification{Title: "carol", Body: "hi @alice", ChatroomID: "room-1", MessageID: "msg-1"}
+
+	testutil.AssertNoError(t, provider.Send(ctx, "device-token", notification))
+	testutil.AssertEqual(t, sent.Message.Token, "device-token")
+	testutil.AssertEqual(t, sent.Message.Notification["body"], "hi @alice")
+	testutil.AssertEqual(t, sent.Message.Data["chatroom_id"], "room-1")
+
+	sendStatus = http.StatusNotFound
+	testutil.AssertErrorIs(t, provider.Send(ctx, "device-token", notification), ErrDeviceGone)
+	testutil.AssertEqual(t, tokenRequests, 1)
+}
@@ -0,0 +1,386 @@
+// Package push sends push notifications for chat messages to users who are
+// not connected. A message notifies the members it mentions by @username and,
+// in a two-member chatroom (a direct conversation), the other member. Devices
+// register a token for one of the configured providers: Web Push for browsers
+// or Firebase Cloud Messaging for mobile apps.
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"jobsity-chat/internal/config"
+	"jobsity-chat/internal/domain"
+)
+
+// ErrDeviceGone is returned by a Provider when the push service no longer
+// knows the token, so the device should be forgotten
+var ErrDeviceGone = errors.New("push device is no longer registered")
+
+const (
+	maxTokenLength   = 4096
+	maxBodyLength    = 200
+	maxMentions      = 20
+	maxUsernameLen   = 50
+	queueSize        = 256
+	messageTimeout   = 30 * time.Second
+	providerTimeout  = 10 * time.Second
+	notificationTTL  = 24 * time.Hour
+	deferredBatch    = 500
+	defaultUserAgent = "ChattorumuPush/1.0"
+
+	// encryptedBody replaces the content of encrypted messages
+	encryptedBody = "New encrypted message"
+)
+
+// Provider delivers notifications through one push service
+type Provider interface {
+	// ValidateToken rejects tokens the provider can never deliver to
+	ValidateToken(token string) error
+	// Send delivers n to the device holding token. It returns ErrDeviceGone
+	// when the push service reports the token as expired or unknown.
+	Send(ctx context.Context, token string, n *domain.PushNotification) error
+}
+
+// ProvidersFromConfig returns the configured providers keyed by platform and
+// the VAPID public key, empty unless Web Push is configured. No providers
+// means push notifications are disabled.
+func ProvidersFromConfig(cfg *config.Config) (map[string]Provider, string, error) {
+	providers := make(map[string]Provider)
+	var vapidPublicKey string
+
+	if cfg.PushVAPIDPrivateKey != "" {
+		webPush, err := NewWebPushProvider(cfg.PushVAPIDPrivateKey, cfg.PushVAPIDSubject)
+		if err != nil {
+			return nil, "", err
+		}
+		providers[domain.PushPlatformWebPush] = webPush
+		vapidPublicKey = webPush.PublicKey()
+	}
+	if cfg.PushFCMCredentialsFile != "" {
+		fcm, err := NewFCMProviderFromFile(cfg.PushFCMCredentialsFile)
+		if err != nil {
+			return nil, "", err
+		}
+		providers[domain.PushPlatformFCM] = fcm
+	}
+	return providers, vapidPublicKey, nil
+}
+
+// Presence reports who is connected and whose messages are hidden from others
+type Presence interface {
+	IsUserOnline(userID string) bool
+	IsShadowBanned(chatroomID, userID string) bool
+}
+
+// mentionPattern matches @username not preceded by a word character, so
+// e-mail addresses are not mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_@])@([A-Za-z0-9_]{3,})`)
+
+// ExtractMentions returns the distinct lowercased usernames mentioned in
+// content
+func ExtractMentions(content string) []string {
+	mentions := []string{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		username := strings.ToLower(match[1])
+		if len(username) > maxUsernameLen || slices.Contains(mentions, username) {
+			continue
+		}
+		mentions = append(mentions, username)
+		if len(mentions) == maxMentions {
+			break
+		}
+	}
+	return mentions
+}
+
+type job struct {
+	orgID string
+	msg   *domain.Message
+}
+
+// Notifier registers devices and notifies offline recipients of messages in
+// the background. Messages are queued by Enqueue and dropped when the queue
+// is full, so a burst of mentions never slows down chat. Notifications due
+// during a recipient's do-not-disturb window are stored and sent together by
+// DeliverDeferred once it ends.
+//
+// Presence is per instance: with several chat servers, a user connected to
+// another instance still gets notified.
+type Notifier struct {
+	repo      domain.PushDeviceRepository
+	presence  Presence
+	providers map[string]Provider
+	jobs      chan job
+	now       func() time.Time
+}
+
+// NewNotifier returns a Notifier delivering through providers, keyed by
+// platform (domain.PushPlatformWebPush, domain.PushPlatformFCM)
+func NewNotifier(repo domain.PushDeviceRepository, presence Presence, providers map[string]Provider) *Notifier {
+	return &Notifier{
+		repo:      repo,
+		presence:  presence,
+		providers: providers,
+		jobs:      make(chan job, queueSize),
+		now:       time.Now,
+	}
+}
+
+// Platforms returns the enabled platforms, sorted
+func (n *Notifier) Platforms() []string {
+	return slices.Sorted(maps.Keys(n.providers))
+}
+
+// RegisterDevice stores a device token for userID. It returns
+// domain.ErrInvalidInput for platforms that are not enabled and tokens the
+// provider rejects.
+func (n *Notifier) RegisterDevice(ctx context.Context, userID, platform, token string) (*domain.PushDevice, error) {
+	provider, ok := n.providers[platform]
+	if !ok {
+		return nil, fmt.Errorf("%w: push platform %q is not enabled", domain.ErrInvalidInput, platform)
+	}
+	token = strings.TrimSpace(token)
+	if token == "" || len(token) > maxTokenLength {
+		return nil, fmt.Errorf("%w: push token must be 1 to %d bytes", domain.ErrInvalidInput, maxTokenLength)
+	}
+	if err := provider.ValidateToken(token); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+	}
+
+	device := &domain.PushDevice{UserID: userID, Platform: platform, Token: token}
+	if err := n.repo.Register(ctx, device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// Devices lists the devices userID registered
+func (n *Notifier) Devices(ctx context.Context, userID string) ([]*domain.PushDevice, error) {
+	return n.repo.ListByUser(ctx, userID)
+}
+
+// RemoveDevice unregisters one of userID's devices
+func (n *Notifier) RemoveDevice(ctx context.Context, userID, deviceID string) error {
+	return n.repo.Delete(ctx, userID, deviceID)
+}
+
+// DNDSchedule returns userID's do-not-disturb schedule
+func (n *Notifier) DNDSchedule(ctx context.Context, userID string) (*domain.DNDSchedule, error) {
+	return n.repo.DNDSchedule(ctx, userID)
+}
+
+// SetDNDSchedule replaces userID's do-not-disturb schedule. It returns
+// domain.ErrInvalidInput for invalid schedules.
+func (n *Notifier) SetDNDSchedule(ctx context.Context, userID string, schedule *domain.DNDSchedule) error {
+	if schedule.Windows == nil {
+		schedule.Windows = []domain.DNDWindow{}
+	}
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+	return n.repo.SetDNDSchedule(ctx, userID, schedule)
+}
+
+// Enqueue queues msg so its recipients are notified. Bot messages are
+// skipped.
+func (n *Notifier) Enqueue(ctx context.Context, msg *domain.Message) {
+	if msg.IsBot {
+		return
+	}
+
+	select {
+	case n.jobs <- job{orgID: domain.OrgIDFromContext(ctx), msg: msg}:
+	default:
+		slog.Warn("push notification queue full, skipping message",
+			slog.String("message_id", msg.ID),
+			slog.String("chatroom_id", msg.ChatroomID))
+	}
+}
+
+// Run processes queued messages with the given number of goroutines until
+// ctx is cancelled
+func (n *Notifier) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-n.jobs:
+					n.process(ctx, j)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (n *Notifier) process(ctx context.Context, j job) {
+	// Nobody else sees a shadow-banned user's messages, so nobody is
+	// notified of them either
+	if n.presence.IsShadowBanned(j.msg.ChatroomID, j.msg.UserID) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(domain.WithOrgID(ctx, j.orgID), messageTimeout)
+	defer cancel()
+
+	// The server cannot read encrypted messages: only the other member of a
+	// direct conversation is notified, without the content
+	mentions, body := ExtractMentions(j.msg.Content), truncate(j.msg.Content, maxBodyLength)
+	if j.msg.Encrypted {
+		mentions, body = []string{}, encryptedBody
+	}
+
+	recipients, err := n.repo.Recipients(ctx, j.msg, mentions)
+	if err != nil {
+		slog.Error("failed to find push recipients",
+			slog.String("error", err.Error()),
+			slog.String("message_id", j.msg.ID))
+		return
+	}
+
+	notification := &domain.PushNotification{
+		Title:      j.msg.Username,
+		Body:       body,
+		ChatroomID: j.msg.ChatroomID,
+		MessageID:  j.msg.ID,
+	}
+	for _, userID := range recipients {
+		if n.presence.IsUserOnline(userID) {
+			continue
+		}
+		if n.deferDuringDND(ctx, userID, notification) {
+			continue
+		}
+		n.notifyUser(ctx, userID, notification)
+	}
+}
+
+// deferDuringDND stores notification for later when userID is in a
+// do-not-disturb window. If the schedule cannot be read, the notification is
+// sent right away rather than lost.
+func (n *Notifier) deferDuringDND(ctx context.Context, userID string, notification *domain.PushNotification) bool {
+	schedule, err := n.repo.DNDSchedule(ctx, userID)
+	if err != nil {
+		slog.Warn("failed to get do-not-disturb schedule",
+			slog.String("error", err.Error()),
+			slog.String("user_id", userID))
+		return false
+	}
+	until, ok := schedule.Until(n.now())
+	if !ok {
+		return false
+	}
+	if err := n.repo.Defer(ctx, userID, notification, until); err != nil {
+		slog.Warn("failed to defer push notification",
+			slog.String("error", err.Error()),
+			slog.String("user_id", userID))
+		return false
+	}
+	return true
+}
+
+// DeliverDeferred sends the notifications held back by do-not-disturb
+// windows that have ended. Each user gets one notification summing up what
+// they missed; users who have come online since are not notified at all.
+func (n *Notifier) DeliverDeferred(ctx context.Context) error {
+	for {
+		deferred, err := n.repo.ClaimDeferred(ctx, n.now(), deferredBatch)
+		if err != nil {
+			return err
+		}
+
+		type recipient struct{ orgID, userID string }
+		pending := make(map[recipient][]*domain.PushNotification)
+		var order []recipient
+		for _, d := range deferred {
+			key := recipient{orgID: d.OrgID, userID: d.UserID}
+			if _, ok := pending[key]; !ok {
+				order = append(order, key)
+			}
+			pending[key] = append(pending[key], &d.Notification)
+		}
+		for _, key := range order {
+			if n.presence.IsUserOnline(key.userID) {
+				continue
+			}
+			n.notifyUser(domain.WithOrgID(ctx, key.orgID), key.userID, summarize(pending[key]))
+		}
+
+		if len(deferred) < deferredBatch {
+			return nil
+		}
+	}
+}
+
+// summarize returns the notification for notifications held back together,
+// pointing at the latest one
+func summarize(notifications []*domain.PushNotification) *domain.PushNotification {
+	latest := notifications[len(notifications)-1]
+	if len(notifications) == 1 {
+		return latest
+	}
+	return &domain.PushNotification{
+		Title:      fmt.Sprintf("%d new messages", len(notifications)),
+		Body:       truncate("Latest from "+latest.Title+": "+latest.Body, maxBodyLength),
+		ChatroomID: latest.ChatroomID,
+		MessageID:  latest.MessageID,
+	}
+}
+
+func (n *Notifier) notifyUser(ctx context.Context, userID string, notification *domain.PushNotification) {
+	devices, err := n.repo.ListByUser(ctx, userID)
+	if err != nil {
+		slog.Error("failed to list push devices",
+			slog.String("error", err.Error()),
+			slog.String("user_id", userID))
+		return
+	}
+
+	for _, device := range devices {
+		provider, ok := n.providers[device.Platform]
+		if !ok {
+			continue
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, providerTimeout)
+		err := provider.Send(sendCtx, device.Token, notification)
+		cancel()
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrDeviceGone):
+			if err := n.repo.DeleteToken(ctx, device.Platform, device.Token); err != nil {
+				slog.Warn("failed to forget expired push device",
+					slog.String("error", err.Error()),
+					slog.String("device_id", device.ID))
+			}
+		default:
+			slog.Warn("failed to send push notification",
+				slog.String("error", err.Error()),
+				slog.String("platform", device.Platform),
+				slog.String("device_id", device.ID))
+		}
+	}
+}
+
+// truncate cuts s to at most n characters
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
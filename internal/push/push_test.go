@@ -0,0 +1,350 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/config"
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected []string
+	}{
+		{"no mentions", nil},
+		{"hey @Alice and @bob_2!", []string{"alice", "bob_2"}},
+		{"@alice,@ALICE @alice", []string{"alice"}},
+		{"mail me at bob@example.com", nil},
+		{"@al is too short", nil},
+		{"@" + strings.Repeat("a", 51), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			testutil.AssertEqual(t, strings.Join(ExtractMentions(tt.content), ","), strings.Join(tt.expected, ","))
+		})
+	}
+}
+
+type stubProvider struct {
+	mu   sync.Mutex
+	sent []string
+	gone map[string]bool
+}
+
+func (p *stubProvider) ValidateToken(token string) error {
+	if strings.HasPrefix(token, "bad") {
+		return errors.New("bad token")
+	}
+	return nil
+}
+
+func (p *stubProvider) Send(ctx context.Context, token string, n *domain.PushNotification) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.gone[token] {
+		return ErrDeviceGone
+	}
+	p.sent = append(p.sent, token+":"+n.Title+":"+n.Body)
+	return nil
+}
+
+type stubDeviceRepo struct {
+	mu         sync.Mutex
+	devices    map[string][]*domain.PushDevice
+	recipients []string
+	mentions   []string
+	deleted    []string
+	orgIDs     []string
+	schedules  map[string]*domain.DNDSchedule
+	deferred   []*domain.DeferredPush
+	done       chan struct{}
+}
+
+func (r *stubDeviceRepo) Register(ctx context.Context, device *domain.PushDevice) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	device.ID = "device-new"
+	r.devices[device.UserID] = append(r.devices[device.UserID], device)
+	return nil
+}
+
+func (r *stubDeviceRepo) ListByUser(ctx context.Context, userID string) ([]*domain.PushDevice, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.devices[userID], nil
+}
+
+func (r *stubDeviceRepo) Delete(ctx context.Context, userID, deviceID string) error {
+	return domain.ErrPushDeviceNotFound
+}
+
+func (r *stubDeviceRepo) DeleteToken(ctx context.Context, platform, token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deleted = append(r.deleted, token)
+	return nil
+}
+
+func (r *stubDeviceRepo) Recipients(ctx context.Context, msg *domain.Message, mentions []string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mentions = mentions
+	r.orgIDs = append(r.orgIDs, domain.OrgIDFromContext(ctx))
+	if r.done != nil {
+		defer close(r.done)
+	}
+	return r.recipients, nil
+}
+
+func (r *stubDeviceRepo) DNDSchedule(ctx context.Context, userID string) (*domain.DNDSchedule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.schedules[userID], nil
+}
+
+func (r *stubDeviceRepo) SetDNDSchedule(ctx context.Context, userID string, schedule *domain.DNDSchedule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.schedules == nil {
+		r.schedules = make(map[string]*domain.DNDSchedule)
+	}
+	r.schedules[userID] = schedule
+	return nil
+}
+
+func (r *stubDeviceRepo) Defer(ctx context.Context, userID string, n *domain.PushNotification, deliverAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deferred = append(r.deferred, &domain.DeferredPush{
+		OrgID:        domain.OrgIDFromContext(ctx),
+		UserID:       userID,
+		Notification: *n,
+		CreatedAt:    deliverAt,
+	})
+	return nil
+}
+
+// ClaimDeferred uses CreatedAt as the delivery time Defer was given
+func (r *stubDeviceRepo) ClaimDeferred(ctx context.Context, now time.Time, limit int) ([]*domain.DeferredPush, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var claimed, kept []*domain.DeferredPush
+	for _, d := range r.deferred {
+		if !d.CreatedAt.After(now) && len(claimed) < limit {
+			claimed = append(claimed, d)
+		} else {
+			kept = append(kept, d)
+		}
+	}
+	r.deferred = kept
+	return claimed, nil
+}
+
+type stubPresence struct {
+	online       map[string]bool
+	shadowBanned map[string]bool
+}
+
+func (p stubPresence) IsUserOnline(userID string) bool { return p.online[userID] }
+
+func (p stubPresence) IsShadowBanned(chatroomID, userID string) bool { return p.shadowBanned[userID] }
+
+func TestProvidersFromConfig(t *testing.T) {
+	providers, vapidPublicKey, err := ProvidersFromConfig(&config.Config{})
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, len(providers), 0)
+	testutil.AssertEqual(t, vapidPublicKey, "")
+
+	_, _, err = ProvidersFromConfig(&config.Config{PushVAPIDPrivateKey: testVAPIDKey(t)})
+	testutil.AssertError(t, err)
+	_, _, err = ProvidersFromConfig(&config.Config{PushFCMCredentialsFile: "/nonexistent/service-account.json"})
+	testutil.AssertError(t, err)
+
+	providers, vapidPublicKey, err = ProvidersFromConfig(&config.Config{
+		PushVAPIDPrivateKey: testVAPIDKey(t),
+		PushVAPIDSubject:    "mailto:ops@example.com",
+	})
+	testutil.AssertNoError(t, err)
+	testutil.AssertNotEqual(t, vapidPublicKey, "")
+	notifier := NewNotifier(&stubDeviceRepo{}, stubPresence{}, providers)
+	testutil.AssertEqual(t, strings.Join(notifier.Platforms(), ","), domain.PushPlatformWebPush)
+}
+
+func TestNotifier_RegisterDevice(t *testing.T) {
+	repo := &stubDeviceRepo{devices: make(map[string][]*domain.PushDevice)}
+	notifier := NewNotifier(repo, stubPresence{}, map[string]Provider{domain.PushPlatformFCM: &stubProvider{}})
+	ctx := context.Background()
+
+	device, err := notifier.RegisterDevice(ctx, "user-1", domain.PushPlatformFCM, "  token-1 ")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, device.ID, "device-new")
+	testutil.AssertEqual(t, device.Token, "token-1")
+
+	_, err = notifier.RegisterDevice(ctx, "user-1", domain.PushPlatformWebPush, "{}")
+	testutil.AssertErrorIs(t, err, domain.ErrInvalidInput)
+	_, err = notifier.RegisterDevice(ctx, "user-1", domain.PushPlatformFCM, "bad-token")
+	testutil.AssertErrorIs(t, err, domain.ErrInvalidInput)
+	_, err = notifier.RegisterDevice(ctx, "user-1", domain.PushPlatformFCM, strings.Repeat("x", maxTokenLength+1))
+	testutil.AssertErrorIs(t, err, domain.ErrInvalidInput)
+}
+
+func TestNotifier_NotifiesOfflineRecipients(t *testing.T) {
+	provider := &stubProvider{gone: map[string]bool{"expired": true}}
+	repo := &stubDeviceRepo{
+		devices: map[string][]*domain.PushDevice{
+			"offline": {
+				{ID: "d1", Platform: domain.PushPlatformFCM, Token: "phone"},
+				{ID: "d2", Platform: domain.PushPlatformFCM, Token: "expired"},
+				{ID: "d3", Platform: domain.PushPlatformWebPush, Token: "browser"},
+			},
+			"online": {{ID: "d4", Platform: domain.PushPlatformFCM, Token: "online-phone"}},
+		},
+		recipients: []string{"offline", "online"},
+		done:       make(chan struct{}),
+	}
+	presence := stubPresence{online: map[string]bool{"online": true}}
+	notifier := NewNotifier(repo, presence, map[string]Provider{domain.PushPlatformFCM: provider})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		notifier.Run(ctx, 1)
+		close(stopped)
+	}()
+
+	orgCtx := domain.WithOrgID(context.Background(), "org-1")
+	notifier.Enqueue(orgCtx, &domain.Message{ID: "bot", Content: "@offline", IsBot: true})
+	notifier.Enqueue(orgCtx, &domain.Message{ID: "msg-1", ChatroomID: "room-1", UserID: "sender", Username: "carol", Content: "hi @Offline"})
+
+	select {
+	case <-repo.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the message to be processed")
+	}
+	cancel()
+	<-stopped
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	testutil.AssertEqual(t, strings.Join(repo.mentions, ","), "offline")
+	testutil.AssertEqual(t, repo.orgIDs[0], "org-1")
+	testutil.AssertEqual(t, strings.Join(provider.sent, "|"), "phone:carol:hi @Offline")
+	testutil.AssertEqual(t, strings.Join(repo.deleted, ","), "expired")
+}
+
+func TestNotifier_SkipsShadowBannedSenders(t *testing.T) {
+	repo := &stubDeviceRepo{recipients: []string{"offline"}}
+	presence := stubPresence{shadowBanned: map[string]bool{"sender": true}}
+	notifier := NewNotifier(repo, presence, map[string]Provider{domain.PushPlatformFCM: &stubProvider{}})
+
+	notifier.process(context.Background(), job{msg: &domain.Message{ID: "msg-1", UserID: "sender", Content: "@offline"}})
+
+	testutil.AssertLen(t, repo.orgIDs, 0)
+}
+
+func TestNotifier_SetDNDSchedule(t *testing.T) {
+	repo := &stubDeviceRepo{}
+	notifier := NewNotifier(repo, stubPresence{}, nil)
+	ctx := context.Background()
+
+	err := notifier.SetDNDSchedule(ctx, "user-1", &domain.DNDSchedule{TimeZone: "Mars/Olympus"})
+	testutil.AssertErrorIs(t, err, domain.ErrInvalidInput)
+	err = notifier.SetDNDSchedule(ctx, "user-1", &domain.DNDSchedule{Windows: []domain.DNDWindow{{Start: "22:00", End: "22:00"}}})
+	testutil.AssertErrorIs(t, err, domain.ErrInvalidInput)
+	err = notifier.SetDNDSchedule(ctx, "user-1", &domain.DNDSchedule{Windows: []domain.DNDWindow{{Start: "9:00", End: "17:00"}}})
+	testutil.AssertErrorIs(t, err, domain.ErrInvalidInput)
+	err = notifier.SetDNDSchedule(ctx, "user-1", &domain.DNDSchedule{Windows: []domain.DNDWindow{{Start: "09:00", End: "17:00", Days: []string{"monday"}}}})
+	testutil.AssertErrorIs(t, err, domain.ErrInvalidInput)
+
+	err = notifier.SetDNDSchedule(ctx, "user-1", &domain.DNDSchedule{})
+	testutil.AssertNoError(t, err)
+	schedule, err := notifier.DNDSchedule(ctx, "user-1")
+	testutil.AssertNoError(t, err)
+	testutil.AssertLen(t, schedule.Windows, 0)
+}
+
+func TestDNDSchedule_Until(t *testing.T) {
+	bogota, err := time.LoadLocation("America/Bogota")
+	testutil.AssertNoError(t, err)
+	schedule := &domain.DNDSchedule{
+		TimeZone: "America/Bogota",
+		Windows: []domain.DNDWindow{
+			{Start: "22:00", End: "07:00"},
+			{Start: "07:00", End: "08:30", Days: []string{"sat", "sun"}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		active bool
+		until  time.Time
+	}{
+		{"before the window", time.Date(2026, 3, 4, 21, 59, 0, 0, bogota), false, time.Time{}},
+		{"evening of a weekday", time.Date(2026, 3, 4, 23, 0, 0, 0, bogota), true, time.Date(2026, 3, 5, 7, 0, 0, 0, bogota)},
+		{"after midnight", time.Date(2026, 3, 5, 3, 0, 0, 0, bogota), true, time.Date(2026, 3, 5, 7, 0, 0, 0, bogota)},
+		{"friday night runs into the weekend window", time.Date(2026, 3, 6, 23, 0, 0, 0, bogota), true, time.Date(2026, 3, 7, 8, 30, 0, 0, bogota)},
+		{"in UTC", time.Date(2026, 3, 5, 4, 0, 0, 0, time.UTC), true, time.Date(2026, 3, 5, 7, 0, 0, 0, bogota)},
+		{"after the window", time.Date(2026, 3, 5, 7, 0, 0, 0, bogota), false, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, active := schedule.Until(tt.at)
+			testutil.AssertEqual(t, active, tt.active)
+			testutil.AssertTrue(t, until.Equal(tt.until), "unexpected end of do-not-disturb: "+until.String())
+		})
+	}
+
+	var none *domain.DNDSchedule
+	_, active := none.Until(time.Now())
+	testutil.AssertFalse(t, active, "a missing schedule is never active")
+}
+
+func TestNotifier_DefersDuringDND(t *testing.T) {
+	provider := &stubProvider{}
+	repo := &stubDeviceRepo{
+		devices: map[string][]*domain.PushDevice{
+			"sleeper":  {{ID: "d1", Platform: domain.PushPlatformFCM, Token: "sleeper-phone"}},
+			"returned": {{ID: "d2", Platform: domain.PushPlatformFCM, Token: "returned-phone"}},
+			"awake":    {{ID: "d3", Platform: domain.PushPlatformFCM, Token: "awake-phone"}},
+		},
+		recipients: []string{"sleeper", "returned", "awake"},
+		schedules: map[string]*domain.DNDSchedule{
+			"sleeper":  {Windows: []domain.DNDWindow{{Start: "22:00", End: "07:00"}}},
+			"returned": {Windows: []domain.DNDWindow{{Start: "22:00", End: "07:00"}}},
+		},
+	}
+	presence := stubPresence{online: map[string]bool{}}
+	notifier := NewNotifier(repo, presence, map[string]Provider{domain.PushPlatformFCM: provider})
+	notifier.now = func() time.Time { return time.Date(2026, 3, 4, 23, 0, 0, 0, time.UTC) }
+
+	orgID := "org-1"
+	for _, msg := range []*domain.Message{
+		{ID: "msg-1", ChatroomID: "room-1", UserID: "sender", Username: "carol", Content: "first"},
+		{ID: "msg-2", ChatroomID: "room-2", UserID: "sender", Username: "dave", Content: "second"},
+	} {
+		notifier.process(context.Background(), job{orgID: orgID, msg: msg})
+	}
+	testutil.AssertEqual(t, strings.Join(provider.sent, "|"), "awake-phone:carol:first|awake-phone:dave:second")
+	testutil.AssertLen(t, repo.deferred, 4)
+	testutil.AssertEqual(t, repo.deferred[0].OrgID, orgID)
+
+	// Nothing is due before the window ends
+	testutil.AssertNoError(t, notifier.DeliverDeferred(context.Background()))
+	testutil.AssertLen(t, provider.sent, 2)
+
+	presence.online["returned"] = true
+	notifier.now = func() time.Time { return time.Date(2026, 3, 5, 7, 0, 0, 0, time.UTC) }
+	testutil.AssertNoError(t, notifier.DeliverDeferred(context.Background()))
+	testutil.AssertLen(t, repo.deferred, 0)
+	testutil.AssertEqual(t, provider.sent[2], "sleeper-phone:2 new messages:Latest from dave: second")
+	testutil.AssertLen(t, provider.sent, 3)
+}
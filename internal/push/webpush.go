@@ -0,0 +1,261 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"jobsity-chat/internal/domain"
+)
+
+const (
+	vapidTokenLifetime = 12 * time.Hour
+	recordSize         = 4096
+)
+
+// Subscription is a browser PushSubscription as serialized by
+// PushSubscription.toJSON(); it is the token of a Web Push device
+type Subscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// WebPushProvider sends encrypted Web Push messages (RFC 8030, RFC 8291)
+// authenticated with VAPID (RFC 8292)
+type WebPushProvider struct {
+	key     *ecdsa.PrivateKey
+	subject string
+	client  *http.Client
+}
+
+// NewWebPushProvider creates a provider from the base64url-encoded VAPID
+// private key (the raw P-256 scalar, as printed by web-push
+// generate-vapid-keys) and the subject, a mailto: or https: URL the push
+// service can contact about the traffic
+func NewWebPushProvider(privateKey, subject string) (*WebPushProvider, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	ecdhKey, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	if u, err := url.Parse(subject); err != nil || (u.Scheme != "mailto" && u.Scheme != "https") {
+		return nil, fmt.Errorf("VAPID subject must be a mailto: or https: URL, got %q", subject)
+	}
+
+	// The uncompressed public key is 0x04 || X || Y
+	pub := ecdhKey.PublicKey().Bytes()
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(pub[1:33]),
+			Y:     new(big.Int).SetBytes(pub[33:]),
+		},
+		D: new(big.Int).SetBytes(raw),
+	}
+	return &WebPushProvider{
+		key:     key,
+		subject: subject,
+		client:  &http.Client{Timeout: providerTimeout},
+	}, nil
+}
+
+// PublicKey returns the base64url-encoded VAPID public key browsers pass as
+// applicationServerKey when subscribing
+func (p *WebPushProvider) PublicKey() string {
+	return base64.RawURLEncoding.EncodeToString(p.publicKeyBytes())
+}
+
+func (p *WebPushProvider) publicKeyBytes() []byte {
+	pub := make([]byte, 65)
+	pub[0] = 4
+	p.key.X.FillBytes(pub[1:33])
+	p.key.Y.FillBytes(pub[33:])
+	return pub
+}
+
+func parseSubscription(token string) (*Subscription, *ecdh.PublicKey, []byte, error) {
+	var sub Subscription
+	if err := json.Unmarshal([]byte(token), &sub); err != nil {
+		return nil, nil, nil, fmt.Errorf("subscription is not valid JSON: %w", err)
+	}
+	endpoint, err := url.Parse(sub.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		return nil, nil, nil, errors.New("subscription endpoint must be an https URL")
+	}
+	p256dh, err := base64.RawURLEncoding.DecodeString(trimPadding(sub.Keys.P256dh))
+	if err != nil {
+		return nil, nil, nil, errors.New("subscription p256dh key is not base64url")
+	}
+	userKey, err := ecdh.P256().NewPublicKey(p256dh)
+	if err != nil {
+		return nil, nil, nil, errors.New("subscription p256dh key is not a P-256 point")
+	}
+	auth, err := base64.RawURLEncoding.DecodeString(trimPadding(sub.Keys.Auth))
+	if err != nil || len(auth) != 16 {
+		return nil, nil, nil, errors.New("subscription auth secret must be 16 bytes")
+	}
+	return &sub, userKey, auth, nil
+}
+
+func trimPadding(s string) string {
+	for len(s) > 0 && s[len(s)-1] == '=' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+func (p *WebPushProvider) ValidateToken(token string) error {
+	_, _, _, err := parseSubscription(token)
+	return err
+}
+
+func (p *WebPushProvider) Send(ctx context.Context, token string, n *domain.PushNotification) error {
+	sub, userKey, auth, err := parseSubscription(token)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	body, err := encrypt(payload, userKey, auth)
+	if err != nil {
+		return err
+	}
+	authorization, err := p.vapidAuthorization(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(notificationTTL.Seconds())))
+	req.Header.Set("Urgency", "high")
+	req.Header.Set("User-Agent", defaultUserAgent)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send web push: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrDeviceGone
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("web push service returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// vapidAuthorization signs an ES256 JWT for the endpoint's origin
+func (p *WebPushProvider) vapidAuthorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint: %w", err)
+	}
+	claims, err := json.Marshal(map[string]any{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(vapidTokenLifetime).Unix(),
+		"sub": p.subject,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode VAPID claims: %w", err)
+	}
+
+	enc := base64.RawURLEncoding
+	signingInput := enc.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`)) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, p.key, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign VAPID token: %w", err)
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	jwt := signingInput + "." + enc.EncodeToString(signature)
+	return "vapid t=" + jwt + ", k=" + p.PublicKey(), nil
+}
+
+// encrypt encodes payload as a single aes128gcm record (RFC 8188) keyed for
+// the subscriber as described in RFC 8291
+func encrypt(payload []byte, userKey *ecdh.PublicKey, auth []byte) ([]byte, error) {
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	sharedSecret, err := serverKey.ECDH(userKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive shared secret: %w", err)
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	serverPub := serverKey.PublicKey().Bytes()
+	keyInfo := "WebPush: info\x00" + string(userKey.Bytes()) + string(serverPub)
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, auth, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Header: salt || record size || key id length || key id (our public key)
+	header := make([]byte, 0, 16+4+1+len(serverPub))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(serverPub)))
+	header = append(header, serverPub...)
+
+	// 0x02 marks the last (and only) record
+	plaintext := append(payload, 0x02)
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
@@ -0,0 +1,148 @@
+package push
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+// testVAPIDKey returns a fresh base64url VAPID private key
+func testVAPIDKey(t *testing.T) string {
+	t.Helper()
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	testutil.AssertNoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(key.Bytes())
+}
+
+func testSubscription(t *testing.T, endpoint string) (string, *ecdh.PrivateKey, []byte) {
+	t.Helper()
+	userKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	testutil.AssertNoError(t, err)
+	auth := make([]byte, 16)
+	rand.Read(auth)
+
+	var sub Subscription
+	sub.Endpoint = endpoint
+	sub.Keys.P256dh = base64.RawURLEncoding.EncodeToString(userKey.PublicKey().Bytes())
+	sub.Keys.Auth = base64.URLEncoding.EncodeToString(auth)
+	token, err := json.Marshal(sub)
+	testutil.AssertNoError(t, err)
+	return string(token), userKey, auth
+}
+
+// decrypt reverses encrypt the way a browser does
+func decrypt(t *testing.T, body []byte, userKey *ecdh.PrivateKey, auth []byte) []byte {
+	t.Helper()
+	salt, rs, idLen := body[:16], binary.BigEndian.Uint32(body[16:20]), int(body[20])
+	testutil.AssertEqual(t, rs, uint32(recordSize))
+	serverPub, ciphertext := body[21:21+idLen], body[21+idLen:]
+
+	serverKey, err := ecdh.P256().NewPublicKey(serverPub)
+	testutil.AssertNoError(t, err)
+	secret, err := userKey.ECDH(serverKey)
+	testutil.AssertNoError(t, err)
+
+	ikm, _ := hkdf.Key(sha256.New, secret, auth, "WebPush: info\x00"+string(userKey.PublicKey().Bytes())+string(serverPub), 32)
+	cek, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, plaintext[len(plaintext)-1], byte(0x02))
+	return plaintext[:len(plaintext)-1]
+}
+
+func TestNewWebPushProvider(t *testing.T) {
+	_, err := NewWebPushProvider("not base64!", "mailto:ops@example.com")
+	testutil.AssertError(t, err)
+	_, err = NewWebPushProvider(testVAPIDKey(t), "ops@example.com")
+	testutil.AssertError(t, err)
+
+	provider, err := NewWebPushProvider(testVAPIDKey(t), "mailto:ops@example.com")
+	testutil.AssertNoError(t, err)
+	pub, err := base64.RawURLEncoding.DecodeString(provider.PublicKey())
+	testutil.AssertNoError(t, err)
+	testutil.AssertLen(t, pub, 65)
+}
+
+func TestWebPushProvider_ValidateToken(t *testing.T) {
+	provider, err := NewWebPushProvider(testVAPIDKey(t), "mailto:ops@example.com")
+	testutil.AssertNoError(t, err)
+
+	valid, _, _ := testSubscription(t, "https://push.example.com/send/abc")
+	testutil.AssertNoError(t, provider.ValidateToken(valid))
+
+	insecure, _, _ := testSubscription(t, "http://push.example.com/send/abc")
+	testutil.AssertError(t, provider.ValidateToken(insecure))
+	testutil.AssertError(t, provider.ValidateToken("not json"))
+	testutil.AssertError(t, provider.ValidateToken(`{"endpoint":"https://push.example.com","keys":{"p256dh":"AAAA","auth":"AAAA"}}`))
+}
+
+func TestWebPushProvider_Send(t *testing.T) {
+	var (
+		received      []byte
+		authorization string
+		status        = http.StatusCreated
+	)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testutil.AssertEqual(t, r.Header.Get("Content-Encoding"), "aes128gcm")
+		testutil.AssertEqual(t, r.Header.Get("TTL"), "86400")
+		authorization = r.Header.Get("Authorization")
+		received, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	provider, err := NewWebPushProvider(testVAPIDKey(t), "mailto:ops@example.com")
+	testutil.AssertNoError(t, err)
+	provider.client = server.Client()
+
+	token, userKey, auth := testSubscription(t, server.URL+"/send/abc")
+	notification := &domain.PushNotification{Title: "carol", Body: "hi @alice", ChatroomID: "room-1", MessageID: "msg-1"}
+	testutil.AssertNoError(t, provider.Send(context.Background(), token, notification))
+
+	var got domain.PushNotification
+	testutil.AssertNoError(t, json.Unmarshal(decrypt(t, received, userKey, auth), &got))
+	testutil.AssertEqual(t, got, *notification)
+
+	// The VAPID JWT is signed by the key advertised in k=
+	jwt, publicKey, ok := strings.Cut(strings.TrimPrefix(authorization, "vapid t="), ", k=")
+	testutil.AssertTrue(t, ok, "authorization has t= and k=")
+	testutil.AssertEqual(t, publicKey, provider.PublicKey())
+	parts := strings.Split(jwt, ".")
+	testutil.AssertLen(t, parts, 3)
+	var claims map[string]any
+	claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	testutil.AssertNoError(t, json.Unmarshal(claimsJSON, &claims))
+	testutil.AssertEqual(t, claims["aud"], any(server.URL))
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	testutil.AssertTrue(t, ecdsa.Verify(&provider.key.PublicKey, digest[:], r, s), "VAPID signature verifies")
+
+	status = http.StatusGone
+	testutil.AssertErrorIs(t, provider.Send(context.Background(), token, notification), ErrDeviceGone)
+
+	status = http.StatusTooManyRequests
+	err = provider.Send(context.Background(), token, notification)
+	testutil.AssertError(t, err)
+	testutil.AssertFalse(t, err == ErrDeviceGone, "rate limiting does not forget the device")
+}
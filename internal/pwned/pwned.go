@@ -0,0 +1,82 @@
+// Package pwned checks passwords against the Have I Been Pwned Pwned
+// Passwords API. Only the first five hex digits of a password's SHA-1 hash
+// leave the server (k-anonymity): the API returns every breached hash with
+// that prefix and the match is done locally.
+package pwned
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultURL = "https://api.pwnedpasswords.com/range/"
+
+	userAgent    = "Chattorumu/1.0 (+password check)"
+	maxBodyBytes = 1 << 20
+)
+
+// Client queries the Pwned Passwords range API
+type Client struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewClient returns a client of the range API at apiURL, to which the hash
+// prefix is appended
+func NewClient(apiURL string) *Client {
+	return &Client{
+		url:        apiURL,
+		httpClient: &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+// Count returns how many times password appears in known breaches, 0 if
+// never
+func (c *Client) Count(ctx context.Context, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+prefix, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create pwned passwords request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	// Padding hides the real number of matches from on-path observers
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("pwned passwords request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("pwned passwords returned status %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, maxBodyBytes))
+	for scanner.Scan() {
+		candidate, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return 0, fmt.Errorf("invalid pwned passwords count %q", count)
+		}
+		return n, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read pwned passwords response: %w", err)
+	}
+	return 0, nil
+}
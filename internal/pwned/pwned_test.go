@@ -0,0 +1,53 @@
+package pwned
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+func newServer(t *testing.T, status int, body string) (*Client, *string) {
+	t.Helper()
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if r.Header.Get("Add-Padding") != "true" {
+			t.Error("request without Add-Padding")
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return NewClient(srv.URL + "/range/"), &path
+}
+
+func TestClient_Count(t *testing.T) {
+	c, path := newServer(t, http.StatusOK,
+		"0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\r\n1F2B668E8AABEF1C59E9EC6F82E3F3CD786:0\r\n")
+
+	n, err := c.Count(context.Background(), "password")
+	if err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	if n != 3861493 {
+		t.Errorf("Count() = %d, want 3861493", n)
+	}
+	if *path != "/range/5BAA6" {
+		t.Errorf("path = %q, only the hash prefix should be sent", *path)
+	}
+
+	n, err = c.Count(context.Background(), "correct horse battery staple")
+	if err != nil || n != 0 {
+		t.Errorf("Count() of an unlisted password = %d, %v; want 0", n, err)
+	}
+}
+
+func TestClient_CountError(t *testing.T) {
+	c, _ := newServer(t, http.StatusServiceUnavailable, "")
+	if _, err := c.Count(context.Background(), "password"); err == nil {
+		t.Error("Count() expected error on a failed request")
+	}
+}
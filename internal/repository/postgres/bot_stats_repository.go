@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"jobsity-chat/internal/domain"
+)
+
+type BotStatsRepository struct {
+	db         *sql.DB
+	recordStmt *sql.Stmt
+	statsStmt  *sql.Stmt
+}
+
+// NewBotStatsRepository creates a new BotStatsRepository with prepared statements.
+// Returns an error if statement preparation fails.
+func NewBotStatsRepository(db *sql.DB) (*BotStatsRepository, error) {
+	repo := &BotStatsRepository{db: db}
+
+	var err error
+	// Bot responses carry no tenant, so the organization is the chatroom's
+	repo.recordStmt, err = db.Prepare(`
+		INSERT INTO bot_command_usage (org_id, chatroom_id, command_type, symbol, requested_by, latency_ms, success)
+		SELECT org_id, id, $2, $3, $4, $5, $6 FROM chatrooms WHERE id = $1
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare record statement: %w", err)
+	}
+
+	repo.statsStmt, err = db.Prepare(`
+		SELECT command_type, symbol, COUNT(*), COUNT(*) FILTER (WHERE NOT success),
+			AVG(latency_ms), PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY latency_ms)
+		FROM bot_command_usage
+		WHERE org_id = $1 AND created_at >= $2
+		GROUP BY command_type, symbol
+		ORDER BY COUNT(*) DESC, command_type, symbol
+		LIMIT 100
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare stats statement: %w", err)
+	}
+
+	return repo, nil
+}
+
+// Record stores usage; commands for chatrooms that no longer exist are dropped
+func (r *BotStatsRepository) Record(ctx context.Context, usage *domain.BotCommandUsage) error {
+	_, err := stmt(ctx, r.recordStmt).ExecContext(ctx,
+		usage.ChatroomID,
+		usage.CommandType,
+		usage.Symbol,
+		usage.RequestedBy,
+		usage.Latency.Milliseconds(),
+		usage.Success,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record bot command usage: %w", err)
+	}
+	return nil
+}
+
+func (r *BotStatsRepository) Stats(ctx context.Context, since time.Time) ([]domain.BotCommandStats, error) {
+	rows, err := stmt(ctx, r.statsStmt).QueryContext(ctx, domain.OrgIDFromContext(ctx), since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bot stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make([]domain.BotCommandStats, 0)
+	for rows.Next() {
+		var s domain.BotCommandStats
+		if err := rows.Scan(&s.CommandType, &s.Symbol, &s.Count, &s.Errors, &s.AvgLatencyMS, &s.P95LatencyMS); err != nil {
+			return nil, fmt.Errorf("failed to scan bot stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bot stats: %w", err)
+	}
+	return stats, nil
+}
+
+func (r *BotStatsRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM bot_command_usage WHERE created_at < $1`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete bot command usage: %w", err)
+	}
+	return result.RowsAffected()
+}
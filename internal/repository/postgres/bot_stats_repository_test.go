@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	botStatsRecordQuery = `
+		INSERT INTO bot_command_usage (org_id, chatroom_id, command_type, symbol, requested_by, latency_ms, success)
+		SELECT org_id, id, $2, $3, $4, $5, $6 FROM chatrooms WHERE id = $1
+	`
+	botStatsQuery = `
+		SELECT command_type, symbol, COUNT(*), COUNT(*) FILTER (WHERE NOT success),
+			AVG(latency_ms), PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY latency_ms)
+		FROM bot_command_usage
+		WHERE org_id = $1 AND created_at >= $2
+		GROUP BY command_type, symbol
+		ORDER BY COUNT(*) DESC, command_type, symbol
+		LIMIT 100
+	`
+)
+
+func TestBotStatsRepository_Record(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupBotStatsRepositoryMocks(mock)
+
+	repo, err := NewBotStatsRepository(db)
+	require.NoError(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta(botStatsRecordQuery)).
+		WithArgs("room-1", "stock", "AAPL.US", "alice", int64(250), true).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = repo.Record(context.Background(), &domain.BotCommandUsage{
+		CommandType: "stock",
+		Symbol:      "AAPL.US",
+		ChatroomID:  "room-1",
+		RequestedBy: "alice",
+		Latency:     250 * time.Millisecond,
+		Success:     true,
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBotStatsRepository_Stats(t *testing.T) {
+	t.Run("aggregates", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupBotStatsRepositoryMocks(mock)
+
+		repo, err := NewBotStatsRepository(db)
+		require.NoError(t, err)
+
+		since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery(regexp.QuoteMeta(botStatsQuery)).
+			WithArgs("org-2", since).
+			WillReturnRows(sqlmock.NewRows([]string{"command_type", "symbol", "count", "errors", "avg", "p95"}).
+				AddRow("stock", "AAPL.US", 10, 1, 120.5, 300.0).
+				AddRow("hello", "zen", 4, 0, 2.0, 3.0))
+
+		stats, err := repo.Stats(domain.WithOrgID(context.Background(), "org-2"), since)
+		require.NoError(t, err)
+		require.Len(t, stats, 2)
+		assert.Equal(t, domain.BotCommandStats{
+			CommandType:  "stock",
+			Symbol:       "AAPL.US",
+			Count:        10,
+			Errors:       1,
+			AvgLatencyMS: 120.5,
+			P95LatencyMS: 300,
+		}, stats[0])
+		assert.Equal(t, "hello", stats[1].CommandType)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database_error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupBotStatsRepositoryMocks(mock)
+
+		repo, err := NewBotStatsRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(botStatsQuery)).
+			WillReturnError(errors.New("database error"))
+
+		_, err = repo.Stats(context.Background(), time.Now())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to query bot stats")
+	})
+}
+
+func TestBotStatsRepository_DeleteBefore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupBotStatsRepositoryMocks(mock)
+
+	repo, err := NewBotStatsRepository(db)
+	require.NoError(t, err)
+
+	before := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`DELETE FROM bot_command_usage WHERE created_at < \$1`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 7))
+
+	deleted, err := repo.DeleteBefore(context.Background(), before)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func setupBotStatsRepositoryMocks(mock sqlmock.Sqlmock) {
+	mock.ExpectPrepare(regexp.QuoteMeta(botStatsRecordQuery))
+	mock.ExpectPrepare(regexp.QuoteMeta(botStatsQuery))
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	"jobsity-chat/internal/domain"
+
+	"github.com/lib/pq"
 )
 
 type ChatroomRepository struct {
@@ -27,8 +31,8 @@ func NewChatroomRepository(db *sql.DB) (*ChatroomRepository, error) {
 
 	var err error
 	repo.createStmt, err = db.Prepare(`
-		INSERT INTO chatrooms (name, created_by)
-		VALUES ($1, $2)
+		INSERT INTO chatrooms (org_id, name, created_by, encrypted)
+		VALUES ($1, $2, $3, $4)
 		RETURNING id, created_at
 	`)
 	if err != nil {
@@ -36,9 +40,9 @@ func NewChatroomRepository(db *sql.DB) (*ChatroomRepository, error) {
 	}
 
 	repo.getByIDStmt, err = db.Prepare(`
-		SELECT id, name, created_at, created_by
+		SELECT id, name, created_at, created_by, encrypted
 		FROM chatrooms
-		WHERE id = $1
+		WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare getByID statement: %w", err)
@@ -55,8 +59,9 @@ func NewChatroomRepository(db *sql.DB) (*ChatroomRepository, error) {
 
 	repo.isMemberStmt, err = db.Prepare(`
 		SELECT EXISTS(
-			SELECT 1 FROM chatroom_members
-			WHERE chatroom_id = $1 AND user_id = $2
+			SELECT 1 FROM chatroom_members cm
+			JOIN chatrooms c ON c.id = cm.chatroom_id
+			WHERE cm.chatroom_id = $1 AND cm.user_id = $2 AND c.org_id = $3 AND c.deleted_at IS NULL
 		)
 	`)
 	if err != nil {
@@ -67,24 +72,29 @@ func NewChatroomRepository(db *sql.DB) (*ChatroomRepository, error) {
 }
 
 func (r *ChatroomRepository) Create(ctx context.Context, chatroom *domain.Chatroom) error {
-	err := r.createStmt.QueryRowContext(ctx,
+	orgID := domain.OrgIDFromContext(ctx)
+	err := stmt(ctx, r.createStmt).QueryRowContext(ctx,
+		orgID,
 		chatroom.Name,
 		chatroom.CreatedBy,
+		chatroom.Encrypted,
 	).Scan(&chatroom.ID, &chatroom.CreatedAt)
 
 	if err != nil {
 		return fmt.Errorf("failed to create chatroom: %w", err)
 	}
+	chatroom.OrgID = orgID
 	return nil
 }
 
 func (r *ChatroomRepository) GetByID(ctx context.Context, id string) (*domain.Chatroom, error) {
-	chatroom := &domain.Chatroom{}
-	err := r.getByIDStmt.QueryRowContext(ctx, id).Scan(
+	chatroom := &domain.Chatroom{OrgID: domain.OrgIDFromContext(ctx)}
+	err := stmt(ctx, r.getByIDStmt).QueryRowContext(ctx, id, chatroom.OrgID).Scan(
 		&chatroom.ID,
 		&chatroom.Name,
 		&chatroom.CreatedAt,
 		&chatroom.CreatedBy,
+		&chatroom.Encrypted,
 	)
 	if err == sql.ErrNoRows {
 		return nil, domain.ErrChatroomNotFound
@@ -97,12 +107,14 @@ func (r *ChatroomRepository) GetByID(ctx context.Context, id string) (*domain.Ch
 
 func (r *ChatroomRepository) List(ctx context.Context) ([]*domain.Chatroom, error) {
 	query := `
-		SELECT id, name, created_at, created_by
+		SELECT id, name, created_at, created_by, encrypted
 		FROM chatrooms
+		WHERE org_id = $1 AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	orgID := domain.OrgIDFromContext(ctx)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query chatrooms: %w", err)
 	}
@@ -110,12 +122,13 @@ func (r *ChatroomRepository) List(ctx context.Context) ([]*domain.Chatroom, erro
 
 	chatrooms := make([]*domain.Chatroom, 0)
 	for rows.Next() {
-		chatroom := &domain.Chatroom{}
+		chatroom := &domain.Chatroom{OrgID: orgID}
 		err := rows.Scan(
 			&chatroom.ID,
 			&chatroom.Name,
 			&chatroom.CreatedAt,
 			&chatroom.CreatedBy,
+			&chatroom.Encrypted,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan chatroom: %w", err)
@@ -130,35 +143,81 @@ func (r *ChatroomRepository) List(ctx context.Context) ([]*domain.Chatroom, erro
 	return chatrooms, nil
 }
 
-func (r *ChatroomRepository) ListPaginated(ctx context.Context, limit int, cursor string) ([]*domain.Chatroom, string, error) {
+// chatroomSortKeys are the ORDER BY keys of the directory sorts; ties are
+// broken by ID so the cursor is stable
+var chatroomSortKeys = map[string]string{
+	domain.ChatroomSortNewest:  "c.created_at",
+	domain.ChatroomSortActive:  "COALESCE(lm.last_message_at, c.created_at)",
+	domain.ChatroomSortMembers: "mc.member_count",
+}
+
+// likeEscaper escapes the LIKE wildcards of a user-supplied substring
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ListPaginated returns a page of the organization's chatrooms with their
+// latest message time and member count. Both are computed in the same query
+// with index lookups per room rather than one query per room. The cursor
+// room's sort key is re-read, so a room whose key changed between pages may
+// be skipped or repeated.
+func (r *ChatroomRepository) ListPaginated(ctx context.Context, opts domain.ChatroomListOptions) ([]*domain.Chatroom, string, error) {
+	limit := opts.Limit
 	if limit <= 0 || limit > 100 {
 		limit = 50
 	}
+	sortKey, ok := chatroomSortKeys[opts.Sort]
+	if !ok {
+		sortKey = chatroomSortKeys[domain.ChatroomSortNewest]
+	}
 
-	var query string
-	var rows *sql.Rows
-	var err error
+	orgID := domain.OrgIDFromContext(ctx)
+	query := `
+		WITH rooms AS (
+			SELECT c.id, c.name, c.created_at, c.created_by, c.encrypted,
+				lm.last_message_at, mc.member_count,
+				ARRAY(SELECT t.tag FROM chatroom_tags t WHERE t.chatroom_id = c.id ORDER BY t.tag) AS tags,
+				st.position AS star_position,
+				` + sortKey + ` AS sort_key
+			FROM chatrooms c
+			LEFT JOIN chatroom_stars st ON st.user_id = $6 AND st.chatroom_id = c.id
+			LEFT JOIN LATERAL (
+				SELECT MAX(m.created_at) AS last_message_at
+				FROM messages m
+				WHERE m.chatroom_id = c.id AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+			) lm ON true
+			LEFT JOIN LATERAL (
+				SELECT COUNT(*) AS member_count
+				FROM chatroom_members cm
+				WHERE cm.chatroom_id = c.id
+			) mc ON true
+			WHERE c.org_id = $1 AND c.deleted_at IS NULL AND c.name ILIKE $2
+				AND (cardinality($5::text[]) = 0 OR c.id IN (
+					SELECT t.chatroom_id
+					FROM chatroom_tags t
+					WHERE t.org_id = $1 AND t.tag = ANY($5)
+					GROUP BY t.chatroom_id
+					HAVING COUNT(*) = cardinality($5::text[])
+				))
+				AND (NOT $7 OR st.user_id IS NOT NULL)
+		)
+		SELECT id, name, created_at, created_by, encrypted, last_message_at, member_count, tags, star_position
+		FROM rooms
+		WHERE $3 = '' OR (sort_key, id) < (SELECT sort_key, id FROM rooms WHERE id::text = $3)
+		ORDER BY sort_key DESC, id DESC
+		LIMIT $4
+	`
+	namePattern := containsPattern(opts.Name)
 
-	if cursor == "" {
-		query = `
-			SELECT id, name, created_at, created_by
-			FROM chatrooms
-			ORDER BY created_at DESC, id DESC
-			LIMIT $1
-		`
-		rows, err = r.db.QueryContext(ctx, query, limit+1)
-	} else {
-		query = `
-			SELECT id, name, created_at, created_by
-			FROM chatrooms
-			WHERE created_at < (SELECT created_at FROM chatrooms WHERE id = $1)
-			   OR (created_at = (SELECT created_at FROM chatrooms WHERE id = $1) AND id < $1)
-			ORDER BY created_at DESC, id DESC
-			LIMIT $2
-		`
-		rows, err = r.db.QueryContext(ctx, query, cursor, limit+1)
+	tags := opts.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	// A NULL user matches no stars
+	var userID any
+	if opts.UserID != "" {
+		userID = opts.UserID
 	}
 
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, orgID, namePattern, opts.Cursor, limit+1, pq.Array(tags), userID, opts.Starred)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to query chatrooms: %w", err)
 	}
@@ -166,16 +225,34 @@ func (r *ChatroomRepository) ListPaginated(ctx context.Context, limit int, curso
 
 	chatrooms := make([]*domain.Chatroom, 0, limit)
 	for rows.Next() {
-		chatroom := &domain.Chatroom{}
+		chatroom := &domain.Chatroom{OrgID: orgID}
+		var lastMessageAt sql.NullTime
+		var roomTags pq.StringArray
+		var starPosition sql.NullInt64
 		err := rows.Scan(
 			&chatroom.ID,
 			&chatroom.Name,
 			&chatroom.CreatedAt,
 			&chatroom.CreatedBy,
+			&chatroom.Encrypted,
+			&lastMessageAt,
+			&chatroom.MemberCount,
+			&roomTags,
+			&starPosition,
 		)
 		if err != nil {
 			return nil, "", fmt.Errorf("failed to scan chatroom: %w", err)
 		}
+		if lastMessageAt.Valid {
+			chatroom.LastMessageAt = &lastMessageAt.Time
+		}
+		if len(roomTags) > 0 {
+			chatroom.Tags = roomTags
+		}
+		if starPosition.Valid {
+			position := int(starPosition.Int64)
+			chatroom.Starred, chatroom.StarPosition = true, &position
+		}
 		chatrooms = append(chatrooms, chatroom)
 	}
 
@@ -193,7 +270,7 @@ func (r *ChatroomRepository) ListPaginated(ctx context.Context, limit int, curso
 }
 
 func (r *ChatroomRepository) AddMember(ctx context.Context, chatroomID, userID string) error {
-	_, err := r.addMemberStmt.ExecContext(ctx, chatroomID, userID)
+	_, err := stmt(ctx, r.addMemberStmt).ExecContext(ctx, chatroomID, userID)
 	if err != nil {
 		return fmt.Errorf("failed to add member to chatroom: %w", err)
 	}
@@ -202,25 +279,276 @@ func (r *ChatroomRepository) AddMember(ctx context.Context, chatroomID, userID s
 
 func (r *ChatroomRepository) IsMember(ctx context.Context, chatroomID, userID string) (bool, error) {
 	var exists bool
-	err := r.isMemberStmt.QueryRowContext(ctx, chatroomID, userID).Scan(&exists)
+	err := stmt(ctx, r.isMemberStmt).QueryRowContext(ctx, chatroomID, userID, domain.OrgIDFromContext(ctx)).Scan(&exists)
 	if err != nil {
 		return false, fmt.Errorf("failed to check chatroom membership: %w", err)
 	}
 	return exists, nil
 }
 
+func (r *ChatroomRepository) AddMembers(ctx context.Context, chatroomID string, identifiers []string) ([]domain.MemberAddResult, error) {
+	orgID := domain.OrgIDFromContext(ctx)
+	results := make([]domain.MemberAddResult, 0, len(identifiers))
+
+	err := r.tm.WithTx(ctx, func(tx *sql.Tx) error {
+		results = results[:0]
+		for _, identifier := range identifiers {
+			result := domain.MemberAddResult{Identifier: identifier}
+
+			// Comparing id as text keeps usernames from failing the UUID cast
+			var deactivated, system bool
+			err := tx.QueryRowContext(ctx, `
+				SELECT id, deactivated_at IS NOT NULL, is_system FROM users
+				WHERE (id::text = $1 OR username = $1) AND org_id = $2
+				ORDER BY id::text = $1 DESC
+				LIMIT 1
+			`, identifier, orgID).Scan(&result.UserID, &deactivated, &system)
+			if err == sql.ErrNoRows {
+				result.Status = domain.MemberUserNotFound
+				results = append(results, result)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to resolve user %q: %w", identifier, err)
+			}
+			if deactivated || system {
+				result.Status = domain.MemberUserDeactivated
+				if system {
+					result.Status = domain.MemberSystemUser
+				}
+				results = append(results, result)
+				continue
+			}
+
+			res, err := tx.ExecContext(ctx, `
+				INSERT INTO chatroom_members (chatroom_id, user_id)
+				VALUES ($1, $2)
+				ON CONFLICT (chatroom_id, user_id) DO NOTHING
+			`, chatroomID, result.UserID)
+			if err != nil {
+				return fmt.Errorf("failed to add member: %w", err)
+			}
+			added, err := res.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("failed to add member: %w", err)
+			}
+
+			result.Status = domain.MemberAdded
+			if added == 0 {
+				result.Status = domain.MemberAlreadyMember
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *ChatroomRepository) GetSettings(ctx context.Context, chatroomID string) (*domain.ChatroomSettings, error) {
+	settings := &domain.ChatroomSettings{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT COALESCE(s.welcome_message, ''), COALESCE(s.public, FALSE), COALESCE(s.guests_can_post, FALSE), COALESCE(s.read_only, FALSE), COALESCE(s.markdown, FALSE), COALESCE(s.version, 1)
+		FROM chatrooms c
+		LEFT JOIN chatroom_settings s ON s.chatroom_id = c.id
+		WHERE c.id = $1 AND c.org_id = $2 AND c.deleted_at IS NULL
+	`, chatroomID, domain.OrgIDFromContext(ctx)).Scan(&settings.WelcomeMessage, &settings.Public, &settings.GuestsCanPost, &settings.ReadOnly, &settings.Markdown, &settings.Version)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrChatroomNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chatroom settings: %w", err)
+	}
+	return settings, nil
+}
+
+func (r *ChatroomRepository) UpdateSettings(ctx context.Context, chatroomID string, settings *domain.ChatroomSettings) error {
+	return r.tm.WithTx(ctx, func(tx *sql.Tx) error {
+		// Locking the chatroom serializes updates, including the first one
+		// that creates the settings row
+		var current int
+		err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(s.version, 1)
+			FROM chatrooms c
+			LEFT JOIN chatroom_settings s ON s.chatroom_id = c.id
+			WHERE c.id = $1 AND c.org_id = $2 AND c.deleted_at IS NULL
+			FOR UPDATE OF c
+		`, chatroomID, domain.OrgIDFromContext(ctx)).Scan(&current)
+		if err == sql.ErrNoRows {
+			return domain.ErrChatroomNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock chatroom settings: %w", err)
+		}
+		if settings.Version != 0 && settings.Version != current {
+			return domain.ErrVersionConflict
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO chatroom_settings (chatroom_id, welcome_message, public, guests_can_post, read_only, markdown, version)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (chatroom_id) DO UPDATE
+			SET welcome_message = EXCLUDED.welcome_message, public = EXCLUDED.public,
+				guests_can_post = EXCLUDED.guests_can_post, read_only = EXCLUDED.read_only,
+				markdown = EXCLUDED.markdown, version = EXCLUDED.version, updated_at = NOW()
+		`, chatroomID, settings.WelcomeMessage, settings.Public, settings.GuestsCanPost, settings.ReadOnly, settings.Markdown, current+1)
+		if err != nil {
+			return fmt.Errorf("failed to update chatroom settings: %w", err)
+		}
+		settings.Version = current + 1
+		return nil
+	})
+}
+
+func (r *ChatroomRepository) ClaimWelcome(ctx context.Context, chatroomID, userID string) (string, error) {
+	// Marking and reading in one statement sends the message once even when
+	// the member opens several connections at the same time
+	var message string
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		UPDATE chatroom_members cm
+		SET welcomed_at = NOW()
+		FROM chatroom_settings s, chatrooms c
+		WHERE cm.chatroom_id = $1 AND cm.user_id = $2 AND cm.welcomed_at IS NULL
+		  AND s.chatroom_id = cm.chatroom_id AND s.welcome_message <> ''
+		  AND c.id = cm.chatroom_id AND c.org_id = $3 AND c.deleted_at IS NULL
+		RETURNING s.welcome_message
+	`, chatroomID, userID, domain.OrgIDFromContext(ctx)).Scan(&message)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to claim welcome message: %w", err)
+	}
+	return message, nil
+}
+
+// activityPreviewLength is how many characters of the latest message the
+// activity summary carries
+const activityPreviewLength = 140
+
+func (r *ChatroomRepository) Activity(ctx context.Context, userID string) ([]*domain.RoomActivity, error) {
+	// Unread messages are the visible ones by others after the read marker,
+	// posted since the member joined. Both lateral lookups walk the
+	// (chatroom_id, seq) index of visible, undeleted messages. Encrypted
+	// chatrooms get neither mentions nor a preview: a cut ciphertext cannot
+	// be decrypted.
+	// Shadow-banned users' messages count only for themselves.
+	query := `
+		SELECT c.id, c.name, unread.unread_count, unread.mention_count,
+			last.id, last.username, last.content, last.created_at
+		FROM chatroom_members cm
+		JOIN chatrooms c ON c.id = cm.chatroom_id AND c.org_id = $2 AND c.deleted_at IS NULL
+		JOIN users me ON me.id = cm.user_id
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS unread_count,
+				COUNT(*) FILTER (WHERE NOT c.encrypted AND strpos(lower(m.content), lower('@' || me.username)) > 0) AS mention_count
+			FROM messages m
+			WHERE m.chatroom_id = cm.chatroom_id AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+			  AND m.seq > cm.last_read_seq AND m.created_at >= cm.joined_at
+			  AND m.user_id <> cm.user_id
+			  AND NOT EXISTS (
+				SELECT 1 FROM chatroom_shadow_bans b
+				WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM cm.user_id
+					AND (b.user_id = m.user_id
+						OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+			)
+		) unread
+		LEFT JOIN LATERAL (
+			SELECT m.id, u.username, CASE WHEN c.encrypted THEN '' ELSE left(m.content, $3) END AS content, m.created_at
+			FROM messages m
+			JOIN users u ON u.id = m.user_id
+			WHERE m.chatroom_id = cm.chatroom_id AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+			  AND NOT EXISTS (
+				SELECT 1 FROM chatroom_shadow_bans b
+				WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM cm.user_id
+					AND (b.user_id = m.user_id
+						OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+			)
+			ORDER BY m.seq DESC
+			LIMIT 1
+		) last ON true
+		WHERE cm.user_id = $1
+		ORDER BY last.created_at DESC NULLS LAST, c.name
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID, domain.OrgIDFromContext(ctx), activityPreviewLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query activity: %w", err)
+	}
+	defer rows.Close()
+
+	var activity []*domain.RoomActivity
+	for rows.Next() {
+		room := &domain.RoomActivity{}
+		var (
+			lastID, lastUsername, lastContent sql.NullString
+			lastCreatedAt                     sql.NullTime
+		)
+		if err := rows.Scan(
+			&room.ChatroomID,
+			&room.ChatroomName,
+			&room.UnreadCount,
+			&room.MentionCount,
+			&lastID,
+			&lastUsername,
+			&lastContent,
+			&lastCreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		if lastID.Valid {
+			room.LastMessage = &domain.MessagePreview{
+				ID:        lastID.String,
+				Username:  lastUsername.String,
+				Content:   lastContent.String,
+				CreatedAt: lastCreatedAt.Time,
+			}
+		}
+		activity = append(activity, room)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating activity: %w", err)
+	}
+	return activity, nil
+}
+
+func (r *ChatroomRepository) MarkRead(ctx context.Context, chatroomID, userID, messageID string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE chatroom_members cm
+		SET last_read_seq = GREATEST(cm.last_read_seq, COALESCE((
+			SELECT m.seq FROM messages m
+			WHERE m.chatroom_id = cm.chatroom_id AND ($3 = '' OR m.id::text = $3)
+			ORDER BY m.seq DESC
+			LIMIT 1
+		), 0))
+		FROM chatrooms c
+		WHERE cm.chatroom_id = $1 AND cm.user_id = $2
+		  AND c.id = cm.chatroom_id AND c.org_id = $4 AND c.deleted_at IS NULL
+	`, chatroomID, userID, messageID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to mark chatroom read: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrNotMember
+	}
+	return nil
+}
+
 // CreateWithMember atomically creates a chatroom and adds a member
 func (r *ChatroomRepository) CreateWithMember(ctx context.Context, chatroom *domain.Chatroom, userID string) error {
+	orgID := domain.OrgIDFromContext(ctx)
 	return r.tm.WithTx(ctx, func(tx *sql.Tx) error {
 		query := `
-			INSERT INTO chatrooms (name, created_by)
-			VALUES ($1, $2)
+			INSERT INTO chatrooms (org_id, name, created_by, encrypted)
+			VALUES ($1, $2, $3, $4)
 			RETURNING id, created_at
 		`
-		if err := tx.QueryRowContext(ctx, query, chatroom.Name, chatroom.CreatedBy).
+		if err := tx.QueryRowContext(ctx, query, orgID, chatroom.Name, chatroom.CreatedBy, chatroom.Encrypted).
 			Scan(&chatroom.ID, &chatroom.CreatedAt); err != nil {
 			return fmt.Errorf("failed to insert chatroom: %w", err)
 		}
+		chatroom.OrgID = orgID
 
 		memberQuery := `
 			INSERT INTO chatroom_members (chatroom_id, user_id)
@@ -233,3 +561,241 @@ func (r *ChatroomRepository) CreateWithMember(ctx context.Context, chatroom *dom
 		return nil
 	})
 }
+
+func (r *ChatroomRepository) Delete(ctx context.Context, id string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE chatrooms SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL
+	`, id, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete chatroom: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrChatroomNotFound
+	}
+	return nil
+}
+
+func (r *ChatroomRepository) Restore(ctx context.Context, id string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE chatrooms SET deleted_at = NULL
+		WHERE id = $1 AND org_id = $2 AND deleted_at IS NOT NULL
+	`, id, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to restore chatroom: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrChatroomNotFound
+	}
+	return nil
+}
+
+// PurgeDeleted spans every organization; messages, memberships and the
+// rest of the chatroom's rows go with it through ON DELETE CASCADE
+func (r *ChatroomRepository) PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM chatrooms WHERE deleted_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted chatrooms: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return count, nil
+}
+
+func (r *ChatroomRepository) SetMemberKey(ctx context.Context, chatroomID, userID, keyData string) error {
+	var encrypted bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT c.encrypted
+		FROM chatroom_members cm
+		JOIN chatrooms c ON c.id = cm.chatroom_id
+		WHERE cm.chatroom_id = $1 AND cm.user_id = $2 AND c.org_id = $3 AND c.deleted_at IS NULL
+	`, chatroomID, userID, domain.OrgIDFromContext(ctx)).Scan(&encrypted)
+	if err == sql.ErrNoRows {
+		return domain.ErrNotMember
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check chatroom membership: %w", err)
+	}
+	if !encrypted {
+		return domain.ErrNotEncrypted
+	}
+
+	_, err = conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO chatroom_member_keys (chatroom_id, user_id, key_data)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (chatroom_id, user_id) DO UPDATE
+		SET key_data = EXCLUDED.key_data, updated_at = CURRENT_TIMESTAMP
+	`, chatroomID, userID, keyData)
+	if err != nil {
+		return fmt.Errorf("failed to set member key: %w", err)
+	}
+	return nil
+}
+
+func (r *ChatroomRepository) ListMembers(ctx context.Context, chatroomID string) ([]*domain.Member, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT u.id, u.username, u.status, u.status_text, u.show_online_status
+		FROM chatroom_members cm
+		JOIN chatrooms c ON c.id = cm.chatroom_id AND c.org_id = $2 AND c.deleted_at IS NULL
+		JOIN users u ON u.id = cm.user_id AND u.deactivated_at IS NULL
+		WHERE cm.chatroom_id = $1
+		ORDER BY u.username
+	`, chatroomID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query members: %w", err)
+	}
+	defer rows.Close()
+
+	members := make([]*domain.Member, 0)
+	for rows.Next() {
+		member := &domain.Member{}
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Status.Status, &member.Status.Text, &member.ShowOnlineStatus); err != nil {
+			return nil, fmt.Errorf("failed to scan member: %w", err)
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating members: %w", err)
+	}
+	return members, nil
+}
+
+// MemberKeys leaves out the keys of users who left the chatroom
+func (r *ChatroomRepository) MemberKeys(ctx context.Context, chatroomID string) ([]*domain.MemberKey, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT k.user_id, u.username, k.key_data, k.updated_at
+		FROM chatroom_member_keys k
+		JOIN chatroom_members cm ON cm.chatroom_id = k.chatroom_id AND cm.user_id = k.user_id
+		JOIN chatrooms c ON c.id = k.chatroom_id AND c.org_id = $2 AND c.deleted_at IS NULL
+		JOIN users u ON u.id = k.user_id
+		WHERE k.chatroom_id = $1
+		ORDER BY k.updated_at, k.user_id
+	`, chatroomID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query member keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*domain.MemberKey, 0)
+	for rows.Next() {
+		key := &domain.MemberKey{}
+		if err := rows.Scan(&key.UserID, &key.Username, &key.KeyData, &key.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan member key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating member keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *ChatroomRepository) SetTags(ctx context.Context, chatroomID string, tags []string) error {
+	return r.tm.WithTx(ctx, func(tx *sql.Tx) error {
+		// Locking the chatroom serializes concurrent replacements
+		var orgID string
+		err := tx.QueryRowContext(ctx, `
+			SELECT org_id FROM chatrooms
+			WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL
+			FOR UPDATE
+		`, chatroomID, domain.OrgIDFromContext(ctx)).Scan(&orgID)
+		if err == sql.ErrNoRows {
+			return domain.ErrChatroomNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock chatroom: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM chatroom_tags WHERE chatroom_id = $1`, chatroomID); err != nil {
+			return fmt.Errorf("failed to clear chatroom tags: %w", err)
+		}
+		if len(tags) == 0 {
+			return nil
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO chatroom_tags (chatroom_id, org_id, tag)
+			SELECT $1, $2, unnest($3::text[])
+		`, chatroomID, orgID, pq.Array(tags))
+		if err != nil {
+			return fmt.Errorf("failed to set chatroom tags: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *ChatroomRepository) RemoveTag(ctx context.Context, chatroomID, tag string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		DELETE FROM chatroom_tags
+		WHERE chatroom_id = $1 AND tag = $2 AND org_id = $3
+	`, chatroomID, tag, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to remove chatroom tag: %w", err)
+	}
+	return nil
+}
+
+func (r *ChatroomRepository) ListTags(ctx context.Context) ([]*domain.TagCount, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT t.tag, COUNT(*) AS chatrooms
+		FROM chatroom_tags t
+		JOIN chatrooms c ON c.id = t.chatroom_id AND c.deleted_at IS NULL
+		WHERE t.org_id = $1
+		GROUP BY t.tag
+		ORDER BY chatrooms DESC, t.tag
+	`, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chatroom tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make([]*domain.TagCount, 0)
+	for rows.Next() {
+		tag := &domain.TagCount{}
+		if err := rows.Scan(&tag.Tag, &tag.Chatrooms); err != nil {
+			return nil, fmt.Errorf("failed to scan chatroom tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chatroom tags: %w", err)
+	}
+	return tags, nil
+}
+
+func (r *ChatroomRepository) Star(ctx context.Context, chatroomID, userID string, position *int) (int, error) {
+	var pos sql.NullInt64
+	if position != nil {
+		pos = sql.NullInt64{Int64: int64(*position), Valid: true}
+	}
+	var starred int
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO chatroom_stars (user_id, chatroom_id, position)
+		SELECT $1, c.id, COALESCE($3::int, (SELECT COALESCE(MAX(position) + 1, 0) FROM chatroom_stars WHERE user_id = $1))
+		FROM chatrooms c
+		WHERE c.id = $2 AND c.org_id = $4 AND c.deleted_at IS NULL
+		ON CONFLICT (user_id, chatroom_id) DO UPDATE
+		SET position = COALESCE($3::int, chatroom_stars.position)
+		RETURNING position
+	`, userID, chatroomID, pos, domain.OrgIDFromContext(ctx)).Scan(&starred)
+	if err == sql.ErrNoRows {
+		return 0, domain.ErrChatroomNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to star chatroom: %w", err)
+	}
+	return starred, nil
+}
+
+func (r *ChatroomRepository) Unstar(ctx context.Context, chatroomID, userID string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		DELETE FROM chatroom_stars WHERE user_id = $1 AND chatroom_id = $2
+	`, userID, chatroomID)
+	if err != nil {
+		return fmt.Errorf("failed to unstar chatroom: %w", err)
+	}
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"jobsity-chat/internal/domain"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -35,8 +36,8 @@ func TestNewChatroomRepository(t *testing.T) {
 		defer db.Close()
 
 		mock.ExpectPrepare(regexp.QuoteMeta(`
-		INSERT INTO chatrooms (name, created_by)
-		VALUES ($1, $2)
+		INSERT INTO chatrooms (org_id, name, created_by, encrypted)
+		VALUES ($1, $2, $3, $4)
 		RETURNING id, created_at
 	`)).WillReturnError(errors.New("prepare failed"))
 
@@ -62,11 +63,11 @@ func TestChatroomRepository_Create(t *testing.T) {
 		createdAt := time.Now()
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		INSERT INTO chatrooms (name, created_by)
-		VALUES ($1, $2)
+		INSERT INTO chatrooms (org_id, name, created_by, encrypted)
+		VALUES ($1, $2, $3, $4)
 		RETURNING id, created_at
 	`)).
-			WithArgs("Test Room", "user-123").
+			WithArgs(domain.DefaultOrganizationID, "Test Room", "user-123", false).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).
 				AddRow(chatroomID, createdAt))
 
@@ -93,8 +94,8 @@ func TestChatroomRepository_Create(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		INSERT INTO chatrooms (name, created_by)
-		VALUES ($1, $2)
+		INSERT INTO chatrooms (org_id, name, created_by, encrypted)
+		VALUES ($1, $2, $3, $4)
 		RETURNING id, created_at
 	`)).
 			WillReturnError(errors.New("database error"))
@@ -125,13 +126,13 @@ func TestChatroomRepository_GetByID(t *testing.T) {
 		createdAt := time.Now()
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, name, created_at, created_by
+		SELECT id, name, created_at, created_by, encrypted
 		FROM chatrooms
-		WHERE id = $1
+		WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL
 	`)).
-			WithArgs(chatroomID).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "created_by"}).
-				AddRow(chatroomID, "Test Room", createdAt, "user-123"))
+			WithArgs(chatroomID, domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "created_by", "encrypted"}).
+				AddRow(chatroomID, "Test Room", createdAt, "user-123", false))
 
 		chatroom, err := repo.GetByID(context.Background(), chatroomID)
 		require.NoError(t, err)
@@ -152,11 +153,11 @@ func TestChatroomRepository_GetByID(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, name, created_at, created_by
+		SELECT id, name, created_at, created_by, encrypted
 		FROM chatrooms
-		WHERE id = $1
+		WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL
 	`)).
-			WithArgs("nonexistent").
+			WithArgs("nonexistent", domain.DefaultOrganizationID).
 			WillReturnError(sql.ErrNoRows)
 
 		chatroom, err := repo.GetByID(context.Background(), "nonexistent")
@@ -176,11 +177,11 @@ func TestChatroomRepository_GetByID(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, name, created_at, created_by
+		SELECT id, name, created_at, created_by, encrypted
 		FROM chatrooms
-		WHERE id = $1
+		WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL
 	`)).
-			WithArgs("room-123").
+			WithArgs("room-123", domain.DefaultOrganizationID).
 			WillReturnError(errors.New("database error"))
 
 		chatroom, err := repo.GetByID(context.Background(), "room-123")
@@ -203,13 +204,14 @@ func TestChatroomRepository_List(t *testing.T) {
 
 		createdAt := time.Now()
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, name, created_at, created_by
+		SELECT id, name, created_at, created_by, encrypted
 		FROM chatrooms
+		WHERE org_id = $1 AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`)).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "created_by"}).
-				AddRow("room-1", "Room 1", createdAt, "user-1").
-				AddRow("room-2", "Room 2", createdAt.Add(-time.Hour), "user-2"))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "created_by", "encrypted"}).
+				AddRow("room-1", "Room 1", createdAt, "user-1", false).
+				AddRow("room-2", "Room 2", createdAt.Add(-time.Hour), "user-2", true))
 
 		chatrooms, err := repo.List(context.Background())
 		require.NoError(t, err)
@@ -230,11 +232,12 @@ func TestChatroomRepository_List(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, name, created_at, created_by
+		SELECT id, name, created_at, created_by, encrypted
 		FROM chatrooms
+		WHERE org_id = $1 AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`)).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "created_by"}))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "created_by", "encrypted"}))
 
 		chatrooms, err := repo.List(context.Background())
 		require.NoError(t, err)
@@ -254,8 +257,9 @@ func TestChatroomRepository_List(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, name, created_at, created_by
+		SELECT id, name, created_at, created_by, encrypted
 		FROM chatrooms
+		WHERE org_id = $1 AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`)).
 			WillReturnError(errors.New("database error"))
@@ -352,11 +356,12 @@ func TestChatroomRepository_IsMember(t *testing.T) {
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
 		SELECT EXISTS(
-			SELECT 1 FROM chatroom_members
-			WHERE chatroom_id = $1 AND user_id = $2
+			SELECT 1 FROM chatroom_members cm
+			JOIN chatrooms c ON c.id = cm.chatroom_id
+			WHERE cm.chatroom_id = $1 AND cm.user_id = $2 AND c.org_id = $3 AND c.deleted_at IS NULL
 		)
 	`)).
-			WithArgs("room-123", "user-456").
+			WithArgs("room-123", "user-456", domain.DefaultOrganizationID).
 			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
 
 		isMember, err := repo.IsMember(context.Background(), "room-123", "user-456")
@@ -377,11 +382,12 @@ func TestChatroomRepository_IsMember(t *testing.T) {
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
 		SELECT EXISTS(
-			SELECT 1 FROM chatroom_members
-			WHERE chatroom_id = $1 AND user_id = $2
+			SELECT 1 FROM chatroom_members cm
+			JOIN chatrooms c ON c.id = cm.chatroom_id
+			WHERE cm.chatroom_id = $1 AND cm.user_id = $2 AND c.org_id = $3 AND c.deleted_at IS NULL
 		)
 	`)).
-			WithArgs("room-123", "user-456").
+			WithArgs("room-123", "user-456", domain.DefaultOrganizationID).
 			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
 
 		isMember, err := repo.IsMember(context.Background(), "room-123", "user-456")
@@ -402,11 +408,12 @@ func TestChatroomRepository_IsMember(t *testing.T) {
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
 		SELECT EXISTS(
-			SELECT 1 FROM chatroom_members
-			WHERE chatroom_id = $1 AND user_id = $2
+			SELECT 1 FROM chatroom_members cm
+			JOIN chatrooms c ON c.id = cm.chatroom_id
+			WHERE cm.chatroom_id = $1 AND cm.user_id = $2 AND c.org_id = $3 AND c.deleted_at IS NULL
 		)
 	`)).
-			WithArgs("room-123", "user-456").
+			WithArgs("room-123", "user-456", domain.DefaultOrganizationID).
 			WillReturnError(errors.New("database error"))
 
 		isMember, err := repo.IsMember(context.Background(), "room-123", "user-456")
@@ -433,11 +440,11 @@ func TestChatroomRepository_CreateWithMember(t *testing.T) {
 		// Expect transaction
 		mock.ExpectBegin()
 		mock.ExpectQuery(regexp.QuoteMeta(`
-			INSERT INTO chatrooms (name, created_by)
-			VALUES ($1, $2)
+			INSERT INTO chatrooms (org_id, name, created_by, encrypted)
+			VALUES ($1, $2, $3, $4)
 			RETURNING id, created_at
 		`)).
-			WithArgs("Test Room", "user-123").
+			WithArgs(domain.DefaultOrganizationID, "Test Room", "user-123", false).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).
 				AddRow(chatroomID, createdAt))
 		mock.ExpectExec(regexp.QuoteMeta(`
@@ -472,8 +479,8 @@ func TestChatroomRepository_CreateWithMember(t *testing.T) {
 
 		mock.ExpectBegin()
 		mock.ExpectQuery(regexp.QuoteMeta(`
-			INSERT INTO chatrooms (name, created_by)
-			VALUES ($1, $2)
+			INSERT INTO chatrooms (org_id, name, created_by, encrypted)
+			VALUES ($1, $2, $3, $4)
 			RETURNING id, created_at
 		`)).
 			WillReturnError(errors.New("database error"))
@@ -490,18 +497,597 @@ func TestChatroomRepository_CreateWithMember(t *testing.T) {
 	})
 }
 
+func TestChatroomRepository_AddMembers(t *testing.T) {
+	resolveQuery := regexp.QuoteMeta(`
+				SELECT id, deactivated_at IS NOT NULL, is_system FROM users
+				WHERE (id::text = $1 OR username = $1) AND org_id = $2
+				ORDER BY id::text = $1 DESC
+				LIMIT 1
+			`)
+	insertQuery := regexp.QuoteMeta(`
+				INSERT INTO chatroom_members (chatroom_id, user_id)
+				VALUES ($1, $2)
+				ON CONFLICT (chatroom_id, user_id) DO NOTHING
+			`)
+
+	t.Run("reports_per_user_results", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupChatroomRepositoryMocks(mock)
+
+		repo, err := NewChatroomRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(resolveQuery).
+			WithArgs("alice", domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "deactivated", "is_system"}).AddRow("user-1", false, false))
+		mock.ExpectExec(insertQuery).
+			WithArgs("room-123", "user-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(resolveQuery).
+			WithArgs("user-2", domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "deactivated", "is_system"}).AddRow("user-2", false, false))
+		mock.ExpectExec(insertQuery).
+			WithArgs("room-123", "user-2").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(resolveQuery).
+			WithArgs("ghost", domain.DefaultOrganizationID).
+			WillReturnError(sql.ErrNoRows)
+		// Neither is inserted
+		mock.ExpectQuery(resolveQuery).
+			WithArgs("former", domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "deactivated", "is_system"}).AddRow("user-3", true, false))
+		mock.ExpectQuery(resolveQuery).
+			WithArgs("StockBot", domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "deactivated", "is_system"}).AddRow("bot-1", false, true))
+		mock.ExpectCommit()
+
+		results, err := repo.AddMembers(context.Background(), "room-123", []string{"alice", "user-2", "ghost", "former", "StockBot"})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.MemberAddResult{
+			{Identifier: "alice", UserID: "user-1", Status: domain.MemberAdded},
+			{Identifier: "user-2", UserID: "user-2", Status: domain.MemberAlreadyMember},
+			{Identifier: "ghost", Status: domain.MemberUserNotFound},
+			{Identifier: "former", UserID: "user-3", Status: domain.MemberUserDeactivated},
+			{Identifier: "StockBot", UserID: "bot-1", Status: domain.MemberSystemUser},
+		}, results)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls_back_on_insert_error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupChatroomRepositoryMocks(mock)
+
+		repo, err := NewChatroomRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(resolveQuery).
+			WithArgs("alice", domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "deactivated", "is_system"}).AddRow("user-1", false, false))
+		mock.ExpectExec(insertQuery).
+			WithArgs("room-123", "user-1").
+			WillReturnError(errors.New("database error"))
+		mock.ExpectRollback()
+
+		results, err := repo.AddMembers(context.Background(), "room-123", []string{"alice"})
+		require.Error(t, err)
+		assert.Nil(t, results)
+		assert.Contains(t, err.Error(), "failed to add member")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestChatroomRepository_Settings(t *testing.T) {
+	getQuery := regexp.QuoteMeta(`
+		SELECT COALESCE(s.welcome_message, ''), COALESCE(s.public, FALSE), COALESCE(s.guests_can_post, FALSE), COALESCE(s.read_only, FALSE), COALESCE(s.markdown, FALSE), COALESCE(s.version, 1)
+		FROM chatrooms c
+		LEFT JOIN chatroom_settings s ON s.chatroom_id = c.id
+		WHERE c.id = $1 AND c.org_id = $2 AND c.deleted_at IS NULL
+	`)
+	lockQuery := regexp.QuoteMeta(`
+			SELECT COALESCE(s.version, 1)
+			FROM chatrooms c
+			LEFT JOIN chatroom_settings s ON s.chatroom_id = c.id
+			WHERE c.id = $1 AND c.org_id = $2 AND c.deleted_at IS NULL
+			FOR UPDATE OF c
+		`)
+	updateQuery := regexp.QuoteMeta(`
+			INSERT INTO chatroom_settings (chatroom_id, welcome_message, public, guests_can_post, read_only, markdown, version)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (chatroom_id) DO UPDATE
+			SET welcome_message = EXCLUDED.welcome_message, public = EXCLUDED.public,
+				guests_can_post = EXCLUDED.guests_can_post, read_only = EXCLUDED.read_only,
+				markdown = EXCLUDED.markdown, version = EXCLUDED.version, updated_at = NOW()
+		`)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupChatroomRepositoryMocks(mock)
+
+	repo, err := NewChatroomRepository(db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mock.ExpectQuery(getQuery).
+		WithArgs("room-123", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"welcome_message", "public", "guests_can_post", "read_only", "markdown", "version"}).AddRow("Hi!", true, false, true, true, 2))
+	settings, err := repo.GetSettings(ctx, "room-123")
+	require.NoError(t, err)
+	assert.Equal(t, "Hi!", settings.WelcomeMessage)
+	assert.True(t, settings.Public)
+	assert.False(t, settings.GuestsCanPost)
+	assert.True(t, settings.ReadOnly)
+	assert.True(t, settings.Markdown)
+	assert.Equal(t, 2, settings.Version)
+
+	mock.ExpectQuery(getQuery).
+		WithArgs("missing", domain.DefaultOrganizationID).
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.GetSettings(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrChatroomNotFound)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).
+		WithArgs("room-123", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+	mock.ExpectExec(updateQuery).
+		WithArgs("room-123", "Welcome", true, true, false, false, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	updated := &domain.ChatroomSettings{WelcomeMessage: "Welcome", Public: true, GuestsCanPost: true, Version: 2}
+	require.NoError(t, repo.UpdateSettings(ctx, "room-123", updated))
+	assert.Equal(t, 3, updated.Version)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).
+		WithArgs("room-123", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+	mock.ExpectRollback()
+	err = repo.UpdateSettings(ctx, "room-123", &domain.ChatroomSettings{WelcomeMessage: "Stale", Version: 2})
+	assert.ErrorIs(t, err, domain.ErrVersionConflict)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).
+		WithArgs("missing", domain.DefaultOrganizationID).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	err = repo.UpdateSettings(ctx, "missing", &domain.ChatroomSettings{})
+	assert.ErrorIs(t, err, domain.ErrChatroomNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChatroomRepository_ClaimWelcome(t *testing.T) {
+	claimQuery := regexp.QuoteMeta(`
+		UPDATE chatroom_members cm
+		SET welcomed_at = NOW()
+		FROM chatroom_settings s, chatrooms c
+		WHERE cm.chatroom_id = $1 AND cm.user_id = $2 AND cm.welcomed_at IS NULL
+		  AND s.chatroom_id = cm.chatroom_id AND s.welcome_message <> ''
+		  AND c.id = cm.chatroom_id AND c.org_id = $3 AND c.deleted_at IS NULL
+		RETURNING s.welcome_message
+	`)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupChatroomRepositoryMocks(mock)
+
+	repo, err := NewChatroomRepository(db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mock.ExpectQuery(claimQuery).
+		WithArgs("room-123", "user-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"welcome_message"}).AddRow("Hi!"))
+	message, err := repo.ClaimWelcome(ctx, "room-123", "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Hi!", message)
+
+	mock.ExpectQuery(claimQuery).
+		WithArgs("room-123", "user-1", domain.DefaultOrganizationID).
+		WillReturnError(sql.ErrNoRows)
+	message, err = repo.ClaimWelcome(ctx, "room-123", "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, message)
+
+	mock.ExpectQuery(claimQuery).
+		WithArgs("room-123", "user-1", domain.DefaultOrganizationID).
+		WillReturnError(errors.New("database error"))
+	_, err = repo.ClaimWelcome(ctx, "room-123", "user-1")
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChatroomRepository_Activity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupChatroomRepositoryMocks(mock)
+
+	repo, err := NewChatroomRepository(db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	createdAt := time.Now()
+	// Unread counts and previews leave out shadow-banned users' messages,
+	// except for those users themselves
+	mock.ExpectQuery(`CROSS JOIN LATERAL .* chatroom_shadow_bans b .* IS DISTINCT FROM cm.user_id .* LEFT JOIN LATERAL .* chatroom_shadow_bans b .* IS DISTINCT FROM cm.user_id`).
+		WithArgs("user-1", domain.DefaultOrganizationID, activityPreviewLength).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "unread_count", "mention_count", "id", "username", "content", "created_at"}).
+			AddRow("room-1", "General", 4, 1, "msg-9", "bob", "hey @alice", createdAt).
+			AddRow("room-2", "Quiet", 0, 0, nil, nil, nil, nil))
+
+	activity, err := repo.Activity(ctx, "user-1")
+	require.NoError(t, err)
+	require.Len(t, activity, 2)
+	assert.Equal(t, 4, activity[0].UnreadCount)
+	assert.Equal(t, 1, activity[0].MentionCount)
+	require.NotNil(t, activity[0].LastMessage)
+	assert.Equal(t, "bob", activity[0].LastMessage.Username)
+	assert.Equal(t, "Quiet", activity[1].ChatroomName)
+	assert.Nil(t, activity[1].LastMessage)
+
+	mock.ExpectQuery(`FROM chatroom_members cm`).
+		WithArgs("user-1", domain.DefaultOrganizationID, activityPreviewLength).
+		WillReturnError(errors.New("database error"))
+	_, err = repo.Activity(ctx, "user-1")
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChatroomRepository_MarkRead(t *testing.T) {
+	markQuery := `UPDATE chatroom_members cm\s+SET last_read_seq = GREATEST`
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupChatroomRepositoryMocks(mock)
+
+	repo, err := NewChatroomRepository(db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mock.ExpectExec(markQuery).
+		WithArgs("room-1", "user-1", "", domain.DefaultOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.MarkRead(ctx, "room-1", "user-1", ""))
+
+	mock.ExpectExec(markQuery).
+		WithArgs("room-1", "outsider", "msg-1", domain.DefaultOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	err = repo.MarkRead(ctx, "room-1", "outsider", "msg-1")
+	assert.ErrorIs(t, err, domain.ErrNotMember)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChatroomRepository_SoftDelete(t *testing.T) {
+	deleteQuery := regexp.QuoteMeta(`
+		UPDATE chatrooms SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL
+	`)
+	restoreQuery := regexp.QuoteMeta(`
+		UPDATE chatrooms SET deleted_at = NULL
+		WHERE id = $1 AND org_id = $2 AND deleted_at IS NOT NULL
+	`)
+	purgeQuery := regexp.QuoteMeta(`DELETE FROM chatrooms WHERE deleted_at < $1`)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupChatroomRepositoryMocks(mock)
+
+	repo, err := NewChatroomRepository(db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mock.ExpectExec(deleteQuery).
+		WithArgs("room-1", domain.DefaultOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.Delete(ctx, "room-1"))
+
+	mock.ExpectExec(deleteQuery).
+		WithArgs("room-1", domain.DefaultOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.Delete(ctx, "room-1"), domain.ErrChatroomNotFound)
+
+	mock.ExpectExec(restoreQuery).
+		WithArgs("room-1", domain.DefaultOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.Restore(ctx, "room-1"))
+
+	mock.ExpectExec(restoreQuery).
+		WithArgs("room-2", domain.DefaultOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.Restore(ctx, "room-2"), domain.ErrChatroomNotFound)
+
+	cutoff := time.Now().Add(-30 * 24 * time.Hour)
+	mock.ExpectExec(purgeQuery).
+		WithArgs(cutoff).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	purged, err := repo.PurgeDeleted(ctx, cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), purged)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChatroomRepository_ListMembers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupChatroomRepositoryMocks(mock)
+
+	repo, err := NewChatroomRepository(db)
+	require.NoError(t, err)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT u.id, u.username, u.status, u.status_text, u.show_online_status`)).
+		WithArgs("room-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "status", "status_text", "show_online_status"}).
+			AddRow("user-1", "alice", domain.StatusDND, "Focusing", true).
+			AddRow("user-2", "bob", domain.StatusActive, "", false))
+
+	members, err := repo.ListMembers(context.Background(), "room-1")
+	require.NoError(t, err)
+	assert.Equal(t, []*domain.Member{
+		{UserID: "user-1", Username: "alice", Status: domain.UserStatus{Status: domain.StatusDND, Text: "Focusing"}, ShowOnlineStatus: true},
+		{UserID: "user-2", Username: "bob", Status: domain.UserStatus{Status: domain.StatusActive}},
+	}, members)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChatroomRepository_MemberKeys(t *testing.T) {
+	membershipQuery := regexp.QuoteMeta(`SELECT c.encrypted`)
+	upsertQuery := regexp.QuoteMeta(`INSERT INTO chatroom_member_keys (chatroom_id, user_id, key_data)`)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupChatroomRepositoryMocks(mock)
+
+	repo, err := NewChatroomRepository(db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mock.ExpectQuery(membershipQuery).
+		WithArgs("room-1", "outsider", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"encrypted"}))
+	assert.ErrorIs(t, repo.SetMemberKey(ctx, "room-1", "outsider", "pk"), domain.ErrNotMember)
+
+	mock.ExpectQuery(membershipQuery).
+		WithArgs("room-2", "user-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"encrypted"}).AddRow(false))
+	assert.ErrorIs(t, repo.SetMemberKey(ctx, "room-2", "user-1", "pk"), domain.ErrNotEncrypted)
+
+	mock.ExpectQuery(membershipQuery).
+		WithArgs("room-1", "user-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"encrypted"}).AddRow(true))
+	mock.ExpectExec(upsertQuery).
+		WithArgs("room-1", "user-1", "pk").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.SetMemberKey(ctx, "room-1", "user-1", "pk"))
+
+	updatedAt := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM chatroom_member_keys k`)).
+		WithArgs("room-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "key_data", "updated_at"}).
+			AddRow("user-1", "alice", "pk", updatedAt))
+	keys, err := repo.MemberKeys(ctx, "room-1")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "alice", keys[0].Username)
+	assert.Equal(t, "pk", keys[0].KeyData)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 // Helper function to set up common mock expectations
+func TestChatroomRepository_ListPaginated(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupChatroomRepositoryMocks(mock)
+
+	repo, err := NewChatroomRepository(db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	columns := []string{"id", "name", "created_at", "created_by", "encrypted", "last_message_at", "member_count", "tags", "star_position"}
+	createdAt := time.Now().Add(-time.Hour)
+	lastMessageAt := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`COALESCE(lm.last_message_at, c.created_at) AS sort_key`)).
+		WithArgs(domain.DefaultOrganizationID, `%50\%\_off%`, "", 3, pq.Array([]string{}), "user-1", false).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("room-3", "50% off", createdAt, "user-1", false, lastMessageAt, 4, "{deals,shopping}", 2).
+			AddRow("room-2", "50%_off deals", createdAt, "user-1", false, nil, 1, "{}", nil).
+			AddRow("room-1", "old 50%_off", createdAt, "user-2", false, nil, 0, "{}", nil))
+
+	rooms, next, err := repo.ListPaginated(ctx, domain.ChatroomListOptions{Limit: 2, Sort: domain.ChatroomSortActive, Name: "50%_off", UserID: "user-1"})
+	require.NoError(t, err)
+	require.Len(t, rooms, 2)
+	assert.Equal(t, "room-2", next)
+	require.NotNil(t, rooms[0].LastMessageAt)
+	assert.True(t, rooms[0].LastMessageAt.Equal(lastMessageAt))
+	assert.Equal(t, 4, rooms[0].MemberCount)
+	assert.Equal(t, []string{"deals", "shopping"}, rooms[0].Tags)
+	assert.True(t, rooms[0].Starred)
+	require.NotNil(t, rooms[0].StarPosition)
+	assert.Equal(t, 2, *rooms[0].StarPosition)
+	assert.Nil(t, rooms[1].LastMessageAt)
+	assert.Nil(t, rooms[1].Tags)
+	assert.False(t, rooms[1].Starred)
+	assert.Nil(t, rooms[1].StarPosition)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`mc.member_count AS sort_key`)).
+		WithArgs(domain.DefaultOrganizationID, "%%", "room-2", 51, pq.Array([]string{"go", "backend"}), nil, false).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	rooms, next, err = repo.ListPaginated(ctx, domain.ChatroomListOptions{Sort: domain.ChatroomSortMembers, Cursor: "room-2", Tags: []string{"go", "backend"}})
+	require.NoError(t, err)
+	assert.Empty(t, rooms)
+	assert.Empty(t, next)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChatroomRepository_Tags(t *testing.T) {
+	lockQuery := regexp.QuoteMeta(`
+			SELECT org_id FROM chatrooms
+			WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL
+			FOR UPDATE
+		`)
+	clearQuery := regexp.QuoteMeta(`DELETE FROM chatroom_tags WHERE chatroom_id = $1`)
+	insertQuery := regexp.QuoteMeta(`
+			INSERT INTO chatroom_tags (chatroom_id, org_id, tag)
+			SELECT $1, $2, unnest($3::text[])
+		`)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupChatroomRepositoryMocks(mock)
+
+	repo, err := NewChatroomRepository(db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).
+		WithArgs("room-123", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id"}).AddRow(domain.DefaultOrganizationID))
+	mock.ExpectExec(clearQuery).WithArgs("room-123").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(insertQuery).
+		WithArgs("room-123", domain.DefaultOrganizationID, pq.Array([]string{"go", "backend"})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	require.NoError(t, repo.SetTags(ctx, "room-123", []string{"go", "backend"}))
+
+	// Clearing the tags inserts nothing
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).
+		WithArgs("room-123", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id"}).AddRow(domain.DefaultOrganizationID))
+	mock.ExpectExec(clearQuery).WithArgs("room-123").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	require.NoError(t, repo.SetTags(ctx, "room-123", nil))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).
+		WithArgs("missing", domain.DefaultOrganizationID).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	assert.ErrorIs(t, repo.SetTags(ctx, "missing", []string{"go"}), domain.ErrChatroomNotFound)
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		DELETE FROM chatroom_tags
+		WHERE chatroom_id = $1 AND tag = $2 AND org_id = $3
+	`)).
+		WithArgs("room-123", "go", domain.DefaultOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.RemoveTag(ctx, "room-123", "go"))
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT t.tag, COUNT(*) AS chatrooms
+		FROM chatroom_tags t
+		JOIN chatrooms c ON c.id = t.chatroom_id AND c.deleted_at IS NULL
+		WHERE t.org_id = $1
+		GROUP BY t.tag
+		ORDER BY chatrooms DESC, t.tag
+	`)).
+		WithArgs(domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"tag", "chatrooms"}).AddRow("backend", 12).AddRow("go", 3))
+	tags, err := repo.ListTags(ctx)
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	assert.Equal(t, domain.TagCount{Tag: "backend", Chatrooms: 12}, *tags[0])
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChatroomRepository_Star(t *testing.T) {
+	starQuery := regexp.QuoteMeta(`
+		INSERT INTO chatroom_stars (user_id, chatroom_id, position)
+		SELECT $1, c.id, COALESCE($3::int, (SELECT COALESCE(MAX(position) + 1, 0) FROM chatroom_stars WHERE user_id = $1))
+		FROM chatrooms c
+		WHERE c.id = $2 AND c.org_id = $4 AND c.deleted_at IS NULL
+		ON CONFLICT (user_id, chatroom_id) DO UPDATE
+		SET position = COALESCE($3::int, chatroom_stars.position)
+		RETURNING position
+	`)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupChatroomRepositoryMocks(mock)
+
+	repo, err := NewChatroomRepository(db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mock.ExpectQuery(starQuery).
+		WithArgs("user-1", "room-123", sql.NullInt64{}, domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(4))
+	position, err := repo.Star(ctx, "room-123", "user-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4, position)
+
+	first := 0
+	mock.ExpectQuery(starQuery).
+		WithArgs("user-1", "room-123", sql.NullInt64{Int64: 0, Valid: true}, domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(0))
+	position, err = repo.Star(ctx, "room-123", "user-1", &first)
+	require.NoError(t, err)
+	assert.Equal(t, 0, position)
+
+	mock.ExpectQuery(starQuery).
+		WithArgs("user-1", "missing", sql.NullInt64{}, domain.DefaultOrganizationID).
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.Star(ctx, "missing", "user-1", nil)
+	assert.ErrorIs(t, err, domain.ErrChatroomNotFound)
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		DELETE FROM chatroom_stars WHERE user_id = $1 AND chatroom_id = $2
+	`)).
+		WithArgs("user-1", "room-123").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.Unstar(ctx, "room-123", "user-1"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func setupChatroomRepositoryMocks(mock sqlmock.Sqlmock) {
 	mock.ExpectPrepare(regexp.QuoteMeta(`
-		INSERT INTO chatrooms (name, created_by)
-		VALUES ($1, $2)
+		INSERT INTO chatrooms (org_id, name, created_by, encrypted)
+		VALUES ($1, $2, $3, $4)
 		RETURNING id, created_at
 	`)).WillReturnCloseError(nil)
 
 	mock.ExpectPrepare(regexp.QuoteMeta(`
-		SELECT id, name, created_at, created_by
+		SELECT id, name, created_at, created_by, encrypted
 		FROM chatrooms
-		WHERE id = $1
+		WHERE id = $1 AND org_id = $2 AND deleted_at IS NULL
 	`)).WillReturnCloseError(nil)
 
 	mock.ExpectPrepare(regexp.QuoteMeta(`
@@ -512,8 +1098,9 @@ func setupChatroomRepositoryMocks(mock sqlmock.Sqlmock) {
 
 	mock.ExpectPrepare(regexp.QuoteMeta(`
 		SELECT EXISTS(
-			SELECT 1 FROM chatroom_members
-			WHERE chatroom_id = $1 AND user_id = $2
+			SELECT 1 FROM chatroom_members cm
+			JOIN chatrooms c ON c.id = cm.chatroom_id
+			WHERE cm.chatroom_id = $1 AND cm.user_id = $2 AND c.org_id = $3 AND c.deleted_at IS NULL
 		)
 	`)).WillReturnCloseError(nil)
 }
@@ -0,0 +1,213 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/lib/pq"
+)
+
+type ChatroomStatsRepository struct {
+	db           *sql.DB
+	presenceStmt *sql.Stmt
+	historyStmt  *sql.Stmt
+	dailyStmt    *sql.Stmt
+	postersStmt  *sql.Stmt
+}
+
+// NewChatroomStatsRepository creates a new ChatroomStatsRepository with
+// prepared statements. Returns an error if statement preparation fails.
+func NewChatroomStatsRepository(db *sql.DB) (*ChatroomStatsRepository, error) {
+	repo := &ChatroomStatsRepository{db: db}
+
+	var err error
+	// Hubs serve every organization, so the organization is the chatroom's
+	repo.presenceStmt, err = db.Prepare(`
+		INSERT INTO chatroom_presence_samples (chatroom_id, org_id, instance_id, sampled_at, connected_users)
+		SELECT c.id, c.org_id, $1, date_trunc('minute', $2::timestamp), s.connected_users
+		FROM unnest($3::uuid[], $4::int[]) AS s(chatroom_id, connected_users)
+		JOIN chatrooms c ON c.id = s.chatroom_id
+		ON CONFLICT (chatroom_id, sampled_at, instance_id) DO UPDATE
+		SET connected_users = GREATEST(chatroom_presence_samples.connected_users, EXCLUDED.connected_users)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare presence statement: %w", err)
+	}
+
+	// A chatroom's concurrency at a sample time is the sum over instances,
+	// and a bucket's the highest of its samples
+	repo.historyStmt, err = db.Prepare(`
+		SELECT t.chatroom_id, c.name, date_bin(make_interval(secs => $3), t.sampled_at, TIMESTAMP '2000-01-01') AS bucket,
+			MAX(t.users)
+		FROM (
+			SELECT chatroom_id, sampled_at, SUM(connected_users) AS users
+			FROM chatroom_presence_samples
+			WHERE org_id = $1 AND sampled_at >= $2 AND ($4::uuid IS NULL OR chatroom_id = $4)
+			GROUP BY chatroom_id, sampled_at
+		) t
+		JOIN chatrooms c ON c.id = t.chatroom_id
+		GROUP BY t.chatroom_id, c.name, bucket
+		ORDER BY c.name, t.chatroom_id, bucket
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare history statement: %w", err)
+	}
+
+	repo.dailyStmt, err = db.Prepare(`
+		SELECT d.day::date, COALESCE(a.messages, 0), COALESCE(p.peak_users, 0)
+		FROM generate_series($2::date, CURRENT_DATE, interval '1 day') AS d(day)
+		LEFT JOIN (
+			SELECT day, SUM(messages) AS messages
+			FROM chatroom_daily_activity
+			WHERE chatroom_id = $1 AND org_id = $3 AND day >= $2::date
+			GROUP BY day
+		) a ON a.day = d.day::date
+		LEFT JOIN chatroom_daily_peaks p ON p.chatroom_id = $1 AND p.org_id = $3 AND p.day = d.day::date
+		ORDER BY d.day
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare daily statement: %w", err)
+	}
+
+	repo.postersStmt, err = db.Prepare(`
+		SELECT a.user_id, u.username, SUM(a.messages) AS messages
+		FROM chatroom_daily_activity a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.chatroom_id = $1 AND a.org_id = $3 AND a.day >= $2::date
+		GROUP BY a.user_id, u.username
+		ORDER BY messages DESC, u.username
+		LIMIT $4
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare posters statement: %w", err)
+	}
+
+	return repo, nil
+}
+
+func (r *ChatroomStatsRepository) RecordPresence(ctx context.Context, instanceID string, sampledAt time.Time, counts map[string]int) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	chatroomIDs := make([]string, 0, len(counts))
+	users := make([]int64, 0, len(counts))
+	for chatroomID, n := range counts {
+		chatroomIDs = append(chatroomIDs, chatroomID)
+		users = append(users, int64(n))
+	}
+
+	_, err := stmt(ctx, r.presenceStmt).ExecContext(ctx, instanceID, sampledAt.UTC(), pq.Array(chatroomIDs), pq.Array(users))
+	if err != nil {
+		return fmt.Errorf("failed to record presence: %w", err)
+	}
+	return nil
+}
+
+func (r *ChatroomStatsRepository) PresenceHistory(ctx context.Context, chatroomID string, since time.Time, bucket time.Duration) ([]*domain.RoomPresenceHistory, error) {
+	var room any
+	if chatroomID != "" {
+		room = chatroomID
+	}
+	rows, err := stmt(ctx, r.historyStmt).QueryContext(ctx, domain.OrgIDFromContext(ctx), since.UTC(), bucket.Seconds(), room)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query presence history: %w", err)
+	}
+	defer rows.Close()
+
+	history := make([]*domain.RoomPresenceHistory, 0)
+	var current *domain.RoomPresenceHistory
+	for rows.Next() {
+		var (
+			id, name string
+			point    domain.PresencePoint
+		)
+		if err := rows.Scan(&id, &name, &point.At, &point.Users); err != nil {
+			return nil, fmt.Errorf("failed to scan presence history: %w", err)
+		}
+		if current == nil || current.ChatroomID != id {
+			current = &domain.RoomPresenceHistory{ChatroomID: id, ChatroomName: name}
+			history = append(history, current)
+		}
+		current.Points = append(current.Points, point)
+		current.PeakUsers = max(current.PeakUsers, point.Users)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating presence history: %w", err)
+	}
+	return history, nil
+}
+
+func (r *ChatroomStatsRepository) DeletePresenceBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM chatroom_presence_samples WHERE sampled_at < $1`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete presence samples: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Refresh refreshes the views concurrently, so statistics stay readable
+// while they are recomputed
+func (r *ChatroomStatsRepository) Refresh(ctx context.Context) error {
+	for _, view := range []string{"chatroom_daily_activity", "chatroom_daily_peaks"} {
+		if _, err := conn(ctx, r.db).ExecContext(ctx, `REFRESH MATERIALIZED VIEW CONCURRENTLY `+view); err != nil {
+			return fmt.Errorf("failed to refresh %s: %w", view, err)
+		}
+	}
+	return nil
+}
+
+func (r *ChatroomStatsRepository) Stats(ctx context.Context, chatroomID string, since time.Time) (*domain.ChatroomStats, error) {
+	orgID := domain.OrgIDFromContext(ctx)
+	since = since.UTC()
+	stats := &domain.ChatroomStats{
+		ChatroomID: chatroomID,
+		Daily:      []domain.DailyActivity{},
+		TopPosters: []domain.TopPoster{},
+	}
+
+	rows, err := stmt(ctx, r.dailyStmt).QueryContext(ctx, chatroomID, since, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily activity: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			day   time.Time
+			daily domain.DailyActivity
+		)
+		if err := rows.Scan(&day, &daily.Messages, &daily.PeakUsers); err != nil {
+			return nil, fmt.Errorf("failed to scan daily activity: %w", err)
+		}
+		daily.Date = day.Format(time.DateOnly)
+		stats.Daily = append(stats.Daily, daily)
+		stats.Messages += daily.Messages
+		stats.PeakUsers = max(stats.PeakUsers, daily.PeakUsers)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating daily activity: %w", err)
+	}
+	stats.Days = len(stats.Daily)
+
+	rows, err = stmt(ctx, r.postersStmt).QueryContext(ctx, chatroomID, since, orgID, domain.TopPostersLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top posters: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var poster domain.TopPoster
+		if err := rows.Scan(&poster.UserID, &poster.Username, &poster.Messages); err != nil {
+			return nil, fmt.Errorf("failed to scan top poster: %w", err)
+		}
+		stats.TopPosters = append(stats.TopPosters, poster)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating top posters: %w", err)
+	}
+	return stats, nil
+}
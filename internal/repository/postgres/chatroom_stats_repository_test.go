@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestChatroomStatsRepository(t *testing.T) (*ChatroomStatsRepository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO chatroom_presence_samples`))
+	mock.ExpectPrepare(regexp.QuoteMeta(`date_bin(make_interval(secs => $3), t.sampled_at, TIMESTAMP '2000-01-01') AS bucket`))
+	mock.ExpectPrepare(regexp.QuoteMeta(`FROM generate_series($2::date, CURRENT_DATE, interval '1 day') AS d(day)`))
+	mock.ExpectPrepare(regexp.QuoteMeta(`SELECT a.user_id, u.username, SUM(a.messages) AS messages`))
+	repo, err := NewChatroomStatsRepository(db)
+	require.NoError(t, err)
+	return repo, mock
+}
+
+func TestChatroomStatsRepository_RecordPresence(t *testing.T) {
+	repo, mock := newTestChatroomStatsRepository(t)
+	sampledAt := time.Date(2026, 3, 1, 12, 30, 15, 0, time.UTC)
+
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT c.id, c.org_id, $1, date_trunc('minute', $2::timestamp), s.connected_users`)).
+		WithArgs("instance-1", sampledAt, pq.Array([]string{"room-1"}), pq.Array([]int64{3})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, repo.RecordPresence(context.Background(), "instance-1", sampledAt, map[string]int{"room-1": 3}))
+	// Nothing to record without connections
+	require.NoError(t, repo.RecordPresence(context.Background(), "instance-1", sampledAt, nil))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChatroomStatsRepository_PresenceHistory(t *testing.T) {
+	repo, mock := newTestChatroomStatsRepository(t)
+	since := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE org_id = $1 AND sampled_at >= $2 AND ($4::uuid IS NULL OR chatroom_id = $4)`)).
+		WithArgs(domain.DefaultOrganizationID, since, float64(300), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"chatroom_id", "name", "bucket", "max"}).
+			AddRow("room-1", "General", since, 4).
+			AddRow("room-1", "General", since.Add(5*time.Minute), 7).
+			AddRow("room-2", "Random", since.Add(5*time.Minute), 1))
+
+	history, err := repo.PresenceHistory(context.Background(), "", since, 5*time.Minute)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "General", history[0].ChatroomName)
+	assert.Equal(t, 7, history[0].PeakUsers)
+	assert.Equal(t, []domain.PresencePoint{{At: since, Users: 4}, {At: since.Add(5 * time.Minute), Users: 7}}, history[0].Points)
+	assert.Len(t, history[1].Points, 1)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM chatroom_presence_samples`)).
+		WithArgs(domain.DefaultOrganizationID, since, float64(60), "room-3").
+		WillReturnRows(sqlmock.NewRows([]string{"chatroom_id", "name", "bucket", "max"}))
+
+	history, err = repo.PresenceHistory(context.Background(), "room-3", since, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChatroomStatsRepository_Refresh(t *testing.T) {
+	repo, mock := newTestChatroomStatsRepository(t)
+
+	mock.ExpectExec(regexp.QuoteMeta(`REFRESH MATERIALIZED VIEW CONCURRENTLY chatroom_daily_activity`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`REFRESH MATERIALIZED VIEW CONCURRENTLY chatroom_daily_peaks`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, repo.Refresh(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChatroomStatsRepository_Stats(t *testing.T) {
+	repo, mock := newTestChatroomStatsRepository(t)
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`LEFT JOIN chatroom_daily_peaks p ON p.chatroom_id = $1 AND p.org_id = $3`)).
+		WithArgs("room-1", since, domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"day", "messages", "peak_users"}).
+			AddRow(since, 12, 4).
+			AddRow(since.AddDate(0, 0, 1), 0, 0).
+			AddRow(since.AddDate(0, 0, 2), 30, 9))
+	mock.ExpectQuery(regexp.QuoteMeta(`ORDER BY messages DESC, u.username`)).
+		WithArgs("room-1", since, domain.DefaultOrganizationID, domain.TopPostersLimit).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "messages"}).
+			AddRow("user-1", "alice", 25).
+			AddRow("user-2", "bob", 17))
+
+	stats, err := repo.Stats(context.Background(), "room-1", since)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Days)
+	assert.Equal(t, int64(42), stats.Messages)
+	assert.Equal(t, 9, stats.PeakUsers)
+	assert.Equal(t, domain.DailyActivity{Date: "2026-03-02", Messages: 0, PeakUsers: 0}, stats.Daily[1])
+	assert.Equal(t, []domain.TopPoster{{UserID: "user-1", Username: "alice", Messages: 25}, {UserID: "user-2", Username: "bob", Messages: 17}}, stats.TopPosters)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"jobsity-chat/internal/domain"
+)
+
+type DataExportRepository struct {
+	db         *sql.DB
+	latestStmt *sql.Stmt
+}
+
+// NewDataExportRepository creates a new DataExportRepository with prepared statements.
+// Returns an error if statement preparation fails.
+func NewDataExportRepository(db *sql.DB) (*DataExportRepository, error) {
+	repo := &DataExportRepository{db: db}
+
+	var err error
+	// Clients poll it until their export is ready, so it is prepared
+	repo.latestStmt, err = db.Prepare(`
+		SELECT e.id, e.user_id, e.status, COALESCE(e.error, ''), e.created_at, e.completed_at, e.expires_at
+		FROM data_exports e
+		JOIN users u ON u.id = e.user_id AND u.org_id = $2
+		WHERE e.user_id = $1
+		ORDER BY e.created_at DESC
+		LIMIT 1
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare latest export statement: %w", err)
+	}
+
+	return repo, nil
+}
+
+func (r *DataExportRepository) Create(ctx context.Context, export *domain.DataExport) error {
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO data_exports (user_id)
+		SELECT u.id FROM users u WHERE u.id = $1 AND u.org_id = $2
+		RETURNING id, status, created_at
+	`, export.UserID, domain.OrgIDFromContext(ctx)).Scan(&export.ID, &export.Status, &export.CreatedAt)
+	if err == sql.ErrNoRows {
+		return domain.ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create data export: %w", err)
+	}
+	return nil
+}
+
+func (r *DataExportRepository) Latest(ctx context.Context, userID string) (*domain.DataExport, error) {
+	export := &domain.DataExport{}
+	err := stmt(ctx, r.latestStmt).QueryRowContext(ctx, userID, domain.OrgIDFromContext(ctx)).Scan(
+		&export.ID,
+		&export.UserID,
+		&export.Status,
+		&export.Error,
+		&export.CreatedAt,
+		&export.CompletedAt,
+		&export.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrExportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+	return export, nil
+}
+
+func (r *DataExportRepository) Complete(ctx context.Context, id string, archive []byte, expiresAt time.Time) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE data_exports
+		SET status = 'ready', archive = $2, completed_at = CURRENT_TIMESTAMP, expires_at = $3
+		WHERE id = $1 AND status = 'pending'
+	`, id, archive, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to complete data export: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrExportNotFound
+	}
+	return nil
+}
+
+func (r *DataExportRepository) Fail(ctx context.Context, id, reason string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE data_exports
+		SET status = 'failed', error = $2, completed_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'pending'
+	`, id, reason)
+	if err != nil {
+		return fmt.Errorf("failed to mark data export failed: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrExportNotFound
+	}
+	return nil
+}
+
+func (r *DataExportRepository) Archive(ctx context.Context, userID, id string) ([]byte, error) {
+	var archive []byte
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT e.archive
+		FROM data_exports e
+		JOIN users u ON u.id = e.user_id AND u.org_id = $3
+		WHERE e.id::text = $1 AND e.user_id = $2 AND e.status = 'ready' AND e.expires_at > NOW()
+	`, id, userID, domain.OrgIDFromContext(ctx)).Scan(&archive)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrExportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data export archive: %w", err)
+	}
+	return archive, nil
+}
+
+func (r *DataExportRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM data_exports WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired data exports: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *DataExportRepository) Memberships(ctx context.Context, userID string) ([]*domain.ExportMembership, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT c.id, c.name, c.created_by = cm.user_id, cm.joined_at
+		FROM chatroom_members cm
+		JOIN chatrooms c ON c.id = cm.chatroom_id AND c.org_id = $2
+		WHERE cm.user_id = $1
+		ORDER BY cm.joined_at, c.id
+	`, userID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memberships: %w", err)
+	}
+	defer rows.Close()
+
+	var memberships []*domain.ExportMembership
+	for rows.Next() {
+		m := &domain.ExportMembership{}
+		if err := rows.Scan(&m.ChatroomID, &m.ChatroomName, &m.Owner, &m.JoinedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan membership: %w", err)
+		}
+		memberships = append(memberships, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating memberships: %w", err)
+	}
+	return memberships, nil
+}
+
+func (r *DataExportRepository) Messages(ctx context.Context, userID string) ([]*domain.ExportMessage, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT m.id, m.chatroom_id, c.name, m.content, m.created_at, m.deleted_at
+		FROM messages m
+		JOIN chatrooms c ON c.id = m.chatroom_id
+		WHERE m.user_id = $1 AND m.org_id = $2
+		ORDER BY m.created_at, m.id
+	`, userID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query authored messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*domain.ExportMessage
+	for rows.Next() {
+		m := &domain.ExportMessage{}
+		if err := rows.Scan(&m.ID, &m.ChatroomID, &m.ChatroomName, &m.Content, &m.CreatedAt, &m.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan authored message: %w", err)
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating authored messages: %w", err)
+	}
+	return messages, nil
+}
@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDataExportRepository(t *testing.T) (*DataExportRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mock.ExpectPrepare(`SELECT e.id, e.user_id, e.status`)
+	repo, err := NewDataExportRepository(db)
+	require.NoError(t, err)
+	return repo, mock
+}
+
+func TestDataExportRepository_CreateAndLatest(t *testing.T) {
+	repo, mock := newTestDataExportRepository(t)
+	ctx := context.Background()
+	createdAt := time.Now()
+
+	mock.ExpectQuery(`INSERT INTO data_exports \(user_id\)\s+SELECT u.id FROM users u WHERE u.id = \$1 AND u.org_id = \$2`).
+		WithArgs("user-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at"}).AddRow("export-1", domain.ExportPending, createdAt))
+	export := &domain.DataExport{UserID: "user-1"}
+	require.NoError(t, repo.Create(ctx, export))
+	assert.Equal(t, "export-1", export.ID)
+	assert.Equal(t, domain.ExportPending, export.Status)
+
+	mock.ExpectQuery(`INSERT INTO data_exports`).
+		WithArgs("other-org-user", domain.DefaultOrganizationID).
+		WillReturnError(sql.ErrNoRows)
+	err := repo.Create(ctx, &domain.DataExport{UserID: "other-org-user"})
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+
+	expiresAt := createdAt.Add(time.Hour)
+	mock.ExpectQuery(`SELECT e.id, e.user_id, e.status`).
+		WithArgs("user-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "status", "error", "created_at", "completed_at", "expires_at"}).
+			AddRow("export-1", "user-1", domain.ExportReady, "", createdAt, createdAt, expiresAt))
+	latest, err := repo.Latest(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ExportReady, latest.Status)
+	require.NotNil(t, latest.ExpiresAt)
+	assert.Equal(t, expiresAt, *latest.ExpiresAt)
+
+	mock.ExpectQuery(`SELECT e.id, e.user_id, e.status`).
+		WithArgs("user-2", domain.DefaultOrganizationID).
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.Latest(ctx, "user-2")
+	assert.ErrorIs(t, err, domain.ErrExportNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDataExportRepository_CompleteFailAndArchive(t *testing.T) {
+	repo, mock := newTestDataExportRepository(t)
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	mock.ExpectExec(`UPDATE data_exports\s+SET status = 'ready'.*WHERE id = \$1 AND status = 'pending'`).
+		WithArgs("export-1", []byte("zip"), expiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.Complete(ctx, "export-1", []byte("zip"), expiresAt))
+
+	mock.ExpectExec(`UPDATE data_exports\s+SET status = 'failed'`).
+		WithArgs("export-1", "boom").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.Fail(ctx, "export-1", "boom"), domain.ErrExportNotFound)
+
+	mock.ExpectQuery(`SELECT e.archive\s+FROM data_exports e`).
+		WithArgs("export-1", "user-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"archive"}).AddRow([]byte("zip")))
+	archive, err := repo.Archive(ctx, "user-1", "export-1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("zip"), archive)
+
+	mock.ExpectQuery(`SELECT e.archive\s+FROM data_exports e`).
+		WithArgs("export-1", "user-2", domain.DefaultOrganizationID).
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.Archive(ctx, "user-2", "export-1")
+	assert.ErrorIs(t, err, domain.ErrExportNotFound)
+
+	mock.ExpectExec(`DELETE FROM data_exports WHERE expires_at < NOW\(\)`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	deleted, err := repo.DeleteExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDataExportRepository_MembershipsAndMessages(t *testing.T) {
+	repo, mock := newTestDataExportRepository(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT c.id, c.name, c.created_by = cm.user_id, cm.joined_at\s+FROM chatroom_members cm`).
+		WithArgs("user-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner", "joined_at"}).
+			AddRow("room-1", "General", true, now).
+			AddRow("room-2", "Random", false, now))
+	memberships, err := repo.Memberships(ctx, "user-1")
+	require.NoError(t, err)
+	require.Len(t, memberships, 2)
+	assert.True(t, memberships[0].Owner)
+	assert.Equal(t, "Random", memberships[1].ChatroomName)
+
+	mock.ExpectQuery(`SELECT m.id, m.chatroom_id, c.name, m.content, m.created_at, m.deleted_at\s+FROM messages m`).
+		WithArgs("user-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "name", "content", "created_at", "deleted_at"}).
+			AddRow("msg-1", "room-1", "General", "Hello", now, nil).
+			AddRow("msg-2", "room-1", "General", "Oops", now, now))
+	messages, err := repo.Messages(ctx, "user-1")
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Nil(t, messages[0].DeletedAt)
+	assert.NotNil(t, messages[1].DeletedAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"jobsity-chat/internal/domain"
+)
+
+type IdentityRepository struct {
+	db                       *sql.DB
+	createStmt               *sql.Stmt
+	getByProviderSubjectStmt *sql.Stmt
+}
+
+// NewIdentityRepository creates a new IdentityRepository with prepared statements.
+// Returns an error if statement preparation fails.
+func NewIdentityRepository(db *sql.DB) (*IdentityRepository, error) {
+	repo := &IdentityRepository{db: db}
+
+	var err error
+	repo.createStmt, err = db.Prepare(`
+		INSERT INTO user_identities (org_id, provider, subject, user_id, email)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare create statement: %w", err)
+	}
+
+	repo.getByProviderSubjectStmt, err = db.Prepare(`
+		SELECT provider, subject, user_id, email, created_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2 AND org_id = $3
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare getByProviderSubject statement: %w", err)
+	}
+
+	return repo, nil
+}
+
+func (r *IdentityRepository) Create(ctx context.Context, identity *domain.UserIdentity) error {
+	err := stmt(ctx, r.createStmt).QueryRowContext(ctx,
+		domain.OrgIDFromContext(ctx),
+		identity.Provider,
+		identity.Subject,
+		identity.UserID,
+		identity.Email,
+	).Scan(&identity.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create identity: %w", err)
+	}
+	return nil
+}
+
+func (r *IdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	identity := &domain.UserIdentity{}
+	err := stmt(ctx, r.getByProviderSubjectStmt).QueryRowContext(ctx, provider, subject, domain.OrgIDFromContext(ctx)).Scan(
+		&identity.Provider,
+		&identity.Subject,
+		&identity.UserID,
+		&identity.Email,
+		&identity.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrIdentityNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+	return identity, nil
+}
+
+func (r *IdentityRepository) ListByProvider(ctx context.Context, provider string) ([]*domain.UserIdentity, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT provider, subject, user_id, email, created_at
+		FROM user_identities
+		WHERE provider = $1 AND org_id = $2
+	`, provider, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []*domain.UserIdentity
+	for rows.Next() {
+		identity := &domain.UserIdentity{}
+		if err := rows.Scan(
+			&identity.Provider,
+			&identity.Subject,
+			&identity.UserID,
+			&identity.Email,
+			&identity.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan identity: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating identities: %w", err)
+	}
+	return identities, nil
+}
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	identityCreateQuery = `
+		INSERT INTO user_identities (org_id, provider, subject, user_id, email)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+	identityGetQuery = `
+		SELECT provider, subject, user_id, email, created_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2 AND org_id = $3
+	`
+)
+
+func TestIdentityRepository_Create(t *testing.T) {
+	t.Run("successful_creation", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupIdentityRepositoryMocks(mock)
+
+		repo, err := NewIdentityRepository(db)
+		require.NoError(t, err)
+
+		createdAt := time.Now()
+		mock.ExpectQuery(regexp.QuoteMeta(identityCreateQuery)).
+			WithArgs(domain.DefaultOrganizationID, "github", "12345", "user-123", "alice@example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(createdAt))
+
+		identity := &domain.UserIdentity{
+			Provider: "github",
+			Subject:  "12345",
+			UserID:   "user-123",
+			Email:    "alice@example.com",
+		}
+		err = repo.Create(context.Background(), identity)
+		require.NoError(t, err)
+		assert.Equal(t, createdAt, identity.CreatedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database_error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupIdentityRepositoryMocks(mock)
+
+		repo, err := NewIdentityRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(identityCreateQuery)).
+			WillReturnError(errors.New("duplicate key"))
+
+		err = repo.Create(context.Background(), &domain.UserIdentity{Provider: "github", Subject: "12345"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to create identity")
+	})
+}
+
+func TestIdentityRepository_GetByProviderSubject(t *testing.T) {
+	t.Run("successful_retrieval", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupIdentityRepositoryMocks(mock)
+
+		repo, err := NewIdentityRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(identityGetQuery)).
+			WithArgs("google", "sub-1", domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"provider", "subject", "user_id", "email", "created_at"}).
+				AddRow("google", "sub-1", "user-123", "alice@example.com", time.Now()))
+
+		identity, err := repo.GetByProviderSubject(context.Background(), "google", "sub-1")
+		require.NoError(t, err)
+		assert.Equal(t, "user-123", identity.UserID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupIdentityRepositoryMocks(mock)
+
+		repo, err := NewIdentityRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(identityGetQuery)).
+			WithArgs("google", "missing", domain.DefaultOrganizationID).
+			WillReturnError(sql.ErrNoRows)
+
+		identity, err := repo.GetByProviderSubject(context.Background(), "google", "missing")
+		assert.Nil(t, identity)
+		assert.Equal(t, domain.ErrIdentityNotFound, err)
+	})
+}
+
+func TestIdentityRepository_ListByProvider(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupIdentityRepositoryMocks(mock)
+
+	repo, err := NewIdentityRepository(db)
+	require.NoError(t, err)
+
+	mock.ExpectQuery(`FROM user_identities\s+WHERE provider = \$1 AND org_id = \$2`).
+		WithArgs("ldap", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"provider", "subject", "user_id", "email", "created_at"}).
+			AddRow("ldap", "uid-1", "user-1", "alice@example.com", time.Now()).
+			AddRow("ldap", "uid-2", "user-2", "bob@example.com", time.Now()))
+
+	identities, err := repo.ListByProvider(context.Background(), "ldap")
+	require.NoError(t, err)
+	require.Len(t, identities, 2)
+	assert.Equal(t, "uid-2", identities[1].Subject)
+	assert.Equal(t, "user-2", identities[1].UserID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func setupIdentityRepositoryMocks(mock sqlmock.Sqlmock) {
+	mock.ExpectPrepare(regexp.QuoteMeta(identityCreateQuery))
+	mock.ExpectPrepare(regexp.QuoteMeta(identityGetQuery))
+}
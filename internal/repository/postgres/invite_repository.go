@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"jobsity-chat/internal/domain"
+)
+
+type RoomInviteRepository struct {
+	db             *sql.DB
+	tm             *TxManager
+	getPendingStmt *sql.Stmt
+}
+
+// NewRoomInviteRepository creates a new RoomInviteRepository with prepared statements.
+// Returns an error if statement preparation fails.
+func NewRoomInviteRepository(db *sql.DB) (*RoomInviteRepository, error) {
+	repo := &RoomInviteRepository{
+		db: db,
+		tm: NewTxManager(db),
+	}
+
+	var err error
+	// Runs for every opened invite link, so it is prepared
+	repo.getPendingStmt, err = db.Prepare(`
+		SELECT i.id, i.chatroom_id, i.email, i.invited_by, i.token_hash, i.expires_at, i.created_at,
+		       c.name, u.username
+		FROM room_invites i
+		JOIN chatrooms c ON c.id = i.chatroom_id AND c.org_id = $2 AND c.deleted_at IS NULL
+		JOIN users u ON u.id = i.invited_by
+		WHERE i.token_hash = $1 AND i.accepted_at IS NULL AND i.revoked_at IS NULL
+		  AND i.expires_at > NOW()
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare getPending statement: %w", err)
+	}
+
+	return repo, nil
+}
+
+func (r *RoomInviteRepository) Create(ctx context.Context, invite *domain.RoomInvite) error {
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO room_invites (chatroom_id, email, token_hash, invited_by, expires_at)
+		SELECT c.id, $2, $3, $4, $5 FROM chatrooms c WHERE c.id = $1 AND c.org_id = $6
+		RETURNING id, created_at
+	`, invite.ChatroomID, invite.Email, invite.TokenHash, invite.InvitedBy, invite.ExpiresAt,
+		domain.OrgIDFromContext(ctx)).Scan(&invite.ID, &invite.CreatedAt)
+	if err == sql.ErrNoRows {
+		return domain.ErrChatroomNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create room invite: %w", err)
+	}
+	return nil
+}
+
+func (r *RoomInviteRepository) GetPending(ctx context.Context, tokenHash string) (*domain.RoomInvite, error) {
+	invite := &domain.RoomInvite{}
+	err := stmt(ctx, r.getPendingStmt).QueryRowContext(ctx, tokenHash, domain.OrgIDFromContext(ctx)).Scan(
+		&invite.ID,
+		&invite.ChatroomID,
+		&invite.Email,
+		&invite.InvitedBy,
+		&invite.TokenHash,
+		&invite.ExpiresAt,
+		&invite.CreatedAt,
+		&invite.ChatroomName,
+		&invite.InviterName,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrInviteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room invite: %w", err)
+	}
+	return invite, nil
+}
+
+func (r *RoomInviteRepository) ListPending(ctx context.Context, chatroomID string) ([]*domain.RoomInvite, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT i.id, i.chatroom_id, i.email, i.invited_by, i.expires_at, i.created_at
+		FROM room_invites i
+		JOIN chatrooms c ON c.id = i.chatroom_id AND c.org_id = $2
+		WHERE i.chatroom_id = $1 AND i.accepted_at IS NULL AND i.revoked_at IS NULL
+		  AND i.expires_at > NOW()
+		ORDER BY i.created_at DESC
+	`, chatroomID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list room invites: %w", err)
+	}
+	defer rows.Close()
+
+	var invites []*domain.RoomInvite
+	for rows.Next() {
+		invite := &domain.RoomInvite{}
+		if err := rows.Scan(&invite.ID, &invite.ChatroomID, &invite.Email, &invite.InvitedBy,
+			&invite.ExpiresAt, &invite.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan room invite: %w", err)
+		}
+		invites = append(invites, invite)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating room invites: %w", err)
+	}
+	return invites, nil
+}
+
+func (r *RoomInviteRepository) Revoke(ctx context.Context, chatroomID, inviteID string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE room_invites i SET revoked_at = NOW()
+		FROM chatrooms c
+		WHERE i.id::text = $1 AND i.chatroom_id = $2 AND c.id = i.chatroom_id AND c.org_id = $3
+		  AND i.accepted_at IS NULL AND i.revoked_at IS NULL
+	`, inviteID, chatroomID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to revoke room invite: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrInviteNotFound
+	}
+	return nil
+}
+
+func (r *RoomInviteRepository) Accept(ctx context.Context, tokenHash, userID string) (*domain.RoomInvite, error) {
+	orgID := domain.OrgIDFromContext(ctx)
+	invite := &domain.RoomInvite{}
+
+	err := r.tm.WithTx(ctx, func(tx *sql.Tx) error {
+		// The invite, its chatroom and the accepting user must all belong
+		// to the organization
+		err := tx.QueryRowContext(ctx, `
+			UPDATE room_invites i SET accepted_at = NOW(), accepted_by = u.id
+			FROM chatrooms c, users u
+			WHERE i.token_hash = $1 AND i.accepted_at IS NULL AND i.revoked_at IS NULL
+			  AND i.expires_at > NOW()
+			  AND c.id = i.chatroom_id AND c.org_id = $3 AND c.deleted_at IS NULL
+			  AND u.id = $2 AND u.org_id = $3
+			RETURNING i.id, i.chatroom_id, i.email, i.invited_by, i.expires_at, i.created_at
+		`, tokenHash, userID, orgID).Scan(
+			&invite.ID,
+			&invite.ChatroomID,
+			&invite.Email,
+			&invite.InvitedBy,
+			&invite.ExpiresAt,
+			&invite.CreatedAt,
+		)
+		if err == sql.ErrNoRows {
+			return domain.ErrInviteNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to accept room invite: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO chatroom_members (chatroom_id, user_id)
+			VALUES ($1, $2)
+			ON CONFLICT (chatroom_id, user_id) DO NOTHING
+		`, invite.ChatroomID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to add invited member: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRoomInviteRepository(t *testing.T) (*RoomInviteRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mock.ExpectPrepare(`SELECT i.id, i.chatroom_id, i.email, .* FROM room_invites i`)
+	repo, err := NewRoomInviteRepository(db)
+	require.NoError(t, err)
+	return repo, mock
+}
+
+func TestRoomInviteRepository_Create(t *testing.T) {
+	repo, mock := newTestRoomInviteRepository(t)
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+	createdAt := time.Now()
+
+	mock.ExpectQuery(`INSERT INTO room_invites .* FROM chatrooms c WHERE c.id = \$1 AND c.org_id = \$6`).
+		WithArgs("room-1", "new@example.com", "hash-1", "owner-1", expiresAt, domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("invite-1", createdAt))
+	invite := &domain.RoomInvite{ChatroomID: "room-1", Email: "new@example.com", TokenHash: "hash-1", InvitedBy: "owner-1", ExpiresAt: expiresAt}
+	require.NoError(t, repo.Create(ctx, invite))
+	assert.Equal(t, "invite-1", invite.ID)
+
+	mock.ExpectQuery(`INSERT INTO room_invites`).WillReturnError(sql.ErrNoRows)
+	err := repo.Create(ctx, &domain.RoomInvite{ChatroomID: "other-org-room", ExpiresAt: expiresAt})
+	assert.ErrorIs(t, err, domain.ErrChatroomNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRoomInviteRepository_GetPending(t *testing.T) {
+	repo, mock := newTestRoomInviteRepository(t)
+	ctx := domain.WithOrgID(context.Background(), "org-1")
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT i.id`).
+		WithArgs("hash-1", "org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "email", "invited_by", "token_hash", "expires_at", "created_at", "name", "username"}).
+			AddRow("invite-1", "room-1", "new@example.com", "owner-1", "hash-1", now.Add(time.Hour), now, "general", "alice"))
+	invite, err := repo.GetPending(ctx, "hash-1")
+	require.NoError(t, err)
+	assert.Equal(t, "general", invite.ChatroomName)
+	assert.Equal(t, "alice", invite.InviterName)
+
+	mock.ExpectQuery(`SELECT i.id`).WithArgs("expired", "org-1").WillReturnError(sql.ErrNoRows)
+	_, err = repo.GetPending(ctx, "expired")
+	assert.ErrorIs(t, err, domain.ErrInviteNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRoomInviteRepository_ListAndRevoke(t *testing.T) {
+	repo, mock := newTestRoomInviteRepository(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT .* FROM room_invites i .* ORDER BY i.created_at DESC`).
+		WithArgs("room-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "email", "invited_by", "expires_at", "created_at"}).
+			AddRow("invite-2", "room-1", "b@example.com", "owner-1", now.Add(time.Hour), now).
+			AddRow("invite-1", "room-1", "a@example.com", "owner-1", now.Add(time.Hour), now.Add(-time.Minute)))
+	invites, err := repo.ListPending(ctx, "room-1")
+	require.NoError(t, err)
+	require.Len(t, invites, 2)
+	assert.Equal(t, "invite-2", invites[0].ID)
+
+	mock.ExpectExec(`UPDATE room_invites i SET revoked_at = NOW\(\)`).
+		WithArgs("invite-1", "room-1", domain.DefaultOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.Revoke(ctx, "room-1", "invite-1"))
+
+	mock.ExpectExec(`UPDATE room_invites i SET revoked_at = NOW\(\)`).
+		WithArgs("invite-1", "room-1", domain.DefaultOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.Revoke(ctx, "room-1", "invite-1"), domain.ErrInviteNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRoomInviteRepository_Accept(t *testing.T) {
+	repo, mock := newTestRoomInviteRepository(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE room_invites i SET accepted_at = NOW\(\), accepted_by = u.id`).
+		WithArgs("hash-1", "user-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "email", "invited_by", "expires_at", "created_at"}).
+			AddRow("invite-1", "room-1", "new@example.com", "owner-1", now.Add(time.Hour), now))
+	mock.ExpectExec(`INSERT INTO chatroom_members`).
+		WithArgs("room-1", "user-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	invite, err := repo.Accept(ctx, "hash-1", "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, "room-1", invite.ChatroomID)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE room_invites`).
+		WithArgs("hash-1", "user-2", domain.DefaultOrganizationID).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	_, err = repo.Accept(ctx, "hash-1", "user-2")
+	assert.ErrorIs(t, err, domain.ErrInviteNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// LeaderLockKey is the advisory lock held by the instance running the
+// background jobs
+const LeaderLockKey int64 = 0x63686174746f7275 // "chattoru"
+
+// LeaderElector elects one instance of those sharing the database to run
+// the background jobs, by holding a session-level advisory lock on a
+// dedicated connection. When the leader stops or loses its connection,
+// Postgres releases the lock and another instance takes it on its next
+// check. It implements domain.Leader.
+type LeaderElector struct {
+	db     *sql.DB
+	key    int64
+	leader atomic.Bool
+	// conn holds the lock while leading. It is only used by Check and
+	// Release, which must not be called concurrently.
+	conn *sql.Conn
+}
+
+func NewLeaderElector(db *sql.DB, key int64) *LeaderElector {
+	return &LeaderElector{db: db, key: key}
+}
+
+// IsLeader reports whether this instance held the lock at its last check
+func (e *LeaderElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Check verifies the lock is still held while leading, and otherwise tries
+// to take it
+func (e *LeaderElector) Check(ctx context.Context) {
+	if e.conn != nil {
+		_, err := e.conn.ExecContext(ctx, "SELECT 1")
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		slog.Warn("lost background job leadership", slog.String("error", err.Error()))
+		e.leader.Store(false)
+		discardConn(e.conn)
+		e.conn = nil
+	}
+
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("leader election failed", slog.String("error", err.Error()))
+		}
+		return
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", e.key).Scan(&acquired); err != nil {
+		if ctx.Err() == nil {
+			slog.Error("leader election failed", slog.String("error", err.Error()))
+		}
+		discardConn(conn)
+		return
+	}
+	if !acquired {
+		conn.Close()
+		return
+	}
+
+	e.conn = conn
+	e.leader.Store(true)
+	slog.Info("acquired background job leadership")
+}
+
+// Run checks the lock every interval until ctx is cancelled, then releases
+// it
+func (e *LeaderElector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			e.Release()
+			return
+		case <-ticker.C:
+			e.Check(ctx)
+		}
+	}
+}
+
+// Release gives up the lock, if held, so another instance can take over
+// without waiting for this one's connection to close
+func (e *LeaderElector) Release() {
+	if e.conn == nil {
+		return
+	}
+	e.leader.Store(false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := e.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", e.key); err != nil {
+		slog.Warn("failed to release background job leadership", slog.String("error", err.Error()))
+		discardConn(e.conn)
+	} else {
+		e.conn.Close()
+		slog.Info("released background job leadership")
+	}
+	e.conn = nil
+}
+
+// discardConn closes conn's underlying connection instead of returning it
+// to the pool, where it could keep holding the lock
+func discardConn(conn *sql.Conn) {
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	conn.Close()
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaderElector_Check(t *testing.T) {
+	t.Run("acquires_the_lock", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT pg_try_advisory_lock").WithArgs(LeaderLockKey).
+			WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+		mock.ExpectExec("SELECT 1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(LeaderLockKey).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		e := NewLeaderElector(db, LeaderLockKey)
+		e.Check(context.Background())
+		assert.True(t, e.IsLeader())
+
+		// Still leading while the connection holding the lock is alive
+		e.Check(context.Background())
+		assert.True(t, e.IsLeader())
+
+		e.Release()
+		assert.False(t, e.IsLeader())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("lock_held_elsewhere", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT pg_try_advisory_lock").WithArgs(LeaderLockKey).
+			WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(false))
+
+		e := NewLeaderElector(db, LeaderLockKey)
+		e.Check(context.Background())
+		assert.False(t, e.IsLeader())
+
+		// Nothing to release
+		e.Release()
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("loses_leadership_with_its_connection", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT pg_try_advisory_lock").WithArgs(LeaderLockKey).
+			WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+		mock.ExpectExec("SELECT 1").WillReturnError(errors.New("connection reset"))
+
+		e := NewLeaderElector(db, LeaderLockKey)
+		e.Check(context.Background())
+		require.True(t, e.IsLeader())
+
+		// The broken connection is discarded rather than pooled, so sqlmock's
+		// only connection is gone and taking the lock again fails too
+		e.Check(context.Background())
+		assert.False(t, e.IsLeader())
+		assert.Nil(t, e.conn)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
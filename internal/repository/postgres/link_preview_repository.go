@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"jobsity-chat/internal/domain"
+)
+
+type LinkPreviewRepository struct {
+	db       *sql.DB
+	saveStmt *sql.Stmt
+}
+
+// NewLinkPreviewRepository creates a new LinkPreviewRepository with prepared statements.
+// Returns an error if statement preparation fails.
+func NewLinkPreviewRepository(db *sql.DB) (*LinkPreviewRepository, error) {
+	repo := &LinkPreviewRepository{db: db}
+
+	var err error
+	repo.saveStmt, err = db.Prepare(`
+		INSERT INTO link_previews (message_id, url, title, description, image_url, site_name)
+		SELECT m.id, $2, $3, $4, $5, $6
+		FROM messages m
+		JOIN chatrooms c ON c.id = m.chatroom_id
+		WHERE m.id = $1 AND c.org_id = $7 AND m.deleted_at IS NULL
+		ON CONFLICT (message_id, url) DO UPDATE
+		SET title = EXCLUDED.title, description = EXCLUDED.description,
+			image_url = EXCLUDED.image_url, site_name = EXCLUDED.site_name, fetched_at = NOW()
+		RETURNING fetched_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare save statement: %w", err)
+	}
+
+	return repo, nil
+}
+
+func (r *LinkPreviewRepository) Save(ctx context.Context, preview *domain.LinkPreview) error {
+	err := stmt(ctx, r.saveStmt).QueryRowContext(ctx,
+		preview.MessageID,
+		preview.URL,
+		preview.Title,
+		preview.Description,
+		preview.ImageURL,
+		preview.SiteName,
+		domain.OrgIDFromContext(ctx),
+	).Scan(&preview.FetchedAt)
+	if err == sql.ErrNoRows {
+		return domain.ErrMessageNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save link preview: %w", err)
+	}
+	return nil
+}
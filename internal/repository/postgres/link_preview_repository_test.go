@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const linkPreviewSaveQuery = `
+		INSERT INTO link_previews (message_id, url, title, description, image_url, site_name)
+		SELECT m.id, $2, $3, $4, $5, $6
+		FROM messages m
+		JOIN chatrooms c ON c.id = m.chatroom_id
+		WHERE m.id = $1 AND c.org_id = $7 AND m.deleted_at IS NULL
+		ON CONFLICT (message_id, url) DO UPDATE
+		SET title = EXCLUDED.title, description = EXCLUDED.description,
+			image_url = EXCLUDED.image_url, site_name = EXCLUDED.site_name, fetched_at = NOW()
+		RETURNING fetched_at
+	`
+
+func TestLinkPreviewRepository_Save(t *testing.T) {
+	preview := func() *domain.LinkPreview {
+		return &domain.LinkPreview{
+			MessageID:   "msg-1",
+			URL:         "https://example.com/post",
+			Title:       "A post",
+			Description: "About things",
+			ImageURL:    "https://example.com/cover.png",
+			SiteName:    "Example",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		result  func(*sqlmock.ExpectedQuery)
+		wantErr error
+	}{
+		{
+			name: "saved",
+			result: func(q *sqlmock.ExpectedQuery) {
+				q.WillReturnRows(sqlmock.NewRows([]string{"fetched_at"}).AddRow(time.Now()))
+			},
+		},
+		{
+			name:    "message_not_found",
+			result:  func(q *sqlmock.ExpectedQuery) { q.WillReturnError(sql.ErrNoRows) },
+			wantErr: domain.ErrMessageNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectPrepare(regexp.QuoteMeta(linkPreviewSaveQuery))
+			repo, err := NewLinkPreviewRepository(db)
+			require.NoError(t, err)
+
+			tt.result(mock.ExpectQuery(regexp.QuoteMeta(linkPreviewSaveQuery)).
+				WithArgs("msg-1", "https://example.com/post", "A post", "About things",
+					"https://example.com/cover.png", "Example", domain.DefaultOrganizationID))
+
+			p := preview()
+			err = repo.Save(context.Background(), p)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.False(t, p.FetchedAt.IsZero())
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("database_error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(linkPreviewSaveQuery))
+		repo, err := NewLinkPreviewRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(linkPreviewSaveQuery)).
+			WillReturnError(errors.New("database error"))
+
+		err = repo.Save(context.Background(), preview())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to save link preview")
+	})
+}
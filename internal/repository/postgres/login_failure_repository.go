@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"jobsity-chat/internal/domain"
+)
+
+type LoginFailureRepository struct {
+	db          *sql.DB
+	getStmt     *sql.Stmt
+	reserveStmt *sql.Stmt
+	releaseStmt *sql.Stmt
+	resetStmt   *sql.Stmt
+}
+
+// NewLoginFailureRepository creates a new LoginFailureRepository with
+// prepared statements. Returns an error if statement preparation fails.
+func NewLoginFailureRepository(db *sql.DB) (*LoginFailureRepository, error) {
+	repo := &LoginFailureRepository{db: db}
+
+	var err error
+	repo.getStmt, err = db.Prepare(`
+		SELECT failures, last_failed_at FROM login_failures
+		WHERE org_id = $1 AND username = $2 AND ip = $3 AND last_failed_at >= $4
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare get statement: %w", err)
+	}
+
+	// The upsert locks the row, so concurrent attempts see each other's
+	// failures. The exponent is capped to keep power() within float8.
+	repo.reserveStmt, err = db.Prepare(`
+		INSERT INTO login_failures (org_id, username, ip, failures, last_failed_at)
+		VALUES ($1, $2, $3, 1, $4)
+		ON CONFLICT (org_id, username, ip) DO UPDATE SET
+			failures = CASE WHEN login_failures.last_failed_at < $5 THEN 1 ELSE GREATEST(login_failures.failures, 0) + 1 END,
+			last_failed_at = EXCLUDED.last_failed_at
+		WHERE login_failures.last_failed_at < $5
+			OR login_failures.failures <= 0
+			OR login_failures.last_failed_at
+				+ LEAST($6::float8 * power(2, LEAST(login_failures.failures - 1, 40)), $7::float8) * interval '1 microsecond' <= $4
+		RETURNING failures, last_failed_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare reserve statement: %w", err)
+	}
+
+	repo.releaseStmt, err = db.Prepare(`
+		UPDATE login_failures SET failures = failures - 1
+		WHERE org_id = $1 AND username = $2 AND ip = $3 AND failures > 0
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare release statement: %w", err)
+	}
+
+	repo.resetStmt, err = db.Prepare(`
+		DELETE FROM login_failures WHERE org_id = $1 AND username = $2 AND ip = $3
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare reset statement: %w", err)
+	}
+
+	return repo, nil
+}
+
+func (r *LoginFailureRepository) Get(ctx context.Context, username, ip string, since time.Time) (*domain.LoginFailures, error) {
+	failures := &domain.LoginFailures{}
+	err := stmt(ctx, r.getStmt).QueryRowContext(ctx, domain.OrgIDFromContext(ctx), strings.ToLower(username), ip, since.UTC()).
+		Scan(&failures.Count, &failures.LastFailedAt)
+	if err == sql.ErrNoRows {
+		return &domain.LoginFailures{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login failures: %w", err)
+	}
+	return failures, nil
+}
+
+func (r *LoginFailureRepository) Reserve(ctx context.Context, username, ip string, at, since time.Time, baseDelay, maxDelay time.Duration) (*domain.LoginFailures, bool, error) {
+	failures := &domain.LoginFailures{}
+	err := stmt(ctx, r.reserveStmt).QueryRowContext(ctx, domain.OrgIDFromContext(ctx), strings.ToLower(username), ip, at.UTC(), since.UTC(),
+		float64(baseDelay.Microseconds()), float64(maxDelay.Microseconds())).
+		Scan(&failures.Count, &failures.LastFailedAt)
+	if err == sql.ErrNoRows {
+		// The conflicting row was kept: the last failure is too recent
+		failures, err = r.Get(ctx, username, ip, since)
+		return failures, false, err
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to reserve login attempt: %w", err)
+	}
+	return failures, true, nil
+}
+
+func (r *LoginFailureRepository) Release(ctx context.Context, username, ip string) error {
+	_, err := stmt(ctx, r.releaseStmt).ExecContext(ctx, domain.OrgIDFromContext(ctx), strings.ToLower(username), ip)
+	if err != nil {
+		return fmt.Errorf("failed to release login attempt: %w", err)
+	}
+	return nil
+}
+
+func (r *LoginFailureRepository) Reset(ctx context.Context, username, ip string) error {
+	_, err := stmt(ctx, r.resetStmt).ExecContext(ctx, domain.OrgIDFromContext(ctx), strings.ToLower(username), ip)
+	if err != nil {
+		return fmt.Errorf("failed to reset login failures: %w", err)
+	}
+	return nil
+}
+
+func (r *LoginFailureRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM login_failures WHERE last_failed_at < $1`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete login failures: %w", err)
+	}
+	return result.RowsAffected()
+}
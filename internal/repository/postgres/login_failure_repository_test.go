@@ -0,0 +1,161 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	loginFailureGetQuery = `
+		SELECT failures, last_failed_at FROM login_failures
+		WHERE org_id = $1 AND username = $2 AND ip = $3 AND last_failed_at >= $4
+	`
+	loginFailureReserveQuery = `
+		INSERT INTO login_failures (org_id, username, ip, failures, last_failed_at)
+		VALUES ($1, $2, $3, 1, $4)
+		ON CONFLICT (org_id, username, ip) DO UPDATE SET
+			failures = CASE WHEN login_failures.last_failed_at < $5 THEN 1 ELSE GREATEST(login_failures.failures, 0) + 1 END,
+			last_failed_at = EXCLUDED.last_failed_at
+		WHERE login_failures.last_failed_at < $5
+			OR login_failures.failures <= 0
+			OR login_failures.last_failed_at
+				+ LEAST($6::float8 * power(2, LEAST(login_failures.failures - 1, 40)), $7::float8) * interval '1 microsecond' <= $4
+		RETURNING failures, last_failed_at
+	`
+	loginFailureReleaseQuery = `
+		UPDATE login_failures SET failures = failures - 1
+		WHERE org_id = $1 AND username = $2 AND ip = $3 AND failures > 0
+	`
+	loginFailureResetQuery = `
+		DELETE FROM login_failures WHERE org_id = $1 AND username = $2 AND ip = $3
+	`
+)
+
+func newLoginFailureRepository(t *testing.T) (*LoginFailureRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mock.ExpectPrepare(regexp.QuoteMeta(loginFailureGetQuery))
+	mock.ExpectPrepare(regexp.QuoteMeta(loginFailureReserveQuery))
+	mock.ExpectPrepare(regexp.QuoteMeta(loginFailureReleaseQuery))
+	mock.ExpectPrepare(regexp.QuoteMeta(loginFailureResetQuery))
+
+	repo, err := NewLoginFailureRepository(db)
+	require.NoError(t, err)
+	return repo, mock
+}
+
+func TestLoginFailureRepository_Get(t *testing.T) {
+	since := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("recorded_failures", func(t *testing.T) {
+		repo, mock := newLoginFailureRepository(t)
+		last := since.Add(time.Minute)
+		mock.ExpectQuery(regexp.QuoteMeta(loginFailureGetQuery)).
+			WithArgs("org-2", "alice", "203.0.113.7", since).
+			WillReturnRows(sqlmock.NewRows([]string{"failures", "last_failed_at"}).AddRow(3, last))
+
+		failures, err := repo.Get(domain.WithOrgID(context.Background(), "org-2"), "Alice", "203.0.113.7", since)
+		require.NoError(t, err)
+		assert.Equal(t, &domain.LoginFailures{Count: 3, LastFailedAt: last}, failures)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no_failures", func(t *testing.T) {
+		repo, mock := newLoginFailureRepository(t)
+		mock.ExpectQuery(regexp.QuoteMeta(loginFailureGetQuery)).
+			WithArgs(domain.DefaultOrganizationID, "alice", "203.0.113.7", since).
+			WillReturnRows(sqlmock.NewRows([]string{"failures", "last_failed_at"}))
+
+		failures, err := repo.Get(context.Background(), "alice", "203.0.113.7", since)
+		require.NoError(t, err)
+		assert.Equal(t, 0, failures.Count)
+	})
+}
+
+func TestLoginFailureRepository_Reserve(t *testing.T) {
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	since := at.Add(-15 * time.Minute)
+
+	t.Run("accepted", func(t *testing.T) {
+		repo, mock := newLoginFailureRepository(t)
+		mock.ExpectQuery(regexp.QuoteMeta(loginFailureReserveQuery)).
+			WithArgs(domain.DefaultOrganizationID, "alice", "203.0.113.7", at, since, float64(1e6), float64(30e6)).
+			WillReturnRows(sqlmock.NewRows([]string{"failures", "last_failed_at"}).AddRow(2, at))
+
+		failures, reserved, err := repo.Reserve(context.Background(), "ALICE", "203.0.113.7", at, since, time.Second, 30*time.Second)
+		require.NoError(t, err)
+		assert.True(t, reserved)
+		assert.Equal(t, 2, failures.Count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("too_recent", func(t *testing.T) {
+		repo, mock := newLoginFailureRepository(t)
+		last := at.Add(-time.Second)
+		mock.ExpectQuery(regexp.QuoteMeta(loginFailureReserveQuery)).
+			WillReturnRows(sqlmock.NewRows([]string{"failures", "last_failed_at"}))
+		mock.ExpectQuery(regexp.QuoteMeta(loginFailureGetQuery)).
+			WithArgs(domain.DefaultOrganizationID, "alice", "203.0.113.7", since).
+			WillReturnRows(sqlmock.NewRows([]string{"failures", "last_failed_at"}).AddRow(3, last))
+
+		failures, reserved, err := repo.Reserve(context.Background(), "alice", "203.0.113.7", at, since, time.Second, 30*time.Second)
+		require.NoError(t, err)
+		assert.False(t, reserved)
+		assert.Equal(t, &domain.LoginFailures{Count: 3, LastFailedAt: last}, failures)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database_error", func(t *testing.T) {
+		repo, mock := newLoginFailureRepository(t)
+		mock.ExpectQuery(regexp.QuoteMeta(loginFailureReserveQuery)).
+			WillReturnError(errors.New("database error"))
+
+		_, _, err := repo.Reserve(context.Background(), "alice", "203.0.113.7", at, since, time.Second, 30*time.Second)
+		assert.Error(t, err)
+	})
+}
+
+func TestLoginFailureRepository_Release(t *testing.T) {
+	repo, mock := newLoginFailureRepository(t)
+	mock.ExpectExec(regexp.QuoteMeta(loginFailureReleaseQuery)).
+		WithArgs("org-2", "alice", "203.0.113.7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, repo.Release(domain.WithOrgID(context.Background(), "org-2"), "Alice", "203.0.113.7"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoginFailureRepository_Reset(t *testing.T) {
+	repo, mock := newLoginFailureRepository(t)
+	mock.ExpectExec(regexp.QuoteMeta(loginFailureResetQuery)).
+		WithArgs(domain.DefaultOrganizationID, "alice", "203.0.113.7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, repo.Reset(context.Background(), "Alice", "203.0.113.7"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoginFailureRepository_DeleteBefore(t *testing.T) {
+	repo, mock := newLoginFailureRepository(t)
+	before := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`DELETE FROM login_failures WHERE last_failed_at < \$1`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	deleted, err := repo.DeleteBefore(context.Background(), before)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,7 +3,9 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"jobsity-chat/internal/domain"
 )
@@ -13,6 +15,7 @@ type MessageRepository struct {
 	createStmt              *sql.Stmt
 	getByChatroomStmt       *sql.Stmt
 	getByChatroomBeforeStmt *sql.Stmt
+	getByChatroomSinceStmt  *sql.Stmt
 }
 
 // NewMessageRepository creates a new MessageRepository with prepared statements.
@@ -22,21 +25,33 @@ func NewMessageRepository(db *sql.DB) (*MessageRepository, error) {
 
 	var err error
 	repo.createStmt, err = db.Prepare(`
-		INSERT INTO messages (chatroom_id, user_id, content, is_bot)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at
+		INSERT INTO messages (chatroom_id, user_id, content, is_bot, org_id,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id,
+			client_msg_id)
+		SELECT $1, $2, $3, $4, org_id, $6, $7, $8, $9, $10, $11, $12, $13
+		FROM chatrooms WHERE id = $1 AND deleted_at IS NULL AND encrypted = $5
+		ON CONFLICT (chatroom_id, user_id, client_msg_id) WHERE client_msg_id IS NOT NULL DO NOTHING
+		RETURNING id, created_at, seq
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare create statement: %w", err)
 	}
 
 	repo.getByChatroomStmt, err = db.Prepare(`
-		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at
+		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at, seq,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id
 		FROM (
-			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at
+			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at, m.seq,
+				m.forwarded_from_id, m.forwarded_from_chatroom_id, m.forwarded_from_user_id, m.forwarded_from_username, m.quote, m.event, m.parent_message_id
 			FROM messages m
 			JOIN users u ON m.user_id = u.id
-			WHERE m.chatroom_id = $1
+			WHERE m.chatroom_id = $1 AND m.org_id = $3 AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM chatroom_shadow_bans b
+					WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM $4
+						AND (b.user_id = m.user_id
+							OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+				)
 			ORDER BY m.created_at DESC
 			LIMIT $2
 		) AS recent_messages
@@ -47,12 +62,20 @@ func NewMessageRepository(db *sql.DB) (*MessageRepository, error) {
 	}
 
 	repo.getByChatroomBeforeStmt, err = db.Prepare(`
-		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at
+		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at, seq,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id
 		FROM (
-			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at
+			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at, m.seq,
+				m.forwarded_from_id, m.forwarded_from_chatroom_id, m.forwarded_from_user_id, m.forwarded_from_username, m.quote, m.event, m.parent_message_id
 			FROM messages m
 			JOIN users u ON m.user_id = u.id
-			WHERE m.chatroom_id = $1 AND m.id < $2
+			WHERE m.chatroom_id = $1 AND m.id < $2 AND m.org_id = $4 AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM chatroom_shadow_bans b
+					WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM $5
+						AND (b.user_id = m.user_id
+							OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+				)
 			ORDER BY m.created_at DESC
 			LIMIT $3
 		) AS earlier_messages
@@ -62,25 +85,128 @@ func NewMessageRepository(db *sql.DB) (*MessageRepository, error) {
 		return nil, fmt.Errorf("failed to prepare getByChatroomBefore statement: %w", err)
 	}
 
+	// Messages are ordered by (created_at, id) so that messages sharing a
+	// timestamp with the since message are neither skipped nor repeated.
+	// Like the other history statements, it leaves out what shadow-banned
+	// users posted, and the bot answers to their commands, unless they are
+	// the viewer.
+	repo.getByChatroomSinceStmt, err = db.Prepare(`
+		SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at, m.seq,
+			m.forwarded_from_id, m.forwarded_from_chatroom_id, m.forwarded_from_user_id, m.forwarded_from_username, m.quote, m.event, m.parent_message_id
+		FROM messages m
+		JOIN users u ON m.user_id = u.id
+		JOIN messages since ON since.id = $2 AND since.chatroom_id = m.chatroom_id
+		WHERE m.chatroom_id = $1 AND m.org_id = $4 AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+			AND (m.created_at, m.id) > (since.created_at, since.id)
+			AND NOT EXISTS (
+				SELECT 1 FROM chatroom_shadow_bans b
+				WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM $5
+					AND (b.user_id = m.user_id
+						OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+			)
+		ORDER BY m.created_at ASC, m.id ASC
+		LIMIT $3
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare getByChatroomSince statement: %w", err)
+	}
+
 	return repo, nil
 }
 
+// Create stores message in the organization of its chatroom, so messages
+// posted outside a request scope (e.g. bot responses) land in the right tenant
 func (r *MessageRepository) Create(ctx context.Context, message *domain.Message) error {
-	err := r.createStmt.QueryRowContext(ctx,
+	var forwardedFromID, forwardedFromChatroomID, forwardedFromUserID, forwardedFromUsername sql.NullString
+	if f := message.ForwardedFrom; f != nil {
+		forwardedFromID = sql.NullString{String: f.MessageID, Valid: f.MessageID != ""}
+		forwardedFromChatroomID = sql.NullString{String: f.ChatroomID, Valid: f.ChatroomID != ""}
+		forwardedFromUserID = sql.NullString{String: f.UserID, Valid: f.UserID != ""}
+		forwardedFromUsername = sql.NullString{String: f.Username, Valid: true}
+	}
+	var quote any
+	if message.Quote != nil {
+		data, err := json.Marshal(message.Quote)
+		if err != nil {
+			return fmt.Errorf("failed to encode quote: %w", err)
+		}
+		quote = data
+	}
+	var event any
+	if message.Event != nil {
+		data, err := json.Marshal(message.Event)
+		if err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+		event = data
+	}
+	parentMessageID := sql.NullString{String: message.ParentMessageID, Valid: message.ParentMessageID != ""}
+	clientMsgID := sql.NullString{String: message.ClientMsgID, Valid: message.ClientMsgID != ""}
+	err := stmt(ctx, r.createStmt).QueryRowContext(ctx,
 		message.ChatroomID,
 		message.UserID,
 		message.Content,
 		message.IsBot,
-	).Scan(&message.ID, &message.CreatedAt)
+		message.Encrypted,
+		forwardedFromID, forwardedFromChatroomID, forwardedFromUserID, forwardedFromUsername,
+		quote, event, parentMessageID, clientMsgID,
+	).Scan(&message.ID, &message.CreatedAt, &message.Seq)
 
+	if err == sql.ErrNoRows && clientMsgID.Valid {
+		// Either the chatroom is gone or the sender already stored the
+		// message
+		err = conn(ctx, r.db).QueryRowContext(ctx, `
+			SELECT id, created_at, seq FROM messages WHERE chatroom_id = $1 AND user_id = $2 AND client_msg_id = $3
+		`, message.ChatroomID, message.UserID, message.ClientMsgID).Scan(&message.ID, &message.CreatedAt, &message.Seq)
+		if err == nil {
+			return domain.ErrDuplicateMessage
+		}
+	}
+	if err == sql.ErrNoRows {
+		return domain.ErrChatroomNotFound
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create message: %w", err)
 	}
 	return nil
 }
 
+func (r *MessageRepository) GetByID(ctx context.Context, id string) (*domain.Message, error) {
+	query := `
+		SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at, m.seq,
+			m.forwarded_from_id, m.forwarded_from_chatroom_id, m.forwarded_from_user_id, m.forwarded_from_username, m.quote, m.event, m.parent_message_id
+		FROM messages m
+		JOIN users u ON m.user_id = u.id
+		WHERE m.id = $1 AND m.org_id = $2 AND m.deleted_at IS NULL
+	`
+	msg := &domain.Message{}
+	var extra messageColumns
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, id, domain.OrgIDFromContext(ctx)).Scan(
+		&msg.ID,
+		&msg.ChatroomID,
+		&msg.UserID,
+		&msg.Username,
+		&msg.Content,
+		&msg.IsBot,
+		&msg.CreatedAt,
+		&msg.Seq,
+		&extra.forwardedFromID, &extra.forwardedFromChatroomID, &extra.forwardedFromUserID, &extra.forwardedFromUsername,
+		&extra.quote, &extra.event, &extra.parentMessageID,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message by ID: %w", err)
+	}
+	if err := extra.apply(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
 func (r *MessageRepository) GetByChatroom(ctx context.Context, chatroomID string, limit int) ([]*domain.Message, error) {
-	rows, err := r.getByChatroomStmt.QueryContext(ctx, chatroomID, limit)
+	rows, err := stmt(ctx, r.getByChatroomStmt).QueryContext(ctx, chatroomID, limit, domain.OrgIDFromContext(ctx), viewerID(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to query messages: %w", err)
 	}
@@ -89,6 +215,7 @@ func (r *MessageRepository) GetByChatroom(ctx context.Context, chatroomID string
 	messages := make([]*domain.Message, 0, limit)
 	for rows.Next() {
 		msg := &domain.Message{}
+		var extra messageColumns
 		err := rows.Scan(
 			&msg.ID,
 			&msg.ChatroomID,
@@ -97,10 +224,16 @@ func (r *MessageRepository) GetByChatroom(ctx context.Context, chatroomID string
 			&msg.Content,
 			&msg.IsBot,
 			&msg.CreatedAt,
+			&msg.Seq,
+			&extra.forwardedFromID, &extra.forwardedFromChatroomID, &extra.forwardedFromUserID, &extra.forwardedFromUsername,
+			&extra.quote, &extra.event, &extra.parentMessageID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan message: %w", err)
 		}
+		if err := extra.apply(msg); err != nil {
+			return nil, err
+		}
 		messages = append(messages, msg)
 	}
 
@@ -112,7 +245,7 @@ func (r *MessageRepository) GetByChatroom(ctx context.Context, chatroomID string
 }
 
 func (r *MessageRepository) GetByChatroomBefore(ctx context.Context, chatroomID string, before string, limit int) ([]*domain.Message, error) {
-	rows, err := r.getByChatroomBeforeStmt.QueryContext(ctx, chatroomID, before, limit)
+	rows, err := stmt(ctx, r.getByChatroomBeforeStmt).QueryContext(ctx, chatroomID, before, limit, domain.OrgIDFromContext(ctx), viewerID(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to query messages before timestamp: %w", err)
 	}
@@ -121,6 +254,46 @@ func (r *MessageRepository) GetByChatroomBefore(ctx context.Context, chatroomID
 	messages := make([]*domain.Message, 0, limit)
 	for rows.Next() {
 		msg := &domain.Message{}
+		var extra messageColumns
+		err := rows.Scan(
+			&msg.ID,
+			&msg.ChatroomID,
+			&msg.UserID,
+			&msg.Username,
+			&msg.Content,
+			&msg.IsBot,
+			&msg.CreatedAt,
+			&msg.Seq,
+			&extra.forwardedFromID, &extra.forwardedFromChatroomID, &extra.forwardedFromUserID, &extra.forwardedFromUsername,
+			&extra.quote, &extra.event, &extra.parentMessageID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		if err := extra.apply(msg); err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (r *MessageRepository) GetByChatroomSince(ctx context.Context, chatroomID string, sinceID string, limit int) ([]*domain.Message, error) {
+	rows, err := stmt(ctx, r.getByChatroomSinceStmt).QueryContext(ctx, chatroomID, sinceID, limit, domain.OrgIDFromContext(ctx), viewerID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages since message: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]*domain.Message, 0, limit)
+	for rows.Next() {
+		msg := &domain.Message{}
+		var extra messageColumns
 		err := rows.Scan(
 			&msg.ID,
 			&msg.ChatroomID,
@@ -129,10 +302,16 @@ func (r *MessageRepository) GetByChatroomBefore(ctx context.Context, chatroomID
 			&msg.Content,
 			&msg.IsBot,
 			&msg.CreatedAt,
+			&msg.Seq,
+			&extra.forwardedFromID, &extra.forwardedFromChatroomID, &extra.forwardedFromUserID, &extra.forwardedFromUsername,
+			&extra.quote, &extra.event, &extra.parentMessageID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan message: %w", err)
 		}
+		if err := extra.apply(msg); err != nil {
+			return nil, err
+		}
 		messages = append(messages, msg)
 	}
 
@@ -142,3 +321,68 @@ func (r *MessageRepository) GetByChatroomBefore(ctx context.Context, chatroomID
 
 	return messages, nil
 }
+
+func (r *MessageRepository) Restore(ctx context.Context, id string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE messages SET deleted_at = NULL
+		WHERE id = $1 AND org_id = $2 AND deleted_at IS NOT NULL
+	`, id, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to restore message: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrMessageNotFound
+	}
+	return nil
+}
+
+// PurgeDeleted spans every organization
+func (r *MessageRepository) PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM messages WHERE deleted_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted messages: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return count, nil
+}
+
+// messageColumns scans the nullable forwarded_from_*, quote, event and
+// parent_message_id columns of a message
+type messageColumns struct {
+	forwardedFromID, forwardedFromChatroomID, forwardedFromUserID, forwardedFromUsername sql.NullString
+	quote, event                                                                         []byte
+	parentMessageID                                                                      sql.NullString
+}
+
+// apply sets the ForwardedFrom, Quote and Event of msg, left nil unless it
+// was forwarded, quotes another message or announces an event, and its
+// ParentMessageID
+func (c *messageColumns) apply(msg *domain.Message) error {
+	msg.ParentMessageID = c.parentMessageID.String
+	if c.forwardedFromUsername.Valid {
+		msg.ForwardedFrom = &domain.ForwardedFrom{
+			MessageID:  c.forwardedFromID.String,
+			ChatroomID: c.forwardedFromChatroomID.String,
+			UserID:     c.forwardedFromUserID.String,
+			Username:   c.forwardedFromUsername.String,
+		}
+	}
+	if c.quote != nil {
+		msg.Quote = &domain.Quote{}
+		if err := json.Unmarshal(c.quote, msg.Quote); err != nil {
+			return fmt.Errorf("failed to decode quote: %w", err)
+		}
+	}
+	if c.event != nil {
+		msg.Event = &domain.CalendarEvent{}
+		if err := json.Unmarshal(c.event, msg.Event); err != nil {
+			return fmt.Errorf("failed to decode event: %w", err)
+		}
+	}
+	return nil
+}
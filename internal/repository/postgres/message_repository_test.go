@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"regexp"
 	"testing"
@@ -34,9 +35,13 @@ func TestNewMessageRepository(t *testing.T) {
 		defer db.Close()
 
 		mock.ExpectPrepare(regexp.QuoteMeta(`
-		INSERT INTO messages (chatroom_id, user_id, content, is_bot)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at
+		INSERT INTO messages (chatroom_id, user_id, content, is_bot, org_id,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id,
+			client_msg_id)
+		SELECT $1, $2, $3, $4, org_id, $6, $7, $8, $9, $10, $11, $12, $13
+		FROM chatrooms WHERE id = $1 AND deleted_at IS NULL AND encrypted = $5
+		ON CONFLICT (chatroom_id, user_id, client_msg_id) WHERE client_msg_id IS NOT NULL DO NOTHING
+		RETURNING id, created_at, seq
 	`)).WillReturnError(errors.New("prepare failed"))
 
 		repo, err := NewMessageRepository(db)
@@ -61,13 +66,17 @@ func TestMessageRepository_Create(t *testing.T) {
 		createdAt := time.Now()
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		INSERT INTO messages (chatroom_id, user_id, content, is_bot)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at
+		INSERT INTO messages (chatroom_id, user_id, content, is_bot, org_id,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id,
+			client_msg_id)
+		SELECT $1, $2, $3, $4, org_id, $6, $7, $8, $9, $10, $11, $12, $13
+		FROM chatrooms WHERE id = $1 AND deleted_at IS NULL AND encrypted = $5
+		ON CONFLICT (chatroom_id, user_id, client_msg_id) WHERE client_msg_id IS NOT NULL DO NOTHING
+		RETURNING id, created_at, seq
 	`)).
-			WithArgs("room-123", "user-123", "Hello World", false).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).
-				AddRow(messageID, createdAt))
+			WithArgs("room-123", "user-123", "Hello World", false, false, nil, nil, nil, nil, nil, nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "seq"}).
+				AddRow(messageID, createdAt, int64(42)))
 
 		message := &domain.Message{
 			ChatroomID: "room-123",
@@ -83,6 +92,33 @@ func TestMessageRepository_Create(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("create_forwarded_message", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupMessageRepositoryMocks(mock)
+
+		repo, err := NewMessageRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(`INSERT INTO messages`).
+			WithArgs("room-456", "user-123", "Hello World", false, false, "msg-1", "room-123", "user-2", "bob", nil, nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "seq"}).
+				AddRow("msg-2", time.Now(), int64(43)))
+
+		message := &domain.Message{
+			ChatroomID: "room-456",
+			UserID:     "user-123",
+			Content:    "Hello World",
+			ForwardedFrom: &domain.ForwardedFrom{
+				MessageID: "msg-1", ChatroomID: "room-123", UserID: "user-2", Username: "bob",
+			},
+		}
+		require.NoError(t, repo.Create(context.Background(), message))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("create_bot_message", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		require.NoError(t, err)
@@ -97,19 +133,25 @@ func TestMessageRepository_Create(t *testing.T) {
 		createdAt := time.Now()
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		INSERT INTO messages (chatroom_id, user_id, content, is_bot)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at
+		INSERT INTO messages (chatroom_id, user_id, content, is_bot, org_id,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id,
+			client_msg_id)
+		SELECT $1, $2, $3, $4, org_id, $6, $7, $8, $9, $10, $11, $12, $13
+		FROM chatrooms WHERE id = $1 AND deleted_at IS NULL AND encrypted = $5
+		ON CONFLICT (chatroom_id, user_id, client_msg_id) WHERE client_msg_id IS NOT NULL DO NOTHING
+		RETURNING id, created_at, seq
 	`)).
-			WithArgs("room-123", "bot-user", "AAPL.US quote is $150.00", true).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).
-				AddRow(messageID, createdAt))
+			WithArgs("room-123", "bot-user", "AAPL.US quote is $150.00", true, false, nil, nil, nil, nil, nil, nil, "msg-123", nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "seq"}).
+				AddRow(messageID, createdAt, int64(42)))
 
 		message := &domain.Message{
 			ChatroomID: "room-123",
 			UserID:     "bot-user",
 			Content:    "AAPL.US quote is $150.00",
 			IsBot:      true,
+			// Answers are threaded replies to the command
+			ParentMessageID: "msg-123",
 		}
 
 		err = repo.Create(context.Background(), message)
@@ -119,6 +161,70 @@ func TestMessageRepository_Create(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("chatroom_not_found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupMessageRepositoryMocks(mock)
+
+		repo, err := NewMessageRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`
+		INSERT INTO messages (chatroom_id, user_id, content, is_bot, org_id,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id,
+			client_msg_id)
+		SELECT $1, $2, $3, $4, org_id, $6, $7, $8, $9, $10, $11, $12, $13
+		FROM chatrooms WHERE id = $1 AND deleted_at IS NULL AND encrypted = $5
+		ON CONFLICT (chatroom_id, user_id, client_msg_id) WHERE client_msg_id IS NOT NULL DO NOTHING
+		RETURNING id, created_at, seq
+	`)).
+			WithArgs("missing-room", "user-123", "Hello World", false, false, nil, nil, nil, nil, nil, nil, nil, nil).
+			WillReturnError(sql.ErrNoRows)
+
+		err = repo.Create(context.Background(), &domain.Message{
+			ChatroomID: "missing-room",
+			UserID:     "user-123",
+			Content:    "Hello World",
+		})
+		assert.ErrorIs(t, err, domain.ErrChatroomNotFound)
+	})
+
+	t.Run("duplicate_client_msg_id", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupMessageRepositoryMocks(mock)
+
+		repo, err := NewMessageRepository(db)
+		require.NoError(t, err)
+
+		createdAt := time.Now()
+		mock.ExpectQuery(`INSERT INTO messages`).
+			WithArgs("room-123", "user-123", "Hello World", false, false, nil, nil, nil, nil, nil, nil, nil, "c-1").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(regexp.QuoteMeta(`
+			SELECT id, created_at, seq FROM messages WHERE chatroom_id = $1 AND user_id = $2 AND client_msg_id = $3
+		`)).
+			WithArgs("room-123", "user-123", "c-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "seq"}).
+				AddRow("msg-1", createdAt, int64(7)))
+
+		message := &domain.Message{
+			ChatroomID:  "room-123",
+			UserID:      "user-123",
+			Content:     "Hello World",
+			ClientMsgID: "c-1",
+		}
+		err = repo.Create(context.Background(), message)
+		assert.ErrorIs(t, err, domain.ErrDuplicateMessage)
+		assert.Equal(t, "msg-1", message.ID)
+		assert.Equal(t, int64(7), message.Seq)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("database_error", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		require.NoError(t, err)
@@ -130,9 +236,13 @@ func TestMessageRepository_Create(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		INSERT INTO messages (chatroom_id, user_id, content, is_bot)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at
+		INSERT INTO messages (chatroom_id, user_id, content, is_bot, org_id,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id,
+			client_msg_id)
+		SELECT $1, $2, $3, $4, org_id, $6, $7, $8, $9, $10, $11, $12, $13
+		FROM chatrooms WHERE id = $1 AND deleted_at IS NULL AND encrypted = $5
+		ON CONFLICT (chatroom_id, user_id, client_msg_id) WHERE client_msg_id IS NOT NULL DO NOTHING
+		RETURNING id, created_at, seq
 	`)).
 			WillReturnError(errors.New("database error"))
 
@@ -162,21 +272,30 @@ func TestMessageRepository_GetByChatroom(t *testing.T) {
 
 		createdAt := time.Now()
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at
+		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at, seq,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id
 		FROM (
-			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at
+			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at, m.seq,
+				m.forwarded_from_id, m.forwarded_from_chatroom_id, m.forwarded_from_user_id, m.forwarded_from_username, m.quote, m.event, m.parent_message_id
 			FROM messages m
 			JOIN users u ON m.user_id = u.id
-			WHERE m.chatroom_id = $1
+			WHERE m.chatroom_id = $1 AND m.org_id = $3 AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM chatroom_shadow_bans b
+					WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM $4
+						AND (b.user_id = m.user_id
+							OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+				)
 			ORDER BY m.created_at DESC
 			LIMIT $2
 		) AS recent_messages
 		ORDER BY created_at ASC
 	`)).
-			WithArgs("room-123", 10).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "user_id", "username", "content", "is_bot", "created_at"}).
-				AddRow("msg-1", "room-123", "user-1", "Alice", "Hello", false, createdAt).
-				AddRow("msg-2", "room-123", "user-2", "Bob", "Hi", false, createdAt.Add(1*time.Second)))
+			WithArgs("room-123", 10, domain.DefaultOrganizationID, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "user_id", "username", "content", "is_bot", "created_at", "seq",
+				"forwarded_from_id", "forwarded_from_chatroom_id", "forwarded_from_user_id", "forwarded_from_username", "quote", "event", "parent_message_id"}).
+				AddRow("msg-1", "room-123", "user-1", "Alice", "Hello", false, createdAt, int64(1), nil, nil, nil, nil, nil, nil, nil).
+				AddRow("msg-2", "room-123", "user-2", "Bob", "Hi", false, createdAt.Add(1*time.Second), int64(2), nil, nil, nil, nil, nil, nil, nil))
 
 		messages, err := repo.GetByChatroom(context.Background(), "room-123", 10)
 		require.NoError(t, err)
@@ -199,19 +318,28 @@ func TestMessageRepository_GetByChatroom(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at
+		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at, seq,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id
 		FROM (
-			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at
+			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at, m.seq,
+				m.forwarded_from_id, m.forwarded_from_chatroom_id, m.forwarded_from_user_id, m.forwarded_from_username, m.quote, m.event, m.parent_message_id
 			FROM messages m
 			JOIN users u ON m.user_id = u.id
-			WHERE m.chatroom_id = $1
+			WHERE m.chatroom_id = $1 AND m.org_id = $3 AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM chatroom_shadow_bans b
+					WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM $4
+						AND (b.user_id = m.user_id
+							OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+				)
 			ORDER BY m.created_at DESC
 			LIMIT $2
 		) AS recent_messages
 		ORDER BY created_at ASC
 	`)).
-			WithArgs("room-123", 10).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "user_id", "username", "content", "is_bot", "created_at"}))
+			WithArgs("room-123", 10, domain.DefaultOrganizationID, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "user_id", "username", "content", "is_bot", "created_at", "seq",
+				"forwarded_from_id", "forwarded_from_chatroom_id", "forwarded_from_user_id", "forwarded_from_username", "quote", "event", "parent_message_id"}))
 
 		messages, err := repo.GetByChatroom(context.Background(), "room-123", 10)
 		require.NoError(t, err)
@@ -232,24 +360,33 @@ func TestMessageRepository_GetByChatroom(t *testing.T) {
 
 		createdAt := time.Now()
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at
+		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at, seq,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id
 		FROM (
-			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at
+			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at, m.seq,
+				m.forwarded_from_id, m.forwarded_from_chatroom_id, m.forwarded_from_user_id, m.forwarded_from_username, m.quote, m.event, m.parent_message_id
 			FROM messages m
 			JOIN users u ON m.user_id = u.id
-			WHERE m.chatroom_id = $1
+			WHERE m.chatroom_id = $1 AND m.org_id = $3 AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM chatroom_shadow_bans b
+					WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM $4
+						AND (b.user_id = m.user_id
+							OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+				)
 			ORDER BY m.created_at DESC
 			LIMIT $2
 		) AS recent_messages
 		ORDER BY created_at ASC
 	`)).
-			WithArgs("room-123", 5).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "user_id", "username", "content", "is_bot", "created_at"}).
-				AddRow("msg-1", "room-123", "user-1", "Alice", "Message 1", false, createdAt).
-				AddRow("msg-2", "room-123", "user-1", "Alice", "Message 2", false, createdAt.Add(1*time.Second)).
-				AddRow("msg-3", "room-123", "user-1", "Alice", "Message 3", false, createdAt.Add(2*time.Second)).
-				AddRow("msg-4", "room-123", "user-1", "Alice", "Message 4", false, createdAt.Add(3*time.Second)).
-				AddRow("msg-5", "room-123", "user-1", "Alice", "Message 5", false, createdAt.Add(4*time.Second)))
+			WithArgs("room-123", 5, domain.DefaultOrganizationID, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "user_id", "username", "content", "is_bot", "created_at", "seq",
+				"forwarded_from_id", "forwarded_from_chatroom_id", "forwarded_from_user_id", "forwarded_from_username", "quote", "event", "parent_message_id"}).
+				AddRow("msg-1", "room-123", "user-1", "Alice", "Message 1", false, createdAt, int64(1), nil, nil, nil, nil, nil, nil, nil).
+				AddRow("msg-2", "room-123", "user-1", "Alice", "Message 2", false, createdAt.Add(1*time.Second), int64(2), nil, nil, nil, nil, nil, nil, nil).
+				AddRow("msg-3", "room-123", "user-1", "Alice", "Message 3", false, createdAt.Add(2*time.Second), int64(3), nil, nil, nil, nil, nil, nil, nil).
+				AddRow("msg-4", "room-123", "user-1", "Alice", "Message 4", false, createdAt.Add(3*time.Second), int64(4), nil, nil, nil, nil, nil, nil, nil).
+				AddRow("msg-5", "room-123", "user-1", "Alice", "Message 5", false, createdAt.Add(4*time.Second), int64(5), nil, nil, nil, nil, nil, nil, nil))
 
 		messages, err := repo.GetByChatroom(context.Background(), "room-123", 5)
 		require.NoError(t, err)
@@ -268,18 +405,26 @@ func TestMessageRepository_GetByChatroom(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at
+		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at, seq,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id
 		FROM (
-			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at
+			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at, m.seq,
+				m.forwarded_from_id, m.forwarded_from_chatroom_id, m.forwarded_from_user_id, m.forwarded_from_username, m.quote, m.event, m.parent_message_id
 			FROM messages m
 			JOIN users u ON m.user_id = u.id
-			WHERE m.chatroom_id = $1
+			WHERE m.chatroom_id = $1 AND m.org_id = $3 AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM chatroom_shadow_bans b
+					WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM $4
+						AND (b.user_id = m.user_id
+							OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+				)
 			ORDER BY m.created_at DESC
 			LIMIT $2
 		) AS recent_messages
 		ORDER BY created_at ASC
 	`)).
-			WithArgs("room-123", 10).
+			WithArgs("room-123", 10, domain.DefaultOrganizationID, nil).
 			WillReturnError(errors.New("database error"))
 
 		messages, err := repo.GetByChatroom(context.Background(), "room-123", 10)
@@ -302,21 +447,30 @@ func TestMessageRepository_GetByChatroomBefore(t *testing.T) {
 
 		createdAt := time.Now()
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at
+		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at, seq,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id
 		FROM (
-			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at
+			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at, m.seq,
+				m.forwarded_from_id, m.forwarded_from_chatroom_id, m.forwarded_from_user_id, m.forwarded_from_username, m.quote, m.event, m.parent_message_id
 			FROM messages m
 			JOIN users u ON m.user_id = u.id
-			WHERE m.chatroom_id = $1 AND m.id < $2
+			WHERE m.chatroom_id = $1 AND m.id < $2 AND m.org_id = $4 AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM chatroom_shadow_bans b
+					WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM $5
+						AND (b.user_id = m.user_id
+							OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+				)
 			ORDER BY m.created_at DESC
 			LIMIT $3
 		) AS earlier_messages
 		ORDER BY created_at ASC
 	`)).
-			WithArgs("room-123", "msg-100", 10).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "user_id", "username", "content", "is_bot", "created_at"}).
-				AddRow("msg-99", "room-123", "user-1", "Alice", "Message 99", false, createdAt).
-				AddRow("msg-98", "room-123", "user-2", "Bob", "Message 98", false, createdAt.Add(1*time.Second)))
+			WithArgs("room-123", "msg-100", 10, domain.DefaultOrganizationID, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "user_id", "username", "content", "is_bot", "created_at", "seq",
+				"forwarded_from_id", "forwarded_from_chatroom_id", "forwarded_from_user_id", "forwarded_from_username", "quote", "event", "parent_message_id"}).
+				AddRow("msg-99", "room-123", "user-1", "Alice", "Message 99", false, createdAt, int64(99), nil, nil, nil, nil, nil, nil, nil).
+				AddRow("msg-98", "room-123", "user-2", "Bob", "Message 98", false, createdAt.Add(1*time.Second), int64(98), nil, nil, nil, nil, nil, nil, nil))
 
 		messages, err := repo.GetByChatroomBefore(context.Background(), "room-123", "msg-100", 10)
 		require.NoError(t, err)
@@ -336,19 +490,28 @@ func TestMessageRepository_GetByChatroomBefore(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at
+		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at, seq,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id
 		FROM (
-			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at
+			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at, m.seq,
+				m.forwarded_from_id, m.forwarded_from_chatroom_id, m.forwarded_from_user_id, m.forwarded_from_username, m.quote, m.event, m.parent_message_id
 			FROM messages m
 			JOIN users u ON m.user_id = u.id
-			WHERE m.chatroom_id = $1 AND m.id < $2
+			WHERE m.chatroom_id = $1 AND m.id < $2 AND m.org_id = $4 AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM chatroom_shadow_bans b
+					WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM $5
+						AND (b.user_id = m.user_id
+							OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+				)
 			ORDER BY m.created_at DESC
 			LIMIT $3
 		) AS earlier_messages
 		ORDER BY created_at ASC
 	`)).
-			WithArgs("room-123", "msg-1", 10).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "user_id", "username", "content", "is_bot", "created_at"}))
+			WithArgs("room-123", "msg-1", 10, domain.DefaultOrganizationID, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "user_id", "username", "content", "is_bot", "created_at", "seq",
+				"forwarded_from_id", "forwarded_from_chatroom_id", "forwarded_from_user_id", "forwarded_from_username", "quote", "event", "parent_message_id"}))
 
 		messages, err := repo.GetByChatroomBefore(context.Background(), "room-123", "msg-1", 10)
 		require.NoError(t, err)
@@ -368,18 +531,26 @@ func TestMessageRepository_GetByChatroomBefore(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at
+		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at, seq,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id
 		FROM (
-			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at
+			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at, m.seq,
+				m.forwarded_from_id, m.forwarded_from_chatroom_id, m.forwarded_from_user_id, m.forwarded_from_username, m.quote, m.event, m.parent_message_id
 			FROM messages m
 			JOIN users u ON m.user_id = u.id
-			WHERE m.chatroom_id = $1 AND m.id < $2
+			WHERE m.chatroom_id = $1 AND m.id < $2 AND m.org_id = $4 AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM chatroom_shadow_bans b
+					WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM $5
+						AND (b.user_id = m.user_id
+							OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+				)
 			ORDER BY m.created_at DESC
 			LIMIT $3
 		) AS earlier_messages
 		ORDER BY created_at ASC
 	`)).
-			WithArgs("room-123", "msg-100", 10).
+			WithArgs("room-123", "msg-100", 10, domain.DefaultOrganizationID, nil).
 			WillReturnError(errors.New("database error"))
 
 		messages, err := repo.GetByChatroomBefore(context.Background(), "room-123", "msg-100", 10)
@@ -389,21 +560,331 @@ func TestMessageRepository_GetByChatroomBefore(t *testing.T) {
 	})
 }
 
+func TestMessageRepository_GetByChatroomSince(t *testing.T) {
+	t.Run("successful_retrieval", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupMessageRepositoryMocks(mock)
+
+		repo, err := NewMessageRepository(db)
+		require.NoError(t, err)
+
+		createdAt := time.Now()
+		mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at, m.seq,
+			m.forwarded_from_id, m.forwarded_from_chatroom_id, m.forwarded_from_user_id, m.forwarded_from_username, m.quote, m.event, m.parent_message_id
+		FROM messages m
+		JOIN users u ON m.user_id = u.id
+		JOIN messages since ON since.id = $2 AND since.chatroom_id = m.chatroom_id
+		WHERE m.chatroom_id = $1 AND m.org_id = $4 AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+			AND (m.created_at, m.id) > (since.created_at, since.id)
+			AND NOT EXISTS (
+					SELECT 1 FROM chatroom_shadow_bans b
+					WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM $5
+						AND (b.user_id = m.user_id
+							OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+				)
+		ORDER BY m.created_at ASC, m.id ASC
+		LIMIT $3
+	`)).
+			WithArgs("room-123", "msg-100", 10, domain.DefaultOrganizationID, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "user_id", "username", "content", "is_bot", "created_at", "seq",
+				"forwarded_from_id", "forwarded_from_chatroom_id", "forwarded_from_user_id", "forwarded_from_username", "quote", "event", "parent_message_id"}).
+				AddRow("msg-101", "room-123", "user-1", "Alice", "Message 101", false, createdAt, int64(101), nil, nil, nil, nil, nil, nil, nil).
+				AddRow("msg-102", "room-123", "user-2", "Bob", "Message 102", false, createdAt.Add(1*time.Second), int64(102), nil, nil, nil, nil, nil, nil, nil))
+
+		messages, err := repo.GetByChatroomSince(context.Background(), "room-123", "msg-100", 10)
+		require.NoError(t, err)
+		assert.Len(t, messages, 2)
+		assert.Equal(t, "msg-101", messages[0].ID)
+		assert.Equal(t, "Bob", messages[1].Username)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database_error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupMessageRepositoryMocks(mock)
+
+		repo, err := NewMessageRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at, m.seq,
+			m.forwarded_from_id, m.forwarded_from_chatroom_id, m.forwarded_from_user_id, m.forwarded_from_username, m.quote, m.event, m.parent_message_id
+		FROM messages m
+		JOIN users u ON m.user_id = u.id
+		JOIN messages since ON since.id = $2 AND since.chatroom_id = m.chatroom_id
+		WHERE m.chatroom_id = $1 AND m.org_id = $4 AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+			AND (m.created_at, m.id) > (since.created_at, since.id)
+			AND NOT EXISTS (
+					SELECT 1 FROM chatroom_shadow_bans b
+					WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM $5
+						AND (b.user_id = m.user_id
+							OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+				)
+		ORDER BY m.created_at ASC, m.id ASC
+		LIMIT $3
+	`)).
+			WithArgs("room-123", "msg-100", 10, domain.DefaultOrganizationID, nil).
+			WillReturnError(errors.New("connection lost"))
+
+		messages, err := repo.GetByChatroomSince(context.Background(), "room-123", "msg-100", 10)
+		require.Error(t, err)
+		assert.Nil(t, messages)
+		assert.Contains(t, err.Error(), "failed to query messages since message")
+	})
+}
+
+func TestMessageRepository_GetByID(t *testing.T) {
+	query := regexp.QuoteMeta(`
+		SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at, m.seq,
+			m.forwarded_from_id, m.forwarded_from_chatroom_id, m.forwarded_from_user_id, m.forwarded_from_username, m.quote, m.event, m.parent_message_id
+		FROM messages m
+		JOIN users u ON m.user_id = u.id
+		WHERE m.id = $1 AND m.org_id = $2 AND m.deleted_at IS NULL
+	`)
+
+	t.Run("message_found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupMessageRepositoryMocks(mock)
+
+		repo, err := NewMessageRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(query).
+			WithArgs("msg-1", domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "user_id", "username", "content", "is_bot", "created_at", "seq",
+				"forwarded_from_id", "forwarded_from_chatroom_id", "forwarded_from_user_id", "forwarded_from_username", "quote", "event", "parent_message_id"}).
+				AddRow("msg-1", "room-123", "user-1", "Alice", "Hello", false, time.Now(), int64(1), nil, nil, nil, nil, nil, nil, nil))
+
+		msg, err := repo.GetByID(context.Background(), "msg-1")
+		require.NoError(t, err)
+		assert.Equal(t, "room-123", msg.ChatroomID)
+		assert.Equal(t, "Alice", msg.Username)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("forwarded_message", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupMessageRepositoryMocks(mock)
+
+		repo, err := NewMessageRepository(db)
+		require.NoError(t, err)
+
+		// The original was purged, so only its author is left
+		mock.ExpectQuery(query).
+			WithArgs("msg-2", domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "user_id", "username", "content", "is_bot", "created_at", "seq",
+				"forwarded_from_id", "forwarded_from_chatroom_id", "forwarded_from_user_id", "forwarded_from_username", "quote", "event", "parent_message_id"}).
+				AddRow("msg-2", "room-456", "user-1", "Alice", "Hello", false, time.Now(), int64(2), nil, nil, "user-2", "bob", nil, nil, nil))
+
+		msg, err := repo.GetByID(context.Background(), "msg-2")
+		require.NoError(t, err)
+		assert.Equal(t, &domain.ForwardedFrom{UserID: "user-2", Username: "bob"}, msg.ForwardedFrom)
+		assert.Nil(t, msg.Quote)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("quote_reply", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupMessageRepositoryMocks(mock)
+
+		repo, err := NewMessageRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(query).
+			WithArgs("msg-3", domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "user_id", "username", "content", "is_bot", "created_at", "seq",
+				"forwarded_from_id", "forwarded_from_chatroom_id", "forwarded_from_user_id", "forwarded_from_username", "quote", "event", "parent_message_id"}).
+				AddRow("msg-3", "room-123", "user-1", "Alice", "Sure", false, time.Now(), int64(3), nil, nil, nil, nil,
+					[]byte(`{"message_id":"msg-1","user_id":"user-2","username":"bob","content":"Lunch?","created_at":"2026-01-28T10:30:00Z"}`), nil, nil))
+
+		msg, err := repo.GetByID(context.Background(), "msg-3")
+		require.NoError(t, err)
+		require.NotNil(t, msg.Quote)
+		assert.Equal(t, "msg-1", msg.Quote.MessageID)
+		assert.Equal(t, "Lunch?", msg.Quote.Content)
+		assert.Equal(t, time.Date(2026, 1, 28, 10, 30, 0, 0, time.UTC), msg.Quote.CreatedAt)
+		assert.Nil(t, msg.ForwardedFrom)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("event", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupMessageRepositoryMocks(mock)
+
+		repo, err := NewMessageRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(query).
+			WithArgs("msg-4", domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "user_id", "username", "content", "is_bot", "created_at", "seq",
+				"forwarded_from_id", "forwarded_from_chatroom_id", "forwarded_from_user_id", "forwarded_from_username", "quote", "event", "parent_message_id"}).
+				AddRow("msg-4", "room-123", "user-1", "Alice", "Team lunch", false, time.Now(), int64(4), nil, nil, nil, nil, nil,
+					[]byte(`{"title":"Team lunch","starts_at":"2026-02-03T12:00:00Z","location":"Cafeteria"}`), nil))
+
+		msg, err := repo.GetByID(context.Background(), "msg-4")
+		require.NoError(t, err)
+		require.NotNil(t, msg.Event)
+		assert.Equal(t, "Team lunch", msg.Event.Title)
+		assert.Equal(t, time.Date(2026, 2, 3, 12, 0, 0, 0, time.UTC), msg.Event.StartsAt)
+		assert.Nil(t, msg.Event.EndsAt)
+		assert.Equal(t, "Cafeteria", msg.Event.Location)
+		assert.Nil(t, msg.Quote)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("message_not_found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupMessageRepositoryMocks(mock)
+
+		repo, err := NewMessageRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(query).
+			WithArgs("missing", domain.DefaultOrganizationID).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err = repo.GetByID(context.Background(), "missing")
+		assert.ErrorIs(t, err, domain.ErrMessageNotFound)
+	})
+}
+
+func TestMessageRepository_RestoreAndPurge(t *testing.T) {
+	restoreQuery := regexp.QuoteMeta(`
+		UPDATE messages SET deleted_at = NULL
+		WHERE id = $1 AND org_id = $2 AND deleted_at IS NOT NULL
+	`)
+	purgeQuery := regexp.QuoteMeta(`DELETE FROM messages WHERE deleted_at < $1`)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupMessageRepositoryMocks(mock)
+
+	repo, err := NewMessageRepository(db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mock.ExpectExec(restoreQuery).
+		WithArgs("msg-1", domain.DefaultOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.Restore(ctx, "msg-1"))
+
+	mock.ExpectExec(restoreQuery).
+		WithArgs("msg-2", domain.DefaultOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.Restore(ctx, "msg-2"), domain.ErrMessageNotFound)
+
+	cutoff := time.Now().Add(-time.Hour)
+	mock.ExpectExec(purgeQuery).
+		WithArgs(cutoff).
+		WillReturnResult(sqlmock.NewResult(0, 5))
+	purged, err := repo.PurgeDeleted(ctx, cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), purged)
+
+	mock.ExpectExec(purgeQuery).
+		WillReturnError(errors.New("database error"))
+	_, err = repo.PurgeDeleted(ctx, cutoff)
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_HistoryHidesShadowBannedUsersFromOthers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupMessageRepositoryMocks(mock)
+
+	repo, err := NewMessageRepository(db)
+	require.NoError(t, err)
+
+	// Only the viewer is exempt from the filter, so they still see what
+	// they posted while shadow-banned
+	shadowBanFilter := regexp.QuoteMeta(`AND NOT EXISTS ( SELECT 1 FROM chatroom_shadow_bans b`)
+	viewerCtx := domain.WithViewerID(context.Background(), "user-1")
+	columns := []string{"id", "chatroom_id", "user_id", "username", "content", "is_bot", "created_at", "seq",
+		"forwarded_from_id", "forwarded_from_chatroom_id", "forwarded_from_user_id", "forwarded_from_username", "quote", "event", "parent_message_id"}
+
+	mock.ExpectQuery(shadowBanFilter).
+		WithArgs("room-123", 10, domain.DefaultOrganizationID, "user-1").
+		WillReturnRows(sqlmock.NewRows(columns))
+	_, err = repo.GetByChatroom(viewerCtx, "room-123", 10)
+	require.NoError(t, err)
+
+	mock.ExpectQuery(shadowBanFilter).
+		WithArgs("room-123", "msg-100", 10, domain.DefaultOrganizationID, "user-1").
+		WillReturnRows(sqlmock.NewRows(columns))
+	_, err = repo.GetByChatroomBefore(viewerCtx, "room-123", "msg-100", 10)
+	require.NoError(t, err)
+
+	mock.ExpectQuery(shadowBanFilter).
+		WithArgs("room-123", "msg-100", 10, domain.DefaultOrganizationID, "user-1").
+		WillReturnRows(sqlmock.NewRows(columns))
+	_, err = repo.GetByChatroomSince(viewerCtx, "room-123", "msg-100", 10)
+	require.NoError(t, err)
+
+	// Without a viewer, nobody is exempt
+	mock.ExpectQuery(shadowBanFilter).
+		WithArgs("room-123", 10, domain.DefaultOrganizationID, nil).
+		WillReturnRows(sqlmock.NewRows(columns))
+	_, err = repo.GetByChatroom(context.Background(), "room-123", 10)
+	require.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 // Helper function to set up common mock expectations
 func setupMessageRepositoryMocks(mock sqlmock.Sqlmock) {
 	mock.ExpectPrepare(regexp.QuoteMeta(`
-		INSERT INTO messages (chatroom_id, user_id, content, is_bot)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at
+		INSERT INTO messages (chatroom_id, user_id, content, is_bot, org_id,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id,
+			client_msg_id)
+		SELECT $1, $2, $3, $4, org_id, $6, $7, $8, $9, $10, $11, $12, $13
+		FROM chatrooms WHERE id = $1 AND deleted_at IS NULL AND encrypted = $5
+		ON CONFLICT (chatroom_id, user_id, client_msg_id) WHERE client_msg_id IS NOT NULL DO NOTHING
+		RETURNING id, created_at, seq
 	`)).WillReturnCloseError(nil)
 
 	mock.ExpectPrepare(regexp.QuoteMeta(`
-		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at
+		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at, seq,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id
 		FROM (
-			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at
+			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at, m.seq,
+				m.forwarded_from_id, m.forwarded_from_chatroom_id, m.forwarded_from_user_id, m.forwarded_from_username, m.quote, m.event, m.parent_message_id
 			FROM messages m
 			JOIN users u ON m.user_id = u.id
-			WHERE m.chatroom_id = $1
+			WHERE m.chatroom_id = $1 AND m.org_id = $3 AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM chatroom_shadow_bans b
+					WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM $4
+						AND (b.user_id = m.user_id
+							OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+				)
 			ORDER BY m.created_at DESC
 			LIMIT $2
 		) AS recent_messages
@@ -411,15 +892,41 @@ func setupMessageRepositoryMocks(mock sqlmock.Sqlmock) {
 	`)).WillReturnCloseError(nil)
 
 	mock.ExpectPrepare(regexp.QuoteMeta(`
-		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at
+		SELECT id, chatroom_id, user_id, username, content, is_bot, created_at, seq,
+			forwarded_from_id, forwarded_from_chatroom_id, forwarded_from_user_id, forwarded_from_username, quote, event, parent_message_id
 		FROM (
-			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at
+			SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at, m.seq,
+				m.forwarded_from_id, m.forwarded_from_chatroom_id, m.forwarded_from_user_id, m.forwarded_from_username, m.quote, m.event, m.parent_message_id
 			FROM messages m
 			JOIN users u ON m.user_id = u.id
-			WHERE m.chatroom_id = $1 AND m.id < $2
+			WHERE m.chatroom_id = $1 AND m.id < $2 AND m.org_id = $4 AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM chatroom_shadow_bans b
+					WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM $5
+						AND (b.user_id = m.user_id
+							OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+				)
 			ORDER BY m.created_at DESC
 			LIMIT $3
 		) AS earlier_messages
 		ORDER BY created_at ASC
 	`)).WillReturnCloseError(nil)
+
+	mock.ExpectPrepare(regexp.QuoteMeta(`
+		SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at, m.seq,
+			m.forwarded_from_id, m.forwarded_from_chatroom_id, m.forwarded_from_user_id, m.forwarded_from_username, m.quote, m.event, m.parent_message_id
+		FROM messages m
+		JOIN users u ON m.user_id = u.id
+		JOIN messages since ON since.id = $2 AND since.chatroom_id = m.chatroom_id
+		WHERE m.chatroom_id = $1 AND m.org_id = $4 AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+			AND (m.created_at, m.id) > (since.created_at, since.id)
+			AND NOT EXISTS (
+					SELECT 1 FROM chatroom_shadow_bans b
+					WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM $5
+						AND (b.user_id = m.user_id
+							OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+				)
+		ORDER BY m.created_at ASC, m.id ASC
+		LIMIT $3
+	`)).WillReturnCloseError(nil)
 }
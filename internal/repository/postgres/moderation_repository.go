@@ -0,0 +1,219 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/lib/pq"
+)
+
+type ModerationRepository struct {
+	db            *sql.DB
+	tm            *TxManager
+	flagStmt      *sql.Stmt
+	countOpenStmt *sql.Stmt
+}
+
+// NewModerationRepository creates a new ModerationRepository with prepared statements.
+// Returns an error if statement preparation fails.
+func NewModerationRepository(db *sql.DB) (*ModerationRepository, error) {
+	repo := &ModerationRepository{db: db, tm: NewTxManager(db)}
+
+	var err error
+	repo.flagStmt, err = db.Prepare(`
+		INSERT INTO message_flags (message_id, reporter_id, reason)
+		SELECT id, $2, $3 FROM messages WHERE id = $1 AND org_id = $4 AND deleted_at IS NULL
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare flag statement: %w", err)
+	}
+
+	repo.countOpenStmt, err = db.Prepare(`
+		SELECT COUNT(*) FROM message_flags
+		WHERE message_id = $1 AND resolved_at IS NULL
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare countOpen statement: %w", err)
+	}
+
+	return repo, nil
+}
+
+func (r *ModerationRepository) Flag(ctx context.Context, flag *domain.MessageFlag) (int, error) {
+	result, err := stmt(ctx, r.flagStmt).ExecContext(ctx, flag.MessageID, flag.ReporterID, flag.Reason, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		if IsUniqueViolation(err, "message_flags_message_reporter_key") {
+			return 0, domain.ErrAlreadyFlagged
+		}
+		return 0, fmt.Errorf("failed to flag message: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if count == 0 {
+		return 0, domain.ErrMessageNotFound
+	}
+
+	var open int
+	if err := stmt(ctx, r.countOpenStmt).QueryRowContext(ctx, flag.MessageID).Scan(&open); err != nil {
+		return 0, fmt.Errorf("failed to count open flags: %w", err)
+	}
+	return open, nil
+}
+
+func (r *ModerationRepository) Hide(ctx context.Context, messageID string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE messages SET hidden_at = COALESCE(hidden_at, CURRENT_TIMESTAMP)
+		WHERE id = $1 AND org_id = $2
+	`, messageID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to hide message: %w", err)
+	}
+	return nil
+}
+
+func (r *ModerationRepository) Queue(ctx context.Context, limit int) ([]*domain.FlaggedMessage, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT m.id, m.chatroom_id, m.user_id, u.username, m.content, m.is_bot, m.created_at,
+			m.hidden_at IS NOT NULL, COUNT(*), MIN(f.created_at),
+			COALESCE(array_agg(f.reason ORDER BY f.created_at) FILTER (WHERE f.reason <> ''), '{}')
+		FROM message_flags f
+		JOIN messages m ON m.id = f.message_id
+		JOIN users u ON u.id = m.user_id
+		WHERE f.resolved_at IS NULL AND m.org_id = $1 AND m.deleted_at IS NULL
+		GROUP BY m.id, u.username
+		ORDER BY COUNT(*) DESC, MIN(f.created_at) ASC
+		LIMIT $2
+	`, domain.OrgIDFromContext(ctx), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query moderation queue: %w", err)
+	}
+	defer rows.Close()
+
+	queue := make([]*domain.FlaggedMessage, 0, limit)
+	for rows.Next() {
+		item := &domain.FlaggedMessage{Message: &domain.Message{}}
+		var reasons pq.StringArray
+		err := rows.Scan(
+			&item.Message.ID,
+			&item.Message.ChatroomID,
+			&item.Message.UserID,
+			&item.Message.Username,
+			&item.Message.Content,
+			&item.Message.IsBot,
+			&item.Message.CreatedAt,
+			&item.Hidden,
+			&item.FlagCount,
+			&item.FirstFlaggedAt,
+			&reasons,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan flagged message: %w", err)
+		}
+		item.Reasons = reasons
+		queue = append(queue, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating moderation queue: %w", err)
+	}
+
+	return queue, nil
+}
+
+// Resolve runs in a transaction so a message is never left hidden with its
+// flags closed
+func (r *ModerationRepository) Resolve(ctx context.Context, messageID, moderatorID, action string) error {
+	orgID := domain.OrgIDFromContext(ctx)
+
+	return r.tm.WithTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE message_flags SET resolved_at = CURRENT_TIMESTAMP, resolved_by = $2
+			WHERE message_id = $1 AND resolved_at IS NULL
+				AND message_id IN (SELECT id FROM messages WHERE org_id = $3)
+		`, messageID, moderatorID, orgID)
+		if err != nil {
+			return fmt.Errorf("failed to resolve flags: %w", err)
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if count == 0 {
+			return domain.ErrNoOpenFlags
+		}
+
+		query := `UPDATE messages SET hidden_at = NULL WHERE id = $1 AND org_id = $2`
+		if action == domain.FlagActionDelete {
+			// Soft delete, so the message can be restored until it is purged
+			query = `UPDATE messages SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND org_id = $2`
+		}
+		if _, err := tx.ExecContext(ctx, query, messageID, orgID); err != nil {
+			return fmt.Errorf("failed to %s flagged message: %w", action, err)
+		}
+		return nil
+	})
+}
+
+// viewerID returns the user ctx reads messages for as a query argument,
+// NULL when reading for nobody in particular
+func viewerID(ctx context.Context) sql.NullString {
+	userID := domain.ViewerIDFromContext(ctx)
+	return sql.NullString{String: userID, Valid: userID != ""}
+}
+
+func (r *ModerationRepository) SetShadowBan(ctx context.Context, chatroomID, userID, moderatorID string, banned bool) error {
+	orgID := domain.OrgIDFromContext(ctx)
+
+	var err error
+	if banned {
+		_, err = conn(ctx, r.db).ExecContext(ctx, `
+			INSERT INTO chatroom_shadow_bans (chatroom_id, user_id, banned_by)
+			SELECT id, $2, $3 FROM chatrooms WHERE id = $1 AND org_id = $4
+			ON CONFLICT (chatroom_id, user_id) DO NOTHING
+		`, chatroomID, userID, moderatorID, orgID)
+	} else {
+		_, err = conn(ctx, r.db).ExecContext(ctx, `
+			DELETE FROM chatroom_shadow_bans
+			WHERE chatroom_id = $1 AND user_id = $2
+				AND chatroom_id IN (SELECT id FROM chatrooms WHERE org_id = $3)
+		`, chatroomID, userID, orgID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update shadow-ban: %w", err)
+	}
+	return nil
+}
+
+func (r *ModerationRepository) ShadowBannedUsers(ctx context.Context, chatroomID string) ([]string, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT b.user_id FROM chatroom_shadow_bans b
+		JOIN chatrooms c ON c.id = b.chatroom_id
+		WHERE b.chatroom_id = $1 AND c.org_id = $2
+		ORDER BY b.created_at
+	`, chatroomID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shadow-bans: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan shadow-ban: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shadow-bans: %w", err)
+	}
+
+	return userIDs, nil
+}
@@ -0,0 +1,222 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	flagQuery = regexp.QuoteMeta(`
+		INSERT INTO message_flags (message_id, reporter_id, reason)
+		SELECT id, $2, $3 FROM messages WHERE id = $1 AND org_id = $4 AND deleted_at IS NULL
+	`)
+	countOpenFlagsQuery = regexp.QuoteMeta(`
+		SELECT COUNT(*) FROM message_flags
+		WHERE message_id = $1 AND resolved_at IS NULL
+	`)
+	resolveFlagsQuery = regexp.QuoteMeta(`
+			UPDATE message_flags SET resolved_at = CURRENT_TIMESTAMP, resolved_by = $2
+			WHERE message_id = $1 AND resolved_at IS NULL
+				AND message_id IN (SELECT id FROM messages WHERE org_id = $3)
+		`)
+)
+
+func newTestModerationRepository(t *testing.T) (*ModerationRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mock.ExpectPrepare(flagQuery)
+	mock.ExpectPrepare(countOpenFlagsQuery)
+
+	repo, err := NewModerationRepository(db)
+	require.NoError(t, err)
+	return repo, mock
+}
+
+func TestModerationRepository_Flag(t *testing.T) {
+	flag := &domain.MessageFlag{MessageID: "msg-1", ReporterID: "user-2", Reason: "spam"}
+
+	t.Run("returns_open_flag_count", func(t *testing.T) {
+		repo, mock := newTestModerationRepository(t)
+
+		mock.ExpectExec(flagQuery).
+			WithArgs("msg-1", "user-2", "spam", domain.DefaultOrganizationID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(countOpenFlagsQuery).
+			WithArgs("msg-1").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+		count, err := repo.Flag(context.Background(), flag)
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already_flagged", func(t *testing.T) {
+		repo, mock := newTestModerationRepository(t)
+
+		mock.ExpectExec(flagQuery).
+			WillReturnError(&pq.Error{Code: "23505", Constraint: "message_flags_message_reporter_key"})
+
+		_, err := repo.Flag(context.Background(), flag)
+		assert.ErrorIs(t, err, domain.ErrAlreadyFlagged)
+	})
+
+	t.Run("message_not_found", func(t *testing.T) {
+		repo, mock := newTestModerationRepository(t)
+
+		mock.ExpectExec(flagQuery).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		_, err := repo.Flag(context.Background(), flag)
+		assert.ErrorIs(t, err, domain.ErrMessageNotFound)
+	})
+}
+
+func TestModerationRepository_Queue(t *testing.T) {
+	repo, mock := newTestModerationRepository(t)
+
+	createdAt := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM message_flags f`)).
+		WithArgs(domain.DefaultOrganizationID, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "user_id", "username", "content", "is_bot", "created_at", "hidden", "count", "first_flagged_at", "reasons"}).
+			AddRow("msg-1", "room-1", "user-1", "alice", "buy now", false, createdAt, true, 3, createdAt, "{spam,ads}"))
+
+	queue, err := repo.Queue(context.Background(), 20)
+	require.NoError(t, err)
+	require.Len(t, queue, 1)
+	assert.Equal(t, "msg-1", queue[0].Message.ID)
+	assert.Equal(t, 3, queue[0].FlagCount)
+	assert.True(t, queue[0].Hidden)
+	assert.Equal(t, []string{"spam", "ads"}, queue[0].Reasons)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestModerationRepository_Resolve(t *testing.T) {
+	t.Run("keep_unhides_message", func(t *testing.T) {
+		repo, mock := newTestModerationRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(resolveFlagsQuery).
+			WithArgs("msg-1", "mod-1", domain.DefaultOrganizationID).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE messages SET hidden_at = NULL WHERE id = $1 AND org_id = $2`)).
+			WithArgs("msg-1", domain.DefaultOrganizationID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.Resolve(context.Background(), "msg-1", "mod-1", domain.FlagActionKeep)
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delete_soft_deletes_message", func(t *testing.T) {
+		repo, mock := newTestModerationRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(resolveFlagsQuery).
+			WithArgs("msg-1", "mod-1", domain.DefaultOrganizationID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE messages SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND org_id = $2`)).
+			WithArgs("msg-1", domain.DefaultOrganizationID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.Resolve(context.Background(), "msg-1", "mod-1", domain.FlagActionDelete)
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no_open_flags", func(t *testing.T) {
+		repo, mock := newTestModerationRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(resolveFlagsQuery).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := repo.Resolve(context.Background(), "msg-1", "mod-1", domain.FlagActionKeep)
+		assert.ErrorIs(t, err, domain.ErrNoOpenFlags)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delete_error_rolls_back", func(t *testing.T) {
+		repo, mock := newTestModerationRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(resolveFlagsQuery).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE messages SET deleted_at`)).
+			WillReturnError(errors.New("database error"))
+		mock.ExpectRollback()
+
+		err := repo.Resolve(context.Background(), "msg-1", "mod-1", domain.FlagActionDelete)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to delete flagged message")
+	})
+}
+
+func TestModerationRepository_SetShadowBan(t *testing.T) {
+	t.Run("ban", func(t *testing.T) {
+		repo, mock := newTestModerationRepository(t)
+
+		mock.ExpectExec(regexp.QuoteMeta(`
+			INSERT INTO chatroom_shadow_bans (chatroom_id, user_id, banned_by)
+			SELECT id, $2, $3 FROM chatrooms WHERE id = $1 AND org_id = $4
+			ON CONFLICT (chatroom_id, user_id) DO NOTHING
+		`)).
+			WithArgs("room-1", "user-1", "mod-1", domain.DefaultOrganizationID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.SetShadowBan(context.Background(), "room-1", "user-1", "mod-1", true)
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unban", func(t *testing.T) {
+		repo, mock := newTestModerationRepository(t)
+
+		mock.ExpectExec(regexp.QuoteMeta(`
+			DELETE FROM chatroom_shadow_bans
+			WHERE chatroom_id = $1 AND user_id = $2
+				AND chatroom_id IN (SELECT id FROM chatrooms WHERE org_id = $3)
+		`)).
+			WithArgs("room-1", "user-1", domain.DefaultOrganizationID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.SetShadowBan(context.Background(), "room-1", "user-1", "mod-1", false)
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestModerationRepository_ShadowBannedUsers(t *testing.T) {
+	repo, mock := newTestModerationRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT b.user_id FROM chatroom_shadow_bans b
+		JOIN chatrooms c ON c.id = b.chatroom_id
+		WHERE b.chatroom_id = $1 AND c.org_id = $2
+		ORDER BY b.created_at
+	`)).
+		WithArgs("room-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("user-1").AddRow("user-2"))
+
+	userIDs, err := repo.ShadowBannedUsers(context.Background(), "room-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user-1", "user-2"}, userIDs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
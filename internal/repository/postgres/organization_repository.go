@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"jobsity-chat/internal/domain"
+)
+
+type OrganizationRepository struct {
+	db            *sql.DB
+	createStmt    *sql.Stmt
+	getByIDStmt   *sql.Stmt
+	getBySlugStmt *sql.Stmt
+}
+
+// NewOrganizationRepository creates a new OrganizationRepository with prepared statements.
+// Returns an error if statement preparation fails.
+func NewOrganizationRepository(db *sql.DB) (*OrganizationRepository, error) {
+	repo := &OrganizationRepository{db: db}
+
+	var err error
+	repo.createStmt, err = db.Prepare(`
+		INSERT INTO organizations (slug, name)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare create statement: %w", err)
+	}
+
+	repo.getByIDStmt, err = db.Prepare(`
+		SELECT id, slug, name, created_at
+		FROM organizations
+		WHERE id = $1
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare getByID statement: %w", err)
+	}
+
+	repo.getBySlugStmt, err = db.Prepare(`
+		SELECT id, slug, name, created_at
+		FROM organizations
+		WHERE slug = $1
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare getBySlug statement: %w", err)
+	}
+
+	return repo, nil
+}
+
+func (r *OrganizationRepository) Create(ctx context.Context, org *domain.Organization) error {
+	err := stmt(ctx, r.createStmt).QueryRowContext(ctx, org.Slug, org.Name).Scan(&org.ID, &org.CreatedAt)
+	if err != nil {
+		if IsUniqueViolation(err, "organizations_slug_key") {
+			return domain.ErrOrganizationExists
+		}
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+	return nil
+}
+
+func (r *OrganizationRepository) GetByID(ctx context.Context, id string) (*domain.Organization, error) {
+	return r.scan(stmt(ctx, r.getByIDStmt).QueryRowContext(ctx, id))
+}
+
+func (r *OrganizationRepository) GetBySlug(ctx context.Context, slug string) (*domain.Organization, error) {
+	return r.scan(stmt(ctx, r.getBySlugStmt).QueryRowContext(ctx, slug))
+}
+
+func (r *OrganizationRepository) scan(row *sql.Row) (*domain.Organization, error) {
+	org := &domain.Organization{}
+	err := row.Scan(&org.ID, &org.Slug, &org.Name, &org.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return org, nil
+}
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	organizationCreateQuery = `
+		INSERT INTO organizations (slug, name)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`
+	organizationGetByIDQuery = `
+		SELECT id, slug, name, created_at
+		FROM organizations
+		WHERE id = $1
+	`
+	organizationGetBySlugQuery = `
+		SELECT id, slug, name, created_at
+		FROM organizations
+		WHERE slug = $1
+	`
+)
+
+func TestOrganizationRepository_Create(t *testing.T) {
+	t.Run("successful_creation", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupOrganizationRepositoryMocks(mock)
+
+		repo, err := NewOrganizationRepository(db)
+		require.NoError(t, err)
+
+		createdAt := time.Now()
+		mock.ExpectQuery(regexp.QuoteMeta(organizationCreateQuery)).
+			WithArgs("acme", "Acme Corp").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("org-1", createdAt))
+
+		org := &domain.Organization{Slug: "acme", Name: "Acme Corp"}
+		err = repo.Create(context.Background(), org)
+		require.NoError(t, err)
+		assert.Equal(t, "org-1", org.ID)
+		assert.Equal(t, createdAt, org.CreatedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("duplicate_slug", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupOrganizationRepositoryMocks(mock)
+
+		repo, err := NewOrganizationRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(organizationCreateQuery)).
+			WithArgs("acme", "Acme Corp").
+			WillReturnError(&pq.Error{Code: "23505", Constraint: "organizations_slug_key"})
+
+		err = repo.Create(context.Background(), &domain.Organization{Slug: "acme", Name: "Acme Corp"})
+		assert.ErrorIs(t, err, domain.ErrOrganizationExists)
+	})
+}
+
+func TestOrganizationRepository_GetBySlug(t *testing.T) {
+	t.Run("successful_retrieval", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupOrganizationRepositoryMocks(mock)
+
+		repo, err := NewOrganizationRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(organizationGetBySlugQuery)).
+			WithArgs("acme").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "slug", "name", "created_at"}).
+				AddRow("org-1", "acme", "Acme Corp", time.Now()))
+
+		org, err := repo.GetBySlug(context.Background(), "acme")
+		require.NoError(t, err)
+		assert.Equal(t, "org-1", org.ID)
+		assert.Equal(t, "Acme Corp", org.Name)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupOrganizationRepositoryMocks(mock)
+
+		repo, err := NewOrganizationRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(organizationGetBySlugQuery)).
+			WithArgs("missing").
+			WillReturnError(sql.ErrNoRows)
+
+		org, err := repo.GetBySlug(context.Background(), "missing")
+		assert.Nil(t, org)
+		assert.Equal(t, domain.ErrOrganizationNotFound, err)
+	})
+}
+
+func TestOrganizationRepository_GetByID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupOrganizationRepositoryMocks(mock)
+
+	repo, err := NewOrganizationRepository(db)
+	require.NoError(t, err)
+
+	mock.ExpectQuery(regexp.QuoteMeta(organizationGetByIDQuery)).
+		WithArgs(domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug", "name", "created_at"}).
+			AddRow(domain.DefaultOrganizationID, "default", "Default", time.Now()))
+
+	org, err := repo.GetByID(context.Background(), domain.DefaultOrganizationID)
+	require.NoError(t, err)
+	assert.Equal(t, "default", org.Slug)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func setupOrganizationRepositoryMocks(mock sqlmock.Sqlmock) {
+	mock.ExpectPrepare(regexp.QuoteMeta(organizationCreateQuery))
+	mock.ExpectPrepare(regexp.QuoteMeta(organizationGetByIDQuery))
+	mock.ExpectPrepare(regexp.QuoteMeta(organizationGetBySlugQuery))
+}
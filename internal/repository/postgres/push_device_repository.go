@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/lib/pq"
+)
+
+type PushDeviceRepository struct {
+	db             *sql.DB
+	recipientsStmt *sql.Stmt
+}
+
+// NewPushDeviceRepository creates a new PushDeviceRepository with prepared statements.
+// Returns an error if statement preparation fails.
+func NewPushDeviceRepository(db *sql.DB) (*PushDeviceRepository, error) {
+	repo := &PushDeviceRepository{db: db}
+
+	var err error
+	// Runs for every message that mentions someone or is sent in a
+	// two-member chatroom, so it is prepared
+	repo.recipientsStmt, err = db.Prepare(`
+		SELECT cm.user_id
+		FROM chatroom_members cm
+		JOIN chatrooms c ON c.id = cm.chatroom_id AND c.org_id = $4 AND c.deleted_at IS NULL
+		JOIN users u ON u.id = cm.user_id AND u.deactivated_at IS NULL
+		WHERE cm.chatroom_id = $1 AND cm.user_id <> $2
+		  -- Nobody else sees a shadow-banned user's messages
+		  AND NOT EXISTS (SELECT 1 FROM chatroom_shadow_bans b WHERE b.chatroom_id = $1 AND b.user_id = $2)
+		  AND (lower(u.username) = ANY($3)
+		       OR EXISTS (
+		           -- A former username still mentions its user while nobody has it
+		           SELECT 1 FROM username_history h
+		           WHERE h.user_id = u.id AND lower(h.old_username) = ANY($3)
+		             AND NOT EXISTS (SELECT 1 FROM users o WHERE o.org_id = $4 AND lower(o.username) = lower(h.old_username))
+		       )
+		       OR (SELECT COUNT(*) FROM chatroom_members n WHERE n.chatroom_id = cm.chatroom_id) = 2)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare recipients statement: %w", err)
+	}
+
+	return repo, nil
+}
+
+func (r *PushDeviceRepository) Register(ctx context.Context, device *domain.PushDevice) error {
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO push_devices (user_id, platform, token)
+		SELECT u.id, $2, $3 FROM users u WHERE u.id = $1 AND u.org_id = $4
+		ON CONFLICT (platform, token) DO UPDATE
+		SET user_id = EXCLUDED.user_id, created_at = NOW()
+		RETURNING id, created_at
+	`, device.UserID, device.Platform, device.Token, domain.OrgIDFromContext(ctx)).Scan(&device.ID, &device.CreatedAt)
+	if err == sql.ErrNoRows {
+		return domain.ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to register push device: %w", err)
+	}
+	return nil
+}
+
+func (r *PushDeviceRepository) ListByUser(ctx context.Context, userID string) ([]*domain.PushDevice, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT d.id, d.user_id, d.platform, d.token, d.created_at
+		FROM push_devices d
+		JOIN users u ON u.id = d.user_id
+		WHERE d.user_id = $1 AND u.org_id = $2
+		ORDER BY d.created_at
+	`, userID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list push devices: %w", err)
+	}
+	defer rows.Close()
+
+	var devices []*domain.PushDevice
+	for rows.Next() {
+		device := &domain.PushDevice{}
+		if err := rows.Scan(&device.ID, &device.UserID, &device.Platform, &device.Token, &device.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan push device: %w", err)
+		}
+		devices = append(devices, device)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating push devices: %w", err)
+	}
+	return devices, nil
+}
+
+func (r *PushDeviceRepository) Delete(ctx context.Context, userID, deviceID string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `
+		DELETE FROM push_devices d
+		USING users u
+		WHERE d.id::text = $1 AND d.user_id = $2 AND u.id = d.user_id AND u.org_id = $3
+	`, deviceID, userID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete push device: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrPushDeviceNotFound
+	}
+	return nil
+}
+
+func (r *PushDeviceRepository) DeleteToken(ctx context.Context, platform, token string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM push_devices WHERE platform = $1 AND token = $2`, platform, token)
+	if err != nil {
+		return fmt.Errorf("failed to delete push token: %w", err)
+	}
+	return nil
+}
+
+func (r *PushDeviceRepository) Recipients(ctx context.Context, msg *domain.Message, mentions []string) ([]string, error) {
+	rows, err := stmt(ctx, r.recipientsStmt).QueryContext(ctx, msg.ChatroomID, msg.UserID, pq.Array(mentions), domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query push recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan push recipient: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating push recipients: %w", err)
+	}
+	return userIDs, nil
+}
+
+func (r *PushDeviceRepository) DNDSchedule(ctx context.Context, userID string) (*domain.DNDSchedule, error) {
+	var data []byte
+	err := conn(ctx, r.db).QueryRowContext(ctx,
+		`SELECT dnd_schedule FROM users WHERE id = $1 AND org_id = $2`,
+		userID, domain.OrgIDFromContext(ctx)).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get do-not-disturb schedule: %w", err)
+	}
+
+	schedule := &domain.DNDSchedule{Windows: []domain.DNDWindow{}}
+	if data == nil {
+		return schedule, nil
+	}
+	if err := json.Unmarshal(data, schedule); err != nil {
+		return nil, fmt.Errorf("failed to decode do-not-disturb schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+func (r *PushDeviceRepository) SetDNDSchedule(ctx context.Context, userID string, schedule *domain.DNDSchedule) error {
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return fmt.Errorf("failed to encode do-not-disturb schedule: %w", err)
+	}
+	result, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE users SET dnd_schedule = $1 WHERE id = $2 AND org_id = $3`,
+		data, userID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to update do-not-disturb schedule: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+// Defer stores deliverAt in UTC, like every other timestamp
+func (r *PushDeviceRepository) Defer(ctx context.Context, userID string, n *domain.PushNotification, deliverAt time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO deferred_push_notifications (org_id, user_id, chatroom_id, message_id, title, body, deliver_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, domain.OrgIDFromContext(ctx), userID, n.ChatroomID, n.MessageID, n.Title, n.Body, deliverAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to defer push notification: %w", err)
+	}
+	return nil
+}
+
+// ClaimDeferred skips notifications another instance is claiming
+func (r *PushDeviceRepository) ClaimDeferred(ctx context.Context, now time.Time, limit int) ([]*domain.DeferredPush, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		DELETE FROM deferred_push_notifications
+		WHERE id IN (
+			SELECT id FROM deferred_push_notifications
+			WHERE deliver_at <= $1
+			ORDER BY created_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING org_id, user_id, chatroom_id, message_id, title, body, created_at
+	`, now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim deferred push notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var deferred []*domain.DeferredPush
+	for rows.Next() {
+		d := &domain.DeferredPush{}
+		if err := rows.Scan(&d.OrgID, &d.UserID, &d.Notification.ChatroomID, &d.Notification.MessageID,
+			&d.Notification.Title, &d.Notification.Body, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan deferred push notification: %w", err)
+		}
+		deferred = append(deferred, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deferred push notifications: %w", err)
+	}
+	// RETURNING does not keep the subquery's order
+	slices.SortStableFunc(deferred, func(a, b *domain.DeferredPush) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return deferred, nil
+}
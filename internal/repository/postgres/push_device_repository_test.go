@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPushDeviceRepository(t *testing.T) (*PushDeviceRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mock.ExpectPrepare(`SELECT cm.user_id\s+FROM chatroom_members cm`)
+	repo, err := NewPushDeviceRepository(db)
+	require.NoError(t, err)
+	return repo, mock
+}
+
+func TestPushDeviceRepository_Register(t *testing.T) {
+	repo, mock := newTestPushDeviceRepository(t)
+	ctx := context.Background()
+	createdAt := time.Now()
+
+	mock.ExpectQuery(`INSERT INTO push_devices .* ON CONFLICT \(platform, token\) DO UPDATE`).
+		WithArgs("user-1", domain.PushPlatformFCM, "token-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("device-1", createdAt))
+	device := &domain.PushDevice{UserID: "user-1", Platform: domain.PushPlatformFCM, Token: "token-1"}
+	require.NoError(t, repo.Register(ctx, device))
+	assert.Equal(t, "device-1", device.ID)
+	assert.Equal(t, createdAt, device.CreatedAt)
+
+	mock.ExpectQuery(`INSERT INTO push_devices`).
+		WithArgs("other-org-user", domain.PushPlatformFCM, "token-1", domain.DefaultOrganizationID).
+		WillReturnError(sql.ErrNoRows)
+	err := repo.Register(ctx, &domain.PushDevice{UserID: "other-org-user", Platform: domain.PushPlatformFCM, Token: "token-1"})
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPushDeviceRepository_ListAndDelete(t *testing.T) {
+	repo, mock := newTestPushDeviceRepository(t)
+	ctx := context.Background()
+
+	mock.ExpectQuery(`SELECT d.id, d.user_id, d.platform, d.token, d.created_at\s+FROM push_devices d`).
+		WithArgs("user-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "platform", "token", "created_at"}).
+			AddRow("device-1", "user-1", domain.PushPlatformWebPush, `{"endpoint":"https://push.example"}`, time.Now()))
+	devices, err := repo.ListByUser(ctx, "user-1")
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, domain.PushPlatformWebPush, devices[0].Platform)
+
+	mock.ExpectExec(`DELETE FROM push_devices d\s+USING users u`).
+		WithArgs("device-1", "user-1", domain.DefaultOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.Delete(ctx, "user-1", "device-1"))
+
+	mock.ExpectExec(`DELETE FROM push_devices d\s+USING users u`).
+		WithArgs("device-1", "user-2", domain.DefaultOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.Delete(ctx, "user-2", "device-1"), domain.ErrPushDeviceNotFound)
+
+	mock.ExpectExec(`DELETE FROM push_devices WHERE platform = \$1 AND token = \$2`).
+		WithArgs(domain.PushPlatformFCM, "expired").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.DeleteToken(ctx, domain.PushPlatformFCM, "expired"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPushDeviceRepository_Recipients(t *testing.T) {
+	repo, mock := newTestPushDeviceRepository(t)
+	ctx := context.Background()
+	msg := &domain.Message{ChatroomID: "room-1", UserID: "sender"}
+
+	// Nobody is notified of a shadow-banned sender's messages
+	mock.ExpectQuery(`SELECT cm.user_id .* NOT EXISTS \(SELECT 1 FROM chatroom_shadow_bans b WHERE b.chatroom_id = \$1 AND b.user_id = \$2\)`).
+		WithArgs("room-1", "sender", pq.Array([]string{"alice"}), domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("user-alice"))
+	recipients, err := repo.Recipients(ctx, msg, []string{"alice"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user-alice"}, recipients)
+
+	mock.ExpectQuery(`SELECT cm.user_id`).
+		WithArgs("room-1", "sender", pq.Array([]string{}), domain.DefaultOrganizationID).
+		WillReturnError(errors.New("database error"))
+	_, err = repo.Recipients(ctx, msg, []string{})
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPushDeviceRepository_DNDSchedule(t *testing.T) {
+	repo, mock := newTestPushDeviceRepository(t)
+	ctx := context.Background()
+
+	mock.ExpectQuery(`SELECT dnd_schedule FROM users`).
+		WithArgs("user-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"dnd_schedule"}).AddRow(nil))
+	schedule, err := repo.DNDSchedule(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, schedule.Windows)
+
+	mock.ExpectQuery(`SELECT dnd_schedule FROM users`).
+		WithArgs("user-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"dnd_schedule"}).
+			AddRow([]byte(`{"time_zone":"Europe/Paris","windows":[{"start":"22:00","end":"07:00"}]}`)))
+	schedule, err = repo.DNDSchedule(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Paris", schedule.TimeZone)
+	assert.Equal(t, []domain.DNDWindow{{Start: "22:00", End: "07:00"}}, schedule.Windows)
+
+	mock.ExpectQuery(`SELECT dnd_schedule FROM users`).
+		WithArgs("missing", domain.DefaultOrganizationID).
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.DNDSchedule(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+
+	mock.ExpectExec(`UPDATE users SET dnd_schedule = \$1`).
+		WithArgs([]byte(`{"time_zone":"","windows":[]}`), "user-1", domain.DefaultOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.SetDNDSchedule(ctx, "user-1", &domain.DNDSchedule{Windows: []domain.DNDWindow{}}))
+
+	mock.ExpectExec(`UPDATE users SET dnd_schedule = \$1`).
+		WithArgs(sqlmock.AnyArg(), "missing", domain.DefaultOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.SetDNDSchedule(ctx, "missing", &domain.DNDSchedule{}), domain.ErrUserNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPushDeviceRepository_DeferAndClaim(t *testing.T) {
+	repo, mock := newTestPushDeviceRepository(t)
+	ctx := domain.WithOrgID(context.Background(), "org-1")
+	deliverAt := time.Date(2026, 3, 5, 7, 0, 0, 0, time.FixedZone("COT", -5*3600))
+	notification := &domain.PushNotification{Title: "carol", Body: "hi", ChatroomID: "room-1", MessageID: "msg-1"}
+
+	mock.ExpectExec(`INSERT INTO deferred_push_notifications`).
+		WithArgs("org-1", "user-1", "room-1", "msg-1", "carol", "hi", deliverAt.UTC()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.Defer(ctx, "user-1", notification, deliverAt))
+
+	now := time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC)
+	first, second := now.Add(-2*time.Hour), now.Add(-time.Hour)
+	mock.ExpectQuery(`DELETE FROM deferred_push_notifications\s+WHERE id IN .* FOR UPDATE SKIP LOCKED`).
+		WithArgs(now, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id", "user_id", "chatroom_id", "message_id", "title", "body", "created_at"}).
+			AddRow("org-1", "user-1", "room-1", "msg-2", "dave", "later", second).
+			AddRow("org-1", "user-1", "room-1", "msg-1", "carol", "hi", first))
+	deferred, err := repo.ClaimDeferred(context.Background(), now, 100)
+	require.NoError(t, err)
+	require.Len(t, deferred, 2)
+	assert.Equal(t, "msg-1", deferred[0].Notification.MessageID)
+	assert.Equal(t, "org-1", deferred[1].OrgID)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
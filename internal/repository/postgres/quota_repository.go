@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"jobsity-chat/internal/domain"
+)
+
+// messageQuotaLockClass is the first key of the transaction-level advisory
+// locks serializing the message quota checks of a chatroom, whose second key
+// is a hash of the chatroom ID
+const messageQuotaLockClass int32 = 0x71756f74 // "quot"
+
+type QuotaRepository struct {
+	db                      *sql.DB
+	getOverridesStmt        *sql.Stmt
+	countRoomsCreatedByStmt *sql.Stmt
+	countMessagesSinceStmt  *sql.Stmt
+}
+
+// NewQuotaRepository creates a new QuotaRepository with prepared statements.
+// Returns an error if statement preparation fails.
+func NewQuotaRepository(db *sql.DB) (*QuotaRepository, error) {
+	repo := &QuotaRepository{db: db}
+
+	var err error
+	repo.getOverridesStmt, err = db.Prepare(`
+		SELECT max_rooms_per_user, max_messages_per_room_per_day
+		FROM organization_quotas
+		WHERE org_id = $1
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare getOverrides statement: %w", err)
+	}
+
+	repo.countRoomsCreatedByStmt, err = db.Prepare(`
+		SELECT COUNT(*) FROM chatrooms
+		WHERE created_by = $1 AND org_id = $2
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare countRoomsCreatedBy statement: %w", err)
+	}
+
+	repo.countMessagesSinceStmt, err = db.Prepare(`
+		SELECT COUNT(*) FROM messages
+		WHERE chatroom_id = $1 AND created_at >= $2 AND org_id = $3 AND NOT is_bot
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare countMessagesSince statement: %w", err)
+	}
+
+	return repo, nil
+}
+
+func (r *QuotaRepository) GetOverrides(ctx context.Context, orgID string) (*domain.QuotaOverrides, error) {
+	var rooms, messages sql.NullInt32
+	err := stmt(ctx, r.getOverridesStmt).QueryRowContext(ctx, orgID).Scan(&rooms, &messages)
+	if err == sql.ErrNoRows {
+		return &domain.QuotaOverrides{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quota overrides: %w", err)
+	}
+
+	overrides := &domain.QuotaOverrides{}
+	if rooms.Valid {
+		v := int(rooms.Int32)
+		overrides.MaxRoomsPerUser = &v
+	}
+	if messages.Valid {
+		v := int(messages.Int32)
+		overrides.MaxMessagesPerRoomPerDay = &v
+	}
+	return overrides, nil
+}
+
+func (r *QuotaRepository) SetOverrides(ctx context.Context, orgID string, overrides *domain.QuotaOverrides) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO organization_quotas (org_id, max_rooms_per_user, max_messages_per_room_per_day)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (org_id) DO UPDATE SET
+			max_rooms_per_user = EXCLUDED.max_rooms_per_user,
+			max_messages_per_room_per_day = EXCLUDED.max_messages_per_room_per_day,
+			updated_at = CURRENT_TIMESTAMP
+	`, orgID, overrides.MaxRoomsPerUser, overrides.MaxMessagesPerRoomPerDay)
+	if err != nil {
+		return fmt.Errorf("failed to set quota overrides: %w", err)
+	}
+	return nil
+}
+
+func (r *QuotaRepository) CountRoomsCreatedBy(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := stmt(ctx, r.countRoomsCreatedByStmt).QueryRowContext(ctx, userID, domain.OrgIDFromContext(ctx)).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count rooms: %w", err)
+	}
+	return count, nil
+}
+
+// CountMessagesSince counts user messages in the chatroom; bot responses do
+// not use up the quota
+func (r *QuotaRepository) CountMessagesSince(ctx context.Context, chatroomID string, since time.Time) (int, error) {
+	var count int
+	err := stmt(ctx, r.countMessagesSinceStmt).QueryRowContext(ctx, chatroomID, since, domain.OrgIDFromContext(ctx)).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count messages: %w", err)
+	}
+	return count, nil
+}
+
+func (r *QuotaRepository) LockChatroomMessages(ctx context.Context, chatroomID string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, hashtext($2))`, messageQuotaLockClass, chatroomID)
+	if err != nil {
+		return fmt.Errorf("failed to lock chatroom message quota: %w", err)
+	}
+	return nil
+}
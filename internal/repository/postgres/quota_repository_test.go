@@ -0,0 +1,180 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	quotaGetOverridesQuery = `
+		SELECT max_rooms_per_user, max_messages_per_room_per_day
+		FROM organization_quotas
+		WHERE org_id = $1
+	`
+	quotaCountRoomsQuery = `
+		SELECT COUNT(*) FROM chatrooms
+		WHERE created_by = $1 AND org_id = $2
+	`
+	quotaCountMessagesQuery = `
+		SELECT COUNT(*) FROM messages
+		WHERE chatroom_id = $1 AND created_at >= $2 AND org_id = $3 AND NOT is_bot
+	`
+)
+
+func TestQuotaRepository_GetOverrides(t *testing.T) {
+	t.Run("partial_overrides", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupQuotaRepositoryMocks(mock)
+
+		repo, err := NewQuotaRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(quotaGetOverridesQuery)).
+			WithArgs("org-1").
+			WillReturnRows(sqlmock.NewRows([]string{"max_rooms_per_user", "max_messages_per_room_per_day"}).
+				AddRow(3, nil))
+
+		overrides, err := repo.GetOverrides(context.Background(), "org-1")
+		require.NoError(t, err)
+		require.NotNil(t, overrides.MaxRoomsPerUser)
+		assert.Equal(t, 3, *overrides.MaxRoomsPerUser)
+		assert.Nil(t, overrides.MaxMessagesPerRoomPerDay)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no_overrides", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupQuotaRepositoryMocks(mock)
+
+		repo, err := NewQuotaRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(quotaGetOverridesQuery)).
+			WithArgs("org-1").
+			WillReturnRows(sqlmock.NewRows([]string{"max_rooms_per_user", "max_messages_per_room_per_day"}))
+
+		overrides, err := repo.GetOverrides(context.Background(), "org-1")
+		require.NoError(t, err)
+		assert.Equal(t, &domain.QuotaOverrides{}, overrides)
+	})
+}
+
+func TestQuotaRepository_SetOverrides(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupQuotaRepositoryMocks(mock)
+
+	repo, err := NewQuotaRepository(db)
+	require.NoError(t, err)
+
+	rooms := 5
+	mock.ExpectExec(`INSERT INTO organization_quotas`).
+		WithArgs("org-1", &rooms, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = repo.SetOverrides(context.Background(), "org-1", &domain.QuotaOverrides{MaxRoomsPerUser: &rooms})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQuotaRepository_CountRoomsCreatedBy(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupQuotaRepositoryMocks(mock)
+
+	repo, err := NewQuotaRepository(db)
+	require.NoError(t, err)
+
+	mock.ExpectQuery(regexp.QuoteMeta(quotaCountRoomsQuery)).
+		WithArgs("user-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+
+	count, err := repo.CountRoomsCreatedBy(context.Background(), "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQuotaRepository_CountMessagesSince(t *testing.T) {
+	t.Run("successful_count", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupQuotaRepositoryMocks(mock)
+
+		repo, err := NewQuotaRepository(db)
+		require.NoError(t, err)
+
+		since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery(regexp.QuoteMeta(quotaCountMessagesQuery)).
+			WithArgs("room-1", since, "org-2").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(120))
+
+		count, err := repo.CountMessagesSince(domain.WithOrgID(context.Background(), "org-2"), "room-1", since)
+		require.NoError(t, err)
+		assert.Equal(t, 120, count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database_error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupQuotaRepositoryMocks(mock)
+
+		repo, err := NewQuotaRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(quotaCountMessagesQuery)).
+			WillReturnError(errors.New("database error"))
+
+		_, err = repo.CountMessagesSince(context.Background(), "room-1", time.Now())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to count messages")
+	})
+}
+
+func TestQuotaRepository_LockChatroomMessages(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupQuotaRepositoryMocks(mock)
+
+	repo, err := NewQuotaRepository(db)
+	require.NoError(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock($1, hashtext($2))`)).
+		WithArgs(messageQuotaLockClass, "room-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, repo.LockChatroomMessages(context.Background(), "room-1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func setupQuotaRepositoryMocks(mock sqlmock.Sqlmock) {
+	mock.ExpectPrepare(regexp.QuoteMeta(quotaGetOverridesQuery))
+	mock.ExpectPrepare(regexp.QuoteMeta(quotaCountRoomsQuery))
+	mock.ExpectPrepare(regexp.QuoteMeta(quotaCountMessagesQuery))
+}
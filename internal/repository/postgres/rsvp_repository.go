@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"jobsity-chat/internal/domain"
+)
+
+type RSVPRepository struct {
+	db       *sql.DB
+	setStmt  *sql.Stmt
+	listStmt *sql.Stmt
+}
+
+// NewRSVPRepository creates a new RSVPRepository with prepared statements.
+// Returns an error if statement preparation fails.
+func NewRSVPRepository(db *sql.DB) (*RSVPRepository, error) {
+	repo := &RSVPRepository{db: db}
+
+	var err error
+	repo.setStmt, err = db.Prepare(`
+		INSERT INTO event_rsvps (message_id, user_id, response)
+		SELECT m.id, $2, $3
+		FROM messages m
+		WHERE m.id = $1 AND m.org_id = $4 AND m.deleted_at IS NULL
+		ON CONFLICT (message_id, user_id) DO UPDATE
+		SET response = EXCLUDED.response, updated_at = NOW()
+		RETURNING updated_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare set statement: %w", err)
+	}
+
+	repo.listStmt, err = db.Prepare(`
+		SELECT r.message_id, m.chatroom_id, r.user_id, u.username, r.response, r.updated_at
+		FROM event_rsvps r
+		JOIN messages m ON m.id = r.message_id
+		JOIN users u ON u.id = r.user_id
+		WHERE r.message_id = $1 AND m.org_id = $2
+		ORDER BY r.updated_at ASC, r.user_id ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare list statement: %w", err)
+	}
+
+	return repo, nil
+}
+
+func (r *RSVPRepository) Set(ctx context.Context, rsvp *domain.RSVP) error {
+	err := stmt(ctx, r.setStmt).QueryRowContext(ctx,
+		rsvp.MessageID,
+		rsvp.UserID,
+		rsvp.Response,
+		domain.OrgIDFromContext(ctx),
+	).Scan(&rsvp.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return domain.ErrMessageNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save rsvp: %w", err)
+	}
+	return nil
+}
+
+func (r *RSVPRepository) List(ctx context.Context, messageID string) ([]*domain.RSVP, error) {
+	rows, err := stmt(ctx, r.listStmt).QueryContext(ctx, messageID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rsvps: %w", err)
+	}
+	defer rows.Close()
+
+	rsvps := []*domain.RSVP{}
+	for rows.Next() {
+		rsvp := &domain.RSVP{}
+		if err := rows.Scan(&rsvp.MessageID, &rsvp.ChatroomID, &rsvp.UserID, &rsvp.Username, &rsvp.Response, &rsvp.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan rsvp: %w", err)
+		}
+		rsvps = append(rsvps, rsvp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rsvps: %w", err)
+	}
+	return rsvps, nil
+}
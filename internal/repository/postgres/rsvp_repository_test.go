@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	rsvpSetQuery = `
+		INSERT INTO event_rsvps (message_id, user_id, response)
+		SELECT m.id, $2, $3
+		FROM messages m
+		WHERE m.id = $1 AND m.org_id = $4 AND m.deleted_at IS NULL
+		ON CONFLICT (message_id, user_id) DO UPDATE
+		SET response = EXCLUDED.response, updated_at = NOW()
+		RETURNING updated_at
+	`
+	rsvpListQuery = `
+		SELECT r.message_id, m.chatroom_id, r.user_id, u.username, r.response, r.updated_at
+		FROM event_rsvps r
+		JOIN messages m ON m.id = r.message_id
+		JOIN users u ON u.id = r.user_id
+		WHERE r.message_id = $1 AND m.org_id = $2
+		ORDER BY r.updated_at ASC, r.user_id ASC
+	`
+)
+
+func newTestRSVPRepository(t *testing.T) (*RSVPRepository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mock.ExpectPrepare(regexp.QuoteMeta(rsvpSetQuery))
+	mock.ExpectPrepare(regexp.QuoteMeta(rsvpListQuery))
+	repo, err := NewRSVPRepository(db)
+	require.NoError(t, err)
+	return repo, mock
+}
+
+func TestRSVPRepository_Set(t *testing.T) {
+	t.Run("saved", func(t *testing.T) {
+		repo, mock := newTestRSVPRepository(t)
+		updatedAt := time.Now()
+		mock.ExpectQuery(regexp.QuoteMeta(rsvpSetQuery)).
+			WithArgs("msg-1", "user-1", domain.RSVPGoing, domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(updatedAt))
+
+		rsvp := &domain.RSVP{MessageID: "msg-1", UserID: "user-1", Response: domain.RSVPGoing}
+		require.NoError(t, repo.Set(context.Background(), rsvp))
+		assert.Equal(t, updatedAt, rsvp.UpdatedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("message_not_found", func(t *testing.T) {
+		repo, mock := newTestRSVPRepository(t)
+		mock.ExpectQuery(regexp.QuoteMeta(rsvpSetQuery)).
+			WithArgs("missing", "user-1", domain.RSVPMaybe, domain.DefaultOrganizationID).
+			WillReturnError(sql.ErrNoRows)
+
+		err := repo.Set(context.Background(), &domain.RSVP{MessageID: "missing", UserID: "user-1", Response: domain.RSVPMaybe})
+		assert.ErrorIs(t, err, domain.ErrMessageNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRSVPRepository_List(t *testing.T) {
+	repo, mock := newTestRSVPRepository(t)
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(rsvpListQuery)).
+		WithArgs("msg-1", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"message_id", "chatroom_id", "user_id", "username", "response", "updated_at"}).
+			AddRow("msg-1", "room-1", "user-1", "alice", domain.RSVPGoing, now).
+			AddRow("msg-1", "room-1", "user-2", "bob", domain.RSVPDeclined, now.Add(time.Minute)))
+
+	rsvps, err := repo.List(context.Background(), "msg-1")
+	require.NoError(t, err)
+	require.Len(t, rsvps, 2)
+	assert.Equal(t, "alice", rsvps[0].Username)
+	assert.Equal(t, domain.RSVPDeclined, rsvps[1].Response)
+
+	mock.ExpectQuery(regexp.QuoteMeta(rsvpListQuery)).
+		WithArgs("msg-2", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"message_id", "chatroom_id", "user_id", "username", "response", "updated_at"}))
+	rsvps, err = repo.List(context.Background(), "msg-2")
+	require.NoError(t, err)
+	assert.Empty(t, rsvps)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
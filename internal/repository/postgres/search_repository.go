@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"jobsity-chat/internal/domain"
+)
+
+type SearchRepository struct {
+	db           *sql.DB
+	messagesStmt *sql.Stmt
+	roomsStmt    *sql.Stmt
+	usersStmt    *sql.Stmt
+}
+
+// NewSearchRepository creates a new SearchRepository with prepared
+// statements. Returns an error if statement preparation fails.
+func NewSearchRepository(db *sql.DB) (*SearchRepository, error) {
+	repo := &SearchRepository{db: db}
+
+	var err error
+	repo.messagesStmt, err = db.Prepare(`
+		SELECT m.id, m.chatroom_id, c.name, m.user_id, u.username, m.content, m.created_at,
+			ts_rank(m.search_vector, q.query) AS rank
+		FROM websearch_to_tsquery('simple', $1) q(query)
+		JOIN messages m ON m.search_vector @@ q.query
+		JOIN chatroom_members cm ON cm.chatroom_id = m.chatroom_id AND cm.user_id = $2
+		JOIN chatrooms c ON c.id = m.chatroom_id AND c.deleted_at IS NULL AND NOT c.encrypted
+		JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = $3 AND m.hidden_at IS NULL AND m.deleted_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM chatroom_shadow_bans b
+				WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM $2
+					AND (b.user_id = m.user_id
+						OR (m.is_bot AND b.user_id = (SELECT p.user_id FROM messages p WHERE p.id = m.parent_message_id)))
+			)
+		ORDER BY rank DESC, m.created_at DESC, m.id
+		LIMIT $4 OFFSET $5
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare messages statement: %w", err)
+	}
+
+	repo.roomsStmt, err = db.Prepare(`
+		SELECT c.id, c.name, c.encrypted, similarity(c.name, $1) AS rank
+		FROM chatrooms c
+		JOIN chatroom_members cm ON cm.chatroom_id = c.id AND cm.user_id = $2
+		WHERE c.org_id = $3 AND c.deleted_at IS NULL AND c.name ILIKE $4
+		ORDER BY rank DESC, c.name, c.id
+		LIMIT $5 OFFSET $6
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare rooms statement: %w", err)
+	}
+
+	repo.usersStmt, err = db.Prepare(`
+		SELECT u.id, u.username, similarity(u.username, $1) AS rank
+		FROM users u
+		WHERE u.org_id = $3 AND u.deactivated_at IS NULL AND u.username ILIKE $4
+			AND EXISTS (
+				SELECT 1
+				FROM chatroom_members mine
+				JOIN chatroom_members theirs ON theirs.chatroom_id = mine.chatroom_id
+				JOIN chatrooms c ON c.id = mine.chatroom_id AND c.deleted_at IS NULL
+				WHERE mine.user_id = $2 AND theirs.user_id = u.id
+			)
+		ORDER BY rank DESC, u.username, u.id
+		LIMIT $5 OFFSET $6
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare users statement: %w", err)
+	}
+
+	return repo, nil
+}
+
+func (r *SearchRepository) SearchMessages(ctx context.Context, opts domain.SearchOptions) ([]*domain.MessageHit, error) {
+	rows, err := stmt(ctx, r.messagesStmt).QueryContext(ctx,
+		opts.Query, opts.UserID, domain.OrgIDFromContext(ctx), opts.Limit, opts.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	defer rows.Close()
+
+	hits := []*domain.MessageHit{}
+	for rows.Next() {
+		hit := &domain.MessageHit{}
+		err := rows.Scan(&hit.ID, &hit.ChatroomID, &hit.ChatroomName, &hit.UserID, &hit.Username,
+			&hit.Content, &hit.CreatedAt, &hit.Rank)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message hit: %w", err)
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating message hits: %w", err)
+	}
+	return hits, nil
+}
+
+func (r *SearchRepository) SearchRooms(ctx context.Context, opts domain.SearchOptions) ([]*domain.RoomHit, error) {
+	rows, err := stmt(ctx, r.roomsStmt).QueryContext(ctx,
+		opts.Query, opts.UserID, domain.OrgIDFromContext(ctx), containsPattern(opts.Query), opts.Limit, opts.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search rooms: %w", err)
+	}
+	defer rows.Close()
+
+	hits := []*domain.RoomHit{}
+	for rows.Next() {
+		hit := &domain.RoomHit{}
+		if err := rows.Scan(&hit.ID, &hit.Name, &hit.Encrypted, &hit.Rank); err != nil {
+			return nil, fmt.Errorf("failed to scan room hit: %w", err)
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating room hits: %w", err)
+	}
+	return hits, nil
+}
+
+func (r *SearchRepository) SearchUsers(ctx context.Context, opts domain.SearchOptions) ([]*domain.UserHit, error) {
+	rows, err := stmt(ctx, r.usersStmt).QueryContext(ctx,
+		opts.Query, opts.UserID, domain.OrgIDFromContext(ctx), containsPattern(opts.Query), opts.Limit, opts.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	hits := []*domain.UserHit{}
+	for rows.Next() {
+		hit := &domain.UserHit{}
+		if err := rows.Scan(&hit.ID, &hit.Username, &hit.Rank); err != nil {
+			return nil, fmt.Errorf("failed to scan user hit: %w", err)
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user hits: %w", err)
+	}
+	return hits, nil
+}
+
+// containsPattern is the ILIKE pattern matching strings that contain s
+func containsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
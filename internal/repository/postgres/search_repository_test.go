@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSearchRepository(t *testing.T) (*SearchRepository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mock.ExpectPrepare(regexp.QuoteMeta(`FROM websearch_to_tsquery('simple', $1) q(query)`))
+	mock.ExpectPrepare(regexp.QuoteMeta(`SELECT c.id, c.name, c.encrypted, similarity(c.name, $1) AS rank`))
+	mock.ExpectPrepare(regexp.QuoteMeta(`SELECT u.id, u.username, similarity(u.username, $1) AS rank`))
+	repo, err := NewSearchRepository(db)
+	require.NoError(t, err)
+	return repo, mock
+}
+
+func TestSearchRepository_SearchMessages(t *testing.T) {
+	repo, mock := newTestSearchRepository(t)
+	createdAt := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`JOIN chatrooms c ON c.id = m.chatroom_id AND c.deleted_at IS NULL AND NOT c.encrypted`)).
+		WithArgs("quarterly report", "user-1", domain.DefaultOrganizationID, 21, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "name", "user_id", "username", "content", "created_at", "rank"}).
+			AddRow("msg-1", "room-1", "General", "user-2", "alice", "The quarterly report is out", createdAt, 0.09))
+
+	hits, err := repo.SearchMessages(context.Background(), domain.SearchOptions{Query: "quarterly report", UserID: "user-1", Limit: 21, Offset: 20})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, domain.MessageHit{
+		ID: "msg-1", ChatroomID: "room-1", ChatroomName: "General", UserID: "user-2", Username: "alice",
+		Content: "The quarterly report is out", CreatedAt: createdAt, Rank: 0.09,
+	}, *hits[0])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchRepository_SearchMessagesHidesShadowBannedUsers(t *testing.T) {
+	repo, mock := newTestSearchRepository(t)
+
+	// The searching user is exempt, so they still find their own messages
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE b.chatroom_id = m.chatroom_id AND b.user_id IS DISTINCT FROM $2`)).
+		WithArgs("report", "user-1", domain.DefaultOrganizationID, 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chatroom_id", "name", "user_id", "username", "content", "created_at", "rank"}))
+
+	hits, err := repo.SearchMessages(context.Background(), domain.SearchOptions{Query: "report", UserID: "user-1", Limit: 20})
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchRepository_SearchRooms(t *testing.T) {
+	repo, mock := newTestSearchRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE c.org_id = $3 AND c.deleted_at IS NULL AND c.name ILIKE $4`)).
+		WithArgs("50%", "user-1", domain.DefaultOrganizationID, `%50\%%`, 11, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "encrypted", "rank"}).
+			AddRow("room-1", "50% off", false, 0.4).
+			AddRow("room-2", "Deals 50%", true, 0.3))
+
+	hits, err := repo.SearchRooms(context.Background(), domain.SearchOptions{Query: "50%", UserID: "user-1", Limit: 11})
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+	assert.Equal(t, "50% off", hits[0].Name)
+	assert.True(t, hits[1].Encrypted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchRepository_SearchUsers(t *testing.T) {
+	repo, mock := newTestSearchRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE u.org_id = $3 AND u.deactivated_at IS NULL AND u.username ILIKE $4`)).
+		WithArgs("ali", "user-1", domain.DefaultOrganizationID, "%ali%", 11, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "rank"}).AddRow("user-2", "alice", 0.5))
+
+	hits, err := repo.SearchUsers(context.Background(), domain.SearchOptions{Query: "ali", UserID: "user-1", Limit: 11, Offset: 10})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, domain.UserHit{ID: "user-2", Username: "alice", Rank: 0.5}, *hits[0])
+
+	mock.ExpectQuery(regexp.QuoteMeta(`u.username ILIKE $4`)).
+		WillReturnError(errors.New("connection reset"))
+	_, err = repo.SearchUsers(context.Background(), domain.SearchOptions{Query: "bob", UserID: "user-1", Limit: 11})
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
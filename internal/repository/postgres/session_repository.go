@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"jobsity-chat/internal/domain"
+
+	"github.com/lib/pq"
 )
 
 type SessionRepository struct {
@@ -24,18 +26,18 @@ func NewSessionRepository(db *sql.DB) (*SessionRepository, error) {
 
 	var err error
 	repo.createStmt, err = db.Prepare(`
-		INSERT INTO sessions (user_id, token, expires_at)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		INSERT INTO sessions (org_id, user_id, token, expires_at, absolute_expires_at, idle_timeout_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, last_activity_at, created_at
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare create statement: %w", err)
 	}
 
 	repo.getByTokenStmt, err = db.Prepare(`
-		SELECT id, user_id, token, expires_at, created_at
+		SELECT id, user_id, token, expires_at, absolute_expires_at, idle_timeout_seconds, last_activity_at, created_at
 		FROM sessions
-		WHERE token = $1 AND expires_at > $2
+		WHERE token = $1 AND expires_at > $2 AND org_id = $3
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare getByToken statement: %w", err)
@@ -55,25 +57,47 @@ func NewSessionRepository(db *sql.DB) (*SessionRepository, error) {
 }
 
 func (r *SessionRepository) Create(ctx context.Context, session *domain.Session) error {
-	err := r.createStmt.QueryRowContext(ctx,
+	absolute := session.AbsoluteExpiresAt
+	if absolute.IsZero() {
+		absolute = session.ExpiresAt
+	}
+	idle := session.IdleTimeout
+	if idle <= 0 {
+		idle = time.Until(absolute)
+	}
+
+	orgID := domain.OrgIDFromContext(ctx)
+	err := stmt(ctx, r.createStmt).QueryRowContext(ctx,
+		orgID,
 		session.UserID,
 		session.Token,
 		session.ExpiresAt,
-	).Scan(&session.ID, &session.CreatedAt)
+		absolute,
+		max(int64(idle.Seconds()), 1),
+	).Scan(&session.ID, &session.LastActivityAt, &session.CreatedAt)
 
 	if err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
 	}
+	session.OrgID = orgID
+	session.AbsoluteExpiresAt = absolute
+	session.IdleTimeout = idle
 	return nil
 }
 
+// GetByToken only finds sessions of the organization ctx is scoped to, so a
+// session cannot be replayed against another tenant
 func (r *SessionRepository) GetByToken(ctx context.Context, token string) (*domain.Session, error) {
-	session := &domain.Session{}
-	err := r.getByTokenStmt.QueryRowContext(ctx, token, time.Now()).Scan(
+	session := &domain.Session{OrgID: domain.OrgIDFromContext(ctx)}
+	var idleSeconds int64
+	err := stmt(ctx, r.getByTokenStmt).QueryRowContext(ctx, token, time.Now(), session.OrgID).Scan(
 		&session.ID,
 		&session.UserID,
 		&session.Token,
 		&session.ExpiresAt,
+		&session.AbsoluteExpiresAt,
+		&idleSeconds,
+		&session.LastActivityAt,
 		&session.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -82,11 +106,12 @@ func (r *SessionRepository) GetByToken(ctx context.Context, token string) (*doma
 	if err != nil {
 		return nil, fmt.Errorf("failed to get session by token: %w", err)
 	}
+	session.IdleTimeout = time.Duration(idleSeconds) * time.Second
 	return session, nil
 }
 
 func (r *SessionRepository) Delete(ctx context.Context, token string) error {
-	_, err := r.deleteStmt.ExecContext(ctx, token)
+	_, err := stmt(ctx, r.deleteStmt).ExecContext(ctx, token)
 	if err != nil {
 		return fmt.Errorf("failed to delete session: %w", err)
 	}
@@ -94,7 +119,7 @@ func (r *SessionRepository) Delete(ctx context.Context, token string) error {
 }
 
 func (r *SessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
-	result, err := r.deleteExpiredStmt.ExecContext(ctx, time.Now())
+	result, err := stmt(ctx, r.deleteExpiredStmt).ExecContext(ctx, time.Now())
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
 	}
@@ -106,3 +131,50 @@ func (r *SessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
 
 	return count, nil
 }
+
+func (r *SessionRepository) DeleteByUserID(ctx context.Context, userID string) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete user sessions: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return count, nil
+}
+
+// Touch slides the expiry of every listed session in a single statement.
+// Expired sessions are left alone so activity cannot revive them.
+func (r *SessionRepository) Touch(ctx context.Context, activity []domain.SessionActivity) (int64, error) {
+	if len(activity) == 0 {
+		return 0, nil
+	}
+
+	tokens := make([]string, len(activity))
+	times := make([]string, len(activity))
+	for i, a := range activity {
+		tokens[i] = a.Token
+		times[i] = a.At.Format(time.RFC3339Nano)
+	}
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE sessions AS s
+		SET last_activity_at = a.at,
+		    expires_at = LEAST(a.at + s.idle_timeout_seconds * INTERVAL '1 second', s.absolute_expires_at)
+		FROM unnest($1::text[], $2::timestamp[]) AS a(token, at)
+		WHERE s.token = a.token AND s.expires_at > a.at
+	`, pq.Array(tokens), pq.Array(times))
+	if err != nil {
+		return 0, fmt.Errorf("failed to touch sessions: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return count, nil
+}
@@ -35,9 +35,9 @@ func TestNewSessionRepository(t *testing.T) {
 		defer db.Close()
 
 		mock.ExpectPrepare(regexp.QuoteMeta(`
-		INSERT INTO sessions (user_id, token, expires_at)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		INSERT INTO sessions (org_id, user_id, token, expires_at, absolute_expires_at, idle_timeout_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, last_activity_at, created_at
 	`)).WillReturnError(errors.New("prepare failed"))
 
 		repo, err := NewSessionRepository(db)
@@ -61,26 +61,31 @@ func TestSessionRepository_Create(t *testing.T) {
 		sessionID := "550e8400-e29b-41d4-a716-446655440000"
 		userID := "user-123"
 		createdAt := time.Now()
+		expiresAt := createdAt.Add(2 * time.Hour)
+		absoluteExpiresAt := createdAt.Add(24 * time.Hour)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		INSERT INTO sessions (user_id, token, expires_at)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		INSERT INTO sessions (org_id, user_id, token, expires_at, absolute_expires_at, idle_timeout_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, last_activity_at, created_at
 	`)).
-			WithArgs(userID, "token123", time.Time{}).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).
-				AddRow(sessionID, createdAt))
+			WithArgs(domain.DefaultOrganizationID, userID, "token123", expiresAt, absoluteExpiresAt, int64(7200)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "last_activity_at", "created_at"}).
+				AddRow(sessionID, createdAt, createdAt))
 
 		session := &domain.Session{
-			UserID:    userID,
-			Token:     "token123",
-			ExpiresAt: time.Time{},
+			UserID:            userID,
+			Token:             "token123",
+			ExpiresAt:         expiresAt,
+			AbsoluteExpiresAt: absoluteExpiresAt,
+			IdleTimeout:       2 * time.Hour,
 		}
 
 		err = repo.Create(context.Background(), session)
 		require.NoError(t, err)
 		assert.Equal(t, sessionID, session.ID)
 		assert.Equal(t, createdAt, session.CreatedAt)
+		assert.Equal(t, createdAt, session.LastActivityAt)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
@@ -95,9 +100,9 @@ func TestSessionRepository_Create(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		INSERT INTO sessions (user_id, token, expires_at)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		INSERT INTO sessions (org_id, user_id, token, expires_at, absolute_expires_at, idle_timeout_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, last_activity_at, created_at
 	`)).
 			WillReturnError(errors.New("database error"))
 
@@ -130,19 +135,20 @@ func TestSessionRepository_GetByToken(t *testing.T) {
 		expiresAt := time.Now().Add(24 * time.Hour)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, user_id, token, expires_at, created_at
+		SELECT id, user_id, token, expires_at, absolute_expires_at, idle_timeout_seconds, last_activity_at, created_at
 		FROM sessions
-		WHERE token = $1 AND expires_at > $2
+		WHERE token = $1 AND expires_at > $2 AND org_id = $3
 	`)).
-			WithArgs("token123", sqlmock.AnyArg()).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "token", "expires_at", "created_at"}).
-				AddRow(sessionID, userID, "token123", expiresAt, createdAt))
+			WithArgs("token123", sqlmock.AnyArg(), domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "token", "expires_at", "absolute_expires_at", "idle_timeout_seconds", "last_activity_at", "created_at"}).
+				AddRow(sessionID, userID, "token123", expiresAt, expiresAt, int64(3600), createdAt, createdAt))
 
 		session, err := repo.GetByToken(context.Background(), "token123")
 		require.NoError(t, err)
 		assert.Equal(t, sessionID, session.ID)
 		assert.Equal(t, userID, session.UserID)
 		assert.Equal(t, "token123", session.Token)
+		assert.Equal(t, time.Hour, session.IdleTimeout)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
@@ -157,11 +163,11 @@ func TestSessionRepository_GetByToken(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, user_id, token, expires_at, created_at
+		SELECT id, user_id, token, expires_at, absolute_expires_at, idle_timeout_seconds, last_activity_at, created_at
 		FROM sessions
-		WHERE token = $1 AND expires_at > $2
+		WHERE token = $1 AND expires_at > $2 AND org_id = $3
 	`)).
-			WithArgs("nonexistent", sqlmock.AnyArg()).
+			WithArgs("nonexistent", sqlmock.AnyArg(), domain.DefaultOrganizationID).
 			WillReturnError(sql.ErrNoRows)
 
 		session, err := repo.GetByToken(context.Background(), "nonexistent")
@@ -170,6 +176,29 @@ func TestSessionRepository_GetByToken(t *testing.T) {
 		assert.Equal(t, domain.ErrSessionNotFound, err)
 	})
 
+	t.Run("session_of_other_organization", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupSessionRepositoryMocks(mock)
+
+		repo, err := NewSessionRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, user_id, token, expires_at, absolute_expires_at, idle_timeout_seconds, last_activity_at, created_at
+		FROM sessions
+		WHERE token = $1 AND expires_at > $2 AND org_id = $3
+	`)).
+			WithArgs("token123", sqlmock.AnyArg(), "org-2").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err = repo.GetByToken(domain.WithOrgID(context.Background(), "org-2"), "token123")
+		assert.Equal(t, domain.ErrSessionNotFound, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("expired_session_returns_not_found", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		require.NoError(t, err)
@@ -182,11 +211,11 @@ func TestSessionRepository_GetByToken(t *testing.T) {
 
 		// Expired sessions should not be returned
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, user_id, token, expires_at, created_at
+		SELECT id, user_id, token, expires_at, absolute_expires_at, idle_timeout_seconds, last_activity_at, created_at
 		FROM sessions
-		WHERE token = $1 AND expires_at > $2
+		WHERE token = $1 AND expires_at > $2 AND org_id = $3
 	`)).
-			WithArgs("expired_token", sqlmock.AnyArg()).
+			WithArgs("expired_token", sqlmock.AnyArg(), domain.DefaultOrganizationID).
 			WillReturnError(sql.ErrNoRows)
 
 		session, err := repo.GetByToken(context.Background(), "expired_token")
@@ -206,11 +235,11 @@ func TestSessionRepository_GetByToken(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, user_id, token, expires_at, created_at
+		SELECT id, user_id, token, expires_at, absolute_expires_at, idle_timeout_seconds, last_activity_at, created_at
 		FROM sessions
-		WHERE token = $1 AND expires_at > $2
+		WHERE token = $1 AND expires_at > $2 AND org_id = $3
 	`)).
-			WithArgs("token123", sqlmock.AnyArg()).
+			WithArgs("token123", sqlmock.AnyArg(), domain.DefaultOrganizationID).
 			WillReturnError(errors.New("database error"))
 
 		session, err := repo.GetByToken(context.Background(), "token123")
@@ -366,18 +395,127 @@ func TestSessionRepository_DeleteExpired(t *testing.T) {
 // Helper function to set up common mock expectations
 func setupSessionRepositoryMocks(mock sqlmock.Sqlmock) {
 	mock.ExpectPrepare(regexp.QuoteMeta(`
-		INSERT INTO sessions (user_id, token, expires_at)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		INSERT INTO sessions (org_id, user_id, token, expires_at, absolute_expires_at, idle_timeout_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, last_activity_at, created_at
 	`)).WillReturnCloseError(nil)
 
 	mock.ExpectPrepare(regexp.QuoteMeta(`
-		SELECT id, user_id, token, expires_at, created_at
+		SELECT id, user_id, token, expires_at, absolute_expires_at, idle_timeout_seconds, last_activity_at, created_at
 		FROM sessions
-		WHERE token = $1 AND expires_at > $2
+		WHERE token = $1 AND expires_at > $2 AND org_id = $3
 	`)).WillReturnCloseError(nil)
 
 	mock.ExpectPrepare(regexp.QuoteMeta(`DELETE FROM sessions WHERE token = $1`)).WillReturnCloseError(nil)
 
 	mock.ExpectPrepare(regexp.QuoteMeta(`DELETE FROM sessions WHERE expires_at <= $1`)).WillReturnCloseError(nil)
 }
+
+func TestSessionRepository_DeleteByUserID(t *testing.T) {
+	t.Run("deletes_all_user_sessions", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupSessionRepositoryMocks(mock)
+
+		repo, err := NewSessionRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM sessions WHERE user_id = $1`)).
+			WithArgs("user-123").
+			WillReturnResult(sqlmock.NewResult(0, 3))
+
+		count, err := repo.DeleteByUserID(context.Background(), "user-123")
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database_error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupSessionRepositoryMocks(mock)
+
+		repo, err := NewSessionRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM sessions WHERE user_id = $1`)).
+			WithArgs("user-123").
+			WillReturnError(errors.New("connection lost"))
+
+		_, err = repo.DeleteByUserID(context.Background(), "user-123")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to delete user sessions")
+	})
+}
+
+func TestSessionRepository_Touch(t *testing.T) {
+	touchQuery := regexp.QuoteMeta(`
+		UPDATE sessions AS s
+		SET last_activity_at = a.at,
+		    expires_at = LEAST(a.at + s.idle_timeout_seconds * INTERVAL '1 second', s.absolute_expires_at)
+		FROM unnest($1::text[], $2::timestamp[]) AS a(token, at)
+		WHERE s.token = a.token AND s.expires_at > a.at
+	`)
+
+	t.Run("renews_sessions_in_one_statement", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupSessionRepositoryMocks(mock)
+
+		repo, err := NewSessionRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectExec(touchQuery).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		now := time.Now()
+		count, err := repo.Touch(context.Background(), []domain.SessionActivity{
+			{Token: "token-1", At: now},
+			{Token: "token-2", At: now},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("empty_batch_skips_query", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupSessionRepositoryMocks(mock)
+
+		repo, err := NewSessionRepository(db)
+		require.NoError(t, err)
+
+		count, err := repo.Touch(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database_error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupSessionRepositoryMocks(mock)
+
+		repo, err := NewSessionRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectExec(touchQuery).
+			WillReturnError(errors.New("connection lost"))
+
+		_, err = repo.Touch(context.Background(), []domain.SessionActivity{{Token: "token-1", At: time.Now()}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to touch sessions")
+	})
+}
@@ -6,6 +6,12 @@ import (
 	"fmt"
 )
 
+type txKey struct{}
+
+// TxManager runs work in a database transaction. Besides the repository
+// internal WithTx, it implements domain.TxManager: repository calls made
+// with the context WithinTx passes on join its transaction, so a service
+// can make changes across repositories atomically.
 type TxManager struct {
 	db *sql.DB
 }
@@ -14,7 +20,25 @@ func NewTxManager(db *sql.DB) *TxManager {
 	return &TxManager{db: db}
 }
 
+// WithinTx runs fn in a transaction that repository calls made with fn's ctx
+// join. It commits when fn returns nil and rolls back otherwise. When ctx
+// already carries a transaction, fn runs in it and the outermost call
+// commits.
+func (tm *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := txFromContext(ctx); ok {
+		return fn(ctx)
+	}
+	return tm.WithTx(ctx, func(tx *sql.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// WithTx runs fn in a transaction, joining the one ctx carries if any
 func (tm *TxManager) WithTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	if tx, ok := txFromContext(ctx); ok {
+		return fn(tx)
+	}
+
 	tx, err := tm.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -33,3 +57,32 @@ func (tm *TxManager) WithTx(ctx context.Context, fn func(*sql.Tx) error) error {
 
 	return nil
 }
+
+func txFromContext(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*sql.Tx)
+	return tx, ok
+}
+
+// querier is what *sql.DB and *sql.Tx have in common
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn returns the transaction ctx carries, or db outside one
+func conn(ctx context.Context, db *sql.DB) querier {
+	if tx, ok := txFromContext(ctx); ok {
+		return tx
+	}
+	return db
+}
+
+// stmt returns the prepared statement bound to the transaction ctx carries,
+// or the statement itself outside one
+func stmt(ctx context.Context, s *sql.Stmt) *sql.Stmt {
+	if tx, ok := txFromContext(ctx); ok {
+		return tx.StmtContext(ctx, s)
+	}
+	return s
+}
@@ -378,3 +378,92 @@ func TestTxManager_WithTx_ConcurrentSafety(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestTxManager_WithinTx_SpansRepositories(t *testing.T) {
+	t.Run("commits_changes_across_repositories", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupSessionRepositoryMocks(mock)
+		sessionRepo, err := NewSessionRepository(db)
+		require.NoError(t, err)
+		userRepo := &UserRepository{db: db}
+		tm := NewTxManager(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE users SET deactivated_at").
+			WithArgs("user-1", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM sessions WHERE user_id").
+			WithArgs("user-1").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("DELETE FROM sessions WHERE token").
+			WithArgs("token-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err = tm.WithinTx(context.Background(), func(ctx context.Context) error {
+			if err := userRepo.SetDeactivated(ctx, "user-1", true); err != nil {
+				return err
+			}
+			if _, err := sessionRepo.DeleteByUserID(ctx, "user-1"); err != nil {
+				return err
+			}
+			// Prepared statements run in the transaction too
+			return sessionRepo.Delete(ctx, "token-1")
+		})
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls_back_when_a_step_fails", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		userRepo := &UserRepository{db: db}
+		sessionRepo := &SessionRepository{db: db}
+		tm := NewTxManager(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE users SET deactivated_at").
+			WithArgs("user-1", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM sessions WHERE user_id").
+			WithArgs("user-1").
+			WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		err = tm.WithinTx(context.Background(), func(ctx context.Context) error {
+			if err := userRepo.SetDeactivated(ctx, "user-1", true); err != nil {
+				return err
+			}
+			_, err := sessionRepo.DeleteByUserID(ctx, "user-1")
+			return err
+		})
+		require.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("nested_transactions_join_the_outer_one", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		tm := NewTxManager(db)
+
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		err = tm.WithinTx(context.Background(), func(ctx context.Context) error {
+			outer, _ := txFromContext(ctx)
+			return tm.WithTx(ctx, func(tx *sql.Tx) error {
+				assert.Same(t, outer, tx)
+				return tm.WithinTx(ctx, func(ctx context.Context) error { return nil })
+			})
+		})
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
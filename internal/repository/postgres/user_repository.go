@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"jobsity-chat/internal/domain"
 )
@@ -22,27 +23,27 @@ func NewUserRepository(db *sql.DB) (*UserRepository, error) {
 
 	var err error
 	repo.createStmt, err = db.Prepare(`
-		INSERT INTO users (username, email, password_hash)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		INSERT INTO users (org_id, username, email, password_hash, is_system)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, role, created_at
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare create statement: %w", err)
 	}
 
 	repo.getByIDStmt, err = db.Prepare(`
-		SELECT id, username, email, password_hash, created_at
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
 		FROM users
-		WHERE id = $1
+		WHERE id = $1 AND org_id = $2
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare getByID statement: %w", err)
 	}
 
 	repo.getByUsernameStmt, err = db.Prepare(`
-		SELECT id, username, email, password_hash, created_at
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
 		FROM users
-		WHERE username = $1
+		WHERE username = $1 AND org_id = $2
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare getByUsername statement: %w", err)
@@ -52,32 +53,40 @@ func NewUserRepository(db *sql.DB) (*UserRepository, error) {
 }
 
 func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
-	err := r.createStmt.QueryRowContext(ctx,
+	orgID := domain.OrgIDFromContext(ctx)
+	err := stmt(ctx, r.createStmt).QueryRowContext(ctx,
+		orgID,
 		user.Username,
 		user.Email,
 		user.PasswordHash,
-	).Scan(&user.ID, &user.CreatedAt)
+		user.IsSystem,
+	).Scan(&user.ID, &user.Role, &user.CreatedAt)
 
 	if err != nil {
-		if IsUniqueViolation(err, "users_username_key") {
+		if IsUniqueViolation(err, "users_org_username_key") {
 			return domain.ErrUsernameExists
 		}
-		if IsUniqueViolation(err, "users_email_key") {
+		if IsUniqueViolation(err, "users_org_email_key") {
 			return domain.ErrEmailExists
 		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
+	user.OrgID = orgID
 	return nil
 }
 
 func (r *UserRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
-	user := &domain.User{}
-	err := r.getByIDStmt.QueryRowContext(ctx, id).Scan(
+	user := &domain.User{OrgID: domain.OrgIDFromContext(ctx)}
+	err := stmt(ctx, r.getByIDStmt).QueryRowContext(ctx, id, user.OrgID).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
+		&user.Role,
+		&user.Locale,
+		&user.DeactivatedAt,
+		&user.IsSystem,
 		&user.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -90,12 +99,16 @@ func (r *UserRepository) GetByID(ctx context.Context, id string) (*domain.User,
 }
 
 func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
-	user := &domain.User{}
-	err := r.getByUsernameStmt.QueryRowContext(ctx, username).Scan(
+	user := &domain.User{OrgID: domain.OrgIDFromContext(ctx)}
+	err := stmt(ctx, r.getByUsernameStmt).QueryRowContext(ctx, username, user.OrgID).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
+		&user.Role,
+		&user.Locale,
+		&user.DeactivatedAt,
+		&user.IsSystem,
 		&user.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -109,16 +122,20 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*d
 
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, created_at
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
 		FROM users
-		WHERE email = $1
+		WHERE email = $1 AND org_id = $2
 	`
-	user := &domain.User{}
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
+	user := &domain.User{OrgID: domain.OrgIDFromContext(ctx)}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, email, user.OrgID).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
+		&user.Role,
+		&user.Locale,
+		&user.DeactivatedAt,
+		&user.IsSystem,
 		&user.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -129,3 +146,225 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.
 	}
 	return user, nil
 }
+
+func (r *UserRepository) HasConfusableUsername(ctx context.Context, username, exceptUserID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM users
+			WHERE org_id = $1 AND username_skeleton(username) = username_skeleton($2) AND id::text <> $3
+		)
+	`
+	var exists bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, domain.OrgIDFromContext(ctx), username, exceptUserID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check confusable usernames: %w", err)
+	}
+	return exists, nil
+}
+
+func (r *UserRepository) UpdateRole(ctx context.Context, userID, role string) error {
+	if !domain.IsValidRole(role) {
+		return domain.ErrInvalidInput
+	}
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE users SET role = $1 WHERE id = $2 AND org_id = $3`, role, userID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if count == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *UserRepository) SetDeactivated(ctx context.Context, userID string, deactivated bool) error {
+	query := `UPDATE users SET deactivated_at = NULL WHERE id = $1 AND org_id = $2`
+	if deactivated {
+		query = `UPDATE users SET deactivated_at = COALESCE(deactivated_at, CURRENT_TIMESTAMP) WHERE id = $1 AND org_id = $2`
+	}
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, userID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to update user deactivation: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if count == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *UserRepository) SetLocale(ctx context.Context, userID, locale string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE users SET locale = $1 WHERE id = $2 AND org_id = $3`, locale, userID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to update user locale: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if count == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *UserRepository) GetProfile(ctx context.Context, id string) (*domain.Profile, error) {
+	query := `
+		SELECT id, username, avatar_url, profile_visibility, show_online_status, profile_version, created_at
+		FROM users
+		WHERE id = $1 AND org_id = $2 AND deactivated_at IS NULL
+	`
+	profile := &domain.Profile{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, id, domain.OrgIDFromContext(ctx)).Scan(
+		&profile.ID,
+		&profile.Username,
+		&profile.AvatarURL,
+		&profile.Visibility,
+		&profile.ShowOnlineStatus,
+		&profile.Version,
+		&profile.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user profile: %w", err)
+	}
+	return profile, nil
+}
+
+func (r *UserRepository) UpdateProfileSettings(ctx context.Context, userID string, settings *domain.ProfileSettings) error {
+	orgID := domain.OrgIDFromContext(ctx)
+	var version int
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		UPDATE users
+		SET avatar_url = $1, profile_visibility = $2, show_online_status = $3, profile_version = profile_version + 1
+		WHERE id = $4 AND org_id = $5 AND ($6 = 0 OR profile_version = $6)
+		RETURNING profile_version
+	`, settings.AvatarURL, settings.Visibility, settings.ShowOnlineStatus, userID, orgID, settings.Version).Scan(&version)
+	if err == sql.ErrNoRows {
+		// Either the user is gone or the version check failed
+		var exists bool
+		if err := conn(ctx, r.db).QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND org_id = $2)`, userID, orgID,
+		).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check user: %w", err)
+		}
+		if !exists {
+			return domain.ErrUserNotFound
+		}
+		return domain.ErrVersionConflict
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update profile settings: %w", err)
+	}
+	settings.Version = version
+	return nil
+}
+
+func (r *UserRepository) GetStatus(ctx context.Context, userID string) (*domain.UserStatus, error) {
+	status := &domain.UserStatus{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT status, status_text
+		FROM users
+		WHERE id = $1 AND org_id = $2 AND deactivated_at IS NULL
+	`, userID, domain.OrgIDFromContext(ctx)).Scan(&status.Status, &status.Text)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user status: %w", err)
+	}
+	return status, nil
+}
+
+func (r *UserRepository) SetStatus(ctx context.Context, userID string, status *domain.UserStatus) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE users SET status = $1, status_text = $2 WHERE id = $3 AND org_id = $4`,
+		status.Status, status.Text, userID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to update user status: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if count == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *UserRepository) CreateGuest(ctx context.Context, user *domain.User) error {
+	orgID := domain.OrgIDFromContext(ctx)
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO users (org_id, username, email, password_hash, role)
+		VALUES ($1, $2, $3, $4, 'guest')
+		RETURNING id, role, created_at
+	`, orgID, user.Username, user.Email, user.PasswordHash).Scan(&user.ID, &user.Role, &user.CreatedAt)
+	if err != nil {
+		if IsUniqueViolation(err, "users_org_username_key") {
+			return domain.ErrUsernameExists
+		}
+		if IsUniqueViolation(err, "users_org_email_key") {
+			return domain.ErrEmailExists
+		}
+		return fmt.Errorf("failed to create guest: %w", err)
+	}
+
+	user.OrgID = orgID
+	return nil
+}
+
+func (r *UserRepository) ConvertGuest(ctx context.Context, user *domain.User) error {
+	orgID := domain.OrgIDFromContext(ctx)
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		UPDATE users
+		SET username = $1, email = $2, password_hash = $3, role = 'user'
+		WHERE id = $4 AND org_id = $5 AND role = 'guest'
+		RETURNING role, created_at
+	`, user.Username, user.Email, user.PasswordHash, user.ID, orgID).Scan(&user.Role, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return domain.ErrUserNotFound
+	}
+	if err != nil {
+		if IsUniqueViolation(err, "users_org_username_key") {
+			return domain.ErrUsernameExists
+		}
+		if IsUniqueViolation(err, "users_org_email_key") {
+			return domain.ErrEmailExists
+		}
+		return fmt.Errorf("failed to convert guest: %w", err)
+	}
+
+	user.OrgID = orgID
+	return nil
+}
+
+// DeleteGuestsCreatedBefore spans every organization; the guests' sessions,
+// memberships and messages go with them through ON DELETE CASCADE
+func (r *UserRepository) DeleteGuestsCreatedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := conn(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM users WHERE role = 'guest' AND created_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired guests: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return count, nil
+}
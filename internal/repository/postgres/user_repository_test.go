@@ -24,21 +24,21 @@ func TestNewUserRepository(t *testing.T) {
 
 		// Expect prepared statements
 		mock.ExpectPrepare(regexp.QuoteMeta(`
-		INSERT INTO users (username, email, password_hash)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		INSERT INTO users (org_id, username, email, password_hash, is_system)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, role, created_at
 	`)).WillReturnCloseError(nil)
 
 		mock.ExpectPrepare(regexp.QuoteMeta(`
-		SELECT id, username, email, password_hash, created_at
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
 		FROM users
-		WHERE id = $1
+		WHERE id = $1 AND org_id = $2
 	`)).WillReturnCloseError(nil)
 
 		mock.ExpectPrepare(regexp.QuoteMeta(`
-		SELECT id, username, email, password_hash, created_at
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
 		FROM users
-		WHERE username = $1
+		WHERE username = $1 AND org_id = $2
 	`)).WillReturnCloseError(nil)
 
 		repo, err := NewUserRepository(db)
@@ -53,9 +53,9 @@ func TestNewUserRepository(t *testing.T) {
 		defer db.Close()
 
 		mock.ExpectPrepare(regexp.QuoteMeta(`
-		INSERT INTO users (username, email, password_hash)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		INSERT INTO users (org_id, username, email, password_hash, is_system)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, role, created_at
 	`)).WillReturnError(errors.New("prepare failed"))
 
 		repo, err := NewUserRepository(db)
@@ -80,13 +80,13 @@ func TestUserRepository_Create(t *testing.T) {
 		userID := "550e8400-e29b-41d4-a716-446655440000"
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		INSERT INTO users (username, email, password_hash)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		INSERT INTO users (org_id, username, email, password_hash, is_system)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, role, created_at
 	`)).
-			WithArgs("testuser", "test@example.com", "hashed_password").
-			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).
-				AddRow(userID, createdAt))
+			WithArgs(domain.DefaultOrganizationID, "testuser", "test@example.com", "hashed_password", false).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "role", "created_at"}).
+				AddRow(userID, "user", createdAt))
 
 		user := &domain.User{
 			Username:     "testuser",
@@ -113,12 +113,12 @@ func TestUserRepository_Create(t *testing.T) {
 
 		// Simulate PostgreSQL unique constraint violation for username
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		INSERT INTO users (username, email, password_hash)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		INSERT INTO users (org_id, username, email, password_hash, is_system)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, role, created_at
 	`)).
-			WithArgs("testuser", "test@example.com", "hashed_password").
-			WillReturnError(&pq.Error{Code: "23505", Constraint: "users_username_key"})
+			WithArgs(domain.DefaultOrganizationID, "testuser", "test@example.com", "hashed_password", false).
+			WillReturnError(&pq.Error{Code: "23505", Constraint: "users_org_username_key"})
 
 		user := &domain.User{
 			Username:     "testuser",
@@ -143,12 +143,12 @@ func TestUserRepository_Create(t *testing.T) {
 
 		// Simulate PostgreSQL unique constraint violation for email
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		INSERT INTO users (username, email, password_hash)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		INSERT INTO users (org_id, username, email, password_hash, is_system)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, role, created_at
 	`)).
-			WithArgs("testuser", "test@example.com", "hashed_password").
-			WillReturnError(&pq.Error{Code: "23505", Constraint: "users_email_key"})
+			WithArgs(domain.DefaultOrganizationID, "testuser", "test@example.com", "hashed_password", false).
+			WillReturnError(&pq.Error{Code: "23505", Constraint: "users_org_email_key"})
 
 		user := &domain.User{
 			Username:     "testuser",
@@ -172,11 +172,11 @@ func TestUserRepository_Create(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		INSERT INTO users (username, email, password_hash)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		INSERT INTO users (org_id, username, email, password_hash, is_system)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, role, created_at
 	`)).
-			WithArgs("testuser", "test@example.com", "hashed_password").
+			WithArgs(domain.DefaultOrganizationID, "testuser", "test@example.com", "hashed_password", false).
 			WillReturnError(errors.New("database error"))
 
 		user := &domain.User{
@@ -206,13 +206,13 @@ func TestUserRepository_GetByID(t *testing.T) {
 		createdAt := time.Now()
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, username, email, password_hash, created_at
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
 		FROM users
-		WHERE id = $1
+		WHERE id = $1 AND org_id = $2
 	`)).
-			WithArgs(userID).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password_hash", "created_at"}).
-				AddRow(userID, "testuser", "test@example.com", "hashed_password", createdAt))
+			WithArgs(userID, domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password_hash", "role", "locale", "deactivated_at", "is_system", "created_at"}).
+				AddRow(userID, "testuser", "test@example.com", "hashed_password", "user", "", nil, false, createdAt))
 
 		user, err := repo.GetByID(context.Background(), userID)
 		require.NoError(t, err)
@@ -237,11 +237,11 @@ func TestUserRepository_GetByID(t *testing.T) {
 		userID := "nonexistent-id"
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, username, email, password_hash, created_at
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
 		FROM users
-		WHERE id = $1
+		WHERE id = $1 AND org_id = $2
 	`)).
-			WithArgs(userID).
+			WithArgs(userID, domain.DefaultOrganizationID).
 			WillReturnError(sql.ErrNoRows)
 
 		user, err := repo.GetByID(context.Background(), userID)
@@ -263,11 +263,11 @@ func TestUserRepository_GetByID(t *testing.T) {
 		userID := "550e8400-e29b-41d4-a716-446655440000"
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, username, email, password_hash, created_at
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
 		FROM users
-		WHERE id = $1
+		WHERE id = $1 AND org_id = $2
 	`)).
-			WithArgs(userID).
+			WithArgs(userID, domain.DefaultOrganizationID).
 			WillReturnError(errors.New("database connection error"))
 
 		user, err := repo.GetByID(context.Background(), userID)
@@ -292,13 +292,13 @@ func TestUserRepository_GetByUsername(t *testing.T) {
 		createdAt := time.Now()
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, username, email, password_hash, created_at
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
 		FROM users
-		WHERE username = $1
+		WHERE username = $1 AND org_id = $2
 	`)).
-			WithArgs("testuser").
-			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password_hash", "created_at"}).
-				AddRow(userID, "testuser", "test@example.com", "hashed_password", createdAt))
+			WithArgs("testuser", domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password_hash", "role", "locale", "deactivated_at", "is_system", "created_at"}).
+				AddRow(userID, "testuser", "test@example.com", "hashed_password", "user", "", nil, false, createdAt))
 
 		user, err := repo.GetByUsername(context.Background(), "testuser")
 		require.NoError(t, err)
@@ -307,6 +307,31 @@ func TestUserRepository_GetByUsername(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("scoped_to_context_organization", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupUserRepositoryMocks(mock)
+
+		repo, err := NewUserRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
+		FROM users
+		WHERE username = $1 AND org_id = $2
+	`)).
+			WithArgs("testuser", "org-2").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password_hash", "role", "locale", "deactivated_at", "is_system", "created_at"}).
+				AddRow("user-2", "testuser", "test@example.com", "hashed_password", "user", "", nil, false, time.Now()))
+
+		user, err := repo.GetByUsername(domain.WithOrgID(context.Background(), "org-2"), "testuser")
+		require.NoError(t, err)
+		assert.Equal(t, "org-2", user.OrgID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("user_not_found", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		require.NoError(t, err)
@@ -318,11 +343,11 @@ func TestUserRepository_GetByUsername(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, username, email, password_hash, created_at
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
 		FROM users
-		WHERE username = $1
+		WHERE username = $1 AND org_id = $2
 	`)).
-			WithArgs("nonexistent").
+			WithArgs("nonexistent", domain.DefaultOrganizationID).
 			WillReturnError(sql.ErrNoRows)
 
 		user, err := repo.GetByUsername(context.Background(), "nonexistent")
@@ -342,11 +367,11 @@ func TestUserRepository_GetByUsername(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, username, email, password_hash, created_at
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
 		FROM users
-		WHERE username = $1
+		WHERE username = $1 AND org_id = $2
 	`)).
-			WithArgs("testuser").
+			WithArgs("testuser", domain.DefaultOrganizationID).
 			WillReturnError(errors.New("database error"))
 
 		user, err := repo.GetByUsername(context.Background(), "testuser")
@@ -371,13 +396,13 @@ func TestUserRepository_GetByEmail(t *testing.T) {
 		createdAt := time.Now()
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, username, email, password_hash, created_at
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
 		FROM users
-		WHERE email = $1
+		WHERE email = $1 AND org_id = $2
 	`)).
-			WithArgs("test@example.com").
-			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password_hash", "created_at"}).
-				AddRow(userID, "testuser", "test@example.com", "hashed_password", createdAt))
+			WithArgs("test@example.com", domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password_hash", "role", "locale", "deactivated_at", "is_system", "created_at"}).
+				AddRow(userID, "testuser", "test@example.com", "hashed_password", "user", "", nil, false, createdAt))
 
 		user, err := repo.GetByEmail(context.Background(), "test@example.com")
 		require.NoError(t, err)
@@ -397,11 +422,11 @@ func TestUserRepository_GetByEmail(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, username, email, password_hash, created_at
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
 		FROM users
-		WHERE email = $1
+		WHERE email = $1 AND org_id = $2
 	`)).
-			WithArgs("nonexistent@example.com").
+			WithArgs("nonexistent@example.com", domain.DefaultOrganizationID).
 			WillReturnError(sql.ErrNoRows)
 
 		user, err := repo.GetByEmail(context.Background(), "nonexistent@example.com")
@@ -421,11 +446,11 @@ func TestUserRepository_GetByEmail(t *testing.T) {
 		require.NoError(t, err)
 
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, username, email, password_hash, created_at
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
 		FROM users
-		WHERE email = $1
+		WHERE email = $1 AND org_id = $2
 	`)).
-			WithArgs("test@example.com").
+			WithArgs("test@example.com", domain.DefaultOrganizationID).
 			WillReturnError(errors.New("database error"))
 
 		user, err := repo.GetByEmail(context.Background(), "test@example.com")
@@ -446,11 +471,11 @@ func TestUserRepository_GetByEmail(t *testing.T) {
 
 		// Return wrong number of columns
 		mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, username, email, password_hash, created_at
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
 		FROM users
-		WHERE email = $1
+		WHERE email = $1 AND org_id = $2
 	`)).
-			WithArgs("test@example.com").
+			WithArgs("test@example.com", domain.DefaultOrganizationID).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).
 				AddRow("123", "testuser"))
 
@@ -461,22 +486,462 @@ func TestUserRepository_GetByEmail(t *testing.T) {
 }
 
 // Helper function to set up common mock expectations
+func TestUserRepository_HasConfusableUsername(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupUserRepositoryMocks(mock)
+
+	repo, err := NewUserRepository(db)
+	require.NoError(t, err)
+
+	mock.ExpectQuery(`username_skeleton\(username\) = username_skeleton\(\$2\)`).
+		WithArgs(domain.DefaultOrganizationID, "St0ck_Bot", "").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	confusable, err := repo.HasConfusableUsername(context.Background(), "St0ck_Bot", "")
+	require.NoError(t, err)
+	assert.True(t, confusable)
+
+	mock.ExpectQuery(`username_skeleton`).
+		WithArgs(domain.DefaultOrganizationID, "alice", "user-1").
+		WillReturnError(errors.New("database error"))
+	_, err = repo.HasConfusableUsername(context.Background(), "alice", "user-1")
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func setupUserRepositoryMocks(mock sqlmock.Sqlmock) {
 	mock.ExpectPrepare(regexp.QuoteMeta(`
-		INSERT INTO users (username, email, password_hash)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		INSERT INTO users (org_id, username, email, password_hash, is_system)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, role, created_at
 	`)).WillReturnCloseError(nil)
 
 	mock.ExpectPrepare(regexp.QuoteMeta(`
-		SELECT id, username, email, password_hash, created_at
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
 		FROM users
-		WHERE id = $1
+		WHERE id = $1 AND org_id = $2
 	`)).WillReturnCloseError(nil)
 
 	mock.ExpectPrepare(regexp.QuoteMeta(`
-		SELECT id, username, email, password_hash, created_at
+		SELECT id, username, email, password_hash, role, locale, deactivated_at, is_system, created_at
 		FROM users
-		WHERE username = $1
+		WHERE username = $1 AND org_id = $2
 	`)).WillReturnCloseError(nil)
 }
+
+func TestUserRepository_UpdateRole(t *testing.T) {
+	t.Run("successful_update", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupUserRepositoryMocks(mock)
+
+		repo, err := NewUserRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET role = $1 WHERE id = $2 AND org_id = $3`)).
+			WithArgs("admin", "user-123", domain.DefaultOrganizationID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err = repo.UpdateRole(context.Background(), "user-123", "admin")
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("user_not_found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupUserRepositoryMocks(mock)
+
+		repo, err := NewUserRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET role = $1 WHERE id = $2 AND org_id = $3`)).
+			WithArgs("admin", "missing", domain.DefaultOrganizationID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = repo.UpdateRole(context.Background(), "missing", "admin")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+
+	t.Run("rejects_unknown_role", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupUserRepositoryMocks(mock)
+
+		repo, err := NewUserRepository(db)
+		require.NoError(t, err)
+
+		err = repo.UpdateRole(context.Background(), "user-123", "superuser")
+		assert.ErrorIs(t, err, domain.ErrInvalidInput)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserRepository_SetDeactivated(t *testing.T) {
+	t.Run("deactivates_user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupUserRepositoryMocks(mock)
+
+		repo, err := NewUserRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET deactivated_at = COALESCE(deactivated_at, CURRENT_TIMESTAMP) WHERE id = $1 AND org_id = $2`)).
+			WithArgs("user-123", domain.DefaultOrganizationID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err = repo.SetDeactivated(context.Background(), "user-123", true)
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("reactivates_user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupUserRepositoryMocks(mock)
+
+		repo, err := NewUserRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET deactivated_at = NULL WHERE id = $1 AND org_id = $2`)).
+			WithArgs("user-123", domain.DefaultOrganizationID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err = repo.SetDeactivated(context.Background(), "user-123", false)
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("user_not_found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupUserRepositoryMocks(mock)
+
+		repo, err := NewUserRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET deactivated_at = NULL WHERE id = $1 AND org_id = $2`)).
+			WithArgs("missing", domain.DefaultOrganizationID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = repo.SetDeactivated(context.Background(), "missing", false)
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+}
+
+func TestUserRepository_SetLocale(t *testing.T) {
+	t.Run("successful_update", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupUserRepositoryMocks(mock)
+
+		repo, err := NewUserRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET locale = $1 WHERE id = $2 AND org_id = $3`)).
+			WithArgs("es", "user-123", domain.DefaultOrganizationID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err = repo.SetLocale(context.Background(), "user-123", "es")
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("user_not_found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupUserRepositoryMocks(mock)
+
+		repo, err := NewUserRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET locale = $1 WHERE id = $2 AND org_id = $3`)).
+			WithArgs("pt", "missing", domain.DefaultOrganizationID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = repo.SetLocale(context.Background(), "missing", "pt")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+}
+
+func TestUserRepository_Status(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupUserRepositoryMocks(mock)
+
+	repo, err := NewUserRepository(db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET status = $1, status_text = $2 WHERE id = $3 AND org_id = $4`)).
+		WithArgs(domain.StatusDND, "Focusing", "user-123", domain.DefaultOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.SetStatus(ctx, "user-123", &domain.UserStatus{Status: domain.StatusDND, Text: "Focusing"}))
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET status = $1, status_text = $2 WHERE id = $3 AND org_id = $4`)).
+		WithArgs(domain.StatusActive, "", "missing", domain.DefaultOrganizationID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.SetStatus(ctx, "missing", &domain.UserStatus{Status: domain.StatusActive}), domain.ErrUserNotFound)
+
+	query := regexp.QuoteMeta(`
+		SELECT status, status_text
+		FROM users
+		WHERE id = $1 AND org_id = $2 AND deactivated_at IS NULL
+	`)
+	mock.ExpectQuery(query).
+		WithArgs("user-123", domain.DefaultOrganizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "status_text"}).AddRow(domain.StatusDND, "Focusing"))
+	status, err := repo.GetStatus(ctx, "user-123")
+	require.NoError(t, err)
+	assert.Equal(t, &domain.UserStatus{Status: domain.StatusDND, Text: "Focusing"}, status)
+
+	mock.ExpectQuery(query).
+		WithArgs("missing", domain.DefaultOrganizationID).
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.GetStatus(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_GetProfile(t *testing.T) {
+	query := regexp.QuoteMeta(`
+		SELECT id, username, avatar_url, profile_visibility, show_online_status, profile_version, created_at
+		FROM users
+		WHERE id = $1 AND org_id = $2 AND deactivated_at IS NULL
+	`)
+
+	t.Run("profile_found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupUserRepositoryMocks(mock)
+
+		repo, err := NewUserRepository(db)
+		require.NoError(t, err)
+
+		createdAt := time.Now()
+		mock.ExpectQuery(query).
+			WithArgs("user-123", domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "avatar_url", "profile_visibility", "show_online_status", "profile_version", "created_at"}).
+				AddRow("user-123", "alice", "https://example.com/a.png", domain.ProfilePrivate, false, 3, createdAt))
+
+		profile, err := repo.GetProfile(context.Background(), "user-123")
+		require.NoError(t, err)
+		assert.Equal(t, &domain.Profile{
+			ID:         "user-123",
+			Username:   "alice",
+			AvatarURL:  "https://example.com/a.png",
+			Visibility: domain.ProfilePrivate,
+			Version:    3,
+			CreatedAt:  createdAt,
+		}, profile)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("user_not_found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupUserRepositoryMocks(mock)
+
+		repo, err := NewUserRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(query).
+			WithArgs("missing", domain.DefaultOrganizationID).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err = repo.GetProfile(context.Background(), "missing")
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+}
+
+func TestUserRepository_UpdateProfileSettings(t *testing.T) {
+	query := regexp.QuoteMeta(`
+		UPDATE users
+		SET avatar_url = $1, profile_visibility = $2, show_online_status = $3, profile_version = profile_version + 1
+		WHERE id = $4 AND org_id = $5 AND ($6 = 0 OR profile_version = $6)
+		RETURNING profile_version
+	`)
+	existsQuery := regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND org_id = $2)`)
+
+	t.Run("successful_update", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupUserRepositoryMocks(mock)
+
+		repo, err := NewUserRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(query).
+			WithArgs("", domain.ProfilePublic, true, "user-123", domain.DefaultOrganizationID, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"profile_version"}).AddRow(3))
+
+		settings := &domain.ProfileSettings{Visibility: domain.ProfilePublic, ShowOnlineStatus: true, Version: 2}
+		err = repo.UpdateProfileSettings(context.Background(), "user-123", settings)
+		require.NoError(t, err)
+		assert.Equal(t, 3, settings.Version)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("version_conflict", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupUserRepositoryMocks(mock)
+
+		repo, err := NewUserRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(query).
+			WithArgs("", domain.ProfilePublic, true, "user-123", domain.DefaultOrganizationID, 1).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(existsQuery).
+			WithArgs("user-123", domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		settings := &domain.ProfileSettings{Visibility: domain.ProfilePublic, ShowOnlineStatus: true, Version: 1}
+		err = repo.UpdateProfileSettings(context.Background(), "user-123", settings)
+		assert.ErrorIs(t, err, domain.ErrVersionConflict)
+		assert.Equal(t, 1, settings.Version)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("user_not_found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupUserRepositoryMocks(mock)
+
+		repo, err := NewUserRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(query).
+			WithArgs("", domain.ProfilePublic, true, "missing", domain.DefaultOrganizationID, 0).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(existsQuery).
+			WithArgs("missing", domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		err = repo.UpdateProfileSettings(context.Background(), "missing", &domain.ProfileSettings{Visibility: domain.ProfilePublic, ShowOnlineStatus: true})
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+}
+
+func TestUserRepository_ConvertGuest(t *testing.T) {
+	query := regexp.QuoteMeta(`
+		UPDATE users
+		SET username = $1, email = $2, password_hash = $3, role = 'user'
+		WHERE id = $4 AND org_id = $5 AND role = 'guest'
+		RETURNING role, created_at
+	`)
+
+	t.Run("converts_guest", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupUserRepositoryMocks(mock)
+
+		repo, err := NewUserRepository(db)
+		require.NoError(t, err)
+
+		createdAt := time.Now()
+		mock.ExpectQuery(query).
+			WithArgs("alice", "alice@example.com", "hash", "guest-1", domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"role", "created_at"}).AddRow(domain.RoleUser, createdAt))
+
+		user := &domain.User{ID: "guest-1", Username: "alice", Email: "alice@example.com", PasswordHash: "hash"}
+		require.NoError(t, repo.ConvertGuest(context.Background(), user))
+		assert.Equal(t, domain.RoleUser, user.Role)
+		assert.Equal(t, domain.DefaultOrganizationID, user.OrgID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not_a_guest", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupUserRepositoryMocks(mock)
+
+		repo, err := NewUserRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(query).
+			WithArgs("alice", "alice@example.com", "hash", "user-1", domain.DefaultOrganizationID).
+			WillReturnError(sql.ErrNoRows)
+
+		user := &domain.User{ID: "user-1", Username: "alice", Email: "alice@example.com", PasswordHash: "hash"}
+		assert.ErrorIs(t, repo.ConvertGuest(context.Background(), user), domain.ErrUserNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("username_taken", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupUserRepositoryMocks(mock)
+
+		repo, err := NewUserRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(query).
+			WithArgs("alice", "alice@example.com", "hash", "guest-1", domain.DefaultOrganizationID).
+			WillReturnError(&pq.Error{Code: "23505", Constraint: "users_org_username_key"})
+
+		user := &domain.User{ID: "guest-1", Username: "alice", Email: "alice@example.com", PasswordHash: "hash"}
+		assert.ErrorIs(t, repo.ConvertGuest(context.Background(), user), domain.ErrUsernameExists)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserRepository_DeleteGuestsCreatedBefore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupUserRepositoryMocks(mock)
+
+	repo, err := NewUserRepository(db)
+	require.NoError(t, err)
+
+	cutoff := time.Now().Add(-24 * time.Hour)
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM users WHERE role = 'guest' AND created_at < $1`)).
+		WithArgs(cutoff).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	count, err := repo.DeleteGuestsCreatedBefore(context.Background(), cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"jobsity-chat/internal/domain"
+)
+
+type UsernameHistoryRepository struct {
+	db                *sql.DB
+	tm                *TxManager
+	historyStmt       *sql.Stmt
+	isHeldStmt        *sql.Stmt
+	resolveFormerStmt *sql.Stmt
+}
+
+// NewUsernameHistoryRepository creates a new UsernameHistoryRepository with
+// prepared statements. Returns an error if statement preparation fails.
+func NewUsernameHistoryRepository(db *sql.DB) (*UsernameHistoryRepository, error) {
+	repo := &UsernameHistoryRepository{db: db, tm: NewTxManager(db)}
+
+	var err error
+	repo.historyStmt, err = db.Prepare(`
+		SELECT old_username, new_username, changed_at FROM username_history
+		WHERE user_id = $1 AND org_id = $2
+		ORDER BY changed_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare history statement: %w", err)
+	}
+
+	repo.isHeldStmt, err = db.Prepare(`
+		SELECT EXISTS (
+			SELECT 1 FROM username_history
+			WHERE org_id = $1 AND lower(old_username) = lower($2) AND changed_at >= $3
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare isHeld statement: %w", err)
+	}
+
+	repo.resolveFormerStmt, err = db.Prepare(`
+		SELECT h.user_id FROM username_history h
+		WHERE h.org_id = $1 AND lower(h.old_username) = lower($2)
+		  AND NOT EXISTS (SELECT 1 FROM users u WHERE u.org_id = $1 AND lower(u.username) = lower($2))
+		ORDER BY h.changed_at DESC
+		LIMIT 1
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare resolveFormer statement: %w", err)
+	}
+
+	return repo, nil
+}
+
+func (r *UsernameHistoryRepository) Rename(ctx context.Context, userID, username string, heldSince time.Time) (*domain.UsernameChange, error) {
+	orgID := domain.OrgIDFromContext(ctx)
+	change := &domain.UsernameChange{NewUsername: username}
+
+	err := r.tm.WithTx(ctx, func(tx *sql.Tx) error {
+		// Locking the user serializes their concurrent renames
+		err := tx.QueryRowContext(ctx, `
+			SELECT username FROM users
+			WHERE id = $1 AND org_id = $2 AND role <> 'guest'
+			FOR UPDATE
+		`, userID, orgID).Scan(&change.OldUsername)
+		if err == sql.ErrNoRows {
+			return domain.ErrUserNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get username: %w", err)
+		}
+
+		var held bool
+		err = tx.QueryRowContext(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM username_history
+				WHERE org_id = $1 AND lower(old_username) = lower($2) AND user_id <> $3 AND changed_at >= $4
+			)
+		`, orgID, username, userID, heldSince.UTC()).Scan(&held)
+		if err != nil {
+			return fmt.Errorf("failed to check former usernames: %w", err)
+		}
+		if held {
+			return domain.ErrUsernameExists
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET username = $1 WHERE id = $2`, username, userID); err != nil {
+			if IsUniqueViolation(err, "users_org_username_key") {
+				return domain.ErrUsernameExists
+			}
+			return fmt.Errorf("failed to rename user: %w", err)
+		}
+
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO username_history (user_id, org_id, old_username, new_username)
+			VALUES ($1, $2, $3, $4)
+			RETURNING changed_at
+		`, userID, orgID, change.OldUsername, username).Scan(&change.ChangedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record username change: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func (r *UsernameHistoryRepository) History(ctx context.Context, userID string) ([]*domain.UsernameChange, error) {
+	rows, err := stmt(ctx, r.historyStmt).QueryContext(ctx, userID, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query username history: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []*domain.UsernameChange{}
+	for rows.Next() {
+		change := &domain.UsernameChange{}
+		if err := rows.Scan(&change.OldUsername, &change.NewUsername, &change.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan username change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating username history: %w", err)
+	}
+	return changes, nil
+}
+
+func (r *UsernameHistoryRepository) IsHeld(ctx context.Context, username string, since time.Time) (bool, error) {
+	var held bool
+	err := stmt(ctx, r.isHeldStmt).QueryRowContext(ctx, domain.OrgIDFromContext(ctx), username, since.UTC()).Scan(&held)
+	if err != nil {
+		return false, fmt.Errorf("failed to check former usernames: %w", err)
+	}
+	return held, nil
+}
+
+func (r *UsernameHistoryRepository) ResolveFormer(ctx context.Context, username string) (string, error) {
+	var userID string
+	err := stmt(ctx, r.resolveFormerStmt).QueryRowContext(ctx, domain.OrgIDFromContext(ctx), username).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", domain.ErrUserNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve former username: %w", err)
+	}
+	return userID, nil
+}
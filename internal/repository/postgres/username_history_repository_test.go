@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestUsernameHistoryRepository(t *testing.T) (*UsernameHistoryRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mock.ExpectPrepare(`SELECT old_username, new_username, changed_at FROM username_history`)
+	mock.ExpectPrepare(`SELECT EXISTS`)
+	mock.ExpectPrepare(`SELECT h.user_id FROM username_history h`)
+	repo, err := NewUsernameHistoryRepository(db)
+	require.NoError(t, err)
+	return repo, mock
+}
+
+func TestUsernameHistoryRepository_Rename(t *testing.T) {
+	ctx := context.Background()
+	heldSince := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("successful_rename", func(t *testing.T) {
+		repo, mock := newTestUsernameHistoryRepository(t)
+		changedAt := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT username FROM users`).
+			WithArgs("user-1", domain.DefaultOrganizationID).
+			WillReturnRows(sqlmock.NewRows([]string{"username"}).AddRow("alice"))
+		mock.ExpectQuery(`SELECT EXISTS`).
+			WithArgs(domain.DefaultOrganizationID, "alicia", "user-1", heldSince).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(`UPDATE users SET username = \$1 WHERE id = \$2`).
+			WithArgs("alicia", "user-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO username_history`).
+			WithArgs("user-1", domain.DefaultOrganizationID, "alice", "alicia").
+			WillReturnRows(sqlmock.NewRows([]string{"changed_at"}).AddRow(changedAt))
+		mock.ExpectCommit()
+
+		change, err := repo.Rename(ctx, "user-1", "alicia", heldSince)
+		require.NoError(t, err)
+		assert.Equal(t, &domain.UsernameChange{OldUsername: "alice", NewUsername: "alicia", ChangedAt: changedAt}, change)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("held_by_former_owner", func(t *testing.T) {
+		repo, mock := newTestUsernameHistoryRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT username FROM users`).
+			WillReturnRows(sqlmock.NewRows([]string{"username"}).AddRow("alice"))
+		mock.ExpectQuery(`SELECT EXISTS`).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		_, err := repo.Rename(ctx, "user-1", "bob", heldSince)
+		assert.ErrorIs(t, err, domain.ErrUsernameExists)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("taken", func(t *testing.T) {
+		repo, mock := newTestUsernameHistoryRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT username FROM users`).
+			WillReturnRows(sqlmock.NewRows([]string{"username"}).AddRow("alice"))
+		mock.ExpectQuery(`SELECT EXISTS`).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(`UPDATE users SET username`).
+			WillReturnError(&pq.Error{Code: "23505", Constraint: "users_org_username_key"})
+		mock.ExpectRollback()
+
+		_, err := repo.Rename(ctx, "user-1", "bob", heldSince)
+		assert.ErrorIs(t, err, domain.ErrUsernameExists)
+	})
+
+	t.Run("user_not_found", func(t *testing.T) {
+		repo, mock := newTestUsernameHistoryRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT username FROM users`).WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := repo.Rename(ctx, "guest-1", "bob", heldSince)
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+}
+
+func TestUsernameHistoryRepository_History(t *testing.T) {
+	repo, mock := newTestUsernameHistoryRepository(t)
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT old_username, new_username, changed_at FROM username_history`).
+		WithArgs("user-1", "org-2").
+		WillReturnRows(sqlmock.NewRows([]string{"old_username", "new_username", "changed_at"}).
+			AddRow("alicia", "alice_b", now).
+			AddRow("alice", "alicia", now.Add(-time.Hour)))
+
+	changes, err := repo.History(domain.WithOrgID(context.Background(), "org-2"), "user-1")
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "alice_b", changes[0].NewUsername)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsernameHistoryRepository_IsHeld(t *testing.T) {
+	repo, mock := newTestUsernameHistoryRepository(t)
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT EXISTS`).
+		WithArgs(domain.DefaultOrganizationID, "Alice", since).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	held, err := repo.IsHeld(context.Background(), "Alice", since)
+	require.NoError(t, err)
+	assert.True(t, held)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsernameHistoryRepository_ResolveFormer(t *testing.T) {
+	repo, mock := newTestUsernameHistoryRepository(t)
+	ctx := context.Background()
+
+	mock.ExpectQuery(`SELECT h.user_id FROM username_history h`).
+		WithArgs(domain.DefaultOrganizationID, "alice").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("user-1"))
+	userID, err := repo.ResolveFormer(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+
+	mock.ExpectQuery(`SELECT h.user_id FROM username_history h`).
+		WithArgs(domain.DefaultOrganizationID, "nobody").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	_, err = repo.ResolveFormer(ctx, "nobody")
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"jobsity-chat/internal/domain"
+)
+
+type WSTicketRepository struct {
+	db                *sql.DB
+	createStmt        *sql.Stmt
+	consumeStmt       *sql.Stmt
+	deleteExpiredStmt *sql.Stmt
+}
+
+// NewWSTicketRepository creates a new WSTicketRepository with prepared statements.
+// Returns an error if statement preparation fails.
+func NewWSTicketRepository(db *sql.DB) (*WSTicketRepository, error) {
+	repo := &WSTicketRepository{db: db}
+
+	var err error
+	repo.createStmt, err = db.Prepare(`
+		INSERT INTO ws_tickets (ticket, session_token, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare create statement: %w", err)
+	}
+
+	repo.consumeStmt, err = db.Prepare(`
+		DELETE FROM ws_tickets
+		WHERE ticket = $1 AND expires_at > $2
+		RETURNING ticket, session_token, expires_at, created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare consume statement: %w", err)
+	}
+
+	repo.deleteExpiredStmt, err = db.Prepare(`DELETE FROM ws_tickets WHERE expires_at <= $1`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare deleteExpired statement: %w", err)
+	}
+
+	return repo, nil
+}
+
+func (r *WSTicketRepository) Create(ctx context.Context, ticket *domain.WSTicket) error {
+	err := stmt(ctx, r.createStmt).QueryRowContext(ctx,
+		ticket.Ticket,
+		ticket.SessionToken,
+		ticket.ExpiresAt,
+	).Scan(&ticket.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create ws ticket: %w", err)
+	}
+	return nil
+}
+
+func (r *WSTicketRepository) Consume(ctx context.Context, ticket string) (*domain.WSTicket, error) {
+	t := &domain.WSTicket{}
+	err := stmt(ctx, r.consumeStmt).QueryRowContext(ctx, ticket, time.Now()).Scan(
+		&t.Ticket,
+		&t.SessionToken,
+		&t.ExpiresAt,
+		&t.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrTicketNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume ws ticket: %w", err)
+	}
+	return t, nil
+}
+
+func (r *WSTicketRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := stmt(ctx, r.deleteExpiredStmt).ExecContext(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired ws tickets: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return count, nil
+}
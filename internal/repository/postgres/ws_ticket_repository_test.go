@@ -0,0 +1,197 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	wsTicketCreateQuery = `
+		INSERT INTO ws_tickets (ticket, session_token, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING created_at
+	`
+	wsTicketConsumeQuery = `
+		DELETE FROM ws_tickets
+		WHERE ticket = $1 AND expires_at > $2
+		RETURNING ticket, session_token, expires_at, created_at
+	`
+	wsTicketDeleteExpiredQuery = `DELETE FROM ws_tickets WHERE expires_at <= $1`
+)
+
+func TestNewWSTicketRepository(t *testing.T) {
+	t.Run("successful_creation", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupWSTicketRepositoryMocks(mock)
+
+		repo, err := NewWSTicketRepository(db)
+		require.NoError(t, err)
+		assert.NotNil(t, repo)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fails_when_prepare_consume_fails", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(wsTicketCreateQuery))
+		mock.ExpectPrepare(regexp.QuoteMeta(wsTicketConsumeQuery)).
+			WillReturnError(errors.New("prepare failed"))
+
+		repo, err := NewWSTicketRepository(db)
+		require.Error(t, err)
+		assert.Nil(t, repo)
+		assert.Contains(t, err.Error(), "failed to prepare consume statement")
+	})
+}
+
+func TestWSTicketRepository_Create(t *testing.T) {
+	t.Run("successful_creation", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupWSTicketRepositoryMocks(mock)
+
+		repo, err := NewWSTicketRepository(db)
+		require.NoError(t, err)
+
+		createdAt := time.Now()
+		expiresAt := createdAt.Add(30 * time.Second)
+
+		mock.ExpectQuery(regexp.QuoteMeta(wsTicketCreateQuery)).
+			WithArgs("ticket-1", "session-token", expiresAt).
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(createdAt))
+
+		ticket := &domain.WSTicket{
+			Ticket:       "ticket-1",
+			SessionToken: "session-token",
+			ExpiresAt:    expiresAt,
+		}
+
+		err = repo.Create(context.Background(), ticket)
+		require.NoError(t, err)
+		assert.Equal(t, createdAt, ticket.CreatedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database_error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupWSTicketRepositoryMocks(mock)
+
+		repo, err := NewWSTicketRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(wsTicketCreateQuery)).
+			WillReturnError(errors.New("foreign key violation"))
+
+		err = repo.Create(context.Background(), &domain.WSTicket{Ticket: "ticket-1"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to create ws ticket")
+	})
+}
+
+func TestWSTicketRepository_Consume(t *testing.T) {
+	t.Run("returns_and_deletes_ticket", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupWSTicketRepositoryMocks(mock)
+
+		repo, err := NewWSTicketRepository(db)
+		require.NoError(t, err)
+
+		now := time.Now()
+		mock.ExpectQuery(regexp.QuoteMeta(wsTicketConsumeQuery)).
+			WithArgs("ticket-1", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"ticket", "session_token", "expires_at", "created_at"}).
+				AddRow("ticket-1", "session-token", now.Add(30*time.Second), now))
+
+		ticket, err := repo.Consume(context.Background(), "ticket-1")
+		require.NoError(t, err)
+		assert.Equal(t, "ticket-1", ticket.Ticket)
+		assert.Equal(t, "session-token", ticket.SessionToken)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown_or_used_ticket", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupWSTicketRepositoryMocks(mock)
+
+		repo, err := NewWSTicketRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(wsTicketConsumeQuery)).
+			WithArgs("ticket-1", sqlmock.AnyArg()).
+			WillReturnError(sql.ErrNoRows)
+
+		ticket, err := repo.Consume(context.Background(), "ticket-1")
+		assert.Nil(t, ticket)
+		assert.Equal(t, domain.ErrTicketNotFound, err)
+	})
+
+	t.Run("database_error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		setupWSTicketRepositoryMocks(mock)
+
+		repo, err := NewWSTicketRepository(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(wsTicketConsumeQuery)).
+			WillReturnError(errors.New("connection lost"))
+
+		_, err = repo.Consume(context.Background(), "ticket-1")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to consume ws ticket")
+	})
+}
+
+func TestWSTicketRepository_DeleteExpired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	setupWSTicketRepositoryMocks(mock)
+
+	repo, err := NewWSTicketRepository(db)
+	require.NoError(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta(wsTicketDeleteExpiredQuery)).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	count, err := repo.DeleteExpired(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func setupWSTicketRepositoryMocks(mock sqlmock.Sqlmock) {
+	mock.ExpectPrepare(regexp.QuoteMeta(wsTicketCreateQuery))
+	mock.ExpectPrepare(regexp.QuoteMeta(wsTicketConsumeQuery))
+	mock.ExpectPrepare(regexp.QuoteMeta(wsTicketDeleteExpiredQuery))
+}
@@ -2,13 +2,15 @@ package service
 
 import (
 	"context"
+	"errors"
 	"regexp"
+	"strings"
 	"time"
 
 	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/i18n"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 var (
@@ -16,95 +18,397 @@ var (
 	emailRegex    = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
 )
 
+// SessionPolicy controls how long sessions live. Remember-me sessions use
+// their own, longer timeouts.
+type SessionPolicy struct {
+	// IdleTimeout expires a session after this long without activity
+	IdleTimeout time.Duration
+	// AbsoluteTimeout caps a session's lifetime regardless of activity
+	AbsoluteTimeout time.Duration
+
+	RememberIdleTimeout     time.Duration
+	RememberAbsoluteTimeout time.Duration
+}
+
+// DefaultSessionPolicy returns a 2h idle timeout within a 24h absolute
+// lifetime, and 7 days within 30 days for remember-me sessions
+func DefaultSessionPolicy() SessionPolicy {
+	return SessionPolicy{
+		IdleTimeout:             2 * time.Hour,
+		AbsoluteTimeout:         24 * time.Hour,
+		RememberIdleTimeout:     7 * 24 * time.Hour,
+		RememberAbsoluteTimeout: 30 * 24 * time.Hour,
+	}
+}
+
+// timeouts returns the idle and absolute timeouts for a session type
+func (p SessionPolicy) timeouts(remember bool) (idle, absolute time.Duration) {
+	if remember {
+		return p.RememberIdleTimeout, p.RememberAbsoluteTimeout
+	}
+	return p.IdleTimeout, p.AbsoluteTimeout
+}
+
+// normalized fills unset timeouts from the defaults and caps idle timeouts
+// at their absolute counterpart
+func (p SessionPolicy) normalized() SessionPolicy {
+	defaults := DefaultSessionPolicy()
+	if p.AbsoluteTimeout <= 0 {
+		p.AbsoluteTimeout = defaults.AbsoluteTimeout
+	}
+	if p.IdleTimeout <= 0 || p.IdleTimeout > p.AbsoluteTimeout {
+		p.IdleTimeout = p.AbsoluteTimeout
+	}
+	if p.RememberAbsoluteTimeout <= 0 {
+		p.RememberAbsoluteTimeout = defaults.RememberAbsoluteTimeout
+	}
+	if p.RememberIdleTimeout <= 0 || p.RememberIdleTimeout > p.RememberAbsoluteTimeout {
+		p.RememberIdleTimeout = p.RememberAbsoluteTimeout
+	}
+	return p
+}
+
+// LoginOptions tunes the session created by LoginWithOptions
+type LoginOptions struct {
+	// RememberMe issues a long-lived session that survives browser restarts
+	RememberMe bool
+}
+
 type AuthService struct {
-	userRepo    domain.UserRepository
-	sessionRepo domain.SessionRepository
+	userRepo      domain.UserRepository
+	sessionRepo   domain.SessionRepository
+	sessionPolicy SessionPolicy
+	// guestSessionTTL is how long guests last; zero disables guest access
+	guestSessionTTL time.Duration
+	// events is nil until SetEventPublisher is called
+	events         EventPublisher
+	hasher         PasswordHasher
+	passwordPolicy PasswordPolicy
+	// breaches is nil unless new passwords are checked against known breaches
+	breaches BreachChecker
+	// usernames is nil until SetUsernameHistory is called
+	usernames    domain.UsernameHistoryRepository
+	usernameHold time.Duration
+	// reserved is nil until SetReservedNames is called
+	reserved *ReservedNames
 }
 
 func NewAuthService(userRepo domain.UserRepository, sessionRepo domain.SessionRepository) *AuthService {
+	return NewAuthServiceWithPolicy(userRepo, sessionRepo, DefaultSessionPolicy())
+}
+
+func NewAuthServiceWithPolicy(userRepo domain.UserRepository, sessionRepo domain.SessionRepository, policy SessionPolicy) *AuthService {
 	return &AuthService{
-		userRepo:    userRepo,
-		sessionRepo: sessionRepo,
+		userRepo:       userRepo,
+		sessionRepo:    sessionRepo,
+		sessionPolicy:  policy.normalized(),
+		hasher:         NewHashingPool(HashingPoolConfig{}),
+		passwordPolicy: PasswordPolicy{}.normalized(),
 	}
 }
 
-func (s *AuthService) Register(ctx context.Context, username, email, password string) (*domain.User, error) {
-	if len(username) < 3 || len(username) > 50 {
-		return nil, domain.ErrInvalidInput
+// SetPasswordHasher replaces the default hashing pool passwords are hashed
+// and checked with
+func (s *AuthService) SetPasswordHasher(hasher PasswordHasher) {
+	s.hasher = hasher
+}
+
+// SetPasswordPolicy sets the rules new passwords must follow
+func (s *AuthService) SetPasswordPolicy(policy PasswordPolicy) {
+	s.passwordPolicy = policy.normalized()
+}
+
+// SetBreachChecker rejects new passwords that appear in known breaches
+func (s *AuthService) SetBreachChecker(breaches BreachChecker) {
+	s.breaches = breaches
+}
+
+// SetUsernameHistory keeps usernames given up within hold from being
+// registered by someone else
+func (s *AuthService) SetUsernameHistory(usernames domain.UsernameHistoryRepository, hold time.Duration) {
+	s.usernames = usernames
+	s.usernameHold = hold
+}
+
+// SetReservedNames keeps new accounts from taking reserved usernames
+func (s *AuthService) SetReservedNames(reserved *ReservedNames) {
+	s.reserved = reserved
+}
+
+// SetEventPublisher publishes UserRegistered events to events from now on
+func (s *AuthService) SetEventPublisher(events EventPublisher) {
+	s.events = events
+}
+
+// SessionPolicy returns the policy applied to new sessions
+func (s *AuthService) SessionPolicy() SessionPolicy {
+	return s.sessionPolicy
+}
+
+// SetGuestSessionTTL enables guest access: CreateGuest issues guests whose
+// session and account last ttl. Zero disables it.
+func (s *AuthService) SetGuestSessionTTL(ttl time.Duration) {
+	s.guestSessionTTL = ttl
+}
+
+// GuestSessionTTL returns how long guests last, or zero when guest access is
+// disabled
+func (s *AuthService) GuestSessionTTL() time.Duration {
+	return s.guestSessionTTL
+}
+
+func (s *AuthService) Register(ctx context.Context, username, email, password string) (_ *domain.User, err error) {
+	defer observe("auth", "Register")(&err)
+
+	user, err := s.newAccount(ctx, username, email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
 	}
-	if !usernameRegex.MatchString(username) {
-		return nil, domain.ErrInvalidInput
+	publish(ctx, s.events, domain.UserRegistered{User: user})
+
+	return user, nil
+}
+
+// newAccount validates the credentials of a new account and returns its
+// user with the username normalized and the password hashed. Invalid
+// credentials return a domain.ValidationError listing every invalid field.
+func (s *AuthService) newAccount(ctx context.Context, username, email, password string) (*domain.User, error) {
+	username = NormalizeUsername(username)
+	var fields []domain.FieldError
+	if field := checkUsername(username, s.reserved); field != nil {
+		fields = append(fields, *field)
 	}
 	if !emailRegex.MatchString(email) || len(email) > 255 {
-		return nil, domain.ErrInvalidInput
+		fields = append(fields, domain.FieldError{Field: "email", Code: domain.FieldInvalid, Message: "must be a valid email address"})
 	}
-	if len(password) < 8 || len(password) > 100 {
-		return nil, domain.ErrInvalidInput
+	fields = append(fields, s.passwordPolicy.check(username, password)...)
+	if len(fields) > 0 {
+		return nil, &domain.ValidationError{Fields: fields}
 	}
 
 	if _, err := s.userRepo.GetByUsername(ctx, username); err == nil {
 		return nil, domain.ErrUsernameExists
 	}
+	field, err := checkConfusable(ctx, s.userRepo, username, "")
+	if err != nil {
+		return nil, err
+	}
+	if field != nil {
+		return nil, &domain.ValidationError{Fields: []domain.FieldError{*field}}
+	}
+
+	if s.usernames != nil {
+		held, err := s.usernames.IsHeld(ctx, username, time.Now().Add(-s.usernameHold))
+		if err != nil {
+			return nil, err
+		}
+		if held {
+			return nil, domain.ErrUsernameExists
+		}
+	}
 
 	if _, err := s.userRepo.GetByEmail(ctx, email); err == nil {
 		return nil, domain.ErrEmailExists
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	if s.breaches != nil {
+		if field := checkBreached(ctx, s.breaches, password); field != nil {
+			return nil, &domain.ValidationError{Fields: []domain.FieldError{*field}}
+		}
+	}
+
+	hashedPassword, err := s.hasher.Hash(ctx, password)
 	if err != nil {
 		return nil, err
 	}
 
-	user := &domain.User{
+	return &domain.User{
 		Username:     username,
 		Email:        email,
-		PasswordHash: string(hashedPassword),
+		PasswordHash: hashedPassword,
+	}, nil
+}
+
+// checkUsername returns why username cannot be taken, if it can't
+func checkUsername(username string, reserved *ReservedNames) *domain.FieldError {
+	switch {
+	case len(username) < 3:
+		return &domain.FieldError{Field: "username", Code: domain.FieldTooShort, Message: "must be at least 3 characters"}
+	case len(username) > 50:
+		return &domain.FieldError{Field: "username", Code: domain.FieldTooLong, Message: "must be at most 50 characters"}
+	case !usernameRegex.MatchString(username):
+		return &domain.FieldError{Field: "username", Code: domain.FieldInvalid, Message: "must contain only letters, digits and underscores"}
+	case reserved.Contains(username):
+		return &domain.FieldError{Field: "username", Code: domain.FieldReserved, Message: "is reserved"}
 	}
+	return nil
+}
 
-	if err := s.userRepo.Create(ctx, user); err != nil {
+// checkConfusable returns why username cannot be taken when another user
+// than exceptUserID has a username it can be mistaken for
+func checkConfusable(ctx context.Context, users domain.UserRepository, username, exceptUserID string) (*domain.FieldError, error) {
+	confusable, err := users.HasConfusableUsername(ctx, username, exceptUserID)
+	if err != nil || !confusable {
+		return nil, err
+	}
+	return &domain.FieldError{Field: "username", Code: domain.FieldConfusable, Message: "is too similar to an existing username"}, nil
+}
+
+// guestEmailDomain is reserved (RFC 2606), so guest addresses never collide
+// with a real user's
+const guestEmailDomain = "guest.invalid"
+
+// CreateGuest creates an ephemeral guest and its session, both lasting the
+// guest session TTL. Guests have no password and cannot log in again once
+// the session ends. Returns domain.ErrGuestsDisabled unless guest access is
+// enabled.
+func (s *AuthService) CreateGuest(ctx context.Context) (_ *domain.Session, _ *domain.User, err error) {
+	defer observe("auth", "CreateGuest")(&err)
+
+	if s.guestSessionTTL <= 0 {
+		return nil, nil, domain.ErrGuestsDisabled
+	}
+
+	username := "guest_" + strings.ReplaceAll(uuid.New().String(), "-", "")[:12]
+	user := &domain.User{
+		Username: username,
+		Email:    username + "@" + guestEmailDomain,
+		// Not a bcrypt hash, so no password matches it
+		PasswordHash: "!",
+	}
+	if err := s.userRepo.CreateGuest(ctx, user); err != nil {
+		return nil, nil, err
+	}
+
+	idle := s.sessionPolicy.IdleTimeout
+	if idle > s.guestSessionTTL {
+		idle = s.guestSessionTTL
+	}
+	now := time.Now()
+	session := &domain.Session{
+		UserID:            user.ID,
+		Token:             domain.GuestTokenPrefix + uuid.New().String(),
+		AbsoluteExpiresAt: now.Add(s.guestSessionTTL),
+		IdleTimeout:       idle,
+		LastActivityAt:    now,
+	}
+	session.ExpiresAt = session.RenewedExpiry(now)
+
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, nil, err
+	}
+	return session, user, nil
+}
+
+// ConvertGuest turns the guest guestID into a full account with the given
+// credentials, keeping its memberships and messages. The guest's sessions
+// are ended; the user logs in with the new credentials.
+func (s *AuthService) ConvertGuest(ctx context.Context, guestID, username, email, password string) (_ *domain.User, err error) {
+	defer observe("auth", "ConvertGuest")(&err)
+
+	user, err := s.newAccount(ctx, username, email, password)
+	if err != nil {
+		return nil, err
+	}
+	user.ID = guestID
+
+	if err := s.userRepo.ConvertGuest(ctx, user); err != nil {
+		return nil, err
+	}
+	if _, err := s.sessionRepo.DeleteByUserID(ctx, guestID); err != nil {
 		return nil, err
 	}
+	publish(ctx, s.events, domain.UserRegistered{User: user})
 
 	return user, nil
 }
 
 func (s *AuthService) Login(ctx context.Context, username, password string) (*domain.Session, *domain.User, error) {
-	user, err := s.userRepo.GetByUsername(ctx, username)
-	if err != nil {
+	return s.LoginWithOptions(ctx, username, password, LoginOptions{})
+}
+
+// LoginWithOptions authenticates the user and creates a session whose
+// timeouts depend on opts
+func (s *AuthService) LoginWithOptions(ctx context.Context, username, password string, opts LoginOptions) (_ *domain.Session, _ *domain.User, err error) {
+	defer observe("auth", "LoginWithOptions")(&err)
+
+	user, err := s.userRepo.GetByUsername(ctx, NormalizeUsername(username))
+	if err != nil || user.IsSystem {
 		return nil, nil, domain.ErrInvalidCredentials
 	}
 
-	if err := bcrypt.CompareHashAndPassword(
-		[]byte(user.PasswordHash), []byte(password),
-	); err != nil {
+	if err := s.hasher.Compare(ctx, user.PasswordHash, password); err != nil {
+		if errors.Is(err, domain.ErrHashingBusy) || ctx.Err() != nil {
+			return nil, nil, err
+		}
 		return nil, nil, domain.ErrInvalidCredentials
 	}
 
-	session := &domain.Session{
-		UserID:    user.ID,
-		Token:     uuid.New().String(),
-		ExpiresAt: time.Now().Add(24 * time.Hour),
+	if !user.IsActive() {
+		return nil, nil, domain.ErrUserDeactivated
 	}
 
-	if err := s.sessionRepo.Create(ctx, session); err != nil {
+	session, err := s.CreateSession(ctx, user.ID, opts)
+	if err != nil {
 		return nil, nil, err
 	}
 
 	return session, user, nil
 }
 
-func (s *AuthService) Logout(ctx context.Context, token string) error {
+// CreateSession issues a new session for an already authenticated user
+func (s *AuthService) CreateSession(ctx context.Context, userID string, opts LoginOptions) (_ *domain.Session, err error) {
+	defer observe("auth", "CreateSession")(&err)
+
+	idle, absolute := s.sessionPolicy.timeouts(opts.RememberMe)
+	now := time.Now()
+	session := &domain.Session{
+		UserID:            userID,
+		Token:             uuid.New().String(),
+		AbsoluteExpiresAt: now.Add(absolute),
+		IdleTimeout:       idle,
+		LastActivityAt:    now,
+	}
+	session.ExpiresAt = session.RenewedExpiry(now)
+
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (s *AuthService) Logout(ctx context.Context, token string) (err error) {
+	defer observe("auth", "Logout")(&err)
 	return s.sessionRepo.Delete(ctx, token)
 }
 
-func (s *AuthService) ValidateSession(ctx context.Context, token string) (*domain.Session, error) {
+func (s *AuthService) ValidateSession(ctx context.Context, token string) (_ *domain.Session, err error) {
+	defer observe("auth", "ValidateSession")(&err)
 	return s.sessionRepo.GetByToken(ctx, token)
 }
 
-func (s *AuthService) GetUserByID(ctx context.Context, userID string) (*domain.User, error) {
+func (s *AuthService) GetUserByID(ctx context.Context, userID string) (_ *domain.User, err error) {
+	defer observe("auth", "GetUserByID")(&err)
 	return s.userRepo.GetByID(ctx, userID)
 }
 
-func (s *AuthService) GetUserByUsername(ctx context.Context, username string) (*domain.User, error) {
+func (s *AuthService) GetUserByUsername(ctx context.Context, username string) (_ *domain.User, err error) {
+	defer observe("auth", "GetUserByUsername")(&err)
 	return s.userRepo.GetByUsername(ctx, username)
 }
+
+// SetLocale stores the user's preferred locale for bot and system messages.
+// An empty locale clears the preference.
+func (s *AuthService) SetLocale(ctx context.Context, userID, locale string) (err error) {
+	defer observe("auth", "SetLocale")(&err)
+
+	if locale != "" && !i18n.IsSupported(locale) {
+		return domain.ErrInvalidInput
+	}
+	return s.userRepo.SetLocale(ctx, userID, locale)
+}
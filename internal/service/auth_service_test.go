@@ -41,6 +41,10 @@ func (m *mockUserRepository) GetByEmail(ctx context.Context, email string) (*dom
 	return nil, errors.New("user not found")
 }
 
+func (m *mockUserRepository) HasConfusableUsername(ctx context.Context, username, exceptUserID string) (bool, error) {
+	return false, nil
+}
+
 func (m *mockUserRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
 	if m.getByID != nil {
 		return m.getByID(ctx, id)
@@ -70,6 +74,61 @@ func (m *mockUserRepository) Create(ctx context.Context, user *domain.User) erro
 	return nil
 }
 
+func (m *mockUserRepository) UpdateRole(ctx context.Context, userID, role string) error {
+	return nil
+}
+
+func (m *mockUserRepository) SetDeactivated(ctx context.Context, userID string, deactivated bool) error {
+	return nil
+}
+
+func (m *mockUserRepository) SetLocale(ctx context.Context, userID, locale string) error {
+	for _, user := range m.users {
+		if user.ID == userID {
+			user.Locale = locale
+			return nil
+		}
+	}
+	return domain.ErrUserNotFound
+}
+
+func (m *mockUserRepository) GetProfile(ctx context.Context, id string) (*domain.Profile, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockUserRepository) UpdateProfileSettings(ctx context.Context, userID string, settings *domain.ProfileSettings) error {
+	return nil
+}
+
+func (m *mockUserRepository) GetStatus(ctx context.Context, userID string) (*domain.UserStatus, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockUserRepository) SetStatus(ctx context.Context, userID string, status *domain.UserStatus) error {
+	return nil
+}
+
+func (m *mockUserRepository) CreateGuest(ctx context.Context, user *domain.User) error {
+	user.Role = domain.RoleGuest
+	return m.Create(ctx, user)
+}
+
+func (m *mockUserRepository) ConvertGuest(ctx context.Context, user *domain.User) error {
+	for key, existing := range m.users {
+		if existing.ID == user.ID && existing.IsGuest() {
+			delete(m.users, key)
+			user.Role = domain.RoleUser
+			m.users[user.Username] = user
+			return nil
+		}
+	}
+	return domain.ErrUserNotFound
+}
+
+func (m *mockUserRepository) DeleteGuestsCreatedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
 type mockSessionRepository struct {
 	sessions map[string]*domain.Session
 	create   func(ctx context.Context, session *domain.Session) error
@@ -107,8 +166,12 @@ func (m *mockSessionRepository) Delete(ctx context.Context, token string) error
 	return nil
 }
 
-func (m *mockSessionRepository) DeleteByUserID(ctx context.Context, userID string) error {
-	return nil
+func (m *mockSessionRepository) DeleteByUserID(ctx context.Context, userID string) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockSessionRepository) Touch(ctx context.Context, activity []domain.SessionActivity) (int64, error) {
+	return 0, nil
 }
 
 func (m *mockSessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
@@ -339,11 +402,178 @@ func TestAuthService_Login_Success(t *testing.T) {
 		t.Error("Expected session to not be expired")
 	}
 
-	// Verify session is 24 hours in the future
-	expectedExpiry := time.Now().Add(24 * time.Hour)
+	// Verify session expires after the idle timeout, capped at 24 hours
+	policy := DefaultSessionPolicy()
+	expectedExpiry := time.Now().Add(policy.IdleTimeout)
 	diff := session.ExpiresAt.Sub(expectedExpiry).Abs()
 	if diff > time.Minute {
-		t.Errorf("Expected session to expire in ~24 hours, but difference is %v", diff)
+		t.Errorf("Expected session to expire after the idle timeout, but difference is %v", diff)
+	}
+
+	expectedAbsolute := time.Now().Add(24 * time.Hour)
+	diff = session.AbsoluteExpiresAt.Sub(expectedAbsolute).Abs()
+	if diff > time.Minute {
+		t.Errorf("Expected absolute expiry in ~24 hours, but difference is %v", diff)
+	}
+
+	if session.IdleTimeout != policy.IdleTimeout {
+		t.Errorf("Expected idle timeout %v, got %v", policy.IdleTimeout, session.IdleTimeout)
+	}
+}
+
+func TestAuthService_LoginWithOptions_RememberMe(t *testing.T) {
+	userRepo := &mockUserRepository{
+		users: make(map[string]*domain.User),
+	}
+	sessionRepo := &mockSessionRepository{
+		sessions: make(map[string]*domain.Session),
+	}
+	policy := SessionPolicy{
+		IdleTimeout:             time.Hour,
+		AbsoluteTimeout:         8 * time.Hour,
+		RememberIdleTimeout:     3 * 24 * time.Hour,
+		RememberAbsoluteTimeout: 14 * 24 * time.Hour,
+	}
+	authService := NewAuthServiceWithPolicy(userRepo, sessionRepo, policy)
+
+	ctx := context.Background()
+	if _, err := authService.Register(ctx, "alice", "alice@example.com", "password123"); err != nil {
+		t.Fatalf("Failed to register user: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		opts         LoginOptions
+		wantIdle     time.Duration
+		wantAbsolute time.Duration
+	}{
+		{"short session", LoginOptions{}, policy.IdleTimeout, policy.AbsoluteTimeout},
+		{"remember me", LoginOptions{RememberMe: true}, policy.RememberIdleTimeout, policy.RememberAbsoluteTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, _, err := authService.LoginWithOptions(ctx, "alice", "password123", tt.opts)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if session.IdleTimeout != tt.wantIdle {
+				t.Errorf("Expected idle timeout %v, got %v", tt.wantIdle, session.IdleTimeout)
+			}
+			if diff := session.AbsoluteExpiresAt.Sub(time.Now().Add(tt.wantAbsolute)).Abs(); diff > time.Minute {
+				t.Errorf("Expected absolute expiry in ~%v, but difference is %v", tt.wantAbsolute, diff)
+			}
+			if diff := session.ExpiresAt.Sub(time.Now().Add(tt.wantIdle)).Abs(); diff > time.Minute {
+				t.Errorf("Expected expiry in ~%v, but difference is %v", tt.wantIdle, diff)
+			}
+		})
+	}
+}
+
+func TestAuthService_Login_PolicyCapsIdleAtAbsolute(t *testing.T) {
+	userRepo := &mockUserRepository{
+		users: make(map[string]*domain.User),
+	}
+	sessionRepo := &mockSessionRepository{
+		sessions: make(map[string]*domain.Session),
+	}
+	authService := NewAuthServiceWithPolicy(userRepo, sessionRepo, SessionPolicy{
+		IdleTimeout:     48 * time.Hour,
+		AbsoluteTimeout: time.Hour,
+	})
+
+	ctx := context.Background()
+	if _, err := authService.Register(ctx, "alice", "alice@example.com", "password123"); err != nil {
+		t.Fatalf("Failed to register user: %v", err)
+	}
+
+	session, _, err := authService.Login(ctx, "alice", "password123")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !session.ExpiresAt.Equal(session.AbsoluteExpiresAt) {
+		t.Errorf("Expected expiry %v to equal absolute expiry %v", session.ExpiresAt, session.AbsoluteExpiresAt)
+	}
+	if session.IdleTimeout != time.Hour {
+		t.Errorf("Expected idle timeout capped at 1h, got %v", session.IdleTimeout)
+	}
+}
+
+func TestAuthService_CreateGuest(t *testing.T) {
+	userRepo := &mockUserRepository{
+		users: make(map[string]*domain.User),
+	}
+	sessionRepo := &mockSessionRepository{
+		sessions: make(map[string]*domain.Session),
+	}
+	authService := NewAuthService(userRepo, sessionRepo)
+	ctx := context.Background()
+
+	if _, _, err := authService.CreateGuest(ctx); !errors.Is(err, domain.ErrGuestsDisabled) {
+		t.Fatalf("Expected ErrGuestsDisabled, got: %v", err)
+	}
+
+	authService.SetGuestSessionTTL(30 * time.Minute)
+	session, user, err := authService.CreateGuest(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !user.IsGuest() || !session.IsGuest() {
+		t.Errorf("Expected a guest user and session, got role %q and token %q", user.Role, session.Token)
+	}
+	if session.UserID != user.ID {
+		t.Errorf("Expected session of %s, got %s", user.ID, session.UserID)
+	}
+	if got := session.AbsoluteExpiresAt.Sub(session.LastActivityAt); got != 30*time.Minute {
+		t.Errorf("Expected the session to last the guest TTL, got %v", got)
+	}
+	if session.IdleTimeout != 30*time.Minute {
+		t.Errorf("Expected idle timeout capped at the guest TTL, got %v", session.IdleTimeout)
+	}
+
+	// Guests have no password to log in with
+	if _, _, err := authService.Login(ctx, user.Username, ""); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got: %v", err)
+	}
+}
+
+func TestAuthService_ConvertGuest(t *testing.T) {
+	userRepo := &mockUserRepository{
+		users: make(map[string]*domain.User),
+	}
+	sessionRepo := &mockSessionRepository{
+		sessions: make(map[string]*domain.Session),
+	}
+	authService := NewAuthService(userRepo, sessionRepo)
+	authService.SetGuestSessionTTL(time.Hour)
+	ctx := context.Background()
+
+	_, guest, err := authService.CreateGuest(ctx)
+	if err != nil {
+		t.Fatalf("Failed to create guest: %v", err)
+	}
+
+	if _, err := authService.ConvertGuest(ctx, guest.ID, "al", "alice@example.com", "password123"); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got: %v", err)
+	}
+
+	user, err := authService.ConvertGuest(ctx, guest.ID, "alice", "alice@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if user.ID != guest.ID || user.Role != domain.RoleUser {
+		t.Errorf("Expected guest %s converted to a user, got %s with role %q", guest.ID, user.ID, user.Role)
+	}
+	if _, _, err := authService.Login(ctx, "alice", "password123"); err != nil {
+		t.Errorf("Expected to log in with the new credentials, got: %v", err)
+	}
+
+	// A converted guest is no longer a guest
+	if _, err := authService.ConvertGuest(ctx, guest.ID, "alice2", "alice2@example.com", "password123"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got: %v", err)
 	}
 }
 
@@ -408,6 +638,30 @@ func TestAuthService_Login_UserNotFound(t *testing.T) {
 	}
 }
 
+func TestAuthService_Login_Deactivated(t *testing.T) {
+	userRepo := &mockUserRepository{
+		users: make(map[string]*domain.User),
+	}
+	sessionRepo := &mockSessionRepository{}
+	authService := NewAuthService(userRepo, sessionRepo)
+
+	ctx := context.Background()
+	registered, err := authService.Register(ctx, "alice", "alice@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Failed to register user: %v", err)
+	}
+	deactivatedAt := time.Now()
+	registered.DeactivatedAt = &deactivatedAt
+
+	session, _, err := authService.Login(ctx, "alice", "password123")
+	if !errors.Is(err, domain.ErrUserDeactivated) {
+		t.Errorf("Expected ErrUserDeactivated, got: %v", err)
+	}
+	if session != nil {
+		t.Errorf("Expected nil session, got: %+v", session)
+	}
+}
+
 func TestAuthService_Logout_Success(t *testing.T) {
 	userRepo := &mockUserRepository{}
 	sessionRepo := &mockSessionRepository{
@@ -487,6 +741,34 @@ func TestAuthService_GetUserByID_NotFound(t *testing.T) {
 	}
 }
 
+func TestAuthService_SetLocale(t *testing.T) {
+	userRepo := &mockUserRepository{
+		users: map[string]*domain.User{
+			"alice": {ID: "user-1", Username: "alice"},
+		},
+	}
+	authService := NewAuthService(userRepo, &mockSessionRepository{})
+	ctx := context.Background()
+
+	if err := authService.SetLocale(ctx, "user-1", "es"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got := userRepo.users["alice"].Locale; got != "es" {
+		t.Errorf("Expected locale 'es', got %q", got)
+	}
+
+	if err := authService.SetLocale(ctx, "user-1", "fr"); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for unsupported locale, got: %v", err)
+	}
+
+	if err := authService.SetLocale(ctx, "user-1", ""); err != nil {
+		t.Fatalf("Expected clearing the locale to succeed, got: %v", err)
+	}
+	if got := userRepo.users["alice"].Locale; got != "" {
+		t.Errorf("Expected locale to be cleared, got %q", got)
+	}
+}
+
 func TestAuthService_PasswordHashing(t *testing.T) {
 	userRepo := &mockUserRepository{
 		users: make(map[string]*domain.User),
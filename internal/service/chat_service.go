@@ -2,24 +2,139 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+	"unicode/utf8"
 
 	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/markdown"
+	"jobsity-chat/internal/observability"
+
+	"github.com/google/uuid"
 )
 
+// errNoRSVPRepository is returned for RSVPs until SetRSVPRepository is called
+var errNoRSVPRepository = errors.New("chat service has no RSVP repository")
+
+// QuotaChecker rejects new chatrooms and messages over the organization's
+// usage quotas with a domain.QuotaExceededError
+type QuotaChecker interface {
+	CheckCreateRoom(ctx context.Context, userID string) error
+	CheckSendMessage(ctx context.Context, chatroomID string) error
+}
+
 type ChatService struct {
 	messageRepo  domain.MessageRepository
 	chatroomRepo domain.ChatroomRepository
+	// quotas is nil when usage quotas are not enforced
+	quotas QuotaChecker
+	// users is nil until SetUserRepository is called
+	users domain.UserRepository
+	// events is nil until SetEventPublisher is called
+	events EventPublisher
+	// presence is nil until SetPresence is called
+	presence ActivityChecker
+	// rsvps is nil until SetRSVPRepository is called
+	rsvps domain.RSVPRepository
+	// reservedRooms is nil until SetReservedRoomNames is called
+	reservedRooms *ReservedNames
+	// tx is nil until SetTxManager is called
+	tx domain.TxManager
 }
 
 func NewChatService(messageRepo domain.MessageRepository, chatroomRepo domain.ChatroomRepository) *ChatService {
+	return NewChatServiceWithQuotas(messageRepo, chatroomRepo, nil)
+}
+
+// NewChatServiceWithQuotas returns a ChatService that rejects new chatrooms
+// and user messages over quota with a domain.QuotaExceededError
+func NewChatServiceWithQuotas(messageRepo domain.MessageRepository, chatroomRepo domain.ChatroomRepository, quotas QuotaChecker) *ChatService {
 	return &ChatService{
 		messageRepo:  messageRepo,
 		chatroomRepo: chatroomRepo,
+		quotas:       quotas,
 	}
 }
 
-func (s *ChatService) SendMessage(ctx context.Context, msg *domain.Message) error {
+// SetEventPublisher publishes MessageSent, RoomCreated and UserJoinedRoom
+// events to events from now on
+func (s *ChatService) SetEventPublisher(events EventPublisher) {
+	s.events = events
+}
+
+// SetUserRepository lets moderators post in read-only chatrooms and rejects
+// messages of deactivated users; until it is called only the owners of
+// read-only chatrooms can post in them, and deactivation is not checked
+func (s *ChatService) SetUserRepository(users domain.UserRepository) {
+	s.users = users
+}
+
+// SetPresence shows members as online, away or offline from their live
+// connections; until it is called they all appear offline
+func (s *ChatService) SetPresence(presence ActivityChecker) {
+	s.presence = presence
+}
+
+// SetReservedRoomNames keeps new chatrooms from taking reserved names with
+// domain.ErrNameReserved
+func (s *ChatService) SetReservedRoomNames(reserved *ReservedNames) {
+	s.reservedRooms = reserved
+}
+
+// SetTxManager checks the message quota in the transaction storing the
+// message, so concurrent sends cannot exceed it
+func (s *ChatService) SetTxManager(tx domain.TxManager) {
+	s.tx = tx
+}
+
+// SetRSVPRepository stores the RSVPs to event messages; until it is called
+// events can be posted but not answered
+func (s *ChatService) SetRSVPRepository(rsvps domain.RSVPRepository) {
+	s.rsvps = rsvps
+}
+
+// MaxCiphertextLength caps a message in an encrypted chatroom, in bytes. It is
+// larger than the plaintext limit to leave room for the encoding, nonce and
+// authentication tag added by clients.
+const MaxCiphertextLength = 8000
+
+// MaxMemberKeyLength caps the key exchange metadata a member publishes for an
+// encrypted chatroom, in bytes
+const MaxMemberKeyLength = 8000
+
+// SendMessage stores a message after checking membership, length and quota.
+// :shortcode: emoji in user messages are expanded first. Encrypted messages
+// are opaque ciphertext and stored as is; msg.Encrypted must match the
+// chatroom or the message is rejected. A reply with msg.QuotedMessageID gets
+// a snapshot of the quoted message in msg.Quote, or domain.ErrInvalidQuote
+// unless it is in the same chatroom. In chatrooms rendering Markdown,
+// msg.HTML is set too.
+func (s *ChatService) SendMessage(ctx context.Context, msg *domain.Message) (err error) {
+	defer observe("chat", "SendMessage")(&err)
+
+	maxLength := 1000
+	if msg.Encrypted {
+		maxLength = MaxCiphertextLength
+	} else if !msg.IsBot {
+		msg.Content = ExpandEmoji(msg.Content)
+	}
+
 	if !msg.IsBot {
+		// A deactivated user may still hold a connection to another
+		// instance, or one opened before their sessions were revoked
+		if s.users != nil {
+			user, err := s.users.GetByID(ctx, msg.UserID)
+			if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
+				return err
+			}
+			if err == nil && !user.IsActive() {
+				return domain.ErrUserDeactivated
+			}
+		}
 		isMember, err := s.chatroomRepo.IsMember(ctx, msg.ChatroomID, msg.UserID)
 		if err != nil {
 			return err
@@ -27,62 +142,854 @@ func (s *ChatService) SendMessage(ctx context.Context, msg *domain.Message) erro
 		if !isMember {
 			return domain.ErrNotMember
 		}
+		if err := s.CheckCanPost(ctx, msg.ChatroomID, msg.UserID); err != nil {
+			return err
+		}
 	}
 
-	if len(msg.Content) == 0 || len(msg.Content) > 1000 {
+	if len(msg.Content) == 0 || len(msg.Content) > maxLength {
 		return domain.ErrInvalidInput
 	}
 
-	return s.messageRepo.Create(ctx, msg)
+	if msg.QuotedMessageID != "" {
+		if err := s.snapshotQuote(ctx, msg); err != nil {
+			return err
+		}
+	}
+
+	if err := s.renderMarkdown(ctx, msg.ChatroomID, msg); err != nil {
+		return err
+	}
+	err = withinTx(ctx, s.tx, func(ctx context.Context) error {
+		if !msg.IsBot && s.quotas != nil {
+			if err := s.quotas.CheckSendMessage(ctx, msg.ChatroomID); err != nil {
+				return err
+			}
+		}
+		return s.messageRepo.Create(ctx, msg)
+	})
+	if err != nil {
+		return err
+	}
+	publish(ctx, s.events, domain.MessageSent{Message: msg})
+	return nil
+}
+
+// ForwardMessage posts a copy of a message to each of chatroomIDs on behalf
+// of userID, attributed to the original author and linking back to the
+// original; forwarding a forwarded message keeps the first attribution.
+// userID must be a member of the original's chatroom and able to post in
+// every target, or nothing is posted. Messages of encrypted chatrooms cannot
+// be forwarded, nor can messages be forwarded into them.
+func (s *ChatService) ForwardMessage(ctx context.Context, messageID, userID string, chatroomIDs []string) (_ []*domain.Message, err error) {
+	defer observe("chat", "ForwardMessage")(&err)
+
+	var targets []string
+	for _, id := range chatroomIDs {
+		if id == "" {
+			return nil, domain.ErrInvalidInput
+		}
+		if !slices.Contains(targets, id) {
+			targets = append(targets, id)
+		}
+	}
+	if len(targets) == 0 || len(targets) > domain.MaxForwardTargets {
+		return nil, domain.ErrInvalidInput
+	}
+
+	original, err := s.messageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	// Non-members must not learn whether the message exists
+	isMember, err := s.chatroomRepo.IsMember(ctx, original.ChatroomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, domain.ErrMessageNotFound
+	}
+	source, err := s.chatroomRepo.GetByID(ctx, original.ChatroomID)
+	if err != nil {
+		return nil, err
+	}
+	if source.Encrypted {
+		return nil, domain.ErrInvalidInput
+	}
+
+	for _, chatroomID := range targets {
+		if chatroomID == original.ChatroomID {
+			return nil, domain.ErrInvalidInput
+		}
+		target, err := s.chatroomRepo.GetByID(ctx, chatroomID)
+		if err != nil {
+			return nil, err
+		}
+		if target.Encrypted {
+			return nil, domain.ErrInvalidInput
+		}
+		isMember, err := s.chatroomRepo.IsMember(ctx, chatroomID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isMember {
+			return nil, domain.ErrNotMember
+		}
+		if err := s.CheckCanPost(ctx, chatroomID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	forwardedFrom := original.ForwardedFrom
+	if forwardedFrom == nil {
+		forwardedFrom = &domain.ForwardedFrom{
+			MessageID:  original.ID,
+			ChatroomID: original.ChatroomID,
+			UserID:     original.UserID,
+			Username:   original.Username,
+		}
+	}
+	username := s.username(ctx, userID)
+
+	forwarded := make([]*domain.Message, 0, len(targets))
+	for _, chatroomID := range targets {
+		msg := &domain.Message{
+			ChatroomID:    chatroomID,
+			UserID:        userID,
+			Username:      username,
+			Content:       original.Content,
+			ForwardedFrom: forwardedFrom,
+			Event:         original.Event,
+		}
+		if err := s.SendMessage(ctx, msg); err != nil {
+			return forwarded, err
+		}
+		forwarded = append(forwarded, msg)
+	}
+	return forwarded, nil
+}
+
+// username returns the username of userID, or "" if it can't be looked up;
+// messages are stored by user ID, so it only fills in the returned message
+func (s *ChatService) username(ctx context.Context, userID string) string {
+	if s.users == nil {
+		return ""
+	}
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return ""
+	}
+	return user.Username
+}
+
+// CreateEvent posts a message announcing event in chatroomID on behalf of
+// userID, with the event's title as its content, following the rules of
+// SendMessage. Events cannot be posted in encrypted chatrooms, as the
+// server would store their details in the clear.
+func (s *ChatService) CreateEvent(ctx context.Context, chatroomID, userID string, event *domain.CalendarEvent) (_ *domain.Message, err error) {
+	defer observe("chat", "CreateEvent")(&err)
+
+	if err := event.Validate(); err != nil {
+		return nil, err
+	}
+	event.StartsAt = event.StartsAt.UTC()
+	if event.EndsAt != nil {
+		endsAt := event.EndsAt.UTC()
+		event.EndsAt = &endsAt
+	}
+
+	chatroom, err := s.chatroomRepo.GetByID(ctx, chatroomID)
+	if err != nil {
+		return nil, err
+	}
+	if chatroom.Encrypted {
+		return nil, fmt.Errorf("%w: events cannot be posted in encrypted chatrooms", domain.ErrInvalidInput)
+	}
+
+	msg := &domain.Message{
+		ChatroomID: chatroomID,
+		UserID:     userID,
+		Username:   s.username(ctx, userID),
+		Content:    event.Title,
+		Event:      event,
+	}
+	if err := s.SendMessage(ctx, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// RSVP records userID's answer to the event message messageID, replacing
+// their earlier one. Every member can answer, in read-only chatrooms too.
+func (s *ChatService) RSVP(ctx context.Context, messageID, userID, response string) (_ *domain.RSVP, err error) {
+	defer observe("chat", "RSVP")(&err)
+
+	if !domain.ValidRSVPResponse(response) {
+		return nil, domain.ErrInvalidInput
+	}
+	if s.rsvps == nil {
+		return nil, errNoRSVPRepository
+	}
+	msg, err := s.GetEvent(ctx, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rsvp := &domain.RSVP{
+		MessageID:  msg.ID,
+		ChatroomID: msg.ChatroomID,
+		UserID:     userID,
+		Username:   s.username(ctx, userID),
+		Response:   response,
+	}
+	if err := s.rsvps.Set(ctx, rsvp); err != nil {
+		return nil, err
+	}
+	return rsvp, nil
+}
+
+// ListRSVPs returns the answers to the event message messageID, oldest
+// first. userID must be a member of its chatroom.
+func (s *ChatService) ListRSVPs(ctx context.Context, messageID, userID string) (_ []*domain.RSVP, err error) {
+	defer observe("chat", "ListRSVPs")(&err)
+
+	if s.rsvps == nil {
+		return nil, errNoRSVPRepository
+	}
+	if _, err := s.GetEvent(ctx, messageID, userID); err != nil {
+		return nil, err
+	}
+	return s.rsvps.List(ctx, messageID)
+}
+
+// GetEvent returns the event message messageID, or domain.ErrNotAnEvent if
+// it doesn't announce one. Non-members of its chatroom get
+// domain.ErrMessageNotFound.
+func (s *ChatService) GetEvent(ctx context.Context, messageID, userID string) (_ *domain.Message, err error) {
+	defer observe("chat", "GetEvent")(&err)
+
+	if _, err := uuid.Parse(messageID); err != nil {
+		return nil, domain.ErrMessageNotFound
+	}
+	msg, err := s.messageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	isMember, err := s.chatroomRepo.IsMember(ctx, msg.ChatroomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, domain.ErrMessageNotFound
+	}
+	if msg.Event == nil {
+		return nil, domain.ErrNotAnEvent
+	}
+	return msg, nil
+}
+
+// snapshotQuote sets msg.Quote to the message msg quotes, which must be in
+// the same chatroom
+func (s *ChatService) snapshotQuote(ctx context.Context, msg *domain.Message) error {
+	if _, err := uuid.Parse(msg.QuotedMessageID); err != nil {
+		return domain.ErrInvalidQuote
+	}
+	quoted, err := s.messageRepo.GetByID(ctx, msg.QuotedMessageID)
+	if errors.Is(err, domain.ErrMessageNotFound) {
+		return domain.ErrInvalidQuote
+	}
+	if err != nil {
+		return err
+	}
+	if quoted.ChatroomID != msg.ChatroomID {
+		return domain.ErrInvalidQuote
+	}
+
+	msg.Quote = &domain.Quote{
+		MessageID: quoted.ID,
+		UserID:    quoted.UserID,
+		Username:  quoted.Username,
+		Content:   quoted.Content,
+		CreatedAt: quoted.CreatedAt,
+	}
+	return nil
+}
+
+// renderMarkdown sets the HTML of messages of chatroomID when the chatroom
+// renders Markdown. Encrypted messages are left alone: the server cannot
+// read them.
+func (s *ChatService) renderMarkdown(ctx context.Context, chatroomID string, messages ...*domain.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	settings, err := s.chatroomRepo.GetSettings(ctx, chatroomID)
+	if err != nil {
+		return err
+	}
+	if !settings.Markdown {
+		return nil
+	}
+	for _, msg := range messages {
+		if !msg.Encrypted {
+			msg.HTML = markdown.Render(msg.Content)
+		}
+	}
+	return nil
+}
+
+// CheckCanPost returns domain.ErrReadOnly when the chatroom is read-only and
+// userID is neither its owner nor a moderator. Bots post anywhere.
+func (s *ChatService) CheckCanPost(ctx context.Context, chatroomID, userID string) (err error) {
+	defer observe("chat", "CheckCanPost")(&err)
+
+	settings, err := s.chatroomRepo.GetSettings(ctx, chatroomID)
+	if err != nil {
+		return err
+	}
+	if !settings.ReadOnly {
+		return nil
+	}
+
+	chatroom, err := s.chatroomRepo.GetByID(ctx, chatroomID)
+	if err != nil {
+		return err
+	}
+	if chatroom.CreatedBy == userID {
+		return nil
+	}
+	if s.users != nil {
+		user, err := s.users.GetByID(ctx, userID)
+		if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
+			return err
+		}
+		if err == nil && user.IsModerator() {
+			return nil
+		}
+	}
+	return domain.ErrReadOnly
+}
+
+func (s *ChatService) GetMessages(ctx context.Context, chatroomID string, limit int) (_ []*domain.Message, err error) {
+	defer observe("chat", "GetMessages")(&err)
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	messages, err := s.messageRepo.GetByChatroom(ctx, chatroomID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return messages, s.renderMarkdown(ctx, chatroomID, messages...)
+}
+
+func (s *ChatService) GetMessagesBefore(ctx context.Context, chatroomID string, before string, limit int) (_ []*domain.Message, err error) {
+	defer observe("chat", "GetMessagesBefore")(&err)
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	messages, err := s.messageRepo.GetByChatroomBefore(ctx, chatroomID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return messages, s.renderMarkdown(ctx, chatroomID, messages...)
 }
 
-func (s *ChatService) GetMessages(ctx context.Context, chatroomID string, limit int) ([]*domain.Message, error) {
+// GetMessagesPage returns up to limit messages, oldest first, posted before
+// the message before (or the latest ones when before is empty), and whether
+// older messages remain.
+func (s *ChatService) GetMessagesPage(ctx context.Context, chatroomID, before string, limit int) (_ []*domain.Message, _ bool, err error) {
+	defer observe("chat", "GetMessagesPage")(&err)
+
 	if limit <= 0 || limit > 100 {
 		limit = 50
 	}
-	return s.messageRepo.GetByChatroom(ctx, chatroomID, limit)
+
+	// Fetch one extra message to know whether history continues
+	var messages []*domain.Message
+	if before != "" {
+		messages, err = s.messageRepo.GetByChatroomBefore(ctx, chatroomID, before, limit+1)
+	} else {
+		messages, err = s.messageRepo.GetByChatroom(ctx, chatroomID, limit+1)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[len(messages)-limit:]
+	}
+	if err := s.renderMarkdown(ctx, chatroomID, messages...); err != nil {
+		return nil, false, err
+	}
+	return messages, hasMore, nil
 }
 
-func (s *ChatService) GetMessagesBefore(ctx context.Context, chatroomID string, before string, limit int) ([]*domain.Message, error) {
+// GetMessagesSince returns up to limit messages posted after the message
+// sinceID, oldest first, and whether more messages follow them. Clients use
+// it to backfill the gap after a reconnect.
+func (s *ChatService) GetMessagesSince(ctx context.Context, chatroomID, sinceID string, limit int) (_ []*domain.Message, _ bool, err error) {
+	defer observe("chat", "GetMessagesSince")(&err)
+
+	if _, err := uuid.Parse(sinceID); err != nil {
+		return nil, false, domain.ErrInvalidInput
+	}
 	if limit <= 0 || limit > 100 {
 		limit = 50
 	}
-	return s.messageRepo.GetByChatroomBefore(ctx, chatroomID, before, limit)
+
+	// Fetch one extra message to know whether the gap continues
+	messages, err := s.messageRepo.GetByChatroomSince(ctx, chatroomID, sinceID, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+	if err := s.renderMarkdown(ctx, chatroomID, messages...); err != nil {
+		return nil, false, err
+	}
+	return messages, hasMore, nil
+}
+
+func (s *ChatService) CreateChatroom(ctx context.Context, name, createdBy string) (_ *domain.Chatroom, err error) {
+	defer observe("chat", "CreateChatroom")(&err)
+	return s.createChatroom(ctx, &domain.Chatroom{Name: name, CreatedBy: createdBy})
+}
+
+// CreateEncryptedChatroom creates an end-to-end encrypted chatroom. The server
+// only relays and stores the ciphertext its members send: commands, emoji
+// shortcodes, link previews and mention notifications do not apply, and
+// members exchange keys through SetMemberKey and MemberKeys.
+func (s *ChatService) CreateEncryptedChatroom(ctx context.Context, name, createdBy string) (_ *domain.Chatroom, err error) {
+	defer observe("chat", "CreateEncryptedChatroom")(&err)
+	return s.createChatroom(ctx, &domain.Chatroom{Name: name, CreatedBy: createdBy, Encrypted: true})
 }
 
-func (s *ChatService) CreateChatroom(ctx context.Context, name, createdBy string) (*domain.Chatroom, error) {
-	if len(name) == 0 || len(name) > 100 {
+func (s *ChatService) createChatroom(ctx context.Context, chatroom *domain.Chatroom) (*domain.Chatroom, error) {
+	if len(chatroom.Name) == 0 || len(chatroom.Name) > 100 {
 		return nil, domain.ErrInvalidInput
 	}
+	if s.reservedRooms.Contains(chatroom.Name) {
+		return nil, domain.ErrNameReserved
+	}
 
-	chatroom := &domain.Chatroom{
-		Name:      name,
-		CreatedBy: createdBy,
+	if s.quotas != nil {
+		if err := s.quotas.CheckCreateRoom(ctx, chatroom.CreatedBy); err != nil {
+			return nil, err
+		}
 	}
 
-	if err := s.chatroomRepo.CreateWithMember(ctx, chatroom, createdBy); err != nil {
+	if err := s.chatroomRepo.CreateWithMember(ctx, chatroom, chatroom.CreatedBy); err != nil {
 		return nil, err
 	}
+	publish(ctx, s.events, domain.RoomCreated{Chatroom: chatroom})
 
 	return chatroom, nil
 }
 
-func (s *ChatService) ListChatrooms(ctx context.Context) ([]*domain.Chatroom, error) {
+// GetChatroom returns a chatroom of the caller's organization
+func (s *ChatService) GetChatroom(ctx context.Context, chatroomID string) (_ *domain.Chatroom, err error) {
+	defer observe("chat", "GetChatroom")(&err)
+	return s.chatroomRepo.GetByID(ctx, chatroomID)
+}
+
+func (s *ChatService) ListChatrooms(ctx context.Context) (_ []*domain.Chatroom, err error) {
+	defer observe("chat", "ListChatrooms")(&err)
 	return s.chatroomRepo.List(ctx)
 }
 
-func (s *ChatService) ListChatroomsPaginated(ctx context.Context, limit int, cursor string) ([]*domain.Chatroom, string, error) {
-	return s.chatroomRepo.ListPaginated(ctx, limit, cursor)
+// ListChatroomsPaginated returns a page of the chatroom directory and the
+// cursor of the next one. Unknown sorts and over-long name filters are
+// rejected with ErrInvalidInput.
+func (s *ChatService) ListChatroomsPaginated(ctx context.Context, opts domain.ChatroomListOptions) (_ []*domain.Chatroom, _ string, err error) {
+	defer observe("chat", "ListChatroomsPaginated")(&err)
+
+	switch opts.Sort {
+	case "":
+		opts.Sort = domain.ChatroomSortNewest
+	case domain.ChatroomSortNewest, domain.ChatroomSortActive, domain.ChatroomSortMembers:
+	default:
+		return nil, "", domain.ErrInvalidInput
+	}
+
+	opts.Name = strings.TrimSpace(opts.Name)
+	if len(opts.Name) > 100 {
+		return nil, "", domain.ErrInvalidInput
+	}
+	if len(opts.Tags) > 0 {
+		tags, err := normalizeTags(opts.Tags)
+		if err != nil {
+			return nil, "", err
+		}
+		opts.Tags = tags
+	}
+	return s.chatroomRepo.ListPaginated(ctx, opts)
+}
+
+// normalizeTags returns the normalized, deduplicated and sorted form of tags,
+// or ErrInvalidInput if one is invalid or there are more than
+// domain.MaxChatroomTags
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag, ok := domain.NormalizeTag(tag)
+		if !ok {
+			return nil, domain.ErrInvalidInput
+		}
+		normalized = append(normalized, tag)
+	}
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+	if len(normalized) > domain.MaxChatroomTags {
+		return nil, domain.ErrInvalidInput
+	}
+	return normalized, nil
+}
+
+// SetChatroomTags replaces a chatroom's tags on behalf of its owner and
+// returns them normalized
+func (s *ChatService) SetChatroomTags(ctx context.Context, chatroomID, requesterID string, tags []string) (_ []string, err error) {
+	defer observe("chat", "SetChatroomTags")(&err)
+
+	chatroom, err := s.chatroomRepo.GetByID(ctx, chatroomID)
+	if err != nil {
+		return nil, err
+	}
+	if chatroom.CreatedBy != requesterID {
+		return nil, domain.ErrNotOwner
+	}
+
+	normalized, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.chatroomRepo.SetTags(ctx, chatroomID, normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// RemoveChatroomTag removes one of a chatroom's tags on behalf of its owner
+func (s *ChatService) RemoveChatroomTag(ctx context.Context, chatroomID, requesterID, tag string) (err error) {
+	defer observe("chat", "RemoveChatroomTag")(&err)
+
+	chatroom, err := s.chatroomRepo.GetByID(ctx, chatroomID)
+	if err != nil {
+		return err
+	}
+	if chatroom.CreatedBy != requesterID {
+		return domain.ErrNotOwner
+	}
+
+	normalized, ok := domain.NormalizeTag(tag)
+	if !ok {
+		return domain.ErrInvalidInput
+	}
+	return s.chatroomRepo.RemoveTag(ctx, chatroomID, normalized)
+}
+
+// StarChatroom stars a chatroom for userID, or moves their star, and returns
+// its position. A nil position keeps an existing star where it is and puts a
+// new one after the user's other starred rooms.
+func (s *ChatService) StarChatroom(ctx context.Context, chatroomID, userID string, position *int) (_ int, err error) {
+	defer observe("chat", "StarChatroom")(&err)
+
+	if position != nil && (*position < 0 || *position > math.MaxInt32) {
+		return 0, domain.ErrInvalidInput
+	}
+	return s.chatroomRepo.Star(ctx, chatroomID, userID, position)
 }
 
-func (s *ChatService) JoinChatroom(ctx context.Context, chatroomID, userID string) error {
+// UnstarChatroom removes userID's star from a chatroom
+func (s *ChatService) UnstarChatroom(ctx context.Context, chatroomID, userID string) (err error) {
+	defer observe("chat", "UnstarChatroom")(&err)
+
 	if _, err := s.chatroomRepo.GetByID(ctx, chatroomID); err != nil {
 		return err
 	}
+	return s.chatroomRepo.Unstar(ctx, chatroomID, userID)
+}
 
-	return s.chatroomRepo.AddMember(ctx, chatroomID, userID)
+// ListTags returns the tags of the organization's chatrooms with how many
+// carry each, the most used first
+func (s *ChatService) ListTags(ctx context.Context) (_ []*domain.TagCount, err error) {
+	defer observe("chat", "ListTags")(&err)
+	return s.chatroomRepo.ListTags(ctx)
 }
 
-func (s *ChatService) IsMember(ctx context.Context, chatroomID, userID string) (bool, error) {
+func (s *ChatService) JoinChatroom(ctx context.Context, chatroomID, userID string) (err error) {
+	defer observe("chat", "JoinChatroom")(&err)
+
+	if _, err := s.chatroomRepo.GetByID(ctx, chatroomID); err != nil {
+		return err
+	}
+
+	// Joining again is a no-op and raises no event
+	isMember, err := s.chatroomRepo.IsMember(ctx, chatroomID, userID)
+	if err != nil {
+		return err
+	}
+	if isMember {
+		return nil
+	}
+
+	if err := s.chatroomRepo.AddMember(ctx, chatroomID, userID); err != nil {
+		return err
+	}
+	publish(ctx, s.events, domain.UserJoinedRoom{ChatroomID: chatroomID, UserID: userID})
+	return nil
+}
+
+// MaxBulkMembers caps how many users one bulk membership add may name
+const MaxBulkMembers = 500
+
+// AddMembers adds the users named by identifiers (user IDs or usernames) to a
+// chatroom on behalf of its owner. Duplicate and blank identifiers are
+// dropped; each remaining one gets a result, so callers can report unknown
+// users without the rest of the batch failing.
+func (s *ChatService) AddMembers(ctx context.Context, chatroomID, requesterID string, identifiers []string) (_ []domain.MemberAddResult, err error) {
+	defer observe("chat", "AddMembers")(&err)
+
+	chatroom, err := s.chatroomRepo.GetByID(ctx, chatroomID)
+	if err != nil {
+		return nil, err
+	}
+	if chatroom.CreatedBy != requesterID {
+		return nil, domain.ErrNotOwner
+	}
+
+	seen := make(map[string]bool, len(identifiers))
+	unique := make([]string, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		identifier = strings.TrimSpace(identifier)
+		if identifier == "" || seen[identifier] {
+			continue
+		}
+		seen[identifier] = true
+		unique = append(unique, identifier)
+	}
+	if len(unique) == 0 || len(unique) > MaxBulkMembers {
+		return nil, domain.ErrInvalidInput
+	}
+
+	results, err := s.chatroomRepo.AddMembers(ctx, chatroomID, unique)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if result.Status == domain.MemberAdded {
+			publish(ctx, s.events, domain.UserJoinedRoom{ChatroomID: chatroomID, UserID: result.UserID})
+		}
+	}
+	return results, nil
+}
+
+// MaxWelcomeMessageLength caps a chatroom's welcome message, in characters
+const MaxWelcomeMessageLength = 1000
+
+// GetChatroomSettings returns a chatroom's settings to one of its members
+func (s *ChatService) GetChatroomSettings(ctx context.Context, chatroomID, userID string) (_ *domain.ChatroomSettings, err error) {
+	defer observe("chat", "GetChatroomSettings")(&err)
+
+	isMember, err := s.chatroomRepo.IsMember(ctx, chatroomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, domain.ErrNotMember
+	}
+	return s.chatroomRepo.GetSettings(ctx, chatroomID)
+}
+
+// UpdateChatroomSettings replaces a chatroom's settings on behalf of its
+// owner. An empty welcome message disables it, and encrypted chatrooms cannot
+// be made public. A non-zero settings.Version must be the current version,
+// or domain.ErrVersionConflict is returned; on success it is set to the new
+// version.
+func (s *ChatService) UpdateChatroomSettings(ctx context.Context, chatroomID, requesterID string, settings *domain.ChatroomSettings) (err error) {
+	defer observe("chat", "UpdateChatroomSettings")(&err)
+
+	chatroom, err := s.chatroomRepo.GetByID(ctx, chatroomID)
+	if err != nil {
+		return err
+	}
+	if chatroom.CreatedBy != requesterID {
+		return domain.ErrNotOwner
+	}
+
+	settings.WelcomeMessage = strings.TrimSpace(settings.WelcomeMessage)
+	if utf8.RuneCountInString(settings.WelcomeMessage) > MaxWelcomeMessageLength {
+		return domain.ErrInvalidInput
+	}
+	// Guests cannot take part in the key exchange of an encrypted chatroom
+	if settings.Public && chatroom.Encrypted {
+		return domain.ErrPublicEncrypted
+	}
+	return s.chatroomRepo.UpdateSettings(ctx, chatroomID, settings)
+}
+
+// GuestAccess reports whether guests may post in a chatroom, or returns
+// domain.ErrGuestNotAllowed when it is not public and guests may not even
+// read it
+func (s *ChatService) GuestAccess(ctx context.Context, chatroomID string) (canPost bool, err error) {
+	defer observe("chat", "GuestAccess")(&err)
+
+	settings, err := s.chatroomRepo.GetSettings(ctx, chatroomID)
+	if err != nil {
+		return false, err
+	}
+	if !settings.Public {
+		return false, domain.ErrGuestNotAllowed
+	}
+	return settings.GuestsCanPost, nil
+}
+
+// DeleteChatroom soft-deletes a chatroom on behalf of its owner. It
+// disappears for everyone, but an administrator can restore it until it is
+// purged.
+func (s *ChatService) DeleteChatroom(ctx context.Context, chatroomID, requesterID string) (err error) {
+	defer observe("chat", "DeleteChatroom")(&err)
+
+	chatroom, err := s.chatroomRepo.GetByID(ctx, chatroomID)
+	if err != nil {
+		return err
+	}
+	if chatroom.CreatedBy != requesterID {
+		return domain.ErrNotOwner
+	}
+
+	if err := s.chatroomRepo.Delete(ctx, chatroomID); err != nil {
+		return err
+	}
+	observability.Audit(ctx, "chatroom_deleted",
+		slog.String("chatroom_id", chatroomID),
+		slog.String("user_id", requesterID))
+	return nil
+}
+
+// RestoreChatroom undoes the deletion of a chatroom that has not been purged
+// yet, with its members and history
+func (s *ChatService) RestoreChatroom(ctx context.Context, chatroomID, adminID string) (err error) {
+	defer observe("chat", "RestoreChatroom")(&err)
+
+	if _, err := uuid.Parse(chatroomID); err != nil {
+		return domain.ErrChatroomNotFound
+	}
+	if err := s.chatroomRepo.Restore(ctx, chatroomID); err != nil {
+		return err
+	}
+	observability.Audit(ctx, "chatroom_restored",
+		slog.String("chatroom_id", chatroomID),
+		slog.String("admin_id", adminID))
+	return nil
+}
+
+// ClaimWelcomeMessage returns the chatroom's welcome message the first time
+// a member connects, and "" afterwards
+func (s *ChatService) ClaimWelcomeMessage(ctx context.Context, chatroomID, userID string) (_ string, err error) {
+	defer observe("chat", "ClaimWelcomeMessage")(&err)
+	return s.chatroomRepo.ClaimWelcome(ctx, chatroomID, userID)
+}
+
+func (s *ChatService) IsMember(ctx context.Context, chatroomID, userID string) (_ bool, err error) {
+	defer observe("chat", "IsMember")(&err)
 	return s.chatroomRepo.IsMember(ctx, chatroomID, userID)
 }
+
+// GetActivity returns the unread and mention counts and latest message of
+// every chatroom the user belongs to
+func (s *ChatService) GetActivity(ctx context.Context, userID string) (_ []*domain.RoomActivity, err error) {
+	defer observe("chat", "GetActivity")(&err)
+	return s.chatroomRepo.Activity(ctx, userID)
+}
+
+// MarkRead records that the user has read the chatroom up to messageID, or up
+// to its latest message when messageID is empty
+func (s *ChatService) MarkRead(ctx context.Context, chatroomID, userID, messageID string) (err error) {
+	defer observe("chat", "MarkRead")(&err)
+
+	if messageID != "" {
+		if _, err := uuid.Parse(messageID); err != nil {
+			return domain.ErrInvalidInput
+		}
+	}
+	return s.chatroomRepo.MarkRead(ctx, chatroomID, userID, messageID)
+}
+
+// SetMemberKey publishes the caller's key exchange metadata for an encrypted
+// chatroom, e.g. their public key, replacing what they published before. The
+// server never interprets it.
+func (s *ChatService) SetMemberKey(ctx context.Context, chatroomID, userID, keyData string) (err error) {
+	defer observe("chat", "SetMemberKey")(&err)
+
+	if len(keyData) == 0 || len(keyData) > MaxMemberKeyLength {
+		return domain.ErrInvalidInput
+	}
+	return s.chatroomRepo.SetMemberKey(ctx, chatroomID, userID, keyData)
+}
+
+// MemberView is a chatroom member as another member sees them. Status is
+// left empty for members who hide their online status.
+type MemberView struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	Status     string `json:"status,omitempty"`
+	StatusText string `json:"status_text,omitempty"`
+}
+
+// ListMembers returns the members of a chatroom with the status each one
+// shows: active, away or do-not-disturb while connected, offline otherwise
+func (s *ChatService) ListMembers(ctx context.Context, chatroomID, userID string) (_ []*MemberView, err error) {
+	defer observe("chat", "ListMembers")(&err)
+
+	isMember, err := s.chatroomRepo.IsMember(ctx, chatroomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, domain.ErrNotMember
+	}
+
+	members, err := s.chatroomRepo.ListMembers(ctx, chatroomID)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]*MemberView, 0, len(members))
+	for _, member := range members {
+		view := &MemberView{ID: member.UserID, Username: member.Username, StatusText: member.Status.Text}
+		if member.ShowOnlineStatus || member.UserID == userID {
+			online, idle := false, false
+			if s.presence != nil {
+				online = s.presence.IsUserOnline(member.UserID)
+				idle = online && s.presence.IsUserIdle(member.UserID)
+			}
+			view.Status = domain.EffectiveStatus(member.Status.Status, online, idle)
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// MemberKeys returns the key exchange metadata published by the members of an
+// encrypted chatroom, to one of its members
+func (s *ChatService) MemberKeys(ctx context.Context, chatroomID, userID string) (_ []*domain.MemberKey, err error) {
+	defer observe("chat", "MemberKeys")(&err)
+
+	chatroom, err := s.chatroomRepo.GetByID(ctx, chatroomID)
+	if err != nil {
+		return nil, err
+	}
+	isMember, err := s.chatroomRepo.IsMember(ctx, chatroomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, domain.ErrNotMember
+	}
+	if !chatroom.Encrypted {
+		return nil, domain.ErrNotEncrypted
+	}
+	return s.chatroomRepo.MemberKeys(ctx, chatroomID)
+}
@@ -3,10 +3,14 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
 )
 
 // Mock repositories for testing
@@ -15,6 +19,7 @@ type mockMessageRepository struct {
 	create             func(ctx context.Context, message *domain.Message) error
 	getByChatroom      func(ctx context.Context, chatroomID string, limit int) ([]*domain.Message, error)
 	getByChatroomBefore func(ctx context.Context, chatroomID string, before string, limit int) ([]*domain.Message, error)
+	getByChatroomSince  func(ctx context.Context, chatroomID string, sinceID string, limit int) ([]*domain.Message, error)
 }
 
 func (m *mockMessageRepository) Create(ctx context.Context, message *domain.Message) error {
@@ -47,6 +52,15 @@ func (m *mockMessageRepository) GetByChatroom(ctx context.Context, chatroomID st
 	return result, nil
 }
 
+func (m *mockMessageRepository) GetByID(ctx context.Context, id string) (*domain.Message, error) {
+	for _, msg := range m.messages {
+		if msg.ID == id {
+			return msg, nil
+		}
+	}
+	return nil, domain.ErrMessageNotFound
+}
+
 func (m *mockMessageRepository) GetByChatroomBefore(ctx context.Context, chatroomID string, before string, limit int) ([]*domain.Message, error) {
 	if m.getByChatroomBefore != nil {
 		return m.getByChatroomBefore(ctx, chatroomID, before, limit)
@@ -73,6 +87,41 @@ func (m *mockMessageRepository) GetByChatroomBefore(ctx context.Context, chatroo
 	return result, nil
 }
 
+func (m *mockMessageRepository) GetByChatroomSince(ctx context.Context, chatroomID string, sinceID string, limit int) ([]*domain.Message, error) {
+	if m.getByChatroomSince != nil {
+		return m.getByChatroomSince(ctx, chatroomID, sinceID, limit)
+	}
+
+	result := []*domain.Message{}
+	found := false
+	for _, msg := range m.messages {
+		if msg.ChatroomID != chatroomID {
+			continue
+		}
+		if found {
+			result = append(result, msg)
+		}
+		if msg.ID == sinceID {
+			found = true
+		}
+	}
+
+	// Apply limit
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+func (m *mockMessageRepository) Restore(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *mockMessageRepository) PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
 type mockChatroomRepository struct {
 	chatrooms        map[string]*domain.Chatroom
 	members          map[string]map[string]bool // chatroomID -> userID -> bool
@@ -130,7 +179,8 @@ func (m *mockChatroomRepository) List(ctx context.Context) ([]*domain.Chatroom,
 	return result, nil
 }
 
-func (m *mockChatroomRepository) ListPaginated(ctx context.Context, limit int, cursor string) ([]*domain.Chatroom, string, error) {
+func (m *mockChatroomRepository) ListPaginated(ctx context.Context, opts domain.ChatroomListOptions) ([]*domain.Chatroom, string, error) {
+	limit := opts.Limit
 	result := []*domain.Chatroom{}
 	for _, chatroom := range m.chatrooms {
 		result = append(result, chatroom)
@@ -156,6 +206,21 @@ func (m *mockChatroomRepository) AddMember(ctx context.Context, chatroomID, user
 	return nil
 }
 
+func (m *mockChatroomRepository) AddMembers(ctx context.Context, chatroomID string, identifiers []string) ([]domain.MemberAddResult, error) {
+	results := make([]domain.MemberAddResult, 0, len(identifiers))
+	for _, userID := range identifiers {
+		status := domain.MemberAdded
+		if m.members[chatroomID][userID] {
+			status = domain.MemberAlreadyMember
+		}
+		if err := m.AddMember(ctx, chatroomID, userID); err != nil {
+			return nil, err
+		}
+		results = append(results, domain.MemberAddResult{Identifier: userID, UserID: userID, Status: status})
+	}
+	return results, nil
+}
+
 func (m *mockChatroomRepository) IsMember(ctx context.Context, chatroomID, userID string) (bool, error) {
 	if m.isMember != nil {
 		return m.isMember(ctx, chatroomID, userID)
@@ -166,6 +231,70 @@ func (m *mockChatroomRepository) IsMember(ctx context.Context, chatroomID, userI
 	return m.members[chatroomID][userID], nil
 }
 
+func (m *mockChatroomRepository) GetSettings(ctx context.Context, chatroomID string) (*domain.ChatroomSettings, error) {
+	return &domain.ChatroomSettings{}, nil
+}
+
+func (m *mockChatroomRepository) UpdateSettings(ctx context.Context, chatroomID string, settings *domain.ChatroomSettings) error {
+	return nil
+}
+
+func (m *mockChatroomRepository) ClaimWelcome(ctx context.Context, chatroomID, userID string) (string, error) {
+	return "", nil
+}
+
+func (m *mockChatroomRepository) Activity(ctx context.Context, userID string) ([]*domain.RoomActivity, error) {
+	return nil, nil
+}
+
+func (m *mockChatroomRepository) MarkRead(ctx context.Context, chatroomID, userID, messageID string) error {
+	return nil
+}
+
+func (m *mockChatroomRepository) Delete(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *mockChatroomRepository) Restore(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *mockChatroomRepository) PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockChatroomRepository) SetMemberKey(ctx context.Context, chatroomID, userID, keyData string) error {
+	return nil
+}
+
+func (m *mockChatroomRepository) MemberKeys(ctx context.Context, chatroomID string) ([]*domain.MemberKey, error) {
+	return nil, nil
+}
+
+func (m *mockChatroomRepository) ListMembers(ctx context.Context, chatroomID string) ([]*domain.Member, error) {
+	return nil, nil
+}
+
+func (m *mockChatroomRepository) SetTags(ctx context.Context, chatroomID string, tags []string) error {
+	return nil
+}
+
+func (m *mockChatroomRepository) RemoveTag(ctx context.Context, chatroomID, tag string) error {
+	return nil
+}
+
+func (m *mockChatroomRepository) ListTags(ctx context.Context) ([]*domain.TagCount, error) {
+	return nil, nil
+}
+
+func (m *mockChatroomRepository) Star(ctx context.Context, chatroomID, userID string, position *int) (int, error) {
+	return 0, nil
+}
+
+func (m *mockChatroomRepository) Unstar(ctx context.Context, chatroomID, userID string) error {
+	return nil
+}
+
 func TestChatService_SendMessage_Success(t *testing.T) {
 	messageRepo := &mockMessageRepository{
 		messages: []*domain.Message{},
@@ -299,6 +428,87 @@ func TestChatService_GetMessages_DefaultLimit(t *testing.T) {
 	}
 }
 
+func TestChatService_GetMessagesSince(t *testing.T) {
+	ids := []string{
+		"00000000-0000-0000-0000-000000000101",
+		"00000000-0000-0000-0000-000000000102",
+		"00000000-0000-0000-0000-000000000103",
+		"00000000-0000-0000-0000-000000000104",
+	}
+	messages := make([]*domain.Message, len(ids))
+	for i, id := range ids {
+		messages[i] = &domain.Message{ID: id, ChatroomID: "chatroom1"}
+	}
+
+	chatService := NewChatService(&mockMessageRepository{messages: messages}, &mockChatroomRepository{})
+	ctx := context.Background()
+
+	result, hasMore, err := chatService.GetMessagesSince(ctx, "chatroom1", ids[0], 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(result) != 2 || result[0].ID != ids[1] || result[1].ID != ids[2] {
+		t.Errorf("Expected the two messages after %s, got %v", ids[0], result)
+	}
+	if !hasMore {
+		t.Error("Expected hasMore with a message left")
+	}
+
+	result, hasMore, err = chatService.GetMessagesSince(ctx, "chatroom1", ids[2], 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(result) != 1 || hasMore {
+		t.Errorf("Expected only the last message, got %d (hasMore=%v)", len(result), hasMore)
+	}
+
+	if _, _, err := chatService.GetMessagesSince(ctx, "chatroom1", "not-a-uuid", 2); err != domain.ErrInvalidInput {
+		t.Errorf("Expected ErrInvalidInput for a malformed ID, got: %v", err)
+	}
+}
+
+func TestChatService_GetMessagesPage(t *testing.T) {
+	history := []*domain.Message{{ID: "m1"}, {ID: "m2"}, {ID: "m3"}, {ID: "m4"}}
+	var requested int
+	latest := func(limit int) []*domain.Message {
+		requested = limit
+		return history[max(len(history)-limit, 0):]
+	}
+	messageRepo := &mockMessageRepository{
+		getByChatroom: func(ctx context.Context, chatroomID string, limit int) ([]*domain.Message, error) {
+			return latest(limit), nil
+		},
+		getByChatroomBefore: func(ctx context.Context, chatroomID string, before string, limit int) ([]*domain.Message, error) {
+			return latest(limit)[:min(limit, 1)], nil
+		},
+	}
+	chatService := NewChatService(messageRepo, &mockChatroomRepository{})
+	ctx := context.Background()
+
+	page, hasMore, err := chatService.GetMessagesPage(ctx, "chatroom1", "", 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if requested != 3 {
+		t.Errorf("Expected one extra message to be fetched, got limit %d", requested)
+	}
+	if len(page) != 2 || page[0].ID != "m3" || page[1].ID != "m4" || !hasMore {
+		t.Errorf("Expected the two latest messages with more left, got %v (hasMore=%v)", page, hasMore)
+	}
+
+	page, hasMore, err = chatService.GetMessagesPage(ctx, "chatroom1", "m2", 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(page) != 1 || hasMore {
+		t.Errorf("Expected the last page, got %d (hasMore=%v)", len(page), hasMore)
+	}
+
+	if _, _, err := chatService.GetMessagesPage(ctx, "chatroom1", "", 500); err != nil || requested != 51 {
+		t.Errorf("Expected an out-of-range limit to fall back to 50, fetched %d", requested)
+	}
+}
+
 func TestChatService_GetMessages_OrderedByTimestamp(t *testing.T) {
 	now := time.Now()
 	messageRepo := &mockMessageRepository{
@@ -413,6 +623,25 @@ func TestChatService_CreateChatroom_EmptyName(t *testing.T) {
 	}
 }
 
+func TestChatService_CreateChatroom_ReservedName(t *testing.T) {
+	chatroomRepo := &mockChatroomRepository{
+		chatrooms: make(map[string]*domain.Chatroom),
+		members:   make(map[string]map[string]bool),
+	}
+	chatService := NewChatService(&mockMessageRepository{}, chatroomRepo)
+	chatService.SetReservedRoomNames(NewReservedNames(DefaultReservedRoomNames))
+
+	ctx := context.Background()
+	for _, name := range []string{"System", "ADM1N", "Announcements"} {
+		if _, err := chatService.CreateChatroom(ctx, name, "user1"); !errors.Is(err, domain.ErrNameReserved) {
+			t.Errorf("CreateChatroom(%q) error = %v, want ErrNameReserved", name, err)
+		}
+	}
+	if _, err := chatService.CreateChatroom(ctx, "System Design", "user1"); err != nil {
+		t.Errorf("CreateChatroom() error = %v", err)
+	}
+}
+
 func TestChatService_JoinChatroom_Success(t *testing.T) {
 	messageRepo := &mockMessageRepository{}
 	chatroomRepo := &mockChatroomRepository{
@@ -437,6 +666,40 @@ func TestChatService_JoinChatroom_Success(t *testing.T) {
 	}
 }
 
+func TestChatService_AddMembers(t *testing.T) {
+	chatroomRepo := &mockChatroomRepository{
+		chatrooms: map[string]*domain.Chatroom{
+			"chatroom1": {ID: "chatroom1", Name: "General", CreatedBy: "owner"},
+		},
+		members: map[string]map[string]bool{
+			"chatroom1": {"owner": true, "user1": true},
+		},
+	}
+	chatService := NewChatService(&mockMessageRepository{}, chatroomRepo)
+	ctx := context.Background()
+
+	results, err := chatService.AddMembers(ctx, "chatroom1", "owner", []string{"user1", "user2", " user2 ", ""})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected duplicates and blanks to be dropped, got %d results", len(results))
+	}
+	if results[0].Status != domain.MemberAlreadyMember || results[1].Status != domain.MemberAdded {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+
+	if _, err := chatService.AddMembers(ctx, "chatroom1", "user1", []string{"user3"}); err != domain.ErrNotOwner {
+		t.Errorf("Expected ErrNotOwner for a non-owner, got: %v", err)
+	}
+	if _, err := chatService.AddMembers(ctx, "chatroom1", "owner", []string{" "}); err != domain.ErrInvalidInput {
+		t.Errorf("Expected ErrInvalidInput without identifiers, got: %v", err)
+	}
+	if _, err := chatService.AddMembers(ctx, "missing", "owner", []string{"user3"}); err == nil {
+		t.Error("Expected an error for an unknown chatroom")
+	}
+}
+
 func TestChatService_JoinChatroom_ChatroomNotFound(t *testing.T) {
 	messageRepo := &mockMessageRepository{}
 	chatroomRepo := &mockChatroomRepository{
@@ -711,3 +974,745 @@ func BenchmarkGetMessages(b *testing.B) {
 		chatService.GetMessages(ctx, "chatroom1", 50)
 	}
 }
+
+func TestChatService_ChatroomSettings(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["chatroom1"] = &domain.Chatroom{ID: "chatroom1", Name: "General", CreatedBy: "owner"}
+	chatroomRepo.Members["chatroom1"] = map[string]bool{"owner": true, "user1": true}
+	chatService := NewChatService(&mockMessageRepository{}, chatroomRepo)
+	ctx := context.Background()
+
+	err := chatService.UpdateChatroomSettings(ctx, "chatroom1", "owner", &domain.ChatroomSettings{WelcomeMessage: "  Be nice!  "})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	settings, err := chatService.GetChatroomSettings(ctx, "chatroom1", "user1")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if settings.WelcomeMessage != "Be nice!" {
+		t.Errorf("Expected trimmed welcome message, got %q", settings.WelcomeMessage)
+	}
+
+	if _, err := chatService.GetChatroomSettings(ctx, "chatroom1", "outsider"); err != domain.ErrNotMember {
+		t.Errorf("Expected ErrNotMember for a non-member, got: %v", err)
+	}
+	if err := chatService.UpdateChatroomSettings(ctx, "chatroom1", "user1", &domain.ChatroomSettings{}); err != domain.ErrNotOwner {
+		t.Errorf("Expected ErrNotOwner for a non-owner, got: %v", err)
+	}
+	tooLong := &domain.ChatroomSettings{WelcomeMessage: strings.Repeat("é", MaxWelcomeMessageLength+1)}
+	if err := chatService.UpdateChatroomSettings(ctx, "chatroom1", "owner", tooLong); err != domain.ErrInvalidInput {
+		t.Errorf("Expected ErrInvalidInput for a long welcome message, got: %v", err)
+	}
+
+	message, err := chatService.ClaimWelcomeMessage(ctx, "chatroom1", "user1")
+	if err != nil || message != "Be nice!" {
+		t.Errorf("Expected the welcome message on first connection, got %q, %v", message, err)
+	}
+	message, err = chatService.ClaimWelcomeMessage(ctx, "chatroom1", "user1")
+	if err != nil || message != "" {
+		t.Errorf("Expected no welcome message afterwards, got %q, %v", message, err)
+	}
+}
+
+func TestChatService_GuestAccess(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["chatroom1"] = &domain.Chatroom{ID: "chatroom1", Name: "General", CreatedBy: "owner"}
+	chatroomRepo.Chatrooms["secret"] = &domain.Chatroom{ID: "secret", Name: "Secret", CreatedBy: "owner", Encrypted: true}
+	chatService := NewChatService(&mockMessageRepository{}, chatroomRepo)
+	ctx := context.Background()
+
+	if _, err := chatService.GuestAccess(ctx, "chatroom1"); !errors.Is(err, domain.ErrGuestNotAllowed) {
+		t.Errorf("Expected ErrGuestNotAllowed for a private chatroom, got: %v", err)
+	}
+
+	if err := chatService.UpdateChatroomSettings(ctx, "chatroom1", "owner", &domain.ChatroomSettings{Public: true}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	canPost, err := chatService.GuestAccess(ctx, "chatroom1")
+	if err != nil || canPost {
+		t.Errorf("Expected read-only guest access, got %v, %v", canPost, err)
+	}
+
+	if err := chatService.UpdateChatroomSettings(ctx, "chatroom1", "owner", &domain.ChatroomSettings{Public: true, GuestsCanPost: true}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	canPost, err = chatService.GuestAccess(ctx, "chatroom1")
+	if err != nil || !canPost {
+		t.Errorf("Expected guests to be able to post, got %v, %v", canPost, err)
+	}
+
+	if err := chatService.UpdateChatroomSettings(ctx, "secret", "owner", &domain.ChatroomSettings{Public: true}); !errors.Is(err, domain.ErrPublicEncrypted) {
+		t.Errorf("Expected ErrPublicEncrypted for an encrypted chatroom, got: %v", err)
+	}
+}
+
+type stubActivity struct {
+	online map[string]bool
+	idle   map[string]bool
+}
+
+func (a stubActivity) IsUserOnline(userID string) bool { return a.online[userID] }
+func (a stubActivity) IsUserIdle(userID string) bool   { return a.idle[userID] }
+
+func TestChatService_ListMembers(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Members["room"] = map[string]bool{"alice": true, "bob": true}
+	chatroomRepo.ListMembersFunc = func(ctx context.Context, chatroomID string) ([]*domain.Member, error) {
+		return []*domain.Member{
+			{UserID: "alice", Username: "alice", Status: domain.UserStatus{Status: domain.StatusActive, Text: "Lunch"}, ShowOnlineStatus: true},
+			{UserID: "bob", Username: "bob", Status: domain.UserStatus{Status: domain.StatusDND}, ShowOnlineStatus: false},
+			{UserID: "carol", Username: "carol", Status: domain.UserStatus{Status: domain.StatusActive}, ShowOnlineStatus: true},
+		}, nil
+	}
+	chatService := NewChatService(&mockMessageRepository{}, chatroomRepo)
+	chatService.SetPresence(stubActivity{
+		online: map[string]bool{"alice": true, "bob": true},
+		idle:   map[string]bool{"alice": true},
+	})
+	ctx := context.Background()
+
+	members, err := chatService.ListMembers(ctx, "room", "alice")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, *members[0], MemberView{ID: "alice", Username: "alice", Status: domain.StatusAway, StatusText: "Lunch"})
+	// bob hides his online status from others
+	testutil.AssertEqual(t, *members[1], MemberView{ID: "bob", Username: "bob"})
+	testutil.AssertEqual(t, *members[2], MemberView{ID: "carol", Username: "carol", Status: domain.StatusOffline})
+
+	members, err = chatService.ListMembers(ctx, "room", "bob")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, members[1].Status, domain.StatusDND)
+
+	_, err = chatService.ListMembers(ctx, "room", "carol")
+	testutil.AssertErrorIs(t, err, domain.ErrNotMember)
+}
+
+func TestChatService_SendMessage_DeactivatedUser(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["room"] = &domain.Chatroom{ID: "room", Name: "General"}
+	chatroomRepo.Members["room"] = map[string]bool{"user1": true}
+	deactivatedAt := time.Now()
+	userRepo := testutil.NewMockUserRepository()
+	userRepo.Users["user1"] = &domain.User{ID: "user1", Role: domain.RoleUser, DeactivatedAt: &deactivatedAt}
+	messageRepo := &mockMessageRepository{}
+	chatService := NewChatService(messageRepo, chatroomRepo)
+	chatService.SetUserRepository(userRepo)
+
+	err := chatService.SendMessage(context.Background(), &domain.Message{ChatroomID: "room", UserID: "user1", Content: "still here"})
+	testutil.AssertErrorIs(t, err, domain.ErrUserDeactivated)
+	testutil.AssertLen(t, messageRepo.messages, 0)
+}
+
+func TestChatService_ReadOnlyChatroom(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["news"] = &domain.Chatroom{ID: "news", Name: "News", CreatedBy: "owner"}
+	chatroomRepo.Members["news"] = map[string]bool{"owner": true, "mod": true, "user1": true}
+	chatroomRepo.Settings = map[string]*domain.ChatroomSettings{"news": {ReadOnly: true, Version: 2}}
+	userRepo := testutil.NewMockUserRepository()
+	userRepo.Users["mod"] = &domain.User{ID: "mod", Role: domain.RoleModerator}
+	userRepo.Users["user1"] = &domain.User{ID: "user1", Role: domain.RoleUser}
+	messageRepo := &mockMessageRepository{}
+	chatService := NewChatService(messageRepo, chatroomRepo)
+	chatService.SetUserRepository(userRepo)
+	ctx := context.Background()
+
+	tests := []struct {
+		userID  string
+		wantErr error
+	}{
+		{"owner", nil},
+		{"mod", nil},
+		{"user1", domain.ErrReadOnly},
+	}
+	for _, tt := range tests {
+		err := chatService.SendMessage(ctx, &domain.Message{ChatroomID: "news", UserID: tt.userID, Content: "hello"})
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tt.userID, tt.wantErr, err)
+		}
+	}
+
+	// Bots answer commands of those allowed to send them
+	if err := chatService.SendMessage(ctx, &domain.Message{ChatroomID: "news", Username: "StockBot", Content: "quote", IsBot: true}); err != nil {
+		t.Errorf("Expected bots to post, got: %v", err)
+	}
+}
+
+func TestChatService_MarkRead(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Members["chatroom1"] = map[string]bool{"user1": true}
+	chatService := NewChatService(&mockMessageRepository{}, chatroomRepo)
+	ctx := context.Background()
+	messageID := "4f6c1a52-9a1e-4c1d-8f0b-2d7c7e0f8a11"
+
+	if err := chatService.MarkRead(ctx, "chatroom1", "user1", messageID); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got := chatroomRepo.ReadUpTo["chatroom1"]["user1"]; got != messageID {
+		t.Errorf("Expected read marker %q, got %q", messageID, got)
+	}
+	if err := chatService.MarkRead(ctx, "chatroom1", "user1", ""); err != nil {
+		t.Errorf("Expected marking the latest message read to succeed, got: %v", err)
+	}
+	if err := chatService.MarkRead(ctx, "chatroom1", "user1", "not-a-uuid"); err != domain.ErrInvalidInput {
+		t.Errorf("Expected ErrInvalidInput for a malformed message ID, got: %v", err)
+	}
+	if err := chatService.MarkRead(ctx, "chatroom1", "outsider", ""); err != domain.ErrNotMember {
+		t.Errorf("Expected ErrNotMember for a non-member, got: %v", err)
+	}
+}
+
+func TestChatService_DeleteAndRestoreChatroom(t *testing.T) {
+	const roomID = "5b0c7c1e-2f4a-4d8e-9a61-0c3f2b7d9e14"
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms[roomID] = &domain.Chatroom{ID: roomID, Name: "General", CreatedBy: "owner"}
+	chatService := NewChatService(&mockMessageRepository{}, chatroomRepo)
+	ctx := context.Background()
+
+	if err := chatService.DeleteChatroom(ctx, roomID, "user1"); err != domain.ErrNotOwner {
+		t.Errorf("Expected ErrNotOwner for a non-owner, got: %v", err)
+	}
+	if err := chatService.DeleteChatroom(ctx, roomID, "owner"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, ok := chatroomRepo.Chatrooms[roomID]; ok {
+		t.Error("Expected the chatroom to be gone after deletion")
+	}
+	if err := chatService.DeleteChatroom(ctx, roomID, "owner"); err != domain.ErrChatroomNotFound {
+		t.Errorf("Expected ErrChatroomNotFound deleting twice, got: %v", err)
+	}
+
+	if err := chatService.RestoreChatroom(ctx, "not-a-uuid", "admin"); err != domain.ErrChatroomNotFound {
+		t.Errorf("Expected ErrChatroomNotFound for a malformed ID, got: %v", err)
+	}
+	if err := chatService.RestoreChatroom(ctx, roomID, "admin"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, ok := chatroomRepo.Chatrooms[roomID]; !ok {
+		t.Error("Expected the chatroom to be back after restoring it")
+	}
+	if err := chatService.RestoreChatroom(ctx, roomID, "admin"); err != domain.ErrChatroomNotFound {
+		t.Errorf("Expected ErrChatroomNotFound restoring a chatroom that is not deleted, got: %v", err)
+	}
+}
+
+func TestChatService_EncryptedChatroom(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	messageRepo := testutil.NewMockMessageRepository()
+	chatService := NewChatService(messageRepo, chatroomRepo)
+	ctx := context.Background()
+
+	room, err := chatService.CreateEncryptedChatroom(ctx, "Secret", "owner")
+	testutil.AssertNoError(t, err)
+	testutil.AssertTrue(t, room.Encrypted, "chatroom should be encrypted")
+
+	// Ciphertext is stored as is and may exceed the plaintext limit
+	ciphertext := ":smile:" + strings.Repeat("A", 1500)
+	msg := &domain.Message{ChatroomID: room.ID, UserID: "owner", Content: ciphertext, Encrypted: true}
+	testutil.AssertNoError(t, chatService.SendMessage(ctx, msg))
+	testutil.AssertEqual(t, msg.Content, ciphertext)
+
+	msg = &domain.Message{ChatroomID: room.ID, UserID: "owner", Content: strings.Repeat("A", MaxCiphertextLength+1), Encrypted: true}
+	testutil.AssertErrorIs(t, chatService.SendMessage(ctx, msg), domain.ErrInvalidInput)
+
+	testutil.AssertErrorIs(t, chatService.SetMemberKey(ctx, room.ID, "owner", ""), domain.ErrInvalidInput)
+	testutil.AssertErrorIs(t, chatService.SetMemberKey(ctx, room.ID, "outsider", "pk"), domain.ErrNotMember)
+	testutil.AssertNoError(t, chatService.SetMemberKey(ctx, room.ID, "owner", "pk-owner"))
+
+	keys, err := chatService.MemberKeys(ctx, room.ID, "owner")
+	testutil.AssertNoError(t, err)
+	testutil.AssertLen(t, keys, 1)
+	testutil.AssertEqual(t, keys[0].KeyData, "pk-owner")
+
+	_, err = chatService.MemberKeys(ctx, room.ID, "outsider")
+	testutil.AssertErrorIs(t, err, domain.ErrNotMember)
+
+	plain, err := chatService.CreateChatroom(ctx, "General", "owner")
+	testutil.AssertNoError(t, err)
+	testutil.AssertErrorIs(t, chatService.SetMemberKey(ctx, plain.ID, "owner", "pk-owner"), domain.ErrNotEncrypted)
+	_, err = chatService.MemberKeys(ctx, plain.ID, "owner")
+	testutil.AssertErrorIs(t, err, domain.ErrNotEncrypted)
+}
+
+type recordingPublisher []domain.Event
+
+func (p *recordingPublisher) Publish(ctx context.Context, event domain.Event) {
+	*p = append(*p, event)
+}
+
+func TestChatService_SendMessage_PublishesMessageSent(t *testing.T) {
+	chatroomRepo := &mockChatroomRepository{
+		members: map[string]map[string]bool{"chatroom1": {"user1": true}},
+	}
+	chatService := NewChatService(&mockMessageRepository{}, chatroomRepo)
+	events := &recordingPublisher{}
+	chatService.SetEventPublisher(events)
+
+	msg := &domain.Message{ChatroomID: "chatroom1", UserID: "user1", Content: "see https://example.com"}
+	if err := chatService.SendMessage(context.Background(), msg); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(*events) != 1 || (*events)[0] != (domain.MessageSent{Message: msg}) {
+		t.Errorf("Expected a MessageSent event for the stored message, got %v", *events)
+	}
+
+	rejected := &domain.Message{ChatroomID: "chatroom1", UserID: "outsider", Content: "https://example.com"}
+	if err := chatService.SendMessage(context.Background(), rejected); err != domain.ErrNotMember {
+		t.Fatalf("Expected ErrNotMember, got: %v", err)
+	}
+	if len(*events) != 1 {
+		t.Error("Expected rejected messages not to be published")
+	}
+}
+
+func TestChatService_PublishesRoomEvents(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatService := NewChatService(&mockMessageRepository{}, chatroomRepo)
+	events := &recordingPublisher{}
+	chatService.SetEventPublisher(events)
+	ctx := context.Background()
+
+	chatroom, err := chatService.CreateChatroom(ctx, "general", "owner")
+	testutil.AssertNoError(t, err)
+	testutil.AssertNoError(t, chatService.JoinChatroom(ctx, chatroom.ID, "user1"))
+	testutil.AssertNoError(t, chatService.JoinChatroom(ctx, chatroom.ID, "user1"))
+	_, err = chatService.AddMembers(ctx, chatroom.ID, "owner", []string{"user1", "user2"})
+	testutil.AssertNoError(t, err)
+
+	expected := []domain.Event{
+		domain.RoomCreated{Chatroom: chatroom},
+		domain.UserJoinedRoom{ChatroomID: chatroom.ID, UserID: "user1"},
+		domain.UserJoinedRoom{ChatroomID: chatroom.ID, UserID: "user2"},
+	}
+	testutil.AssertLen(t, *events, len(expected))
+	for i, event := range expected {
+		testutil.AssertEqual(t, (*events)[i], event)
+	}
+}
+
+func TestChatService_SendMessage_ExpandsEmoji(t *testing.T) {
+	chatroomRepo := &mockChatroomRepository{
+		members: map[string]map[string]bool{"chatroom1": {"user1": true}},
+	}
+	chatService := NewChatService(&mockMessageRepository{}, chatroomRepo)
+
+	msg := &domain.Message{ChatroomID: "chatroom1", UserID: "user1", Content: "shipped :rocket: :unknown:"}
+	if err := chatService.SendMessage(context.Background(), msg); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if msg.Content != "shipped 🚀 :unknown:" {
+		t.Errorf("Expected shortcodes to be expanded, got %q", msg.Content)
+	}
+
+	bot := &domain.Message{ChatroomID: "chatroom1", UserID: "bot", Content: "AAPL :rocket:", IsBot: true}
+	if err := chatService.SendMessage(context.Background(), bot); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if bot.Content != "AAPL :rocket:" {
+		t.Errorf("Expected bot messages to be stored as sent, got %q", bot.Content)
+	}
+}
+
+func TestChatService_ListChatroomsPaginated(t *testing.T) {
+	var got domain.ChatroomListOptions
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.ListPaginatedFunc = func(ctx context.Context, opts domain.ChatroomListOptions) ([]*domain.Chatroom, string, error) {
+		got = opts
+		return nil, "", nil
+	}
+	chatService := NewChatService(testutil.NewMockMessageRepository(), chatroomRepo)
+	ctx := context.Background()
+
+	if _, _, err := chatService.ListChatroomsPaginated(ctx, domain.ChatroomListOptions{Limit: 10, Name: "  general "}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got.Sort != domain.ChatroomSortNewest || got.Name != "general" {
+		t.Errorf("Expected the newest sort and a trimmed name, got %+v", got)
+	}
+
+	tests := []domain.ChatroomListOptions{
+		{Sort: "popular"},
+		{Name: strings.Repeat("a", 101)},
+	}
+	for _, opts := range tests {
+		if _, _, err := chatService.ListChatroomsPaginated(ctx, opts); err != domain.ErrInvalidInput {
+			t.Errorf("Expected ErrInvalidInput for %+v, got: %v", opts, err)
+		}
+	}
+}
+
+func TestChatService_ForwardMessage(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["general"] = &domain.Chatroom{ID: "general", Name: "General", CreatedBy: "owner"}
+	chatroomRepo.Chatrooms["random"] = &domain.Chatroom{ID: "random", Name: "Random", CreatedBy: "owner"}
+	chatroomRepo.Chatrooms["news"] = &domain.Chatroom{ID: "news", Name: "News", CreatedBy: "owner"}
+	chatroomRepo.Chatrooms["secret"] = &domain.Chatroom{ID: "secret", Name: "Secret", CreatedBy: "owner", Encrypted: true}
+	chatroomRepo.Members["general"] = map[string]bool{"alice": true, "bob": true}
+	chatroomRepo.Members["random"] = map[string]bool{"alice": true}
+	chatroomRepo.Members["news"] = map[string]bool{"alice": true}
+	chatroomRepo.Members["secret"] = map[string]bool{"alice": true}
+	chatroomRepo.Settings = map[string]*domain.ChatroomSettings{"news": {ReadOnly: true}}
+	userRepo := testutil.NewMockUserRepository()
+	userRepo.Users["alice"] = &domain.User{ID: "alice", Username: "alice", Role: domain.RoleUser}
+	messageRepo := testutil.NewMockMessageRepository()
+	messageRepo.Messages = []*domain.Message{
+		{ID: "original", ChatroomID: "general", UserID: "bob", Username: "bob", Content: "ship it"},
+	}
+	chatService := NewChatService(messageRepo, chatroomRepo)
+	chatService.SetUserRepository(userRepo)
+	ctx := context.Background()
+
+	forwarded, err := chatService.ForwardMessage(ctx, "original", "alice", []string{"random", "random"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(forwarded) != 1 {
+		t.Fatalf("Expected one forwarded message, got %d", len(forwarded))
+	}
+	msg := forwarded[0]
+	if msg.ChatroomID != "random" || msg.UserID != "alice" || msg.Username != "alice" || msg.Content != "ship it" {
+		t.Errorf("Unexpected forwarded message: %+v", msg)
+	}
+	want := domain.ForwardedFrom{MessageID: "original", ChatroomID: "general", UserID: "bob", Username: "bob"}
+	if msg.ForwardedFrom == nil || *msg.ForwardedFrom != want {
+		t.Errorf("Expected attribution %+v, got %+v", want, msg.ForwardedFrom)
+	}
+
+	// Forwarding a forward keeps the original attribution
+	msg.ID = "forward"
+	again, err := chatService.ForwardMessage(ctx, "forward", "alice", []string{"general"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if *again[0].ForwardedFrom != want {
+		t.Errorf("Expected attribution %+v, got %+v", want, again[0].ForwardedFrom)
+	}
+
+	tests := []struct {
+		name      string
+		userID    string
+		messageID string
+		targets   []string
+		wantErr   error
+	}{
+		{"no targets", "alice", "original", nil, domain.ErrInvalidInput},
+		{"same chatroom", "alice", "original", []string{"general"}, domain.ErrInvalidInput},
+		{"encrypted target", "alice", "original", []string{"secret"}, domain.ErrInvalidInput},
+		{"unknown target", "alice", "original", []string{"missing"}, domain.ErrChatroomNotFound},
+		{"not a member of the target", "bob", "original", []string{"random"}, domain.ErrNotMember},
+		{"read-only target", "alice", "original", []string{"random", "news"}, domain.ErrReadOnly},
+		{"not a member of the source", "carol", "original", []string{"random"}, domain.ErrMessageNotFound},
+		{"unknown message", "alice", "missing", []string{"random"}, domain.ErrMessageNotFound},
+	}
+	for _, tt := range tests {
+		count := len(messageRepo.Messages)
+		_, err := chatService.ForwardMessage(ctx, tt.messageID, tt.userID, tt.targets)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.wantErr, err)
+		}
+		if len(messageRepo.Messages) != count {
+			t.Errorf("%s: expected nothing to be posted", tt.name)
+		}
+	}
+}
+
+func TestChatService_QuoteReply(t *testing.T) {
+	const quotedID = "7d1e6c0a-3b52-4f0e-9c4d-1a2b3c4d5e6f"
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["general"] = &domain.Chatroom{ID: "general", Name: "General", CreatedBy: "owner"}
+	chatroomRepo.Members["general"] = map[string]bool{"alice": true}
+	messageRepo := testutil.NewMockMessageRepository()
+	createdAt := time.Now().Add(-time.Hour)
+	messageRepo.Messages = []*domain.Message{
+		{ID: quotedID, ChatroomID: "general", UserID: "bob", Username: "bob", Content: "lunch?", CreatedAt: createdAt},
+	}
+	chatService := NewChatService(messageRepo, chatroomRepo)
+	ctx := context.Background()
+
+	for _, quoted := range []string{"not-a-uuid", "0f9e8d7c-6b5a-4e3d-8c2b-1a0f9e8d7c6b"} {
+		err := chatService.SendMessage(ctx, &domain.Message{ChatroomID: "general", UserID: "alice", Content: "sure", QuotedMessageID: quoted})
+		if !errors.Is(err, domain.ErrInvalidQuote) {
+			t.Errorf("%s: expected ErrInvalidQuote, got %v", quoted, err)
+		}
+	}
+
+	reply := &domain.Message{ChatroomID: "general", UserID: "alice", Content: "sure", QuotedMessageID: quotedID}
+	if err := chatService.SendMessage(ctx, reply); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	want := domain.Quote{MessageID: quotedID, UserID: "bob", Username: "bob", Content: "lunch?", CreatedAt: createdAt}
+	if reply.Quote == nil || *reply.Quote != want {
+		t.Errorf("Expected quote %+v, got %+v", want, reply.Quote)
+	}
+}
+
+func TestChatService_Markdown(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["general"] = &domain.Chatroom{ID: "general", Name: "General", CreatedBy: "owner"}
+	chatroomRepo.Members["general"] = map[string]bool{"alice": true}
+	messageRepo := testutil.NewMockMessageRepository()
+	chatService := NewChatService(messageRepo, chatroomRepo)
+	ctx := context.Background()
+
+	plain := &domain.Message{ChatroomID: "general", UserID: "alice", Content: "**plain** <b>"}
+	if err := chatService.SendMessage(ctx, plain); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if plain.HTML != "" {
+		t.Errorf("Expected no HTML without markdown, got %q", plain.HTML)
+	}
+
+	chatroomRepo.Settings["general"] = &domain.ChatroomSettings{Markdown: true, Version: 2}
+	rich := &domain.Message{ChatroomID: "general", UserID: "alice", Content: "**bold** [docs](https://example.com)"}
+	if err := chatService.SendMessage(ctx, rich); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	want := `<strong>bold</strong> <a href="https://example.com" rel="nofollow noopener noreferrer">docs</a>`
+	if rich.HTML != want {
+		t.Errorf("Expected HTML %q, got %q", want, rich.HTML)
+	}
+
+	// History follows the chatroom's current setting
+	messages, err := chatService.GetMessages(ctx, "general", 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(messages) != 2 || messages[0].HTML != "<strong>plain</strong> &lt;b&gt;" {
+		t.Errorf("Expected history rendered, got %+v", messages)
+	}
+	if messages[0].Content != "**plain** <b>" {
+		t.Errorf("Expected raw content kept, got %q", messages[0].Content)
+	}
+}
+
+func TestChatService_CreateEvent(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["general"] = &domain.Chatroom{ID: "general", Name: "General", CreatedBy: "owner"}
+	chatroomRepo.Chatrooms["secret"] = &domain.Chatroom{ID: "secret", Name: "Secret", CreatedBy: "owner", Encrypted: true}
+	chatroomRepo.Members["general"] = map[string]bool{"alice": true}
+	chatroomRepo.Members["secret"] = map[string]bool{"alice": true}
+	userRepo := testutil.NewMockUserRepository()
+	userRepo.Users["alice"] = &domain.User{ID: "alice", Username: "alice", Role: domain.RoleUser}
+	messageRepo := testutil.NewMockMessageRepository()
+	chatService := NewChatService(messageRepo, chatroomRepo)
+	chatService.SetUserRepository(userRepo)
+	ctx := context.Background()
+
+	startsAt := time.Date(2026, 2, 3, 12, 0, 0, 0, time.FixedZone("UTC-5", -5*3600))
+	endsAt := startsAt.Add(-time.Hour)
+	invalid := []*domain.CalendarEvent{
+		{Title: "  ", StartsAt: startsAt},
+		{Title: "Lunch"},
+		{Title: "Lunch", StartsAt: startsAt, EndsAt: &endsAt},
+		{Title: strings.Repeat("a", domain.MaxEventTitleLength+1), StartsAt: startsAt},
+	}
+	for _, event := range invalid {
+		if _, err := chatService.CreateEvent(ctx, "general", "alice", event); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Errorf("%+v: expected ErrInvalidInput, got %v", event, err)
+		}
+	}
+	if _, err := chatService.CreateEvent(ctx, "secret", "alice", &domain.CalendarEvent{Title: "Lunch", StartsAt: startsAt}); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput in an encrypted chatroom, got %v", err)
+	}
+	if _, err := chatService.CreateEvent(ctx, "general", "bob", &domain.CalendarEvent{Title: "Lunch", StartsAt: startsAt}); !errors.Is(err, domain.ErrNotMember) {
+		t.Errorf("Expected ErrNotMember, got %v", err)
+	}
+
+	msg, err := chatService.CreateEvent(ctx, "general", "alice", &domain.CalendarEvent{Title: " Team lunch ", StartsAt: startsAt, Location: "Cafeteria"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if msg.Content != "Team lunch" || msg.Username != "alice" || msg.Event == nil {
+		t.Fatalf("Unexpected event message: %+v", msg)
+	}
+	if msg.Event.StartsAt.Location() != time.UTC || !msg.Event.StartsAt.Equal(startsAt) {
+		t.Errorf("Expected the start time in UTC, got %v", msg.Event.StartsAt)
+	}
+	if len(messageRepo.Messages) != 1 {
+		t.Errorf("Expected the event message stored, got %d messages", len(messageRepo.Messages))
+	}
+}
+
+func TestChatService_RSVP(t *testing.T) {
+	const (
+		eventID = "3f2b1c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d"
+		plainID = "9a8b7c6d-5e4f-4a3b-8c1d-0e9f8a7b6c5d"
+	)
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["general"] = &domain.Chatroom{ID: "general", Name: "General", CreatedBy: "owner"}
+	chatroomRepo.Members["general"] = map[string]bool{"alice": true, "bob": true}
+	// Members answer in read-only chatrooms too
+	chatroomRepo.Settings["general"] = &domain.ChatroomSettings{ReadOnly: true}
+	userRepo := testutil.NewMockUserRepository()
+	userRepo.Users["bob"] = &domain.User{ID: "bob", Username: "bob", Role: domain.RoleUser}
+	messageRepo := testutil.NewMockMessageRepository()
+	messageRepo.Messages = []*domain.Message{
+		{ID: eventID, ChatroomID: "general", UserID: "owner", Content: "Lunch", Event: &domain.CalendarEvent{Title: "Lunch", StartsAt: time.Now()}},
+		{ID: plainID, ChatroomID: "general", UserID: "owner", Content: "hello"},
+	}
+	rsvpRepo := testutil.NewMockRSVPRepository()
+	chatService := NewChatService(messageRepo, chatroomRepo)
+	chatService.SetUserRepository(userRepo)
+	chatService.SetRSVPRepository(rsvpRepo)
+	ctx := context.Background()
+
+	if _, err := chatService.RSVP(ctx, eventID, "bob", "sure"); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+	if _, err := chatService.RSVP(ctx, plainID, "bob", domain.RSVPGoing); !errors.Is(err, domain.ErrNotAnEvent) {
+		t.Errorf("Expected ErrNotAnEvent, got %v", err)
+	}
+	if _, err := chatService.RSVP(ctx, eventID, "mallory", domain.RSVPGoing); !errors.Is(err, domain.ErrMessageNotFound) {
+		t.Errorf("Expected ErrMessageNotFound for a non-member, got %v", err)
+	}
+
+	rsvp, err := chatService.RSVP(ctx, eventID, "bob", domain.RSVPMaybe)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if rsvp.ChatroomID != "general" || rsvp.Username != "bob" || rsvp.Response != domain.RSVPMaybe {
+		t.Errorf("Unexpected RSVP: %+v", rsvp)
+	}
+	if _, err := chatService.RSVP(ctx, eventID, "bob", domain.RSVPGoing); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := chatService.RSVP(ctx, eventID, "alice", domain.RSVPDeclined); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	rsvps, err := chatService.ListRSVPs(ctx, eventID, "alice")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(rsvps) != 2 || rsvps[0].UserID != "bob" || rsvps[0].Response != domain.RSVPGoing || rsvps[1].UserID != "alice" {
+		t.Errorf("Unexpected RSVPs: %+v", rsvps)
+	}
+	if _, err := chatService.ListRSVPs(ctx, eventID, "mallory"); !errors.Is(err, domain.ErrMessageNotFound) {
+		t.Errorf("Expected ErrMessageNotFound for a non-member, got %v", err)
+	}
+}
+
+func TestChatService_ChatroomTags(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["general"] = &domain.Chatroom{ID: "general", Name: "General", CreatedBy: "owner"}
+	chatroomRepo.Chatrooms["random"] = &domain.Chatroom{ID: "random", Name: "Random", CreatedBy: "owner"}
+	chatService := NewChatService(testutil.NewMockMessageRepository(), chatroomRepo)
+	ctx := context.Background()
+
+	tags, err := chatService.SetChatroomTags(ctx, "general", "owner", []string{" Go ", "backend", "go", "Café"})
+	if err != nil {
+		t.Fatalf("SetChatroomTags failed: %v", err)
+	}
+	if !reflect.DeepEqual(tags, []string{"backend", "café", "go"}) {
+		t.Errorf("Expected normalized, sorted and deduplicated tags, got %v", tags)
+	}
+	if _, err := chatService.SetChatroomTags(ctx, "random", "owner", []string{"go"}); err != nil {
+		t.Fatalf("SetChatroomTags failed: %v", err)
+	}
+
+	if _, err := chatService.SetChatroomTags(ctx, "general", "alice", []string{"go"}); !errors.Is(err, domain.ErrNotOwner) {
+		t.Errorf("Expected ErrNotOwner, got %v", err)
+	}
+	tooMany := make([]string, domain.MaxChatroomTags+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("tag-%d", i)
+	}
+	for _, invalid := range [][]string{{"two words"}, {"c++"}, {strings.Repeat("a", domain.MaxTagLength+1)}, tooMany} {
+		if _, err := chatService.SetChatroomTags(ctx, "general", "owner", invalid); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Errorf("%v: expected ErrInvalidInput, got %v", invalid, err)
+		}
+	}
+
+	rooms, _, err := chatService.ListChatroomsPaginated(ctx, domain.ChatroomListOptions{Limit: 10, Tags: []string{"GO", "backend"}})
+	if err != nil {
+		t.Fatalf("ListChatroomsPaginated failed: %v", err)
+	}
+	if len(rooms) != 1 || rooms[0].ID != "general" {
+		t.Errorf("Expected only the room carrying both tags, got %v", rooms)
+	}
+	if _, _, err := chatService.ListChatroomsPaginated(ctx, domain.ChatroomListOptions{Tags: []string{"a b"}}); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for an invalid tag filter, got %v", err)
+	}
+
+	counts, err := chatService.ListTags(ctx)
+	if err != nil {
+		t.Fatalf("ListTags failed: %v", err)
+	}
+	if len(counts) != 3 || *counts[0] != (domain.TagCount{Tag: "go", Chatrooms: 2}) {
+		t.Errorf("Expected go first with 2 chatrooms, got %v", counts)
+	}
+
+	if err := chatService.RemoveChatroomTag(ctx, "general", "owner", "GO"); err != nil {
+		t.Fatalf("RemoveChatroomTag failed: %v", err)
+	}
+	if !reflect.DeepEqual(chatroomRepo.Chatrooms["general"].Tags, []string{"backend", "café"}) {
+		t.Errorf("Expected go removed, got %v", chatroomRepo.Chatrooms["general"].Tags)
+	}
+	if err := chatService.RemoveChatroomTag(ctx, "general", "alice", "backend"); !errors.Is(err, domain.ErrNotOwner) {
+		t.Errorf("Expected ErrNotOwner, got %v", err)
+	}
+}
+
+func TestChatService_StarChatroom(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	for _, id := range []string{"general", "random", "news"} {
+		chatroomRepo.Chatrooms[id] = &domain.Chatroom{ID: id, Name: id, CreatedBy: "owner"}
+	}
+	chatService := NewChatService(testutil.NewMockMessageRepository(), chatroomRepo)
+	ctx := context.Background()
+
+	for i, id := range []string{"general", "random"} {
+		position, err := chatService.StarChatroom(ctx, id, "alice", nil)
+		if err != nil {
+			t.Fatalf("StarChatroom failed: %v", err)
+		}
+		if position != i {
+			t.Errorf("Expected %s starred at position %d, got %d", id, i, position)
+		}
+	}
+	// Starring again keeps the position, unless one is given
+	if position, _ := chatService.StarChatroom(ctx, "general", "alice", nil); position != 0 {
+		t.Errorf("Expected general to stay at position 0, got %d", position)
+	}
+	first := 0
+	if position, _ := chatService.StarChatroom(ctx, "random", "alice", &first); position != 0 {
+		t.Errorf("Expected random moved to position 0, got %d", position)
+	}
+
+	negative := -1
+	if _, err := chatService.StarChatroom(ctx, "news", "alice", &negative); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for a negative position, got %v", err)
+	}
+	if _, err := chatService.StarChatroom(ctx, "missing", "alice", nil); !errors.Is(err, domain.ErrChatroomNotFound) {
+		t.Errorf("Expected ErrChatroomNotFound, got %v", err)
+	}
+
+	rooms, _, err := chatService.ListChatroomsPaginated(ctx, domain.ChatroomListOptions{Limit: 10, UserID: "alice", Starred: true})
+	if err != nil {
+		t.Fatalf("ListChatroomsPaginated failed: %v", err)
+	}
+	if len(rooms) != 2 {
+		t.Fatalf("Expected alice's 2 starred rooms, got %d", len(rooms))
+	}
+	for _, room := range rooms {
+		if !room.Starred || room.StarPosition == nil {
+			t.Errorf("Expected %s reported starred, got %+v", room.ID, room)
+		}
+	}
+	rooms, _, _ = chatService.ListChatroomsPaginated(ctx, domain.ChatroomListOptions{Limit: 10, UserID: "bob"})
+	for _, room := range rooms {
+		if room.Starred {
+			t.Errorf("Expected %s not starred for bob", room.ID)
+		}
+	}
+
+	if err := chatService.UnstarChatroom(ctx, "general", "alice"); err != nil {
+		t.Fatalf("UnstarChatroom failed: %v", err)
+	}
+	if _, starred := chatroomRepo.Stars["alice"]["general"]; starred {
+		t.Error("Expected general unstarred")
+	}
+	if err := chatService.UnstarChatroom(ctx, "missing", "alice"); !errors.Is(err, domain.ErrChatroomNotFound) {
+		t.Errorf("Expected ErrChatroomNotFound, got %v", err)
+	}
+}
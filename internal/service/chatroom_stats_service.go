@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// DefaultChatroomStatsDays is how many days chatroom statistics cover when
+// the caller does not say
+const DefaultChatroomStatsDays = 30
+
+// ChatroomStatsService reports chatroom activity to members. Statistics are
+// read from materialized views the chatroom_stats_refresh job recomputes
+// with Refresh; peak concurrency comes from the hub presence the
+// presence_sample job records on every instance with SamplePresence.
+type ChatroomStatsService struct {
+	statsRepo    domain.ChatroomStatsRepository
+	chatroomRepo domain.ChatroomRepository
+}
+
+func NewChatroomStatsService(statsRepo domain.ChatroomStatsRepository, chatroomRepo domain.ChatroomRepository) *ChatroomStatsService {
+	return &ChatroomStatsService{statsRepo: statsRepo, chatroomRepo: chatroomRepo}
+}
+
+// Stats returns a chatroom's activity over its last days, today included;
+// zero days means DefaultChatroomStatsDays. Only members may read it.
+func (s *ChatroomStatsService) Stats(ctx context.Context, chatroomID, userID string, days int) (_ *domain.ChatroomStats, err error) {
+	defer observe("chatroom_stats", "Stats")(&err)
+
+	if days == 0 {
+		days = DefaultChatroomStatsDays
+	}
+	if days < 1 || days > domain.MaxChatroomStatsDays {
+		return nil, domain.ErrInvalidInput
+	}
+	if _, err := uuid.Parse(chatroomID); err != nil {
+		return nil, domain.ErrChatroomNotFound
+	}
+	if _, err := s.chatroomRepo.GetByID(ctx, chatroomID); err != nil {
+		return nil, err
+	}
+	isMember, err := s.chatroomRepo.IsMember(ctx, chatroomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, domain.ErrNotMember
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	return s.statsRepo.Stats(ctx, chatroomID, today.AddDate(0, 0, 1-days))
+}
+
+// SamplePresence records the connected users per chatroom this instance's
+// hub serves, as the presence_sample job
+func (s *ChatroomStatsService) SamplePresence(ctx context.Context, instanceID string, counts map[string]int) error {
+	maps.DeleteFunc(counts, func(_ string, n int) bool { return n <= 0 })
+	if err := s.statsRepo.RecordPresence(ctx, instanceID, time.Now(), counts); err != nil {
+		return fmt.Errorf("presence sample failed: %w", err)
+	}
+	return nil
+}
+
+// Refresh deletes the presence samples statistics no longer cover and
+// recomputes the statistics, as the chatroom_stats_refresh job
+func (s *ChatroomStatsService) Refresh(ctx context.Context) error {
+	cutoff := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -domain.MaxChatroomStatsDays)
+	deleted, err := s.statsRepo.DeletePresenceBefore(ctx, cutoff)
+	if err != nil {
+		return fmt.Errorf("chatroom stats refresh failed: %w", err)
+	}
+	if err := s.statsRepo.Refresh(ctx); err != nil {
+		return fmt.Errorf("chatroom stats refresh failed: %w", err)
+	}
+	slog.Info("chatroom stats refreshed", slog.Int64("presence_samples_deleted", deleted))
+	return nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+const statsRoomID = "6f1c1a52-4e0b-4c8e-9d3a-0b6f5a1f2c01"
+
+func TestChatroomStatsService_Stats(t *testing.T) {
+	statsRepo := testutil.NewMockChatroomStatsRepository()
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms[statsRoomID] = &domain.Chatroom{ID: statsRoomID, Name: "General"}
+	chatroomRepo.AddMember(context.Background(), statsRoomID, "user-1")
+	statsService := NewChatroomStatsService(statsRepo, chatroomRepo)
+
+	var since time.Time
+	statsRepo.StatsFunc = func(ctx context.Context, chatroomID string, from time.Time) (*domain.ChatroomStats, error) {
+		since = from
+		return &domain.ChatroomStats{ChatroomID: chatroomID, Days: 7}, nil
+	}
+
+	stats, err := statsService.Stats(context.Background(), statsRoomID, "user-1", 7)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.ChatroomID != statsRoomID {
+		t.Errorf("Expected the stats of %s, got %+v", statsRoomID, stats)
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if !since.Equal(today.AddDate(0, 0, -6)) {
+		t.Errorf("Expected 7 days up to today, got since %v", since)
+	}
+
+	if _, err := statsService.Stats(context.Background(), statsRoomID, "user-1", 0); err != nil {
+		t.Errorf("Expected the default period, got %v", err)
+	}
+	if !since.Equal(today.AddDate(0, 0, 1-DefaultChatroomStatsDays)) {
+		t.Errorf("Expected %d days by default, got since %v", DefaultChatroomStatsDays, since)
+	}
+
+	tests := []struct {
+		name       string
+		chatroomID string
+		userID     string
+		days       int
+		expected   error
+	}{
+		{"too_many_days", statsRoomID, "user-1", domain.MaxChatroomStatsDays + 1, domain.ErrInvalidInput},
+		{"negative_days", statsRoomID, "user-1", -1, domain.ErrInvalidInput},
+		{"invalid_id", "room-1", "user-1", 7, domain.ErrChatroomNotFound},
+		{"unknown_room", "0b6f5a1f-2c01-4e0b-9d3a-6f1c1a524c8e", "user-1", 7, domain.ErrChatroomNotFound},
+		{"not_member", statsRoomID, "user-2", 7, domain.ErrNotMember},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := statsService.Stats(context.Background(), tt.chatroomID, tt.userID, tt.days); !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestChatroomStatsService_SamplePresence(t *testing.T) {
+	statsRepo := testutil.NewMockChatroomStatsRepository()
+	statsService := NewChatroomStatsService(statsRepo, testutil.NewMockChatroomRepository())
+
+	err := statsService.SamplePresence(context.Background(), "instance-1", map[string]int{"room-1": 3, "room-2": 0})
+	if err != nil {
+		t.Fatalf("SamplePresence failed: %v", err)
+	}
+	samples := statsRepo.Presence["instance-1"]
+	if len(samples) != 1 || len(samples[0]) != 1 || samples[0]["room-1"] != 3 {
+		t.Errorf("Expected only the occupied chatroom to be recorded, got %v", samples)
+	}
+
+	if err := statsService.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if statsRepo.Refreshes != 1 {
+		t.Errorf("Expected 1 refresh, got %d", statsRepo.Refreshes)
+	}
+}
@@ -7,10 +7,13 @@ import (
 
 var stockCommandRegex = regexp.MustCompile(`^/stock=([a-zA-Z0-9.]{1,20})$`)
 var helloCommandRegex = regexp.MustCompile(`^/hello$`)
+var giphyCommandRegex = regexp.MustCompile(`^/giphy\s+(\S.{0,99})$`)
 
 type Command struct {
 	Type      string
 	StockCode string
+	// Query is the search text of a giphy command
+	Query string
 }
 
 // ParseCommand parses a message as a command, returning the command and true if valid
@@ -30,5 +33,12 @@ func ParseCommand(content string) (*Command, bool) {
 		}, true
 	}
 
+	if matches := giphyCommandRegex.FindStringSubmatch(content); matches != nil {
+		return &Command{
+			Type:  "giphy",
+			Query: strings.Join(strings.Fields(matches[1]), " "),
+		}, true
+	}
+
 	return nil, false
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -283,3 +284,37 @@ func BenchmarkParseCommand_WithWhitespace(b *testing.B) {
 		ParseCommand("  /stock=AAPL.US  ")
 	}
 }
+
+func TestParseCommand_Giphy(t *testing.T) {
+	tests := []struct {
+		input       string
+		shouldParse bool
+		query       string
+	}{
+		{"/giphy cats", true, "cats"},
+		{"/giphy   happy   dance ", true, "happy dance"},
+		{"/giphy", false, ""},
+		{"/giphy    ", false, ""},
+		{"/giphycats", false, ""},
+		{"/giphy " + strings.Repeat("a", 101), false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			cmd, isCommand := ParseCommand(tt.input)
+
+			if isCommand != tt.shouldParse {
+				t.Fatalf("Expected isCommand=%v, got %v", tt.shouldParse, isCommand)
+			}
+			if !tt.shouldParse {
+				return
+			}
+			if cmd.Type != "giphy" {
+				t.Errorf("Expected Type 'giphy', got '%s'", cmd.Type)
+			}
+			if cmd.Query != tt.query {
+				t.Errorf("Expected Query '%s', got '%s'", tt.query, cmd.Query)
+			}
+		})
+	}
+}
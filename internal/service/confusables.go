@@ -0,0 +1,56 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeUsername returns the NFKC form of a username as typed, so
+// compatibility characters such as fullwidth ａｌｉｃｅ or the ﬁ ligature
+// become the plain letters they stand for
+func NormalizeUsername(username string) string {
+	return norm.NFKC.String(strings.TrimSpace(username))
+}
+
+// lookalikes maps characters to the lowercase ASCII letter they can pass
+// for. Digits and letters that look alike map to the same letter, so i, l,
+// I and 1 all become l.
+var lookalikes = map[rune]rune{
+	'0': 'o', '1': 'l', 'i': 'l', '|': 'l', '!': 'l', '3': 'e', '4': 'a',
+	'@': 'a', '5': 's', '$': 's', '7': 't', '8': 'b',
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o',
+	'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'l', 'ј': 'j',
+	'ѕ': 's', 'ԁ': 'd', 'ӏ': 'l', 'ԛ': 'q', 'ԝ': 'w',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'l', 'κ': 'k', 'ν': 'v',
+	'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x',
+}
+
+// digraphs are letter pairs that read as a single letter
+var digraphs = strings.NewReplacer("rn", "m", "vv", "w")
+
+// nameSkeleton reduces name to the letters it reads as: compatibility
+// characters are decomposed, accents dropped, case folded and lookalikes
+// mapped to the ASCII letter they resemble. Separators and anything else
+// that is neither a letter nor a digit are dropped. Two names with the same
+// skeleton can be mistaken for each other.
+//
+// The username_skeleton SQL function, which finds confusable usernames,
+// reduces the ASCII letters, digits and underscores of usernames the same
+// way; keep them in step.
+func nameSkeleton(name string) string {
+	folded := cases.Fold().String(norm.NFKD.String(name))
+	var b strings.Builder
+	for _, r := range folded {
+		if l, ok := lookalikes[r]; ok {
+			b.WriteRune(l)
+		} else if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return digraphs.Replace(b.String())
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestNormalizeUsername(t *testing.T) {
+	tests := map[string]string{
+		"alice":   "alice",
+		" alice ": "alice",
+		"ａｌｉｃｅ２":  "alice2",
+		"ﬁona":    "fiona",
+		"Ａlice":   "Alice",
+	}
+	for in, want := range tests {
+		if got := NormalizeUsername(in); got != want {
+			t.Errorf("NormalizeUsername(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNameSkeleton(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"StockBot", "st0ck_bot", true},
+		{"alice", "ALICE", true},
+		{"alice", "a1ice", true},
+		{"alice", "álíce", true},
+		{"william", "wi11iarn", true},
+		{"paypal", "рaypal", true}, // Cyrillic р
+		{"strasse", "STRAßE", true},
+		{"alice", "alicia", false},
+		{"bob", "b0b_2", false},
+	}
+	for _, tt := range tests {
+		if same := nameSkeleton(tt.a) == nameSkeleton(tt.b); same != tt.same {
+			t.Errorf("nameSkeleton(%q) == nameSkeleton(%q) is %v, want %v", tt.a, tt.b, same, tt.same)
+		}
+	}
+}
+
+func TestAuthService_Register_ConfusableUsername(t *testing.T) {
+	ctx := context.Background()
+	userRepo := testutil.NewMockUserRepository()
+	userRepo.Users["bot-id"] = testutil.NewTestUser(testutil.WithUserID("bot-id"), testutil.WithUsername("StockBot"))
+	authService := NewAuthService(userRepo, testutil.NewMockSessionRepository())
+
+	for _, username := range []string{"stockbot", "St0ck_B0t", "ｓｔｏｃｋｂｏｔ"} {
+		_, err := authService.Register(ctx, username, "mallory@example.com", "password123")
+		var invalid *domain.ValidationError
+		if !errors.As(err, &invalid) || invalid.Fields[0].Code != domain.FieldConfusable {
+			t.Errorf("Register(%q) error = %v, want a confusable username", username, err)
+		}
+	}
+
+	user, err := authService.Register(ctx, "ａｌｉｃｅ", "alice@example.com", "password123")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, user.Username, "alice")
+}
+
+func TestProfileService_ChangeUsername_Confusable(t *testing.T) {
+	ctx := context.Background()
+	profiles, _, _ := newUsernameTestService(UsernamePolicy{})
+
+	_, err := profiles.ChangeUsername(ctx, aliceID, "B0B")
+	var invalid *domain.ValidationError
+	testutil.AssertTrue(t, errors.As(err, &invalid), "expected ValidationError")
+	testutil.AssertEqual(t, invalid.Fields[0].Code, domain.FieldConfusable)
+
+	// Users may change the case of their own username
+	change, err := profiles.ChangeUsername(ctx, aliceID, "Alice")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, change.NewUsername, "Alice")
+}
@@ -0,0 +1,348 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"jobsity-chat/internal/domain"
+)
+
+// ErrEmptyDirectory is returned when the directory lists no users while some
+// are linked to it. That usually means a misconfigured filter, so the sync
+// refuses to deactivate everyone.
+var ErrEmptyDirectory = errors.New("directory returned no users")
+
+// DeactivationNotifier is told when a user is deactivated, so their live
+// connections are closed rather than kept until they reconnect
+type DeactivationNotifier interface {
+	UserDeactivated(orgID, userID string) error
+}
+
+// DirectorySyncOptions configures a DirectorySyncService
+type DirectorySyncOptions struct {
+	// GroupRooms maps directory group names to the chatrooms their members
+	// are added to. Group names are matched case-insensitively.
+	GroupRooms map[string][]string
+	// DryRun logs the changes a sync would make without applying them
+	DryRun bool
+}
+
+// DirectorySyncReport counts the changes made (or, in dry-run mode, planned)
+// by one sync
+type DirectorySyncReport struct {
+	Created          int  `json:"created"`
+	Conflicts        int  `json:"conflicts"` // listed accounts whose email a local user has
+	Deactivated      int  `json:"deactivated"`
+	Reactivated      int  `json:"reactivated"`
+	MembershipsAdded int  `json:"memberships_added"`
+	Skipped          int  `json:"skipped"`
+	DryRun           bool `json:"dry_run"`
+}
+
+// DirectorySyncService provisions users from an enterprise directory. Listed
+// accounts get a newly created user; like OAuthService, they are never linked
+// to an existing user by email, since local emails are not verified. Accounts
+// that are disabled or no longer listed are deactivated and signed out. Members of mapped groups join the group's chatrooms. Memberships are
+// only ever added, so users can still leave rooms they were placed in.
+type DirectorySyncService struct {
+	source       domain.DirectorySource
+	userRepo     domain.UserRepository
+	identityRepo domain.IdentityRepository
+	chatroomRepo domain.ChatroomRepository
+	sessionRepo  domain.SessionRepository
+	groupRooms   map[string][]string
+	dryRun       bool
+	hasher       PasswordHasher
+	// tx is nil until SetTxManager is called
+	tx domain.TxManager
+	// deactivations is nil until SetDeactivationNotifier is called
+	deactivations DeactivationNotifier
+}
+
+func NewDirectorySyncService(
+	source domain.DirectorySource,
+	userRepo domain.UserRepository,
+	identityRepo domain.IdentityRepository,
+	chatroomRepo domain.ChatroomRepository,
+	sessionRepo domain.SessionRepository,
+	opts DirectorySyncOptions,
+) *DirectorySyncService {
+	groupRooms := make(map[string][]string, len(opts.GroupRooms))
+	for group, rooms := range opts.GroupRooms {
+		key := strings.ToLower(group)
+		groupRooms[key] = append(groupRooms[key], rooms...)
+	}
+
+	return &DirectorySyncService{
+		source:       source,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		chatroomRepo: chatroomRepo,
+		sessionRepo:  sessionRepo,
+		groupRooms:   groupRooms,
+		dryRun:       opts.DryRun,
+		hasher:       NewHashingPool(HashingPoolConfig{}),
+	}
+}
+
+// SetPasswordHasher replaces the default hashing pool the random passwords
+// of created accounts are hashed with
+func (s *DirectorySyncService) SetPasswordHasher(hasher PasswordHasher) {
+	s.hasher = hasher
+}
+
+// SetTxManager makes the changes to each user, such as creating and linking
+// the account or deactivating it and signing it out, one transaction
+func (s *DirectorySyncService) SetTxManager(tx domain.TxManager) {
+	s.tx = tx
+}
+
+// SetDeactivationNotifier reports the users the sync deactivates to
+// deactivations from now on
+func (s *DirectorySyncService) SetDeactivationNotifier(deactivations DeactivationNotifier) {
+	s.deactivations = deactivations
+}
+
+// Sync reconciles local users with the directory once
+func (s *DirectorySyncService) Sync(ctx context.Context) (*DirectorySyncReport, error) {
+	provider := s.source.Name()
+
+	entries, err := s.source.ListUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list directory users: %w", err)
+	}
+
+	identities, err := s.identityRepo.ListByProvider(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 && len(identities) > 0 {
+		return nil, ErrEmptyDirectory
+	}
+
+	linked := make(map[string]*domain.UserIdentity, len(identities))
+	for _, identity := range identities {
+		linked[identity.Subject] = identity
+	}
+
+	report := &DirectorySyncReport{DryRun: s.dryRun}
+	listed := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.ExternalID == "" || listed[entry.ExternalID] {
+			report.Skipped++
+			continue
+		}
+		listed[entry.ExternalID] = true
+
+		// One bad entry must not hold up the rest of the directory
+		err := withinTx(ctx, s.tx, func(ctx context.Context) error {
+			return s.syncUser(ctx, entry, linked[entry.ExternalID], report)
+		})
+		if err != nil {
+			report.Skipped++
+			slog.Warn("directory sync skipped user",
+				slog.String("provider", provider),
+				slog.String("external_id", entry.ExternalID),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	for subject, identity := range linked {
+		if listed[subject] {
+			continue
+		}
+		err := withinTx(ctx, s.tx, func(ctx context.Context) error {
+			user, err := s.userRepo.GetByID(ctx, identity.UserID)
+			if err != nil {
+				return err
+			}
+			return s.setActive(ctx, user, false, report)
+		})
+		if err != nil {
+			report.Skipped++
+			slog.Warn("directory sync could not deactivate user",
+				slog.String("provider", provider),
+				slog.String("user_id", identity.UserID),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	slog.Info("directory sync completed",
+		slog.String("provider", provider),
+		slog.Bool("dry_run", s.dryRun),
+		slog.Int("listed", len(entries)),
+		slog.Int("created", report.Created),
+		slog.Int("conflicts", report.Conflicts),
+		slog.Int("deactivated", report.Deactivated),
+		slog.Int("reactivated", report.Reactivated),
+		slog.Int("memberships_added", report.MembershipsAdded),
+		slog.Int("skipped", report.Skipped))
+	return report, nil
+}
+
+func (s *DirectorySyncService) syncUser(ctx context.Context, entry domain.DirectoryUser, identity *domain.UserIdentity, report *DirectorySyncReport) error {
+	var user *domain.User
+	var err error
+	if identity != nil {
+		user, err = s.userRepo.GetByID(ctx, identity.UserID)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Disabled accounts are never provisioned
+		if !entry.Active {
+			return nil
+		}
+		user, err = s.linkUser(ctx, entry, report)
+		if errors.Is(err, domain.ErrEmailExists) {
+			// Reported as a conflict rather than skipped
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			// Dry run: the user would be created with these memberships
+			report.MembershipsAdded += len(s.roomsFor(entry.Groups))
+			return nil
+		}
+	}
+
+	if err := s.setActive(ctx, user, entry.Active, report); err != nil {
+		return err
+	}
+	if !entry.Active {
+		return nil
+	}
+	return s.addMemberships(ctx, user, entry.Groups, report)
+}
+
+// linkUser creates a user for entry and links them. The directory's address
+// is trusted, but the local user who registered it first need not be its
+// owner, so a taken address is reported as a conflict with
+// domain.ErrEmailExists for an administrator to resolve. In dry-run mode a
+// user that would be created is returned as nil.
+func (s *DirectorySyncService) linkUser(ctx context.Context, entry domain.DirectoryUser, report *DirectorySyncReport) (*domain.User, error) {
+	existing, err := s.userRepo.GetByEmail(ctx, entry.Email)
+	if err == nil {
+		report.Conflicts++
+		slog.Warn("directory sync skipped user whose email a local user has",
+			slog.String("provider", s.source.Name()),
+			slog.String("external_id", entry.ExternalID),
+			slog.String("user_id", existing.ID))
+		return nil, domain.ErrEmailExists
+	}
+	if !errors.Is(err, domain.ErrUserNotFound) {
+		return nil, err
+	}
+
+	report.Created++
+	slog.Info("directory sync creating user",
+		slog.String("provider", s.source.Name()),
+		slog.String("external_id", entry.ExternalID),
+		slog.Bool("dry_run", s.dryRun))
+	if s.dryRun {
+		return nil, nil
+	}
+	// The directory is managed by the organization's administrators, who may
+	// use reserved usernames
+	user, err := provisionUser(ctx, s.userRepo, s.hasher, entry.Email, entry.Username, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.identityRepo.Create(ctx, &domain.UserIdentity{
+		Provider: s.source.Name(),
+		Subject:  entry.ExternalID,
+		UserID:   user.ID,
+		Email:    entry.Email,
+	}); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// setActive deactivates or reactivates user to match the directory.
+// Deactivated users are signed out everywhere and their live connections
+// closed.
+func (s *DirectorySyncService) setActive(ctx context.Context, user *domain.User, active bool, report *DirectorySyncReport) error {
+	if user.IsActive() == active {
+		return nil
+	}
+
+	if active {
+		report.Reactivated++
+	} else {
+		report.Deactivated++
+	}
+	slog.Info("directory sync updating user status",
+		slog.String("provider", s.source.Name()),
+		slog.String("user_id", user.ID),
+		slog.Bool("active", active),
+		slog.Bool("dry_run", s.dryRun))
+	if s.dryRun {
+		return nil
+	}
+
+	if err := s.userRepo.SetDeactivated(ctx, user.ID, !active); err != nil {
+		return err
+	}
+	if !active {
+		if _, err := s.sessionRepo.DeleteByUserID(ctx, user.ID); err != nil {
+			return err
+		}
+		if s.deactivations != nil {
+			if err := s.deactivations.UserDeactivated(domain.OrgIDFromContext(ctx), user.ID); err != nil {
+				slog.Warn("failed to notify user deactivation",
+					slog.String("error", err.Error()),
+					slog.String("user_id", user.ID))
+			}
+		}
+	}
+	return nil
+}
+
+func (s *DirectorySyncService) addMemberships(ctx context.Context, user *domain.User, groups []string, report *DirectorySyncReport) error {
+	for _, roomID := range s.roomsFor(groups) {
+		member, err := s.chatroomRepo.IsMember(ctx, roomID, user.ID)
+		if err != nil {
+			return err
+		}
+		if member {
+			continue
+		}
+
+		report.MembershipsAdded++
+		if s.dryRun {
+			continue
+		}
+		if err := s.chatroomRepo.AddMember(ctx, roomID, user.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// roomsFor returns the distinct chatrooms mapped to groups
+func (s *DirectorySyncService) roomsFor(groups []string) []string {
+	var rooms []string
+	seen := make(map[string]bool)
+	for _, group := range groups {
+		for _, roomID := range s.groupRooms[strings.ToLower(group)] {
+			if !seen[roomID] {
+				seen[roomID] = true
+				rooms = append(rooms, roomID)
+			}
+		}
+	}
+	return rooms
+}
+
+// Run syncs once, as the directory_sync job
+func (s *DirectorySyncService) Run(ctx context.Context) error {
+	_, err := s.Sync(ctx)
+	return err
+}
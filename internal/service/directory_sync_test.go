@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+type fakeDirectory struct {
+	users []domain.DirectoryUser
+	err   error
+}
+
+func (f *fakeDirectory) Name() string { return "ldap" }
+
+func (f *fakeDirectory) ListUsers(ctx context.Context) ([]domain.DirectoryUser, error) {
+	return f.users, f.err
+}
+
+type directorySyncFixture struct {
+	userRepo     *testutil.MockUserRepository
+	identityRepo *testutil.MockIdentityRepository
+	chatroomRepo *testutil.MockChatroomRepository
+	sessionRepo  *testutil.MockSessionRepository
+}
+
+func newDirectorySyncFixture() *directorySyncFixture {
+	return &directorySyncFixture{
+		userRepo:     testutil.NewMockUserRepository(),
+		identityRepo: testutil.NewMockIdentityRepository(),
+		chatroomRepo: testutil.NewMockChatroomRepository(),
+		sessionRepo:  testutil.NewMockSessionRepository(),
+	}
+}
+
+type recordingDeactivations struct {
+	users []string
+}
+
+func (r *recordingDeactivations) UserDeactivated(orgID, userID string) error {
+	r.users = append(r.users, orgID+"/"+userID)
+	return nil
+}
+
+func (f *directorySyncFixture) service(source domain.DirectorySource, opts DirectorySyncOptions) *DirectorySyncService {
+	return NewDirectorySyncService(source, f.userRepo, f.identityRepo, f.chatroomRepo, f.sessionRepo, opts)
+}
+
+func TestDirectorySync_CreatesUserWithGroupRooms(t *testing.T) {
+	f := newDirectorySyncFixture()
+	source := &fakeDirectory{users: []domain.DirectoryUser{{
+		ExternalID: "uid-1",
+		Username:   "alice",
+		Email:      "alice@example.com",
+		Active:     true,
+		Groups:     []string{"Engineering", "everyone"},
+	}}}
+	svc := f.service(source, DirectorySyncOptions{GroupRooms: map[string][]string{
+		"engineering": {"room-eng", "room-general"},
+		"everyone":    {"room-general"},
+	}})
+
+	report, err := svc.Sync(context.Background())
+
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, report.Created, 1)
+	testutil.AssertEqual(t, report.MembershipsAdded, 2)
+
+	user, err := f.userRepo.GetByEmail(context.Background(), "alice@example.com")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, user.Username, "alice")
+
+	identity, err := f.identityRepo.GetByProviderSubject(context.Background(), "ldap", "uid-1")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, identity.UserID, user.ID)
+
+	testutil.AssertTrue(t, f.chatroomRepo.Members["room-eng"][user.ID], "user should join room-eng")
+	testutil.AssertTrue(t, f.chatroomRepo.Members["room-general"][user.ID], "user should join room-general")
+
+	// A second run has nothing left to do
+	report, err = svc.Sync(context.Background())
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, *report, DirectorySyncReport{})
+}
+
+func TestDirectorySync_DoesNotLinkExistingUserByEmail(t *testing.T) {
+	f := newDirectorySyncFixture()
+	// Local emails are not verified: anyone may have registered these
+	squatter := testutil.NewTestUser(testutil.WithUserID("user-1"), testutil.WithEmail("ceo@example.com"))
+	f.userRepo.Users[squatter.ID] = squatter
+	bot := testutil.NewTestUser(testutil.WithUserID("bot-1"), testutil.WithEmail("bot@example.com"))
+	bot.IsSystem = true
+	f.userRepo.Users[bot.ID] = bot
+
+	svc := f.service(&fakeDirectory{users: []domain.DirectoryUser{
+		{ExternalID: "uid-2", Username: "ceo", Email: "ceo@example.com", Active: true, Groups: []string{"executives"}},
+		{ExternalID: "uid-3", Username: "bot", Email: "bot@example.com", Active: true, Groups: []string{"executives"}},
+	}}, DirectorySyncOptions{GroupRooms: map[string][]string{"executives": {"room-board"}}})
+
+	report, err := svc.Sync(context.Background())
+
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, report.Conflicts, 2)
+	testutil.AssertEqual(t, report.Created, 0)
+	testutil.AssertEqual(t, report.MembershipsAdded, 0)
+	_, err = f.identityRepo.GetByProviderSubject(context.Background(), "ldap", "uid-2")
+	testutil.AssertErrorIs(t, err, domain.ErrIdentityNotFound)
+	_, err = f.identityRepo.GetByProviderSubject(context.Background(), "ldap", "uid-3")
+	testutil.AssertErrorIs(t, err, domain.ErrIdentityNotFound)
+	testutil.AssertFalse(t, f.chatroomRepo.Members["room-board"][squatter.ID], "the local user must not join the group's rooms")
+	testutil.AssertFalse(t, f.chatroomRepo.Members["room-board"][bot.ID], "a system account must not join the group's rooms")
+}
+
+func TestDirectorySync_DeactivatesDisabledAndRemovedUsers(t *testing.T) {
+	f := newDirectorySyncFixture()
+	for _, id := range []string{"user-1", "user-2"} {
+		f.userRepo.Users[id] = testutil.NewTestUser(testutil.WithUserID(id))
+		f.sessionRepo.Sessions["token-"+id] = testutil.NewTestSession(
+			testutil.WithSessionUserID(id), testutil.WithToken("token-"+id))
+	}
+	f.identityRepo.Identities["ldap:uid-1"] = &domain.UserIdentity{Provider: "ldap", Subject: "uid-1", UserID: "user-1"}
+	f.identityRepo.Identities["ldap:uid-2"] = &domain.UserIdentity{Provider: "ldap", Subject: "uid-2", UserID: "user-2"}
+
+	// uid-1 is disabled, uid-2 is gone from the directory
+	svc := f.service(&fakeDirectory{users: []domain.DirectoryUser{
+		{ExternalID: "uid-1", Email: "alice@example.com", Active: false},
+	}}, DirectorySyncOptions{})
+
+	report, err := svc.Sync(context.Background())
+
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, report.Deactivated, 2)
+	testutil.AssertFalse(t, f.userRepo.Users["user-1"].IsActive(), "disabled user should be deactivated")
+	testutil.AssertFalse(t, f.userRepo.Users["user-2"].IsActive(), "removed user should be deactivated")
+	testutil.AssertEqual(t, len(f.sessionRepo.Sessions), 0)
+}
+
+func TestDirectorySync_ClosesConnectionsOfDeactivatedUsers(t *testing.T) {
+	f := newDirectorySyncFixture()
+	f.userRepo.Users["user-1"] = testutil.NewTestUser(testutil.WithUserID("user-1"))
+	f.identityRepo.Identities["ldap:uid-1"] = &domain.UserIdentity{Provider: "ldap", Subject: "uid-1", UserID: "user-1"}
+	deactivations := &recordingDeactivations{}
+
+	svc := f.service(&fakeDirectory{users: []domain.DirectoryUser{
+		{ExternalID: "uid-1", Email: "alice@example.com", Active: false},
+	}}, DirectorySyncOptions{})
+	svc.SetDeactivationNotifier(deactivations)
+
+	_, err := svc.Sync(domain.WithOrgID(context.Background(), "org-1"))
+
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, len(deactivations.users), 1)
+	testutil.AssertEqual(t, deactivations.users[0], "org-1/user-1")
+}
+
+func TestDirectorySync_ChangesEachUserInOneTransaction(t *testing.T) {
+	f := newDirectorySyncFixture()
+	f.userRepo.Users["user-1"] = testutil.NewTestUser(testutil.WithUserID("user-1"))
+	f.identityRepo.Identities["ldap:uid-1"] = &domain.UserIdentity{Provider: "ldap", Subject: "uid-1", UserID: "user-1"}
+	tx := &testutil.MockTxManager{}
+
+	// uid-1 is gone from the directory and uid-2 is new
+	svc := f.service(&fakeDirectory{users: []domain.DirectoryUser{
+		{ExternalID: "uid-2", Username: "bob", Email: "bob@example.com", Active: true},
+	}}, DirectorySyncOptions{})
+	svc.SetTxManager(tx)
+
+	report, err := svc.Sync(context.Background())
+
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, report.Created, 1)
+	testutil.AssertEqual(t, report.Deactivated, 1)
+	testutil.AssertEqual(t, tx.Units, 2)
+}
+
+func TestDirectorySync_ReactivatesUser(t *testing.T) {
+	f := newDirectorySyncFixture()
+	user := testutil.NewTestUser(testutil.WithUserID("user-1"))
+	deactivatedAt := time.Now()
+	user.DeactivatedAt = &deactivatedAt
+	f.userRepo.Users[user.ID] = user
+	f.identityRepo.Identities["ldap:uid-1"] = &domain.UserIdentity{Provider: "ldap", Subject: "uid-1", UserID: "user-1"}
+
+	svc := f.service(&fakeDirectory{users: []domain.DirectoryUser{
+		{ExternalID: "uid-1", Email: "alice@example.com", Active: true},
+	}}, DirectorySyncOptions{})
+
+	report, err := svc.Sync(context.Background())
+
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, report.Reactivated, 1)
+	testutil.AssertTrue(t, user.IsActive(), "user should be reactivated")
+}
+
+func TestDirectorySync_DryRunChangesNothing(t *testing.T) {
+	f := newDirectorySyncFixture()
+	f.userRepo.Users["user-1"] = testutil.NewTestUser(testutil.WithUserID("user-1"))
+	f.identityRepo.Identities["ldap:uid-1"] = &domain.UserIdentity{Provider: "ldap", Subject: "uid-1", UserID: "user-1"}
+
+	svc := f.service(&fakeDirectory{users: []domain.DirectoryUser{
+		{ExternalID: "uid-2", Username: "carol", Email: "carol@example.com", Active: true, Groups: []string{"sales"}},
+	}}, DirectorySyncOptions{
+		GroupRooms: map[string][]string{"sales": {"room-sales"}},
+		DryRun:     true,
+	})
+
+	report, err := svc.Sync(context.Background())
+
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, *report, DirectorySyncReport{
+		Created:          1,
+		Deactivated:      1,
+		MembershipsAdded: 1,
+		DryRun:           true,
+	})
+	testutil.AssertEqual(t, len(f.userRepo.Users), 1)
+	testutil.AssertEqual(t, len(f.identityRepo.Identities), 1)
+	testutil.AssertTrue(t, f.userRepo.Users["user-1"].IsActive(), "dry run must not deactivate")
+	testutil.AssertEqual(t, len(f.chatroomRepo.Members), 0)
+}
+
+func TestDirectorySync_RefusesEmptyDirectory(t *testing.T) {
+	f := newDirectorySyncFixture()
+	f.userRepo.Users["user-1"] = testutil.NewTestUser(testutil.WithUserID("user-1"))
+	f.identityRepo.Identities["ldap:uid-1"] = &domain.UserIdentity{Provider: "ldap", Subject: "uid-1", UserID: "user-1"}
+
+	_, err := f.service(&fakeDirectory{}, DirectorySyncOptions{}).Sync(context.Background())
+
+	testutil.AssertErrorIs(t, err, ErrEmptyDirectory)
+	testutil.AssertTrue(t, f.userRepo.Users["user-1"].IsActive(), "user must stay active")
+}
+
+func TestDirectorySync_SourceError(t *testing.T) {
+	f := newDirectorySyncFixture()
+	sourceErr := errors.New("connection refused")
+
+	_, err := f.service(&fakeDirectory{err: sourceErr}, DirectorySyncOptions{}).Sync(context.Background())
+
+	testutil.AssertErrorIs(t, err, sourceErr)
+}
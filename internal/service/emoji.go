@@ -0,0 +1,84 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+)
+
+// emojiShortcodePattern matches :shortcode: tokens. Unknown codes, like the
+// minutes in "12:30:45", are left as typed.
+var emojiShortcodePattern = regexp.MustCompile(`:[a-z0-9_+\-]{1,32}:`)
+
+// emojiShortcodes maps the common Slack/GitHub shortcodes to emoji
+var emojiShortcodes = map[string]string{
+	"+1":                         "👍",
+	"-1":                         "👎",
+	"100":                        "💯",
+	"angry":                      "😠",
+	"beers":                      "🍻",
+	"blush":                      "😊",
+	"bulb":                       "💡",
+	"cake":                       "🍰",
+	"chart_with_downwards_trend": "📉",
+	"chart_with_upwards_trend":   "📈",
+	"check":                      "✔️",
+	"clap":                       "👏",
+	"coffee":                     "☕",
+	"confused":                   "😕",
+	"cry":                        "😢",
+	"eyes":                       "👀",
+	"fire":                       "🔥",
+	"grin":                       "😁",
+	"grinning":                   "😀",
+	"heart":                      "❤️",
+	"heart_eyes":                 "😍",
+	"joy":                        "😂",
+	"laughing":                   "😆",
+	"moneybag":                   "💰",
+	"muscle":                     "💪",
+	"ok_hand":                    "👌",
+	"party":                      "🥳",
+	"pensive":                    "😔",
+	"pizza":                      "🍕",
+	"point_up":                   "☝️",
+	"pray":                       "🙏",
+	"question":                   "❓",
+	"raised_hands":               "🙌",
+	"rocket":                     "🚀",
+	"rofl":                       "🤣",
+	"scream":                     "😱",
+	"see_no_evil":                "🙈",
+	"slightly_smiling_face":      "🙂",
+	"smile":                      "😄",
+	"smiley":                     "😃",
+	"smirk":                      "😏",
+	"sob":                        "😭",
+	"sparkles":                   "✨",
+	"star":                       "⭐",
+	"sunglasses":                 "😎",
+	"sweat_smile":                "😅",
+	"tada":                       "🎉",
+	"thinking":                   "🤔",
+	"thumbsdown":                 "👎",
+	"thumbsup":                   "👍",
+	"upside_down_face":           "🙃",
+	"warning":                    "⚠️",
+	"wave":                       "👋",
+	"white_check_mark":           "✅",
+	"wink":                       "😉",
+	"x":                          "❌",
+	"zap":                        "⚡",
+}
+
+// ExpandEmoji replaces known :shortcode: tokens in content with their emoji
+func ExpandEmoji(content string) string {
+	if !strings.Contains(content, ":") {
+		return content
+	}
+	return emojiShortcodePattern.ReplaceAllStringFunc(content, func(token string) string {
+		if emoji, ok := emojiShortcodes[token[1:len(token)-1]]; ok {
+			return emoji
+		}
+		return token
+	})
+}
@@ -0,0 +1,27 @@
+package service
+
+import "testing"
+
+func TestExpandEmoji(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"no shortcodes", "no shortcodes"},
+		{"ship it :rocket:", "ship it 🚀"},
+		{":+1::tada:", "👍🎉"},
+		{"AAPL :chart_with_upwards_trend: today", "AAPL 📈 today"},
+		{"unknown :not_an_emoji: stays", "unknown :not_an_emoji: stays"},
+		{"at 12:30:45 sharp", "at 12:30:45 sharp"},
+		{"case :Rocket: matters", "case :Rocket: matters"},
+		{"::", "::"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := ExpandEmoji(tt.input); got != tt.expected {
+				t.Errorf("ExpandEmoji(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package service
+
+import (
+	"context"
+
+	"jobsity-chat/internal/domain"
+)
+
+// EventPublisher takes the domain events services raise once a change is
+// stored
+type EventPublisher interface {
+	Publish(ctx context.Context, event domain.Event)
+}
+
+// publish hands event to events, which is nil when nothing subscribes
+func publish(ctx context.Context, events EventPublisher, event domain.Event) {
+	if events != nil {
+		events.Publish(ctx, event)
+	}
+}
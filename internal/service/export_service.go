@@ -0,0 +1,253 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/observability"
+)
+
+// DefaultExportTTL is how long a ready data export can be downloaded
+const DefaultExportTTL = 7 * 24 * time.Hour
+
+const (
+	// exportStaleAfter is how long an export may stay pending before it is
+	// considered lost, e.g. to a restart, and a new one is started
+	exportStaleAfter = 15 * time.Minute
+	// exportRetryAfter is how long a failed export is reported before the
+	// next request starts a new one, so polling clients see the failure
+	exportRetryAfter = time.Minute
+
+	exportQueueSize = 64
+	exportTimeout   = 5 * time.Minute
+)
+
+type exportJob struct {
+	orgID    string
+	exportID string
+	userID   string
+}
+
+// ExportService assembles a user's profile, memberships and messages into a
+// zip archive they can download, for data access requests. Archives are
+// built in the background by Run.
+type ExportService struct {
+	exportRepo domain.DataExportRepository
+	userRepo   domain.UserRepository
+	ttl        time.Duration
+	jobs       chan exportJob
+}
+
+// NewExportService returns an ExportService whose archives can be downloaded
+// for ttl, or DefaultExportTTL when ttl is not positive
+func NewExportService(exportRepo domain.DataExportRepository, userRepo domain.UserRepository, ttl time.Duration) *ExportService {
+	if ttl <= 0 {
+		ttl = DefaultExportTTL
+	}
+	return &ExportService{
+		exportRepo: exportRepo,
+		userRepo:   userRepo,
+		ttl:        ttl,
+		jobs:       make(chan exportJob, exportQueueSize),
+	}
+}
+
+// Request returns the user's current data export. A new one is started
+// unless the latest is still being assembled, can be downloaded or failed
+// less than a minute ago.
+func (s *ExportService) Request(ctx context.Context, userID string) (*domain.DataExport, error) {
+	latest, err := s.exportRepo.Latest(ctx, userID)
+	switch {
+	case err == nil && exportCurrent(latest):
+		return latest, nil
+	case err != nil && !errors.Is(err, domain.ErrExportNotFound):
+		return nil, err
+	}
+
+	export := &domain.DataExport{UserID: userID}
+	if err := s.exportRepo.Create(ctx, export); err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.jobs <- exportJob{orgID: domain.OrgIDFromContext(ctx), exportID: export.ID, userID: userID}:
+	default:
+		const reason = "too many exports in progress, try again later"
+		if err := s.exportRepo.Fail(ctx, export.ID, reason); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		export.Status, export.Error, export.CompletedAt = domain.ExportFailed, reason, &now
+		return export, nil
+	}
+
+	observability.Audit(ctx, "data_export_requested",
+		slog.String("user_id", userID),
+		slog.String("export_id", export.ID))
+	return export, nil
+}
+
+func exportCurrent(export *domain.DataExport) bool {
+	switch export.Status {
+	case domain.ExportPending:
+		return time.Since(export.CreatedAt) < exportStaleAfter
+	case domain.ExportReady:
+		return export.ExpiresAt != nil && time.Now().Before(*export.ExpiresAt)
+	case domain.ExportFailed:
+		return export.CompletedAt != nil && time.Since(*export.CompletedAt) < exportRetryAfter
+	}
+	return false
+}
+
+// Archive returns the zip archive of one of the user's ready exports
+func (s *ExportService) Archive(ctx context.Context, userID, exportID string) ([]byte, error) {
+	archive, err := s.exportRepo.Archive(ctx, userID, exportID)
+	if err != nil {
+		return nil, err
+	}
+	observability.Audit(ctx, "data_export_downloaded",
+		slog.String("user_id", userID),
+		slog.String("export_id", exportID))
+	return archive, nil
+}
+
+// Run assembles requested exports with the given number of goroutines
+// until ctx is cancelled
+func (s *ExportService) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-s.jobs:
+					s.process(ctx, j)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// DeleteExpired deletes the archives of every organization that can no
+// longer be downloaded, as the export_cleanup job
+func (s *ExportService) DeleteExpired(ctx context.Context) error {
+	deleted, err := s.exportRepo.DeleteExpired(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info("data export cleanup completed", slog.Int64("exports_deleted", deleted))
+	return nil
+}
+
+func (s *ExportService) process(ctx context.Context, j exportJob) {
+	ctx, cancel := context.WithTimeout(domain.WithOrgID(ctx, j.orgID), exportTimeout)
+	defer cancel()
+
+	archive, err := s.build(ctx, j.userID)
+	if err == nil {
+		err = s.exportRepo.Complete(ctx, j.exportID, archive, time.Now().Add(s.ttl))
+	}
+	if err != nil {
+		slog.Error("data export failed",
+			slog.String("error", err.Error()),
+			slog.String("export_id", j.exportID))
+		if err := s.exportRepo.Fail(ctx, j.exportID, "failed to assemble the export"); err != nil {
+			slog.Error("failed to mark data export failed",
+				slog.String("error", err.Error()),
+				slog.String("export_id", j.exportID))
+		}
+		return
+	}
+	slog.Info("data export ready",
+		slog.String("export_id", j.exportID),
+		slog.Int("bytes", len(archive)))
+}
+
+// exportProfile is the profile.json of an export archive
+type exportProfile struct {
+	ID                string    `json:"id"`
+	Username          string    `json:"username"`
+	Email             string    `json:"email"`
+	Role              string    `json:"role"`
+	Locale            string    `json:"locale,omitempty"`
+	AvatarURL         string    `json:"avatar_url,omitempty"`
+	ProfileVisibility string    `json:"profile_visibility,omitempty"`
+	ShowOnlineStatus  bool      `json:"show_online_status"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// build writes profile.json, memberships.json and messages.json into a zip
+// archive
+func (s *ExportService) build(ctx context.Context, userID string) ([]byte, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	profile := exportProfile{
+		ID:        user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Role:      user.Role,
+		Locale:    user.Locale,
+		CreatedAt: user.CreatedAt,
+	}
+	if settings, err := s.userRepo.GetProfile(ctx, userID); err == nil {
+		profile.AvatarURL = settings.AvatarURL
+		profile.ProfileVisibility = settings.Visibility
+		profile.ShowOnlineStatus = settings.ShowOnlineStatus
+	} else if !errors.Is(err, domain.ErrUserNotFound) {
+		return nil, err
+	}
+
+	memberships, err := s.exportRepo.Memberships(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := s.exportRepo.Messages(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if memberships == nil {
+		memberships = []*domain.ExportMembership{}
+	}
+	if messages == nil {
+		messages = []*domain.ExportMessage{}
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range []struct {
+		name string
+		v    any
+	}{
+		{"profile.json", profile},
+		{"memberships.json", memberships},
+		{"messages.json", messages},
+	} {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s to export: %w", file.name, err)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.v); err != nil {
+			return nil, fmt.Errorf("failed to write %s to export: %w", file.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close export archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func newExportTestService() (*ExportService, *testutil.MockDataExportRepository) {
+	exportRepo := testutil.NewMockDataExportRepository()
+	userRepo := testutil.NewMockUserRepository()
+	userRepo.Users["user-1"] = testutil.NewTestUser(
+		testutil.WithUserID("user-1"),
+		testutil.WithUsername("alice"),
+		testutil.WithEmail("alice@example.com"),
+	)
+	exportRepo.UserMembership["user-1"] = []*domain.ExportMembership{
+		{ChatroomID: "room-1", ChatroomName: "General", Owner: true},
+	}
+	exportRepo.UserMessages["user-1"] = []*domain.ExportMessage{
+		{ID: "msg-1", ChatroomID: "room-1", ChatroomName: "General", Content: "Hello"},
+	}
+	return NewExportService(exportRepo, userRepo, time.Hour), exportRepo
+}
+
+func readExportFile(t *testing.T, archive []byte, name string, v any) {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	testutil.AssertNoError(t, err)
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		testutil.AssertNoError(t, err)
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		testutil.AssertNoError(t, err)
+		testutil.AssertNoError(t, json.Unmarshal(data, v))
+		return
+	}
+	t.Fatalf("%s not found in export archive", name)
+}
+
+func TestExportService_Request(t *testing.T) {
+	ctx := context.Background()
+	exports, exportRepo := newExportTestService()
+
+	export, err := exports.Request(ctx, "user-1")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, export.Status, domain.ExportPending)
+
+	again, err := exports.Request(ctx, "user-1")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, again.ID, export.ID)
+	testutil.AssertLen(t, exportRepo.Exports, 1)
+
+	exports.process(ctx, <-exports.jobs)
+
+	ready, err := exports.Request(ctx, "user-1")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, ready.ID, export.ID)
+	testutil.AssertEqual(t, ready.Status, domain.ExportReady)
+
+	archive, err := exports.Archive(ctx, "user-1", export.ID)
+	testutil.AssertNoError(t, err)
+
+	var profile exportProfile
+	readExportFile(t, archive, "profile.json", &profile)
+	testutil.AssertEqual(t, profile.Username, "alice")
+	testutil.AssertEqual(t, profile.Email, "alice@example.com")
+
+	var memberships []*domain.ExportMembership
+	readExportFile(t, archive, "memberships.json", &memberships)
+	testutil.AssertLen(t, memberships, 1)
+	testutil.AssertTrue(t, memberships[0].Owner, "membership should be marked owned")
+
+	var messages []*domain.ExportMessage
+	readExportFile(t, archive, "messages.json", &messages)
+	testutil.AssertLen(t, messages, 1)
+	testutil.AssertEqual(t, messages[0].Content, "Hello")
+
+	_, err = exports.Archive(ctx, "user-2", export.ID)
+	testutil.AssertErrorIs(t, err, domain.ErrExportNotFound)
+}
+
+func TestExportService_RequestAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	exports, exportRepo := newExportTestService()
+	exportRepo.MessagesFunc = func(ctx context.Context, userID string) ([]*domain.ExportMessage, error) {
+		return nil, errors.New("database error")
+	}
+
+	export, err := exports.Request(ctx, "user-1")
+	testutil.AssertNoError(t, err)
+	exports.process(ctx, <-exports.jobs)
+
+	failed, err := exports.Request(ctx, "user-1")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, failed.ID, export.ID)
+	testutil.AssertEqual(t, failed.Status, domain.ExportFailed)
+
+	// Retried once the failure has been reported for a while
+	completedAt := time.Now().Add(-2 * exportRetryAfter)
+	exportRepo.Exports[0].CompletedAt = &completedAt
+	retried, err := exports.Request(ctx, "user-1")
+	testutil.AssertNoError(t, err)
+	testutil.AssertNotEqual(t, retried.ID, export.ID)
+	testutil.AssertEqual(t, retried.Status, domain.ExportPending)
+}
+
+func TestExportService_RequestRestartsStaleExport(t *testing.T) {
+	ctx := context.Background()
+	exports, exportRepo := newExportTestService()
+
+	export, err := exports.Request(ctx, "user-1")
+	testutil.AssertNoError(t, err)
+	<-exports.jobs // lost, e.g. to a restart
+	exportRepo.Exports[0].CreatedAt = time.Now().Add(-2 * exportStaleAfter)
+
+	restarted, err := exports.Request(ctx, "user-1")
+	testutil.AssertNoError(t, err)
+	testutil.AssertNotEqual(t, restarted.ID, export.ID)
+}
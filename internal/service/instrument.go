@@ -0,0 +1,59 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/observability"
+)
+
+// Results of an instrumented service call
+const (
+	resultOK       = "ok"
+	resultRejected = "rejected"
+	resultError    = "error"
+)
+
+// rejections are the errors a service returns for bad input or a forbidden
+// action. They are counted apart from real failures so an error-rate alert
+// does not fire on a burst of mistyped passwords.
+var rejections = []error{
+	domain.ErrInvalidInput,
+	domain.ErrInvalidCredentials,
+	domain.ErrUserDeactivated,
+	domain.ErrUserNotFound,
+	domain.ErrUsernameExists,
+	domain.ErrEmailExists,
+	domain.ErrSessionNotFound,
+	domain.ErrSessionExpired,
+	domain.ErrChatroomNotFound,
+	domain.ErrNotMember,
+	domain.ErrNotOwner,
+	domain.ErrQuotaExceeded,
+}
+
+// observe records a service call in the service_calls_total and
+// service_call_duration_seconds metrics. Methods name their error result and
+// defer the returned func as their first statement:
+//
+//	defer observe("chat", "SendMessage")(&err)
+func observe(service, method string) func(*error) {
+	start := time.Now()
+	return func(err *error) {
+		observability.ServiceCallDuration.WithLabelValues(service, method).Observe(time.Since(start).Seconds())
+		observability.ServiceCallsTotal.WithLabelValues(service, method, callResult(*err)).Inc()
+	}
+}
+
+func callResult(err error) string {
+	if err == nil {
+		return resultOK
+	}
+	for _, rejection := range rejections {
+		if errors.Is(err, rejection) {
+			return resultRejected
+		}
+	}
+	return resultError
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/observability"
+	"jobsity-chat/internal/testutil"
+
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCallResult(t *testing.T) {
+	testutil.AssertEqual(t, callResult(nil), resultOK)
+	testutil.AssertEqual(t, callResult(domain.ErrNotMember), resultRejected)
+	testutil.AssertEqual(t, callResult(fmt.Errorf("wrapped: %w", domain.ErrInvalidInput)), resultRejected)
+	testutil.AssertEqual(t, callResult(&domain.QuotaExceededError{Quota: domain.QuotaRoomsPerUser, Limit: 1}), resultRejected)
+	testutil.AssertEqual(t, callResult(errors.New("connection refused")), resultError)
+}
+
+func TestChatService_SendMessage_RecordsMetrics(t *testing.T) {
+	messageRepo := &mockMessageRepository{}
+	chatroomRepo := &mockChatroomRepository{
+		members: map[string]map[string]bool{"chatroom1": {"user1": true}},
+	}
+	chatService := NewChatService(messageRepo, chatroomRepo)
+	ctx := context.Background()
+
+	count := func(result string) float64 {
+		return promtestutil.ToFloat64(observability.ServiceCallsTotal.WithLabelValues("chat", "SendMessage", result))
+	}
+	ok, rejected, failed := count(resultOK), count(resultRejected), count(resultError)
+
+	testutil.AssertNoError(t, chatService.SendMessage(ctx, &domain.Message{ChatroomID: "chatroom1", UserID: "user1", Content: "hi"}))
+	testutil.AssertError(t, chatService.SendMessage(ctx, &domain.Message{ChatroomID: "chatroom1", UserID: "user2", Content: "hi"}))
+	messageRepo.create = func(ctx context.Context, message *domain.Message) error {
+		return errors.New("connection refused")
+	}
+	testutil.AssertError(t, chatService.SendMessage(ctx, &domain.Message{ChatroomID: "chatroom1", UserID: "user1", Content: "hi"}))
+
+	testutil.AssertEqual(t, count(resultOK)-ok, 1.0)
+	testutil.AssertEqual(t, count(resultRejected)-rejected, 1.0)
+	testutil.AssertEqual(t, count(resultError)-failed, 1.0)
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"jobsity-chat/internal/domain"
+)
+
+// DefaultInviteTTL is how long an emailed chatroom invite can be accepted
+const DefaultInviteTTL = 7 * 24 * time.Hour
+
+// InviteMailer emails an invite link carrying token
+type InviteMailer interface {
+	SendInvite(ctx context.Context, to, inviterName, chatroomName, token string, expiresIn time.Duration) error
+}
+
+// InviteService lets chatroom owners invite email addresses. The invite link
+// pre-fills registration, and accepting it joins the chatroom.
+type InviteService struct {
+	inviteRepo   domain.RoomInviteRepository
+	chatroomRepo domain.ChatroomRepository
+	userRepo     domain.UserRepository
+	mailer       InviteMailer
+	ttl          time.Duration
+	// events is nil until SetEventPublisher is called
+	events EventPublisher
+}
+
+// NewInviteService returns an InviteService whose invites expire after ttl,
+// or DefaultInviteTTL when ttl is not positive
+func NewInviteService(inviteRepo domain.RoomInviteRepository, chatroomRepo domain.ChatroomRepository, userRepo domain.UserRepository, mailer InviteMailer, ttl time.Duration) *InviteService {
+	if ttl <= 0 {
+		ttl = DefaultInviteTTL
+	}
+	return &InviteService{
+		inviteRepo:   inviteRepo,
+		chatroomRepo: chatroomRepo,
+		userRepo:     userRepo,
+		mailer:       mailer,
+		ttl:          ttl,
+	}
+}
+
+// SetEventPublisher publishes a UserJoinedRoom event for each accepted
+// invite from now on
+func (s *InviteService) SetEventPublisher(events EventPublisher) {
+	s.events = events
+}
+
+// Invite emails an invite to the chatroom on behalf of its owner. Addresses
+// that already have an account get the same invite, which they accept once
+// signed in, so owners cannot tell which addresses are registered.
+func (s *InviteService) Invite(ctx context.Context, chatroomID, requesterID, email string) (*domain.RoomInvite, error) {
+	chatroom, err := s.ownedChatroom(ctx, chatroomID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !emailRegex.MatchString(email) || len(email) > 255 {
+		return nil, domain.ErrInvalidInput
+	}
+	inviter, err := s.userRepo.GetByID(ctx, requesterID)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate invite token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+	invite := &domain.RoomInvite{
+		ChatroomID: chatroom.ID,
+		Email:      email,
+		InvitedBy:  requesterID,
+		Token:      token,
+		TokenHash:  hashInviteToken(token),
+		ExpiresAt:  time.Now().Add(s.ttl),
+	}
+	if err := s.inviteRepo.Create(ctx, invite); err != nil {
+		return nil, err
+	}
+
+	if err := s.mailer.SendInvite(ctx, email, inviter.Username, chatroom.Name, token, s.ttl); err != nil {
+		// An invite nobody received would only linger in the pending list
+		if rErr := s.inviteRepo.Revoke(ctx, chatroom.ID, invite.ID); rErr != nil {
+			slog.WarnContext(ctx, "failed to revoke unsent invite",
+				slog.String("invite_id", invite.ID),
+				slog.String("error", rErr.Error()))
+		}
+		return nil, fmt.Errorf("failed to send invite email: %w", err)
+	}
+	return invite, nil
+}
+
+// PendingInvites lists a chatroom's pending invites to its owner
+func (s *InviteService) PendingInvites(ctx context.Context, chatroomID, requesterID string) ([]*domain.RoomInvite, error) {
+	if _, err := s.ownedChatroom(ctx, chatroomID, requesterID); err != nil {
+		return nil, err
+	}
+	return s.inviteRepo.ListPending(ctx, chatroomID)
+}
+
+// RevokeInvite cancels a pending invite on behalf of the chatroom owner
+func (s *InviteService) RevokeInvite(ctx context.Context, chatroomID, requesterID, inviteID string) error {
+	if _, err := s.ownedChatroom(ctx, chatroomID, requesterID); err != nil {
+		return err
+	}
+	return s.inviteRepo.Revoke(ctx, chatroomID, inviteID)
+}
+
+// LookupInvite returns the pending invite a link's token belongs to, so the
+// registration form can show the chatroom and pre-fill the email address
+func (s *InviteService) LookupInvite(ctx context.Context, token string) (*domain.RoomInvite, error) {
+	return s.inviteRepo.GetPending(ctx, hashInviteToken(token))
+}
+
+// AcceptInvite joins userID to the invite's chatroom. Each invite can be
+// accepted once.
+func (s *InviteService) AcceptInvite(ctx context.Context, token, userID string) (*domain.RoomInvite, error) {
+	invite, err := s.inviteRepo.Accept(ctx, hashInviteToken(token), userID)
+	if err != nil {
+		return nil, err
+	}
+	publish(ctx, s.events, domain.UserJoinedRoom{ChatroomID: invite.ChatroomID, UserID: userID})
+	return invite, nil
+}
+
+func (s *InviteService) ownedChatroom(ctx context.Context, chatroomID, requesterID string) (*domain.Chatroom, error) {
+	chatroom, err := s.chatroomRepo.GetByID(ctx, chatroomID)
+	if err != nil {
+		return nil, err
+	}
+	if chatroom.CreatedBy != requesterID {
+		return nil, domain.ErrNotOwner
+	}
+	return chatroom, nil
+}
+
+// hashInviteToken returns the form of an invite token that is stored, so a
+// database leak does not leak usable invite links
+func hashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+type stubInviteMailer struct {
+	to, inviterName, chatroomName, token string
+	expiresIn                            time.Duration
+	err                                  error
+}
+
+func (m *stubInviteMailer) SendInvite(ctx context.Context, to, inviterName, chatroomName, token string, expiresIn time.Duration) error {
+	m.to, m.inviterName, m.chatroomName, m.token, m.expiresIn = to, inviterName, chatroomName, token, expiresIn
+	return m.err
+}
+
+func newTestInviteService(t *testing.T, mailer InviteMailer) (*InviteService, *testutil.MockRoomInviteRepository) {
+	t.Helper()
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["room-1"] = testutil.NewTestChatroom(
+		testutil.WithChatroomID("room-1"), testutil.WithChatroomName("general"), testutil.WithCreatedBy("owner-1"))
+	userRepo := testutil.NewMockUserRepository()
+	userRepo.Users["owner-1"] = testutil.NewTestUser(testutil.WithUserID("owner-1"), testutil.WithUsername("alice"))
+	userRepo.Users["user-2"] = testutil.NewTestUser(testutil.WithUserID("user-2"), testutil.WithEmail("bob@example.com"))
+	inviteRepo := testutil.NewMockRoomInviteRepository()
+	return NewInviteService(inviteRepo, chatroomRepo, userRepo, mailer, 0), inviteRepo
+}
+
+func TestInviteService_InviteAndAccept(t *testing.T) {
+	mailer := &stubInviteMailer{}
+	invites, inviteRepo := newTestInviteService(t, mailer)
+	ctx := context.Background()
+
+	invite, err := invites.Invite(ctx, "room-1", "owner-1", "  New@Example.com ")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, invite.Email, "new@example.com")
+	testutil.AssertEqual(t, mailer.to, "new@example.com")
+	testutil.AssertEqual(t, mailer.inviterName, "alice")
+	testutil.AssertEqual(t, mailer.chatroomName, "general")
+	testutil.AssertEqual(t, mailer.expiresIn, DefaultInviteTTL)
+	testutil.AssertEqual(t, len(mailer.token), 64)
+	testutil.AssertNotEqual(t, invite.TokenHash, mailer.token)
+
+	found, err := invites.LookupInvite(ctx, mailer.token)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, found.ID, invite.ID)
+
+	accepted, err := invites.AcceptInvite(ctx, mailer.token, "new-user")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, accepted.ChatroomID, "room-1")
+	testutil.AssertEqual(t, inviteRepo.Accepted[invite.ID], "new-user")
+
+	_, err = invites.AcceptInvite(ctx, mailer.token, "someone-else")
+	testutil.AssertErrorIs(t, err, domain.ErrInviteNotFound)
+}
+
+func TestInviteService_InviteValidation(t *testing.T) {
+	invites, _ := newTestInviteService(t, &stubInviteMailer{})
+	ctx := context.Background()
+
+	_, err := invites.Invite(ctx, "room-1", "user-2", "new@example.com")
+	testutil.AssertErrorIs(t, err, domain.ErrNotOwner)
+	_, err = invites.Invite(ctx, "missing", "owner-1", "new@example.com")
+	testutil.AssertErrorIs(t, err, domain.ErrChatroomNotFound)
+	_, err = invites.Invite(ctx, "room-1", "owner-1", "not-an-email")
+	testutil.AssertErrorIs(t, err, domain.ErrInvalidInput)
+}
+
+func TestInviteService_InviteRegisteredAddress(t *testing.T) {
+	mailer := &stubInviteMailer{}
+	invites, _ := newTestInviteService(t, mailer)
+	ctx := context.Background()
+
+	// A registered address is invited like any other, without revealing
+	// that it has an account
+	invite, err := invites.Invite(ctx, "room-1", "owner-1", "bob@example.com")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, invite.Email, "bob@example.com")
+	testutil.AssertEqual(t, mailer.to, "bob@example.com")
+}
+
+func TestInviteService_RevokesUnsentInvites(t *testing.T) {
+	invites, inviteRepo := newTestInviteService(t, &stubInviteMailer{err: errors.New("smtp down")})
+	ctx := context.Background()
+
+	_, err := invites.Invite(ctx, "room-1", "owner-1", "new@example.com")
+	testutil.AssertError(t, err)
+	testutil.AssertEqual(t, len(inviteRepo.Invites), 0)
+}
+
+func TestInviteService_PendingAndRevoke(t *testing.T) {
+	mailer := &stubInviteMailer{}
+	invites, _ := newTestInviteService(t, mailer)
+	ctx := context.Background()
+
+	invite, err := invites.Invite(ctx, "room-1", "owner-1", "new@example.com")
+	testutil.AssertNoError(t, err)
+
+	_, err = invites.PendingInvites(ctx, "room-1", "user-2")
+	testutil.AssertErrorIs(t, err, domain.ErrNotOwner)
+	pending, err := invites.PendingInvites(ctx, "room-1", "owner-1")
+	testutil.AssertNoError(t, err)
+	testutil.AssertLen(t, pending, 1)
+
+	testutil.AssertErrorIs(t, invites.RevokeInvite(ctx, "room-1", "user-2", invite.ID), domain.ErrNotOwner)
+	testutil.AssertNoError(t, invites.RevokeInvite(ctx, "room-1", "owner-1", invite.ID))
+	_, err = invites.LookupInvite(ctx, mailer.token)
+	testutil.AssertErrorIs(t, err, domain.ErrInviteNotFound)
+}
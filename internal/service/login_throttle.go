@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"jobsity-chat/internal/domain"
+)
+
+// LoginThrottleConfig sets the delays of a LoginThrottle; zero values use
+// the defaults
+type LoginThrottleConfig struct {
+	// BaseDelay is the delay after the first failure, doubled on each
+	// further one; default 1s
+	BaseDelay time.Duration
+	// MaxDelay caps the delay; default 30s
+	MaxDelay time.Duration
+	// Window is how long failures are remembered after the last one;
+	// default 15m
+	Window time.Duration
+}
+
+// LoginThrottle delays repeated failed logins of a username from an IP:
+// after n consecutive failures the next attempt is accepted BaseDelay·2ⁿ⁻¹
+// after the last one. Failures are kept in a store shared by the server
+// instances, so the delays hold behind a load balancer. Keying on both
+// username and IP slows down password guessing without locking out the
+// account owner or other users behind the same IP.
+type LoginThrottle struct {
+	repo domain.LoginFailureRepository
+	cfg  LoginThrottleConfig
+	now  func() time.Time
+}
+
+func NewLoginThrottle(repo domain.LoginFailureRepository, cfg LoginThrottleConfig) *LoginThrottle {
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = time.Second
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = 30 * time.Second
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 15 * time.Minute
+	}
+	return &LoginThrottle{repo: repo, cfg: cfg, now: time.Now}
+}
+
+// Delay returns how long after the last of the given consecutive failures
+// the next attempt is accepted
+func (t *LoginThrottle) Delay(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	delay := t.cfg.BaseDelay
+	for range failures - 1 {
+		if delay >= t.cfg.MaxDelay {
+			break
+		}
+		delay *= 2
+	}
+	return min(delay, t.cfg.MaxDelay)
+}
+
+// Reserve counts an attempt of username from ip as a failure before its
+// password is checked, so that concurrent attempts cannot all pass while
+// none has failed yet. It returns a LoginThrottledError when the last
+// failure is too recent for another attempt. An attempt that succeeds is
+// forgotten by Succeeded; one that fails for another reason than its
+// credentials is given back by Release.
+func (t *LoginThrottle) Reserve(ctx context.Context, username, ip string) error {
+	now := t.now()
+	failures, reserved, err := t.repo.Reserve(ctx, username, ip, now, now.Add(-t.cfg.Window), t.cfg.BaseDelay, t.cfg.MaxDelay)
+	if err != nil {
+		return err
+	}
+	if !reserved {
+		// wait is not positive when the failures were forgotten meanwhile
+		wait := failures.LastFailedAt.Add(t.Delay(failures.Count)).Sub(now)
+		return &domain.LoginThrottledError{RetryAfter: max(wait, time.Second)}
+	}
+	// The count includes this attempt
+	if failures.Count > 2 {
+		slog.Warn("repeated failed logins",
+			slog.String("username", username),
+			slog.String("ip", ip),
+			slog.Int("failures", failures.Count-1))
+	}
+	return nil
+}
+
+// Release gives back an attempt reserved by Reserve that did not fail on
+// its credentials, e.g. because the server was busy
+func (t *LoginThrottle) Release(ctx context.Context, username, ip string) error {
+	return t.repo.Release(ctx, username, ip)
+}
+
+// Succeeded forgets the failures of username from ip
+func (t *LoginThrottle) Succeeded(ctx context.Context, username, ip string) error {
+	return t.repo.Reset(ctx, username, ip)
+}
+
+// DeleteExpired deletes the failures older than the window, as the
+// login_failure_cleanup job
+func (t *LoginThrottle) DeleteExpired(ctx context.Context) error {
+	deleted, err := t.repo.DeleteBefore(ctx, t.now().Add(-t.cfg.Window))
+	if err != nil {
+		return fmt.Errorf("login failure cleanup failed: %w", err)
+	}
+	slog.Info("login failure cleanup completed", slog.Int64("login_failures_deleted", deleted))
+	return nil
+}
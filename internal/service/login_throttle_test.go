@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestLoginThrottle_Delay(t *testing.T) {
+	throttle := NewLoginThrottle(nil, LoginThrottleConfig{BaseDelay: time.Second, MaxDelay: 5 * time.Second})
+
+	for failures, want := range []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		testutil.AssertEqual(t, throttle.Delay(failures), want)
+	}
+	testutil.AssertEqual(t, throttle.Delay(1000), 5*time.Second)
+}
+
+func TestLoginThrottle_Reserve(t *testing.T) {
+	repo := testutil.NewMockLoginFailureRepository()
+	throttle := NewLoginThrottle(repo, LoginThrottleConfig{})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	throttle.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// The first attempt is accepted and counted as a failure until it
+	// succeeds, so the next one waits a second
+	testutil.AssertNoError(t, throttle.Reserve(ctx, "alice", "203.0.113.7"))
+	err := throttle.Reserve(ctx, "alice", "203.0.113.7")
+	var throttled *domain.LoginThrottledError
+	testutil.AssertTrue(t, errors.As(err, &throttled), "expected LoginThrottledError")
+	testutil.AssertEqual(t, throttled.RetryAfter, time.Second)
+	testutil.AssertErrorIs(t, err, domain.ErrLoginThrottled)
+	testutil.AssertEqual(t, repo.Failures[testutil.LoginFailureKey("alice", "203.0.113.7")].Count, 1)
+
+	// The delay doubles with each failure
+	now = now.Add(time.Second)
+	testutil.AssertNoError(t, throttle.Reserve(ctx, "Alice", "203.0.113.7"))
+	now = now.Add(time.Second)
+	err = throttle.Reserve(ctx, "alice", "203.0.113.7")
+	testutil.AssertTrue(t, errors.As(err, &throttled), "expected LoginThrottledError")
+	testutil.AssertEqual(t, throttled.RetryAfter, time.Second)
+
+	// Other IPs and usernames are unaffected
+	testutil.AssertNoError(t, throttle.Reserve(ctx, "alice", "198.51.100.1"))
+	testutil.AssertNoError(t, throttle.Reserve(ctx, "bob", "203.0.113.7"))
+
+	// A released attempt is not counted
+	testutil.AssertNoError(t, throttle.Release(ctx, "bob", "203.0.113.7"))
+	testutil.AssertEqual(t, repo.Failures[testutil.LoginFailureKey("bob", "203.0.113.7")].Count, 0)
+	testutil.AssertNoError(t, throttle.Reserve(ctx, "bob", "203.0.113.7"))
+
+	// A success forgets the failures
+	testutil.AssertNoError(t, throttle.Succeeded(ctx, "alice", "203.0.113.7"))
+	testutil.AssertNoError(t, throttle.Reserve(ctx, "alice", "203.0.113.7"))
+}
+
+func TestLoginThrottle_ReserveConcurrent(t *testing.T) {
+	repo := testutil.NewMockLoginFailureRepository()
+	throttle := NewLoginThrottle(repo, LoginThrottleConfig{})
+	ctx := context.Background()
+
+	const attempts = 20
+	var wg sync.WaitGroup
+	var accepted atomic.Int32
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := throttle.Reserve(ctx, "alice", "203.0.113.7")
+			if err == nil {
+				accepted.Add(1)
+			} else if !errors.Is(err, domain.ErrLoginThrottled) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	testutil.AssertEqual(t, accepted.Load(), int32(1))
+	testutil.AssertEqual(t, repo.Failures[testutil.LoginFailureKey("alice", "203.0.113.7")].Count, 1)
+}
+
+func TestLoginThrottle_WindowRestartsCount(t *testing.T) {
+	repo := testutil.NewMockLoginFailureRepository()
+	throttle := NewLoginThrottle(repo, LoginThrottleConfig{Window: time.Minute})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	throttle.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for range 3 {
+		testutil.AssertNoError(t, throttle.Reserve(ctx, "alice", "203.0.113.7"))
+		now = now.Add(10 * time.Second)
+	}
+	testutil.AssertEqual(t, repo.Failures[testutil.LoginFailureKey("alice", "203.0.113.7")].Count, 3)
+
+	now = now.Add(2 * time.Minute)
+	testutil.AssertNoError(t, throttle.Reserve(ctx, "alice", "203.0.113.7"))
+	testutil.AssertEqual(t, repo.Failures[testutil.LoginFailureKey("alice", "203.0.113.7")].Count, 1)
+
+	now = now.Add(2 * time.Minute)
+	testutil.AssertNoError(t, throttle.DeleteExpired(ctx))
+	testutil.AssertEqual(t, len(repo.Failures), 0)
+}
+
+func TestLoginThrottle_RepositoryError(t *testing.T) {
+	repo := testutil.NewMockLoginFailureRepository()
+	repo.ReserveFunc = func(ctx context.Context, username, ip string, at, since time.Time, baseDelay, maxDelay time.Duration) (*domain.LoginFailures, bool, error) {
+		return nil, false, errors.New("database error")
+	}
+	throttle := NewLoginThrottle(repo, LoginThrottleConfig{})
+
+	err := throttle.Reserve(context.Background(), "alice", "203.0.113.7")
+	testutil.AssertError(t, err)
+	testutil.AssertFalse(t, errors.Is(err, domain.ErrLoginThrottled), "a repository error is not a throttle")
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/observability"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultFlagHideThreshold is how many open flags hide a message
+	DefaultFlagHideThreshold = 3
+
+	// MaxFlagReasonLength bounds the reason a user gives for a flag
+	MaxFlagReasonLength = 500
+
+	defaultQueueLimit = 50
+	maxQueueLimit     = 200
+)
+
+// ShadowBanFilter applies shadow-bans to live message delivery
+type ShadowBanFilter interface {
+	SetShadowBanned(chatroomID, userID string, banned bool)
+}
+
+// ModerationService handles message flags, the moderation queue and
+// shadow-bans
+type ModerationService struct {
+	moderationRepo domain.ModerationRepository
+	messageRepo    domain.MessageRepository
+	chatroomRepo   domain.ChatroomRepository
+	hideThreshold  int
+	shadowFilter   ShadowBanFilter
+}
+
+func NewModerationService(moderationRepo domain.ModerationRepository, messageRepo domain.MessageRepository, chatroomRepo domain.ChatroomRepository) *ModerationService {
+	return &ModerationService{
+		moderationRepo: moderationRepo,
+		messageRepo:    messageRepo,
+		chatroomRepo:   chatroomRepo,
+		hideThreshold:  DefaultFlagHideThreshold,
+	}
+}
+
+// SetHideThreshold sets how many open flags hide a message from chatroom
+// history until a moderator reviews it. Zero or less never hides messages.
+func (s *ModerationService) SetHideThreshold(threshold int) {
+	s.hideThreshold = threshold
+}
+
+// SetShadowBanFilter makes shadow-bans take effect on live connections
+// immediately instead of on the next connection to the chatroom
+func (s *ModerationService) SetShadowBanFilter(filter ShadowBanFilter) {
+	s.shadowFilter = filter
+}
+
+// FlagMessage records reporterID's flag on a message in a chatroom they
+// belong to, hiding the message once it reaches the hide threshold.
+// Returns whether the message is now hidden.
+func (s *ModerationService) FlagMessage(ctx context.Context, messageID, reporterID, reason string) (bool, error) {
+	if _, err := uuid.Parse(messageID); err != nil {
+		return false, domain.ErrMessageNotFound
+	}
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > MaxFlagReasonLength {
+		return false, domain.ErrInvalidInput
+	}
+
+	message, err := s.messageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return false, err
+	}
+	if message.UserID == reporterID {
+		return false, domain.ErrInvalidInput
+	}
+
+	isMember, err := s.chatroomRepo.IsMember(ctx, message.ChatroomID, reporterID)
+	if err != nil {
+		return false, err
+	}
+	if !isMember {
+		return false, domain.ErrNotMember
+	}
+
+	openFlags, err := s.moderationRepo.Flag(ctx, &domain.MessageFlag{
+		MessageID:  messageID,
+		ReporterID: reporterID,
+		Reason:     reason,
+	})
+	if err != nil {
+		return false, err
+	}
+	observability.Audit(ctx, "message_flagged",
+		slog.String("message_id", messageID),
+		slog.String("chatroom_id", message.ChatroomID),
+		slog.String("reporter_id", reporterID),
+		slog.Int("open_flags", openFlags))
+
+	if s.hideThreshold <= 0 || openFlags < s.hideThreshold {
+		return false, nil
+	}
+	if err := s.moderationRepo.Hide(ctx, messageID); err != nil {
+		return false, err
+	}
+	observability.Audit(ctx, "message_hidden",
+		slog.String("message_id", messageID),
+		slog.String("chatroom_id", message.ChatroomID),
+		slog.Int("open_flags", openFlags))
+	return true, nil
+}
+
+// Queue returns the messages awaiting review, most flagged first
+func (s *ModerationService) Queue(ctx context.Context, limit int) ([]*domain.FlaggedMessage, error) {
+	if limit <= 0 {
+		limit = defaultQueueLimit
+	}
+	if limit > maxQueueLimit {
+		limit = maxQueueLimit
+	}
+	return s.moderationRepo.Queue(ctx, limit)
+}
+
+// Resolve closes the open flags on a message, either keeping it (and
+// showing it again if it was hidden) or soft-deleting it
+func (s *ModerationService) Resolve(ctx context.Context, messageID, moderatorID, action string) error {
+	if action != domain.FlagActionKeep && action != domain.FlagActionDelete {
+		return domain.ErrInvalidInput
+	}
+	if _, err := uuid.Parse(messageID); err != nil {
+		return domain.ErrNoOpenFlags
+	}
+
+	if err := s.moderationRepo.Resolve(ctx, messageID, moderatorID, action); err != nil {
+		return err
+	}
+	observability.Audit(ctx, "message_flags_resolved",
+		slog.String("message_id", messageID),
+		slog.String("moderator_id", moderatorID),
+		slog.String("resolution", action))
+	return nil
+}
+
+// RestoreMessage shows a deleted message again, if it has not been purged
+// yet. Its flags stay resolved.
+func (s *ModerationService) RestoreMessage(ctx context.Context, messageID, adminID string) error {
+	if _, err := uuid.Parse(messageID); err != nil {
+		return domain.ErrMessageNotFound
+	}
+
+	if err := s.messageRepo.Restore(ctx, messageID); err != nil {
+		return err
+	}
+	observability.Audit(ctx, "message_restored",
+		slog.String("message_id", messageID),
+		slog.String("admin_id", adminID))
+	return nil
+}
+
+// SetShadowBan shadow-bans or lifts the shadow-ban of a member of a
+// chatroom. A shadow-banned user's messages are still stored and echoed back
+// to them, but not delivered to anyone else.
+func (s *ModerationService) SetShadowBan(ctx context.Context, chatroomID, userID, moderatorID string, banned bool) error {
+	if userID == moderatorID {
+		return domain.ErrInvalidInput
+	}
+	if _, err := s.chatroomRepo.GetByID(ctx, chatroomID); err != nil {
+		return err
+	}
+	if banned {
+		isMember, err := s.chatroomRepo.IsMember(ctx, chatroomID, userID)
+		if err != nil {
+			return err
+		}
+		if !isMember {
+			return domain.ErrNotMember
+		}
+	}
+
+	if err := s.moderationRepo.SetShadowBan(ctx, chatroomID, userID, moderatorID, banned); err != nil {
+		return err
+	}
+	if s.shadowFilter != nil {
+		s.shadowFilter.SetShadowBanned(chatroomID, userID, banned)
+	}
+
+	action := "user_shadow_banned"
+	if !banned {
+		action = "user_shadow_ban_lifted"
+	}
+	observability.Audit(ctx, action,
+		slog.String("chatroom_id", chatroomID),
+		slog.String("target_user_id", userID),
+		slog.String("moderator_id", moderatorID))
+	return nil
+}
+
+// ShadowBannedUsers returns the IDs of the users shadow-banned in a chatroom
+func (s *ModerationService) ShadowBannedUsers(ctx context.Context, chatroomID string) ([]string, error) {
+	return s.moderationRepo.ShadowBannedUsers(ctx, chatroomID)
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+const flaggedMessageID = "33333333-3333-3333-3333-333333333333"
+
+type recordingShadowFilter map[string]bool
+
+func (f recordingShadowFilter) SetShadowBanned(chatroomID, userID string, banned bool) {
+	f[chatroomID+"/"+userID] = banned
+}
+
+func newModerationTestService(members ...string) (*ModerationService, *testutil.MockModerationRepository) {
+	moderationRepo := testutil.NewMockModerationRepository()
+	messageRepo := testutil.NewMockMessageRepository()
+	messageRepo.Messages = append(messageRepo.Messages, testutil.NewTestMessage(
+		testutil.WithMessageID(flaggedMessageID),
+		testutil.WithMessageChatroomID("room-1"),
+		testutil.WithMessageUserID("author"),
+	))
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["room-1"] = testutil.NewTestChatroom(testutil.WithChatroomID("room-1"))
+	chatroomRepo.Members["room-1"] = map[string]bool{"author": true}
+	for _, member := range members {
+		chatroomRepo.Members["room-1"][member] = true
+	}
+	return NewModerationService(moderationRepo, messageRepo, chatroomRepo), moderationRepo
+}
+
+func TestModerationService_FlagMessage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("hides_message_at_threshold", func(t *testing.T) {
+		moderation, moderationRepo := newModerationTestService("user-1", "user-2")
+		moderation.SetHideThreshold(2)
+
+		hidden, err := moderation.FlagMessage(ctx, flaggedMessageID, "user-1", "spam")
+		testutil.AssertNoError(t, err)
+		testutil.AssertFalse(t, hidden, "one flag should not hide the message")
+
+		hidden, err = moderation.FlagMessage(ctx, flaggedMessageID, "user-2", "")
+		testutil.AssertNoError(t, err)
+		testutil.AssertTrue(t, hidden, "second flag should hide the message")
+		testutil.AssertTrue(t, moderationRepo.Hidden[flaggedMessageID], "message should be hidden in the repository")
+	})
+
+	t.Run("zero_threshold_never_hides", func(t *testing.T) {
+		moderation, moderationRepo := newModerationTestService("user-1")
+		moderation.SetHideThreshold(0)
+
+		hidden, err := moderation.FlagMessage(ctx, flaggedMessageID, "user-1", "")
+		testutil.AssertNoError(t, err)
+		testutil.AssertFalse(t, hidden || moderationRepo.Hidden[flaggedMessageID], "message should stay visible")
+	})
+
+	t.Run("rejected_flags", func(t *testing.T) {
+		moderation, _ := newModerationTestService("user-1")
+
+		_, err := moderation.FlagMessage(ctx, flaggedMessageID, "author", "")
+		testutil.AssertErrorIs(t, err, domain.ErrInvalidInput)
+
+		_, err = moderation.FlagMessage(ctx, flaggedMessageID, "outsider", "")
+		testutil.AssertErrorIs(t, err, domain.ErrNotMember)
+
+		_, err = moderation.FlagMessage(ctx, "44444444-4444-4444-4444-444444444444", "user-1", "")
+		testutil.AssertErrorIs(t, err, domain.ErrMessageNotFound)
+
+		_, err = moderation.FlagMessage(ctx, flaggedMessageID, "user-1", strings.Repeat("x", MaxFlagReasonLength+1))
+		testutil.AssertErrorIs(t, err, domain.ErrInvalidInput)
+
+		_, err = moderation.FlagMessage(ctx, flaggedMessageID, "user-1", "")
+		testutil.AssertNoError(t, err)
+		_, err = moderation.FlagMessage(ctx, flaggedMessageID, "user-1", "")
+		testutil.AssertErrorIs(t, err, domain.ErrAlreadyFlagged)
+	})
+}
+
+func TestModerationService_Resolve(t *testing.T) {
+	ctx := context.Background()
+
+	moderation, moderationRepo := newModerationTestService("user-1")
+	_, err := moderation.FlagMessage(ctx, flaggedMessageID, "user-1", "")
+	testutil.AssertNoError(t, err)
+
+	err = moderation.Resolve(ctx, flaggedMessageID, "mod-1", "ignore")
+	testutil.AssertErrorIs(t, err, domain.ErrInvalidInput)
+
+	err = moderation.Resolve(ctx, flaggedMessageID, "mod-1", domain.FlagActionDelete)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, moderationRepo.Resolutions[flaggedMessageID], domain.FlagActionDelete)
+
+	err = moderation.Resolve(ctx, flaggedMessageID, "mod-1", domain.FlagActionKeep)
+	testutil.AssertErrorIs(t, err, domain.ErrNoOpenFlags)
+}
+
+func TestModerationService_RestoreMessage(t *testing.T) {
+	ctx := context.Background()
+	messageRepo := testutil.NewMockMessageRepository()
+	messageRepo.DeletedAt[flaggedMessageID] = time.Now()
+	moderation := NewModerationService(testutil.NewMockModerationRepository(), messageRepo, testutil.NewMockChatroomRepository())
+
+	err := moderation.RestoreMessage(ctx, "not-a-uuid", "admin-1")
+	testutil.AssertErrorIs(t, err, domain.ErrMessageNotFound)
+
+	err = moderation.RestoreMessage(ctx, flaggedMessageID, "admin-1")
+	testutil.AssertNoError(t, err)
+	_, deleted := messageRepo.DeletedAt[flaggedMessageID]
+	testutil.AssertFalse(t, deleted, "message should no longer be deleted")
+
+	err = moderation.RestoreMessage(ctx, flaggedMessageID, "admin-1")
+	testutil.AssertErrorIs(t, err, domain.ErrMessageNotFound)
+}
+
+func TestModerationService_SetShadowBan(t *testing.T) {
+	ctx := context.Background()
+
+	moderation, moderationRepo := newModerationTestService("user-1")
+	filter := recordingShadowFilter{}
+	moderation.SetShadowBanFilter(filter)
+
+	err := moderation.SetShadowBan(ctx, "room-1", "user-1", "mod-1", true)
+	testutil.AssertNoError(t, err)
+	testutil.AssertTrue(t, moderationRepo.ShadowBans["room-1"]["user-1"], "ban should be stored")
+	testutil.AssertTrue(t, filter["room-1/user-1"], "ban should reach the live filter")
+
+	banned, err := moderation.ShadowBannedUsers(ctx, "room-1")
+	testutil.AssertNoError(t, err)
+	testutil.AssertLen(t, banned, 1)
+
+	err = moderation.SetShadowBan(ctx, "room-1", "user-1", "mod-1", false)
+	testutil.AssertNoError(t, err)
+	testutil.AssertFalse(t, moderationRepo.ShadowBans["room-1"]["user-1"], "ban should be lifted")
+	testutil.AssertFalse(t, filter["room-1/user-1"], "lift should reach the live filter")
+
+	err = moderation.SetShadowBan(ctx, "room-1", "mod-1", "mod-1", true)
+	testutil.AssertErrorIs(t, err, domain.ErrInvalidInput)
+
+	err = moderation.SetShadowBan(ctx, "room-1", "outsider", "mod-1", true)
+	testutil.AssertErrorIs(t, err, domain.ErrNotMember)
+
+	err = moderation.SetShadowBan(ctx, "room-missing", "user-1", "mod-1", true)
+	testutil.AssertErrorIs(t, err, domain.ErrChatroomNotFound)
+}
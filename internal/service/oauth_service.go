@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"jobsity-chat/internal/domain"
+)
+
+// maxUsernameAttempts bounds the search for a free username for new accounts
+const maxUsernameAttempts = 10
+
+// SessionCreator issues sessions to users who authenticated
+type SessionCreator interface {
+	CreateSession(ctx context.Context, userID string, opts LoginOptions) (*domain.Session, error)
+}
+
+// OAuthService signs users in with external identity providers. On first
+// login, a provider account with a verified email gets a newly created user.
+// It is never linked to an existing user by email alone, since local emails
+// are not verified and whoever registered one first would get the account;
+// users link providers themselves while signed in, see Link.
+type OAuthService struct {
+	userRepo     domain.UserRepository
+	identityRepo domain.IdentityRepository
+	authService  SessionCreator
+	hasher       PasswordHasher
+	// tx is nil until SetTxManager is called
+	tx domain.TxManager
+	// reserved is nil until SetReservedNames is called
+	reserved *ReservedNames
+}
+
+func NewOAuthService(userRepo domain.UserRepository, identityRepo domain.IdentityRepository, authService SessionCreator) *OAuthService {
+	return &OAuthService{
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		authService:  authService,
+		hasher:       NewHashingPool(HashingPoolConfig{}),
+	}
+}
+
+// SetPasswordHasher replaces the default hashing pool the random passwords
+// of new accounts are hashed with, so sign-ups share the login's pool
+func (s *OAuthService) SetPasswordHasher(hasher PasswordHasher) {
+	s.hasher = hasher
+}
+
+// SetTxManager makes creating an account and linking it to its external
+// identity one transaction, so a failed link leaves no orphaned account
+func (s *OAuthService) SetTxManager(tx domain.TxManager) {
+	s.tx = tx
+}
+
+// SetReservedNames keeps new accounts from taking reserved usernames; a
+// provider suggesting one gets it suffixed, as if it were taken
+func (s *OAuthService) SetReservedNames(reserved *ReservedNames) {
+	s.reserved = reserved
+}
+
+// Login resolves profile to a local user, linking or creating it as needed,
+// and issues a session
+func (s *OAuthService) Login(ctx context.Context, profile *domain.ExternalProfile, opts LoginOptions) (*domain.Session, *domain.User, error) {
+	user, err := s.resolveUser(ctx, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.IsSystem {
+		return nil, nil, domain.ErrInvalidCredentials
+	}
+	if !user.IsActive() {
+		return nil, nil, domain.ErrUserDeactivated
+	}
+
+	session, err := s.authService.CreateSession(ctx, user.ID, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return session, user, nil
+}
+
+func (s *OAuthService) resolveUser(ctx context.Context, profile *domain.ExternalProfile) (*domain.User, error) {
+	identity, err := s.identityRepo.GetByProviderSubject(ctx, profile.Provider, profile.Subject)
+	if err == nil {
+		return s.userRepo.GetByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, domain.ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	var user *domain.User
+	err = withinTx(ctx, s.tx, func(ctx context.Context) error {
+		user, err = s.linkUser(ctx, profile)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("linked external identity",
+		slog.String("provider", profile.Provider),
+		slog.String("user_id", user.ID))
+	return user, nil
+}
+
+// linkUser creates a user for profile and links them. An address the
+// provider does not vouch for could belong to anyone, and one taken by a
+// local user must be linked by that user, signed in.
+func (s *OAuthService) linkUser(ctx context.Context, profile *domain.ExternalProfile) (*domain.User, error) {
+	if !profile.EmailVerified {
+		return nil, domain.ErrEmailNotVerified
+	}
+	_, err := s.userRepo.GetByEmail(ctx, profile.Email)
+	if err == nil {
+		return nil, domain.ErrEmailExists
+	}
+	if !errors.Is(err, domain.ErrUserNotFound) {
+		return nil, err
+	}
+
+	user, err := s.createUser(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.identityRepo.Create(ctx, &domain.UserIdentity{
+		Provider: profile.Provider,
+		Subject:  profile.Subject,
+		UserID:   user.ID,
+		Email:    profile.Email,
+	}); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Link links the provider account of profile to userID, who signed in with
+// their password, so they can sign in through the provider from then on.
+// The email of the provider account does not matter.
+func (s *OAuthService) Link(ctx context.Context, userID string, profile *domain.ExternalProfile) error {
+	identity, err := s.identityRepo.GetByProviderSubject(ctx, profile.Provider, profile.Subject)
+	if err == nil {
+		if identity.UserID != userID {
+			return domain.ErrIdentityLinked
+		}
+		return nil
+	}
+	if !errors.Is(err, domain.ErrIdentityNotFound) {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.IsSystem || !user.IsActive() {
+		return domain.ErrInvalidCredentials
+	}
+
+	if err := s.identityRepo.Create(ctx, &domain.UserIdentity{
+		Provider: profile.Provider,
+		Subject:  profile.Subject,
+		UserID:   user.ID,
+		Email:    profile.Email,
+	}); err != nil {
+		return err
+	}
+
+	slog.Info("linked external identity",
+		slog.String("provider", profile.Provider),
+		slog.String("user_id", user.ID))
+	return nil
+}
+
+// createUser registers a user for profile. The account gets a random
+// password, so it can only sign in through the provider.
+func (s *OAuthService) createUser(ctx context.Context, profile *domain.ExternalProfile) (*domain.User, error) {
+	return provisionUser(ctx, s.userRepo, s.hasher, profile.Email, profile.Username, s.reserved)
+}
+
+// provisionUser creates a user with a random password for an account managed
+// elsewhere. The suggested username is sanitized and suffixed when it is
+// taken, reserved or can be mistaken for another user's.
+func provisionUser(ctx context.Context, userRepo domain.UserRepository, hasher PasswordHasher, email, suggestedUsername string, reserved *ReservedNames) (*domain.User, error) {
+	if !emailRegex.MatchString(email) || len(email) > 255 {
+		return nil, domain.ErrInvalidInput
+	}
+
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return nil, err
+	}
+	hashedPassword, err := hasher.Hash(ctx, hex.EncodeToString(password))
+	if err != nil {
+		return nil, err
+	}
+
+	base := sanitizeUsername(suggestedUsername)
+	for attempt := range maxUsernameAttempts {
+		username := base
+		if attempt > 0 {
+			suffix := fmt.Sprintf("_%d", attempt+1)
+			username = base[:min(len(base), 50-len(suffix))] + suffix
+		}
+
+		if reserved.Contains(username) {
+			continue
+		}
+		if _, err := userRepo.GetByUsername(ctx, username); err == nil {
+			continue
+		}
+		confusable, err := userRepo.HasConfusableUsername(ctx, username, "")
+		if err != nil {
+			return nil, err
+		}
+		if confusable {
+			continue
+		}
+
+		user := &domain.User{
+			Username:     username,
+			Email:        email,
+			PasswordHash: hashedPassword,
+		}
+		if err := userRepo.Create(ctx, user); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	return nil, domain.ErrUsernameExists
+}
+
+// sanitizeUsername turns a provider-suggested name into a valid username
+func sanitizeUsername(name string) string {
+	var b strings.Builder
+	for _, r := range NormalizeUsername(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		case r == '-' || r == '.' || r == ' ':
+			b.WriteRune('_')
+		}
+	}
+
+	username := b.String()
+	if strings.Trim(username, "_") == "" {
+		return "user"
+	}
+	if len(username) > 50 {
+		username = username[:50]
+	}
+	for len(username) < 3 {
+		username += "_"
+	}
+	return username
+}
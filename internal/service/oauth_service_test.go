@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func newTestOAuthService() (*OAuthService, *testutil.MockUserRepository, *testutil.MockIdentityRepository) {
+	userRepo := testutil.NewMockUserRepository()
+	identityRepo := testutil.NewMockIdentityRepository()
+	authService := NewAuthService(userRepo, testutil.NewMockSessionRepository())
+	return NewOAuthService(userRepo, identityRepo, authService), userRepo, identityRepo
+}
+
+func TestOAuthService_Login_CreatesUser(t *testing.T) {
+	oauthService, userRepo, identityRepo := newTestOAuthService()
+
+	session, user, err := oauthService.Login(context.Background(), &domain.ExternalProfile{
+		Provider:      "github",
+		Subject:       "42",
+		Email:         "octo@example.com",
+		EmailVerified: true,
+		Username:      "octo-cat",
+	}, LoginOptions{})
+
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, user.Username, "octo_cat")
+	testutil.AssertEqual(t, session.UserID, user.ID)
+	testutil.AssertEqual(t, len(userRepo.Users), 1)
+	testutil.AssertEqual(t, identityRepo.Identities["github:42"].UserID, user.ID)
+}
+
+// busyHasher is a saturated password hashing pool
+type busyHasher struct{}
+
+func (busyHasher) Hash(ctx context.Context, password string) (string, error) {
+	return "", domain.ErrHashingBusy
+}
+
+func (busyHasher) Compare(ctx context.Context, hash, password string) error {
+	return domain.ErrHashingBusy
+}
+
+func TestOAuthService_Login_HashingBusy(t *testing.T) {
+	oauthService, userRepo, _ := newTestOAuthService()
+	oauthService.SetPasswordHasher(busyHasher{})
+
+	// Sign-ups wait for the shared hashing pool like registrations
+	_, _, err := oauthService.Login(context.Background(), &domain.ExternalProfile{
+		Provider:      "github",
+		Subject:       "42",
+		Email:         "octo@example.com",
+		EmailVerified: true,
+		Username:      "octo",
+	}, LoginOptions{})
+
+	testutil.AssertErrorIs(t, err, domain.ErrHashingBusy)
+	testutil.AssertEqual(t, len(userRepo.Users), 0)
+}
+
+func TestOAuthService_Login_RefusesLinkByEmail(t *testing.T) {
+	oauthService, userRepo, identityRepo := newTestOAuthService()
+	existing := testutil.NewTestUser(
+		testutil.WithUserID("user-1"),
+		testutil.WithUsername("alice"),
+		testutil.WithEmail("alice@example.com"),
+	)
+	userRepo.Users[existing.ID] = existing
+
+	// Even verified by the provider, the address may have been registered
+	// locally by someone else, e.g. to take over the account later
+	_, _, err := oauthService.Login(context.Background(), &domain.ExternalProfile{
+		Provider:      "google",
+		Subject:       "g-1",
+		Email:         "alice@example.com",
+		EmailVerified: true,
+		Username:      "alice",
+	}, LoginOptions{})
+
+	testutil.AssertErrorIs(t, err, domain.ErrEmailExists)
+	testutil.AssertEqual(t, len(identityRepo.Identities), 0)
+}
+
+func TestOAuthService_Login_RefusesUnverifiedEmail(t *testing.T) {
+	oauthService, userRepo, identityRepo := newTestOAuthService()
+
+	_, _, err := oauthService.Login(context.Background(), &domain.ExternalProfile{
+		Provider: "github",
+		Subject:  "42",
+		Email:    "alice@example.com",
+		Username: "alice",
+	}, LoginOptions{})
+
+	testutil.AssertErrorIs(t, err, domain.ErrEmailNotVerified)
+	testutil.AssertEqual(t, len(userRepo.Users), 0)
+	testutil.AssertEqual(t, len(identityRepo.Identities), 0)
+}
+
+func TestOAuthService_Link(t *testing.T) {
+	oauthService, userRepo, identityRepo := newTestOAuthService()
+	alice := testutil.NewTestUser(testutil.WithUserID("user-1"), testutil.WithEmail("alice@example.com"))
+	userRepo.Users[alice.ID] = alice
+	bob := testutil.NewTestUser(testutil.WithUserID("user-2"), testutil.WithUsername("bob"), testutil.WithEmail("bob@example.com"))
+	userRepo.Users[bob.ID] = bob
+	ctx := context.Background()
+
+	// A signed-in user links any provider account, whatever its email
+	profile := &domain.ExternalProfile{Provider: "github", Subject: "42", Email: "alice@work.example.com"}
+	testutil.AssertNoError(t, oauthService.Link(ctx, "user-1", profile))
+	testutil.AssertEqual(t, identityRepo.Identities["github:42"].UserID, "user-1")
+
+	// Linking again is a no-op, but nobody else can take the identity
+	testutil.AssertNoError(t, oauthService.Link(ctx, "user-1", profile))
+	testutil.AssertErrorIs(t, oauthService.Link(ctx, "user-2", profile), domain.ErrIdentityLinked)
+
+	_, user, err := oauthService.Login(ctx, profile, LoginOptions{})
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, user.ID, "user-1")
+}
+
+func TestOAuthService_Login_ExistingIdentity(t *testing.T) {
+	oauthService, userRepo, identityRepo := newTestOAuthService()
+	existing := testutil.NewTestUser(testutil.WithUserID("user-1"))
+	userRepo.Users[existing.ID] = existing
+	identityRepo.Identities["github:42"] = &domain.UserIdentity{Provider: "github", Subject: "42", UserID: "user-1"}
+
+	// The provider email may have changed since the account was linked
+	_, user, err := oauthService.Login(context.Background(), &domain.ExternalProfile{
+		Provider: "github",
+		Subject:  "42",
+		Email:    "new@example.com",
+	}, LoginOptions{})
+
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, user.ID, "user-1")
+}
+
+func TestOAuthService_Login_DeactivatedUser(t *testing.T) {
+	oauthService, userRepo, identityRepo := newTestOAuthService()
+	existing := testutil.NewTestUser(testutil.WithUserID("user-1"))
+	deactivatedAt := time.Now()
+	existing.DeactivatedAt = &deactivatedAt
+	userRepo.Users[existing.ID] = existing
+	identityRepo.Identities["github:42"] = &domain.UserIdentity{Provider: "github", Subject: "42", UserID: "user-1"}
+
+	session, _, err := oauthService.Login(context.Background(), &domain.ExternalProfile{
+		Provider: "github",
+		Subject:  "42",
+	}, LoginOptions{})
+
+	testutil.AssertErrorIs(t, err, domain.ErrUserDeactivated)
+	testutil.AssertNil(t, session)
+}
+
+func TestOAuthService_Login_UsernameTaken(t *testing.T) {
+	oauthService, userRepo, _ := newTestOAuthService()
+	taken := testutil.NewTestUser(
+		testutil.WithUserID("user-1"),
+		testutil.WithUsername("octocat"),
+		testutil.WithEmail("someone@example.com"),
+	)
+	userRepo.Users[taken.ID] = taken
+
+	_, user, err := oauthService.Login(context.Background(), &domain.ExternalProfile{
+		Provider:      "github",
+		Subject:       "42",
+		Email:         "octo@example.com",
+		EmailVerified: true,
+		Username:      "octocat",
+	}, LoginOptions{})
+
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, user.Username, "octocat_2")
+}
+
+func TestOAuthService_Login_UsernameReserved(t *testing.T) {
+	oauthService, _, _ := newTestOAuthService()
+	oauthService.SetReservedNames(NewReservedNames(DefaultReservedUsernames))
+
+	_, user, err := oauthService.Login(context.Background(), &domain.ExternalProfile{
+		Provider:      "github",
+		Subject:       "42",
+		Email:         "octo@example.com",
+		EmailVerified: true,
+		Username:      "Admin",
+	}, LoginOptions{})
+
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, user.Username, "Admin_2")
+}
+
+func TestOAuthService_Login_UsernameConfusable(t *testing.T) {
+	oauthService, userRepo, _ := newTestOAuthService()
+	taken := testutil.NewTestUser(
+		testutil.WithUserID("user-1"),
+		testutil.WithUsername("octocat"),
+		testutil.WithEmail("someone@example.com"),
+	)
+	userRepo.Users[taken.ID] = taken
+
+	_, user, err := oauthService.Login(context.Background(), &domain.ExternalProfile{
+		Provider:      "github",
+		Subject:       "42",
+		Email:         "octo@example.com",
+		EmailVerified: true,
+		Username:      "0ctoCat",
+	}, LoginOptions{})
+
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, user.Username, "0ctoCat_2")
+}
+
+func TestSanitizeUsername(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"octocat", "octocat"},
+		{"jane.doe", "jane_doe"},
+		{"Zoë Smith", "Zo_Smith"},
+		{"ab", "ab_"},
+		{"日本", "user"},
+		{"", "user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			testutil.AssertEqual(t, sanitizeUsername(tt.in), tt.want)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/observability"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHasher hashes passwords and checks them against their hash
+type PasswordHasher interface {
+	Hash(ctx context.Context, password string) (string, error)
+	// Compare returns nil when password matches hash
+	Compare(ctx context.Context, hash, password string) error
+}
+
+// HashingPoolConfig sizes a HashingPool; zero values use the defaults
+type HashingPoolConfig struct {
+	// Workers is how many hashes run at once; default GOMAXPROCS
+	Workers int
+	// QueueSize is how many hashes may wait for a worker; default 4 per
+	// worker. Hashes beyond it are rejected right away.
+	QueueSize int
+	// MaxWait is how long a hash waits for a worker before it is rejected;
+	// default 5s
+	MaxWait time.Duration
+	// Cost is the bcrypt cost; default 12
+	Cost int
+}
+
+// HashingPool runs bcrypt on a bounded number of workers, so a burst of
+// registrations or logins cannot take every CPU from the other endpoints.
+// When workers and queue are full, or a hash waited MaxWait for a worker,
+// it returns domain.ErrHashingBusy for the caller to retry later.
+type HashingPool struct {
+	cfg HashingPoolConfig
+	// admitted holds a slot per hash running or queued, workers one per
+	// hash running
+	admitted chan struct{}
+	workers  chan struct{}
+}
+
+func NewHashingPool(cfg HashingPoolConfig) *HashingPool {
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.GOMAXPROCS(0)
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 4 * cfg.Workers
+	}
+	if cfg.MaxWait <= 0 {
+		cfg.MaxWait = 5 * time.Second
+	}
+	if cfg.Cost <= 0 {
+		cfg.Cost = 12
+	}
+	return &HashingPool{
+		cfg:      cfg,
+		admitted: make(chan struct{}, cfg.Workers+cfg.QueueSize),
+		workers:  make(chan struct{}, cfg.Workers),
+	}
+}
+
+func (p *HashingPool) Hash(ctx context.Context, password string) (string, error) {
+	var hash []byte
+	err := p.run(ctx, "hash", func() (err error) {
+		hash, err = bcrypt.GenerateFromPassword([]byte(password), p.cfg.Cost)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (p *HashingPool) Compare(ctx context.Context, hash, password string) error {
+	return p.run(ctx, "compare", func() error {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	})
+}
+
+// run runs fn on a worker. A caller whose context ends stops waiting, but
+// fn keeps its worker until it finishes, since bcrypt cannot be
+// interrupted; the pool never runs more than Workers hashes.
+func (p *HashingPool) run(ctx context.Context, op string, fn func() error) error {
+	select {
+	case p.admitted <- struct{}{}:
+	default:
+		observability.PasswordHashRejectedTotal.WithLabelValues(op, "queue_full").Inc()
+		return domain.ErrHashingBusy
+	}
+
+	timer := time.NewTimer(p.cfg.MaxWait)
+	defer timer.Stop()
+	select {
+	case p.workers <- struct{}{}:
+	case <-timer.C:
+		<-p.admitted
+		observability.PasswordHashRejectedTotal.WithLabelValues(op, "timeout").Inc()
+		return domain.ErrHashingBusy
+	case <-ctx.Done():
+		<-p.admitted
+		return ctx.Err()
+	}
+
+	observability.PasswordHashesInFlight.Inc()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			<-p.workers
+			<-p.admitted
+			observability.PasswordHashesInFlight.Dec()
+		}()
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
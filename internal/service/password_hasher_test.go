@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashingPool_HashAndCompare(t *testing.T) {
+	pool := NewHashingPool(HashingPoolConfig{Cost: bcrypt.MinCost})
+	ctx := context.Background()
+
+	hash, err := pool.Hash(ctx, "password123")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if err := pool.Compare(ctx, hash, "password123"); err != nil {
+		t.Errorf("Compare() of the right password error = %v", err)
+	}
+	if err := pool.Compare(ctx, hash, "wrong"); !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		t.Errorf("Compare() of a wrong password error = %v", err)
+	}
+}
+
+// occupy runs a hash on pool that blocks until the returned func is called
+func occupy(t *testing.T, pool *HashingPool) (release func()) {
+	t.Helper()
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	go pool.run(context.Background(), "hash", func() error {
+		close(started)
+		<-unblock
+		return nil
+	})
+	<-started
+	return func() { close(unblock) }
+}
+
+func TestHashingPool_Saturated(t *testing.T) {
+	pool := NewHashingPool(HashingPoolConfig{Workers: 1, QueueSize: 1, MaxWait: 50 * time.Millisecond, Cost: bcrypt.MinCost})
+	release := occupy(t, pool)
+	defer release()
+
+	// The queued hash gives up after MaxWait
+	if _, err := pool.Hash(context.Background(), "password123"); !errors.Is(err, domain.ErrHashingBusy) {
+		t.Errorf("Hash() waiting for a worker error = %v, want ErrHashingBusy", err)
+	}
+
+	// With the queue full too, hashes are rejected right away
+	queued := make(chan error, 1)
+	go func() {
+		_, err := pool.Hash(context.Background(), "password123")
+		queued <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	start := time.Now()
+	if _, err := pool.Hash(context.Background(), "password123"); !errors.Is(err, domain.ErrHashingBusy) {
+		t.Errorf("Hash() with a full queue error = %v, want ErrHashingBusy", err)
+	}
+	if time.Since(start) > 25*time.Millisecond {
+		t.Error("Hash() with a full queue should not wait")
+	}
+	<-queued
+}
+
+func TestHashingPool_ContextCanceled(t *testing.T) {
+	pool := NewHashingPool(HashingPoolConfig{Workers: 1, Cost: bcrypt.MinCost})
+	release := occupy(t, pool)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Hash(ctx, "password123"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Hash() error = %v, want the context's", err)
+	}
+
+	// The canceled hash gave its queue slot back
+	release()
+	if _, err := pool.Hash(context.Background(), "password123"); err != nil {
+		t.Errorf("Hash() after release error = %v", err)
+	}
+}
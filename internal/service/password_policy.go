@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"jobsity-chat/internal/domain"
+)
+
+// PasswordPolicy is what a new password must satisfy; zero values use the
+// defaults
+type PasswordPolicy struct {
+	// MinLength is the minimum number of characters; default 8
+	MinLength int
+	// MaxLength is the maximum number of bytes; default and at most 72,
+	// the most bcrypt hashes
+	MaxLength int
+	// MinCharClasses is how many of lowercase letters, uppercase letters,
+	// digits and other characters the password must mix; default 1
+	MinCharClasses int
+	// Banned passwords are rejected regardless of case
+	Banned []string
+}
+
+func (p PasswordPolicy) normalized() PasswordPolicy {
+	if p.MinLength <= 0 {
+		p.MinLength = 8
+	}
+	if p.MaxLength <= 0 || p.MaxLength > 72 {
+		p.MaxLength = 72
+	}
+	p.MinCharClasses = min(max(p.MinCharClasses, 1), 4)
+	banned := make([]string, len(p.Banned))
+	for i, password := range p.Banned {
+		banned[i] = strings.ToLower(password)
+	}
+	p.Banned = banned
+	return p
+}
+
+// BreachChecker counts the known breaches a password appears in
+type BreachChecker interface {
+	Count(ctx context.Context, password string) (int, error)
+}
+
+// check returns why password does not satisfy the policy, if it doesn't
+func (p PasswordPolicy) check(username, password string) []domain.FieldError {
+	var errs []domain.FieldError
+	fail := func(code, message string) {
+		errs = append(errs, domain.FieldError{Field: "password", Code: code, Message: message})
+	}
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		fail(domain.FieldTooShort, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if len(password) > p.MaxLength {
+		fail(domain.FieldTooLong, fmt.Sprintf("must be at most %d bytes", p.MaxLength))
+	}
+	if charClasses(password) < p.MinCharClasses {
+		fail(domain.FieldTooFewCharClasses, fmt.Sprintf(
+			"must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinCharClasses))
+	}
+	lower := strings.ToLower(password)
+	for _, banned := range p.Banned {
+		if lower == banned {
+			fail(domain.FieldBanned, "is too common")
+			break
+		}
+	}
+	if username != "" && lower == strings.ToLower(username) {
+		fail(domain.FieldBanned, "must not be the username")
+	}
+	return errs
+}
+
+func charClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	n := 0
+	for _, has := range []bool{lower, upper, digit, other} {
+		if has {
+			n++
+		}
+	}
+	return n
+}
+
+// checkBreached returns a FieldError when password appears in a known
+// breach. The check is skipped when the breach service fails, so an outage
+// does not block registrations.
+func checkBreached(ctx context.Context, breaches BreachChecker, password string) *domain.FieldError {
+	count, err := breaches.Count(ctx, password)
+	if err != nil {
+		slog.Warn("password breach check failed", slog.String("error", err.Error()))
+		return nil
+	}
+	if count == 0 {
+		return nil
+	}
+	return &domain.FieldError{
+		Field:   "password",
+		Code:    domain.FieldBreached,
+		Message: "appears in a known data breach",
+	}
+}
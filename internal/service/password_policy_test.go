@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 10, MinCharClasses: 3, Banned: []string{"Chattorumu2024!"}}.normalized()
+
+	tests := []struct {
+		password string
+		codes    []string
+	}{
+		{"Correct-Horse-9", nil},
+		{"Sh0rt!", []string{domain.FieldTooShort}},
+		{"alllowercaseletters", []string{domain.FieldTooFewCharClasses}},
+		{"chattorumu2024!", []string{domain.FieldBanned}},
+		{"Alice_Smith9", []string{domain.FieldBanned}},
+		{"Aa1-" + strings.Repeat("a", 70), []string{domain.FieldTooLong}},
+		{"short", []string{domain.FieldTooShort, domain.FieldTooFewCharClasses}},
+	}
+	for _, tt := range tests {
+		fields := policy.check("alice_smith9", tt.password)
+		codes := make([]string, len(fields))
+		for i, f := range fields {
+			testutil.AssertEqual(t, f.Field, "password")
+			codes[i] = f.Code
+		}
+		testutil.AssertEqual(t, len(codes), len(tt.codes))
+		for i := range min(len(codes), len(tt.codes)) {
+			testutil.AssertEqual(t, codes[i], tt.codes[i])
+		}
+	}
+}
+
+func TestPasswordPolicy_Defaults(t *testing.T) {
+	policy := PasswordPolicy{MaxLength: 100}.normalized()
+	testutil.AssertEqual(t, policy.MinLength, 8)
+	testutil.AssertEqual(t, policy.MaxLength, 72)
+	testutil.AssertEqual(t, len(policy.check("bob", "password123")), 0)
+}
+
+type fakeBreachChecker struct {
+	counts map[string]int
+	err    error
+}
+
+func (f fakeBreachChecker) Count(ctx context.Context, password string) (int, error) {
+	return f.counts[password], f.err
+}
+
+func TestAuthService_Register_PasswordPolicy(t *testing.T) {
+	authService := NewAuthService(&mockUserRepository{users: make(map[string]*domain.User)}, &mockSessionRepository{})
+	authService.SetPasswordPolicy(PasswordPolicy{MinCharClasses: 2})
+
+	_, err := authService.Register(context.Background(), "a!", "not-an-email", "password")
+	var invalid *domain.ValidationError
+	testutil.AssertTrue(t, errors.As(err, &invalid), "expected ValidationError")
+	testutil.AssertErrorIs(t, err, domain.ErrInvalidInput)
+
+	fields := make(map[string]string)
+	for _, f := range invalid.Fields {
+		fields[f.Field] = f.Code
+	}
+	testutil.AssertEqual(t, fields["username"], domain.FieldTooShort)
+	testutil.AssertEqual(t, fields["email"], domain.FieldInvalid)
+	testutil.AssertEqual(t, fields["password"], domain.FieldTooFewCharClasses)
+}
+
+func TestAuthService_Register_BreachedPassword(t *testing.T) {
+	authService := NewAuthService(&mockUserRepository{users: make(map[string]*domain.User)}, &mockSessionRepository{})
+	authService.SetBreachChecker(fakeBreachChecker{counts: map[string]int{"password123": 250000}})
+
+	_, err := authService.Register(context.Background(), "alice", "alice@example.com", "password123")
+	var invalid *domain.ValidationError
+	testutil.AssertTrue(t, errors.As(err, &invalid), "expected ValidationError")
+	testutil.AssertEqual(t, invalid.Fields[0].Code, domain.FieldBreached)
+
+	_, err = authService.Register(context.Background(), "alice", "alice@example.com", "unlisted-passphrase")
+	testutil.AssertNoError(t, err)
+
+	// An unreachable breach service does not block registrations
+	authService.SetBreachChecker(fakeBreachChecker{err: errors.New("timeout")})
+	_, err = authService.Register(context.Background(), "bob", "bob@example.com", "password123")
+	testutil.AssertNoError(t, err)
+}
+
+func TestAuthService_Register_ReservedUsername(t *testing.T) {
+	authService := NewAuthService(&mockUserRepository{users: make(map[string]*domain.User)}, &mockSessionRepository{})
+	authService.SetReservedNames(NewReservedNames(DefaultReservedUsernames))
+
+	_, err := authService.Register(context.Background(), "St0ck_Bot", "bot@example.com", "password123")
+	var invalid *domain.ValidationError
+	testutil.AssertTrue(t, errors.As(err, &invalid), "expected ValidationError")
+	testutil.AssertEqual(t, invalid.Fields[0].Code, domain.FieldReserved)
+
+	// The server's own accounts may take reserved usernames
+	user, err := authService.RegisterSystemUser(context.Background(), "StockBot", "bot@example.com")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, user.Username, "StockBot")
+	_, err = authService.RegisterSystemUser(context.Background(), "StockBot", "bot@example.com")
+	testutil.AssertErrorIs(t, err, domain.ErrUsernameExists)
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"jobsity-chat/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// MaxAvatarURLLength bounds the avatar URL a user may set
+const MaxAvatarURLLength = 2048
+
+// PresenceChecker reports whether a user currently has a live connection
+type PresenceChecker interface {
+	IsUserOnline(userID string) bool
+}
+
+// ActivityChecker also reports whether a connected user has been inactive
+// long enough to be shown as away
+type ActivityChecker interface {
+	PresenceChecker
+	IsUserIdle(userID string) bool
+}
+
+// StatusPublisher tells the chatrooms a user is connected to that they
+// changed their status
+type StatusPublisher interface {
+	PublishStatus(userID string, status domain.UserStatus) error
+}
+
+// ProfileView is a profile as one viewer sees it. Fields the owner's privacy
+// settings hide from that viewer are left empty.
+type ProfileView struct {
+	ID        string     `json:"id"`
+	Username  string     `json:"username"`
+	AvatarURL string     `json:"avatar_url,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	Online    *bool      `json:"online,omitempty"`
+	// Version of the profile settings, shown to their owner only
+	Version int `json:"version,omitempty"`
+}
+
+// ProfileSettingsUpdate changes the fields that are set and keeps the rest.
+// A non-zero Version must be the current version of the settings.
+type ProfileSettingsUpdate struct {
+	AvatarURL        *string `json:"avatar_url"`
+	Visibility       *string `json:"profile_visibility"`
+	ShowOnlineStatus *bool   `json:"show_online_status"`
+	Version          int     `json:"version"`
+}
+
+// ProfileService serves public user profiles and their privacy settings
+type ProfileService struct {
+	userRepo domain.UserRepository
+	presence PresenceChecker
+	// statuses is nil until SetStatusPublisher is called
+	statuses StatusPublisher
+	// usernames is nil until SetUsernameHistory is called, and renames
+	// until SetRenameNotifier is
+	usernames      domain.UsernameHistoryRepository
+	usernamePolicy UsernamePolicy
+	renames        RenameNotifier
+	// reserved is nil until SetReservedNames is called
+	reserved *ReservedNames
+}
+
+func NewProfileService(userRepo domain.UserRepository, presence PresenceChecker) *ProfileService {
+	return &ProfileService{
+		userRepo: userRepo,
+		presence: presence,
+	}
+}
+
+// SetStatusPublisher announces status changes from now on
+func (s *ProfileService) SetStatusPublisher(statuses StatusPublisher) {
+	s.statuses = statuses
+}
+
+// GetProfile returns userID's profile as viewerID sees it. Users always see
+// their own full profile; others see only the username of a private profile
+// and no online status when the owner hides it.
+func (s *ProfileService) GetProfile(ctx context.Context, viewerID, userID string) (*ProfileView, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	profile, err := s.userRepo.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &ProfileView{ID: profile.ID, Username: profile.Username}
+	self := viewerID == profile.ID
+	if !self && profile.Visibility == domain.ProfilePrivate {
+		return view, nil
+	}
+
+	view.AvatarURL = profile.AvatarURL
+	view.CreatedAt = &profile.CreatedAt
+	if self {
+		view.Version = profile.Version
+	}
+	if s.presence != nil && (self || profile.ShowOnlineStatus) {
+		online := s.presence.IsUserOnline(profile.ID)
+		view.Online = &online
+	}
+	return view, nil
+}
+
+// UpdateSettings applies update to the user's profile settings and returns
+// the result. The update is merged into the settings it read, so it fails
+// with domain.ErrVersionConflict if they change before it is written, as well
+// as when update names an older version.
+func (s *ProfileService) UpdateSettings(ctx context.Context, userID string, update ProfileSettingsUpdate) (*domain.ProfileSettings, error) {
+	profile, err := s.userRepo.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if update.Version != 0 && update.Version != profile.Version {
+		return nil, domain.ErrVersionConflict
+	}
+
+	settings := domain.ProfileSettings{
+		AvatarURL:        profile.AvatarURL,
+		Visibility:       profile.Visibility,
+		ShowOnlineStatus: profile.ShowOnlineStatus,
+		Version:          profile.Version,
+	}
+	if update.AvatarURL != nil {
+		settings.AvatarURL = *update.AvatarURL
+	}
+	if update.Visibility != nil {
+		settings.Visibility = *update.Visibility
+	}
+	if update.ShowOnlineStatus != nil {
+		settings.ShowOnlineStatus = *update.ShowOnlineStatus
+	}
+
+	if !domain.IsValidProfileVisibility(settings.Visibility) || !isValidAvatarURL(settings.AvatarURL) {
+		return nil, domain.ErrInvalidInput
+	}
+
+	if err := s.userRepo.UpdateProfileSettings(ctx, userID, &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// GetStatus returns the status the user chose
+func (s *ProfileService) GetStatus(ctx context.Context, userID string) (*domain.UserStatus, error) {
+	return s.userRepo.GetStatus(ctx, userID)
+}
+
+// SetStatus stores the status the user chose and announces it in the
+// chatrooms they are connected to. Surrounding spaces are trimmed from the
+// text, which may be at most domain.MaxStatusTextLength characters.
+func (s *ProfileService) SetStatus(ctx context.Context, userID string, status domain.UserStatus) (*domain.UserStatus, error) {
+	status.Text = strings.TrimSpace(status.Text)
+	if !domain.IsValidStatus(status.Status) || utf8.RuneCountInString(status.Text) > domain.MaxStatusTextLength {
+		return nil, domain.ErrInvalidInput
+	}
+
+	if err := s.userRepo.SetStatus(ctx, userID, &status); err != nil {
+		return nil, err
+	}
+	if s.statuses != nil {
+		if err := s.statuses.PublishStatus(userID, status); err != nil {
+			// The status is stored; the chatrooms see it on their next
+			// member list
+			slog.Warn("failed to publish status",
+				slog.String("error", err.Error()),
+				slog.String("user_id", userID))
+		}
+	}
+	return &status, nil
+}
+
+// isValidAvatarURL accepts an empty URL, which clears the avatar, or an
+// absolute http(s) URL
+func isValidAvatarURL(avatarURL string) bool {
+	if avatarURL == "" {
+		return true
+	}
+	if len(avatarURL) > MaxAvatarURLLength {
+		return false
+	}
+	u, err := url.Parse(avatarURL)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
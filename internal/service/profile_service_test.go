@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+const (
+	aliceID = "11111111-1111-1111-1111-111111111111"
+	bobID   = "22222222-2222-2222-2222-222222222222"
+)
+
+type stubPresence map[string]bool
+
+func (p stubPresence) IsUserOnline(userID string) bool {
+	return p[userID]
+}
+
+func newProfileTestService() (*ProfileService, *testutil.MockUserRepository) {
+	userRepo := testutil.NewMockUserRepository()
+	userRepo.Users[aliceID] = testutil.NewTestUser(testutil.WithUserID(aliceID), testutil.WithUsername("alice"))
+	userRepo.Users[bobID] = testutil.NewTestUser(testutil.WithUserID(bobID), testutil.WithUsername("bob"))
+	return NewProfileService(userRepo, stubPresence{aliceID: true}), userRepo
+}
+
+func TestProfileService_GetProfile(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("public_profile", func(t *testing.T) {
+		profiles, userRepo := newProfileTestService()
+		userRepo.ProfileSettings[aliceID] = domain.ProfileSettings{
+			AvatarURL:        "https://example.com/alice.png",
+			Visibility:       domain.ProfilePublic,
+			ShowOnlineStatus: true,
+		}
+
+		view, err := profiles.GetProfile(ctx, bobID, aliceID)
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, view.Username, "alice")
+		testutil.AssertEqual(t, view.AvatarURL, "https://example.com/alice.png")
+		testutil.AssertNotNil(t, view.CreatedAt)
+		testutil.AssertTrue(t, view.Online != nil && *view.Online, "alice should be shown online")
+	})
+
+	t.Run("online_status_hidden", func(t *testing.T) {
+		profiles, userRepo := newProfileTestService()
+		userRepo.ProfileSettings[aliceID] = domain.ProfileSettings{Visibility: domain.ProfilePublic}
+
+		view, err := profiles.GetProfile(ctx, bobID, aliceID)
+		testutil.AssertNoError(t, err)
+		testutil.AssertTrue(t, view.Online == nil, "online status should be hidden")
+
+		self, err := profiles.GetProfile(ctx, aliceID, aliceID)
+		testutil.AssertNoError(t, err)
+		testutil.AssertTrue(t, self.Online != nil, "users should see their own online status")
+	})
+
+	t.Run("private_profile", func(t *testing.T) {
+		profiles, userRepo := newProfileTestService()
+		userRepo.ProfileSettings[aliceID] = domain.ProfileSettings{
+			AvatarURL:        "https://example.com/alice.png",
+			Visibility:       domain.ProfilePrivate,
+			ShowOnlineStatus: true,
+		}
+
+		view, err := profiles.GetProfile(ctx, bobID, aliceID)
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, view.Username, "alice")
+		testutil.AssertEqual(t, view.AvatarURL, "")
+		testutil.AssertTrue(t, view.CreatedAt == nil && view.Online == nil, "private profile should only show the username")
+
+		self, err := profiles.GetProfile(ctx, aliceID, aliceID)
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, self.AvatarURL, "https://example.com/alice.png")
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		profiles, _ := newProfileTestService()
+
+		_, err := profiles.GetProfile(ctx, bobID, "33333333-3333-3333-3333-333333333333")
+		testutil.AssertErrorIs(t, err, domain.ErrUserNotFound)
+
+		_, err = profiles.GetProfile(ctx, bobID, "not-a-uuid")
+		testutil.AssertErrorIs(t, err, domain.ErrUserNotFound)
+	})
+}
+
+func TestProfileService_UpdateSettings(t *testing.T) {
+	ctx := context.Background()
+	ptr := func(s string) *string { return &s }
+
+	t.Run("partial_update_keeps_other_fields", func(t *testing.T) {
+		profiles, userRepo := newProfileTestService()
+		hide := false
+
+		settings, err := profiles.UpdateSettings(ctx, aliceID, ProfileSettingsUpdate{ShowOnlineStatus: &hide})
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, *settings, domain.ProfileSettings{Visibility: domain.ProfilePublic, Version: 2})
+
+		settings, err = profiles.UpdateSettings(ctx, aliceID, ProfileSettingsUpdate{AvatarURL: ptr("https://example.com/a.png")})
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, *settings, domain.ProfileSettings{AvatarURL: "https://example.com/a.png", Visibility: domain.ProfilePublic, Version: 3})
+		testutil.AssertEqual(t, userRepo.ProfileSettings[aliceID], *settings)
+	})
+
+	t.Run("stale_version", func(t *testing.T) {
+		profiles, userRepo := newProfileTestService()
+		hide := false
+
+		_, err := profiles.UpdateSettings(ctx, aliceID, ProfileSettingsUpdate{ShowOnlineStatus: &hide, Version: 1})
+		testutil.AssertNoError(t, err)
+
+		_, err = profiles.UpdateSettings(ctx, aliceID, ProfileSettingsUpdate{AvatarURL: ptr("https://example.com/a.png"), Version: 1})
+		testutil.AssertErrorIs(t, err, domain.ErrVersionConflict)
+		testutil.AssertEqual(t, userRepo.ProfileSettings[aliceID].AvatarURL, "")
+	})
+
+	t.Run("invalid_input", func(t *testing.T) {
+		profiles, _ := newProfileTestService()
+
+		tests := []ProfileSettingsUpdate{
+			{Visibility: ptr("friends")},
+			{AvatarURL: ptr("javascript:alert(1)")},
+			{AvatarURL: ptr("/relative.png")},
+		}
+		for _, update := range tests {
+			_, err := profiles.UpdateSettings(ctx, aliceID, update)
+			testutil.AssertErrorIs(t, err, domain.ErrInvalidInput)
+		}
+	})
+}
+
+type recordingStatusPublisher map[string]domain.UserStatus
+
+func (p recordingStatusPublisher) PublishStatus(userID string, status domain.UserStatus) error {
+	p[userID] = status
+	return nil
+}
+
+func TestProfileService_SetStatus(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		status  domain.UserStatus
+		want    domain.UserStatus
+		wantErr error
+	}{
+		{"dnd", domain.UserStatus{Status: domain.StatusDND, Text: "  Focusing "}, domain.UserStatus{Status: domain.StatusDND, Text: "Focusing"}, nil},
+		{"custom_text", domain.UserStatus{Status: domain.StatusActive, Text: strings.Repeat("ü", domain.MaxStatusTextLength)}, domain.UserStatus{Status: domain.StatusActive, Text: strings.Repeat("ü", domain.MaxStatusTextLength)}, nil},
+		{"offline_is_not_chosen", domain.UserStatus{Status: domain.StatusOffline}, domain.UserStatus{}, domain.ErrInvalidInput},
+		{"unknown", domain.UserStatus{Status: "busy"}, domain.UserStatus{}, domain.ErrInvalidInput},
+		{"text_too_long", domain.UserStatus{Status: domain.StatusActive, Text: strings.Repeat("a", domain.MaxStatusTextLength+1)}, domain.UserStatus{}, domain.ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profiles, userRepo := newProfileTestService()
+			published := recordingStatusPublisher{}
+			profiles.SetStatusPublisher(published)
+
+			status, err := profiles.SetStatus(ctx, aliceID, tt.status)
+			if tt.wantErr != nil {
+				testutil.AssertErrorIs(t, err, tt.wantErr)
+				testutil.AssertEqual(t, len(userRepo.Statuses), 0)
+				return
+			}
+			testutil.AssertNoError(t, err)
+			testutil.AssertEqual(t, *status, tt.want)
+			testutil.AssertEqual(t, userRepo.Statuses[aliceID], tt.want)
+			testutil.AssertEqual(t, published[aliceID], tt.want)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/observability"
+)
+
+// QuotaService enforces usage quotas. The global defaults apply to every
+// organization unless it has overrides; zero limits are unlimited.
+type QuotaService struct {
+	repo     domain.QuotaRepository
+	defaults domain.QuotaLimits
+	now      func() time.Time
+}
+
+func NewQuotaService(repo domain.QuotaRepository, defaults domain.QuotaLimits) *QuotaService {
+	return &QuotaService{
+		repo:     repo,
+		defaults: defaults,
+		now:      time.Now,
+	}
+}
+
+// Limits returns the effective limits of the organization ctx is scoped to
+func (s *QuotaService) Limits(ctx context.Context) (domain.QuotaLimits, error) {
+	overrides, err := s.repo.GetOverrides(ctx, domain.OrgIDFromContext(ctx))
+	if err != nil {
+		return domain.QuotaLimits{}, err
+	}
+	return s.defaults.Apply(overrides), nil
+}
+
+// CheckCreateRoom returns a QuotaExceededError when userID already created
+// the maximum number of chatrooms
+func (s *QuotaService) CheckCreateRoom(ctx context.Context, userID string) error {
+	limits, err := s.Limits(ctx)
+	if err != nil || limits.MaxRoomsPerUser <= 0 {
+		return err
+	}
+
+	count, err := s.repo.CountRoomsCreatedBy(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if count >= limits.MaxRoomsPerUser {
+		return exceeded(domain.QuotaRoomsPerUser, int64(limits.MaxRoomsPerUser))
+	}
+	return nil
+}
+
+// CheckSendMessage returns a QuotaExceededError when the chatroom reached
+// its message limit for the current UTC day. Within the transaction storing
+// the message, concurrent sends to the chatroom are counted one after the
+// other, each seeing the messages stored before it.
+func (s *QuotaService) CheckSendMessage(ctx context.Context, chatroomID string) error {
+	limits, err := s.Limits(ctx)
+	if err != nil || limits.MaxMessagesPerRoomPerDay <= 0 {
+		return err
+	}
+
+	if err := s.repo.LockChatroomMessages(ctx, chatroomID); err != nil {
+		return err
+	}
+	startOfDay := s.now().UTC().Truncate(24 * time.Hour)
+	count, err := s.repo.CountMessagesSince(ctx, chatroomID, startOfDay)
+	if err != nil {
+		return err
+	}
+	if count >= limits.MaxMessagesPerRoomPerDay {
+		return exceeded(domain.QuotaMessagesPerRoomPerDay, int64(limits.MaxMessagesPerRoomPerDay))
+	}
+	return nil
+}
+
+func exceeded(quota string, limit int64) error {
+	observability.QuotaRejectionsTotal.WithLabelValues(quota).Inc()
+	return &domain.QuotaExceededError{Quota: quota, Limit: limit}
+}
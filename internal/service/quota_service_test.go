@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestQuotaService_Limits(t *testing.T) {
+	repo := testutil.NewMockQuotaRepository()
+	rooms := 0
+	repo.Overrides["org-2"] = &domain.QuotaOverrides{MaxRoomsPerUser: &rooms}
+
+	quotas := NewQuotaService(repo, domain.QuotaLimits{MaxRoomsPerUser: 5, MaxMessagesPerRoomPerDay: 100})
+
+	limits, err := quotas.Limits(context.Background())
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, limits.MaxRoomsPerUser, 5)
+
+	limits, err = quotas.Limits(domain.WithOrgID(context.Background(), "org-2"))
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, limits.MaxRoomsPerUser, 0)
+	testutil.AssertEqual(t, limits.MaxMessagesPerRoomPerDay, 100)
+}
+
+func TestQuotaService_CheckCreateRoom(t *testing.T) {
+	repo := testutil.NewMockQuotaRepository()
+	repo.RoomCounts["user-1"] = 2
+	repo.RoomCounts["user-2"] = 1
+
+	quotas := NewQuotaService(repo, domain.QuotaLimits{MaxRoomsPerUser: 2})
+
+	err := quotas.CheckCreateRoom(context.Background(), "user-1")
+	var quotaErr *domain.QuotaExceededError
+	testutil.AssertTrue(t, errors.As(err, &quotaErr), "expected QuotaExceededError")
+	testutil.AssertEqual(t, quotaErr.Quota, domain.QuotaRoomsPerUser)
+	testutil.AssertErrorIs(t, err, domain.ErrQuotaExceeded)
+
+	testutil.AssertNoError(t, quotas.CheckCreateRoom(context.Background(), "user-2"))
+}
+
+func TestQuotaService_CheckSendMessage(t *testing.T) {
+	counts := map[string]int{"room-1": 10}
+	var since time.Time
+	var locked []string
+	repo := testutil.NewMockQuotaRepository()
+	repo.LockChatroomMessagesFunc = func(ctx context.Context, chatroomID string) error {
+		locked = append(locked, chatroomID)
+		return nil
+	}
+	repo.CountMessagesSinceFunc = func(ctx context.Context, chatroomID string, s time.Time) (int, error) {
+		// Concurrent sends are counted one after the other
+		testutil.AssertEqual(t, locked[len(locked)-1], chatroomID)
+		since = s
+		return counts[chatroomID], nil
+	}
+
+	quotas := NewQuotaService(repo, domain.QuotaLimits{MaxMessagesPerRoomPerDay: 10})
+	quotas.now = func() time.Time { return time.Date(2024, 3, 5, 17, 30, 0, 0, time.UTC) }
+
+	err := quotas.CheckSendMessage(context.Background(), "room-1")
+	testutil.AssertErrorIs(t, err, domain.ErrQuotaExceeded)
+	testutil.AssertEqual(t, since, time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC))
+
+	testutil.AssertNoError(t, quotas.CheckSendMessage(context.Background(), "room-2"))
+}
+
+func TestQuotaService_Unlimited(t *testing.T) {
+	repo := testutil.NewMockQuotaRepository()
+	repo.CountRoomsCreatedByFunc = func(ctx context.Context, userID string) (int, error) {
+		t.Fatal("usage should not be counted without a limit")
+		return 0, nil
+	}
+	repo.CountMessagesSinceFunc = func(ctx context.Context, chatroomID string, since time.Time) (int, error) {
+		t.Fatal("usage should not be counted without a limit")
+		return 0, nil
+	}
+
+	quotas := NewQuotaService(repo, domain.QuotaLimits{})
+
+	testutil.AssertNoError(t, quotas.CheckCreateRoom(context.Background(), "user-1"))
+	testutil.AssertNoError(t, quotas.CheckSendMessage(context.Background(), "room-1"))
+}
+
+func TestChatService_Quotas(t *testing.T) {
+	repo := testutil.NewMockQuotaRepository()
+	repo.RoomCounts["user1"] = 1
+	repo.MessageCounts["chatroom1"] = 3
+	quotas := NewQuotaService(repo, domain.QuotaLimits{MaxRoomsPerUser: 1, MaxMessagesPerRoomPerDay: 3})
+
+	messageRepo := &mockMessageRepository{}
+	chatroomRepo := &mockChatroomRepository{
+		chatrooms: make(map[string]*domain.Chatroom),
+		members: map[string]map[string]bool{
+			"chatroom1": {"user1": true},
+		},
+	}
+	chatService := NewChatServiceWithQuotas(messageRepo, chatroomRepo, quotas)
+	ctx := context.Background()
+
+	_, err := chatService.CreateChatroom(ctx, "General", "user1")
+	testutil.AssertErrorIs(t, err, domain.ErrQuotaExceeded)
+
+	err = chatService.SendMessage(ctx, &domain.Message{ChatroomID: "chatroom1", UserID: "user1", Content: "hi"})
+	testutil.AssertErrorIs(t, err, domain.ErrQuotaExceeded)
+
+	// Bot responses are not subject to the message quota
+	err = chatService.SendMessage(ctx, &domain.Message{ChatroomID: "chatroom1", UserID: "bot", Content: "quote", IsBot: true})
+	testutil.AssertNoError(t, err)
+	testutil.AssertLen(t, messageRepo.messages, 1)
+}
+
+func TestChatService_QuotaCheckedInInsertTransaction(t *testing.T) {
+	repo := testutil.NewMockQuotaRepository()
+	quotas := NewQuotaService(repo, domain.QuotaLimits{MaxMessagesPerRoomPerDay: 3})
+
+	txm := &testutil.MockTxManager{}
+	inTx := false
+	repo.LockChatroomMessagesFunc = func(ctx context.Context, chatroomID string) error {
+		inTx = txm.Units == 1
+		return nil
+	}
+
+	chatService := NewChatServiceWithQuotas(&mockMessageRepository{}, &mockChatroomRepository{
+		chatrooms: make(map[string]*domain.Chatroom),
+		members:   map[string]map[string]bool{"chatroom1": {"user1": true}},
+	}, quotas)
+	chatService.SetTxManager(txm)
+
+	err := chatService.SendMessage(context.Background(), &domain.Message{ChatroomID: "chatroom1", UserID: "user1", Content: "hi"})
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, txm.Units, 1)
+	testutil.AssertTrue(t, inTx, "quota should be checked inside the insert transaction")
+}
@@ -0,0 +1,42 @@
+package service
+
+// DefaultReservedUsernames are the usernames nobody can register or change
+// to, so no one can pose as the server, its bots or its staff
+var DefaultReservedUsernames = []string{
+	"admin", "administrator", "root", "system", "api", "stockbot", "bot",
+	"moderator", "mod", "staff", "support", "security", "official",
+	"everyone", "here", "null", "undefined",
+}
+
+// DefaultReservedRoomNames are the chatroom names nobody can create a
+// chatroom with
+var DefaultReservedRoomNames = []string{
+	"admin", "administrator", "system", "api", "stockbot", "moderators",
+	"staff", "support", "security", "official", "announcements",
+}
+
+// ReservedNames are names users cannot take. A name is reserved when it
+// spells one of them, in any case, with separators or with lookalike
+// characters: Admin, st0ck_bot and Cyrillic аdmin all match.
+type ReservedNames struct {
+	skeletons map[string]bool
+}
+
+func NewReservedNames(names []string) *ReservedNames {
+	r := &ReservedNames{skeletons: make(map[string]bool, len(names))}
+	for _, name := range names {
+		if skeleton := nameSkeleton(name); skeleton != "" {
+			r.skeletons[skeleton] = true
+		}
+	}
+	return r
+}
+
+// Contains reports whether name is reserved. A nil ReservedNames reserves
+// nothing.
+func (r *ReservedNames) Contains(name string) bool {
+	if r == nil {
+		return false
+	}
+	return r.skeletons[nameSkeleton(name)]
+}
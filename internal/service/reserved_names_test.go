@@ -0,0 +1,42 @@
+package service
+
+import (
+	"testing"
+)
+
+func TestReservedNames_Contains(t *testing.T) {
+	reserved := NewReservedNames(DefaultReservedUsernames)
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"admin", true},
+		{"ADMIN", true},
+		{"Adm1n", true},
+		{"adm_in", true},
+		{"аdmin", true}, // Cyrillic а
+		{"ａｄｍｉｎ", true},
+		{"StockBot", true},
+		{"st0ck-b0t", true},
+		{"Stock Bot", true},
+		{"sys7em", true},
+		{"rnod", true},
+		{"admins", false},
+		{"alice", false},
+		{"stockbot2", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := reserved.Contains(tt.name); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReservedNames_Nil(t *testing.T) {
+	var reserved *ReservedNames
+	if reserved.Contains("admin") {
+		t.Error("a nil ReservedNames should reserve nothing")
+	}
+}
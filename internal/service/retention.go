@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"jobsity-chat/internal/domain"
+)
+
+// DefaultDeletedRetention is how long deleted chatrooms and messages can be
+// restored before they are purged
+const DefaultDeletedRetention = 30 * 24 * time.Hour
+
+// DeletedPurger permanently deletes the chatrooms and messages of every
+// organization that have been soft-deleted for longer than the retention
+// window
+type DeletedPurger struct {
+	chatroomRepo domain.ChatroomRepository
+	messageRepo  domain.MessageRepository
+	retention    time.Duration
+}
+
+func NewDeletedPurger(chatroomRepo domain.ChatroomRepository, messageRepo domain.MessageRepository, retention time.Duration) *DeletedPurger {
+	if retention <= 0 {
+		retention = DefaultDeletedRetention
+	}
+	return &DeletedPurger{
+		chatroomRepo: chatroomRepo,
+		messageRepo:  messageRepo,
+		retention:    retention,
+	}
+}
+
+// Purge deletes what has been soft-deleted for longer than the retention
+// window and returns how many chatrooms and messages it purged. Messages of
+// purged chatrooms are not counted.
+func (p *DeletedPurger) Purge(ctx context.Context) (rooms, messages int64, err error) {
+	cutoff := time.Now().Add(-p.retention)
+
+	rooms, err = p.chatroomRepo.PurgeDeleted(ctx, cutoff)
+	if err != nil {
+		return 0, 0, err
+	}
+	messages, err = p.messageRepo.PurgeDeleted(ctx, cutoff)
+	if err != nil {
+		return rooms, 0, err
+	}
+	return rooms, messages, nil
+}
+
+// Run purges once and logs what was purged, as the deleted_purge job
+func (p *DeletedPurger) Run(ctx context.Context) error {
+	rooms, messages, err := p.Purge(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info("deleted data purge completed",
+		slog.Int64("chatrooms_purged", rooms),
+		slog.Int64("messages_purged", messages))
+	return nil
+}
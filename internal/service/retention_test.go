@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestDeletedPurger_Purge(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Deleted["old-room"] = &domain.Chatroom{ID: "old-room"}
+	chatroomRepo.DeletedAt["old-room"] = now.Add(-48 * time.Hour)
+	chatroomRepo.Deleted["new-room"] = &domain.Chatroom{ID: "new-room"}
+	chatroomRepo.DeletedAt["new-room"] = now.Add(-time.Hour)
+
+	messageRepo := testutil.NewMockMessageRepository()
+	messageRepo.Messages = append(messageRepo.Messages,
+		&domain.Message{ID: "old-msg"},
+		&domain.Message{ID: "new-msg"},
+		&domain.Message{ID: "kept-msg"},
+	)
+	messageRepo.DeletedAt["old-msg"] = now.Add(-48 * time.Hour)
+	messageRepo.DeletedAt["new-msg"] = now.Add(-time.Hour)
+
+	purger := NewDeletedPurger(chatroomRepo, messageRepo, 24*time.Hour)
+	rooms, messages, err := purger.Purge(ctx)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, rooms, int64(1))
+	testutil.AssertEqual(t, messages, int64(1))
+
+	_, restorable := chatroomRepo.Deleted["new-room"]
+	testutil.AssertTrue(t, restorable, "chatroom deleted within the retention window should be kept")
+	_, purged := chatroomRepo.Deleted["old-room"]
+	testutil.AssertFalse(t, purged, "chatroom deleted before the retention window should be purged")
+	testutil.AssertEqual(t, len(messageRepo.Messages), 2)
+}
+
+func TestDeletedPurger_PurgeError(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.PurgeDeletedFunc = func(ctx context.Context, cutoff time.Time) (int64, error) {
+		return 0, errors.New("database error")
+	}
+	messageRepo := testutil.NewMockMessageRepository()
+	messagesPurged := false
+	messageRepo.PurgeDeletedFunc = func(ctx context.Context, cutoff time.Time) (int64, error) {
+		messagesPurged = true
+		return 0, nil
+	}
+
+	_, _, err := NewDeletedPurger(chatroomRepo, messageRepo, 0).Purge(context.Background())
+	testutil.AssertError(t, err)
+	testutil.AssertFalse(t, messagesPurged, "messages should not be purged after a failure")
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"jobsity-chat/internal/domain"
+)
+
+const (
+	// MinSearchQueryLength and MaxSearchQueryLength bound a search query, in
+	// characters
+	MinSearchQueryLength = 2
+	MaxSearchQueryLength = 200
+
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+)
+
+// SearchRequest is a global search. Types selects the kinds of results, all
+// of domain.SearchTypes when empty; Limit applies to each kind. Cursors holds
+// the next_cursor of each kind being paged through.
+type SearchRequest struct {
+	Query   string
+	Types   []string
+	Limit   int
+	Cursors map[string]string
+}
+
+// SearchPage is one page of one kind of search result, best match first
+type SearchPage[T any] struct {
+	Items []T `json:"items"`
+	// NextCursor fetches the next page of this kind; empty on the last one
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// SearchResults holds a page of each kind of result searched for; kinds not
+// searched for are nil
+type SearchResults struct {
+	Messages *SearchPage[*domain.MessageHit] `json:"messages,omitempty"`
+	Rooms    *SearchPage[*domain.RoomHit]    `json:"rooms,omitempty"`
+	Users    *SearchPage[*domain.UserHit]    `json:"users,omitempty"`
+}
+
+// SearchService searches messages, chatrooms and users across the chatrooms
+// the caller belongs to
+type SearchService struct {
+	repo domain.SearchRepository
+}
+
+func NewSearchService(repo domain.SearchRepository) *SearchService {
+	return &SearchService{repo: repo}
+}
+
+// Search returns a ranked page of each kind of result req asks for. An
+// invalid query, type, limit or cursor is rejected with ErrInvalidInput.
+func (s *SearchService) Search(ctx context.Context, userID string, req SearchRequest) (_ *SearchResults, err error) {
+	defer observe("search", "Search")(&err)
+
+	query := strings.TrimSpace(req.Query)
+	if n := utf8.RuneCountInString(query); n < MinSearchQueryLength || n > MaxSearchQueryLength {
+		return nil, domain.ErrInvalidInput
+	}
+	types := req.Types
+	if len(types) == 0 {
+		types = domain.SearchTypes
+	}
+	for _, t := range types {
+		if !slices.Contains(domain.SearchTypes, t) {
+			return nil, domain.ErrInvalidInput
+		}
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	if limit < 1 || limit > maxSearchLimit {
+		return nil, domain.ErrInvalidInput
+	}
+
+	results := &SearchResults{}
+	for _, t := range domain.SearchTypes {
+		if !slices.Contains(types, t) {
+			continue
+		}
+		offset, ok := searchOffset(req.Cursors[t])
+		if !ok {
+			return nil, domain.ErrInvalidInput
+		}
+		opts := domain.SearchOptions{Query: query, UserID: userID, Limit: limit, Offset: offset}
+
+		switch t {
+		case domain.SearchMessages:
+			results.Messages, err = searchPage(ctx, s.repo.SearchMessages, opts)
+		case domain.SearchRooms:
+			results.Rooms, err = searchPage(ctx, s.repo.SearchRooms, opts)
+		case domain.SearchUsers:
+			results.Users, err = searchPage(ctx, s.repo.SearchUsers, opts)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// searchOffset decodes a search cursor, the offset of the page in the ranked
+// results; the empty cursor is the first page
+func searchOffset(cursor string) (int, bool) {
+	if cursor == "" {
+		return 0, true
+	}
+	offset, err := strconv.Atoi(cursor)
+	return offset, err == nil && offset > 0
+}
+
+// searchPage runs one search, fetching one extra hit to know whether there
+// is a next page
+func searchPage[T any](ctx context.Context, search func(context.Context, domain.SearchOptions) ([]T, error), opts domain.SearchOptions) (*SearchPage[T], error) {
+	limit := opts.Limit
+	opts.Limit++
+	items, err := search(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &SearchPage[T]{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = strconv.Itoa(opts.Offset + limit)
+	}
+	return page, nil
+}
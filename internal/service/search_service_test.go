@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestSearchService_Search(t *testing.T) {
+	repo := testutil.NewMockSearchRepository()
+	for i := range 5 {
+		repo.Messages = append(repo.Messages, &domain.MessageHit{ID: fmt.Sprintf("msg-%d", i), Content: fmt.Sprintf("deploy #%d done", i)})
+	}
+	repo.Rooms = []*domain.RoomHit{{ID: "room-1", Name: "Deploys"}, {ID: "room-2", Name: "Random"}}
+	repo.Users = []*domain.UserHit{{ID: "user-2", Username: "deploy-bot"}}
+	searchService := NewSearchService(repo)
+	ctx := context.Background()
+
+	results, err := searchService.Search(ctx, "user-1", SearchRequest{Query: "  deploy ", Limit: 2})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results.Messages.Items) != 2 || results.Messages.NextCursor != "2" {
+		t.Errorf("Expected the first 2 messages and a next cursor, got %+v", results.Messages)
+	}
+	if len(results.Rooms.Items) != 1 || results.Rooms.NextCursor != "" {
+		t.Errorf("Expected 1 room and no next cursor, got %+v", results.Rooms)
+	}
+	if len(results.Users.Items) != 1 {
+		t.Errorf("Expected 1 user, got %+v", results.Users)
+	}
+
+	// Each kind pages on its own
+	results, err = searchService.Search(ctx, "user-1", SearchRequest{
+		Query:   "deploy",
+		Types:   []string{domain.SearchMessages},
+		Limit:   2,
+		Cursors: map[string]string{domain.SearchMessages: "4"},
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if results.Rooms != nil || results.Users != nil {
+		t.Errorf("Expected only messages, got %+v", results)
+	}
+	if len(results.Messages.Items) != 1 || results.Messages.Items[0].ID != "msg-4" || results.Messages.NextCursor != "" {
+		t.Errorf("Expected the last message, got %+v", results.Messages)
+	}
+
+	invalid := []SearchRequest{
+		{Query: "a"},
+		{Query: " x "},
+		{Query: "deploy", Types: []string{"files"}},
+		{Query: "deploy", Limit: maxSearchLimit + 1},
+		{Query: "deploy", Limit: -1},
+		{Query: "deploy", Cursors: map[string]string{domain.SearchRooms: "abc"}},
+		{Query: "deploy", Cursors: map[string]string{domain.SearchRooms: "-5"}},
+	}
+	for _, req := range invalid {
+		if _, err := searchService.Search(ctx, "user-1", req); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Errorf("%+v: expected ErrInvalidInput, got %v", req, err)
+		}
+	}
+
+	repo.SearchUsersFunc = func(ctx context.Context, opts domain.SearchOptions) ([]*domain.UserHit, error) {
+		return nil, errors.New("database error")
+	}
+	if _, err := searchService.Search(ctx, "user-1", SearchRequest{Query: "deploy"}); err == nil {
+		t.Error("Expected the repository error")
+	}
+}
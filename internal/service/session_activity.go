@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"jobsity-chat/internal/domain"
+)
+
+// SessionActivityTracker batches session renewals. Requests and WebSocket
+// messages call Touch, which only queues a renewal when it would extend the
+// session noticeably; Run flushes queued renewals in one write per interval.
+type SessionActivityTracker struct {
+	repo          domain.SessionRepository
+	flushInterval time.Duration
+
+	mu      sync.Mutex
+	pending map[string]time.Time
+}
+
+func NewSessionActivityTracker(repo domain.SessionRepository, flushInterval time.Duration) *SessionActivityTracker {
+	return &SessionActivityTracker{
+		repo:          repo,
+		flushInterval: flushInterval,
+		pending:       make(map[string]time.Time),
+	}
+}
+
+// renewalThreshold is the minimum expiry extension worth a database write:
+// a tenth of the idle timeout, at most one minute.
+func renewalThreshold(idle time.Duration) time.Duration {
+	return min(idle/10, time.Minute)
+}
+
+// Touch records activity on session. The session's in-memory expiry is
+// advanced so callers holding it (e.g. a WebSocket connection) do not queue
+// a renewal for every message.
+func (t *SessionActivityTracker) Touch(session *domain.Session) {
+	if session == nil || session.IdleTimeout <= 0 {
+		return
+	}
+
+	now := time.Now()
+	renewed := session.RenewedExpiry(now)
+	if renewed.Sub(session.ExpiresAt) < renewalThreshold(session.IdleTimeout) {
+		return
+	}
+
+	t.mu.Lock()
+	t.pending[session.Token] = now
+	t.mu.Unlock()
+
+	session.ExpiresAt = renewed
+	session.LastActivityAt = now
+}
+
+// Pending returns the number of queued renewals
+func (t *SessionActivityTracker) Pending() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.pending)
+}
+
+// Flush writes all queued renewals
+func (t *SessionActivityTracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	if len(t.pending) == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	activity := make([]domain.SessionActivity, 0, len(t.pending))
+	for token, at := range t.pending {
+		activity = append(activity, domain.SessionActivity{Token: token, At: at})
+	}
+	t.pending = make(map[string]time.Time)
+	t.mu.Unlock()
+
+	renewed, err := t.repo.Touch(ctx, activity)
+	if err != nil {
+		// Put the batch back unless newer activity arrived meanwhile
+		t.mu.Lock()
+		for _, a := range activity {
+			if _, ok := t.pending[a.Token]; !ok {
+				t.pending[a.Token] = a.At
+			}
+		}
+		t.mu.Unlock()
+		return err
+	}
+
+	slog.Debug("renewed sessions",
+		slog.Int("queued", len(activity)),
+		slog.Int64("renewed", renewed))
+	return nil
+}
+
+// Run flushes periodically until ctx is cancelled, then flushes once more
+func (t *SessionActivityTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := t.Flush(flushCtx); err != nil {
+				slog.Error("failed to flush session activity on shutdown", slog.String("error", err.Error()))
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := t.Flush(ctx); err != nil {
+				slog.Error("failed to flush session activity", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
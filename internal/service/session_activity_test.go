@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func newActiveSession(token string, expiresIn, idle time.Duration) *domain.Session {
+	now := time.Now()
+	return &domain.Session{
+		Token:             token,
+		ExpiresAt:         now.Add(expiresIn),
+		AbsoluteExpiresAt: now.Add(24 * time.Hour),
+		IdleTimeout:       idle,
+	}
+}
+
+func TestSessionActivityTracker_Touch(t *testing.T) {
+	tests := []struct {
+		name        string
+		session     *domain.Session
+		wantPending int
+	}{
+		{
+			name:        "recently renewed session is skipped",
+			session:     newActiveSession("fresh", 2*time.Hour, 2*time.Hour),
+			wantPending: 0,
+		},
+		{
+			name:        "session due for renewal is queued",
+			session:     newActiveSession("stale", time.Hour, 2*time.Hour),
+			wantPending: 1,
+		},
+		{
+			name: "session at its absolute limit is skipped",
+			session: &domain.Session{
+				Token:             "capped",
+				ExpiresAt:         time.Now().Add(time.Hour),
+				AbsoluteExpiresAt: time.Now().Add(time.Hour),
+				IdleTimeout:       2 * time.Hour,
+			},
+			wantPending: 0,
+		},
+		{
+			name:        "session without idle timeout is skipped",
+			session:     &domain.Session{Token: "legacy", ExpiresAt: time.Now().Add(time.Hour)},
+			wantPending: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewSessionActivityTracker(testutil.NewMockSessionRepository(), time.Minute)
+
+			tracker.Touch(tt.session)
+
+			testutil.AssertEqual(t, tracker.Pending(), tt.wantPending)
+		})
+	}
+}
+
+func TestSessionActivityTracker_TouchAdvancesExpiry(t *testing.T) {
+	tracker := NewSessionActivityTracker(testutil.NewMockSessionRepository(), time.Minute)
+	session := newActiveSession("stale", time.Hour, 2*time.Hour)
+
+	tracker.Touch(session)
+	tracker.Touch(session)
+
+	testutil.AssertEqual(t, tracker.Pending(), 1)
+	testutil.AssertTrue(t, session.ExpiresAt.After(time.Now().Add(time.Hour+50*time.Minute)), "in-memory expiry should slide forward")
+}
+
+func TestSessionActivityTracker_Flush(t *testing.T) {
+	repo := testutil.NewMockSessionRepository()
+	stored := newActiveSession("stale", time.Hour, 2*time.Hour)
+	repo.Sessions[stored.Token] = stored
+
+	tracker := NewSessionActivityTracker(repo, time.Minute)
+	tracker.Touch(newActiveSession("stale", time.Hour, 2*time.Hour))
+
+	err := tracker.Flush(context.Background())
+
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, tracker.Pending(), 0)
+	testutil.AssertTrue(t, stored.ExpiresAt.After(time.Now().Add(time.Hour+50*time.Minute)), "stored expiry should slide forward")
+}
+
+func TestSessionActivityTracker_FlushRequeuesOnError(t *testing.T) {
+	repo := testutil.NewMockSessionRepository()
+	repo.TouchFunc = func(ctx context.Context, activity []domain.SessionActivity) (int64, error) {
+		return 0, errors.New("connection lost")
+	}
+
+	tracker := NewSessionActivityTracker(repo, time.Minute)
+	tracker.Touch(newActiveSession("stale", time.Hour, 2*time.Hour))
+
+	err := tracker.Flush(context.Background())
+
+	testutil.AssertError(t, err)
+	testutil.AssertEqual(t, tracker.Pending(), 1)
+}
+
+func TestSessionActivityTracker_RunFlushesOnShutdown(t *testing.T) {
+	flushed := make(chan int, 1)
+	repo := testutil.NewMockSessionRepository()
+	repo.TouchFunc = func(ctx context.Context, activity []domain.SessionActivity) (int64, error) {
+		flushed <- len(activity)
+		return int64(len(activity)), nil
+	}
+
+	tracker := NewSessionActivityTracker(repo, time.Hour)
+	tracker.Touch(newActiveSession("stale", time.Hour, 2*time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		tracker.Run(ctx)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case n := <-flushed:
+		testutil.AssertEqual(t, n, 1)
+	case <-time.After(time.Second):
+		t.Fatal("expected pending activity to be flushed on shutdown")
+	}
+	<-done
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"jobsity-chat/internal/domain"
+)
+
+// RegisterSystemUser creates a system account, one the server itself posts
+// as, such as StockBot's. Unlike Register it may take a reserved username.
+// The account has no password and cannot log in by any means.
+func (s *AuthService) RegisterSystemUser(ctx context.Context, username, email string) (_ *domain.User, err error) {
+	defer observe("auth", "RegisterSystemUser")(&err)
+
+	username = NormalizeUsername(username)
+	if field := checkUsername(username, nil); field != nil {
+		return nil, &domain.ValidationError{Fields: []domain.FieldError{*field}}
+	}
+	if _, err := s.userRepo.GetByUsername(ctx, username); err == nil {
+		return nil, domain.ErrUsernameExists
+	}
+
+	user := &domain.User{
+		Username: username,
+		Email:    email,
+		IsSystem: true,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	publish(ctx, s.events, domain.UserRegistered{User: user})
+
+	return user, nil
+}
+
+// EnsureSystemUser returns the system account with username, creating it
+// with email if there is none. It fails when a regular user has the
+// username, rather than posting as someone who can log in.
+func (s *AuthService) EnsureSystemUser(ctx context.Context, username, email string) (*domain.User, error) {
+	user, err := s.RegisterSystemUser(ctx, username, email)
+	if errors.Is(err, domain.ErrUsernameExists) {
+		user, err = s.userRepo.GetByUsername(ctx, NormalizeUsername(username))
+		if err == nil && !user.IsSystem {
+			return nil, fmt.Errorf("user %q exists but is not a system account", username)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
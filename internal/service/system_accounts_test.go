@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestAuthService_EnsureSystemUser(t *testing.T) {
+	ctx := context.Background()
+	userRepo := testutil.NewMockUserRepository()
+	authService := NewAuthService(userRepo, testutil.NewMockSessionRepository())
+
+	bot, err := authService.EnsureSystemUser(ctx, "StockBot", "bot@example.com")
+	testutil.AssertNoError(t, err)
+	testutil.AssertTrue(t, bot.IsSystem, "bot should be a system account")
+	testutil.AssertEqual(t, bot.PasswordHash, "")
+
+	again, err := authService.EnsureSystemUser(ctx, "StockBot", "bot@example.com")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, again.ID, bot.ID)
+
+	// Nobody can log in to it, whatever the password
+	_, _, err = authService.Login(ctx, "StockBot", "")
+	testutil.AssertErrorIs(t, err, domain.ErrInvalidCredentials)
+}
+
+func TestAuthService_EnsureSystemUser_RegularUserTaken(t *testing.T) {
+	userRepo := testutil.NewMockUserRepository()
+	userRepo.Users["user-1"] = testutil.NewTestUser(testutil.WithUserID("user-1"), testutil.WithUsername("StockBot"))
+	authService := NewAuthService(userRepo, testutil.NewMockSessionRepository())
+
+	_, err := authService.EnsureSystemUser(context.Background(), "StockBot", "bot@example.com")
+	testutil.AssertError(t, err)
+}
+
+func TestOAuthService_Login_RefusesSystemUser(t *testing.T) {
+	oauthService, userRepo, _ := newTestOAuthService()
+	bot := testutil.NewTestUser(testutil.WithUserID("bot-1"), testutil.WithUsername("StockBot"), testutil.WithEmail("bot@example.com"))
+	bot.IsSystem = true
+	userRepo.Users[bot.ID] = bot
+
+	_, _, err := oauthService.Login(context.Background(), &domain.ExternalProfile{
+		Provider:      "github",
+		Subject:       "42",
+		Email:         "bot@example.com",
+		EmailVerified: true,
+		Username:      "StockBot",
+	}, LoginOptions{})
+	testutil.AssertErrorIs(t, err, domain.ErrEmailExists)
+}
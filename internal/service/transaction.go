@@ -0,0 +1,15 @@
+package service
+
+import (
+	"context"
+
+	"jobsity-chat/internal/domain"
+)
+
+// withinTx runs fn as one unit of work in txm, or directly when txm is nil
+func withinTx(ctx context.Context, txm domain.TxManager, fn func(ctx context.Context) error) error {
+	if txm == nil {
+		return fn(ctx)
+	}
+	return txm.WithinTx(ctx, fn)
+}
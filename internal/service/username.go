@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"jobsity-chat/internal/domain"
+)
+
+// UsernamePolicy limits username changes
+type UsernamePolicy struct {
+	// Cooldown is how long a user must wait between two changes
+	Cooldown time.Duration
+	// HoldPeriod is how long a username given up stays unavailable to
+	// other users
+	HoldPeriod time.Duration
+}
+
+// RenameNotifier is told when a user changes their username, so live
+// connections stop showing the old one
+type RenameNotifier interface {
+	UserRenamed(orgID, userID string) error
+}
+
+var errUsernameHistoryUnset = errors.New("username history is not configured")
+
+// SetUsernameHistory enables username changes under policy, keeping the
+// former usernames in history
+func (s *ProfileService) SetUsernameHistory(history domain.UsernameHistoryRepository, policy UsernamePolicy) {
+	s.usernames = history
+	s.usernamePolicy = policy
+}
+
+// SetReservedNames keeps users from changing to reserved usernames
+func (s *ProfileService) SetReservedNames(reserved *ReservedNames) {
+	s.reserved = reserved
+}
+
+// SetRenameNotifier reports username changes to renames from now on
+func (s *ProfileService) SetRenameNotifier(renames RenameNotifier) {
+	s.renames = renames
+}
+
+// ChangeUsername renames the user. Returns a domain.UsernameCooldownError
+// when they changed it less than the policy's cooldown ago, and
+// domain.ErrUsernameExists when another user has the username or gave it
+// up within the hold period.
+func (s *ProfileService) ChangeUsername(ctx context.Context, userID, username string) (*domain.UsernameChange, error) {
+	if s.usernames == nil {
+		return nil, errUsernameHistoryUnset
+	}
+	username = NormalizeUsername(username)
+	if field := checkUsername(username, s.reserved); field != nil {
+		return nil, &domain.ValidationError{Fields: []domain.FieldError{*field}}
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsGuest() {
+		return nil, domain.ErrUserNotFound
+	}
+	if user.Username == username {
+		return nil, &domain.ValidationError{Fields: []domain.FieldError{
+			{Field: "username", Code: domain.FieldInvalid, Message: "is already your username"},
+		}}
+	}
+
+	if other, err := s.userRepo.GetByUsername(ctx, username); err == nil && other.ID != userID {
+		return nil, domain.ErrUsernameExists
+	}
+	field, err := checkConfusable(ctx, s.userRepo, username, userID)
+	if err != nil {
+		return nil, err
+	}
+	if field != nil {
+		return nil, &domain.ValidationError{Fields: []domain.FieldError{*field}}
+	}
+
+	now := time.Now()
+	history, err := s.usernames.History(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) > 0 {
+		if until := history[0].ChangedAt.Add(s.usernamePolicy.Cooldown); now.Before(until) {
+			return nil, &domain.UsernameCooldownError{Until: until}
+		}
+	}
+
+	change, err := s.usernames.Rename(ctx, userID, username, now.Add(-s.usernamePolicy.HoldPeriod))
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("username changed",
+		slog.String("user_id", userID),
+		slog.String("old_username", change.OldUsername),
+		slog.String("new_username", change.NewUsername))
+
+	if s.renames != nil {
+		if err := s.renames.UserRenamed(domain.OrgIDFromContext(ctx), userID); err != nil {
+			slog.Warn("failed to notify username change",
+				slog.String("error", err.Error()),
+				slog.String("user_id", userID))
+		}
+	}
+	return change, nil
+}
+
+// UsernameHistory returns the user's username changes, newest first
+func (s *ProfileService) UsernameHistory(ctx context.Context, userID string) ([]*domain.UsernameChange, error) {
+	if s.usernames == nil {
+		return []*domain.UsernameChange{}, nil
+	}
+	return s.usernames.History(ctx, userID)
+}
+
+// ResolveUsername returns the ID of the user who has username, or else of
+// the user who last gave it up and nobody took since. Returns
+// domain.ErrUserNotFound when there is neither.
+func (s *ProfileService) ResolveUsername(ctx context.Context, username string) (string, error) {
+	username = NormalizeUsername(username)
+	user, err := s.userRepo.GetByUsername(ctx, username)
+	if err == nil {
+		return user.ID, nil
+	}
+	if !errors.Is(err, domain.ErrUserNotFound) || s.usernames == nil {
+		return "", err
+	}
+	return s.usernames.ResolveFormer(ctx, username)
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+type recordingRenames []string
+
+func (r *recordingRenames) UserRenamed(orgID, userID string) error {
+	*r = append(*r, userID)
+	return nil
+}
+
+func newUsernameTestService(policy UsernamePolicy) (*ProfileService, *testutil.MockUsernameHistoryRepository, *recordingRenames) {
+	profiles, userRepo := newProfileTestService()
+	history := testutil.NewMockUsernameHistoryRepository(userRepo)
+	profiles.SetUsernameHistory(history, policy)
+	renames := &recordingRenames{}
+	profiles.SetRenameNotifier(renames)
+	return profiles, history, renames
+}
+
+func TestProfileService_ChangeUsername(t *testing.T) {
+	ctx := context.Background()
+	profiles, history, renames := newUsernameTestService(UsernamePolicy{Cooldown: time.Hour, HoldPeriod: 24 * time.Hour})
+
+	change, err := profiles.ChangeUsername(ctx, aliceID, "alicia")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, change.OldUsername, "alice")
+	testutil.AssertEqual(t, change.NewUsername, "alicia")
+	testutil.AssertEqual(t, len(*renames), 1)
+
+	// A second change waits for the cooldown
+	_, err = profiles.ChangeUsername(ctx, aliceID, "alice_b")
+	var cooldown *domain.UsernameCooldownError
+	testutil.AssertTrue(t, errors.As(err, &cooldown), "expected UsernameCooldownError")
+	testutil.AssertTrue(t, cooldown.Until.After(time.Now()), "cooldown should end in the future")
+
+	// The old username is held for its former owner
+	_, err = profiles.ChangeUsername(ctx, bobID, "alice")
+	testutil.AssertErrorIs(t, err, domain.ErrUsernameExists)
+	history.Changes[aliceID][0].ChangedAt = time.Now().Add(-48 * time.Hour)
+	_, err = profiles.ChangeUsername(ctx, bobID, "alice")
+	testutil.AssertNoError(t, err)
+
+	// Taken usernames are refused
+	_, err = profiles.ChangeUsername(ctx, aliceID, "alice")
+	testutil.AssertErrorIs(t, err, domain.ErrUsernameExists)
+}
+
+func TestProfileService_ChangeUsername_Invalid(t *testing.T) {
+	ctx := context.Background()
+	profiles, _, renames := newUsernameTestService(UsernamePolicy{})
+
+	for _, username := range []string{"al", "alice!", "alice"} {
+		_, err := profiles.ChangeUsername(ctx, aliceID, username)
+		var invalid *domain.ValidationError
+		testutil.AssertTrue(t, errors.As(err, &invalid), "expected ValidationError for "+username)
+	}
+	testutil.AssertEqual(t, len(*renames), 0)
+}
+
+func TestProfileService_ResolveUsername(t *testing.T) {
+	ctx := context.Background()
+	profiles, _, _ := newUsernameTestService(UsernamePolicy{})
+
+	_, err := profiles.ChangeUsername(ctx, aliceID, "alicia")
+	testutil.AssertNoError(t, err)
+
+	for username, want := range map[string]string{"alicia": aliceID, "alice": aliceID, "bob": bobID} {
+		userID, err := profiles.ResolveUsername(ctx, username)
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, userID, want)
+	}
+	_, err = profiles.ResolveUsername(ctx, "carol")
+	testutil.AssertErrorIs(t, err, domain.ErrUserNotFound)
+}
+
+func TestAuthService_Register_HeldUsername(t *testing.T) {
+	userRepo := testutil.NewMockUserRepository()
+	userRepo.Users[aliceID] = testutil.NewTestUser(testutil.WithUserID(aliceID), testutil.WithUsername("alice"))
+	history := testutil.NewMockUsernameHistoryRepository(userRepo)
+	_, err := history.Rename(context.Background(), aliceID, "alicia", time.Now())
+	testutil.AssertNoError(t, err)
+
+	authService := NewAuthService(userRepo, testutil.NewMockSessionRepository())
+	authService.SetUsernameHistory(history, time.Hour)
+
+	_, err = authService.Register(context.Background(), "Alice", "new@example.com", "password123")
+	testutil.AssertErrorIs(t, err, domain.ErrUsernameExists)
+}
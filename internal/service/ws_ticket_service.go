@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"jobsity-chat/internal/domain"
+)
+
+// DefaultWSTicketTTL is how long a WebSocket ticket can be redeemed
+const DefaultWSTicketTTL = 30 * time.Second
+
+// WSTicketService mints and redeems single-use WebSocket connection tickets
+type WSTicketService struct {
+	ticketRepo  domain.WSTicketRepository
+	sessionRepo domain.SessionRepository
+	ttl         time.Duration
+}
+
+func NewWSTicketService(ticketRepo domain.WSTicketRepository, sessionRepo domain.SessionRepository) *WSTicketService {
+	return &WSTicketService{
+		ticketRepo:  ticketRepo,
+		sessionRepo: sessionRepo,
+		ttl:         DefaultWSTicketTTL,
+	}
+}
+
+// Issue creates a ticket for session
+func (s *WSTicketService) Issue(ctx context.Context, session *domain.Session) (*domain.WSTicket, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate ws ticket: %w", err)
+	}
+
+	ticket := &domain.WSTicket{
+		Ticket:       hex.EncodeToString(buf),
+		SessionToken: session.Token,
+		ExpiresAt:    time.Now().Add(s.ttl),
+	}
+	if err := s.ticketRepo.Create(ctx, ticket); err != nil {
+		return nil, err
+	}
+	return ticket, nil
+}
+
+// Redeem consumes ticket and returns the session it was issued for. The
+// session must still be valid.
+func (s *WSTicketService) Redeem(ctx context.Context, ticket string) (*domain.Session, error) {
+	t, err := s.ticketRepo.Consume(ctx, ticket)
+	if err != nil {
+		return nil, err
+	}
+	return s.sessionRepo.GetByToken(ctx, t.SessionToken)
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+func TestWSTicketService_IssueAndRedeem(t *testing.T) {
+	sessionRepo := testutil.NewMockSessionRepository()
+	session := testutil.NewTestSession(testutil.WithToken("session-token"))
+	sessionRepo.Sessions[session.Token] = session
+	ticketRepo := testutil.NewMockWSTicketRepository()
+
+	tickets := NewWSTicketService(ticketRepo, sessionRepo)
+	ctx := context.Background()
+
+	ticket, err := tickets.Issue(ctx, session)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, len(ticket.Ticket), 64)
+	testutil.AssertTrue(t, ticket.ExpiresAt.Sub(time.Now()) <= DefaultWSTicketTTL, "ticket should expire within the TTL")
+
+	redeemed, err := tickets.Redeem(ctx, ticket.Ticket)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, redeemed.Token, session.Token)
+
+	_, err = tickets.Redeem(ctx, ticket.Ticket)
+	testutil.AssertErrorIs(t, err, domain.ErrTicketNotFound)
+}
+
+func TestWSTicketService_RedeemExpiredTicket(t *testing.T) {
+	sessionRepo := testutil.NewMockSessionRepository()
+	ticketRepo := testutil.NewMockWSTicketRepository()
+	ticketRepo.Tickets["stale"] = &domain.WSTicket{
+		Ticket:       "stale",
+		SessionToken: "session-token",
+		ExpiresAt:    time.Now().Add(-time.Second),
+	}
+
+	_, err := NewWSTicketService(ticketRepo, sessionRepo).Redeem(context.Background(), "stale")
+
+	testutil.AssertErrorIs(t, err, domain.ErrTicketNotFound)
+}
+
+func TestWSTicketService_RedeemForExpiredSession(t *testing.T) {
+	sessionRepo := testutil.NewMockSessionRepository()
+	session := testutil.NewTestSession(
+		testutil.WithToken("session-token"),
+		testutil.WithExpired(),
+	)
+	sessionRepo.Sessions[session.Token] = session
+	ticketRepo := testutil.NewMockWSTicketRepository()
+
+	tickets := NewWSTicketService(ticketRepo, sessionRepo)
+	ticket, err := tickets.Issue(context.Background(), session)
+	testutil.AssertNoError(t, err)
+
+	_, err = tickets.Redeem(context.Background(), ticket.Ticket)
+
+	testutil.AssertError(t, err)
+}
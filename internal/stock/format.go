@@ -0,0 +1,155 @@
+package stock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/template"
+
+	"jobsity-chat/internal/config"
+	"jobsity-chat/internal/i18n"
+)
+
+// DefaultTemplateLocale keys the quote template used for locales without
+// their own in a template file
+const DefaultTemplateLocale = "default"
+
+// suffixCurrencies maps Stooq market suffixes to the currency quotes are
+// priced in. London quotes are in pence.
+var suffixCurrencies = map[string]string{
+	"US": "USD",
+	"UK": "GBX",
+	"DE": "EUR",
+	"FR": "EUR",
+	"JP": "JPY",
+	"HK": "HKD",
+	"HU": "HUF",
+	"PL": "PLN",
+}
+
+// CurrencyForSymbol returns the currency of a Stooq symbol's market, or ""
+// when it is not known
+func CurrencyForSymbol(symbol string) string {
+	dot := strings.LastIndexByte(symbol, '.')
+	if dot < 0 {
+		return ""
+	}
+	return suffixCurrencies[strings.ToUpper(symbol[dot+1:])]
+}
+
+// Formatter renders the bot's quote responses. Deployments can replace the
+// translated i18n.BotQuote message with text/template templates, per locale
+// or for all of them, to localize it differently or add branding. Templates
+// see the fields symbol, price, currency, change, change_percent, open, high,
+// low and volume, e.g.
+//
+//	{{.symbol}}: {{printf "%.2f" .price}} {{.currency}}{{with .change}} ({{printf "%+.2f" .}}){{end}}
+//
+// currency is empty for unknown markets. change and change_percent, the move
+// since the open, are nil when Stooq has no opening price, and so are open,
+// high, low and volume when Stooq reports them as N/D.
+type Formatter struct {
+	// templates maps locales, and DefaultTemplateLocale, to their template
+	templates map[string]*template.Template
+}
+
+// NewFormatter parses templates keyed by locale, DefaultTemplateLocale
+// applying to the others. Locales without a template use the i18n catalog.
+// Templates are tried on a sample quote so unknown fields are rejected here
+// rather than when answering a command.
+func NewFormatter(templates map[string]string) (*Formatter, error) {
+	f := &Formatter{templates: make(map[string]*template.Template, len(templates))}
+	sample := &Quote{Symbol: "AAPL.US", Price: 151.5, Open: 150, High: 152, Low: 149, Volume: 1000000, Currency: "USD"}
+	for locale, text := range templates {
+		if locale != DefaultTemplateLocale && !i18n.IsSupported(locale) {
+			return nil, fmt.Errorf("quote template for unsupported locale %q", locale)
+		}
+		tmpl, err := template.New(locale).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quote template for %s: %w", locale, err)
+		}
+		if err := tmpl.Execute(&bytes.Buffer{}, quoteFields(sample)); err != nil {
+			return nil, fmt.Errorf("invalid quote template for %s: %w", locale, err)
+		}
+		f.templates[locale] = tmpl
+	}
+	return f, nil
+}
+
+// FormatterFromConfig returns the Formatter for STOCK_BOT_QUOTE_TEMPLATE, a
+// template for every locale, and STOCK_BOT_QUOTE_TEMPLATE_FILE, a JSON object
+// of templates keyed by locale (or "default") that takes precedence. Without
+// either, quotes use the i18n catalog.
+func FormatterFromConfig(cfg *config.Config) (*Formatter, error) {
+	templates := make(map[string]string)
+	if cfg.StockBotQuoteTemplate != "" {
+		templates[DefaultTemplateLocale] = cfg.StockBotQuoteTemplate
+	}
+	if cfg.StockBotQuoteTemplateFile != "" {
+		data, err := os.ReadFile(cfg.StockBotQuoteTemplateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read quote templates: %w", err)
+		}
+		var fromFile map[string]string
+		if err := json.Unmarshal(data, &fromFile); err != nil {
+			return nil, fmt.Errorf("failed to parse quote templates: %w", err)
+		}
+		for locale, text := range fromFile {
+			templates[locale] = text
+		}
+	}
+	return NewFormatter(templates)
+}
+
+// Format renders q for locale with its template, the default template or,
+// failing both, the i18n catalog
+func (f *Formatter) Format(locale string, q *Quote) string {
+	tmpl, ok := f.templates[locale]
+	if !ok {
+		tmpl, ok = f.templates[DefaultTemplateLocale]
+	}
+	if ok {
+		var buf bytes.Buffer
+		err := tmpl.Execute(&buf, quoteFields(q))
+		if err == nil {
+			return buf.String()
+		}
+		slog.Warn("quote template failed, using the translated message",
+			slog.String("error", err.Error()),
+			slog.String("template", tmpl.Name()))
+	}
+	if percent, ok := q.ChangePercent(); ok {
+		return i18n.T(locale, i18n.BotQuoteChange, q.Symbol, q.Price, percent)
+	}
+	return i18n.T(locale, i18n.BotQuote, q.Symbol, q.Price)
+}
+
+func quoteFields(q *Quote) map[string]any {
+	fields := map[string]any{
+		"symbol":         q.Symbol,
+		"price":          q.Price,
+		"currency":       q.Currency,
+		"change":         nil,
+		"change_percent": nil,
+		"open":           optionalField(q.Open),
+		"high":           optionalField(q.High),
+		"low":            optionalField(q.Low),
+		"volume":         optionalField(q.Volume),
+	}
+	if percent, ok := q.ChangePercent(); ok {
+		fields["change"] = q.Price - q.Open
+		fields["change_percent"] = percent
+	}
+	return fields
+}
+
+// optionalField returns nil for the zero value Quote uses for N/D fields
+func optionalField[T float64 | int64](v T) any {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
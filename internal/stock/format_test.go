@@ -0,0 +1,132 @@
+package stock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"jobsity-chat/internal/config"
+)
+
+func TestCurrencyForSymbol(t *testing.T) {
+	tests := map[string]string{
+		"AAPL.US": "USD",
+		"aapl.us": "USD",
+		"VOD.UK":  "GBX",
+		"CDR.PL":  "PLN",
+		"BTC.V":   "",
+		"AAPL":    "",
+	}
+	for symbol, expected := range tests {
+		if got := CurrencyForSymbol(symbol); got != expected {
+			t.Errorf("CurrencyForSymbol(%q) = %q, expected %q", symbol, got, expected)
+		}
+	}
+}
+
+func TestFormatter_Format(t *testing.T) {
+	f, err := NewFormatter(map[string]string{
+		DefaultTemplateLocale: `{{.symbol}}: {{printf "%.2f" .price}} {{.currency}}{{with .change}} ({{printf "%+.2f" .}}){{end}}`,
+		"es":                  `Acme Bolsa · {{.symbol}} cotiza a {{printf "%.2f" .price}} {{.currency}}`,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	quote := &Quote{Symbol: "AAPL.US", Price: 151.5, Open: 150, Currency: "USD"}
+	tests := []struct {
+		name     string
+		locale   string
+		quote    *Quote
+		expected string
+	}{
+		{"default template", "en", quote, "AAPL.US: 151.50 USD (+1.50)"},
+		{"locale template", "es", quote, "Acme Bolsa · AAPL.US cotiza a 151.50 USD"},
+		{"unknown open", "pt", &Quote{Symbol: "AAPL.US", Price: 151.5, Currency: "USD"}, "AAPL.US: 151.50 USD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.Format(tt.locale, tt.quote); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFormatter_FallsBackToCatalog(t *testing.T) {
+	f, err := NewFormatter(map[string]string{"es": `{{.symbol}} {{.price}}`})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	got := f.Format("en", &Quote{Symbol: "AAPL.US", Price: 93.42})
+	if expected := "AAPL.US quote is $93.42 per share"; got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	got = f.Format("en", &Quote{Symbol: "AAPL.US", Price: 148.5, Open: 150})
+	if expected := "AAPL.US quote is $148.50 per share (-1.00% today)"; got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestFormatter_IntradayFields(t *testing.T) {
+	f, err := NewFormatter(map[string]string{
+		DefaultTemplateLocale: `{{.symbol}}{{with .change_percent}} {{printf "%+.1f%%" .}}{{end}}{{with .volume}} vol {{.}}{{end}}{{with .high}} H {{.}}{{end}}`,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	got := f.Format("en", &Quote{Symbol: "AAPL.US", Price: 151.5, Open: 150, High: 152, Low: 149, Volume: 1000000})
+	if expected := "AAPL.US +1.0% vol 1000000 H 152"; got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	got = f.Format("en", &Quote{Symbol: "AAPL.US", Price: 151.5})
+	if expected := "AAPL.US"; got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestNewFormatter_InvalidTemplates(t *testing.T) {
+	tests := map[string]map[string]string{
+		"syntax error":       {DefaultTemplateLocale: `{{.symbol`},
+		"unknown field":      {DefaultTemplateLocale: `{{.ticker}}`},
+		"unsupported locale": {"xx": `{{.symbol}}`},
+	}
+	for name, templates := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewFormatter(templates); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestFormatterFromConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotes.json")
+	if err := os.WriteFile(path, []byte(`{"pt": "{{.symbol}} vale {{.price}}"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := FormatterFromConfig(&config.Config{
+		StockBotQuoteTemplate:     `{{.symbol}} is at {{.price}}`,
+		StockBotQuoteTemplateFile: path,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	quote := &Quote{Symbol: "AAPL.US", Price: 151.5}
+	if got, expected := f.Format("pt", quote), "AAPL.US vale 151.5"; got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if got, expected := f.Format("en", quote), "AAPL.US is at 151.5"; got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	if _, err := FormatterFromConfig(&config.Config{StockBotQuoteTemplateFile: filepath.Join(t.TempDir(), "missing.json")}); err == nil {
+		t.Error("Expected an error for a missing template file")
+	}
+}
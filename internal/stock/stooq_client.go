@@ -1,80 +1,252 @@
 package stock
 
 import (
+	"bytes"
 	"context"
 	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strconv"
 	"time"
+
+	"jobsity-chat/internal/config"
 )
 
 var (
 	ErrStockNotFound   = errors.New("stock not found")
 	ErrInvalidResponse = errors.New("invalid response from Stooq API")
+	// ErrResponseTooLarge is returned for responses over the configured
+	// MaxResponseBytes
+	ErrResponseTooLarge = errors.New("response from Stooq API too large")
+	// ErrRedirect is returned when Stooq redirects to another host or
+	// redirects too often
+	ErrRedirect = errors.New("unexpected redirect from Stooq API")
 )
 
+// StatusError is returned when Stooq answers with a status other than 200 OK
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// Temporary reports whether retrying the request may succeed
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// Defaults of StooqConfig
+const (
+	DefaultStooqTimeout          = 10 * time.Second
+	DefaultStooqMaxRetries       = 3
+	DefaultStooqMaxResponseBytes = 64 << 10
+	DefaultStooqUserAgent        = "ChattorumuStockBot/1.0"
+
+	maxStooqRedirects = 3
+)
+
+// StooqConfig tunes the HTTP requests of a StooqClient. Zero values use the
+// defaults.
+type StooqConfig struct {
+	// Timeout bounds each attempt, from dialing to reading the body
+	Timeout time.Duration
+	// MaxRetries is the number of attempts for failed connections, 429s
+	// and 5xx responses
+	MaxRetries       int
+	MaxResponseBytes int64
+	UserAgent        string
+}
+
 // Quote represents a stock quote
 type Quote struct {
 	Symbol string
 	Price  float64
 	Date   string
 	Time   string
+	// Open, High, Low and Volume are 0 when Stooq reports them as N/D, e.g.
+	// before the market opens
+	Open   float64
+	High   float64
+	Low    float64
+	Volume int64
+	// Currency is empty when the symbol's market is not known
+	Currency string
+}
+
+// ChangePercent returns the move of the price since the open, in percent.
+// ok is false when the opening price is not known.
+func (q *Quote) ChangePercent() (percent float64, ok bool) {
+	if q.Open <= 0 {
+		return 0, false
+	}
+	return (q.Price - q.Open) / q.Open * 100, true
 }
 
 // StooqClient handles requests to the Stooq API
 type StooqClient struct {
 	baseURL    string
 	httpClient *http.Client
+	cfg        StooqConfig
 }
 
-// NewStooqClient creates a new Stooq API client
+// NewStooqClient creates a new Stooq API client with the default StooqConfig
 func NewStooqClient(baseURL string) *StooqClient {
+	return NewStooqClientWithConfig(baseURL, StooqConfig{})
+}
+
+// NewStooqClientWithConfig creates a new Stooq API client. Connections to
+// Stooq are kept alive and reused across quotes, and redirects are only
+// followed within the host they started on.
+func NewStooqClientWithConfig(baseURL string, cfg StooqConfig) *StooqClient {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultStooqTimeout
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = DefaultStooqMaxRetries
+	}
+	if cfg.MaxResponseBytes <= 0 {
+		cfg.MaxResponseBytes = DefaultStooqMaxResponseBytes
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = DefaultStooqUserAgent
+	}
+
+	dialer := &net.Dialer{Timeout: cfg.Timeout, KeepAlive: 30 * time.Second}
 	return &StooqClient{
 		baseURL: baseURL,
+		cfg:     cfg,
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: cfg.Timeout,
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   cfg.Timeout,
+				ResponseHeaderTimeout: cfg.Timeout,
+				MaxIdleConns:          10,
+				MaxIdleConnsPerHost:   10,
+				IdleConnTimeout:       90 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxStooqRedirects {
+					return fmt.Errorf("%w: stopped after %d redirects", ErrRedirect, len(via))
+				}
+				if req.URL.Hostname() != via[0].URL.Hostname() {
+					return fmt.Errorf("%w: to %s", ErrRedirect, req.URL.Host)
+				}
+				return nil
+			},
 		},
 	}
 }
 
-// GetQuote fetches a stock quote from Stooq API
+// StooqClientFromConfig returns the StooqClient for STOOQ_API_URL and its
+// STOOQ_API_* tuning
+func StooqClientFromConfig(cfg *config.Config) *StooqClient {
+	return NewStooqClientWithConfig(cfg.StooqAPIURL, StooqConfig{
+		Timeout:          cfg.StooqAPITimeout,
+		MaxRetries:       cfg.StooqAPIMaxRetries,
+		MaxResponseBytes: cfg.StooqAPIMaxResponseBytes,
+		UserAgent:        cfg.StooqAPIUserAgent,
+	})
+}
+
+// GetQuote fetches a stock quote from Stooq API. Failed connections, 429s and
+// 5xx responses are retried with a growing delay; other statuses fail with a
+// *StatusError right away.
 func (c *StooqClient) GetQuote(ctx context.Context, stockCode string) (*Quote, error) {
 	url := fmt.Sprintf("%s/q/l/?s=%s&f=sd2t2ohlcv&h&e=csv", c.baseURL, stockCode)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Retry logic with exponential backoff
-	var resp *http.Response
 	var lastErr error
-	for attempt := 1; attempt <= 3; attempt++ {
-		resp, lastErr = c.httpClient.Do(req)
-		if lastErr == nil && resp.StatusCode == http.StatusOK {
-			break
+	for attempt := 1; attempt <= c.cfg.MaxRetries; attempt++ {
+		if attempt > 1 {
+			// Respect the per-command deadline instead of sleeping past it
+			select {
+			case <-time.After(time.Duration(attempt-1) * time.Second):
+			case <-ctx.Done():
+				return nil, fmt.Errorf("quote request cancelled: %w", ctx.Err())
+			}
 		}
-		if resp != nil {
-			resp.Body.Close()
+
+		body, err := c.fetch(ctx, url)
+		if err == nil {
+			return c.parseCSV(bytes.NewReader(body))
 		}
-		if attempt < 3 {
-			time.Sleep(time.Duration(attempt) * time.Second)
+		lastErr = err
+		if !retryable(err) {
+			return nil, err
 		}
 	}
+	return nil, fmt.Errorf("failed to fetch quote after %d attempts: %w", c.cfg.MaxRetries, lastErr)
+}
+
+// fetch GETs url and returns its body, at most MaxResponseBytes of it
+func (c *StooqClient) fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.cfg.UserAgent)
+	req.Header.Set("Accept", "text/csv")
 
-	if lastErr != nil {
-		return nil, fmt.Errorf("failed to fetch quote after 3 attempts: %w", lastErr)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
+
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		// Drain a little so the connection can be reused
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.cfg.MaxResponseBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if int64(len(body)) > c.cfg.MaxResponseBytes {
+		return nil, ErrResponseTooLarge
+	}
+	return body, nil
+}
+
+// retryable reports whether a failed fetch is worth another attempt
+func retryable(err error) bool {
+	var statusErr *StatusError
+	switch {
+	case errors.As(err, &statusErr):
+		return statusErr.Temporary()
+	case errors.Is(err, ErrRedirect), errors.Is(err, ErrResponseTooLarge),
+		errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	}
+	return true
+}
+
+// Ping checks that the Stooq API is reachable with a single request and
+// no retries. Any non-5xx response counts as reachable.
+func (c *StooqClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.cfg.UserAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Stooq: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// Parse CSV response
-	return c.parseCSV(resp.Body)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
 }
 
 // parseCSV parses the CSV response from Stooq API
@@ -117,10 +289,28 @@ func (c *StooqClient) parseCSV(body io.Reader) (*Quote, error) {
 		return nil, fmt.Errorf("failed to parse price: %w", err)
 	}
 
-	return &Quote{
-		Symbol: symbol,
-		Price:  price,
-		Date:   date,
-		Time:   time,
-	}, nil
+	quote := &Quote{
+		Symbol:   symbol,
+		Price:    price,
+		Date:     date,
+		Time:     time,
+		Open:     optionalFloat(data[3]),
+		High:     optionalFloat(data[4]),
+		Low:      optionalFloat(data[5]),
+		Currency: CurrencyForSymbol(symbol),
+	}
+	if len(data) > 7 {
+		quote.Volume = int64(optionalFloat(data[7]))
+	}
+	return quote, nil
+}
+
+// optionalFloat parses a field that Stooq may report as N/D, returning 0
+// for it and anything else that is not a number
+func optionalFloat(field string) float64 {
+	v, err := strconv.ParseFloat(field, 64)
+	if err != nil || v < 0 {
+		return 0
+	}
+	return v
 }
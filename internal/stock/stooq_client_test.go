@@ -219,6 +219,46 @@ func TestParseCSV_ValidResponse(t *testing.T) {
 	if quote.Price != 151.5 {
 		t.Errorf("Expected price 151.5, got %.2f", quote.Price)
 	}
+
+	if quote.Open != 150.0 {
+		t.Errorf("Expected open 150.00, got %.2f", quote.Open)
+	}
+
+	if quote.High != 152.0 || quote.Low != 149.0 {
+		t.Errorf("Expected high/low 152.00/149.00, got %.2f/%.2f", quote.High, quote.Low)
+	}
+
+	if quote.Volume != 1000000 {
+		t.Errorf("Expected volume 1000000, got %d", quote.Volume)
+	}
+
+	if quote.Currency != "USD" {
+		t.Errorf("Expected currency USD, got %s", quote.Currency)
+	}
+
+	percent, ok := quote.ChangePercent()
+	if !ok || percent != 1.0 {
+		t.Errorf("Expected change of +1.00%%, got %.2f (ok=%v)", percent, ok)
+	}
+}
+
+func TestParseCSV_IntradayNotAvailable(t *testing.T) {
+	csv := "Symbol,Date,Time,Open,High,Low,Close,Volume\nAAPL.US,2026-01-28,08:00:00,N/D,N/D,N/D,151.5,N/D"
+	client := NewStooqClient("http://example.com")
+
+	quote, err := client.parseCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if quote.Open != 0 || quote.High != 0 || quote.Low != 0 || quote.Volume != 0 {
+		t.Errorf("Expected N/D fields to be 0, got open=%.2f high=%.2f low=%.2f volume=%d",
+			quote.Open, quote.High, quote.Low, quote.Volume)
+	}
+
+	if _, ok := quote.ChangePercent(); ok {
+		t.Error("Expected no change percent without an opening price")
+	}
 }
 
 func TestParseCSV_InvalidFormat(t *testing.T) {
@@ -494,3 +534,161 @@ func createMockCSV(symbol, date, time, close string) io.Reader {
 		symbol + "," + date + "," + time + ",150.0,152.0,149.0," + close + ",1000000"
 	return strings.NewReader(csv)
 }
+
+func TestPing(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"reachable", http.StatusOK, false},
+		{"client error still reachable", http.StatusNotFound, false},
+		{"server error", http.StatusBadGateway, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			err := NewStooqClient(server.URL).Ping(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Ping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPing_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	if err := NewStooqClient(server.URL).Ping(context.Background()); err == nil {
+		t.Error("Expected error for unreachable server")
+	}
+}
+
+func TestGetQuote_RetryBackoffRespectsContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewStooqClient(server.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GetQuote(ctx, "AAPL.US")
+	if err == nil {
+		t.Fatal("Expected error for cancelled retries")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected retries to stop at the deadline, took %v", elapsed)
+	}
+}
+
+func TestGetQuote_ClientErrorStatusNotRetried(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	_, err := NewStooqClient(server.URL).GetQuote(context.Background(), "AAPL.US")
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected a 404 StatusError, got: %v", err)
+	}
+	if errors.Is(err, ErrStockNotFound) {
+		t.Error("Expected a status error to be distinct from ErrStockNotFound")
+	}
+	if attempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d", attempts)
+	}
+}
+
+func TestGetQuote_UserAgent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("User-Agent"); got != "TestBot/2.0" {
+			t.Errorf("Expected User-Agent TestBot/2.0, got %q", got)
+		}
+		w.Write([]byte("Symbol,Date,Time,Open,High,Low,Close,Volume\nAAPL.US,2026-01-28,22:00:00,150.0,152.0,149.0,151.5,1000000"))
+	}))
+	defer server.Close()
+
+	client := NewStooqClientWithConfig(server.URL, StooqConfig{UserAgent: "TestBot/2.0"})
+	if _, err := client.GetQuote(context.Background(), "AAPL.US"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+}
+
+func TestGetQuote_ResponseTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Symbol,Date,Time,Open,High,Low,Close,Volume\n" + strings.Repeat("x", 1024)))
+	}))
+	defer server.Close()
+
+	client := NewStooqClientWithConfig(server.URL, StooqConfig{MaxResponseBytes: 512})
+	if _, err := client.GetQuote(context.Background(), "AAPL.US"); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Expected ErrResponseTooLarge, got: %v", err)
+	}
+}
+
+func TestGetQuote_Redirects(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected the redirect to another host not to be followed")
+	}))
+	defer other.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/q/l/":
+			http.Redirect(w, r, "/csv?"+r.URL.RawQuery, http.StatusFound)
+		case "/csv":
+			w.Write([]byte("Symbol,Date,Time,Open,High,Low,Close,Volume\nAAPL.US,2026-01-28,22:00:00,150.0,152.0,149.0,151.5,1000000"))
+		case "/away/q/l/":
+			http.Redirect(w, r, strings.Replace(other.URL, "127.0.0.1", "localhost", 1)+"/q/l/", http.StatusFound)
+		case "/loop/q/l/":
+			http.Redirect(w, r, r.URL.String(), http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	quote, err := NewStooqClient(server.URL).GetQuote(context.Background(), "AAPL.US")
+	if err != nil {
+		t.Fatalf("Expected a same-host redirect to be followed, got: %v", err)
+	}
+	if quote.Symbol != "AAPL.US" {
+		t.Errorf("Expected symbol AAPL.US, got %s", quote.Symbol)
+	}
+
+	for _, path := range []string{"/away", "/loop"} {
+		_, err := NewStooqClient(server.URL+path).GetQuote(context.Background(), "AAPL.US")
+		if !errors.Is(err, ErrRedirect) {
+			t.Errorf("%s: expected ErrRedirect, got: %v", path, err)
+		}
+	}
+}
+
+func TestStatusError_Temporary(t *testing.T) {
+	tests := map[int]bool{
+		http.StatusBadRequest:          false,
+		http.StatusNotFound:            false,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusBadGateway:          true,
+	}
+	for code, expected := range tests {
+		if got := (&StatusError{StatusCode: code}).Temporary(); got != expected {
+			t.Errorf("StatusError{%d}.Temporary() = %v, expected %v", code, got, expected)
+		}
+	}
+}
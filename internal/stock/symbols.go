@@ -0,0 +1,114 @@
+package stock
+
+import (
+	_ "embed"
+	"slices"
+	"strings"
+)
+
+// maxSuggestions bounds the close matches offered for an unknown symbol
+const maxSuggestions = 3
+
+//go:embed symbols.txt
+var symbolList string
+
+// knownSymbols are the bundled symbols suggestions are drawn from
+var knownSymbols = parseSymbols(symbolList)
+
+func parseSymbols(list string) []string {
+	var symbols []string
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		symbols = append(symbols, strings.ToUpper(line))
+	}
+	return symbols
+}
+
+// Suggest returns up to three bundled symbols close to stockCode, closest
+// first, for answering a lookup Stooq did not find. A code without a market
+// suffix, like "APPL", is also compared to the symbols without theirs, so
+// "AAPL" suggests AAPL.US.
+func Suggest(stockCode string) []string {
+	return suggest(knownSymbols, stockCode)
+}
+
+func suggest(symbols []string, stockCode string) []string {
+	code := strings.ToUpper(strings.TrimSpace(stockCode))
+	if code == "" {
+		return nil
+	}
+	_, hasSuffix := splitSymbol(code)
+	// One typo in short codes already makes most of them look alike
+	maxDistance := 2
+	if len(code) <= 4 {
+		maxDistance = 1
+	}
+
+	type match struct {
+		symbol   string
+		distance int
+	}
+	var matches []match
+	for _, symbol := range symbols {
+		if symbol == code {
+			continue
+		}
+		distance := editDistance(code, symbol)
+		if !hasSuffix {
+			base, _ := splitSymbol(symbol)
+			distance = min(distance, editDistance(code, base))
+		}
+		if distance <= maxDistance {
+			matches = append(matches, match{symbol, distance})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b match) int {
+		return a.distance - b.distance
+	})
+
+	suggestions := make([]string, 0, min(len(matches), maxSuggestions))
+	for _, m := range matches[:min(len(matches), maxSuggestions)] {
+		suggestions = append(suggestions, m.symbol)
+	}
+	return suggestions
+}
+
+// splitSymbol returns the symbol without its market suffix, and whether it
+// had one
+func splitSymbol(symbol string) (string, bool) {
+	dot := strings.LastIndexByte(symbol, '.')
+	if dot < 0 {
+		return symbol, false
+	}
+	return symbol[:dot], true
+}
+
+// editDistance is the number of insertions, deletions, substitutions and
+// transpositions of adjacent characters turning a into b
+func editDistance(a, b string) int {
+	// rows i-2, i-1 and i of the distance matrix
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
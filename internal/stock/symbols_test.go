@@ -0,0 +1,69 @@
+package stock
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	symbols := []string{"AAPL.US", "AMZN.US", "MSFT.US", "META.US", "GOOG.US", "GOOGL.US", "VOD.UK", "F.US"}
+	tests := []struct {
+		code     string
+		expected []string
+	}{
+		{"AAPL", []string{"AAPL.US"}},
+		{"appl", []string{"AAPL.US"}},
+		{"APPL.US", []string{"AAPL.US"}},
+		{"AAPL.UK", []string{"AAPL.US"}},
+		{"MSTF", []string{"MSFT.US"}},
+		{"GOOGLE.US", []string{"GOOGL.US", "GOOG.US"}},
+		{"VOD.US", []string{"VOD.UK"}},
+		{"META.US", []string{}},
+		{"XYZQ", []string{}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			got := suggest(symbols, tt.code)
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("suggest(%q) = %v, expected %v", tt.code, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSuggest_Limit(t *testing.T) {
+	symbols := []string{"AB.US", "AC.US", "AD.US", "AE.US", "AF.US"}
+	if got := suggest(symbols, "AA"); len(got) != maxSuggestions {
+		t.Errorf("Expected %d suggestions, got %v", maxSuggestions, got)
+	}
+}
+
+func TestSuggest_BundledSymbols(t *testing.T) {
+	if len(knownSymbols) == 0 {
+		t.Fatal("Expected bundled symbols")
+	}
+	if got := Suggest("APPL"); len(got) == 0 || got[0] != "AAPL.US" {
+		t.Errorf("Expected AAPL.US first, got %v", got)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"AAPL", "AAPL", 0},
+		{"AAPL", "APPL", 1},
+		{"MSFT", "MSTF", 1},
+		{"GOOG", "GOOGL", 1},
+		{"", "ABC", 3},
+		{"TSLA", "NVDA", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.expected {
+			t.Errorf("editDistance(%q, %q) = %d, expected %d", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
@@ -5,10 +5,16 @@ package testutil
 import (
 	"context"
 	"errors"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/observability"
 )
 
 // Common test errors
@@ -22,19 +28,36 @@ type MockUserRepository struct {
 	mu sync.RWMutex
 
 	// Function overrides - set these to customize behavior
-	CreateFunc        func(ctx context.Context, user *domain.User) error
-	GetByIDFunc       func(ctx context.Context, id string) (*domain.User, error)
-	GetByUsernameFunc func(ctx context.Context, username string) (*domain.User, error)
-	GetByEmailFunc    func(ctx context.Context, email string) (*domain.User, error)
-
-	// In-memory storage for simple tests
-	Users map[string]*domain.User
+	CreateFunc         func(ctx context.Context, user *domain.User) error
+	GetByIDFunc        func(ctx context.Context, id string) (*domain.User, error)
+	GetByUsernameFunc  func(ctx context.Context, username string) (*domain.User, error)
+	GetByEmailFunc     func(ctx context.Context, email string) (*domain.User, error)
+	UpdateRoleFunc     func(ctx context.Context, userID, role string) error
+	SetDeactivatedFunc func(ctx context.Context, userID string, deactivated bool) error
+	SetLocaleFunc      func(ctx context.Context, userID, locale string) error
+	GetProfileFunc     func(ctx context.Context, id string) (*domain.Profile, error)
+
+	UpdateProfileSettingsFunc     func(ctx context.Context, userID string, settings *domain.ProfileSettings) error
+	CreateGuestFunc               func(ctx context.Context, user *domain.User) error
+	ConvertGuestFunc              func(ctx context.Context, user *domain.User) error
+	DeleteGuestsCreatedBeforeFunc func(ctx context.Context, cutoff time.Time) (int64, error)
+	GetStatusFunc                 func(ctx context.Context, userID string) (*domain.UserStatus, error)
+	SetStatusFunc                 func(ctx context.Context, userID string, status *domain.UserStatus) error
+
+	// In-memory storage for simple tests. Users without an entry in
+	// ProfileSettings have the default public settings, and those without
+	// one in Statuses are active.
+	Users           map[string]*domain.User
+	ProfileSettings map[string]domain.ProfileSettings
+	Statuses        map[string]domain.UserStatus
 }
 
 // NewMockUserRepository creates a new MockUserRepository with initialized maps
 func NewMockUserRepository() *MockUserRepository {
 	return &MockUserRepository{
-		Users: make(map[string]*domain.User),
+		Users:           make(map[string]*domain.User),
+		ProfileSettings: make(map[string]domain.ProfileSettings),
+		Statuses:        make(map[string]domain.UserStatus),
 	}
 }
 
@@ -62,6 +85,9 @@ func (m *MockUserRepository) Create(ctx context.Context, user *domain.User) erro
 	if user.ID == "" {
 		user.ID = "user-" + user.Username
 	}
+	if user.Role == "" {
+		user.Role = domain.RoleUser
+	}
 	if user.CreatedAt.IsZero() {
 		user.CreatedAt = time.Now()
 	}
@@ -97,6 +123,26 @@ func (m *MockUserRepository) GetByUsername(ctx context.Context, username string)
 	return nil, domain.ErrUserNotFound
 }
 
+func (m *MockUserRepository) HasConfusableUsername(ctx context.Context, username, exceptUserID string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	skeleton := usernameSkeleton(username)
+	for _, user := range m.Users {
+		if user.ID != exceptUserID && usernameSkeleton(user.Username) == skeleton {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// usernameSkeleton does what the username_skeleton SQL function does
+func usernameSkeleton(username string) string {
+	s := strings.NewReplacer("0", "o", "1", "l", "i", "l", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b", "_", "").
+		Replace(strings.ToLower(username))
+	return strings.NewReplacer("rn", "m", "vv", "w").Replace(s)
+}
+
 func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
 	if m.GetByEmailFunc != nil {
 		return m.GetByEmailFunc(ctx, email)
@@ -112,15 +158,207 @@ func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (*dom
 	return nil, domain.ErrUserNotFound
 }
 
+func (m *MockUserRepository) UpdateRole(ctx context.Context, userID, role string) error {
+	if m.UpdateRoleFunc != nil {
+		return m.UpdateRoleFunc(ctx, userID, role)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.Users[userID]
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+	user.Role = role
+	return nil
+}
+
+func (m *MockUserRepository) SetDeactivated(ctx context.Context, userID string, deactivated bool) error {
+	if m.SetDeactivatedFunc != nil {
+		return m.SetDeactivatedFunc(ctx, userID, deactivated)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.Users[userID]
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+	switch {
+	case !deactivated:
+		user.DeactivatedAt = nil
+	case user.DeactivatedAt == nil:
+		now := time.Now()
+		user.DeactivatedAt = &now
+	}
+	return nil
+}
+
+func (m *MockUserRepository) SetLocale(ctx context.Context, userID, locale string) error {
+	if m.SetLocaleFunc != nil {
+		return m.SetLocaleFunc(ctx, userID, locale)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.Users[userID]
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+	user.Locale = locale
+	return nil
+}
+
+func (m *MockUserRepository) GetProfile(ctx context.Context, id string) (*domain.Profile, error) {
+	if m.GetProfileFunc != nil {
+		return m.GetProfileFunc(ctx, id)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.Users[id]
+	if !ok || !user.IsActive() {
+		return nil, domain.ErrUserNotFound
+	}
+	settings, ok := m.ProfileSettings[id]
+	if !ok {
+		settings = domain.ProfileSettings{Visibility: domain.ProfilePublic, ShowOnlineStatus: true, Version: 1}
+	}
+	return &domain.Profile{
+		ID:               user.ID,
+		Username:         user.Username,
+		AvatarURL:        settings.AvatarURL,
+		Visibility:       settings.Visibility,
+		ShowOnlineStatus: settings.ShowOnlineStatus,
+		Version:          settings.Version,
+		CreatedAt:        user.CreatedAt,
+	}, nil
+}
+
+func (m *MockUserRepository) UpdateProfileSettings(ctx context.Context, userID string, settings *domain.ProfileSettings) error {
+	if m.UpdateProfileSettingsFunc != nil {
+		return m.UpdateProfileSettingsFunc(ctx, userID, settings)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.Users[userID]; !ok {
+		return domain.ErrUserNotFound
+	}
+	if m.ProfileSettings == nil {
+		m.ProfileSettings = make(map[string]domain.ProfileSettings)
+	}
+	current := 1
+	if stored, ok := m.ProfileSettings[userID]; ok {
+		current = stored.Version
+	}
+	if settings.Version != 0 && settings.Version != current {
+		return domain.ErrVersionConflict
+	}
+	settings.Version = current + 1
+	m.ProfileSettings[userID] = *settings
+	return nil
+}
+
+func (m *MockUserRepository) GetStatus(ctx context.Context, userID string) (*domain.UserStatus, error) {
+	if m.GetStatusFunc != nil {
+		return m.GetStatusFunc(ctx, userID)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.Users[userID]
+	if !ok || !user.IsActive() {
+		return nil, domain.ErrUserNotFound
+	}
+	status, ok := m.Statuses[userID]
+	if !ok {
+		status = domain.UserStatus{Status: domain.StatusActive}
+	}
+	return &status, nil
+}
+
+func (m *MockUserRepository) SetStatus(ctx context.Context, userID string, status *domain.UserStatus) error {
+	if m.SetStatusFunc != nil {
+		return m.SetStatusFunc(ctx, userID, status)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.Users[userID]; !ok {
+		return domain.ErrUserNotFound
+	}
+	if m.Statuses == nil {
+		m.Statuses = make(map[string]domain.UserStatus)
+	}
+	m.Statuses[userID] = *status
+	return nil
+}
+
+func (m *MockUserRepository) CreateGuest(ctx context.Context, user *domain.User) error {
+	if m.CreateGuestFunc != nil {
+		return m.CreateGuestFunc(ctx, user)
+	}
+	user.Role = domain.RoleGuest
+	return m.Create(ctx, user)
+}
+
+func (m *MockUserRepository) ConvertGuest(ctx context.Context, user *domain.User) error {
+	if m.ConvertGuestFunc != nil {
+		return m.ConvertGuestFunc(ctx, user)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	guest, ok := m.Users[user.ID]
+	if !ok || !guest.IsGuest() {
+		return domain.ErrUserNotFound
+	}
+	for _, u := range m.Users {
+		if u.ID == user.ID {
+			continue
+		}
+		if u.Username == user.Username {
+			return domain.ErrUsernameExists
+		}
+		if u.Email == user.Email {
+			return domain.ErrEmailExists
+		}
+	}
+	user.Role = domain.RoleUser
+	user.CreatedAt = guest.CreatedAt
+	m.Users[user.ID] = user
+	return nil
+}
+
+func (m *MockUserRepository) DeleteGuestsCreatedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	if m.DeleteGuestsCreatedBeforeFunc != nil {
+		return m.DeleteGuestsCreatedBeforeFunc(ctx, cutoff)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int64
+	for id, user := range m.Users {
+		if user.IsGuest() && user.CreatedAt.Before(cutoff) {
+			delete(m.Users, id)
+			count++
+		}
+	}
+	return count, nil
+}
+
 // MockSessionRepository implements domain.SessionRepository for testing
 type MockSessionRepository struct {
 	mu sync.RWMutex
 
 	// Function overrides
-	CreateFunc        func(ctx context.Context, session *domain.Session) error
-	GetByTokenFunc    func(ctx context.Context, token string) (*domain.Session, error)
-	DeleteFunc        func(ctx context.Context, token string) error
-	DeleteExpiredFunc func(ctx context.Context) (int64, error)
+	CreateFunc         func(ctx context.Context, session *domain.Session) error
+	GetByTokenFunc     func(ctx context.Context, token string) (*domain.Session, error)
+	DeleteFunc         func(ctx context.Context, token string) error
+	DeleteExpiredFunc  func(ctx context.Context) (int64, error)
+	DeleteByUserIDFunc func(ctx context.Context, userID string) (int64, error)
+	TouchFunc          func(ctx context.Context, activity []domain.SessionActivity) (int64, error)
 
 	// In-memory storage
 	Sessions map[string]*domain.Session
@@ -192,300 +430,1989 @@ func (m *MockSessionRepository) DeleteExpired(ctx context.Context) (int64, error
 	return count, nil
 }
 
-// MockChatroomRepository implements domain.ChatroomRepository for testing
-type MockChatroomRepository struct {
-	mu sync.RWMutex
+func (m *MockSessionRepository) DeleteByUserID(ctx context.Context, userID string) (int64, error) {
+	if m.DeleteByUserIDFunc != nil {
+		return m.DeleteByUserIDFunc(ctx, userID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int64
+	for token, session := range m.Sessions {
+		if session.UserID == userID {
+			delete(m.Sessions, token)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MockSessionRepository) Touch(ctx context.Context, activity []domain.SessionActivity) (int64, error) {
+	if m.TouchFunc != nil {
+		return m.TouchFunc(ctx, activity)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int64
+	for _, a := range activity {
+		session, ok := m.Sessions[a.Token]
+		if !ok || !session.ExpiresAt.After(a.At) {
+			continue
+		}
+		session.ExpiresAt = session.RenewedExpiry(a.At)
+		session.LastActivityAt = a.At
+		count++
+	}
+	return count, nil
+}
+
+// MockWSTicketRepository implements domain.WSTicketRepository for testing
+type MockWSTicketRepository struct {
+	mu sync.Mutex
 
 	// Function overrides
-	CreateFunc           func(ctx context.Context, chatroom *domain.Chatroom) error
-	CreateWithMemberFunc func(ctx context.Context, chatroom *domain.Chatroom, userID string) error
-	GetByIDFunc          func(ctx context.Context, id string) (*domain.Chatroom, error)
-	ListFunc             func(ctx context.Context) ([]*domain.Chatroom, error)
-	ListPaginatedFunc    func(ctx context.Context, limit int, cursor string) ([]*domain.Chatroom, string, error)
-	AddMemberFunc        func(ctx context.Context, chatroomID, userID string) error
-	IsMemberFunc         func(ctx context.Context, chatroomID, userID string) (bool, error)
+	CreateFunc        func(ctx context.Context, ticket *domain.WSTicket) error
+	ConsumeFunc       func(ctx context.Context, ticket string) (*domain.WSTicket, error)
+	DeleteExpiredFunc func(ctx context.Context) (int64, error)
 
 	// In-memory storage
-	Chatrooms map[string]*domain.Chatroom
-	Members   map[string]map[string]bool // chatroomID -> userID -> isMember
+	Tickets map[string]*domain.WSTicket
 }
 
-// NewMockChatroomRepository creates a new MockChatroomRepository with initialized maps
-func NewMockChatroomRepository() *MockChatroomRepository {
-	return &MockChatroomRepository{
-		Chatrooms: make(map[string]*domain.Chatroom),
-		Members:   make(map[string]map[string]bool),
+// NewMockWSTicketRepository creates a new MockWSTicketRepository with initialized maps
+func NewMockWSTicketRepository() *MockWSTicketRepository {
+	return &MockWSTicketRepository{
+		Tickets: make(map[string]*domain.WSTicket),
 	}
 }
 
-func (m *MockChatroomRepository) Create(ctx context.Context, chatroom *domain.Chatroom) error {
+func (m *MockWSTicketRepository) Create(ctx context.Context, ticket *domain.WSTicket) error {
 	if m.CreateFunc != nil {
-		return m.CreateFunc(ctx, chatroom)
+		return m.CreateFunc(ctx, ticket)
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if chatroom.ID == "" {
-		chatroom.ID = "chatroom-" + chatroom.Name
-	}
-	if chatroom.CreatedAt.IsZero() {
-		chatroom.CreatedAt = time.Now()
-	}
-	m.Chatrooms[chatroom.ID] = chatroom
+	ticket.CreatedAt = time.Now()
+	m.Tickets[ticket.Ticket] = ticket
 	return nil
 }
 
-func (m *MockChatroomRepository) CreateWithMember(ctx context.Context, chatroom *domain.Chatroom, userID string) error {
-	if m.CreateWithMemberFunc != nil {
-		return m.CreateWithMemberFunc(ctx, chatroom, userID)
+func (m *MockWSTicketRepository) Consume(ctx context.Context, ticket string) (*domain.WSTicket, error) {
+	if m.ConsumeFunc != nil {
+		return m.ConsumeFunc(ctx, ticket)
 	}
-	if err := m.Create(ctx, chatroom); err != nil {
-		return err
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.Tickets[ticket]
+	if !ok || !t.ExpiresAt.After(time.Now()) {
+		return nil, domain.ErrTicketNotFound
 	}
-	return m.AddMember(ctx, chatroom.ID, userID)
+	delete(m.Tickets, ticket)
+	return t, nil
 }
 
-func (m *MockChatroomRepository) GetByID(ctx context.Context, id string) (*domain.Chatroom, error) {
-	if m.GetByIDFunc != nil {
-		return m.GetByIDFunc(ctx, id)
+func (m *MockWSTicketRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	if m.DeleteExpiredFunc != nil {
+		return m.DeleteExpiredFunc(ctx)
 	}
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	if chatroom, ok := m.Chatrooms[id]; ok {
-		return chatroom, nil
+	var count int64
+	now := time.Now()
+	for ticket, t := range m.Tickets {
+		if !t.ExpiresAt.After(now) {
+			delete(m.Tickets, ticket)
+			count++
+		}
 	}
-	return nil, domain.ErrChatroomNotFound
+	return count, nil
 }
 
-func (m *MockChatroomRepository) List(ctx context.Context) ([]*domain.Chatroom, error) {
-	if m.ListFunc != nil {
-		return m.ListFunc(ctx)
-	}
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// MockIdentityRepository implements domain.IdentityRepository for testing
+type MockIdentityRepository struct {
+	mu sync.Mutex
 
-	result := make([]*domain.Chatroom, 0, len(m.Chatrooms))
-	for _, chatroom := range m.Chatrooms {
-		result = append(result, chatroom)
-	}
-	return result, nil
+	// Function overrides
+	CreateFunc               func(ctx context.Context, identity *domain.UserIdentity) error
+	GetByProviderSubjectFunc func(ctx context.Context, provider, subject string) (*domain.UserIdentity, error)
+	ListByProviderFunc       func(ctx context.Context, provider string) ([]*domain.UserIdentity, error)
+
+	// In-memory storage, keyed by provider + ":" + subject
+	Identities map[string]*domain.UserIdentity
 }
 
-func (m *MockChatroomRepository) ListPaginated(ctx context.Context, limit int, cursor string) ([]*domain.Chatroom, string, error) {
-	if m.ListPaginatedFunc != nil {
-		return m.ListPaginatedFunc(ctx, limit, cursor)
-	}
-	chatrooms, err := m.List(ctx)
-	if err != nil {
-		return nil, "", err
+// NewMockIdentityRepository creates a new MockIdentityRepository with initialized maps
+func NewMockIdentityRepository() *MockIdentityRepository {
+	return &MockIdentityRepository{
+		Identities: make(map[string]*domain.UserIdentity),
 	}
+}
 
-	// Simple pagination: return up to limit items
-	if len(chatrooms) > limit {
-		return chatrooms[:limit], chatrooms[limit-1].ID, nil
+func (m *MockIdentityRepository) Create(ctx context.Context, identity *domain.UserIdentity) error {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, identity)
 	}
-	return chatrooms, "", nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	identity.CreatedAt = time.Now()
+	m.Identities[identity.Provider+":"+identity.Subject] = identity
+	return nil
 }
 
-func (m *MockChatroomRepository) AddMember(ctx context.Context, chatroomID, userID string) error {
-	if m.AddMemberFunc != nil {
-		return m.AddMemberFunc(ctx, chatroomID, userID)
+func (m *MockIdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	if m.GetByProviderSubjectFunc != nil {
+		return m.GetByProviderSubjectFunc(ctx, provider, subject)
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if m.Members == nil {
-		m.Members = make(map[string]map[string]bool)
+	if identity, ok := m.Identities[provider+":"+subject]; ok {
+		return identity, nil
 	}
-	if m.Members[chatroomID] == nil {
-		m.Members[chatroomID] = make(map[string]bool)
-	}
-	m.Members[chatroomID][userID] = true
-	return nil
+	return nil, domain.ErrIdentityNotFound
 }
 
-func (m *MockChatroomRepository) IsMember(ctx context.Context, chatroomID, userID string) (bool, error) {
-	if m.IsMemberFunc != nil {
-		return m.IsMemberFunc(ctx, chatroomID, userID)
+func (m *MockIdentityRepository) ListByProvider(ctx context.Context, provider string) ([]*domain.UserIdentity, error) {
+	if m.ListByProviderFunc != nil {
+		return m.ListByProviderFunc(ctx, provider)
 	}
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	if members, ok := m.Members[chatroomID]; ok {
-		return members[userID], nil
+	var identities []*domain.UserIdentity
+	for _, identity := range m.Identities {
+		if identity.Provider == provider {
+			identities = append(identities, identity)
+		}
 	}
-	return false, nil
+	return identities, nil
 }
 
-// MockMessageRepository implements domain.MessageRepository for testing
-type MockMessageRepository struct {
-	mu sync.RWMutex
+// MockOrganizationRepository implements domain.OrganizationRepository for testing
+type MockOrganizationRepository struct {
+	mu sync.Mutex
 
 	// Function overrides
-	CreateFunc              func(ctx context.Context, message *domain.Message) error
-	GetByChatroomFunc       func(ctx context.Context, chatroomID string, limit int) ([]*domain.Message, error)
-	GetByChatroomBeforeFunc func(ctx context.Context, chatroomID string, before string, limit int) ([]*domain.Message, error)
+	CreateFunc    func(ctx context.Context, org *domain.Organization) error
+	GetByIDFunc   func(ctx context.Context, id string) (*domain.Organization, error)
+	GetBySlugFunc func(ctx context.Context, slug string) (*domain.Organization, error)
 
-	// In-memory storage
-	Messages []*domain.Message
+	// In-memory storage, keyed by ID
+	Organizations map[string]*domain.Organization
 }
 
-// NewMockMessageRepository creates a new MockMessageRepository with initialized slices
-func NewMockMessageRepository() *MockMessageRepository {
-	return &MockMessageRepository{
-		Messages: make([]*domain.Message, 0),
+// NewMockOrganizationRepository creates a new MockOrganizationRepository with initialized maps
+func NewMockOrganizationRepository() *MockOrganizationRepository {
+	return &MockOrganizationRepository{
+		Organizations: make(map[string]*domain.Organization),
 	}
 }
 
-func (m *MockMessageRepository) Create(ctx context.Context, message *domain.Message) error {
+func (m *MockOrganizationRepository) Create(ctx context.Context, org *domain.Organization) error {
 	if m.CreateFunc != nil {
-		return m.CreateFunc(ctx, message)
+		return m.CreateFunc(ctx, org)
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if message.ID == "" {
-		message.ID = "msg-" + time.Now().Format("20060102150405.000")
+	for _, existing := range m.Organizations {
+		if existing.Slug == org.Slug {
+			return domain.ErrOrganizationExists
+		}
 	}
-	if message.CreatedAt.IsZero() {
-		message.CreatedAt = time.Now()
+	if org.ID == "" {
+		org.ID = "org-" + org.Slug
 	}
-	m.Messages = append(m.Messages, message)
+	org.CreatedAt = time.Now()
+	m.Organizations[org.ID] = org
 	return nil
 }
 
-func (m *MockMessageRepository) GetByChatroom(ctx context.Context, chatroomID string, limit int) ([]*domain.Message, error) {
-	if m.GetByChatroomFunc != nil {
-		return m.GetByChatroomFunc(ctx, chatroomID, limit)
+func (m *MockOrganizationRepository) GetByID(ctx context.Context, id string) (*domain.Organization, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(ctx, id)
 	}
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	result := make([]*domain.Message, 0)
-	for _, msg := range m.Messages {
-		if msg.ChatroomID == chatroomID {
-			result = append(result, msg)
+	if org, ok := m.Organizations[id]; ok {
+		return org, nil
+	}
+	return nil, domain.ErrOrganizationNotFound
+}
+
+func (m *MockOrganizationRepository) GetBySlug(ctx context.Context, slug string) (*domain.Organization, error) {
+	if m.GetBySlugFunc != nil {
+		return m.GetBySlugFunc(ctx, slug)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, org := range m.Organizations {
+		if org.Slug == slug {
+			return org, nil
 		}
 	}
+	return nil, domain.ErrOrganizationNotFound
+}
 
-	// Return last 'limit' messages (most recent)
-	if len(result) > limit {
-		result = result[len(result)-limit:]
+// MockQuotaRepository implements domain.QuotaRepository for testing
+type MockQuotaRepository struct {
+	mu sync.Mutex
+
+	// Function overrides
+	GetOverridesFunc         func(ctx context.Context, orgID string) (*domain.QuotaOverrides, error)
+	SetOverridesFunc         func(ctx context.Context, orgID string, overrides *domain.QuotaOverrides) error
+	CountRoomsCreatedByFunc  func(ctx context.Context, userID string) (int, error)
+	CountMessagesSinceFunc   func(ctx context.Context, chatroomID string, since time.Time) (int, error)
+	LockChatroomMessagesFunc func(ctx context.Context, chatroomID string) error
+
+	// In-memory storage: overrides by organization ID, rooms created by user
+	// ID and messages sent today by chatroom ID
+	Overrides     map[string]*domain.QuotaOverrides
+	RoomCounts    map[string]int
+	MessageCounts map[string]int
+}
+
+// NewMockQuotaRepository creates a new MockQuotaRepository with initialized maps
+func NewMockQuotaRepository() *MockQuotaRepository {
+	return &MockQuotaRepository{
+		Overrides:     make(map[string]*domain.QuotaOverrides),
+		RoomCounts:    make(map[string]int),
+		MessageCounts: make(map[string]int),
 	}
-	return result, nil
 }
 
-func (m *MockMessageRepository) GetByChatroomBefore(ctx context.Context, chatroomID string, before string, limit int) ([]*domain.Message, error) {
-	if m.GetByChatroomBeforeFunc != nil {
-		return m.GetByChatroomBeforeFunc(ctx, chatroomID, before, limit)
+func (m *MockQuotaRepository) GetOverrides(ctx context.Context, orgID string) (*domain.QuotaOverrides, error) {
+	if m.GetOverridesFunc != nil {
+		return m.GetOverridesFunc(ctx, orgID)
 	}
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	result := make([]*domain.Message, 0)
-	foundBefore := false
+	if overrides, ok := m.Overrides[orgID]; ok {
+		return overrides, nil
+	}
+	return &domain.QuotaOverrides{}, nil
+}
 
-	for i := len(m.Messages) - 1; i >= 0; i-- {
-		msg := m.Messages[i]
-		if msg.ChatroomID != chatroomID {
-			continue
-		}
-		if msg.ID == before {
-			foundBefore = true
-			continue
+func (m *MockQuotaRepository) SetOverrides(ctx context.Context, orgID string, overrides *domain.QuotaOverrides) error {
+	if m.SetOverridesFunc != nil {
+		return m.SetOverridesFunc(ctx, orgID, overrides)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Overrides[orgID] = overrides
+	return nil
+}
+
+func (m *MockQuotaRepository) CountRoomsCreatedBy(ctx context.Context, userID string) (int, error) {
+	if m.CountRoomsCreatedByFunc != nil {
+		return m.CountRoomsCreatedByFunc(ctx, userID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.RoomCounts[userID], nil
+}
+
+func (m *MockQuotaRepository) CountMessagesSince(ctx context.Context, chatroomID string, since time.Time) (int, error) {
+	if m.CountMessagesSinceFunc != nil {
+		return m.CountMessagesSinceFunc(ctx, chatroomID, since)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.MessageCounts[chatroomID], nil
+}
+
+func (m *MockQuotaRepository) LockChatroomMessages(ctx context.Context, chatroomID string) error {
+	if m.LockChatroomMessagesFunc != nil {
+		return m.LockChatroomMessagesFunc(ctx, chatroomID)
+	}
+	return nil
+}
+
+// MockBotStatsRepository implements domain.BotStatsRepository for testing
+type MockBotStatsRepository struct {
+	mu sync.Mutex
+
+	// Function overrides
+	RecordFunc       func(ctx context.Context, usage *domain.BotCommandUsage) error
+	StatsFunc        func(ctx context.Context, since time.Time) ([]domain.BotCommandStats, error)
+	DeleteBeforeFunc func(ctx context.Context, before time.Time) (int64, error)
+
+	// Recorded usage, in order
+	Usage []domain.BotCommandUsage
+}
+
+// NewMockBotStatsRepository creates a new MockBotStatsRepository
+func NewMockBotStatsRepository() *MockBotStatsRepository {
+	return &MockBotStatsRepository{}
+}
+
+func (m *MockBotStatsRepository) Record(ctx context.Context, usage *domain.BotCommandUsage) error {
+	if m.RecordFunc != nil {
+		return m.RecordFunc(ctx, usage)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Usage = append(m.Usage, *usage)
+	return nil
+}
+
+// Stats counts the recorded usage per command type and symbol, ignoring
+// since and latencies
+func (m *MockBotStatsRepository) Stats(ctx context.Context, since time.Time) ([]domain.BotCommandStats, error) {
+	if m.StatsFunc != nil {
+		return m.StatsFunc(ctx, since)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make([]domain.BotCommandStats, 0)
+	index := make(map[[2]string]int)
+	for _, usage := range m.Usage {
+		key := [2]string{usage.CommandType, usage.Symbol}
+		i, ok := index[key]
+		if !ok {
+			i = len(stats)
+			index[key] = i
+			stats = append(stats, domain.BotCommandStats{CommandType: usage.CommandType, Symbol: usage.Symbol})
 		}
-		if foundBefore || before == "" {
-			result = append([]*domain.Message{msg}, result...)
-			if len(result) >= limit {
-				break
-			}
+		stats[i].Count++
+		if !usage.Success {
+			stats[i].Errors++
 		}
 	}
-	return result, nil
+	return stats, nil
 }
 
-// MockMessagePublisher implements websocket.MessagePublisher for testing
-type MockMessagePublisher struct {
+// DeleteBefore deletes nothing, as the recorded usage has no timestamps
+func (m *MockBotStatsRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	if m.DeleteBeforeFunc != nil {
+		return m.DeleteBeforeFunc(ctx, before)
+	}
+	return 0, nil
+}
+
+// MockChatroomRepository implements domain.ChatroomRepository for testing
+type MockChatroomRepository struct {
 	mu sync.RWMutex
 
 	// Function overrides
-	PublishStockCommandFunc func(ctx context.Context, chatroomID, stockCode, requestedBy string) error
-	PublishHelloCommandFunc func(ctx context.Context, chatroomID, requestedBy string) error
+	CreateFunc           func(ctx context.Context, chatroom *domain.Chatroom) error
+	CreateWithMemberFunc func(ctx context.Context, chatroom *domain.Chatroom, userID string) error
+	GetByIDFunc          func(ctx context.Context, id string) (*domain.Chatroom, error)
+	ListFunc             func(ctx context.Context) ([]*domain.Chatroom, error)
+	ListPaginatedFunc    func(ctx context.Context, opts domain.ChatroomListOptions) ([]*domain.Chatroom, string, error)
+	AddMemberFunc        func(ctx context.Context, chatroomID, userID string) error
+	AddMembersFunc       func(ctx context.Context, chatroomID string, identifiers []string) ([]domain.MemberAddResult, error)
+	IsMemberFunc         func(ctx context.Context, chatroomID, userID string) (bool, error)
+	GetSettingsFunc      func(ctx context.Context, chatroomID string) (*domain.ChatroomSettings, error)
+	UpdateSettingsFunc   func(ctx context.Context, chatroomID string, settings *domain.ChatroomSettings) error
+	ClaimWelcomeFunc     func(ctx context.Context, chatroomID, userID string) (string, error)
+	ActivityFunc         func(ctx context.Context, userID string) ([]*domain.RoomActivity, error)
+	MarkReadFunc         func(ctx context.Context, chatroomID, userID, messageID string) error
+	DeleteFunc           func(ctx context.Context, id string) error
+	RestoreFunc          func(ctx context.Context, id string) error
+	PurgeDeletedFunc     func(ctx context.Context, cutoff time.Time) (int64, error)
+	SetMemberKeyFunc     func(ctx context.Context, chatroomID, userID, keyData string) error
+	MemberKeysFunc       func(ctx context.Context, chatroomID string) ([]*domain.MemberKey, error)
+	ListMembersFunc      func(ctx context.Context, chatroomID string) ([]*domain.Member, error)
+	SetTagsFunc          func(ctx context.Context, chatroomID string, tags []string) error
+	RemoveTagFunc        func(ctx context.Context, chatroomID, tag string) error
+	ListTagsFunc         func(ctx context.Context) ([]*domain.TagCount, error)
+	StarFunc             func(ctx context.Context, chatroomID, userID string, position *int) (int, error)
+	UnstarFunc           func(ctx context.Context, chatroomID, userID string) error
 
-	// Call tracking
-	StockCommands []StockCommandCall
-	HelloCommands []HelloCommandCall
+	// In-memory storage
+	Chatrooms map[string]*domain.Chatroom
+	Members   map[string]map[string]bool // chatroomID -> userID -> isMember
+	Settings  map[string]*domain.ChatroomSettings
+	Welcomed  map[string]map[string]bool   // chatroomID -> userID -> welcomed
+	ReadUpTo  map[string]map[string]string // chatroomID -> userID -> last read message ID
+	// Deleted holds soft-deleted chatrooms, moved out of Chatrooms
+	Deleted   map[string]*domain.Chatroom
+	DeletedAt map[string]time.Time
+	Keys      map[string]map[string]*domain.MemberKey // chatroomID -> userID -> key
+	Stars     map[string]map[string]int               // userID -> chatroomID -> position
 }
 
-// StockCommandCall records a call to PublishStockCommand
-type StockCommandCall struct {
-	ChatroomID  string
-	StockCode   string
-	RequestedBy string
+// NewMockChatroomRepository creates a new MockChatroomRepository with initialized maps
+func NewMockChatroomRepository() *MockChatroomRepository {
+	return &MockChatroomRepository{
+		Chatrooms: make(map[string]*domain.Chatroom),
+		Members:   make(map[string]map[string]bool),
+		Settings:  make(map[string]*domain.ChatroomSettings),
+		Welcomed:  make(map[string]map[string]bool),
+		ReadUpTo:  make(map[string]map[string]string),
+		Deleted:   make(map[string]*domain.Chatroom),
+		DeletedAt: make(map[string]time.Time),
+		Keys:      make(map[string]map[string]*domain.MemberKey),
+		Stars:     make(map[string]map[string]int),
+	}
 }
 
-// HelloCommandCall records a call to PublishHelloCommand
-type HelloCommandCall struct {
-	ChatroomID  string
-	RequestedBy string
+func (m *MockChatroomRepository) Create(ctx context.Context, chatroom *domain.Chatroom) error {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, chatroom)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if chatroom.ID == "" {
+		chatroom.ID = "chatroom-" + chatroom.Name
+	}
+	if chatroom.CreatedAt.IsZero() {
+		chatroom.CreatedAt = time.Now()
+	}
+	m.Chatrooms[chatroom.ID] = chatroom
+	return nil
 }
 
-// NewMockMessagePublisher creates a new MockMessagePublisher
-func NewMockMessagePublisher() *MockMessagePublisher {
-	return &MockMessagePublisher{
-		StockCommands: make([]StockCommandCall, 0),
-		HelloCommands: make([]HelloCommandCall, 0),
+func (m *MockChatroomRepository) CreateWithMember(ctx context.Context, chatroom *domain.Chatroom, userID string) error {
+	if m.CreateWithMemberFunc != nil {
+		return m.CreateWithMemberFunc(ctx, chatroom, userID)
+	}
+	if err := m.Create(ctx, chatroom); err != nil {
+		return err
 	}
+	return m.AddMember(ctx, chatroom.ID, userID)
 }
 
-func (m *MockMessagePublisher) PublishStockCommand(ctx context.Context, chatroomID, stockCode, requestedBy string) error {
-	if m.PublishStockCommandFunc != nil {
-		return m.PublishStockCommandFunc(ctx, chatroomID, stockCode, requestedBy)
+func (m *MockChatroomRepository) GetByID(ctx context.Context, id string) (*domain.Chatroom, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(ctx, id)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if chatroom, ok := m.Chatrooms[id]; ok {
+		return chatroom, nil
+	}
+	return nil, domain.ErrChatroomNotFound
+}
+
+func (m *MockChatroomRepository) List(ctx context.Context) ([]*domain.Chatroom, error) {
+	if m.ListFunc != nil {
+		return m.ListFunc(ctx)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*domain.Chatroom, 0, len(m.Chatrooms))
+	for _, chatroom := range m.Chatrooms {
+		result = append(result, chatroom)
+	}
+	return result, nil
+}
+
+func (m *MockChatroomRepository) ListPaginated(ctx context.Context, opts domain.ChatroomListOptions) ([]*domain.Chatroom, string, error) {
+	if m.ListPaginatedFunc != nil {
+		return m.ListPaginatedFunc(ctx, opts)
+	}
+	limit := opts.Limit
+	all, err := m.List(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	m.mu.RLock()
+	stars := maps.Clone(m.Stars[opts.UserID])
+	m.mu.RUnlock()
+
+	chatrooms := make([]*domain.Chatroom, 0, len(all))
+	for _, chatroom := range all {
+		if !hasAllTags(chatroom, opts.Tags) {
+			continue
+		}
+		position, starred := stars[chatroom.ID]
+		if opts.Starred && !starred {
+			continue
+		}
+		if starred {
+			room := *chatroom
+			room.Starred, room.StarPosition = true, &position
+			chatroom = &room
+		}
+		chatrooms = append(chatrooms, chatroom)
+	}
+
+	// Simple pagination: return up to limit items
+	if len(chatrooms) > limit {
+		return chatrooms[:limit], chatrooms[limit-1].ID, nil
+	}
+	return chatrooms, "", nil
+}
+
+func (m *MockChatroomRepository) AddMember(ctx context.Context, chatroomID, userID string) error {
+	if m.AddMemberFunc != nil {
+		return m.AddMemberFunc(ctx, chatroomID, userID)
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.StockCommands = append(m.StockCommands, StockCommandCall{
-		ChatroomID:  chatroomID,
-		StockCode:   stockCode,
-		RequestedBy: requestedBy,
-	})
+	if m.Members == nil {
+		m.Members = make(map[string]map[string]bool)
+	}
+	if m.Members[chatroomID] == nil {
+		m.Members[chatroomID] = make(map[string]bool)
+	}
+	m.Members[chatroomID][userID] = true
 	return nil
 }
 
-func (m *MockMessagePublisher) PublishHelloCommand(ctx context.Context, chatroomID, requestedBy string) error {
-	if m.PublishHelloCommandFunc != nil {
-		return m.PublishHelloCommandFunc(ctx, chatroomID, requestedBy)
+func (m *MockChatroomRepository) AddMembers(ctx context.Context, chatroomID string, identifiers []string) ([]domain.MemberAddResult, error) {
+	if m.AddMembersFunc != nil {
+		return m.AddMembersFunc(ctx, chatroomID, identifiers)
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.HelloCommands = append(m.HelloCommands, HelloCommandCall{
-		ChatroomID:  chatroomID,
-		RequestedBy: requestedBy,
-	})
-	return nil
+	if m.Members == nil {
+		m.Members = make(map[string]map[string]bool)
+	}
+	if m.Members[chatroomID] == nil {
+		m.Members[chatroomID] = make(map[string]bool)
+	}
+
+	// Identifiers are taken as user IDs
+	results := make([]domain.MemberAddResult, 0, len(identifiers))
+	for _, userID := range identifiers {
+		status := domain.MemberAdded
+		if m.Members[chatroomID][userID] {
+			status = domain.MemberAlreadyMember
+		}
+		m.Members[chatroomID][userID] = true
+		results = append(results, domain.MemberAddResult{Identifier: userID, UserID: userID, Status: status})
+	}
+	return results, nil
 }
 
-// GetStockCommandCalls returns all recorded stock command calls
-func (m *MockMessagePublisher) GetStockCommandCalls() []StockCommandCall {
+func (m *MockChatroomRepository) IsMember(ctx context.Context, chatroomID, userID string) (bool, error) {
+	if m.IsMemberFunc != nil {
+		return m.IsMemberFunc(ctx, chatroomID, userID)
+	}
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return append([]StockCommandCall{}, m.StockCommands...)
+
+	if members, ok := m.Members[chatroomID]; ok {
+		return members[userID], nil
+	}
+	return false, nil
 }
 
-// GetHelloCommandCalls returns all recorded hello command calls
-func (m *MockMessagePublisher) GetHelloCommandCalls() []HelloCommandCall {
+func (m *MockChatroomRepository) GetSettings(ctx context.Context, chatroomID string) (*domain.ChatroomSettings, error) {
+	if m.GetSettingsFunc != nil {
+		return m.GetSettingsFunc(ctx, chatroomID)
+	}
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return append([]HelloCommandCall{}, m.HelloCommands...)
+
+	if _, ok := m.Chatrooms[chatroomID]; !ok {
+		return nil, domain.ErrChatroomNotFound
+	}
+	if settings, ok := m.Settings[chatroomID]; ok {
+		copied := *settings
+		return &copied, nil
+	}
+	return &domain.ChatroomSettings{Version: 1}, nil
 }
 
-// Reset clears all recorded calls
-func (m *MockMessagePublisher) Reset() {
+func (m *MockChatroomRepository) UpdateSettings(ctx context.Context, chatroomID string, settings *domain.ChatroomSettings) error {
+	if m.UpdateSettingsFunc != nil {
+		return m.UpdateSettingsFunc(ctx, chatroomID, settings)
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.StockCommands = make([]StockCommandCall, 0)
-	m.HelloCommands = make([]HelloCommandCall, 0)
+
+	if _, ok := m.Chatrooms[chatroomID]; !ok {
+		return domain.ErrChatroomNotFound
+	}
+	if m.Settings == nil {
+		m.Settings = make(map[string]*domain.ChatroomSettings)
+	}
+	current := 1
+	if stored, ok := m.Settings[chatroomID]; ok {
+		current = stored.Version
+	}
+	if settings.Version != 0 && settings.Version != current {
+		return domain.ErrVersionConflict
+	}
+	settings.Version = current + 1
+	copied := *settings
+	m.Settings[chatroomID] = &copied
+	return nil
+}
+
+func (m *MockChatroomRepository) ClaimWelcome(ctx context.Context, chatroomID, userID string) (string, error) {
+	if m.ClaimWelcomeFunc != nil {
+		return m.ClaimWelcomeFunc(ctx, chatroomID, userID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	settings, ok := m.Settings[chatroomID]
+	if !ok || settings.WelcomeMessage == "" || !m.Members[chatroomID][userID] || m.Welcomed[chatroomID][userID] {
+		return "", nil
+	}
+	if m.Welcomed == nil {
+		m.Welcomed = make(map[string]map[string]bool)
+	}
+	if m.Welcomed[chatroomID] == nil {
+		m.Welcomed[chatroomID] = make(map[string]bool)
+	}
+	m.Welcomed[chatroomID][userID] = true
+	return settings.WelcomeMessage, nil
+}
+
+func (m *MockChatroomRepository) Activity(ctx context.Context, userID string) ([]*domain.RoomActivity, error) {
+	if m.ActivityFunc != nil {
+		return m.ActivityFunc(ctx, userID)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var activity []*domain.RoomActivity
+	for chatroomID, members := range m.Members {
+		if chatroom, ok := m.Chatrooms[chatroomID]; ok && members[userID] {
+			activity = append(activity, &domain.RoomActivity{ChatroomID: chatroomID, ChatroomName: chatroom.Name})
+		}
+	}
+	return activity, nil
+}
+
+func (m *MockChatroomRepository) MarkRead(ctx context.Context, chatroomID, userID, messageID string) error {
+	if m.MarkReadFunc != nil {
+		return m.MarkReadFunc(ctx, chatroomID, userID, messageID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.Members[chatroomID][userID] {
+		return domain.ErrNotMember
+	}
+	if m.ReadUpTo == nil {
+		m.ReadUpTo = make(map[string]map[string]string)
+	}
+	if m.ReadUpTo[chatroomID] == nil {
+		m.ReadUpTo[chatroomID] = make(map[string]string)
+	}
+	m.ReadUpTo[chatroomID][userID] = messageID
+	return nil
+}
+
+func (m *MockChatroomRepository) Delete(ctx context.Context, id string) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(ctx, id)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	chatroom, ok := m.Chatrooms[id]
+	if !ok {
+		return domain.ErrChatroomNotFound
+	}
+	if m.Deleted == nil {
+		m.Deleted = make(map[string]*domain.Chatroom)
+	}
+	if m.DeletedAt == nil {
+		m.DeletedAt = make(map[string]time.Time)
+	}
+	delete(m.Chatrooms, id)
+	m.Deleted[id] = chatroom
+	m.DeletedAt[id] = time.Now()
+	return nil
+}
+
+func (m *MockChatroomRepository) Restore(ctx context.Context, id string) error {
+	if m.RestoreFunc != nil {
+		return m.RestoreFunc(ctx, id)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	chatroom, ok := m.Deleted[id]
+	if !ok {
+		return domain.ErrChatroomNotFound
+	}
+	delete(m.Deleted, id)
+	delete(m.DeletedAt, id)
+	m.Chatrooms[id] = chatroom
+	return nil
+}
+
+func (m *MockChatroomRepository) PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error) {
+	if m.PurgeDeletedFunc != nil {
+		return m.PurgeDeletedFunc(ctx, cutoff)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purged int64
+	for id, deletedAt := range m.DeletedAt {
+		if deletedAt.Before(cutoff) {
+			delete(m.Deleted, id)
+			delete(m.DeletedAt, id)
+			delete(m.Members, id)
+			delete(m.Settings, id)
+			delete(m.Keys, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (m *MockChatroomRepository) SetMemberKey(ctx context.Context, chatroomID, userID, keyData string) error {
+	if m.SetMemberKeyFunc != nil {
+		return m.SetMemberKeyFunc(ctx, chatroomID, userID, keyData)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	chatroom, ok := m.Chatrooms[chatroomID]
+	if !ok || !m.Members[chatroomID][userID] {
+		return domain.ErrNotMember
+	}
+	if !chatroom.Encrypted {
+		return domain.ErrNotEncrypted
+	}
+	if m.Keys[chatroomID] == nil {
+		m.Keys[chatroomID] = make(map[string]*domain.MemberKey)
+	}
+	m.Keys[chatroomID][userID] = &domain.MemberKey{UserID: userID, KeyData: keyData, UpdatedAt: time.Now()}
+	return nil
+}
+
+func (m *MockChatroomRepository) MemberKeys(ctx context.Context, chatroomID string) ([]*domain.MemberKey, error) {
+	if m.MemberKeysFunc != nil {
+		return m.MemberKeysFunc(ctx, chatroomID)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]*domain.MemberKey, 0, len(m.Keys[chatroomID]))
+	for userID, key := range m.Keys[chatroomID] {
+		if m.Members[chatroomID][userID] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].UserID < keys[j].UserID })
+	return keys, nil
+}
+
+// ListMembers returns the members in Members, which are all active and
+// named by their IDs, unless ListMembersFunc is set
+func (m *MockChatroomRepository) ListMembers(ctx context.Context, chatroomID string) ([]*domain.Member, error) {
+	if m.ListMembersFunc != nil {
+		return m.ListMembersFunc(ctx, chatroomID)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	members := make([]*domain.Member, 0, len(m.Members[chatroomID]))
+	for userID, isMember := range m.Members[chatroomID] {
+		if isMember {
+			members = append(members, &domain.Member{
+				UserID:           userID,
+				Username:         userID,
+				Status:           domain.UserStatus{Status: domain.StatusActive},
+				ShowOnlineStatus: true,
+			})
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Username < members[j].Username })
+	return members, nil
+}
+
+func hasAllTags(chatroom *domain.Chatroom, tags []string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(chatroom.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetTags stores the tags on the chatroom in Chatrooms, unless SetTagsFunc is
+// set
+func (m *MockChatroomRepository) SetTags(ctx context.Context, chatroomID string, tags []string) error {
+	if m.SetTagsFunc != nil {
+		return m.SetTagsFunc(ctx, chatroomID, tags)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	chatroom, ok := m.Chatrooms[chatroomID]
+	if !ok {
+		return domain.ErrChatroomNotFound
+	}
+	chatroom.Tags = slices.Clone(tags)
+	return nil
+}
+
+func (m *MockChatroomRepository) RemoveTag(ctx context.Context, chatroomID, tag string) error {
+	if m.RemoveTagFunc != nil {
+		return m.RemoveTagFunc(ctx, chatroomID, tag)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if chatroom, ok := m.Chatrooms[chatroomID]; ok {
+		chatroom.Tags = slices.DeleteFunc(chatroom.Tags, func(t string) bool { return t == tag })
+	}
+	return nil
+}
+
+// ListTags counts the tags of the chatrooms in Chatrooms, unless ListTagsFunc
+// is set
+func (m *MockChatroomRepository) ListTags(ctx context.Context) ([]*domain.TagCount, error) {
+	if m.ListTagsFunc != nil {
+		return m.ListTagsFunc(ctx)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, chatroom := range m.Chatrooms {
+		for _, tag := range chatroom.Tags {
+			counts[tag]++
+		}
+	}
+	tags := make([]*domain.TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, &domain.TagCount{Tag: tag, Chatrooms: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Chatrooms != tags[j].Chatrooms {
+			return tags[i].Chatrooms > tags[j].Chatrooms
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags, nil
+}
+
+// Star records the star in Stars, unless StarFunc is set
+func (m *MockChatroomRepository) Star(ctx context.Context, chatroomID, userID string, position *int) (int, error) {
+	if m.StarFunc != nil {
+		return m.StarFunc(ctx, chatroomID, userID, position)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.Chatrooms[chatroomID]; !ok {
+		return 0, domain.ErrChatroomNotFound
+	}
+	if m.Stars == nil {
+		m.Stars = make(map[string]map[string]int)
+	}
+	if m.Stars[userID] == nil {
+		m.Stars[userID] = make(map[string]int)
+	}
+	current, starred := m.Stars[userID][chatroomID]
+	switch {
+	case position != nil:
+		current = *position
+	case !starred:
+		current = 0
+		for _, p := range m.Stars[userID] {
+			current = max(current, p+1)
+		}
+	}
+	m.Stars[userID][chatroomID] = current
+	return current, nil
+}
+
+func (m *MockChatroomRepository) Unstar(ctx context.Context, chatroomID, userID string) error {
+	if m.UnstarFunc != nil {
+		return m.UnstarFunc(ctx, chatroomID, userID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.Stars[userID], chatroomID)
+	return nil
+}
+
+// MockMessageRepository implements domain.MessageRepository for testing
+type MockMessageRepository struct {
+	mu sync.RWMutex
+
+	// Function overrides
+	CreateFunc              func(ctx context.Context, message *domain.Message) error
+	GetByIDFunc             func(ctx context.Context, id string) (*domain.Message, error)
+	GetByChatroomFunc       func(ctx context.Context, chatroomID string, limit int) ([]*domain.Message, error)
+	GetByChatroomBeforeFunc func(ctx context.Context, chatroomID string, before string, limit int) ([]*domain.Message, error)
+	GetByChatroomSinceFunc  func(ctx context.Context, chatroomID string, sinceID string, limit int) ([]*domain.Message, error)
+	RestoreFunc             func(ctx context.Context, id string) error
+	PurgeDeletedFunc        func(ctx context.Context, cutoff time.Time) (int64, error)
+
+	// In-memory storage
+	Messages []*domain.Message
+	// DeletedAt marks soft-deleted messages of Messages
+	DeletedAt map[string]time.Time
+}
+
+// NewMockMessageRepository creates a new MockMessageRepository with initialized slices
+func NewMockMessageRepository() *MockMessageRepository {
+	return &MockMessageRepository{
+		Messages:  make([]*domain.Message, 0),
+		DeletedAt: make(map[string]time.Time),
+	}
+}
+
+func (m *MockMessageRepository) Create(ctx context.Context, message *domain.Message) error {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, message)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if message.ClientMsgID != "" {
+		for _, stored := range m.Messages {
+			if stored.ChatroomID == message.ChatroomID && stored.UserID == message.UserID &&
+				stored.ClientMsgID == message.ClientMsgID {
+				message.ID, message.CreatedAt, message.Seq = stored.ID, stored.CreatedAt, stored.Seq
+				return domain.ErrDuplicateMessage
+			}
+		}
+	}
+	if message.ID == "" {
+		message.ID = "msg-" + time.Now().Format("20060102150405.000")
+	}
+	if message.CreatedAt.IsZero() {
+		message.CreatedAt = time.Now()
+	}
+	m.Messages = append(m.Messages, message)
+	return nil
+}
+
+func (m *MockMessageRepository) GetByID(ctx context.Context, id string) (*domain.Message, error) {
+	if m.GetByIDFunc != nil {
+		return m.GetByIDFunc(ctx, id)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, msg := range m.Messages {
+		if msg.ID == id {
+			return msg, nil
+		}
+	}
+	return nil, domain.ErrMessageNotFound
+}
+
+func (m *MockMessageRepository) GetByChatroom(ctx context.Context, chatroomID string, limit int) ([]*domain.Message, error) {
+	if m.GetByChatroomFunc != nil {
+		return m.GetByChatroomFunc(ctx, chatroomID, limit)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*domain.Message, 0)
+	for _, msg := range m.Messages {
+		if msg.ChatroomID == chatroomID {
+			result = append(result, msg)
+		}
+	}
+
+	// Return last 'limit' messages (most recent)
+	if len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+	return result, nil
+}
+
+func (m *MockMessageRepository) GetByChatroomBefore(ctx context.Context, chatroomID string, before string, limit int) ([]*domain.Message, error) {
+	if m.GetByChatroomBeforeFunc != nil {
+		return m.GetByChatroomBeforeFunc(ctx, chatroomID, before, limit)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*domain.Message, 0)
+	foundBefore := false
+
+	for i := len(m.Messages) - 1; i >= 0; i-- {
+		msg := m.Messages[i]
+		if msg.ChatroomID != chatroomID {
+			continue
+		}
+		if msg.ID == before {
+			foundBefore = true
+			continue
+		}
+		if foundBefore || before == "" {
+			result = append([]*domain.Message{msg}, result...)
+			if len(result) >= limit {
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func (m *MockMessageRepository) GetByChatroomSince(ctx context.Context, chatroomID string, sinceID string, limit int) ([]*domain.Message, error) {
+	if m.GetByChatroomSinceFunc != nil {
+		return m.GetByChatroomSinceFunc(ctx, chatroomID, sinceID, limit)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*domain.Message, 0)
+	found := false
+	for _, msg := range m.Messages {
+		if msg.ChatroomID != chatroomID {
+			continue
+		}
+		if found {
+			result = append(result, msg)
+			if len(result) >= limit {
+				break
+			}
+		}
+		if msg.ID == sinceID {
+			found = true
+		}
+	}
+	return result, nil
+}
+
+func (m *MockMessageRepository) Restore(ctx context.Context, id string) error {
+	if m.RestoreFunc != nil {
+		return m.RestoreFunc(ctx, id)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.DeletedAt[id]; !ok {
+		return domain.ErrMessageNotFound
+	}
+	delete(m.DeletedAt, id)
+	return nil
+}
+
+func (m *MockMessageRepository) PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error) {
+	if m.PurgeDeletedFunc != nil {
+		return m.PurgeDeletedFunc(ctx, cutoff)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purged int64
+	kept := m.Messages[:0]
+	for _, msg := range m.Messages {
+		if deletedAt, ok := m.DeletedAt[msg.ID]; ok && deletedAt.Before(cutoff) {
+			delete(m.DeletedAt, msg.ID)
+			purged++
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	m.Messages = kept
+	return purged, nil
+}
+
+// MockRSVPRepository implements domain.RSVPRepository for testing
+type MockRSVPRepository struct {
+	mu sync.Mutex
+
+	// Function overrides
+	SetFunc  func(ctx context.Context, rsvp *domain.RSVP) error
+	ListFunc func(ctx context.Context, messageID string) ([]*domain.RSVP, error)
+
+	// RSVPs are the stored answers, oldest first
+	RSVPs []*domain.RSVP
+}
+
+// NewMockRSVPRepository creates a new MockRSVPRepository
+func NewMockRSVPRepository() *MockRSVPRepository {
+	return &MockRSVPRepository{}
+}
+
+func (m *MockRSVPRepository) Set(ctx context.Context, rsvp *domain.RSVP) error {
+	if m.SetFunc != nil {
+		return m.SetFunc(ctx, rsvp)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rsvp.UpdatedAt = time.Now()
+	m.RSVPs = slices.DeleteFunc(m.RSVPs, func(r *domain.RSVP) bool {
+		return r.MessageID == rsvp.MessageID && r.UserID == rsvp.UserID
+	})
+	copied := *rsvp
+	m.RSVPs = append(m.RSVPs, &copied)
+	return nil
+}
+
+func (m *MockRSVPRepository) List(ctx context.Context, messageID string) ([]*domain.RSVP, error) {
+	if m.ListFunc != nil {
+		return m.ListFunc(ctx, messageID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rsvps := []*domain.RSVP{}
+	for _, rsvp := range m.RSVPs {
+		if rsvp.MessageID == messageID {
+			copied := *rsvp
+			rsvps = append(rsvps, &copied)
+		}
+	}
+	return rsvps, nil
+}
+
+// MockMessagePublisher implements websocket.MessagePublisher for testing
+type MockMessagePublisher struct {
+	mu sync.RWMutex
+
+	// Function overrides
+	PublishStockCommandFunc func(ctx context.Context, chatroomID, messageID, stockCode, requestedBy string) error
+	PublishHelloCommandFunc func(ctx context.Context, chatroomID, messageID, requestedBy string) error
+	PublishGiphyCommandFunc func(ctx context.Context, chatroomID, messageID, query, requestedBy string) error
+
+	// Call tracking
+	StockCommands []StockCommandCall
+	HelloCommands []HelloCommandCall
+	GiphyCommands []GiphyCommandCall
+}
+
+// StockCommandCall records a call to PublishStockCommand
+type StockCommandCall struct {
+	ChatroomID  string
+	MessageID   string
+	StockCode   string
+	RequestedBy string
+	// RequestedByID is the user ID carried by the context
+	RequestedByID string
+}
+
+// HelloCommandCall records a call to PublishHelloCommand
+type HelloCommandCall struct {
+	ChatroomID  string
+	MessageID   string
+	RequestedBy string
+}
+
+// GiphyCommandCall records a call to PublishGiphyCommand
+type GiphyCommandCall struct {
+	ChatroomID  string
+	MessageID   string
+	Query       string
+	RequestedBy string
+}
+
+// NewMockMessagePublisher creates a new MockMessagePublisher
+func NewMockMessagePublisher() *MockMessagePublisher {
+	return &MockMessagePublisher{
+		StockCommands: make([]StockCommandCall, 0),
+		HelloCommands: make([]HelloCommandCall, 0),
+		GiphyCommands: make([]GiphyCommandCall, 0),
+	}
+}
+
+func (m *MockMessagePublisher) PublishStockCommand(ctx context.Context, chatroomID, messageID, stockCode, requestedBy string) error {
+	if m.PublishStockCommandFunc != nil {
+		return m.PublishStockCommandFunc(ctx, chatroomID, messageID, stockCode, requestedBy)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.StockCommands = append(m.StockCommands, StockCommandCall{
+		ChatroomID:    chatroomID,
+		MessageID:     messageID,
+		StockCode:     stockCode,
+		RequestedBy:   requestedBy,
+		RequestedByID: observability.UserID(ctx),
+	})
+	return nil
+}
+
+func (m *MockMessagePublisher) PublishHelloCommand(ctx context.Context, chatroomID, messageID, requestedBy string) error {
+	if m.PublishHelloCommandFunc != nil {
+		return m.PublishHelloCommandFunc(ctx, chatroomID, messageID, requestedBy)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.HelloCommands = append(m.HelloCommands, HelloCommandCall{
+		ChatroomID:  chatroomID,
+		MessageID:   messageID,
+		RequestedBy: requestedBy,
+	})
+	return nil
+}
+
+func (m *MockMessagePublisher) PublishGiphyCommand(ctx context.Context, chatroomID, messageID, query, requestedBy string) error {
+	if m.PublishGiphyCommandFunc != nil {
+		return m.PublishGiphyCommandFunc(ctx, chatroomID, messageID, query, requestedBy)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.GiphyCommands = append(m.GiphyCommands, GiphyCommandCall{
+		ChatroomID:  chatroomID,
+		MessageID:   messageID,
+		Query:       query,
+		RequestedBy: requestedBy,
+	})
+	return nil
+}
+
+// GetStockCommandCalls returns all recorded stock command calls
+func (m *MockMessagePublisher) GetStockCommandCalls() []StockCommandCall {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]StockCommandCall{}, m.StockCommands...)
+}
+
+// GetHelloCommandCalls returns all recorded hello command calls
+func (m *MockMessagePublisher) GetHelloCommandCalls() []HelloCommandCall {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]HelloCommandCall{}, m.HelloCommands...)
+}
+
+// GetGiphyCommandCalls returns all recorded giphy command calls
+func (m *MockMessagePublisher) GetGiphyCommandCalls() []GiphyCommandCall {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]GiphyCommandCall{}, m.GiphyCommands...)
+}
+
+// Reset clears all recorded calls
+func (m *MockMessagePublisher) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.StockCommands = make([]StockCommandCall, 0)
+	m.HelloCommands = make([]HelloCommandCall, 0)
+}
+
+// MockModerationRepository implements domain.ModerationRepository for testing
+type MockModerationRepository struct {
+	mu sync.RWMutex
+
+	// Function overrides
+	FlagFunc    func(ctx context.Context, flag *domain.MessageFlag) (int, error)
+	HideFunc    func(ctx context.Context, messageID string) error
+	QueueFunc   func(ctx context.Context, limit int) ([]*domain.FlaggedMessage, error)
+	ResolveFunc func(ctx context.Context, messageID, moderatorID, action string) error
+
+	SetShadowBanFunc      func(ctx context.Context, chatroomID, userID, moderatorID string, banned bool) error
+	ShadowBannedUsersFunc func(ctx context.Context, chatroomID string) ([]string, error)
+
+	// In-memory storage: open flags by message ID, hidden message IDs, the
+	// action each resolved message was resolved with and shadow-banned
+	// users by chatroom ID
+	Flags       map[string][]domain.MessageFlag
+	Hidden      map[string]bool
+	Resolutions map[string]string
+	ShadowBans  map[string]map[string]bool
+}
+
+// NewMockModerationRepository creates a new MockModerationRepository with initialized maps
+func NewMockModerationRepository() *MockModerationRepository {
+	return &MockModerationRepository{
+		Flags:       make(map[string][]domain.MessageFlag),
+		Hidden:      make(map[string]bool),
+		Resolutions: make(map[string]string),
+		ShadowBans:  make(map[string]map[string]bool),
+	}
+}
+
+func (m *MockModerationRepository) Flag(ctx context.Context, flag *domain.MessageFlag) (int, error) {
+	if m.FlagFunc != nil {
+		return m.FlagFunc(ctx, flag)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.Flags[flag.MessageID] {
+		if existing.ReporterID == flag.ReporterID {
+			return 0, domain.ErrAlreadyFlagged
+		}
+	}
+	m.Flags[flag.MessageID] = append(m.Flags[flag.MessageID], *flag)
+	return len(m.Flags[flag.MessageID]), nil
+}
+
+func (m *MockModerationRepository) Hide(ctx context.Context, messageID string) error {
+	if m.HideFunc != nil {
+		return m.HideFunc(ctx, messageID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Hidden[messageID] = true
+	return nil
+}
+
+func (m *MockModerationRepository) Queue(ctx context.Context, limit int) ([]*domain.FlaggedMessage, error) {
+	if m.QueueFunc != nil {
+		return m.QueueFunc(ctx, limit)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	queue := make([]*domain.FlaggedMessage, 0, len(m.Flags))
+	for messageID, flags := range m.Flags {
+		if len(queue) == limit {
+			break
+		}
+		queue = append(queue, &domain.FlaggedMessage{
+			Message:   &domain.Message{ID: messageID},
+			FlagCount: len(flags),
+			Hidden:    m.Hidden[messageID],
+		})
+	}
+	return queue, nil
+}
+
+func (m *MockModerationRepository) Resolve(ctx context.Context, messageID, moderatorID, action string) error {
+	if m.ResolveFunc != nil {
+		return m.ResolveFunc(ctx, messageID, moderatorID, action)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.Flags[messageID]) == 0 {
+		return domain.ErrNoOpenFlags
+	}
+	delete(m.Flags, messageID)
+	delete(m.Hidden, messageID)
+	m.Resolutions[messageID] = action
+	return nil
+}
+
+func (m *MockModerationRepository) SetShadowBan(ctx context.Context, chatroomID, userID, moderatorID string, banned bool) error {
+	if m.SetShadowBanFunc != nil {
+		return m.SetShadowBanFunc(ctx, chatroomID, userID, moderatorID, banned)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !banned {
+		delete(m.ShadowBans[chatroomID], userID)
+		return nil
+	}
+	if m.ShadowBans[chatroomID] == nil {
+		m.ShadowBans[chatroomID] = make(map[string]bool)
+	}
+	m.ShadowBans[chatroomID][userID] = true
+	return nil
+}
+
+func (m *MockModerationRepository) ShadowBannedUsers(ctx context.Context, chatroomID string) ([]string, error) {
+	if m.ShadowBannedUsersFunc != nil {
+		return m.ShadowBannedUsersFunc(ctx, chatroomID)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	userIDs := make([]string, 0, len(m.ShadowBans[chatroomID]))
+	for userID := range m.ShadowBans[chatroomID] {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Strings(userIDs)
+	return userIDs, nil
+}
+
+// MockRoomInviteRepository implements domain.RoomInviteRepository for testing
+type MockRoomInviteRepository struct {
+	mu sync.Mutex
+
+	// Function overrides
+	CreateFunc      func(ctx context.Context, invite *domain.RoomInvite) error
+	GetPendingFunc  func(ctx context.Context, tokenHash string) (*domain.RoomInvite, error)
+	ListPendingFunc func(ctx context.Context, chatroomID string) ([]*domain.RoomInvite, error)
+	RevokeFunc      func(ctx context.Context, chatroomID, inviteID string) error
+	AcceptFunc      func(ctx context.Context, tokenHash, userID string) (*domain.RoomInvite, error)
+
+	// In-memory storage
+	Invites  map[string]*domain.RoomInvite // token hash -> pending invite
+	Accepted map[string]string             // invite ID -> accepting user ID
+}
+
+// NewMockRoomInviteRepository creates a new MockRoomInviteRepository with initialized maps
+func NewMockRoomInviteRepository() *MockRoomInviteRepository {
+	return &MockRoomInviteRepository{
+		Invites:  make(map[string]*domain.RoomInvite),
+		Accepted: make(map[string]string),
+	}
+}
+
+func (m *MockRoomInviteRepository) Create(ctx context.Context, invite *domain.RoomInvite) error {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, invite)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if invite.ID == "" {
+		invite.ID = "invite-" + invite.Email
+	}
+	invite.CreatedAt = time.Now()
+	m.Invites[invite.TokenHash] = invite
+	return nil
+}
+
+func (m *MockRoomInviteRepository) GetPending(ctx context.Context, tokenHash string) (*domain.RoomInvite, error) {
+	if m.GetPendingFunc != nil {
+		return m.GetPendingFunc(ctx, tokenHash)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	invite, ok := m.Invites[tokenHash]
+	if !ok || !invite.ExpiresAt.After(time.Now()) {
+		return nil, domain.ErrInviteNotFound
+	}
+	return invite, nil
+}
+
+func (m *MockRoomInviteRepository) ListPending(ctx context.Context, chatroomID string) ([]*domain.RoomInvite, error) {
+	if m.ListPendingFunc != nil {
+		return m.ListPendingFunc(ctx, chatroomID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var invites []*domain.RoomInvite
+	for _, invite := range m.Invites {
+		if invite.ChatroomID == chatroomID && invite.ExpiresAt.After(time.Now()) {
+			invites = append(invites, invite)
+		}
+	}
+	sort.Slice(invites, func(i, j int) bool { return invites[i].CreatedAt.After(invites[j].CreatedAt) })
+	return invites, nil
+}
+
+func (m *MockRoomInviteRepository) Revoke(ctx context.Context, chatroomID, inviteID string) error {
+	if m.RevokeFunc != nil {
+		return m.RevokeFunc(ctx, chatroomID, inviteID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for tokenHash, invite := range m.Invites {
+		if invite.ID == inviteID && invite.ChatroomID == chatroomID {
+			delete(m.Invites, tokenHash)
+			return nil
+		}
+	}
+	return domain.ErrInviteNotFound
+}
+
+func (m *MockRoomInviteRepository) Accept(ctx context.Context, tokenHash, userID string) (*domain.RoomInvite, error) {
+	if m.AcceptFunc != nil {
+		return m.AcceptFunc(ctx, tokenHash, userID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	invite, ok := m.Invites[tokenHash]
+	if !ok || !invite.ExpiresAt.After(time.Now()) {
+		return nil, domain.ErrInviteNotFound
+	}
+	delete(m.Invites, tokenHash)
+	m.Accepted[invite.ID] = userID
+	return invite, nil
+}
+
+// MockDataExportRepository implements domain.DataExportRepository for testing
+type MockDataExportRepository struct {
+	mu sync.Mutex
+
+	// Function overrides
+	CreateFunc      func(ctx context.Context, export *domain.DataExport) error
+	LatestFunc      func(ctx context.Context, userID string) (*domain.DataExport, error)
+	CompleteFunc    func(ctx context.Context, id string, archive []byte, expiresAt time.Time) error
+	FailFunc        func(ctx context.Context, id, reason string) error
+	ArchiveFunc     func(ctx context.Context, userID, id string) ([]byte, error)
+	MembershipsFunc func(ctx context.Context, userID string) ([]*domain.ExportMembership, error)
+	MessagesFunc    func(ctx context.Context, userID string) ([]*domain.ExportMessage, error)
+
+	// In-memory storage
+	Exports        []*domain.DataExport // oldest first
+	Archives       map[string][]byte    // export ID -> archive
+	UserMembership map[string][]*domain.ExportMembership
+	UserMessages   map[string][]*domain.ExportMessage
+}
+
+// NewMockDataExportRepository creates a new MockDataExportRepository with initialized maps
+func NewMockDataExportRepository() *MockDataExportRepository {
+	return &MockDataExportRepository{
+		Archives:       make(map[string][]byte),
+		UserMembership: make(map[string][]*domain.ExportMembership),
+		UserMessages:   make(map[string][]*domain.ExportMessage),
+	}
+}
+
+func (m *MockDataExportRepository) Create(ctx context.Context, export *domain.DataExport) error {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, export)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	export.ID = "export-" + strconv.Itoa(len(m.Exports)+1)
+	export.Status = domain.ExportPending
+	export.CreatedAt = time.Now()
+	stored := *export
+	m.Exports = append(m.Exports, &stored)
+	return nil
+}
+
+func (m *MockDataExportRepository) Latest(ctx context.Context, userID string) (*domain.DataExport, error) {
+	if m.LatestFunc != nil {
+		return m.LatestFunc(ctx, userID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.Exports) - 1; i >= 0; i-- {
+		if m.Exports[i].UserID == userID {
+			export := *m.Exports[i]
+			return &export, nil
+		}
+	}
+	return nil, domain.ErrExportNotFound
+}
+
+func (m *MockDataExportRepository) Complete(ctx context.Context, id string, archive []byte, expiresAt time.Time) error {
+	if m.CompleteFunc != nil {
+		return m.CompleteFunc(ctx, id, archive, expiresAt)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	export := m.pending(id)
+	if export == nil {
+		return domain.ErrExportNotFound
+	}
+	now := time.Now()
+	export.Status, export.CompletedAt, export.ExpiresAt = domain.ExportReady, &now, &expiresAt
+	m.Archives[id] = archive
+	return nil
+}
+
+func (m *MockDataExportRepository) Fail(ctx context.Context, id, reason string) error {
+	if m.FailFunc != nil {
+		return m.FailFunc(ctx, id, reason)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	export := m.pending(id)
+	if export == nil {
+		return domain.ErrExportNotFound
+	}
+	now := time.Now()
+	export.Status, export.Error, export.CompletedAt = domain.ExportFailed, reason, &now
+	return nil
+}
+
+func (m *MockDataExportRepository) pending(id string) *domain.DataExport {
+	for _, export := range m.Exports {
+		if export.ID == id && export.Status == domain.ExportPending {
+			return export
+		}
+	}
+	return nil
+}
+
+func (m *MockDataExportRepository) Archive(ctx context.Context, userID, id string) ([]byte, error) {
+	if m.ArchiveFunc != nil {
+		return m.ArchiveFunc(ctx, userID, id)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, export := range m.Exports {
+		if export.ID == id && export.UserID == userID && export.Status == domain.ExportReady &&
+			export.ExpiresAt.After(time.Now()) {
+			return m.Archives[id], nil
+		}
+	}
+	return nil, domain.ErrExportNotFound
+}
+
+func (m *MockDataExportRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var kept []*domain.DataExport
+	for _, export := range m.Exports {
+		if export.ExpiresAt == nil || export.ExpiresAt.After(time.Now()) {
+			kept = append(kept, export)
+			continue
+		}
+		delete(m.Archives, export.ID)
+	}
+	deleted := int64(len(m.Exports) - len(kept))
+	m.Exports = kept
+	return deleted, nil
+}
+
+func (m *MockDataExportRepository) Memberships(ctx context.Context, userID string) ([]*domain.ExportMembership, error) {
+	if m.MembershipsFunc != nil {
+		return m.MembershipsFunc(ctx, userID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.UserMembership[userID], nil
+}
+
+func (m *MockDataExportRepository) Messages(ctx context.Context, userID string) ([]*domain.ExportMessage, error) {
+	if m.MessagesFunc != nil {
+		return m.MessagesFunc(ctx, userID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.UserMessages[userID], nil
+}
+
+// MockTxManager runs units of work without a real transaction, counting
+// them in Units
+type MockTxManager struct {
+	mu    sync.Mutex
+	Units int
+}
+
+func (m *MockTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.mu.Lock()
+	m.Units++
+	m.mu.Unlock()
+	return fn(ctx)
+}
+
+// MockSearchRepository implements domain.SearchRepository for testing. It
+// matches the query as a case-insensitive substring of the stored hits, in
+// their order, and ignores the caller's memberships.
+type MockSearchRepository struct {
+	// Function overrides
+	SearchMessagesFunc func(ctx context.Context, opts domain.SearchOptions) ([]*domain.MessageHit, error)
+	SearchRoomsFunc    func(ctx context.Context, opts domain.SearchOptions) ([]*domain.RoomHit, error)
+	SearchUsersFunc    func(ctx context.Context, opts domain.SearchOptions) ([]*domain.UserHit, error)
+
+	Messages []*domain.MessageHit
+	Rooms    []*domain.RoomHit
+	Users    []*domain.UserHit
+}
+
+// NewMockSearchRepository creates a new MockSearchRepository
+func NewMockSearchRepository() *MockSearchRepository {
+	return &MockSearchRepository{}
+}
+
+func (m *MockSearchRepository) SearchMessages(ctx context.Context, opts domain.SearchOptions) ([]*domain.MessageHit, error) {
+	if m.SearchMessagesFunc != nil {
+		return m.SearchMessagesFunc(ctx, opts)
+	}
+	return mockSearch(m.Messages, opts, func(h *domain.MessageHit) string { return h.Content }), nil
+}
+
+func (m *MockSearchRepository) SearchRooms(ctx context.Context, opts domain.SearchOptions) ([]*domain.RoomHit, error) {
+	if m.SearchRoomsFunc != nil {
+		return m.SearchRoomsFunc(ctx, opts)
+	}
+	return mockSearch(m.Rooms, opts, func(h *domain.RoomHit) string { return h.Name }), nil
+}
+
+func (m *MockSearchRepository) SearchUsers(ctx context.Context, opts domain.SearchOptions) ([]*domain.UserHit, error) {
+	if m.SearchUsersFunc != nil {
+		return m.SearchUsersFunc(ctx, opts)
+	}
+	return mockSearch(m.Users, opts, func(h *domain.UserHit) string { return h.Username }), nil
+}
+
+func mockSearch[T any](hits []T, opts domain.SearchOptions, text func(T) string) []T {
+	query := strings.ToLower(opts.Query)
+	matches := []T{}
+	for _, hit := range hits {
+		if strings.Contains(strings.ToLower(text(hit)), query) {
+			matches = append(matches, hit)
+		}
+	}
+	start := min(opts.Offset, len(matches))
+	end := min(start+opts.Limit, len(matches))
+	return matches[start:end]
+}
+
+// MockChatroomStatsRepository implements domain.ChatroomStatsRepository for
+// testing. It keeps the recorded presence samples and returns StatsResult
+// from Stats.
+type MockChatroomStatsRepository struct {
+	mu sync.Mutex
+
+	// Function overrides
+	RecordPresenceFunc  func(ctx context.Context, instanceID string, sampledAt time.Time, counts map[string]int) error
+	PresenceHistoryFunc func(ctx context.Context, chatroomID string, since time.Time, bucket time.Duration) ([]*domain.RoomPresenceHistory, error)
+	RefreshFunc         func(ctx context.Context) error
+	StatsFunc           func(ctx context.Context, chatroomID string, since time.Time) (*domain.ChatroomStats, error)
+
+	// Presence holds the recorded counts per instance, latest sample last
+	Presence    map[string][]map[string]int
+	Refreshes   int
+	StatsResult *domain.ChatroomStats
+}
+
+// NewMockChatroomStatsRepository creates a new MockChatroomStatsRepository
+func NewMockChatroomStatsRepository() *MockChatroomStatsRepository {
+	return &MockChatroomStatsRepository{
+		Presence:    make(map[string][]map[string]int),
+		StatsResult: &domain.ChatroomStats{},
+	}
+}
+
+func (m *MockChatroomStatsRepository) RecordPresence(ctx context.Context, instanceID string, sampledAt time.Time, counts map[string]int) error {
+	if m.RecordPresenceFunc != nil {
+		return m.RecordPresenceFunc(ctx, instanceID, sampledAt, counts)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Presence[instanceID] = append(m.Presence[instanceID], maps.Clone(counts))
+	return nil
+}
+
+func (m *MockChatroomStatsRepository) PresenceHistory(ctx context.Context, chatroomID string, since time.Time, bucket time.Duration) ([]*domain.RoomPresenceHistory, error) {
+	if m.PresenceHistoryFunc != nil {
+		return m.PresenceHistoryFunc(ctx, chatroomID, since, bucket)
+	}
+	return []*domain.RoomPresenceHistory{}, nil
+}
+
+func (m *MockChatroomStatsRepository) DeletePresenceBefore(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockChatroomStatsRepository) Refresh(ctx context.Context) error {
+	if m.RefreshFunc != nil {
+		return m.RefreshFunc(ctx)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Refreshes++
+	return nil
+}
+
+func (m *MockChatroomStatsRepository) Stats(ctx context.Context, chatroomID string, since time.Time) (*domain.ChatroomStats, error) {
+	if m.StatsFunc != nil {
+		return m.StatsFunc(ctx, chatroomID, since)
+	}
+	stats := *m.StatsResult
+	stats.ChatroomID = chatroomID
+	return &stats, nil
+}
+
+// MockLoginFailureRepository implements domain.LoginFailureRepository for
+// testing
+type MockLoginFailureRepository struct {
+	mu sync.Mutex
+
+	// Function overrides
+	GetFunc     func(ctx context.Context, username, ip string, since time.Time) (*domain.LoginFailures, error)
+	ReserveFunc func(ctx context.Context, username, ip string, at, since time.Time, baseDelay, maxDelay time.Duration) (*domain.LoginFailures, bool, error)
+
+	// In-memory storage: failures by LoginFailureKey
+	Failures map[string]domain.LoginFailures
+}
+
+// NewMockLoginFailureRepository creates a new MockLoginFailureRepository with
+// initialized maps
+func NewMockLoginFailureRepository() *MockLoginFailureRepository {
+	return &MockLoginFailureRepository{
+		Failures: make(map[string]domain.LoginFailures),
+	}
+}
+
+// LoginFailureKey is the key of the failures of username from ip in
+// MockLoginFailureRepository.Failures
+func LoginFailureKey(username, ip string) string {
+	return strings.ToLower(username) + "|" + ip
+}
+
+func (m *MockLoginFailureRepository) Get(ctx context.Context, username, ip string, since time.Time) (*domain.LoginFailures, error) {
+	if m.GetFunc != nil {
+		return m.GetFunc(ctx, username, ip, since)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	failures, ok := m.Failures[LoginFailureKey(username, ip)]
+	if !ok || failures.LastFailedAt.Before(since) {
+		return &domain.LoginFailures{}, nil
+	}
+	return &failures, nil
+}
+
+func (m *MockLoginFailureRepository) Reserve(ctx context.Context, username, ip string, at, since time.Time, baseDelay, maxDelay time.Duration) (*domain.LoginFailures, bool, error) {
+	if m.ReserveFunc != nil {
+		return m.ReserveFunc(ctx, username, ip, at, since, baseDelay, maxDelay)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := LoginFailureKey(username, ip)
+	failures := m.Failures[key]
+	if failures.LastFailedAt.Before(since) || failures.Count <= 0 {
+		failures.Count = 0
+	} else {
+		delay := baseDelay
+		for range failures.Count - 1 {
+			if delay >= maxDelay {
+				break
+			}
+			delay *= 2
+		}
+		if at.Before(failures.LastFailedAt.Add(min(delay, maxDelay))) {
+			return &failures, false, nil
+		}
+	}
+	failures.Count++
+	failures.LastFailedAt = at
+	m.Failures[key] = failures
+	return &failures, true, nil
+}
+
+func (m *MockLoginFailureRepository) Release(ctx context.Context, username, ip string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := LoginFailureKey(username, ip)
+	if failures, ok := m.Failures[key]; ok && failures.Count > 0 {
+		failures.Count--
+		m.Failures[key] = failures
+	}
+	return nil
+}
+
+func (m *MockLoginFailureRepository) Reset(ctx context.Context, username, ip string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.Failures, LoginFailureKey(username, ip))
+	return nil
+}
+
+func (m *MockLoginFailureRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for key, failures := range m.Failures {
+		if failures.LastFailedAt.Before(before) {
+			delete(m.Failures, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// MockUsernameHistoryRepository implements domain.UsernameHistoryRepository
+// for testing, renaming the users of a MockUserRepository
+type MockUsernameHistoryRepository struct {
+	mu sync.Mutex
+
+	users *MockUserRepository
+	// Changes holds the username changes by user ID, oldest first
+	Changes map[string][]*domain.UsernameChange
+}
+
+// NewMockUsernameHistoryRepository creates a MockUsernameHistoryRepository
+// renaming the users of users
+func NewMockUsernameHistoryRepository(users *MockUserRepository) *MockUsernameHistoryRepository {
+	return &MockUsernameHistoryRepository{
+		users:   users,
+		Changes: make(map[string][]*domain.UsernameChange),
+	}
+}
+
+func (m *MockUsernameHistoryRepository) Rename(ctx context.Context, userID, username string, heldSince time.Time) (*domain.UsernameChange, error) {
+	if m.heldBy(username, userID, heldSince) {
+		return nil, domain.ErrUsernameExists
+	}
+
+	m.users.mu.Lock()
+	defer m.users.mu.Unlock()
+	user, ok := m.users.Users[userID]
+	if !ok || user.IsGuest() {
+		return nil, domain.ErrUserNotFound
+	}
+	for _, other := range m.users.Users {
+		if other.ID != userID && other.Username == username {
+			return nil, domain.ErrUsernameExists
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	change := &domain.UsernameChange{OldUsername: user.Username, NewUsername: username, ChangedAt: time.Now()}
+	m.Changes[userID] = append(m.Changes[userID], change)
+	user.Username = username
+	return change, nil
+}
+
+// heldBy reports whether a user other than userID gave up username at or
+// after since
+func (m *MockUsernameHistoryRepository) heldBy(username, userID string, since time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, changes := range m.Changes {
+		for _, change := range changes {
+			if id != userID && strings.EqualFold(change.OldUsername, username) && !change.ChangedAt.Before(since) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m *MockUsernameHistoryRepository) History(ctx context.Context, userID string) ([]*domain.UsernameChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changes := slices.Clone(m.Changes[userID])
+	slices.Reverse(changes)
+	if changes == nil {
+		changes = []*domain.UsernameChange{}
+	}
+	return changes, nil
+}
+
+func (m *MockUsernameHistoryRepository) IsHeld(ctx context.Context, username string, since time.Time) (bool, error) {
+	return m.heldBy(username, "", since), nil
+}
+
+func (m *MockUsernameHistoryRepository) ResolveFormer(ctx context.Context, username string) (string, error) {
+	if _, err := m.users.GetByUsername(ctx, username); err == nil {
+		return "", domain.ErrUserNotFound
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var userID string
+	var last time.Time
+	for id, changes := range m.Changes {
+		for _, change := range changes {
+			if strings.EqualFold(change.OldUsername, username) && !change.ChangedAt.Before(last) {
+				userID, last = id, change.ChangedAt
+			}
+		}
+	}
+	if userID == "" {
+		return "", domain.ErrUserNotFound
+	}
+	return userID, nil
 }
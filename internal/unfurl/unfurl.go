@@ -0,0 +1,347 @@
+// Package unfurl builds link previews from the OpenGraph metadata of URLs
+// posted in chat messages. Only hosts on an allowlist are fetched, and
+// connections to loopback, private and other non-public addresses are refused
+// at dial time so redirects and DNS rebinding cannot reach internal services.
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"jobsity-chat/internal/domain"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	ErrNotAllowed = errors.New("url not allowed")
+	ErrNoPreview  = errors.New("page has no preview metadata")
+)
+
+const (
+	maxRedirects    = 3
+	maxBodyBytes    = 512 << 10
+	maxTitleLength  = 300
+	maxDescLength   = 1000
+	maxURLLength    = 2048
+	fetchTimeout    = 5 * time.Second
+	userAgent       = "ChattorumuBot/1.0 (+link preview)"
+	anyDomainMarker = "*"
+)
+
+// Allowlist is the set of domains whose pages may be fetched. A domain also
+// allows its subdomains.
+type Allowlist struct {
+	any     bool
+	domains []string
+}
+
+// ParseAllowlist parses a comma-separated list of domains. "*" allows every
+// public host; an empty list allows none.
+func ParseAllowlist(spec string) Allowlist {
+	var allow Allowlist
+	for part := range strings.SplitSeq(spec, ",") {
+		domain := strings.ToLower(strings.Trim(strings.TrimSpace(part), "."))
+		switch {
+		case domain == "":
+		case domain == anyDomainMarker:
+			allow.any = true
+		default:
+			allow.domains = append(allow.domains, domain)
+		}
+	}
+	return allow
+}
+
+// Empty reports whether no host is allowed
+func (a Allowlist) Empty() bool {
+	return !a.any && len(a.domains) == 0
+}
+
+// Allows reports whether host, without port, may be fetched
+func (a Allowlist) Allows(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return false
+	}
+	if a.any {
+		return true
+	}
+	for _, domain := range a.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// urlPattern matches http(s) URLs up to whitespace or a quote
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+
+// ExtractURLs returns up to limit distinct http(s) URLs found in content, in
+// order of appearance. Trailing punctuation is not part of the URL.
+func ExtractURLs(content string, limit int) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, match := range urlPattern.FindAllString(content, -1) {
+		match = strings.TrimRight(match, ".,;:!?)]}")
+		if len(match) > maxURLLength || seen[match] {
+			continue
+		}
+		seen[match] = true
+		urls = append(urls, match)
+		if len(urls) == limit {
+			break
+		}
+	}
+	return urls
+}
+
+// Fetcher downloads pages and extracts their preview metadata
+type Fetcher struct {
+	allow  Allowlist
+	client *http.Client
+
+	// allowPrivate disables the address check; tests use it to reach
+	// httptest servers on loopback
+	allowPrivate bool
+}
+
+func NewFetcher(allow Allowlist) *Fetcher {
+	f := &Fetcher{allow: allow}
+
+	dialer := &net.Dialer{
+		Timeout: fetchTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if f.allowPrivate {
+				return nil
+			}
+			return checkAddress(address)
+		},
+	}
+	f.client = &http.Client{
+		Timeout: fetchTimeout,
+		Transport: &http.Transport{
+			// No proxy: the dial check must see the real destination
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   fetchTimeout,
+			ResponseHeaderTimeout: fetchTimeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("%w: too many redirects", ErrNotAllowed)
+			}
+			return f.checkURL(req.URL)
+		},
+	}
+	return f
+}
+
+// checkAddress rejects dialing anything but a public unicast address
+func checkAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotAllowed, err)
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotAllowed, err)
+	}
+	if !isPublic(addr) {
+		return fmt.Errorf("%w: non-public address %s", ErrNotAllowed, addr)
+	}
+	return nil
+}
+
+// nonPublicPrefixes are the special-purpose ranges netip has no predicate for
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *Fetcher) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q", ErrNotAllowed, u.Scheme)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: credentials in url", ErrNotAllowed)
+	}
+	if !f.allow.Allows(u.Hostname()) {
+		return fmt.Errorf("%w: host %q", ErrNotAllowed, u.Hostname())
+	}
+	return nil
+}
+
+// Fetch downloads rawURL and returns its preview. It returns ErrNotAllowed
+// for URLs that may not be fetched and ErrNoPreview for pages without a title.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*domain.LinkPreview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotAllowed, err)
+	}
+	if err := f.checkURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", u.Redacted(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("%w: content type %q", ErrNoPreview, mediaType)
+	}
+
+	preview := parseHead(io.LimitReader(resp.Body, maxBodyBytes), resp.Request.URL)
+	if preview.Title == "" {
+		return nil, ErrNoPreview
+	}
+	preview.URL = rawURL
+	return preview, nil
+}
+
+// parseHead reads OpenGraph tags from the document head, falling back to
+// <title> and the description meta tag. Relative image URLs are resolved
+// against base.
+func parseHead(r io.Reader, base *url.URL) *domain.LinkPreview {
+	var (
+		preview           domain.LinkPreview
+		title, desc       string
+		inTitle           bool
+		ogTitle, ogDesc   string
+		ogImage, siteName string
+	)
+
+	z := html.NewTokenizer(r)
+loop:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			break loop
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tag := z.Token()
+			switch tag.Data {
+			case "body":
+				break loop
+			case "title":
+				inTitle = title == ""
+			case "meta":
+				key, content := metaAttrs(tag)
+				switch key {
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					ogDesc = content
+				case "og:image", "og:image:url":
+					if ogImage == "" {
+						ogImage = content
+					}
+				case "og:site_name":
+					siteName = content
+				case "description":
+					desc = content
+				}
+			}
+		case html.TextToken:
+			if inTitle {
+				title += string(z.Text())
+			}
+		case html.EndTagToken:
+			if tag, _ := z.TagName(); string(tag) == "head" {
+				break loop
+			} else if string(tag) == "title" {
+				inTitle = false
+			}
+		}
+	}
+
+	preview.Title = truncate(firstNonEmpty(ogTitle, title), maxTitleLength)
+	preview.Description = truncate(firstNonEmpty(ogDesc, desc), maxDescLength)
+	preview.SiteName = truncate(siteName, maxTitleLength)
+	if ogImage != "" {
+		if img, err := base.Parse(ogImage); err == nil && (img.Scheme == "http" || img.Scheme == "https") && len(img.String()) <= maxURLLength {
+			preview.ImageURL = img.String()
+		}
+	}
+	return &preview
+}
+
+// metaAttrs returns the property (or name) and content of a meta tag
+func metaAttrs(tag html.Token) (key, content string) {
+	for _, attr := range tag.Attr {
+		switch attr.Key {
+		case "property":
+			key = strings.ToLower(attr.Val)
+		case "name":
+			if key == "" {
+				key = strings.ToLower(attr.Val)
+			}
+		case "content":
+			content = attr.Val
+		}
+	}
+	return key, content
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// truncate collapses whitespace and cuts s to at most n characters
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
@@ -0,0 +1,161 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+
+	"jobsity-chat/internal/testutil"
+)
+
+func TestExtractURLs(t *testing.T) {
+	tests := []struct {
+		content  string
+		limit    int
+		expected []string
+	}{
+		{"no links here", 3, nil},
+		{"see https://example.com/a.", 3, []string{"https://example.com/a"}},
+		{"(http://example.com/x?y=1), http://example.com/x?y=1", 3, []string{"http://example.com/x?y=1"}},
+		{"ftp://example.com javascript:alert(1)", 3, nil},
+		{"https://a.io https://b.io https://c.io https://d.io", 2, []string{"https://a.io", "https://b.io"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			testutil.AssertEqual(t, strings.Join(ExtractURLs(tt.content, tt.limit), " "), strings.Join(tt.expected, " "))
+		})
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	allow := ParseAllowlist(" Example.com, news.site ,")
+
+	testutil.AssertFalse(t, allow.Empty(), "allowlist should not be empty")
+	testutil.AssertTrue(t, allow.Allows("example.com"), "listed domain")
+	testutil.AssertTrue(t, allow.Allows("www.EXAMPLE.com."), "subdomain")
+	testutil.AssertTrue(t, allow.Allows("news.site"), "second domain")
+	testutil.AssertFalse(t, allow.Allows("badexample.com"), "suffix without dot")
+	testutil.AssertFalse(t, allow.Allows("site"), "parent domain")
+
+	testutil.AssertTrue(t, ParseAllowlist("").Empty(), "empty spec")
+	testutil.AssertTrue(t, ParseAllowlist("*").Allows("anything.org"), "wildcard")
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1::1", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			testutil.AssertEqual(t, isPublic(netip.MustParseAddr(tt.addr)), tt.public)
+		})
+	}
+}
+
+func TestParseHead(t *testing.T) {
+	base, _ := url.Parse("https://example.com/posts/1")
+	page := `<!doctype html><html><head>
+		<title>Fallback title</title>
+		<meta property="og:title" content="  The   Post ">
+		<meta name="description" content="Plain description">
+		<meta property="og:image" content="/img/cover.png">
+		<meta property="og:site_name" content="Example">
+		</head><body><meta property="og:title" content="ignored"></body></html>`
+
+	preview := parseHead(strings.NewReader(page), base)
+
+	testutil.AssertEqual(t, preview.Title, "The Post")
+	testutil.AssertEqual(t, preview.Description, "Plain description")
+	testutil.AssertEqual(t, preview.ImageURL, "https://example.com/img/cover.png")
+	testutil.AssertEqual(t, preview.SiteName, "Example")
+
+	preview = parseHead(strings.NewReader(`<title>Only a title</title><meta property="og:image" content="javascript:x">`), base)
+	testutil.AssertEqual(t, preview.Title, "Only a title")
+	testutil.AssertEqual(t, preview.ImageURL, "")
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><meta property="og:title" content="Hello"></head></html>`))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png"))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://elsewhere.test/page", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	ctx := context.Background()
+
+	t.Run("allowed_page", func(t *testing.T) {
+		f := NewFetcher(ParseAllowlist(serverURL.Hostname()))
+		f.allowPrivate = true
+
+		preview, err := f.Fetch(ctx, server.URL+"/page")
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, preview.Title, "Hello")
+		testutil.AssertEqual(t, preview.URL, server.URL+"/page")
+	})
+
+	t.Run("not_html", func(t *testing.T) {
+		f := NewFetcher(ParseAllowlist(serverURL.Hostname()))
+		f.allowPrivate = true
+
+		_, err := f.Fetch(ctx, server.URL+"/image")
+		testutil.AssertErrorIs(t, err, ErrNoPreview)
+	})
+
+	t.Run("host_not_allowed", func(t *testing.T) {
+		f := NewFetcher(ParseAllowlist("example.com"))
+		f.allowPrivate = true
+
+		_, err := f.Fetch(ctx, server.URL+"/page")
+		testutil.AssertErrorIs(t, err, ErrNotAllowed)
+	})
+
+	t.Run("redirect_off_allowlist", func(t *testing.T) {
+		f := NewFetcher(ParseAllowlist(serverURL.Hostname()))
+		f.allowPrivate = true
+
+		_, err := f.Fetch(ctx, server.URL+"/redirect")
+		testutil.AssertErrorIs(t, err, ErrNotAllowed)
+	})
+
+	t.Run("private_address_refused", func(t *testing.T) {
+		f := NewFetcher(ParseAllowlist("*"))
+
+		_, err := f.Fetch(ctx, server.URL+"/page")
+		if !errors.Is(err, ErrNotAllowed) {
+			t.Fatalf("expected the loopback server to be refused, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"jobsity-chat/internal/domain"
+)
+
+const (
+	// MaxPreviewsPerMessage caps how many URLs of one message are fetched
+	MaxPreviewsPerMessage = 3
+
+	queueSize      = 256
+	messageTimeout = 15 * time.Second
+)
+
+// PreviewFetcher fetches the preview of one URL
+type PreviewFetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*domain.LinkPreview, error)
+}
+
+// Notifier pushes a message's previews to the clients in its chatroom
+type Notifier interface {
+	PublishLinkPreviews(msg *domain.Message, previews []*domain.LinkPreview) error
+}
+
+type job struct {
+	orgID string
+	msg   *domain.Message
+	urls  []string
+}
+
+// Worker fetches previews for posted messages in the background, stores them
+// and notifies the chatroom. Messages are queued by Enqueue and dropped when
+// the queue is full, so a burst of links never slows down chat.
+type Worker struct {
+	fetcher  PreviewFetcher
+	repo     domain.LinkPreviewRepository
+	notifier Notifier
+	jobs     chan job
+}
+
+func NewWorker(fetcher PreviewFetcher, repo domain.LinkPreviewRepository, notifier Notifier) *Worker {
+	return &Worker{
+		fetcher:  fetcher,
+		repo:     repo,
+		notifier: notifier,
+		jobs:     make(chan job, queueSize),
+	}
+}
+
+// Enqueue queues msg for unfurling if it contains URLs. Bot messages and
+// encrypted messages are skipped.
+func (w *Worker) Enqueue(ctx context.Context, msg *domain.Message) {
+	if msg.IsBot || msg.Encrypted {
+		return
+	}
+	urls := ExtractURLs(msg.Content, MaxPreviewsPerMessage)
+	if len(urls) == 0 {
+		return
+	}
+
+	select {
+	case w.jobs <- job{orgID: domain.OrgIDFromContext(ctx), msg: msg, urls: urls}:
+	default:
+		slog.Warn("link preview queue full, skipping message",
+			slog.String("message_id", msg.ID),
+			slog.String("chatroom_id", msg.ChatroomID))
+	}
+}
+
+// Run processes queued messages with the given number of goroutines until
+// ctx is cancelled
+func (w *Worker) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-w.jobs:
+					w.process(ctx, j)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) process(ctx context.Context, j job) {
+	ctx, cancel := context.WithTimeout(domain.WithOrgID(ctx, j.orgID), messageTimeout)
+	defer cancel()
+
+	var previews []*domain.LinkPreview
+	for _, rawURL := range j.urls {
+		preview, err := w.fetcher.Fetch(ctx, rawURL)
+		if err != nil {
+			if !errors.Is(err, ErrNotAllowed) && !errors.Is(err, ErrNoPreview) {
+				slog.Debug("link preview fetch failed",
+					slog.String("error", err.Error()),
+					slog.String("message_id", j.msg.ID))
+			}
+			continue
+		}
+
+		preview.MessageID = j.msg.ID
+		if err := w.repo.Save(ctx, preview); err != nil {
+			slog.Error("failed to save link preview",
+				slog.String("error", err.Error()),
+				slog.String("message_id", j.msg.ID))
+			continue
+		}
+		previews = append(previews, preview)
+	}
+	if len(previews) == 0 {
+		return
+	}
+
+	if err := w.notifier.PublishLinkPreviews(j.msg, previews); err != nil {
+		slog.Warn("failed to publish link previews",
+			slog.String("error", err.Error()),
+			slog.String("message_id", j.msg.ID))
+	}
+}
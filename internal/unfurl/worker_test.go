@@ -0,0 +1,89 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+)
+
+type stubFetcher map[string]*domain.LinkPreview
+
+func (f stubFetcher) Fetch(ctx context.Context, rawURL string) (*domain.LinkPreview, error) {
+	if preview, ok := f[rawURL]; ok {
+		copied := *preview
+		copied.URL = rawURL
+		return &copied, nil
+	}
+	return nil, ErrNoPreview
+}
+
+type stubPreviewRepo struct {
+	mu     sync.Mutex
+	saved  []*domain.LinkPreview
+	orgIDs []string
+}
+
+func (r *stubPreviewRepo) Save(ctx context.Context, preview *domain.LinkPreview) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if preview.URL == "https://broken.example/" {
+		return errors.New("database error")
+	}
+	r.saved = append(r.saved, preview)
+	r.orgIDs = append(r.orgIDs, domain.OrgIDFromContext(ctx))
+	return nil
+}
+
+type recordingNotifier chan []*domain.LinkPreview
+
+func (n recordingNotifier) PublishLinkPreviews(msg *domain.Message, previews []*domain.LinkPreview) error {
+	n <- previews
+	return nil
+}
+
+func TestWorker(t *testing.T) {
+	fetcher := stubFetcher{
+		"https://example.com/a":   {Title: "A"},
+		"https://broken.example/": {Title: "Broken"},
+	}
+	repo := &stubPreviewRepo{}
+	notified := make(recordingNotifier, 1)
+	worker := NewWorker(fetcher, repo, notified)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		worker.Run(ctx, 2)
+		close(done)
+	}()
+
+	orgCtx := domain.WithOrgID(context.Background(), "org-1")
+	worker.Enqueue(orgCtx, &domain.Message{ID: "msg-0", Content: "no links"})
+	worker.Enqueue(orgCtx, &domain.Message{ID: "msg-bot", Content: "https://example.com/a", IsBot: true})
+	worker.Enqueue(orgCtx, &domain.Message{
+		ID:      "msg-1",
+		Content: "look https://example.com/a and https://broken.example/ and https://none.example",
+	})
+
+	select {
+	case previews := <-notified:
+		testutil.AssertLen(t, previews, 1)
+		testutil.AssertEqual(t, previews[0].MessageID, "msg-1")
+		testutil.AssertEqual(t, previews[0].Title, "A")
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected previews to be published")
+	}
+
+	cancel()
+	<-done
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	testutil.AssertLen(t, repo.saved, 1)
+	testutil.AssertEqual(t, repo.orgIDs[0], "org-1")
+}
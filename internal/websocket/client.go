@@ -3,12 +3,15 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/i18n"
+	"jobsity-chat/internal/observability"
 	"jobsity-chat/internal/service"
 
 	"github.com/gorilla/websocket"
@@ -19,6 +22,40 @@ const (
 	pongWait       = 60 * time.Second
 	pingPeriod     = 54 * time.Second // Must be less than pongWait
 	maxMessageSize = 1024
+
+	// sendBufferSize is how many frames may wait for a connection's
+	// WritePump; a broadcast finding the buffer full drops the connection
+	sendBufferSize = 256
+
+	// maxEncryptedMessageSize is the read limit in encrypted chatrooms, whose
+	// frames carry up to service.MaxCiphertextLength bytes of ciphertext
+	maxEncryptedMessageSize = 16384
+
+	// maxClientMsgIDLength bounds the client_msg_id a client may attach to a
+	// message; longer IDs are ignored
+	maxClientMsgIDLength = 64
+
+	// recentAckLimit is how many client_msg_ids a connection remembers to
+	// re-acknowledge retried sends without persisting them twice
+	recentAckLimit = 128
+
+	// DefaultMalformedFrameBudget is how many malformed frames a connection
+	// may send before it is closed, unless the hub sets another budget
+	DefaultMalformedFrameBudget = 5
+
+	// DefaultSlowClientThreshold is the percentage of its send buffer a
+	// connection may fill before it is warned it is falling behind, unless
+	// the hub sets another threshold
+	DefaultSlowClientThreshold = 75
+)
+
+// Codes of the error frames answering malformed frames
+const (
+	// ErrorCodeMalformedFrame answers a frame that is not a JSON object
+	ErrorCodeMalformedFrame = "malformed_frame"
+	// ErrorCodeUnknownFrame answers a frame type the connection's protocol
+	// version does not know
+	ErrorCodeUnknownFrame = "unknown_frame"
 )
 
 type Client struct {
@@ -28,23 +65,87 @@ type Client struct {
 	userID      string
 	username    string
 	chatroomID  string
-	chatService *service.ChatService
+	orgID       string
+	locale      string
+	chatService ChatService
 	publisher   MessagePublisher
 	writeMu     sync.Mutex
 	closed      atomic.Bool
 	sendClosed  atomic.Bool // Guards against double-close of send channel
+	sendMu      sync.Mutex  // Serializes direct replies with closing send
 	ctx         context.Context
 	ctxCancel   context.CancelFunc
+
+	// onActivity is called for every message read from the connection
+	onActivity func()
+	// lastActive is the UnixNano time the client last sent a message, or
+	// connected. The hub shows users whose clients are all inactive as away.
+	lastActive atomic.Int64
+	// status is the status the user chose. The hub updates it on all of
+	// the user's connections when they change it.
+	status atomic.Pointer[domain.UserStatus]
+
+	// encrypted is set for end-to-end encrypted chatrooms, whose messages are
+	// relayed as is instead of being parsed for commands
+	encrypted bool
+
+	// readOnly is set for guests who may read but not post in the chatroom
+	readOnly bool
+
+	// protocolVersion is the protocol version the client negotiated
+	protocolVersion int
+
+	// closeFrame, when set before the hub closes send, is the close message
+	// WritePump sends instead of an empty one
+	closeFrame []byte
+	// replaced is set when a newer connection of the same user took over, so
+	// leaving is not announced to the room
+	replaced atomic.Bool
+	// degraded is set while the send buffer is backed up past the hub's slow
+	// client threshold, between the connection_degraded frame and the
+	// connection_recovered one
+	degraded atomic.Bool
+	// degradedFrame is the connection_degraded frame WritePump sends ahead
+	// of the backlog it warns about
+	degradedFrame atomic.Pointer[[]byte]
+
+	// recentAcks maps the client_msg_ids acknowledged on this connection to
+	// the persisted message IDs, oldest first in ackOrder, sparing the
+	// store a retry on the same connection. Only touched by ReadPump.
+	recentAcks map[string]string
+	ackOrder   []string
+
+	// malformedFrames counts the malformed frames read from the
+	// connection. Only touched by ReadPump.
+	malformedFrames int
+}
+
+// ChatService stores the messages clients send and serves the ones they
+// missed
+type ChatService interface {
+	SendMessage(ctx context.Context, msg *domain.Message) error
+	GetMessagesSince(ctx context.Context, chatroomID, sinceID string, limit int) ([]*domain.Message, bool, error)
 }
 
 type MessagePublisher interface {
-	PublishStockCommand(ctx context.Context, chatroomID, stockCode, requestedBy string) error
-	PublishHelloCommand(ctx context.Context, chatroomID, requestedBy string) error
+	PublishStockCommand(ctx context.Context, chatroomID, messageID, stockCode, requestedBy string) error
+	PublishHelloCommand(ctx context.Context, chatroomID, messageID, requestedBy string) error
+	PublishGiphyCommand(ctx context.Context, chatroomID, messageID, query, requestedBy string) error
 }
 
+// ClientMessage is a frame sent by the web client. ClientMsgID is an optional
+// client-generated ID echoed in the message_ack, error and chat_message frames
+// for the message so the client can track delivery and retry failed sends.
+// A fetch_since frame asks for up to Limit messages posted after SinceID.
+// QuotedMessageID makes a message a reply quoting another message of the
+// chatroom.
 type ClientMessage struct {
-	Type    string `json:"type"`
-	Content string `json:"content"`
+	Type            string `json:"type"`
+	Content         string `json:"content"`
+	ClientMsgID     string `json:"client_msg_id,omitempty"`
+	SinceID         string `json:"since_id,omitempty"`
+	Limit           int    `json:"limit,omitempty"`
+	QuotedMessageID string `json:"quoted_message_id,omitempty"`
 }
 
 type ServerMessage struct {
@@ -57,24 +158,117 @@ type ServerMessage struct {
 	IsError   bool       `json:"is_error,omitempty"`
 	CreatedAt *time.Time `json:"created_at,omitempty"`
 	Message   string     `json:"message,omitempty"`
+
+	ClientMsgID string `json:"client_msg_id,omitempty"`
+	// Code identifies the failure of an error frame answering a malformed
+	// frame, e.g. ErrorCodeMalformedFrame
+	Code string `json:"code,omitempty"`
+
+	// Messages and HasMore answer a fetch_since frame
+	Messages []ServerMessage `json:"messages,omitempty"`
+	HasMore  bool            `json:"has_more,omitempty"`
+
+	// Previews carries the link previews of a message_updated frame
+	Previews []*domain.LinkPreview `json:"previews,omitempty"`
+
+	// Attachment is the image of a bot chat_message, e.g. a /giphy result
+	Attachment *domain.Attachment `json:"attachment,omitempty"`
+
+	// ForwardedFrom attributes a forwarded chat_message to its original
+	// author
+	ForwardedFrom *domain.ForwardedFrom `json:"forwarded_from,omitempty"`
+	// Quote is the snapshot of the message a chat_message replies to
+	Quote *domain.Quote `json:"quote,omitempty"`
+	// ParentMessageID is the message a threaded chat_message answers, such
+	// as the command a bot answers
+	ParentMessageID string `json:"parent_message_id,omitempty"`
+	// HTML is the content of a chat_message rendered from Markdown, in
+	// chatrooms that enable it
+	HTML string `json:"html,omitempty"`
+	// Event is the event a chat_message announces
+	Event *domain.CalendarEvent `json:"event,omitempty"`
+
+	// RSVP is a member's answer to an event of an event_rsvp frame
+	RSVP *domain.RSVP `json:"rsvp,omitempty"`
+
+	// Status and StatusText are the status others see of the user of a
+	// presence frame
+	Status     string `json:"status,omitempty"`
+	StatusText string `json:"status_text,omitempty"`
+
+	// Queued and Capacity are the frames waiting in the connection's send
+	// buffer and its size, in a connection_degraded frame
+	Queued   int `json:"queued,omitempty"`
+	Capacity int `json:"capacity,omitempty"`
 }
 
 func NewClient(ctx context.Context, hub *Hub, conn *websocket.Conn, userID, username, chatroomID string,
-	chatService *service.ChatService, publisher MessagePublisher) *Client {
-	clientCtx, cancel := context.WithCancel(ctx)
+	chatService ChatService, publisher MessagePublisher) *Client {
+	clientCtx, cancel := context.WithCancel(domain.WithViewerID(ctx, userID))
 
-	return &Client{
+	client := &Client{
 		hub:         hub,
 		conn:        conn,
-		send:        make(chan []byte, 256),
+		send:        make(chan []byte, sendBufferSize),
 		userID:      userID,
 		username:    username,
 		chatroomID:  chatroomID,
+		orgID:       domain.OrgIDFromContext(ctx),
+		locale:      i18n.LocaleFromContext(ctx),
 		chatService: chatService,
 		publisher:   publisher,
 		ctx:         clientCtx,
 		ctxCancel:   cancel,
+		recentAcks:  make(map[string]string),
+
+		protocolVersion: ProtocolVersion,
+	}
+	client.lastActive.Store(time.Now().UnixNano())
+	return client
+}
+
+// SetActivityHook registers fn to be called whenever the client sends a
+// message, e.g. to keep its session alive. Must be called before ReadPump.
+func (c *Client) SetActivityHook(fn func()) {
+	c.onActivity = fn
+}
+
+// SetStatus sets the status the user chose, e.g. the stored one when they
+// connect. Must be called before the client is registered; later changes go
+// through Hub.PublishStatus.
+func (c *Client) SetStatus(status domain.UserStatus) {
+	c.status.Store(&status)
+}
+
+// currentStatus returns the status the user chose, active unless set
+func (c *Client) currentStatus() domain.UserStatus {
+	if status := c.status.Load(); status != nil {
+		return *status
 	}
+	return domain.UserStatus{Status: domain.StatusActive}
+}
+
+// SetEncrypted marks the client's chatroom as end-to-end encrypted. Must be
+// called before ReadPump.
+func (c *Client) SetEncrypted(encrypted bool) {
+	c.encrypted = encrypted
+}
+
+// SetReadOnly rejects the client's messages and commands with an error
+// instead of posting them. Must be called before ReadPump.
+func (c *Client) SetReadOnly(readOnly bool) {
+	c.readOnly = readOnly
+}
+
+// SetProtocolVersion sets the protocol version the client negotiated,
+// ProtocolVersion unless set. Must be called before ReadPump.
+func (c *Client) SetProtocolVersion(version int) {
+	c.protocolVersion = version
+}
+
+// ProtocolVersion returns the protocol version the client negotiated
+func (c *Client) ProtocolVersion() int {
+	return c.protocolVersion
 }
 
 func (c *Client) ReadPump() {
@@ -83,22 +277,17 @@ func (c *Client) ReadPump() {
 		c.hub.Unregister(c)
 		c.closeConnection()
 
-		leftMsg := ServerMessage{
-			Type:     "user_left",
-			Username: c.username,
-		}
-		data, err := json.Marshal(leftMsg)
-		if err != nil {
-			slog.Error("failed to marshal user left message",
-				slog.String("error", err.Error()),
-				slog.String("username", c.username))
-		} else {
-			// Non-critical broadcast, so we ignore errors
-			_ = c.hub.Broadcast(c.chatroomID, data)
+		// Non-critical broadcast, so we ignore errors
+		if !c.replaced.Load() {
+			_ = c.hub.BroadcastLocalized(c.chatroomID, c.systemMessage("user_left", i18n.SystemUserLeft))
 		}
 	}()
 
-	c.conn.SetReadLimit(maxMessageSize)
+	readLimit := int64(maxMessageSize)
+	if c.encrypted {
+		readLimit = maxEncryptedMessageSize
+	}
+	c.conn.SetReadLimit(readLimit)
 	if err := c.conn.SetReadDeadline(time.Now().Add(pongWait)); err != nil {
 		slog.Warn("failed to set read deadline",
 			slog.String("error", err.Error()),
@@ -117,19 +306,8 @@ func (c *Client) ReadPump() {
 		return nil
 	})
 
-	joinedMsg := ServerMessage{
-		Type:     "user_joined",
-		Username: c.username,
-	}
-	data, err := json.Marshal(joinedMsg)
-	if err != nil {
-		slog.Error("failed to marshal user joined message",
-			slog.String("error", err.Error()),
-			slog.String("username", c.username))
-	} else {
-		// Non-critical broadcast, so we ignore errors
-		_ = c.hub.Broadcast(c.chatroomID, data)
-	}
+	// Non-critical broadcast, so we ignore errors
+	_ = c.hub.BroadcastLocalized(c.chatroomID, c.systemMessage("user_joined", i18n.SystemUserJoined))
 
 	for {
 		_, message, err := c.conn.ReadMessage()
@@ -142,71 +320,110 @@ func (c *Client) ReadPump() {
 			break
 		}
 
+		c.lastActive.Store(time.Now().UnixNano())
+		if c.onActivity != nil {
+			c.onActivity()
+		}
+
 		var clientMsg ClientMessage
 		if err := json.Unmarshal(message, &clientMsg); err != nil {
 			slog.Warn("invalid message format",
 				slog.String("error", err.Error()),
 				slog.String("user", c.username))
+			if !c.malformedFrame(ErrorCodeMalformedFrame, i18n.ErrorMalformedFrame, "") {
+				break
+			}
 			continue
 		}
 
-		if cmd, isCommand := service.ParseCommand(clientMsg.Content); isCommand {
-			func() {
-				ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
-				defer cancel()
-
-				var err error
-				switch cmd.Type {
-				case "stock":
-					err = c.publisher.PublishStockCommand(ctx, c.chatroomID, cmd.StockCode, c.username)
-				case "hello":
-					err = c.publisher.PublishHelloCommand(ctx, c.chatroomID, c.username)
-				default:
-					slog.Warn("unknown command type",
-						slog.String("type", cmd.Type),
-						slog.String("user", c.username))
-					return
-				}
+		clientMsgID := clientMsg.ClientMsgID
+		if len(clientMsgID) > maxClientMsgIDLength {
+			clientMsgID = ""
+		}
 
-				if err != nil {
-					slog.Error("error publishing command",
-						slog.String("error", err.Error()),
-						slog.String("type", cmd.Type),
-						slog.String("user", c.username))
-
-					// Send error message to client
-					errorMsg := ServerMessage{
-						Type:    "error",
-						Message: "Failed to process command",
-					}
-					data, marshalErr := json.Marshal(errorMsg)
-					if marshalErr != nil {
-						slog.Error("failed to marshal error message",
-							slog.String("error", marshalErr.Error()))
-					} else {
-						c.send <- data
-					}
-				}
-			}()
+		if !IsClientFrame(c.protocolVersion, clientMsg.Type) {
+			slog.Warn("unknown frame type",
+				slog.String("type", clientMsg.Type),
+				slog.Int("protocol_version", c.protocolVersion),
+				slog.String("user", c.username))
+			if !c.malformedFrame(ErrorCodeUnknownFrame, i18n.ErrorUnknownFrame, clientMsgID) {
+				break
+			}
 			continue
 		}
 
-		// Save regular message to database
+		if clientMsg.Type == "fetch_since" {
+			c.fetchSince(clientMsg.SinceID, clientMsg.Limit)
+			continue
+		}
+
+		if c.readOnly {
+			c.sendError(i18n.ErrorReadOnly, clientMsgID)
+			continue
+		}
+
+		// A retry of a message that was already persisted is acknowledged
+		// again instead of being saved twice
+		if messageID, ok := c.recentAcks[clientMsgID]; ok {
+			c.sendAck(messageID, clientMsgID)
+			continue
+		}
+
+		// Commands are stored like any message, so the bot's answer can be a
+		// threaded reply to them
+		cmd, isCommand := service.ParseCommand(clientMsg.Content)
+		isCommand = isCommand && !c.encrypted
+
 		msg := &domain.Message{
-			ChatroomID: c.chatroomID,
-			UserID:     c.userID,
-			Username:   c.username,
-			Content:    clientMsg.Content,
-			IsBot:      false,
+			ChatroomID:      c.chatroomID,
+			UserID:          c.userID,
+			Username:        c.username,
+			Content:         clientMsg.Content,
+			IsBot:           false,
+			Encrypted:       c.encrypted,
+			QuotedMessageID: clientMsg.QuotedMessageID,
+			ClientMsgID:     clientMsgID,
 		}
 
 		ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
 		if err := c.chatService.SendMessage(ctx, msg); err != nil {
 			cancel()
+			if errors.Is(err, domain.ErrDuplicateMessage) {
+				// Resent after a reconnect: the original was broadcast and
+				// its command published when it was stored
+				c.rememberAck(clientMsgID, msg.ID)
+				c.sendAck(msg.ID, clientMsgID)
+				continue
+			}
+			if errors.Is(err, domain.ErrQuotaExceeded) {
+				slog.Warn("message rejected by quota",
+					slog.String("error", err.Error()),
+					slog.String("user", c.username),
+					slog.String("chatroom_id", c.chatroomID))
+				c.sendError(i18n.ErrorMessageQuota, clientMsgID)
+				continue
+			}
+			if errors.Is(err, domain.ErrReadOnly) {
+				c.sendError(i18n.ErrorRoomReadOnly, clientMsgID)
+				continue
+			}
+			if errors.Is(err, domain.ErrInvalidQuote) {
+				c.sendError(i18n.ErrorInvalidQuote, clientMsgID)
+				continue
+			}
+			if errors.Is(err, domain.ErrUserDeactivated) {
+				slog.Info("closing connection of deactivated user",
+					slog.String("user", c.username),
+					slog.String("chatroom_id", c.chatroomID))
+				_ = c.writeMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(CloseDisconnected, "account deactivated"))
+				break
+			}
 			slog.Error("error saving message",
 				slog.String("error", err.Error()),
 				slog.String("user", c.username),
 				slog.String("chatroom_id", c.chatroomID))
+			c.sendError(i18n.ErrorMessageFailed, clientMsgID)
 			continue
 		}
 		cancel()
@@ -220,6 +437,10 @@ func (c *Client) ReadPump() {
 			Content:   msg.Content,
 			IsBot:     msg.IsBot,
 			CreatedAt: &msg.CreatedAt,
+			Quote:     msg.Quote,
+			HTML:      msg.HTML,
+
+			ClientMsgID: clientMsgID,
 		}
 
 		data, err := json.Marshal(serverMsg)
@@ -229,16 +450,254 @@ func (c *Client) ReadPump() {
 				slog.String("message_id", msg.ID))
 		} else {
 			// Send ACK immediately to client (optimistic)
-			ackMsg := ServerMessage{
-				Type: "message_ack",
-				ID:   msg.ID,
-			}
-			ackData, _ := json.Marshal(ackMsg)
-			c.send <- ackData
+			c.rememberAck(clientMsgID, msg.ID)
+			c.sendAck(msg.ID, clientMsgID)
 
 			// Broadcast in background to avoid blocking ReadPump
 			go c.broadcastMessageAsync(c.chatroomID, data, msg.ID)
 		}
+
+		if isCommand {
+			c.publishCommand(cmd, msg.ID, clientMsgID)
+		}
+	}
+}
+
+// publishCommand asks the bot to answer cmd, sent in message messageID.
+// Failures are reported to this client only, with the clientMsgID of the
+// command message, which was delivered anyway.
+func (c *Client) publishCommand(cmd *service.Command, messageID, clientMsgID string) {
+	ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
+	defer cancel()
+
+	// Each command gets its own correlation ID so it can be traced
+	// through RabbitMQ and the bot independently of the connection.
+	ctx = observability.WithCorrelationID(ctx, observability.NewCorrelationID())
+	// Lets the bot's errors be sent back to this user only
+	ctx = observability.WithUserID(ctx, c.userID)
+
+	var err error
+	switch cmd.Type {
+	case "stock":
+		err = c.publisher.PublishStockCommand(ctx, c.chatroomID, messageID, cmd.StockCode, c.username)
+	case "hello":
+		err = c.publisher.PublishHelloCommand(ctx, c.chatroomID, messageID, c.username)
+	case "giphy":
+		err = c.publisher.PublishGiphyCommand(ctx, c.chatroomID, messageID, cmd.Query, c.username)
+	default:
+		slog.Warn("unknown command type",
+			slog.String("type", cmd.Type),
+			slog.String("user", c.username))
+		return
+	}
+
+	if err != nil {
+		observability.FromContext(ctx).Error("error publishing command",
+			slog.String("error", err.Error()),
+			slog.String("type", cmd.Type),
+			slog.String("user", c.username))
+
+		if errors.Is(err, domain.ErrBotUnavailable) {
+			c.sendError(i18n.ErrorBotUnavailable, clientMsgID)
+		} else {
+			c.sendError(i18n.ErrorCommandFailed, clientMsgID)
+		}
+	}
+}
+
+// reply queues data for this client only. It is dropped if the hub already
+// closed the send channel or the buffer is full.
+func (c *Client) reply(data []byte) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if c.sendClosed.Load() {
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+		slog.Warn("send buffer full, dropping reply",
+			slog.String("user", c.username),
+			slog.String("chatroom", c.chatroomID))
+	}
+}
+
+// fetchSince replies with the messages posted after sinceID so a reconnecting
+// client can fill the gap without a REST round trip. Messages are oldest
+// first; HasMore tells the client to ask again from the last one.
+func (c *Client) fetchSince(sinceID string, limit int) {
+	ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
+	defer cancel()
+
+	messages, hasMore, err := c.chatService.GetMessagesSince(ctx, c.chatroomID, sinceID, limit)
+	if err != nil {
+		slog.Warn("failed to fetch messages since",
+			slog.String("error", err.Error()),
+			slog.String("since_id", sinceID),
+			slog.String("user", c.username),
+			slog.String("chatroom_id", c.chatroomID))
+		c.sendError(i18n.ErrorFetchFailed, "")
+		return
+	}
+
+	reply := ServerMessage{
+		Type:     "messages_since",
+		Messages: make([]ServerMessage, 0, len(messages)),
+		HasMore:  hasMore,
+	}
+	for _, msg := range messages {
+		reply.Messages = append(reply.Messages, ServerMessage{
+			Type:            "chat_message",
+			ID:              msg.ID,
+			UserID:          msg.UserID,
+			Username:        msg.Username,
+			Content:         msg.Content,
+			IsBot:           msg.IsBot,
+			CreatedAt:       &msg.CreatedAt,
+			ForwardedFrom:   msg.ForwardedFrom,
+			Quote:           msg.Quote,
+			ParentMessageID: msg.ParentMessageID,
+			HTML:            msg.HTML,
+			Event:           msg.Event,
+		})
+	}
+
+	data, err := json.Marshal(reply)
+	if err != nil {
+		slog.Error("failed to marshal messages since",
+			slog.String("error", err.Error()))
+		return
+	}
+	c.reply(data)
+}
+
+// SendWelcome sends the chatroom's welcome message to this client only
+func (c *Client) SendWelcome(message string) {
+	data, err := json.Marshal(ServerMessage{
+		Type:    "welcome",
+		Message: message,
+	})
+	if err != nil {
+		slog.Error("failed to marshal welcome message",
+			slog.String("error", err.Error()))
+		return
+	}
+	c.reply(data)
+}
+
+// sendAck acknowledges a message or command to this client only. messageID
+// is empty for commands, which are not persisted.
+func (c *Client) sendAck(messageID, clientMsgID string) {
+	data, err := json.Marshal(ServerMessage{
+		Type:        "message_ack",
+		ID:          messageID,
+		ClientMsgID: clientMsgID,
+	})
+	if err != nil {
+		slog.Error("failed to marshal message ack",
+			slog.String("error", err.Error()))
+		return
+	}
+	c.reply(data)
+}
+
+// rememberAck records an acknowledged client_msg_id, evicting the oldest once
+// recentAckLimit is reached
+func (c *Client) rememberAck(clientMsgID, messageID string) {
+	if clientMsgID == "" {
+		return
+	}
+	if len(c.ackOrder) >= recentAckLimit {
+		delete(c.recentAcks, c.ackOrder[0])
+		c.ackOrder = c.ackOrder[1:]
+	}
+	c.recentAcks[clientMsgID] = messageID
+	c.ackOrder = append(c.ackOrder, clientMsgID)
+}
+
+// sendError sends the error message for key, translated to the client's
+// locale, to this client only. clientMsgID identifies the failed message, if
+// any.
+func (c *Client) sendError(key, clientMsgID string) {
+	data, err := json.Marshal(ServerMessage{
+		Type:        "error",
+		Message:     i18n.T(c.locale, key),
+		ClientMsgID: clientMsgID,
+	})
+	if err != nil {
+		slog.Error("failed to marshal error message",
+			slog.String("error", err.Error()))
+		return
+	}
+	c.reply(data)
+}
+
+// malformedFrame answers a malformed frame with an error of code, or closes
+// the connection with a policy violation once it spent the hub's malformed
+// frame budget. It returns false when the connection was closed.
+func (c *Client) malformedFrame(code, key, clientMsgID string) bool {
+	observability.WebSocketMalformedFramesTotal.WithLabelValues(code).Inc()
+	c.malformedFrames++
+
+	if budget := c.hub.malformedFrameBudget; budget > 0 && c.malformedFrames > budget {
+		slog.Warn("closing connection after too many malformed frames",
+			slog.Int("malformed_frames", c.malformedFrames),
+			slog.String("user", c.username),
+			slog.String("chatroom_id", c.chatroomID))
+		observability.WebSocketMalformedFrameClosesTotal.Inc()
+		_ = c.writeMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "too many malformed frames"))
+		return false
+	}
+
+	data, err := json.Marshal(ServerMessage{
+		Type:        "error",
+		Code:        code,
+		Message:     i18n.T(c.locale, key),
+		ClientMsgID: clientMsgID,
+	})
+	if err != nil {
+		slog.Error("failed to marshal error message",
+			slog.String("error", err.Error()))
+		return true
+	}
+	c.reply(data)
+	return true
+}
+
+// sendRecovered tells a degraded client it caught up with its send buffer
+func (c *Client) sendRecovered() {
+	slog.Info("slow client caught up",
+		slog.String("user", c.username),
+		slog.String("chatroom_id", c.chatroomID))
+
+	data, err := json.Marshal(ServerMessage{Type: "connection_recovered"})
+	if err != nil {
+		slog.Error("failed to marshal connection recovered",
+			slog.String("error", err.Error()))
+		return
+	}
+	c.reply(data)
+}
+
+// systemMessage renders a user_joined or user_left event for this client's
+// user, with its Message translated to each recipient's locale
+func (c *Client) systemMessage(eventType, key string) func(locale string) []byte {
+	return func(locale string) []byte {
+		data, err := json.Marshal(ServerMessage{
+			Type:     eventType,
+			Username: c.username,
+			Message:  i18n.T(locale, key, c.username),
+		})
+		if err != nil {
+			slog.Error("failed to marshal system message",
+				slog.String("error", err.Error()),
+				slog.String("type", eventType),
+				slog.String("username", c.username))
+			return nil
+		}
+		return data
 	}
 }
 
@@ -252,7 +711,7 @@ func (c *Client) broadcastMessageAsync(chatroomID string, data []byte, messageID
 	c.hub.pendingBroadcasts.Add(1)
 	defer c.hub.pendingBroadcasts.Done()
 
-	if err := c.hub.Broadcast(chatroomID, data); err != nil {
+	if err := c.hub.BroadcastFrom(chatroomID, c.userID, data); err != nil {
 		slog.Warn("broadcast failed, message persisted in database",
 			slog.String("error", err.Error()),
 			slog.String("message_id", messageID),
@@ -275,13 +734,25 @@ func (c *Client) WritePump() {
 		case message, ok := <-c.send:
 			if !ok {
 				// Hub closed the channel
-				_ = c.writeMessage(websocket.CloseMessage, []byte{})
+				closeFrame := c.closeFrame
+				if closeFrame == nil {
+					closeFrame = []byte{}
+				}
+				_ = c.writeMessage(websocket.CloseMessage, closeFrame)
 				return
 			}
 
+			if frame := c.degradedFrame.Swap(nil); frame != nil {
+				if err := c.writeMessage(websocket.TextMessage, *frame); err != nil {
+					return
+				}
+			}
 			if err := c.writeMessage(websocket.TextMessage, message); err != nil {
 				return
 			}
+			if len(c.send) == 0 && c.degraded.CompareAndSwap(true, false) {
+				c.sendRecovered()
+			}
 
 		case <-ticker.C:
 			if err := c.writeMessage(websocket.PingMessage, nil); err != nil {
@@ -319,9 +790,26 @@ func (c *Client) closeConnection() {
 	}
 }
 
+// setCloseFrame sets the close code and reason sent once the hub closes the
+// send channel. Only called from the hub's Run loop before closeSendOnce.
+func (c *Client) setCloseFrame(code int, reason string) {
+	c.closeFrame = websocket.FormatCloseMessage(code, reason)
+}
+
+// Reject closes a connection that the hub refused to register with code and
+// reason. The pumps must not have been started.
+func (c *Client) Reject(code int, reason string) {
+	_ = c.writeMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+	c.closeConnection()
+	c.ctxCancel()
+}
+
 // closeSendOnce safely closes the send channel exactly once.
 // Uses atomic bool to prevent double-close panic.
 func (c *Client) closeSendOnce() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
 	if c.sendClosed.CompareAndSwap(false, true) {
 		close(c.send)
 	}
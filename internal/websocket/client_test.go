@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +12,8 @@ import (
 	"testing"
 	"time"
 
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/i18n"
 	"jobsity-chat/internal/service"
 	"jobsity-chat/internal/testutil"
 
@@ -380,6 +383,24 @@ func TestClient_SendChannel_Buffered(t *testing.T) {
 	}
 }
 
+func TestClient_SendWelcome(t *testing.T) {
+	hub := NewHub()
+	chatService := service.NewChatService(testutil.NewMockMessageRepository(), testutil.NewMockChatroomRepository())
+	client := NewClient(context.Background(), hub, nil, "user-123", "testuser", "room-1", chatService, testutil.NewMockMessagePublisher())
+
+	client.SendWelcome("Please keep it on topic")
+
+	select {
+	case data := <-client.send:
+		var msg ServerMessage
+		testutil.AssertNoError(t, json.Unmarshal(data, &msg))
+		testutil.AssertEqual(t, msg.Type, "welcome")
+		testutil.AssertEqual(t, msg.Message, "Please keep it on topic")
+	default:
+		t.Fatal("expected a welcome frame")
+	}
+}
+
 func TestClient_ContextCancellation(t *testing.T) {
 	hub := NewHub()
 	ctx, cancel := context.WithCancel(context.Background())
@@ -486,6 +507,7 @@ func TestClient_StockCommand_Published(t *testing.T) {
 	messageRepo := testutil.NewMockMessageRepository()
 
 	// Add membership so message can be sent
+	chatroomRepo.Chatrooms["room-1"] = &domain.Chatroom{ID: "room-1", Name: "General"}
 	chatroomRepo.Members = map[string]map[string]bool{
 		"room-1": {"user-123": true},
 	}
@@ -549,10 +571,651 @@ func TestClient_StockCommand_Published(t *testing.T) {
 	// Check if stock command was published
 	calls := publisher.GetStockCommandCalls()
 	if len(calls) > 0 {
+		// The command is stored, and the bot's answer replies to it
+		testutil.AssertLen(t, messageRepo.Messages, 1)
+		testutil.AssertEqual(t, calls[0].MessageID, messageRepo.Messages[0].ID)
 		testutil.AssertEqual(t, calls[0].StockCode, "AAPL.US")
 		testutil.AssertEqual(t, calls[0].ChatroomID, "room-1")
 		testutil.AssertEqual(t, calls[0].RequestedBy, "testuser")
+		testutil.AssertEqual(t, calls[0].RequestedByID, "user-123")
+	}
+}
+
+func TestClient_MessageQuotaExceeded(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["room-1"] = &domain.Chatroom{ID: "room-1", Name: "General"}
+	chatroomRepo.Members = map[string]map[string]bool{
+		"room-1": {"user-123": true},
+	}
+	messageRepo := testutil.NewMockMessageRepository()
+	quotaRepo := testutil.NewMockQuotaRepository()
+	quotaRepo.MessageCounts["room-1"] = 5
+	quotas := service.NewQuotaService(quotaRepo, domain.QuotaLimits{MaxMessagesPerRoomPerDay: 5})
+	chatService := service.NewChatServiceWithQuotas(messageRepo, chatroomRepo, quotas)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		data, _ := json.Marshal(ClientMessage{Type: "chat_message", Content: "hello"})
+		conn.WriteMessage(websocket.TextMessage, data)
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:], nil)
+	testutil.AssertNoError(t, err)
+	defer conn.Close()
+
+	client := NewClient(context.Background(), NewHub(), conn, "user-123", "testuser", "room-1", chatService, testutil.NewMockMessagePublisher())
+	go client.ReadPump()
+
+	select {
+	case data := <-client.send:
+		var msg ServerMessage
+		testutil.AssertNoError(t, json.Unmarshal(data, &msg))
+		testutil.AssertEqual(t, msg.Type, "error")
+		testutil.AssertContains(t, msg.Message, "daily message limit")
+	case <-time.After(time.Second):
+		t.Fatal("expected an error message")
+	}
+	testutil.AssertLen(t, messageRepo.Messages, 0)
+}
+
+func TestClient_MessageAckEchoesClientMsgID(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["room-1"] = &domain.Chatroom{ID: "room-1", Name: "General"}
+	chatroomRepo.Members = map[string]map[string]bool{
+		"room-1": {"user-123": true},
+	}
+	messageRepo := testutil.NewMockMessageRepository()
+	chatService := service.NewChatService(messageRepo, chatroomRepo)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// The second frame is a retry of the first
+		data, _ := json.Marshal(ClientMessage{Type: "chat_message", Content: "hello", ClientMsgID: "c-1"})
+		conn.WriteMessage(websocket.TextMessage, data)
+		conn.WriteMessage(websocket.TextMessage, data)
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:], nil)
+	testutil.AssertNoError(t, err)
+	defer conn.Close()
+
+	hub := NewHub()
+	client := NewClient(context.Background(), hub, conn, "user-123", "testuser", "room-1", chatService, testutil.NewMockMessagePublisher())
+	go client.ReadPump()
+
+	var acks []ServerMessage
+	for len(acks) < 2 {
+		select {
+		case data := <-client.send:
+			var msg ServerMessage
+			testutil.AssertNoError(t, json.Unmarshal(data, &msg))
+			acks = append(acks, msg)
+		case <-time.After(time.Second):
+			t.Fatalf("expected two acks, got %d", len(acks))
+		}
+	}
+
+	for _, ack := range acks {
+		testutil.AssertEqual(t, ack.Type, "message_ack")
+		testutil.AssertEqual(t, ack.ClientMsgID, "c-1")
+	}
+	testutil.AssertEqual(t, acks[1].ID, acks[0].ID)
+	testutil.AssertLen(t, messageRepo.Messages, 1)
+
+	// The chat_message broadcast echoes the client_msg_id
+	for {
+		select {
+		case broadcast := <-hub.broadcast:
+			var msg ServerMessage
+			if broadcast.Message == nil {
+				continue // localized user_joined
+			}
+			testutil.AssertNoError(t, json.Unmarshal(broadcast.Message, &msg))
+			testutil.AssertEqual(t, msg.ID, acks[0].ID)
+			testutil.AssertEqual(t, msg.ClientMsgID, "c-1")
+			return
+		case <-time.After(time.Second):
+			t.Fatal("expected a chat_message broadcast")
+		}
+	}
+}
+
+func TestClient_RetryAfterReconnectIsAckedOnce(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["room-1"] = &domain.Chatroom{ID: "room-1", Name: "General"}
+	chatroomRepo.Members = map[string]map[string]bool{
+		"room-1": {"user-123": true},
+	}
+	// Stored before the connection dropped, without the ack reaching the
+	// client
+	messageRepo := testutil.NewMockMessageRepository()
+	messageRepo.Messages = append(messageRepo.Messages, &domain.Message{
+		ID: "msg-1", ChatroomID: "room-1", UserID: "user-123", Content: "hello", ClientMsgID: "c-1", CreatedAt: time.Now(),
+	})
+	chatService := service.NewChatService(messageRepo, chatroomRepo)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		data, _ := json.Marshal(ClientMessage{Type: "chat_message", Content: "hello", ClientMsgID: "c-1"})
+		conn.WriteMessage(websocket.TextMessage, data)
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:], nil)
+	testutil.AssertNoError(t, err)
+	defer conn.Close()
+
+	hub := NewHub()
+	client := NewClient(context.Background(), hub, conn, "user-123", "testuser", "room-1", chatService, testutil.NewMockMessagePublisher())
+	go client.ReadPump()
+
+	select {
+	case data := <-client.send:
+		var ack ServerMessage
+		testutil.AssertNoError(t, json.Unmarshal(data, &ack))
+		testutil.AssertEqual(t, ack.Type, "message_ack")
+		testutil.AssertEqual(t, ack.ID, "msg-1")
+		testutil.AssertEqual(t, ack.ClientMsgID, "c-1")
+	case <-time.After(time.Second):
+		t.Fatal("expected an ack")
+	}
+	testutil.AssertLen(t, messageRepo.Messages, 1)
+
+	// The original was already broadcast
+	for {
+		select {
+		case broadcast := <-hub.broadcast:
+			if broadcast.Message != nil {
+				t.Fatal("expected no chat_message broadcast for a retry")
+			}
+		case <-time.After(300 * time.Millisecond):
+			return
+		}
+	}
+}
+
+func TestClient_ClientMsgIDReusedInAnotherRoomIsStored(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["room-1"] = &domain.Chatroom{ID: "room-1", Name: "General"}
+	chatroomRepo.Chatrooms["room-2"] = &domain.Chatroom{ID: "room-2", Name: "Random"}
+	chatroomRepo.Members = map[string]map[string]bool{
+		"room-1": {"user-123": true},
+		"room-2": {"user-123": true},
+	}
+	messageRepo := testutil.NewMockMessageRepository()
+	messageRepo.Messages = append(messageRepo.Messages, &domain.Message{
+		ID: "msg-1", ChatroomID: "room-1", UserID: "user-123", Content: "hello", ClientMsgID: "c-1", CreatedAt: time.Now(),
+	})
+	chatService := service.NewChatService(messageRepo, chatroomRepo)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		data, _ := json.Marshal(ClientMessage{Type: "chat_message", Content: "hi there", ClientMsgID: "c-1"})
+		conn.WriteMessage(websocket.TextMessage, data)
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:], nil)
+	testutil.AssertNoError(t, err)
+	defer conn.Close()
+
+	client := NewClient(context.Background(), NewHub(), conn, "user-123", "testuser", "room-2", chatService, testutil.NewMockMessagePublisher())
+	go client.ReadPump()
+
+	select {
+	case data := <-client.send:
+		var ack ServerMessage
+		testutil.AssertNoError(t, json.Unmarshal(data, &ack))
+		testutil.AssertEqual(t, ack.Type, "message_ack")
+		testutil.AssertEqual(t, ack.ClientMsgID, "c-1")
+		testutil.AssertTrue(t, ack.ID != "msg-1", "ack should not carry the other room's message")
+	case <-time.After(time.Second):
+		t.Fatal("expected an ack")
+	}
+	testutil.AssertLen(t, messageRepo.Messages, 2)
+	testutil.AssertEqual(t, messageRepo.Messages[1].ChatroomID, "room-2")
+}
+
+func TestClient_EncryptedRoomStoresCommandsAsCiphertext(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["room-1"] = &domain.Chatroom{ID: "room-1", Name: "General"}
+	chatroomRepo.Members = map[string]map[string]bool{
+		"room-1": {"user-123": true},
+	}
+	messageRepo := testutil.NewMockMessageRepository()
+	publisher := testutil.NewMockMessagePublisher()
+	chatService := service.NewChatService(messageRepo, chatroomRepo)
+
+	// Ciphertext may look like anything, a command or a :shortcode: included
+	content := "/stock=AAPL.US :smile:"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		data, _ := json.Marshal(ClientMessage{Type: "chat_message", Content: content})
+		conn.WriteMessage(websocket.TextMessage, data)
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:], nil)
+	testutil.AssertNoError(t, err)
+	defer conn.Close()
+
+	client := NewClient(context.Background(), NewHub(), conn, "user-123", "testuser", "room-1", chatService, publisher)
+	client.SetEncrypted(true)
+	go client.ReadPump()
+
+	select {
+	case data := <-client.send:
+		var msg ServerMessage
+		testutil.AssertNoError(t, json.Unmarshal(data, &msg))
+		testutil.AssertEqual(t, msg.Type, "message_ack")
+	case <-time.After(time.Second):
+		t.Fatal("expected a message ack")
+	}
+	testutil.AssertLen(t, publisher.GetStockCommandCalls(), 0)
+	testutil.AssertLen(t, messageRepo.Messages, 1)
+	testutil.AssertEqual(t, messageRepo.Messages[0].Content, content)
+	testutil.AssertTrue(t, messageRepo.Messages[0].Encrypted, "message should be marked encrypted")
+}
+
+func TestClient_SendFailureEchoesClientMsgID(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["room-1"] = &domain.Chatroom{ID: "room-1", Name: "General"}
+	chatroomRepo.Members = map[string]map[string]bool{
+		"room-1": {"user-123": true},
+	}
+	chatService := service.NewChatService(testutil.NewMockMessageRepository(), chatroomRepo)
+	publisher := testutil.NewMockMessagePublisher()
+	publisher.PublishStockCommandFunc = func(ctx context.Context, chatroomID, messageID, stockCode, requestedBy string) error {
+		return errors.New("broker unavailable")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		data, _ := json.Marshal(ClientMessage{Type: "chat_message", Content: "/stock=AAPL.US", ClientMsgID: "c-2"})
+		conn.WriteMessage(websocket.TextMessage, data)
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:], nil)
+	testutil.AssertNoError(t, err)
+	defer conn.Close()
+
+	client := NewClient(context.Background(), NewHub(), conn, "user-123", "testuser", "room-1", chatService, publisher)
+	go client.ReadPump()
+
+	// The command message is delivered before it reaches the bot
+	select {
+	case data := <-client.send:
+		var msg ServerMessage
+		testutil.AssertNoError(t, json.Unmarshal(data, &msg))
+		testutil.AssertEqual(t, msg.Type, "message_ack")
+	case <-time.After(time.Second):
+		t.Fatal("expected a message ack")
+	}
+
+	select {
+	case data := <-client.send:
+		var msg ServerMessage
+		testutil.AssertNoError(t, json.Unmarshal(data, &msg))
+		testutil.AssertEqual(t, msg.Type, "error")
+		testutil.AssertEqual(t, msg.ClientMsgID, "c-2")
+	case <-time.After(time.Second):
+		t.Fatal("expected an error message")
+	}
+}
+
+func TestClient_CommandWithoutBotInRoom(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["room-1"] = &domain.Chatroom{ID: "room-1", Name: "General"}
+	chatroomRepo.Members = map[string]map[string]bool{
+		"room-1": {"user-123": true},
+	}
+	chatService := service.NewChatService(testutil.NewMockMessageRepository(), chatroomRepo)
+	publisher := testutil.NewMockMessagePublisher()
+	publisher.PublishGiphyCommandFunc = func(ctx context.Context, chatroomID, messageID, query, requestedBy string) error {
+		return domain.ErrBotUnavailable
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		data, _ := json.Marshal(ClientMessage{Type: "chat_message", Content: "/giphy cats", ClientMsgID: "c-3"})
+		conn.WriteMessage(websocket.TextMessage, data)
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:], nil)
+	testutil.AssertNoError(t, err)
+	defer conn.Close()
+
+	client := NewClient(context.Background(), NewHub(), conn, "user-123", "testuser", "room-1", chatService, publisher)
+	go client.ReadPump()
+
+	// The command message is delivered before it reaches the bot
+	select {
+	case data := <-client.send:
+		var msg ServerMessage
+		testutil.AssertNoError(t, json.Unmarshal(data, &msg))
+		testutil.AssertEqual(t, msg.Type, "message_ack")
+	case <-time.After(time.Second):
+		t.Fatal("expected a message ack")
+	}
+
+	select {
+	case data := <-client.send:
+		var msg ServerMessage
+		testutil.AssertNoError(t, json.Unmarshal(data, &msg))
+		testutil.AssertEqual(t, msg.Type, "error")
+		testutil.AssertEqual(t, msg.Message, i18n.T(i18n.English, i18n.ErrorBotUnavailable))
+	case <-time.After(time.Second):
+		t.Fatal("expected an error message")
+	}
+}
+
+func TestClient_ReadOnlyRejectsMessages(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Members = map[string]map[string]bool{
+		"room-1": {"guest-1": true},
+	}
+	messageRepo := testutil.NewMockMessageRepository()
+	chatService := service.NewChatService(messageRepo, chatroomRepo)
+	publisher := testutil.NewMockMessagePublisher()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for _, content := range []string{"hello", "/stock=AAPL.US"} {
+			data, _ := json.Marshal(ClientMessage{Type: "chat_message", Content: content, ClientMsgID: content})
+			conn.WriteMessage(websocket.TextMessage, data)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:], nil)
+	testutil.AssertNoError(t, err)
+	defer conn.Close()
+
+	client := NewClient(context.Background(), NewHub(), conn, "guest-1", "guest_1", "room-1", chatService, publisher)
+	client.SetReadOnly(true)
+	go client.ReadPump()
+
+	for _, want := range []string{"hello", "/stock=AAPL.US"} {
+		select {
+		case data := <-client.send:
+			var msg ServerMessage
+			testutil.AssertNoError(t, json.Unmarshal(data, &msg))
+			testutil.AssertEqual(t, msg.Type, "error")
+			testutil.AssertEqual(t, msg.ClientMsgID, want)
+			testutil.AssertContains(t, msg.Message, "register to post")
+		case <-time.After(time.Second):
+			t.Fatal("expected an error message")
+		}
+	}
+	testutil.AssertLen(t, messageRepo.Messages, 0)
+	testutil.AssertLen(t, publisher.StockCommands, 0)
+}
+
+func TestClient_ReadOnlyChatroomRejectsMembers(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["room-1"] = &domain.Chatroom{ID: "room-1", Name: "News", CreatedBy: "owner-1"}
+	chatroomRepo.Members = map[string]map[string]bool{
+		"room-1": {"user-123": true},
+	}
+	chatroomRepo.Settings = map[string]*domain.ChatroomSettings{"room-1": {ReadOnly: true, Version: 2}}
+	messageRepo := testutil.NewMockMessageRepository()
+	chatService := service.NewChatService(messageRepo, chatroomRepo)
+	chatService.SetUserRepository(testutil.NewMockUserRepository())
+	publisher := testutil.NewMockMessagePublisher()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for _, content := range []string{"hello", "/stock=AAPL.US"} {
+			data, _ := json.Marshal(ClientMessage{Type: "chat_message", Content: content, ClientMsgID: content})
+			conn.WriteMessage(websocket.TextMessage, data)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:], nil)
+	testutil.AssertNoError(t, err)
+	defer conn.Close()
+
+	client := NewClient(context.Background(), NewHub(), conn, "user-123", "alice", "room-1", chatService, publisher)
+	go client.ReadPump()
+
+	for _, want := range []string{"hello", "/stock=AAPL.US"} {
+		select {
+		case data := <-client.send:
+			var msg ServerMessage
+			testutil.AssertNoError(t, json.Unmarshal(data, &msg))
+			testutil.AssertEqual(t, msg.Type, "error")
+			testutil.AssertEqual(t, msg.ClientMsgID, want)
+			testutil.AssertContains(t, msg.Message, "owner and moderators")
+		case <-time.After(time.Second):
+			t.Fatal("expected an error message")
+		}
+	}
+	testutil.AssertLen(t, messageRepo.Messages, 0)
+	testutil.AssertLen(t, publisher.StockCommands, 0)
+}
+
+func TestClient_QuoteReply(t *testing.T) {
+	const (
+		quotedID = "00000000-0000-0000-0000-000000000301"
+		otherID  = "00000000-0000-0000-0000-000000000302"
+	)
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["room-1"] = &domain.Chatroom{ID: "room-1", Name: "General", CreatedBy: "owner-1"}
+	chatroomRepo.Members = map[string]map[string]bool{
+		"room-1": {"user-123": true},
+	}
+	messageRepo := testutil.NewMockMessageRepository()
+	messageRepo.Messages = []*domain.Message{
+		{ID: quotedID, ChatroomID: "room-1", UserID: "user-456", Username: "bob", Content: "lunch?", CreatedAt: time.Now()},
+		{ID: otherID, ChatroomID: "room-2", UserID: "user-456", Username: "bob", Content: "elsewhere", CreatedAt: time.Now()},
+	}
+	chatService := service.NewChatService(messageRepo, chatroomRepo)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for _, quoted := range []string{otherID, quotedID} {
+			data, _ := json.Marshal(ClientMessage{Type: "chat_message", Content: "sure", ClientMsgID: quoted, QuotedMessageID: quoted})
+			conn.WriteMessage(websocket.TextMessage, data)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:], nil)
+	testutil.AssertNoError(t, err)
+	defer conn.Close()
+
+	client := NewClient(context.Background(), NewHub(), conn, "user-123", "alice", "room-1", chatService, testutil.NewMockMessagePublisher())
+	go client.ReadPump()
+
+	for _, want := range []string{"error", "message_ack"} {
+		select {
+		case data := <-client.send:
+			var msg ServerMessage
+			testutil.AssertNoError(t, json.Unmarshal(data, &msg))
+			testutil.AssertEqual(t, msg.Type, want)
+			if want == "error" {
+				testutil.AssertContains(t, msg.Message, "quoted message")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected a %s frame", want)
+		}
+	}
+
+	testutil.AssertLen(t, messageRepo.Messages, 3)
+	reply := messageRepo.Messages[2]
+	testutil.AssertNotNil(t, reply.Quote)
+	testutil.AssertEqual(t, reply.Quote.MessageID, quotedID)
+	testutil.AssertEqual(t, reply.Quote.Username, "bob")
+	testutil.AssertEqual(t, reply.Quote.Content, "lunch?")
+}
+
+func TestClient_FetchSince(t *testing.T) {
+	messageRepo := testutil.NewMockMessageRepository()
+	ids := []string{
+		"00000000-0000-0000-0000-000000000201",
+		"00000000-0000-0000-0000-000000000202",
+		"00000000-0000-0000-0000-000000000203",
+	}
+	for _, id := range ids {
+		messageRepo.Messages = append(messageRepo.Messages, &domain.Message{
+			ID: id, ChatroomID: "room-1", UserID: "user-456", Username: "bob", Content: "*hi* " + id, CreatedAt: time.Now(),
+		})
+	}
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["room-1"] = &domain.Chatroom{ID: "room-1", Name: "General"}
+	chatroomRepo.Settings["room-1"] = &domain.ChatroomSettings{Markdown: true, Version: 1}
+	chatService := service.NewChatService(messageRepo, chatroomRepo)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		data, _ := json.Marshal(ClientMessage{Type: "fetch_since", SinceID: ids[0], Limit: 1})
+		conn.WriteMessage(websocket.TextMessage, data)
+		data, _ = json.Marshal(ClientMessage{Type: "fetch_since", SinceID: "bogus"})
+		conn.WriteMessage(websocket.TextMessage, data)
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:], nil)
+	testutil.AssertNoError(t, err)
+	defer conn.Close()
+
+	client := NewClient(context.Background(), NewHub(), conn, "user-123", "testuser", "room-1", chatService, testutil.NewMockMessagePublisher())
+	go client.ReadPump()
+
+	var msg ServerMessage
+	select {
+	case data := <-client.send:
+		testutil.AssertNoError(t, json.Unmarshal(data, &msg))
+	case <-time.After(time.Second):
+		t.Fatal("expected a messages_since reply")
+	}
+	testutil.AssertEqual(t, msg.Type, "messages_since")
+	testutil.AssertLen(t, msg.Messages, 1)
+	testutil.AssertEqual(t, msg.Messages[0].ID, ids[1])
+	testutil.AssertEqual(t, msg.Messages[0].Type, "chat_message")
+	testutil.AssertEqual(t, msg.Messages[0].Username, "bob")
+	testutil.AssertEqual(t, msg.Messages[0].HTML, "<em>hi</em> "+ids[1])
+	testutil.AssertTrue(t, msg.HasMore, "expected has_more with a message left")
+
+	select {
+	case data := <-client.send:
+		var errMsg ServerMessage
+		testutil.AssertNoError(t, json.Unmarshal(data, &errMsg))
+		testutil.AssertEqual(t, errMsg.Type, "error")
+	case <-time.After(time.Second):
+		t.Fatal("expected an error for a malformed since_id")
+	}
+	testutil.AssertLen(t, messageRepo.Messages, len(ids))
+}
+
+func TestClient_RememberAckEvictsOldest(t *testing.T) {
+	client := NewClient(context.Background(), NewHub(), nil, "user-123", "testuser", "room-1", nil, nil)
+
+	client.rememberAck("", "ignored")
+	for i := 0; i <= recentAckLimit; i++ {
+		client.rememberAck(fmt.Sprintf("c-%d", i), fmt.Sprintf("m-%d", i))
+	}
+
+	testutil.AssertLen(t, client.ackOrder, recentAckLimit)
+	if _, ok := client.recentAcks["c-0"]; ok {
+		t.Error("expected the oldest client_msg_id to be evicted")
 	}
+	testutil.AssertEqual(t, client.recentAcks[fmt.Sprintf("c-%d", recentAckLimit)], fmt.Sprintf("m-%d", recentAckLimit))
+}
+
+func TestClient_SystemMessageLocalized(t *testing.T) {
+	client := NewClient(i18n.WithLocale(context.Background(), i18n.Spanish), NewHub(), nil, "user-123", "ana", "room-1", nil, nil)
+	testutil.AssertEqual(t, client.locale, i18n.Spanish)
+
+	render := client.systemMessage("user_joined", i18n.SystemUserJoined)
+
+	var msg ServerMessage
+	testutil.AssertNoError(t, json.Unmarshal(render(i18n.Portuguese), &msg))
+	testutil.AssertEqual(t, msg.Type, "user_joined")
+	testutil.AssertEqual(t, msg.Username, "ana")
+	testutil.AssertEqual(t, msg.Message, "ana entrou na sala")
 }
 
 // Test message type constants
@@ -618,3 +1281,195 @@ func BenchmarkNewClient(b *testing.B) {
 		_ = NewClient(ctx, hub, conn, "user-123", "testuser", "room-1", chatService, publisher)
 	}
 }
+
+func TestClient_UnknownFrameType(t *testing.T) {
+	messageRepo := testutil.NewMockMessageRepository()
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["room-1"] = &domain.Chatroom{ID: "room-1", Name: "General"}
+	chatroomRepo.Members = map[string]map[string]bool{
+		"room-1": {"user-123": true},
+	}
+	chatService := service.NewChatService(messageRepo, chatroomRepo)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		data, _ := json.Marshal(ClientMessage{Type: "typing", Content: "hello", ClientMsgID: "c-1"})
+		conn.WriteMessage(websocket.TextMessage, data)
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:], nil)
+	testutil.AssertNoError(t, err)
+	defer conn.Close()
+
+	client := NewClient(context.Background(), NewHub(), conn, "user-123", "testuser", "room-1", chatService, testutil.NewMockMessagePublisher())
+	testutil.AssertEqual(t, client.ProtocolVersion(), ProtocolVersion)
+	go client.ReadPump()
+
+	select {
+	case data := <-client.send:
+		var msg ServerMessage
+		testutil.AssertNoError(t, json.Unmarshal(data, &msg))
+		testutil.AssertEqual(t, msg.Type, "error")
+		testutil.AssertEqual(t, msg.ClientMsgID, "c-1")
+		testutil.AssertEqual(t, msg.Code, ErrorCodeUnknownFrame)
+		testutil.AssertEqual(t, msg.Message, i18n.T(i18n.English, i18n.ErrorUnknownFrame))
+	case <-time.After(time.Second):
+		t.Fatal("expected an error for an unknown frame type")
+	}
+	testutil.AssertLen(t, messageRepo.Messages, 0)
+}
+
+func TestClient_MalformedFrameBudget(t *testing.T) {
+	closed := make(chan *websocket.CloseError, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for _, frame := range []string{"not json", `{"type":"typing"}`, "[1,2"} {
+			conn.WriteMessage(websocket.TextMessage, []byte(frame))
+		}
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, _, err = conn.ReadMessage()
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) {
+			closed <- closeErr
+		}
+		close(closed)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:], nil)
+	testutil.AssertNoError(t, err)
+	defer conn.Close()
+
+	hub := NewHub()
+	hub.SetMalformedFrameBudget(2)
+	client := NewClient(context.Background(), hub, conn, "user-123", "testuser", "room-1", nil, testutil.NewMockMessagePublisher())
+	go client.ReadPump()
+
+	// The frames within the budget are answered with errors
+	for _, want := range []string{ErrorCodeMalformedFrame, ErrorCodeUnknownFrame} {
+		select {
+		case data := <-client.send:
+			var msg ServerMessage
+			testutil.AssertNoError(t, json.Unmarshal(data, &msg))
+			testutil.AssertEqual(t, msg.Type, "error")
+			testutil.AssertEqual(t, msg.Code, want)
+		case <-time.After(time.Second):
+			t.Fatalf("expected a %s error", want)
+		}
+	}
+
+	// The next one closes the connection
+	closeErr, ok := <-closed
+	testutil.AssertTrue(t, ok, "expected a close frame")
+	testutil.AssertEqual(t, closeErr.Code, websocket.ClosePolicyViolation)
+	testutil.AssertEqual(t, closeErr.Text, "too many malformed frames")
+}
+
+func TestClient_DegradedConnectionRecovers(t *testing.T) {
+	frames := make(chan ServerMessage, 3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for range 3 {
+			var msg ServerMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				break
+			}
+			frames <- msg
+		}
+		close(frames)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:], nil)
+	testutil.AssertNoError(t, err)
+
+	client := NewClient(context.Background(), NewHub(), conn, "user-123", "testuser", "room-1", nil, testutil.NewMockMessagePublisher())
+	defer client.closeConnection()
+
+	// The hub found the buffer backed up behind this message
+	degraded, _ := json.Marshal(ServerMessage{Type: "connection_degraded", Queued: 200, Capacity: sendBufferSize})
+	client.degraded.Store(true)
+	client.degradedFrame.Store(&degraded)
+	client.send <- []byte(`{"type":"chat_message","content":"late"}`)
+	go client.WritePump()
+
+	// The warning overtakes the backlog, and recovery follows once it drains
+	var types []string
+	for msg := range frames {
+		types = append(types, msg.Type)
+	}
+	testutil.AssertLen(t, types, 3)
+	testutil.AssertEqual(t, types[0], "connection_degraded")
+	testutil.AssertEqual(t, types[1], "chat_message")
+	testutil.AssertEqual(t, types[2], "connection_recovered")
+	testutil.AssertFalse(t, client.degraded.Load(), "expected the client to have recovered")
+}
+
+func TestClient_DeactivatedUserIsDisconnected(t *testing.T) {
+	chatroomRepo := testutil.NewMockChatroomRepository()
+	chatroomRepo.Chatrooms["room-1"] = &domain.Chatroom{ID: "room-1", Name: "General"}
+	chatroomRepo.Members = map[string]map[string]bool{
+		"room-1": {"user-123": true},
+	}
+	deactivatedAt := time.Now()
+	userRepo := testutil.NewMockUserRepository()
+	userRepo.Users["user-123"] = &domain.User{ID: "user-123", Username: "testuser", DeactivatedAt: &deactivatedAt}
+	messageRepo := testutil.NewMockMessageRepository()
+	chatService := service.NewChatService(messageRepo, chatroomRepo)
+	chatService.SetUserRepository(userRepo)
+
+	closeCode := make(chan int, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		data, _ := json.Marshal(ClientMessage{Type: "chat_message", Content: "hello", ClientMsgID: "c-1"})
+		conn.WriteMessage(websocket.TextMessage, data)
+		_, _, err = conn.ReadMessage()
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) {
+			closeCode <- closeErr.Code
+		}
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:], nil)
+	testutil.AssertNoError(t, err)
+	defer conn.Close()
+
+	hub := NewHub()
+	client := NewClient(context.Background(), hub, conn, "user-123", "testuser", "room-1", chatService, testutil.NewMockMessagePublisher())
+	go client.ReadPump()
+
+	select {
+	case code := <-closeCode:
+		testutil.AssertEqual(t, code, CloseDisconnected)
+	case <-time.After(time.Second):
+		t.Fatal("expected the connection to be closed")
+	}
+	testutil.AssertLen(t, messageRepo.Messages, 0)
+}
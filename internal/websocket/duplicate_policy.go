@@ -0,0 +1,53 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DuplicatePolicy decides what happens when a user opens another connection
+// to a chatroom they are already connected to
+type DuplicatePolicy string
+
+const (
+	// DuplicateAllow keeps every connection (one per tab or device)
+	DuplicateAllow DuplicatePolicy = "allow"
+	// DuplicateReplaceOldest closes the user's existing connection in favour
+	// of the new one
+	DuplicateReplaceOldest DuplicatePolicy = "replace-oldest"
+	// DuplicateReject refuses the new connection
+	DuplicateReject DuplicatePolicy = "reject"
+)
+
+// Application close codes (4000-4999 are reserved for applications by RFC 6455)
+const (
+	// CloseReplaced is sent to a connection replaced by a newer one of the
+	// same user under DuplicateReplaceOldest
+	CloseReplaced = 4001
+	// CloseDuplicateConnection is sent to a connection refused under
+	// DuplicateReject
+	CloseDuplicateConnection = 4002
+	// CloseDisconnected is sent to connections an administrator closed, by
+	// default
+	CloseDisconnected = 4003
+	// CloseUsernameChanged is sent to the connections of a user who changed
+	// their username. Unlike the codes above, clients should reconnect right
+	// away to pick up the new name.
+	CloseUsernameChanged = 4004
+)
+
+var ErrDuplicateConnection = errors.New("user is already connected to this chatroom")
+
+// ParseDuplicatePolicy parses WS_DUPLICATE_CONNECTION_POLICY, defaulting to
+// DuplicateAllow
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	switch policy := DuplicatePolicy(strings.ToLower(strings.TrimSpace(s))); policy {
+	case "":
+		return DuplicateAllow, nil
+	case DuplicateAllow, DuplicateReplaceOldest, DuplicateReject:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown duplicate connection policy %q (want allow, replace-oldest or reject)", s)
+	}
+}
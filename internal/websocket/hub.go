@@ -3,18 +3,38 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"jobsity-chat/internal/domain"
 	"jobsity-chat/internal/observability"
 )
 
+// heartbeatInterval is how often the Run loop records that it is alive.
+const heartbeatInterval = 5 * time.Second
+
+// countUpdateInterval is how often pending user_count_update frames are
+// sent, so a burst of joins and leaves produces one update instead of one
+// per connection.
+const countUpdateInterval = 250 * time.Millisecond
+
 // BroadcastMessage represents a message to be sent to all clients in a chatroom.
 type BroadcastMessage struct {
 	ChatroomID string
 	Message    []byte
+	// Render, when set, replaces Message with a per-locale rendering so
+	// every client receives the message in its own language
+	Render func(locale string) []byte
+	// SenderID is the user who posted the message, if any. Messages from a
+	// user shadow-banned in the chatroom are only delivered to that user.
+	SenderID string
+	// RecipientID, when set, restricts delivery to that user's clients
+	RecipientID string
 }
 
 // Hub maintains the set of active clients and broadcasts messages to them.
@@ -35,16 +55,35 @@ type Hub struct {
 	// See: https://go.dev/wiki/CodeReviewComments#channel-size
 	broadcast chan *BroadcastMessage
 
-	// register channel for new client connections.
-	register chan *Client
+	// register channel for new client connections. The Run loop answers
+	// each registration on its result channel.
+	register chan registration
 
 	// unregister channel for client disconnections.
 	unregister chan *Client
 
-	// userCountUpdate triggers sending user count updates to all clients.
-	// Buffer of 10 prevents blocking on rapid connect/disconnect.
+	// disconnect channel for closing a user's connections on request. The
+	// Run loop answers with the number of connections closed.
+	disconnect chan disconnectRequest
+
+	// userCountUpdate marks the user counts as changed; they are sent on
+	// the next countUpdateInterval tick. Buffer of 10 prevents blocking on
+	// rapid connect/disconnect.
 	userCountUpdate chan struct{}
 
+	// countUpdateInterval overrides the package default; tests shorten it.
+	countUpdateInterval time.Duration
+
+	// lastCounts maps organization IDs to the room counts last sent to
+	// them. Only used in Run() loop.
+	lastCounts map[string]map[string]int
+
+	// startedAt is when the hub was created, for Stats
+	startedAt time.Time
+	// dropped counts messages that could not be queued or delivered
+	// because a queue or a client's send buffer was full
+	dropped atomic.Uint64
+
 	// done signals hub shutdown initiation.
 	// Checked by Broadcast() to prevent new messages during shutdown.
 	done chan struct{}
@@ -52,6 +91,49 @@ type Hub struct {
 	// pendingBroadcasts tracks background broadcast goroutines.
 	// Used to ensure graceful shutdown waits for all broadcasts to complete.
 	pendingBroadcasts sync.WaitGroup
+
+	// lastHeartbeat is the UnixNano time the Run loop last completed an
+	// iteration of its heartbeat tick. Zero when the loop is not running.
+	// A stale value means the loop is blocked or has exited.
+	lastHeartbeat atomic.Int64
+
+	// duplicatePolicy applies when a user connects to a chatroom they are
+	// already connected to. Set before Run.
+	duplicatePolicy DuplicatePolicy
+
+	// shadowBans maps chatroom IDs to the shadow-banned user IDs in them.
+	// Guarded by shadowMu since moderators change it from HTTP handlers.
+	shadowMu   sync.RWMutex
+	shadowBans map[string]map[string]bool
+
+	// awayAfter is how long a user's connections must all be inactive for
+	// them to be shown as away; zero disables it. Set before Run.
+	awayAfter time.Duration
+	// idle holds the connected users last announced as idle. Only used in
+	// Run() loop.
+	idle map[string]bool
+
+	// malformedFrameBudget is how many malformed frames a connection may
+	// send before it is closed; zero never closes it. Set before Run.
+	malformedFrameBudget int
+	// slowClientThreshold is how many frames may wait in a connection's send
+	// buffer before it is warned with connection_degraded; zero disables
+	// the warning. Set before Run.
+	slowClientThreshold int
+}
+
+type registration struct {
+	client *Client
+	result chan error
+}
+
+type disconnectRequest struct {
+	orgID      string
+	userID     string
+	chatroomID string
+	code       int
+	reason     string
+	result     chan int
 }
 
 // NewHub creates a new Hub instance.
@@ -59,32 +141,93 @@ func NewHub() *Hub {
 	return &Hub{
 		clients:         make(map[string]map[*Client]bool),
 		broadcast:       make(chan *BroadcastMessage, 1024),
-		register:        make(chan *Client),
+		register:        make(chan registration),
 		unregister:      make(chan *Client),
+		disconnect:      make(chan disconnectRequest),
 		userCountUpdate: make(chan struct{}, 10),
 		done:            make(chan struct{}),
+		lastCounts:      make(map[string]map[string]int),
+		startedAt:       time.Now(),
+		duplicatePolicy: DuplicateAllow,
+		shadowBans:      make(map[string]map[string]bool),
+		idle:            make(map[string]bool),
+
+		malformedFrameBudget: DefaultMalformedFrameBudget,
+		slowClientThreshold:  sendBufferSize * DefaultSlowClientThreshold / 100,
 	}
 }
 
+// SetDuplicatePolicy sets what happens when a user opens more than one
+// connection to the same chatroom. Must be called before Run.
+func (h *Hub) SetDuplicatePolicy(policy DuplicatePolicy) {
+	h.duplicatePolicy = policy
+}
+
+// SetAwayAfter sets how long a connected user must be inactive to be shown as
+// away; zero disables it. Must be called before Run.
+func (h *Hub) SetAwayAfter(d time.Duration) {
+	h.awayAfter = d
+}
+
+// SetMalformedFrameBudget sets how many malformed frames a connection may
+// send, each answered with an error, before it is closed; zero never closes
+// it. Must be called before Run.
+func (h *Hub) SetMalformedFrameBudget(n int) {
+	h.malformedFrameBudget = n
+}
+
+// SetSlowClientThreshold sets the percentage of its send buffer a
+// connection may fill before it is sent a connection_degraded frame, ahead
+// of being dropped once the buffer is full; zero disables the warning. Must
+// be called before Run.
+func (h *Hub) SetSlowClientThreshold(percent int) {
+	h.slowClientThreshold = sendBufferSize * percent / 100
+}
+
 // Run starts the hub's main event loop. It handles client registration,
 // unregistration, broadcasts, and user count updates.
 // All client map modifications happen here to avoid data races.
 func (h *Hub) Run(ctx context.Context) error {
 	defer h.shutdown()
 
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	interval := h.countUpdateInterval
+	if interval <= 0 {
+		interval = countUpdateInterval
+	}
+	countTicker := time.NewTicker(interval)
+	defer countTicker.Stop()
+	countsChanged := false
+
+	h.lastHeartbeat.Store(time.Now().UnixNano())
+	defer h.lastHeartbeat.Store(0)
+
 	for {
 		select {
 		case <-ctx.Done():
 			slog.Info("hub shutting down gracefully")
 			return ctx.Err()
 
-		case client := <-h.register:
+		case now := <-heartbeat.C:
+			h.checkAway(now)
+			h.lastHeartbeat.Store(now.UnixNano())
+
+		case reg := <-h.register:
+			client := reg.client
+			if err := h.applyDuplicatePolicy(client); err != nil {
+				reg.result <- err
+				continue
+			}
+
 			h.mutex.Lock()
 			if h.clients[client.chatroomID] == nil {
 				h.clients[client.chatroomID] = make(map[*Client]bool)
 			}
 			h.clients[client.chatroomID][client] = true
 			h.mutex.Unlock()
+			reg.result <- nil
 
 			observability.WebSocketConnectionsActive.WithLabelValues(client.chatroomID).Inc()
 			slog.Info("client registered",
@@ -104,8 +247,22 @@ func (h *Hub) Run(ctx context.Context) error {
 			default:
 			}
 
+		case req := <-h.disconnect:
+			req.result <- h.disconnectUser(req)
+
+			select {
+			case h.userCountUpdate <- struct{}{}:
+			default:
+			}
+
 		case <-h.userCountUpdate:
-			h.sendUserCountUpdate()
+			countsChanged = true
+
+		case <-countTicker.C:
+			if countsChanged {
+				h.sendUserCountUpdate()
+				countsChanged = false
+			}
 
 		case message := <-h.broadcast:
 			h.mutex.RLock()
@@ -113,12 +270,40 @@ func (h *Hub) Run(ctx context.Context) error {
 			h.mutex.RUnlock()
 
 			if ok {
+				shadowBanned := message.SenderID != "" && h.IsShadowBanned(message.ChatroomID, message.SenderID)
+				var rendered map[string][]byte
+				if message.Render != nil {
+					rendered = make(map[string][]byte)
+				}
 				var clientsToRemove []*Client
+				kind := "broadcast"
+				if message.RecipientID != "" {
+					kind = "direct"
+				}
 				for client := range clients {
+					if shadowBanned && client.userID != message.SenderID {
+						continue
+					}
+					if message.RecipientID != "" && client.userID != message.RecipientID {
+						continue
+					}
+					data := message.Message
+					if message.Render != nil {
+						var cached bool
+						if data, cached = rendered[client.locale]; !cached {
+							data = message.Render(client.locale)
+							rendered[client.locale] = data
+						}
+						if data == nil {
+							continue
+						}
+					}
 					select {
-					case client.send <- message.Message:
-						observability.WebSocketMessagesSent.WithLabelValues(message.ChatroomID, "broadcast").Inc()
+					case client.send <- data:
+						observability.WebSocketMessagesSent.WithLabelValues(message.ChatroomID, kind).Inc()
+						h.checkSlowClient(client)
 					default:
+						h.dropped.Add(1)
 						clientsToRemove = append(clientsToRemove, client)
 					}
 				}
@@ -136,6 +321,105 @@ func (h *Hub) Run(ctx context.Context) error {
 	}
 }
 
+// checkSlowClient warns a client whose send buffer filled past the slow
+// client threshold that it is falling behind, once until it catches up, so
+// it can tell its user before the hub drops it. Only called from the Run
+// loop.
+func (h *Hub) checkSlowClient(client *Client) {
+	queued := len(client.send)
+	if h.slowClientThreshold <= 0 || queued < h.slowClientThreshold {
+		return
+	}
+	if !client.degraded.CompareAndSwap(false, true) {
+		return
+	}
+
+	slog.Warn("slow client falling behind",
+		slog.String("user", client.username),
+		slog.String("chatroom_id", client.chatroomID),
+		slog.Int("queued", queued),
+		slog.Int("capacity", cap(client.send)))
+	observability.WebSocketConnectionsDegradedTotal.Inc()
+
+	data, err := json.Marshal(ServerMessage{
+		Type:     "connection_degraded",
+		Queued:   queued,
+		Capacity: cap(client.send),
+	})
+	if err != nil {
+		slog.Error("failed to marshal connection degraded", slog.String("error", err.Error()))
+		return
+	}
+	client.degradedFrame.Store(&data)
+}
+
+// applyDuplicatePolicy enforces the duplicate connection policy for a client
+// about to be registered. Under DuplicateReject it returns
+// ErrDuplicateConnection; under DuplicateReplaceOldest it closes the user's
+// existing connections to the chatroom with CloseReplaced.
+func (h *Hub) applyDuplicatePolicy(client *Client) error {
+	if h.duplicatePolicy == DuplicateAllow || h.duplicatePolicy == "" {
+		return nil
+	}
+
+	var existing []*Client
+	for other := range h.clients[client.chatroomID] {
+		if other.userID == client.userID {
+			existing = append(existing, other)
+		}
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+
+	observability.WebSocketDuplicateConnectionsTotal.WithLabelValues(string(h.duplicatePolicy)).Inc()
+	if h.duplicatePolicy == DuplicateReject {
+		slog.Info("rejected duplicate connection",
+			slog.String("user", client.username),
+			slog.String("chatroom_id", client.chatroomID))
+		return ErrDuplicateConnection
+	}
+
+	for _, other := range existing {
+		other.replaced.Store(true)
+		other.setCloseFrame(CloseReplaced, "replaced by a newer connection")
+		h.unregisterClient(other)
+		slog.Info("replaced duplicate connection",
+			slog.String("user", other.username),
+			slog.String("chatroom_id", other.chatroomID))
+	}
+	return nil
+}
+
+// disconnectUser closes the matching connections with the requested close
+// frame. Must only be called from within the Hub's Run loop.
+func (h *Hub) disconnectUser(req disconnectRequest) int {
+	var matched []*Client
+	for chatroomID, clients := range h.clients {
+		if req.chatroomID != "" && chatroomID != req.chatroomID {
+			continue
+		}
+		for client := range clients {
+			if client.userID == req.userID && client.orgID == req.orgID {
+				matched = append(matched, client)
+			}
+		}
+	}
+
+	for _, client := range matched {
+		client.setCloseFrame(req.code, req.reason)
+		h.unregisterClient(client)
+	}
+	if len(matched) > 0 {
+		slog.Info("disconnected user",
+			slog.String("user_id", req.userID),
+			slog.String("chatroom_id", req.chatroomID),
+			slog.Int("connections", len(matched)),
+			slog.Int("close_code", req.code))
+	}
+	return len(matched)
+}
+
 func (h *Hub) unregisterClient(client *Client) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
@@ -208,6 +492,109 @@ func (h *Hub) shutdown() {
 // Returns an error if the queue is full or if the hub is shutting down.
 // Callers should log the error appropriately.
 func (h *Hub) Broadcast(chatroomID string, message []byte) error {
+	return h.enqueue(&BroadcastMessage{ChatroomID: chatroomID, Message: message})
+}
+
+// BroadcastFrom sends a message posted by senderID to all clients in a
+// chatroom, or only to the sender's own clients if they are shadow-banned
+// there.
+func (h *Hub) BroadcastFrom(chatroomID, senderID string, message []byte) error {
+	return h.enqueue(&BroadcastMessage{ChatroomID: chatroomID, Message: message, SenderID: senderID})
+}
+
+// ErrUserNotConnected is returned by SendToUser when the user has no
+// connection to the chatroom, so callers can fall back to e.g. a push
+// notification
+var ErrUserNotConnected = errors.New("user is not connected to this chatroom")
+
+// SendToUser sends a message to all of userID's connections in a chatroom
+// and nobody else, e.g. a bot error meant for whoever issued the command, a
+// mention or call signaling. Like Broadcast it does not block; connections
+// closing before the Run loop delivers the message miss it.
+func (h *Hub) SendToUser(chatroomID, userID string, message []byte) error {
+	if !h.IsUserConnected(chatroomID, userID) {
+		return ErrUserNotConnected
+	}
+	return h.enqueue(&BroadcastMessage{ChatroomID: chatroomID, Message: message, RecipientID: userID})
+}
+
+// IsUserConnected reports whether the user has a connection to the chatroom.
+// Thread-safe for external callers.
+func (h *Hub) IsUserConnected(chatroomID, userID string) bool {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for client := range h.clients[chatroomID] {
+		if client.userID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// PublishMessage sends a chat_message frame for msg, stored outside a
+// WebSocket connection (e.g. forwarded over REST), to its chatroom. Like
+// messages sent over WebSocket, it only reaches the sender if they are
+// shadow-banned.
+func (h *Hub) PublishMessage(msg *domain.Message) error {
+	data, err := json.Marshal(ServerMessage{
+		Type:            "chat_message",
+		ID:              msg.ID,
+		UserID:          msg.UserID,
+		Username:        msg.Username,
+		Content:         msg.Content,
+		IsBot:           msg.IsBot,
+		CreatedAt:       &msg.CreatedAt,
+		ForwardedFrom:   msg.ForwardedFrom,
+		Quote:           msg.Quote,
+		ParentMessageID: msg.ParentMessageID,
+		HTML:            msg.HTML,
+		Event:           msg.Event,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	return h.BroadcastFrom(msg.ChatroomID, msg.UserID, data)
+}
+
+// PublishLinkPreviews sends a message_updated frame with the link previews of
+// msg to its chatroom. Like the message itself, it only reaches the sender
+// if they are shadow-banned.
+func (h *Hub) PublishLinkPreviews(msg *domain.Message, previews []*domain.LinkPreview) error {
+	data, err := json.Marshal(ServerMessage{
+		Type:     "message_updated",
+		ID:       msg.ID,
+		Previews: previews,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal link previews: %w", err)
+	}
+	return h.BroadcastFrom(msg.ChatroomID, msg.UserID, data)
+}
+
+// PublishRSVP sends an event_rsvp frame with a member's answer to an event
+// to the event's chatroom. Like messages, answers of shadow-banned members
+// only reach themselves.
+func (h *Hub) PublishRSVP(rsvp *domain.RSVP) error {
+	data, err := json.Marshal(ServerMessage{
+		Type: "event_rsvp",
+		ID:   rsvp.MessageID,
+		RSVP: rsvp,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal rsvp: %w", err)
+	}
+	return h.BroadcastFrom(rsvp.ChatroomID, rsvp.UserID, data)
+}
+
+// BroadcastLocalized sends a message rendered for each client's locale to all
+// clients in a chatroom. render is called once per distinct locale from the
+// Run loop and may return nil to skip those clients.
+func (h *Hub) BroadcastLocalized(chatroomID string, render func(locale string) []byte) error {
+	return h.enqueue(&BroadcastMessage{ChatroomID: chatroomID, Render: render})
+}
+
+func (h *Hub) enqueue(message *BroadcastMessage) error {
 	select {
 	case <-h.done:
 		// Hub is shutting down, reject new broadcasts
@@ -216,22 +603,76 @@ func (h *Hub) Broadcast(chatroomID string, message []byte) error {
 	}
 
 	select {
-	case h.broadcast <- &BroadcastMessage{
-		ChatroomID: chatroomID,
-		Message:    message,
-	}:
+	case h.broadcast <- message:
 		return nil
 	case <-h.done:
 		// Race: hub shutdown occurred between our first check and the send attempt
 		return fmt.Errorf("hub is shutting down")
 	default:
 		// Queue is full, cannot broadcast without blocking
-		return fmt.Errorf("broadcast queue full for chatroom %q", chatroomID)
+		h.dropped.Add(1)
+		return fmt.Errorf("broadcast queue full for chatroom %q", message.ChatroomID)
+	}
+}
+
+// LastHeartbeat returns when the Run loop last reported it was alive.
+// The zero time means the loop has not started or has exited.
+// Thread-safe for external callers.
+func (h *Hub) LastHeartbeat() time.Time {
+	nanos := h.lastHeartbeat.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// HeartbeatInterval returns how often the Run loop records a heartbeat
+func (h *Hub) HeartbeatInterval() time.Duration {
+	return heartbeatInterval
+}
+
+// Register adds client to its chatroom. Returns ErrDuplicateConnection if the
+// duplicate connection policy refuses it; the caller must then close the
+// connection with CloseDuplicateConnection.
+func (h *Hub) Register(client *Client) error {
+	result := make(chan error, 1)
+	h.register <- registration{client: client, result: result}
+	return <-result
+}
+
+// DisconnectUser closes a user's connections in an organization, only those
+// to chatroomID unless it is empty, sending code and reason in the close
+// frame. It returns how many connections were closed. The user can connect
+// again unless they were also banned or signed out.
+func (h *Hub) DisconnectUser(orgID, userID, chatroomID string, code int, reason string) (int, error) {
+	req := disconnectRequest{
+		orgID:      orgID,
+		userID:     userID,
+		chatroomID: chatroomID,
+		code:       code,
+		reason:     reason,
+		result:     make(chan int, 1),
+	}
+	select {
+	case h.disconnect <- req:
+		return <-req.result, nil
+	case <-h.done:
+		return 0, fmt.Errorf("hub is shutting down")
 	}
 }
 
-func (h *Hub) Register(client *Client) {
-	h.register <- client
+// UserRenamed closes a user's connections with CloseUsernameChanged, as
+// they carry the old username into the messages they send
+func (h *Hub) UserRenamed(orgID, userID string) error {
+	_, err := h.DisconnectUser(orgID, userID, "", CloseUsernameChanged, "username changed")
+	return err
+}
+
+// UserDeactivated closes a user's connections with CloseDisconnected, as
+// their account was deactivated
+func (h *Hub) UserDeactivated(orgID, userID string) error {
+	_, err := h.DisconnectUser(orgID, userID, "", CloseDisconnected, "account deactivated")
+	return err
 }
 
 func (h *Hub) Unregister(client *Client) {
@@ -263,32 +704,329 @@ func (h *Hub) GetAllConnectedCounts() map[string]int {
 	return counts
 }
 
-// sendUserCountUpdate must only be called from within the Hub's Run loop
-func (h *Hub) sendUserCountUpdate() {
-	counts := make(map[string]int)
-	for chatroomID, clients := range h.clients {
-		counts[chatroomID] = len(clients)
+// SetShadowBanned shadow-bans or lifts the shadow-ban of a user in a
+// chatroom. Thread-safe for external callers.
+func (h *Hub) SetShadowBanned(chatroomID, userID string, banned bool) {
+	h.shadowMu.Lock()
+	defer h.shadowMu.Unlock()
+
+	if !banned {
+		delete(h.shadowBans[chatroomID], userID)
+		if len(h.shadowBans[chatroomID]) == 0 {
+			delete(h.shadowBans, chatroomID)
+		}
+		return
+	}
+	if h.shadowBans[chatroomID] == nil {
+		h.shadowBans[chatroomID] = make(map[string]bool)
+	}
+	h.shadowBans[chatroomID][userID] = true
+}
+
+// SetShadowBans replaces the shadow-banned users of a chatroom, e.g. with
+// the stored list when a client connects. Thread-safe for external callers.
+func (h *Hub) SetShadowBans(chatroomID string, userIDs []string) {
+	h.shadowMu.Lock()
+	defer h.shadowMu.Unlock()
+
+	if len(userIDs) == 0 {
+		delete(h.shadowBans, chatroomID)
+		return
+	}
+	banned := make(map[string]bool, len(userIDs))
+	for _, userID := range userIDs {
+		banned[userID] = true
+	}
+	h.shadowBans[chatroomID] = banned
+}
+
+// IsShadowBanned reports whether a user is shadow-banned in a chatroom.
+// Thread-safe for external callers.
+func (h *Hub) IsShadowBanned(chatroomID, userID string) bool {
+	h.shadowMu.RLock()
+	defer h.shadowMu.RUnlock()
+
+	return h.shadowBans[chatroomID][userID]
+}
+
+// IsUserOnline reports whether the user has a connection to any chatroom.
+// Thread-safe for external callers.
+func (h *Hub) IsUserOnline(userID string) bool {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for _, clients := range h.clients {
+		for client := range clients {
+			if client.userID == userID {
+				return true
+			}
+		}
 	}
+	return false
+}
+
+// IsUserIdle reports whether none of the user's connections has sent a
+// message within the away timeout. Thread-safe for external callers.
+func (h *Hub) IsUserIdle(userID string) bool {
+	if h.awayAfter <= 0 {
+		return false
+	}
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	cutoff := time.Now().Add(-h.awayAfter).UnixNano()
+	for _, clients := range h.clients {
+		for client := range clients {
+			if client.userID == userID && client.lastActive.Load() > cutoff {
+				return false
+			}
+		}
+	}
+	return true
+}
 
-	message := map[string]any{
-		"type":        "user_count_update",
-		"user_counts": counts,
+// PublishStatus records the status a user chose on their connections and
+// sends a presence frame with it to every chatroom they are connected to.
+// Thread-safe for external callers.
+func (h *Hub) PublishStatus(userID string, status domain.UserStatus) error {
+	h.mutex.RLock()
+	var (
+		username   string
+		lastActive int64
+		rooms      []string
+	)
+	for chatroomID, clients := range h.clients {
+		connected := false
+		for client := range clients {
+			if client.userID != userID {
+				continue
+			}
+			client.status.Store(&status)
+			username = client.username
+			lastActive = max(lastActive, client.lastActive.Load())
+			connected = true
+		}
+		if connected {
+			rooms = append(rooms, chatroomID)
+		}
 	}
+	h.mutex.RUnlock()
 
-	data, err := json.Marshal(message)
+	if len(rooms) == 0 {
+		return nil
+	}
+	idle := h.awayAfter > 0 && time.Since(time.Unix(0, lastActive)) >= h.awayAfter
+	data, err := presenceFrame(userID, username, status, idle)
 	if err != nil {
-		slog.Error("failed to marshal user count update", slog.String("error", err.Error()))
+		return err
+	}
+	var errs []error
+	for _, chatroomID := range rooms {
+		if err := h.enqueue(&BroadcastMessage{ChatroomID: chatroomID, Message: data}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkAway sends a presence frame for every user who became idle, or
+// active again, since the last check, unless it leaves the status others
+// see unchanged. Must only be called from within the Hub's Run loop.
+func (h *Hub) checkAway(now time.Time) {
+	if h.awayAfter <= 0 {
 		return
 	}
 
-	for chatroomID := range h.clients {
-		if clients, ok := h.clients[chatroomID]; ok && len(clients) > 0 {
+	type presence struct {
+		username   string
+		status     domain.UserStatus
+		lastActive int64
+		rooms      []string
+	}
+	users := make(map[string]*presence)
+	h.mutex.RLock()
+	for chatroomID, clients := range h.clients {
+		for client := range clients {
+			p := users[client.userID]
+			if p == nil {
+				p = &presence{username: client.username, status: client.currentStatus()}
+				users[client.userID] = p
+			}
+			p.lastActive = max(p.lastActive, client.lastActive.Load())
+			if len(p.rooms) == 0 || p.rooms[len(p.rooms)-1] != chatroomID {
+				p.rooms = append(p.rooms, chatroomID)
+			}
+		}
+	}
+	h.mutex.RUnlock()
+
+	for userID := range h.idle {
+		if users[userID] == nil {
+			delete(h.idle, userID)
+		}
+	}
+
+	for userID, p := range users {
+		idle := now.Sub(time.Unix(0, p.lastActive)) >= h.awayAfter
+		if idle == h.idle[userID] {
+			continue
+		}
+		if idle {
+			h.idle[userID] = true
+		} else {
+			delete(h.idle, userID)
+		}
+		if domain.EffectiveStatus(p.status.Status, true, idle) == domain.EffectiveStatus(p.status.Status, true, !idle) {
+			continue
+		}
+
+		data, err := presenceFrame(userID, p.username, p.status, idle)
+		if err != nil {
+			slog.Error("failed to marshal presence", slog.String("error", err.Error()))
+			return
+		}
+		for _, chatroomID := range p.rooms {
+			select {
+			case h.broadcast <- &BroadcastMessage{ChatroomID: chatroomID, Message: data}:
+			default:
+				h.dropped.Add(1)
+				slog.Warn("broadcast channel full, skipping presence update",
+					slog.String("chatroom_id", chatroomID))
+			}
+		}
+	}
+}
+
+// presenceFrame is the presence frame telling a chatroom the status others
+// see of a connected user
+func presenceFrame(userID, username string, status domain.UserStatus, idle bool) ([]byte, error) {
+	data, err := json.Marshal(ServerMessage{
+		Type:       "presence",
+		UserID:     userID,
+		Username:   username,
+		Status:     domain.EffectiveStatus(status.Status, true, idle),
+		StatusText: status.Text,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal presence: %w", err)
+	}
+	return data, nil
+}
+
+// HubStats is a snapshot of the hub's connections and queues
+type HubStats struct {
+	Rooms       int `json:"rooms"`
+	Connections int `json:"connections"`
+	UniqueUsers int `json:"unique_users"`
+	// SendBufferUsed and SendBufferCapacity add up the messages waiting in,
+	// and the size of, every connection's send buffer
+	SendBufferUsed     int `json:"send_buffer_used"`
+	SendBufferCapacity int `json:"send_buffer_capacity"`
+	// SendBufferMaxUsed is the fullest connection's backlog
+	SendBufferMaxUsed int `json:"send_buffer_max_used"`
+	// DegradedConnections are the connections warned they are falling
+	// behind and not caught up yet
+	DegradedConnections int `json:"degraded_connections"`
+	BroadcastQueued     int `json:"broadcast_queued"`
+	BroadcastCapacity   int `json:"broadcast_capacity"`
+	// DroppedMessages counts messages lost to a full broadcast queue or
+	// send buffer since the hub started
+	DroppedMessages uint64    `json:"dropped_messages"`
+	StartedAt       time.Time `json:"started_at"`
+	UptimeSeconds   int64     `json:"uptime_seconds"`
+}
+
+// Stats returns a snapshot of the hub's connections and queues.
+// Thread-safe for external callers.
+func (h *Hub) Stats() HubStats {
+	stats := HubStats{
+		BroadcastQueued:   len(h.broadcast),
+		BroadcastCapacity: cap(h.broadcast),
+		DroppedMessages:   h.dropped.Load(),
+		StartedAt:         h.startedAt,
+		UptimeSeconds:     int64(time.Since(h.startedAt).Seconds()),
+	}
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	users := make(map[string]bool)
+	for _, clients := range h.clients {
+		if len(clients) == 0 {
+			continue
+		}
+		stats.Rooms++
+		for client := range clients {
+			stats.Connections++
+			users[client.userID] = true
+			used := len(client.send)
+			stats.SendBufferUsed += used
+			stats.SendBufferCapacity += cap(client.send)
+			stats.SendBufferMaxUsed = max(stats.SendBufferMaxUsed, used)
+			if client.degraded.Load() {
+				stats.DegradedConnections++
+			}
+		}
+	}
+	stats.UniqueUsers = len(users)
+	return stats
+}
+
+// sendUserCountUpdate must only be called from within the Hub's Run loop.
+// Each chatroom only receives the counts of its own organization's rooms,
+// and only when one of them changed since the last update.
+func (h *Hub) sendUserCountUpdate() {
+	orgCounts := make(map[string]map[string]int)
+	orgRooms := make(map[string][]string)
+	for chatroomID, clients := range h.clients {
+		if len(clients) == 0 {
+			continue
+		}
+		// Every client of a chatroom belongs to the chatroom's organization
+		var orgID string
+		for client := range clients {
+			orgID = client.orgID
+			break
+		}
+		if orgCounts[orgID] == nil {
+			orgCounts[orgID] = make(map[string]int)
+		}
+		orgCounts[orgID][chatroomID] = len(clients)
+		orgRooms[orgID] = append(orgRooms[orgID], chatroomID)
+	}
+
+	for orgID := range h.lastCounts {
+		if orgCounts[orgID] == nil {
+			// Nobody left to tell
+			delete(h.lastCounts, orgID)
+		}
+	}
+
+	for orgID, counts := range orgCounts {
+		if maps.Equal(counts, h.lastCounts[orgID]) {
+			continue
+		}
+		h.lastCounts[orgID] = counts
+
+		message := map[string]any{
+			"type":        "user_count_update",
+			"user_counts": counts,
+		}
+
+		data, err := json.Marshal(message)
+		if err != nil {
+			slog.Error("failed to marshal user count update", slog.String("error", err.Error()))
+			return
+		}
+
+		for _, chatroomID := range orgRooms[orgID] {
 			select {
 			case h.broadcast <- &BroadcastMessage{
 				ChatroomID: chatroomID,
 				Message:    data,
 			}:
 			default:
+				h.dropped.Add(1)
 				slog.Warn("broadcast channel full, skipping user count update",
 					slog.String("chatroom_id", chatroomID))
 			}
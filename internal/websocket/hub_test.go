@@ -2,10 +2,17 @@ package websocket
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"jobsity-chat/internal/domain"
+	"jobsity-chat/internal/testutil"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -284,6 +291,244 @@ func TestHub_BroadcastMessage(t *testing.T) {
 	}
 }
 
+func TestHub_BroadcastLocalized(t *testing.T) {
+	hub := NewHub()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = hub.Run(ctx)
+	}()
+
+	clients := map[string]*Client{}
+	for _, tc := range []struct{ userID, locale string }{
+		{"user-1", "en"},
+		{"user-2", "es"},
+		{"user-3", "es"},
+	} {
+		clients[tc.userID] = &Client{
+			hub:        hub,
+			send:       make(chan []byte, 256),
+			userID:     tc.userID,
+			chatroomID: "test-room",
+			locale:     tc.locale,
+		}
+		hub.Register(clients[tc.userID])
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	var renders atomic.Int32
+	err := hub.BroadcastLocalized("test-room", func(locale string) []byte {
+		renders.Add(1)
+		return []byte("hello in " + locale)
+	})
+	if err != nil {
+		t.Fatalf("BroadcastLocalized failed: %v", err)
+	}
+
+	for userID, want := range map[string]string{
+		"user-1": "hello in en",
+		"user-2": "hello in es",
+		"user-3": "hello in es",
+	} {
+		msg, err := drainCountUpdates(clients[userID].send, 200*time.Millisecond)
+		if err != nil {
+			t.Errorf("%s did not receive broadcast message", userID)
+		} else if string(msg) != want {
+			t.Errorf("%s: expected %q, got %q", userID, want, msg)
+		}
+	}
+
+	if got := renders.Load(); got != 2 {
+		t.Errorf("expected one render per locale, got %d", got)
+	}
+}
+
+func TestHub_BroadcastFromShadowBannedUser(t *testing.T) {
+	hub := NewHub()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = hub.Run(ctx)
+	}()
+
+	banned := &Client{
+		hub:        hub,
+		send:       make(chan []byte, 256),
+		userID:     "user-1",
+		username:   "user1",
+		chatroomID: "test-room",
+	}
+	other := &Client{
+		hub:        hub,
+		send:       make(chan []byte, 256),
+		userID:     "user-2",
+		username:   "user2",
+		chatroomID: "test-room",
+	}
+	hub.Register(banned)
+	hub.Register(other)
+	time.Sleep(50 * time.Millisecond)
+
+	hub.SetShadowBanned("test-room", "user-1", true)
+	if !hub.IsShadowBanned("test-room", "user-1") {
+		t.Fatal("Expected user-1 to be shadow-banned")
+	}
+
+	if err := hub.BroadcastFrom("test-room", "user-1", []byte("hidden")); err != nil {
+		t.Fatalf("BroadcastFrom failed: %v", err)
+	}
+
+	msg, err := drainCountUpdates(banned.send, 200*time.Millisecond)
+	if err != nil || string(msg) != "hidden" {
+		t.Errorf("Expected shadow-banned sender to receive own message, got %q (%v)", msg, err)
+	}
+	if msg, err := drainCountUpdates(other.send, 100*time.Millisecond); err == nil {
+		t.Errorf("Expected other client to receive nothing, got %q", msg)
+	}
+
+	// Messages from other users still reach the shadow-banned user
+	if err := hub.BroadcastFrom("test-room", "user-2", []byte("visible")); err != nil {
+		t.Fatalf("BroadcastFrom failed: %v", err)
+	}
+	for _, client := range []*Client{banned, other} {
+		if msg, err := drainCountUpdates(client.send, 200*time.Millisecond); err != nil || string(msg) != "visible" {
+			t.Errorf("Expected %s to receive 'visible', got %q (%v)", client.userID, msg, err)
+		}
+	}
+
+	hub.SetShadowBans("test-room", nil)
+	if err := hub.BroadcastFrom("test-room", "user-1", []byte("restored")); err != nil {
+		t.Fatalf("BroadcastFrom failed: %v", err)
+	}
+	if msg, err := drainCountUpdates(other.send, 200*time.Millisecond); err != nil || string(msg) != "restored" {
+		t.Errorf("Expected 'restored' after lifting the ban, got %q (%v)", msg, err)
+	}
+}
+
+func TestHub_SendToUser(t *testing.T) {
+	hub := NewHub()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = hub.Run(ctx)
+	}()
+
+	requester := &Client{hub: hub, send: make(chan []byte, 256), userID: "user-1", username: "user1", chatroomID: "test-room"}
+	requesterTab := &Client{hub: hub, send: make(chan []byte, 256), userID: "user-1", username: "user1", chatroomID: "test-room"}
+	other := &Client{hub: hub, send: make(chan []byte, 256), userID: "user-2", username: "user2", chatroomID: "test-room"}
+	elsewhere := &Client{hub: hub, send: make(chan []byte, 256), userID: "user-1", username: "user1", chatroomID: "other-room"}
+	for _, client := range []*Client{requester, requesterTab, other, elsewhere} {
+		hub.Register(client)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if err := hub.SendToUser("test-room", "user-1", []byte("private")); err != nil {
+		t.Fatalf("SendToUser failed: %v", err)
+	}
+
+	for _, client := range []*Client{requester, requesterTab} {
+		if msg, err := drainCountUpdates(client.send, 200*time.Millisecond); err != nil || string(msg) != "private" {
+			t.Errorf("Expected the requester's clients to receive 'private', got %q (%v)", msg, err)
+		}
+	}
+	for _, client := range []*Client{other, elsewhere} {
+		if msg, err := drainCountUpdates(client.send, 100*time.Millisecond); err == nil {
+			t.Errorf("Expected %s in %s to receive nothing, got %q", client.userID, client.chatroomID, msg)
+		}
+	}
+
+	if !hub.IsUserConnected("test-room", "user-2") || hub.IsUserConnected("other-room", "user-2") {
+		t.Error("Expected user-2 to be connected to test-room only")
+	}
+	if err := hub.SendToUser("other-room", "user-2", []byte("private")); !errors.Is(err, ErrUserNotConnected) {
+		t.Errorf("Expected ErrUserNotConnected, got %v", err)
+	}
+}
+
+func TestHub_PublishLinkPreviews(t *testing.T) {
+	hub := NewHub()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = hub.Run(ctx)
+	}()
+
+	client := &Client{
+		hub:        hub,
+		send:       make(chan []byte, 256),
+		userID:     "user-2",
+		username:   "user2",
+		chatroomID: "test-room",
+	}
+	hub.Register(client)
+	time.Sleep(50 * time.Millisecond)
+
+	msg := &domain.Message{ID: "msg-1", ChatroomID: "test-room", UserID: "user-1"}
+	previews := []*domain.LinkPreview{{MessageID: "msg-1", URL: "https://example.com", Title: "Example"}}
+	if err := hub.PublishLinkPreviews(msg, previews); err != nil {
+		t.Fatalf("PublishLinkPreviews failed: %v", err)
+	}
+
+	data, err := drainCountUpdates(client.send, 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected a message_updated frame: %v", err)
+	}
+	var frame ServerMessage
+	if err := json.Unmarshal(data, &frame); err != nil {
+		t.Fatalf("Failed to decode frame: %v", err)
+	}
+	if frame.Type != "message_updated" || frame.ID != "msg-1" || len(frame.Previews) != 1 || frame.Previews[0].Title != "Example" {
+		t.Errorf("Unexpected frame: %s", data)
+	}
+}
+
+func TestHub_PublishRSVP(t *testing.T) {
+	hub := NewHub()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = hub.Run(ctx)
+	}()
+
+	client := &Client{
+		hub:        hub,
+		send:       make(chan []byte, 256),
+		userID:     "user-2",
+		username:   "user2",
+		chatroomID: "test-room",
+	}
+	hub.Register(client)
+	time.Sleep(50 * time.Millisecond)
+
+	rsvp := &domain.RSVP{MessageID: "msg-1", ChatroomID: "test-room", UserID: "user-1", Username: "user1", Response: domain.RSVPGoing}
+	if err := hub.PublishRSVP(rsvp); err != nil {
+		t.Fatalf("PublishRSVP failed: %v", err)
+	}
+
+	data, err := drainCountUpdates(client.send, 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected an event_rsvp frame: %v", err)
+	}
+	var frame ServerMessage
+	if err := json.Unmarshal(data, &frame); err != nil {
+		t.Fatalf("Failed to decode frame: %v", err)
+	}
+	if frame.Type != "event_rsvp" || frame.ID != "msg-1" || frame.RSVP == nil || frame.RSVP.Response != domain.RSVPGoing {
+		t.Errorf("Unexpected frame: %s", data)
+	}
+}
+
 func TestHub_BroadcastToMultipleChatrooms(t *testing.T) {
 	hub := NewHub()
 
@@ -343,6 +588,199 @@ func TestHub_BroadcastToMultipleChatrooms(t *testing.T) {
 	}
 }
 
+func TestHub_UserCountUpdateIsolatedPerOrganization(t *testing.T) {
+	hub := NewHub()
+	hub.countUpdateInterval = 20 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = hub.Run(ctx)
+	}()
+
+	clientA := &Client{
+		hub:        hub,
+		send:       make(chan []byte, 256),
+		userID:     "user-1",
+		username:   "user1",
+		chatroomID: "room-a",
+		orgID:      "org-a",
+	}
+
+	clientB := &Client{
+		hub:        hub,
+		send:       make(chan []byte, 256),
+		userID:     "user-2",
+		username:   "user2",
+		chatroomID: "room-b",
+		orgID:      "org-b",
+	}
+
+	hub.Register(clientA)
+	hub.Register(clientB)
+
+	time.Sleep(100 * time.Millisecond)
+
+	for name, tc := range map[string]struct {
+		client    *Client
+		ownRoom   string
+		otherRoom string
+	}{
+		"org-a": {clientA, "room-a", "room-b"},
+		"org-b": {clientB, "room-b", "room-a"},
+	} {
+		received := false
+		for len(tc.client.send) > 0 {
+			msg := string(<-tc.client.send)
+			if !strings.Contains(msg, "user_count_update") {
+				continue
+			}
+			received = true
+			if strings.Contains(msg, tc.otherRoom) {
+				t.Errorf("%s received another organization's room count: %s", name, msg)
+			}
+			if !strings.Contains(msg, tc.ownRoom) {
+				t.Errorf("%s count update is missing its own room: %s", name, msg)
+			}
+		}
+		if !received {
+			t.Errorf("%s received no user count update", name)
+		}
+	}
+}
+
+func TestHub_UserCountUpdatesCoalesced(t *testing.T) {
+	hub := NewHub()
+	hub.countUpdateInterval = 100 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = hub.Run(ctx)
+	}()
+
+	clients := make([]*Client, 5)
+	for i := range clients {
+		clients[i] = &Client{
+			hub:        hub,
+			send:       make(chan []byte, 256),
+			userID:     fmt.Sprintf("user-%d", i),
+			chatroomID: "test-room",
+		}
+		hub.Register(clients[i])
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	updates := 0
+	for len(clients[0].send) > 0 {
+		if strings.Contains(string(<-clients[0].send), "user_count_update") {
+			updates++
+		}
+	}
+	// A burst of joins is announced once, twice if it straddled a tick
+	if updates == 0 || updates > 2 {
+		t.Errorf("Expected the joins to be coalesced into 1 or 2 updates, got %d", updates)
+	}
+}
+
+func TestHub_SendUserCountUpdateOnlyWhenChanged(t *testing.T) {
+	hub := NewHub()
+	client := &Client{hub: hub, send: make(chan []byte, 256), userID: "user-1", chatroomID: "test-room", orgID: "org-a"}
+	hub.clients["test-room"] = map[*Client]bool{client: true}
+
+	hub.sendUserCountUpdate()
+	if len(hub.broadcast) != 1 {
+		t.Fatalf("Expected 1 queued update, got %d", len(hub.broadcast))
+	}
+
+	// Same counts: nothing new to say
+	hub.sendUserCountUpdate()
+	if len(hub.broadcast) != 1 {
+		t.Errorf("Expected no update for unchanged counts, got %d queued", len(hub.broadcast))
+	}
+
+	other := &Client{hub: hub, send: make(chan []byte, 256), userID: "user-2", chatroomID: "test-room", orgID: "org-a"}
+	hub.clients["test-room"][other] = true
+	hub.sendUserCountUpdate()
+	if len(hub.broadcast) != 2 {
+		t.Errorf("Expected an update for the changed count, got %d queued", len(hub.broadcast))
+	}
+}
+
+func TestHub_Stats(t *testing.T) {
+	hub := NewHub()
+	clients := []*Client{
+		{hub: hub, send: make(chan []byte, 4), userID: "user-1", chatroomID: "room-1"},
+		{hub: hub, send: make(chan []byte, 4), userID: "user-1", chatroomID: "room-2"},
+		{hub: hub, send: make(chan []byte, 4), userID: "user-2", chatroomID: "room-1"},
+	}
+	for _, client := range clients {
+		if hub.clients[client.chatroomID] == nil {
+			hub.clients[client.chatroomID] = make(map[*Client]bool)
+		}
+		hub.clients[client.chatroomID][client] = true
+	}
+	clients[0].send <- []byte("a")
+	clients[0].send <- []byte("b")
+	clients[2].send <- []byte("c")
+
+	// Queue one broadcast past capacity to count a drop
+	for range cap(hub.broadcast) + 1 {
+		hub.Broadcast("room-1", []byte("x"))
+	}
+
+	stats := hub.Stats()
+	testutil.AssertEqual(t, stats.Rooms, 2)
+	testutil.AssertEqual(t, stats.Connections, 3)
+	testutil.AssertEqual(t, stats.UniqueUsers, 2)
+	testutil.AssertEqual(t, stats.SendBufferUsed, 3)
+	testutil.AssertEqual(t, stats.SendBufferCapacity, 12)
+	testutil.AssertEqual(t, stats.SendBufferMaxUsed, 2)
+	testutil.AssertEqual(t, stats.BroadcastQueued, cap(hub.broadcast))
+	testutil.AssertEqual(t, stats.DroppedMessages, uint64(1))
+}
+
+func TestHub_WarnsSlowClients(t *testing.T) {
+	hub := NewHub()
+	// 5 of the 256 frames of the send buffer
+	hub.SetSlowClientThreshold(2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = hub.Run(ctx)
+	}()
+
+	slow := &Client{hub: hub, send: make(chan []byte, sendBufferSize), userID: "user-1", username: "user1", chatroomID: "room-1"}
+	testutil.AssertNoError(t, hub.Register(slow))
+
+	// Nothing reads the client's buffer
+	for i := range 5 {
+		testutil.AssertNoError(t, hub.Broadcast("room-1", []byte(fmt.Sprint(i))))
+	}
+	deadline := time.Now().Add(time.Second)
+	for slow.degradedFrame.Load() == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	frame := slow.degradedFrame.Load()
+	testutil.AssertTrue(t, frame != nil, "expected a connection_degraded frame")
+	var msg ServerMessage
+	testutil.AssertNoError(t, json.Unmarshal(*frame, &msg))
+	testutil.AssertEqual(t, msg.Type, "connection_degraded")
+	testutil.AssertTrue(t, msg.Queued >= 5, "expected the queued frames")
+	testutil.AssertEqual(t, msg.Capacity, sendBufferSize)
+	testutil.AssertEqual(t, hub.Stats().DegradedConnections, 1)
+
+	// The client is only warned once until it catches up
+	slow.degradedFrame.Store(nil)
+	testutil.AssertNoError(t, hub.Broadcast("room-1", []byte("more")))
+	time.Sleep(50 * time.Millisecond)
+	testutil.AssertTrue(t, slow.degradedFrame.Load() == nil, "expected a single warning")
+}
+
 func TestHub_ShutdownWithMultipleClients(t *testing.T) {
 	hub := NewHub()
 
@@ -499,6 +937,88 @@ func TestHub_GetConnectedUserCount(t *testing.T) {
 	}
 }
 
+func TestHub_DuplicatePolicy(t *testing.T) {
+	newClient := func(hub *Hub, userID string) *Client {
+		return &Client{
+			hub:        hub,
+			send:       make(chan []byte, 256),
+			userID:     userID,
+			username:   userID,
+			chatroomID: "room1",
+		}
+	}
+
+	tests := []struct {
+		policy        DuplicatePolicy
+		wantErr       error
+		wantCount     int
+		wantOldClosed bool
+	}{
+		{DuplicateAllow, nil, 2, false},
+		{DuplicateReject, ErrDuplicateConnection, 1, false},
+		{DuplicateReplaceOldest, nil, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			hub := NewHub()
+			hub.SetDuplicatePolicy(tt.policy)
+			ctx, cancel := context.WithCancel(context.Background())
+			go hub.Run(ctx)
+			defer func() {
+				cancel()
+				<-hub.done
+			}()
+
+			old := newClient(hub, "user1")
+			if err := hub.Register(old); err != nil {
+				t.Fatalf("first Register failed: %v", err)
+			}
+			// Another user is never affected by the policy
+			if err := hub.Register(newClient(hub, "user2")); err != nil {
+				t.Fatalf("Register of another user failed: %v", err)
+			}
+
+			if err := hub.Register(newClient(hub, "user1")); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			if count := hub.GetConnectedUserCount("room1"); count != tt.wantCount+1 {
+				t.Errorf("expected %d connections, got %d", tt.wantCount+1, count)
+			}
+			if got := old.sendClosed.Load(); got != tt.wantOldClosed {
+				t.Errorf("old connection closed = %v, want %v", got, tt.wantOldClosed)
+			}
+			if tt.wantOldClosed {
+				if !old.replaced.Load() {
+					t.Error("expected the old connection to be marked replaced")
+				}
+				if want := websocket.FormatCloseMessage(CloseReplaced, "replaced by a newer connection"); string(old.closeFrame) != string(want) {
+					t.Errorf("unexpected close frame %q", old.closeFrame)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDuplicatePolicy(t *testing.T) {
+	for input, want := range map[string]DuplicatePolicy{
+		"":               DuplicateAllow,
+		"allow":          DuplicateAllow,
+		"Replace-Oldest": DuplicateReplaceOldest,
+		" reject ":       DuplicateReject,
+	} {
+		got, err := ParseDuplicatePolicy(input)
+		if err != nil || got != want {
+			t.Errorf("ParseDuplicatePolicy(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+
+	if _, err := ParseDuplicatePolicy("newest"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
+
 // TestHub_GetAllConnectedCounts tests the GetAllConnectedCounts method
 func TestHub_GetAllConnectedCounts(t *testing.T) {
 	hub := NewHub()
@@ -560,6 +1080,103 @@ func TestHub_GetAllConnectedCounts(t *testing.T) {
 	}
 }
 
+func TestHub_IsUserOnline(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer func() {
+		cancel()
+		<-hub.done
+	}()
+
+	if hub.IsUserOnline("user1") {
+		t.Error("Expected user1 to be offline before connecting")
+	}
+
+	client := &Client{
+		hub:        hub,
+		send:       make(chan []byte, 256),
+		userID:     "user1",
+		username:   "alice",
+		chatroomID: "room1",
+	}
+	hub.Register(client)
+	time.Sleep(10 * time.Millisecond)
+
+	if !hub.IsUserOnline("user1") {
+		t.Error("Expected user1 to be online")
+	}
+	if hub.IsUserOnline("user2") {
+		t.Error("Expected user2 to be offline")
+	}
+
+	hub.Unregister(client)
+	time.Sleep(10 * time.Millisecond)
+
+	if hub.IsUserOnline("user1") {
+		t.Error("Expected user1 to be offline after disconnecting")
+	}
+}
+
+func TestHub_CheckAway(t *testing.T) {
+	hub := NewHub()
+	hub.SetAwayAfter(time.Minute)
+	now := time.Now()
+
+	alice := &Client{hub: hub, send: make(chan []byte, 256), userID: "user-1", username: "alice", chatroomID: "room-1"}
+	alice.lastActive.Store(now.Add(-2 * time.Minute).UnixNano())
+	bob := &Client{hub: hub, send: make(chan []byte, 256), userID: "user-2", username: "bob", chatroomID: "room-1"}
+	bob.lastActive.Store(now.Add(-2 * time.Minute).UnixNano())
+	bob.SetStatus(domain.UserStatus{Status: domain.StatusDND})
+	carol := &Client{hub: hub, send: make(chan []byte, 256), userID: "user-3", username: "carol", chatroomID: "room-1"}
+	carol.lastActive.Store(now.UnixNano())
+	hub.clients["room-1"] = map[*Client]bool{alice: true, bob: true, carol: true}
+
+	// Only alice's status changes: bob is not to be disturbed either way
+	hub.checkAway(now)
+	if len(hub.broadcast) != 1 {
+		t.Fatalf("Expected 1 presence frame, got %d", len(hub.broadcast))
+	}
+	var msg ServerMessage
+	testutil.AssertNoError(t, json.Unmarshal((<-hub.broadcast).Message, &msg))
+	testutil.AssertEqual(t, msg.Type, "presence")
+	testutil.AssertEqual(t, msg.UserID, "user-1")
+	testutil.AssertEqual(t, msg.Status, domain.StatusAway)
+	testutil.AssertTrue(t, hub.IsUserIdle("user-1"), "alice should be idle")
+	testutil.AssertFalse(t, hub.IsUserIdle("user-3"), "carol should not be idle")
+
+	// Announced once
+	hub.checkAway(now)
+	testutil.AssertEqual(t, len(hub.broadcast), 0)
+
+	alice.lastActive.Store(now.UnixNano())
+	hub.checkAway(now)
+	testutil.AssertNoError(t, json.Unmarshal((<-hub.broadcast).Message, &msg))
+	testutil.AssertEqual(t, msg.UserID, "user-1")
+	testutil.AssertEqual(t, msg.Status, domain.StatusActive)
+}
+
+func TestHub_PublishStatus(t *testing.T) {
+	hub := NewHub()
+	client := &Client{hub: hub, send: make(chan []byte, 256), userID: "user-1", username: "alice", chatroomID: "room-1"}
+	client.lastActive.Store(time.Now().UnixNano())
+	hub.clients["room-1"] = map[*Client]bool{client: true}
+
+	status := domain.UserStatus{Status: domain.StatusDND, Text: "Focusing"}
+	testutil.AssertNoError(t, hub.PublishStatus("user-1", status))
+	testutil.AssertEqual(t, client.currentStatus(), status)
+
+	var msg ServerMessage
+	testutil.AssertNoError(t, json.Unmarshal((<-hub.broadcast).Message, &msg))
+	testutil.AssertEqual(t, msg.Type, "presence")
+	testutil.AssertEqual(t, msg.Status, domain.StatusDND)
+	testutil.AssertEqual(t, msg.StatusText, "Focusing")
+
+	// Nobody to tell about users who are not connected
+	testutil.AssertNoError(t, hub.PublishStatus("user-2", status))
+	testutil.AssertEqual(t, len(hub.broadcast), 0)
+}
+
 func TestHub_GracefulShutdownWithPendingBroadcasts(t *testing.T) {
 	hub := NewHub()
 
@@ -640,3 +1257,83 @@ func TestHub_BroadcastDuringShutdown(t *testing.T) {
 		t.Errorf("Expected 'hub is shutting down' error, got %q", err.Error())
 	}
 }
+
+func TestHub_Heartbeat(t *testing.T) {
+	hub := NewHub()
+
+	if !hub.LastHeartbeat().IsZero() {
+		t.Fatal("Expected zero heartbeat before Run")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = hub.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.After(time.Second)
+	for hub.LastHeartbeat().IsZero() {
+		select {
+		case <-deadline:
+			t.Fatal("Hub did not record a heartbeat after starting")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	if age := time.Since(hub.LastHeartbeat()); age > time.Second {
+		t.Errorf("Expected fresh heartbeat, got age %v", age)
+	}
+
+	cancel()
+	<-done
+
+	if !hub.LastHeartbeat().IsZero() {
+		t.Error("Expected heartbeat to be cleared after Run exits")
+	}
+}
+
+func TestHub_DisconnectUser(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer func() {
+		cancel()
+		<-hub.done
+	}()
+
+	target := &Client{hub: hub, send: make(chan []byte, 4), userID: "user1", chatroomID: "room1", orgID: "org1"}
+	otherRoom := &Client{hub: hub, send: make(chan []byte, 4), userID: "user1", chatroomID: "room2", orgID: "org1"}
+	otherOrg := &Client{hub: hub, send: make(chan []byte, 4), userID: "user1", chatroomID: "room1", orgID: "org2"}
+	otherUser := &Client{hub: hub, send: make(chan []byte, 4), userID: "user2", chatroomID: "room1", orgID: "org1"}
+	for _, client := range []*Client{target, otherRoom, otherOrg, otherUser} {
+		if err := hub.Register(client); err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+	}
+
+	n, err := hub.DisconnectUser("org1", "user1", "room1", 1008, "banned")
+	if err != nil || n != 1 {
+		t.Fatalf("DisconnectUser = %d, %v; want 1, nil", n, err)
+	}
+	if !target.sendClosed.Load() {
+		t.Error("expected the room connection to be closed")
+	}
+	if want := websocket.FormatCloseMessage(1008, "banned"); string(target.closeFrame) != string(want) {
+		t.Errorf("unexpected close frame %q", target.closeFrame)
+	}
+	for _, client := range []*Client{otherRoom, otherOrg, otherUser} {
+		if client.sendClosed.Load() {
+			t.Errorf("connection of %s in %s/%s should stay open", client.userID, client.orgID, client.chatroomID)
+		}
+	}
+
+	// Without a chatroom every connection of the user in the org is closed
+	n, err = hub.DisconnectUser("org1", "user1", "", CloseDisconnected, "")
+	if err != nil || n != 1 {
+		t.Fatalf("DisconnectUser = %d, %v; want 1, nil", n, err)
+	}
+	if !otherRoom.sendClosed.Load() || otherOrg.sendClosed.Load() {
+		t.Error("expected only the org's remaining connection to be closed")
+	}
+}
@@ -0,0 +1,208 @@
+package websocket
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ProtocolVersion is the version of the WebSocket protocol the server
+// speaks by default. Clients ask for a version with the protocol_version
+// query parameter of the handshake; connections without one get version 1,
+// which predates negotiation.
+const ProtocolVersion = 1
+
+// SupportedProtocolVersions are the protocol versions the server accepts,
+// oldest first. A breaking change to a frame adds a version here, so older
+// clients keep the frames they were written for until it is dropped.
+var SupportedProtocolVersions = []int{1}
+
+// CloseUnsupportedProtocolVersion is sent to connections asking for a
+// protocol version the server does not speak. The reason lists the
+// supported ones; reconnecting with the same version cannot succeed.
+const CloseUnsupportedProtocolVersion = 4005
+
+// FrameSchema documents a frame type of the protocol
+type FrameSchema struct {
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Fields      []string `json:"fields,omitempty"`
+	// Since is the first protocol version with the frame
+	Since int `json:"since"`
+	// Until is the last protocol version with the frame; 0 while it is
+	// still current
+	Until int `json:"until,omitempty"`
+}
+
+// availableIn reports whether the frame is part of protocol version
+func (f FrameSchema) availableIn(version int) bool {
+	return version >= f.Since && (f.Until == 0 || version <= f.Until)
+}
+
+// ClientFrames are the frames clients may send, in every protocol version.
+// The fields are those of ClientMessage.
+var ClientFrames = []FrameSchema{
+	{
+		Type:        "chat_message",
+		Description: "Posts a message or a bot command; a frame without a type is one too",
+		Fields:      []string{"content", "client_msg_id", "quoted_message_id"},
+		Since:       1,
+	},
+	{
+		Type:        "fetch_since",
+		Description: "Asks for up to limit messages posted after since_id, answered with messages_since",
+		Fields:      []string{"since_id", "limit"},
+		Since:       1,
+	},
+}
+
+// ServerFrames are the frames the server sends, in every protocol version.
+// The fields are those of ServerMessage, besides user_count_update's.
+var ServerFrames = []FrameSchema{
+	{
+		Type:        "chat_message",
+		Description: "A message of a user or bot",
+		Fields: []string{"id", "user_id", "username", "content", "is_bot", "is_error", "created_at",
+			"client_msg_id", "attachment", "forwarded_from", "quote", "parent_message_id", "html", "event"},
+		Since: 1,
+	},
+	{
+		Type:        "message_ack",
+		Description: "The sender's message was stored",
+		Fields:      []string{"id", "client_msg_id"},
+		Since:       1,
+	},
+	{
+		Type:        "messages_since",
+		Description: "The chat_messages answering a fetch_since, oldest first",
+		Fields:      []string{"messages", "has_more"},
+		Since:       1,
+	},
+	{
+		Type:        "message_updated",
+		Description: "Link previews fetched for a message after it was sent",
+		Fields:      []string{"id", "previews"},
+		Since:       1,
+	},
+	{
+		Type:        "event_rsvp",
+		Description: "A member answered an event posted in the chatroom",
+		Fields:      []string{"id", "rsvp"},
+		Since:       1,
+	},
+	{
+		Type:        "welcome",
+		Description: "The chatroom's welcome message, sent to a member on their first connection",
+		Fields:      []string{"message"},
+		Since:       1,
+	},
+	{
+		Type:        "user_joined",
+		Description: "A user connected to the chatroom",
+		Fields:      []string{"username", "message"},
+		Since:       1,
+	},
+	{
+		Type:        "user_left",
+		Description: "A user disconnected from the chatroom",
+		Fields:      []string{"username", "message"},
+		Since:       1,
+	},
+	{
+		Type:        "user_count_update",
+		Description: "How many users are connected to each chatroom of the organization",
+		Fields:      []string{"user_counts"},
+		Since:       1,
+	},
+	{
+		Type:        "presence",
+		Description: "The status others see of a connected user changed",
+		Fields:      []string{"user_id", "username", "status", "status_text"},
+		Since:       1,
+	},
+	{
+		Type:        "connection_degraded",
+		Description: "The connection is falling behind: queued of the capacity frames its send buffer holds are waiting, and it is dropped once the buffer is full",
+		Fields:      []string{"queued", "capacity"},
+		Since:       1,
+	},
+	{
+		Type:        "connection_recovered",
+		Description: "A degraded connection caught up with its send buffer",
+		Since:       1,
+	},
+	{
+		Type:        "error",
+		Description: "A frame of this client failed, translated to the user's locale; code is set for malformed frames",
+		Fields:      []string{"message", "client_msg_id", "code"},
+		Since:       1,
+	},
+}
+
+// ProtocolSchema is the schema of a protocol version
+type ProtocolSchema struct {
+	Version      int           `json:"version"`
+	Current      int           `json:"current"`
+	Supported    []int         `json:"supported"`
+	ClientFrames []FrameSchema `json:"client_frames"`
+	ServerFrames []FrameSchema `json:"server_frames"`
+}
+
+// Schema returns the frames of protocol version, which must be supported
+func Schema(version int) ProtocolSchema {
+	return ProtocolSchema{
+		Version:      version,
+		Current:      ProtocolVersion,
+		Supported:    SupportedProtocolVersions,
+		ClientFrames: framesIn(ClientFrames, version),
+		ServerFrames: framesIn(ServerFrames, version),
+	}
+}
+
+func framesIn(frames []FrameSchema, version int) []FrameSchema {
+	var in []FrameSchema
+	for _, frame := range frames {
+		if frame.availableIn(version) {
+			in = append(in, frame)
+		}
+	}
+	return in
+}
+
+// IsClientFrame reports whether clients may send frameType in protocol
+// version
+func IsClientFrame(version int, frameType string) bool {
+	if frameType == "" {
+		frameType = "chat_message"
+	}
+	for _, frame := range ClientFrames {
+		if frame.Type == frameType {
+			return frame.availableIn(version)
+		}
+	}
+	return false
+}
+
+// ParseProtocolVersion parses the protocol_version a client asked for. The
+// empty string is version 1, spoken by clients predating negotiation.
+func ParseProtocolVersion(s string) (int, error) {
+	if s == "" {
+		return 1, nil
+	}
+	version, err := strconv.Atoi(s)
+	if err != nil || !slices.Contains(SupportedProtocolVersions, version) {
+		return 0, fmt.Errorf("unsupported protocol version %q (supported: %s)", s, SupportedVersionList())
+	}
+	return version, nil
+}
+
+// SupportedVersionList returns the supported protocol versions as a
+// comma-separated list, e.g. for a close reason
+func SupportedVersionList() string {
+	versions := make([]string, len(SupportedProtocolVersions))
+	for i, version := range SupportedProtocolVersions {
+		versions[i] = strconv.Itoa(version)
+	}
+	return strings.Join(versions, ", ")
+}
@@ -0,0 +1,52 @@
+package websocket
+
+import (
+	"testing"
+
+	"jobsity-chat/internal/testutil"
+)
+
+func TestParseProtocolVersion(t *testing.T) {
+	for input, want := range map[string]int{"": 1, "1": 1} {
+		got, err := ParseProtocolVersion(input)
+		if err != nil || got != want {
+			t.Errorf("ParseProtocolVersion(%q) = %d, %v; want %d", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"0", "2", "v1", "-1"} {
+		if _, err := ParseProtocolVersion(input); err == nil {
+			t.Errorf("ParseProtocolVersion(%q) expected error", input)
+		}
+	}
+}
+
+func TestIsClientFrame(t *testing.T) {
+	testutil.AssertTrue(t, IsClientFrame(1, "chat_message"), "chat_message is a v1 frame")
+	testutil.AssertTrue(t, IsClientFrame(1, "fetch_since"), "fetch_since is a v1 frame")
+	testutil.AssertTrue(t, IsClientFrame(1, ""), "a frame without a type is a chat_message")
+	testutil.AssertFalse(t, IsClientFrame(1, "typing"), "typing is not a frame")
+	testutil.AssertFalse(t, IsClientFrame(1, "user_joined"), "server frames cannot be sent")
+}
+
+func TestSchema(t *testing.T) {
+	frames := []FrameSchema{
+		{Type: "old", Since: 1, Until: 1},
+		{Type: "current", Since: 1},
+		{Type: "new", Since: 2},
+	}
+	in := func(version int) []string {
+		var types []string
+		for _, frame := range framesIn(frames, version) {
+			types = append(types, frame.Type)
+		}
+		return types
+	}
+	testutil.AssertEqual(t, len(in(1)), 2)
+	testutil.AssertEqual(t, in(1)[0], "old")
+	testutil.AssertEqual(t, len(in(2)), 2)
+	testutil.AssertEqual(t, in(2)[1], "new")
+
+	schema := Schema(ProtocolVersion)
+	testutil.AssertEqual(t, schema.Current, ProtocolVersion)
+	testutil.AssertLen(t, schema.ClientFrames, len(ClientFrames))
+}
@@ -0,0 +1,169 @@
+// Package zen provides the phrases the stock bot answers /hello with. The
+// source, selected with STOCK_BOT_ZEN_PROVIDER by app.NewZenProvider, is the
+// phrases embedded in the i18n catalogs, a phrase file, or an external quotes
+// API.
+package zen
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"jobsity-chat/internal/i18n"
+)
+
+const (
+	ProviderEmbedded = "embedded"
+	ProviderFile     = "file"
+	ProviderAPI      = "api"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown zen provider")
+	ErrNoPhrases       = errors.New("no zen phrases")
+)
+
+// QuoteProvider returns a zen phrase for the requester's locale
+type QuoteProvider interface {
+	Quote(ctx context.Context, locale string) (string, error)
+}
+
+// EmbeddedProvider picks a random phrase from the locale's i18n catalog
+type EmbeddedProvider struct{}
+
+func NewEmbeddedProvider() *EmbeddedProvider {
+	return &EmbeddedProvider{}
+}
+
+func (p *EmbeddedProvider) Quote(ctx context.Context, locale string) (string, error) {
+	return i18n.ZenPhrase(locale, rand.Int64()), nil
+}
+
+// FileProvider picks a random phrase from a text file with one phrase per
+// line. Blank lines and lines starting with # are skipped. The same phrases
+// are used for every locale.
+type FileProvider struct {
+	phrases []string
+}
+
+// NewFileProvider loads the phrases in path. Returns ErrNoPhrases if the file
+// has none.
+func NewFileProvider(path string) (*FileProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zen phrases: %w", err)
+	}
+	defer f.Close()
+
+	var phrases []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		phrases = append(phrases, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read zen phrases: %w", err)
+	}
+	if len(phrases) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoPhrases, path)
+	}
+
+	return &FileProvider{phrases: phrases}, nil
+}
+
+func (p *FileProvider) Quote(ctx context.Context, locale string) (string, error) {
+	return p.phrases[rand.IntN(len(p.phrases))], nil
+}
+
+// APIProvider fetches a random quote from an external quotes API. It
+// understands ZenQuotes ([{"q": ..., "a": ...}]) and Quotable-style
+// ({"content": ..., "author": ...}) responses. Those APIs only serve English,
+// so other locales, as well as failed requests, are answered by the fallback.
+// An empty locale, sent by clients predating locales, is English.
+type APIProvider struct {
+	url        string
+	httpClient *http.Client
+	fallback   QuoteProvider
+}
+
+func NewAPIProvider(url string, fallback QuoteProvider) *APIProvider {
+	return &APIProvider{
+		url: url,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		fallback: fallback,
+	}
+}
+
+func (p *APIProvider) Quote(ctx context.Context, locale string) (string, error) {
+	if locale != "" && locale != i18n.English && p.fallback != nil {
+		return p.fallback.Quote(ctx, locale)
+	}
+
+	quote, err := p.fetch(ctx)
+	if err != nil && p.fallback != nil {
+		return p.fallback.Quote(ctx, locale)
+	}
+	return quote, err
+}
+
+type apiQuote struct {
+	Q       string `json:"q"`
+	A       string `json:"a"`
+	Content string `json:"content"`
+	Author  string `json:"author"`
+}
+
+func (p *APIProvider) fetch(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch quote: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("quotes API returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", fmt.Errorf("failed to read quote: %w", err)
+	}
+
+	var quote apiQuote
+	var quotes []apiQuote
+	if err := json.Unmarshal(body, &quotes); err == nil && len(quotes) > 0 {
+		quote = quotes[0]
+	} else if err := json.Unmarshal(body, &quote); err != nil {
+		return "", fmt.Errorf("failed to decode quote: %w", err)
+	}
+
+	text, author := quote.Q, quote.A
+	if text == "" {
+		text, author = quote.Content, quote.Author
+	}
+	if text == "" {
+		return "", ErrNoPhrases
+	}
+	if author == "" {
+		return text, nil
+	}
+	return text + " — " + author, nil
+}
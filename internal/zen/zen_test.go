@@ -0,0 +1,109 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+type staticProvider string
+
+func (p staticProvider) Quote(ctx context.Context, locale string) (string, error) {
+	return string(p) + " (" + locale + ")", nil
+}
+
+func TestEmbeddedProvider(t *testing.T) {
+	quote, err := NewEmbeddedProvider().Quote(context.Background(), "es")
+	if err != nil || quote == "" {
+		t.Errorf("expected a phrase, got %q, %v", quote, err)
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "phrases.txt")
+	content := "# house phrases\nShip it.\n\n  Measure twice, cut once.  \n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := NewFileProvider(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"Ship it.", "Measure twice, cut once."}; !slices.Equal(provider.phrases, want) {
+		t.Errorf("phrases = %q, want %q", provider.phrases, want)
+	}
+
+	quote, _ := provider.Quote(context.Background(), "en")
+	if !slices.Contains(provider.phrases, quote) {
+		t.Errorf("unexpected phrase %q", quote)
+	}
+}
+
+func TestFileProvider_Empty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "phrases.txt")
+	if err := os.WriteFile(path, []byte("# nothing here\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFileProvider(path); !errors.Is(err, ErrNoPhrases) {
+		t.Errorf("expected ErrNoPhrases, got %v", err)
+	}
+	if _, err := NewFileProvider(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestAPIProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		locale   string
+		expected string
+	}{
+		{"zenquotes", http.StatusOK, `[{"q":"Be here now.","a":"Ram Dass","h":"<blockquote>"}]`, "en", "Be here now. — Ram Dass"},
+		{"quotable", http.StatusOK, `{"content":"Less is more.","author":"Mies"}`, "en", "Less is more. — Mies"},
+		{"no_author", http.StatusOK, `{"content":"Less is more."}`, "en", "Less is more."},
+		{"server_error", http.StatusServiceUnavailable, ``, "en", "fallback (en)"},
+		{"malformed", http.StatusOK, `<html>`, "en", "fallback (en)"},
+		{"empty_quote", http.StatusOK, `[]`, "en", "fallback (en)"},
+		{"no_locale", http.StatusOK, `[{"q":"Be here now.","a":"Ram Dass"}]`, "", "Be here now. — Ram Dass"},
+		{"non_english", http.StatusOK, `[{"q":"Be here now.","a":"Ram Dass"}]`, "pt", "fallback (pt)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			provider := NewAPIProvider(server.URL, staticProvider("fallback"))
+			quote, err := provider.Quote(context.Background(), tt.locale)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if quote != tt.expected {
+				t.Errorf("Quote() = %q, want %q", quote, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAPIProvider_NoFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if _, err := NewAPIProvider(server.URL, nil).Quote(context.Background(), "en"); err == nil {
+		t.Error("expected an error without a fallback")
+	}
+}
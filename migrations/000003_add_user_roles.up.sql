@@ -0,0 +1,4 @@
+-- User roles: regular users, room moderators and platform administrators
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'moderator', 'admin'));
@@ -0,0 +1,4 @@
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS idle_timeout_seconds,
+    DROP COLUMN IF EXISTS absolute_expires_at,
+    DROP COLUMN IF EXISTS last_activity_at;
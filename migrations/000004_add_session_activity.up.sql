@@ -0,0 +1,11 @@
+-- Sliding sessions: expires_at = LEAST(last_activity_at + idle timeout, absolute_expires_at)
+ALTER TABLE sessions
+    ADD COLUMN IF NOT EXISTS last_activity_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS absolute_expires_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS idle_timeout_seconds INTEGER NOT NULL DEFAULT 86400
+        CHECK (idle_timeout_seconds > 0);
+
+-- Existing sessions keep their fixed expiry
+UPDATE sessions SET absolute_expires_at = expires_at WHERE absolute_expires_at IS NULL;
+
+ALTER TABLE sessions ALTER COLUMN absolute_expires_at SET NOT NULL;
@@ -0,0 +1,10 @@
+-- Single-use WebSocket connection tickets, exchanged for a session so
+-- long-lived session tokens never appear in URLs
+CREATE TABLE IF NOT EXISTS ws_tickets (
+    ticket VARCHAR(64) PRIMARY KEY,
+    session_token VARCHAR(255) NOT NULL REFERENCES sessions(token) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ws_tickets_expires ON ws_tickets(expires_at);
@@ -0,0 +1,3 @@
+-- Users disabled by directory sync (or an administrator) keep their history
+-- but can no longer sign in
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMP;
@@ -0,0 +1,17 @@
+DROP INDEX IF EXISTS idx_chatrooms_org;
+
+ALTER TABLE user_identities DROP CONSTRAINT IF EXISTS user_identities_pkey;
+ALTER TABLE user_identities ADD PRIMARY KEY (provider, subject);
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_org_username_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_org_email_key;
+ALTER TABLE users ADD CONSTRAINT users_username_key UNIQUE (username);
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+
+ALTER TABLE user_identities DROP COLUMN IF EXISTS org_id;
+ALTER TABLE sessions DROP COLUMN IF EXISTS org_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS org_id;
+ALTER TABLE chatrooms DROP COLUMN IF EXISTS org_id;
+ALTER TABLE users DROP COLUMN IF EXISTS org_id;
+
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,33 @@
+-- Organizations (tenants). Existing data moves into the default organization.
+CREATE TABLE IF NOT EXISTS organizations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    slug VARCHAR(63) UNIQUE NOT NULL CHECK (slug ~ '^[a-z0-9]([a-z0-9-]*[a-z0-9])?$'),
+    name VARCHAR(100) NOT NULL CHECK (length(name) >= 1),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+INSERT INTO organizations (id, slug, name)
+VALUES ('00000000-0000-0000-0000-000000000001', 'default', 'Default')
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL
+    DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE chatrooms ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL
+    DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL
+    DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL
+    DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE user_identities ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL
+    DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE;
+
+-- Usernames, emails and external identities are unique per organization
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_username_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+ALTER TABLE users ADD CONSTRAINT users_org_username_key UNIQUE (org_id, username);
+ALTER TABLE users ADD CONSTRAINT users_org_email_key UNIQUE (org_id, email);
+
+ALTER TABLE user_identities DROP CONSTRAINT IF EXISTS user_identities_pkey;
+ALTER TABLE user_identities ADD PRIMARY KEY (org_id, provider, subject);
+
+CREATE INDEX IF NOT EXISTS idx_chatrooms_org ON chatrooms(org_id, created_at DESC);
@@ -0,0 +1,9 @@
+-- Per-organization overrides of the global usage quotas. NULL inherits the
+-- global limit; 0 means unlimited.
+CREATE TABLE IF NOT EXISTS organization_quotas (
+    org_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    max_rooms_per_user INTEGER CHECK (max_rooms_per_user >= 0),
+    max_messages_per_room_per_day INTEGER CHECK (max_messages_per_room_per_day >= 0),
+    max_attachment_bytes BIGINT CHECK (max_attachment_bytes >= 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
@@ -0,0 +1,3 @@
+-- Preferred locale for bot and system messages; empty uses the browser's
+-- Accept-Language
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(10) NOT NULL DEFAULT '';
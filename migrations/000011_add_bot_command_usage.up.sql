@@ -0,0 +1,14 @@
+-- One row per bot command answered, for usage analytics
+CREATE TABLE IF NOT EXISTS bot_command_usage (
+    id BIGSERIAL PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    chatroom_id UUID NOT NULL,
+    command_type VARCHAR(20) NOT NULL,
+    symbol VARCHAR(20) NOT NULL DEFAULT '',
+    requested_by VARCHAR(50) NOT NULL DEFAULT '',
+    latency_ms INTEGER NOT NULL,
+    success BOOLEAN NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_bot_command_usage_org_created ON bot_command_usage(org_id, created_at);
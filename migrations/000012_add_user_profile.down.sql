@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS show_online_status;
+ALTER TABLE users DROP COLUMN IF EXISTS profile_visibility;
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_url;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS message_flags;
+ALTER TABLE messages DROP COLUMN IF EXISTS hidden_at;
@@ -0,0 +1,16 @@
+-- Messages hidden from history after collecting too many flags
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMP;
+
+-- One flag per user per message. Flags stay open until a moderator keeps or
+-- deletes the message.
+CREATE TABLE IF NOT EXISTS message_flags (
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(500) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT message_flags_message_reporter_key UNIQUE (message_id, reporter_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_flags_open ON message_flags(message_id) WHERE resolved_at IS NULL;
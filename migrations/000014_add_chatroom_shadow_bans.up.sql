@@ -0,0 +1,8 @@
+-- Users whose messages in a chatroom are only delivered back to themselves
+CREATE TABLE IF NOT EXISTS chatroom_shadow_bans (
+    chatroom_id UUID NOT NULL REFERENCES chatrooms(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    banned_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (chatroom_id, user_id)
+);
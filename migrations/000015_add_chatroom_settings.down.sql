@@ -0,0 +1,2 @@
+ALTER TABLE chatroom_members DROP COLUMN IF EXISTS welcomed_at;
+DROP TABLE IF EXISTS chatroom_settings;
@@ -0,0 +1,9 @@
+-- Owner-configurable chatroom settings; rooms without a row use the defaults
+CREATE TABLE IF NOT EXISTS chatroom_settings (
+    chatroom_id UUID PRIMARY KEY REFERENCES chatrooms(id) ON DELETE CASCADE,
+    welcome_message TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+-- Set once the member has been sent the chatroom's welcome message
+ALTER TABLE chatroom_members ADD COLUMN IF NOT EXISTS welcomed_at TIMESTAMP;
@@ -0,0 +1,11 @@
+-- OpenGraph previews of the URLs posted in messages
+CREATE TABLE IF NOT EXISTS link_previews (
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    title VARCHAR(300) NOT NULL,
+    description VARCHAR(1000) NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    site_name VARCHAR(300) NOT NULL DEFAULT '',
+    fetched_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (message_id, url)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_messages_chatroom_seq;
+ALTER TABLE messages DROP COLUMN IF EXISTS seq;
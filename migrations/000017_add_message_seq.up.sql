@@ -0,0 +1,6 @@
+-- Monotonic message sequence numbers, exposed by /api/v2. Existing rows are
+-- numbered in physical order; clients should only rely on seq increasing
+-- for messages posted from now on.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS seq BIGINT GENERATED ALWAYS AS IDENTITY;
+
+CREATE INDEX IF NOT EXISTS idx_messages_chatroom_seq ON messages(chatroom_id, seq);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_messages_chatroom_seq_visible;
+ALTER TABLE chatroom_members DROP COLUMN IF EXISTS last_read_seq;
@@ -0,0 +1,11 @@
+-- Highest message seq a member has read. Existing members start caught up.
+ALTER TABLE chatroom_members ADD COLUMN IF NOT EXISTS last_read_seq BIGINT NOT NULL DEFAULT 0;
+
+UPDATE chatroom_members cm
+SET last_read_seq = COALESCE((SELECT MAX(m.seq) FROM messages m WHERE m.chatroom_id = cm.chatroom_id), 0);
+
+-- Covers the unread count and latest message lookups of the activity
+-- summary, which only look at visible messages
+CREATE INDEX IF NOT EXISTS idx_messages_chatroom_seq_visible
+    ON messages(chatroom_id, seq) INCLUDE (user_id, created_at)
+    WHERE hidden_at IS NULL;
@@ -0,0 +1,12 @@
+-- Devices registered for push notifications. Token is a Web Push
+-- subscription (JSON) or an FCM registration token.
+CREATE TABLE IF NOT EXISTS push_devices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform VARCHAR(16) NOT NULL CHECK (platform IN ('webpush', 'fcm')),
+    token TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    UNIQUE (platform, token)
+);
+
+CREATE INDEX IF NOT EXISTS idx_push_devices_user ON push_devices(user_id);
@@ -0,0 +1,18 @@
+-- Email invitations to a chatroom. Only the SHA-256 of the invite token is
+-- stored; the token itself is only in the emailed link.
+CREATE TABLE IF NOT EXISTS room_invites (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    chatroom_id UUID NOT NULL REFERENCES chatrooms(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    invited_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    accepted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_room_invites_pending
+    ON room_invites(chatroom_id, created_at DESC)
+    WHERE accepted_at IS NULL AND revoked_at IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS profile_version;
+ALTER TABLE chatroom_settings DROP COLUMN IF EXISTS version;
@@ -0,0 +1,5 @@
+-- Versions for optimistic concurrency: each update bumps the version, and
+-- an update naming an older one is rejected. Rooms without a settings row
+-- are at version 1.
+ALTER TABLE chatroom_settings ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS profile_version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_messages_deleted;
+DROP INDEX IF EXISTS idx_chatrooms_deleted;
+ALTER TABLE messages DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE chatrooms DROP COLUMN IF EXISTS deleted_at;
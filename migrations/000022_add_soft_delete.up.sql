@@ -0,0 +1,8 @@
+-- Deleted chatrooms and messages are kept, hidden everywhere, until they are
+-- purged after the retention window; an administrator can restore them
+-- until then
+ALTER TABLE chatrooms ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_chatrooms_deleted ON chatrooms(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_messages_deleted ON messages(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS chatroom_member_keys;
+
+DELETE FROM messages m USING chatrooms c WHERE c.id = m.chatroom_id AND c.encrypted;
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_content_check;
+ALTER TABLE messages ADD CONSTRAINT messages_content_check
+    CHECK (length(content) > 0 AND length(content) <= 1000);
+
+ALTER TABLE chatrooms DROP COLUMN IF EXISTS encrypted;
@@ -0,0 +1,18 @@
+-- End-to-end encrypted chatrooms. Messages of these rooms carry ciphertext
+-- the server cannot read, which is longer than the plaintext it encrypts.
+ALTER TABLE chatrooms ADD COLUMN IF NOT EXISTS encrypted BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_content_check;
+ALTER TABLE messages ADD CONSTRAINT messages_content_check
+    CHECK (length(content) > 0 AND length(content) <= 8000);
+
+-- Key exchange metadata each member publishes for an encrypted chatroom,
+-- e.g. a public key and the room key wrapped for other members. The server
+-- stores it as is.
+CREATE TABLE IF NOT EXISTS chatroom_member_keys (
+    chatroom_id UUID NOT NULL REFERENCES chatrooms(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key_data TEXT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (chatroom_id, user_id)
+);
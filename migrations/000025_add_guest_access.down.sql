@@ -0,0 +1,9 @@
+ALTER TABLE chatroom_settings DROP COLUMN IF EXISTS guests_can_post;
+ALTER TABLE chatroom_settings DROP COLUMN IF EXISTS public;
+
+DROP INDEX IF EXISTS idx_users_guest_created_at;
+
+DELETE FROM users WHERE role = 'guest';
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check
+    CHECK (role IN ('user', 'moderator', 'admin'));
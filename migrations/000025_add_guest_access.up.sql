@@ -0,0 +1,11 @@
+-- Guests are ephemeral users created without credentials. They can read,
+-- and post if allowed, only in chatrooms flagged public.
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check
+    CHECK (role IN ('user', 'moderator', 'admin', 'guest'));
+
+-- Expired guests are deleted by age
+CREATE INDEX IF NOT EXISTS idx_users_guest_created_at ON users(created_at) WHERE role = 'guest';
+
+ALTER TABLE chatroom_settings ADD COLUMN IF NOT EXISTS public BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE chatroom_settings ADD COLUMN IF NOT EXISTS guests_can_post BOOLEAN NOT NULL DEFAULT FALSE;
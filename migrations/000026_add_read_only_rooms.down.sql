@@ -0,0 +1 @@
+ALTER TABLE chatroom_settings DROP COLUMN IF EXISTS read_only;
@@ -0,0 +1,2 @@
+-- Read-only (announcement) chatrooms: only the owner and moderators post
+ALTER TABLE chatroom_settings ADD COLUMN IF NOT EXISTS read_only BOOLEAN NOT NULL DEFAULT FALSE;
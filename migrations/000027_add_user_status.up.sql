@@ -0,0 +1,5 @@
+-- The status a user chose for others to see. Away is also shown for
+-- connected users who have been inactive for a while.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status VARCHAR(10) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'away', 'dnd'));
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_text VARCHAR(100) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS deferred_push_notifications;
+ALTER TABLE users DROP COLUMN IF EXISTS dnd_schedule;
@@ -0,0 +1,18 @@
+-- Do-not-disturb schedules, as {"time_zone": ..., "windows": [...]}
+ALTER TABLE users ADD COLUMN IF NOT EXISTS dnd_schedule JSONB;
+
+-- Push notifications held back during a do-not-disturb window, delivered
+-- together when it ends
+CREATE TABLE IF NOT EXISTS deferred_push_notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chatroom_id UUID NOT NULL,
+    message_id UUID NOT NULL,
+    title TEXT NOT NULL,
+    body TEXT NOT NULL,
+    deliver_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_deferred_push_deliver_at ON deferred_push_notifications(deliver_at);
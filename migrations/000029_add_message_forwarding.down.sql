@@ -0,0 +1,4 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS forwarded_from_username;
+ALTER TABLE messages DROP COLUMN IF EXISTS forwarded_from_user_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS forwarded_from_chatroom_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS forwarded_from_id;
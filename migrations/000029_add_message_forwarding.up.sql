@@ -0,0 +1,6 @@
+-- Where a forwarded message came from. The author's username is kept as it
+-- was when forwarded, so the attribution survives the original being purged.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS forwarded_from_id UUID REFERENCES messages(id) ON DELETE SET NULL;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS forwarded_from_chatroom_id UUID REFERENCES chatrooms(id) ON DELETE SET NULL;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS forwarded_from_user_id UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS forwarded_from_username VARCHAR(50);
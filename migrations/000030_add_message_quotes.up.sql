@@ -0,0 +1,3 @@
+-- Snapshot of the message a reply quotes: message_id, user_id, username,
+-- content and created_at as they were when the reply was sent
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS quote JSONB;
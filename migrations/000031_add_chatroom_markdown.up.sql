@@ -0,0 +1,2 @@
+-- Markdown rendering of messages, a per-chatroom opt-in
+ALTER TABLE chatroom_settings ADD COLUMN IF NOT EXISTS markdown BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS event_rsvps;
+ALTER TABLE messages DROP COLUMN IF EXISTS event;
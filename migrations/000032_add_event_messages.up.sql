@@ -0,0 +1,11 @@
+-- Event messages: title, starts_at, ends_at and location of the event a
+-- message announces
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS event JSONB;
+
+CREATE TABLE IF NOT EXISTS event_rsvps (
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    response VARCHAR(20) NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (message_id, user_id)
+);
@@ -0,0 +1,11 @@
+-- Tags chatrooms are browsed and filtered by in the directory. org_id is
+-- copied from the chatroom so filtering stays an index scan per tag.
+CREATE TABLE IF NOT EXISTS chatroom_tags (
+    chatroom_id UUID NOT NULL REFERENCES chatrooms(id) ON DELETE CASCADE,
+    org_id UUID NOT NULL,
+    tag VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chatroom_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chatroom_tags_org_tag ON chatroom_tags(org_id, tag, chatroom_id);
@@ -0,0 +1,8 @@
+-- Chatrooms users starred, in the order they arranged them
+CREATE TABLE IF NOT EXISTS chatroom_stars (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chatroom_id UUID NOT NULL REFERENCES chatrooms(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    starred_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, chatroom_id)
+);
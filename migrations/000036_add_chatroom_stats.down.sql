@@ -0,0 +1,3 @@
+DROP MATERIALIZED VIEW IF EXISTS chatroom_daily_peaks;
+DROP MATERIALIZED VIEW IF EXISTS chatroom_daily_activity;
+DROP TABLE IF EXISTS chatroom_presence_samples;
@@ -0,0 +1,37 @@
+-- Connected users per chatroom as seen by each server instance's hub,
+-- sampled periodically; a chatroom's concurrency at a sample time is the
+-- sum over instances
+CREATE TABLE IF NOT EXISTS chatroom_presence_samples (
+    chatroom_id UUID NOT NULL REFERENCES chatrooms(id) ON DELETE CASCADE,
+    org_id UUID NOT NULL,
+    instance_id TEXT NOT NULL,
+    sampled_at TIMESTAMP NOT NULL,
+    connected_users INTEGER NOT NULL CHECK (connected_users >= 0),
+    PRIMARY KEY (chatroom_id, sampled_at, instance_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_presence_samples_sampled_at ON chatroom_presence_samples(sampled_at);
+
+-- Messages per chatroom, day and poster over the last 90 days. Refreshed by
+-- the chatroom_stats_refresh job; the unique index allows refreshing it
+-- concurrently with reads.
+CREATE MATERIALIZED VIEW IF NOT EXISTS chatroom_daily_activity AS
+SELECT m.chatroom_id, m.org_id, m.created_at::date AS day, m.user_id, COUNT(*) AS messages
+FROM messages m
+WHERE m.created_at >= CURRENT_DATE - 89 AND m.deleted_at IS NULL
+GROUP BY m.chatroom_id, m.org_id, m.created_at::date, m.user_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chatroom_daily_activity ON chatroom_daily_activity(chatroom_id, day, user_id);
+
+-- Peak concurrent users per chatroom and day, from the presence samples
+CREATE MATERIALIZED VIEW IF NOT EXISTS chatroom_daily_peaks AS
+SELECT chatroom_id, org_id, sampled_at::date AS day, MAX(connected_users) AS peak_users
+FROM (
+    SELECT chatroom_id, org_id, sampled_at, SUM(connected_users) AS connected_users
+    FROM chatroom_presence_samples
+    WHERE sampled_at >= CURRENT_DATE - 89
+    GROUP BY chatroom_id, org_id, sampled_at
+) totals
+GROUP BY chatroom_id, org_id, sampled_at::date;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chatroom_daily_peaks ON chatroom_daily_peaks(chatroom_id, day);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_presence_samples_org_sampled_at;
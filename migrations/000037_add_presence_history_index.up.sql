@@ -0,0 +1,2 @@
+-- Presence history is read per organization over a recent period
+CREATE INDEX IF NOT EXISTS idx_presence_samples_org_sampled_at ON chatroom_presence_samples(org_id, sampled_at);
@@ -0,0 +1,12 @@
+-- Consecutive failed logins per username and client IP, shared by the
+-- server instances to delay repeated attempts. Usernames are lowercased.
+CREATE TABLE IF NOT EXISTS login_failures (
+    org_id UUID NOT NULL,
+    username TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    failures INTEGER NOT NULL CHECK (failures > 0),
+    last_failed_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (org_id, username, ip)
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_failures_last_failed_at ON login_failures(last_failed_at);
@@ -0,0 +1,13 @@
+-- Usernames users changed away from. Old mentions keep resolving to the user
+-- who had the name, and other users cannot take it for a while.
+CREATE TABLE IF NOT EXISTS username_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    org_id UUID NOT NULL,
+    old_username VARCHAR(50) NOT NULL,
+    new_username VARCHAR(50) NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_username_history_user ON username_history(user_id, changed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_username_history_old_username ON username_history(org_id, lower(old_username), changed_at DESC);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_users_org_username_skeleton;
+DROP FUNCTION IF EXISTS username_skeleton(TEXT);
@@ -0,0 +1,12 @@
+-- username_skeleton reduces a username to the letters it reads as, so
+-- usernames that can be mistaken for each other compare equal: case is
+-- folded, underscores dropped, lookalike digits mapped to letters (i, l and
+-- 1 all read as l) and rn, vv read as m, w. Kept in step with the service's
+-- nameSkeleton for the ASCII characters usernames are made of.
+CREATE OR REPLACE FUNCTION username_skeleton(username TEXT) RETURNS TEXT
+LANGUAGE SQL IMMUTABLE STRICT PARALLEL SAFE
+AS $$
+    SELECT replace(replace(translate(lower(username), '0134578i_', 'oleastbl'), 'rn', 'm'), 'vv', 'w')
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_users_org_username_skeleton ON users(org_id, username_skeleton(username));
@@ -0,0 +1,7 @@
+-- System accounts are the ones the server itself posts as, such as the stock
+-- bot's. They have no password and nobody can log in to them.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_system BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- The stock bot used to be registered with a well-known password
+UPDATE users SET is_system = TRUE, password_hash = ''
+WHERE username = 'StockBot' AND email = 'bot@jobsity.com';
@@ -0,0 +1,5 @@
+-- The message a threaded reply answers, such as the command a bot response
+-- answers. Replies stay when their parent is purged.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS parent_message_id UUID REFERENCES messages(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_messages_parent_message_id ON messages(parent_message_id) WHERE parent_message_id IS NOT NULL;
@@ -0,0 +1,5 @@
+-- The client_msg_id a sender attached to a message, so a message resent
+-- after a reconnect is acknowledged again instead of being stored twice
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS client_msg_id TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_user_client_msg_id ON messages(user_id, client_msg_id) WHERE client_msg_id IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE organization_quotas ADD COLUMN IF NOT EXISTS max_attachment_bytes BIGINT CHECK (max_attachment_bytes >= 0);
@@ -0,0 +1,2 @@
+-- Nothing enforced the attachment size quota: messages have no attachments
+ALTER TABLE organization_quotas DROP COLUMN IF EXISTS max_attachment_bytes;
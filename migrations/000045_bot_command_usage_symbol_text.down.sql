@@ -0,0 +1 @@
+ALTER TABLE bot_command_usage ALTER COLUMN symbol TYPE VARCHAR(20) USING LEFT(symbol, 20);
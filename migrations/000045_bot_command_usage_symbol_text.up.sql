@@ -0,0 +1,2 @@
+-- Symbols longer than 20 characters failed the usage insert
+ALTER TABLE bot_command_usage ALTER COLUMN symbol TYPE TEXT;
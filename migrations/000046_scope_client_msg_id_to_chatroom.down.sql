@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_messages_chatroom_user_client_msg_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_user_client_msg_id ON messages(user_id, client_msg_id) WHERE client_msg_id IS NOT NULL;
@@ -0,0 +1,5 @@
+-- A client_msg_id is unique per sender within a chatroom, so an ID reused in
+-- another chatroom stores a new message instead of acknowledging the old one
+DROP INDEX IF EXISTS idx_messages_user_client_msg_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_chatroom_user_client_msg_id ON messages(chatroom_id, user_id, client_msg_id) WHERE client_msg_id IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_messages_chatroom_seq_live;
+
+CREATE INDEX IF NOT EXISTS idx_messages_chatroom_seq_visible
+    ON messages(chatroom_id, seq) INCLUDE (user_id, created_at)
+    WHERE hidden_at IS NULL;
@@ -0,0 +1,8 @@
+-- The activity summary and chatroom list skip deleted messages as well as
+-- hidden ones, so the index leaves both out instead of filtering deleted
+-- messages from the heap
+DROP INDEX IF EXISTS idx_messages_chatroom_seq_visible;
+
+CREATE INDEX IF NOT EXISTS idx_messages_chatroom_seq_live
+    ON messages(chatroom_id, seq) INCLUDE (user_id, created_at)
+    WHERE hidden_at IS NULL AND deleted_at IS NULL;
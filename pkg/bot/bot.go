@@ -0,0 +1,384 @@
+// Package bot is a framework for chat bots that connect to chatrooms as a
+// regular user through the client SDK. Commands are messages starting with
+// a prefix, "!" by default so they do not collide with the server's own
+// slash commands, and are routed to the handler registered for their name.
+//
+//	c, _ := client.New("https://chat.example.com")
+//	c.SetToken(os.Getenv("BOT_TOKEN"))
+//	b := bot.New(c, bot.Options{})
+//	b.Use(bot.Cooldown(2 * time.Second))
+//	b.Command("roll", "Roll a six-sided die", func(ctx context.Context, m *bot.Message) error {
+//		return m.Reply(strconv.Itoa(rand.IntN(6) + 1))
+//	})
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+//	defer stop()
+//	err := b.Run(ctx, chatroomID)
+//
+// Unlike the stock bot, which answers /stock commands the server hands it
+// over RabbitMQ, these bots need nothing but an account and the public API.
+package bot
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"jobsity-chat/pkg/client"
+)
+
+const (
+	defaultPrefix          = "!"
+	defaultHandlerTimeout  = 30 * time.Second
+	defaultShutdownTimeout = 10 * time.Second
+	defaultMaxConcurrency  = 16
+)
+
+// HandlerFunc handles a message. A returned error is passed to the bot's
+// ErrorHandler.
+type HandlerFunc func(ctx context.Context, msg *Message) error
+
+// Middleware wraps a handler, e.g. to filter, throttle or log messages
+type Middleware func(HandlerFunc) HandlerFunc
+
+// Options configures a bot; zero values use the defaults
+type Options struct {
+	// Prefix starts command messages; default "!"
+	Prefix string
+	// HandlerTimeout bounds each handler's context; default 30s
+	HandlerTimeout time.Duration
+	// ShutdownTimeout is how long Run waits for running handlers once its
+	// context is canceled before canceling theirs; default 10s
+	ShutdownTimeout time.Duration
+	// MaxConcurrency caps the handlers running at once; messages wait
+	// while it is reached. Default 16.
+	MaxConcurrency int
+	// DisableHelp stops the bot answering <prefix>help with its commands
+	DisableHelp bool
+	// ErrorHandler is called with the errors handlers return; by default
+	// they are logged
+	ErrorHandler func(ctx context.Context, msg *Message, err error)
+	// Connect configures the chatroom connections
+	Connect client.ConnectOptions
+	Logger  *slog.Logger
+}
+
+type command struct {
+	name        string
+	description string
+	handler     HandlerFunc
+}
+
+// Bot routes the messages of the chatrooms it runs in to its handlers
+type Bot struct {
+	client *client.Client
+	opts   Options
+
+	mu         sync.RWMutex
+	commands   map[string]command
+	onMessage  HandlerFunc
+	notFound   HandlerFunc
+	middleware []Middleware
+}
+
+// New returns a bot that uses c, which must be logged in or have a session
+// token, and is a member of the chatrooms it will run in
+func New(c *client.Client, opts Options) *Bot {
+	if opts.Prefix == "" {
+		opts.Prefix = defaultPrefix
+	}
+	if opts.HandlerTimeout <= 0 {
+		opts.HandlerTimeout = defaultHandlerTimeout
+	}
+	if opts.ShutdownTimeout <= 0 {
+		opts.ShutdownTimeout = defaultShutdownTimeout
+	}
+	if opts.MaxConcurrency <= 0 {
+		opts.MaxConcurrency = defaultMaxConcurrency
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	b := &Bot{
+		client:   c,
+		opts:     opts,
+		commands: make(map[string]command),
+	}
+	if b.opts.ErrorHandler == nil {
+		b.opts.ErrorHandler = b.logError
+	}
+	return b
+}
+
+// Client returns the SDK client the bot uses, e.g. to call the REST API
+// from handlers
+func (b *Bot) Client() *client.Client {
+	return b.client
+}
+
+// Use appends middleware run around every handler, in the order given
+func (b *Bot) Use(middleware ...Middleware) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.middleware = append(b.middleware, middleware...)
+}
+
+// Command routes <prefix><name> messages to h. Names are case-insensitive;
+// the description is listed by the help command.
+func (b *Bot) Command(name, description string, h HandlerFunc) {
+	name = strings.ToLower(name)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.commands[name] = command{name: name, description: description, handler: h}
+}
+
+// OnMessage handles the messages that are not commands
+func (b *Bot) OnMessage(h HandlerFunc) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onMessage = h
+}
+
+// NotFound handles commands no handler is registered for; they are ignored
+// by default
+func (b *Bot) NotFound(h HandlerFunc) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.notFound = h
+}
+
+// Run connects to the chatrooms and handles their messages until ctx is
+// canceled or every connection ended for good. On cancellation it stops
+// taking messages, gives running handlers ShutdownTimeout to finish, then
+// closes the connections and returns nil. Otherwise it returns why the
+// connections ended.
+func (b *Bot) Run(ctx context.Context, chatroomIDs ...string) error {
+	if len(chatroomIDs) == 0 {
+		return errors.New("no chatrooms to run in")
+	}
+
+	self := b.client.User()
+	if self == nil {
+		var err error
+		if self, err = b.client.Me(ctx); err != nil {
+			return fmt.Errorf("failed to identify the bot's user: %w", err)
+		}
+	}
+
+	conns := make([]*client.Conn, 0, len(chatroomIDs))
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	for _, chatroomID := range chatroomIDs {
+		conn, err := b.client.Connect(ctx, chatroomID, b.opts.Connect)
+		if err != nil {
+			return fmt.Errorf("failed to connect to chatroom %s: %w", chatroomID, err)
+		}
+		conns = append(conns, conn)
+	}
+	b.opts.Logger.Info("bot running",
+		slog.String("user", self.Username),
+		slog.Int("chatrooms", len(conns)))
+
+	// Handlers outlive ctx so they can finish during shutdown
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+
+	d := &dispatcher{
+		bot:     b,
+		self:    self.ID,
+		ctx:     handlerCtx,
+		running: make(chan struct{}, b.opts.MaxConcurrency),
+	}
+
+	var readers sync.WaitGroup
+	errs := make([]error, len(conns))
+	for i, conn := range conns {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			errs[i] = d.serve(ctx, conn)
+		}()
+	}
+	readers.Wait()
+
+	finished := make(chan struct{})
+	go func() {
+		d.handlers.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(b.opts.ShutdownTimeout):
+		b.opts.Logger.Warn("bot handlers did not finish in time, canceling them")
+		cancelHandlers()
+		<-finished
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+func (b *Bot) logError(_ context.Context, msg *Message, err error) {
+	b.opts.Logger.Error("bot handler failed",
+		slog.String("chatroom_id", msg.ChatroomID),
+		slog.String("message_id", msg.ID),
+		slog.String("command", msg.Command),
+		slog.String("error", err.Error()))
+}
+
+// route returns the handler of a message wrapped in the middleware, or nil
+// if nothing handles it
+func (b *Bot) route(msg *Message) HandlerFunc {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var h HandlerFunc
+	switch {
+	case !msg.IsCommand():
+		h = b.onMessage
+	case b.commands[msg.Command].handler != nil:
+		h = b.commands[msg.Command].handler
+	case msg.Command == "help" && !b.opts.DisableHelp:
+		h = b.help
+	default:
+		h = b.notFound
+	}
+	if h == nil {
+		return nil
+	}
+
+	for i := len(b.middleware) - 1; i >= 0; i-- {
+		h = b.middleware[i](h)
+	}
+	return h
+}
+
+// help replies with the commands and their descriptions
+func (b *Bot) help(_ context.Context, msg *Message) error {
+	b.mu.RLock()
+	commands := make([]command, 0, len(b.commands))
+	for _, cmd := range b.commands {
+		commands = append(commands, cmd)
+	}
+	b.mu.RUnlock()
+	slices.SortFunc(commands, func(a, b command) int { return cmp.Compare(a.name, b.name) })
+
+	var sb strings.Builder
+	sb.WriteString("Commands:")
+	for _, cmd := range commands {
+		sb.WriteString("\n" + b.opts.Prefix + cmd.name)
+		if cmd.description != "" {
+			sb.WriteString(" - " + cmd.description)
+		}
+	}
+	return msg.Reply(sb.String())
+}
+
+// dispatcher runs the handlers of a Run
+type dispatcher struct {
+	bot  *Bot
+	self string
+	ctx  context.Context
+
+	running  chan struct{}
+	handlers sync.WaitGroup
+}
+
+// serve dispatches the messages of a connection until ctx is canceled or
+// the connection ends for good
+func (d *dispatcher) serve(ctx context.Context, conn *client.Conn) error {
+	logger := d.bot.opts.Logger.With(slog.String("chatroom_id", conn.ChatroomID()))
+	for {
+		var event client.Event
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-conn.Events():
+			if !ok {
+				return nil
+			}
+			event = e
+		}
+
+		switch e := event.(type) {
+		case *client.MessageEvent:
+			if !d.dispatch(ctx, conn, e.Message) {
+				return nil
+			}
+		case *client.MessagesSinceEvent:
+			// Messages posted while the connection was down
+			for _, msg := range e.Messages {
+				if !d.dispatch(ctx, conn, msg) {
+					return nil
+				}
+			}
+		case *client.DisconnectedEvent:
+			if e.Final {
+				logger.Error("bot disconnected", slog.String("error", fmt.Sprint(e.Err)))
+				return fmt.Errorf("chatroom %s: %w", conn.ChatroomID(), e.Err)
+			}
+			logger.Warn("bot connection lost, reconnecting", slog.String("error", fmt.Sprint(e.Err)))
+		case *client.ReconnectedEvent:
+			logger.Info("bot reconnected", slog.Int("attempts", e.Attempts))
+		}
+	}
+}
+
+// dispatch runs the handler of a message once fewer than MaxConcurrency
+// are running, reporting false if ctx was canceled while waiting
+func (d *dispatcher) dispatch(ctx context.Context, conn *client.Conn, m client.Message) bool {
+	if m.UserID == d.self {
+		return true
+	}
+	msg := newMessage(m, d.bot.opts.Prefix, conn)
+	h := d.bot.route(msg)
+	if h == nil {
+		return true
+	}
+
+	select {
+	case d.running <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+
+	d.handlers.Add(1)
+	go func() {
+		defer d.handlers.Done()
+		defer func() { <-d.running }()
+
+		hctx, cancel := context.WithTimeout(d.ctx, d.bot.opts.HandlerTimeout)
+		defer cancel()
+
+		if err := d.call(hctx, h, msg); err != nil {
+			d.bot.opts.ErrorHandler(hctx, msg, err)
+		}
+	}()
+	return true
+}
+
+// call runs a handler, turning a panic into an error so one bad message
+// does not take the bot down
+func (d *dispatcher) call(ctx context.Context, h HandlerFunc, msg *Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			d.bot.opts.Logger.Error("bot handler panicked",
+				slog.Any("panic", r),
+				slog.String("stack", string(debug.Stack())))
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return h(ctx, msg)
+}
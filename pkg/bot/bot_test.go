@@ -0,0 +1,253 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"jobsity-chat/pkg/client"
+)
+
+// frame is a frame the bot sends
+type frame struct {
+	Type            string `json:"type"`
+	Content         string `json:"content"`
+	QuotedMessageID string `json:"quoted_message_id"`
+}
+
+// chatServer is a chat server with one chatroom, room1, whose connection
+// sends the given messages and forwards what the bot sends to sent
+type chatServer struct {
+	client *client.Client
+	sent   chan frame
+}
+
+func newChatServer(t *testing.T, messages ...map[string]any) *chatServer {
+	t.Helper()
+	s := &chatServer{sent: make(chan frame, 16)}
+	upgrader := websocket.Upgrader{Subprotocols: []string{"chat"}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/auth/me", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"id": "bot1", "username": "helper"})
+	})
+	mux.HandleFunc("/ws/chat/room1", func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for _, msg := range messages {
+			msg["type"] = "chat_message"
+			ws.WriteJSON(msg)
+		}
+		for {
+			var f frame
+			if err := ws.ReadJSON(&f); err != nil {
+				return
+			}
+			s.sent <- f
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	c, err := client.New(srv.URL)
+	if err != nil {
+		t.Fatalf("client.New() error = %v", err)
+	}
+	c.SetToken("tok")
+	s.client = c
+	return s
+}
+
+func (s *chatServer) next(t *testing.T) frame {
+	t.Helper()
+	select {
+	case f := <-s.sent:
+		return f
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the bot to send")
+		return frame{}
+	}
+}
+
+// run runs b in room1 until the returned stop is called, which returns
+// Run's error
+func run(t *testing.T, b *Bot) (stop func() error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- b.Run(ctx, "room1") }()
+
+	var stopped atomic.Bool
+	t.Cleanup(func() {
+		if !stopped.Load() {
+			cancel()
+			<-result
+		}
+	})
+	return func() error {
+		stopped.Store(true)
+		cancel()
+		select {
+		case err := <-result:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run did not return")
+			return nil
+		}
+	}
+}
+
+func TestBot_RoutesMessages(t *testing.T) {
+	s := newChatServer(t,
+		map[string]any{"id": "m1", "user_id": "bot1", "content": "!ping"},
+		map[string]any{"id": "m2", "user_id": "u1", "content": "!unknown"},
+		map[string]any{"id": "m3", "user_id": "u1", "content": "hello there"},
+		map[string]any{"id": "m4", "user_id": "u1", "content": "  !PING  a  b "},
+	)
+	b := New(s.client, Options{MaxConcurrency: 1})
+
+	b.Command("ping", "Answer pong", func(ctx context.Context, msg *Message) error {
+		return msg.Reply("pong " + strings.Join(msg.Args, ","))
+	})
+	b.OnMessage(func(ctx context.Context, msg *Message) error {
+		return msg.Send("heard " + msg.Content)
+	})
+	stop := run(t, b)
+
+	// The bot's own message and the unknown command are ignored
+	if f := s.next(t); f.Content != "heard hello there" || f.QuotedMessageID != "" {
+		t.Errorf("first frame = %+v, want the message handler's", f)
+	}
+	if f := s.next(t); f.Content != "pong a,b" || f.QuotedMessageID != "m4" {
+		t.Errorf("second frame = %+v, want a reply to m4", f)
+	}
+	if err := stop(); err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+func TestBot_Help(t *testing.T) {
+	s := newChatServer(t, map[string]any{"id": "m1", "user_id": "u1", "content": "!help"})
+	b := New(s.client, Options{})
+	b.Command("roll", "Roll a die", func(context.Context, *Message) error { return nil })
+	b.Command("Flip", "", func(context.Context, *Message) error { return nil })
+	run(t, b)
+
+	f := s.next(t)
+	if f.Content != "Commands:\n!flip\n!roll - Roll a die" || f.QuotedMessageID != "m1" {
+		t.Errorf("help = %+v", f)
+	}
+}
+
+func TestBot_HandlerErrorsAndPanics(t *testing.T) {
+	s := newChatServer(t,
+		map[string]any{"id": "m1", "user_id": "u1", "content": "!fail"},
+		map[string]any{"id": "m2", "user_id": "u1", "content": "!panic"},
+	)
+	errs := make(chan error, 2)
+	b := New(s.client, Options{ErrorHandler: func(ctx context.Context, msg *Message, err error) {
+		errs <- err
+	}})
+	b.Command("fail", "", func(context.Context, *Message) error { return errors.New("boom") })
+	b.Command("panic", "", func(context.Context, *Message) error { panic("oops") })
+	run(t, b)
+
+	for range 2 {
+		select {
+		case err := <-errs:
+			if err == nil {
+				t.Error("ErrorHandler got a nil error")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for handler errors")
+		}
+	}
+}
+
+func TestBot_ShutdownWaitsForHandlers(t *testing.T) {
+	s := newChatServer(t, map[string]any{"id": "m1", "user_id": "u1", "content": "!slow"})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	b := New(s.client, Options{ShutdownTimeout: 5 * time.Second})
+	b.Command("slow", "", func(ctx context.Context, msg *Message) error {
+		close(started)
+		<-release
+		return msg.Reply("done")
+	})
+	stop := run(t, b)
+
+	<-started
+	result := make(chan error, 1)
+	go func() { result <- stop() }()
+
+	select {
+	case <-result:
+		t.Fatal("Run returned before the handler finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+
+	// The connection stays open for the handler's reply
+	if f := s.next(t); f.Content != "done" {
+		t.Errorf("reply = %+v", f)
+	}
+	if err := <-result; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+func TestBot_ShutdownCancelsSlowHandlers(t *testing.T) {
+	s := newChatServer(t, map[string]any{"id": "m1", "user_id": "u1", "content": "!stuck"})
+	started := make(chan struct{})
+	b := New(s.client, Options{ShutdownTimeout: 10 * time.Millisecond})
+	b.Command("stuck", "", func(ctx context.Context, msg *Message) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	stop := run(t, b)
+
+	<-started
+	if err := stop(); err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+func TestBot_RunWithoutChatrooms(t *testing.T) {
+	s := newChatServer(t)
+	if err := New(s.client, Options{}).Run(context.Background()); err == nil {
+		t.Error("Run() without chatrooms expected error")
+	}
+}
+
+func TestNewMessage(t *testing.T) {
+	tests := []struct {
+		content string
+		command string
+		text    string
+		args    int
+	}{
+		{"!roll", "roll", "", 0},
+		{"!Roll 2d6  fast", "roll", "2d6  fast", 2},
+		{"! roll", "", "", 0},
+		{"roll", "", "", 0},
+		{"/stock=AAPL.US", "", "", 0},
+	}
+	for _, tt := range tests {
+		msg := newMessage(client.Message{Content: tt.content}, "!", nil)
+		if msg.Command != tt.command || msg.Text != tt.text || len(msg.Args) != tt.args {
+			t.Errorf("newMessage(%q) = %q %q %v", tt.content, msg.Command, msg.Text, msg.Args)
+		}
+	}
+}
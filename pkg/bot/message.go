@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"strings"
+
+	"jobsity-chat/pkg/client"
+)
+
+// Message is a chatroom message a handler receives, parsed as a command when
+// it starts with the bot's prefix
+type Message struct {
+	client.Message
+	// Command is the lowercased command name without the prefix, empty for
+	// messages that are not commands
+	Command string
+	// Args are the whitespace-separated words after the command name
+	Args []string
+	// Text is everything after the command name, trimmed
+	Text string
+
+	conn *client.Conn
+}
+
+func newMessage(m client.Message, prefix string, conn *client.Conn) *Message {
+	msg := &Message{Message: m, conn: conn}
+
+	rest, ok := strings.CutPrefix(strings.TrimSpace(m.Content), prefix)
+	if !ok {
+		return msg
+	}
+	name, text, _ := strings.Cut(rest, " ")
+	if name == "" {
+		return msg
+	}
+	msg.Command = strings.ToLower(name)
+	msg.Text = strings.TrimSpace(text)
+	msg.Args = strings.Fields(msg.Text)
+	return msg
+}
+
+// IsCommand reports whether the message is a command
+func (m *Message) IsCommand() bool {
+	return m.Command != ""
+}
+
+// Reply posts text to the message's chatroom quoting the message
+func (m *Message) Reply(text string) error {
+	_, err := m.conn.Reply(m.ID, text)
+	return err
+}
+
+// Send posts text to the message's chatroom
+func (m *Message) Send(text string) error {
+	_, err := m.conn.Send(text)
+	return err
+}
+
+// Conn returns the connection the message arrived on
+func (m *Message) Conn() *client.Conn {
+	return m.conn
+}
@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AllowUsers only lets messages of the given usernames through, e.g. for
+// commands reserved to operators
+func AllowUsers(usernames ...string) Middleware {
+	allowed := make([]string, len(usernames))
+	for i, username := range usernames {
+		allowed[i] = strings.ToLower(username)
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg *Message) error {
+			if !slices.Contains(allowed, strings.ToLower(msg.Username)) {
+				return nil
+			}
+			return next(ctx, msg)
+		}
+	}
+}
+
+// IgnoreBots drops the messages the server posts as a bot, such as stock
+// quotes, so bots do not answer each other in a loop
+func IgnoreBots(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, msg *Message) error {
+		if msg.IsBot {
+			return nil
+		}
+		return next(ctx, msg)
+	}
+}
+
+// Cooldown drops the commands of a user sent within d of the user's
+// previous handled command; other messages pass through
+func Cooldown(d time.Duration) Middleware {
+	var mu sync.Mutex
+	last := make(map[string]time.Time)
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg *Message) error {
+			if !msg.IsCommand() {
+				return next(ctx, msg)
+			}
+
+			now := time.Now()
+			mu.Lock()
+			if at, ok := last[msg.UserID]; ok && now.Sub(at) < d {
+				mu.Unlock()
+				return nil
+			}
+			last[msg.UserID] = now
+			// Forget users whose cooldown is over so the map stays small
+			for userID, at := range last {
+				if now.Sub(at) >= d {
+					delete(last, userID)
+				}
+			}
+			mu.Unlock()
+
+			return next(ctx, msg)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"jobsity-chat/pkg/client"
+)
+
+func counting(calls *int) HandlerFunc {
+	return func(context.Context, *Message) error {
+		*calls++
+		return nil
+	}
+}
+
+func TestAllowUsers(t *testing.T) {
+	var calls int
+	h := AllowUsers("Alice")(counting(&calls))
+
+	h(context.Background(), &Message{Message: client.Message{Username: "alice"}})
+	h(context.Background(), &Message{Message: client.Message{Username: "mallory"}})
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestIgnoreBots(t *testing.T) {
+	var calls int
+	h := IgnoreBots(counting(&calls))
+
+	h(context.Background(), &Message{Message: client.Message{IsBot: true}})
+	h(context.Background(), &Message{})
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestCooldown(t *testing.T) {
+	var calls int
+	h := Cooldown(time.Hour)(counting(&calls))
+	command := func(userID string) *Message {
+		return &Message{Message: client.Message{UserID: userID}, Command: "roll"}
+	}
+
+	h(context.Background(), command("u1"))
+	h(context.Background(), command("u1"))
+	h(context.Background(), command("u2"))
+	h(context.Background(), &Message{Message: client.Message{UserID: "u1"}})
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3: one command of each user and the plain message", calls)
+	}
+}
+
+func TestBot_MiddlewareOrder(t *testing.T) {
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(ctx context.Context, msg *Message) error {
+				order = append(order, name)
+				return next(ctx, msg)
+			}
+		}
+	}
+
+	b := New(nil, Options{})
+	b.Use(mark("first"), mark("second"))
+	b.Command("roll", "", func(context.Context, *Message) error {
+		order = append(order, "handler")
+		return nil
+	})
+
+	h := b.route(&Message{Command: "roll"})
+	if h == nil {
+		t.Fatal("route() = nil")
+	}
+	h(context.Background(), &Message{Command: "roll"})
+	if len(order) != 3 || order[0] != "first" || order[1] != "second" || order[2] != "handler" {
+		t.Errorf("order = %v", order)
+	}
+	if b.route(&Message{Command: "unknown"}) != nil {
+		t.Error("route() of an unknown command without NotFound should be nil")
+	}
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// RegisterRequest creates an account
+type RegisterRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	// InviteToken is the token of an emailed chatroom invite, if any
+	InviteToken string `json:"invite_token,omitempty"`
+}
+
+// Register creates an account. It does not log in.
+func (c *Client) Register(ctx context.Context, req RegisterRequest) (*User, error) {
+	var user User
+	if err := c.call(ctx, http.MethodPost, "/api/v1/auth/register", nil, req, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Login starts a session; the client authenticates with it from then on
+func (c *Client) Login(ctx context.Context, username, password string) (*User, error) {
+	body := struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}{username, password}
+
+	var resp struct {
+		User         User   `json:"user"`
+		SessionToken string `json:"session_token"`
+		CSRFToken    string `json:"csrf_token"`
+	}
+	if err := c.call(ctx, http.MethodPost, "/api/v1/auth/login", nil, body, &resp); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = resp.SessionToken
+	c.csrfToken = resp.CSRFToken
+	c.user = &resp.User
+	return &resp.User, nil
+}
+
+// Logout ends the session
+func (c *Client) Logout(ctx context.Context) error {
+	if err := c.call(ctx, http.MethodPost, "/api/v1/auth/logout", nil, nil, nil); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = ""
+	c.csrfToken = ""
+	c.user = nil
+	return nil
+}
+
+// Me returns the user the session belongs to
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var user User
+	if err := c.call(ctx, http.MethodGet, "/api/v1/auth/me", nil, nil, &user); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.user = &user
+	return &user, nil
+}
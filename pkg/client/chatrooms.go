@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListChatroomsOptions filters and pages the chatroom directory; zero
+// values leave a filter out
+type ListChatroomsOptions struct {
+	// Name matches a substring of the chatroom name
+	Name string
+	// Tags only lists chatrooms carrying every one of them
+	Tags []string
+	// Starred only lists the chatrooms the user starred
+	Starred bool
+	// Sort is newest, active or members
+	Sort   string
+	Limit  int
+	Cursor string
+}
+
+// CreateChatroom creates a chatroom the user owns and belongs to
+func (c *Client) CreateChatroom(ctx context.Context, name string) (*Chatroom, error) {
+	body := struct {
+		Name string `json:"name"`
+	}{name}
+
+	var chatroom Chatroom
+	if err := c.call(ctx, http.MethodPost, "/api/v1/chatrooms", nil, body, &chatroom); err != nil {
+		return nil, err
+	}
+	return &chatroom, nil
+}
+
+// ListChatrooms returns a page of the chatroom directory
+func (c *Client) ListChatrooms(ctx context.Context, opts ListChatroomsOptions) (*ChatroomPage, error) {
+	query := url.Values{}
+	if opts.Name != "" {
+		query.Set("name", opts.Name)
+	}
+	if len(opts.Tags) > 0 {
+		query.Set("tag", strings.Join(opts.Tags, ","))
+	}
+	if opts.Starred {
+		query.Set("starred", "true")
+	}
+	if opts.Sort != "" {
+		query.Set("sort", opts.Sort)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		query.Set("cursor", opts.Cursor)
+	}
+
+	var page ChatroomPage
+	if err := c.call(ctx, http.MethodGet, "/api/v1/chatrooms", query, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// JoinChatroom makes the user a member of a chatroom
+func (c *Client) JoinChatroom(ctx context.Context, chatroomID string) error {
+	return c.call(ctx, http.MethodPost, "/api/v1/chatrooms/"+url.PathEscape(chatroomID)+"/join", nil, nil, nil)
+}
+
+// Messages returns up to limit of a chatroom's latest messages, or of those
+// posted before the given time unless it is zero; zero limit uses the
+// server's default
+func (c *Client) Messages(ctx context.Context, chatroomID string, limit int, before time.Time) ([]Message, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if !before.IsZero() {
+		query.Set("before", before.UTC().Format(time.RFC3339Nano))
+	}
+
+	var resp struct {
+		Messages []Message `json:"messages"`
+	}
+	if err := c.call(ctx, http.MethodGet, "/api/v1/chatrooms/"+url.PathEscape(chatroomID)+"/messages", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Messages, nil
+}
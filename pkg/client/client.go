@@ -0,0 +1,200 @@
+// Package client is a Go SDK for the chat server: a REST client for
+// authentication, chatrooms and messages, and a WebSocket connection to a
+// chatroom that reconnects on its own and delivers typed events. It only
+// depends on the standard library and gorilla/websocket, so integrations
+// and bots can use it outside this module.
+//
+//	c, err := client.New("https://chat.example.com")
+//	if err != nil { ... }
+//	if _, err := c.Login(ctx, "alice", "secret"); err != nil { ... }
+//	conn, err := c.Connect(ctx, chatroomID, client.ConnectOptions{})
+//	if err != nil { ... }
+//	defer conn.Close()
+//	for event := range conn.Events() {
+//		if msg, ok := event.(*client.MessageEvent); ok { ... }
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// sessionCookie carries the session token on REST requests
+	sessionCookie = "session_id"
+	// csrfHeader carries the CSRF token servers in embedded cookie mode
+	// require on requests that change state
+	csrfHeader = "X-CSRF-Token"
+
+	defaultTimeout = 30 * time.Second
+)
+
+// Client calls the chat server's REST API as one user. It is safe for
+// concurrent use.
+type Client struct {
+	baseURL *url.URL
+	http    *http.Client
+
+	mu        sync.RWMutex
+	token     string
+	csrfToken string
+	user      *User
+}
+
+// New returns a Client for the server at baseURL, e.g.
+// https://chat.example.com, with no session until Login or SetToken
+func New(baseURL string) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: want http(s)://host", baseURL)
+	}
+	return &Client{
+		baseURL: u,
+		http:    &http.Client{Timeout: defaultTimeout},
+	}, nil
+}
+
+// SetHTTPClient replaces the HTTP client requests are sent with, e.g. to
+// set a proxy or a different timeout
+func (c *Client) SetHTTPClient(hc *http.Client) {
+	c.http = hc
+}
+
+// HTTPClient returns the HTTP client requests are sent with
+func (c *Client) HTTPClient() *http.Client {
+	return c.http
+}
+
+// SetToken authenticates the client with an existing session token, e.g. one
+// a bot was provisioned with, instead of logging in
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// Token returns the session token, empty until Login or SetToken
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// User returns the user the client logged in as, nil before Login or Me
+func (c *Client) User() *User {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.user
+}
+
+// APIError is a response of the server outside the 2xx range
+type APIError struct {
+	StatusCode int
+	// Message is the server's error message, or the status text when the
+	// body carries none
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("chat server returned %d: %s", e.StatusCode, e.Message)
+}
+
+// IsStatus reports whether err is an APIError with the given status code
+func IsStatus(err error, code int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == code
+}
+
+// NewRequest builds an authenticated request to path, relative to the base
+// URL, with body encoded as JSON unless nil. It lets callers reach endpoints
+// the SDK does not wrap; send it with Do or HTTPClient().Do.
+func (c *Client) NewRequest(ctx context.Context, method, path string, body any) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request body: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.String()+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.token != "" {
+		req.AddCookie(&http.Cookie{Name: sessionCookie, Value: c.token})
+	}
+	if c.csrfToken != "" {
+		req.Header.Set(csrfHeader, c.csrfToken)
+	}
+	return req, nil
+}
+
+// Do sends req and decodes the JSON response into out, unless out is nil.
+// Responses outside the 2xx range are returned as an *APIError.
+func (c *Client) Do(req *http.Request, out any) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return responseError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// call sends a request built by NewRequest with query added to the URL
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	req, err := c.NewRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	return c.Do(req, out)
+}
+
+// responseError reads the {"error":"..."} body the server answers errors with
+func responseError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		apiErr.Message = body.Error
+	} else if text := strings.TrimSpace(string(data)); text != "" {
+		apiErr.Message = text
+	}
+	return apiErr
+}
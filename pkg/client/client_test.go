@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return c
+}
+
+func TestNew_InvalidBaseURL(t *testing.T) {
+	for _, baseURL := range []string{"", "chat.example.com", "ftp://chat.example.com", "http://"} {
+		if _, err := New(baseURL); err == nil {
+			t.Errorf("New(%q) expected error", baseURL)
+		}
+	}
+}
+
+func TestClient_LoginAuthenticatesLaterRequests(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["username"] != "alice" || body["password"] != "secret" {
+			http.Error(w, `{"error":"invalid credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"user":          map[string]string{"id": "u1", "username": "alice"},
+			"session_token": "tok",
+			"csrf_token":    "csrf",
+		})
+	})
+	mux.HandleFunc("POST /api/v1/chatrooms", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(sessionCookie)
+		if err != nil || cookie.Value != "tok" || r.Header.Get(csrfHeader) != "csrf" {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"id": "room1", "name": "general", "created_by": "u1"})
+	})
+	c := newTestClient(t, mux)
+	ctx := context.Background()
+
+	if _, err := c.Login(ctx, "alice", "wrong"); !IsStatus(err, http.StatusUnauthorized) {
+		t.Fatalf("Login() with a wrong password error = %v, want 401", err)
+	}
+
+	user, err := c.Login(ctx, "alice", "secret")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if user.ID != "u1" || c.User().Username != "alice" || c.Token() != "tok" {
+		t.Errorf("Login() user = %+v, token = %q", user, c.Token())
+	}
+
+	chatroom, err := c.CreateChatroom(ctx, "general")
+	if err != nil {
+		t.Fatalf("CreateChatroom() error = %v", err)
+	}
+	if chatroom.ID != "room1" || chatroom.CreatedBy != "u1" {
+		t.Errorf("CreateChatroom() = %+v", chatroom)
+	}
+}
+
+func TestClient_APIError(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"chatroom not found"}`, http.StatusNotFound)
+	}))
+
+	err := c.JoinChatroom(context.Background(), "missing")
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("JoinChatroom() error = %v, want *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "chatroom not found" {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+}
+
+func TestClient_ListChatroomsQuery(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want := "limit=5&name=go&sort=active&starred=true&tag=a%2Cb"
+		if r.URL.RawQuery != want {
+			t.Errorf("query = %q, want %q", r.URL.RawQuery, want)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"chatrooms": []map[string]string{{"id": "room1"}}, "next_cursor": "next"})
+	}))
+
+	page, err := c.ListChatrooms(context.Background(), ListChatroomsOptions{
+		Name: "go", Tags: []string{"a", "b"}, Starred: true, Sort: "active", Limit: 5,
+	})
+	if err != nil {
+		t.Fatalf("ListChatrooms() error = %v", err)
+	}
+	if len(page.Chatrooms) != 1 || page.NextCursor != "next" {
+		t.Errorf("ListChatrooms() = %+v", page)
+	}
+}
+
+func TestClient_MessagesBefore(t *testing.T) {
+	before := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/chatrooms/room1/messages" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("before"); got != "2026-01-02T03:04:05Z" {
+			t.Errorf("before = %q", got)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"messages": []map[string]string{{"id": "m1", "content": "hi"}}})
+	}))
+
+	messages, err := c.Messages(context.Background(), "room1", 0, before)
+	if err != nil {
+		t.Fatalf("Messages() error = %v", err)
+	}
+	if len(messages) != 1 || messages[0].Content != "hi" {
+		t.Errorf("Messages() = %+v", messages)
+	}
+}
@@ -0,0 +1,422 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// chatSubprotocol is the subprotocol the server selects; the session
+	// token travels as a second token.<token> entry so it stays out of URLs
+	// and access logs
+	chatSubprotocol  = "chat"
+	tokenSubprotocol = "token."
+
+	// ProtocolVersion is the version of the WebSocket protocol the SDK
+	// speaks, asked for in every handshake
+	ProtocolVersion = 1
+
+	// Close codes the server ends a connection with that reconnecting
+	// cannot recover from: another connection of the user replaced it, was
+	// kept instead of it, an admin disconnected the user, or the server no
+	// longer speaks ProtocolVersion
+	closeReplaced                   = 4001
+	closeDuplicateConnection        = 4002
+	closeDisconnected               = 4003
+	closeUnsupportedProtocolVersion = 4005
+
+	// readTimeout exceeds the server's ping period, so a connection that
+	// went silent is detected and re-established
+	readTimeout      = 75 * time.Second
+	writeTimeout     = 10 * time.Second
+	handshakeTimeout = 10 * time.Second
+
+	defaultMinBackoff  = 500 * time.Millisecond
+	defaultMaxBackoff  = 30 * time.Second
+	defaultEventBuffer = 256
+)
+
+var (
+	// ErrNotConnected is returned by sends while the connection is being
+	// re-established
+	ErrNotConnected = errors.New("not connected")
+	// ErrClosed is returned by sends after the connection ended
+	ErrClosed = errors.New("connection closed")
+)
+
+// ConnectOptions configures a WebSocket connection; the zero value
+// reconnects with the default backoff
+type ConnectOptions struct {
+	// DisableReconnect ends the connection the first time it drops
+	DisableReconnect bool
+	// MinBackoff and MaxBackoff bound the delay between reconnection
+	// attempts, which doubles from MinBackoff; default 500ms and 30s
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// EventBuffer is the capacity of the events channel; default 256. Reads
+	// from the server stop while it is full.
+	EventBuffer int
+}
+
+// Conn is a WebSocket connection to a chatroom. It re-establishes itself
+// when it drops, unless the server ended it for good, and catches up on the
+// messages posted meanwhile. It is safe for concurrent use.
+type Conn struct {
+	client     *Client
+	chatroomID string
+	opts       ConnectOptions
+	dialer     *websocket.Dialer
+
+	events chan Event
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	ws     *websocket.Conn
+	lastID string
+}
+
+// Connect opens a WebSocket connection to a chatroom the user belongs to.
+// ctx bounds the first dial only; the connection lasts until Close or a
+// final DisconnectedEvent.
+func (c *Client) Connect(ctx context.Context, chatroomID string, opts ConnectOptions) (*Conn, error) {
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = defaultMinBackoff
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = max(defaultMaxBackoff, opts.MinBackoff)
+	}
+	if opts.EventBuffer <= 0 {
+		opts.EventBuffer = defaultEventBuffer
+	}
+
+	conn := &Conn{
+		client:     c,
+		chatroomID: chatroomID,
+		opts:       opts,
+		dialer: &websocket.Dialer{
+			Proxy:            http.ProxyFromEnvironment,
+			HandshakeTimeout: handshakeTimeout,
+		},
+		events: make(chan Event, opts.EventBuffer),
+		done:   make(chan struct{}),
+	}
+
+	ws, err := conn.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn.ws = ws
+	conn.ctx, conn.cancel = context.WithCancel(context.Background())
+
+	go conn.run(ws)
+	return conn, nil
+}
+
+// ChatroomID returns the chatroom the connection belongs to
+func (c *Conn) ChatroomID() string {
+	return c.chatroomID
+}
+
+// Events returns the events of the connection. It is closed once the
+// connection ends, after a final DisconnectedEvent unless Close ended it.
+func (c *Conn) Events() <-chan Event {
+	return c.events
+}
+
+// Done is closed once the connection ended and Events was closed
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Send posts a message and returns the client_msg_id it was sent with. The
+// server answers with an AckEvent or an ErrorEvent carrying the same ID.
+func (c *Conn) Send(content string) (string, error) {
+	return c.send(content, "")
+}
+
+// Reply posts a message quoting another message of the chatroom
+func (c *Conn) Reply(quotedMessageID, content string) (string, error) {
+	return c.send(content, quotedMessageID)
+}
+
+func (c *Conn) send(content, quotedMessageID string) (string, error) {
+	clientMsgID := newClientMsgID()
+	err := c.write(outgoingFrame{
+		Type:            "message",
+		Content:         content,
+		ClientMsgID:     clientMsgID,
+		QuotedMessageID: quotedMessageID,
+	})
+	if err != nil {
+		return "", err
+	}
+	return clientMsgID, nil
+}
+
+// FetchSince asks for up to limit messages posted after the message sinceID,
+// answered with a MessagesSinceEvent; zero limit uses the server's default
+func (c *Conn) FetchSince(sinceID string, limit int) error {
+	return c.write(outgoingFrame{Type: "fetch_since", SinceID: sinceID, Limit: limit})
+}
+
+// Close ends the connection and waits for the events channel to close
+func (c *Conn) Close() error {
+	c.cancel()
+
+	c.mu.Lock()
+	ws := c.ws
+	c.mu.Unlock()
+
+	var err error
+	if ws != nil {
+		msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		_ = ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeTimeout))
+		err = ws.Close()
+	}
+	<-c.done
+	return err
+}
+
+// outgoingFrame is a frame the client sends
+type outgoingFrame struct {
+	Type            string `json:"type"`
+	Content         string `json:"content,omitempty"`
+	ClientMsgID     string `json:"client_msg_id,omitempty"`
+	SinceID         string `json:"since_id,omitempty"`
+	Limit           int    `json:"limit,omitempty"`
+	QuotedMessageID string `json:"quoted_message_id,omitempty"`
+}
+
+func (c *Conn) write(f outgoingFrame) error {
+	if c.ctx.Err() != nil {
+		return ErrClosed
+	}
+
+	// Holding the lock for the write also serializes writers, which
+	// gorilla/websocket requires
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ws == nil {
+		return ErrNotConnected
+	}
+	if err := c.ws.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	return c.ws.WriteJSON(f)
+}
+
+// dial opens a WebSocket connection. A handshake the server refused is
+// returned as an *APIError.
+func (c *Conn) dial(ctx context.Context) (*websocket.Conn, error) {
+	u := *c.client.baseURL
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+	u.Path += "/ws/chat/" + url.PathEscape(c.chatroomID)
+	u.RawQuery = url.Values{"protocol_version": {strconv.Itoa(ProtocolVersion)}}.Encode()
+
+	dialer := *c.dialer
+	dialer.Subprotocols = []string{chatSubprotocol}
+	if token := c.client.Token(); token != "" {
+		dialer.Subprotocols = append(dialer.Subprotocols, tokenSubprotocol+token)
+	}
+
+	ws, resp, err := dialer.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		if resp != nil && errors.Is(err, websocket.ErrBadHandshake) {
+			defer resp.Body.Close()
+			return nil, responseError(resp)
+		}
+		return nil, fmt.Errorf("failed to connect to chatroom: %w", err)
+	}
+	return ws, nil
+}
+
+// run delivers the events of the connection until it ends for good
+func (c *Conn) run(ws *websocket.Conn) {
+	defer close(c.done)
+	defer close(c.events)
+	defer c.cancel()
+
+	for {
+		err := c.read(ws)
+		c.mu.Lock()
+		c.ws = nil
+		c.mu.Unlock()
+		ws.Close()
+		if c.ctx.Err() != nil {
+			return
+		}
+
+		final := c.opts.DisableReconnect || isFinal(err)
+		if !c.emit(&DisconnectedEvent{Err: err, Final: final}) || final {
+			return
+		}
+
+		var attempts int
+		ws, attempts, err = c.reconnect()
+		if err != nil {
+			if c.ctx.Err() == nil {
+				c.emit(&DisconnectedEvent{Err: err, Final: true})
+			}
+			return
+		}
+
+		c.mu.Lock()
+		c.ws = ws
+		lastID := c.lastID
+		c.mu.Unlock()
+		if c.ctx.Err() != nil {
+			ws.Close()
+			return
+		}
+
+		if !c.emit(&ReconnectedEvent{Attempts: attempts}) {
+			ws.Close()
+			return
+		}
+		if lastID != "" {
+			_ = c.FetchSince(lastID, 0)
+		}
+	}
+}
+
+// read delivers the frames of ws as events until it fails
+func (c *Conn) read(ws *websocket.Conn) error {
+	extend := func() error {
+		return ws.SetReadDeadline(time.Now().Add(readTimeout))
+	}
+	if err := extend(); err != nil {
+		return err
+	}
+	ws.SetPingHandler(func(data string) error {
+		if err := extend(); err != nil {
+			return err
+		}
+		err := ws.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(writeTimeout))
+		if errors.Is(err, websocket.ErrCloseSent) {
+			return nil
+		}
+		return err
+	})
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if err := extend(); err != nil {
+			return err
+		}
+
+		event, err := decodeEvent(data, c.chatroomID)
+		if err != nil {
+			// A malformed frame is the server's bug; skip it rather than
+			// dropping the connection
+			continue
+		}
+		c.track(event)
+		if !c.emit(event) {
+			return ErrClosed
+		}
+	}
+}
+
+// track remembers the latest message seen, which reconnecting catches up
+// from
+func (c *Conn) track(event Event) {
+	var id string
+	switch e := event.(type) {
+	case *MessageEvent:
+		id = e.ID
+	case *MessagesSinceEvent:
+		if len(e.Messages) > 0 {
+			id = e.Messages[len(e.Messages)-1].ID
+		}
+	}
+	if id == "" {
+		return
+	}
+	c.mu.Lock()
+	c.lastID = id
+	c.mu.Unlock()
+}
+
+// emit delivers an event, reporting false if the connection was closed
+// while waiting for room in the channel
+func (c *Conn) emit(event Event) bool {
+	select {
+	case c.events <- event:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+// reconnect dials until it succeeds, the connection is closed or the server
+// refuses it for good, waiting between attempts with exponential backoff
+func (c *Conn) reconnect() (*websocket.Conn, int, error) {
+	delay := c.opts.MinBackoff
+	for attempt := 1; ; attempt++ {
+		timer := time.NewTimer(delay)
+		select {
+		case <-c.ctx.Done():
+			timer.Stop()
+			return nil, attempt, c.ctx.Err()
+		case <-timer.C:
+		}
+
+		ws, err := c.dial(c.ctx)
+		if err == nil {
+			return ws, attempt, nil
+		}
+		if isFinal(err) {
+			return nil, attempt, err
+		}
+		delay = min(delay*2, c.opts.MaxBackoff)
+	}
+}
+
+// isFinal reports whether err ended the connection in a way reconnecting
+// cannot recover from: the session is no longer valid, the user may not
+// enter the chatroom, or the server closed the connection on purpose
+func isFinal(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+			return true
+		}
+		return false
+	}
+	return websocket.IsCloseError(err,
+		websocket.CloseNormalClosure,
+		websocket.ClosePolicyViolation,
+		closeReplaced,
+		closeDuplicateConnection,
+		closeDisconnected,
+		closeUnsupportedProtocolVersion,
+	)
+}
+
+// newClientMsgID returns a random ID the server echoes in the acknowledgment
+// or error of a message
+func newClientMsgID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsServer upgrades chatroom connections authenticated with token tok and
+// hands each to serve, numbered from 1
+func wsServer(t *testing.T, serve func(n int, ws *websocket.Conn)) *Client {
+	t.Helper()
+	upgrader := websocket.Upgrader{Subprotocols: []string{chatSubprotocol}}
+	var conns atomic.Int32
+
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ws/chat/room1" {
+			http.Error(w, `{"error":"chatroom not found"}`, http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("protocol_version") != "1" {
+			http.Error(w, `{"error":"Unsupported protocol version"}`, http.StatusBadRequest)
+			return
+		}
+		protocols := websocket.Subprotocols(r)
+		if len(protocols) != 2 || protocols[1] != tokenSubprotocol+"tok" {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		serve(int(conns.Add(1)), ws)
+	}))
+	c.SetToken("tok")
+	return c
+}
+
+func nextEvent(t *testing.T, conn *Conn) Event {
+	t.Helper()
+	select {
+	case event, ok := <-conn.Events():
+		if !ok {
+			t.Fatal("events closed")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for event")
+		return nil
+	}
+}
+
+func TestConn_SendAndReceive(t *testing.T) {
+	c := wsServer(t, func(_ int, ws *websocket.Conn) {
+		var f outgoingFrame
+		if err := ws.ReadJSON(&f); err != nil {
+			return
+		}
+		ws.WriteJSON(map[string]any{"type": "message_ack", "id": "m1", "client_msg_id": f.ClientMsgID})
+		ws.WriteJSON(map[string]any{"type": "chat_message", "id": "m1", "username": "alice", "content": f.Content, "client_msg_id": f.ClientMsgID})
+		ws.WriteJSON(map[string]any{"type": "something_new"})
+		ws.ReadMessage()
+	})
+
+	conn, err := c.Connect(context.Background(), "room1", ConnectOptions{})
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer conn.Close()
+
+	clientMsgID, err := conn.Send("hello")
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	ack, ok := nextEvent(t, conn).(*AckEvent)
+	if !ok || ack.MessageID != "m1" || ack.ClientMsgID != clientMsgID {
+		t.Errorf("first event = %+v, want ack of %s", ack, clientMsgID)
+	}
+	msg, ok := nextEvent(t, conn).(*MessageEvent)
+	if !ok || msg.Content != "hello" || msg.ChatroomID != "room1" || msg.ClientMsgID != clientMsgID {
+		t.Errorf("second event = %+v, want the message", msg)
+	}
+	if unknown, ok := nextEvent(t, conn).(*UnknownEvent); !ok || unknown.Type() != "something_new" {
+		t.Errorf("third event = %+v, want unknown event", unknown)
+	}
+}
+
+func TestConn_ReconnectsAndCatchesUp(t *testing.T) {
+	fetched := make(chan outgoingFrame, 1)
+	c := wsServer(t, func(n int, ws *websocket.Conn) {
+		if n == 1 {
+			ws.WriteJSON(map[string]any{"type": "chat_message", "id": "m1", "content": "before"})
+			// Drop the connection without a close frame
+			return
+		}
+		var f outgoingFrame
+		if err := ws.ReadJSON(&f); err != nil {
+			return
+		}
+		fetched <- f
+		ws.WriteJSON(map[string]any{"type": "messages_since", "messages": []map[string]string{{"id": "m2", "content": "missed"}}})
+		ws.ReadMessage()
+	})
+
+	conn, err := c.Connect(context.Background(), "room1", ConnectOptions{MinBackoff: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer conn.Close()
+
+	if _, ok := nextEvent(t, conn).(*MessageEvent); !ok {
+		t.Fatal("want the message before the disconnection")
+	}
+	if d, ok := nextEvent(t, conn).(*DisconnectedEvent); !ok || d.Final {
+		t.Fatalf("want a disconnection that is not final, got %+v", d)
+	}
+	if _, ok := nextEvent(t, conn).(*ReconnectedEvent); !ok {
+		t.Fatal("want a reconnection")
+	}
+	since, ok := nextEvent(t, conn).(*MessagesSinceEvent)
+	if !ok || len(since.Messages) != 1 || since.Messages[0].Content != "missed" {
+		t.Fatalf("want the missed messages, got %+v", since)
+	}
+	if f := <-fetched; f.Type != "fetch_since" || f.SinceID != "m1" {
+		t.Errorf("catch-up frame = %+v, want fetch_since from m1", f)
+	}
+}
+
+func TestConn_ReplacedIsFinal(t *testing.T) {
+	c := wsServer(t, func(_ int, ws *websocket.Conn) {
+		msg := websocket.FormatCloseMessage(closeReplaced, "replaced")
+		ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+		ws.ReadMessage()
+	})
+
+	conn, err := c.Connect(context.Background(), "room1", ConnectOptions{MinBackoff: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer conn.Close()
+
+	if d, ok := nextEvent(t, conn).(*DisconnectedEvent); !ok || !d.Final {
+		t.Fatalf("want a final disconnection, got %+v", d)
+	}
+	select {
+	case <-conn.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection did not end")
+	}
+	if _, err := conn.Send("hello"); err != ErrClosed {
+		t.Errorf("Send() after the end error = %v", err)
+	}
+}
+
+func TestConnect_Refused(t *testing.T) {
+	c := wsServer(t, func(int, *websocket.Conn) {})
+
+	if _, err := c.Connect(context.Background(), "other", ConnectOptions{}); !IsStatus(err, http.StatusNotFound) {
+		t.Errorf("Connect() to an unknown chatroom error = %v, want 404", err)
+	}
+	c.SetToken("expired")
+	if _, err := c.Connect(context.Background(), "room1", ConnectOptions{}); !IsStatus(err, http.StatusUnauthorized) {
+		t.Errorf("Connect() with an expired token error = %v, want 401", err)
+	}
+}
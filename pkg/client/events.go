@@ -0,0 +1,236 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event is something that happened on a WebSocket connection: a frame from
+// the server, or the connection dropping or coming back. Switch on its
+// concrete type; Type returns the frame type, or disconnected and
+// reconnected.
+type Event interface {
+	Type() string
+}
+
+// MessageEvent is a message posted to the chatroom (chat_message)
+type MessageEvent struct {
+	Message
+	// ClientMsgID is the ID the sender attached to the message, when it was
+	// sent on this connection
+	ClientMsgID string
+}
+
+// AckEvent acknowledges a message sent on this connection (message_ack).
+// MessageID is empty for commands, which are not stored.
+type AckEvent struct {
+	MessageID   string
+	ClientMsgID string
+}
+
+// ErrorEvent reports a message or command the server rejected (error), in
+// the user's language
+type ErrorEvent struct {
+	Message     string
+	ClientMsgID string
+}
+
+// WelcomeEvent carries the chatroom's welcome message, sent on a user's
+// first connection (welcome)
+type WelcomeEvent struct {
+	Message string
+}
+
+// MessagesSinceEvent answers FetchSince with the messages posted after the
+// given one, oldest first (messages_since). HasMore asks to fetch again
+// from the last one.
+type MessagesSinceEvent struct {
+	Messages []Message
+	HasMore  bool
+}
+
+// UserJoinedEvent and UserLeftEvent announce users connecting to and
+// leaving the chatroom (user_joined, user_left); Message is a sentence
+// describing it in the user's language
+type UserJoinedEvent struct {
+	Username string
+	Message  string
+}
+
+type UserLeftEvent struct {
+	Username string
+	Message  string
+}
+
+// UserCountEvent maps the organization's chatroom IDs to their connected
+// users (user_count_update)
+type UserCountEvent struct {
+	Counts map[string]int
+}
+
+// MessageUpdatedEvent carries the link previews of a message, fetched after
+// it was posted (message_updated)
+type MessageUpdatedEvent struct {
+	MessageID string
+	Previews  []LinkPreview
+}
+
+// RSVPEvent is a member's answer to an event (event_rsvp)
+type RSVPEvent struct {
+	RSVP RSVP
+}
+
+// PresenceEvent is the status others see of a connected user: active, away
+// or dnd (presence)
+type PresenceEvent struct {
+	UserID     string
+	Username   string
+	Status     string
+	StatusText string
+}
+
+// DegradedEvent warns the connection is falling behind: Queued of the
+// Capacity frames the server buffers for it are waiting, and the server
+// drops it once they are all taken (connection_degraded)
+type DegradedEvent struct {
+	Queued   int
+	Capacity int
+}
+
+// RecoveredEvent reports a degraded connection caught up
+// (connection_recovered)
+type RecoveredEvent struct{}
+
+// UnknownEvent is a frame of a type this version of the SDK does not know,
+// so newer servers do not break older clients
+type UnknownEvent struct {
+	FrameType string
+	Raw       json.RawMessage
+}
+
+// DisconnectedEvent reports the connection dropped. Unless Final, the
+// connection is being re-established and a ReconnectedEvent follows; Final
+// disconnections end the connection and close the events channel.
+type DisconnectedEvent struct {
+	Err   error
+	Final bool
+}
+
+// ReconnectedEvent reports the connection was re-established. Messages
+// posted while it was down are fetched with FetchSince and arrive as a
+// MessagesSinceEvent.
+type ReconnectedEvent struct {
+	Attempts int
+}
+
+func (*MessageEvent) Type() string        { return "chat_message" }
+func (*AckEvent) Type() string            { return "message_ack" }
+func (*ErrorEvent) Type() string          { return "error" }
+func (*WelcomeEvent) Type() string        { return "welcome" }
+func (*MessagesSinceEvent) Type() string  { return "messages_since" }
+func (*UserJoinedEvent) Type() string     { return "user_joined" }
+func (*UserLeftEvent) Type() string       { return "user_left" }
+func (*UserCountEvent) Type() string      { return "user_count_update" }
+func (*MessageUpdatedEvent) Type() string { return "message_updated" }
+func (*RSVPEvent) Type() string           { return "event_rsvp" }
+func (*PresenceEvent) Type() string       { return "presence" }
+func (*DegradedEvent) Type() string       { return "connection_degraded" }
+func (*RecoveredEvent) Type() string      { return "connection_recovered" }
+func (e *UnknownEvent) Type() string      { return e.FrameType }
+func (*DisconnectedEvent) Type() string   { return "disconnected" }
+func (*ReconnectedEvent) Type() string    { return "reconnected" }
+
+// frame is a frame the server sends, with the fields of every type
+type frame struct {
+	Type          string         `json:"type"`
+	ID            string         `json:"id"`
+	UserID        string         `json:"user_id"`
+	Username      string         `json:"username"`
+	Content       string         `json:"content"`
+	IsBot         bool           `json:"is_bot"`
+	IsError       bool           `json:"is_error"`
+	CreatedAt     *time.Time     `json:"created_at"`
+	Message       string         `json:"message"`
+	ClientMsgID   string         `json:"client_msg_id"`
+	Messages      []frame        `json:"messages"`
+	HasMore       bool           `json:"has_more"`
+	Previews      []LinkPreview  `json:"previews"`
+	Attachment    *Attachment    `json:"attachment"`
+	ForwardedFrom *ForwardedFrom `json:"forwarded_from"`
+	Quote         *Quote         `json:"quote"`
+	HTML          string         `json:"html"`
+	Event         *CalendarEvent `json:"event"`
+	RSVP          *RSVP          `json:"rsvp"`
+	Status        string         `json:"status"`
+	StatusText    string         `json:"status_text"`
+	UserCounts    map[string]int `json:"user_counts"`
+	Queued        int            `json:"queued"`
+	Capacity      int            `json:"capacity"`
+}
+
+func (f *frame) message(chatroomID string) Message {
+	msg := Message{
+		ID:            f.ID,
+		ChatroomID:    chatroomID,
+		UserID:        f.UserID,
+		Username:      f.Username,
+		Content:       f.Content,
+		IsBot:         f.IsBot,
+		IsError:       f.IsError,
+		HTML:          f.HTML,
+		ForwardedFrom: f.ForwardedFrom,
+		Quote:         f.Quote,
+		Event:         f.Event,
+		Attachment:    f.Attachment,
+	}
+	if f.CreatedAt != nil {
+		msg.CreatedAt = *f.CreatedAt
+	}
+	return msg
+}
+
+// decodeEvent decodes a frame of the chatroom's connection
+func decodeEvent(data []byte, chatroomID string) (Event, error) {
+	var f frame
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+
+	switch f.Type {
+	case "chat_message":
+		return &MessageEvent{Message: f.message(chatroomID), ClientMsgID: f.ClientMsgID}, nil
+	case "message_ack":
+		return &AckEvent{MessageID: f.ID, ClientMsgID: f.ClientMsgID}, nil
+	case "error":
+		return &ErrorEvent{Message: f.Message, ClientMsgID: f.ClientMsgID}, nil
+	case "welcome":
+		return &WelcomeEvent{Message: f.Message}, nil
+	case "messages_since":
+		event := &MessagesSinceEvent{Messages: make([]Message, 0, len(f.Messages)), HasMore: f.HasMore}
+		for i := range f.Messages {
+			event.Messages = append(event.Messages, f.Messages[i].message(chatroomID))
+		}
+		return event, nil
+	case "user_joined":
+		return &UserJoinedEvent{Username: f.Username, Message: f.Message}, nil
+	case "user_left":
+		return &UserLeftEvent{Username: f.Username, Message: f.Message}, nil
+	case "user_count_update":
+		return &UserCountEvent{Counts: f.UserCounts}, nil
+	case "message_updated":
+		return &MessageUpdatedEvent{MessageID: f.ID, Previews: f.Previews}, nil
+	case "event_rsvp":
+		if f.RSVP == nil {
+			return &UnknownEvent{FrameType: f.Type, Raw: data}, nil
+		}
+		return &RSVPEvent{RSVP: *f.RSVP}, nil
+	case "presence":
+		return &PresenceEvent{UserID: f.UserID, Username: f.Username, Status: f.Status, StatusText: f.StatusText}, nil
+	case "connection_degraded":
+		return &DegradedEvent{Queued: f.Queued, Capacity: f.Capacity}, nil
+	case "connection_recovered":
+		return &RecoveredEvent{}, nil
+	default:
+		return &UnknownEvent{FrameType: f.Type, Raw: data}, nil
+	}
+}
@@ -0,0 +1,111 @@
+package client
+
+import "time"
+
+// User is a chat user
+type User struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	// Email is only returned for the current user
+	Email  string `json:"email,omitempty"`
+	Locale string `json:"locale,omitempty"`
+}
+
+// Chatroom is a chatroom of the directory
+type Chatroom struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+	Encrypted bool      `json:"encrypted"`
+	// UserCount is how many users are connected to the chatroom right now
+	UserCount     int        `json:"user_count"`
+	MemberCount   int        `json:"member_count"`
+	LastMessageAt *time.Time `json:"last_message_at"`
+	Tags          []string   `json:"tags"`
+	Starred       bool       `json:"starred"`
+	StarPosition  *int       `json:"star_position"`
+}
+
+// ChatroomPage is a page of the chatroom directory
+type ChatroomPage struct {
+	Chatrooms []Chatroom `json:"chatrooms"`
+	// NextCursor fetches the next page; empty on the last one
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Message is a chat message
+type Message struct {
+	ID         string    `json:"id"`
+	ChatroomID string    `json:"chatroom_id,omitempty"`
+	UserID     string    `json:"user_id"`
+	Username   string    `json:"username"`
+	Content    string    `json:"content"`
+	IsBot      bool      `json:"is_bot"`
+	CreatedAt  time.Time `json:"created_at"`
+	// IsError marks a bot's reply reporting a failed command; only set on
+	// messages delivered over a WebSocket connection
+	IsError bool `json:"is_error,omitempty"`
+	// HTML is Content rendered from Markdown, in chatrooms that enable it
+	HTML          string         `json:"html,omitempty"`
+	ForwardedFrom *ForwardedFrom `json:"forwarded_from,omitempty"`
+	Quote         *Quote         `json:"quote,omitempty"`
+	Event         *CalendarEvent `json:"event,omitempty"`
+	// Attachment is the image of a bot message, e.g. a /giphy result
+	Attachment *Attachment `json:"attachment,omitempty"`
+}
+
+// ForwardedFrom attributes a forwarded message to its original author
+type ForwardedFrom struct {
+	MessageID  string `json:"message_id,omitempty"`
+	ChatroomID string `json:"chatroom_id,omitempty"`
+	UserID     string `json:"user_id,omitempty"`
+	Username   string `json:"username"`
+}
+
+// Quote is the snapshot of the message a reply quotes
+type Quote struct {
+	MessageID string    `json:"message_id"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CalendarEvent is the event an event message announces
+type CalendarEvent struct {
+	Title    string     `json:"title"`
+	StartsAt time.Time  `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+	Location string     `json:"location,omitempty"`
+}
+
+// Attachment is an image attached to a message
+type Attachment struct {
+	Type   string `json:"type"`
+	URL    string `json:"url"`
+	Title  string `json:"title,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+// LinkPreview is the preview of a link in a message
+type LinkPreview struct {
+	MessageID   string    `json:"message_id"`
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	SiteName    string    `json:"site_name,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// RSVP is a member's answer to an event: going, maybe or declined
+type RSVP struct {
+	MessageID  string    `json:"message_id"`
+	ChatroomID string    `json:"chatroom_id"`
+	UserID     string    `json:"user_id"`
+	Username   string    `json:"username"`
+	Response   string    `json:"response"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
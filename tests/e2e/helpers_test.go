@@ -4,23 +4,22 @@
 package e2e
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"net/http"
 	"net/http/cookiejar"
-	"sync"
 	"testing"
 	"time"
 
-	"github.com/gorilla/websocket"
+	"jobsity-chat/pkg/client"
 )
 
-// TestClient wraps http.Client with cookie handling for a single user session
+// TestClient is a single user's session: the SDK client for API calls, and
+// its HTTP client with a cookie jar for tests that send raw requests
 type TestClient struct {
 	*http.Client
 	t            *testing.T
+	api          *client.Client
 	sessionToken string
 	userID       string
 	username     string
@@ -33,84 +32,60 @@ func NewTestClient(t *testing.T) *TestClient {
 		t.Fatalf("failed to create cookie jar: %v", err)
 	}
 
+	api, err := client.New(baseURL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	httpClient := &http.Client{
+		Timeout: 30 * time.Second,
+		Jar:     jar,
+	}
+	api.SetHTTPClient(httpClient)
+
 	return &TestClient{
-		Client: &http.Client{
-			Timeout: 30 * time.Second,
-			Jar:     jar,
-		},
-		t: t,
+		Client: httpClient,
+		t:      t,
+		api:    api,
 	}
 }
 
 // RegisterUser registers a new user and returns the response
 func (tc *TestClient) RegisterUser(username, email, password string) (*RegisterResponse, error) {
-	body := map[string]string{
-		"username": username,
-		"email":    email,
-		"password": password,
-	}
-
-	resp, err := tc.PostJSON("/api/v1/auth/register", body)
+	user, err := tc.api.Register(context.Background(), client.RegisterRequest{
+		Username: username,
+		Email:    email,
+		Password: password,
+	})
 	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("register failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, fmt.Errorf("register failed: %w", err)
 	}
 
-	var result RegisterResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode register response: %w", err)
-	}
-
-	tc.userID = result.ID
-	tc.username = result.Username
-	return &result, nil
+	tc.userID = user.ID
+	tc.username = user.Username
+	return registerResponse(user), nil
 }
 
 // LoginUser logs in a user and stores the session token
 func (tc *TestClient) LoginUser(username, password string) (*LoginResponse, error) {
-	body := map[string]string{
-		"username": username,
-		"password": password,
-	}
-
-	resp, err := tc.PostJSON("/api/v1/auth/login", body)
+	user, err := tc.api.Login(context.Background(), username, password)
 	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("login failed with status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	var result LoginResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode login response: %w", err)
+		return nil, fmt.Errorf("login failed: %w", err)
 	}
 
-	tc.sessionToken = result.SessionToken
-	tc.userID = result.User.ID
-	tc.username = result.User.Username
-	return &result, nil
+	tc.sessionToken = tc.api.Token()
+	tc.userID = user.ID
+	tc.username = user.Username
+	return &LoginResponse{
+		Success:      true,
+		User:         *registerResponse(user),
+		SessionToken: tc.sessionToken,
+	}, nil
 }
 
 // Logout logs out the current user
 func (tc *TestClient) Logout() error {
-	resp, err := tc.PostJSON("/api/v1/auth/logout", nil)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("logout failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	if err := tc.api.Logout(context.Background()); err != nil {
+		return fmt.Errorf("logout failed: %w", err)
 	}
 
 	tc.sessionToken = ""
@@ -119,130 +94,72 @@ func (tc *TestClient) Logout() error {
 
 // GetMe returns the current user information
 func (tc *TestClient) GetMe() (*RegisterResponse, error) {
-	resp, err := tc.Get(baseURL + "/api/v1/auth/me")
+	user, err := tc.api.Me(context.Background())
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("get me failed: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("get me failed with status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	var result RegisterResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode me response: %w", err)
-	}
-
-	return &result, nil
+	return registerResponse(user), nil
 }
 
 // CreateChatroom creates a new chatroom
 func (tc *TestClient) CreateChatroom(name string) (*ChatroomResponse, error) {
-	body := map[string]string{
-		"name": name,
-	}
-
-	resp, err := tc.PostJSON("/api/v1/chatrooms", body)
+	chatroom, err := tc.api.CreateChatroom(context.Background(), name)
 	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("create chatroom failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, fmt.Errorf("create chatroom failed: %w", err)
 	}
-
-	var result ChatroomResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode chatroom response: %w", err)
-	}
-
-	return &result, nil
+	return chatroomResponse(chatroom), nil
 }
 
 // ListChatrooms lists all chatrooms
 func (tc *TestClient) ListChatrooms() (*ListChatroomsResponse, error) {
-	resp, err := tc.Get(baseURL + "/api/v1/chatrooms")
+	page, err := tc.api.ListChatrooms(context.Background(), client.ListChatroomsOptions{})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("list chatrooms failed: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("list chatrooms failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	result := &ListChatroomsResponse{NextCursor: page.NextCursor}
+	for i := range page.Chatrooms {
+		result.Chatrooms = append(result.Chatrooms, *chatroomResponse(&page.Chatrooms[i]))
 	}
-
-	var result ListChatroomsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode chatrooms response: %w", err)
-	}
-
-	return &result, nil
+	return result, nil
 }
 
 // JoinChatroom joins a chatroom
 func (tc *TestClient) JoinChatroom(chatroomID string) error {
-	resp, err := tc.PostJSON(fmt.Sprintf("/api/v1/chatrooms/%s/join", chatroomID), nil)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("join chatroom failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	if err := tc.api.JoinChatroom(context.Background(), chatroomID); err != nil {
+		return fmt.Errorf("join chatroom failed: %w", err)
 	}
-
 	return nil
 }
 
 // GetMessages gets messages from a chatroom
 func (tc *TestClient) GetMessages(chatroomID string, limit int) (*MessagesResponse, error) {
-	url := fmt.Sprintf("%s/api/v1/chatrooms/%s/messages", baseURL, chatroomID)
-	if limit > 0 {
-		url = fmt.Sprintf("%s?limit=%d", url, limit)
-	}
-
-	resp, err := tc.Get(url)
+	messages, err := tc.api.Messages(context.Background(), chatroomID, limit, time.Time{})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("get messages failed: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("get messages failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	result := &MessagesResponse{}
+	for _, msg := range messages {
+		result.Messages = append(result.Messages, MessageResponse{
+			ID:         msg.ID,
+			ChatroomID: msg.ChatroomID,
+			UserID:     msg.UserID,
+			Username:   msg.Username,
+			Content:    msg.Content,
+			IsBot:      msg.IsBot,
+			CreatedAt:  msg.CreatedAt.Format(time.RFC3339Nano),
+		})
 	}
-
-	var result MessagesResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode messages response: %w", err)
-	}
-
-	return &result, nil
+	return result, nil
 }
 
-// PostJSON makes a POST request with JSON body
+// PostJSON makes an authenticated POST request with JSON body
 func (tc *TestClient) PostJSON(path string, body any) (*http.Response, error) {
-	var bodyReader io.Reader
-	if body != nil {
-		jsonBody, err := json.Marshal(body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal body: %w", err)
-		}
-		bodyReader = bytes.NewReader(jsonBody)
-	}
-
-	req, err := http.NewRequest(http.MethodPost, baseURL+path, bodyReader)
+	req, err := tc.api.NewRequest(context.Background(), http.MethodPost, path, body)
 	if err != nil {
 		return nil, err
 	}
-
-	req.Header.Set("Content-Type", "application/json")
 	return tc.Do(req)
 }
 
@@ -286,15 +203,28 @@ type MessagesResponse struct {
 	Messages []MessageResponse `json:"messages"`
 }
 
+func registerResponse(user *client.User) *RegisterResponse {
+	return &RegisterResponse{ID: user.ID, Username: user.Username, Email: user.Email}
+}
+
+func chatroomResponse(chatroom *client.Chatroom) *ChatroomResponse {
+	return &ChatroomResponse{
+		ID:        chatroom.ID,
+		Name:      chatroom.Name,
+		CreatedAt: chatroom.CreatedAt.Format(time.RFC3339Nano),
+		CreatedBy: chatroom.CreatedBy,
+		UserCount: chatroom.UserCount,
+	}
+}
+
 // WebSocket helpers
 
-// WSClient represents a WebSocket client for testing
+// WSClient is an SDK connection to a chatroom with its events flattened
+// into WSMessages tests can match on
 type WSClient struct {
 	t          *testing.T
-	conn       *websocket.Conn
-	mu         sync.Mutex
+	conn       *client.Conn
 	messages   chan WSMessage
-	done       chan struct{}
 	chatroomID string
 }
 
@@ -315,15 +245,13 @@ type WSMessage struct {
 	Error      string         `json:"error,omitempty"`
 }
 
-// ConnectWebSocket connects to a chatroom via WebSocket
+// ConnectWebSocket connects to a chatroom via WebSocket. Tests assert on
+// disconnections, so the connection is not re-established.
 func (tc *TestClient) ConnectWebSocket(chatroomID string) (*WSClient, error) {
-	url := fmt.Sprintf("%s/ws/chat/%s?token=%s", wsURL, chatroomID, tc.sessionToken)
-
-	dialer := websocket.Dialer{
-		HandshakeTimeout: 10 * time.Second,
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	conn, _, err := dialer.Dial(url, nil)
+	conn, err := tc.api.Connect(ctx, chatroomID, client.ConnectOptions{DisableReconnect: true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to WebSocket: %w", err)
 	}
@@ -332,7 +260,6 @@ func (tc *TestClient) ConnectWebSocket(chatroomID string) (*WSClient, error) {
 		t:          tc.t,
 		conn:       conn,
 		messages:   make(chan WSMessage, 100),
-		done:       make(chan struct{}),
 		chatroomID: chatroomID,
 	}
 
@@ -341,50 +268,48 @@ func (tc *TestClient) ConnectWebSocket(chatroomID string) (*WSClient, error) {
 	return wsc, nil
 }
 
-// readLoop reads messages from the WebSocket connection
+// readLoop converts the connection's events until it ends
 func (wsc *WSClient) readLoop() {
 	defer close(wsc.messages)
 
-	for {
+	for event := range wsc.conn.Events() {
+		msg := WSMessage{Type: event.Type(), ChatroomID: wsc.chatroomID}
+		switch e := event.(type) {
+		case *client.MessageEvent:
+			createdAt := e.CreatedAt
+			msg.ID = e.ID
+			msg.UserID = e.UserID
+			msg.Username = e.Username
+			msg.Content = e.Content
+			msg.IsBot = e.IsBot
+			msg.IsError = e.IsError
+			msg.CreatedAt = &createdAt
+		case *client.AckEvent:
+			msg.ID = e.MessageID
+		case *client.ErrorEvent:
+			msg.Error = e.Message
+		case *client.UserJoinedEvent:
+			msg.Username = e.Username
+		case *client.UserLeftEvent:
+			msg.Username = e.Username
+		case *client.UserCountEvent:
+			msg.UserCounts = e.Counts
+		case *client.DisconnectedEvent:
+			continue
+		}
+
 		select {
-		case <-wsc.done:
-			return
+		case wsc.messages <- msg:
 		default:
-			_, data, err := wsc.conn.ReadMessage()
-			if err != nil {
-				if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
-					return
-				}
-				// Connection closed
-				return
-			}
-
-			var msg WSMessage
-			if err := json.Unmarshal(data, &msg); err != nil {
-				wsc.t.Logf("failed to unmarshal WebSocket message: %v", err)
-				continue
-			}
-
-			select {
-			case wsc.messages <- msg:
-			default:
-				wsc.t.Log("message channel full, dropping message")
-			}
+			wsc.t.Log("message channel full, dropping message")
 		}
 	}
 }
 
 // SendMessage sends a chat message
 func (wsc *WSClient) SendMessage(content string) error {
-	wsc.mu.Lock()
-	defer wsc.mu.Unlock()
-
-	msg := map[string]string{
-		"type":    "message",
-		"content": content,
-	}
-
-	return wsc.conn.WriteJSON(msg)
+	_, err := wsc.conn.Send(content)
+	return err
 }
 
 // WaitForMessage waits for a message matching the predicate
@@ -434,10 +359,6 @@ func (wsc *WSClient) DrainMessages() {
 
 // Close closes the WebSocket connection
 func (wsc *WSClient) Close() error {
-	close(wsc.done)
-	wsc.mu.Lock()
-	defer wsc.mu.Unlock()
-
 	return wsc.conn.Close()
 }
 
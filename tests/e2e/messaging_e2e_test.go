@@ -40,7 +40,7 @@ func TestMessaging_PublishStockCommand(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err := rmq.PublishStockCommand(ctx, "test-chatroom-1", "AAPL.US", "test-user-1")
+	err := rmq.PublishStockCommand(ctx, "test-chatroom-1", "", "AAPL.US", "test-user-1")
 	assert.NoError(t, err, "should publish stock command without error")
 }
 
@@ -50,7 +50,7 @@ func TestMessaging_PublishHelloCommand(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err := rmq.PublishHelloCommand(ctx, "test-chatroom-2", "test-user-2")
+	err := rmq.PublishHelloCommand(ctx, "test-chatroom-2", "", "test-user-2")
 	assert.NoError(t, err, "should publish hello command without error")
 }
 
@@ -167,7 +167,7 @@ func TestMessaging_Publish_ContextTimeout(t *testing.T) {
 	defer cancel()
 
 	startTime := time.Now()
-	err := rmq.PublishStockCommand(ctx, "timeout-test-room", "AAPL.US", "user")
+	err := rmq.PublishStockCommand(ctx, "timeout-test-room", "", "AAPL.US", "user")
 	duration := time.Since(startTime)
 
 	assert.NoError(t, err, "should publish without error")
@@ -101,7 +101,7 @@ func setupTestEnvironment(ctx context.Context) (func(), error) {
 
 	// Connect to RabbitMQ with timeout
 	rmqCtx, rmqCancel := context.WithTimeout(ctx, 30*time.Second)
-	rmq, err = messaging.NewRabbitMQWithRetry(rmqCtx, rmqURL)
+	rmq, err = messaging.NewRabbitMQWithRetry(rmqCtx, rmqURL, messaging.DefaultTopology())
 	rmqCancel()
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
@@ -281,24 +281,82 @@ func runMigrations(db *sql.DB) error {
 	schema := `
 		CREATE EXTENSION IF NOT EXISTS "pgcrypto";
 
+		CREATE TABLE IF NOT EXISTS organizations (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			slug VARCHAR(63) UNIQUE NOT NULL CHECK (slug ~ '^[a-z0-9]([a-z0-9-]*[a-z0-9])?$'),
+			name VARCHAR(100) NOT NULL CHECK (length(name) >= 1),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+		);
+
+		INSERT INTO organizations (id, slug, name)
+		VALUES ('00000000-0000-0000-0000-000000000001', 'default', 'Default')
+		ON CONFLICT (id) DO NOTHING;
+
+		CREATE TABLE IF NOT EXISTS organization_quotas (
+			org_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+			max_rooms_per_user INTEGER CHECK (max_rooms_per_user >= 0),
+			max_messages_per_room_per_day INTEGER CHECK (max_messages_per_room_per_day >= 0),
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS bot_command_usage (
+			id BIGSERIAL PRIMARY KEY,
+			org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+			chatroom_id UUID NOT NULL,
+			command_type VARCHAR(20) NOT NULL,
+			symbol TEXT NOT NULL DEFAULT '',
+			requested_by VARCHAR(50) NOT NULL DEFAULT '',
+			latency_ms INTEGER NOT NULL,
+			success BOOLEAN NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+		);
+
 		CREATE TABLE IF NOT EXISTS users (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			username VARCHAR(50) UNIQUE NOT NULL CHECK (length(username) >= 3),
-			email VARCHAR(255) UNIQUE NOT NULL CHECK (email ~* '^[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}$'),
+			org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE,
+			username VARCHAR(50) NOT NULL CHECK (length(username) >= 3),
+			email VARCHAR(255) NOT NULL CHECK (email ~* '^[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}$'),
 			password_hash VARCHAR(255) NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+			role VARCHAR(20) NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin')),
+			locale VARCHAR(10) NOT NULL DEFAULT '',
+			deactivated_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+			CONSTRAINT users_org_username_key UNIQUE (org_id, username),
+			CONSTRAINT users_org_email_key UNIQUE (org_id, email)
 		);
 
 		CREATE TABLE IF NOT EXISTS sessions (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE,
 			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
 			token VARCHAR(255) UNIQUE NOT NULL,
 			expires_at TIMESTAMP NOT NULL,
+			absolute_expires_at TIMESTAMP NOT NULL,
+			idle_timeout_seconds INTEGER NOT NULL DEFAULT 86400 CHECK (idle_timeout_seconds > 0),
+			last_activity_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS ws_tickets (
+			ticket VARCHAR(64) PRIMARY KEY,
+			session_token VARCHAR(255) NOT NULL REFERENCES sessions(token) ON DELETE CASCADE,
+			expires_at TIMESTAMP NOT NULL,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
 		);
 
+		CREATE TABLE IF NOT EXISTS user_identities (
+			org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE,
+			provider VARCHAR(50) NOT NULL,
+			subject VARCHAR(255) NOT NULL,
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			email VARCHAR(255) NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+			PRIMARY KEY (org_id, provider, subject)
+		);
+
 		CREATE TABLE IF NOT EXISTS chatrooms (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE,
 			name VARCHAR(100) NOT NULL CHECK (length(name) >= 1),
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
 			created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
@@ -313,6 +371,7 @@ func runMigrations(db *sql.DB) error {
 
 		CREATE TABLE IF NOT EXISTS messages (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id) ON DELETE CASCADE,
 			chatroom_id UUID NOT NULL REFERENCES chatrooms(id) ON DELETE CASCADE,
 			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
 			content TEXT NOT NULL CHECK (length(content) > 0 AND length(content) <= 1000),
@@ -351,13 +410,21 @@ func setupChatServer(db *sql.DB, rmq *messaging.RabbitMQ) (func(), error) {
 	authService := service.NewAuthService(userRepo, sessionRepo)
 	chatService := service.NewChatService(messageRepo, chatroomRepo)
 
+	// Responses are attributed to StockBot
+	stockBot := messaging.DefaultBot(messaging.DefaultTopology().CommandRoutingKey)
+	stockBot.UserID = "test-bot-001"
+	bots, err := messaging.NewBotRegistry(stockBot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build bot registry: %w", err)
+	}
+
 	// Create WebSocket hub
 	testHub = websocket.NewHub()
 	hubCtx, hubCancel := context.WithCancel(context.Background())
 	go testHub.Run(hubCtx)
 
 	// Create and start response consumer
-	responseConsumer = messaging.NewResponseConsumer(rmq, testHub, chatService, "test-bot-001")
+	responseConsumer = messaging.NewResponseConsumer(rmq, testHub, chatService, bots)
 	consumerCtx, consumerCancel := context.WithCancel(context.Background())
 	err = responseConsumer.Start(consumerCtx)
 	if err != nil {